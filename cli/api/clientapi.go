@@ -0,0 +1,95 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ClientHttpClient talks to the control port of a fuse client, which is its pprof port.
+type ClientHttpClient struct {
+	host string
+}
+
+func NewClientHttpClient(host string) *ClientHttpClient {
+	return &ClientHttpClient{host: host}
+}
+
+// GetConfig returns the config of the fuse client that can be changed at runtime.
+func (c *ClientHttpClient) GetConfig() (conf *proto.ClientConfig, err error) {
+	conf = &proto.ClientConfig{}
+	if err = c.serveRequest(proto.ClientGetConfigPath, nil, conf); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// SetConfig changes the config of the fuse client by the given keys and values, and returns the changed config.
+func (c *ClientHttpClient) SetConfig(params map[string]string) (conf *proto.ClientConfig, err error) {
+	conf = &proto.ClientConfig{}
+	if err = c.serveRequest(proto.ClientSetConfigPath, params, conf); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// GetStat returns the statistics of the caches and the streams of the fuse client.
+func (c *ClientHttpClient) GetStat() (stat *proto.ClientStat, err error) {
+	stat = &proto.ClientStat{}
+	if err = c.serveRequest(proto.ClientStatPath, nil, stat); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// serveRequest sends the request to the fuse client and unmarshals the data of the reply into the result.
+func (c *ClientHttpClient) serveRequest(path string, params map[string]string, result interface{}) (err error) {
+	var values = url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	var reqURL = fmt.Sprintf("http://%s%s?%s", c.host, path, values.Encode())
+	var client = &http.Client{Timeout: requestTimeout}
+	var resp *http.Response
+	if resp, err = client.Get(reqURL); err != nil {
+		log.LogErrorf("serveRequest: send http request fail: url(%v) err(%v)", reqURL, err)
+		return
+	}
+	var respData []byte
+	respData, err = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return
+	}
+	var body = &struct {
+		Code int32           `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err = json.Unmarshal(respData, body); err != nil {
+		return fmt.Errorf("unmarshal response body from client[%v] err:%v", c.host, err)
+	}
+	if body.Code != http.StatusOK {
+		return fmt.Errorf("client[%v] replies: %v", c.host, body.Msg)
+	}
+	return json.Unmarshal(body.Data, result)
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/cli/api"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClientUse   = "client [COMMAND]"
+	cmdClientShort = "Manage the config and show the statistics of a mounted fuse client"
+)
+
+func newClientCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdClientUse,
+		Short: cmdClientShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newClientInfoCmd(),
+		newClientSetCmd(),
+		newClientStatCmd(),
+	)
+	return cmd
+}
+
+const (
+	cmdClientInfoShort = "Show the config of a fuse client that can be changed at runtime"
+	cmdClientSetShort  = "Change the config of a fuse client without remounting"
+	cmdClientStatShort = "Show the statistics of the caches and the streams of a fuse client"
+)
+
+func newClientInfoCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpInfo + " [CLIENT ADDRESS]",
+		Short: cmdClientInfoShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf *proto.ClientConfig
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if conf, err = api.NewClientHttpClient(args[0]).GetConfig(); err != nil {
+				return
+			}
+			stdout("Config of client [%v]:\n%s", args[0], formatClientConfig(conf))
+		},
+	}
+	return cmd
+}
+
+func newClientSetCmd() *cobra.Command {
+	var (
+		optReadRate      int64
+		optWriteRate     int64
+		optIcacheTimeout int64
+		optIcacheSize    int64
+		optLogLevel      string
+		optFollowerRead  string
+	)
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [CLIENT ADDRESS]",
+		Short: cmdClientSetShort,
+		Long: `Change the config of a mounted fuse client through its control port, which is the profPort of
+the mount options, such as "cfs-cli client set 192.168.0.11:27510 --read-rate 1000". The page cache
+of the kernel and the inode cache are kept, the changes are lost once the client is remounted.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf *proto.ClientConfig
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var params = make(map[string]string)
+			if optReadRate >= 0 {
+				params[proto.ClientConfigReadRate] = strconv.FormatInt(optReadRate, 10)
+			}
+			if optWriteRate >= 0 {
+				params[proto.ClientConfigWriteRate] = strconv.FormatInt(optWriteRate, 10)
+			}
+			if optIcacheTimeout >= 0 {
+				params[proto.ClientConfigIcacheTimeout] = strconv.FormatInt(optIcacheTimeout, 10)
+			}
+			if optIcacheSize >= 0 {
+				params[proto.ClientConfigIcacheSize] = strconv.FormatInt(optIcacheSize, 10)
+			}
+			if optLogLevel != "" {
+				params[proto.ClientConfigLogLevel] = optLogLevel
+			}
+			if optFollowerRead != "" {
+				var enable bool
+				if enable, err = strconv.ParseBool(optFollowerRead); err != nil {
+					return
+				}
+				params[proto.ClientConfigFollowerRead] = strconv.FormatBool(enable)
+			}
+			if len(params) == 0 {
+				err = fmt.Errorf("no config is specified")
+				return
+			}
+			if conf, err = api.NewClientHttpClient(args[0]).SetConfig(params); err != nil {
+				return
+			}
+			stdout("Config of client [%v] has been changed:\n%s", args[0], formatClientConfig(conf))
+		},
+	}
+	cmd.Flags().Int64Var(&optReadRate, CliFlagReadRate, -1, "Specify the read rate limit of the client, 0 means unlimited")
+	cmd.Flags().Int64Var(&optWriteRate, CliFlagWriteRate, -1, "Specify the write rate limit of the client, 0 means unlimited")
+	cmd.Flags().Int64Var(&optIcacheTimeout, CliFlagIcacheTimeout, -1, "Specify the expiration of the inode cache [Unit: s]")
+	cmd.Flags().Int64Var(&optIcacheSize, CliFlagIcacheSize, -1, "Specify the max number of the inodes in the inode cache")
+	cmd.Flags().StringVar(&optLogLevel, CliFlagLogLevel, "", "Specify the log level [debug, info, warn, error]")
+	cmd.Flags().StringVar(&optFollowerRead, CliFlagEnableFollowerRead, "", "Enable read from replica follower")
+	return cmd
+}
+
+func newClientStatCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpStatus + " [CLIENT ADDRESS]",
+		Short: cmdClientStatShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var stat *proto.ClientStat
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if stat, err = api.NewClientHttpClient(args[0]).GetStat(); err != nil {
+				return
+			}
+			stdout("Statistics of client [%v]:\n%s", args[0], formatClientStat(stat))
+		},
+	}
+	return cmd
+}
@@ -39,6 +39,12 @@ func (cmd *ChubaoFSCmd) newClusterCmd(client *master.MasterClient) *cobra.Comman
 		newClusterFreezeCmd(client),
 		newClusterSetThresholdCmd(client),
 		newClusterDeleteParasCmd(client),
+		newClusterSetCmd(client),
+		newClusterCordonCmd(client),
+		newClusterAuditCmd(client),
+		newClusterSlowOpsCmd(client),
+		newClusterBackupMetaCmd(client),
+		newClusterLogLevelCmd(),
 	)
 	return clusterCmd
 }
@@ -76,6 +82,22 @@ func newClusterInfoCmd(client *master.MasterClient) *cobra.Command {
 			stdout(fmt.Sprintf("  DeleteWorkerSleepMs: %v\n", delPara[nodeDeleteWorkerSleepMs]))
 			stdout(fmt.Sprintf("  AutoRepairRate     : %v\n", delPara[nodeAutoRepairRateKey]))
 			stdout("\n")
+			if policies, err := client.AdminAPI().ListRebuildPolicies(); err == nil && len(policies) > 0 {
+				stdout("[Rebuild Policies]\n")
+				stdout("%v\n", rebuildPolicyTableHeader)
+				for _, policy := range policies {
+					stdout("%v\n", formatRebuildPolicyTableRow(policy))
+				}
+				stdout("\n")
+			}
+			if len(cv.Cordons) > 0 {
+				stdout("[Cordons]\n")
+				stdout("%v\n", cordonTableHeader)
+				for _, cordon := range cv.Cordons {
+					stdout("%v\n", formatCordonTableRow(cordon))
+				}
+				stdout("\n")
+			}
 		},
 	}
 	return cmd
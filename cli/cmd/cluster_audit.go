@@ -0,0 +1,95 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterAuditUse   = "audit"
+	cmdClusterAuditShort = "List the admin operations recorded by the master"
+)
+
+// parseAuditTime accepts either an RFC3339 time or a duration before now, such as "24h".
+func parseAuditTime(value string) (t int64, err error) {
+	if value == "" {
+		return
+	}
+	var duration time.Duration
+	if duration, err = time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return 0, fmt.Errorf("invalid duration: %v", value)
+		}
+		return time.Now().Add(-duration).Unix(), nil
+	}
+	var tm time.Time
+	if tm, err = time.Parse(time.RFC3339, value); err != nil {
+		return 0, fmt.Errorf("invalid time or duration: %v", value)
+	}
+	return tm.Unix(), nil
+}
+
+func newClusterAuditCmd(client *sdk.MasterClient) *cobra.Command {
+	var (
+		optSince  string
+		optUntil  string
+		optOp     string
+		optTarget string
+		optLimit  int
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdClusterAuditUse,
+		Short: cmdClusterAuditShort,
+		Long: `List the latest admin operations which change the cluster, such as creating, updating and
+deleting volumes, decommissioning nodes and changing users, with the client, the parameters and
+the result of each operation. The credentials in the parameters are not recorded. --since and
+--until accept an RFC3339 time or a duration before now, e.g. "24h".`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var from, to int64
+			var events []*proto.AuditEvent
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if from, err = parseAuditTime(optSince); err != nil {
+				return
+			}
+			if to, err = parseAuditTime(optUntil); err != nil {
+				return
+			}
+			if events, err = client.AdminAPI().ListAuditEvents(from, to, optOp, optTarget, optLimit); err != nil {
+				return
+			}
+			stdout("%v\n", auditEventTableHeader)
+			for _, event := range events {
+				stdout("%v\n", formatAuditEventTableRow(event))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optSince, CliFlagSince, "", "List the operations since the time")
+	cmd.Flags().StringVar(&optUntil, CliFlagUntil, "", "List the operations before the time")
+	cmd.Flags().StringVar(&optOp, CliFlagOp, "", "List the operations whose API path contains the string, e.g. \"vol\"")
+	cmd.Flags().StringVar(&optTarget, CliFlagTarget, "", "List the operations on the volume, node or user")
+	cmd.Flags().IntVar(&optLimit, CliFlagLimit, 100, "Maximum number of the latest operations to list")
+	return cmd
+}
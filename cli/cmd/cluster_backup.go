@@ -0,0 +1,118 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterBackupMetaUse   = "backup-meta [DIR]"
+	cmdClusterBackupMetaShort = "Back up the metadata of the masters to a directory"
+)
+
+func newClusterBackupMetaCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdClusterBackupMetaUse,
+		Short: cmdClusterBackupMetaShort,
+		Long: `Back up a consistent snapshot of the metadata of the masters, i.e. the volumes, the nodes,
+the partitions and the users, to a gzip archive in the directory. The archive is verified after
+it is downloaded. It contains the credentials of the users, so keep it safe. To bootstrap a new
+master group from the archive, set "restoreMeta" to the path of the archive in the config of
+every master of the group, and start the masters with an empty storeDir and walDir.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err    error
+				tmp    *os.File
+				header *proto.MasterMetaBackup
+				dir    = args[0]
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if tmp, err = ioutil.TempFile(dir, ".meta-backup-"); err != nil {
+				return
+			}
+			defer os.Remove(tmp.Name())
+			err = client.AdminAPI().BackupMeta(tmp)
+			if e := tmp.Close(); err == nil {
+				err = e
+			}
+			if err != nil {
+				return
+			}
+			if header, err = verifyMetaBackup(tmp.Name()); err != nil {
+				return
+			}
+			file := path.Join(dir, fmt.Sprintf("%v-meta-%v.gz", header.ClusterName,
+				time.Unix(header.CreateTime, 0).Format("20060102150405")))
+			if err = os.Rename(tmp.Name(), file); err != nil {
+				return
+			}
+			stdout("Metadata of cluster %v is backed up to %v\n", header.ClusterName, file)
+			stdout("  Applied : %v\n", header.Applied)
+			stdout("  Keys    : %v\n", header.Keys)
+		},
+	}
+	return cmd
+}
+
+// verifyMetaBackup reads through the archive, the gzip checksum of which detects a broken download.
+func verifyMetaBackup(file string) (header *proto.MasterMetaBackup, err error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	gr, err := gzip.NewReader(fp)
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+	decoder := json.NewDecoder(gr)
+	header = &proto.MasterMetaBackup{}
+	if err = decoder.Decode(header); err != nil {
+		return nil, fmt.Errorf("decode the header of the backup: %v", err)
+	}
+	var keys uint64
+	for {
+		var kv json.RawMessage
+		if err = decoder.Decode(&kv); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode the key-value pair %v of the backup: %v", keys, err)
+		}
+		keys++
+	}
+	if keys != header.Keys {
+		return nil, fmt.Errorf("the backup is incomplete, %v of %v key-value pairs are found", keys, header.Keys)
+	}
+	return header, nil
+}
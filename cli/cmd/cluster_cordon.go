@@ -0,0 +1,174 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterCordonUse   = "cordon [COMMAND]"
+	cmdClusterCordonShort = "Manage the zones and nodes kept away from new replicas"
+)
+
+var cordonTypes = []string{proto.CordonTypeZone, proto.CordonTypeDataNode, proto.CordonTypeMetaNode}
+
+func newClusterCordonCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdClusterCordonUse,
+		Short: cmdClusterCordonShort,
+	}
+	cmd.AddCommand(
+		newClusterCordonSetCmd(client),
+		newClusterCordonDeleteCmd(client),
+		newClusterCordonListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdClusterCordonSetShort    = "Cordon a zone or a node for a planned maintenance"
+	cmdClusterCordonDeleteShort = "Remove the cordon of a zone or a node"
+	cmdClusterCordonListShort   = "List the active cordons"
+)
+
+// parseCordonUntil accepts either an RFC3339 time or a duration from now, such as "4h".
+func parseCordonUntil(value string) (until int64, err error) {
+	if value == "" {
+		return
+	}
+	var duration time.Duration
+	if duration, err = time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return 0, fmt.Errorf("invalid duration: %v", value)
+		}
+		return time.Now().Add(duration).Unix(), nil
+	}
+	var t time.Time
+	if t, err = time.Parse(time.RFC3339, value); err != nil {
+		return 0, fmt.Errorf("invalid time or duration: %v", value)
+	}
+	return t.Unix(), nil
+}
+
+func validCordonArgs(client *sdk.MasterClient, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return cordonTypes, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		switch args[0] {
+		case proto.CordonTypeZone:
+			return validZones(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		case proto.CordonTypeDataNode:
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		case proto.CordonTypeMetaNode:
+			return validMetaNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+func newClusterCordonSetCmd(client *sdk.MasterClient) *cobra.Command {
+	var optReason, optUntil string
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [TYPE] [NAME]",
+		Short: cmdClusterCordonSetShort,
+		Long: `Keep the new data and meta partitions, as well as the replicas added by decommission and
+recovery, away from a zone or a node. TYPE is one of zone, dataNode and metaNode, NAME is the
+zone name or the node address. The existing replicas are not moved. With --until the cordon
+expires by itself at the given RFC3339 time or after the given duration, e.g. "6h".`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var until int64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if until, err = parseCordonUntil(optUntil); err != nil {
+				return
+			}
+			if err = client.AdminAPI().SetCordon(args[0], args[1], optReason, until); err != nil {
+				return
+			}
+			if until == 0 {
+				stdout("%v [%v] is cordoned until uncordoned.\n", args[0], args[1])
+				return
+			}
+			stdout("%v [%v] is cordoned until %v.\n", args[0], args[1], formatTime(until))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validCordonArgs(client, args, toComplete)
+		},
+	}
+	cmd.Flags().StringVar(&optReason, CliFlagReason, "", "Reason of the cordon, e.g. the maintenance ticket")
+	cmd.Flags().StringVar(&optUntil, CliFlagUntil, "", "Expiration of the cordon, an RFC3339 time or a duration from now")
+	return cmd
+}
+
+func newClusterCordonDeleteCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpDelete + " [TYPE] [NAME]",
+		Short: cmdClusterCordonDeleteShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().RemoveCordon(args[0], args[1]); err != nil {
+				return
+			}
+			stdout("%v [%v] is uncordoned.\n", args[0], args[1])
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validCordonArgs(client, args, toComplete)
+		},
+	}
+	return cmd
+}
+
+func newClusterCordonListCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdClusterCordonListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var cordons []*proto.NodeCordon
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if cordons, err = client.AdminAPI().ListCordons(); err != nil {
+				return
+			}
+			stdout("%v\n", cordonTableHeader)
+			for _, cordon := range cordons {
+				stdout("%v\n", formatCordonTableRow(cordon))
+			}
+		},
+	}
+	return cmd
+}
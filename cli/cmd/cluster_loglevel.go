@@ -0,0 +1,184 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterLogLevelUse   = "loglevel [COMMAND]"
+	cmdClusterLogLevelShort = "Manage the log level of a running node"
+
+	cmdClusterLogLevelSetShort = "Change the log level of a node, or of a module of the node"
+	cmdClusterLogLevelGetShort = "Show the log level and the module levels of a node"
+)
+
+// defaultProfPorts is the default prof port of the roles, which is used if --port is not given.
+var defaultProfPorts = map[string]string{
+	"master":   "17020",
+	"metanode": defaultMetaNodeProfPort,
+	"datanode": defaultDataNodeProfPort,
+	"authnode": "10088",
+	"client":   "27510",
+}
+
+func newClusterLogLevelCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdClusterLogLevelUse,
+		Short: cmdClusterLogLevelShort,
+	}
+	cmd.AddCommand(
+		newClusterLogLevelSetCmd(),
+		newClusterLogLevelGetCmd(),
+	)
+	return cmd
+}
+
+func validProfRoles() []string {
+	roles := make([]string, 0, len(defaultProfPorts))
+	for role := range defaultProfPorts {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// logLevelAddr returns the prof address of the node, the port is the default prof port of the role if not given.
+func logLevelAddr(role, addr, port string) (string, error) {
+	if port == "" {
+		var ok bool
+		if port, ok = defaultProfPorts[strings.ToLower(role)]; !ok {
+			return "", fmt.Errorf("no default prof port of role %v, specify it by --port", role)
+		}
+	}
+	return profAddr(addr, port), nil
+}
+
+func newClusterLogLevelSetCmd() *cobra.Command {
+	var optModule, optPort string
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [ROLE] [ADDR] [LEVEL]",
+		Short: cmdClusterLogLevelSetShort,
+		Long: `Change the log level of a running node through its prof port without restarting it. ROLE is
+one of master, metanode, datanode, authnode and client, ADDR is the address of the node and LEVEL is
+one of debug, info, warn, error and critical. With --module only the lines logged by the module are
+changed, e.g. "metanode" or "sdk/data/stream", and the level "default" makes the module follow the
+level of the node again. The change is lost after the node restarts.`,
+		Args: cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var addr, msg string
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if addr, err = logLevelAddr(args[0], args[1], optPort); err != nil {
+				return
+			}
+			values := url.Values{"level": {args[2]}}
+			if optModule != "" {
+				values.Set("module", optModule)
+			}
+			if err = requestLogLevel(fmt.Sprintf("http://%v%v?%v", addr, log.SetLogLevelPath, values.Encode()), &msg); err != nil {
+				return
+			}
+			stdout("%v %v: %v\n", args[0], args[1], msg)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return validProfRoles(), cobra.ShellCompDirectiveNoFileComp
+			case 2:
+				return []string{"debug", "info", "warn", "error", "critical", log.LevelDefault}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optModule, CliFlagModule, "", "Change the level of the module only, e.g. metanode")
+	cmd.Flags().StringVar(&optPort, CliFlagPort, "", "Specify the prof port of the node, the default port of the role if empty")
+	return cmd
+}
+
+func newClusterLogLevelGetCmd() *cobra.Command {
+	var optPort string
+	var cmd = &cobra.Command{
+		Use:   CliOpGet + " [ROLE] [ADDR]",
+		Short: cmdClusterLogLevelGetShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var addr string
+			var levels log.LogLevels
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if addr, err = logLevelAddr(args[0], args[1], optPort); err != nil {
+				return
+			}
+			if err = requestLogLevel(fmt.Sprintf("http://%v%v", addr, log.GetLogLevelPath), &levels); err != nil {
+				return
+			}
+			stdout("  Level    : %v\n", levels.Level)
+			modules := make([]string, 0, len(levels.Modules))
+			for module := range levels.Modules {
+				modules = append(modules, module)
+			}
+			sort.Strings(modules)
+			for _, module := range modules {
+				stdout("  Module   : %v %v\n", module, levels.Modules[module])
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return validProfRoles(), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optPort, CliFlagPort, "", "Specify the prof port of the node, the default port of the role if empty")
+	return cmd
+}
+
+func requestLogLevel(reqURL string, data interface{}) (err error) {
+	var resp *http.Response
+	if resp, err = http.Get(reqURL); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32       `json:"code"`
+		Msg  string      `json:"msg"`
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return fmt.Errorf("decode log level: %v", err)
+	}
+	if body.Code != http.StatusOK {
+		return fmt.Errorf("request log level: %v", body.Msg)
+	}
+	return
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterSetShort = "Set the rebuild policy of the missing replicas of the cluster or a zone"
+)
+
+func newClusterSetCmd(client *sdk.MasterClient) *cobra.Command {
+	var (
+		optZone             string
+		optRebuildDelay     time.Duration
+		optRebuildBandwidth uint64
+		optReset            bool
+	)
+	var cmd = &cobra.Command{
+		Use:   CliOpSet,
+		Short: cmdClusterSetShort,
+		Long: `Set how the master rebuilds the replicas of the data partitions missing from the data nodes.
+A replica missing longer than the rebuild delay is rebuilt on another data node, so that a short
+network blip or a restart does not copy the whole data of a node. A zero delay turns the rebuild
+off and the missing replicas are only reported. The rebuild bandwidth caps the repair traffic
+of each data node in MB/s, zero means unlimited.
+
+The policy applies to the whole cluster unless --zonename is given, and the policy of a zone overrides
+the one of the cluster. --reset removes the policy of the zone, or the one of the cluster.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optReset {
+				if err = client.AdminAPI().RemoveRebuildPolicy(optZone); err != nil {
+					return
+				}
+				stdout("Rebuild policy of %v is removed.\n", formatRebuildPolicyZone(optZone))
+				return
+			}
+			if !cmd.Flags().Changed(CliFlagRebuildDelay) && !cmd.Flags().Changed(CliFlagRebuildBandwidth) {
+				err = fmt.Errorf("specify --%v or --%v", CliFlagRebuildDelay, CliFlagRebuildBandwidth)
+				return
+			}
+			if optRebuildDelay < 0 {
+				err = fmt.Errorf("negative rebuild delay: %v", optRebuildDelay)
+				return
+			}
+			var policy *proto.RebuildPolicy
+			if policy, err = currentRebuildPolicy(client, optZone); err != nil {
+				return
+			}
+			if cmd.Flags().Changed(CliFlagRebuildDelay) {
+				policy.DelaySec = int64(optRebuildDelay / time.Second)
+			}
+			if cmd.Flags().Changed(CliFlagRebuildBandwidth) {
+				policy.MaxBandwidth = optRebuildBandwidth
+			}
+			if err = client.AdminAPI().SetRebuildPolicy(policy); err != nil {
+				return
+			}
+			stdout("Rebuild policy of %v is set to delay %v and bandwidth %v.\n", formatRebuildPolicyZone(optZone),
+				formatRebuildDelay(policy.DelaySec), formatQosLimit(policy.MaxBandwidth, "MB/s"))
+		},
+	}
+	cmd.Flags().StringVar(&optZone, CliFlagZoneName, "", "Specify the zone, the policy applies to the whole cluster if empty")
+	cmd.Flags().DurationVar(&optRebuildDelay, CliFlagRebuildDelay, 0, "Rebuild the replicas missing longer than the delay, 0 turns the rebuild off")
+	cmd.Flags().Uint64Var(&optRebuildBandwidth, CliFlagRebuildBandwidth, 0, "Specify the repair bandwidth of each data node in MB/s, 0 means unlimited")
+	cmd.Flags().BoolVar(&optReset, CliFlagReset, false, "Remove the rebuild policy of the zone or the cluster")
+	return cmd
+}
+
+// currentRebuildPolicy returns the policy of the zone to be updated, a zone without a policy starts
+// from the one of the cluster, which it overrides as a whole.
+func currentRebuildPolicy(client *sdk.MasterClient, zone string) (policy *proto.RebuildPolicy, err error) {
+	var policies []*proto.RebuildPolicy
+	if policies, err = client.AdminAPI().ListRebuildPolicies(); err != nil {
+		return
+	}
+	policy = &proto.RebuildPolicy{Zone: zone}
+	for _, p := range policies {
+		if p.Zone == zone {
+			return p, nil
+		}
+		if p.Zone == "" {
+			policy.DelaySec, policy.MaxBandwidth = p.DelaySec, p.MaxBandwidth
+		}
+	}
+	return
+}
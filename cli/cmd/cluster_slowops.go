@@ -0,0 +1,221 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterSlowOpsUse   = "slowops"
+	cmdClusterSlowOpsShort = "Aggregate the slow operations of the data nodes and the meta nodes"
+
+	slowOpsGroupByNode      = "node"
+	slowOpsGroupByDisk      = "disk"
+	slowOpsGroupByPartition = "partition"
+	slowOpsGroupByOp        = "op"
+
+	dataNodeSlowOpsPath = "/slowOps"
+	metaNodeSlowOpsPath = "/getSlowOps"
+	slowOpsConcurrency  = 16
+)
+
+// slowOpGroup is the statistics of the slow operations aggregated by a key.
+type slowOpGroup struct {
+	Key      string
+	Count    int
+	TotalUs  int64
+	MaxUs    int64
+	LastTime int64
+	LastOp   string
+}
+
+func (g *slowOpGroup) add(op *proto.SlowOp) {
+	g.Count++
+	g.TotalUs += op.LatencyUs
+	if op.LatencyUs > g.MaxUs {
+		g.MaxUs = op.LatencyUs
+	}
+	if op.Time >= g.LastTime {
+		g.LastTime = op.Time
+		g.LastOp = op.Op
+	}
+}
+
+// nodeSlowOps is the slow operations of a node, the role is "data" or "meta".
+type nodeSlowOps struct {
+	role string
+	addr string
+	ops  []*proto.SlowOp
+	err  error
+}
+
+func newClusterSlowOpsCmd(client *sdk.MasterClient) *cobra.Command {
+	var (
+		optSince    string
+		optGroupBy  string
+		optLimit    int
+		optMetaPort string
+		optDataPort string
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdClusterSlowOpsUse,
+		Short: cmdClusterSlowOpsShort,
+		Long: `Collect the latest slow operations recorded by all the data nodes and meta nodes, and
+aggregate them by node, disk, partition or operation, the groups with the most slow operations
+are listed first. An operation is slow if it takes longer than the threshold of the node, which
+is tuned at runtime by the "slowOpThresholdMs" parameter of "datanode config" and "metanode config".
+Each node keeps the latest 1024 slow operations. --since accepts an RFC3339 time or a duration
+before now, e.g. "1h".`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var since int64
+			var cv *proto.ClusterView
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			switch optGroupBy {
+			case slowOpsGroupByNode, slowOpsGroupByDisk, slowOpsGroupByPartition, slowOpsGroupByOp:
+			default:
+				err = fmt.Errorf("invalid group: %v", optGroupBy)
+				return
+			}
+			if since, err = parseAuditTime(optSince); err != nil {
+				return
+			}
+			if cv, err = client.AdminAPI().GetCluster(); err != nil {
+				return
+			}
+			results := collectSlowOps(cv, optDataPort, optMetaPort, since)
+			groups := aggregateSlowOps(results, optGroupBy)
+			if optLimit > 0 && len(groups) > optLimit {
+				groups = groups[:optLimit]
+			}
+			stdout("%v\n", slowOpGroupTableHeader)
+			for _, group := range groups {
+				stdout("%v\n", formatSlowOpGroupTableRow(group))
+			}
+			for _, result := range results {
+				if result.err != nil {
+					stdout("Failed to collect the slow operations of %v node %v: %v\n", result.role, result.addr, result.err)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optSince, CliFlagSince, "1h", "Aggregate the slow operations since the time")
+	cmd.Flags().StringVar(&optGroupBy, CliFlagGroupBy, slowOpsGroupByDisk, "Aggregate by node, disk, partition or op")
+	cmd.Flags().IntVar(&optLimit, CliFlagLimit, 20, "Maximum number of the groups to list, 0 for all")
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta nodes")
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	return cmd
+}
+
+// collectSlowOps requests the slow operations of all the nodes concurrently, the nodes failed to
+// respond are reported with the error.
+func collectSlowOps(cv *proto.ClusterView, dataPort, metaPort string, since int64) (results []*nodeSlowOps) {
+	for _, node := range cv.DataNodes {
+		results = append(results, &nodeSlowOps{role: "data", addr: node.Addr})
+	}
+	for _, node := range cv.MetaNodes {
+		results = append(results, &nodeSlowOps{role: "meta", addr: node.Addr})
+	}
+	var wg sync.WaitGroup
+	var limit = make(chan struct{}, slowOpsConcurrency)
+	for _, result := range results {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(result *nodeSlowOps) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			if result.role == "data" {
+				result.ops, result.err = requestNodeSlowOps(result.addr, dataPort, dataNodeSlowOpsPath, since)
+			} else {
+				result.ops, result.err = requestNodeSlowOps(result.addr, metaPort, metaNodeSlowOpsPath, since)
+			}
+		}(result)
+	}
+	wg.Wait()
+	return
+}
+
+func requestNodeSlowOps(nodeAddr, port, path string, since int64) (ops []*proto.SlowOp, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v%v?since=%v", profAddr(nodeAddr, port), path, since)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32           `json:"code"`
+		Msg  string          `json:"msg"`
+		Data []*proto.SlowOp `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode slow ops: %v", err)
+	}
+	if body.Code != http.StatusOK {
+		return nil, fmt.Errorf("request slow ops: %v", body.Msg)
+	}
+	return body.Data, nil
+}
+
+// aggregateSlowOps groups the slow operations, ordered by the count and then the max latency.
+func aggregateSlowOps(results []*nodeSlowOps, groupBy string) (groups []*slowOpGroup) {
+	var byKey = make(map[string]*slowOpGroup)
+	for _, result := range results {
+		for _, op := range result.ops {
+			var key string
+			switch groupBy {
+			case slowOpsGroupByNode:
+				key = fmt.Sprintf("%v %v", result.role, result.addr)
+			case slowOpsGroupByDisk:
+				disk := op.Disk
+				if disk == "" {
+					disk = "-"
+				}
+				key = fmt.Sprintf("%v %v %v", result.role, result.addr, disk)
+			case slowOpsGroupByPartition:
+				key = fmt.Sprintf("%v %v", result.role, op.PartitionID)
+			case slowOpsGroupByOp:
+				key = op.Op
+			}
+			group, ok := byKey[key]
+			if !ok {
+				group = &slowOpGroup{Key: key}
+				byKey[key] = group
+				groups = append(groups, group)
+			}
+			group.add(op)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].MaxUs > groups[j].MaxUs
+	})
+	return
+}
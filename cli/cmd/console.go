@@ -0,0 +1,442 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdConsoleUse   = "console"
+	cmdConsoleShort = "Run the commands interactively with the history, the completion and a persistent context"
+
+	consoleKeyMaster = "master"
+)
+
+var (
+	defaultConsoleHistoryPath = path.Join(defaultHomeDir, ".cfs-cli_history")
+	defaultConsoleContextPath = path.Join(defaultHomeDir, ".cfs-cli-context.json")
+
+	consoleVarPattern = regexp.MustCompile(`\$(\w+)`)
+	consoleVarName    = regexp.MustCompile(`^\w+$`)
+
+	// consoleRunning is set while the console runs, so that a failed command returns to the console instead of exiting.
+	consoleRunning bool
+)
+
+// consoleAbort is the panic of a failed command in the console, which is recovered by the console.
+type consoleAbort struct{}
+
+// consoleContext is kept across the commands and the sessions of the console. The variables are
+// referred to by $NAME in the commands, and the master addresses override the ones of the config.
+type consoleContext struct {
+	MasterAddr []string          `json:"masterAddr,omitempty"`
+	Vars       map[string]string `json:"vars"`
+}
+
+// consoleVars are the context variables completed by the console, the other names are accepted as well.
+var consoleVars = map[string]func(client *sdk.MasterClient, toComplete string) []string{
+	consoleKeyMaster: nil,
+	"vol": func(client *sdk.MasterClient, toComplete string) []string {
+		return validVols(client, toComplete)
+	},
+	"datanode": validDataNodes,
+	"metanode": validMetaNodes,
+	"user":     validUsers,
+	"zone":     validZones,
+}
+
+// consoleBuiltins are the commands of the console itself, which are not the commands of the cli.
+var consoleBuiltins = [][2]string{
+	{"use [NAME] [VALUE]", "Set a context variable referred to by $NAME, clear it without VALUE, or list the context without NAME"},
+	{"use master [ADDR,...]", "Switch to the masters of another cluster, or back to the masters of the config without ADDR"},
+	{"history", "Show the history of the commands"},
+	{"exit", "Exit the console, the same as quit and Ctrl-D"},
+}
+
+type console struct {
+	config *Config
+	client *sdk.MasterClient
+	ctx    *consoleContext
+	editor *lineEditor
+	record bool
+}
+
+func newConsoleCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdConsoleUse,
+		Short: cmdConsoleShort,
+		Long: `Run the commands of the cli interactively, e.g. "vol info ltptest", without typing the name
+of the cli. The commands, the volumes, the nodes, the users and the zones are completed by the Tab key,
+the arrow keys browse the history of the commands, which is kept in ~/.cfs-cli_history. The context
+variables set by "use NAME VALUE" are referred to by $NAME in the commands, e.g. "use vol ltptest"
+and then "vol info $vol", they are kept in ~/.cfs-cli-context.json across the sessions. "use master"
+switches the console to another cluster. A failed command returns to the console. The commands are
+read from the standard input line by line if it is not a terminal.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if consoleRunning {
+				err = fmt.Errorf("already in the console")
+				return
+			}
+			var c *console
+			if c, err = newConsole(); err != nil {
+				return
+			}
+			consoleRunning = true
+			defer func() {
+				consoleRunning = false
+			}()
+			c.run()
+		},
+	}
+	return cmd
+}
+
+func newConsole() (c *console, err error) {
+	c = &console{ctx: &consoleContext{Vars: make(map[string]string)}}
+	if c.config, err = LoadConfig(); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = ioutil.ReadFile(defaultConsoleContextPath); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, c.ctx); err != nil {
+			return nil, fmt.Errorf("load console context %v: %v", defaultConsoleContextPath, err)
+		}
+		if c.ctx.Vars == nil {
+			c.ctx.Vars = make(map[string]string)
+		}
+	}
+	c.connect()
+	c.editor = newLineEditor(os.Stdin, os.Stdout, c.complete)
+	// the history is only kept for the commands typed on a terminal
+	if c.record = c.editor.terminal; c.record {
+		if data, err = ioutil.ReadFile(defaultConsoleHistoryPath); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			c.editor.addHistory(line)
+		}
+	}
+	return c, nil
+}
+
+// connect creates the master client of the context, or of the config if the context has no masters.
+func (c *console) connect() {
+	masters := c.config.MasterAddr
+	if len(c.ctx.MasterAddr) > 0 {
+		masters = c.ctx.MasterAddr
+	}
+	c.client = sdk.NewMasterClient(masters, false)
+	c.client.SetTimeout(c.config.Timeout)
+}
+
+func (c *console) prompt() string {
+	var items []string
+	if len(c.ctx.MasterAddr) > 0 {
+		items = append(items, consoleKeyMaster+"="+strings.Join(c.ctx.MasterAddr, ","))
+	}
+	for _, name := range c.varNames() {
+		items = append(items, name+"="+c.ctx.Vars[name])
+	}
+	if len(items) == 0 {
+		return "cfs-cli> "
+	}
+	return fmt.Sprintf("cfs-cli [%v]> ", strings.Join(items, " "))
+}
+
+func (c *console) varNames() []string {
+	names := make([]string, 0, len(c.ctx.Vars))
+	for name := range c.ctx.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *console) run() {
+	if c.editor.terminal {
+		stdout("Console of the cluster %v, input 'help' for the commands and 'exit' to quit.\n", strings.Join(c.client.Nodes(), ","))
+	}
+	for {
+		line, err := c.editor.readLine(c.prompt())
+		if err == errLineInterrupted {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				stdout("Error: %v\n", err)
+			}
+			return
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if c.editor.addHistory(line) && c.record {
+			c.appendHistory(line)
+		}
+		if !c.execute(line) {
+			return
+		}
+	}
+}
+
+func (c *console) appendHistory(line string) {
+	file, err := os.OpenFile(defaultConsoleHistoryPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	_, _ = file.WriteString(line + "\n")
+	_ = file.Close()
+}
+
+// execute runs the line, and returns false if the console should exit.
+func (c *console) execute(line string) bool {
+	words, err := splitConsoleLine(line)
+	if err == nil {
+		words, err = c.expand(words)
+	}
+	if err != nil {
+		stdout("Error: %v\n", err)
+		return true
+	}
+	switch words[0] {
+	case "exit", "quit":
+		return false
+	case "history":
+		for i, h := range c.editor.history {
+			stdout("%5d  %v\n", i+1, h)
+		}
+		return true
+	case "use":
+		if err = c.use(words[1:]); err != nil {
+			stdout("Error: %v\n", err)
+		}
+		return true
+	}
+	c.runCommand(words, os.Stdout, os.Stderr)
+	if len(words) == 1 && words[0] == "help" {
+		stdout("\nConsole Commands:\n")
+		for _, builtin := range consoleBuiltins {
+			stdout("  %-24v%v\n", builtin[0], builtin[1])
+		}
+	}
+	return true
+}
+
+// runCommand runs a command of the cli, the command tree is created for every command so that the
+// flags of the last command are not kept.
+func (c *console) runCommand(args []string, out, errOut io.Writer) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(consoleAbort); !ok {
+				panic(r)
+			}
+		}
+	}()
+	root := NewRootCmd(c.client).CFSCmd
+	root.SetArgs(args)
+	root.SetOut(out)
+	root.SetErr(errOut)
+	_ = root.Execute()
+}
+
+// expand replaces $NAME in the words by the context variables.
+func (c *console) expand(words []string) (expanded []string, err error) {
+	for _, word := range words {
+		word = consoleVarPattern.ReplaceAllStringFunc(word, func(ref string) string {
+			value, ok := c.ctx.Vars[ref[1:]]
+			if !ok && err == nil {
+				err = fmt.Errorf("unknown context variable %v, set it by 'use %v VALUE'", ref, ref[1:])
+			}
+			return value
+		})
+		expanded = append(expanded, word)
+	}
+	return
+}
+
+func (c *console) use(args []string) (err error) {
+	if len(args) == 0 {
+		masters := strings.Join(c.client.Nodes(), ",")
+		if len(c.ctx.MasterAddr) == 0 {
+			masters += " (config)"
+		}
+		stdout("  %-10v: %v\n", consoleKeyMaster, masters)
+		for _, name := range c.varNames() {
+			stdout("  %-10v: %v\n", name, c.ctx.Vars[name])
+		}
+		return
+	}
+	name := args[0]
+	if !consoleVarName.MatchString(name) {
+		return fmt.Errorf("invalid context variable name: %v", name)
+	}
+	switch {
+	case name == consoleKeyMaster && len(args) > 1:
+		c.ctx.MasterAddr = strings.Split(args[1], ",")
+		c.connect()
+	case name == consoleKeyMaster:
+		c.ctx.MasterAddr = nil
+		c.connect()
+	case len(args) > 1:
+		c.ctx.Vars[name] = args[1]
+	default:
+		delete(c.ctx.Vars, name)
+	}
+	var data []byte
+	if data, err = json.MarshalIndent(c.ctx, "", "  "); err != nil {
+		return
+	}
+	return ioutil.WriteFile(defaultConsoleContextPath, data, 0600)
+}
+
+// complete returns the candidates of the last word of the line, which are the console commands and
+// the context variables, or the completions of the cli command.
+func (c *console) complete(line string) (wordStart int, candidates []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(consoleAbort); !ok {
+				panic(r)
+			}
+			candidates = nil
+		}
+	}()
+	args := strings.Fields(line)
+	toComplete := ""
+	if len(args) > 0 && !strings.HasSuffix(line, " ") {
+		toComplete, args = args[len(args)-1], args[:len(args)-1]
+	}
+	wordStart = len(line) - len(toComplete)
+	if strings.HasPrefix(toComplete, "$") {
+		for _, name := range c.varNames() {
+			candidates = append(candidates, "$"+name)
+		}
+		return wordStart, filterPrefix(candidates, toComplete)
+	}
+	var err error
+	if args, err = c.expand(args); err != nil {
+		return
+	}
+	if len(args) > 0 && args[0] == "use" {
+		switch len(args) {
+		case 1:
+			for name := range consoleVars {
+				candidates = append(candidates, name)
+			}
+			for name := range c.ctx.Vars {
+				if _, ok := consoleVars[name]; !ok {
+					candidates = append(candidates, name)
+				}
+			}
+			sort.Strings(candidates)
+		case 2:
+			if valid := consoleVars[args[1]]; valid != nil {
+				candidates = valid(c.client, toComplete)
+			}
+		}
+		return wordStart, filterPrefix(candidates, toComplete)
+	}
+	if len(args) == 0 {
+		candidates = append(candidates, "exit", "history", "quit", "use")
+	}
+	var buf bytes.Buffer
+	c.runCommand(append(append([]string{cobra.ShellCompNoDescRequestCmd}, args...), toComplete), &buf, ioutil.Discard)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// the last line is the directive of the completion
+	directive, _ := strconv.Atoi(strings.TrimPrefix(lines[len(lines)-1], ":"))
+	if cobra.ShellCompDirective(directive)&cobra.ShellCompDirectiveError != 0 {
+		return wordStart, nil
+	}
+	for _, candidate := range lines[:len(lines)-1] {
+		if candidate != "" && candidate != cobra.ShellCompRequestCmd && candidate != cobra.ShellCompNoDescRequestCmd {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Strings(candidates)
+	return wordStart, filterPrefix(candidates, toComplete)
+}
+
+func filterPrefix(candidates []string, prefix string) (filtered []string) {
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return
+}
+
+// splitConsoleLine splits the line into the words separated by the spaces, the spaces in the single
+// or the double quotes and the ones escaped by a backslash are kept in the words.
+func splitConsoleLine(line string) (words []string, err error) {
+	var (
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape: %v", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return
+}
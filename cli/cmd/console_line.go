@@ -0,0 +1,386 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+
+	maxConsoleHistory = 1000
+)
+
+// the keys decoded from the escape sequences
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// errLineInterrupted is returned by readLine if the line is dropped by Ctrl-C.
+var errLineInterrupted = errors.New("interrupted")
+
+// lineCompleter returns the candidates of the word before the cursor, the word starts at the given offset of the line.
+type lineCompleter func(line string) (wordStart int, candidates []string)
+
+// lineEditor reads the lines of the console. On a terminal the line is edited in the raw mode with the
+// history and the tab completion, otherwise the plain lines are read without any prompt, so that the
+// commands can be piped into the console.
+type lineEditor struct {
+	in       *os.File
+	out      io.Writer
+	terminal bool
+	history  []string
+	complete lineCompleter
+
+	// the state of the line being edited
+	prompt  string
+	buf     []rune
+	pos     int
+	histIdx int
+	saved   []rune // the line being edited before browsing the history
+}
+
+func newLineEditor(in *os.File, out io.Writer, complete lineCompleter) *lineEditor {
+	return &lineEditor{
+		in:       in,
+		out:      out,
+		terminal: isTerminal(int(in.Fd())),
+		complete: complete,
+	}
+}
+
+// addHistory appends the line to the history, the line same as the last one is skipped.
+func (e *lineEditor) addHistory(line string) bool {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return false
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxConsoleHistory {
+		e.history = e.history[len(e.history)-maxConsoleHistory:]
+	}
+	return true
+}
+
+// readByte reads a byte without buffering, so that the input after the line is left to the commands
+// which read the standard input themselves, such as the confirmations.
+func (e *lineEditor) readByte() (b byte, err error) {
+	var p [1]byte
+	for {
+		var n int
+		if n, err = e.in.Read(p[:]); n == 1 {
+			return p[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (e *lineEditor) readLine(prompt string) (line string, err error) {
+	if !e.terminal {
+		return e.readPlainLine()
+	}
+	var restore func()
+	if restore, err = makeRaw(int(e.in.Fd())); err != nil {
+		// fall back to the plain lines if the raw mode is not available
+		e.terminal = false
+		fmt.Fprint(e.out, prompt)
+		return e.readPlainLine()
+	}
+	defer restore()
+	e.prompt, e.buf, e.pos, e.histIdx, e.saved = prompt, nil, 0, len(e.history), nil
+	e.refresh()
+	for {
+		var key rune
+		if key, err = e.readKey(); err != nil {
+			return
+		}
+		switch key {
+		case keyCR, keyLF:
+			fmt.Fprint(e.out, "\n")
+			return string(e.buf), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", errLineInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case keyBackspace, 8:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyDelete:
+			e.deleteAt(e.pos)
+		case keyLeft, keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, keyCtrlF:
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.buf)
+		case keyUp, keyCtrlP:
+			e.browseHistory(-1)
+		case keyDown, keyCtrlN:
+			e.browseHistory(1)
+		case keyCtrlU:
+			e.buf, e.pos = append([]rune{}, e.buf[e.pos:]...), 0
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlW:
+			start := e.pos
+			for start > 0 && e.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.buf[start-1] != ' ' {
+				start--
+			}
+			e.buf, e.pos = append(e.buf[:start], e.buf[e.pos:]...), start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyTab:
+			e.completeWord()
+		case keyUnknown:
+		default:
+			if key >= 32 {
+				e.buf = append(e.buf, 0)
+				copy(e.buf[e.pos+1:], e.buf[e.pos:])
+				e.buf[e.pos] = key
+				e.pos++
+			}
+		}
+		e.refresh()
+	}
+}
+
+func (e *lineEditor) readPlainLine() (line string, err error) {
+	var b []byte
+	for {
+		var c byte
+		if c, err = e.readByte(); err != nil {
+			if err == io.EOF && len(b) > 0 {
+				return string(b), nil
+			}
+			return
+		}
+		if c == '\n' {
+			return strings.TrimSuffix(string(b), "\r"), nil
+		}
+		b = append(b, c)
+	}
+}
+
+// readKey reads a key, which is a rune or one of the keys decoded from the escape sequences.
+func (e *lineEditor) readKey() (key rune, err error) {
+	var c byte
+	if c, err = e.readByte(); err != nil {
+		return
+	}
+	if c == keyEscape {
+		return e.readEscape()
+	}
+	if c < utf8.RuneSelf {
+		return rune(c), nil
+	}
+	p := []byte{c}
+	for !utf8.FullRune(p) {
+		if c, err = e.readByte(); err != nil {
+			return
+		}
+		p = append(p, c)
+	}
+	key, _ = utf8.DecodeRune(p)
+	return
+}
+
+// readEscape decodes the escape sequences of the arrows, home, end and delete.
+func (e *lineEditor) readEscape() (key rune, err error) {
+	var c byte
+	if c, err = e.readByte(); err != nil {
+		return
+	}
+	if c != '[' && c != 'O' {
+		return keyUnknown, nil
+	}
+	var param []byte
+	for {
+		if c, err = e.readByte(); err != nil {
+			return
+		}
+		if (c < '0' || c > '9') && c != ';' {
+			break
+		}
+		param = append(param, c)
+	}
+	switch c {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch string(param) {
+		case "1", "7":
+			return keyHome, nil
+		case "4", "8":
+			return keyEnd, nil
+		case "3":
+			return keyDelete, nil
+		}
+	}
+	return keyUnknown, nil
+}
+
+func (e *lineEditor) deleteAt(pos int) {
+	if pos < len(e.buf) {
+		e.buf = append(e.buf[:pos], e.buf[pos+1:]...)
+	}
+}
+
+// browseHistory moves to the previous or the next line of the history, the line being edited is kept
+// and restored after the last line of the history.
+func (e *lineEditor) browseHistory(step int) {
+	idx := e.histIdx + step
+	if idx < 0 || idx > len(e.history) {
+		return
+	}
+	if e.histIdx == len(e.history) {
+		e.saved = append([]rune{}, e.buf...)
+	}
+	e.histIdx = idx
+	if idx == len(e.history) {
+		e.buf = append([]rune{}, e.saved...)
+	} else {
+		e.buf = []rune(e.history[idx])
+	}
+	e.pos = len(e.buf)
+}
+
+// completeWord completes the word before the cursor. A single candidate replaces the word, otherwise
+// the word is extended to the common prefix of the candidates, or the candidates are listed.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	head := string(e.buf[:e.pos])
+	start, candidates := e.complete(head)
+	if len(candidates) == 0 {
+		return
+	}
+	word := head[start:]
+	var replacement string
+	if len(candidates) == 1 {
+		replacement = candidates[0]
+		if !strings.HasSuffix(replacement, "=") {
+			replacement += " "
+		}
+	} else {
+		replacement = commonPrefix(candidates)
+		if replacement == word {
+			fmt.Fprint(e.out, "\n"+formatCandidates(candidates)+"\n")
+			return
+		}
+	}
+	tail := e.buf[e.pos:]
+	e.buf = append([]rune(head[:start]+replacement), tail...)
+	e.pos = len(e.buf) - len(tail)
+}
+
+func commonPrefix(items []string) string {
+	prefix := items[0]
+	for _, item := range items[1:] {
+		for !strings.HasPrefix(item, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// formatCandidates lists the candidates in columns of the width of the longest one.
+func formatCandidates(candidates []string) string {
+	width := 0
+	for _, c := range candidates {
+		if len(c) > width {
+			width = len(c)
+		}
+	}
+	width += 2
+	columns := 80 / width
+	if columns < 1 {
+		columns = 1
+	}
+	var b strings.Builder
+	for i, c := range candidates {
+		if i > 0 && i%columns == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("%-*s", width, c))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// refresh redraws the line and moves the cursor to its position.
+func (e *lineEditor) refresh() {
+	s := "\r" + e.prompt + string(e.buf) + "\x1b[K"
+	if back := len(e.buf) - e.pos; back > 0 {
+		s += fmt.Sprintf("\x1b[%dD", back)
+	}
+	fmt.Fprint(e.out, s)
+}
@@ -36,6 +36,11 @@ const (
 	CliOpDelReplica        = "del-replica"
 	CliOpExpand              = "expand"
 	CliOpShrink              = "shrink"
+	CliOpCancel              = "cancel"
+	CliOpPause               = "pause"
+	CliOpResume              = "resume"
+	CliOpOverride            = "override"
+	CliOpShow                = "show"
 
 	//Shorthand format of operation name
 	CliOpDecommissionShortHand = "dec"
@@ -73,6 +78,50 @@ const (
 	CliFlagDelBatchCount      = "delete-batch-count"
 	CliFlagDelWorkerSleepMs   = "delete-worker-sleep-ms"
 	CliFlagMarkDelRate        = "mark-delete-rate"
+	CliFlagInactiveDays       = "inactive-days"
+	CliFlagMaxIOPS            = "max-iops"
+	CliFlagMaxBandwidth       = "max-bandwidth"
+	CliFlagRetentionDays      = "retention-days"
+	CliFlagDuration           = "duration"
+	CliFlagMetaPort           = "meta-port"
+	CliFlagDataPort           = "data-port"
+	CliFlagRepair             = "repair"
+	CliFlagReclaim            = "reclaim"
+	CliFlagGracePeriod        = "grace-period"
+	CliFlagEffect             = "effect"
+	CliFlagVerifyRead         = "verify-read"
+	CliFlagMpReplicas         = "mp-replicas"
+	CliFlagExtentCount        = "extent-count"
+	CliFlagStatus             = "status"
+	CliFlagTrashDays          = "trash-days"
+	CliFlagZoneAntiAffinity   = "zone-anti-affinity"
+	CliFlagStrictZones        = "strict-zones"
+	CliFlagAtime              = "atime"
+	CliFlagFileAudit          = "file-audit"
+	CliFlagAll                = "all"
+	CliFlagReason             = "reason"
+	CliFlagUntil              = "until"
+	CliFlagClients            = "clients"
+	CliFlagReadOnly           = "read-only"
+	CliFlagRootSquash         = "root-squash"
+	CliFlagReadRate           = "read-rate"
+	CliFlagWriteRate          = "write-rate"
+	CliFlagIcacheTimeout      = "icache-timeout"
+	CliFlagIcacheSize         = "icache-size"
+	CliFlagLogLevel           = "log-level"
+	CliFlagRecursive          = "recursive"
+	CliFlagSince              = "since"
+	CliFlagOp                 = "op"
+	CliFlagTarget             = "target"
+	CliFlagLimit              = "limit"
+	CliFlagGroupBy            = "group-by"
+	CliFlagTimeout            = "timeout"
+	CliFlagWait               = "wait"
+	CliFlagRebuildDelay       = "rebuild-delay"
+	CliFlagRebuildBandwidth   = "rebuild-bandwidth"
+	CliFlagReset              = "reset"
+	CliFlagModule             = "module"
+	CliFlagPort               = "port"
 
 	//CliFlagSetDataPartitionCount	= "count" use dp-count instead
 
@@ -36,6 +36,14 @@ func newDataNodeCmd(client *master.MasterClient) *cobra.Command {
 		newDataNodeListCmd(client),
 		newDataNodeInfoCmd(client),
 		newDataNodeDecommissionCmd(client),
+		newDataNodeThrottleCmd(client),
+		newDataNodeOrphanScanCmd(client),
+		newDataNodeWarmUpCmd(client),
+		newDataNodeConfigCmd(client),
+		newDataNodeDiskRecoveryCmd(client),
+		newDataNodeDrainCmd(client),
+		newDataNodeDiskCmd(client),
+		newDataNodeMaintenanceCmd(client),
 	)
 	return cmd
 }
@@ -0,0 +1,178 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataNodeDiskUse          = "disk"
+	cmdDataNodeDiskShort        = "Manage the disks of a data node"
+	cmdDataNodeDiskListUse      = "list [NODE ADDRESS]"
+	cmdDataNodeDiskListShort    = "List the disks of a data node"
+	cmdDataNodeDiskOfflineUse   = "offline [NODE ADDRESS] [DISK PATH]"
+	cmdDataNodeDiskOfflineShort = "Take a disk of a data node offline"
+	cmdDataNodeDiskOnlineUse    = "online [NODE ADDRESS] [DISK PATH]"
+	cmdDataNodeDiskOnlineShort  = "Bring an offline disk of a data node back"
+
+	dataNodeDisksPath       = "/disks"
+	dataNodeDiskOfflinePath = "/diskOffline"
+	dataNodeDiskOnlinePath  = "/diskOnline"
+)
+
+func newDataNodeDiskCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeDiskUse,
+		Short: cmdDataNodeDiskShort,
+		Long: `Manage the disks of a data node. A data node takes a disk offline by itself once
+the io errors of the disk reach the maximum, which is set by diskMaxErrCnt in the config
+file of the data node. The partitions on an offline disk stop serving, and the master
+reports the disk among the bad disks of the data node. The data node collects the SMART
+attributes of the disks with smartctl as well, and the master warns of the disks predicted
+to fail, which are shown with the SMART state WARNING so that their partitions are migrated
+before the disks die.`,
+	}
+	cmd.AddCommand(
+		newDataNodeDiskListCmd(client),
+		newDataNodeDiskOfflineCmd(client),
+		newDataNodeDiskOnlineCmd(client),
+	)
+	return cmd
+}
+
+func newDataNodeDiskListCmd(client *master.MasterClient) *cobra.Command {
+	var optDataPort string
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeDiskListUse,
+		Short: cmdDataNodeDiskListShort,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			reqURL := fmt.Sprintf("http://%v%v", profAddr(args[0], optDataPort), dataNodeDisksPath)
+			report := &struct {
+				Disks []*proto.DiskInfo `json:"disks"`
+				Zone  string            `json:"zone"`
+			}{}
+			if err = requestDataNodeDisk(reqURL, report); err != nil {
+				return
+			}
+			// the master warns of the disks predicted to fail by the heartbeats of the data node
+			failing := make(map[string]bool)
+			if node, e := client.NodeAPI().GetDataNode(args[0]); e == nil {
+				for _, path := range node.FailingDisks {
+					failing[path] = true
+				}
+			}
+			stdout("%v\n", diskTableHeader)
+			for _, disk := range report.Disks {
+				stdout("%v\n", formatDiskTableRow(disk, failing[disk.Path]))
+			}
+			for _, disk := range report.Disks {
+				if disk.Smart != nil && disk.Smart.PredictedFailure {
+					stdout("Disk %v (%v) is predicted to fail: %v\n", disk.Path, disk.Smart.Device, disk.Smart.Reason)
+				}
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data node")
+	return cmd
+}
+
+func newDataNodeDiskOfflineCmd(client *master.MasterClient) *cobra.Command {
+	return newDataNodeDiskToggleCmd(client, cmdDataNodeDiskOfflineUse, cmdDataNodeDiskOfflineShort,
+		`Take a disk of a data node offline as if it reached the maximum of io errors. The
+partitions on the disk stop serving so that their replicas on the other nodes take over.`,
+		dataNodeDiskOfflinePath)
+}
+
+func newDataNodeDiskOnlineCmd(client *master.MasterClient) *cobra.Command {
+	return newDataNodeDiskToggleCmd(client, cmdDataNodeDiskOnlineUse, cmdDataNodeDiskOnlineShort,
+		`Bring an offline disk of a data node back and clear its io errors. The disk takes
+the new partitions again, while the partitions stopped with the disk stay unavailable
+until the data node restarts.`,
+		dataNodeDiskOnlinePath)
+}
+
+func newDataNodeDiskToggleCmd(client *master.MasterClient, use, short, long, path string) *cobra.Command {
+	var optDataPort string
+	var cmd = &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  long,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			reqURL := fmt.Sprintf("http://%v%v?disk=%v", profAddr(args[0], optDataPort), path, url.QueryEscape(args[1]))
+			disk := &proto.DiskInfo{}
+			if err = requestDataNodeDisk(reqURL, disk); err != nil {
+				return
+			}
+			stdout("%v\n", diskTableHeader)
+			stdout("%v\n", formatDiskTableRow(disk, false))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data node")
+	return cmd
+}
+
+func requestDataNodeDisk(reqURL string, data interface{}) (err error) {
+	var resp *http.Response
+	if resp, err = http.Get(reqURL); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32       `json:"code"`
+		Msg  string      `json:"msg"`
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return fmt.Errorf("decode disks: %v", err)
+	}
+	if body.Code != http.StatusOK {
+		return fmt.Errorf("request disks: %v", body.Msg)
+	}
+	return
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataNodeDiskRecoveryUse   = "disk-recovery [NODE ADDRESS]"
+	cmdDataNodeDiskRecoveryShort = "Show the progress of recovering the replicas lost with the replaced disks"
+)
+
+func newDataNodeDiskRecoveryCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeDiskRecoveryUse,
+		Short: cmdDataNodeDiskRecoveryShort,
+		Long: `Show the progress of recovering the replicas lost with the replaced disks.
+A data node finding its partitions missing on startup registers the disks which are
+mounted and hold no partition as replaced. The master then re-creates the lost replicas
+on the same data node, and they catch up the data from the other replicas. The
+recoveries of all the data nodes are shown if no address is given.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr string
+			var views []*proto.DiskRecoveryView
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if len(args) > 0 {
+				nodeAddr = args[0]
+			}
+			if views, err = client.NodeAPI().GetDiskRecoveries(nodeAddr); err != nil {
+				return
+			}
+			if len(views) == 0 {
+				stdout("No disk recovery.\n")
+				return
+			}
+			for _, view := range views {
+				printDiskRecovery(view)
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func printDiskRecovery(view *proto.DiskRecoveryView) {
+	stdout("[%v]\n", view.Addr)
+	stdout("  Disks      : %v\n", view.Disks)
+	stdout("  Start time : %v\n", formatTime(view.StartTime))
+	stdout("  Pending    : %v\n", len(view.Pending))
+	stdout("  Recovering : %v\n", len(view.Recovering))
+	stdout("  Recovered  : %v\n", len(view.Recovered))
+	if len(view.Failed) == 0 {
+		stdout("\n")
+		return
+	}
+	partitionIDs := make([]uint64, 0, len(view.Failed))
+	for id := range view.Failed {
+		partitionIDs = append(partitionIDs, id)
+	}
+	sort.Slice(partitionIDs, func(i, j int) bool { return partitionIDs[i] < partitionIDs[j] })
+	stdout("  Failed     :\n")
+	stdout("  %v\n", diskRecoveryFailureTableHeader)
+	for _, id := range partitionIDs {
+		stdout("  %v\n", formatDiskRecoveryFailureTableRow(id, view.Failed[id]))
+	}
+	stdout("\n")
+}
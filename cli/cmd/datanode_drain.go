@@ -0,0 +1,114 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataNodeDrainUse   = "drain [NODE ADDRESS]"
+	cmdDataNodeDrainShort = "Move the writes and raft leaders off a data node and shut it down"
+
+	dataNodeDrainPath       = "/drain"
+	dataNodeDrainStatusPath = "/drainStatus"
+	dataNodeDrainPoll       = 5 * time.Second
+)
+
+func newDataNodeDrainCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optDataPort string
+		optTimeout  time.Duration
+		optWait     bool
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeDrainUse,
+		Short: cmdDataNodeDrainShort,
+		Long: `Drain a data node before it is upgraded or restarted. The data node reports its
+partitions read-only so that the clients write to the other partitions, rejects the new
+writes, waits for the writes in flight, hands the raft leaders of its partitions over to the
+other replicas, and then exits. Sending SIGUSR1 to the data node drains it as well. The
+progress is shown if the data node is being drained. With --wait, the progress is shown
+until the data node exits.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var status *proto.DataNodeDrainStatus
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			nodeAddr := args[0]
+			reqURL := fmt.Sprintf("http://%v%v?timeout=%v", profAddr(nodeAddr, optDataPort), dataNodeDrainPath, int64(optTimeout/time.Second))
+			if status, err = requestDrainStatus(reqURL); err != nil {
+				return
+			}
+			stdout("%v\n", dataNodeDrainTableHeader)
+			stdout("%v\n", formatDataNodeDrainTableRow(nodeAddr, status))
+			if !optWait {
+				return
+			}
+			reqURL = fmt.Sprintf("http://%v%v", profAddr(nodeAddr, optDataPort), dataNodeDrainStatusPath)
+			for {
+				time.Sleep(dataNodeDrainPoll)
+				if status, err = requestDrainStatus(reqURL); err != nil {
+					// the data node exits once it is drained
+					stdout("Data node %v exited: %v\n", nodeAddr, err)
+					err = nil
+					return
+				}
+				stdout("%v\n", formatDataNodeDrainTableRow(nodeAddr, status))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data node")
+	cmd.Flags().DurationVar(&optTimeout, CliFlagTimeout, 0, "Shut down the data node after the timeout even if it is not drained, 0 for the default of the data node")
+	cmd.Flags().BoolVar(&optWait, CliFlagWait, false, "Show the progress until the data node exits")
+	return cmd
+}
+
+func requestDrainStatus(reqURL string) (status *proto.DataNodeDrainStatus, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(reqURL); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                      `json:"code"`
+		Msg  string                     `json:"msg"`
+		Data *proto.DataNodeDrainStatus `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode drain status: %v", err)
+	}
+	if body.Code != http.StatusOK {
+		return nil, fmt.Errorf("request drain: %v", body.Msg)
+	}
+	return body.Data, nil
+}
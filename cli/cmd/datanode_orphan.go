@@ -0,0 +1,191 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataNodeOrphanScanUse   = "orphan-scan [NODE ADDRESS]"
+	cmdDataNodeOrphanScanShort = "List and reclaim the extents on a data node not referenced by any inode"
+)
+
+func newDataNodeOrphanScanCmd(client *master.MasterClient) *cobra.Command {
+	var optMetaPort string
+	var optDataPort string
+	var optReclaim bool
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeOrphanScanUse,
+		Short: cmdDataNodeOrphanScanShort,
+		Long: `List the extents on a data node not referenced by any inode.
+The referenced extents are collected from all the meta partitions of the volumes
+before the data node is scanned. An extent becomes a candidate when it is not
+referenced and not modified during the grace period of the data node, and it is
+only reclaimed with --reclaim after it has stayed a candidate for the grace period.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr = args[0]
+			var results []*proto.OrphanExtentScanResponse
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optReclaim && !optYes {
+				stdout("Reclaim the orphaned extents of data node [%v] (yes/no)[no]:", nodeAddr)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			if results, err = scanDataNodeOrphans(client, nodeAddr, optMetaPort, optDataPort, optReclaim); err != nil {
+				return
+			}
+			printOrphanExtentScanResults(results)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta nodes")
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	cmd.Flags().BoolVar(&optReclaim, CliFlagReclaim, false, "Delete the extents orphaned for the grace period")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+// scanDataNodeOrphans collects the referenced extents of every partition on the data node, and asks
+// the data node to scan the partitions. Any error of the meta partitions aborts the scan, since the
+// extents referenced by an unreachable meta partition would be taken as orphaned.
+func scanDataNodeOrphans(client *master.MasterClient, nodeAddr, metaPort, dataPort string, reclaim bool) (results []*proto.OrphanExtentScanResponse, err error) {
+	var node *proto.DataNodeInfo
+	if node, err = client.NodeAPI().GetDataNode(nodeAddr); err != nil {
+		return
+	}
+	partitions := make(map[string][]uint64) // volume name to the partitions on the node
+	for _, id := range node.PersistenceDataPartitions {
+		var dp *proto.DataPartitionInfo
+		if dp, err = client.AdminAPI().GetDataPartition("", id); err != nil {
+			return
+		}
+		partitions[dp.VolName] = append(partitions[dp.VolName], id)
+	}
+	volumes := make([]string, 0, len(partitions))
+	for volumeName := range partitions {
+		volumes = append(volumes, volumeName)
+	}
+	sort.Strings(volumes)
+
+	for _, volumeName := range volumes {
+		var referenced map[uint64][]uint64
+		if referenced, err = getReferencedExtents(client, volumeName, metaPort); err != nil {
+			return
+		}
+		for _, id := range partitions[volumeName] {
+			var result *proto.OrphanExtentScanResponse
+			if result, err = requestOrphanExtentScan(nodeAddr, dataPort, &proto.OrphanExtentScanRequest{
+				PartitionID: id,
+				Referenced:  referenced[id],
+				Reclaim:     reclaim,
+			}); err != nil {
+				return
+			}
+			results = append(results, result)
+		}
+	}
+	return
+}
+
+// getReferencedExtents returns the extents referenced by the inodes of the volume, grouped by data partitions.
+func getReferencedExtents(client *master.MasterClient, volumeName, metaPort string) (referenced map[uint64][]uint64, err error) {
+	var views []*proto.MetaPartitionView
+	if views, err = client.ClientAPI().GetMetaPartitions(volumeName); err != nil {
+		return
+	}
+	inodes := make(map[uint64]*fsckInode)
+	for _, view := range views {
+		if err = getFsckInodes(view, metaPort, inodes); err != nil {
+			return
+		}
+	}
+	referenced = make(map[uint64][]uint64)
+	for _, inode := range inodes {
+		for _, ek := range inode.Extents {
+			referenced[ek.PartitionId] = append(referenced[ek.PartitionId], ek.ExtentId)
+		}
+	}
+	return
+}
+
+func requestOrphanExtentScan(nodeAddr, port string, req *proto.OrphanExtentScanRequest) (result *proto.OrphanExtentScanResponse, err error) {
+	var data []byte
+	if data, err = json.Marshal(req); err != nil {
+		return
+	}
+	var resp *http.Response
+	if resp, err = http.Post(fmt.Sprintf("http://%v/orphanScan", profAddr(nodeAddr, port)), "application/json", bytes.NewReader(data)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                           `json:"code"`
+		Msg  string                          `json:"msg"`
+		Data *proto.OrphanExtentScanResponse `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode orphan scan of data partition %v from %v: %v", req.PartitionID, nodeAddr, err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("scan orphans of data partition %v on %v: %v", req.PartitionID, nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
+
+func printOrphanExtentScanResults(results []*proto.OrphanExtentScanResponse) {
+	var candidates, reclaimed int
+	var reclaimedSize uint64
+	stdout("%v\n", orphanExtentTableHeader)
+	for _, result := range results {
+		for _, extent := range result.Candidates {
+			stdout("%v\n", formatOrphanExtentTableRow(result, extent, false))
+		}
+		for _, extent := range result.Reclaimed {
+			stdout("%v\n", formatOrphanExtentTableRow(result, extent, true))
+			reclaimedSize += extent.Size
+		}
+		candidates += len(result.Candidates)
+		reclaimed += len(result.Reclaimed)
+	}
+	stdout("\n")
+	stdout("Partitions scanned : %v\n", len(results))
+	stdout("Candidates         : %v\n", candidates)
+	stdout("Reclaimed          : %v (%v)\n", reclaimed, formatSize(reclaimedSize))
+}
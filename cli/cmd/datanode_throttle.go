@@ -0,0 +1,149 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataNodeThrottleUse   = "throttle [COMMAND]"
+	cmdDataNodeThrottleShort = "Throttle the IO of clients on a data node"
+)
+
+func newDataNodeThrottleCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeThrottleUse,
+		Short: cmdDataNodeThrottleShort,
+	}
+	cmd.AddCommand(
+		newDataNodeThrottleSetCmd(client),
+		newDataNodeThrottleDelCmd(client),
+		newDataNodeThrottleListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdDataNodeThrottleSetShort  = "Set the IOPS and bandwidth limits of a client on the data node"
+	cmdDataNodeThrottleDelShort  = "Remove the throttle of a client on the data node"
+	cmdDataNodeThrottleListShort = "List the throttled clients of the data node"
+)
+
+func newDataNodeThrottleSetCmd(client *master.MasterClient) *cobra.Command {
+	var optMaxIOPS uint64
+	var optMaxBandwidth uint64
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [NODE ADDRESS] [CLIENT IP]",
+		Short: cmdDataNodeThrottleSetShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var throttles []*proto.ClientThrottle
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optMaxIOPS == 0 && optMaxBandwidth == 0 {
+				err = fmt.Errorf("at least one of --%v and --%v should be specified", CliFlagMaxIOPS, CliFlagMaxBandwidth)
+				return
+			}
+			if throttles, err = client.NodeAPI().SetDataNodeThrottle(args[0], args[1], optMaxIOPS, optMaxBandwidth); err != nil {
+				return
+			}
+			stdout("Throttle of client [%v] has been set on data node [%v].\n", args[1], args[0])
+			printClientThrottles(throttles)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Uint64Var(&optMaxIOPS, CliFlagMaxIOPS, 0, "Specify the IOPS limit of the client")
+	cmd.Flags().Uint64Var(&optMaxBandwidth, CliFlagMaxBandwidth, 0, "Specify the bandwidth limit of the client [Unit: MB/s]")
+	return cmd
+}
+
+func newDataNodeThrottleDelCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpDelete + " [NODE ADDRESS] [CLIENT IP]",
+		Short: cmdDataNodeThrottleDelShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var throttles []*proto.ClientThrottle
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if throttles, err = client.NodeAPI().SetDataNodeThrottle(args[0], args[1], 0, 0); err != nil {
+				return
+			}
+			stdout("Throttle of client [%v] has been removed from data node [%v].\n", args[1], args[0])
+			printClientThrottles(throttles)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newDataNodeThrottleListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList + " [NODE ADDRESS]",
+		Short:   cmdDataNodeThrottleListShort,
+		Aliases: []string{"ls"},
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var throttles []*proto.ClientThrottle
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if throttles, err = client.NodeAPI().ListDataNodeThrottles(args[0]); err != nil {
+				return
+			}
+			printClientThrottles(throttles)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func printClientThrottles(throttles []*proto.ClientThrottle) {
+	stdout("%v\n", clientThrottleTableHeader)
+	for _, throttle := range throttles {
+		stdout("%v\n", formatClientThrottleTableRow(throttle))
+	}
+}
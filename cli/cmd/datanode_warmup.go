@@ -0,0 +1,142 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataNodeWarmUpUse   = "warm-up [NODE ADDRESS] [PARTITION ID]..."
+	cmdDataNodeWarmUpShort = "Pre-read the hot extents of data partitions into the page cache of a data node"
+)
+
+func newDataNodeWarmUpCmd(client *master.MasterClient) *cobra.Command {
+	var optDataPort string
+	var optExtentCount int
+	var optStatus bool
+	var cmd = &cobra.Command{
+		Use:   cmdDataNodeWarmUpUse,
+		Short: cmdDataNodeWarmUpShort,
+		Long: `Pre-read the hottest extents of the data partitions into the page cache of a data node,
+so that the reads are not served from the disks after the data node restarts. The heat
+of the extents is collected from the reads of the clients and persisted with the partitions.
+All the partitions on the data node are warmed up if no partition is specified. The warm up
+runs in background and is throttled by the warmUpRate of the data node, use --status to
+show the progress.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr = args[0]
+			var partitions []uint64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			for _, arg := range args[1:] {
+				var id uint64
+				if id, err = strconv.ParseUint(arg, 10, 64); err != nil {
+					err = fmt.Errorf("invalid partition id %v", arg)
+					return
+				}
+				partitions = append(partitions, id)
+			}
+			if len(partitions) == 0 {
+				var node *proto.DataNodeInfo
+				if node, err = client.NodeAPI().GetDataNode(nodeAddr); err != nil {
+					return
+				}
+				partitions = node.PersistenceDataPartitions
+			}
+			stdout("%v\n", warmUpStatusTableHeader)
+			for _, id := range partitions {
+				var status *proto.DataPartitionWarmUpStatus
+				if optStatus {
+					status, err = getDataPartitionWarmUpStatus(nodeAddr, optDataPort, id)
+				} else {
+					status, err = requestDataPartitionWarmUp(nodeAddr, optDataPort, &proto.DataPartitionWarmUpRequest{
+						PartitionID: id,
+						Count:       optExtentCount,
+					})
+				}
+				if err != nil {
+					return
+				}
+				stdout("%v\n", formatWarmUpStatusTableRow(status))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data node")
+	cmd.Flags().IntVar(&optExtentCount, CliFlagExtentCount, 0, "Specify the number of the hottest extents to warm up in each partition, 0 for the data node default")
+	cmd.Flags().BoolVar(&optStatus, CliFlagStatus, false, "Show the progress of the warm up instead of starting it")
+	return cmd
+}
+
+type warmUpResponse struct {
+	Code int32                            `json:"code"`
+	Msg  string                           `json:"msg"`
+	Data *proto.DataPartitionWarmUpStatus `json:"data"`
+}
+
+func requestDataPartitionWarmUp(nodeAddr, port string, req *proto.DataPartitionWarmUpRequest) (status *proto.DataPartitionWarmUpStatus, err error) {
+	var data []byte
+	if data, err = json.Marshal(req); err != nil {
+		return
+	}
+	var resp *http.Response
+	if resp, err = http.Post(fmt.Sprintf("http://%v/warmUp", profAddr(nodeAddr, port)), "application/json", bytes.NewReader(data)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &warmUpResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode warm up of data partition %v from %v: %v", req.PartitionID, nodeAddr, err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("warm up data partition %v on %v: %v", req.PartitionID, nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
+
+func getDataPartitionWarmUpStatus(nodeAddr, port string, partitionID uint64) (status *proto.DataPartitionWarmUpStatus, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/warmUp?id=%v", profAddr(nodeAddr, port), partitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &warmUpResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode warm up status of data partition %v from %v: %v", partitionID, nodeAddr, err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("get warm up status of data partition %v on %v: %v", partitionID, nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
@@ -39,6 +39,11 @@ func newDataPartitionCmd(client *master.MasterClient) *cobra.Command {
 		newDataPartitionDecommissionCmd(client),
 		newDataPartitionReplicateCmd(client),
 		newDataPartitionDeleteReplicaCmd(client),
+		newDataPartitionScrubStatusCmd(client),
+		newDataPartitionVerifyCmd(client),
+		newDataPartitionRepairCmd(client),
+		newDataPartitionResetRaftCmd(client),
+		newDataPartitionRaftStatusCmd(client),
 	)
 	return cmd
 }
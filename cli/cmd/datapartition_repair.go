@@ -0,0 +1,130 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataPartitionRepairUse   = "repair [DATA PARTITION ID] [REPLICA ADDRESS]"
+	cmdDataPartitionRepairShort = "Re-sync a replica of a data partition from the leader"
+)
+
+func newDataPartitionRepairCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optDataPort string
+		optYes      bool
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdDataPartitionRepairUse,
+		Short: cmdDataPartitionRepairShort,
+		Long: `Re-sync all the normal extents of a suspect replica of a data partition from the leader in
+background. The extents not modified for 10 minutes are truncated on the replica and copied
+from the leader from the beginning, the extents missing on the replica are created, and the
+extents being written are repaired to the size on the leader. The replica serves nothing
+from the truncated extents until they are copied, so the other replicas must be healthy.
+Use "datapartition verify" to check the replicas after the re-sync.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.DataPartitionInfo
+				resync      *proto.DataPartitionResync
+				replica     = args[1]
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if partition, err = client.AdminAPI().GetDataPartition("", partitionID); err != nil {
+				return
+			}
+			isReplica := false
+			for _, host := range partition.Hosts {
+				isReplica = isReplica || host == replica
+			}
+			if !isReplica {
+				err = fmt.Errorf("%v is not a replica of data partition %v", replica, partitionID)
+				return
+			}
+			if !optYes {
+				stdout("Re-sync replica [%v] of data partition [%v] from the leader (yes/no)[no]:", replica, partitionID)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			errs := make([]string, 0)
+			for _, host := range leaderFirstHosts(partition) {
+				if host == replica {
+					continue
+				}
+				var e error
+				if resync, e = resyncDataPartitionReplica(host, optDataPort, partitionID, replica); e == nil {
+					break
+				}
+				errs = append(errs, e.Error())
+			}
+			if resync == nil {
+				err = fmt.Errorf("no leader re-synced replica %v of data partition %v: %v", replica, partitionID, strings.Join(errs, "; "))
+				return
+			}
+			stdout("Replica %v of data partition %v is being re-synced from leader %v: %v extents to create, "+
+				"%v extents to copy from the beginning, %v extents to repair\n", resync.Replica, resync.PartitionID,
+				resync.Leader, resync.CreatedExtents, resync.ResetExtents, resync.RepairedExtents)
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func resyncDataPartitionReplica(nodeAddr, port string, partitionID uint64, replica string) (resync *proto.DataPartitionResync, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/resyncReplica?id=%v&replica=%v", profAddr(nodeAddr, port),
+		partitionID, url.QueryEscape(replica))); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                      `json:"code"`
+		Msg  string                     `json:"msg"`
+		Data *proto.DataPartitionResync `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode re-sync of data partition %v from %v: %v", partitionID, nodeAddr, err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("re-sync data partition %v on %v: %v", partitionID, nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
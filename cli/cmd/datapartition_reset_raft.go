@@ -0,0 +1,106 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataPartitionResetRaftUse   = "reset-raft [DATA PARTITION ID]"
+	cmdDataPartitionResetRaftShort = "Rebuild the raft group of a data partition from a surviving replica"
+)
+
+func newDataPartitionResetRaftCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optSurvivor string
+		optYes      bool
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdDataPartitionResetRaftUse,
+		Short: cmdDataPartitionResetRaftShort,
+		Long: `Rebuild the raft group of a data partition which has lost its quorum permanently, e.g. two of
+the three data nodes are dead, from the surviving replica given by --survivor. The survivor
+becomes the only member of the raft group and keeps its data, the replicas on the lost data
+nodes are removed from the partition. The master refuses to reset the group while any other
+replica is on an active data node, and the survivor refuses it while the group has a leader.
+The writes not replicated to the survivor are lost. Add the replicas back with
+"datapartition add-replica" after the reset.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.DataPartitionInfo
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if optSurvivor == "" {
+				err = fmt.Errorf("the surviving replica must be given by --survivor")
+				return
+			}
+			if partition, err = client.AdminAPI().GetDataPartition("", partitionID); err != nil {
+				return
+			}
+			lost := make([]string, 0, len(partition.Hosts))
+			isReplica := false
+			for _, host := range partition.Hosts {
+				if host == optSurvivor {
+					isReplica = true
+					continue
+				}
+				lost = append(lost, host)
+			}
+			if !isReplica {
+				err = fmt.Errorf("%v is not a replica of data partition %v", optSurvivor, partitionID)
+				return
+			}
+			stdout("Data partition [%v] of volume [%v]\n", partitionID, partition.VolName)
+			stdout("  Survivor        : %v\n", optSurvivor)
+			stdout("  Replicas to drop: %v\n", lost)
+			stdout("The raft group is rebuilt from the survivor only, the writes not replicated to it are lost.\n")
+			if !optYes {
+				stdout("Type the data partition ID to confirm the reset: ")
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != args[0] {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			if err = client.AdminAPI().ResetDataPartitionRaft(partitionID, optSurvivor); err != nil {
+				return
+			}
+			stdout("Raft group of data partition %v is reset to survivor %v\n", partitionID, optSurvivor)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optSurvivor, "survivor", "", "Specify the address of the surviving replica")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataPartitionScrubStatusUse   = "scrub-status [DATA PARTITION ID]"
+	cmdDataPartitionScrubStatusShort = "Show the progress of scrubbing the replicas of a data partition"
+)
+
+func newDataPartitionScrubStatusCmd(client *master.MasterClient) *cobra.Command {
+	var optDataPort string
+	var cmd = &cobra.Command{
+		Use:   cmdDataPartitionScrubStatusUse,
+		Short: cmdDataPartitionScrubStatusShort,
+		Long: `Show the progress of scrubbing the replicas of a data partition. Each data node scrubs
+its partitions one after another in background, reading the extents at the scrubRate and
+verifying the blocks against their crc. The corrupt blocks are repaired from the other
+replicas. A partition is scrubbed once in the scrubIntervalHours, within the hours of the
+scrubWindow of the data node.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.DataPartitionInfo
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if partition, err = client.AdminAPI().GetDataPartition("", partitionID); err != nil {
+				return
+			}
+			stdout("%v\n", scrubStatusTableHeader)
+			for _, host := range partition.Hosts {
+				status, e := getDataPartitionScrubStatus(host, optDataPort, partitionID)
+				if e != nil {
+					status = &proto.DataPartitionScrubStatus{PartitionID: partitionID, Err: e.Error()}
+				}
+				stdout("%v\n", formatScrubStatusTableRow(host, status))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	return cmd
+}
+
+func getDataPartitionScrubStatus(nodeAddr, port string, partitionID uint64) (status *proto.DataPartitionScrubStatus, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/scrubStatus?id=%v", profAddr(nodeAddr, port), partitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                           `json:"code"`
+		Msg  string                          `json:"msg"`
+		Data *proto.DataPartitionScrubStatus `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode scrub status of data partition %v from %v: %v", partitionID, nodeAddr, err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("get scrub status of data partition %v on %v: %v", partitionID, nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdDataPartitionVerifyUse   = "verify [DATA PARTITION ID]"
+	cmdDataPartitionVerifyShort = "Compare the extents of the replicas of a data partition"
+)
+
+func newDataPartitionVerifyCmd(client *master.MasterClient) *cobra.Command {
+	var optDataPort string
+	var cmd = &cobra.Command{
+		Use:   cmdDataPartitionVerifyUse,
+		Short: cmdDataPartitionVerifyShort,
+		Long: `Compare the size and the crc of the normal extents of the replicas of a data partition,
+and list the extents missing on some replicas or mismatching among them. The comparison is
+coordinated by the leader of the partition. The crc of an extent is computed once it is not
+modified for 10 minutes, so the extents modified recently are counted as unverified.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.DataPartitionInfo
+				report      *proto.DataPartitionVerifyReport
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if partition, err = client.AdminAPI().GetDataPartition("", partitionID); err != nil {
+				return
+			}
+			errs := make([]string, 0)
+			for _, host := range leaderFirstHosts(partition) {
+				var e error
+				if report, e = verifyDataPartition(host, optDataPort, partitionID); e == nil {
+					break
+				}
+				errs = append(errs, e.Error())
+			}
+			if report == nil {
+				err = fmt.Errorf("no replica verified data partition %v: %v", partitionID, strings.Join(errs, "; "))
+				return
+			}
+			stdout("%v", formatDataPartitionVerifyReport(report))
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	return cmd
+}
+
+// leaderFirstHosts returns the hosts of the data partition starting with the leader reported to the master, so that
+// the other replicas are tried in case the leader changed.
+func leaderFirstHosts(partition *proto.DataPartitionInfo) []string {
+	var leader string
+	for _, replica := range partition.Replicas {
+		if replica.IsLeader {
+			leader = replica.Addr
+		}
+	}
+	hosts := make([]string, 0, len(partition.Hosts))
+	if leader != "" {
+		hosts = append(hosts, leader)
+	}
+	for _, host := range partition.Hosts {
+		if host != leader {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func verifyDataPartition(nodeAddr, port string, partitionID uint64) (report *proto.DataPartitionVerifyReport, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/verifyPartition?id=%v", profAddr(nodeAddr, port), partitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                            `json:"code"`
+		Msg  string                           `json:"msg"`
+		Data *proto.DataPartitionVerifyReport `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode verify report of data partition %v from %v: %v", partitionID, nodeAddr, err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("verify data partition %v on %v: %v", partitionID, nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
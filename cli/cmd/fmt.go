@@ -17,11 +17,14 @@ package cmd
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util"
 )
 
 func formatClusterView(cv *proto.ClusterView) string {
@@ -101,6 +104,16 @@ func formatSimpleVolView(svv *proto.SimpleVolView) string {
 	sb.WriteString(fmt.Sprintf("  Follower read        : %v\n", formatEnabledDisabled(svv.FollowerRead)))
 	sb.WriteString(fmt.Sprintf("  Enable token         : %v\n", formatEnabledDisabled(svv.EnableToken)))
 	sb.WriteString(fmt.Sprintf("  Cross zone           : %v\n", formatEnabledDisabled(svv.CrossZone)))
+	sb.WriteString(fmt.Sprintf("  Zone anti-affinity   : %v\n", formatEnabledDisabled(svv.ZoneAntiAffinity)))
+	sb.WriteString(fmt.Sprintf("  Strict zones         : %v\n", formatEnabledDisabled(svv.StrictZones)))
+	sb.WriteString(fmt.Sprintf("  Max IOPS             : %v\n", formatQosLimit(svv.MaxIOPS, "")))
+	sb.WriteString(fmt.Sprintf("  Max bandwidth        : %v\n", formatQosLimit(svv.MaxBandwidth, "MB/s")))
+	sb.WriteString(fmt.Sprintf("  Verify read          : %v\n", formatEnabledDisabled(svv.VerifyRead)))
+	sb.WriteString(fmt.Sprintf("  WORM retention       : %v\n", formatWormRetention(svv.WormRetentionDays, svv.WormOverrideUntil)))
+	sb.WriteString(fmt.Sprintf("  Trash                : %v\n", formatTrashDays(svv.TrashDays)))
+	sb.WriteString(fmt.Sprintf("  Trash size           : %v\n", formatSize(svv.TrashSize)))
+	sb.WriteString(fmt.Sprintf("  Access time          : %v\n", formatEnabledDisabled(svv.Atime)))
+	sb.WriteString(fmt.Sprintf("  File audit           : %v\n", formatEnabledDisabled(svv.FileAudit)))
 	sb.WriteString(fmt.Sprintf("  Inode count          : %v\n", svv.InodeCount))
 	sb.WriteString(fmt.Sprintf("  Dentry count         : %v\n", svv.DentryCount))
 	sb.WriteString(fmt.Sprintf("  Max metaPartition ID : %v\n", svv.MaxMetaPartitionID))
@@ -145,6 +158,42 @@ func formatDataPartitionTableRow(view *proto.DataPartitionResponse) string {
 		strings.Join(view.Hosts, ","))
 }
 
+var (
+	partitionZoneTablePattern = "%-8v    %-6v    %-12v    %-32v    %-18v"
+	partitionZoneTableHeader  = fmt.Sprintf(partitionZoneTablePattern,
+		"ID", "TYPE", "PLACEMENT", "ZONES", "HOSTS")
+)
+
+// formatPartitionZoneTableRow shows the number of the replicas of a partition in each zone, and flags the
+// partition if its replicas span fewer zones than required.
+func formatPartitionZoneTableRow(partitionType string, id uint64, hosts []string, zoneOf map[string]string, required int) string {
+	var zones []string
+	var counts = make(map[string]int)
+	for _, host := range hosts {
+		zone, ok := zoneOf[host]
+		if !ok {
+			zone = "unknown"
+		}
+		if counts[zone] == 0 {
+			zones = append(zones, zone)
+		}
+		counts[zone]++
+	}
+	var distribution = make([]string, 0, len(zones))
+	for _, zone := range zones {
+		distribution = append(distribution, fmt.Sprintf("%v:%v", zone, counts[zone]))
+	}
+	if required > len(hosts) {
+		required = len(hosts)
+	}
+	var placement = "OK"
+	if len(zones) < required {
+		placement = fmt.Sprintf("SHORT(%v/%v)", len(zones), required)
+	}
+	return fmt.Sprintf(partitionZoneTablePattern,
+		id, partitionType, placement, strings.Join(distribution, ","), strings.Join(hosts, ","))
+}
+
 var (
 	partitionInfoTablePattern = "%-8v    %-8v    %-10v     %-18v    %-18v"
 	partitionInfoTableHeader  = fmt.Sprintf(partitionInfoTablePattern,
@@ -335,6 +384,33 @@ func formatEnabledDisabled(b bool) string {
 	return "Disabled"
 }
 
+func formatQosLimit(limit uint64, unit string) string {
+	if limit == 0 {
+		return "Unlimited"
+	}
+	if unit == "" {
+		return strconv.FormatUint(limit, 10)
+	}
+	return fmt.Sprintf("%v %v", limit, unit)
+}
+
+func formatWormRetention(days uint32, overrideUntil int64) string {
+	if days == 0 {
+		return "Disabled"
+	}
+	if overrideUntil > time.Now().Unix() {
+		return fmt.Sprintf("%v days (overridden until %v)", days, formatTime(overrideUntil))
+	}
+	return fmt.Sprintf("%v days", days)
+}
+
+func formatTrashDays(days uint32) string {
+	if days == 0 {
+		return "Disabled"
+	}
+	return fmt.Sprintf("%v days", days)
+}
+
 func formatNodeStatus(status bool) string {
 	if status {
 		return "Active"
@@ -390,21 +466,22 @@ func formatDataReplica(indentation string, replica *proto.DataReplica, rowTable
 	return sb.String()
 }
 
-var metaReplicaTableRowPattern = "%-18v    %-6v    %-6v    %-10v"
+var metaReplicaTableRowPattern = "%-18v    %-6v    %-6v    %-10v    %-10v"
 
 func formatMetaReplicaTableHeader() string {
-	return fmt.Sprintf(metaReplicaTableRowPattern, "ADDRESS", "ISLEADER", "STATUS", "REPORT TIME")
+	return fmt.Sprintf(metaReplicaTableRowPattern, "ADDRESS", "ISLEADER", "STATUS", "MEM USED", "REPORT TIME")
 }
 
 func formatMetaReplica(indentation string, replica *proto.MetaReplicaInfo, rowTable bool) string {
 	if rowTable {
 		return fmt.Sprintf(metaReplicaTableRowPattern, replica.Addr, replica.IsLeader, formatMetaPartitionStatus(replica.Status),
-		formatTime(replica.ReportTime))
+		formatSize(replica.MemUsed), formatTime(replica.ReportTime))
 	}
 	var sb = strings.Builder{}
 	sb.WriteString(fmt.Sprintf("%v- Addr           : %v\n", indentation, replica.Addr))
 	sb.WriteString(fmt.Sprintf("%v  Status         : %v\n", indentation, formatMetaPartitionStatus(replica.Status)))
 	sb.WriteString(fmt.Sprintf("%v  IsLeader       : %v\n", indentation, replica.IsLeader))
+	sb.WriteString(fmt.Sprintf("%v  MemUsed        : %v\n", indentation, formatSize(replica.MemUsed)))
 	sb.WriteString(fmt.Sprintf("%v  ReportTime     : %v\n", indentation, formatTime(replica.ReportTime)))
 	return sb.String()
 }
@@ -445,10 +522,19 @@ func formatDataNodeDetail(dn *proto.DataNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(dn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", dn.DataPartitionCount))
 	sb.WriteString(fmt.Sprintf("  Bad disks           : %v\n", dn.BadDisks))
+	sb.WriteString(fmt.Sprintf("  Failing disks       : %v\n", dn.FailingDisks))
+	sb.WriteString(fmt.Sprintf("  Maintenance         : %v\n", formatMaintenance(dn.Maintenance, dn.MaintenanceSince)))
 	sb.WriteString(fmt.Sprintf("  Persist partitions  : %v\n", dn.PersistenceDataPartitions))
 	return sb.String()
 }
 
+func formatMaintenance(maintenance bool, since int64) string {
+	if !maintenance {
+		return "No"
+	}
+	return fmt.Sprintf("Yes, since %v", formatTime(since))
+}
+
 var metaNodeDetailTableRowPattern = "%-6v    %-6v    %-18v    %-6v    %-6v    %-6v    %-10v"
 
 func formatMetaNodeDetailTableHeader() string {
@@ -471,6 +557,8 @@ func formatMetaNodeDetail(mn *proto.MetaNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  IsActive            : %v\n", formatNodeStatus(mn.IsActive)))
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(mn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", mn.MetaPartitionCount))
+	sb.WriteString(fmt.Sprintf("  Memory full         : %v\n", mn.MemFull))
+	sb.WriteString(fmt.Sprintf("  Maintenance         : %v\n", formatMaintenance(mn.Maintenance, mn.MaintenanceSince)))
 	sb.WriteString(fmt.Sprintf("  Persist partitions  : %v\n", mn.PersistenceMetaPartitions))
 	return sb.String()
 }
@@ -496,3 +584,517 @@ func formatZoneView(zv *proto.ZoneView) string {
 	}
 	return sb.String()
 }
+
+var (
+	nodeSetTablePattern = "%-8v    %-16v    %-8v    %-10v    %-10v"
+	nodeSetTableHeader  = fmt.Sprintf(nodeSetTablePattern, "ID", "ZONE", "CAPACITY", "DATANODES", "METANODES")
+)
+
+func formatNodeSetTableRow(ns *proto.NodeSetInfo) string {
+	return fmt.Sprintf(nodeSetTablePattern, ns.ID, ns.ZoneName, ns.Capacity, ns.DataNodeLen, ns.MetaNodeLen)
+}
+
+func formatNodeSetInfo(ns *proto.NodeSetInfo) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  ID                  : %v\n", ns.ID))
+	sb.WriteString(fmt.Sprintf("  Zone                : %v\n", ns.ZoneName))
+	sb.WriteString(fmt.Sprintf("  Capacity            : %v\n", ns.Capacity))
+	sb.WriteString(fmt.Sprintf("\nDataNodes[%v]:\n", ns.DataNodeLen))
+	sb.WriteString(fmt.Sprintf("  %v\n", formatNodeViewTableHeader()))
+	for _, nv := range ns.DataNodes {
+		sb.WriteString(fmt.Sprintf("  %v\n", formatNodeView(&nv, true)))
+	}
+	sb.WriteString(fmt.Sprintf("\nMetaNodes[%v]:\n", ns.MetaNodeLen))
+	sb.WriteString(fmt.Sprintf("  %v\n", formatNodeViewTableHeader()))
+	for _, nv := range ns.MetaNodes {
+		sb.WriteString(fmt.Sprintf("  %v\n", formatNodeView(&nv, true)))
+	}
+	return sb.String()
+}
+
+var (
+	adminTaskTablePattern = "%-22v    %-12v    %-8v    %-8v    %-6v    %-8v    %-20v    %v"
+	adminTaskTableHeader  = fmt.Sprintf(adminTaskTablePattern,
+		"NODE", "PARTITION", "STATUS", "PAUSED", "SENDS", "AGE", "OPERATION", "ID")
+)
+
+func formatAdminTaskTableRow(view *proto.AdminTaskView) string {
+	return fmt.Sprintf(adminTaskTablePattern,
+		view.NodeAddr, view.PartitionID, formatAdminTaskStatus(view.Status), view.Paused, view.SendCount,
+		time.Since(time.Unix(view.CreateTime, 0)).Truncate(time.Second), view.OpName, view.ID)
+}
+
+func formatAdminTaskStatus(status int8) string {
+	switch status {
+	case proto.TaskStart:
+		return "Waiting"
+	case proto.TaskRunning:
+		return "Running"
+	case proto.TaskSucceeds:
+		return "Succeed"
+	case proto.TaskFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+var (
+	tierRuleTablePattern = "%-24v    %-14v    %-8v"
+	tierRuleTableHeader  = fmt.Sprintf(tierRuleTablePattern, "RULE", "INACTIVE DAYS", "STATUS")
+)
+
+func formatTierRuleTableRow(rule *proto.TierRule) string {
+	return fmt.Sprintf(tierRuleTablePattern,
+		rule.Name, rule.InactiveDays, formatEnabledDisabled(rule.Enable))
+}
+
+var (
+	tierRuleStatTablePattern = "%-24v    %-12v    %-10v    %-10v    %-10v"
+	tierRuleStatTableHeader  = fmt.Sprintf(tierRuleStatTablePattern, "RULE", "PARTITIONS", "INODES", "SIZE", "UPDATE TIME")
+)
+
+func formatTierRuleStatTableRow(stat *proto.TierRuleStat) string {
+	return fmt.Sprintf(tierRuleStatTablePattern,
+		stat.RuleName, stat.ScannedPartitions, stat.CandidateInodes, formatSize(stat.CandidateBytes),
+		formatTime(stat.UpdateTime))
+}
+
+var (
+	clientThrottleTablePattern = "%-40v    %-12v    %v"
+	clientThrottleTableHeader  = fmt.Sprintf(clientThrottleTablePattern, "CLIENT IP", "MAX IOPS", "MAX BANDWIDTH")
+)
+
+func formatClientThrottleTableRow(throttle *proto.ClientThrottle) string {
+	return fmt.Sprintf(clientThrottleTablePattern,
+		throttle.ClientIP, formatQosLimit(throttle.MaxIOPS, ""), formatQosLimit(throttle.MaxBandwidth/util.MB, "MB/s"))
+}
+
+var (
+	orphanExtentTablePattern = "%-12v    %-12v    %-10v    %-19v    %-19v    %v"
+	orphanExtentTableHeader  = fmt.Sprintf(orphanExtentTablePattern,
+		"PARTITION ID", "EXTENT ID", "SIZE", "FIRST SEEN", "RECLAIMABLE AT", "STATUS")
+)
+
+func formatOrphanExtentTableRow(result *proto.OrphanExtentScanResponse, extent *proto.OrphanExtent, reclaimed bool) string {
+	status := "Candidate"
+	if reclaimed {
+		status = "Reclaimed"
+	}
+	return fmt.Sprintf(orphanExtentTablePattern,
+		result.PartitionID, extent.ExtentID, formatSize(extent.Size), formatTime(extent.FirstSeen),
+		formatTime(extent.FirstSeen+result.GracePeriod), status)
+}
+
+var (
+	repairLinkTablePattern = "%-20v    %-20v    %v"
+	repairLinkTableHeader  = fmt.Sprintf(repairLinkTablePattern, "SRC ZONE", "DST ZONE", "MAX BANDWIDTH")
+)
+
+func formatRepairLinkTableRow(link *proto.RepairLink) string {
+	return fmt.Sprintf(repairLinkTablePattern, link.SrcZone, link.DstZone, formatQosLimit(link.MaxBandwidth, "MB/s"))
+}
+
+var (
+	rebuildPolicyTablePattern = "%-20v    %-12v    %v"
+	rebuildPolicyTableHeader  = fmt.Sprintf(rebuildPolicyTablePattern, "ZONE", "DELAY", "MAX BANDWIDTH")
+)
+
+func formatRebuildPolicyTableRow(policy *proto.RebuildPolicy) string {
+	return fmt.Sprintf(rebuildPolicyTablePattern, formatRebuildPolicyZone(policy.Zone), formatRebuildDelay(policy.DelaySec),
+		formatQosLimit(policy.MaxBandwidth, "MB/s"))
+}
+
+func formatRebuildPolicyZone(zone string) string {
+	if zone == "" {
+		return "(cluster)"
+	}
+	return zone
+}
+
+func formatRebuildDelay(delaySec int64) string {
+	if delaySec == 0 {
+		return "Off"
+	}
+	return (time.Duration(delaySec) * time.Second).String()
+}
+
+var (
+	cordonTablePattern = "%-10v    %-22v    %-19v    %-19v    %v"
+	cordonTableHeader  = fmt.Sprintf(cordonTablePattern, "TYPE", "NAME", "SINCE", "UNTIL", "REASON")
+)
+
+func formatCordonTableRow(cordon *proto.NodeCordon) string {
+	var until = "-"
+	if cordon.Until != 0 {
+		until = formatTime(cordon.Until)
+	}
+	return fmt.Sprintf(cordonTablePattern, cordon.Type, cordon.Name, formatTime(cordon.Since), until, cordon.Reason)
+}
+
+var (
+	slowOpGroupTablePattern = "%-48v    %-8v    %-10v    %-10v    %-19v    %v"
+	slowOpGroupTableHeader  = fmt.Sprintf(slowOpGroupTablePattern, "GROUP", "COUNT", "AVG(ms)", "MAX(ms)", "LAST", "LAST OP")
+)
+
+func formatSlowOpGroupTableRow(group *slowOpGroup) string {
+	avg := float64(group.TotalUs) / float64(group.Count) / 1000
+	return fmt.Sprintf(slowOpGroupTablePattern, group.Key, group.Count, fmt.Sprintf("%.1f", avg),
+		fmt.Sprintf("%.1f", float64(group.MaxUs)/1000), formatTime(group.LastTime), group.LastOp)
+}
+
+func formatMetaNodeMemUsage(usage *proto.MetaNodeMemUsage) string {
+	var sb = strings.Builder{}
+	var estimated uint64
+	for _, partition := range usage.Partitions {
+		estimated += partition.MemUsed
+	}
+	usageThreshold := "disabled"
+	if usage.UsageThreshold > 0 {
+		usageThreshold = fmt.Sprintf("%v (%v)", usage.UsageThreshold, formatSize(uint64(float64(usage.Total)*usage.UsageThreshold)))
+	}
+	sb.WriteString(fmt.Sprintf("  Used                : %v\n", formatSize(usage.Used)))
+	sb.WriteString(fmt.Sprintf("  Total               : %v\n", formatSize(usage.Total)))
+	sb.WriteString(fmt.Sprintf("  Estimated by items  : %v\n", formatSize(estimated)))
+	sb.WriteString(fmt.Sprintf("  Usage threshold     : %v\n", usageThreshold))
+	sb.WriteString(fmt.Sprintf("  Read-only threshold : %v (%v)\n", usage.ReadOnlyThreshold,
+		formatSize(uint64(float64(usage.Total)*usage.ReadOnlyThreshold))))
+	sb.WriteString(fmt.Sprintf("  Memory full         : %v\n", usage.MemFull))
+	return sb.String()
+}
+
+var (
+	metaPartitionMemTablePattern = "%-12v    %-20v    %-12v    %-12v    %v"
+	metaPartitionMemTableHeader  = fmt.Sprintf(metaPartitionMemTablePattern, "PARTITION", "VOLUME", "INODES", "DENTRIES", "MEM USED")
+)
+
+func formatMetaPartitionMemTableRow(partition *proto.MetaPartitionMemUsage) string {
+	return fmt.Sprintf(metaPartitionMemTablePattern, partition.PartitionID, partition.VolName, partition.InodeCnt,
+		partition.DentryCnt, formatSize(partition.MemUsed))
+}
+
+var (
+	auditEventTablePattern = "%-19v    %-21v    %-32v    %-20v    %-24v    %v"
+	auditEventTableHeader  = fmt.Sprintf(auditEventTablePattern, "TIME", "CLIENT", "OPERATION", "TARGET", "RESULT", "PARAMS")
+)
+
+func formatAuditEventTableRow(event *proto.AuditEvent) string {
+	var result = "OK"
+	if event.Code != proto.ErrCodeSuccess {
+		result = fmt.Sprintf("%v(%v)", event.Code, event.Msg)
+	}
+	var client = event.Client
+	if event.ForwardedFor != "" {
+		client = event.ForwardedFor
+	}
+	var keys = make([]string, 0, len(event.Params))
+	for key := range event.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params = make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, key+"="+event.Params[key])
+	}
+	return fmt.Sprintf(auditEventTablePattern, formatTime(event.Time), client, event.Op, event.Target, result, strings.Join(params, " "))
+}
+
+var (
+	nfsExportTablePattern = "%-24v    %-20v    %-9v    %-11v    %v"
+	nfsExportTableHeader  = fmt.Sprintf(nfsExportTablePattern, "PATH", "VOLUME", "READ ONLY", "ROOT SQUASH", "CLIENTS")
+)
+
+func formatNFSExportTableRow(export *proto.NFSExport) string {
+	var clients = "*"
+	if len(export.Clients) > 0 {
+		clients = strings.Join(export.Clients, ",")
+	}
+	return fmt.Sprintf(nfsExportTablePattern, export.Path, export.Volume, formatYesNo(export.ReadOnly), formatYesNo(export.RootSquash), clients)
+}
+
+var (
+	fileExtentTablePattern = "%-12v    %-10v    %-12v    %-10v    %v"
+	fileExtentTableHeader  = fmt.Sprintf(fileExtentTablePattern,
+		"FILE OFFSET", "SIZE", "PARTITION ID", "EXTENT ID", "EXTENT OFFSET")
+)
+
+func formatFileExtentTableRow(ek proto.ExtentKey) string {
+	return fmt.Sprintf(fileExtentTablePattern, ek.FileOffset, formatSize(uint64(ek.Size)), ek.PartitionId, ek.ExtentId, ek.ExtentOffset)
+}
+
+var (
+	extentReplicaTablePattern = "%-12v    %-22v    %-8v    %-12v    %v"
+	extentReplicaTableHeader  = fmt.Sprintf(extentReplicaTablePattern, "PARTITION ID", "ADDRESS", "ISLEADER", "STATUS", "DISK")
+)
+
+func formatExtentReplicaTableRow(partition *proto.DataPartitionInfo, addr string) string {
+	replica := findDataReplica(partition, addr)
+	if replica == nil {
+		return fmt.Sprintf(extentReplicaTablePattern, partition.PartitionID, addr, "N/A", "Unreported", "N/A")
+	}
+	return fmt.Sprintf(extentReplicaTablePattern, partition.PartitionID, addr, formatYesNo(replica.IsLeader),
+		formatDataPartitionStatus(replica.Status), replica.DiskPath)
+}
+
+var (
+	nodeConfigTablePattern = "%-24v    %-20v    %v"
+	nodeConfigTableHeader  = fmt.Sprintf(nodeConfigTablePattern, "KEY", "VALUE", "OVERRIDDEN")
+)
+
+func formatNodeConfigTableRow(config *proto.NodeConfig) string {
+	return fmt.Sprintf(nodeConfigTablePattern, config.Key, config.Value, formatYesNo(config.Overridden))
+}
+
+var (
+	diskRecoveryFailureTablePattern = "%-12v    %v"
+	diskRecoveryFailureTableHeader  = fmt.Sprintf(diskRecoveryFailureTablePattern, "PARTITION ID", "LAST ERROR")
+)
+
+func formatDiskRecoveryFailureTableRow(partitionID uint64, msg string) string {
+	return fmt.Sprintf(diskRecoveryFailureTablePattern, partitionID, msg)
+}
+
+var (
+	warmUpStatusTablePattern = "%-12v    %-8v    %-16v    %-10v    %-19v    %-19v    %v"
+	warmUpStatusTableHeader  = fmt.Sprintf(warmUpStatusTablePattern,
+		"PARTITION ID", "RUNNING", "WARMED EXTENTS", "SIZE", "START TIME", "END TIME", "ERROR")
+)
+
+func formatWarmUpStatusTableRow(status *proto.DataPartitionWarmUpStatus) string {
+	startTime, endTime := "N/A", "N/A"
+	if status.StartTime > 0 {
+		startTime = formatTime(status.StartTime)
+	}
+	if status.EndTime > 0 {
+		endTime = formatTime(status.EndTime)
+	}
+	return fmt.Sprintf(warmUpStatusTablePattern, status.PartitionID, formatYesNo(status.Running),
+		fmt.Sprintf("%v/%v", status.WarmedExtents, status.Extents), formatSize(status.WarmedSize), startTime, endTime, status.Err)
+}
+
+var (
+	scrubStatusTablePattern = "%-21v    %-8v    %-17v    %-10v    %-8v    %-8v    %-19v    %-19v    %v"
+	scrubStatusTableHeader  = fmt.Sprintf(scrubStatusTablePattern,
+		"ADDRESS", "RUNNING", "SCRUBBED EXTENTS", "SIZE", "CORRUPT", "REPAIRED", "START TIME", "LAST FINISH", "ERROR")
+)
+
+func formatScrubStatusTableRow(addr string, status *proto.DataPartitionScrubStatus) string {
+	startTime, lastFinishTime := "N/A", "N/A"
+	if status.StartTime > 0 {
+		startTime = formatTime(status.StartTime)
+	}
+	if status.LastFinishTime > 0 {
+		lastFinishTime = formatTime(status.LastFinishTime)
+	}
+	return fmt.Sprintf(scrubStatusTablePattern, addr, formatYesNo(status.Running),
+		fmt.Sprintf("%v/%v", status.ScrubbedExtents, status.Extents), formatSize(status.ScrubbedSize),
+		status.CorruptBlocks, status.RepairedBlocks, startTime, lastFinishTime, status.Err)
+}
+
+var (
+	extentMismatchTablePattern = "%-12v    %-8v    %-21v    %-10v    %-10v    %v"
+	extentMismatchTableHeader  = fmt.Sprintf(extentMismatchTablePattern, "EXTENT", "REASON", "ADDRESS", "SIZE", "CRC", "MODIFY TIME")
+)
+
+func formatDataPartitionVerifyReport(report *proto.DataPartitionVerifyReport) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Partition ID     : %v\n", report.PartitionID))
+	sb.WriteString(fmt.Sprintf("  Leader           : %v\n", report.Leader))
+	sb.WriteString(fmt.Sprintf("  Hosts            : %v\n", strings.Join(report.Hosts, ", ")))
+	sb.WriteString(fmt.Sprintf("  Verify time      : %v\n", formatTime(report.VerifyTime)))
+	sb.WriteString(fmt.Sprintf("  Extents          : %v\n", report.Extents))
+	sb.WriteString(fmt.Sprintf("  Verified         : %v\n", report.Verified))
+	sb.WriteString(fmt.Sprintf("  Unverified       : %v\n", report.Unverified))
+	sb.WriteString(fmt.Sprintf("  Mismatches       : %v\n", len(report.Mismatches)))
+	for host, msg := range report.HostErrors {
+		sb.WriteString(fmt.Sprintf("  Failed host      : %v %v\n", host, msg))
+	}
+	if len(report.Mismatches) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("%v\n", extentMismatchTableHeader))
+	for _, mismatch := range report.Mismatches {
+		for i, replica := range mismatch.Replicas {
+			extentID, reason := "", ""
+			if i == 0 {
+				extentID, reason = strconv.FormatUint(mismatch.ExtentID, 10), mismatch.Reason
+			}
+			if replica.Missing {
+				sb.WriteString(fmt.Sprintf(extentMismatchTablePattern+"\n", extentID, reason, replica.Addr, "missing", "-", "-"))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(extentMismatchTablePattern+"\n", extentID, reason, replica.Addr,
+				replica.Size, replica.Crc, formatTime(replica.ModifyTime)))
+		}
+	}
+	return sb.String()
+}
+
+var (
+	raftStatusTablePattern = "%-21v    %-8v    %-10v    %-21v    %-6v    %-10v    %-10v    %-10v    %-9v    %-7v    %-8v    %v"
+	raftStatusTableHeader  = fmt.Sprintf(raftStatusTablePattern, "ADDRESS", "NODE ID", "STATE", "LEADER", "TERM",
+		"INDEX", "COMMIT", "APPLIED", "UNAPPLIED", "PENDING", "STATUS", "ERROR")
+	raftReplicaTablePattern = "%-21v    %-8v    %-10v    %-10v    %-10v    %-9v    %-6v    %-6v    %-8v    %-11v    %v"
+	raftReplicaTableHeader  = fmt.Sprintf(raftReplicaTablePattern, "PEER", "NODE ID", "MATCH", "COMMIT", "NEXT",
+		"STATE", "ACTIVE", "PAUSED", "INFLIGHT", "SNAPSHOTING", "LAST ACTIVE")
+)
+
+func formatRaftStatus(peers []proto.Peer, statuses []*replicaRaftStatus) string {
+	peerAddrs := make(map[uint64]string, len(peers))
+	for _, peer := range peers {
+		peerAddrs[peer.ID] = peer.Addr
+	}
+	peerAddr := func(id uint64) string {
+		if addr, ok := peerAddrs[id]; ok {
+			return addr
+		}
+		if id == 0 {
+			return "N/A"
+		}
+		return strconv.FormatUint(id, 10)
+	}
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%v\n", raftStatusTableHeader))
+	var leader *replicaRaftStatus
+	for _, result := range statuses {
+		st := result.status
+		if result.err != nil || st == nil {
+			sb.WriteString(fmt.Sprintf(raftStatusTablePattern+"\n", result.addr, "", "", "", "", "", "", "", "", "", "", result.err))
+			continue
+		}
+		status := "running"
+		if st.Stopped {
+			status = "stopped"
+		} else if st.RestoringSnapshot {
+			status = "snapshot"
+		}
+		var unapplied uint64
+		if st.Commit > st.Applied {
+			unapplied = st.Commit - st.Applied
+		}
+		sb.WriteString(fmt.Sprintf(raftStatusTablePattern+"\n", result.addr, st.NodeID, st.State, peerAddr(st.Leader),
+			st.Term, st.Index, st.Commit, st.Applied, unapplied, st.PendQueue, status, ""))
+		if st.Leader != 0 && st.Leader == st.NodeID {
+			leader = result
+		}
+	}
+	if leader == nil {
+		sb.WriteString("\nNo replica reports itself as the leader\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("\nReplication progress on leader %v:\n", leader.addr))
+	sb.WriteString(fmt.Sprintf("%v\n", raftReplicaTableHeader))
+	for _, id := range sortedReplicaIDs(leader.status.Replicas) {
+		replica := leader.status.Replicas[id]
+		lastActive := "N/A"
+		if !replica.LastActive.IsZero() {
+			lastActive = formatTimeToString(replica.LastActive)
+		}
+		sb.WriteString(fmt.Sprintf(raftReplicaTablePattern+"\n", peerAddr(id), id, replica.Match, replica.Commit,
+			replica.Next, replica.State, formatYesNo(replica.Active), formatYesNo(replica.Paused), replica.Inflight,
+			formatYesNo(replica.Snapshoting), lastActive))
+	}
+	return sb.String()
+}
+
+var (
+	trashEntryTablePattern = "%-24v    %-19v    %-10v    %-12v    %v"
+	trashEntryTableHeader  = fmt.Sprintf(trashEntryTablePattern, "NAME", "DELETE TIME", "SIZE", "PARENT INODE", "ORIGINAL NAME")
+)
+
+func formatTrashEntryTableRow(entry *meta.TrashEntry) string {
+	return fmt.Sprintf(trashEntryTablePattern, entry.Name, formatTimeToString(entry.DeleteTime), formatSize(entry.Size),
+		entry.ParentID, entry.OrigName)
+}
+
+var (
+	volPermissionGrantTablePattern = "%-20v    %-6v    %-20v    %-32v    %v"
+	volPermissionGrantTableHeader  = fmt.Sprintf(volPermissionGrantTablePattern, "PRINCIPAL", "TYPE", "ACCESS KEY", "PERMISSION", "FLAGS")
+)
+
+func formatVolPermissionGrantTableRow(grant *volPermissionGrant) string {
+	return fmt.Sprintf(volPermissionGrantTablePattern,
+		grant.principal, grant.kind, grant.accessKey, grant.permission, strings.Join(grant.flags, ","))
+}
+
+func formatClientConfig(conf *proto.ClientConfig) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Read rate            : %v\n", formatQosLimit(uint64(conf.ReadRate), "")))
+	sb.WriteString(fmt.Sprintf("  Write rate           : %v\n", formatQosLimit(uint64(conf.WriteRate), "")))
+	sb.WriteString(fmt.Sprintf("  Inode cache timeout  : %v\n", time.Duration(conf.IcacheTimeout)*time.Second))
+	sb.WriteString(fmt.Sprintf("  Inode cache size     : %v\n", conf.IcacheSize))
+	sb.WriteString(fmt.Sprintf("  Log level            : %v\n", conf.LogLevel))
+	sb.WriteString(fmt.Sprintf("  Follower read        : %v\n", formatEnabledDisabled(conf.FollowerRead)))
+	return sb.String()
+}
+
+func formatClientStat(stat *proto.ClientStat) string {
+	var sb = strings.Builder{}
+	sb.WriteString("  Inode cache:\n")
+	sb.WriteString(fmt.Sprintf("    Inodes             : %v/%v\n", stat.InodeCache.Inodes, stat.InodeCache.MaxInodes))
+	sb.WriteString(fmt.Sprintf("    Evictions          : %v\n", stat.InodeCache.Evictions))
+	sb.WriteString("  Streams:\n")
+	sb.WriteString(fmt.Sprintf("    Open streams       : %v\n", stat.Streams.OpenStreams))
+	sb.WriteString(fmt.Sprintf("    Dirty streams      : %v\n", stat.Streams.DirtyStreams))
+	sb.WriteString(fmt.Sprintf("    Dirty handlers     : %v\n", stat.Streams.DirtyHandlers))
+	sb.WriteString(fmt.Sprintf("    Inflight packets   : %v\n", stat.Streams.InflightPackets))
+	sb.WriteString(fmt.Sprintf("    Flushes            : %v\n", stat.Streams.Flushes))
+	sb.WriteString(fmt.Sprintf("    Flush errors       : %v\n", stat.Streams.FlushErrors))
+	sb.WriteString("    Flush latency      :\n")
+	for _, bucket := range stat.Streams.FlushLatency {
+		sb.WriteString(fmt.Sprintf("      <= %-8v      : %v\n", bucket.Bound, bucket.Count))
+	}
+	sb.WriteString(fmt.Sprintf("    Read-ahead hit     : %v\n", formatSize(stat.Streams.ReadAheadHits)))
+	sb.WriteString("  Read cache:\n")
+	sb.WriteString(fmt.Sprintf("    Status             : %v\n", formatEnabledDisabled(stat.ReadCache.Enabled)))
+	if stat.ReadCache.Enabled {
+		sb.WriteString(fmt.Sprintf("    Size               : %v/%v\n", formatSize(uint64(stat.ReadCache.Size)), formatSize(uint64(stat.ReadCache.Capacity))))
+	}
+	sb.WriteString(fmt.Sprintf("    Hit                : %v\n", formatSize(stat.ReadCache.HitBytes)))
+	sb.WriteString(fmt.Sprintf("    Miss               : %v\n", formatSize(stat.ReadCache.MissBytes)))
+	return sb.String()
+}
+
+var (
+	dataNodeDrainTablePattern = "%-21v    %-12v    %-19v    %-15v    %v"
+	dataNodeDrainTableHeader  = fmt.Sprintf(dataNodeDrainTablePattern, "ADDRESS", "PHASE", "START TIME", "INFLIGHT WRITES", "RAFT LEADERS")
+)
+
+func formatDataNodeDrainTableRow(addr string, status *proto.DataNodeDrainStatus) string {
+	var phase, startTime = status.Phase, "-"
+	if phase == "" {
+		phase = "-"
+	}
+	if status.StartTime != 0 {
+		startTime = formatTime(status.StartTime)
+	}
+	return fmt.Sprintf(dataNodeDrainTablePattern, addr, phase, startTime, status.InflightWrites, status.RaftLeaders)
+}
+
+var (
+	diskTablePattern = "%-32v    %-12v    %-10v    %-21v    %-10v    %-10v    %-8v    %-8v    %v"
+	diskTableHeader  = fmt.Sprintf(diskTablePattern, "PATH", "STATUS", "PARTITIONS", "USED/TOTAL", "READ ERRS", "WRITE ERRS", "MAX ERRS", "SMART", "OFFLINE")
+)
+
+// formatDiskTableRow formats a disk of a data node, the failing disks are the ones which the master warns of.
+func formatDiskTableRow(disk *proto.DiskInfo, failing bool) string {
+	var offline = "-"
+	if disk.OfflineTime != 0 {
+		offline = fmt.Sprintf("%v: %v", formatTime(disk.OfflineTime), disk.OfflineReason)
+	}
+	return fmt.Sprintf(diskTablePattern, disk.Path, formatDataPartitionStatus(int8(disk.Status)), disk.Partitions,
+		fmt.Sprintf("%v/%v", formatSize(disk.Used), formatSize(disk.Total)),
+		disk.ReadErrCnt, disk.WriteErrCnt, disk.MaxErrCnt, formatDiskSmart(disk.Smart, failing), offline)
+}
+
+func formatDiskSmart(smart *proto.DiskSmart, failing bool) string {
+	switch {
+	case failing || smart != nil && smart.PredictedFailure:
+		return "WARNING"
+	case smart == nil || smart.Err != "":
+		return "-"
+	default:
+		return "OK"
+	}
+}
@@ -25,14 +25,6 @@ func NewVolumeClient(opCode MasterOp, client *master.MasterClient) (vol *volumeC
 
 func (vol *volumeClient) excuteHttp() (err error) {
 	switch vol.opCode {
-	case OpExpandVol:
-		var vv *proto.SimpleVolView
-		if vv, err = vol.client.AdminAPI().GetVolumeSimpleInfo(vol.name); err != nil {
-			return
-		}
-		if err = vol.client.AdminAPI().VolExpand(vol.name, vol.capacity, calcAuthKey(vv.Owner)); err != nil {
-			return
-		}
 	case OpShrinkVol:
 		var vv *proto.SimpleVolView
 		if vv, err = vol.client.AdminAPI().GetVolumeSimpleInfo(vol.name); err != nil {
@@ -15,6 +15,9 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 
@@ -26,6 +29,8 @@ import (
 const (
 	cmdMetaNodeUse   = "metanode [COMMAND]"
 	cmdMetaNodeShort = "Manage meta nodes"
+
+	metaNodeMemUsagePath = "/getMemUsage"
 )
 
 func newMetaNodeCmd(client *master.MasterClient) *cobra.Command {
@@ -37,6 +42,8 @@ func newMetaNodeCmd(client *master.MasterClient) *cobra.Command {
 		newMetaNodeListCmd(client),
 		newMetaNodeInfoCmd(client),
 		newMetaNodeDecommissionCmd(client),
+		newMetaNodeConfigCmd(client),
+		newMetaNodeMaintenanceCmd(client),
 	)
 	return cmd
 }
@@ -89,6 +96,8 @@ func newMetaNodeListCmd(client *master.MasterClient) *cobra.Command {
 }
 
 func newMetaNodeInfoCmd(client *master.MasterClient) *cobra.Command {
+	var optMetaPort string
+	var optLimit int
 	var cmd = &cobra.Command{
 		Use:   CliOpInfo + " [NODE ADDRESS]",
 		Short: cmdMetaNodeInfoShort,
@@ -109,6 +118,21 @@ func newMetaNodeInfoCmd(client *master.MasterClient) *cobra.Command {
 			stdout("[Meta node info]\n")
 			stdout(formatMetaNodeDetail(metanodeInfo, false))
 
+			// the memory taken by the partitions is estimated by the meta node itself
+			usage, e := requestMetaNodeMemUsage(nodeAddr, optMetaPort)
+			if e != nil {
+				stdout("Failed to get the memory usage of the partitions: %v\n", e)
+				return
+			}
+			stdout("\n[Memory usage]\n")
+			stdout(formatMetaNodeMemUsage(usage))
+			stdout("\n%v\n", metaPartitionMemTableHeader)
+			for i, partition := range usage.Partitions {
+				if optLimit > 0 && i >= optLimit {
+					break
+				}
+				stdout("%v\n", formatMetaPartitionMemTableRow(partition))
+			}
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) != 0 {
@@ -117,8 +141,31 @@ func newMetaNodeInfoCmd(client *master.MasterClient) *cobra.Command {
 			return validMetaNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 	}
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta node")
+	cmd.Flags().IntVar(&optLimit, CliFlagLimit, 20, "Maximum number of the partitions taking most memory to list, 0 for all")
 	return cmd
 }
+
+func requestMetaNodeMemUsage(nodeAddr, port string) (usage *proto.MetaNodeMemUsage, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v%v", profAddr(nodeAddr, port), metaNodeMemUsagePath)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                   `json:"code"`
+		Msg  string                  `json:"msg"`
+		Data *proto.MetaNodeMemUsage `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode memory usage: %v", err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("request memory usage: %v", body.Msg)
+	}
+	return body.Data, nil
+}
+
 func newMetaNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   CliOpDecommission + " [NODE ADDRESS]",
@@ -39,6 +39,9 @@ func newMetaPartitionCmd(client *master.MasterClient) *cobra.Command {
 		newMetaPartitionDecommissionCmd(client),
 		newMetaPartitionReplicateCmd(client),
 		newMetaPartitionDeleteReplicaCmd(client),
+		newMetaPartitionRaftStatusCmd(client),
+		newMetaPartitionExportCmd(client),
+		newMetaPartitionImportCmd(client),
 	)
 	return cmd
 }
@@ -0,0 +1,306 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdMetaPartitionExportUse   = "export [PARTITION ID] [FILE]"
+	cmdMetaPartitionExportShort = "Export the inodes and dentries of a meta partition to a file"
+	cmdMetaPartitionImportUse   = "import [PARTITION ID] [FILE]"
+	cmdMetaPartitionImportShort = "Import an export file into an empty meta partition"
+
+	metaNodeExportPath = "/exportPartition?pid=%v"
+	metaNodeImportPath = "/importPartition?pid=%v"
+)
+
+func newMetaPartitionExportCmd(client *master.MasterClient) *cobra.Command {
+	var optMetaPort string
+	var cmd = &cobra.Command{
+		Use:   cmdMetaPartitionExportUse,
+		Short: cmdMetaPartitionExportShort,
+		Long: `Export the inodes, the dentries, the extended attributes and the multipart uploads of a meta
+partition to a gzip archive, which is verified after it is downloaded. The leader is asked
+first, and the followers are asked in turn if the leader fails. The archive is loaded into
+a rebuilt partition by "metapartition import".`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.MetaPartitionInfo
+				header      *proto.MetaPartitionExport
+				file        = args[1]
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if partition, err = client.ClientAPI().GetMetaPartition(partitionID); err != nil {
+				return
+			}
+			for _, host := range leaderFirstMetaHosts(partition) {
+				if header, err = exportMetaPartition(host, optMetaPort, partitionID, file); err == nil {
+					stdout("Meta partition %v is exported from %v to %v\n", partitionID, host, file)
+					break
+				}
+				stdout("Export meta partition %v from %v failed: %v\n", partitionID, host, err)
+			}
+			if err != nil {
+				return
+			}
+			stdout("  Volume     : %v\n", header.VolName)
+			stdout("  Range      : [%v, %v]\n", header.Start, header.End)
+			stdout("  Cursor     : %v\n", header.Cursor)
+			stdout("  Apply ID   : %v\n", header.ApplyID)
+			stdout("  Inodes     : %v\n", header.Inodes)
+			stdout("  Dentries   : %v\n", header.Dentries)
+			stdout("  Extends    : %v\n", header.Extends)
+			stdout("  Multiparts : %v\n", header.Multiparts)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta nodes")
+	return cmd
+}
+
+func newMetaPartitionImportCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optMetaPort string
+		optYes      bool
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdMetaPartitionImportUse,
+		Short: cmdMetaPartitionImportShort,
+		Long: `Import an archive made by "metapartition export" into a meta partition, e.g. a partition
+rebuilt after all its replicas are lost. The archive is verified before it is sent to the
+leader of the partition, which refuses the import unless the partition holds nothing but
+the root inode, or any inode of the archive is out of the range of the partition. The
+items are replicated to the followers through raft.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.MetaPartitionInfo
+				header      *proto.MetaPartitionExport
+				imported    *proto.MetaPartitionExport
+				leader      string
+				file        = args[1]
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if header, err = verifyMetaPartitionExport(file); err != nil {
+				return
+			}
+			if partition, err = client.ClientAPI().GetMetaPartition(partitionID); err != nil {
+				return
+			}
+			for _, replica := range partition.Replicas {
+				if replica.IsLeader {
+					leader = replica.Addr
+				}
+			}
+			if leader == "" {
+				err = fmt.Errorf("meta partition %v has no leader", partitionID)
+				return
+			}
+			stdout("Import %v into meta partition [%v] of volume [%v] range [%v, %v] led by %v\n",
+				file, partitionID, partition.VolName, partition.Start, partition.End, leader)
+			stdout("  Exported from : meta partition [%v] of volume [%v] range [%v, %v] at %v\n",
+				header.PartitionID, header.VolName, header.Start, header.End,
+				time.Unix(header.CreateTime, 0).Format("2006-01-02 15:04:05"))
+			stdout("  Items         : inodes(%v) dentries(%v) extends(%v) multiparts(%v)\n",
+				header.Inodes, header.Dentries, header.Extends, header.Multiparts)
+			if header.VolName != partition.VolName {
+				stdout("The archive is exported from another volume.\n")
+			}
+			if !optYes {
+				stdout("Type the meta partition ID to confirm the import: ")
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != args[0] {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			if imported, err = importMetaPartition(leader, optMetaPort, partitionID, file); err != nil {
+				return
+			}
+			stdout("Meta partition %v is imported from %v\n", partitionID, file)
+			stdout("  Cursor     : %v\n", imported.Cursor)
+			stdout("  Inodes     : %v\n", imported.Inodes)
+			stdout("  Dentries   : %v\n", imported.Dentries)
+			stdout("  Extends    : %v\n", imported.Extends)
+			stdout("  Multiparts : %v\n", imported.Multiparts)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta nodes")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+// leaderFirstMetaHosts returns the addresses of the replicas of the meta partition with the leader first.
+func leaderFirstMetaHosts(partition *proto.MetaPartitionInfo) []string {
+	hosts := make([]string, 0, len(partition.Replicas))
+	for _, replica := range partition.Replicas {
+		if replica.IsLeader {
+			hosts = append([]string{replica.Addr}, hosts...)
+		} else {
+			hosts = append(hosts, replica.Addr)
+		}
+	}
+	return hosts
+}
+
+// exportMetaPartition downloads the export to a temporary file next to the file, which replaces the file once it is
+// verified.
+func exportMetaPartition(nodeAddr, port string, partitionID uint64, file string) (header *proto.MetaPartitionExport, err error) {
+	tmp, err := ioutil.TempFile(path.Dir(file), ".mp-export-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	err = downloadMetaPartitionExport(nodeAddr, port, partitionID, tmp)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	if header, err = verifyMetaPartitionExport(tmp.Name()); err != nil {
+		return
+	}
+	if header.PartitionID != partitionID {
+		return nil, fmt.Errorf("the export of meta partition %v is received", header.PartitionID)
+	}
+	err = os.Rename(tmp.Name(), file)
+	return
+}
+
+func downloadMetaPartitionExport(nodeAddr, port string, partitionID uint64, w io.Writer) (err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v"+metaNodeExportPath, profAddr(nodeAddr, port), partitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if strings.HasPrefix(resp.Header.Get("content-type"), "application/gzip") {
+		_, err = io.Copy(w, resp.Body)
+		return
+	}
+	body := &struct {
+		Code int32  `json:"code"`
+		Msg  string `json:"msg"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return fmt.Errorf("decode export: %v", err)
+	}
+	return fmt.Errorf("request export: %v", body.Msg)
+}
+
+func importMetaPartition(nodeAddr, port string, partitionID uint64, file string) (imported *proto.MetaPartitionExport, err error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	var resp *http.Response
+	reqURL := fmt.Sprintf("http://%v"+metaNodeImportPath, profAddr(nodeAddr, port), partitionID)
+	if resp, err = http.Post(reqURL, "application/gzip", fp); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32                      `json:"code"`
+		Msg  string                     `json:"msg"`
+		Data *proto.MetaPartitionExport `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode import result: %v", err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		if body.Data != nil {
+			stdout("Imported before the failure: inodes(%v) dentries(%v) extends(%v) multiparts(%v)\n",
+				body.Data.Inodes, body.Data.Dentries, body.Data.Extends, body.Data.Multiparts)
+		}
+		return nil, fmt.Errorf("request import: %v", body.Msg)
+	}
+	return body.Data, nil
+}
+
+// verifyMetaPartitionExport reads through the archive and counts the items of every kind against the header.
+func verifyMetaPartitionExport(file string) (header *proto.MetaPartitionExport, err error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	gr, err := gzip.NewReader(fp)
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+	decoder := json.NewDecoder(gr)
+	header = &proto.MetaPartitionExport{}
+	if err = decoder.Decode(header); err != nil {
+		return nil, fmt.Errorf("decode the header of the export: %v", err)
+	}
+	var items uint64
+	for {
+		var item json.RawMessage
+		if err = decoder.Decode(&item); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode the item %v of the export: %v", items, err)
+		}
+		items++
+	}
+	if total := header.Inodes + header.Dentries + header.Extends + header.Multiparts; items != total {
+		return nil, fmt.Errorf("the export is incomplete, %v of %v items are found", items, total)
+	}
+	return header, nil
+}
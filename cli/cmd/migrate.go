@@ -0,0 +1,531 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+const (
+	cmdMigrateUse   = "migrate [SRC_CLUSTER/VOLUME] [DST_CLUSTER/VOLUME]"
+	cmdMigrateShort = "Copy the files of a volume to another volume of the same or another cluster"
+
+	migrateChunkSize          = 1024 * 1024
+	migrateCheckpointInterval = 10 * time.Second
+	migrateCheckpointVersion  = 1
+)
+
+func newMigrateCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optWorkers    int
+		optBandwidth  int
+		optCheckpoint string
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdMigrateUse,
+		Short: cmdMigrateShort,
+		Long: `Copy the directories, files, symlinks and hard links of a volume with their attributes and
+extended attributes to another volume. A cluster is given by its master addresses separated by
+commas, e.g. 192.168.0.1:17010,192.168.0.2:17010/vol, and the cluster of the CLI is used if only
+the volume is given. The files are copied by parallel workers, and the entries migrated are
+recorded in the checkpoint file, so an interrupted migration is resumed by running the command
+again, which skips the entries unchanged since they were migrated. The entries removed from the
+source volume are not removed from the destination volume.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err        error
+				src        *backupVolume
+				dst        *backupVolume
+				srcVol     string
+				dstVol     string
+				srcClient  *master.MasterClient
+				dstClient  *master.MasterClient
+				checkpoint *migrateCheckpoint
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if srcClient, srcVol, err = parseMigrateVolume(client, args[0]); err != nil {
+				return
+			}
+			if dstClient, dstVol, err = parseMigrateVolume(client, args[1]); err != nil {
+				return
+			}
+			if srcVol == dstVol && strings.Join(srcClient.Nodes(), ",") == strings.Join(dstClient.Nodes(), ",") {
+				err = fmt.Errorf("the source and the destination are the same volume")
+				return
+			}
+			if optWorkers <= 0 {
+				err = fmt.Errorf("invalid workers: %v", optWorkers)
+				return
+			}
+			if optCheckpoint == "" {
+				optCheckpoint = fmt.Sprintf("cfs-migrate-%v-%v.checkpoint", srcVol, dstVol)
+			}
+			if src, err = openBackupVolume(srcClient, srcVol); err != nil {
+				return
+			}
+			defer src.close()
+			if dst, err = openBackupVolume(dstClient, dstVol); err != nil {
+				return
+			}
+			defer dst.close()
+			header := &migrateCheckpointHeader{
+				Version: migrateCheckpointVersion,
+				Src:     path.Join(src.mw.Cluster(), srcVol),
+				Dst:     path.Join(dst.mw.Cluster(), dstVol),
+			}
+			if checkpoint, err = openMigrateCheckpoint(optCheckpoint, header); err != nil {
+				return
+			}
+			defer checkpoint.close()
+			m := &migrator{
+				src:        src,
+				dst:        dst,
+				checkpoint: checkpoint,
+				links:      make(map[uint64]uint64),
+				tasks:      make(chan *migrateTask, optWorkers),
+			}
+			if optBandwidth > 0 {
+				m.limiter = rate.NewLimiter(rate.Limit(optBandwidth*1024*1024), migrateChunkSize)
+			}
+			stdout("Migrate %v to %v, checkpoint %v\n", header.Src, header.Dst, optCheckpoint)
+			if err = m.run(optWorkers); err != nil {
+				return
+			}
+			m.printProgress()
+			if m.failed > 0 {
+				stdout("%v entries failed to be migrated, run the command again to retry them\n", m.failed)
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().IntVar(&optWorkers, "workers", 8, "Specify the number of files copied in parallel")
+	cmd.Flags().IntVar(&optBandwidth, "bw-limit", 0, "Specify the bandwidth limit of copying data in MB/s, 0 for no limit")
+	cmd.Flags().StringVar(&optCheckpoint, "checkpoint", "", "Specify the checkpoint file, cfs-migrate-[SRC]-[DST].checkpoint in the current directory by default")
+	return cmd
+}
+
+// parseMigrateVolume parses "masters/volume" into the client of the masters and the volume, or the volume of the
+// cluster of the client if no masters are given.
+func parseMigrateVolume(client *master.MasterClient, arg string) (*master.MasterClient, string, error) {
+	i := strings.LastIndex(arg, "/")
+	if i < 0 {
+		return client, arg, nil
+	}
+	vol := arg[i+1:]
+	c := master.NewMasterClientFromString(arg[:i], false)
+	if vol == "" || len(c.Nodes()) == 0 {
+		return nil, "", fmt.Errorf("invalid volume: %v, expected [MASTER ADDRESSES/]VOLUME", arg)
+	}
+	return c, vol, nil
+}
+
+type migrateCheckpointHeader struct {
+	Version int    `json:"version"`
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+}
+
+// migrateCheckpointEntry is an entry migrated, which is skipped by a later migration if the source entry is not
+// changed since then.
+type migrateCheckpointEntry struct {
+	Path       string `json:"path"`
+	Inode      uint64 `json:"ino"`
+	Size       uint64 `json:"size"`
+	ModifyTime int64  `json:"mtime"`
+}
+
+// migrateCheckpoint is a file of json lines, the header followed by the entries migrated, which are appended and
+// flushed periodically during the migration.
+type migrateCheckpoint struct {
+	sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	done map[string]*migrateCheckpointEntry
+}
+
+func openMigrateCheckpoint(name string, header *migrateCheckpointHeader) (c *migrateCheckpoint, err error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+		}
+	}()
+	c = &migrateCheckpoint{file: file, done: make(map[string]*migrateCheckpointEntry)}
+	c.w = bufio.NewWriter(file)
+	c.enc = json.NewEncoder(c.w)
+	r := bufio.NewReader(file)
+	line, err := r.ReadBytes('\n')
+	if err == io.EOF && len(line) == 0 {
+		if err = c.enc.Encode(header); err != nil {
+			return
+		}
+		return c, c.w.Flush()
+	}
+	prev := &migrateCheckpointHeader{}
+	if err = json.Unmarshal(line, prev); err != nil {
+		return nil, fmt.Errorf("decode the header of checkpoint %v: %v", name, err)
+	}
+	if *prev != *header {
+		return nil, fmt.Errorf("checkpoint %v is of the migration from %v to %v", name, prev.Src, prev.Dst)
+	}
+	for {
+		if line, err = r.ReadBytes('\n'); err != nil && err != io.EOF {
+			return
+		}
+		entry := &migrateCheckpointEntry{}
+		// the line partially written by an interrupted migration is ignored
+		if json.Unmarshal(line, entry) == nil {
+			c.done[entry.Path] = entry
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	// start a new line in case the last one is partially written
+	if _, err = c.w.WriteString("\n"); err != nil {
+		return
+	}
+	return c, nil
+}
+
+// isDone returns whether the entry is migrated and not changed since then.
+func (c *migrateCheckpoint) isDone(p string, info *proto.InodeInfo) bool {
+	entry, ok := c.done[p]
+	return ok && entry.Inode == info.Inode && entry.Size == info.Size && entry.ModifyTime == info.ModifyTime.Unix()
+}
+
+func (c *migrateCheckpoint) add(p string, info *proto.InodeInfo) error {
+	c.Lock()
+	defer c.Unlock()
+	return c.enc.Encode(&migrateCheckpointEntry{
+		Path:       p,
+		Inode:      info.Inode,
+		Size:       info.Size,
+		ModifyTime: info.ModifyTime.Unix(),
+	})
+}
+
+func (c *migrateCheckpoint) flush() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.w.Flush()
+}
+
+func (c *migrateCheckpoint) close() {
+	c.flush()
+	c.file.Close()
+}
+
+type migrateTask struct {
+	path     string
+	info     *proto.InodeInfo
+	ino      uint64 // inode of the destination file
+	truncate bool   // the destination file exists, e.g. partially copied by an interrupted migration
+}
+
+type migrateDir struct {
+	ino  uint64
+	info *proto.InodeInfo
+}
+
+type migrator struct {
+	src        *backupVolume
+	dst        *backupVolume
+	checkpoint *migrateCheckpoint
+	limiter    *rate.Limiter
+	links      map[uint64]uint64 // inode of the source hard links to the destination inode
+	dirs       []*migrateDir
+	tasks      chan *migrateTask
+	wg         sync.WaitGroup
+	entries    uint64
+	files      uint64
+	bytes      uint64
+	skipped    uint64 // unchanged since the checkpoint
+	failed     uint64
+}
+
+func (m *migrator) run(workers int) (err error) {
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	stopC := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(migrateCheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopC:
+				return
+			case <-ticker.C:
+				if err := m.checkpoint.flush(); err != nil {
+					stdout("Flush checkpoint: %v\n", err)
+				}
+				m.printProgress()
+			}
+		}
+	}()
+	var root *proto.InodeInfo
+	if root, err = m.src.mw.InodeGet_ll(proto.RootIno); err == nil {
+		m.dirs = append(m.dirs, &migrateDir{ino: proto.RootIno, info: root})
+		err = m.walk("/", root, proto.RootIno)
+	}
+	close(m.tasks)
+	m.wg.Wait()
+	close(stopC)
+	if err != nil {
+		return
+	}
+	// the times of directories are changed by their children, so they are set at last
+	for i := len(m.dirs) - 1; i >= 0; i-- {
+		if err = m.setattr(m.dirs[i].ino, m.dirs[i].info); err != nil {
+			return fmt.Errorf("set attributes of directory %v: %v", m.dirs[i].info.Inode, err)
+		}
+	}
+	return m.checkpoint.flush()
+}
+
+func (m *migrator) printProgress() {
+	stdout("Entries: %v, copied files: %v (%v), unchanged: %v, failed: %v\n", atomic.LoadUint64(&m.entries),
+		atomic.LoadUint64(&m.files), formatSize(atomic.LoadUint64(&m.bytes)), atomic.LoadUint64(&m.skipped),
+		atomic.LoadUint64(&m.failed))
+}
+
+func (m *migrator) fail(p string, err error) {
+	stdout("Skip %v: %v\n", p, err)
+	atomic.AddUint64(&m.failed, 1)
+}
+
+// walk creates the children of the directory in the destination, and dispatches the files to the workers.
+func (m *migrator) walk(p string, info *proto.InodeInfo, dir uint64) (err error) {
+	if err = m.dst.setxattrs(dir, m.xattrs(p, info.Inode)); err != nil {
+		return fmt.Errorf("set xattrs of %v: %v", p, err)
+	}
+	var dentries []proto.Dentry
+	if dentries, err = m.src.mw.ReadDir_ll(info.Inode); err != nil {
+		return fmt.Errorf("read dir %v: %v", p, err)
+	}
+	for _, dentry := range dentries {
+		childPath := path.Join(p, dentry.Name)
+		if p == "/" && dentry.Name == proto.TrashDirName {
+			continue
+		}
+		child, getErr := m.src.mw.InodeGet_ll(dentry.Inode)
+		if getErr != nil {
+			// removed during the migration
+			m.fail(childPath, getErr)
+			continue
+		}
+		atomic.AddUint64(&m.entries, 1)
+		var ino uint64
+		if ino, err = m.create(childPath, child, dir, dentry.Name); err != nil {
+			return fmt.Errorf("create %v: %v", childPath, err)
+		}
+		if proto.IsDir(child.Mode) {
+			m.dirs = append(m.dirs, &migrateDir{ino: ino, info: child})
+			if err = m.walk(childPath, child, ino); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// create creates the entry in the destination unless it exists, and returns the destination inode.
+func (m *migrator) create(p string, info *proto.InodeInfo, parent uint64, name string) (ino uint64, err error) {
+	var mode uint32
+	exist := true
+	if ino, mode, err = m.dst.mw.Lookup_ll(parent, name); err == syscall.ENOENT {
+		exist = false
+	} else if err != nil {
+		return
+	}
+	if exist && proto.OsModeType(mode) != proto.OsModeType(info.Mode) {
+		return 0, fmt.Errorf("%v exists in the destination with another type", p)
+	}
+	switch {
+	case proto.IsDir(info.Mode):
+		if !exist {
+			var dstInfo *proto.InodeInfo
+			if dstInfo, err = m.dst.mw.Create_ll(parent, name, info.Mode, info.Uid, info.Gid, nil); err != nil {
+				return
+			}
+			ino = dstInfo.Inode
+		}
+		return ino, nil
+	case proto.IsSymlink(info.Mode):
+		if exist && m.checkpoint.isDone(p, info) {
+			atomic.AddUint64(&m.skipped, 1)
+			return
+		}
+		if exist {
+			if _, err = m.dst.mw.Delete_ll(parent, name, false); err != nil {
+				return
+			}
+		}
+		var dstInfo *proto.InodeInfo
+		if dstInfo, err = m.dst.mw.Create_ll(parent, name, info.Mode, info.Uid, info.Gid, info.Target); err != nil {
+			return
+		}
+		if err = m.dst.setxattrs(dstInfo.Inode, m.xattrs(p, info.Inode)); err != nil {
+			return
+		}
+		if err = m.setattr(dstInfo.Inode, info); err != nil {
+			return
+		}
+		return dstInfo.Inode, m.checkpoint.add(p, info)
+	}
+	if target, ok := m.links[info.Inode]; ok {
+		if !exist {
+			if _, err = m.dst.mw.Link(parent, name, target); err != nil {
+				return
+			}
+		}
+		atomic.AddUint64(&m.skipped, 1)
+		return target, nil
+	}
+	if exist && m.checkpoint.isDone(p, info) {
+		if info.Nlink > 1 {
+			m.links[info.Inode] = ino
+		}
+		atomic.AddUint64(&m.skipped, 1)
+		return
+	}
+	if !exist {
+		var dstInfo *proto.InodeInfo
+		if dstInfo, err = m.dst.mw.Create_ll(parent, name, info.Mode, info.Uid, info.Gid, nil); err != nil {
+			return
+		}
+		ino = dstInfo.Inode
+	}
+	if info.Nlink > 1 {
+		m.links[info.Inode] = ino
+	}
+	m.tasks <- &migrateTask{path: p, info: info, ino: ino, truncate: exist}
+	return
+}
+
+func (m *migrator) worker() {
+	defer m.wg.Done()
+	buf := make([]byte, migrateChunkSize)
+	for task := range m.tasks {
+		if err := m.copyFile(task, buf); err != nil {
+			m.fail(task.path, err)
+			continue
+		}
+		if err := m.checkpoint.add(task.path, task.info); err != nil {
+			m.fail(task.path, err)
+			continue
+		}
+		atomic.AddUint64(&m.files, 1)
+	}
+}
+
+// copyFile copies the data, the extended attributes and the attributes of the file, the data is verified by the size.
+func (m *migrator) copyFile(task *migrateTask, buf []byte) (err error) {
+	if err = m.src.ec.OpenStream(task.info.Inode); err != nil {
+		return
+	}
+	defer func() {
+		m.src.ec.CloseStream(task.info.Inode)
+		m.src.ec.EvictStream(task.info.Inode)
+	}()
+	if err = m.dst.ec.OpenStream(task.ino); err != nil {
+		return
+	}
+	defer func() {
+		m.dst.ec.CloseStream(task.ino)
+		m.dst.ec.EvictStream(task.ino)
+	}()
+	if task.truncate {
+		if err = m.dst.ec.Truncate(task.ino, 0); err != nil {
+			return
+		}
+	}
+	r := &backupFileReader{ec: m.src.ec, ino: task.info.Inode, size: task.info.Size}
+	var offset int
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if m.limiter != nil {
+				if err = m.limiter.WaitN(context.Background(), n); err != nil {
+					return
+				}
+			}
+			if _, err = m.dst.ec.Write(task.ino, offset, buf[:n], 0); err != nil {
+				return
+			}
+			offset += n
+			atomic.AddUint64(&m.bytes, uint64(n))
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if err = m.dst.ec.Flush(task.ino); err != nil {
+		return
+	}
+	if err = m.dst.setxattrs(task.ino, m.xattrs(task.path, task.info.Inode)); err != nil {
+		return
+	}
+	return m.setattr(task.ino, task.info)
+}
+
+// xattrs returns the extended attributes of the source inode, which are skipped if they fail to be read.
+func (m *migrator) xattrs(p string, ino uint64) map[string]string {
+	xattrs, err := m.src.xattrs(ino)
+	if err != nil {
+		stdout("Skip the xattrs of %v: %v\n", p, err)
+	}
+	return xattrs
+}
+
+func (m *migrator) setattr(ino uint64, info *proto.InodeInfo) error {
+	valid := proto.AttrMode | proto.AttrUid | proto.AttrGid | proto.AttrModifyTime | proto.AttrAccessTime
+	return m.dst.mw.Setattr(ino, valid, info.Mode, info.Uid, info.Gid, info.AccessTime.Unix(), info.ModifyTime.Unix())
+}
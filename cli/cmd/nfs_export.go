@@ -0,0 +1,155 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdNFSExportUse   = "nfs-export [COMMAND]"
+	cmdNFSExportShort = "Manage the volumes exported by the NFS gateways"
+)
+
+func newNFSExportCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdNFSExportUse,
+		Short: cmdNFSExportShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newNFSExportSetCmd(client),
+		newNFSExportDeleteCmd(client),
+		newNFSExportListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdNFSExportSetShort    = "Export a volume through the NFS gateways"
+	cmdNFSExportDeleteShort = "Remove an NFS export"
+	cmdNFSExportListShort   = "List the NFS exports"
+)
+
+func newNFSExportSetCmd(client *sdk.MasterClient) *cobra.Command {
+	var (
+		optClients    []string
+		optReadOnly   bool
+		optRootSquash bool
+	)
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [PATH] [VOLUME]",
+		Short: cmdNFSExportSetShort,
+		Long: `Export a volume through the NFS gateways, the NFS clients mount the volume by PATH, such as
+"mount -t nfs -o vers=3,nolock gateway:/ltptest /mnt". An existing export of the same PATH is replaced.
+The clients allowed to mount the export are given as CIDRs by --clients, every client is allowed by default.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			export := &proto.NFSExport{
+				Path:       args[0],
+				Volume:     args[1],
+				Clients:    optClients,
+				ReadOnly:   optReadOnly,
+				RootSquash: optRootSquash,
+			}
+			if err = client.AdminAPI().SetNFSExport(export); err != nil {
+				return
+			}
+			stdout("Volume [%v] is exported as [%v].\n", export.Volume, export.Path)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 1 {
+				return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringSliceVar(&optClients, CliFlagClients, nil, "CIDRs of the clients allowed to mount the export")
+	cmd.Flags().BoolVar(&optReadOnly, CliFlagReadOnly, false, "Export the volume read-only")
+	cmd.Flags().BoolVar(&optRootSquash, CliFlagRootSquash, false, "Map the root user of the clients to the anonymous user")
+	return cmd
+}
+
+func newNFSExportDeleteCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpDelete + " [PATH]",
+		Short: cmdNFSExportDeleteShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().RemoveNFSExport(args[0]); err != nil {
+				return
+			}
+			stdout("NFS export [%v] is removed.\n", args[0])
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			exports, err := client.AdminAPI().ListNFSExports()
+			if err != nil {
+				errout("Error: %v", err)
+			}
+			paths := make([]string, 0, len(exports))
+			for _, export := range exports {
+				if strings.HasPrefix(export.Path, toComplete) {
+					paths = append(paths, export.Path)
+				}
+			}
+			return paths, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newNFSExportListCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdNFSExportListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var exports []*proto.NFSExport
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if exports, err = client.AdminAPI().ListNFSExports(); err != nil {
+				return
+			}
+			stdout("%v\n", nfsExportTableHeader)
+			for _, export := range exports {
+				stdout("%v\n", formatNFSExportTableRow(export))
+			}
+		},
+	}
+	return cmd
+}
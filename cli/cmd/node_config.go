@@ -0,0 +1,179 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdNodeConfigUse       = "config [COMMAND]"
+	cmdDataNodeConfigShort = "Show and tune the runtime parameters of a data node"
+	cmdMetaNodeConfigShort = "Show and tune the runtime parameters of a meta node"
+	cmdNodeConfigShowShort = "Show the runtime parameters of the node"
+	cmdNodeConfigSetShort  = "Set a runtime parameter of the node, use 'default' to follow the cluster setting again"
+)
+
+// nodeConfigAPI describes how the runtime parameters are served on the prof port of a kind of node.
+type nodeConfigAPI struct {
+	portFlag    string
+	defaultPort string
+	getPath     string
+	setPath     string
+	validNodes  func(client *master.MasterClient, toComplete string) []string
+}
+
+var (
+	dataNodeConfigAPI = &nodeConfigAPI{
+		portFlag:    CliFlagDataPort,
+		defaultPort: defaultDataNodeProfPort,
+		getPath:     "/config",
+		setPath:     "/setConfig",
+		validNodes:  validDataNodes,
+	}
+	metaNodeConfigAPI = &nodeConfigAPI{
+		portFlag:    CliFlagMetaPort,
+		defaultPort: defaultMetaNodeProfPort,
+		getPath:     "/getConfig",
+		setPath:     "/setConfig",
+		validNodes:  validMetaNodes,
+	}
+)
+
+func newDataNodeConfigCmd(client *master.MasterClient) *cobra.Command {
+	return newNodeConfigCmd(client, cmdDataNodeConfigShort, dataNodeConfigAPI)
+}
+
+func newMetaNodeConfigCmd(client *master.MasterClient) *cobra.Command {
+	return newNodeConfigCmd(client, cmdMetaNodeConfigShort, metaNodeConfigAPI)
+}
+
+func newNodeConfigCmd(client *master.MasterClient, short string, api *nodeConfigAPI) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdNodeConfigUse,
+		Short: short,
+	}
+	cmd.AddCommand(
+		newNodeConfigShowCmd(client, api),
+		newNodeConfigSetCmd(client, api),
+	)
+	return cmd
+}
+
+func newNodeConfigShowCmd(client *master.MasterClient, api *nodeConfigAPI) *cobra.Command {
+	var optPort string
+	var cmd = &cobra.Command{
+		Use:   CliOpShow + " [NODE ADDRESS]",
+		Short: cmdNodeConfigShowShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var configs []*proto.NodeConfig
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if configs, err = requestNodeConfig(args[0], optPort, api.getPath, nil); err != nil {
+				return
+			}
+			printNodeConfigs(configs)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return api.validNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optPort, api.portFlag, api.defaultPort, "Specify the prof port of the node")
+	return cmd
+}
+
+func newNodeConfigSetCmd(client *master.MasterClient, api *nodeConfigAPI) *cobra.Command {
+	var optPort string
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [NODE ADDRESS] [KEY] [VALUE]",
+		Short: cmdNodeConfigSetShort,
+		Long: `Set a runtime parameter of the node. The value takes effect immediately and
+takes precedence over the cluster-wide setting, but it is not persisted and is
+lost when the node restarts. Set the value to 'default' to follow the cluster-wide
+setting again.`,
+		Args: cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var configs []*proto.NodeConfig
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			params := url.Values{}
+			params.Set("key", args[1])
+			params.Set("value", args[2])
+			if configs, err = requestNodeConfig(args[0], optPort, api.setPath, params); err != nil {
+				return
+			}
+			stdout("Config [%v] has been set to [%v] on node [%v].\n", args[1], args[2], args[0])
+			printNodeConfigs(configs)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return api.validNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optPort, api.portFlag, api.defaultPort, "Specify the prof port of the node")
+	return cmd
+}
+
+func requestNodeConfig(nodeAddr, port, path string, params url.Values) (configs []*proto.NodeConfig, err error) {
+	reqURL := fmt.Sprintf("http://%v%v", profAddr(nodeAddr, port), path)
+	if len(params) != 0 {
+		reqURL += "?" + params.Encode()
+	}
+	var resp *http.Response
+	if resp, err = http.Get(reqURL); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32               `json:"code"`
+		Msg  string              `json:"msg"`
+		Data []*proto.NodeConfig `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode config of node %v: %v", nodeAddr, err)
+	}
+	if body.Code != http.StatusOK {
+		return nil, fmt.Errorf("request config of node %v: %v", nodeAddr, body.Msg)
+	}
+	return body.Data, nil
+}
+
+func printNodeConfigs(configs []*proto.NodeConfig) {
+	stdout("%v\n", nodeConfigTableHeader)
+	for _, config := range configs {
+		stdout("%v\n", formatNodeConfigTableRow(config))
+	}
+}
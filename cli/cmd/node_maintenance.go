@@ -0,0 +1,84 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdNodeMaintenanceUse       = "maintenance [on|off] [NODE ADDRESS]"
+	cmdDataNodeMaintenanceShort = "Turn the maintenance of a data node on or off for a planned outage"
+	cmdMetaNodeMaintenanceShort = "Turn the maintenance of a meta node on or off for a planned outage"
+
+	maintenanceOn  = "on"
+	maintenanceOff = "off"
+)
+
+func newDataNodeMaintenanceCmd(client *master.MasterClient) *cobra.Command {
+	return newNodeMaintenanceCmd(client, cmdDataNodeMaintenanceShort, client.NodeAPI().SetDataNodeMaintenance, validDataNodes)
+}
+
+func newMetaNodeMaintenanceCmd(client *master.MasterClient) *cobra.Command {
+	return newNodeMaintenanceCmd(client, cmdMetaNodeMaintenanceShort, client.NodeAPI().SetMetaNodeMaintenance, validMetaNodes)
+}
+
+func newNodeMaintenanceCmd(client *master.MasterClient, short string, setMaintenance func(string, bool) error,
+	validNodes func(*master.MasterClient, string) []string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdNodeMaintenanceUse,
+		Short: short,
+		Long: `Put a node in maintenance before a short planned outage, such as a reboot or an upgrade,
+and take it out once the node is back. No new partition is placed on a node in maintenance,
+and its missing replicas are neither alarmed nor counted as failures, so that they are not
+rebuilt on the other nodes. The maintenance lasts until it is turned off.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var enable bool
+			switch args[0] {
+			case maintenanceOn:
+				enable = true
+			case maintenanceOff:
+			default:
+				err = fmt.Errorf("expect %v or %v: %v", maintenanceOn, maintenanceOff, args[0])
+				return
+			}
+			nodeAddr := args[1]
+			if err = setMaintenance(nodeAddr, enable); err != nil {
+				return
+			}
+			stdout("Maintenance of node %v is turned %v.\n", nodeAddr, args[0])
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return []string{maintenanceOn, maintenanceOff}, cobra.ShellCompDirectiveNoFileComp
+			case 1:
+				return validNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
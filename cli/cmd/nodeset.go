@@ -0,0 +1,171 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdNodeSetUse   = "nodeset [COMMAND]"
+	cmdNodeSetShort = "Manage the node sets of the zones"
+)
+
+func newNodeSetCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdNodeSetUse,
+		Short: cmdNodeSetShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newNodeSetListCmd(client),
+		newNodeSetInfoCmd(client),
+		newNodeSetCreateCmd(client),
+		newNodeSetMigrateNodeCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdNodeSetListShort        = "List the node sets"
+	cmdNodeSetInfoShort        = "Show the nodes of a node set"
+	cmdNodeSetCreateShort      = "Create an empty node set in a zone"
+	cmdNodeSetMigrateNodeShort = "Move a data node or meta node to another node set of its zone"
+)
+
+func newNodeSetListCmd(client *sdk.MasterClient) *cobra.Command {
+	var optZone string
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdNodeSetListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				nodeSets []*proto.NodeSetInfo
+				err      error
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if nodeSets, err = client.AdminAPI().ListNodeSets(optZone); err != nil {
+				return
+			}
+			stdout("%v\n", nodeSetTableHeader)
+			for _, ns := range nodeSets {
+				stdout("%v\n", formatNodeSetTableRow(ns))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optZone, CliFlagZoneName, "", "List the node sets of the zone only")
+	return cmd
+}
+
+func newNodeSetInfoCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpInfo + " [NODESET ID]",
+		Short: cmdNodeSetInfoShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				id      uint64
+				nodeSet *proto.NodeSetInfo
+				err     error
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if id, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if nodeSet, err = client.AdminAPI().GetNodeSet(id); err != nil {
+				return
+			}
+			stdout("%v", formatNodeSetInfo(nodeSet))
+		},
+	}
+	return cmd
+}
+
+func newNodeSetCreateCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpCreate + " [ZONE]",
+		Short: cmdNodeSetCreateShort,
+		Long: `Create an empty node set in the zone. The new nodes of the zone join a node set which is not
+full, move the existing nodes into the new node set with "nodeset migrate-node".`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				nodeSet *proto.NodeSetInfo
+				err     error
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if nodeSet, err = client.AdminAPI().CreateNodeSet(args[0]); err != nil {
+				return
+			}
+			stdout("Node set [%v] is created in zone [%v] with capacity %v.\n", nodeSet.ID, nodeSet.ZoneName, nodeSet.Capacity)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validZones(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newNodeSetMigrateNodeCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "migrate-node [ADDRESS] [NODESET ID]",
+		Short: cmdNodeSetMigrateNodeShort,
+		Long: `Move a data node or meta node to another node set of its zone, which must not be full. The replicas of
+a partition are placed on the nodes of one node set, so only the partitions placed afterwards follow the new node
+set. The replicas already on the node stay in their raft groups until they are decommissioned.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				id  uint64
+				err error
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if id, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				err = fmt.Errorf("parse node set id [%v] failed: %v", args[1], err)
+				return
+			}
+			if err = client.AdminAPI().MigrateNodeToNodeSet(args[0], id); err != nil {
+				return
+			}
+			stdout("Node [%v] is moved to node set [%v].\n", args[0], id)
+		},
+	}
+	return cmd
+}
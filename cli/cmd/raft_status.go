@@ -0,0 +1,158 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+	"github.com/tiglabs/raft"
+)
+
+const (
+	cmdRaftStatusUse                = "raft-status [PARTITION ID]"
+	cmdDataPartitionRaftStatusShort = "Show the raft status of the replicas of a data partition"
+	cmdMetaPartitionRaftStatusShort = "Show the raft status of the replicas of a meta partition"
+	cmdRaftStatusLong               = `Show the raft status reported by every replica of the partition: the term, the leader,
+the last, committed and applied indexes, and the proposals pending. The replication
+progress of the followers and the last time they were active are shown as seen by the
+leader.`
+	dataNodeRaftStatusPath = "/raftStatus?raftID=%v"
+	metaNodeRaftStatusPath = "/getRaftStatus?pid=%v"
+)
+
+// replicaRaftStatus is the raft status reported by a replica of a partition.
+type replicaRaftStatus struct {
+	addr   string
+	status *raft.Status
+	err    error
+}
+
+func newDataPartitionRaftStatusCmd(client *master.MasterClient) *cobra.Command {
+	var optDataPort string
+	var cmd = &cobra.Command{
+		Use:   cmdRaftStatusUse,
+		Short: cmdDataPartitionRaftStatusShort,
+		Long:  cmdRaftStatusLong,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.DataPartitionInfo
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if partition, err = client.AdminAPI().GetDataPartition("", partitionID); err != nil {
+				return
+			}
+			statuses := collectRaftStatus(partition.Hosts, optDataPort, fmt.Sprintf(dataNodeRaftStatusPath, partitionID))
+			stdout("%v", formatRaftStatus(partition.Peers, statuses))
+		},
+	}
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	return cmd
+}
+
+func newMetaPartitionRaftStatusCmd(client *master.MasterClient) *cobra.Command {
+	var optMetaPort string
+	var cmd = &cobra.Command{
+		Use:   cmdRaftStatusUse,
+		Short: cmdMetaPartitionRaftStatusShort,
+		Long:  cmdRaftStatusLong,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				partition   *proto.MetaPartitionInfo
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				return
+			}
+			if partition, err = client.ClientAPI().GetMetaPartition(partitionID); err != nil {
+				return
+			}
+			statuses := collectRaftStatus(partition.Hosts, optMetaPort, fmt.Sprintf(metaNodeRaftStatusPath, partitionID))
+			stdout("%v", formatRaftStatus(partition.Peers, statuses))
+		},
+	}
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta nodes")
+	return cmd
+}
+
+// collectRaftStatus requests the raft status of the partition from all the replicas concurrently.
+func collectRaftStatus(hosts []string, port, path string) (statuses []*replicaRaftStatus) {
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		result := &replicaRaftStatus{addr: host}
+		statuses = append(statuses, result)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.status, result.err = requestRaftStatus(result.addr, port, path)
+		}()
+	}
+	wg.Wait()
+	return
+}
+
+func requestRaftStatus(nodeAddr, port, path string) (status *raft.Status, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v%v", profAddr(nodeAddr, port), path)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32        `json:"code"`
+		Msg  string       `json:"msg"`
+		Data *raft.Status `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode raft status: %v", err)
+	}
+	if body.Code != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("request raft status: %v", body.Msg)
+	}
+	return body.Data, nil
+}
+
+// sortedReplicaIDs returns the node IDs of the replication progress in order.
+func sortedReplicaIDs(replicas map[uint64]*raft.ReplicaStatus) []uint64 {
+	ids := make([]uint64, 0, len(replicas))
+	for id := range replicas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
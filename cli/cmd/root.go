@@ -19,6 +19,7 @@ import (
 	"github.com/chubaofs/chubaofs/util/log"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
 
@@ -63,6 +64,12 @@ func NewRootCmd(client *master.MasterClient) *ChubaoFSCmd {
 		newConfigCmd(),
 		newCompatibilityCmd(),
 		newZoneCmd(client),
+		newNodeSetCmd(client),
+		newTaskCmd(client),
+		newNFSExportCmd(client),
+		newClientCmd(),
+		newMigrateCmd(client),
+		newConsoleCmd(),
 	)
 	return cmd
 }
@@ -74,10 +81,17 @@ func stdout(format string, a ...interface{}) {
 func errout(format string, a ...interface{}) {
 	log.LogErrorf(format + "\n", a...)
 	_, _ = fmt.Fprintf(os.Stderr, format, a...)
+	if consoleRunning && !strings.HasSuffix(format, "\n") {
+		// keep the prompt of the console on its own line
+		_, _ = fmt.Fprintln(os.Stderr)
+	}
 	OsExitWithLogFlush()
 }
 
 func OsExitWithLogFlush() {
 	log.LogFlush()
+	if consoleRunning {
+		panic(consoleAbort{})
+	}
 	os.Exit(1)
 }
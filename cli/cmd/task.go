@@ -0,0 +1,167 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdTaskUse   = "task [COMMAND]"
+	cmdTaskShort = "Manage admin tasks sent from master to the nodes"
+)
+
+func newTaskCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdTaskUse,
+		Short: cmdTaskShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newTaskListCmd(client),
+		newTaskCancelCmd(client),
+		newTaskPauseCmd(client),
+		newTaskResumeCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdTaskListShort   = "List unfinished admin tasks"
+	cmdTaskCancelShort = "Cancel an admin task"
+	cmdTaskPauseShort  = "Pause sending an admin task to the node"
+	cmdTaskResumeShort = "Resume sending an admin task to the node"
+
+	cmdTaskCancelLong = `Cancel an admin task queued on the master, so that it will not be sent or resent to the node.
+The master only holds the tasks which have not been sent or are waiting for the responses of the
+nodes, a task which is already running on the node, such as the repair or the decommission of a
+partition, is not interrupted and runs to the end.`
+	cmdTaskPauseLong = `Pause sending an admin task queued on the master to the node until it is resumed. A task which
+is already running on the node is not paused, and the node reports no progress of the tasks.`
+)
+
+func newTaskListCmd(client *master.MasterClient) *cobra.Command {
+	var optAddr string
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdTaskListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var views []*proto.AdminTaskView
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if views, err = client.AdminAPI().ListTasks(optAddr); err != nil {
+				return
+			}
+			sort.Slice(views, func(i, j int) bool {
+				return views[i].CreateTime < views[j].CreateTime
+			})
+			stdout("%v\n", adminTaskTableHeader)
+			for _, view := range views {
+				stdout("%v\n", formatAdminTaskTableRow(view))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optAddr, CliFlagAddress, "", "List tasks of the specified node only")
+	return cmd
+}
+
+func newTaskCancelCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   CliOpCancel + " [NODE ADDRESS] [TASK ID]",
+		Short: cmdTaskCancelShort,
+		Long:  cmdTaskCancelLong,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr, taskID = args[0], args[1]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes {
+				stdout("Cancel task [%v] of node [%v] (yes/no)[no]:", taskID, nodeAddr)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			if err = client.AdminAPI().CancelTask(nodeAddr, taskID); err != nil {
+				return
+			}
+			stdout("Task [%v] of node [%v] has been canceled, it will not be sent to the node again.\n", taskID, nodeAddr)
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func newTaskPauseCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpPause + " [NODE ADDRESS] [TASK ID]",
+		Short: cmdTaskPauseShort,
+		Long:  cmdTaskPauseLong,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr, taskID = args[0], args[1]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().PauseTask(nodeAddr, taskID); err != nil {
+				return
+			}
+			stdout("Task [%v] of node [%v] has been paused, it will not be sent to the node until resumed.\n", taskID, nodeAddr)
+		},
+	}
+	return cmd
+}
+
+func newTaskResumeCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpResume + " [NODE ADDRESS] [TASK ID]",
+		Short: cmdTaskResumeShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr, taskID = args[0], args[1]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().ResumeTask(nodeAddr, taskID); err != nil {
+				return
+			}
+			stdout("Task [%v] of node [%v] has been resumed.\n", taskID, nodeAddr)
+		},
+	}
+	return cmd
+}
@@ -17,9 +17,11 @@ package cmd
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/spf13/cobra"
 )
 
@@ -39,7 +41,13 @@ func newUserCmd(client *master.MasterClient) *cobra.Command {
 		newUserInfoCmd(client),
 		newUserListCmd(client),
 		newUserPermCmd(client),
+		newUserUpdatePolicyCmd(client),
+		newUserPrefixPolicyCmd(client),
 		newUserUpdateCmd(client),
+		newUserRegenerateKeyCmd(client),
+		newUserRotateKeyCmd(client),
+		newUserRetireKeyCmd(client),
+		newUserAssumeRoleCmd(client),
 		newUserDeleteCmd(client),
 	)
 	return cmd
@@ -317,63 +325,397 @@ func newUserPermCmd(client *master.MasterClient) *cobra.Command {
 		Args:  cobra.MinimumNArgs(3),
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
-			var userID = args[0]
-			var volume = args[1]
 			var perm proto.Permission
 			defer func() {
 				if err != nil {
 					errout("Error: %v", err)
 				}
 			}()
-
-			perm = proto.BuiltinPermissionPrefix
-			if subdir != "" && subdir != "/" {
-				perm = proto.Permission(string(perm) + subdir + ":")
+			if perm, err = parseUserPerm(args[2], subdir); err != nil {
+				return
 			}
+			err = setUserPerm(client, args[0], args[1], subdir, perm, false)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&subdir, "subdir", "", "Subdir")
+	cmd.AddCommand(
+		newUserPermGrantCmd(client),
+		newUserPermRevokeCmd(client),
+	)
+	return cmd
+}
 
-			switch strings.ToLower(args[2]) {
-			case "ro", "readonly":
-				perm = perm + "ReadOnly"
-			case "rw", "readwrite":
-				perm = perm + "Writable"
-			case "none":
-				perm = proto.NonePermission
-			default:
-				err = fmt.Errorf("Permission must be on of ro, rw, none ")
+const (
+	cmdUserPermGrantUse    = "grant [USER ID] [VOLUME] [PERM (READONLY,RO,READWRITE,RW)]"
+	cmdUserPermGrantShort  = "Grant the permission of a volume to a user"
+	cmdUserPermRevokeUse   = "revoke [USER ID] [VOLUME]"
+	cmdUserPermRevokeShort = "Revoke all the permissions of a volume from a user"
+)
+
+func newUserPermGrantCmd(client *master.MasterClient) *cobra.Command {
+	var subdir string
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserPermGrantUse,
+		Short: cmdUserPermGrantShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var perm proto.Permission
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if perm, err = parseUserPerm(args[2], subdir); err != nil {
 				return
 			}
-			stdout("Setup volume permission\n")
+			if perm.IsNone() {
+				err = fmt.Errorf("Permission must be one of ro, rw ")
+				return
+			}
+			err = setUserPerm(client, args[0], args[1], subdir, perm, optYes)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validUserVolArgs(client, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&subdir, "subdir", "", "Grant the permission of the subdir only")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func newUserPermRevokeCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserPermRevokeUse,
+		Short: cmdUserPermRevokeShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			err = setUserPerm(client, args[0], args[1], "", proto.NonePermission, optYes)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validUserVolArgs(client, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func parseUserPerm(value, subdir string) (perm proto.Permission, err error) {
+	perm = proto.BuiltinPermissionPrefix
+	if subdir != "" && subdir != "/" {
+		perm = proto.Permission(string(perm) + subdir + ":")
+	}
+	switch strings.ToLower(value) {
+	case "ro", "readonly":
+		perm = perm + "ReadOnly"
+	case "rw", "readwrite":
+		perm = perm + "Writable"
+	case "none":
+		perm = proto.NonePermission
+	default:
+		err = fmt.Errorf("Permission must be on of ro, rw, none ")
+	}
+	return
+}
+
+// setUserPerm replaces the policies of the volume with the permission, or removes them if the permission is none.
+func setUserPerm(client *master.MasterClient, userID, volume, subdir string, perm proto.Permission, optYes bool) (err error) {
+	stdout("Setup volume permission\n")
+	stdout("  User ID   : %v\n", userID)
+	stdout("  Volume    : %v\n", volume)
+	stdout("  Subdir    : %v\n", subdir)
+	stdout("  Permission: %v\n", perm.ReadableString())
+
+	if !optYes {
+		stdout("\nConfirm (yes/no)[yes]: ")
+		var userConfirm string
+		_, _ = fmt.Scanln(&userConfirm)
+		if userConfirm != "yes" && len(userConfirm) != 0 {
+			err = fmt.Errorf("Abort by user.\n")
+			return
+		}
+	}
+	var userInfo *proto.UserInfo
+	if userInfo, err = client.UserAPI().GetUserInfo(userID); err != nil {
+		return
+	}
+	if _, err = client.AdminAPI().GetVolumeSimpleInfo(volume); err != nil {
+		return
+	}
+	if perm.IsNone() {
+		param := proto.NewUserPermRemoveParam(userID, volume)
+		userInfo, err = client.UserAPI().RemovePolicy(param)
+	} else {
+		param := proto.NewUserPermUpdateParam(userID, volume)
+		param.SetPolicy(perm.String())
+		userInfo, err = client.UserAPI().UpdatePolicy(param)
+	}
+	if err != nil {
+		return
+	}
+	printUserInfo(userInfo)
+	return
+}
+
+const (
+	cmdUserUpdatePolicyUse   = "update-policy [USER ID] [VOLUME] [POLICY]..."
+	cmdUserUpdatePolicyShort = "Replace the policies of a volume of a user"
+)
+
+func newUserUpdatePolicyCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserUpdatePolicyUse,
+		Short: cmdUserUpdatePolicyShort,
+		Long: `Replace the policies of a volume of a user with the given permissions and actions,
+e.g. "perm:builtin:ReadOnly", "perm:builtin:/subdir:Writable" or "action:oss:GetObject".`,
+		Args: cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID, volume, policies = args[0], args[1], args[2:]
+			var userInfo *proto.UserInfo
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			param := proto.NewUserPermUpdateParam(userID, volume)
+			for _, policy := range policies {
+				if proto.ParsePermission(policy).IsNone() && proto.ParseAction(policy).IsNone() {
+					err = fmt.Errorf("Invalid policy: %v ", policy)
+					return
+				}
+				param.SetPolicy(policy)
+			}
+			stdout("Update volume policies\n")
 			stdout("  User ID   : %v\n", userID)
 			stdout("  Volume    : %v\n", volume)
-			stdout("  Subdir    : %v\n", subdir)
-			stdout("  Permission: %v\n", perm.ReadableString())
+			stdout("  Policies  : %v\n", strings.Join(policies, ", "))
+			if !optYes {
+				stdout("\nConfirm (yes/no)[yes]: ")
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" && len(userConfirm) != 0 {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			if _, err = client.AdminAPI().GetVolumeSimpleInfo(volume); err != nil {
+				return
+			}
+			if userInfo, err = client.UserAPI().UpdatePolicy(param); err != nil {
+				return
+			}
+			printUserInfo(userInfo)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validUserVolArgs(client, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
 
-			// ask user for confirm
-			stdout("\nConfirm (yes/no)[yes]: ")
-			var userConfirm string
-			_, _ = fmt.Scanln(&userConfirm)
-			if userConfirm != "yes" && len(userConfirm) != 0 {
-				err = fmt.Errorf("Abort by user.\n")
+const (
+	cmdUserPrefixPolicyUse         = "prefix-policy [COMMAND]"
+	cmdUserPrefixPolicyShort       = "Manage the policies of object key prefixes of a user"
+	cmdUserPrefixPolicySetUse      = CliOpSet + " [USER ID] [VOLUME] [PREFIX] [POLICY]..."
+	cmdUserPrefixPolicySetShort    = "Allow or deny the policies on the objects under the prefix"
+	cmdUserPrefixPolicyDeleteUse   = CliOpDelete + " [USER ID] [VOLUME] [PREFIX]"
+	cmdUserPrefixPolicyDeleteShort = "Delete the policies of the prefix"
+)
+
+func newUserPrefixPolicyCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdUserPrefixPolicyUse,
+		Short: cmdUserPrefixPolicyShort,
+	}
+	cmd.AddCommand(
+		newUserPrefixPolicySetCmd(client),
+		newUserPrefixPolicyDeleteCmd(client),
+	)
+	return cmd
+}
+
+func newUserPrefixPolicySetCmd(client *master.MasterClient) *cobra.Command {
+	var optEffect string
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserPrefixPolicySetUse,
+		Short: cmdUserPrefixPolicySetShort,
+		Long: `Allow or deny the policies on the objects whose keys start with the prefix, e.g.
+"logs/teamA/*" with "perm:builtin:Writable" or "action:oss:DeleteObject".
+A deny policy wins over the allow ones, and the volume policies apply if no prefix matches.`,
+		Args: cobra.MinimumNArgs(4),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID, volume, prefix, policies = args[0], args[1], args[2], args[3:]
+			var userInfo *proto.UserInfo
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var effect proto.PrefixEffect
+			if effect, err = parsePrefixEffect(optEffect); err != nil {
+				return
+			}
+			for _, policy := range policies {
+				if proto.ParsePermission(policy).IsNone() && proto.ParseAction(policy).IsNone() {
+					err = fmt.Errorf("Invalid policy: %v ", policy)
+					return
+				}
+			}
+			stdout("Set prefix policies\n")
+			stdout("  User ID   : %v\n", userID)
+			stdout("  Volume    : %v\n", volume)
+			stdout("  Prefix    : %v\n", prefix)
+			stdout("  Effect    : %v\n", effect)
+			stdout("  Policies  : %v\n", strings.Join(policies, ", "))
+			if !optYes {
+				stdout("\nConfirm (yes/no)[yes]: ")
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" && len(userConfirm) != 0 {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var param = proto.UserPrefixPolicyParam{UserID: userID, Volume: volume, Prefix: prefix, Effect: effect, Actions: policies}
+			if userInfo, err = client.UserAPI().SetPrefixPolicy(&param); err != nil {
 				return
 			}
+			printUserInfo(userInfo)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validUserVolArgs(client, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optEffect, CliFlagEffect, "allow", "Specify the effect of the policies [allow, deny]")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func newUserPrefixPolicyDeleteCmd(client *master.MasterClient) *cobra.Command {
+	var optEffect string
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserPrefixPolicyDeleteUse,
+		Short: cmdUserPrefixPolicyDeleteShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID, volume, prefix = args[0], args[1], args[2]
 			var userInfo *proto.UserInfo
-			if userInfo, err = client.UserAPI().GetUserInfo(userID); err != nil {
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var effect proto.PrefixEffect
+			if effect, err = parsePrefixEffect(optEffect); err != nil {
 				return
 			}
-			if _, err = client.AdminAPI().GetVolumeSimpleInfo(volume); err != nil {
+			if !optYes {
+				stdout("Delete the %v policies of prefix [%v] of user [%v] on volume [%v] (yes/no)[no]:", effect, prefix, userID, volume)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var param = proto.UserPrefixPolicyParam{UserID: userID, Volume: volume, Prefix: prefix, Effect: effect}
+			if userInfo, err = client.UserAPI().RemovePrefixPolicy(&param); err != nil {
 				return
 			}
-			if perm.IsNone() {
-				param := proto.NewUserPermRemoveParam(userID, volume)
-				userInfo, err = client.UserAPI().RemovePolicy(param)
-			} else {
-				param := proto.NewUserPermUpdateParam(userID, volume)
-				param.SetPolicy(perm.String())
-				userInfo, err = client.UserAPI().UpdatePolicy(param)
+			printUserInfo(userInfo)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return validUserVolArgs(client, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optEffect, CliFlagEffect, "allow", "Specify the effect of the policies [allow, deny]")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func parsePrefixEffect(value string) (effect proto.PrefixEffect, err error) {
+	switch strings.ToLower(value) {
+	case "allow":
+		effect = proto.PrefixEffectAllow
+	case "deny":
+		effect = proto.PrefixEffectDeny
+	default:
+		err = fmt.Errorf("Effect must be one of allow, deny ")
+	}
+	return
+}
+
+const (
+	cmdUserRegenerateKeyUse   = "regenerate-key [USER ID]"
+	cmdUserRegenerateKeyShort = "Regenerate the access key and secret key of a user"
+
+	userAccessKeyLength = 16
+	userSecretKeyLength = 32
+	// the generated access key may collide with the one of another user
+	userRegenerateKeyRetry = 3
+)
+
+func newUserRegenerateKeyCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserRegenerateKeyUse,
+		Short: cmdUserRegenerateKeyShort,
+		Long: `Regenerate the access key and secret key of a user.
+The old keys become invalid immediately, and the clients using them must be updated.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID = args[0]
+			var userInfo *proto.UserInfo
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes {
+				stdout("Regenerate the keys of user [%v], the old keys become invalid (yes/no)[no]:", userID)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			for i := 0; i < userRegenerateKeyRetry; i++ {
+				var param = proto.UserUpdateParam{
+					UserID:    userID,
+					AccessKey: util.RandomString(userAccessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter),
+					SecretKey: util.RandomString(userSecretKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter),
+				}
+				if userInfo, err = client.UserAPI().UpdateUser(&param); err != proto.ErrDuplicateAccessKey {
+					break
+				}
 			}
 			if err != nil {
 				return
 			}
+			stdout("Regenerate keys success:\n")
 			printUserInfo(userInfo)
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -383,10 +725,168 @@ func newUserPermCmd(client *master.MasterClient) *cobra.Command {
 			return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 	}
-	cmd.Flags().StringVar(&subdir, "subdir", "", "Subdir")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	return cmd
 }
 
+const (
+	cmdUserRotateKeyUse   = "rotate-key [USER ID]"
+	cmdUserRotateKeyShort = "Create new keys of a user and keep the old keys valid for a grace period"
+	cmdUserRetireKeyUse   = "retire-key [USER ID]"
+	cmdUserRetireKeyShort = "Invalidate the old keys of a user before the grace period ends"
+)
+
+func newUserRotateKeyCmd(client *master.MasterClient) *cobra.Command {
+	var optGracePeriod time.Duration
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserRotateKeyUse,
+		Short: cmdUserRotateKeyShort,
+		Long: `Create a new access key and secret key of a user.
+Both the old and the new keys are accepted during the grace period, so that the
+clients could be updated one by one. The old keys are retired automatically when
+the grace period ends, or by 'retire-key' once all the clients are updated.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID = args[0]
+			var userInfo *proto.UserInfo
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optGracePeriod < 0 {
+				err = fmt.Errorf("invalid grace period: %v", optGracePeriod)
+				return
+			}
+			if !optYes {
+				stdout("Rotate the keys of user [%v], the old keys stay valid for %v (yes/no)[no]:", userID, optGracePeriod)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var param = proto.UserRotateKeyParam{UserID: userID, GracePeriod: int64(optGracePeriod / time.Second)}
+			if userInfo, err = client.UserAPI().RotateKeys(&param); err != nil {
+				return
+			}
+			stdout("Rotate keys success:\n")
+			printUserInfo(userInfo)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().DurationVar(&optGracePeriod, CliFlagGracePeriod, 24*time.Hour, "Specify how long the old keys stay valid")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func newUserRetireKeyCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdUserRetireKeyUse,
+		Short: cmdUserRetireKeyShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID = args[0]
+			var userInfo *proto.UserInfo
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes {
+				stdout("Retire the old keys of user [%v], they become invalid immediately (yes/no)[no]:", userID)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			if userInfo, err = client.UserAPI().RetireKeys(userID); err != nil {
+				return
+			}
+			stdout("Retire keys success:\n")
+			printUserInfo(userInfo)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+const (
+	cmdUserAssumeRoleUse   = "assume-role [USER ID]"
+	cmdUserAssumeRoleShort = "Issue temporary session credential of a user"
+)
+
+func newUserAssumeRoleCmd(client *master.MasterClient) *cobra.Command {
+	var optDuration time.Duration
+	var cmd = &cobra.Command{
+		Use:   cmdUserAssumeRoleUse,
+		Short: cmdUserAssumeRoleShort,
+		Long: `Issue a temporary access key, secret key and session token of a user.
+The session credential has the same permissions as the user. Requests signed with it
+must carry the session token in the 'X-Amz-Security-Token' header or query parameter,
+and are rejected after the credential expires.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID = args[0]
+			var session *proto.SessionCredential
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var param = proto.UserAssumeRoleParam{UserID: userID, Duration: int64(optDuration / time.Second)}
+			if session, err = client.UserAPI().AssumeRole(&param); err != nil {
+				return
+			}
+			stdout("Assume role success:\n")
+			stdout("  User ID      : %v\n", userID)
+			stdout("  Access Key   : %v\n", session.AccessKey)
+			stdout("  Secret Key   : %v\n", session.SecretKey)
+			stdout("  Session Token: %v\n", session.SessionToken)
+			stdout("  Expire Time  : %v\n", time.Unix(session.ExpireTime, 0).Format(proto.TimeFormat))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().DurationVar(&optDuration, CliFlagDuration, time.Hour, "Specify how long the session credential stays valid [15m, 12h]")
+	return cmd
+}
+
+// validUserVolArgs completes the user ID and then the volume.
+func validUserVolArgs(client *master.MasterClient, args []string, toComplete string) []string {
+	switch len(args) {
+	case 0:
+		return validUsers(client, toComplete)
+	case 1:
+		return validVols(client, toComplete)
+	default:
+		return nil
+	}
+}
+
 const (
 	cmdUserListShort = "List cluster users"
 )
@@ -425,6 +925,14 @@ func printUserInfo(userInfo *proto.UserInfo) {
 	stdout("  Secret Key : %v\n", userInfo.SecretKey)
 	stdout("  Type       : %v\n", userInfo.UserType)
 	stdout("  Create Time: %v\n", userInfo.CreateTime)
+	if userInfo.RetiringAccessKey != "" {
+		stdout("  Retiring Access Key : %v\n", userInfo.RetiringAccessKey)
+		stdout("  Retiring Secret Key : %v\n", userInfo.RetiringSecretKey)
+		stdout("  Retiring Expire Time: %v\n", time.Unix(userInfo.RetiringExpireTime, 0).Format(proto.TimeFormat))
+	}
+	if len(userInfo.Sessions) > 0 {
+		stdout("  Sessions   : %v\n", len(userInfo.Sessions))
+	}
 	if userInfo.Policy == nil {
 		return
 	}
@@ -436,4 +944,14 @@ func printUserInfo(userInfo *proto.UserInfo) {
 	for vol, perms := range userInfo.Policy.AuthorizedVols {
 		stdout("%-20v    %-12v\n", vol, strings.Join(perms, ","))
 	}
+	if len(userInfo.Policy.PrefixPolicies) == 0 {
+		return
+	}
+	stdout("[Prefixes]\n")
+	stdout("%-20v    %-20v    %-6v    %-12v\n", "VOLUME", "PREFIX", "EFFECT", "POLICIES")
+	for vol, rules := range userInfo.Policy.PrefixPolicies {
+		for _, rule := range rules {
+			stdout("%-20v    %-20v    %-6v    %-12v\n", vol, rule.Prefix, rule.Effect, strings.Join(rule.Actions, ","))
+		}
+	}
 }
@@ -49,6 +49,16 @@ func newVolCmd(client *master.MasterClient) *cobra.Command {
 		newVolDeleteCmd(client),
 		newVolTransferCmd(client),
 		newVolAddDPCmd(client),
+		newVolTierPolicyCmd(client),
+		newVolWormCmd(client),
+		newVolFsckCmd(client),
+		newVolTrashCmd(client),
+		newVolLocateCmd(client),
+		newVolRmdirCmd(client),
+		newVolDuCmd(client),
+		newVolChattrCmd(client),
+		newVolAuditPermissionsCmd(client),
+		newVolBackupCmd(client),
 	)
 	return cmd
 }
@@ -103,6 +113,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	var optFollowerRead bool
 	var optYes bool
 	var optZoneName string
+	var optZoneAntiAffinity bool
+	var optStrictZones bool
 	var cmd = &cobra.Command{
 		Use:   cmdVolCreateUse,
 		Short: cmdVolCreateShort,
@@ -127,6 +139,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 				stdout("  Replicas            : %v\n", optReplicas)
 				stdout("  Allow follower read : %v\n", formatEnabledDisabled(optFollowerRead))
 				stdout("  ZoneName            : %v\n", optZoneName)
+				stdout("  Zone anti-affinity  : %v\n", formatEnabledDisabled(optZoneAntiAffinity))
+				stdout("  Strict zones        : %v\n", formatEnabledDisabled(optStrictZones))
 				stdout("\nConfirm (yes/no)[yes]: ")
 				var userConfirm string
 				_, _ = fmt.Scanln(&userConfirm)
@@ -138,7 +152,7 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 
 			err = client.AdminAPI().CreateVolume(
 				volumeName, userID, optMPCount, optDPSize,
-				optCapacity, optReplicas, optFollowerRead, optZoneName)
+				optCapacity, optReplicas, optFollowerRead, optZoneName, optZoneAntiAffinity, optStrictZones)
 			if err != nil {
 				err = fmt.Errorf("Create volume failed case:\n%v\n", err)
 				return
@@ -152,7 +166,9 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().Uint64Var(&optCapacity, CliFlagCapacity, cmdVolDefaultCapacity, "Specify volume capacity [Unit: GB]")
 	cmd.Flags().IntVar(&optReplicas, CliFlagReplicas, cmdVolDefaultReplicas, "Specify data partition replicas number")
 	cmd.Flags().BoolVar(&optFollowerRead, CliFlagEnableFollowerRead, cmdVolDefaultFollowerReader, "Enable read form replica follower")
-	cmd.Flags().StringVar(&optZoneName, CliFlagZoneName, cmdVolDefaultZoneName, "Specify volume zone name")
+	cmd.Flags().StringVar(&optZoneName, CliFlagZoneName, cmdVolDefaultZoneName, "Specify volume zone name, or zone names separated by commas to spread partitions over")
+	cmd.Flags().BoolVar(&optZoneAntiAffinity, CliFlagZoneAntiAffinity, false, "Place the replicas of a partition in different zones of the zone names")
+	cmd.Flags().BoolVar(&optStrictZones, CliFlagStrictZones, false, "Refuse to place a partition whose replicas span fewer zones than required")
 	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	return cmd
 }
@@ -162,10 +178,19 @@ const (
 func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 	var optCapacity uint64
 	var optReplicas int
+	var optMpReplicas int
 	var optFollowerRead string
 	var optAuthenticate string
 	var optEnableToken string
 	var optZoneName string
+	var optMaxIOPS int64
+	var optMaxBandwidth int64
+	var optVerifyRead string
+	var optTrashDays int64
+	var optZoneAntiAffinity string
+	var optStrictZones string
+	var optAtime string
+	var optFileAudit string
 	var optYes bool
 	var confirmString = strings.Builder{}
 	var vv *proto.SimpleVolView
@@ -201,6 +226,13 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 			} else {
 				confirmString.WriteString(fmt.Sprintf("  Replicas            : %v\n", vv.DpReplicaNum))
 			}
+			if optMpReplicas > 0 {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  Meta replicas       : %v -> %v\n", vv.MpReplicaNum, optMpReplicas))
+				vv.MpReplicaNum = uint8(optMpReplicas)
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Meta replicas       : %v\n", vv.MpReplicaNum))
+			}
 			if optFollowerRead != "" {
 				isChange = true
 				var enable bool
@@ -242,6 +274,82 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 			} else {
 				confirmString.WriteString(fmt.Sprintf("  ZoneName            : %v\n", vv.ZoneName))
 			}
+			if optMaxIOPS >= 0 {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  Max IOPS            : %v -> %v\n", formatQosLimit(vv.MaxIOPS, ""), formatQosLimit(uint64(optMaxIOPS), "")))
+				vv.MaxIOPS = uint64(optMaxIOPS)
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Max IOPS            : %v\n", formatQosLimit(vv.MaxIOPS, "")))
+			}
+			if optMaxBandwidth >= 0 {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  Max bandwidth       : %v -> %v\n", formatQosLimit(vv.MaxBandwidth, "MB/s"), formatQosLimit(uint64(optMaxBandwidth), "MB/s")))
+				vv.MaxBandwidth = uint64(optMaxBandwidth)
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Max bandwidth       : %v\n", formatQosLimit(vv.MaxBandwidth, "MB/s")))
+			}
+			if optVerifyRead != "" {
+				isChange = true
+				var enable bool
+				if enable, err = strconv.ParseBool(optVerifyRead); err != nil {
+					return
+				}
+				confirmString.WriteString(fmt.Sprintf("  Verify read         : %v -> %v\n", formatEnabledDisabled(vv.VerifyRead), formatEnabledDisabled(enable)))
+				vv.VerifyRead = enable
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Verify read         : %v\n", formatEnabledDisabled(vv.VerifyRead)))
+			}
+			if optTrashDays >= 0 {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  Trash               : %v -> %v\n", formatTrashDays(vv.TrashDays), formatTrashDays(uint32(optTrashDays))))
+				vv.TrashDays = uint32(optTrashDays)
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Trash               : %v\n", formatTrashDays(vv.TrashDays)))
+			}
+			if optZoneAntiAffinity != "" {
+				isChange = true
+				var enable bool
+				if enable, err = strconv.ParseBool(optZoneAntiAffinity); err != nil {
+					return
+				}
+				confirmString.WriteString(fmt.Sprintf("  Zone anti-affinity  : %v -> %v\n", formatEnabledDisabled(vv.ZoneAntiAffinity), formatEnabledDisabled(enable)))
+				vv.ZoneAntiAffinity = enable
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Zone anti-affinity  : %v\n", formatEnabledDisabled(vv.ZoneAntiAffinity)))
+			}
+			if optStrictZones != "" {
+				isChange = true
+				var enable bool
+				if enable, err = strconv.ParseBool(optStrictZones); err != nil {
+					return
+				}
+				confirmString.WriteString(fmt.Sprintf("  Strict zones        : %v -> %v\n", formatEnabledDisabled(vv.StrictZones), formatEnabledDisabled(enable)))
+				vv.StrictZones = enable
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Strict zones        : %v\n", formatEnabledDisabled(vv.StrictZones)))
+			}
+			if optAtime != "" {
+				isChange = true
+				var enable bool
+				if enable, err = strconv.ParseBool(optAtime); err != nil {
+					return
+				}
+				confirmString.WriteString(fmt.Sprintf("  Access time         : %v -> %v\n", formatEnabledDisabled(vv.Atime), formatEnabledDisabled(enable)))
+				vv.Atime = enable
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Access time         : %v\n", formatEnabledDisabled(vv.Atime)))
+			}
+			if optFileAudit != "" {
+				isChange = true
+				var enable bool
+				if enable, err = strconv.ParseBool(optFileAudit); err != nil {
+					return
+				}
+				confirmString.WriteString(fmt.Sprintf("  File audit          : %v -> %v\n", formatEnabledDisabled(vv.FileAudit), formatEnabledDisabled(enable)))
+				vv.FileAudit = enable
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  File audit          : %v\n", formatEnabledDisabled(vv.FileAudit)))
+			}
 			if vv.CrossZone == true && "" != optZoneName {
 				err = fmt.Errorf("Can not set zone name of the volume that cross zone\n")
 			}
@@ -263,9 +371,7 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 					return
 				}
 			}
-			err = client.AdminAPI().UpdateVolume(vv.Name, vv.Capacity, int(vv.DpReplicaNum),
-				vv.FollowerRead, vv.Authenticate, vv.EnableToken, calcAuthKey(vv.Owner), vv.ZoneName)
-			if err != nil {
+			if err = client.AdminAPI().UpdateVolumeWithRequest(proto.NewUpdateVolRequest(vv, calcAuthKey(vv.Owner))); err != nil {
 				return
 			}
 			stdout("Volume configuration has been set successfully.\n")
@@ -279,11 +385,20 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 		},
 	}
 	cmd.Flags().Uint64Var(&optCapacity, CliFlagCapacity, 0, "Specify volume capacity [Unit: GB]")
-	cmd.Flags().IntVar(&optReplicas, CliFlagReplicas, 0, "Specify data partition replicas number")
+	cmd.Flags().IntVar(&optReplicas, CliFlagReplicas, 0, "Specify data partition replicas number, existing partitions are adjusted gradually")
+	cmd.Flags().IntVar(&optMpReplicas, CliFlagMpReplicas, 0, "Specify meta partition replicas number, existing partitions are adjusted gradually")
 	cmd.Flags().StringVar(&optFollowerRead, CliFlagEnableFollowerRead, "", "Enable read form replica follower")
 	cmd.Flags().StringVar(&optAuthenticate, CliFlagAuthenticate, "", "Enable authenticate")
 	cmd.Flags().StringVar(&optEnableToken, CliFlagEnableToken, "", "ReadOnly/ReadWrite token validation for fuse client")
-	cmd.Flags().StringVar(&optZoneName, CliFlagZoneName, "", "Specify volume zone name")
+	cmd.Flags().StringVar(&optZoneName, CliFlagZoneName, "", "Specify volume zone name, or zone names separated by commas to spread partitions over")
+	cmd.Flags().StringVar(&optZoneAntiAffinity, CliFlagZoneAntiAffinity, "", "Place the replicas of a partition in different zones of the zone names")
+	cmd.Flags().StringVar(&optStrictZones, CliFlagStrictZones, "", "Refuse to place a partition whose replicas span fewer zones than required")
+	cmd.Flags().Int64Var(&optMaxIOPS, CliFlagMaxIOPS, -1, "Specify the IOPS limit of the volume, divided evenly among its data nodes, 0 means unlimited")
+	cmd.Flags().Int64Var(&optMaxBandwidth, CliFlagMaxBandwidth, -1, "Specify the bandwidth limit of the volume, divided evenly among its data nodes, 0 means unlimited [Unit: MB/s]")
+	cmd.Flags().StringVar(&optVerifyRead, CliFlagVerifyRead, "", "Verify the checksums of every read, at the cost of CPU")
+	cmd.Flags().Int64Var(&optTrashDays, CliFlagTrashDays, -1, "Specify the days that deleted files are kept in the trash, 0 disables the trash")
+	cmd.Flags().StringVar(&optAtime, CliFlagAtime, "", "Update the access time of files and directories with the relatime policy")
+	cmd.Flags().StringVar(&optFileAudit, CliFlagFileAudit, "", "Record the file operations to the audit log of the meta nodes")
 	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	return cmd
 }
@@ -297,6 +412,7 @@ func newVolInfoCmd(client *master.MasterClient) *cobra.Command {
 	var (
 		optMetaDetail bool
 		optDataDetail bool
+		optZones      bool
 	)
 
 	var cmd = &cobra.Command{
@@ -352,6 +468,11 @@ func newVolInfoCmd(client *master.MasterClient) *cobra.Command {
 					stdout("%v\n", formatDataPartitionTableRow(dp))
 				}
 			}
+
+			// print the zone distribution of the replicas
+			if optZones {
+				err = printVolPartitionZones(client, svv)
+			}
 			return
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -363,9 +484,54 @@ func newVolInfoCmd(client *master.MasterClient) *cobra.Command {
 	}
 	cmd.Flags().BoolVarP(&optMetaDetail, "meta-partition", "m", false, "Display meta partition detail information")
 	cmd.Flags().BoolVarP(&optDataDetail, "data-partition", "d", false, "Display data partition detail information")
+	cmd.Flags().BoolVarP(&optZones, "zones", "z", false, "Display the zone distribution of the replicas of every partition")
 	return cmd
 }
 
+// printVolPartitionZones prints the zones of the replicas of every partition of the volume, the partitions whose
+// replicas span fewer zones than required are flagged.
+func printVolPartitionZones(client *master.MasterClient, svv *proto.SimpleVolView) (err error) {
+	var topo *proto.TopologyView
+	if topo, err = client.AdminAPI().Topo(); err != nil {
+		return fmt.Errorf("Get cluster topology failed:\n%v\n", err)
+	}
+	var dataNodeZones = make(map[string]string)
+	var metaNodeZones = make(map[string]string)
+	for _, zone := range topo.Zones {
+		for _, ns := range zone.NodeSet {
+			for _, node := range ns.DataNodes {
+				dataNodeZones[node.Addr] = zone.Name
+			}
+			for _, node := range ns.MetaNodes {
+				metaNodeZones[node.Addr] = zone.Name
+			}
+		}
+	}
+	var mps []*proto.MetaPartitionView
+	if mps, err = client.ClientAPI().GetMetaPartitions(svv.Name); err != nil {
+		return fmt.Errorf("Get volume metadata detail information failed:\n%v\n", err)
+	}
+	var dpView *proto.DataPartitionsView
+	if dpView, err = client.ClientAPI().GetDataPartitions(svv.Name); err != nil {
+		return fmt.Errorf("Get volume data detail information failed:\n%v\n", err)
+	}
+	sort.SliceStable(mps, func(i, j int) bool {
+		return mps[i].PartitionID < mps[j].PartitionID
+	})
+	sort.SliceStable(dpView.DataPartitions, func(i, j int) bool {
+		return dpView.DataPartitions[i].PartitionID < dpView.DataPartitions[j].PartitionID
+	})
+	stdout("Zone distribution (%v zones required):\n", svv.RequiredZones)
+	stdout("%v\n", partitionZoneTableHeader)
+	for _, mp := range mps {
+		stdout("%v\n", formatPartitionZoneTableRow("meta", mp.PartitionID, mp.Members, metaNodeZones, svv.RequiredZones))
+	}
+	for _, dp := range dpView.DataPartitions {
+		stdout("%v\n", formatPartitionZoneTableRow("data", dp.PartitionID, dp.Hosts, dataNodeZones, svv.RequiredZones))
+	}
+	return
+}
+
 const (
 	cmdVolDeleteUse   = "delete [VOLUME NAME]"
 	cmdVolDeleteShort = "Delete a volume from cluster"
@@ -533,8 +699,85 @@ const (
 )
 
 func newVolExpandCmd(client *master.MasterClient) *cobra.Command {
-	volClient := NewVolumeClient(OpExpandVol, client)
-	return newVolSetCapacityCmd(CliOpExpand, cmdExpandVolCmdShort, volClient)
+	var optYes bool
+	var optForce bool
+	var cmd = &cobra.Command{
+		Use:   CliOpExpand + " [VOLUME] [CAPACITY]",
+		Short: cmdExpandVolCmdShort,
+		Long: `Expand the capacity of a volume online, in GB. The capacity has to be larger than the
+current one, and the replicas of the volume have to fit in the total space of the data nodes,
+unless forced. Expanding a volume to its current capacity does nothing and succeeds.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var capacity uint64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if capacity, err = strconv.ParseUint(args[1], 10, 64); err != nil || capacity == 0 {
+				err = fmt.Errorf("Invalid capacity [%v], expect a positive number of GB\n", args[1])
+				return
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			if svv.Status == 1 {
+				err = fmt.Errorf("Volume [%v] has been marked deleted\n", volumeName)
+				return
+			}
+			if capacity < svv.Capacity {
+				err = fmt.Errorf("Capacity [%v GB] is less than the current capacity [%v GB], use %v instead\n",
+					capacity, svv.Capacity, CliOpShrink)
+				return
+			}
+			if capacity == svv.Capacity {
+				stdout("Volume [%v] has the capacity of %v GB already.\n", volumeName, capacity)
+				return
+			}
+			if !optForce {
+				var cs *proto.ClusterStatInfo
+				if cs, err = client.AdminAPI().GetClusterStat(); err != nil {
+					return
+				}
+				if cs.DataNodeStatInfo != nil && capacity*uint64(svv.DpReplicaNum) > cs.DataNodeStatInfo.TotalGB {
+					err = fmt.Errorf("%v replicas of %v GB exceed the total space of the data nodes [%v GB], "+
+						"use --force to expand anyway\n", svv.DpReplicaNum, capacity, cs.DataNodeStatInfo.TotalGB)
+					return
+				}
+			}
+			if !optYes {
+				stdout("Expand volume [%v] from %v GB to %v GB (yes/no)[no]:", volumeName, svv.Capacity, capacity)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var view *proto.VolCapacityView
+			if view, err = client.AdminAPI().VolExpand(volumeName, capacity, calcAuthKey(svv.Owner)); err != nil {
+				return
+			}
+			if !view.Completed {
+				err = fmt.Errorf("Expand volume [%v] not completed, the capacity is %v GB\n", volumeName, view.Capacity)
+				return
+			}
+			stdout("Volume [%v] has been expanded from %v GB to %v GB.\n", volumeName, view.OldCapacity, view.Capacity)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	cmd.Flags().BoolVarP(&optForce, "force", "f", false, "Expand beyond the total space of the data nodes")
+	return cmd
 }
 
 func newVolShrinkCmd(client *master.MasterClient) *cobra.Command {
@@ -0,0 +1,211 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolAuditPermissionsUse   = "audit-permissions [VOLUME NAME]"
+	cmdVolAuditPermissionsShort = "List all the users, prefix policies and tokens with access to the volume"
+)
+
+// Flags of overly broad grants reported by the permission audit.
+const (
+	auditFlagWriteAll    = "WRITE-ALL"    // writable to the whole volume without subdir or prefix restriction
+	auditFlagWholeVolume = "WHOLE-VOLUME" // prefix policy allowing the whole volume
+	auditFlagAdmin       = "ADMIN"        // granted to a root or admin user
+	auditFlagRetiringKey = "RETIRING-KEY" // a retiring access key of the user is still valid
+	auditFlagSessions    = "SESSIONS"     // the user has unexpired session credentials
+	auditFlagRWToken     = "RW-TOKEN"     // read-write token of the volume
+)
+
+type volPermissionGrant struct {
+	principal  string
+	kind       string
+	accessKey  string
+	permission string
+	flags      []string
+}
+
+type volPermissionAudit struct {
+	volume *proto.SimpleVolView
+	grants []*volPermissionGrant
+}
+
+func newVolAuditPermissionsCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolAuditPermissionsUse,
+		Short: cmdVolAuditPermissionsShort,
+		Long: `Enumerate the owner, the users granted with permissions, the prefix policies and the tokens
+of the volume, together with the S3 access keys of the users, and flag the overly broad grants:
+
+  WRITE-ALL     writable to the whole volume without subdir restriction
+  WHOLE-VOLUME  prefix policy allowing the whole volume
+  ADMIN         granted to a root or admin user
+  RETIRING-KEY  a retiring access key of the user is still valid
+  SESSIONS      the user has unexpired session credentials
+  RW-TOKEN      read-write token of the volume`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var audit *volPermissionAudit
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if audit, err = auditVolPermissions(client, args[0]); err != nil {
+				return
+			}
+			printVolPermissionAudit(audit)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func auditVolPermissions(client *master.MasterClient, volumeName string) (audit *volPermissionAudit, err error) {
+	audit = &volPermissionAudit{}
+	if audit.volume, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+		return
+	}
+	var users []*proto.UserInfo
+	if users, err = client.UserAPI().ListUsers(""); err != nil {
+		return
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	var now = time.Now().Unix()
+	for _, user := range users {
+		if user.Policy == nil {
+			continue
+		}
+		var grants []*volPermissionGrant
+		var isOwner bool
+		for _, vol := range user.Policy.OwnVols {
+			if vol == volumeName {
+				isOwner = true
+				break
+			}
+		}
+		if isOwner || user.UserID == audit.volume.Owner {
+			grants = append(grants, &volPermissionGrant{kind: "Owner", permission: "All"})
+		}
+		for _, value := range user.Policy.AuthorizedVols[volumeName] {
+			var grant = &volPermissionGrant{kind: "User", permission: proto.Permission(value).ReadableString()}
+			if perm := proto.Permission(value); perm.IsBuiltin() {
+				if strings.HasSuffix(value, "Writable") && perm.MatchSubdir("") {
+					grant.flags = append(grant.flags, auditFlagWriteAll)
+				}
+			} else {
+				grant.permission = value
+			}
+			grants = append(grants, grant)
+		}
+		for _, rule := range user.Policy.PrefixPolicies[volumeName] {
+			var grant = &volPermissionGrant{
+				kind:       "Prefix",
+				permission: string(rule.Effect) + " " + rule.Prefix + " " + strings.Join(rule.Actions, ","),
+			}
+			if rule.Effect == proto.PrefixEffectAllow && strings.TrimPrefix(strings.TrimSuffix(rule.Prefix, "*"), "/") == "" {
+				grant.flags = append(grant.flags, auditFlagWholeVolume)
+			}
+			grants = append(grants, grant)
+		}
+		if len(grants) == 0 {
+			continue
+		}
+		// flags of the user apply to all of its grants
+		var userFlags []string
+		if user.UserType == proto.UserTypeRoot || user.UserType == proto.UserTypeAdmin {
+			userFlags = append(userFlags, auditFlagAdmin)
+		}
+		if user.RetiringAccessKey != "" && user.RetiringExpireTime > now {
+			userFlags = append(userFlags, auditFlagRetiringKey)
+		}
+		var sessions int
+		for _, session := range user.Sessions {
+			if !session.Expired(now) {
+				sessions++
+			}
+		}
+		if sessions > 0 {
+			userFlags = append(userFlags, auditFlagSessions+"="+strconv.Itoa(sessions))
+		}
+		for _, grant := range grants {
+			grant.principal = user.UserID
+			grant.accessKey = user.AccessKey
+			grant.flags = append(grant.flags, userFlags...)
+			audit.grants = append(audit.grants, grant)
+		}
+	}
+
+	var tokens = make([]*proto.Token, 0, len(audit.volume.Tokens))
+	for _, token := range audit.volume.Tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Value < tokens[j].Value })
+	for _, token := range tokens {
+		var grant = &volPermissionGrant{principal: maskToken(token.Value), kind: "Token", permission: "ReadOnly"}
+		if token.TokenType == proto.ReadWriteToken {
+			grant.permission = "ReadWrite"
+			if audit.volume.EnableToken {
+				grant.flags = append(grant.flags, auditFlagRWToken)
+			}
+		}
+		if !audit.volume.EnableToken {
+			grant.permission += "(disabled)"
+		}
+		audit.grants = append(audit.grants, grant)
+	}
+	return
+}
+
+// maskToken hides the token value except the first characters, the report is meant to be shared.
+func maskToken(value string) string {
+	if len(value) <= 4 {
+		return "****"
+	}
+	return value[:4] + "****"
+}
+
+func printVolPermissionAudit(audit *volPermissionAudit) {
+	var flagged int
+	stdout("Volume          : %v\n", audit.volume.Name)
+	stdout("Owner           : %v\n", audit.volume.Owner)
+	stdout("Authenticate    : %v\n", formatEnabledDisabled(audit.volume.Authenticate))
+	stdout("Token           : %v\n", formatEnabledDisabled(audit.volume.EnableToken))
+	stdout("\n%v\n", volPermissionGrantTableHeader)
+	for _, grant := range audit.grants {
+		if len(grant.flags) > 0 {
+			flagged++
+		}
+		stdout("%v\n", formatVolPermissionGrantTableRow(grant))
+	}
+	stdout("\n%v grants, %v flagged.\n", len(audit.grants), flagged)
+}
@@ -0,0 +1,258 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolBackupUse          = "backup [COMMAND]"
+	cmdVolBackupShort        = "Back up volumes to an S3 compatible storage"
+	cmdVolBackupCreateUse    = "create [VOLUME NAME]"
+	cmdVolBackupCreateShort  = "Back up a volume, incrementally from the latest backup by default"
+	cmdVolBackupListUse      = "list [VOLUME NAME]"
+	cmdVolBackupListShort    = "List the backups of a volume"
+	cmdVolBackupRestoreUse   = "restore [VOLUME NAME] [BACKUP ID] [PATH]"
+	cmdVolBackupRestoreShort = "Restore a backup of a volume to a new directory"
+
+	backupManifestVersion = 1
+	backupIDFormat        = "20060102150405"
+
+	backupEntryDir     = "dir"
+	backupEntryFile    = "file"
+	backupEntrySymlink = "symlink"
+	backupRootPath     = "."
+)
+
+// The manifest of a backup is a gzip stream of json lines, the header followed by the entries of the volume in the
+// pre-order of the tree, so a directory always comes before its children.
+type backupHeader struct {
+	Version       int    `json:"version"`
+	Cluster       string `json:"cluster"`
+	Volume        string `json:"volume"`
+	ID            string `json:"id"`
+	Parent        string `json:"parent,omitempty"` // the backup the unchanged files are shared with
+	CreateTime    int64  `json:"createTime"`
+	Entries       uint64 `json:"entries"`
+	Files         uint64 `json:"files"`
+	Bytes         uint64 `json:"bytes"`
+	UploadedFiles uint64 `json:"uploadedFiles"`
+	UploadedBytes uint64 `json:"uploadedBytes"`
+	Skipped       uint64 `json:"skipped"` // files failed to be read, which are not in the backup
+}
+
+type backupEntry struct {
+	Path       string            `json:"path"`
+	Type       string            `json:"type"`
+	Mode       uint32            `json:"mode"` // os.FileMode
+	Uid        uint32            `json:"uid"`
+	Gid        uint32            `json:"gid"`
+	Size       uint64            `json:"size"`
+	ModifyTime int64             `json:"mtime"`
+	AccessTime int64             `json:"atime"`
+	Inode      uint64            `json:"ino"`
+	LinkTo     string            `json:"linkTo,omitempty"` // path of the previous hard link
+	Target     string            `json:"target,omitempty"` // target of the symlink
+	XAttrs     map[string]string `json:"xattrs,omitempty"`
+	Extents    []proto.ExtentKey `json:"extents,omitempty"`
+	Object     string            `json:"object,omitempty"` // key of the data, which may belong to a previous backup
+	MD5        string            `json:"md5,omitempty"`
+}
+
+func newVolBackupCmd(client *master.MasterClient) *cobra.Command {
+	var store = &backupStoreConfig{}
+	var cmd = &cobra.Command{
+		Use:   cmdVolBackupUse,
+		Short: cmdVolBackupShort,
+		Long: `Back up the metadata and the data of volumes to a bucket of an S3 compatible storage, and
+restore them. A backup only uploads the files whose extents or modification time are changed
+since the previous backup, and shares the data of the others with it.`,
+	}
+	store.addFlags(cmd)
+	cmd.AddCommand(
+		newVolBackupCreateCmd(client, store),
+		newVolBackupListCmd(client, store),
+		newVolBackupRestoreCmd(client, store),
+	)
+	return cmd
+}
+
+func newVolBackupListCmd(client *master.MasterClient, config *backupStoreConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolBackupListUse,
+		Short: cmdVolBackupListShort,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err     error
+				store   *backupStore
+				view    *proto.ClusterView
+				ids     []string
+				volName = args[0]
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if store, err = newBackupStore(config); err != nil {
+				return
+			}
+			if view, err = client.AdminAPI().GetCluster(); err != nil {
+				return
+			}
+			if ids, err = store.listBackups(view.Name, volName); err != nil {
+				return
+			}
+			stdout("%-16v  %-16v  %-19v  %10v  %10v  %10v  %8v\n",
+				"ID", "PARENT", "TIME", "FILES", "SIZE", "UPLOADED", "SKIPPED")
+			for _, id := range ids {
+				var header *backupHeader
+				if header, _, err = store.openManifest(view.Name, volName, id); err != nil {
+					return
+				}
+				stdout("%-16v  %-16v  %-19v  %10v  %10v  %10v  %8v\n", header.ID, header.Parent,
+					time.Unix(header.CreateTime, 0).Format("2006-01-02 15:04:05"), header.Files,
+					formatSize(header.Bytes), formatSize(header.UploadedBytes), header.Skipped)
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+// backupManifestReader reads the entries of the manifest of a backup.
+type backupManifestReader struct {
+	body io.ReadCloser
+	gr   *gzip.Reader
+	dec  *json.Decoder
+}
+
+// openManifest reads the header of the manifest of the backup, the entries of which are read by the reader returned.
+func (s *backupStore) openManifest(cluster, volume, id string) (header *backupHeader, mr *backupManifestReader, err error) {
+	mr = &backupManifestReader{}
+	if mr.body, err = s.get(s.backupKey(cluster, volume, id) + backupManifestObject); err != nil {
+		return
+	}
+	if mr.gr, err = gzip.NewReader(mr.body); err != nil {
+		mr.body.Close()
+		return
+	}
+	mr.dec = json.NewDecoder(mr.gr)
+	header = &backupHeader{}
+	if err = mr.dec.Decode(header); err != nil {
+		mr.close()
+		return nil, nil, fmt.Errorf("decode the header of backup %v: %v", id, err)
+	}
+	if header.Version != backupManifestVersion {
+		mr.close()
+		return nil, nil, fmt.Errorf("unsupported manifest version %v of backup %v", header.Version, id)
+	}
+	return
+}
+
+// next returns the next entry, or io.EOF at the end of the manifest.
+func (mr *backupManifestReader) next() (entry *backupEntry, err error) {
+	entry = &backupEntry{}
+	if err = mr.dec.Decode(entry); err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode manifest entry: %v", err)
+	}
+	return
+}
+
+func (mr *backupManifestReader) close() {
+	mr.gr.Close()
+	mr.body.Close()
+}
+
+// backupVolume is the client of a volume which reads and writes both metadata and data.
+type backupVolume struct {
+	mw meta.Wrapper
+	ec stream.Client
+}
+
+func openBackupVolume(client *master.MasterClient, volName string) (v *backupVolume, err error) {
+	var (
+		mw *meta.MetaWrapper
+		ec *stream.ExtentClient
+	)
+	if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:  volName,
+		Masters: client.Nodes(),
+	}); err != nil {
+		return nil, err
+	}
+	if ec, err = stream.NewExtentClient(&stream.ExtentConfig{
+		Volume:            volName,
+		Masters:           client.Nodes(),
+		FollowerRead:      true,
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+	}); err != nil {
+		mw.Close()
+		return nil, err
+	}
+	return &backupVolume{mw: mw, ec: ec}, nil
+}
+
+func (v *backupVolume) close() {
+	v.ec.Close()
+	v.mw.Close()
+}
+
+func (v *backupVolume) xattrs(ino uint64) (xattrs map[string]string, err error) {
+	var names []string
+	if names, err = v.mw.XAttrsList_ll(ino); err != nil || len(names) == 0 {
+		return
+	}
+	xattrs = make(map[string]string, len(names))
+	for _, name := range names {
+		var info *proto.XAttrInfo
+		if info, err = v.mw.XAttrGet_ll(ino, name); err != nil {
+			return
+		}
+		xattrs[name] = info.XAttrs[name]
+	}
+	return
+}
+
+func (v *backupVolume) setxattrs(ino uint64, xattrs map[string]string) (err error) {
+	for name, value := range xattrs {
+		if err = v.mw.XAttrSet_ll(ino, []byte(name), []byte(value)); err != nil {
+			return
+		}
+	}
+	return
+}
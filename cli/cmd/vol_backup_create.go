@@ -0,0 +1,366 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+func newVolBackupCreateCmd(client *master.MasterClient, config *backupStoreConfig) *cobra.Command {
+	var optFull bool
+	var cmd = &cobra.Command{
+		Use:   cmdVolBackupCreateUse,
+		Short: cmdVolBackupCreateShort,
+		Long: `Back up the directories, files and symlinks of a volume with their attributes and extended
+attributes, except the trash. The data of a file is uploaded only if its size, extents or
+modification time are changed since the latest backup, otherwise the backup refers to the data
+uploaded before, so do not delete the objects of a backup which later backups are based on.
+The overwrites update the modification time of a file at most once a second, so a file
+overwritten within the same second as it is backed up is uploaded by the next full backup only.
+The files failed to be read, e.g. truncated during the backup, are skipped and counted. The
+backup is complete once its manifest is uploaded at last.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err     error
+				store   *backupStore
+				vol     *backupVolume
+				header  *backupHeader
+				volName = args[0]
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if store, err = newBackupStore(config); err != nil {
+				return
+			}
+			if vol, err = openBackupVolume(client, volName); err != nil {
+				return
+			}
+			defer vol.close()
+			if header, err = createBackup(store, vol, volName, optFull); err != nil {
+				return
+			}
+			stdout("Backup %v of volume %v is created\n", header.ID, volName)
+			stdout("  Parent         : %v\n", header.Parent)
+			stdout("  Entries        : %v\n", header.Entries)
+			stdout("  Files          : %v (%v)\n", header.Files, formatSize(header.Bytes))
+			stdout("  Uploaded files : %v (%v)\n", header.UploadedFiles, formatSize(header.UploadedBytes))
+			stdout("  Skipped files  : %v\n", header.Skipped)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVar(&optFull, "full", false, "Upload all the files instead of the changed ones")
+	return cmd
+}
+
+type backupCreator struct {
+	store   *backupStore
+	vol     *backupVolume
+	header  *backupHeader
+	key     string                  // key prefix of the backup
+	parent  map[uint64]*backupEntry // files of the parent backup by inode
+	links   map[uint64]string       // inode of the hard links to the first path
+	entries *json.Encoder
+}
+
+func createBackup(store *backupStore, vol *backupVolume, volName string, full bool) (header *backupHeader, err error) {
+	cluster := vol.mw.Cluster()
+	now := time.Now()
+	c := &backupCreator{
+		store: store,
+		vol:   vol,
+		header: &backupHeader{
+			Version:    backupManifestVersion,
+			Cluster:    cluster,
+			Volume:     volName,
+			ID:         now.Format(backupIDFormat),
+			CreateTime: now.Unix(),
+		},
+		parent: make(map[uint64]*backupEntry),
+		links:  make(map[uint64]string),
+	}
+	c.key = store.backupKey(cluster, volName, c.header.ID)
+	var ids []string
+	if ids, err = store.listBackups(cluster, volName); err != nil {
+		return
+	}
+	if len(ids) > 0 && ids[len(ids)-1] >= c.header.ID {
+		return nil, fmt.Errorf("backup %v exists, please retry later", ids[len(ids)-1])
+	}
+	if len(ids) > 0 && !full {
+		c.header.Parent = ids[len(ids)-1]
+		if err = c.loadParent(); err != nil {
+			return
+		}
+	}
+
+	// the entries are spooled to a local file, since the header with the counts comes first in the manifest
+	var tmp *os.File
+	if tmp, err = ioutil.TempFile("", "cfs-backup-"); err != nil {
+		return
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	bw := bufio.NewWriter(tmp)
+	c.entries = json.NewEncoder(bw)
+	var root *proto.InodeInfo
+	if root, err = vol.mw.InodeGet_ll(proto.RootIno); err != nil {
+		return
+	}
+	if err = c.walk(backupRootPath, root); err != nil {
+		return
+	}
+	if err = bw.Flush(); err != nil {
+		return
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	if err = c.uploadManifest(tmp); err != nil {
+		return
+	}
+	return c.header, nil
+}
+
+func (c *backupCreator) loadParent() (err error) {
+	_, mr, err := c.store.openManifest(c.header.Cluster, c.header.Volume, c.header.Parent)
+	if err != nil {
+		return
+	}
+	defer mr.close()
+	for {
+		var entry *backupEntry
+		if entry, err = mr.next(); err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+		if entry.Type == backupEntryFile && entry.Object != "" {
+			c.parent[entry.Inode] = entry
+		}
+	}
+}
+
+// walk backs up the entry and then its children in the order of names.
+func (c *backupCreator) walk(p string, info *proto.InodeInfo) (err error) {
+	if err = c.backup(p, info); err != nil {
+		return
+	}
+	if !proto.IsDir(info.Mode) {
+		return
+	}
+	var dentries []proto.Dentry
+	if dentries, err = c.vol.mw.ReadDir_ll(info.Inode); err != nil {
+		return fmt.Errorf("read dir %v: %v", p, err)
+	}
+	sort.Slice(dentries, func(i, j int) bool {
+		return dentries[i].Name < dentries[j].Name
+	})
+	for _, dentry := range dentries {
+		// the soft-deleted files in the trash are not backed up
+		if info.Inode == proto.RootIno && dentry.Name == proto.TrashDirName {
+			continue
+		}
+		var child *proto.InodeInfo
+		if child, err = c.vol.mw.InodeGet_ll(dentry.Inode); err != nil {
+			return fmt.Errorf("get inode of %v: %v", path.Join(p, dentry.Name), err)
+		}
+		if err = c.walk(path.Join(p, dentry.Name), child); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *backupCreator) backup(p string, info *proto.InodeInfo) (err error) {
+	entry := &backupEntry{
+		Path:       p,
+		Mode:       uint32(proto.OsMode(info.Mode)),
+		Uid:        info.Uid,
+		Gid:        info.Gid,
+		Size:       info.Size,
+		ModifyTime: info.ModifyTime.Unix(),
+		AccessTime: info.AccessTime.Unix(),
+		Inode:      info.Inode,
+	}
+	if entry.XAttrs, err = c.vol.xattrs(info.Inode); err != nil {
+		return fmt.Errorf("get xattrs of %v: %v", p, err)
+	}
+	switch {
+	case proto.IsDir(info.Mode):
+		entry.Type = backupEntryDir
+	case proto.IsSymlink(info.Mode):
+		entry.Type = backupEntrySymlink
+		entry.Target = string(info.Target)
+	default:
+		entry.Type = backupEntryFile
+		if first, ok := c.links[info.Inode]; ok {
+			entry.LinkTo = first
+			break
+		}
+		if err = c.backupFile(entry); err != nil {
+			if _, ok := err.(*backupReadError); !ok {
+				return fmt.Errorf("back up %v: %v", p, err)
+			}
+			stdout("Skip %v: %v\n", p, err)
+			c.header.Skipped++
+			return nil
+		}
+		if info.Nlink > 1 {
+			c.links[info.Inode] = p
+		}
+		c.header.Files++
+		c.header.Bytes += entry.Size
+	}
+	c.header.Entries++
+	return c.entries.Encode(entry)
+}
+
+// backupReadError is the failure to read a file, e.g. removed or truncated during the backup, which skips the file
+// instead of failing the backup.
+type backupReadError struct {
+	err error
+}
+
+func (e *backupReadError) Error() string {
+	return e.err.Error()
+}
+
+// backupFile uploads the data of the file unless its size, extents and modification time are the same as in the parent.
+// The extents are not changed by the overwrites, which update the modification time instead.
+func (c *backupCreator) backupFile(entry *backupEntry) (err error) {
+	if _, _, entry.Extents, err = c.vol.mw.GetExtents(entry.Inode); err != nil {
+		return &backupReadError{fmt.Errorf("get extents: %v", err)}
+	}
+	if prev, ok := c.parent[entry.Inode]; ok && prev.Size == entry.Size && prev.ModifyTime == entry.ModifyTime &&
+		equalExtents(prev.Extents, entry.Extents) {
+		entry.Object, entry.MD5 = prev.Object, prev.MD5
+		return
+	}
+	if err = c.vol.ec.OpenStream(entry.Inode); err != nil {
+		return &backupReadError{err}
+	}
+	defer func() {
+		c.vol.ec.CloseStream(entry.Inode)
+		c.vol.ec.EvictStream(entry.Inode)
+	}()
+	var (
+		h    = md5.New()
+		size int64
+		key  = c.key + path.Join(backupDataDir, strconv.FormatUint(entry.Inode, 10))
+	)
+	r := &backupFileReader{ec: c.vol.ec, ino: entry.Inode, size: entry.Size}
+	if size, err = c.store.put(key, io.TeeReader(r, h)); err != nil {
+		if r.err != nil {
+			return &backupReadError{r.err}
+		}
+		return
+	}
+	if uint64(size) != entry.Size {
+		return &backupReadError{fmt.Errorf("uploaded %v bytes, expected size %v", size, entry.Size)}
+	}
+	entry.Object, entry.MD5 = key, hex.EncodeToString(h.Sum(nil))
+	c.header.UploadedFiles++
+	c.header.UploadedBytes += entry.Size
+	return
+}
+
+func equalExtents(a, b []proto.ExtentKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// uploadManifest uploads the header followed by the entries spooled, which completes the backup.
+func (c *backupCreator) uploadManifest(entries io.Reader) (err error) {
+	pr, pw := io.Pipe()
+	go func() {
+		gw := gzip.NewWriter(pw)
+		err := json.NewEncoder(gw).Encode(c.header)
+		if err == nil {
+			_, err = io.Copy(gw, entries)
+		}
+		if err == nil {
+			err = gw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	_, err = c.store.put(c.key+backupManifestObject, pr)
+	pr.CloseWithError(err)
+	return
+}
+
+// backupFileReader reads a file of the volume up to the size, which fails if the file is truncated.
+type backupFileReader struct {
+	ec     stream.Client
+	ino    uint64
+	offset uint64
+	size   uint64
+	err    error
+}
+
+func (r *backupFileReader) Read(p []byte) (n int, err error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	size := len(p)
+	if rest := r.size - r.offset; uint64(size) > rest {
+		size = int(rest)
+	}
+	if n, err = r.ec.Read(r.ino, p, int(r.offset), size); err != nil && err != io.EOF {
+		r.err = err
+		return
+	}
+	if n == 0 {
+		r.err = fmt.Errorf("file is truncated at %v, expected size %v", r.offset, r.size)
+		return 0, r.err
+	}
+	r.offset += uint64(n)
+	return n, nil
+}
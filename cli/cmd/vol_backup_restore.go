@@ -0,0 +1,289 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+func newVolBackupRestoreCmd(client *master.MasterClient, config *backupStoreConfig) *cobra.Command {
+	var optTargetVol string
+	var cmd = &cobra.Command{
+		Use:   cmdVolBackupRestoreUse,
+		Short: cmdVolBackupRestoreShort,
+		Long: `Restore a backup of a volume to a directory of the volume, or of the volume given by
+--target-vol. The directory must not exist, it is created with its parents. The checksums of
+the files are verified when they are restored.`,
+		Args: cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err       error
+				store     *backupStore
+				view      *proto.ClusterView
+				vol       *backupVolume
+				restorer  *backupRestorer
+				volName   = args[0]
+				backupID  = args[1]
+				dir       = path.Clean("/" + args[2])
+				targetVol = optTargetVol
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if targetVol == "" {
+				targetVol = volName
+			}
+			if store, err = newBackupStore(config); err != nil {
+				return
+			}
+			if view, err = client.AdminAPI().GetCluster(); err != nil {
+				return
+			}
+			if vol, err = openBackupVolume(client, targetVol); err != nil {
+				return
+			}
+			defer vol.close()
+			if restorer, err = restoreBackup(store, vol, view.Name, volName, backupID, dir); err != nil {
+				return
+			}
+			stdout("Backup %v of volume %v is restored to %v of volume %v\n", backupID, volName, dir, targetVol)
+			stdout("  Entries        : %v\n", restorer.entries)
+			stdout("  Files          : %v (%v)\n", restorer.files, formatSize(restorer.bytes))
+			stdout("  Skipped links  : %v\n", restorer.skipped)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optTargetVol, "target-vol", "", "Specify the volume to restore to, the backed up volume by default")
+	return cmd
+}
+
+type backupRestorer struct {
+	store   *backupStore
+	vol     *backupVolume
+	inodes  map[string]uint64 // path in the manifest to the inode restored
+	dirs    []*backupEntry
+	buf     []byte
+	entries uint64
+	files   uint64
+	bytes   uint64
+	skipped uint64
+}
+
+func restoreBackup(store *backupStore, vol *backupVolume, cluster, volName, backupID, dir string) (r *backupRestorer, err error) {
+	header, mr, err := store.openManifest(cluster, volName, backupID)
+	if err != nil {
+		return
+	}
+	defer mr.close()
+	if dir == "/" {
+		return nil, fmt.Errorf("backup is not restored to the root directory")
+	}
+	var parent uint64
+	if parent, err = vol.mkdirAll(path.Dir(dir)); err != nil {
+		return
+	}
+	if _, _, err = vol.mw.Lookup_ll(parent, path.Base(dir)); err == nil {
+		return nil, fmt.Errorf("%v exists", dir)
+	} else if err != syscall.ENOENT {
+		return
+	}
+	var root *proto.InodeInfo
+	if root, err = vol.mw.Create_ll(parent, path.Base(dir), proto.Mode(os.ModeDir|0755), 0, 0, nil); err != nil {
+		return
+	}
+	r = &backupRestorer{
+		store:  store,
+		vol:    vol,
+		inodes: map[string]uint64{backupRootPath: root.Inode},
+		buf:    make([]byte, backupPartSize),
+	}
+	for {
+		var entry *backupEntry
+		if entry, err = mr.next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		if err = r.restore(entry); err != nil {
+			return nil, fmt.Errorf("restore %v: %v", entry.Path, err)
+		}
+		r.entries++
+	}
+	if r.entries != header.Entries {
+		return nil, fmt.Errorf("the backup is incomplete, %v of %v entries are found", r.entries, header.Entries)
+	}
+	// the times of directories are changed by their children, so they are set at last
+	for i := len(r.dirs) - 1; i >= 0; i-- {
+		if err = r.setattr(r.inodes[r.dirs[i].Path], r.dirs[i]); err != nil {
+			return nil, fmt.Errorf("restore %v: %v", r.dirs[i].Path, err)
+		}
+	}
+	return r, nil
+}
+
+func (v *backupVolume) mkdirAll(dir string) (ino uint64, err error) {
+	ino = proto.RootIno
+	for _, name := range strings.Split(dir, "/") {
+		if name == "" {
+			continue
+		}
+		var (
+			child uint64
+			mode  uint32
+			info  *proto.InodeInfo
+		)
+		if child, mode, err = v.mw.Lookup_ll(ino, name); err == nil {
+			if !proto.IsDir(mode) {
+				return 0, fmt.Errorf("%v is not a directory", name)
+			}
+			ino = child
+			continue
+		}
+		if err != syscall.ENOENT {
+			return
+		}
+		if info, err = v.mw.Create_ll(ino, name, proto.Mode(os.ModeDir|0755), 0, 0, nil); err != nil {
+			return
+		}
+		ino = info.Inode
+	}
+	return ino, nil
+}
+
+func (r *backupRestorer) restore(entry *backupEntry) (err error) {
+	var info *proto.InodeInfo
+	if entry.Path == backupRootPath {
+		r.dirs = append(r.dirs, entry)
+		return r.vol.setxattrs(r.inodes[backupRootPath], entry.XAttrs)
+	}
+	parent, ok := r.inodes[path.Dir(entry.Path)]
+	if !ok {
+		return fmt.Errorf("parent is not restored")
+	}
+	name := path.Base(entry.Path)
+	mode := proto.Mode(os.FileMode(entry.Mode))
+	switch entry.Type {
+	case backupEntryDir:
+		if info, err = r.vol.mw.Create_ll(parent, name, mode, entry.Uid, entry.Gid, nil); err != nil {
+			return
+		}
+		r.inodes[entry.Path] = info.Inode
+		r.dirs = append(r.dirs, entry)
+		return r.vol.setxattrs(info.Inode, entry.XAttrs)
+	case backupEntrySymlink:
+		if info, err = r.vol.mw.Create_ll(parent, name, mode, entry.Uid, entry.Gid, []byte(entry.Target)); err != nil {
+			return
+		}
+	case backupEntryFile:
+		if entry.LinkTo != "" {
+			target, ok := r.inodes[entry.LinkTo]
+			if !ok {
+				r.skipped++
+				return nil
+			}
+			_, err = r.vol.mw.Link(parent, name, target)
+			return
+		}
+		if info, err = r.vol.mw.Create_ll(parent, name, mode, entry.Uid, entry.Gid, nil); err != nil {
+			return
+		}
+		r.inodes[entry.Path] = info.Inode
+		if err = r.writeFile(info.Inode, entry); err != nil {
+			return
+		}
+		r.files++
+		r.bytes += entry.Size
+	default:
+		return fmt.Errorf("unknown entry type: %v", entry.Type)
+	}
+	if err = r.vol.setxattrs(info.Inode, entry.XAttrs); err != nil {
+		return
+	}
+	return r.setattr(info.Inode, entry)
+}
+
+// writeFile writes the data of the file downloaded from the store, and verifies the size and the checksum.
+func (r *backupRestorer) writeFile(ino uint64, entry *backupEntry) (err error) {
+	if entry.Object == "" {
+		return fmt.Errorf("no data in the backup")
+	}
+	body, err := r.store.get(entry.Object)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	if err = r.vol.ec.OpenStream(ino); err != nil {
+		return
+	}
+	defer func() {
+		r.vol.ec.CloseStream(ino)
+		r.vol.ec.EvictStream(ino)
+	}()
+	var (
+		h      = md5.New()
+		offset int
+	)
+	for {
+		n, readErr := body.Read(r.buf)
+		if n > 0 {
+			if _, err = r.vol.ec.Write(ino, offset, r.buf[:n], 0); err != nil {
+				return
+			}
+			h.Write(r.buf[:n])
+			offset += n
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if err = r.vol.ec.Flush(ino); err != nil {
+		return
+	}
+	if uint64(offset) != entry.Size {
+		return fmt.Errorf("size mismatch: expected %v, got %v", entry.Size, offset)
+	}
+	if checksum := hex.EncodeToString(h.Sum(nil)); checksum != entry.MD5 {
+		return fmt.Errorf("checksum mismatch: expected %v, got %v", entry.MD5, checksum)
+	}
+	return
+}
+
+func (r *backupRestorer) setattr(ino uint64, entry *backupEntry) error {
+	valid := proto.AttrMode | proto.AttrUid | proto.AttrGid | proto.AttrModifyTime | proto.AttrAccessTime
+	return r.vol.mw.Setattr(ino, valid, proto.Mode(os.FileMode(entry.Mode)), entry.Uid, entry.Gid,
+		entry.AccessTime, entry.ModifyTime)
+}
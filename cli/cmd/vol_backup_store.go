@@ -0,0 +1,226 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// objects larger than a part are uploaded by multipart upload, a part is buffered in memory
+	backupPartSize = 16 * 1024 * 1024
+
+	backupManifestObject = "manifest.gz"
+	backupDataDir        = "data"
+)
+
+// backupStoreConfig is the S3 compatible storage the backups are kept in.
+type backupStoreConfig struct {
+	endpoint  string
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+}
+
+func (c *backupStoreConfig) addFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&c.endpoint, "endpoint", "", "Specify the endpoint of the S3 storage, e.g. http://127.0.0.1:9000")
+	cmd.PersistentFlags().StringVar(&c.region, "region", "default", "Specify the region of the S3 storage")
+	cmd.PersistentFlags().StringVar(&c.bucket, "bucket", "", "Specify the bucket the backups are kept in")
+	cmd.PersistentFlags().StringVar(&c.prefix, "prefix", "chubaofs-backup", "Specify the key prefix of the backups in the bucket")
+	cmd.PersistentFlags().StringVar(&c.accessKey, "access-key", "", "Specify the access key, read from AWS_ACCESS_KEY_ID if not set")
+	cmd.PersistentFlags().StringVar(&c.secretKey, "secret-key", "", "Specify the secret key, read from AWS_SECRET_ACCESS_KEY if not set")
+}
+
+// backupObjectAPI is the part of the S3 client used by the store.
+type backupObjectAPI interface {
+	ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error
+	HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+	PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// backupStore keeps the backups of the volumes of a cluster under "prefix/cluster/volume/backupID/", the manifest of
+// a backup is uploaded after all its data, so a backup without the manifest is incomplete.
+type backupStore struct {
+	s3     backupObjectAPI
+	bucket string
+	prefix string
+}
+
+func newBackupStore(c *backupStoreConfig) (store *backupStore, err error) {
+	if c.endpoint == "" || c.bucket == "" {
+		return nil, fmt.Errorf("the endpoint and the bucket of the S3 storage must be given by --endpoint and --bucket")
+	}
+	accessKey, secretKey := c.accessKey, c.secretKey
+	if accessKey == "" {
+		accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if secretKey == "" {
+		secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	var sess *session.Session
+	if sess, err = session.NewSession(); err != nil {
+		return
+	}
+	config := aws.NewConfig()
+	config.Endpoint = aws.String(c.endpoint)
+	config.Region = aws.String(c.region)
+	config.Credentials = credentials.NewStaticCredentials(accessKey, secretKey, "")
+	config.S3ForcePathStyle = aws.Bool(true)
+	return &backupStore{s3: s3.New(sess, config), bucket: c.bucket, prefix: strings.Trim(c.prefix, "/")}, nil
+}
+
+func (s *backupStore) volumeKey(cluster, volume string) string {
+	return path.Join(s.prefix, cluster, volume) + "/"
+}
+
+func (s *backupStore) backupKey(cluster, volume, backupID string) string {
+	return path.Join(s.prefix, cluster, volume, backupID) + "/"
+}
+
+// listBackups returns the IDs of the complete backups of the volume in the order of creation.
+func (s *backupStore) listBackups(cluster, volume string) (ids []string, err error) {
+	prefix := s.volumeKey(cluster, volume)
+	var dirs []string
+	err = s.s3.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}, func(output *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, p := range output.CommonPrefixes {
+			dirs = append(dirs, strings.TrimSuffix(strings.TrimPrefix(aws.StringValue(p.Prefix), prefix), "/"))
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+	for _, id := range dirs {
+		var exist bool
+		if exist, err = s.exist(s.backupKey(cluster, volume, id) + backupManifestObject); err != nil {
+			return
+		}
+		if exist {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return
+}
+
+func (s *backupStore) exist(key string) (bool, error) {
+	_, err := s.s3.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err == nil {
+		return true, nil
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *backupStore) get(key string) (io.ReadCloser, error) {
+	output, err := s.s3.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("get %v: %v", key, err)
+	}
+	return output.Body, nil
+}
+
+// put uploads the content read from r, by multipart upload if it is larger than a part, and returns the size.
+func (s *backupStore) put(key string, r io.Reader) (size int64, err error) {
+	buf := make([]byte, backupPartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err = s.s3.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(buf[:n]),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("put %v: %v", key, err)
+		}
+		return int64(n), nil
+	}
+	if err != nil {
+		return
+	}
+
+	upload, err := s.s3.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create multipart upload of %v: %v", key, err)
+	}
+	defer func() {
+		if err != nil {
+			s.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(s.bucket),
+				Key:      aws.String(key),
+				UploadId: upload.UploadId,
+			})
+		}
+	}()
+	var parts []*s3.CompletedPart
+	for n > 0 {
+		var part *s3.UploadPartOutput
+		number := aws.Int64(int64(len(parts) + 1))
+		if part, err = s.s3.UploadPart(&s3.UploadPartInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(key),
+			UploadId:   upload.UploadId,
+			PartNumber: number,
+			Body:       bytes.NewReader(buf[:n]),
+		}); err != nil {
+			return 0, fmt.Errorf("upload part %v of %v: %v", *number, key, err)
+		}
+		parts = append(parts, &s3.CompletedPart{ETag: part.ETag, PartNumber: number})
+		size += int64(n)
+		if n, err = io.ReadFull(r, buf); err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		} else if err != nil {
+			return
+		}
+	}
+	if _, err = s.s3.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}); err != nil {
+		return 0, fmt.Errorf("complete multipart upload of %v: %v", key, err)
+	}
+	return
+}
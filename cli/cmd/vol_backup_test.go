@@ -0,0 +1,268 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/data/stream/streamtest"
+	"github.com/chubaofs/chubaofs/sdk/meta/metatest"
+)
+
+// fakeBackupS3 keeps the objects in memory, the objects of the tests are smaller than a part so the
+// multipart upload is not supported.
+type fakeBackupS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+var _ backupObjectAPI = (*fakeBackupS3)(nil)
+
+func newFakeBackupS3() *fakeBackupS3 {
+	return &fakeBackupS3{objects: make(map[string][]byte)}
+}
+
+func (f *fakeBackupS3) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	f.Lock()
+	prefix, delimiter := aws.StringValue(input.Prefix), aws.StringValue(input.Delimiter)
+	dirs := make(map[string]bool)
+	output := &s3.ListObjectsV2Output{}
+	for key := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			dirs[key[:len(prefix)+i+1]] = true
+			continue
+		}
+		output.Contents = append(output.Contents, &s3.Object{Key: aws.String(key)})
+	}
+	f.Unlock()
+	for dir := range dirs {
+		output.CommonPrefixes = append(output.CommonPrefixes, &s3.CommonPrefix{Prefix: aws.String(dir)})
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeBackupS3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.objects[aws.StringValue(input.Key)]; !ok {
+		return nil, awserr.New("NotFound", "Not Found", nil)
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (f *fakeBackupS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	data, ok := f.objects[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "Not Found", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (f *fakeBackupS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	data, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.objects[aws.StringValue(input.Key)] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeBackupS3) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	return nil, fmt.Errorf("multipart upload is not supported")
+}
+
+func (f *fakeBackupS3) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	return nil, fmt.Errorf("multipart upload is not supported")
+}
+
+func (f *fakeBackupS3) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	return nil, fmt.Errorf("multipart upload is not supported")
+}
+
+func (f *fakeBackupS3) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	return nil, fmt.Errorf("multipart upload is not supported")
+}
+
+func newTestBackupVolume(t *testing.T) (*metatest.MetaWrapper, *backupVolume) {
+	mw := metatest.NewMetaWrapper("vol", "owner")
+	ec, err := streamtest.NewExtentClient(&stream.ExtentConfig{
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+	})
+	if err != nil {
+		t.Fatalf("new extent client: %v", err)
+	}
+	return mw, &backupVolume{mw: mw, ec: ec}
+}
+
+func writeTestFile(t *testing.T, vol *backupVolume, parent uint64, name string, data []byte) uint64 {
+	info, err := vol.mw.Create_ll(parent, name, proto.Mode(0644), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("create %v: %v", name, err)
+	}
+	if err = vol.ec.OpenStream(info.Inode); err != nil {
+		t.Fatalf("open %v: %v", name, err)
+	}
+	defer vol.ec.CloseStream(info.Inode)
+	if _, err = vol.ec.Write(info.Inode, 0, data, 0); err != nil {
+		t.Fatalf("write %v: %v", name, err)
+	}
+	if err = vol.ec.Flush(info.Inode); err != nil {
+		t.Fatalf("flush %v: %v", name, err)
+	}
+	return info.Inode
+}
+
+func readTestFile(t *testing.T, vol *backupVolume, p string) []byte {
+	ino := uint64(proto.RootIno)
+	for _, name := range strings.Split(strings.Trim(p, "/"), "/") {
+		var err error
+		if ino, _, err = vol.mw.Lookup_ll(ino, name); err != nil {
+			t.Fatalf("lookup %v: %v", p, err)
+		}
+	}
+	info, err := vol.mw.InodeGet_ll(ino)
+	if err != nil {
+		t.Fatalf("get %v: %v", p, err)
+	}
+	if err = vol.ec.OpenStream(ino); err != nil {
+		t.Fatalf("open %v: %v", p, err)
+	}
+	defer vol.ec.CloseStream(ino)
+	data := make([]byte, info.Size)
+	if _, err = vol.ec.Read(ino, data, 0, len(data)); err != nil {
+		t.Fatalf("read %v: %v", p, err)
+	}
+	return data
+}
+
+// waitNextSecond waits until the second changes, since the backups are identified by the create time in seconds.
+func waitNextSecond() {
+	now := time.Now()
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+}
+
+func TestBackupIncremental(t *testing.T) {
+	mw, vol := newTestBackupVolume(t)
+	store := &backupStore{s3: newFakeBackupS3(), bucket: "backup", prefix: "test"}
+
+	fileA := writeTestFile(t, vol, proto.RootIno, "a", []byte("hello"))
+	dir, err := mw.Create_ll(proto.RootIno, "dir", proto.Mode(os.ModeDir|0755), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	writeTestFile(t, vol, dir.Inode, "b", []byte("world"))
+	trash, err := mw.Create_ll(proto.RootIno, proto.TrashDirName, proto.Mode(os.ModeDir|0700), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("create trash: %v", err)
+	}
+	writeTestFile(t, vol, trash.Inode, proto.TrashEntryName(100, time.Now()), []byte("deleted"))
+
+	header, err := createBackup(store, vol, "vol", false)
+	if err != nil {
+		t.Fatalf("create backup: %v", err)
+	}
+	if header.Parent != "" || header.Files != 2 || header.UploadedFiles != 2 || header.Entries != 4 {
+		t.Fatalf("unexpected first backup, the trash should be skipped: %+v", header)
+	}
+
+	// an overwrite keeps the extents of the file and updates the modify time only
+	info, _ := mw.InodeGet_ll(fileA)
+	if err = mw.Setattr(fileA, proto.AttrModifyTime, 0, 0, 0, 0, info.ModifyTime.Unix()+1); err != nil {
+		t.Fatalf("set modify time: %v", err)
+	}
+	waitNextSecond()
+	second, err := createBackup(store, vol, "vol", false)
+	if err != nil {
+		t.Fatalf("create second backup: %v", err)
+	}
+	if second.Parent != header.ID || second.Files != 2 || second.UploadedFiles != 1 {
+		t.Fatalf("unexpected second backup, only the overwritten file should be uploaded: %+v", second)
+	}
+
+	waitNextSecond()
+	third, err := createBackup(store, vol, "vol", false)
+	if err != nil {
+		t.Fatalf("create third backup: %v", err)
+	}
+	if third.Parent != second.ID || third.Files != 2 || third.UploadedFiles != 0 {
+		t.Fatalf("unexpected third backup, no file should be uploaded: %+v", third)
+	}
+
+	ids, err := store.listBackups(mw.Cluster(), "vol")
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("unexpected backups: ids(%v) err(%v)", ids, err)
+	}
+	if !sort.StringsAreSorted(ids) || ids[2] != third.ID {
+		t.Fatalf("backups are not in the order of creation: %v", ids)
+	}
+
+	if _, err = restoreBackup(store, vol, mw.Cluster(), "vol", third.ID, "/restored"); err != nil {
+		t.Fatalf("restore backup: %v", err)
+	}
+	if data := readTestFile(t, vol, "/restored/a"); string(data) != "hello" {
+		t.Fatalf("unexpected data of a: %q", data)
+	}
+	if data := readTestFile(t, vol, "/restored/dir/b"); string(data) != "world" {
+		t.Fatalf("unexpected data of b: %q", data)
+	}
+	restored, _, err := mw.Lookup_ll(proto.RootIno, "restored")
+	if err != nil {
+		t.Fatalf("lookup restored: %v", err)
+	}
+	if _, _, err = mw.Lookup_ll(restored, proto.TrashDirName); err == nil {
+		t.Fatalf("the trash should not be restored")
+	}
+}
+
+func TestBackupFull(t *testing.T) {
+	_, vol := newTestBackupVolume(t)
+	store := &backupStore{s3: newFakeBackupS3(), bucket: "backup", prefix: "test"}
+	writeTestFile(t, vol, proto.RootIno, "a", []byte("hello"))
+
+	if _, err := createBackup(store, vol, "vol", false); err != nil {
+		t.Fatalf("create backup: %v", err)
+	}
+	waitNextSecond()
+	header, err := createBackup(store, vol, "vol", true)
+	if err != nil {
+		t.Fatalf("create full backup: %v", err)
+	}
+	if header.Parent != "" || header.UploadedFiles != 1 {
+		t.Fatalf("unexpected full backup, all the files should be uploaded: %+v", header)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolChattrUse   = "chattr [VOLUME NAME] [PATH] [+|-][ia]"
+	cmdVolChattrShort = "Show or change the immutable and the append-only flags of a file"
+)
+
+func newVolChattrCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolChattrUse,
+		Short: cmdVolChattrShort,
+		Long: `Show the flags of a regular file of the volume, or add (+) or remove (-) the flags like chattr.
+The file with the immutable flag (i) can not be modified, linked, renamed or deleted, and the file with
+the append-only flag (a) can only be appended. The flags are enforced by the meta nodes, and the data
+nodes refuse to overwrite the data of the flagged files, even through the handles opened before.`,
+		Args: cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName, filePath = args[0], path.Clean("/" + args[1])
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var mw *meta.MetaWrapper
+			if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+				Volume:  volumeName,
+				Masters: client.Nodes(),
+			}); err != nil {
+				return
+			}
+			defer mw.Close()
+			var ino uint64
+			if ino, err = lookupPath(mw, filePath); err != nil {
+				return
+			}
+			var info *proto.InodeInfo
+			if info, err = mw.InodeGet_ll(ino); err != nil {
+				return
+			}
+			if len(args) > 2 {
+				var flags uint32
+				if flags, err = parseInodeFlags(info.Flags, args[2]); err != nil {
+					return
+				}
+				if err = mw.SetInodeFlags_ll(ino, flags); err != nil {
+					return
+				}
+				info.Flags = flags
+			}
+			stdout("%v %v\n", formatInodeFlags(info.Flags), filePath)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+// parseInodeFlags applies the mode like "+i" or "-a" to the flags.
+func parseInodeFlags(flags uint32, mode string) (uint32, error) {
+	if len(mode) < 2 || mode[0] != '+' && mode[0] != '-' {
+		return 0, fmt.Errorf("invalid mode %v, expect [+|-][ia]", mode)
+	}
+	var changed uint32
+	for _, c := range mode[1:] {
+		switch c {
+		case 'i':
+			changed |= proto.InodeFlagImmutable
+		case 'a':
+			changed |= proto.InodeFlagAppendOnly
+		default:
+			return 0, fmt.Errorf("invalid flag %c, expect i or a", c)
+		}
+	}
+	if mode[0] == '+' {
+		return flags | changed, nil
+	}
+	return flags &^ changed, nil
+}
+
+func formatInodeFlags(flags uint32) string {
+	var s = []byte("--")
+	if flags&proto.InodeFlagImmutable != 0 {
+		s[0] = 'i'
+	}
+	if flags&proto.InodeFlagAppendOnly != 0 {
+		s[1] = 'a'
+	}
+	return string(s)
+}
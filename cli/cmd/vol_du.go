@@ -0,0 +1,90 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolDuUse   = "du [VOLUME NAME] [PATH]"
+	cmdVolDuShort = "Show the disk usage of a path of the volume"
+)
+
+func newVolDuCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolDuUse,
+		Short: cmdVolDuShort,
+		Long: `Show the number of files, the number of subdirectories and the total size of the files in a
+directory of the volume recursively. The statistics are maintained by the meta nodes in the background,
+so they may fall behind the recent changes by minutes.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName, filePath = args[0], "/"
+			if len(args) > 1 {
+				filePath = path.Clean("/" + args[1])
+			}
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var mw *meta.MetaWrapper
+			if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+				Volume:  volumeName,
+				Masters: client.Nodes(),
+			}); err != nil {
+				return
+			}
+			defer mw.Close()
+			var ino uint64
+			if ino, err = lookupPath(mw, filePath); err != nil {
+				return
+			}
+			var info *proto.InodeInfo
+			if info, err = mw.InodeGet_ll(ino); err != nil {
+				return
+			}
+			summary := &proto.DirSummary{Files: 1, Bytes: info.Size}
+			if proto.IsDir(info.Mode) {
+				if summary, err = mw.GetDirSummary_ll(ino); err == syscall.ENODATA {
+					err = fmt.Errorf("the statistics of %v are not computed yet, please retry later", filePath)
+				}
+				if err != nil {
+					return
+				}
+			}
+			stdout("Path            : %v\n", filePath)
+			stdout("Files           : %v\n", summary.Files)
+			stdout("Subdirectories  : %v\n", summary.Subdirs)
+			stdout("Size            : %v (%v bytes)\n", formatSize(summary.Bytes), summary.Bytes)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,358 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolFsckUse   = "fsck [VOLUME NAME]"
+	cmdVolFsckShort = "Check the consistency between the metadata and the extents of the volume"
+)
+
+const (
+	defaultMetaNodeProfPort = "17220"
+	defaultDataNodeProfPort = "17320"
+
+	// extents modified within the period may be written by clients right now,
+	// so they are not reported as orphaned.
+	fsckExtentGracePeriod = time.Hour
+)
+
+type fsckDentry struct {
+	ParentId uint64
+	Name     string
+	Inode    uint64
+	Type     uint32
+}
+
+type fsckInode struct {
+	Inode   uint64
+	Type    uint32
+	Size    uint64
+	NLink   uint32
+	Extents []proto.ExtentKey
+}
+
+type fsckDataPartition struct {
+	id          uint64
+	extents     map[uint64]*storage.ExtentInfo
+	maxExtentID uint64
+	referenced  map[uint64]bool
+}
+
+type fsckMissingExtent struct {
+	inode  uint64
+	ek     proto.ExtentKey
+	reason string
+}
+
+type fsckOrphanedExtent struct {
+	partitionID uint64
+	extent      *storage.ExtentInfo
+}
+
+type fsckReport struct {
+	inodeCount       int
+	dentryCount      int
+	extentCount      int
+	danglingDentries []*fsckDentry
+	orphanedExtents  []*fsckOrphanedExtent
+	missingExtents   []*fsckMissingExtent
+}
+
+func newVolFsckCmd(client *master.MasterClient) *cobra.Command {
+	var optMetaPort string
+	var optDataPort string
+	var optRepair bool
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdVolFsckUse,
+		Short: cmdVolFsckShort,
+		Long: `Check the consistency between the metadata and the extents of the volume.
+Dangling dentries, orphaned extents and missing extents are reported.
+With --repair, the dangling dentries are removed after they are confirmed again,
+the extents are never changed.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var report *fsckReport
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if report, err = checkVolume(client, volumeName, optMetaPort, optDataPort); err != nil {
+				return
+			}
+			printFsckReport(report)
+			if !optRepair || len(report.danglingDentries) == 0 {
+				return
+			}
+			if !optYes {
+				stdout("Remove %v dangling dentries of volume [%v] (yes/no)[no]:", len(report.danglingDentries), volumeName)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			err = repairDanglingDentries(client, volumeName, report.danglingDentries)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optMetaPort, CliFlagMetaPort, defaultMetaNodeProfPort, "Specify the prof port of the meta nodes")
+	cmd.Flags().StringVar(&optDataPort, CliFlagDataPort, defaultDataNodeProfPort, "Specify the prof port of the data nodes")
+	cmd.Flags().BoolVar(&optRepair, CliFlagRepair, false, "Remove the dangling dentries")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+// checkVolume scans the extents of the data partitions before the metadata, so that the extents
+// written during the check are either referenced by the metadata, or newer than the scanned ones.
+func checkVolume(client *master.MasterClient, volumeName, metaPort, dataPort string) (report *fsckReport, err error) {
+	var (
+		dpView   *proto.DataPartitionsView
+		mpViews  []*proto.MetaPartitionView
+		dps      = make(map[uint64]*fsckDataPartition)
+		inodes   = make(map[uint64]*fsckInode)
+		dentries []*fsckDentry
+	)
+	report = &fsckReport{}
+	if dpView, err = client.ClientAPI().GetDataPartitions(volumeName); err != nil {
+		return
+	}
+	for _, view := range dpView.DataPartitions {
+		var dp *fsckDataPartition
+		if dp, err = getFsckDataPartition(view, dataPort); err != nil {
+			return
+		}
+		dps[dp.id] = dp
+		report.extentCount += len(dp.extents)
+	}
+	if mpViews, err = client.ClientAPI().GetMetaPartitions(volumeName); err != nil {
+		return
+	}
+	// dentries are loaded before inodes, since inodes are created before their dentries
+	for _, view := range mpViews {
+		var partDentries []*fsckDentry
+		if partDentries, err = getFsckDentries(view, metaPort); err != nil {
+			return
+		}
+		dentries = append(dentries, partDentries...)
+	}
+	for _, view := range mpViews {
+		if err = getFsckInodes(view, metaPort, inodes); err != nil {
+			return
+		}
+	}
+	report.inodeCount = len(inodes)
+	report.dentryCount = len(dentries)
+
+	for _, dentry := range dentries {
+		if _, ok := inodes[dentry.Inode]; !ok {
+			report.danglingDentries = append(report.danglingDentries, dentry)
+		}
+	}
+	for _, inode := range inodes {
+		if !proto.IsRegular(inode.Type) {
+			continue
+		}
+		for _, ek := range inode.Extents {
+			checkExtentKey(report, dps, inode.Inode, ek)
+		}
+	}
+	deadline := time.Now().Add(-fsckExtentGracePeriod).Unix()
+	for _, dp := range dps {
+		for id, extent := range dp.extents {
+			if storage.IsTinyExtent(id) || extent.IsDeleted || dp.referenced[id] || extent.ModifyTime > deadline {
+				continue
+			}
+			report.orphanedExtents = append(report.orphanedExtents, &fsckOrphanedExtent{partitionID: dp.id, extent: extent})
+		}
+	}
+	return
+}
+
+func checkExtentKey(report *fsckReport, dps map[uint64]*fsckDataPartition, inode uint64, ek proto.ExtentKey) {
+	dp, ok := dps[ek.PartitionId]
+	if !ok {
+		report.missingExtents = append(report.missingExtents, &fsckMissingExtent{inode: inode, ek: ek, reason: "data partition not found"})
+		return
+	}
+	dp.referenced[ek.ExtentId] = true
+	// tiny extents are shared by files, and the extents newer than the scanned ones are created during the check
+	if storage.IsTinyExtent(ek.ExtentId) || ek.ExtentId > dp.maxExtentID {
+		return
+	}
+	extent, ok := dp.extents[ek.ExtentId]
+	switch {
+	case !ok:
+		report.missingExtents = append(report.missingExtents, &fsckMissingExtent{inode: inode, ek: ek, reason: "extent not found"})
+	case extent.IsDeleted:
+		report.missingExtents = append(report.missingExtents, &fsckMissingExtent{inode: inode, ek: ek, reason: "extent deleted"})
+	case ek.ExtentOffset+uint64(ek.Size) > extent.Size:
+		report.missingExtents = append(report.missingExtents, &fsckMissingExtent{inode: inode, ek: ek,
+			reason: fmt.Sprintf("extent size %v is less than %v", extent.Size, ek.ExtentOffset+uint64(ek.Size))})
+	}
+}
+
+func profAddr(addr, port string) string {
+	return fmt.Sprintf("%v:%v", strings.Split(addr, ":")[0], port)
+}
+
+func getFsckDataPartition(view *proto.DataPartitionResponse, port string) (dp *fsckDataPartition, err error) {
+	addr := view.LeaderAddr
+	if addr == "" && len(view.Hosts) > 0 {
+		addr = view.Hosts[0]
+	}
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/partition?id=%v", profAddr(addr, port), view.PartitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get data partition %v from %v: %v", view.PartitionID, addr, resp.Status)
+	}
+	body := &struct {
+		Code int32  `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Extents []*storage.ExtentInfo `json:"extents"`
+		} `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode data partition %v from %v: %v", view.PartitionID, addr, err)
+	}
+	if body.Code != http.StatusOK {
+		return nil, fmt.Errorf("get data partition %v from %v: %v", view.PartitionID, addr, body.Msg)
+	}
+	dp = &fsckDataPartition{
+		id:         view.PartitionID,
+		extents:    make(map[uint64]*storage.ExtentInfo, len(body.Data.Extents)),
+		referenced: make(map[uint64]bool),
+	}
+	for _, extent := range body.Data.Extents {
+		dp.extents[extent.FileID] = extent
+		if extent.FileID > dp.maxExtentID {
+			dp.maxExtentID = extent.FileID
+		}
+	}
+	return
+}
+
+func getFsckDentries(view *proto.MetaPartitionView, port string) (dentries []*fsckDentry, err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/getAllDentry?pid=%v", profAddr(view.LeaderAddr, port), view.PartitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		Code int32         `json:"code"`
+		Msg  string        `json:"msg"`
+		Data []*fsckDentry `json:"data"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("decode dentries of meta partition %v from %v: %v", view.PartitionID, view.LeaderAddr, err)
+	}
+	if body.Code != http.StatusOK {
+		return nil, fmt.Errorf("get dentries of meta partition %v from %v: %v", view.PartitionID, view.LeaderAddr, body.Msg)
+	}
+	return body.Data, nil
+}
+
+func getFsckInodes(view *proto.MetaPartitionView, port string, inodes map[uint64]*fsckInode) (err error) {
+	var resp *http.Response
+	if resp, err = http.Get(fmt.Sprintf("http://%v/getAllInodes?pid=%v", profAddr(view.LeaderAddr, port), view.PartitionID)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		inode := &fsckInode{}
+		if err = dec.Decode(inode); err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("decode inodes of meta partition %v from %v: %v", view.PartitionID, view.LeaderAddr, err)
+		}
+		inodes[inode.Inode] = inode
+	}
+}
+
+// repairDanglingDentries removes the dentries whose inode still does not exist.
+func repairDanglingDentries(client *master.MasterClient, volumeName string, dentries []*fsckDentry) (err error) {
+	var mw *meta.MetaWrapper
+	if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:  volumeName,
+		Masters: client.Nodes(),
+	}); err != nil {
+		return
+	}
+	defer mw.Close()
+	var removed int
+	for _, dentry := range dentries {
+		if _, err = mw.InodeGet_ll(dentry.Inode); err != syscall.ENOENT {
+			stdout("Skip dentry [%v] of parent [%v]: inode [%v] is found again\n", dentry.Name, dentry.ParentId, dentry.Inode)
+			continue
+		}
+		if _, err = mw.Delete_ll(dentry.ParentId, dentry.Name, false); err != nil {
+			stdout("Remove dentry [%v] of parent [%v] failed: %v\n", dentry.Name, dentry.ParentId, err)
+			continue
+		}
+		removed++
+	}
+	stdout("%v of %v dangling dentries have been removed.\n", removed, len(dentries))
+	return nil
+}
+
+func printFsckReport(report *fsckReport) {
+	stdout("Inodes          : %v\n", report.inodeCount)
+	stdout("Dentries        : %v\n", report.dentryCount)
+	stdout("Extents         : %v\n", report.extentCount)
+	stdout("\nDangling dentries: %v\n", len(report.danglingDentries))
+	for _, dentry := range report.danglingDentries {
+		stdout("  parent(%v) name(%v) inode(%v)\n", dentry.ParentId, dentry.Name, dentry.Inode)
+	}
+	stdout("\nOrphaned extents: %v\n", len(report.orphanedExtents))
+	for _, orphaned := range report.orphanedExtents {
+		stdout("  partition(%v) extent(%v) size(%v) modified(%v)\n",
+			orphaned.partitionID, orphaned.extent.FileID, orphaned.extent.Size, formatTime(orphaned.extent.ModifyTime))
+	}
+	stdout("\nMissing extents : %v\n", len(report.missingExtents))
+	for _, missing := range report.missingExtents {
+		stdout("  inode(%v) %v: %v\n", missing.inode, missing.ek.String(), missing.reason)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolLocateUse   = "locate [VOLUME NAME] [PATH]"
+	cmdVolLocateShort = "Locate the meta partition, extents and data nodes of a file"
+)
+
+type fileLocation struct {
+	path           string
+	inode          *proto.InodeInfo
+	metaPartition  *proto.MetaPartitionView
+	extents        []proto.ExtentKey
+	dataPartitions map[uint64]*proto.DataPartitionInfo
+}
+
+func newVolLocateCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolLocateUse,
+		Short: cmdVolLocateShort,
+		Long: `Resolve the path to its inode, and show the meta partition holding the inode,
+the extents of the file, and the data nodes and disks holding the replicas of each extent.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var location *fileLocation
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if location, err = locateFile(client, args[0], args[1]); err != nil {
+				return
+			}
+			printFileLocation(location)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func locateFile(client *master.MasterClient, volumeName, path string) (location *fileLocation, err error) {
+	var mw *meta.MetaWrapper
+	if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:  volumeName,
+		Masters: client.Nodes(),
+	}); err != nil {
+		return
+	}
+	defer mw.Close()
+
+	location = &fileLocation{
+		path:           path,
+		dataPartitions: make(map[uint64]*proto.DataPartitionInfo),
+	}
+	var ino uint64
+	if ino, err = lookupPath(mw, path); err != nil {
+		return
+	}
+	if location.inode, err = mw.InodeGet_ll(ino); err != nil {
+		return nil, fmt.Errorf("get inode %v: %v", ino, err)
+	}
+
+	var views []*proto.MetaPartitionView
+	if views, err = client.ClientAPI().GetMetaPartitions(volumeName); err != nil {
+		return
+	}
+	for _, view := range views {
+		if ino >= view.Start && ino <= view.End {
+			location.metaPartition = view
+			break
+		}
+	}
+
+	if !proto.IsRegular(location.inode.Mode) {
+		return
+	}
+	if _, _, location.extents, err = mw.GetExtents(ino); err != nil {
+		return nil, fmt.Errorf("get extents of inode %v: %v", ino, err)
+	}
+	for _, ek := range location.extents {
+		if _, ok := location.dataPartitions[ek.PartitionId]; ok {
+			continue
+		}
+		var partition *proto.DataPartitionInfo
+		if partition, err = client.AdminAPI().GetDataPartition(volumeName, ek.PartitionId); err != nil {
+			return nil, fmt.Errorf("get data partition %v: %v", ek.PartitionId, err)
+		}
+		location.dataPartitions[ek.PartitionId] = partition
+	}
+	return
+}
+
+// lookupPath resolves the path from the root of the volume, the last component can be of any type.
+func lookupPath(mw *meta.MetaWrapper, path string) (ino uint64, err error) {
+	ino = proto.RootIno
+	for _, name := range strings.Split(path, "/") {
+		if name == "" {
+			continue
+		}
+		if ino, _, err = mw.Lookup_ll(ino, name); err != nil {
+			return 0, fmt.Errorf("lookup %v in %v: %v", name, path, err)
+		}
+	}
+	return
+}
+
+func printFileLocation(location *fileLocation) {
+	inode := location.inode
+	stdout("Path            : %v\n", location.path)
+	stdout("Inode           : %v\n", inode.Inode)
+	stdout("Type            : %v\n", formatInodeType(inode.Mode))
+	stdout("Size            : %v\n", formatSize(inode.Size))
+	stdout("Nlink           : %v\n", inode.Nlink)
+	if mp := location.metaPartition; mp != nil {
+		stdout("Meta partition  : %v\n", mp.PartitionID)
+		stdout("Inode range     : %v ~ %v\n", mp.Start, mp.End)
+		stdout("Meta leader     : %v\n", mp.LeaderAddr)
+		stdout("Meta members    : %v\n", strings.Join(mp.Members, ", "))
+	} else {
+		stdout("Meta partition  : N/A\n")
+	}
+	if !proto.IsRegular(inode.Mode) {
+		return
+	}
+
+	stdout("\nExtents:\n")
+	stdout("%v\n", fileExtentTableHeader)
+	for _, ek := range location.extents {
+		stdout("%v\n", formatFileExtentTableRow(ek))
+	}
+
+	ids := make([]uint64, 0, len(location.dataPartitions))
+	for id := range location.dataPartitions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	stdout("\nData partitions:\n")
+	stdout("%v\n", extentReplicaTableHeader)
+	for _, id := range ids {
+		partition := location.dataPartitions[id]
+		for _, host := range partition.Hosts {
+			stdout("%v\n", formatExtentReplicaTableRow(partition, host))
+		}
+	}
+}
+
+func formatInodeType(mode uint32) string {
+	switch {
+	case proto.IsDir(mode):
+		return "Directory"
+	case proto.IsSymlink(mode):
+		return "Symlink"
+	case proto.IsRegular(mode):
+		return "File"
+	default:
+		return proto.OsMode(mode).Type().String()
+	}
+}
+
+func findDataReplica(partition *proto.DataPartitionInfo, addr string) *proto.DataReplica {
+	for _, replica := range partition.Replicas {
+		if replica.Addr == addr {
+			return replica
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolRmdirUse   = "rmdir [VOLUME NAME] [PATH]"
+	cmdVolRmdirShort = "Remove a directory of the volume"
+)
+
+func newVolRmdirCmd(client *master.MasterClient) *cobra.Command {
+	var optRecursive bool
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdVolRmdirUse,
+		Short: cmdVolRmdirShort,
+		Long: `Remove an empty directory of the volume. With --recursive, the directory is removed with
+everything in it: it disappears from the volume at once, and the files in it are removed by the
+meta nodes in the background, without being moved to the trash.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName, dirPath = args[0], path.Clean("/" + args[1])
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if dirPath == "/" {
+				err = fmt.Errorf("the root directory can not be removed")
+				return
+			}
+			if optRecursive && !optYes {
+				stdout("Directory [%v] of volume [%v] and everything in it will be deleted permanently (yes/no)[no]:", dirPath, volumeName)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var mw *meta.MetaWrapper
+			if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+				Volume:  volumeName,
+				Masters: client.Nodes(),
+			}); err != nil {
+				return
+			}
+			defer mw.Close()
+			var parentID uint64
+			if parentID, err = lookupPath(mw, path.Dir(dirPath)); err != nil {
+				return
+			}
+			if optRecursive {
+				err = mw.RemoveRecursive(parentID, path.Base(dirPath))
+			} else {
+				_, err = mw.Delete_ll(parentID, path.Base(dirPath), true)
+			}
+			if err != nil {
+				return
+			}
+			stdout("Remove directory [%v] of volume [%v] successfully.\n", dirPath, volumeName)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optRecursive, CliFlagRecursive, "r", false, "Remove the directory and everything in it")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
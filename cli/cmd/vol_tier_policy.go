@@ -0,0 +1,187 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolTierPolicyUse   = "tier-policy [COMMAND]"
+	cmdVolTierPolicyShort = "Manage the cold file report rules of the volume"
+)
+
+func newVolTierPolicyCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolTierPolicyUse,
+		Short: cmdVolTierPolicyShort,
+		Long: `Manage the tier rules of the volume. The meta nodes count the files not accessed for the
+inactive days of each enabled rule, which are shown as the candidates of the rule. The rules are
+read-only, they report the cold files and their size but never migrate the data.`,
+	}
+	cmd.AddCommand(
+		newVolTierPolicySetCmd(client),
+		newVolTierPolicyDelCmd(client),
+		newVolTierPolicyInfoCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdVolTierPolicySetShort = "Add or update a tier rule of the volume"
+)
+
+func newVolTierPolicySetCmd(client *master.MasterClient) *cobra.Command {
+	var optInactiveDays uint32
+	var optEnable string
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [VOLUME NAME] [RULE NAME]",
+		Short: cmdVolTierPolicySetShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var rule = &proto.TierRule{
+				Name:         args[1],
+				InactiveDays: optInactiveDays,
+				Enable:       true,
+			}
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optEnable != "" {
+				if rule.Enable, err = strconv.ParseBool(optEnable); err != nil {
+					return
+				}
+			}
+			if err = rule.Validate(); err != nil {
+				return
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			if err = client.AdminAPI().SetVolTierRule(volumeName, calcAuthKey(svv.Owner), rule); err != nil {
+				return
+			}
+			stdout("Tier rule [%v] of volume [%v] has been set successfully.\n", rule.Name, volumeName)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Uint32Var(&optInactiveDays, CliFlagInactiveDays, 30, "Match the files not accessed for the specified days")
+	cmd.Flags().StringVar(&optEnable, CliFlagEnable, "", "Enable or disable the rule")
+	return cmd
+}
+
+const (
+	cmdVolTierPolicyDelShort = "Delete a tier rule of the volume"
+)
+
+func newVolTierPolicyDelCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   CliOpDelete + " [VOLUME NAME] [RULE NAME]",
+		Short: cmdVolTierPolicyDelShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var ruleName = args[1]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes {
+				stdout("Delete tier rule [%v] of volume [%v] (yes/no)[no]:", ruleName, volumeName)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			if err = client.AdminAPI().DeleteVolTierRule(volumeName, calcAuthKey(svv.Owner), ruleName); err != nil {
+				return
+			}
+			stdout("Tier rule [%v] of volume [%v] has been deleted successfully.\n", ruleName, volumeName)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+const (
+	cmdVolTierPolicyInfoShort = "Show tiering policy of the volume"
+)
+
+func newVolTierPolicyInfoCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpInfo + " [VOLUME NAME]",
+		Short: cmdVolTierPolicyInfoShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var view *proto.VolTierPolicyView
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if view, err = client.AdminAPI().GetVolTierPolicy(args[0]); err != nil {
+				return
+			}
+			stdout("Rules:\n")
+			stdout("%v\n", tierRuleTableHeader)
+			for _, rule := range view.Rules {
+				stdout("%v\n", formatTierRuleTableRow(rule))
+			}
+			stdout("\nCandidates:\n")
+			stdout("%v\n", tierRuleStatTableHeader)
+			for _, stat := range view.Stats {
+				stdout("%v\n", formatTierRuleStatTableRow(stat))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,187 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolTrashUse   = "trash [COMMAND]"
+	cmdVolTrashShort = "Manage the soft-deleted files in the trash of the volume"
+)
+
+func newVolTrashCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolTrashUse,
+		Short: cmdVolTrashShort,
+	}
+	cmd.AddCommand(
+		newVolTrashListCmd(client),
+		newVolTrashRestoreCmd(client),
+		newVolTrashPurgeCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdVolTrashListShort    = "List the soft-deleted files in the trash"
+	cmdVolTrashRestoreShort = "Restore the files in the trash to where they were deleted"
+	cmdVolTrashPurgeShort   = "Delete the expired files in the trash permanently"
+)
+
+func newTrashMetaWrapper(client *master.MasterClient, volumeName string) (*meta.MetaWrapper, error) {
+	return meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:  volumeName,
+		Masters: client.Nodes(),
+	})
+}
+
+func newVolTrashListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpList + " [VOLUME NAME]",
+		Short: cmdVolTrashListShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var mw *meta.MetaWrapper
+			if mw, err = newTrashMetaWrapper(client, volumeName); err != nil {
+				return
+			}
+			defer mw.Close()
+			var entries []*meta.TrashEntry
+			if entries, err = mw.ListTrash(); err != nil {
+				return
+			}
+			stdout("%v\n", trashEntryTableHeader)
+			for _, entry := range entries {
+				stdout("%v\n", formatTrashEntryTableRow(entry))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newVolTrashRestoreCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "restore [VOLUME NAME] [NAME]...",
+		Short: cmdVolTrashRestoreShort,
+		Long: `Restore the files in the trash to the directories and names they were deleted with.
+The names are the ones listed by "trash list". A file can not be restored if its directory
+has been deleted, or its name has been used by another file.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var mw *meta.MetaWrapper
+			if mw, err = newTrashMetaWrapper(client, volumeName); err != nil {
+				return
+			}
+			defer mw.Close()
+			var failed int
+			for _, name := range args[1:] {
+				if e := mw.RestoreTrash(name); e != nil {
+					stdout("Restore [%v] failed: %v\n", name, e)
+					failed++
+					continue
+				}
+				stdout("Restore [%v] successfully.\n", name)
+			}
+			if failed > 0 {
+				err = fmt.Errorf("%v of %v files are not restored", failed, len(args)-1)
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newVolTrashPurgeCmd(client *master.MasterClient) *cobra.Command {
+	var optAll bool
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   "purge [VOLUME NAME]",
+		Short: cmdVolTrashPurgeShort,
+		Long: `Delete the files which have been kept in the trash for the trash days of the volume permanently.
+The expired files are also purged by the clients of the volume periodically, except that the trash
+is disabled. With --all, every file in the trash is purged.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes {
+				if optAll {
+					stdout("All files in the trash of volume [%v] will be deleted permanently (yes/no)[no]:", volumeName)
+				} else {
+					stdout("Expired files in the trash of volume [%v] will be deleted permanently (yes/no)[no]:", volumeName)
+				}
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var mw *meta.MetaWrapper
+			if mw, err = newTrashMetaWrapper(client, volumeName); err != nil {
+				return
+			}
+			defer mw.Close()
+			var purged int
+			purged, err = mw.PurgeTrash(optAll)
+			stdout("%v files purged from the trash of volume [%v].\n", purged, volumeName)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVar(&optAll, CliFlagAll, false, "Purge all files in the trash regardless of the delete time")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
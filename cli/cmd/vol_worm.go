@@ -0,0 +1,143 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdVolWormUse   = "worm [COMMAND]"
+	cmdVolWormShort = "Manage the write-once-read-many retention of the volume"
+)
+
+func newVolWormCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolWormUse,
+		Short: cmdVolWormShort,
+	}
+	cmd.AddCommand(
+		newVolWormSetCmd(client),
+		newVolWormOverrideCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdVolWormSetShort      = "Set the retention days of files in the volume"
+	cmdVolWormOverrideShort = "Allow files in the retention period to be modified or deleted for a while"
+)
+
+func newVolWormSetCmd(client *master.MasterClient) *cobra.Command {
+	var optRetentionDays uint32
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [VOLUME NAME]",
+		Short: cmdVolWormSetShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes {
+				stdout("Files of volume [%v] can not be modified or deleted within %v days after creation, "+
+					"and the retention can not be reduced later (yes/no)[no]:", volumeName, optRetentionDays)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			if err = client.AdminAPI().SetVolWormRetention(volumeName, calcAuthKey(svv.Owner), optRetentionDays); err != nil {
+				return
+			}
+			stdout("Retention of volume [%v] has been set to %v days.\n", volumeName, optRetentionDays)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Uint32Var(&optRetentionDays, CliFlagRetentionDays, 0, "Specify the retention days of files")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func newVolWormOverrideCmd(client *master.MasterClient) *cobra.Command {
+	var optDuration time.Duration
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   CliOpOverride + " [VOLUME NAME]",
+		Short: cmdVolWormOverrideShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !optYes && optDuration > 0 {
+				stdout("Files of volume [%v] in the retention period can be modified or deleted in the next %v (yes/no)[no]:",
+					volumeName, optDuration)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			var until int64
+			if until, err = client.AdminAPI().OverrideVolWorm(volumeName, calcAuthKey(svv.Owner), optDuration); err != nil {
+				return
+			}
+			if optDuration == 0 {
+				stdout("Retention override of volume [%v] has been ended.\n", volumeName)
+				return
+			}
+			stdout("Retention of volume [%v] has been overridden until %v.\n", volumeName, formatTime(until))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().DurationVar(&optDuration, CliFlagDuration, time.Hour, "Specify how long the override lasts, 0 ends the override")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
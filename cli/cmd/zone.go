@@ -35,6 +35,7 @@ func newZoneCmd(client *sdk.MasterClient) *cobra.Command {
 	cmd.AddCommand(
 		newZoneListCmd(client),
 		newZoneInfoCmd(client),
+		newZoneRepairLinkCmd(client),
 	)
 	return cmd
 }
@@ -0,0 +1,107 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	sdk "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdZoneRepairLinkUse   = "repair-link [COMMAND]"
+	cmdZoneRepairLinkShort = "Manage the bandwidth budgets of the repair traffic between zones"
+)
+
+func newZoneRepairLinkCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdZoneRepairLinkUse,
+		Short: cmdZoneRepairLinkShort,
+	}
+	cmd.AddCommand(
+		newZoneRepairLinkSetCmd(client),
+		newZoneRepairLinkListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdZoneRepairLinkSetShort  = "Set the repair bandwidth from the source zone to the destination zone"
+	cmdZoneRepairLinkListShort = "List the repair bandwidth budgets between zones"
+)
+
+func newZoneRepairLinkSetCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpSet + " [SRC ZONE] [DST ZONE] [BANDWIDTH]",
+		Short: cmdZoneRepairLinkSetShort,
+		Long: `Set the bandwidth in MB/s that the data nodes of the destination zone may use in total
+to repair their replicas from the data nodes of the source zone. Zero removes the budget.`,
+		Args: cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var bandwidth uint64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if bandwidth, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+				return
+			}
+			if err = client.AdminAPI().SetRepairLink(args[0], args[1], bandwidth); err != nil {
+				return
+			}
+			if bandwidth == 0 {
+				stdout("Repair bandwidth from zone [%v] to zone [%v] is no longer limited.\n", args[0], args[1])
+				return
+			}
+			stdout("Repair bandwidth from zone [%v] to zone [%v] is set to %vMB/s.\n", args[0], args[1], bandwidth)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 1 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validZones(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newZoneRepairLinkListCmd(client *sdk.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdZoneRepairLinkListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var links []*proto.RepairLink
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if links, err = client.AdminAPI().ListRepairLinks(); err != nil {
+				return
+			}
+			stdout("%v\n", repairLinkTableHeader)
+			for _, link := range links {
+				stdout("%v\n", formatRepairLinkTableRow(link))
+			}
+		},
+	}
+	return cmd
+}
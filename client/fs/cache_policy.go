@@ -0,0 +1,208 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"sync/atomic"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The cache policy of a subtree is set by the xattr "cfs.cache" on its root directory,
+// and is inherited by all the files and directories under it until another directory
+// sets its own policy. "pin" keeps the inodes, dentries and file data cached for PinnedValidDuration,
+// "none" caches nothing so that every access goes to the meta and data nodes, and "default"
+// follows the mount options.
+const (
+	XAttrKeyCachePolicy = "cfs.cache"
+
+	CachePolicyDefault = "default"
+	CachePolicyPin     = "pin"
+	CachePolicyNone    = "none"
+)
+
+const (
+	// the expiration duration of the pinned inodes and dentries in both the inode cache and the FUSE cache
+	PinnedValidDuration = 10 * time.Minute
+)
+
+type cachePolicy uint32
+
+const (
+	cachePolicyInherit cachePolicy = iota
+	cachePolicyDefault
+	cachePolicyPin
+	cachePolicyNone
+)
+
+func parseCachePolicy(value string) (policy cachePolicy, ok bool) {
+	switch value {
+	case "":
+		return cachePolicyInherit, true
+	case CachePolicyDefault:
+		return cachePolicyDefault, true
+	case CachePolicyPin:
+		return cachePolicyPin, true
+	case CachePolicyNone:
+		return cachePolicyNone, true
+	default:
+		return cachePolicyInherit, false
+	}
+}
+
+func (p cachePolicy) String() string {
+	switch p {
+	case cachePolicyPin:
+		return CachePolicyPin
+	case cachePolicyNone:
+		return CachePolicyNone
+	case cachePolicyDefault:
+		return CachePolicyDefault
+	default:
+		return "inherit"
+	}
+}
+
+// entryValid returns the duration the kernel may cache a dentry for.
+func (p cachePolicy) entryValid() time.Duration {
+	switch p {
+	case cachePolicyPin:
+		return PinnedValidDuration
+	case cachePolicyNone:
+		return 0
+	default:
+		return LookupValidDuration
+	}
+}
+
+// attrValid returns the duration the kernel may cache the attributes of an inode for.
+func (p cachePolicy) attrValid() time.Duration {
+	switch p {
+	case cachePolicyPin:
+		return PinnedValidDuration
+	case cachePolicyNone:
+		return 0
+	default:
+		return AttrValidDuration
+	}
+}
+
+// openFlags returns the flags telling the kernel how to cache the data of an opened file.
+func (p cachePolicy) openFlags(keepCache bool) fuse.OpenResponseFlags {
+	switch p {
+	case cachePolicyPin:
+		return fuse.OpenKeepCache
+	case cachePolicyNone:
+		return fuse.OpenDirectIO
+	default:
+		if keepCache {
+			return fuse.OpenKeepCache
+		}
+		return 0
+	}
+}
+
+// cachePolicy returns the effective cache policy of the directory and its subtree.
+func (d *Dir) cachePolicy() cachePolicy {
+	if own := cachePolicy(atomic.LoadUint32(&d.ownPolicy)); own != cachePolicyInherit {
+		return own
+	}
+	if inherited := cachePolicy(atomic.LoadUint32(&d.inheritedPolicy)); inherited != cachePolicyInherit {
+		return inherited
+	}
+	return cachePolicyDefault
+}
+
+func (d *Dir) inheritCachePolicy(policy cachePolicy) {
+	atomic.StoreUint32(&d.inheritedPolicy, uint32(policy))
+}
+
+// loadCachePolicy reads the own cache policy of the directory from its xattr.
+func (d *Dir) loadCachePolicy() {
+	if !d.super.enableXattr {
+		return
+	}
+	info, err := d.super.mw.XAttrGet_ll(d.info.Inode, XAttrKeyCachePolicy)
+	if err != nil {
+		log.LogWarnf("loadCachePolicy: ino(%v) err(%v)", d.info.Inode, err)
+		return
+	}
+	value := string(info.Get(XAttrKeyCachePolicy))
+	policy, ok := parseCachePolicy(value)
+	if !ok {
+		log.LogWarnf("loadCachePolicy: ino(%v) invalid policy(%v)", d.info.Inode, value)
+	}
+	atomic.StoreUint32(&d.ownPolicy, uint32(policy))
+}
+
+// cachePolicy returns the cache policy of the file, which is inherited from the directory it is looked up from.
+func (f *File) cachePolicy() cachePolicy {
+	if inherited := cachePolicy(atomic.LoadUint32(&f.inheritedPolicy)); inherited != cachePolicyInherit {
+		return inherited
+	}
+	return cachePolicyDefault
+}
+
+func (f *File) inheritCachePolicy(policy cachePolicy) {
+	atomic.StoreUint32(&f.inheritedPolicy, uint32(policy))
+}
+
+// adoptChild passes the cache policy of the directory down to the child node.
+// It is done on every lookup, so a changed policy reaches the cached nodes of the subtree as they are accessed.
+func (d *Dir) adoptChild(child fs.Node) {
+	policy := d.cachePolicy()
+	switch node := child.(type) {
+	case *Dir:
+		node.inheritCachePolicy(policy)
+	case *File:
+		node.inheritCachePolicy(policy)
+	}
+}
+
+// InodeGetWithPolicy returns the inode info honoring the cache policy,
+// the inode cache is bypassed by "none" and holds the pinned inodes longer.
+func (s *Super) InodeGetWithPolicy(ino uint64, policy cachePolicy) (*proto.InodeInfo, error) {
+	switch policy {
+	case cachePolicyNone:
+		s.ic.Delete(ino)
+		info, err := s.mw.InodeGet_ll(ino)
+		if err != nil || info == nil {
+			log.LogErrorf("InodeGetWithPolicy: ino(%v) err(%v) info(%v)", ino, err, info)
+			if err != nil {
+				return nil, ParseError(err)
+			}
+			return nil, fuse.ENOENT
+		}
+		s.ec.RefreshExtentsCache(ino)
+		return info, nil
+	case cachePolicyPin:
+		if info := s.ic.Get(ino); info != nil {
+			return info, nil
+		}
+		info, err := s.InodeGet(ino)
+		if err != nil {
+			return nil, err
+		}
+		s.ic.PutWithExpiration(info, PinnedValidDuration)
+		return info, nil
+	default:
+		return s.InodeGet(ino)
+	}
+}
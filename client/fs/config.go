@@ -0,0 +1,143 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The config of the client below can be changed through its control port without remounting,
+// so that the page cache of the kernel and the inode cache are kept.
+
+func (s *Super) initConfig(opt *proto.MountOptions, inodeExpiration time.Duration, inodeCacheSize int) {
+	s.conf = proto.ClientConfig{
+		IcacheTimeout: int64(inodeExpiration / time.Second),
+		IcacheSize:    int64(inodeCacheSize),
+		LogLevel:      strings.ToLower(opt.Loglvl),
+		FollowerRead:  opt.FollowerRead,
+	}
+	if opt.ReadRate > 0 {
+		s.conf.ReadRate = opt.ReadRate
+	}
+	if opt.WriteRate > 0 {
+		s.conf.WriteRate = opt.WriteRate
+	}
+}
+
+// GetConfig replies the config of the client that can be changed at runtime.
+func (s *Super) GetConfig(w http.ResponseWriter, r *http.Request) {
+	s.confLock.Lock()
+	conf := s.conf
+	s.confLock.Unlock()
+	replyJSON(w, http.StatusOK, "", &conf)
+}
+
+// SetConfig changes the config of the client given by the form values, and replies the changed config.
+// None of the given values takes effect if any of them is invalid.
+func (s *Super) SetConfig(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		replyJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	s.confLock.Lock()
+	defer s.confLock.Unlock()
+	conf := s.conf
+	var (
+		logLevel log.Level
+		err      error
+	)
+	if conf.ReadRate, err = parseConfigInt(r, proto.ClientConfigReadRate, conf.ReadRate, 0); err != nil {
+		replyJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if conf.WriteRate, err = parseConfigInt(r, proto.ClientConfigWriteRate, conf.WriteRate, 0); err != nil {
+		replyJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if conf.IcacheTimeout, err = parseConfigInt(r, proto.ClientConfigIcacheTimeout, conf.IcacheTimeout, 0); err != nil {
+		replyJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if conf.IcacheSize, err = parseConfigInt(r, proto.ClientConfigIcacheSize, conf.IcacheSize, 1); err != nil {
+		replyJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if value := r.FormValue(proto.ClientConfigLogLevel); value != "" {
+		if logLevel, err = log.ParseLevel(value); err != nil {
+			replyJSON(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		conf.LogLevel = strings.ToLower(value)
+	}
+	if value := r.FormValue(proto.ClientConfigFollowerRead); value != "" {
+		if conf.FollowerRead, err = strconv.ParseBool(value); err != nil {
+			replyJSON(w, http.StatusBadRequest, fmt.Sprintf("invalid %v: %v", proto.ClientConfigFollowerRead, value), nil)
+			return
+		}
+	}
+
+	if conf.ReadRate != s.conf.ReadRate {
+		s.ec.SetReadRate(int(conf.ReadRate))
+	}
+	if conf.WriteRate != s.conf.WriteRate {
+		s.ec.SetWriteRate(int(conf.WriteRate))
+	}
+	if conf.IcacheTimeout != s.conf.IcacheTimeout {
+		s.ic.SetExpiration(time.Duration(conf.IcacheTimeout) * time.Second)
+	}
+	if conf.IcacheSize != s.conf.IcacheSize {
+		s.ic.SetMaxElements(int(conf.IcacheSize))
+	}
+	if conf.LogLevel != s.conf.LogLevel {
+		log.SetLevel(logLevel)
+	}
+	if conf.FollowerRead != s.conf.FollowerRead {
+		s.ec.SetFollowerRead(conf.FollowerRead)
+	}
+	log.LogWarnf("SetConfig: volume(%v) config changed from %+v to %+v", s.volname, s.conf, conf)
+	s.conf = conf
+	replyJSON(w, http.StatusOK, "", &conf)
+}
+
+func parseConfigInt(r *http.Request, key string, old, min int64) (val int64, err error) {
+	value := r.FormValue(key)
+	if value == "" {
+		return old, nil
+	}
+	if val, err = strconv.ParseInt(value, 10, 64); err != nil || val < min {
+		return 0, fmt.Errorf("invalid %v: %v", key, value)
+	}
+	return
+}
+
+func replyJSON(w http.ResponseWriter, code int, msg string, data interface{}) {
+	reply := &proto.HTTPReply{Code: int32(code), Msg: msg, Data: data}
+	body, err := json.Marshal(reply)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
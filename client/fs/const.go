@@ -38,14 +38,23 @@ const (
 )
 
 const (
-	// the expiration duration of the dentry in the cache (used internally)
+	// the expiration duration of the dentry in the cache (used internally), unless the client watches the changes
 	DentryValidDuration = 5 * time.Second
+
+	// the interval and the limit of every meta partition of reading the changelog to invalidate the cached dentries
+	ChangelogPollInterval = time.Second
+	ChangelogReadLimit    = 10000
 )
 
 const (
 	DeleteExtentsTimeout = 600 * time.Second
 )
 
+const (
+	// the number of dentries read from the meta node for a page of readdir
+	DefaultReadDirLimit = 1024
+)
+
 var (
 	// The following two are used in the FUSE cache
 	// every time the lookup will be performed on the fly, and the result will not be cached
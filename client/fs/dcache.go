@@ -17,20 +17,29 @@ package fs
 import (
 	"sync"
 	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util/log"
 )
 
-// DentryCache defines the dentry cache.
+// DentryCache defines the dentry cache of a directory, the dentries of which expire after the TTL of the cache.
 type DentryCache struct {
 	sync.Mutex
-	cache      map[string]uint64
+	cache map[string]dentryCacheEntry
+	ttl   time.Duration
+}
+
+type dentryCacheEntry struct {
+	ino        uint64
 	expiration time.Time
 }
 
 // NewDentryCache returns a new dentry cache.
-func NewDentryCache() *DentryCache {
+func NewDentryCache(ttl time.Duration) *DentryCache {
 	return &DentryCache{
-		cache:      make(map[string]uint64),
-		expiration: time.Now().Add(DentryValidDuration),
+		cache: make(map[string]dentryCacheEntry),
+		ttl:   ttl,
 	}
 }
 
@@ -41,8 +50,7 @@ func (dc *DentryCache) Put(name string, ino uint64) {
 	}
 	dc.Lock()
 	defer dc.Unlock()
-	dc.cache[name] = ino
-	dc.expiration = time.Now().Add(DentryValidDuration)
+	dc.cache[name] = dentryCacheEntry{ino: ino, expiration: time.Now().Add(dc.ttl)}
 }
 
 // Get gets the item from the cache based on the given key.
@@ -53,12 +61,15 @@ func (dc *DentryCache) Get(name string) (uint64, bool) {
 
 	dc.Lock()
 	defer dc.Unlock()
-	if dc.expiration.Before(time.Now()) {
-		dc.cache = make(map[string]uint64)
+	entry, ok := dc.cache[name]
+	if !ok {
 		return 0, false
 	}
-	ino, ok := dc.cache[name]
-	return ino, ok
+	if entry.expiration.Before(time.Now()) {
+		delete(dc.cache, name)
+		return 0, false
+	}
+	return entry.ino, true
 }
 
 // Delete deletes the item based on the given key.
@@ -70,3 +81,65 @@ func (dc *DentryCache) Delete(name string) {
 	defer dc.Unlock()
 	delete(dc.cache, name)
 }
+
+// watchChanges invalidates the dentries and inodes cached by the client once they are changed by the other clients,
+// which are read from the changelog of the volume, so that the dentries can be cached longer than
+// DentryValidDuration. An error is returned if the changelog can not be read, e.g. disabled on the meta nodes.
+func (s *Super) watchChanges() error {
+	cursor, err := s.mw.ChangelogCursor()
+	if err != nil {
+		return err
+	}
+	go func() {
+		t := time.NewTicker(ChangelogPollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.closeC:
+				return
+			case <-t.C:
+			}
+			events, next, err := s.mw.ReadChangelog(cursor, ChangelogReadLimit)
+			if err != nil {
+				// the changes may be missed, so the cached dentries are dropped
+				log.LogWarnf("watchChanges: volume(%v) err(%v)", s.volname, err)
+				s.dropDentryCaches()
+				if err == meta.ErrChangelogExpired {
+					if next, err = s.mw.ChangelogCursor(); err == nil {
+						cursor = next
+					}
+				}
+				continue
+			}
+			for _, event := range events {
+				s.invalidateChange(event)
+			}
+			cursor = next
+		}
+	}()
+	return nil
+}
+
+func (s *Super) invalidateChange(event *proto.ChangeEvent) {
+	switch event.Op {
+	case proto.ChangeCreate, proto.ChangeUnlink, proto.ChangeReplace:
+		s.fslock.Lock()
+		node := s.nodeCache[event.ParentID]
+		s.fslock.Unlock()
+		if dir, ok := node.(*Dir); ok {
+			dir.dentryCache().Delete(event.Name)
+		}
+		s.ic.Delete(event.ParentID)
+	}
+	s.ic.Delete(event.Inode)
+}
+
+func (s *Super) dropDentryCaches() {
+	s.fslock.Lock()
+	defer s.fslock.Unlock()
+	for _, node := range s.nodeCache {
+		if dir, ok := node.(*Dir); ok {
+			dir.setDentryCache(nil)
+		}
+	}
+}
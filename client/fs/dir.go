@@ -16,6 +16,8 @@ package fs
 
 import (
 	"os"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -30,9 +32,12 @@ import (
 
 // Dir defines the structure of a directory
 type Dir struct {
-	super  *Super
-	info   *proto.InodeInfo
-	dcache *DentryCache
+	super           *Super
+	info            *proto.InodeInfo
+	dcacheLock      sync.Mutex
+	dcache          *DentryCache
+	ownPolicy       uint32 // cachePolicy set by the xattr of the directory, accessed atomically
+	inheritedPolicy uint32 // cachePolicy of the parent directory, accessed atomically
 }
 
 // Functions that Dir needs to implement
@@ -45,7 +50,7 @@ var (
 	_ fs.NodeRemover         = (*Dir)(nil)
 	_ fs.NodeFsyncer         = (*Dir)(nil)
 	_ fs.NodeRequestLookuper = (*Dir)(nil)
-	_ fs.HandleReadDirAller  = (*Dir)(nil)
+	_ fs.HandleReadDirPager  = (*Dir)(nil)
 	_ fs.NodeRenamer         = (*Dir)(nil)
 	_ fs.NodeSetattrer       = (*Dir)(nil)
 	_ fs.NodeSymlinker       = (*Dir)(nil)
@@ -66,12 +71,14 @@ func NewDir(s *Super, i *proto.InodeInfo) fs.Node {
 // Attr set the attributes of a directory.
 func (d *Dir) Attr(ctx context.Context, a *fuse.Attr) error {
 	ino := d.info.Inode
-	info, err := d.super.InodeGet(ino)
+	policy := d.cachePolicy()
+	info, err := d.super.InodeGetWithPolicy(ino, policy)
 	if err != nil {
 		log.LogErrorf("Attr: ino(%v) err(%v)", ino, err)
 		return ParseError(err)
 	}
 	fillAttr(info, a)
+	a.Valid = policy.attrValid()
 	log.LogDebugf("TRACE Attr: inode(%v)", info)
 	return nil
 }
@@ -92,16 +99,16 @@ func (d *Dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.Cr
 
 	d.super.ic.Put(info)
 	child := NewFile(d.super, info)
+	d.adoptChild(child)
 	d.super.ec.OpenStream(info.Inode)
 
 	d.super.fslock.Lock()
 	d.super.nodeCache[info.Inode] = child
 	d.super.fslock.Unlock()
 
-	if d.super.keepCache {
-		resp.Flags |= fuse.OpenKeepCache
-	}
-	resp.EntryValid = LookupValidDuration
+	policy := d.cachePolicy()
+	resp.Flags |= policy.openFlags(d.super.keepCache)
+	resp.EntryValid = policy.entryValid()
 
 	d.super.ic.Delete(d.info.Inode)
 
@@ -140,6 +147,7 @@ func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error
 
 	d.super.ic.Put(info)
 	child := NewDir(d.super, info)
+	d.adoptChild(child)
 
 	d.super.fslock.Lock()
 	d.super.nodeCache[info.Inode] = child
@@ -155,7 +163,7 @@ func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error
 // Remove handles the remove request.
 func (d *Dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
 	start := time.Now()
-	d.dcache.Delete(req.Name)
+	d.dentryCache().Delete(req.Name)
 
 	var err error
 	metric := exporter.NewTPCnt("remove")
@@ -192,7 +200,11 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 
 	log.LogDebugf("TRACE Lookup: parent(%v) req(%v)", d.info.Inode, req)
 
-	ino, ok := d.dcache.Get(req.Name)
+	policy := d.cachePolicy()
+	var ok bool
+	if policy != cachePolicyNone {
+		ino, ok = d.dentryCache().Get(req.Name)
+	}
 	if !ok {
 		ino, _, err = d.super.mw.Lookup_ll(d.info.Inode, req.Name)
 		if err != nil {
@@ -201,9 +213,13 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 			}
 			return nil, ParseError(err)
 		}
+		// the path resolved repeatedly does not go to the meta nodes until the dentry expires or is changed
+		if policy != cachePolicyNone && !d.super.disableDcache {
+			d.ensureDentryCache().Put(req.Name, ino)
+		}
 	}
 
-	info, err := d.super.InodeGet(ino)
+	info, err := d.super.InodeGetWithPolicy(ino, policy)
 	if err != nil {
 		log.LogErrorf("Lookup: parent(%v) name(%v) ino(%v) err(%v)", d.info.Inode, req.Name, ino, err)
 		dummyInodeInfo := &proto.InodeInfo{Inode: ino}
@@ -224,30 +240,40 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 	}
 	d.super.fslock.Unlock()
 
-	resp.EntryValid = LookupValidDuration
+	if dir, isDir := child.(*Dir); isDir && !ok {
+		dir.loadCachePolicy()
+	}
+	d.adoptChild(child)
+	resp.EntryValid = policy.entryValid()
 	return child, nil
 }
 
-// ReadDirAll gets all the dentries in a directory and puts them into the cache.
-func (d *Dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+// ReadDirPage gets a page of the dentries in a directory from the marker and puts them into the cache.
+// The dentry cache is rebuilt when the directory is read from the beginning.
+func (d *Dir) ReadDirPage(ctx context.Context, marker string) ([]fuse.Dirent, string, error) {
 	start := time.Now()
 
 	var err error
 	metric := exporter.NewTPCnt("readdir")
 	defer metric.Set(err)
 
-	children, err := d.super.mw.ReadDir_ll(d.info.Inode)
+	children, next, err := d.super.mw.ReadDirLimit_ll(d.info.Inode, marker, DefaultReadDirLimit)
 	if err != nil {
-		log.LogErrorf("Readdir: ino(%v) err(%v)", d.info.Inode, err)
-		return make([]fuse.Dirent, 0), ParseError(err)
+		log.LogErrorf("Readdir: ino(%v) marker(%v) err(%v)", d.info.Inode, marker, err)
+		return make([]fuse.Dirent, 0), "", ParseError(err)
 	}
 
-	inodes := make([]uint64, 0, len(children))
+	// the inodes are got in batches while the page is walked, and put into the inode cache for the lookups
+	iget := d.super.mw.NewBatchIgetPipeline()
 	dirents := make([]fuse.Dirent, 0, len(children))
 
-	var dcache *DentryCache
-	if !d.super.disableDcache {
-		dcache = NewDentryCache()
+	dcache := d.dentryCache()
+	if marker == "" {
+		d.super.updateAtime(d.info.Inode)
+		dcache = nil
+		if !d.super.disableDcache && d.cachePolicy() != cachePolicyNone {
+			dcache = NewDentryCache(d.super.dentryTTL)
+		}
 	}
 
 	for _, child := range children {
@@ -256,20 +282,20 @@ func (d *Dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
 			Type:  ParseType(child.Type),
 			Name:  child.Name,
 		}
-		inodes = append(inodes, child.Inode)
+		iget.Add(child.Inode)
 		dirents = append(dirents, dentry)
 		dcache.Put(child.Name, child.Inode)
 	}
 
-	infos := d.super.mw.BatchInodeGet(inodes)
+	infos := iget.Wait()
 	for _, info := range infos {
 		d.super.ic.Put(info)
 	}
-	d.dcache = dcache
+	d.setDentryCache(dcache)
 
 	elapsed := time.Since(start)
-	log.LogDebugf("TRACE ReadDir: ino(%v) (%v)ns", d.info.Inode, elapsed.Nanoseconds())
-	return dirents, nil
+	log.LogDebugf("TRACE ReadDir: ino(%v) marker(%v) entries(%v) next(%v) (%v)ns", d.info.Inode, marker, len(dirents), next, elapsed.Nanoseconds())
+	return dirents, next, nil
 }
 
 // Rename handles the rename request.
@@ -280,7 +306,8 @@ func (d *Dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Nod
 		return fuse.ENOTSUP
 	}
 	start := time.Now()
-	d.dcache.Delete(req.OldName)
+	d.dentryCache().Delete(req.OldName)
+	dstDir.dentryCache().Delete(req.NewName)
 
 	var err error
 	metric := exporter.NewTPCnt("rename")
@@ -320,6 +347,7 @@ func (d *Dir) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.
 	}
 
 	fillAttr(info, &resp.Attr)
+	resp.Attr.Valid = d.cachePolicy().attrValid()
 
 	elapsed := time.Since(start)
 	log.LogDebugf("TRACE Setattr: ino(%v) req(%v) inodeSize(%v) (%v)ns", ino, req, info.Size, elapsed.Nanoseconds())
@@ -345,6 +373,7 @@ func (d *Dir) Mknod(ctx context.Context, req *fuse.MknodRequest) (fs.Node, error
 
 	d.super.ic.Put(info)
 	child := NewFile(d.super, info)
+	d.adoptChild(child)
 
 	d.super.fslock.Lock()
 	d.super.nodeCache[info.Inode] = child
@@ -372,6 +401,7 @@ func (d *Dir) Symlink(ctx context.Context, req *fuse.SymlinkRequest) (fs.Node, e
 
 	d.super.ic.Put(info)
 	child := NewFile(d.super, info)
+	d.adoptChild(child)
 
 	d.super.fslock.Lock()
 	d.super.nodeCache[info.Inode] = child
@@ -418,28 +448,120 @@ func (d *Dir) Link(ctx context.Context, req *fuse.LinkRequest, old fs.Node) (fs.
 		d.super.nodeCache[info.Inode] = newFile
 	}
 	d.super.fslock.Unlock()
+	d.adoptChild(newFile)
 
 	elapsed := time.Since(start)
 	log.LogDebugf("TRACE Link: parent(%v) name(%v) ino(%v) (%v)ns", d.info.Inode, req.NewName, info.Inode, elapsed.Nanoseconds())
 	return newFile, nil
 }
 
-// Getxattr has not been implemented yet.
+// Getxattr handles the getxattr request.
 func (d *Dir) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
-	return fuse.ENOSYS
+	if !d.super.enableXattr {
+		return fuse.ENOSYS
+	}
+	ino := d.info.Inode
+	name := req.Name
+	info, err := d.super.mw.XAttrGet_ll(ino, name)
+	if err != nil {
+		log.LogErrorf("GetXattr: ino(%v) name(%v) err(%v)", ino, name, err)
+		return ParseError(err)
+	}
+	value := info.Get(name)
+	if req.Position > 0 {
+		value = value[req.Position:]
+	}
+	if req.Size > 0 && req.Size < uint32(len(value)) {
+		value = value[:req.Size]
+	}
+	resp.Xattr = value
+	log.LogDebugf("TRACE GetXattr: ino(%v) name(%v)", ino, name)
+	return nil
 }
 
-// Listxattr has not been implemented yet.
+// Listxattr handles the listxattr request.
 func (d *Dir) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
-	return fuse.ENOSYS
+	if !d.super.enableXattr {
+		return fuse.ENOSYS
+	}
+	ino := d.info.Inode
+	keys, err := d.super.mw.XAttrsList_ll(ino)
+	if err != nil {
+		log.LogErrorf("ListXattr: ino(%v) err(%v)", ino, err)
+		return ParseError(err)
+	}
+	for _, key := range keys {
+		resp.Append(key)
+	}
+	log.LogDebugf("TRACE Listxattr: ino(%v)", ino)
+	return nil
 }
 
-// Setxattr has not been implemented yet.
+// Setxattr handles the setxattr request. Setting "cfs.cache" changes the cache policy of the subtree.
 func (d *Dir) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
-	return fuse.ENOSYS
+	if !d.super.enableXattr {
+		return fuse.ENOSYS
+	}
+	ino := d.info.Inode
+	name := req.Name
+	value := req.Xattr
+	var policy cachePolicy
+	if name == XAttrKeyCachePolicy {
+		var ok bool
+		if policy, ok = parseCachePolicy(string(value)); !ok || policy == cachePolicyInherit {
+			log.LogWarnf("Setxattr: ino(%v) invalid cache policy(%v)", ino, string(value))
+			return fuse.Errno(syscall.EINVAL)
+		}
+	}
+	if err := d.super.mw.XAttrSet_ll(ino, []byte(name), value); err != nil {
+		log.LogErrorf("Setxattr: ino(%v) name(%v) err(%v)", ino, name, err)
+		return ParseError(err)
+	}
+	if name == XAttrKeyCachePolicy {
+		atomic.StoreUint32(&d.ownPolicy, uint32(policy))
+		log.LogInfof("Setxattr: ino(%v) cache policy(%v)", ino, policy)
+	}
+	log.LogDebugf("TRACE Setxattr: ino(%v) name(%v)", ino, name)
+	return nil
 }
 
-// Removexattr has not been implemented yet.
+// Removexattr handles the removexattr request. Removing "cfs.cache" makes the subtree inherit the cache policy again.
 func (d *Dir) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
-	return fuse.ENOSYS
+	if !d.super.enableXattr {
+		return fuse.ENOSYS
+	}
+	ino := d.info.Inode
+	name := req.Name
+	if err := d.super.mw.XAttrDel_ll(ino, name); err != nil {
+		log.LogErrorf("Removexattr: ino(%v) name(%v) err(%v)", ino, name, err)
+		return ParseError(err)
+	}
+	if name == XAttrKeyCachePolicy {
+		atomic.StoreUint32(&d.ownPolicy, uint32(cachePolicyInherit))
+	}
+	log.LogDebugf("TRACE RemoveXattr: ino(%v) name(%v)", ino, name)
+	return nil
+}
+
+// dentryCache returns the dentry cache of the directory, nil if none.
+func (d *Dir) dentryCache() *DentryCache {
+	d.dcacheLock.Lock()
+	defer d.dcacheLock.Unlock()
+	return d.dcache
+}
+
+// ensureDentryCache returns the dentry cache of the directory, which is created if none.
+func (d *Dir) ensureDentryCache() *DentryCache {
+	d.dcacheLock.Lock()
+	defer d.dcacheLock.Unlock()
+	if d.dcache == nil {
+		d.dcache = NewDentryCache(d.super.dentryTTL)
+	}
+	return d.dcache
+}
+
+func (d *Dir) setDentryCache(dcache *DentryCache) {
+	d.dcacheLock.Lock()
+	d.dcache = dcache
+	d.dcacheLock.Unlock()
 }
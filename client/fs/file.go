@@ -17,6 +17,7 @@ package fs
 import (
 	"fmt"
 	"io"
+	"syscall"
 	"time"
 
 	"bazil.org/fuse"
@@ -32,8 +33,9 @@ import (
 
 // File defines the structure of a file.
 type File struct {
-	super *Super
-	info  *proto.InodeInfo
+	super           *Super
+	info            *proto.InodeInfo
+	inheritedPolicy uint32 // cachePolicy, accessed atomically
 	sync.RWMutex
 }
 
@@ -47,6 +49,8 @@ var (
 	_ fs.HandleReader      = (*File)(nil)
 	_ fs.HandleWriter      = (*File)(nil)
 	_ fs.HandleFlusher     = (*File)(nil)
+	_ fs.HandleFallocater  = (*File)(nil)
+	_ fs.HandleLocker      = (*File)(nil)
 	_ fs.NodeFsyncer       = (*File)(nil)
 	_ fs.NodeSetattrer     = (*File)(nil)
 	_ fs.NodeReadlinker    = (*File)(nil)
@@ -64,7 +68,8 @@ func NewFile(s *Super, i *proto.InodeInfo) fs.Node {
 // Attr sets the attributes of a file.
 func (f *File) Attr(ctx context.Context, a *fuse.Attr) error {
 	ino := f.info.Inode
-	info, err := f.super.InodeGet(ino)
+	policy := f.cachePolicy()
+	info, err := f.super.InodeGetWithPolicy(ino, policy)
 	if err != nil {
 		log.LogErrorf("Attr: ino(%v) err(%v)", ino, err)
 		if err == fuse.ENOENT {
@@ -75,6 +80,7 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) error {
 	}
 
 	fillAttr(info, a)
+	a.Valid = policy.attrValid()
 	fileSize, gen := f.fileSize(ino)
 	log.LogDebugf("Attr: ino(%v) fileSize(%v) gen(%v) inode.gen(%v)", ino, fileSize, gen, info.Generation)
 	if gen >= info.Generation {
@@ -120,12 +126,19 @@ func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 	ino := f.info.Inode
 	start := time.Now()
 
+	if info, e := f.super.InodeGet(ino); e == nil && !flagsAllowOpen(info.Flags, req.Flags) {
+		log.LogWarnf("Open: ino(%v) flags(%v) req(%v) refused", ino, info.Flags, req)
+		return nil, fuse.EPERM
+	}
+
 	f.super.ec.OpenStream(ino)
 
 	f.super.ec.RefreshExtentsCache(ino)
 
-	if f.super.keepCache {
-		resp.Flags |= fuse.OpenKeepCache
+	resp.Flags |= f.cachePolicy().openFlags(f.super.keepCache)
+	if isDirectIOEnabled(req.Flags) {
+		// bypass the page cache of the kernel, including its writeback cache
+		resp.Flags |= fuse.OpenDirectIO
 	}
 
 	elapsed := time.Since(start)
@@ -133,6 +146,18 @@ func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 	return f, nil
 }
 
+// flagsAllowOpen refuses to open an immutable file for writing, and an append-only file for writing
+// without O_APPEND. The flags are enforced by the meta nodes anyway.
+func flagsAllowOpen(inodeFlags uint32, flags fuse.OpenFlags) bool {
+	if flags.IsReadOnly() && flags&fuse.OpenTruncate == 0 {
+		return true
+	}
+	if inodeFlags&proto.InodeFlagImmutable != 0 {
+		return false
+	}
+	return inodeFlags&proto.InodeFlagAppendOnly == 0 || flags&fuse.OpenAppend != 0 && flags&fuse.OpenTruncate == 0
+}
+
 // Release handles the release request.
 func (f *File) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error) {
 	ino := f.info.Inode
@@ -140,6 +165,10 @@ func (f *File) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error
 
 	start := time.Now()
 
+	if req.ReleaseFlags&fuse.ReleaseFlockUnlock != 0 {
+		f.releaseFlock(req.LockOwner)
+	}
+
 	//log.LogDebugf("TRACE Release close stream: ino(%v) req(%v)", ino, req)
 
 	err = f.super.ec.CloseStream(ino)
@@ -183,6 +212,8 @@ func (f *File) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadR
 		log.LogWarnf("Read: ino(%v) offset(%v) reqsize(%v) req(%v) size(%v)", f.info.Inode, req.Offset, req.Size, req, size)
 	}
 
+	f.super.updateAtime(f.info.Inode)
+
 	elapsed := time.Since(start)
 	log.LogDebugf("TRACE Read: ino(%v) offset(%v) reqsize(%v) req(%v) size(%v) (%v)ns", f.info.Inode, req.Offset, req.Size, req, size, elapsed.Nanoseconds())
 	return nil
@@ -214,7 +245,12 @@ func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	var waitForFlush bool
 	var flags int
 
-	if isDirectIOEnabled(req.FileFlags) || (req.FileFlags&fuse.OpenSync != 0) {
+	if isDirectIOEnabled(req.FileFlags) {
+		flags |= proto.FlagsDirectWrite
+		if f.super.enSyncWrite {
+			flags |= proto.FlagsSyncWrite
+		}
+	} else if req.FileFlags&fuse.OpenSync != 0 {
 		waitForFlush = true
 		if f.super.enSyncWrite {
 			flags |= proto.FlagsSyncWrite
@@ -232,6 +268,10 @@ func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 
 	size, err := f.super.ec.Write(ino, int(req.Offset), req.Data, flags)
 	if err != nil {
+		if err == syscall.EPERM {
+			log.LogWarnf("Write: ino(%v) offset(%v) len(%v) err(%v)", ino, req.Offset, reqlen, err)
+			return fuse.EPERM
+		}
 		msg := fmt.Sprintf("Write: ino(%v) offset(%v) len(%v) err(%v)", ino, req.Offset, reqlen, err)
 		f.super.handleError("Write", msg)
 		return fuse.EIO
@@ -256,6 +296,50 @@ func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	return nil
 }
 
+// Fallocate handles the fallocate request. The space is not preallocated since the extents
+// are allocated on write, so only the size of the file is extended unless FALLOC_FL_KEEP_SIZE
+// is given. The punched and zeroed ranges are removed from the extents and read as zeros.
+func (f *File) Fallocate(ctx context.Context, req *fuse.FallocateRequest) (err error) {
+	ino := f.info.Inode
+	start := time.Now()
+
+	log.LogDebugf("TRACE Fallocate enter: ino(%v) req(%v)", ino, req)
+
+	if req.Mode&(fuse.FallocateCollapseRange|fuse.FallocateInsertRange) != 0 {
+		return fuse.Errno(syscall.EOPNOTSUPP)
+	}
+	if req.Mode&fuse.FallocatePunchHole != 0 && req.Mode&fuse.FallocateKeepSize == 0 {
+		return fuse.Errno(syscall.EINVAL)
+	}
+
+	metric := exporter.NewTPCnt("fallocate")
+	defer metric.Set(err)
+
+	defer func() {
+		f.super.ic.Delete(ino)
+	}()
+
+	if req.Mode&(fuse.FallocatePunchHole|fuse.FallocateZeroRange) != 0 {
+		if err = f.super.ec.PunchHole(ino, int(req.Offset), int(req.Length)); err != nil {
+			msg := fmt.Sprintf("Fallocate: punch hole failed, ino(%v) req(%v) err(%v)", ino, req, err)
+			f.super.handleError("Fallocate", msg)
+			return fuse.EIO
+		}
+	}
+
+	end := req.Offset + req.Length
+	if filesize, _ := f.fileSize(ino); req.Mode&fuse.FallocateKeepSize == 0 && end > uint64(filesize) {
+		if err = f.super.ec.Truncate(ino, int(end)); err != nil {
+			log.LogErrorf("Fallocate: extend ino(%v) size(%v) err(%v)", ino, end, err)
+			return fuse.EIO
+		}
+	}
+
+	elapsed := time.Since(start)
+	log.LogDebugf("TRACE Fallocate: ino(%v) req(%v) (%v)ns", ino, req, elapsed.Nanoseconds())
+	return nil
+}
+
 // Flush only when fsyncOnClose is enabled.
 func (f *File) Flush(ctx context.Context, req *fuse.FlushRequest) (err error) {
 	if !f.super.fsyncOnClose {
@@ -334,6 +418,7 @@ func (f *File) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse
 	}
 
 	fillAttr(info, &resp.Attr)
+	resp.Attr.Valid = f.cachePolicy().attrValid()
 
 	elapsed := time.Since(start)
 	log.LogDebugf("TRACE Setattr: ino(%v) req(%v) (%v)ns", ino, req, elapsed.Nanoseconds())
@@ -39,6 +39,7 @@ type InodeCache struct {
 	lruList     *list.List
 	expiration  time.Duration
 	maxElements int
+	evictions   uint64 // the number of the inodes evicted from the cache
 }
 
 // NewInodeCache returns a new inode cache.
@@ -55,6 +56,14 @@ func NewInodeCache(exp time.Duration, maxElements int) *InodeCache {
 
 // Put puts the given inode info into the inode cache.
 func (ic *InodeCache) Put(info *proto.InodeInfo) {
+	ic.RLock()
+	expiration := ic.expiration
+	ic.RUnlock()
+	ic.PutWithExpiration(info, expiration)
+}
+
+// PutWithExpiration puts the given inode info into the inode cache with its own expiration.
+func (ic *InodeCache) PutWithExpiration(info *proto.InodeInfo, expiration time.Duration) {
 	ic.Lock()
 	old, ok := ic.cache[info.Inode]
 	if ok {
@@ -66,7 +75,7 @@ func (ic *InodeCache) Put(info *proto.InodeInfo) {
 		ic.evict(true)
 	}
 
-	inodeSetExpiration(info, ic.expiration)
+	inodeSetExpiration(info, expiration)
 	element := ic.lruList.PushFront(info)
 	ic.cache[info.Inode] = element
 	ic.Unlock()
@@ -103,6 +112,34 @@ func (ic *InodeCache) Delete(ino uint64) {
 	ic.Unlock()
 }
 
+// SetExpiration changes the expiration of the inodes put into the cache afterwards.
+func (ic *InodeCache) SetExpiration(exp time.Duration) {
+	ic.Lock()
+	ic.expiration = exp
+	ic.Unlock()
+}
+
+// SetMaxElements changes the max number of the inodes in the cache, and evicts the inodes beyond it.
+func (ic *InodeCache) SetMaxElements(maxElements int) {
+	ic.Lock()
+	ic.maxElements = maxElements
+	for ic.lruList.Len() > ic.maxElements {
+		ic.evict(true)
+	}
+	ic.Unlock()
+}
+
+// Stat returns the statistics of the inode cache.
+func (ic *InodeCache) Stat() *proto.ClientInodeCacheStat {
+	ic.RLock()
+	defer ic.RUnlock()
+	return &proto.ClientInodeCacheStat{
+		Inodes:    ic.lruList.Len(),
+		MaxInodes: ic.maxElements,
+		Evictions: ic.evictions,
+	}
+}
+
 // Foreground eviction cares more about the speed.
 // Background eviction evicts all expired items from the cache.
 // The caller should grab the WRITE lock of the inode cache.
@@ -125,6 +162,7 @@ func (ic *InodeCache) evict(foreground bool) {
 
 		ic.lruList.Remove(element)
 		delete(ic.cache, info.Inode)
+		ic.evictions++
 		count++
 	}
 
@@ -144,6 +182,7 @@ func (ic *InodeCache) evict(foreground bool) {
 		}
 		ic.lruList.Remove(element)
 		delete(ic.cache, info.Inode)
+		ic.evictions++
 		count++
 	}
 }
@@ -47,6 +47,27 @@ func (s *Super) InodeGet(ino uint64) (*proto.InodeInfo, error) {
 	return info, nil
 }
 
+// updateAtime updates the access time of the inode with the relatime policy if the volume tracks it.
+// The cached inode is checked first, so that the meta node is only asked when the access time is due.
+func (s *Super) updateAtime(ino uint64) {
+	if !s.mw.AtimeEnabled() {
+		return
+	}
+	info, err := s.InodeGet(ino)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if !proto.RelatimeDue(info.AccessTime.Unix(), info.ModifyTime.Unix(), info.CreateTime.Unix(), now.Unix()) {
+		return
+	}
+	if err = s.mw.UpdateAtime_ll(ino, now); err != nil {
+		log.LogWarnf("updateAtime: ino(%v) err(%v)", ino, err)
+		return
+	}
+	s.ic.Delete(ino)
+}
+
 func setattr(info *proto.InodeInfo, req *fuse.SetattrRequest) (valid uint32) {
 	if req.Valid.Mode() {
 		info.Mode = proto.Mode(req.Mode)
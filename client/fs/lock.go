@@ -0,0 +1,148 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"math"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	"golang.org/x/net/context"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The advisory locks are only sent by the kernel if the client is mounted with enableFileLock,
+// otherwise they are handled by the kernel locally and do not work across the mounts.
+
+const (
+	minLockWaitInterval = 10 * time.Millisecond
+	maxLockWaitInterval = time.Second
+)
+
+func toFileLock(owner uint64, lock *fuse.FileLock, flags fuse.LockFlags) *proto.FileLock {
+	fl := &proto.FileLock{
+		Owner: owner,
+		Pid:   uint32(lock.PID),
+		Start: lock.Start,
+		End:   lock.End,
+		Flock: flags&fuse.LockFlock != 0,
+	}
+	switch lock.Type {
+	case fuse.LockRead:
+		fl.Type = proto.FileLockRead
+	case fuse.LockWrite:
+		fl.Type = proto.FileLockWrite
+	default:
+		fl.Type = proto.FileLockUnlock
+	}
+	return fl
+}
+
+// Lock acquires the advisory lock without waiting.
+func (f *File) Lock(ctx context.Context, req *fuse.LockRequest) error {
+	ino := f.info.Inode
+	lock := toFileLock(req.LockOwner, &req.Lock, req.LockFlags)
+	if conflict, err := f.super.mw.SetLock_ll(ino, lock); err != nil {
+		if err != syscall.EAGAIN {
+			log.LogErrorf("Lock: ino(%v) req(%v) err(%v)", ino, req, err)
+		}
+		log.LogDebugf("TRACE Lock: ino(%v) req(%v) conflict(%v)", ino, req, conflict)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE Lock: ino(%v) req(%v)", ino, req)
+	return nil
+}
+
+// LockWait acquires the advisory lock, polling the meta node until the lock is released by the other owners
+// or the request is interrupted.
+func (f *File) LockWait(ctx context.Context, req *fuse.LockWaitRequest) error {
+	ino := f.info.Inode
+	lock := toFileLock(req.LockOwner, &req.Lock, req.LockFlags)
+	interval := minLockWaitInterval
+	for {
+		_, err := f.super.mw.SetLock_ll(ino, lock)
+		if err == nil {
+			log.LogDebugf("TRACE LockWait: ino(%v) req(%v)", ino, req)
+			return nil
+		}
+		if err != syscall.EAGAIN {
+			log.LogErrorf("LockWait: ino(%v) req(%v) err(%v)", ino, req, err)
+			return ParseError(err)
+		}
+		select {
+		case <-ctx.Done():
+			log.LogDebugf("TRACE LockWait: interrupted, ino(%v) req(%v)", ino, req)
+			return fuse.EINTR
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > maxLockWaitInterval {
+			interval = maxLockWaitInterval
+		}
+	}
+}
+
+// Unlock releases the advisory lock.
+func (f *File) Unlock(ctx context.Context, req *fuse.UnlockRequest) error {
+	ino := f.info.Inode
+	lock := toFileLock(req.LockOwner, &req.Lock, req.LockFlags)
+	if _, err := f.super.mw.SetLock_ll(ino, lock); err != nil {
+		log.LogErrorf("Unlock: ino(%v) req(%v) err(%v)", ino, req, err)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE Unlock: ino(%v) req(%v)", ino, req)
+	return nil
+}
+
+// QueryLock returns the advisory lock conflicting with the given one.
+func (f *File) QueryLock(ctx context.Context, req *fuse.QueryLockRequest, resp *fuse.QueryLockResponse) error {
+	ino := f.info.Inode
+	lock := toFileLock(req.LockOwner, &req.Lock, req.LockFlags)
+	conflict, err := f.super.mw.GetLock_ll(ino, lock)
+	if err != nil {
+		log.LogErrorf("QueryLock: ino(%v) req(%v) err(%v)", ino, req, err)
+		return ParseError(err)
+	}
+	if conflict != nil {
+		resp.Lock = fuse.FileLock{
+			Start: conflict.Start,
+			End:   conflict.End,
+			Type:  fuse.LockRead,
+			PID:   int32(conflict.Pid),
+		}
+		if conflict.Type == proto.FileLockWrite {
+			resp.Lock.Type = fuse.LockWrite
+		}
+	}
+	log.LogDebugf("TRACE QueryLock: ino(%v) req(%v) resp(%v)", ino, req, resp)
+	return nil
+}
+
+// releaseFlock releases the flock(2) lock of the owner when the file is closed.
+func (f *File) releaseFlock(owner uint64) {
+	ino := f.info.Inode
+	lock := &proto.FileLock{
+		Owner: owner,
+		Start: 0,
+		End:   math.MaxUint64,
+		Type:  proto.FileLockUnlock,
+		Flock: true,
+	}
+	if _, err := f.super.mw.SetLock_ll(ino, lock); err != nil {
+		log.LogWarnf("releaseFlock: ino(%v) owner(%v) err(%v)", ino, owner, err)
+	}
+}
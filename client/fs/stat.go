@@ -0,0 +1,31 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"net/http"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// GetStat replies the statistics of the inode cache, the streams and the local read cache of the client.
+func (s *Super) GetStat(w http.ResponseWriter, r *http.Request) {
+	stat := &proto.ClientStat{
+		InodeCache: *s.ic.Stat(),
+		Streams:    *s.ec.StreamStat(),
+		ReadCache:  *s.ec.ReadCacheStat(),
+	}
+	replyJSON(w, http.StatusOK, "", stat)
+}
@@ -17,6 +17,7 @@ package fs
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,9 +31,11 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/data/stream"
 	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
 	"github.com/chubaofs/chubaofs/util/ump"
+	"github.com/jacobsa/daemonize"
 )
 
 // Super defines the struct of a super block.
@@ -51,9 +54,16 @@ type Super struct {
 	fslock    sync.Mutex
 
 	disableDcache bool
+	dentryTTL     time.Duration // the dentries changed by the other clients are invalidated if longer than default
 	fsyncOnClose  bool
 	enableXattr   bool
 	rootIno       uint64
+
+	confLock sync.Mutex
+	conf     proto.ClientConfig // the mount options that can be changed at runtime
+
+	closeOnce sync.Once
+	closeC    chan struct{} // closed once the volume is unmounted, to stop the background workers
 }
 
 // Functions that Super needs to implement
@@ -65,6 +75,7 @@ var (
 // NewSuper returns a new Super.
 func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	s = new(Super)
+	s.closeC = make(chan struct{})
 	var masters = strings.Split(opt.Master, meta.HostsSeparator)
 	var metaConfig = &meta.MetaConfig{
 		Volume:        opt.Volname,
@@ -73,6 +84,16 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		Authenticate:  opt.Authenticate,
 		TicketMess:    opt.TicketMess,
 		ValidateOwner: opt.Authenticate || opt.AccessKey == "",
+		ConnMux:       opt.ConnMux,
+		OnAsyncTaskError: func(err error) {
+			// The mounted volume is no longer accessible once the ticket is invalid.
+			if err == proto.ErrInvalidTicket {
+				log.LogErrorf("NewSuper: invalid ticket: volume(%v) err(%v)", opt.Volname, err)
+				log.LogFlush()
+				daemonize.SignalOutcome(err)
+				os.Exit(1)
+			}
+		},
 	}
 	s.mw, err = meta.NewMetaWrapper(metaConfig)
 	if err != nil {
@@ -96,24 +117,46 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		s.enSyncWrite = true
 	}
 	s.keepCache = opt.KeepCache
-	s.ic = NewInodeCache(inodeExpiration, MaxInodeCache)
+	inodeCacheSize := MaxInodeCache
+	if opt.IcacheSize > 0 {
+		inodeCacheSize = int(opt.IcacheSize)
+	}
+	s.ic = NewInodeCache(inodeExpiration, inodeCacheSize)
 	s.orphan = NewOrphanInodeList()
 	s.nodeCache = make(map[uint64]fs.Node)
 	s.disableDcache = opt.DisableDcache
 	s.fsyncOnClose = opt.FsyncOnClose
 	s.enableXattr = opt.EnableXattr
+	readAheadSize := opt.ReadAheadSize * util.MB
+	if opt.ReadAheadSize == 0 {
+		// the option is set to 0 to disable read-ahead, while it is negative if not set
+		readAheadSize = -1
+	} else if opt.ReadAheadSize < 0 {
+		readAheadSize = 0
+	}
 
 	var extentConfig = &stream.ExtentConfig{
-		Volume:            opt.Volname,
-		Masters:           masters,
-		FollowerRead:      opt.FollowerRead,
-		NearRead:          opt.NearRead,
-		ReadRate:          opt.ReadRate,
-		WriteRate:         opt.WriteRate,
-		OnAppendExtentKey: s.mw.AppendExtentKey,
-		OnGetExtents:      s.mw.GetExtents,
-		OnTruncate:        s.mw.Truncate,
-		OnEvictIcache:     s.ic.Delete,
+		Volume:             opt.Volname,
+		Masters:            masters,
+		FollowerRead:       opt.FollowerRead,
+		NearRead:           opt.NearRead,
+		ReadRate:           opt.ReadRate,
+		WriteRate:          opt.WriteRate,
+		WriteStreams:       opt.WriteStreams,
+		WriteStripeSize:    opt.WriteStripeSize * util.MB,
+		MaxInflightPackets: opt.MaxInflightPackets,
+		PacketRetryLimit:   opt.PacketRetryLimit,
+		ReadDeadline:       opt.ReadDeadline,
+		ReadCacheDir:       opt.ReadCacheDir,
+		ReadCacheSize:      opt.ReadCacheSize * util.MB,
+		ReadAheadSize:      readAheadSize,
+		ConnMux:            opt.ConnMux,
+		OnAppendExtentKey:  s.mw.AppendExtentKey,
+		OnGetExtents:       s.mw.GetExtents,
+		OnTruncate:         s.mw.Truncate,
+		OnPunchHole:        s.mw.PunchHole,
+		OnEvictIcache:      s.ic.Delete,
+		OnUpdateModifyTime: s.mw.UpdateModifyTime,
 	}
 	s.ec, err = stream.NewExtentClient(extentConfig)
 	if err != nil {
@@ -123,11 +166,32 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	if s.rootIno, err = s.mw.GetRootIno(opt.SubDir); err != nil {
 		return nil, err
 	}
+	s.initConfig(opt, inodeExpiration, inodeCacheSize)
 
-	log.LogInfof("NewSuper: cluster(%v) volname(%v) icacheExpiration(%v) LookupValidDuration(%v) AttrValidDuration(%v)", s.cluster, s.volname, inodeExpiration, LookupValidDuration, AttrValidDuration)
+	s.dentryTTL = DentryValidDuration
+	if opt.DentryCacheTTL > 0 && !s.disableDcache {
+		s.dentryTTL = time.Duration(opt.DentryCacheTTL) * time.Second
+	}
+	if s.dentryTTL > DentryValidDuration {
+		if err = s.watchChanges(); err != nil {
+			log.LogWarnf("NewSuper: dentry cache TTL is limited to %v since the changelog is not readable, err(%v)",
+				DentryValidDuration, err)
+			s.dentryTTL = DentryValidDuration
+			err = nil
+		}
+	}
+
+	log.LogInfof("NewSuper: cluster(%v) volname(%v) icacheExpiration(%v) LookupValidDuration(%v) AttrValidDuration(%v) dentryTTL(%v)", s.cluster, s.volname, inodeExpiration, LookupValidDuration, AttrValidDuration, s.dentryTTL)
 	return s, nil
 }
 
+// Close stops the background workers of the super block once the volume is unmounted.
+func (s *Super) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closeC)
+	})
+}
+
 // Root returns the root directory where it resides.
 func (s *Super) Root() (fs.Node, error) {
 	inode, err := s.InodeGet(s.rootIno)
@@ -135,6 +199,7 @@ func (s *Super) Root() (fs.Node, error) {
 		return nil, err
 	}
 	root := NewDir(s, inode)
+	root.(*Dir).loadCachePolicy()
 	return root, nil
 }
 
@@ -171,6 +236,7 @@ func (s *Super) SetRate(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("Set read rate failed\n"))
 		} else {
 			msg := s.ec.SetReadRate(val)
+			s.recordRate(&s.conf.ReadRate, val)
 			w.Write([]byte(fmt.Sprintf("Set read rate to %v successfully\n", msg)))
 		}
 	}
@@ -181,11 +247,22 @@ func (s *Super) SetRate(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("Set write rate failed\n"))
 		} else {
 			msg := s.ec.SetWriteRate(val)
+			s.recordRate(&s.conf.WriteRate, val)
 			w.Write([]byte(fmt.Sprintf("Set write rate to %v successfully\n", msg)))
 		}
 	}
 }
 
+// recordRate keeps the rate set by SetRate in the config of the client.
+func (s *Super) recordRate(rate *int64, val int) {
+	s.confLock.Lock()
+	defer s.confLock.Unlock()
+	*rate = 0
+	if val > 0 {
+		*rate = int64(val)
+	}
+}
+
 func (s *Super) exporterKey(act string) string {
 	return fmt.Sprintf("%v_fuseclient_%v", s.cluster, act)
 }
@@ -48,6 +48,7 @@ import (
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 	"github.com/chubaofs/chubaofs/util/ump"
 	"github.com/jacobsa/daemonize"
 )
@@ -129,6 +130,12 @@ func main() {
 	}
 	defer log.LogFlush()
 
+	if err = tracing.Init(ModuleName, cfg); err != nil {
+		daemonize.SignalOutcome(err)
+		os.Exit(1)
+	}
+	defer tracing.Stop()
+
 	outputFilePath := path.Join(opt.Logpath, LoggerPrefix, LoggerOutput)
 	outputFile, err := os.OpenFile(outputFilePath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
 	if err != nil {
@@ -174,6 +181,7 @@ func main() {
 		_ = daemonize.SignalOutcome(nil)
 	}
 	defer fsConn.Close()
+	defer super.Close()
 
 	exporter.RegistConsul(super.ClusterName(), ModuleName, cfg)
 
@@ -241,8 +249,12 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 	http.HandleFunc(ControlCommandSetRate, super.SetRate)
 	http.HandleFunc(ControlCommandGetRate, super.GetRate)
 	http.HandleFunc(log.SetLogLevelPath, log.SetLogLevel)
+	http.HandleFunc(log.GetLogLevelPath, log.GetLogLevel)
 	http.HandleFunc(ControlCommandFreeOSMemory, freeOSMemory)
 	http.HandleFunc(log.GetLogPath, log.GetLog)
+	http.HandleFunc(proto.ClientGetConfigPath, super.GetConfig)
+	http.HandleFunc(proto.ClientSetConfigPath, super.SetConfig)
+	http.HandleFunc(proto.ClientStatPath, super.GetStat)
 
 	go func() {
 		if opt.Profport != "" {
@@ -285,6 +297,10 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 		options = append(options, fuse.PosixACL())
 	}
 
+	if opt.EnableFileLock {
+		options = append(options, fuse.LockingFlock(), fuse.LockingPOSIX())
+	}
+
 	fsConn, err = fuse.Mount(opt.MountPoint, options...)
 	return
 }
@@ -350,6 +366,18 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.EnableXattr = GlobalMountOptions[proto.EnableXattr].GetBool()
 	opt.NearRead = GlobalMountOptions[proto.NearRead].GetBool()
 	opt.EnablePosixACL = GlobalMountOptions[proto.EnablePosixACL].GetBool()
+	opt.WriteStreams = GlobalMountOptions[proto.WriteStreams].GetInt64()
+	opt.WriteStripeSize = GlobalMountOptions[proto.WriteStripeSize].GetInt64()
+	opt.MaxInflightPackets = GlobalMountOptions[proto.MaxInflightPackets].GetInt64()
+	opt.PacketRetryLimit = GlobalMountOptions[proto.PacketRetryLimit].GetInt64()
+	opt.ReadDeadline = GlobalMountOptions[proto.ReadDeadline].GetBool()
+	opt.IcacheSize = GlobalMountOptions[proto.IcacheSize].GetInt64()
+	opt.ReadCacheDir = GlobalMountOptions[proto.ReadCacheDir].GetString()
+	opt.ReadCacheSize = GlobalMountOptions[proto.ReadCacheSize].GetInt64()
+	opt.ReadAheadSize = GlobalMountOptions[proto.ReadAheadSize].GetInt64()
+	opt.EnableFileLock = GlobalMountOptions[proto.EnableFileLock].GetBool()
+	opt.ConnMux = GlobalMountOptions[proto.ConnMux].GetBool()
+	opt.DentryCacheTTL = GlobalMountOptions[proto.DentryCacheTTL].GetInt64()
 
 	if opt.MountPoint == "" || opt.Volname == "" || opt.Owner == "" || opt.Master == "" {
 		return nil, errors.New(fmt.Sprintf("invalid config file: lack of mandatory fields, mountPoint(%v), volName(%v), owner(%v), masterAddr(%v)", opt.MountPoint, opt.Volname, opt.Owner, opt.Master))
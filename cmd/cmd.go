@@ -42,7 +42,10 @@ import (
 	"github.com/chubaofs/chubaofs/datanode"
 	"github.com/chubaofs/chubaofs/master"
 	"github.com/chubaofs/chubaofs/metanode"
+	"github.com/chubaofs/chubaofs/nfsnode"
+	"github.com/chubaofs/chubaofs/smbnode"
 	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/health"
 	"github.com/chubaofs/chubaofs/util/log"
 	"github.com/chubaofs/chubaofs/util/ump"
 )
@@ -51,10 +54,18 @@ const (
 	ConfigKeyRole       = "role"
 	ConfigKeyLogDir     = "logDir"
 	ConfigKeyLogLevel   = "logLevel"
+	ConfigKeyLogStorm   = "logStormLimit"
+	ConfigKeyLogSample  = "logStormSample" // keeps 1 of every n lines suppressed during the log storms
+	ConfigKeyLogFormat  = "logFormat"      // text or json
 	ConfigKeyProfPort   = "prof"
 	ConfigKeyWarnLogDir = "warnLogDir"
 )
 
+// EnvPrefix is the prefix of the environment variables which override the config keys, such as
+// CFS_CONFIG_MASTER_ADDR. It is more specific than CFS_ so that the other variables of the deployments,
+// such as CFS_SRC_PATH of the builds, are never taken as config keys.
+const EnvPrefix = "CFS_CONFIG_"
+
 const (
 	RoleMaster  = "master"
 	RoleMeta    = "metanode"
@@ -62,6 +73,8 @@ const (
 	RoleAuth    = "authnode"
 	RoleObject  = "objectnode"
 	RoleConsole = "console"
+	RoleNfs     = "nfsnode"
+	RoleSmb     = "smbnode"
 )
 
 const (
@@ -71,6 +84,8 @@ const (
 	ModuleAuth    = "authNode"
 	ModuleObject  = "objectNode"
 	ModuleConsole = "console"
+	ModuleNfs     = "nfsNode"
+	ModuleSmb     = "smbNode"
 )
 
 const (
@@ -81,19 +96,45 @@ var (
 	configFile       = flag.String("c", "", "config file path")
 	configVersion    = flag.Bool("v", false, "show version")
 	configForeground = flag.Bool("f", false, "run foreground")
+	configValidate   = flag.Bool("t", false, "validate the config file and exit")
 )
 
+func init() {
+	flag.BoolVar(configValidate, "validate-config", false, "validate the config file and exit")
+}
+
 func interceptSignal(s common.Server) {
 	sigC := make(chan os.Signal, 1)
 	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
+	// SIGUSR1 drains the server before shutting it down, which is used by the rolling upgrades.
+	drainer, canDrain := s.(common.Drainer)
+	if canDrain {
+		signal.Notify(sigC, syscall.SIGUSR1)
+	}
 	syslog.Println("action[interceptSignal] register system signal.")
 	go func() {
 		sig := <-sigC
 		syslog.Printf("action[interceptSignal] received signal: %s.", sig.String())
+		health.SetRunning(false)
+		if canDrain && sig == syscall.SIGUSR1 {
+			if err := drainer.Drain(0); err != nil {
+				syslog.Printf("action[interceptSignal] drain failed: %v.", err)
+			}
+		}
 		s.Shutdown()
 	}()
 }
 
+// loadConfig loads the config file, which is YAML if its extension is .yaml or .yml and JSON otherwise,
+// and overrides the keys by the environment variables with EnvPrefix.
+func loadConfig(file string) (cfg *config.Config, envKeys []string, err error) {
+	if cfg, err = config.LoadConfigFile(file); err != nil {
+		return
+	}
+	envKeys = cfg.OverrideByEnv(EnvPrefix, os.Environ())
+	return
+}
+
 func modifyOpenFiles() (err error) {
 	var rLimit syscall.Rlimit
 	err = syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit)
@@ -123,12 +164,15 @@ func main() {
 		fmt.Printf("%v", Version)
 		os.Exit(0)
 	}
+	if *configValidate {
+		os.Exit(validateConfigFile(*configFile))
+	}
 
 	/*
 	 * LoadConfigFile should be checked before start daemon, since it will
 	 * call os.Exit() w/o notifying the parent process.
 	 */
-	cfg, err := config.LoadConfigFile(*configFile)
+	cfg, envKeys, err := loadConfig(*configFile)
 	if err != nil {
 		daemonize.SignalOutcome(err)
 		os.Exit(1)
@@ -177,6 +221,12 @@ func main() {
 	case RoleConsole:
 		server = console.NewServer()
 		module = ModuleConsole
+	case RoleNfs:
+		server = nfsnode.NewServer()
+		module = ModuleNfs
+	case RoleSmb:
+		server = smbnode.NewServer()
+		module = ModuleSmb
 	default:
 		daemonize.SignalOutcome(fmt.Errorf("Fatal: role mismatch: %v", role))
 		os.Exit(1)
@@ -205,6 +255,17 @@ func main() {
 		os.Exit(1)
 	}
 	defer log.LogFlush()
+	// the log storm guard is enabled by default, a negative limit disables it
+	if limit := cfg.GetInt64(ConfigKeyLogStorm); limit != 0 {
+		log.SetStormLimit(limit)
+	}
+	log.SetStormSampling(cfg.GetInt64(ConfigKeyLogSample))
+	logFormat, err := log.ParseFormat(cfg.GetString(ConfigKeyLogFormat))
+	if err != nil {
+		daemonize.SignalOutcome(fmt.Errorf("Fatal: failed to init log - %v", err))
+		os.Exit(1)
+	}
+	log.SetFormat(logFormat, role)
 
 	// Init output file
 	outputFilePath := path.Join(logDir, module, LoggerOutput)
@@ -225,6 +286,9 @@ func main() {
 	}
 
 	syslog.Printf("Hello, ChubaoFS Storage\n%s\n", Version)
+	if len(envKeys) > 0 {
+		syslog.Printf("config keys overridden by the environment: %v\n", strings.Join(envKeys, ", "))
+	}
 
 	err = modifyOpenFiles()
 	if err != nil {
@@ -243,6 +307,9 @@ func main() {
 	if profPort != "" {
 		go func() {
 			http.HandleFunc(log.SetLogLevelPath, log.SetLogLevel)
+			http.HandleFunc(log.GetLogLevelPath, log.GetLogLevel)
+			http.HandleFunc(health.PathLiveness, health.LivenessHandler)
+			http.HandleFunc(health.PathReadiness, health.ReadinessHandler)
 			e := http.ListenAndServe(fmt.Sprintf(":%v", profPort), nil)
 			if e != nil {
 				log.LogFlush()
@@ -253,6 +320,7 @@ func main() {
 	}
 
 	interceptSignal(server)
+	interceptReload(server, cfg, *configFile)
 	err = server.Start(cfg)
 	if err != nil {
 		log.LogFlush()
@@ -262,6 +330,7 @@ func main() {
 	}
 
 	daemonize.SignalOutcome(nil)
+	health.SetRunning(true)
 
 	// Block main goroutine until server shutdown.
 	server.Sync()
@@ -287,6 +356,11 @@ func startDaemon() error {
 	env := []string{
 		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
 	}
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, EnvPrefix) {
+			env = append(env, e)
+		}
+	}
 
 	err = daemonize.Run(cmdPath, args, env, os.Stdout)
 	if err != nil {
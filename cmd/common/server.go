@@ -3,6 +3,7 @@ package common
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/chubaofs/chubaofs/util/config"
 )
@@ -27,6 +28,22 @@ type Server interface {
 	Sync()
 }
 
+// Drainer is implemented by the servers which move their load to the other servers gracefully before
+// they are shut down. Drain returns once the server can be shut down or the timeout expires, the
+// server chooses the timeout if it is zero.
+type Drainer interface {
+	Drain(timeout time.Duration) error
+}
+
+// Reloader is implemented by the servers which apply a subset of the config keys again on SIGHUP,
+// the changes of the other keys take effect after the server restarts.
+type Reloader interface {
+	// ReloadableKeys returns the config keys applied by Reload.
+	ReloadableKeys() []string
+	// Reload applies the reloadable keys of the config, nothing is applied if an error is returned.
+	Reload(cfg *config.Config) error
+}
+
 type DoStartFunc func(s Server, cfg *config.Config) (err error)
 type DoShutdownFunc func(s Server)
 
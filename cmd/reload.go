@@ -0,0 +1,151 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	syslog "log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// reloadReport collects the result of a reload, which is logged once the reload is done.
+type reloadReport struct {
+	applied []string
+	failed  []string
+	restart []string
+}
+
+func (r *reloadReport) String() string {
+	join := func(items []string) string {
+		if len(items) == 0 {
+			return "none"
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("applied(%v) failed(%v) require restart(%v)", join(r.applied), join(r.failed), join(r.restart))
+}
+
+// applyKeys applies the changed keys among the given keys by the apply function,
+// and returns the changed keys which are not among the given keys.
+func (r *reloadReport) applyKeys(changed, keys []string, apply func() error) (rest []string) {
+	var matched []string
+	for _, key := range changed {
+		if containsKey(keys, key) {
+			matched = append(matched, key)
+		} else {
+			rest = append(rest, key)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	if err := apply(); err != nil {
+		r.failed = append(r.failed, fmt.Sprintf("%v: %v", strings.Join(matched, " "), err))
+		return
+	}
+	r.applied = append(r.applied, matched...)
+	return
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// interceptReload reloads the config file on SIGHUP and applies the changes of the reloadable keys,
+// the changes of the other keys are reported to take effect after the server restarts. Only the keys
+// applied are advanced in the config in effect, so that the keys which failed are applied again and the
+// keys which require a restart are reported again by the next reload.
+func interceptReload(s common.Server, cfg *config.Config, configFile string) {
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGHUP)
+	go func() {
+		current := cfg
+		for range sigC {
+			syslog.Printf("action[interceptReload] reload config file %v.", configFile)
+			reloaded, _, err := loadConfig(configFile)
+			if err != nil {
+				syslog.Printf("action[interceptReload] load config file failed, the config is kept: %v.", err)
+				log.LogErrorf("action[interceptReload] load config file(%v) failed, the config is kept: %v", configFile, err)
+				continue
+			}
+			report := reloadConfig(s, current, reloaded)
+			syslog.Printf("action[interceptReload] config reloaded: %v.", report)
+			log.LogWarnf("action[interceptReload] config reloaded: %v", report)
+			current = current.CopyKeys(reloaded, report.applied)
+		}
+	}()
+}
+
+func reloadConfig(s common.Server, current, reloaded *config.Config) *reloadReport {
+	report := new(reloadReport)
+	changed := reloaded.DiffKeys(current)
+	changed = report.applyKeys(changed, []string{ConfigKeyLogLevel}, func() error {
+		name := reloaded.GetString(ConfigKeyLogLevel)
+		if name == "" {
+			// the same as the default level on start
+			log.SetLevel(log.ErrorLevel)
+			return nil
+		}
+		level, err := log.ParseLevel(name)
+		if err != nil {
+			return err
+		}
+		log.SetLevel(level)
+		return nil
+	})
+	changed = report.applyKeys(changed, []string{ConfigKeyLogStorm}, func() error {
+		limit := reloaded.GetInt64(ConfigKeyLogStorm)
+		if limit == 0 {
+			limit = log.DefaultStormLinesPerSecond
+		}
+		log.SetStormLimit(limit)
+		return nil
+	})
+	changed = report.applyKeys(changed, []string{ConfigKeyLogSample}, func() error {
+		log.SetStormSampling(reloaded.GetInt64(ConfigKeyLogSample))
+		return nil
+	})
+	changed = report.applyKeys(changed, []string{ConfigKeyLogFormat}, func() error {
+		format, err := log.ParseFormat(reloaded.GetString(ConfigKeyLogFormat))
+		if err != nil {
+			return err
+		}
+		log.SetFormat(format, reloaded.GetString(ConfigKeyRole))
+		return nil
+	})
+	changed = report.applyKeys(changed, exporter.ReloadableKeys, func() error {
+		return exporter.Reload(reloaded)
+	})
+	if reloader, ok := s.(common.Reloader); ok {
+		changed = report.applyKeys(changed, reloader.ReloadableKeys(), func() error {
+			return reloader.Reload(reloaded)
+		})
+	}
+	report.restart = changed
+	return report
+}
@@ -0,0 +1,464 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/metanode"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/raftstore"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The config keys of the roles checked by the validation, which are defined by the packages of the roles.
+const (
+	cfgClusterName          = "clusterName"
+	cfgID                   = "id"
+	cfgIP                   = "ip"
+	cfgPort                 = "port"
+	cfgPeers                = "peers"
+	cfgWalDir               = "walDir"
+	cfgStoreDir             = "storeDir"
+	cfgHeartbeatPort        = "heartbeatPort"
+	cfgReplicaPort          = "replicaPort"
+	cfgMetadataDir          = "metadataDir"
+	cfgRaftDir              = "raftDir"
+	cfgRaftHeartbeatPort    = "raftHeartbeatPort"
+	cfgRaftReplicaPort      = "raftReplicaPort"
+	cfgTotalMem             = "totalMem"
+	cfgMemUsageThreshold    = "memUsageThreshold"
+	cfgMemReadOnlyThreshold = "memReadOnlyThreshold"
+	cfgDisks                = "disks"
+	cfgRaftHeartbeat        = "raftHeartbeat"
+	cfgRaftReplica          = "raftReplica"
+	cfgPortmapListen        = "portmapListen"
+)
+
+// configCheck is the result of a check of the config file.
+type configCheck struct {
+	key string
+	msg string
+	err error
+}
+
+// configValidator checks the config of a role without touching the state on the disks, so that a bad
+// config is reported in full before the server starts instead of failing on the first error.
+type configValidator struct {
+	cfg    *config.Config
+	checks []*configCheck
+	ports  map[string]string // the ports to listen on, to the keys
+}
+
+func (v *configValidator) ok(key, format string, a ...interface{}) {
+	v.checks = append(v.checks, &configCheck{key: key, msg: fmt.Sprintf(format, a...)})
+}
+
+func (v *configValidator) fail(key string, err error) {
+	v.checks = append(v.checks, &configCheck{key: key, err: err})
+}
+
+func (v *configValidator) failed() (n int) {
+	for _, check := range v.checks {
+		if check.err != nil {
+			n++
+		}
+	}
+	return
+}
+
+// str returns the string value of the key, and reports the key which is missing or not a string.
+func (v *configValidator) str(key string, required bool) (value string, ok bool) {
+	raw, present := v.cfg.Get(key)
+	if !present {
+		if required {
+			v.fail(key, fmt.Errorf("required but not set"))
+		}
+		return
+	}
+	switch raw.(type) {
+	case string, float64, bool:
+		// the numbers and the booleans of YAML are taken as strings by the servers
+		value = v.cfg.GetString(key)
+	default:
+		v.fail(key, fmt.Errorf("should be a string, but is %v", raw))
+		return
+	}
+	if value == "" && required {
+		v.fail(key, fmt.Errorf("required but empty"))
+		return "", false
+	}
+	return value, value != ""
+}
+
+// strs returns the string array of the key, and reports the key which is missing or not an array of strings.
+func (v *configValidator) strs(key string, required bool) (values []string, ok bool) {
+	raw, present := v.cfg.Get(key)
+	if !present {
+		if required {
+			v.fail(key, fmt.Errorf("required but not set"))
+		}
+		return
+	}
+	if _, isString := raw.(string); isString {
+		// such as the value of an environment variable, which is split by commas
+		raw = v.cfg.GetSlice(key)
+	}
+	items, isArray := raw.([]interface{})
+	if !isArray {
+		v.fail(key, fmt.Errorf("should be an array of strings, but is %v", raw))
+		return
+	}
+	for _, item := range items {
+		value, isString := item.(string)
+		if !isString {
+			v.fail(key, fmt.Errorf("should be an array of strings, but has %v", item))
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 && required {
+		v.fail(key, fmt.Errorf("required but empty"))
+		return nil, false
+	}
+	return values, len(values) > 0
+}
+
+// port checks the port is valid, free, and not configured for another key. The default port is
+// used by the server if the key is not set, and the key is skipped if there is no default.
+func (v *configValidator) port(key string, required bool, defaultPort string) {
+	port, ok := v.str(key, required)
+	if !ok {
+		if required || defaultPort == "" {
+			return
+		}
+		port = defaultPort
+	}
+	v.listenPort(key, port)
+}
+
+func (v *configValidator) listenPort(key, port string) {
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		v.fail(key, fmt.Errorf("invalid port %v", port))
+		return
+	}
+	if other, exist := v.ports[port]; exist {
+		v.fail(key, fmt.Errorf("port %v is configured for %v too", port, other))
+		return
+	}
+	v.ports[port] = key
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		v.fail(key, fmt.Errorf("port %v is not free: %v", port, err))
+		return
+	}
+	ln.Close()
+	v.ok(key, "port %v is free", port)
+}
+
+// dir checks the directory of the key is writable. The missing directories are created by the
+// servers, in which case the nearest existing parent should be writable.
+func (v *configValidator) dir(key string, required bool) {
+	dir, ok := v.str(key, required)
+	if !ok {
+		return
+	}
+	if err := checkDirWritable(dir); err != nil {
+		v.fail(key, err)
+		return
+	}
+	v.ok(key, "%v is writable", dir)
+}
+
+func checkDirWritable(dir string) error {
+	p := filepath.Clean(dir)
+	for {
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) && p != filepath.Dir(p) {
+			p = filepath.Dir(p)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%v is not a directory", p)
+		}
+		f, err := ioutil.TempFile(p, ".validate-")
+		if err != nil {
+			return fmt.Errorf("%v is not writable: %v", p, err)
+		}
+		f.Close()
+		os.Remove(f.Name())
+		return nil
+	}
+}
+
+// addrs checks the addresses of the key are well-formed host:port.
+func (v *configValidator) addrs(key string) {
+	addrs, ok := v.strs(key, true)
+	if !ok {
+		return
+	}
+	for _, addr := range addrs {
+		if err := checkAddr(addr); err != nil {
+			v.fail(key, err)
+			return
+		}
+	}
+	v.ok(key, "%v", strings.Join(addrs, ","))
+}
+
+func checkAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %v: %v", addr, err)
+	}
+	if host == "" {
+		return fmt.Errorf("invalid address %v: missing host", addr)
+	}
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return fmt.Errorf("invalid address %v: bad port", addr)
+	}
+	return nil
+}
+
+// peers checks the raft peers of the master and the authnode, which are id:ip:port separated by
+// commas, and that the node itself is one of them.
+func (v *configValidator) peers(listenKey string) {
+	peers, ok := v.str(cfgPeers, true)
+	id, idOk := v.str(cfgID, true)
+	ip, ipOk := v.str(cfgIP, true)
+	port := v.cfg.GetString(listenKey)
+	if !ok {
+		return
+	}
+	if idOk {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			v.fail(cfgID, fmt.Errorf("invalid id %v", id))
+			idOk = false
+		}
+	}
+	ids := make(map[string]bool)
+	self := false
+	for _, peer := range strings.Split(peers, ",") {
+		arr := strings.Split(peer, ":")
+		if len(arr) != 3 {
+			v.fail(cfgPeers, fmt.Errorf("invalid peer %v, should be id:ip:port", peer))
+			return
+		}
+		if _, err := strconv.ParseUint(arr[0], 10, 64); err != nil {
+			v.fail(cfgPeers, fmt.Errorf("invalid id of peer %v", peer))
+			return
+		}
+		if err := checkAddr(net.JoinHostPort(arr[1], arr[2])); err != nil {
+			v.fail(cfgPeers, fmt.Errorf("invalid peer %v: %v", peer, err))
+			return
+		}
+		if ids[arr[0]] {
+			v.fail(cfgPeers, fmt.Errorf("id %v is duplicated", arr[0]))
+			return
+		}
+		ids[arr[0]] = true
+		if idOk && arr[0] == id {
+			self = true
+			if ipOk && arr[1] != ip {
+				v.fail(cfgPeers, fmt.Errorf("peer %v does not match ip %v", peer, ip))
+				return
+			}
+			if port != "" && arr[2] != port {
+				v.fail(cfgPeers, fmt.Errorf("peer %v does not match %v %v", peer, listenKey, port))
+				return
+			}
+		}
+	}
+	if idOk && !self {
+		v.fail(cfgPeers, fmt.Errorf("id %v is not one of the peers", id))
+		return
+	}
+	v.ok(cfgPeers, "%v", peers)
+}
+
+// raftPorts checks the raft ports of the master and the authnode, which are replaced by the
+// defaults if not greater than 1024.
+func (v *configValidator) raftPorts() {
+	for _, key := range []string{cfgHeartbeatPort, cfgReplicaPort} {
+		port := v.cfg.GetInt64(key)
+		if port <= 1024 && key == cfgHeartbeatPort {
+			port = raftstore.DefaultHeartbeatPort
+		} else if port <= 1024 {
+			port = raftstore.DefaultReplicaPort
+		}
+		v.listenPort(key, strconv.FormatInt(port, 10))
+	}
+}
+
+// disks checks the disks of the datanode, which are path:reservedSpace.
+func (v *configValidator) disks() {
+	disks, ok := v.strs(cfgDisks, true)
+	if !ok {
+		return
+	}
+	for _, disk := range disks {
+		arr := strings.Split(disk, ":")
+		if len(arr) != 2 {
+			v.fail(cfgDisks, fmt.Errorf("invalid disk %v, should be path:reservedSpace", disk))
+			continue
+		}
+		if _, err := strconv.ParseUint(arr[1], 10, 64); err != nil {
+			v.fail(cfgDisks, fmt.Errorf("invalid reserved space of disk %v", disk))
+			continue
+		}
+		info, err := os.Stat(arr[0])
+		if err != nil {
+			v.fail(cfgDisks, err)
+			continue
+		}
+		if !info.IsDir() {
+			v.fail(cfgDisks, fmt.Errorf("%v is not a directory", arr[0]))
+			continue
+		}
+		if err = checkDirWritable(arr[0]); err != nil {
+			v.fail(cfgDisks, err)
+			continue
+		}
+		v.ok(cfgDisks, "%v is writable", arr[0])
+	}
+}
+
+// memory checks the memory of the metanode is within the physical memory.
+func (v *configValidator) memory() {
+	value, ok := v.str(cfgTotalMem, true)
+	if !ok {
+		return
+	}
+	totalMem, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || totalMem == 0 {
+		v.fail(cfgTotalMem, fmt.Errorf("invalid memory %v", value))
+		return
+	}
+	if total, _, err := util.GetMemInfo(); err == nil && totalMem > total-util.GB {
+		v.fail(cfgTotalMem, fmt.Errorf("%v exceeds the physical memory %v less 1GB", totalMem, total))
+		return
+	}
+	v.ok(cfgTotalMem, "%v", totalMem)
+	usage, readOnly := v.cfg.GetFloat(cfgMemUsageThreshold), v.cfg.GetFloat(cfgMemReadOnlyThreshold)
+	if readOnly <= 0 {
+		readOnly = metanode.MaxUsedMemFactor
+	}
+	if usage > readOnly {
+		v.fail(cfgMemUsageThreshold, fmt.Errorf("%v is greater than %v %v", usage, cfgMemReadOnlyThreshold, readOnly))
+	}
+}
+
+func (v *configValidator) validate() {
+	role, _ := v.str(ConfigKeyRole, true)
+	v.dir(ConfigKeyLogDir, true)
+	v.dir(ConfigKeyWarnLogDir, false)
+	v.port(ConfigKeyProfPort, false, "")
+	if level, ok := v.str(ConfigKeyLogLevel, false); ok {
+		switch strings.ToLower(level) {
+		case "debug", "info", "warn", "error":
+		default:
+			v.fail(ConfigKeyLogLevel, fmt.Errorf("unknown level %v, should be one of debug, info, warn and error", level))
+		}
+	}
+	if format, ok := v.str(ConfigKeyLogFormat, false); ok {
+		if _, err := log.ParseFormat(format); err != nil {
+			v.fail(ConfigKeyLogFormat, err)
+		}
+	}
+
+	switch role {
+	case RoleMaster, RoleAuth:
+		listenKey := proto.ListenPort
+		if role == RoleAuth {
+			listenKey = cfgPort
+		}
+		v.str(cfgClusterName, true)
+		v.port(listenKey, true, "")
+		v.raftPorts()
+		v.peers(listenKey)
+		v.dir(cfgWalDir, true)
+		v.dir(cfgStoreDir, true)
+	case RoleMeta:
+		v.port(proto.ListenPort, true, "")
+		v.port(cfgRaftHeartbeatPort, true, "")
+		v.port(cfgRaftReplicaPort, true, "")
+		v.dir(cfgMetadataDir, true)
+		v.dir(cfgRaftDir, true)
+		v.memory()
+		v.addrs(proto.MasterAddr)
+	case RoleData:
+		v.port(proto.ListenPort, true, "")
+		v.port(cfgRaftHeartbeat, true, "")
+		v.port(cfgRaftReplica, true, "")
+		v.dir(cfgRaftDir, true)
+		v.disks()
+		v.addrs(proto.MasterAddr)
+	case RoleObject:
+		v.port(proto.ListenPort, false, "80")
+		v.addrs(proto.MasterAddr)
+	case RoleConsole:
+		v.port(proto.ListenPort, false, "80")
+		v.addrs(proto.MasterAddr)
+	case RoleNfs:
+		v.port(proto.ListenPort, false, "2049")
+		v.port(cfgPortmapListen, false, "")
+		v.addrs(proto.MasterAddr)
+	case RoleSmb:
+		v.port(proto.ListenPort, false, "445")
+		v.addrs(proto.MasterAddr)
+	case "":
+	default:
+		v.fail(ConfigKeyRole, fmt.Errorf("unknown role %v", role))
+	}
+}
+
+// validateConfigFile checks the config file for the role of it and prints the report, it returns
+// the exit code of the process.
+func validateConfigFile(file string) int {
+	cfg, envKeys, err := loadConfig(file)
+	if err != nil {
+		fmt.Printf("FAIL  %v: %v\n", file, err)
+		return 1
+	}
+	for _, key := range envKeys {
+		fmt.Printf("env   %v: overridden by the environment\n", key)
+	}
+	v := &configValidator{cfg: cfg, ports: make(map[string]string)}
+	v.validate()
+	for _, check := range v.checks {
+		if check.err != nil {
+			fmt.Printf("FAIL  %v: %v\n", check.key, check.err)
+		} else {
+			fmt.Printf("ok    %v: %v\n", check.key, check.msg)
+		}
+	}
+	if n := v.failed(); n > 0 {
+		fmt.Printf("%v: %v of the checks failed\n", file, n)
+		return 1
+	}
+	fmt.Printf("%v: config is valid for role %v\n", file, cfg.GetString(ConfigKeyRole))
+	return 0
+}
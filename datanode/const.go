@@ -41,6 +41,11 @@ const (
 	RaftNotStarted = "RaftNotStarted"
 )
 
+const (
+	// MetricDeadlineExceeded counts the requests failed fast since their deadlines have passed.
+	MetricDeadlineExceeded = "deadlineExceeded"
+)
+
 // Action description
 const (
 	ActionNotifyFollowerToRepair        = "ActionNotifyFollowerRepair"
@@ -54,6 +59,7 @@ const (
 	ActionAddDataPartitionRaftMember    = "ActionAddDataPartitionRaftMember"
 	ActionRemoveDataPartitionRaftMember = "ActionRemoveDataPartitionRaftMember"
 	ActionDataPartitionTryToLeader      = "ActionDataPartitionTryToLeader"
+	ActionResetDataPartitionRaftMember  = "ActionResetDataPartitionRaftMember"
 
 	ActionCreateDataPartition        = "ActionCreateDataPartition"
 	ActionLoadDataPartition          = "ActionLoadDataPartition"
@@ -62,6 +68,10 @@ const (
 	ActionSyncTinyDeleteRecord       = "ActionSyncTinyDeleteRecord"
 	ActionStreamReadTinyExtentRepair = "ActionStreamReadTinyExtentRepair"
 	ActionBatchMarkDelete            = "ActionBatchMarkDelete"
+	ActionSetClientThrottle          = "ActionSetClientThrottle"
+	ActionCheckDeadline              = "ActionCheckDeadline"
+	ActionCheckQos                   = "ActionCheckQos"
+	ActionSealExtents                = "ActionSealExtents"
 )
 
 // Apply the raft log operation. Currently we only have the random write operation.
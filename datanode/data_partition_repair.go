@@ -36,11 +36,14 @@ import (
 type DataPartitionRepairTask struct {
 	TaskType                       uint8
 	addr                           string
+	zone                           string
 	extents                        map[uint64]*storage.ExtentInfo
 	ExtentsToBeCreated             []*storage.ExtentInfo
 	ExtentsToBeRepaired            []*storage.ExtentInfo
+	ExtentsToBeReset               []*storage.ExtentInfo // extents truncated before being repaired from the beginning
 	LeaderTinyDeleteRecordFileSize int64
 	LeaderAddr                     string
+	LeaderSealedExtents            []proto.ExtentKey // ranges sealed on the leader, which are merged by the followers
 }
 
 func NewDataPartitionRepairTask(extentFiles []*storage.ExtentInfo, tinyDeleteRecordFileSize int64, source, leaderAddr string) (task *DataPartitionRepairTask) {
@@ -141,8 +144,10 @@ func (dp *DataPartition) buildDataPartitionRepairTask(repairTasks []*DataPartiti
 	// new repair task for the leader
 	repairTasks[0] = NewDataPartitionRepairTask(extents, leaderTinyDeleteRecordFileSize, dp.getReplicaAddr(0), dp.getReplicaAddr(0))
 	repairTasks[0].addr = dp.getReplicaAddr(0)
+	repairTasks[0].zone = getPeerZone(repairTasks[0].addr)
 
 	// new repair tasks for the followers
+	sealed := dp.sealedExtentKeys()
 	for index := 1; index < dp.getReplicaLen(); index++ {
 		extents, err := dp.getRemoteExtentInfo(extentType, tinyExtents, dp.getReplicaAddr(index))
 		if err != nil {
@@ -151,6 +156,8 @@ func (dp *DataPartition) buildDataPartitionRepairTask(repairTasks []*DataPartiti
 		}
 		repairTasks[index] = NewDataPartitionRepairTask(extents, leaderTinyDeleteRecordFileSize, dp.getReplicaAddr(index), dp.getReplicaAddr(0))
 		repairTasks[index].addr = dp.getReplicaAddr(index)
+		repairTasks[index].zone = getPeerZone(repairTasks[index].addr)
+		repairTasks[index].LeaderSealedExtents = sealed
 	}
 
 	return
@@ -345,7 +352,7 @@ func (dp *DataPartition) buildExtentCreationTasks(repairTasks []*DataPartitionRe
 				if dp.ExtentStore().IsDeletedNormalExtent(extentID) {
 					continue
 				}
-				ei := &storage.ExtentInfo{Source: extentInfo.Source, FileID: extentID, Size: extentInfo.Size}
+				ei := &storage.ExtentInfo{Source: selectRepairSource(repairTasks, index, extentInfo), FileID: extentID, Size: extentInfo.Size}
 				repairTask.ExtentsToBeCreated = append(repairTask.ExtentsToBeCreated, ei)
 				repairTask.ExtentsToBeRepaired = append(repairTask.ExtentsToBeRepaired, ei)
 				log.LogInfof("action[generatorAddExtentsTasks] addFile(%v_%v) on Index(%v).", dp.partitionID, ei, index)
@@ -376,7 +383,7 @@ func (dp *DataPartition) buildExtentRepairTasks(repairTasks []*DataPartitionRepa
 				continue
 			}
 			if extentInfo.Size < maxFileInfo.Size {
-				fixExtent := &storage.ExtentInfo{Source: maxFileInfo.Source, FileID: extentID, Size: maxFileInfo.Size}
+				fixExtent := &storage.ExtentInfo{Source: selectRepairSource(repairTasks, index, maxFileInfo), FileID: extentID, Size: maxFileInfo.Size}
 				repairTasks[index].ExtentsToBeRepaired = append(repairTasks[index].ExtentsToBeRepaired, fixExtent)
 				log.LogInfof("action[generatorFixExtentSizeTasks] fixExtent(%v_%v) on Index(%v) on(%v).",
 					dp.partitionID, fixExtent, index, repairTasks[index].addr)
@@ -396,17 +403,21 @@ func (dp *DataPartition) buildExtentRepairTasks(repairTasks []*DataPartitionRepa
 }
 
 func (dp *DataPartition) notifyFollower(wg *sync.WaitGroup, index int, members []*DataPartitionRepairTask) (err error) {
-	p := repl.NewPacketToNotifyExtentRepair(dp.partitionID) // notify all the followers to repair
-	var conn *net.TCPConn
 	target := dp.getReplicaAddr(index)
-	p.Data, _ = json.Marshal(members[index])
-	p.Size = uint32(len(p.Data))
-	conn, err = gConnPool.GetConnect(target)
 	defer func() {
 		wg.Done()
 		log.LogInfof(fmt.Sprintf(ActionNotifyFollowerToRepair+" to host(%v) Partition(%v) failed (%v)", target, dp.partitionID, err))
 	}()
-	if err != nil {
+	return dp.sendRepairTask(target, members[index])
+}
+
+// sendRepairTask sends the repair task to the replica, and waits for the replica to finish it.
+func (dp *DataPartition) sendRepairTask(target string, task *DataPartitionRepairTask) (err error) {
+	p := repl.NewPacketToNotifyExtentRepair(dp.partitionID)
+	var conn *net.TCPConn
+	p.Data, _ = json.Marshal(task)
+	p.Size = uint32(len(p.Data))
+	if conn, err = gConnPool.GetConnect(target); err != nil {
 		return err
 	}
 	defer gConnPool.PutConnect(conn, true)
@@ -416,6 +427,9 @@ func (dp *DataPartition) notifyFollower(wg *sync.WaitGroup, index int, members [
 	if err = p.ReadFromConn(conn, proto.NoReadDeadlineTime); err != nil {
 		return err
 	}
+	if p.ResultCode != proto.OpOk {
+		err = fmt.Errorf("repair on host(%v) failed: %v", target, string(p.Data[:p.Size]))
+	}
 	return err
 }
 
@@ -498,6 +512,7 @@ func (dp *DataPartition) streamRepairExtent(remoteExtentInfo *storage.ExtentInfo
 		log.LogWarnf("action[streamRepairExtent] err(%v).", err)
 		return
 	}
+	sourceZone := getPeerZone(remoteExtentInfo.Source)
 	currFixOffset := localExtentInfo.Size
 	var (
 		hasRecoverySize uint64
@@ -543,6 +558,7 @@ func (dp *DataPartition) streamRepairExtent(remoteExtentInfo *storage.ExtentInfo
 				remoteExtentInfo.Source, remoteExtentInfo.Size, currFixOffset, request.GetUniqueLogId(), reply.GetUniqueLogId())
 			return errors.Trace(err, "streamRepairExtent receive data error")
 		}
+		waitRepairBandwidth(sourceZone, int(reply.Size))
 		isEmptyResponse := false
 		// Write it to local extent file
 		if storage.IsTinyExtent(uint64(localExtentInfo.FileID)) {
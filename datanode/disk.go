@@ -56,6 +56,11 @@ type Disk struct {
 	Status        int // disk status such as READONLY
 	ReservedSpace uint64
 
+	offlineReason string // why the disk is unavailable
+	offlineTime   int64
+	manualOffline bool // taken offline by the operator rather than by the io errors
+	smart         *proto.DiskSmart
+
 	RejectWrite                               bool
 	partitionMap                              map[uint64]*DataPartition
 	syncTinyDeleteRecordFromLeaderOnEveryDisk chan bool
@@ -90,6 +95,14 @@ func (d *Disk) PartitionCount() int {
 	return len(d.partitionMap)
 }
 
+// setReservedSpace changes the space reserved on the disk and computes the usage again.
+func (d *Disk) setReservedSpace(reservedSpace uint64) {
+	d.Lock()
+	d.ReservedSpace = reservedSpace
+	d.Unlock()
+	d.computeUsage()
+}
+
 // Compute the disk usage
 func (d *Disk) computeUsage() (err error) {
 	d.RLock()
@@ -146,10 +159,91 @@ func (d *Disk) computeUsage() (err error) {
 
 func (d *Disk) incReadErrCnt() {
 	atomic.AddUint64(&d.ReadErrCnt, 1)
+	d.checkErrCnt()
 }
 
 func (d *Disk) incWriteErrCnt() {
 	atomic.AddUint64(&d.WriteErrCnt, 1)
+	d.checkErrCnt()
+}
+
+// checkErrCnt takes the disk offline once its io errors reach the maximum, so that a dying disk does not slow down
+// the partitions on the other disks of the node.
+func (d *Disk) checkErrCnt() {
+	if d.MaxErrCnt <= 0 {
+		return
+	}
+	readErrCnt := atomic.LoadUint64(&d.ReadErrCnt)
+	writeErrCnt := atomic.LoadUint64(&d.WriteErrCnt)
+	if readErrCnt+writeErrCnt < uint64(d.MaxErrCnt) {
+		return
+	}
+	d.markOffline(fmt.Sprintf("%v read errors and %v write errors", readErrCnt, writeErrCnt), false)
+}
+
+// markOffline makes the disk unavailable and stops the raft of its partitions. The master finds the disk in the
+// bad disks of the heartbeat.
+func (d *Disk) markOffline(reason string, manual bool) {
+	d.Lock()
+	if d.Status == proto.Unavailable {
+		d.Unlock()
+		return
+	}
+	d.Status = proto.Unavailable
+	d.offlineReason = reason
+	d.offlineTime = time.Now().Unix()
+	d.manualOffline = manual
+	d.Unlock()
+	mesg := fmt.Sprintf("disk path %v on %v is offline: %v", d.Path, LocalIP, reason)
+	exporter.Warning(mesg)
+	log.LogErrorf(mesg)
+	d.ForceExitRaftStore()
+}
+
+// markOnline makes the disk available again and clears its io errors. The partitions stopped with the disk stay
+// unavailable until the data node restarts, the disk only takes the new partitions.
+func (d *Disk) markOnline() (ok bool) {
+	d.Lock()
+	defer d.Unlock()
+	if d.Status != proto.Unavailable {
+		return false
+	}
+	atomic.StoreUint64(&d.ReadErrCnt, 0)
+	atomic.StoreUint64(&d.WriteErrCnt, 0)
+	d.offlineReason = ""
+	d.offlineTime = 0
+	d.manualOffline = false
+	if d.Available <= 0 {
+		d.Status = proto.ReadOnly
+	} else {
+		d.Status = proto.ReadWrite
+	}
+	log.LogWarnf("action[markOnline] disk path %v on %v is online", d.Path, LocalIP)
+	return true
+}
+
+// Info returns the state of the disk.
+func (d *Disk) Info() *proto.DiskInfo {
+	d.RLock()
+	defer d.RUnlock()
+	return &proto.DiskInfo{
+		Path:          d.Path,
+		Total:         d.Total,
+		Used:          d.Used,
+		Available:     d.Available,
+		Unallocated:   d.Unallocated,
+		Allocated:     atomic.LoadUint64(&d.Allocated),
+		Status:        d.Status,
+		RestSize:      d.ReservedSpace,
+		Partitions:    len(d.partitionMap),
+		ReadErrCnt:    atomic.LoadUint64(&d.ReadErrCnt),
+		WriteErrCnt:   atomic.LoadUint64(&d.WriteErrCnt),
+		MaxErrCnt:     d.MaxErrCnt,
+		OfflineReason: d.offlineReason,
+		OfflineTime:   d.offlineTime,
+		ManualOffline: d.manualOffline,
+		Smart:         d.smart,
+	}
 }
 
 func (d *Disk) startScheduleToUpdateSpaceInfo() {
@@ -220,11 +314,7 @@ func (d *Disk) triggerDiskError(err error) {
 		return
 	}
 	if IsDiskErr(err.Error()) {
-		mesg := fmt.Sprintf("disk path %v error on %v", d.Path, LocalIP)
-		exporter.Warning(mesg)
-		log.LogErrorf(mesg)
-		d.ForceExitRaftStore()
-		d.Status = proto.Unavailable
+		d.markOffline(err.Error(), false)
 	}
 	return
 }
@@ -234,15 +324,21 @@ func (d *Disk) updateSpaceInfo() (err error) {
 	if err = syscall.Statfs(d.Path, &statsInfo); err != nil {
 		d.incReadErrCnt()
 	}
-	if d.Status == proto.Unavailable {
+	d.Lock()
+	unavailable := d.Status == proto.Unavailable
+	if !unavailable {
+		if d.Available <= 0 {
+			d.Status = proto.ReadOnly
+		} else {
+			d.Status = proto.ReadWrite
+		}
+	}
+	d.Unlock()
+	if unavailable {
 		mesg := fmt.Sprintf("disk path %v error on %v", d.Path, LocalIP)
 		log.LogErrorf(mesg)
 		exporter.Warning(mesg)
 		d.ForceExitRaftStore()
-	} else if d.Available <= 0 {
-		d.Status = proto.ReadOnly
-	} else {
-		d.Status = proto.ReadWrite
 	}
 	log.LogDebugf("action[updateSpaceInfo] disk(%v) total(%v) available(%v) remain(%v) "+
 		"restSize(%v) maxErrs(%v) readErrs(%v) writeErrs(%v) status(%v)", d.Path,
@@ -333,6 +429,13 @@ func (d *Disk) RestorePartition(visitor PartitionVisitor) {
 		}
 		break
 	}
+	// the startup fails later in checkLocalPartitionMatchWithMaster if the master is unreachable,
+	// nothing can be told about the local partitions here
+	checkUnknown := dataNode != nil
+	if !checkUnknown {
+		log.LogErrorf("action[RestorePartition]: get datanode info from master failed, skip the unknown partition check")
+		dataNode = &proto.DataNodeInfo{}
+	}
 	dinfo := convert(dataNode)
 	if len(dinfo.PersistenceDataPartitions) == 0 {
 		log.LogWarnf("action[RestorePartition]: length of PersistenceDataPartitions is 0, ExpiredPartition check " +
@@ -353,6 +456,10 @@ func (d *Disk) RestorePartition(visitor PartitionVisitor) {
 	var wg sync.WaitGroup
 	for _, fileInfo := range fileInfoList {
 		filename := fileInfo.Name()
+		if strings.HasPrefix(filename, ExpiredPartitionPrefix) {
+			d.space.dataNode.selfCheck.addQuarantined(path.Join(d.Path, filename))
+			continue
+		}
 		if !d.isPartitionDir(filename) {
 			continue
 		}
@@ -365,12 +472,8 @@ func (d *Disk) RestorePartition(visitor PartitionVisitor) {
 		log.LogDebugf("acton[RestorePartition] disk(%v) path(%v) PartitionID(%v) partitionSize(%v).",
 			d.Path, fileInfo.Name(), partitionID, partitionSize)
 
-		if isExpiredPartition(partitionID, dinfo.PersistenceDataPartitions) {
-			log.LogErrorf("action[RestorePartition]: find expired partition[%s], rename it and you can delete it "+
-				"manually", filename)
-			oldName := path.Join(d.Path, filename)
-			newName := path.Join(d.Path, ExpiredPartitionPrefix+filename)
-			os.Rename(oldName, newName)
+		if checkUnknown && isExpiredPartition(partitionID, dinfo.PersistenceDataPartitions) {
+			d.space.dataNode.handleUnknownPartition(d, filename, partitionID)
 			continue
 		}
 
@@ -0,0 +1,89 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// isReplaced tells whether the disk looks like a newly installed one: the directory is the root of a
+// mounted file system and holds no partition. The mount point check keeps the lost partitions from being
+// re-created on the root file system when the disk fails to be mounted.
+func (d *Disk) isReplaced() bool {
+	if d.PartitionCount() > 0 {
+		return false
+	}
+	fileInfoList, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return false
+	}
+	for _, fileInfo := range fileInfoList {
+		if d.isPartitionDir(fileInfo.Name()) || strings.HasPrefix(fileInfo.Name(), ExpiredPartitionPrefix) {
+			return false
+		}
+	}
+	var stat, parentStat syscall.Stat_t
+	if err = syscall.Stat(d.Path, &stat); err != nil {
+		return false
+	}
+	if err = syscall.Stat(path.Dir(path.Clean(d.Path)), &parentStat); err != nil {
+		return false
+	}
+	return stat.Dev != parentStat.Dev
+}
+
+func (manager *SpaceManager) replacedDisks() (disks []string) {
+	manager.diskMutex.RLock()
+	defer manager.diskMutex.RUnlock()
+	disks = make([]string, 0)
+	for _, d := range manager.disks {
+		if d.isReplaced() {
+			disks = append(disks, d.Path)
+		}
+	}
+	sort.Strings(disks)
+	return
+}
+
+// recoverReplacedDisks asks the master to re-create the lost partitions on this node if some disks have
+// been replaced, it returns false if no disk is replaced or the master rejects the recovery.
+func (s *DataNode) recoverReplacedDisks(lackPartitions []uint64) bool {
+	disks := s.space.replacedDisks()
+	if len(disks) == 0 {
+		return false
+	}
+	req := &proto.DiskRecoveryRequest{Addr: s.localServerAddr, Disks: disks, PartitionIDs: lackPartitions}
+	if _, err := MasterClient.NodeAPI().RecoverDisk(req); err != nil {
+		log.LogErrorf("action[recoverReplacedDisks] register replaced disks(%v) err(%v)", disks, err)
+		return false
+	}
+	s.selfCheck.Lock()
+	s.selfCheck.ReplacedDisks = append(s.selfCheck.ReplacedDisks, disks...)
+	s.selfCheck.Unlock()
+	msg := fmt.Sprintf("action[recoverReplacedDisks] disks(%v) are replaced, lack partitions(%v) on datanode(%v) "+
+		"are re-created by the master", disks, lackPartitions, s.localServerAddr)
+	log.LogWarn(msg)
+	exporter.Warning(msg)
+	return true
+}
@@ -0,0 +1,268 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	DefaultSmartInterval = 10 * time.Minute
+
+	// MetricDiskSmartAttr is the raw value of a SMART attribute of a disk.
+	MetricDiskSmartAttr = "diskSmartAttr"
+	// MetricDiskFailurePredicted is 1 for a disk predicted to fail, 0 otherwise.
+	MetricDiskFailurePredicted = "diskFailurePredicted"
+
+	smartctlCmd     = "smartctl"
+	smartctlTimeout = 30 * time.Second
+	mountsFile      = "/proc/mounts"
+)
+
+// The SMART attributes of which a raw value above zero predicts the failure of a disk.
+var smartFailureAttrs = map[int]bool{
+	5:   true, // Reallocated_Sector_Ct
+	187: true, // Reported_Uncorrect
+	188: true, // Command_Timeout
+	197: true, // Current_Pending_Sector
+	198: true, // Offline_Uncorrectable
+}
+
+// smartctlOutput is the part of the json output of smartctl used to predict the failures.
+type smartctlOutput struct {
+	Smartctl struct {
+		Messages []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	AtaSmartAttributes struct {
+		Table []struct {
+			ID     int    `json:"id"`
+			Name   string `json:"name"`
+			Value  int    `json:"value"`
+			Worst  int    `json:"worst"`
+			Thresh int    `json:"thresh"`
+			Raw    struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NvmeSmartHealth *struct {
+		CriticalWarning int   `json:"critical_warning"`
+		MediaErrors     int64 `json:"media_errors"`
+		PercentageUsed  int64 `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// startSmartCollector collects the SMART attributes of the disks periodically.
+func (s *DataNode) startSmartCollector() {
+	if s.smartInterval <= 0 {
+		log.LogInfof("action[startSmartCollector] SMART collection is disabled")
+		return
+	}
+	attrGauge := exporter.NewGaugeVec(MetricDiskSmartAttr, "", []string{"path", "device", "attr"})
+	failureGauge := exporter.NewGaugeVec(MetricDiskFailurePredicted, "", []string{"path"})
+	go func() {
+		ticker := time.NewTicker(s.smartInterval)
+		defer ticker.Stop()
+		for {
+			for _, d := range s.space.GetDisks() {
+				smart := collectDiskSmart(d.Path)
+				d.setSmart(smart)
+				for _, attr := range smart.Attributes {
+					attrGauge.SetWithLabelValues(float64(attr.Raw), d.Path, smart.Device, attr.Name)
+				}
+				var predicted float64
+				if smart.PredictedFailure {
+					predicted = 1
+				}
+				failureGauge.SetWithLabelValues(predicted, d.Path)
+			}
+			select {
+			case <-s.stopC:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (d *Disk) setSmart(smart *proto.DiskSmart) {
+	d.Lock()
+	old := d.smart
+	d.smart = smart
+	d.Unlock()
+	if smart.PredictedFailure && (old == nil || !old.PredictedFailure) {
+		mesg := fmt.Sprintf("disk path %v on %v is predicted to fail: %v", d.Path, LocalIP, smart.Reason)
+		exporter.Warning(mesg)
+		log.LogErrorf("%v", mesg)
+	}
+	if smart.Err != "" {
+		log.LogWarnf("action[setSmart] disk(%v) err(%v)", d.Path, smart.Err)
+	}
+}
+
+// isFailurePredicted returns whether the SMART attributes of the disk predict its failure.
+func (d *Disk) isFailurePredicted() bool {
+	d.RLock()
+	defer d.RUnlock()
+	return d.smart != nil && d.smart.PredictedFailure
+}
+
+// collectDiskSmart runs smartctl on the device mounted on the path. The disks failed to be collected, e.g. because
+// smartctl is not installed, are not predicted to fail.
+func collectDiskSmart(path string) (smart *proto.DiskSmart) {
+	smart = &proto.DiskSmart{UpdateTime: time.Now().Unix()}
+	device, err := mountedDevice(path)
+	if err != nil {
+		smart.Err = err.Error()
+		return
+	}
+	smart.Device = device
+	ctx, cancel := context.WithTimeout(context.Background(), smartctlTimeout)
+	defer cancel()
+	// smartctl exits with a bit mask of the problems found, the output is still parsed then.
+	data, err := exec.CommandContext(ctx, smartctlCmd, "-H", "-A", "-j", device).Output()
+	if len(data) == 0 {
+		smart.Err = fmt.Sprintf("run %v on %v: %v", smartctlCmd, device, err)
+		return
+	}
+	output := &smartctlOutput{}
+	if err = json.Unmarshal(data, output); err != nil {
+		smart.Err = fmt.Sprintf("parse the output of %v on %v: %v", smartctlCmd, device, err)
+		return
+	}
+	parseSmartctlOutput(smart, output)
+	return
+}
+
+func parseSmartctlOutput(smart *proto.DiskSmart, output *smartctlOutput) {
+	if output.SmartStatus == nil {
+		for _, msg := range output.Smartctl.Messages {
+			if msg.Severity == "error" {
+				smart.Err = msg.String
+				return
+			}
+		}
+		smart.Err = "no SMART status"
+		return
+	}
+	smart.Passed = output.SmartStatus.Passed
+	reasons := make([]string, 0)
+	if !smart.Passed {
+		reasons = append(reasons, "health self-assessment failed")
+	}
+	for _, attr := range output.AtaSmartAttributes.Table {
+		smart.Attributes = append(smart.Attributes, &proto.SmartAttribute{
+			ID:        attr.ID,
+			Name:      attr.Name,
+			Value:     attr.Value,
+			Worst:     attr.Worst,
+			Threshold: attr.Thresh,
+			Raw:       attr.Raw.Value,
+		})
+		if smartFailureAttrs[attr.ID] && attr.Raw.Value > 0 {
+			reasons = append(reasons, fmt.Sprintf("%v=%v", attr.Name, attr.Raw.Value))
+		}
+	}
+	if nvme := output.NvmeSmartHealth; nvme != nil {
+		smart.Attributes = append(smart.Attributes,
+			&proto.SmartAttribute{Name: "Critical_Warning", Raw: int64(nvme.CriticalWarning)},
+			&proto.SmartAttribute{Name: "Media_Errors", Raw: nvme.MediaErrors},
+			&proto.SmartAttribute{Name: "Percentage_Used", Raw: nvme.PercentageUsed},
+		)
+		if nvme.CriticalWarning != 0 {
+			reasons = append(reasons, fmt.Sprintf("Critical_Warning=%v", nvme.CriticalWarning))
+		}
+		if nvme.MediaErrors > 0 {
+			reasons = append(reasons, fmt.Sprintf("Media_Errors=%v", nvme.MediaErrors))
+		}
+	}
+	if len(reasons) > 0 {
+		smart.PredictedFailure = true
+		smart.Reason = strings.Join(reasons, ", ")
+	}
+}
+
+// mountedDevice returns the device of the longest mount point containing the path.
+func mountedDevice(path string) (device string, err error) {
+	fp, err := os.Open(mountsFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	var mountPoint string
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		point := unescapeMountPoint(fields[1])
+		if !isPathUnder(path, point) || len(point) <= len(mountPoint) {
+			continue
+		}
+		device, mountPoint = fields[0], point
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if device == "" {
+		err = fmt.Errorf("no device is mounted on %v", path)
+	}
+	return
+}
+
+func isPathUnder(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// unescapeMountPoint decodes the octal escapes such as \040 of the spaces in /proc/mounts.
+func unescapeMountPoint(point string) string {
+	if !strings.Contains(point, "\\") {
+		return point
+	}
+	var sb strings.Builder
+	for i := 0; i < len(point); i++ {
+		if point[i] == '\\' && i+4 <= len(point) {
+			if c, err := strconv.ParseUint(point[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(point[i])
+	}
+	return sb.String()
+}
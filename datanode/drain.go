@@ -0,0 +1,276 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/util/health"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Phases of draining the data node before it exits.
+const (
+	DrainPhaseNone         = ""
+	DrainPhaseReadOnly     = "readOnly"     // the partitions are reported read-only, so that the clients stop choosing them
+	DrainPhaseFlushing     = "flushing"     // the new writes are rejected, and the writes in flight are finished
+	DrainPhaseTransferring = "transferring" // the raft leaders of the partitions are transferred to the other replicas
+	DrainPhaseDone         = "done"
+)
+
+const (
+	DefaultDrainTimeout = 5 * time.Minute
+
+	// the clients refresh the views of the data partitions every minute
+	drainClientRefreshWait = time.Minute + 10*time.Second
+	// the writes in flight are finished if there is none for the period
+	drainQuietPeriod       = 3 * time.Second
+	drainCheckInterval     = 100 * time.Millisecond
+	drainTransferInterval  = 5 * time.Second
+	drainTryToLeaderSecond = 30
+)
+
+var ErrDataNodeDraining = errors.New("data node is draining")
+
+type drainState struct {
+	sync.RWMutex
+	phase        string
+	startTime    time.Time
+	reportedTime time.Time // time when the partitions are reported read-only to the master
+}
+
+// inflight writes of the partitions, including the ones forwarded by the other replicas
+var inflightWrites int64
+
+func (s *DataNode) drainPhase() string {
+	s.drain.RLock()
+	defer s.drain.RUnlock()
+	return s.drain.phase
+}
+
+func (s *DataNode) isDraining() bool {
+	return s.drainPhase() != DrainPhaseNone
+}
+
+// isRejectingWrites returns true if the new writes from the clients are rejected, so that the clients
+// write to the other partitions.
+func (s *DataNode) isRejectingWrites() bool {
+	switch s.drainPhase() {
+	case DrainPhaseFlushing, DrainPhaseTransferring, DrainPhaseDone:
+		return true
+	}
+	return false
+}
+
+func (s *DataNode) setDrainPhase(phase string) {
+	s.drain.Lock()
+	s.drain.phase = phase
+	s.drain.Unlock()
+	log.LogWarnf("action[drain] phase(%v)", phase)
+}
+
+// markDrainReported records that the partitions have been reported read-only to the master.
+func (s *DataNode) markDrainReported() {
+	s.drain.Lock()
+	defer s.drain.Unlock()
+	if s.drain.phase != DrainPhaseNone && s.drain.reportedTime.IsZero() {
+		s.drain.reportedTime = time.Now()
+	}
+}
+
+func (s *DataNode) drainStatus() *proto.DataNodeDrainStatus {
+	s.drain.RLock()
+	status := &proto.DataNodeDrainStatus{Phase: s.drain.phase}
+	if !s.drain.startTime.IsZero() {
+		status.StartTime = s.drain.startTime.Unix()
+	}
+	s.drain.RUnlock()
+	status.InflightWrites = atomic.LoadInt64(&inflightWrites)
+	s.space.RangePartitions(func(dp *DataPartition) bool {
+		if _, isLeader := dp.IsRaftLeader(); isLeader {
+			status.RaftLeaders++
+		}
+		return true
+	})
+	return status
+}
+
+// beginDrain starts draining the data node, false is returned if it is being drained.
+func (s *DataNode) beginDrain() bool {
+	s.drain.Lock()
+	defer s.drain.Unlock()
+	if s.drain.phase != DrainPhaseNone {
+		return false
+	}
+	s.drain.phase = DrainPhaseReadOnly
+	s.drain.startTime = time.Now()
+	health.SetRunning(false)
+	log.LogWarnf("action[drain] phase(%v)", DrainPhaseReadOnly)
+	return true
+}
+
+// Drain moves the writes and the raft leaders of the partitions to the other data nodes, so that the
+// data node can be shut down without failing the requests of the clients.
+func (s *DataNode) Drain(timeout time.Duration) error {
+	if !s.beginDrain() {
+		return ErrDataNodeDraining
+	}
+	s.runDrain(timeout)
+	return nil
+}
+
+func (s *DataNode) runDrain(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	// The master marks the partitions read-only on the heartbeat, and the clients stop writing to
+	// them once they refresh the views of the partitions.
+	s.waitDrain(deadline, func() bool {
+		s.drain.RLock()
+		defer s.drain.RUnlock()
+		return !s.drain.reportedTime.IsZero() && time.Since(s.drain.reportedTime) >= drainClientRefreshWait
+	})
+
+	s.setDrainPhase(DrainPhaseFlushing)
+	quietSince := time.Now()
+	s.waitDrain(deadline, func() bool {
+		if atomic.LoadInt64(&inflightWrites) > 0 {
+			quietSince = time.Now()
+		}
+		return time.Since(quietSince) >= drainQuietPeriod
+	})
+
+	s.setDrainPhase(DrainPhaseTransferring)
+	for s.transferRaftLeaders() > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainTransferInterval)
+	}
+
+	s.setDrainPhase(DrainPhaseDone)
+	if time.Now().After(deadline) {
+		log.LogWarnf("action[drain] timeout(%v) expired: %+v", timeout, s.drainStatus())
+	}
+}
+
+// waitDrain waits until the condition is met or the deadline expires.
+func (s *DataNode) waitDrain(deadline time.Time, done func() bool) {
+	for !done() && time.Now().Before(deadline) {
+		time.Sleep(drainCheckInterval)
+	}
+}
+
+// transferRaftLeaders asks the other replicas to take over the raft leaders of the partitions, and
+// returns the number of the partitions of which the data node is still the leader.
+func (s *DataNode) transferRaftLeaders() (leaders int) {
+	s.space.RangePartitions(func(dp *DataPartition) bool {
+		if _, isLeader := dp.IsRaftLeader(); !isLeader {
+			return true
+		}
+		leaders++
+		for _, addr := range dp.Replicas() {
+			if addr == s.localServerAddr {
+				continue
+			}
+			err := tryToLeader(dp.partitionID, addr)
+			if err == nil {
+				log.LogInfof("action[drain] partition(%v) leader is transferred to %v", dp.partitionID, addr)
+				break
+			}
+			log.LogWarnf("action[drain] partition(%v) transfer leader to %v failed: %v", dp.partitionID, addr, err)
+		}
+		return true
+	})
+	return
+}
+
+func tryToLeader(partitionID uint64, addr string) (err error) {
+	p := proto.NewPacket()
+	p.Opcode = proto.OpDataPartitionTryToLeader
+	p.PartitionID = partitionID
+	p.ReqID = proto.GenerateRequestID()
+	conn, err := gConnPool.GetConnect(addr)
+	if err != nil {
+		return
+	}
+	defer func() {
+		gConnPool.PutConnect(conn, err != nil)
+	}()
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	if err = p.ReadFromConn(conn, drainTryToLeaderSecond); err != nil {
+		return
+	}
+	if p.ResultCode != proto.OpOk {
+		err = fmt.Errorf("%v: %v", p.GetResultMsg(), string(p.Data[:p.Size]))
+	}
+	return
+}
+
+// countInflightWrite counts the write until the returned function is called.
+func countInflightWrite(p *repl.Packet) func() {
+	if !p.IsWriteOperation() && !p.IsCreateExtentOperation() {
+		return func() {}
+	}
+	atomic.AddInt64(&inflightWrites, 1)
+	return func() {
+		atomic.AddInt64(&inflightWrites, -1)
+	}
+}
+
+// drainAPI starts draining the data node, and the data node exits once it is drained. The progress is
+// returned if the data node is being drained.
+func (s *DataNode) drainAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramTimeout = "timeout"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var timeout time.Duration
+	if value := r.FormValue(paramTimeout); value != "" {
+		seconds, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			err = fmt.Errorf("parse param %v fail: %v", paramTimeout, err)
+			s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if s.beginDrain() {
+		go func() {
+			s.runDrain(timeout)
+			log.LogWarnf("action[drain] drained, shutting down")
+			s.Shutdown()
+		}()
+	}
+	s.buildSuccessResp(w, s.drainStatus())
+}
+
+// drainStatusAPI returns the progress of draining the data node.
+func (s *DataNode) drainStatusAPI(w http.ResponseWriter, r *http.Request) {
+	s.buildSuccessResp(w, s.drainStatus())
+}
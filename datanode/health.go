@@ -0,0 +1,83 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/health"
+)
+
+// at most the partitions are listed in the reason of the failed check
+const healthMaxReportedPartitions = 10
+
+func (s *DataNode) registerHealthChecks() {
+	health.RegisterLiveness("disks", s.checkDisksAvailable)
+	health.RegisterReadiness("disks", s.checkDisksAvailable)
+	health.RegisterReadiness("partitions", s.checkPartitionsReady)
+}
+
+// checkDisksAvailable fails if none of the disks is available, the disks with too many IO errors are
+// marked unavailable.
+func (s *DataNode) checkDisksAvailable() error {
+	disks := s.space.GetDisks()
+	if len(disks) == 0 {
+		return errors.New("no disk")
+	}
+	for _, d := range disks {
+		if d.Status != proto.Unavailable {
+			return nil
+		}
+	}
+	return fmt.Errorf("all %v disks are unavailable", len(disks))
+}
+
+// checkPartitionsReady requires the data partitions on the available disks to have loaded the extent
+// headers, and their raft to be running with a leader and not restoring a snapshot.
+func (s *DataNode) checkPartitionsReady() error {
+	var (
+		total    int
+		notReady []uint64
+	)
+	s.space.RangePartitions(func(dp *DataPartition) bool {
+		if dp.Disk() == nil || dp.Disk().Status == proto.Unavailable {
+			return true
+		}
+		total++
+		if dp.loadExtentHeaderStatus != FinishLoadDataPartitionExtentHeader || !dp.isRaftReady() {
+			notReady = append(notReady, dp.partitionID)
+		}
+		return true
+	})
+	if len(notReady) == 0 {
+		return nil
+	}
+	reported := notReady
+	if len(reported) > healthMaxReportedPartitions {
+		reported = reported[:healthMaxReportedPartitions]
+	}
+	return fmt.Errorf("%v of %v partitions are not ready: %v", len(notReady), total, reported)
+}
+
+func (dp *DataPartition) isRaftReady() bool {
+	raftPartition := dp.raftPartition
+	if raftPartition == nil {
+		return false
+	}
+	status := raftPartition.Status()
+	return status != nil && !status.Stopped && !status.RestoringSnapshot && status.Leader != 0
+}
@@ -3,6 +3,8 @@ package datanode
 import (
 	"context"
 	"fmt"
+
+	"github.com/chubaofs/chubaofs/util"
 	"golang.org/x/time/rate"
 )
 
@@ -11,6 +13,8 @@ var (
 	MaxExtentRepairLimit    = 20000
 	MinExtentRepairLimit    = 5
 	extentRepairLimiteRater = make(chan struct{}, MaxExtentRepairLimit)
+	warmUpLimiter           = rate.NewLimiter(rate.Limit(DefaultWarmUpRate*WarmUpBlockSize), WarmUpBlockSize)
+	scrubLimiter            = rate.NewLimiter(rate.Limit(DefaultScrubRate*util.MB), util.BlockSize)
 )
 
 func requestDoExtentRepair() (err error) {
@@ -0,0 +1,234 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
+	"golang.org/x/time/rate"
+)
+
+// Keys of the runtime tunable parameters of the data node.
+const (
+	NodeConfigMarkDeleteRate        = "markDeleteRate"  // extents marked deleted per second, 0 for unlimited
+	NodeConfigAutoRepairLimit       = "autoRepairLimit" // concurrent extent repairs
+	NodeConfigAutoRepair            = "autoRepair"
+	NodeConfigOrphanExtentGraceHour = ConfigKeyOrphanExtentGraceHours
+	NodeConfigWarmUpRate            = ConfigKeyWarmUpRate // MB per second, 0 for unlimited
+	NodeConfigScrubRate             = ConfigKeyScrubRate  // MB per second, 0 disables the scrub
+	NodeConfigScrubIntervalHours    = ConfigKeyScrubIntervalHours
+	NodeConfigScrubWindow           = ConfigKeyScrubWindow
+	NodeConfigSlowOpThresholdMs     = slowop.ConfigKeyThresholdMs // suffixed with ".OpName" for the threshold of an operation
+)
+
+// NodeConfigValueDefault drops the value set at runtime, so that the parameter follows
+// the cluster-wide setting or the config file again.
+const NodeConfigValueDefault = "default"
+
+var (
+	// values set by the operator at runtime, they are not persisted
+	nodeConfigOverrides = make(map[string]string)
+	nodeConfigLock      sync.RWMutex
+)
+
+func getNodeConfigOverride(key string) (value string, ok bool) {
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	value, ok = nodeConfigOverrides[key]
+	return
+}
+
+func getNodeConfigOverrideUint64(key string) (value uint64, ok bool) {
+	var str string
+	if str, ok = getNodeConfigOverride(key); !ok {
+		return
+	}
+	value, _ = strconv.ParseUint(str, 10, 64)
+	return
+}
+
+func (s *DataNode) getOrphanExtentGracePeriod() time.Duration {
+	if hours, ok := getNodeConfigOverrideUint64(NodeConfigOrphanExtentGraceHour); ok {
+		return time.Duration(hours) * time.Hour
+	}
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	return s.orphanExtentGracePeriod
+}
+
+func (s *DataNode) nodeConfigs() (configs []*proto.NodeConfig) {
+	markDeleteRate := uint64(0)
+	if limit := deleteLimiteRater.Limit(); limit != rate.Inf {
+		markDeleteRate = uint64(limit)
+	}
+	warmUpRate := uint64(0)
+	if limit := warmUpLimiter.Limit(); limit != rate.Inf {
+		warmUpRate = uint64(limit) / util.MB
+	}
+	values := map[string]string{
+		NodeConfigMarkDeleteRate:        strconv.FormatUint(markDeleteRate, 10),
+		NodeConfigAutoRepairLimit:       strconv.Itoa(cap(extentRepairLimiteRater)),
+		NodeConfigAutoRepair:            strconv.FormatBool(AutoRepairStatus),
+		NodeConfigOrphanExtentGraceHour: strconv.FormatInt(int64(s.getOrphanExtentGracePeriod()/time.Hour), 10),
+		NodeConfigWarmUpRate:            strconv.FormatUint(warmUpRate, 10),
+		NodeConfigScrubRate:             strconv.FormatUint(s.getScrubRate(), 10),
+		NodeConfigScrubIntervalHours:    strconv.FormatInt(int64(s.getScrubInterval()/time.Hour), 10),
+		NodeConfigScrubWindow:           s.getScrubWindow(),
+	}
+	for key, value := range slowop.Thresholds() {
+		values[key] = value
+	}
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	configs = make([]*proto.NodeConfig, 0, len(values))
+	for key, value := range values {
+		_, overridden := nodeConfigOverrides[key]
+		configs = append(configs, &proto.NodeConfig{Key: key, Value: value, Overridden: overridden})
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Key < configs[j].Key
+	})
+	return
+}
+
+// setNodeConfig applies the value immediately, the cluster-wide settings are fetched from the master
+// again if the value is reset to the default.
+func (s *DataNode) setNodeConfig(key, value string) (err error) {
+	if value == NodeConfigValueDefault {
+		nodeConfigLock.Lock()
+		delete(nodeConfigOverrides, key)
+		warmUpRate, scrubRate := s.warmUpRate, s.scrubRate
+		nodeConfigLock.Unlock()
+		switch key {
+		case NodeConfigMarkDeleteRate, NodeConfigAutoRepairLimit:
+			s.updateNodeInfo()
+		case NodeConfigAutoRepair:
+			AutoRepairStatus = true
+		case NodeConfigOrphanExtentGraceHour:
+		case NodeConfigWarmUpRate:
+			setLimiter(warmUpLimiter, warmUpRate*util.MB)
+		case NodeConfigScrubRate:
+			setLimiter(scrubLimiter, scrubRate*util.MB)
+		case NodeConfigScrubIntervalHours, NodeConfigScrubWindow:
+		default:
+			if !slowop.IsThresholdKey(key) {
+				return fmt.Errorf("unknown config key: %v", key)
+			}
+			if err = slowop.ResetThreshold(key); err != nil {
+				return
+			}
+		}
+		log.LogInfof("action[setNodeConfig] key(%v) is reset to default", key)
+		return
+	}
+	switch key {
+	case NodeConfigMarkDeleteRate:
+		var rateLimit uint64
+		if rateLimit, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		setLimiter(deleteLimiteRater, rateLimit)
+	case NodeConfigAutoRepairLimit:
+		var limit uint64
+		if limit, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		setDoExtentRepair(int(limit))
+	case NodeConfigAutoRepair:
+		var autoRepair bool
+		if autoRepair, err = strconv.ParseBool(value); err != nil {
+			return
+		}
+		AutoRepairStatus = autoRepair
+	case NodeConfigOrphanExtentGraceHour:
+		var hours uint64
+		if hours, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		if hours == 0 {
+			return fmt.Errorf("%v must be positive", key)
+		}
+	case NodeConfigWarmUpRate:
+		var warmUpRate uint64
+		if warmUpRate, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		setLimiter(warmUpLimiter, warmUpRate*util.MB)
+	case NodeConfigScrubRate:
+		var scrubRate uint64
+		if scrubRate, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		setLimiter(scrubLimiter, scrubRate*util.MB)
+	case NodeConfigScrubIntervalHours:
+		var hours uint64
+		if hours, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		if hours == 0 {
+			return fmt.Errorf("%v must be positive", key)
+		}
+	case NodeConfigScrubWindow:
+		if _, _, err = parseScrubWindow(value); err != nil {
+			return
+		}
+	default:
+		if !slowop.IsThresholdKey(key) {
+			return fmt.Errorf("unknown config key: %v", key)
+		}
+		var threshold uint64
+		if threshold, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		if err = slowop.SetThreshold(key, threshold); err != nil {
+			return
+		}
+	}
+	nodeConfigLock.Lock()
+	nodeConfigOverrides[key] = value
+	nodeConfigLock.Unlock()
+	log.LogInfof("action[setNodeConfig] key(%v) value(%v)", key, value)
+	return
+}
+
+func (s *DataNode) getNodeConfigAPI(w http.ResponseWriter, r *http.Request) {
+	s.buildSuccessResp(w, s.nodeConfigs())
+}
+
+func (s *DataNode) setNodeConfigAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramKey   = "key"
+		paramValue = "value"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.setNodeConfig(r.FormValue(paramKey), r.FormValue(paramValue)); err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.buildSuccessResp(w, s.nodeConfigs())
+}
@@ -41,8 +41,13 @@ func (m *DataNode) updateNodeInfo() {
 		log.LogErrorf("[updateDataNodeInfo] %s", err.Error())
 		return
 	}
-	setLimiter(deleteLimiteRater, clusterInfo.DataNodeDeleteLimitRate)
-	setDoExtentRepair(int(clusterInfo.DataNodeAutoRepairLimitRate))
+	// the values set on the node at runtime take precedence over the cluster-wide settings
+	if _, ok := getNodeConfigOverride(NodeConfigMarkDeleteRate); !ok {
+		setLimiter(deleteLimiteRater, clusterInfo.DataNodeDeleteLimitRate)
+	}
+	if _, ok := getNodeConfigOverride(NodeConfigAutoRepairLimit); !ok {
+		setDoExtentRepair(int(clusterInfo.DataNodeAutoRepairLimitRate))
+	}
 	log.LogInfof("updateNodeInfo from master:"+
 		"deleteLimite(%v),autoRepairLimit(%v)", clusterInfo.DataNodeDeleteLimitRate,
 		clusterInfo.DataNodeAutoRepairLimitRate)
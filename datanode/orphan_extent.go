@@ -0,0 +1,125 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	DefaultOrphanExtentGracePeriod = 24 * time.Hour
+)
+
+// orphanExtents records the extents found not referenced by any inode. An extent is only reclaimed
+// if it stays orphaned for the grace period, so that the extents being written when they are scanned,
+// or referenced by the inodes created during the scan, are never deleted. The records are kept in
+// memory, the grace period restarts after the data node restarts.
+type orphanExtents struct {
+	sync.Mutex
+	firstSeen map[uint64]int64
+}
+
+// scanOrphanExtents compares the extents of the partition with the referenced ones, and deletes
+// the extents which have been orphaned for the grace period if reclaim is set.
+func (dp *DataPartition) scanOrphanExtents(referenced []uint64, grace time.Duration, reclaim bool) (resp *proto.OrphanExtentScanResponse, err error) {
+	var extents []*storage.ExtentInfo
+	if extents, _, err = dp.extentStore.GetAllWatermarks(storage.NormalExtentFilter()); err != nil {
+		return
+	}
+	refs := make(map[uint64]bool, len(referenced))
+	for _, id := range referenced {
+		refs[id] = true
+	}
+	resp = &proto.OrphanExtentScanResponse{
+		PartitionID: dp.partitionID,
+		GracePeriod: int64(grace / time.Second),
+	}
+	now := time.Now().Unix()
+	deadline := now - int64(grace/time.Second)
+
+	dp.orphans.Lock()
+	defer dp.orphans.Unlock()
+	if dp.orphans.firstSeen == nil {
+		dp.orphans.firstSeen = make(map[uint64]int64)
+	}
+	current := make(map[uint64]int64)
+	for _, ei := range extents {
+		if ei.IsDeleted || refs[ei.FileID] || ei.ModifyTime > deadline {
+			continue
+		}
+		firstSeen, ok := dp.orphans.firstSeen[ei.FileID]
+		if !ok {
+			firstSeen = now
+		}
+		orphan := &proto.OrphanExtent{ExtentID: ei.FileID, Size: ei.Size, FirstSeen: firstSeen}
+		if !reclaim || firstSeen > deadline {
+			current[ei.FileID] = firstSeen
+			resp.Candidates = append(resp.Candidates, orphan)
+			continue
+		}
+		if err = dp.extentStore.MarkDelete(ei.FileID, 0, 0); err != nil {
+			log.LogErrorf("action[scanOrphanExtents] partition(%v) delete extent(%v) err(%v)", dp.partitionID, ei.FileID, err)
+			current[ei.FileID] = firstSeen
+			resp.Candidates = append(resp.Candidates, orphan)
+			continue
+		}
+		log.LogWarnf("action[scanOrphanExtents] partition(%v) reclaimed orphaned extent(%v) size(%v) first seen at %v",
+			dp.partitionID, ei.FileID, ei.Size, time.Unix(firstSeen, 0).Format(proto.TimeFormat))
+		resp.Reclaimed = append(resp.Reclaimed, orphan)
+	}
+	// the extents referenced again are forgotten
+	dp.orphans.firstSeen = current
+	return resp, nil
+}
+
+func (s *DataNode) scanOrphanExtents(w http.ResponseWriter, r *http.Request) {
+	var (
+		body []byte
+		req  = &proto.OrphanExtentScanRequest{}
+		resp *proto.OrphanExtentScanResponse
+		err  error
+	)
+	if r.Method != http.MethodPost {
+		s.buildFailureResp(w, http.StatusMethodNotAllowed, "only POST is allowed")
+		return
+	}
+	if body, err = ioutil.ReadAll(r.Body); err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = json.Unmarshal(body, req); err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("unmarshal request fail: %v", err))
+		return
+	}
+	partition := s.space.Partition(req.PartitionID)
+	if partition == nil {
+		s.buildFailureResp(w, http.StatusNotFound, "partition not exist")
+		return
+	}
+	if resp, err = partition.scanOrphanExtents(req.Referenced, s.getOrphanExtentGracePeriod(), req.Reclaim); err != nil {
+		s.buildFailureResp(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.buildSuccessResp(w, resp)
+}
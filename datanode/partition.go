@@ -60,6 +60,7 @@ type DataPartitionMetadata struct {
 	Hosts                   []string
 	DataPartitionCreateType int
 	LastTruncateID          uint64
+	Worm                    bool
 }
 
 type sortedPeers []proto.Peer
@@ -118,6 +119,14 @@ type DataPartition struct {
 	loadExtentHeaderStatus        int
 	DataPartitionCreateType       int
 	isLoadingDataPartition        bool
+	orphans                       orphanExtents
+	heat                          extentHeat
+	warmUp                        partitionWarmUp
+	scrub                         partitionScrub
+	resyncing                     int32 // whether a replica is being re-synced from this leader
+	sealed                        sealedExtents
+	worm                          int32 // 1 if the volume has a retention policy, persisted with the metadata
+	metadataLock                  sync.Mutex
 }
 
 func CreateDataPartition(dpCfg *dataPartitionCfg, disk *Disk, request *proto.CreateDataPartitionRequest) (dp *DataPartition, err error) {
@@ -210,9 +219,16 @@ func LoadDataPartition(partitionDir string, disk *Disk) (dp *DataPartition, err
 	if err = dp.LoadAppliedID(); err != nil {
 		log.LogErrorf("action[loadApplyIndex] %v", err)
 	}
+	if err = dp.loadExtentHeat(); err != nil {
+		log.LogErrorf("action[loadExtentHeat] partition(%v) err(%v)", dp.partitionID, err)
+		err = nil
+	}
 	log.LogInfof("Action(LoadDataPartition) PartitionID(%v) meta(%v)", dp.partitionID, meta)
 	dp.DataPartitionCreateType = meta.DataPartitionCreateType
 	dp.lastTruncateID = meta.LastTruncateID
+	if meta.Worm {
+		dp.worm = 1
+	}
 	if meta.DataPartitionCreateType == proto.NormalCreateDataPartition {
 		err = dp.StartRaft()
 	} else {
@@ -252,6 +268,9 @@ func newDataPartition(dpCfg *dataPartitionCfg, disk *Disk) (dp *DataPartition, e
 	if err != nil {
 		return
 	}
+	if err = partition.loadSealedExtents(); err != nil {
+		return
+	}
 
 	disk.AttachDataPartition(partition)
 	dp = partition
@@ -361,6 +380,9 @@ func (dp *DataPartition) Stop() {
 		if dp.stopC != nil {
 			close(dp.stopC)
 		}
+		if err := dp.persistExtentHeat(); err != nil {
+			log.LogErrorf("action[persistExtentHeat] partition(%v) err(%v)", dp.partitionID, err)
+		}
 		// Close the store and raftstore.
 		dp.extentStore.Close()
 		dp.stopRaft()
@@ -408,6 +430,8 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 		metadataFile *os.File
 		metaData     []byte
 	)
+	dp.metadataLock.Lock()
+	defer dp.metadataLock.Unlock()
 	fileName := path.Join(dp.Path(), TempMetadataFileName)
 	if metadataFile, err = os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0666); err != nil {
 		return
@@ -430,6 +454,7 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 		DataPartitionCreateType: dp.DataPartitionCreateType,
 		CreateTime:              time.Now().Format(TimeLayout),
 		LastTruncateID:          dp.lastTruncateID,
+		Worm:                    dp.isWorm(),
 	}
 	if metaData, err = json.Marshal(md); err != nil {
 		return
@@ -460,6 +485,9 @@ func (dp *DataPartition) statusUpdateScheduler() {
 			}
 		case <-snapshotTicker.C:
 			dp.ReloadSnapshot()
+			if err := dp.persistExtentHeat(); err != nil {
+				log.LogErrorf("action[persistExtentHeat] partition(%v) err(%v)", dp.partitionID, err)
+			}
 		case <-dp.stopC:
 			ticker.Stop()
 			snapshotTicker.Stop()
@@ -541,9 +569,8 @@ func (dp *DataPartition) checkIsDiskError(err error) (diskError bool) {
 		dp.stopRaft()
 		dp.disk.incReadErrCnt()
 		dp.disk.incWriteErrCnt()
-		dp.disk.Status = proto.Unavailable
+		dp.disk.markOffline(err.Error(), false)
 		dp.statusUpdate()
-		dp.disk.ForceExitRaftStore()
 		diskError = true
 	}
 	return
@@ -656,6 +683,20 @@ func (dp *DataPartition) Load() (response *proto.LoadDataPartitionResponse) {
 // 2. if the extent does not even exist, create the extent first, and then repair.
 func (dp *DataPartition) DoExtentStoreRepair(repairTask *DataPartitionRepairTask) {
 	store := dp.extentStore
+	for _, extentInfo := range repairTask.ExtentsToBeReset {
+		if storage.IsTinyExtent(extentInfo.FileID) || !store.HasExtent(extentInfo.FileID) ||
+			store.IsDeletedNormalExtent(extentInfo.FileID) {
+			continue
+		}
+		if !AutoRepairStatus {
+			log.LogWarnf("AutoRepairStatus is False,so cannot Reset extent(%v)", extentInfo.String())
+			continue
+		}
+		if err := store.Reset(extentInfo.FileID); err != nil {
+			log.LogWarnf("action[DoExtentStoreRepair] partition(%v) reset extent(%v) err(%v)",
+				dp.partitionID, extentInfo.FileID, err)
+		}
+	}
 	for _, extentInfo := range repairTask.ExtentsToBeCreated {
 		if storage.IsTinyExtent(extentInfo.FileID) {
 			continue
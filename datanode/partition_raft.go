@@ -32,6 +32,7 @@ import (
 	"github.com/chubaofs/chubaofs/repl"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
 	raftproto "github.com/tiglabs/raft/proto"
 )
@@ -372,6 +373,51 @@ func (dp *DataPartition) removeRaftNode(req *proto.RemoveDataPartitionRaftMember
 	return
 }
 
+// resetRaftMembers restarts the raft instance of the partition with the given peers only. It is used to rebuild the
+// raft group from a surviving replica once the quorum is lost permanently, so it is refused while the group still
+// has a leader. The raft log of the replica is kept, the uncommitted entries of which are committed by the new group.
+func (dp *DataPartition) resetRaftMembers(newPeers []proto.Peer) (err error) {
+	if len(newPeers) == 0 {
+		return fmt.Errorf("partition(%v) no peers to reset the raft group to", dp.partitionID)
+	}
+	found := false
+	for _, peer := range newPeers {
+		if peer.ID == dp.config.NodeID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("partition(%v) local node(%v) is not in the new peers(%v)", dp.partitionID, dp.config.NodeID, newPeers)
+	}
+	if dp.raftPartition != nil {
+		if leaderID, _ := dp.raftPartition.LeaderTerm(); leaderID != 0 {
+			return fmt.Errorf("partition(%v) raft group has leader(%v), no need to reset", dp.partitionID, leaderID)
+		}
+	}
+	oldPeers := dp.config.Peers
+	newHosts := make([]string, 0, len(newPeers))
+	for _, peer := range newPeers {
+		newHosts = append(newHosts, peer.Addr)
+	}
+	dp.stopRaft()
+	dp.config.Peers = newPeers
+	dp.config.Hosts = newHosts
+	dp.replicasInit()
+	if err = dp.PersistMetadata(); err != nil {
+		log.LogErrorf("action[resetRaftMembers] partition(%v) persist metadata err(%v)", dp.partitionID, err)
+		return
+	}
+	if err = dp.StartRaft(); err != nil {
+		log.LogErrorf("action[resetRaftMembers] partition(%v) start raft err(%v)", dp.partitionID, err)
+		return
+	}
+	mesg := fmt.Sprintf("partition(%v) raft members on %v are reset from %v to %v", dp.partitionID, LocalIP, oldPeers, newPeers)
+	exporter.Warning(mesg)
+	log.LogWarnf("%v", mesg)
+	return
+}
+
 func (dp *DataPartition) storeAppliedID(applyIndex uint64) (err error) {
 	filename := path.Join(dp.Path(), TempApplyIndexFile)
 	fp, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_TRUNC|os.O_CREATE, 0755)
@@ -0,0 +1,143 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// resyncReplica re-syncs all the normal extents of a replica from the leader in background. The extents of which
+// the crc is computed on the leader are truncated on the replica and copied from the beginning, the extents missing
+// on the replica are created, and the other extents are repaired to the size on the leader.
+func (dp *DataPartition) resyncReplica(localAddr, replica string) (resync *proto.DataPartitionResync, err error) {
+	leaderAddr, isLeader := dp.IsRaftLeader()
+	if !isLeader {
+		err = fmt.Errorf("partition(%v) is not led by %v, leader(%v)", dp.partitionID, localAddr, leaderAddr)
+		return
+	}
+	if replica == localAddr {
+		err = fmt.Errorf("the leader %v of partition(%v) can not be re-synced from itself, transfer the leadership first",
+			localAddr, dp.partitionID)
+		return
+	}
+	isReplica := false
+	for _, host := range dp.Replicas() {
+		isReplica = isReplica || host == replica
+	}
+	if !isReplica {
+		err = fmt.Errorf("%v is not a replica of partition(%v)", replica, dp.partitionID)
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&dp.resyncing, 0, 1) {
+		err = fmt.Errorf("a replica of partition(%v) is being re-synced", dp.partitionID)
+		return
+	}
+	defer func() {
+		if err != nil {
+			atomic.StoreInt32(&dp.resyncing, 0)
+		}
+	}()
+
+	localExtents, tinyDeleteRecordFileSize, err := dp.getLocalExtentInfo(proto.NormalExtentType, nil)
+	if err != nil {
+		return
+	}
+	remoteExtents, err := dp.getRemoteExtentInfo(proto.NormalExtentType, nil, replica)
+	if err != nil {
+		return
+	}
+	task := NewDataPartitionRepairTask(remoteExtents, tinyDeleteRecordFileSize, localAddr, localAddr)
+	task.addr = replica
+	resync = &proto.DataPartitionResync{
+		PartitionID: dp.partitionID,
+		Leader:      localAddr,
+		Replica:     replica,
+		StartTime:   time.Now().Unix(),
+	}
+	for _, ei := range localExtents {
+		if ei.IsDeleted || dp.ExtentStore().IsDeletedNormalExtent(ei.FileID) {
+			continue
+		}
+		info := &storage.ExtentInfo{Source: localAddr, FileID: ei.FileID, Size: ei.Size}
+		if _, ok := task.extents[ei.FileID]; !ok {
+			task.ExtentsToBeCreated = append(task.ExtentsToBeCreated, info)
+			resync.CreatedExtents++
+			continue
+		}
+		// the extents modified recently are still being written, which are only repaired to the size on the leader
+		if ei.Crc != 0 {
+			task.ExtentsToBeReset = append(task.ExtentsToBeReset, info)
+			resync.ResetExtents++
+		}
+		task.ExtentsToBeRepaired = append(task.ExtentsToBeRepaired, info)
+		resync.RepairedExtents++
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&dp.resyncing, 0)
+		log.LogInfof("action[resyncReplica] partition(%v) replica(%v) start: created(%v) reset(%v) repaired(%v)",
+			dp.partitionID, replica, resync.CreatedExtents, resync.ResetExtents, resync.RepairedExtents)
+		if e := dp.sendRepairTask(replica, task); e != nil {
+			mesg := fmt.Sprintf("re-sync replica %v of partition %v failed: %v", replica, dp.partitionID, e)
+			exporter.Warning(mesg)
+			log.LogErrorf("%v", mesg)
+			return
+		}
+		log.LogInfof("action[resyncReplica] partition(%v) replica(%v) finished, cost(%v)",
+			dp.partitionID, replica, time.Since(time.Unix(resync.StartTime, 0)))
+	}()
+	return
+}
+
+func (s *DataNode) resyncReplicaAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramID      = "id"
+		paramReplica = "replica"
+	)
+	if err := r.ParseForm(); err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partitionID, err := strconv.ParseUint(r.FormValue(paramID), 10, 64)
+	if err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("parse param %v fail: %v", paramID, err))
+		return
+	}
+	replica := r.FormValue(paramReplica)
+	if replica == "" {
+		s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("param %v is required", paramReplica))
+		return
+	}
+	partition := s.space.Partition(partitionID)
+	if partition == nil {
+		s.buildFailureResp(w, http.StatusNotFound, "partition not exist")
+		return
+	}
+	resync, err := partition.resyncReplica(s.localServerAddr, replica)
+	if err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.buildSuccessResp(w, resync)
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ExtentMismatchMissing = "missing"
+	ExtentMismatchSize    = "size"
+	ExtentMismatchCrc     = "crc"
+)
+
+// verifyReplicas compares the size and the crc of the normal extents on all the replicas of the partition. The crc
+// of an extent is computed from the crc of its blocks once it is not modified for a while, so the extents modified
+// recently are not verified.
+func (dp *DataPartition) verifyReplicas(localAddr string) (report *proto.DataPartitionVerifyReport, err error) {
+	leaderAddr, isLeader := dp.IsRaftLeader()
+	if !isLeader {
+		err = fmt.Errorf("partition(%v) is not led by %v, leader(%v)", dp.partitionID, localAddr, leaderAddr)
+		return
+	}
+	hosts := dp.Replicas()
+	report = &proto.DataPartitionVerifyReport{
+		PartitionID: dp.partitionID,
+		Leader:      localAddr,
+		Hosts:       hosts,
+		HostErrors:  make(map[string]string),
+		Mismatches:  make([]*proto.ExtentMismatch, 0),
+		VerifyTime:  time.Now().Unix(),
+	}
+
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		hostExtents = make(map[string]map[uint64]*storage.ExtentInfo)
+	)
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			var (
+				extents []*storage.ExtentInfo
+				e       error
+			)
+			if host == localAddr {
+				extents, _, e = dp.getLocalExtentInfo(proto.NormalExtentType, nil)
+			} else {
+				extents, e = dp.getRemoteExtentInfo(proto.NormalExtentType, nil, host)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if e != nil {
+				report.HostErrors[host] = e.Error()
+				return
+			}
+			extentMap := make(map[uint64]*storage.ExtentInfo, len(extents))
+			for _, ei := range extents {
+				extentMap[ei.FileID] = ei
+			}
+			hostExtents[host] = extentMap
+		}(host)
+	}
+	wg.Wait()
+
+	extentIDs := make([]uint64, 0)
+	for _, extentMap := range hostExtents {
+		for extentID := range extentMap {
+			extentIDs = append(extentIDs, extentID)
+		}
+	}
+	sort.Slice(extentIDs, func(i, j int) bool { return extentIDs[i] < extentIDs[j] })
+
+	for i, extentID := range extentIDs {
+		if i > 0 && extentID == extentIDs[i-1] {
+			continue
+		}
+		report.Extents++
+		mismatch := compareExtentReplicas(extentID, hosts, hostExtents)
+		if mismatch == nil {
+			report.Verified++
+			continue
+		}
+		if mismatch.Reason == "" {
+			report.Unverified++
+			continue
+		}
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+	log.LogInfof("action[verifyReplicas] partition(%v) extents(%v) verified(%v) unverified(%v) mismatches(%v) hostErrors(%v)",
+		dp.partitionID, report.Extents, report.Verified, report.Unverified, len(report.Mismatches), report.HostErrors)
+	return
+}
+
+// compareExtentReplicas returns nil if the extent is consistent on the replicas reporting their extents, or the
+// mismatch without a reason if it is not verified yet.
+func compareExtentReplicas(extentID uint64, hosts []string, hostExtents map[string]map[uint64]*storage.ExtentInfo) (mismatch *proto.ExtentMismatch) {
+	var (
+		missing, sizeDiffers, crcDiffers bool
+		first                            *storage.ExtentInfo
+	)
+	replicas := make([]*proto.ExtentReplica, 0, len(hosts))
+	for _, host := range hosts {
+		extentMap, ok := hostExtents[host]
+		if !ok {
+			continue
+		}
+		ei := extentMap[extentID]
+		if ei == nil {
+			missing = true
+			replicas = append(replicas, &proto.ExtentReplica{Addr: host, Missing: true})
+			continue
+		}
+		replicas = append(replicas, &proto.ExtentReplica{Addr: host, Size: ei.Size, Crc: ei.Crc, ModifyTime: ei.ModifyTime})
+		if ei.Crc == 0 {
+			return &proto.ExtentMismatch{ExtentID: extentID, Replicas: replicas}
+		}
+		if first == nil {
+			first = ei
+			continue
+		}
+		sizeDiffers = sizeDiffers || ei.Size != first.Size
+		crcDiffers = crcDiffers || ei.Crc != first.Crc
+	}
+	mismatch = &proto.ExtentMismatch{ExtentID: extentID, Replicas: replicas}
+	switch {
+	case missing:
+		mismatch.Reason = ExtentMismatchMissing
+	case sizeDiffers:
+		mismatch.Reason = ExtentMismatchSize
+	case crcDiffers:
+		mismatch.Reason = ExtentMismatchCrc
+	default:
+		mismatch = nil
+	}
+	return
+}
+
+func (s *DataNode) verifyPartitionAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramID = "id"
+	)
+	if err := r.ParseForm(); err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partitionID, err := strconv.ParseUint(r.FormValue(paramID), 10, 64)
+	if err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("parse param %v fail: %v", paramID, err))
+		return
+	}
+	partition := s.space.Partition(partitionID)
+	if partition == nil {
+		s.buildFailureResp(w, http.StatusNotFound, "partition not exist")
+		return
+	}
+	report, err := partition.verifyReplicas(s.localServerAddr)
+	if err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.buildSuccessResp(w, report)
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/qos"
+)
+
+// maxQosWait bounds how long a request waits for the tokens, since the request holds the goroutine of its
+// connection meanwhile. The request which would wait longer is refused with TryAgainError, then the client
+// retries it later.
+const maxQosWait = time.Second
+
+var (
+	volQosLimiters = make(map[string]*qos.Limiter)
+	volQosLock     sync.RWMutex
+
+	// throttles of the client IPs, which are persisted by the master and sent with the heartbeat
+	clientThrottles    = make(map[string]*qos.Limiter)
+	clientThrottleLock sync.RWMutex
+)
+
+// updateVolQos applies the budgets sent by the master with the heartbeat.
+// The limiters of the volumes that are no longer limited are removed.
+func updateVolQos(budgets map[string]*proto.VolQosBudget) {
+	volQosLock.Lock()
+	defer volQosLock.Unlock()
+	for volName, budget := range budgets {
+		if limiter, ok := volQosLimiters[volName]; ok {
+			limiter.Update(budget.MaxIOPS, budget.MaxBandwidth)
+			continue
+		}
+		volQosLimiters[volName] = qos.NewLimiter(budget.MaxIOPS, budget.MaxBandwidth)
+		log.LogInfof("action[updateVolQos] vol(%v) maxIOPS(%v) maxBandwidth(%v)",
+			volName, budget.MaxIOPS, budget.MaxBandwidth)
+	}
+	for volName := range volQosLimiters {
+		if _, ok := budgets[volName]; !ok {
+			delete(volQosLimiters, volName)
+			log.LogInfof("action[updateVolQos] vol(%v) is no longer limited", volName)
+		}
+	}
+}
+
+// setClientThrottle sets the limits of the client, the throttle is removed if both limits are zero.
+func setClientThrottle(clientIP string, maxIOPS, maxBandwidth uint64) (err error) {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return fmt.Errorf("invalid client IP(%v)", clientIP)
+	}
+	// the same address may be written in different forms, e.g. IPv6 in upper case
+	clientIP = ip.String()
+	clientThrottleLock.Lock()
+	defer clientThrottleLock.Unlock()
+	if maxIOPS == 0 && maxBandwidth == 0 {
+		delete(clientThrottles, clientIP)
+		log.LogInfof("action[setClientThrottle] client(%v) is no longer throttled", clientIP)
+		return
+	}
+	if limiter, ok := clientThrottles[clientIP]; ok {
+		limiter.Update(maxIOPS, maxBandwidth)
+	} else {
+		clientThrottles[clientIP] = qos.NewLimiter(maxIOPS, maxBandwidth)
+	}
+	log.LogInfof("action[setClientThrottle] client(%v) maxIOPS(%v) maxBandwidth(%v)", clientIP, maxIOPS, maxBandwidth)
+	return
+}
+
+// updateClientThrottles applies the throttles sent by the master with the heartbeat.
+// The clients that are not in the throttles are no longer throttled.
+func updateClientThrottles(throttles []*proto.ClientThrottle) {
+	clientThrottleLock.Lock()
+	defer clientThrottleLock.Unlock()
+	throttled := make(map[string]bool, len(throttles))
+	for _, throttle := range throttles {
+		ip := net.ParseIP(throttle.ClientIP)
+		if ip == nil {
+			log.LogWarnf("action[updateClientThrottles] invalid client IP(%v)", throttle.ClientIP)
+			continue
+		}
+		clientIP := ip.String()
+		throttled[clientIP] = true
+		if limiter, ok := clientThrottles[clientIP]; ok {
+			limiter.Update(throttle.MaxIOPS, throttle.MaxBandwidth)
+			continue
+		}
+		clientThrottles[clientIP] = qos.NewLimiter(throttle.MaxIOPS, throttle.MaxBandwidth)
+		log.LogInfof("action[updateClientThrottles] client(%v) maxIOPS(%v) maxBandwidth(%v)",
+			clientIP, throttle.MaxIOPS, throttle.MaxBandwidth)
+	}
+	for clientIP := range clientThrottles {
+		if !throttled[clientIP] {
+			delete(clientThrottles, clientIP)
+			log.LogInfof("action[updateClientThrottles] client(%v) is no longer throttled", clientIP)
+		}
+	}
+}
+
+func listClientThrottles() (throttles []*proto.ClientThrottle) {
+	clientThrottleLock.RLock()
+	defer clientThrottleLock.RUnlock()
+	throttles = make([]*proto.ClientThrottle, 0, len(clientThrottles))
+	for clientIP, limiter := range clientThrottles {
+		maxIOPS, maxBandwidth := limiter.Limits()
+		throttles = append(throttles, &proto.ClientThrottle{
+			ClientIP:     clientIP,
+			MaxIOPS:      maxIOPS,
+			MaxBandwidth: maxBandwidth,
+		})
+	}
+	sort.Slice(throttles, func(i, j int) bool { return throttles[i].ClientIP < throttles[j].ClientIP })
+	return
+}
+
+// waitQos blocks the IO requested by the clients until both the client and the volume have enough tokens,
+// at most for maxQosWait. Replication and repair traffic between the data nodes is never throttled.
+func waitQos(p *repl.Packet, c net.Conn) (err error) {
+	if !isClientIO(p) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), maxQosWait)
+	defer cancel()
+	if host, _, e := net.SplitHostPort(c.RemoteAddr().String()); e == nil {
+		clientThrottleLock.RLock()
+		limiter, ok := clientThrottles[host]
+		clientThrottleLock.RUnlock()
+		if ok {
+			if e = limiter.Wait(ctx, int(p.Size)); e != nil {
+				return fmt.Errorf("%v: client(%v) is throttled: %v", storage.TryAgainError, host, e)
+			}
+		}
+	}
+	partition, ok := p.Object.(*DataPartition)
+	if !ok {
+		return
+	}
+	volQosLock.RLock()
+	limiter, ok := volQosLimiters[partition.volumeID]
+	volQosLock.RUnlock()
+	if !ok {
+		return
+	}
+	if e := limiter.Wait(ctx, int(p.Size)); e != nil {
+		return fmt.Errorf("%v: vol(%v) is throttled: %v", storage.TryAgainError, partition.volumeID, e)
+	}
+	return
+}
+
+func isClientIO(p *repl.Packet) bool {
+	switch p.Opcode {
+	case proto.OpWrite, proto.OpSyncWrite:
+		return p.IsLeaderPacket()
+	case proto.OpRandomWrite, proto.OpSyncRandomWrite, proto.OpStreamRead, proto.OpStreamFollowerRead:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// reloadableConfig holds the values of the config keys which are applied again when the config is reloaded.
+type reloadableConfig struct {
+	orphanExtentGracePeriod time.Duration
+	warmUpRate              uint64
+	scrubRate               uint64
+	scrubInterval           time.Duration
+	scrubWindow             string
+	reservedSpaces          map[string]uint64 // disk path to the reserved space
+}
+
+func parseReloadableConfig(cfg *config.Config) (rc *reloadableConfig, err error) {
+	rc = &reloadableConfig{reservedSpaces: make(map[string]uint64)}
+	rc.orphanExtentGracePeriod = DefaultOrphanExtentGracePeriod
+	if hours := cfg.GetInt64(ConfigKeyOrphanExtentGraceHours); hours > 0 {
+		rc.orphanExtentGracePeriod = time.Duration(hours) * time.Hour
+	}
+	rc.warmUpRate = DefaultWarmUpRate
+	if warmUpRate := cfg.GetInt64(ConfigKeyWarmUpRate); warmUpRate > 0 {
+		rc.warmUpRate = uint64(warmUpRate)
+	}
+	rc.scrubRate = DefaultScrubRate
+	if scrubRate := cfg.GetInt64(ConfigKeyScrubRate); scrubRate > 0 {
+		rc.scrubRate = uint64(scrubRate)
+	} else if scrubRate < 0 {
+		rc.scrubRate = 0
+	}
+	rc.scrubInterval = DefaultScrubIntervalHours * time.Hour
+	if hours := cfg.GetInt64(ConfigKeyScrubIntervalHours); hours > 0 {
+		rc.scrubInterval = time.Duration(hours) * time.Hour
+	}
+	rc.scrubWindow = cfg.GetString(ConfigKeyScrubWindow)
+	if _, _, err = parseScrubWindow(rc.scrubWindow); err != nil {
+		return
+	}
+	for _, d := range cfg.GetSlice(ConfigKeyDisks) {
+		var (
+			path          string
+			reservedSpace uint64
+		)
+		if path, reservedSpace, err = parseDiskConfig(d); err != nil {
+			return
+		}
+		rc.reservedSpaces[path] = reservedSpace
+	}
+	return
+}
+
+// parseDiskConfig parses a disk of the config in the format of "PATH:RESERVED_SPACE".
+func parseDiskConfig(d interface{}) (path string, reservedSpace uint64, err error) {
+	str, ok := d.(string)
+	if !ok {
+		return "", 0, fmt.Errorf("Invalid disk configuration %v. Example: PATH:RESERVE_SIZE", d)
+	}
+	arr := strings.Split(str, ":")
+	if len(arr) != 2 {
+		return "", 0, fmt.Errorf("Invalid disk configuration %v. Example: PATH:RESERVE_SIZE", str)
+	}
+	path = arr[0]
+	if reservedSpace, err = strconv.ParseUint(arr[1], 10, 64); err != nil {
+		return "", 0, fmt.Errorf("Invalid disk reserved space. Error: %s", err.Error())
+	}
+	if reservedSpace < DefaultDiskRetainMin {
+		reservedSpace = DefaultDiskRetainMin
+	}
+	return
+}
+
+// applyReloadableConfig applies the config, the rates set at runtime by the node config API are kept.
+func (s *DataNode) applyReloadableConfig(rc *reloadableConfig) {
+	nodeConfigLock.Lock()
+	s.orphanExtentGracePeriod = rc.orphanExtentGracePeriod
+	s.warmUpRate = rc.warmUpRate
+	s.scrubRate = rc.scrubRate
+	s.scrubInterval = rc.scrubInterval
+	s.scrubWindow = rc.scrubWindow
+	_, warmUpRateOverridden := nodeConfigOverrides[NodeConfigWarmUpRate]
+	_, scrubRateOverridden := nodeConfigOverrides[NodeConfigScrubRate]
+	nodeConfigLock.Unlock()
+	if !warmUpRateOverridden {
+		setLimiter(warmUpLimiter, rc.warmUpRate*util.MB)
+	}
+	if !scrubRateOverridden {
+		setLimiter(scrubLimiter, rc.scrubRate*util.MB)
+	}
+	if s.space == nil {
+		return
+	}
+	for _, disk := range s.space.GetDisks() {
+		if reservedSpace, ok := rc.reservedSpaces[disk.Path]; ok {
+			disk.setReservedSpace(reservedSpace)
+		}
+	}
+}
+
+// ReloadableKeys returns the config keys applied by Reload. The disks added to or removed from the
+// config take effect after the data node restarts, only the reserved space of the loaded disks is applied.
+func (s *DataNode) ReloadableKeys() []string {
+	return []string{ConfigKeyOrphanExtentGraceHours, ConfigKeyWarmUpRate, ConfigKeyScrubRate,
+		ConfigKeyScrubIntervalHours, ConfigKeyScrubWindow, ConfigKeyDisks}
+}
+
+// Reload applies the reloadable keys of the config.
+func (s *DataNode) Reload(cfg *config.Config) (err error) {
+	var rc *reloadableConfig
+	if rc, err = parseReloadableConfig(cfg); err != nil {
+		return
+	}
+	s.applyReloadableConfig(rc)
+	log.LogInfof("action[Reload] orphanExtentGracePeriod(%v) warmUpRate(%v) scrubRate(%v) scrubInterval(%v) scrubWindow(%v) reservedSpaces(%v)",
+		rc.orphanExtentGracePeriod, rc.warmUpRate, rc.scrubRate, rc.scrubInterval, rc.scrubWindow, rc.reservedSpaces)
+	return
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/qos"
+)
+
+var (
+	// limiters of the repair traffic from the source zones, the budgets are sent by the master with the heartbeat
+	repairLimiters    = make(map[string]*qos.Limiter)
+	repairLimiterLock sync.RWMutex
+	// limiter of all the repair traffic of the data node, the bandwidth is sent by the master with the heartbeat
+	rebuildLimiter = qos.NewLimiter(0, 0)
+
+	// zones of the peers, a data node never changes its zone once registered
+	peerZones sync.Map
+)
+
+// updateRepairBandwidth applies the repair budgets of the source zones sent by the master with the heartbeat.
+func updateRepairBandwidth(budgets map[string]uint64) {
+	repairLimiterLock.Lock()
+	defer repairLimiterLock.Unlock()
+	for zone, bandwidth := range budgets {
+		if limiter, ok := repairLimiters[zone]; ok {
+			limiter.Update(0, bandwidth)
+			continue
+		}
+		repairLimiters[zone] = qos.NewLimiter(0, bandwidth)
+		log.LogInfof("action[updateRepairBandwidth] srcZone(%v) maxBandwidth(%v)", zone, bandwidth)
+	}
+	for zone := range repairLimiters {
+		if _, ok := budgets[zone]; !ok {
+			delete(repairLimiters, zone)
+			log.LogInfof("action[updateRepairBandwidth] srcZone(%v) is no longer limited", zone)
+		}
+	}
+}
+
+// updateRebuildBandwidth applies the bandwidth of all the repair traffic sent by the master with the heartbeat,
+// zero means unlimited.
+func updateRebuildBandwidth(bandwidth uint64) {
+	if _, old := rebuildLimiter.Limits(); old != bandwidth {
+		rebuildLimiter.Update(0, bandwidth)
+		log.LogInfof("action[updateRebuildBandwidth] maxBandwidth(%v)", bandwidth)
+	}
+}
+
+// waitRepairBandwidth blocks until the repair data of the given size from the zone is allowed.
+func waitRepairBandwidth(zone string, size int) {
+	rebuildLimiter.Wait(context.Background(), size)
+	repairLimiterLock.RLock()
+	limiter, ok := repairLimiters[zone]
+	repairLimiterLock.RUnlock()
+	if !ok {
+		return
+	}
+	limiter.Wait(context.Background(), size)
+}
+
+// getPeerZone returns the zone of the data node, or an empty string if the master cannot be reached.
+func getPeerZone(addr string) string {
+	if zone, ok := peerZones.Load(addr); ok {
+		return zone.(string)
+	}
+	var (
+		node *proto.DataNodeInfo
+		err  error
+	)
+	if node, err = MasterClient.NodeAPI().GetDataNode(addr); err != nil {
+		log.LogWarnf("action[getPeerZone] get data node(%v) err(%v)", addr, err)
+		return ""
+	}
+	peerZones.Store(addr, node.ZoneName)
+	return node.ZoneName
+}
+
+// selectRepairSource chooses the replica from which the target replica repairs the extent.
+// Any replica that holds the whole extent can be the source, and the ones in the same zone as the target
+// are preferred so that the links between the zones are not saturated by the repair.
+func selectRepairSource(repairTasks []*DataPartitionRepairTask, target int, maxExtentInfo *storage.ExtentInfo) string {
+	targetZone := repairTasks[target].zone
+	if targetZone == "" {
+		return maxExtentInfo.Source
+	}
+	var sameZoneSource string
+	for index, task := range repairTasks {
+		if task == nil || index == target || task.zone != targetZone {
+			continue
+		}
+		extentInfo, ok := task.extents[maxExtentInfo.FileID]
+		if !ok || extentInfo.IsDeleted || extentInfo.Size < maxExtentInfo.Size {
+			continue
+		}
+		if task.addr == maxExtentInfo.Source {
+			return task.addr
+		}
+		if sameZoneSource == "" {
+			sameZoneSource = task.addr
+		}
+	}
+	if sameZoneSource != "" {
+		return sameZoneSource
+	}
+	return maxExtentInfo.Source
+}
@@ -0,0 +1,451 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ScrubStatusFileName     = "SCRUB"
+	TempScrubStatusFileName = ".scrub"
+
+	DefaultScrubRate          = 10 // MB per second
+	DefaultScrubIntervalHours = 7 * 24
+	scrubCheckInterval        = time.Minute
+
+	// MetricScrubCorruptBlocks counts the blocks found mismatching their crc by the scrub.
+	MetricScrubCorruptBlocks = "scrubCorruptBlocks"
+	// MetricScrubRepairedBlocks counts the corrupt blocks repaired from the other replicas.
+	MetricScrubRepairedBlocks = "scrubRepairedBlocks"
+)
+
+var errScrubPaused = fmt.Errorf("scrub paused")
+
+// partitionScrub records the progress of scrubbing the partition. A round stopped by the schedule is resumed
+// from the next extent.
+type partitionScrub struct {
+	sync.Mutex
+	status     *proto.DataPartitionScrubStatus
+	nextExtent uint64 // the extent to resume the round from, 0 if no round is in progress
+}
+
+func (s *DataNode) getScrubRate() uint64 {
+	if rate, ok := getNodeConfigOverrideUint64(NodeConfigScrubRate); ok {
+		return rate
+	}
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	return s.scrubRate
+}
+
+func (s *DataNode) getScrubInterval() time.Duration {
+	if hours, ok := getNodeConfigOverrideUint64(NodeConfigScrubIntervalHours); ok {
+		return time.Duration(hours) * time.Hour
+	}
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	return s.scrubInterval
+}
+
+func (s *DataNode) getScrubWindow() string {
+	if window, ok := getNodeConfigOverride(NodeConfigScrubWindow); ok {
+		return window
+	}
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	return s.scrubWindow
+}
+
+// parseScrubWindow parses the hours of the day to scrub in, such as "1-6" for 01:00 to 06:00 and "22-6" for
+// 22:00 to 06:00 of the next day. An empty window allows the whole day.
+func parseScrubWindow(window string) (start, end int, err error) {
+	if window == "" {
+		return 0, 24, nil
+	}
+	arr := strings.Split(window, "-")
+	if len(arr) != 2 {
+		err = fmt.Errorf("invalid scrub window %v, example: 1-6", window)
+		return
+	}
+	if start, err = strconv.Atoi(arr[0]); err != nil {
+		return
+	}
+	if end, err = strconv.Atoi(arr[1]); err != nil {
+		return
+	}
+	if start < 0 || start > 23 || end < 0 || end > 24 || start == end {
+		err = fmt.Errorf("invalid scrub window %v, the hours should be between 0 and 24", window)
+	}
+	return
+}
+
+func inScrubWindow(window string, now time.Time) bool {
+	start, end, err := parseScrubWindow(window)
+	if err != nil {
+		return false
+	}
+	hour := now.Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// scrubAllowed tells whether the schedule allows scrubbing now, the scrub is disabled with the rate of 0.
+func (s *DataNode) scrubAllowed(now time.Time) bool {
+	return s.getScrubRate() > 0 && inScrubWindow(s.getScrubWindow(), now) && !s.isDraining()
+}
+
+// startScrubScheduler scrubs the partitions one after another in background, each of them once in the scrub
+// interval. The reads are throttled by the scrub rate, so that the silent corruptions are found and repaired from
+// the other replicas before the clients read them, without starving the foreground requests.
+func (s *DataNode) startScrubScheduler() {
+	go func() {
+		ticker := time.NewTicker(scrubCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopC:
+				return
+			case <-ticker.C:
+				if !s.scrubAllowed(time.Now()) {
+					continue
+				}
+				if dp := s.nextScrubPartition(); dp != nil {
+					dp.doScrub(s)
+				}
+			}
+		}
+	}()
+}
+
+// nextScrubPartition returns the partition of which a round is in progress, or the one scrubbed the longest
+// time ago among the ones due.
+func (s *DataNode) nextScrubPartition() (next *DataPartition) {
+	now := time.Now().Unix()
+	interval := int64(s.getScrubInterval() / time.Second)
+	var nextFinishTime int64
+	s.space.RangePartitions(func(dp *DataPartition) bool {
+		if dp.Disk().Status == proto.Unavailable {
+			return true
+		}
+		dp.scrub.Lock()
+		if dp.scrub.status == nil {
+			dp.scrub.status = dp.loadScrubStatus()
+		}
+		inProgress, lastFinishTime := dp.scrub.nextExtent > 0, dp.scrub.status.LastFinishTime
+		dp.scrub.Unlock()
+		if inProgress {
+			next = dp
+			return false
+		}
+		if lastFinishTime+interval > now {
+			return true
+		}
+		if next == nil || lastFinishTime < nextFinishTime {
+			next, nextFinishTime = dp, lastFinishTime
+		}
+		return true
+	})
+	return
+}
+
+func (dp *DataPartition) scrubStatus() *proto.DataPartitionScrubStatus {
+	dp.scrub.Lock()
+	defer dp.scrub.Unlock()
+	if dp.scrub.status == nil {
+		dp.scrub.status = dp.loadScrubStatus()
+	}
+	status := *dp.scrub.status
+	return &status
+}
+
+// doScrub runs a round of scrub on the partition, or resumes the round stopped by the schedule.
+func (dp *DataPartition) doScrub(s *DataNode) {
+	extents, _, err := dp.ExtentStore().GetAllWatermarks(storage.NormalExtentFilter())
+	if err != nil {
+		log.LogErrorf("action[doScrub] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+	sort.Slice(extents, func(i, j int) bool { return extents[i].FileID < extents[j].FileID })
+
+	dp.scrub.Lock()
+	if dp.scrub.nextExtent == 0 {
+		dp.scrub.status = &proto.DataPartitionScrubStatus{
+			PartitionID:    dp.partitionID,
+			StartTime:      time.Now().Unix(),
+			LastFinishTime: dp.scrub.status.LastFinishTime,
+		}
+	}
+	dp.scrub.status.Running = true
+	dp.scrub.status.Extents = len(extents)
+	dp.scrub.status.Err = ""
+	nextExtent := dp.scrub.nextExtent
+	dp.scrub.Unlock()
+
+	buf := make([]byte, util.BlockSize)
+	for _, ei := range extents {
+		if ei.FileID < nextExtent {
+			continue
+		}
+		select {
+		case <-dp.stopC:
+			err = fmt.Errorf("partition stopped")
+		default:
+		}
+		if err == nil && !s.scrubAllowed(time.Now()) {
+			err = errScrubPaused
+		}
+		if err != nil {
+			nextExtent = ei.FileID
+			break
+		}
+		var size uint64
+		if size, err = dp.scrubExtent(s, ei, buf); err != nil {
+			log.LogWarnf("action[doScrub] partition(%v) scrub extent(%v) err(%v)", dp.partitionID, ei.FileID, err)
+			if err != storage.ExtentNotFoundError && !os.IsNotExist(err) {
+				nextExtent = ei.FileID
+				break
+			}
+			err = nil
+		}
+		dp.scrub.Lock()
+		dp.scrub.status.ScrubbedExtents++
+		dp.scrub.status.ScrubbedSize += size
+		dp.scrub.Unlock()
+	}
+
+	dp.scrub.Lock()
+	dp.scrub.status.Running = false
+	dp.scrub.status.EndTime = time.Now().Unix()
+	if err != nil {
+		dp.scrub.status.Err = err.Error()
+		dp.scrub.nextExtent = nextExtent
+	} else {
+		dp.scrub.status.LastFinishTime = dp.scrub.status.EndTime
+		dp.scrub.nextExtent = 0
+	}
+	status := *dp.scrub.status
+	dp.scrub.Unlock()
+	if err == nil {
+		if e := dp.persistScrubStatus(&status); e != nil {
+			log.LogWarnf("action[doScrub] partition(%v) persist scrub status err(%v)", dp.partitionID, e)
+		}
+	}
+	log.LogInfof("action[doScrub] partition(%v) scrubbed %v of %v extents size(%v) corrupt(%v) repaired(%v) err(%v)",
+		dp.partitionID, status.ScrubbedExtents, status.Extents, status.ScrubbedSize, status.CorruptBlocks,
+		status.RepairedBlocks, err)
+}
+
+// scrubExtent verifies the blocks of the extent against their crc, the corrupt blocks are repaired from the other
+// replicas.
+func (dp *DataPartition) scrubExtent(s *DataNode, ei *storage.ExtentInfo, buf []byte) (size uint64, err error) {
+	store := dp.ExtentStore()
+	labels := map[string]string{"vol": dp.volumeID}
+	for offset := int64(0); offset < int64(ei.Size); offset += util.BlockSize {
+		n := int64(util.Min(util.BlockSize, int(int64(ei.Size)-offset)))
+		if err = scrubLimiter.WaitN(context.Background(), int(n)); err != nil {
+			return
+		}
+		if _, err = store.Read(ei.FileID, offset, n, buf, false); err != nil {
+			dp.checkIsDiskError(err)
+			return
+		}
+		size += uint64(n)
+		if _, err = store.VerifyRead(ei.FileID, offset, n, buf[:n]); err == nil {
+			continue
+		}
+		if !isBlockCrcMismatch(err) {
+			return
+		}
+		blockNo := int(offset / util.BlockSize)
+		log.LogErrorf("action[scrubExtent] partition(%v) extent(%v) block(%v) err(%v)", dp.partitionID, ei.FileID, blockNo, err)
+		exporter.NewCounter(MetricScrubCorruptBlocks).AddWithLabels(1, labels)
+		repaired := false
+		if err = dp.repairCorruptBlock(s.localServerAddr, ei.FileID, blockNo); err != nil {
+			exporter.Warning(fmt.Sprintf("partition(%v) extent(%v) block(%v) on %v is corrupt and failed to be repaired: %v",
+				dp.partitionID, ei.FileID, blockNo, s.localServerAddr, err))
+		} else {
+			repaired = true
+			exporter.NewCounter(MetricScrubRepairedBlocks).AddWithLabels(1, labels)
+		}
+		dp.scrub.Lock()
+		dp.scrub.status.CorruptBlocks++
+		if repaired {
+			dp.scrub.status.RepairedBlocks++
+		}
+		dp.scrub.Unlock()
+		err = nil
+	}
+	return
+}
+
+// repairCorruptBlock overwrites the corrupt block with the one of another replica which matches the crc recorded.
+func (dp *DataPartition) repairCorruptBlock(localAddr string, extentID uint64, blockNo int) (err error) {
+	store := dp.ExtentStore()
+	var expectCrc uint32
+	if expectCrc, err = store.BlockCrc(extentID, blockNo); err != nil {
+		return
+	}
+	offset := int64(blockNo) * util.BlockSize
+	err = fmt.Errorf("no other replica")
+	for _, addr := range dp.Replicas() {
+		if addr == localAddr {
+			continue
+		}
+		var data []byte
+		if data, err = dp.readRemoteBlock(addr, extentID, offset); err != nil {
+			log.LogWarnf("action[repairCorruptBlock] partition(%v) extent(%v) block(%v) read from %v err(%v)",
+				dp.partitionID, extentID, blockNo, addr, err)
+			continue
+		}
+		if crc := crc32.ChecksumIEEE(data); crc != expectCrc {
+			err = fmt.Errorf("block on %v mismatches the crc expectCrc(%v) actualCrc(%v)", addr, expectCrc, crc)
+			continue
+		}
+		// the block may be overwritten by the clients during the repair, which resets the crc
+		var crc uint32
+		if crc, err = store.BlockCrc(extentID, blockNo); err != nil || crc != expectCrc {
+			log.LogWarnf("action[repairCorruptBlock] partition(%v) extent(%v) block(%v) is overwritten",
+				dp.partitionID, extentID, blockNo)
+			return
+		}
+		if err = store.Write(extentID, offset, util.BlockSize, data, expectCrc, storage.RandomWriteType, true); err != nil {
+			dp.checkIsDiskError(err)
+			return
+		}
+		log.LogWarnf("action[repairCorruptBlock] partition(%v) extent(%v) block(%v) is repaired from %v",
+			dp.partitionID, extentID, blockNo, addr)
+		return
+	}
+	return
+}
+
+// readRemoteBlock reads a whole block of the extent from the replica on the address.
+func (dp *DataPartition) readRemoteBlock(addr string, extentID uint64, offset int64) (data []byte, err error) {
+	request := repl.NewExtentRepairReadPacket(dp.partitionID, extentID, int(offset), util.BlockSize)
+	var conn *net.TCPConn
+	if conn, err = gConnPool.GetConnect(addr); err != nil {
+		return
+	}
+	defer gConnPool.PutConnect(conn, true)
+	if err = request.WriteToConn(conn); err != nil {
+		return
+	}
+	zone := getPeerZone(addr)
+	data = make([]byte, 0, util.BlockSize)
+	for len(data) < util.BlockSize {
+		reply := repl.NewPacket()
+		if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk {
+			err = fmt.Errorf("read from %v: %v", addr, string(reply.Data[:reply.Size]))
+			return
+		}
+		if reply.ReqID != request.ReqID || reply.ExtentID != extentID || reply.ExtentOffset != offset+int64(len(data)) {
+			err = fmt.Errorf("unexpected reply %v of request %v", reply.GetUniqueLogId(), request.GetUniqueLogId())
+			return
+		}
+		waitRepairBandwidth(zone, int(reply.Size))
+		data = append(data, reply.Data[:reply.Size]...)
+	}
+	return
+}
+
+// persistScrubStatus writes the status of the last complete round into the partition directory, so that the
+// partitions are not scrubbed again right after the data node restarts.
+func (dp *DataPartition) persistScrubStatus(status *proto.DataPartitionScrubStatus) (err error) {
+	var data []byte
+	if data, err = json.Marshal(status); err != nil {
+		return
+	}
+	fileName := path.Join(dp.Path(), TempScrubStatusFileName)
+	if err = ioutil.WriteFile(fileName, data, 0644); err != nil {
+		return
+	}
+	defer os.Remove(fileName)
+	err = os.Rename(fileName, path.Join(dp.Path(), ScrubStatusFileName))
+	return
+}
+
+func (dp *DataPartition) loadScrubStatus() (status *proto.DataPartitionScrubStatus) {
+	status = &proto.DataPartitionScrubStatus{PartitionID: dp.partitionID}
+	data, err := ioutil.ReadFile(path.Join(dp.Path(), ScrubStatusFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.LogWarnf("action[loadScrubStatus] partition(%v) err(%v)", dp.partitionID, err)
+		}
+		return
+	}
+	if err = json.Unmarshal(data, status); err != nil {
+		log.LogWarnf("action[loadScrubStatus] partition(%v) err(%v)", dp.partitionID, err)
+		return &proto.DataPartitionScrubStatus{PartitionID: dp.partitionID}
+	}
+	return
+}
+
+// scrubStatusAPI returns the scrub progress of the partition, or of all the partitions if no partition is given.
+func (s *DataNode) scrubStatusAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramID = "id"
+	)
+	if err := r.ParseForm(); err != nil {
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if value := r.FormValue(paramID); value != "" {
+		partitionID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("parse param %v fail: %v", paramID, err))
+			return
+		}
+		partition := s.space.Partition(partitionID)
+		if partition == nil {
+			s.buildFailureResp(w, http.StatusNotFound, "partition not exist")
+			return
+		}
+		s.buildSuccessResp(w, partition.scrubStatus())
+		return
+	}
+	statuses := make([]*proto.DataPartitionScrubStatus, 0)
+	s.space.RangePartitions(func(dp *DataPartition) bool {
+		statuses = append(statuses, dp.scrubStatus())
+		return true
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].PartitionID < statuses[j].PartitionID })
+	s.buildSuccessResp(w, statuses)
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	SealedExtentsFile = "SEALED_EXTENTS"
+)
+
+// sealRange is the range [Offset, Offset+Size) of an extent.
+type sealRange struct {
+	Offset uint64 `json:"o"`
+	Size   uint64 `json:"s"`
+}
+
+func (r sealRange) end() uint64 {
+	return r.Offset + r.Size
+}
+
+// sealedExtents records the ranges of the extents which hold the data of the immutable and the
+// append-only files. The meta nodes seal and unseal the ranges on all the replicas when the flags
+// of a file change or data is appended to an append-only file, and the overwrites of the sealed
+// ranges are refused no matter how the file is opened. The records are persisted in the directory
+// of the partition, and sent from the leader to the followers with the repair tasks, so that a new
+// replica gets them as well.
+type sealedExtents struct {
+	sync.RWMutex
+	ranges map[uint64][]sealRange
+}
+
+func (dp *DataPartition) sealedExtentsPath() string {
+	return path.Join(dp.path, SealedExtentsFile)
+}
+
+// loadSealedExtents loads the sealed ranges persisted by the partition.
+func (dp *DataPartition) loadSealedExtents() (err error) {
+	dp.sealed.Lock()
+	defer dp.sealed.Unlock()
+	dp.sealed.ranges = make(map[uint64][]sealRange)
+	data, err := ioutil.ReadFile(dp.sealedExtentsPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &dp.sealed.ranges); err != nil {
+		return fmt.Errorf("load sealed extents of partition(%v): %v", dp.partitionID, err)
+	}
+	return
+}
+
+// persistSealedExtents writes the sealed ranges to a temporary file and renames it, so that the
+// records are never half written. The caller must hold the lock.
+func (dp *DataPartition) persistSealedExtents() (err error) {
+	data, err := json.Marshal(dp.sealed.ranges)
+	if err != nil {
+		return
+	}
+	tmpPath := dp.sealedExtentsPath() + ".tmp"
+	var fp *os.File
+	if fp, err = os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+		return
+	}
+	if _, err = fp.Write(data); err == nil {
+		err = fp.Sync()
+	}
+	fp.Close()
+	if err != nil {
+		return
+	}
+	return os.Rename(tmpPath, dp.sealedExtentsPath())
+}
+
+// sealExtents seals or unseals the ranges of the extent keys.
+func (dp *DataPartition) sealExtents(req *proto.SealExtentsRequest) (err error) {
+	dp.sealed.Lock()
+	defer dp.sealed.Unlock()
+	for _, ek := range req.Extents {
+		r := sealRange{Offset: ek.ExtentOffset, Size: uint64(ek.Size)}
+		if req.Unseal {
+			dp.sealed.subtract(ek.ExtentId, r)
+		} else {
+			dp.sealed.add(ek.ExtentId, r)
+		}
+	}
+	if err = dp.persistSealedExtents(); err != nil {
+		err = fmt.Errorf("persist sealed extents of partition(%v): %v", dp.partitionID, err)
+	}
+	return
+}
+
+// mergeSealedExtents adds the ranges sealed on the leader, the ranges only sealed on this replica are kept.
+func (dp *DataPartition) mergeSealedExtents(eks []proto.ExtentKey) {
+	if len(eks) == 0 {
+		return
+	}
+	dp.sealed.Lock()
+	defer dp.sealed.Unlock()
+	changed := false
+	for _, ek := range eks {
+		r := sealRange{Offset: ek.ExtentOffset, Size: uint64(ek.Size)}
+		if !dp.sealed.covers(ek.ExtentId, r) {
+			dp.sealed.add(ek.ExtentId, r)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := dp.persistSealedExtents(); err != nil {
+		log.LogErrorf("action[mergeSealedExtents] partition(%v) err(%v)", dp.partitionID, err)
+	}
+}
+
+// sealedExtentKeys returns the sealed ranges as extent keys, which are sent to the followers.
+func (dp *DataPartition) sealedExtentKeys() (eks []proto.ExtentKey) {
+	dp.sealed.RLock()
+	defer dp.sealed.RUnlock()
+	for extentID, ranges := range dp.sealed.ranges {
+		for _, r := range ranges {
+			eks = append(eks, proto.ExtentKey{PartitionId: dp.partitionID, ExtentId: extentID, ExtentOffset: r.Offset, Size: uint32(r.Size)})
+		}
+	}
+	return
+}
+
+// isSealed returns if the range of the extent overlaps any sealed range.
+func (dp *DataPartition) isSealed(extentID, offset, size uint64) bool {
+	dp.sealed.RLock()
+	defer dp.sealed.RUnlock()
+	for _, r := range dp.sealed.ranges[extentID] {
+		if r.Offset < offset+size && offset < r.end() {
+			return true
+		}
+	}
+	return false
+}
+
+// dropSealedExtent forgets the sealed ranges of the deleted data, size zero means the whole extent.
+func (dp *DataPartition) dropSealedExtent(extentID, offset, size uint64) {
+	dp.sealed.Lock()
+	defer dp.sealed.Unlock()
+	if _, ok := dp.sealed.ranges[extentID]; !ok {
+		return
+	}
+	if size == 0 {
+		delete(dp.sealed.ranges, extentID)
+	} else {
+		dp.sealed.subtract(extentID, sealRange{Offset: offset, Size: size})
+	}
+	if err := dp.persistSealedExtents(); err != nil {
+		log.LogErrorf("action[dropSealedExtent] partition(%v) extent(%v) err(%v)", dp.partitionID, extentID, err)
+	}
+}
+
+// add merges the range with the overlapping and the adjacent ranges of the extent.
+func (s *sealedExtents) add(extentID uint64, r sealRange) {
+	if r.Size == 0 {
+		return
+	}
+	ranges := make([]sealRange, 0, len(s.ranges[extentID])+1)
+	for _, x := range s.ranges[extentID] {
+		if x.end() < r.Offset || r.end() < x.Offset {
+			ranges = append(ranges, x)
+			continue
+		}
+		start, end := x.Offset, x.end()
+		if r.Offset < start {
+			start = r.Offset
+		}
+		if r.end() > end {
+			end = r.end()
+		}
+		r = sealRange{Offset: start, Size: end - start}
+	}
+	ranges = append(ranges, r)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Offset < ranges[j].Offset })
+	s.ranges[extentID] = ranges
+}
+
+// subtract removes the range from the ranges of the extent.
+func (s *sealedExtents) subtract(extentID uint64, r sealRange) {
+	ranges := make([]sealRange, 0, len(s.ranges[extentID])+1)
+	for _, x := range s.ranges[extentID] {
+		if x.end() <= r.Offset || r.end() <= x.Offset {
+			ranges = append(ranges, x)
+			continue
+		}
+		if x.Offset < r.Offset {
+			ranges = append(ranges, sealRange{Offset: x.Offset, Size: r.Offset - x.Offset})
+		}
+		if x.end() > r.end() {
+			ranges = append(ranges, sealRange{Offset: r.end(), Size: x.end() - r.end()})
+		}
+	}
+	if len(ranges) == 0 {
+		delete(s.ranges, extentID)
+		return
+	}
+	s.ranges[extentID] = ranges
+}
+
+// covers returns if the range is within a sealed range of the extent.
+func (s *sealedExtents) covers(extentID uint64, r sealRange) bool {
+	for _, x := range s.ranges[extentID] {
+		if x.Offset <= r.Offset && r.end() <= x.end() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOverwrite checks the overwrites sent by the clients before they are forwarded to the followers,
+// which are the random writes and the append writes to the offsets already written. The sealed ranges
+// are never overwritten, and the data in the retention period of the volume is protected by checkWorm.
+func checkOverwrite(p *repl.Packet) (err error) {
+	partition := p.Object.(*DataPartition)
+	switch {
+	case p.IsRandomWrite():
+	case p.IsLeaderPacket() && p.IsWriteOperation() && !p.IsTinyExtentType():
+		ei, e := partition.ExtentStore().Watermark(p.ExtentID)
+		if e != nil || uint64(p.ExtentOffset) >= ei.Size {
+			return
+		}
+	default:
+		return
+	}
+	if partition.isSealed(p.ExtentID, uint64(p.ExtentOffset), uint64(p.Size)) {
+		err = fmt.Errorf("%v: extent(%v) offset(%v) size(%v) holds the data of an immutable or append-only file",
+			storage.NotPermittedError, p.ExtentID, p.ExtentOffset, p.Size)
+		log.LogWarnf("action[checkOverwrite] dp(%v) req(%v) refused: %v", partition.partitionID, p.ReqID, err)
+		return
+	}
+	return checkWorm(partition, p)
+}
@@ -0,0 +1,144 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Policies applied to the local partitions which are not expected by the master on startup.
+const (
+	// rename the partition directory with ExpiredPartitionPrefix, so that it can be restored or deleted manually
+	UnknownPartitionPolicyQuarantine = "quarantine"
+	// delete the partition directory once the master confirms that the partition has been deleted
+	// or this node is no longer one of its replicas, otherwise quarantine it
+	UnknownPartitionPolicyDelete = "delete"
+)
+
+// SelfCheckReport records how the local partitions diverge from the view of the master on startup.
+type SelfCheckReport struct {
+	sync.Mutex
+	StartTime         int64
+	Policy            string
+	MissingPartitions []uint64 // expected by the master but not found on the local disks
+	UnknownPartitions []uint64 // found on the local disks but not expected by the master
+	DeletedPartitions []uint64 // unknown partitions which have been deleted from the cluster
+	StalePartitions   []uint64 // unknown partitions whose replica has been moved off this node while it was offline
+	QuarantinedDirs   []string
+	RemovedDirs       []string
+	ReplacedDisks     []string // the missing partitions are re-created on this node by the master
+}
+
+func newSelfCheckReport(policy string) *SelfCheckReport {
+	return &SelfCheckReport{
+		StartTime:         time.Now().Unix(),
+		Policy:            policy,
+		MissingPartitions: make([]uint64, 0),
+		UnknownPartitions: make([]uint64, 0),
+		DeletedPartitions: make([]uint64, 0),
+		StalePartitions:   make([]uint64, 0),
+		QuarantinedDirs:   make([]string, 0),
+		RemovedDirs:       make([]string, 0),
+		ReplacedDisks:     make([]string, 0),
+	}
+}
+
+func parseUnknownPartitionPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return UnknownPartitionPolicyQuarantine, nil
+	case UnknownPartitionPolicyQuarantine, UnknownPartitionPolicyDelete:
+		return policy, nil
+	}
+	return "", fmt.Errorf("Err:invalid %v(%v)", ConfigKeyUnknownPartitionPolicy, policy)
+}
+
+func (r *SelfCheckReport) addMissing(partitionIDs []uint64) {
+	r.Lock()
+	defer r.Unlock()
+	r.MissingPartitions = append(r.MissingPartitions, partitionIDs...)
+}
+
+func (r *SelfCheckReport) addQuarantined(dir string) {
+	r.Lock()
+	defer r.Unlock()
+	r.QuarantinedDirs = append(r.QuarantinedDirs, dir)
+}
+
+// handleUnknownPartition applies the policy to a local partition which is not expected by the master.
+func (s *DataNode) handleUnknownPartition(d *Disk, filename string, partitionID uint64) {
+	var (
+		report  = s.selfCheck
+		dir     = path.Join(d.Path, filename)
+		deleted bool
+		stale   bool
+	)
+	partition, err := MasterClient.AdminAPI().GetDataPartition("", partitionID)
+	switch {
+	case err == proto.ErrDataPartitionNotExists:
+		deleted = true
+	case err == nil:
+		stale = true
+		for _, host := range partition.Hosts {
+			if host == s.localServerAddr {
+				stale = false
+				break
+			}
+		}
+	default:
+		log.LogWarnf("action[handleUnknownPartition] get partition(%v) from master err(%v)", partitionID, err)
+	}
+
+	report.Lock()
+	report.UnknownPartitions = append(report.UnknownPartitions, partitionID)
+	if deleted {
+		report.DeletedPartitions = append(report.DeletedPartitions, partitionID)
+	}
+	if stale {
+		report.StalePartitions = append(report.StalePartitions, partitionID)
+	}
+	report.Unlock()
+
+	if report.Policy == UnknownPartitionPolicyDelete && (deleted || stale) {
+		if err = os.RemoveAll(dir); err == nil {
+			report.Lock()
+			report.RemovedDirs = append(report.RemovedDirs, dir)
+			report.Unlock()
+			log.LogWarnf("action[handleUnknownPartition] partition(%v) deleted(%v) stale(%v), dir(%v) removed",
+				partitionID, deleted, stale, dir)
+			return
+		}
+		log.LogErrorf("action[handleUnknownPartition] remove dir(%v) err(%v), quarantine it instead", dir, err)
+	}
+
+	newDir := path.Join(d.Path, ExpiredPartitionPrefix+filename)
+	if err = os.Rename(dir, newDir); err != nil {
+		log.LogErrorf("action[handleUnknownPartition] quarantine dir(%v) err(%v)", dir, err)
+		return
+	}
+	report.addQuarantined(newDir)
+	msg := fmt.Sprintf("action[handleUnknownPartition] partition(%v) is not expected by master, deleted(%v) stale(%v), "+
+		"dir quarantined to (%v), you can restore or delete it manually", partitionID, deleted, stale, newDir)
+	log.LogWarn(msg)
+	exporter.Warning(msg)
+}
@@ -0,0 +1,28 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// the reads are always copied through the user space in Darwin(Apple MacOS)
+const sendfileSupported = false
+
+func sendfile(conn *net.TCPConn, file *os.File, offset int64, size int) (err error) {
+	return syscall.ENOSYS
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+const sendfileSupported = true
+
+// sendfile sends the data of the file from the offset to the connection in the kernel, without copying it through
+// the user space. The file descriptors are held during the call, so that they are not closed and reused meanwhile.
+func sendfile(conn *net.TCPConn, file *os.File, offset int64, size int) (err error) {
+	src, err := file.SyscallConn()
+	if err != nil {
+		return
+	}
+	dst, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+	var (
+		sent    int
+		sendErr error
+	)
+	ctlErr := src.Control(func(infd uintptr) {
+		// the write waits for the connection to be writable, and fails after the write deadline
+		err = dst.Write(func(outfd uintptr) bool {
+			for sent < size {
+				n, e := syscall.Sendfile(int(outfd), int(infd), &offset, size-sent)
+				if n > 0 {
+					sent += n
+				}
+				switch {
+				case e == syscall.EAGAIN:
+					return false
+				case e == syscall.EINTR:
+					continue
+				case e != nil:
+					sendErr = os.NewSyscallError("sendfile", e)
+					return true
+				case n == 0:
+					// the file is shorter than expected
+					sendErr = io.ErrUnexpectedEOF
+					return true
+				}
+			}
+			return true
+		})
+	})
+	if ctlErr != nil {
+		return ctlErr
+	}
+	if err == nil {
+		err = sendErr
+	}
+	return
+}
@@ -20,7 +20,6 @@ import (
 	"net/http"
 	"regexp"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,6 +37,8 @@ import (
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 var (
@@ -54,8 +55,8 @@ var (
 const (
 	DefaultZoneName         = proto.DefaultZoneName
 	DefaultRaftDir          = "raft"
-	DefaultRaftLogsToRetain = 10 // Count of raft logs per data partition
-	DefaultDiskMaxErr       = 1
+	DefaultRaftLogsToRetain = 10           // Count of raft logs per data partition
+	DefaultDiskMaxErr       = 10           // io errors to take a disk offline
 	DefaultDiskRetainMin    = 5 * util.GB  // GB
 	DefaultDiskRetainMax    = 30 * util.GB // GB
 )
@@ -73,6 +74,16 @@ const (
 	ConfigKeyRaftDir       = "raftDir"       // string
 	ConfigKeyRaftHeartbeat = "raftHeartbeat" // string
 	ConfigKeyRaftReplica   = "raftReplica"   // string
+
+	ConfigKeyUnknownPartitionPolicy = "unknownPartitionPolicy" // string
+	ConfigKeyOrphanExtentGraceHours = "orphanExtentGraceHours" // int
+	ConfigKeyWarmUpRate             = "warmUpRate"             // int, MB per second
+	ConfigKeyDiskMaxErrCnt          = "diskMaxErrCnt"          // int, io errors to take a disk offline
+	ConfigKeySmartIntervalMin       = "smartIntervalMin"       // int, minutes between the SMART collections, negative disables them
+	ConfigKeyScrubRate              = "scrubRate"              // int, MB per second read by the scrub, negative disables it
+	ConfigKeyScrubIntervalHours     = "scrubIntervalHours"     // int, hours between the scrubs of a partition
+	ConfigKeyScrubWindow            = "scrubWindow"            // string, hours of the day to scrub in, such as 1-6
+	ConfigKeyDisableZeroCopyRead    = "disableZeroCopyRead"    // bool, copy the data of the reads through the user space
 )
 
 // DataNode defines the structure of a data node.
@@ -89,6 +100,20 @@ type DataNode struct {
 	raftReplica     string
 	raftStore       raftstore.RaftStore
 
+	unknownPartitionPolicy string
+	selfCheck              *SelfCheckReport
+
+	orphanExtentGracePeriod time.Duration
+	warmUpRate              uint64 // MB per second
+	diskMaxErrCnt           int
+	smartInterval           time.Duration
+	scrubRate               uint64 // MB per second
+	scrubInterval           time.Duration
+	scrubWindow             string
+	disableZeroCopyRead     bool
+
+	drain drainState
+
 	tcpListener net.Listener
 	stopC       chan bool
 
@@ -129,6 +154,12 @@ func doStart(server common.Server, cfg *config.Config) (err error) {
 	}
 
 	exporter.Init(ModuleName, cfg)
+	if err = tracing.Init(ModuleName, cfg); err != nil {
+		return fmt.Errorf("Err:bad tracing config: %v", err)
+	}
+	if err = slowop.Init(cfg); err != nil {
+		return fmt.Errorf("Err:bad slow op config: %v", err)
+	}
 	s.register(cfg)
 
 	// start the raft server
@@ -140,8 +171,11 @@ func doStart(server common.Server, cfg *config.Config) (err error) {
 	if err = s.startSpaceManager(cfg); err != nil {
 		return
 	}
+	s.registerHealthChecks()
+	s.startSmartCollector()
+	s.startScrubScheduler()
 
-	// check local partition compare with master ,if lack,then not start
+	// check local partition compare with master ,if lack,then not start unless the disks have been replaced
 	if err = s.checkLocalPartitionMatchWithMaster(); err != nil {
 		log.LogError(err)
 		exporter.Warning(err.Error())
@@ -169,6 +203,7 @@ func doShutdown(server common.Server) {
 	s.stopUpdateNodeInfo()
 	s.stopTCPService()
 	s.stopRaftServer()
+	tracing.Stop()
 }
 
 func (s *DataNode) parseConfig(cfg *config.Config) (err error) {
@@ -196,10 +231,31 @@ func (s *DataNode) parseConfig(cfg *config.Config) (err error) {
 	if s.zoneName == "" {
 		s.zoneName = DefaultZoneName
 	}
+	if s.unknownPartitionPolicy, err = parseUnknownPartitionPolicy(cfg.GetString(ConfigKeyUnknownPartitionPolicy)); err != nil {
+		return
+	}
+	s.selfCheck = newSelfCheckReport(s.unknownPartitionPolicy)
+	var rc *reloadableConfig
+	if rc, err = parseReloadableConfig(cfg); err != nil {
+		return
+	}
+	s.applyReloadableConfig(rc)
+	s.diskMaxErrCnt = DefaultDiskMaxErr
+	if maxErrCnt := cfg.GetInt64(ConfigKeyDiskMaxErrCnt); maxErrCnt > 0 {
+		s.diskMaxErrCnt = int(maxErrCnt)
+	}
+	s.smartInterval = DefaultSmartInterval
+	if minutes := cfg.GetInt64(ConfigKeySmartIntervalMin); minutes != 0 {
+		s.smartInterval = time.Duration(minutes) * time.Minute
+	}
+	s.disableZeroCopyRead = cfg.GetBool(ConfigKeyDisableZeroCopyRead)
 
 	log.LogDebugf("action[parseConfig] load masterAddrs(%v).", MasterClient.Nodes())
 	log.LogDebugf("action[parseConfig] load port(%v).", s.port)
 	log.LogDebugf("action[parseConfig] load zoneName(%v).", s.zoneName)
+	log.LogDebugf("action[parseConfig] load unknownPartitionPolicy(%v).", s.unknownPartitionPolicy)
+	log.LogDebugf("action[parseConfig] load orphanExtentGracePeriod(%v).", s.orphanExtentGracePeriod)
+	log.LogDebugf("action[parseConfig] load warmUpRate(%v).", s.warmUpRate)
 	return
 }
 
@@ -218,12 +274,10 @@ func (s *DataNode) startSpaceManager(cfg *config.Config) (err error) {
 	for _, d := range cfg.GetSlice(ConfigKeyDisks) {
 		log.LogDebugf("action[startSpaceManager] load disk raw config(%v).", d)
 
-		// format "PATH:RESET_SIZE
-		arr := strings.Split(d.(string), ":")
-		if len(arr) != 2 {
-			return errors.New("Invalid disk configuration. Example: PATH:RESERVE_SIZE")
+		path, reservedSpace, err := parseDiskConfig(d)
+		if err != nil {
+			return err
 		}
-		path := arr[0]
 		fileInfo, err := os.Stat(path)
 		if err != nil {
 			return errors.New(fmt.Sprintf("Stat disk path error: %s", err.Error()))
@@ -231,19 +285,11 @@ func (s *DataNode) startSpaceManager(cfg *config.Config) (err error) {
 		if !fileInfo.IsDir() {
 			return errors.New("Disk path is not dir")
 		}
-		reservedSpace, err := strconv.ParseUint(arr[1], 10, 64)
-		if err != nil {
-			return errors.New(fmt.Sprintf("Invalid disk reserved space. Error: %s", err.Error()))
-		}
-
-		if reservedSpace < DefaultDiskRetainMin {
-			reservedSpace = DefaultDiskRetainMin
-		}
 
 		wg.Add(1)
 		go func(wg *sync.WaitGroup, path string, reservedSpace uint64) {
 			defer wg.Done()
-			s.space.LoadDisk(path, reservedSpace, DefaultDiskMaxErr)
+			s.space.LoadDisk(path, reservedSpace, s.diskMaxErrCnt)
 		}(&wg, path, reservedSpace)
 	}
 	wg.Wait()
@@ -340,6 +386,10 @@ func (s *DataNode) checkLocalPartitionMatchWithMaster() (err error) {
 	if len(lackPartitions) == 0 {
 		return
 	}
+	s.selfCheck.addMissing(lackPartitions)
+	if s.recoverReplacedDisks(lackPartitions) {
+		return
+	}
 	err = fmt.Errorf("LackPartitions %v on datanode %v,datanode cannot start", lackPartitions, s.localServerAddr)
 	log.LogErrorf(err.Error())
 	return
@@ -354,6 +404,20 @@ func (s *DataNode) registerHandler() {
 	http.HandleFunc("/stats", s.getStatAPI)
 	http.HandleFunc("/raftStatus", s.getRaftStatus)
 	http.HandleFunc("/setAutoRepairStatus", s.setAutoRepairStatus)
+	http.HandleFunc("/selfCheck", s.getSelfCheckReport)
+	http.HandleFunc("/orphanScan", s.scanOrphanExtents)
+	http.HandleFunc("/warmUp", s.warmUpPartitionAPI)
+	http.HandleFunc("/config", s.getNodeConfigAPI)
+	http.HandleFunc("/setConfig", s.setNodeConfigAPI)
+	http.HandleFunc("/slowOps", s.getSlowOpsAPI)
+	http.HandleFunc("/drain", s.drainAPI)
+	http.HandleFunc("/drainStatus", s.drainStatusAPI)
+	http.HandleFunc("/diskOffline", s.diskOfflineAPI)
+	http.HandleFunc("/diskOnline", s.diskOnlineAPI)
+	http.HandleFunc("/smart", s.getSmartAPI)
+	http.HandleFunc("/scrubStatus", s.scrubStatusAPI)
+	http.HandleFunc("/verifyPartition", s.verifyPartitionAPI)
+	http.HandleFunc("/resyncReplica", s.resyncReplicaAPI)
 }
 
 func (s *DataNode) startTCPService() (err error) {
@@ -395,8 +459,17 @@ func (s *DataNode) serveConn(conn net.Conn) {
 	c, _ := conn.(*net.TCPConn)
 	c.SetKeepAlive(true)
 	c.SetNoDelay(true)
+	request := repl.NewPacket()
+	if err := request.ReadFromConnFromCli(c, proto.NoReadDeadlineTime); err != nil {
+		c.Close()
+		return
+	}
+	if request.Opcode == proto.OpMuxConn {
+		repl.ServeMuxConn(c, request, s.Prepare, s.OperatePacket, s.Post)
+		return
+	}
 	packetProcessor := repl.NewReplProtocol(c, s.Prepare, s.OperatePacket, s.Post)
-	packetProcessor.ServerConn()
+	packetProcessor.ServerConnFrom(request)
 }
 
 // Increase the disk error count by one.
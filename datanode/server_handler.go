@@ -22,6 +22,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/slowop"
 	"github.com/tiglabs/raft"
 )
 
@@ -30,34 +31,13 @@ var (
 )
 
 func (s *DataNode) getDiskAPI(w http.ResponseWriter, r *http.Request) {
-	disks := make([]interface{}, 0)
+	disks := make([]*proto.DiskInfo, 0)
 	for _, diskItem := range s.space.GetDisks() {
-		disk := &struct {
-			Path        string `json:"path"`
-			Total       uint64 `json:"total"`
-			Used        uint64 `json:"used"`
-			Available   uint64 `json:"available"`
-			Unallocated uint64 `json:"unallocated"`
-			Allocated   uint64 `json:"allocated"`
-			Status      int    `json:"status"`
-			RestSize    uint64 `json:"restSize"`
-			Partitions  int    `json:"partitions"`
-		}{
-			Path:        diskItem.Path,
-			Total:       diskItem.Total,
-			Used:        diskItem.Used,
-			Available:   diskItem.Available,
-			Unallocated: diskItem.Unallocated,
-			Allocated:   diskItem.Allocated,
-			Status:      diskItem.Status,
-			RestSize:    diskItem.ReservedSpace,
-			Partitions:  diskItem.PartitionCount(),
-		}
-		disks = append(disks, disk)
+		disks = append(disks, diskItem.Info())
 	}
 	diskReport := &struct {
-		Disks []interface{} `json:"disks"`
-		Zone  string        `json:"zone"`
+		Disks []*proto.DiskInfo `json:"disks"`
+		Zone  string            `json:"zone"`
 	}{
 		Disks: disks,
 		Zone:  s.zoneName,
@@ -65,6 +45,76 @@ func (s *DataNode) getDiskAPI(w http.ResponseWriter, r *http.Request) {
 	s.buildSuccessResp(w, diskReport)
 }
 
+// getSmartAPI returns the SMART attributes collected of the disks, or of the disk given.
+func (s *DataNode) getSmartAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramDisk = "disk"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	path := r.FormValue(paramDisk)
+	smarts := make(map[string]*proto.DiskSmart)
+	for _, disk := range s.space.GetDisks() {
+		if path != "" && disk.Path != path {
+			continue
+		}
+		smarts[disk.Path] = disk.Info().Smart
+	}
+	if path != "" && len(smarts) == 0 {
+		s.buildFailureResp(w, http.StatusNotFound, fmt.Sprintf("disk %v is not found", path))
+		return
+	}
+	s.buildSuccessResp(w, smarts)
+}
+
+// diskOfflineAPI takes a disk offline by hand, as if it had reached the maximum of io errors.
+func (s *DataNode) diskOfflineAPI(w http.ResponseWriter, r *http.Request) {
+	disk, ok := s.parseDiskToToggle(w, r)
+	if !ok {
+		return
+	}
+	disk.markOffline("taken offline by hand", true)
+	s.buildSuccessResp(w, disk.Info())
+}
+
+// diskOnlineAPI brings an offline disk back and clears its io errors.
+func (s *DataNode) diskOnlineAPI(w http.ResponseWriter, r *http.Request) {
+	disk, ok := s.parseDiskToToggle(w, r)
+	if !ok {
+		return
+	}
+	if !disk.markOnline() {
+		s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("disk %v is not offline", disk.Path))
+		return
+	}
+	s.buildSuccessResp(w, disk.Info())
+}
+
+func (s *DataNode) parseDiskToToggle(w http.ResponseWriter, r *http.Request) (disk *Disk, ok bool) {
+	const (
+		paramDisk = "disk"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	path := r.FormValue(paramDisk)
+	if path == "" {
+		s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("lack of param %v", paramDisk))
+		return
+	}
+	disk, err := s.space.GetDisk(path)
+	if err != nil {
+		s.buildFailureResp(w, http.StatusNotFound, fmt.Sprintf("disk %v is not found", path))
+		return
+	}
+	return disk, true
+}
+
 func (s *DataNode) getStatAPI(w http.ResponseWriter, r *http.Request) {
 	response := &proto.DataNodeHeartbeatResponse{}
 	s.buildHeartBeatResponse(response)
@@ -72,6 +122,34 @@ func (s *DataNode) getStatAPI(w http.ResponseWriter, r *http.Request) {
 	s.buildSuccessResp(w, response)
 }
 
+func (s *DataNode) getSelfCheckReport(w http.ResponseWriter, r *http.Request) {
+	s.selfCheck.Lock()
+	defer s.selfCheck.Unlock()
+	s.buildSuccessResp(w, s.selfCheck)
+}
+
+// getSlowOpsAPI lists the latest slow operations finished since the unix time of the param.
+func (s *DataNode) getSlowOpsAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramSince = "since"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var since int64
+	if value := r.FormValue(paramSince); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = fmt.Errorf("parse param %v fail: %v", paramSince, err)
+			s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	s.buildSuccessResp(w, slowop.Recent(since))
+}
+
 func (s *DataNode) setAutoRepairStatus(w http.ResponseWriter, r *http.Request) {
 	const (
 		paramAutoRepair = "autoRepair"
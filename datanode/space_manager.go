@@ -341,11 +341,13 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	response.MaxCapacity = stat.MaxCapacityToCreatePartition
 	response.RemainingCapacity = stat.RemainingCapacityToCreatePartition
 	response.BadDisks = make([]string, 0)
+	response.FailingDisks = make([]string, 0)
 	stat.Unlock()
 
 	response.ZoneName = s.zoneName
 	response.PartitionReports = make([]*proto.PartitionReport, 0)
 	space := s.space
+	draining := s.isDraining()
 	space.RangePartitions(func(partition *DataPartition) bool {
 		leaderAddr, isLeader := partition.IsRaftLeader()
 		vr := &proto.PartitionReport{
@@ -359,6 +361,10 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 			ExtentCount:     partition.GetExtentCount(),
 			NeedCompare:     true,
 		}
+		// the master stops allocating the partitions of a draining data node to the clients
+		if draining && vr.PartitionStatus == proto.ReadWrite {
+			vr.PartitionStatus = proto.ReadOnly
+		}
 		log.LogDebugf("action[Heartbeats] dpid(%v), status(%v) total(%v) used(%v) leader(%v) isLeader(%v).", vr.PartitionID, vr.PartitionStatus, vr.Total, vr.Used, leaderAddr, vr.IsLeader)
 		response.PartitionReports = append(response.PartitionReports, vr)
 		return true
@@ -369,5 +375,8 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 		if d.Status == proto.Unavailable {
 			response.BadDisks = append(response.BadDisks, d.Path)
 		}
+		if d.isFailurePredicted() {
+			response.FailingDisks = append(response.FailingDisks, d.Path)
+		}
 	}
 }
@@ -0,0 +1,85 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	MetricVerifiedReadBytes  = "verifiedReadBytes"
+	MetricVerifiedReadErrors = "verifiedReadErrors"
+)
+
+var (
+	verifyReadVols     = make(map[string]bool)
+	verifyReadVolsLock sync.RWMutex
+)
+
+// updateVerifyReadVols applies the volumes whose reads are verified, which are sent by the master with the heartbeat.
+func updateVerifyReadVols(vols []string) {
+	newVols := make(map[string]bool, len(vols))
+	for _, volName := range vols {
+		newVols[volName] = true
+	}
+	verifyReadVolsLock.Lock()
+	defer verifyReadVolsLock.Unlock()
+	for volName := range newVols {
+		if !verifyReadVols[volName] {
+			log.LogInfof("action[updateVerifyReadVols] vol(%v) verifies reads", volName)
+		}
+	}
+	for volName := range verifyReadVols {
+		if !newVols[volName] {
+			log.LogInfof("action[updateVerifyReadVols] vol(%v) no longer verifies reads", volName)
+		}
+	}
+	verifyReadVols = newVols
+}
+
+func isVerifyReadVol(volName string) bool {
+	verifyReadVolsLock.RLock()
+	defer verifyReadVolsLock.RUnlock()
+	return verifyReadVols[volName]
+}
+
+// verifyRead checks the data read from the extent against the block crc if the volume verifies reads.
+func verifyRead(partition *DataPartition, extentID uint64, offset, size int64, data []byte) (err error) {
+	if !isVerifyReadVol(partition.volumeID) {
+		return
+	}
+	var verified int64
+	verified, err = partition.ExtentStore().VerifyRead(extentID, offset, size, data)
+	labels := map[string]string{"vol": partition.volumeID}
+	if err != nil {
+		log.LogErrorf("action[verifyRead] partition(%v) extent(%v) offset(%v) size(%v) err(%v)",
+			partition.partitionID, extentID, offset, size, err)
+		if isBlockCrcMismatch(err) {
+			exporter.NewCounter(MetricVerifiedReadErrors).AddWithLabels(1, labels)
+		}
+		return
+	}
+	exporter.NewCounter(MetricVerifiedReadBytes).AddWithLabels(verified, labels)
+	return
+}
+
+func isBlockCrcMismatch(err error) bool {
+	return err != nil && strings.Contains(err.Error(), storage.BlockCrcMismatchError.Error())
+}
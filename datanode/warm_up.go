@@ -0,0 +1,294 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ExtentHeatFileName       = "HEAT"
+	TempExtentHeatFileName   = ".heat"
+	DefaultWarmUpExtentCount = 1024
+	MaxPersistedExtentHeat   = 4096
+	DefaultWarmUpRate        = 100 // MB per second
+	WarmUpBlockSize          = util.MB
+)
+
+// extentHeat records the size of data read from the extents by the clients. The heat is halved
+// every time it is persisted, so that the recent reads weigh more than the old ones. The hottest
+// extents are persisted in the partition directory, so that they can be warmed up after restart.
+type extentHeat struct {
+	sync.Mutex
+	heat map[uint64]uint64
+}
+
+// partitionWarmUp records the progress of the last warm up of the partition.
+type partitionWarmUp struct {
+	sync.Mutex
+	status *proto.DataPartitionWarmUpStatus
+}
+
+func (dp *DataPartition) recordExtentRead(extentID uint64, size uint32) {
+	dp.heat.Lock()
+	if dp.heat.heat == nil {
+		dp.heat.heat = make(map[uint64]uint64)
+	}
+	dp.heat.heat[extentID] += uint64(size)
+	dp.heat.Unlock()
+}
+
+// hottestExtents returns at most count extents in descending order of heat.
+func (dp *DataPartition) hottestExtents(count int) (heats []*proto.ExtentHeat) {
+	dp.heat.Lock()
+	heats = make([]*proto.ExtentHeat, 0, len(dp.heat.heat))
+	for extentID, heat := range dp.heat.heat {
+		heats = append(heats, &proto.ExtentHeat{ExtentID: extentID, Heat: heat})
+	}
+	dp.heat.Unlock()
+	sort.Slice(heats, func(i, j int) bool {
+		if heats[i].Heat != heats[j].Heat {
+			return heats[i].Heat > heats[j].Heat
+		}
+		return heats[i].ExtentID < heats[j].ExtentID
+	})
+	if len(heats) > count {
+		heats = heats[:count]
+	}
+	return
+}
+
+// persistExtentHeat writes the hottest extents into the partition directory and decays the heat.
+func (dp *DataPartition) persistExtentHeat() (err error) {
+	heats := dp.hottestExtents(MaxPersistedExtentHeat)
+	dp.heat.Lock()
+	for extentID, heat := range dp.heat.heat {
+		if heat >>= 1; heat == 0 {
+			delete(dp.heat.heat, extentID)
+			continue
+		}
+		dp.heat.heat[extentID] = heat
+	}
+	dp.heat.Unlock()
+
+	var data []byte
+	if data, err = json.Marshal(heats); err != nil {
+		return
+	}
+	fileName := path.Join(dp.Path(), TempExtentHeatFileName)
+	if err = ioutil.WriteFile(fileName, data, 0644); err != nil {
+		return
+	}
+	defer os.Remove(fileName)
+	err = os.Rename(fileName, path.Join(dp.Path(), ExtentHeatFileName))
+	return
+}
+
+// loadExtentHeat restores the heat persisted before the data node restarts.
+func (dp *DataPartition) loadExtentHeat() (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path.Join(dp.Path(), ExtentHeatFileName)); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	heats := make([]*proto.ExtentHeat, 0)
+	if err = json.Unmarshal(data, &heats); err != nil {
+		return
+	}
+	dp.heat.Lock()
+	dp.heat.heat = make(map[uint64]uint64, len(heats))
+	for _, h := range heats {
+		dp.heat.heat[h.ExtentID] = h.Heat
+	}
+	dp.heat.Unlock()
+	return
+}
+
+func (dp *DataPartition) warmUpStatus() *proto.DataPartitionWarmUpStatus {
+	dp.warmUp.Lock()
+	defer dp.warmUp.Unlock()
+	if dp.warmUp.status == nil {
+		return &proto.DataPartitionWarmUpStatus{PartitionID: dp.partitionID}
+	}
+	status := *dp.warmUp.status
+	return &status
+}
+
+// startWarmUp pre-reads the specified extents, or the hottest extents if none is specified, into
+// the page cache in background. The reading is throttled by the node-wide warm up rate so that the
+// foreground requests are not starved. Tiny extents are shared by small files and never warmed up.
+func (dp *DataPartition) startWarmUp(req *proto.DataPartitionWarmUpRequest) (status *proto.DataPartitionWarmUpStatus, err error) {
+	extents := make([]uint64, 0)
+	if len(req.ExtentIDs) > 0 {
+		extents = append(extents, req.ExtentIDs...)
+	} else {
+		count := req.Count
+		if count <= 0 {
+			count = DefaultWarmUpExtentCount
+		}
+		for _, h := range dp.hottestExtents(count) {
+			extents = append(extents, h.ExtentID)
+		}
+	}
+
+	dp.warmUp.Lock()
+	if dp.warmUp.status != nil && dp.warmUp.status.Running {
+		dp.warmUp.Unlock()
+		return nil, fmt.Errorf("partition %v is warming up", dp.partitionID)
+	}
+	dp.warmUp.status = &proto.DataPartitionWarmUpStatus{
+		PartitionID: dp.partitionID,
+		Running:     true,
+		Extents:     len(extents),
+		StartTime:   time.Now().Unix(),
+	}
+	dp.warmUp.Unlock()
+
+	go dp.doWarmUp(extents)
+	return dp.warmUpStatus(), nil
+}
+
+func (dp *DataPartition) doWarmUp(extents []uint64) {
+	var err error
+	buf := make([]byte, WarmUpBlockSize)
+	for _, extentID := range extents {
+		select {
+		case <-dp.stopC:
+			err = fmt.Errorf("partition stopped")
+		default:
+		}
+		if err != nil {
+			break
+		}
+		if storage.IsTinyExtent(extentID) {
+			continue
+		}
+		var size uint64
+		if size, err = dp.warmUpExtent(extentID, buf); err != nil {
+			log.LogWarnf("action[doWarmUp] partition(%v) warm up extent(%v) err(%v)", dp.partitionID, extentID, err)
+			if os.IsNotExist(err) {
+				err = nil
+				continue
+			}
+			break
+		}
+		dp.warmUp.Lock()
+		dp.warmUp.status.WarmedExtents++
+		dp.warmUp.status.WarmedSize += size
+		dp.warmUp.Unlock()
+	}
+
+	dp.warmUp.Lock()
+	dp.warmUp.status.Running = false
+	dp.warmUp.status.EndTime = time.Now().Unix()
+	if err != nil {
+		dp.warmUp.status.Err = err.Error()
+	}
+	status := *dp.warmUp.status
+	dp.warmUp.Unlock()
+	log.LogInfof("action[doWarmUp] partition(%v) warmed up %v of %v extents size(%v) err(%v)",
+		dp.partitionID, status.WarmedExtents, status.Extents, status.WarmedSize, err)
+}
+
+// warmUpExtent reads the whole extent file, so that its data stays in the page cache.
+func (dp *DataPartition) warmUpExtent(extentID uint64, buf []byte) (size uint64, err error) {
+	var f *os.File
+	if f, err = os.Open(path.Join(dp.Path(), strconv.FormatUint(extentID, 10))); err != nil {
+		return
+	}
+	defer f.Close()
+	for {
+		var n int
+		n, err = f.Read(buf)
+		size += uint64(n)
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return
+		}
+		if err = warmUpLimiter.WaitN(context.Background(), n); err != nil {
+			return
+		}
+	}
+}
+
+func (s *DataNode) warmUpPartitionAPI(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramID = "id"
+	)
+	var (
+		partition *DataPartition
+		status    *proto.DataPartitionWarmUpStatus
+		err       error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		if err = r.ParseForm(); err != nil {
+			s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var partitionID uint64
+		if partitionID, err = strconv.ParseUint(r.FormValue(paramID), 10, 64); err != nil {
+			s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("parse param %v fail: %v", paramID, err))
+			return
+		}
+		if partition = s.space.Partition(partitionID); partition == nil {
+			s.buildFailureResp(w, http.StatusNotFound, "partition not exist")
+			return
+		}
+		s.buildSuccessResp(w, partition.warmUpStatus())
+	case http.MethodPost:
+		var body []byte
+		req := &proto.DataPartitionWarmUpRequest{}
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err = json.Unmarshal(body, req); err != nil {
+			s.buildFailureResp(w, http.StatusBadRequest, fmt.Sprintf("unmarshal request fail: %v", err))
+			return
+		}
+		if partition = s.space.Partition(req.PartitionID); partition == nil {
+			s.buildFailureResp(w, http.StatusNotFound, "partition not exist")
+			return
+		}
+		if status, err = partition.startWarmUp(req); err != nil {
+			s.buildFailureResp(w, http.StatusConflict, err.Error())
+			return
+		}
+		s.buildSuccessResp(w, status)
+	default:
+		s.buildFailureResp(w, http.StatusMethodNotAllowed, "only GET and POST are allowed")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var (
+	volWorms       = make(map[string]*proto.VolWorm)
+	volWormsLoaded bool
+	volWormsLock   sync.RWMutex
+)
+
+// updateVolWorms applies the retention policies of the volumes, which are sent by the master with the heartbeat.
+func updateVolWorms(worms map[string]*proto.VolWorm) {
+	if worms == nil {
+		worms = make(map[string]*proto.VolWorm)
+	}
+	volWormsLock.Lock()
+	defer volWormsLock.Unlock()
+	for volName, worm := range worms {
+		if old, ok := volWorms[volName]; !ok || *old != *worm {
+			log.LogInfof("action[updateVolWorms] vol(%v) retention(%vs) override until(%v)",
+				volName, worm.RetentionSeconds, worm.OverrideUntil)
+		}
+	}
+	volWorms = worms
+	volWormsLoaded = true
+}
+
+// updatePartitionWorms marks the partitions of the volumes having a retention policy, which are refused to be
+// overwritten until the policies are received from the master after a restart.
+func (manager *SpaceManager) updatePartitionWorms(worms map[string]*proto.VolWorm) {
+	manager.RangePartitions(func(dp *DataPartition) bool {
+		_, worm := worms[dp.volumeID]
+		dp.updateWorm(worm)
+		return true
+	})
+}
+
+func (dp *DataPartition) isWorm() bool {
+	return atomic.LoadInt32(&dp.worm) == 1
+}
+
+func (dp *DataPartition) updateWorm(worm bool) {
+	var value int32
+	if worm {
+		value = 1
+	}
+	if atomic.SwapInt32(&dp.worm, value) == value {
+		return
+	}
+	if err := dp.PersistMetadata(); err != nil {
+		log.LogErrorf("action[updateWorm] dp(%v) worm(%v) persist metadata err(%v)", dp.partitionID, worm, err)
+	}
+}
+
+func getVolWorm(volName string) (worm *proto.VolWorm, loaded bool) {
+	volWormsLock.RLock()
+	defer volWormsLock.RUnlock()
+	return volWorms[volName], volWormsLoaded
+}
+
+// checkWorm refuses the write which overwrites the data in the retention period of the volume.
+// The data node knows neither the files nor their create time, so the data of a normal extent is
+// protected until the retention period has passed since the extent was written last, which is never
+// shorter than the retention of the file as the data is written after the file is created. A tiny
+// extent holds the data of many files, so its overwrites are refused as long as the volume has a
+// retention policy. Until the policies are received from the master, the writes are refused only
+// if the partition was marked as of a volume with a retention policy before.
+func checkWorm(partition *DataPartition, p *repl.Packet) (err error) {
+	worm, loaded := getVolWorm(partition.volumeID)
+	if !loaded {
+		if !partition.isWorm() {
+			return
+		}
+		return fmt.Errorf("%v: retention policy of vol(%v) is not loaded", storage.TryAgainError, partition.volumeID)
+	}
+	if worm == nil {
+		return
+	}
+	now := time.Now().Unix()
+	if !storage.IsTinyExtent(p.ExtentID) {
+		var ei *storage.ExtentInfo
+		if ei, err = partition.ExtentStore().Watermark(p.ExtentID); err != nil {
+			// the missing extent is left to the write itself
+			return nil
+		}
+		if ei.ModifyTime > 0 && ei.ModifyTime+worm.RetentionSeconds <= now {
+			return
+		}
+	}
+	if worm.OverrideUntil > now {
+		log.LogWarnf("action[checkWorm] audit: vol(%v) dp(%v) extent(%v) offset(%v) size(%v) allowed by retention override, req(%v)",
+			partition.volumeID, partition.partitionID, p.ExtentID, p.ExtentOffset, p.Size, p.ReqID)
+		return
+	}
+	err = fmt.Errorf("%v: extent(%v) of vol(%v) is in the retention period", storage.NotPermittedError, p.ExtentID, partition.volumeID)
+	log.LogWarnf("action[checkWorm] dp(%v) req(%v) refused: %v", partition.partitionID, p.ReqID, err)
+	return
+}
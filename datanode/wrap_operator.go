@@ -33,14 +33,20 @@ import (
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
+	"github.com/chubaofs/chubaofs/util/tracing"
 	"github.com/tiglabs/raft"
 	raftProto "github.com/tiglabs/raft/proto"
 )
 
-func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
+func (s *DataNode) OperatePacket(p *repl.Packet, c net.Conn) (err error) {
 	sz := p.Size
 	tpObject := exporter.NewTPCnt(p.GetOpMsg())
+	span := tracing.StartSpan(p.GetOpMsg(), tracing.SpanKindServer, p.Trace)
+	span.SetAttribute(tracing.AttributePeer, c.RemoteAddr().String())
+	span.SetAttribute(tracing.AttributePartitionID, p.PartitionID)
 	start := time.Now().UnixNano()
+	defer countInflightWrite(p)()
 	defer func() {
 		resultSize := p.Size
 		p.Size = sz
@@ -64,7 +70,20 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		}
 		p.Size = resultSize
 		tpObject.Set(err)
+		if latency := time.Duration(time.Now().UnixNano() - start); slowop.IsSlow(p.GetOpMsg(), latency) {
+			s.recordSlowOp(p, c, latency)
+		}
+		span.SetAttribute(tracing.AttributeResult, p.GetResultMsg())
+		span.Finish(err)
 	}()
+	if e := waitQos(p, c); e != nil {
+		s.handleQosThrottled(p, c, e)
+		return
+	}
+	if p.DeadlineExceeded() {
+		s.handleDeadlineExceeded(p, c)
+		return
+	}
 	switch p.Opcode {
 	case proto.OpCreateExtent:
 		s.handlePacketToCreateExtent(p)
@@ -82,6 +101,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		s.handleMarkDeletePacket(p, c)
 	case proto.OpBatchDeleteExtent:
 		s.handleBatchMarkDeletePacket(p, c)
+	case proto.OpSealExtents:
+		s.handleSealExtentsPacket(p)
 	case proto.OpRandomWrite, proto.OpSyncRandomWrite:
 		s.handleRandomWritePacket(p)
 	case proto.OpNotifyReplicasToRepair:
@@ -106,6 +127,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		s.handlePacketToRemoveDataPartitionRaftMember(p)
 	case proto.OpDataPartitionTryToLeader:
 		s.handlePacketToDataPartitionTryToLeaderrr(p)
+	case proto.OpResetDataPartitionRaftMember:
+		s.handlePacketToResetDataPartitionRaftMember(p)
 	case proto.OpGetPartitionSize:
 		s.handlePacketToGetPartitionSize(p)
 	case proto.OpGetMaxExtentIDAndPartitionSize:
@@ -114,6 +137,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		s.handlePacketToReadTinyDeleteRecordFile(p, c)
 	case proto.OpBroadcastMinAppliedID:
 		s.handleBroadcastMinAppliedID(p)
+	case proto.OpDataNodeClientThrottle:
+		s.handlePacketToSetClientThrottle(p)
 	default:
 		p.PackErrorBody(repl.ErrorUnknownOp.Error(), repl.ErrorUnknownOp.Error()+strconv.Itoa(int(p.Opcode)))
 	}
@@ -166,6 +191,10 @@ func (s *DataNode) handlePacketToCreateDataPartition(p *repl.Packet) {
 		err = fmt.Errorf("from master Task(%v) failed,error unavali opcode(%v)", task.ToString(), task.OpCode)
 		return
 	}
+	if s.isDraining() {
+		err = ErrDataNodeDraining
+		return
+	}
 
 	bytes, err = json.Marshal(task.Request)
 	if err != nil {
@@ -206,11 +235,19 @@ func (s *DataNode) handleHeartbeatPacket(p *repl.Packet) {
 	go func() {
 		request := &proto.HeartBeatRequest{}
 		response := &proto.DataNodeHeartbeatResponse{}
+		draining := s.isDraining()
 		s.buildHeartBeatResponse(response)
 
 		if task.OpCode == proto.OpDataNodeHeartbeat {
 			marshaled, _ := json.Marshal(task.Request)
 			_ = json.Unmarshal(marshaled, request)
+			updateVolQos(request.VolQos)
+			updateRepairBandwidth(request.RepairBandwidth)
+			updateRebuildBandwidth(request.RebuildBandwidth)
+			updateVerifyReadVols(request.VerifyReadVols)
+			updateVolWorms(request.VolWorms)
+			s.space.updatePartitionWorms(request.VolWorms)
+			updateClientThrottles(request.ClientThrottles)
 			response.Status = proto.TaskSucceeds
 		} else {
 			response.Status = proto.TaskFailed
@@ -223,10 +260,47 @@ func (s *DataNode) handleHeartbeatPacket(p *repl.Packet) {
 			log.LogErrorf(err.Error())
 			return
 		}
+		if draining {
+			s.markDrainReported()
+		}
 	}()
 
 }
 
+// Handle OpDataNodeClientThrottle packet.
+func (s *DataNode) handlePacketToSetClientThrottle(p *repl.Packet) {
+	var (
+		err   error
+		reply []byte
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionSetClientThrottle, err.Error())
+		}
+	}()
+	task := &proto.AdminTask{}
+	if err = json.Unmarshal(p.Data, task); err != nil {
+		return
+	}
+	if task.OpCode != proto.OpDataNodeClientThrottle {
+		err = fmt.Errorf("illegal opcode")
+		return
+	}
+	request := &proto.ClientThrottleRequest{}
+	bytes, _ := json.Marshal(task.Request)
+	p.AddMesgLog(string(bytes))
+	if err = json.Unmarshal(bytes, request); err != nil {
+		return
+	}
+	if err = setClientThrottle(request.ClientIP, request.MaxIOPS, request.MaxBandwidth); err != nil {
+		return
+	}
+	if reply, err = json.Marshal(listClientThrottles()); err != nil {
+		return
+	}
+	p.PacketOkWithBody(reply)
+}
+
 // Handle OpDeleteDataPartition packet.
 func (s *DataNode) handlePacketToDeleteDataPartition(p *repl.Packet) {
 	task := &proto.AdminTask{}
@@ -333,11 +407,13 @@ func (s *DataNode) handleMarkDeletePacket(p *repl.Packet, c net.Conn) {
 			log.LogInfof("handleMarkDeletePacket Delete PartitionID(%v)_Extent(%v)_Offset(%v)_Size(%v)",
 				p.PartitionID, p.ExtentID, ext.ExtentOffset, ext.Size)
 			partition.ExtentStore().MarkDelete(p.ExtentID, int64(ext.ExtentOffset), int64(ext.Size))
+			partition.dropSealedExtent(p.ExtentID, ext.ExtentOffset, uint64(ext.Size))
 		}
 	} else {
 		log.LogInfof("handleMarkDeletePacket Delete PartitionID(%v)_Extent(%v)",
 			p.PartitionID, p.ExtentID)
 		partition.ExtentStore().MarkDelete(p.ExtentID, 0, 0)
+		partition.dropSealedExtent(p.ExtentID, 0, 0)
 	}
 
 	return
@@ -365,12 +441,38 @@ func (s *DataNode) handleBatchMarkDeletePacket(p *repl.Packet, c net.Conn) {
 			DeleteLimiterWait()
 			log.LogInfof(fmt.Sprintf("recive DeleteExtent (%v) from (%v)", ext, c.RemoteAddr().String()))
 			store.MarkDelete(ext.ExtentId, int64(ext.ExtentOffset), int64(ext.Size))
+			if storage.IsTinyExtent(ext.ExtentId) {
+				partition.dropSealedExtent(ext.ExtentId, ext.ExtentOffset, uint64(ext.Size))
+			} else {
+				partition.dropSealedExtent(ext.ExtentId, 0, 0)
+			}
 		}
 	}
 
 	return
 }
 
+// Handle OpSealExtents packet.
+func (s *DataNode) handleSealExtentsPacket(p *repl.Packet) {
+	var err error
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionSealExtents, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	partition := p.Object.(*DataPartition)
+	req := &proto.SealExtentsRequest{}
+	if err = json.Unmarshal(p.Data[:p.Size], req); err != nil {
+		return
+	}
+	if err = partition.sealExtents(req); err != nil {
+		return
+	}
+	log.LogInfof("handleSealExtentsPacket PartitionID(%v) unseal(%v) extents(%v)", p.PartitionID, req.Unseal, req.Extents)
+}
+
 // Handle OpWrite packet.
 func (s *DataNode) handleWritePacket(p *repl.Packet) {
 	var err error
@@ -464,6 +566,9 @@ func (s *DataNode) handleStreamReadPacket(p *repl.Packet, connect net.Conn, isRe
 	if err = partition.CheckLeader(p, connect); err != nil {
 		return
 	}
+	if !isRepairRead {
+		partition.recordExtentRead(p.ExtentID, p.Size)
+	}
 	s.extentRepairReadPacket(p, connect, isRepairRead)
 
 	return
@@ -493,6 +598,42 @@ func (s *DataNode) handleTinyExtentRepairReadPacket(p *repl.Packet, connect net.
 	s.tinyExtentRepairRead(p, connect)
 }
 
+// handleDeadlineExceeded fails the request fast instead of processing it, since the client
+// has stopped waiting for the response.
+func (s *DataNode) handleDeadlineExceeded(p *repl.Packet, c net.Conn) {
+	exporter.NewCounter(MetricDeadlineExceeded).Add(1)
+	p.PackErrorBody(ActionCheckDeadline, proto.ErrDeadlineExceeded.Error())
+	if p.IsReadOperation() {
+		// the responses of the read requests are written by the operators themselves
+		_ = p.WriteToConn(c)
+	}
+}
+
+// handleQosThrottled refuses the request which would wait too long for the tokens, the client retries it later.
+func (s *DataNode) handleQosThrottled(p *repl.Packet, c net.Conn, err error) {
+	p.PackErrorBody(ActionCheckQos, err.Error())
+	if p.IsReadOperation() {
+		// the responses of the read requests are written by the operators themselves
+		_ = p.WriteToConn(c)
+	}
+}
+
+// recordSlowOp records the operation with the disk of the partition, so that a slow disk stands out
+// in the slow operations of the cluster.
+func (s *DataNode) recordSlowOp(p *repl.Packet, c net.Conn, latency time.Duration) {
+	op := &proto.SlowOp{
+		Op:          p.GetOpMsg(),
+		PartitionID: p.PartitionID,
+		Peer:        c.RemoteAddr().String(),
+		LatencyUs:   int64(latency / time.Microsecond),
+		Result:      p.GetResultMsg(),
+	}
+	if dp, ok := p.Object.(*DataPartition); ok && dp.Disk() != nil {
+		op.Disk = dp.Disk().Path
+	}
+	slowop.Record(op)
+}
+
 func (s *DataNode) extentRepairReadPacket(p *repl.Packet, connect net.Conn, isRepairRead bool) {
 	var (
 		err error
@@ -512,21 +653,45 @@ func (s *DataNode) extentRepairReadPacket(p *repl.Packet, connect net.Conn, isRe
 		if needReplySize <= 0 {
 			break
 		}
+		if p.DeadlineExceeded() {
+			// stop sending the rest of the data which the client will not receive
+			exporter.NewCounter(MetricDeadlineExceeded).Add(1)
+			err = proto.ErrDeadlineExceeded
+			return
+		}
 		err = nil
 		reply := repl.NewStreamReadResponsePacket(p.ReqID, p.PartitionID, p.ExtentID)
 		reply.StartT = p.StartT
 		currReadSize := uint32(util.Min(int(needReplySize), util.ReadBlockSize))
+		tpObject := exporter.NewTPCnt(p.GetOpMsg())
+		reply.ExtentOffset = offset
+		reply.Opcode = p.Opcode
+		p.Size = uint32(currReadSize)
+		p.ExtentOffset = offset
+		var sent bool
+		if sent, err = s.zeroCopyRead(partition, reply, currReadSize, connect); sent {
+			tpObject.Set(err)
+			p.CRC = reply.CRC
+			if err != nil {
+				return
+			}
+			p.ResultCode = proto.OpOk
+			needReplySize -= currReadSize
+			offset += int64(currReadSize)
+			log.LogReadf(fmt.Sprintf("action[operatePacket] %v.",
+				reply.LogMessage(reply.GetOpMsg(), connect.RemoteAddr().String(), reply.StartT, err)))
+			continue
+		}
 		if currReadSize == util.ReadBlockSize {
 			reply.Data, _ = proto.Buffers.Get(util.ReadBlockSize)
 		} else {
 			reply.Data = make([]byte, currReadSize)
 		}
-		tpObject := exporter.NewTPCnt(p.GetOpMsg())
-		reply.ExtentOffset = offset
-		p.Size = uint32(currReadSize)
-		p.ExtentOffset = offset
 		reply.CRC, err = store.Read(reply.ExtentID, offset, int64(currReadSize), reply.Data, isRepairRead)
 		partition.checkIsDiskError(err)
+		if err == nil && !isRepairRead {
+			err = verifyRead(partition, reply.ExtentID, offset, int64(currReadSize), reply.Data)
+		}
 		tpObject.Set(err)
 		p.CRC = reply.CRC
 		if err != nil {
@@ -691,7 +856,7 @@ func (s *DataNode) tinyExtentRepairRead(request *repl.Packet, connect net.Conn)
 	return
 }
 
-func (s *DataNode) handlePacketToReadTinyDeleteRecordFile(p *repl.Packet, connect *net.TCPConn) {
+func (s *DataNode) handlePacketToReadTinyDeleteRecordFile(p *repl.Packet, connect net.Conn) {
 	var (
 		err error
 	)
@@ -750,6 +915,7 @@ func (s *DataNode) handlePacketToNotifyExtentRepair(p *repl.Packet) {
 		p.PackErrorBody(ActionRepair, err.Error())
 		return
 	}
+	partition.mergeSealedExtents(mf.LeaderSealedExtents)
 	partition.DoExtentStoreRepair(mf)
 	p.PacketOkReply()
 	return
@@ -977,6 +1143,48 @@ func (s *DataNode) handlePacketToRemoveDataPartitionRaftMember(p *repl.Packet) {
 	return
 }
 
+// Handle OpResetDataPartitionRaftMember packet.
+func (s *DataNode) handlePacketToResetDataPartitionRaftMember(p *repl.Packet) {
+	var (
+		err     error
+		reqData []byte
+		req     = &proto.ResetDataPartitionRaftMemberRequest{}
+	)
+
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionResetDataPartitionRaftMember, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+
+	adminTask := &proto.AdminTask{}
+	decode := json.NewDecoder(bytes.NewBuffer(p.Data))
+	decode.UseNumber()
+	if err = decode.Decode(adminTask); err != nil {
+		return
+	}
+
+	reqData, err = json.Marshal(adminTask.Request)
+	p.AddMesgLog(string(reqData))
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(reqData, req); err != nil {
+		return
+	}
+
+	dp := s.space.Partition(req.PartitionId)
+	if dp == nil {
+		err = fmt.Errorf("partition(%v) not exist", req.PartitionId)
+		return
+	}
+	p.PartitionID = req.PartitionId
+	log.LogWarnf("handlePacketToResetDataPartitionRaftMember recive MasterCommand: %v", string(reqData))
+	err = dp.resetRaftMembers(req.NewPeers)
+}
+
 func (s *DataNode) handlePacketToDataPartitionTryToLeaderrr(p *repl.Packet) {
 	var (
 		err error
@@ -999,6 +1207,10 @@ func (s *DataNode) handlePacketToDataPartitionTryToLeaderrr(p *repl.Packet) {
 	if dp.raftPartition.IsRaftLeader() {
 		return
 	}
+	if s.isDraining() {
+		err = ErrDataNodeDraining
+		return
+	}
 	err = dp.raftPartition.TryToLeader(dp.partitionID)
 	return
 }
@@ -45,6 +45,9 @@ func (s *DataNode) Prepare(p *repl.Packet) (err error) {
 	if err = s.checkPartition(p); err != nil {
 		return
 	}
+	if err = checkOverwrite(p); err != nil {
+		return
+	}
 
 	// For certain packet, we meed to add some additional extent information.
 	if err = s.addExtentInfo(p); err != nil {
@@ -85,6 +88,10 @@ func (s *DataNode) checkPartition(p *repl.Packet) (err error) {
 			err = storage.NoSpaceError
 			return
 		}
+		if p.IsLeaderPacket() && s.isRejectingWrites() {
+			err = ErrDataNodeDraining
+			return
+		}
 	}
 	return
 }
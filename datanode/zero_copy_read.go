@@ -0,0 +1,64 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"net"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/exporter"
+)
+
+const (
+	MetricZeroCopyReadBytes = "zeroCopyReadBytes"
+)
+
+// zeroCopyRead sends a whole block of a normal extent from the extent file to the connection by sendfile, with the
+// crc recorded of the block, so the data is not copied through the user space. It returns false without sending
+// anything if the block cannot be sent so, e.g. the read is unaligned or the block is not written as a whole, in
+// which case the block is read as usual.
+// The block may be overwritten after its crc is taken, in which case the client finds the crc mismatched and reads
+// the block again.
+func (s *DataNode) zeroCopyRead(partition *DataPartition, reply *repl.Packet, size uint32, connect net.Conn) (sent bool, err error) {
+	if !sendfileSupported || s.disableZeroCopyRead || storage.IsTinyExtent(reply.ExtentID) ||
+		size != util.BlockSize || reply.ExtentOffset%util.BlockSize != 0 || isVerifyReadVol(partition.volumeID) {
+		return
+	}
+	conn, ok := connect.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	file, crc, e := partition.ExtentStore().BlockFile(reply.ExtentID, reply.ExtentOffset)
+	if e != nil || crc == 0 {
+		// the error is reported by the read instead
+		return
+	}
+
+	reply.CRC = crc
+	reply.Size = size
+	reply.ResultCode = proto.OpOk
+	if err = reply.WriteHeaderToConn(conn); err != nil {
+		return true, err
+	}
+	if err = sendfile(conn, file, reply.ExtentOffset, util.BlockSize); err != nil {
+		partition.checkIsDiskError(err)
+		return true, err
+	}
+	exporter.NewCounter(MetricZeroCopyReadBytes).Add(util.BlockSize)
+	return true, nil
+}
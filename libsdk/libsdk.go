@@ -0,0 +1,745 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package main builds libcfs, the C library the Hadoop FileSystem shim and the other non-Go clients access
+// the volumes with:
+//
+//	go build -buildmode=c-shared -o libcfs.so github.com/chubaofs/chubaofs/libsdk
+//
+// The functions return zero or a non-negative value on success and the negated errno on failure.
+package main
+
+/*
+#include <stdint.h>
+#include <sys/types.h>
+
+#define CFS_NAME_MAX  256
+#define CFS_MAX_HOSTS 8
+#define CFS_HOST_MAX  64
+
+#define CFS_ATTR_MODE  0x01
+#define CFS_ATTR_UID   0x02
+#define CFS_ATTR_GID   0x04
+#define CFS_ATTR_MTIME 0x08
+#define CFS_ATTR_ATIME 0x10
+
+struct cfs_stat_info {
+	uint64_t ino;
+	uint64_t size;
+	uint64_t blocks;
+	uint64_t atime;
+	uint64_t mtime;
+	uint64_t ctime;
+	uint32_t mode;
+	uint32_t nlink;
+	uint32_t blk_size;
+	uint32_t uid;
+	uint32_t gid;
+};
+
+struct cfs_file_status {
+	char name[CFS_NAME_MAX];
+	struct cfs_stat_info stat;
+};
+
+struct cfs_block_location {
+	int64_t offset;
+	int64_t length;
+	int32_t num_hosts;
+	char hosts[CFS_MAX_HOSTS][CFS_HOST_MAX];
+};
+*/
+import "C"
+
+import (
+	"net"
+	"os"
+	gopath "path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration keys of cfs_set_client.
+const (
+	keyVolName      = "volName"
+	keyMasterAddr   = "masterAddr"
+	keyOwner        = "owner"
+	keyFollowerRead = "followerRead"
+	keyLogDir       = "logDir"
+	keyLogLevel     = "logLevel"
+)
+
+const (
+	loggerPrefix = "libcfs"
+
+	// the block size reported to the applications, which is the maximum size of an extent
+	blockSize = util.ExtentSize
+
+	maxListCount     = 1 << 20
+	maxLocationCount = 1 << 20
+)
+
+var (
+	clients      = make(map[int64]*client)
+	clientLock   sync.Mutex
+	nextClientID int64
+)
+
+func main() {}
+
+type file struct {
+	ino   uint64
+	flags int
+}
+
+type client struct {
+	volName      string
+	masters      []string
+	owner        string
+	followerRead bool
+	logDir       string
+	logLevel     string
+
+	mw *meta.MetaWrapper
+	ec *stream.ExtentClient
+
+	files    map[int]*file
+	nextFd   int
+	fileLock sync.Mutex
+}
+
+func getClient(id C.int64_t) *client {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	return clients[int64(id)]
+}
+
+// errno returns the negated errno of the error.
+func errno(err error) C.int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(syscall.Errno); ok {
+		return -C.int(e)
+	}
+	return -C.int(syscall.EIO)
+}
+
+//export cfs_new_client
+func cfs_new_client() C.int64_t {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	nextClientID++
+	clients[nextClientID] = &client{files: make(map[int]*file)}
+	return C.int64_t(nextClientID)
+}
+
+//export cfs_set_client
+func cfs_set_client(id C.int64_t, key, val *C.char) C.int {
+	c := getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	v := C.GoString(val)
+	switch C.GoString(key) {
+	case keyVolName:
+		c.volName = v
+	case keyMasterAddr:
+		c.masters = strings.Split(v, ",")
+	case keyOwner:
+		c.owner = v
+	case keyFollowerRead:
+		followerRead, err := strconv.ParseBool(v)
+		if err != nil {
+			return -C.int(syscall.EINVAL)
+		}
+		c.followerRead = followerRead
+	case keyLogDir:
+		c.logDir = v
+	case keyLogLevel:
+		c.logLevel = v
+	default:
+		return -C.int(syscall.EINVAL)
+	}
+	return 0
+}
+
+//export cfs_start_client
+func cfs_start_client(id C.int64_t) C.int {
+	c := getClient(id)
+	if c == nil || c.volName == "" || len(c.masters) == 0 || c.mw != nil {
+		return -C.int(syscall.EINVAL)
+	}
+	if c.logDir != "" {
+		level := log.WarnLevel
+		switch strings.ToLower(c.logLevel) {
+		case "debug":
+			level = log.DebugLevel
+		case "info":
+			level = log.InfoLevel
+		case "error":
+			level = log.ErrorLevel
+		}
+		if _, err := log.InitLog(c.logDir, loggerPrefix, level, nil); err != nil {
+			return errno(err)
+		}
+	}
+	mw, err := meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:        c.volName,
+		Owner:         c.owner,
+		Masters:       c.masters,
+		ValidateOwner: c.owner != "",
+	})
+	if err != nil {
+		log.LogErrorf("cfs_start_client: new meta wrapper vol(%v) err(%v)", c.volName, err)
+		return -C.int(syscall.EIO)
+	}
+	ec, err := stream.NewExtentClient(&stream.ExtentConfig{
+		Volume:             c.volName,
+		Masters:            c.masters,
+		FollowerRead:       c.followerRead,
+		OnAppendExtentKey:  mw.AppendExtentKey,
+		OnGetExtents:       mw.GetExtents,
+		OnTruncate:         mw.Truncate,
+		OnUpdateModifyTime: mw.UpdateModifyTime,
+	})
+	if err != nil {
+		log.LogErrorf("cfs_start_client: new extent client vol(%v) err(%v)", c.volName, err)
+		mw.Close()
+		return -C.int(syscall.EIO)
+	}
+	c.mw, c.ec = mw, ec
+	return 0
+}
+
+//export cfs_close_client
+func cfs_close_client(id C.int64_t) {
+	clientLock.Lock()
+	c := clients[int64(id)]
+	delete(clients, int64(id))
+	clientLock.Unlock()
+	if c == nil || c.mw == nil {
+		return
+	}
+	c.fileLock.Lock()
+	for fd, f := range c.files {
+		c.ec.CloseStream(f.ino)
+		delete(c.files, fd)
+	}
+	c.fileLock.Unlock()
+	c.ec.Close()
+	c.mw.Close()
+}
+
+// splitPath returns the components of the absolute path.
+func splitPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, syscall.EINVAL
+	}
+	path = gopath.Clean(path)
+	if path == "/" {
+		return nil, nil
+	}
+	return strings.Split(path[1:], "/"), nil
+}
+
+func (c *client) lookupPath(path string) (ino uint64, err error) {
+	components, err := splitPath(path)
+	if err != nil {
+		return
+	}
+	ino = proto.RootIno
+	for _, name := range components {
+		if ino, _, err = c.mw.Lookup_ll(ino, name); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// lookupParent returns the parent directory and the name of the path, which must not be the root.
+func (c *client) lookupParent(path string) (parent uint64, name string, err error) {
+	components, err := splitPath(path)
+	if err != nil {
+		return
+	}
+	if len(components) == 0 {
+		return 0, "", syscall.EINVAL
+	}
+	parent, err = c.lookupPath("/" + strings.Join(components[:len(components)-1], "/"))
+	return parent, components[len(components)-1], err
+}
+
+// unixMode converts the mode of an inode to the mode of stat(2).
+func unixMode(mode uint32) uint32 {
+	osMode := proto.OsMode(mode)
+	m := uint32(osMode.Perm())
+	switch {
+	case osMode.IsDir():
+		m |= syscall.S_IFDIR
+	case osMode&os.ModeSymlink != 0:
+		m |= syscall.S_IFLNK
+	default:
+		m |= syscall.S_IFREG
+	}
+	if osMode&os.ModeSetuid != 0 {
+		m |= syscall.S_ISUID
+	}
+	if osMode&os.ModeSetgid != 0 {
+		m |= syscall.S_ISGID
+	}
+	if osMode&os.ModeSticky != 0 {
+		m |= syscall.S_ISVTX
+	}
+	return m
+}
+
+func (c *client) fillStat(info *proto.InodeInfo, stat *C.struct_cfs_stat_info) {
+	size := info.Size
+	if s, _, valid := c.ec.FileSize(info.Inode); valid {
+		size = uint64(s)
+	}
+	stat.ino = C.uint64_t(info.Inode)
+	stat.size = C.uint64_t(size)
+	stat.blocks = C.uint64_t((size + 511) >> 9)
+	stat.atime = C.uint64_t(info.AccessTime.Unix())
+	stat.mtime = C.uint64_t(info.ModifyTime.Unix())
+	stat.ctime = C.uint64_t(info.CreateTime.Unix())
+	stat.mode = C.uint32_t(unixMode(info.Mode))
+	stat.nlink = C.uint32_t(info.Nlink)
+	stat.blk_size = C.uint32_t(blockSize)
+	stat.uid = C.uint32_t(info.Uid)
+	stat.gid = C.uint32_t(info.Gid)
+}
+
+//export cfs_getattr
+func cfs_getattr(id C.int64_t, path *C.char, stat *C.struct_cfs_stat_info) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	ino, err := c.lookupPath(C.GoString(path))
+	if err != nil {
+		return errno(err)
+	}
+	info, err := c.mw.InodeGet_ll(ino)
+	if err != nil {
+		return errno(err)
+	}
+	c.fillStat(info, stat)
+	return 0
+}
+
+//export cfs_setattr
+func cfs_setattr(id C.int64_t, path *C.char, stat *C.struct_cfs_stat_info, valid C.int) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	ino, err := c.lookupPath(C.GoString(path))
+	if err != nil {
+		return errno(err)
+	}
+	var mode uint32
+	if valid&C.CFS_ATTR_MODE != 0 {
+		info, err := c.mw.InodeGet_ll(ino)
+		if err != nil {
+			return errno(err)
+		}
+		osMode := proto.OsModeType(info.Mode) | os.FileMode(stat.mode&0777)
+		mode = proto.Mode(osMode)
+	}
+	// the bits of CFS_ATTR_* are those of the meta SDK
+	err = c.mw.Setattr(ino, uint32(valid), mode, uint32(stat.uid), uint32(stat.gid), int64(stat.atime), int64(stat.mtime))
+	return errno(err)
+}
+
+//export cfs_open
+func cfs_open(id C.int64_t, path *C.char, flags C.int, mode C.mode_t) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	p := C.GoString(path)
+	fl := int(flags)
+	ino, err := c.lookupPath(p)
+	switch {
+	case err == syscall.ENOENT && fl&os.O_CREATE != 0:
+		var parent uint64
+		var name string
+		if parent, name, err = c.lookupParent(p); err != nil {
+			return errno(err)
+		}
+		var info *proto.InodeInfo
+		if info, err = c.mw.Create_ll(parent, name, proto.Mode(os.FileMode(mode&0777)), 0, 0, nil); err != nil {
+			return errno(err)
+		}
+		ino = info.Inode
+	case err != nil:
+		return errno(err)
+	case fl&os.O_CREATE != 0 && fl&os.O_EXCL != 0:
+		return -C.int(syscall.EEXIST)
+	default:
+		info, err := c.mw.InodeGet_ll(ino)
+		if err != nil {
+			return errno(err)
+		}
+		if proto.IsDir(info.Mode) && fl&(os.O_WRONLY|os.O_RDWR) != 0 {
+			return -C.int(syscall.EISDIR)
+		}
+	}
+
+	if err = c.ec.OpenStream(ino); err != nil {
+		return errno(err)
+	}
+	if fl&os.O_TRUNC != 0 && fl&(os.O_WRONLY|os.O_RDWR) != 0 {
+		if err = c.ec.Truncate(ino, 0); err != nil {
+			c.ec.CloseStream(ino)
+			return errno(err)
+		}
+	}
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+	c.nextFd++
+	c.files[c.nextFd] = &file{ino: ino, flags: fl}
+	return C.int(c.nextFd)
+}
+
+func (c *client) getFile(fd C.int) *file {
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+	return c.files[int(fd)]
+}
+
+//export cfs_flush
+func cfs_flush(id C.int64_t, fd C.int) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	f := c.getFile(fd)
+	if f == nil {
+		return -C.int(syscall.EBADF)
+	}
+	return errno(c.ec.Flush(f.ino))
+}
+
+//export cfs_close
+func cfs_close(id C.int64_t, fd C.int) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	c.fileLock.Lock()
+	f := c.files[int(fd)]
+	delete(c.files, int(fd))
+	c.fileLock.Unlock()
+	if f == nil {
+		return -C.int(syscall.EBADF)
+	}
+	return errno(c.ec.CloseStream(f.ino))
+}
+
+//export cfs_write
+func cfs_write(id C.int64_t, fd C.int, buf unsafe.Pointer, size C.size_t, off C.off_t) C.ssize_t {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.ssize_t(syscall.EINVAL)
+	}
+	f := c.getFile(fd)
+	if f == nil {
+		return -C.ssize_t(syscall.EBADF)
+	}
+	if f.flags&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return -C.ssize_t(syscall.EBADF)
+	}
+	offset := int(off)
+	if f.flags&os.O_APPEND != 0 {
+		if s, _, valid := c.ec.FileSize(f.ino); valid {
+			offset = s
+		}
+	}
+	data := C.GoBytes(buf, C.int(size))
+	n, err := c.ec.Write(f.ino, offset, data, 0)
+	if err != nil {
+		return C.ssize_t(errno(err))
+	}
+	return C.ssize_t(n)
+}
+
+//export cfs_read
+func cfs_read(id C.int64_t, fd C.int, buf unsafe.Pointer, size C.size_t, off C.off_t) C.ssize_t {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.ssize_t(syscall.EINVAL)
+	}
+	f := c.getFile(fd)
+	if f == nil {
+		return -C.ssize_t(syscall.EBADF)
+	}
+	data := (*[1 << 30]byte)(buf)[:size:size]
+	n, err := c.ec.Read(f.ino, data, int(off), int(size))
+	if err != nil && n <= 0 {
+		return C.ssize_t(errno(err))
+	}
+	return C.ssize_t(n)
+}
+
+//export cfs_mkdirs
+func cfs_mkdirs(id C.int64_t, path *C.char, mode C.mode_t) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	components, err := splitPath(C.GoString(path))
+	if err != nil {
+		return errno(err)
+	}
+	ino := proto.RootIno
+	for _, name := range components {
+		child, childMode, err := c.mw.Lookup_ll(ino, name)
+		if err == syscall.ENOENT {
+			var info *proto.InodeInfo
+			info, err = c.mw.Create_ll(ino, name, proto.Mode(os.ModeDir|os.FileMode(mode&0777)), 0, 0, nil)
+			if err == syscall.EEXIST {
+				// created by another client at the same time
+				child, childMode, err = c.mw.Lookup_ll(ino, name)
+			} else if err == nil {
+				child, childMode = info.Inode, info.Mode
+			}
+		}
+		if err != nil {
+			return errno(err)
+		}
+		if !proto.IsDir(childMode) {
+			return -C.int(syscall.ENOTDIR)
+		}
+		ino = child
+	}
+	return 0
+}
+
+// delete removes the entry, the directories are removed with all their entries if recursive.
+func (c *client) delete(parent uint64, name string, recursive bool) error {
+	ino, mode, err := c.mw.Lookup_ll(parent, name)
+	if err != nil {
+		return err
+	}
+	isDir := proto.IsDir(mode)
+	if isDir && recursive {
+		dentries, err := c.mw.ReadDir_ll(ino)
+		if err != nil {
+			return err
+		}
+		for _, dentry := range dentries {
+			if err = c.delete(ino, dentry.Name, true); err != nil && err != syscall.ENOENT {
+				return err
+			}
+		}
+	}
+	info, err := c.mw.Delete_ll(parent, name, isDir)
+	if err != nil {
+		return err
+	}
+	if info != nil && !isDir && info.Nlink == 0 {
+		c.mw.Evict(info.Inode)
+	}
+	return nil
+}
+
+//export cfs_delete
+func cfs_delete(id C.int64_t, path *C.char, recursive C.int) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	parent, name, err := c.lookupParent(C.GoString(path))
+	if err != nil {
+		return errno(err)
+	}
+	return errno(c.delete(parent, name, recursive != 0))
+}
+
+// cfs_rename renames the entry only if the destination does not exist, following the rename of HDFS.
+// The destination becomes visible at once, so renaming a directory of outputs commits them atomically.
+//
+//export cfs_rename
+func cfs_rename(id C.int64_t, from, to *C.char) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	srcParent, srcName, err := c.lookupParent(C.GoString(from))
+	if err != nil {
+		return errno(err)
+	}
+	dstParent, dstName, err := c.lookupParent(C.GoString(to))
+	if err != nil {
+		return errno(err)
+	}
+	return errno(c.mw.RenameNoReplace(srcParent, srcName, dstParent, dstName))
+}
+
+func copyCString(dst *C.char, size int, s string) {
+	b := (*[1 << 30]byte)(unsafe.Pointer(dst))[:size:size]
+	n := copy(b[:size-1], s)
+	b[n] = 0
+}
+
+// cfs_list_status lists the entries of the directory sorted by name which follow the name start_after,
+// an empty name lists from the first entry. It returns the number of the entries filled in, and the
+// listing is complete once it is less than count.
+//
+//export cfs_list_status
+func cfs_list_status(id C.int64_t, path *C.char, startAfter *C.char, statuses *C.struct_cfs_file_status, count C.int) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil || count < 0 || count > maxListCount {
+		return -C.int(syscall.EINVAL)
+	}
+	ino, err := c.lookupPath(C.GoString(path))
+	if err != nil {
+		return errno(err)
+	}
+	dentries, err := c.mw.ReadDir_ll(ino)
+	if err != nil {
+		return errno(err)
+	}
+	after := C.GoString(startAfter)
+	for len(dentries) > 0 && dentries[0].Name <= after {
+		dentries = dentries[1:]
+	}
+
+	out := (*[maxListCount]C.struct_cfs_file_status)(unsafe.Pointer(statuses))[:count:count]
+	n := 0
+	for n < int(count) && len(dentries) > 0 {
+		batch := dentries
+		if len(batch) > int(count)-n {
+			batch = batch[:int(count)-n]
+		}
+		dentries = dentries[len(batch):]
+		inodes := make([]uint64, 0, len(batch))
+		for _, dentry := range batch {
+			inodes = append(inodes, dentry.Inode)
+		}
+		infos := make(map[uint64]*proto.InodeInfo, len(inodes))
+		for _, info := range c.mw.BatchInodeGet(inodes) {
+			infos[info.Inode] = info
+		}
+		for _, dentry := range batch {
+			info, ok := infos[dentry.Inode]
+			if !ok {
+				// deleted after listed
+				continue
+			}
+			copyCString(&out[n].name[0], C.CFS_NAME_MAX, dentry.Name)
+			c.fillStat(info, &out[n].stat)
+			n++
+		}
+	}
+	return C.int(n)
+}
+
+// blockLocation is a range of a file stored in the same data partition.
+type blockLocation struct {
+	offset      uint64
+	length      uint64
+	partitionID uint64
+}
+
+// blockLocations merges the extent keys overlapping the range into the ranges stored in the same data partition.
+func blockLocations(extents []proto.ExtentKey, offset, length uint64) (blocks []*blockLocation) {
+	end := offset + length
+	var last *blockLocation
+	for _, ek := range extents {
+		ekEnd := ek.FileOffset + uint64(ek.Size)
+		if ekEnd <= offset || ek.FileOffset >= end {
+			continue
+		}
+		if last != nil && last.partitionID == ek.PartitionId && last.offset+last.length == ek.FileOffset {
+			last.length += uint64(ek.Size)
+			continue
+		}
+		last = &blockLocation{offset: ek.FileOffset, length: uint64(ek.Size), partitionID: ek.PartitionId}
+		blocks = append(blocks, last)
+	}
+	return
+}
+
+// cfs_get_block_locations returns the hosts storing the range of the file, by the ranges stored in the same
+// data partition, so that the tasks can be scheduled next to their data. It returns the number of the
+// locations filled in, which is at most count.
+//
+//export cfs_get_block_locations
+func cfs_get_block_locations(id C.int64_t, path *C.char, off, length C.int64_t, locations *C.struct_cfs_block_location, count C.int) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil || off < 0 || length < 0 || count < 0 || count > maxLocationCount {
+		return -C.int(syscall.EINVAL)
+	}
+	ino, err := c.lookupPath(C.GoString(path))
+	if err != nil {
+		return errno(err)
+	}
+	_, _, extents, err := c.mw.GetExtents(ino)
+	if err != nil {
+		return errno(err)
+	}
+	blocks := blockLocations(extents, uint64(off), uint64(length))
+	if len(blocks) > int(count) {
+		blocks = blocks[:count]
+	}
+	out := (*[maxLocationCount]C.struct_cfs_block_location)(unsafe.Pointer(locations))[:count:count]
+	for i, block := range blocks {
+		loc := &out[i]
+		loc.offset = C.int64_t(block.offset)
+		loc.length = C.int64_t(block.length)
+		loc.num_hosts = 0
+		hosts, err := c.ec.PartitionHosts(block.partitionID)
+		if err != nil {
+			log.LogWarnf("cfs_get_block_locations: ino(%v) partition(%v) err(%v)", ino, block.partitionID, err)
+			continue
+		}
+		for _, addr := range hosts {
+			if int(loc.num_hosts) == C.CFS_MAX_HOSTS {
+				break
+			}
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			copyCString(&loc.hosts[loc.num_hosts][0], C.CFS_HOST_MAX, host)
+			loc.num_hosts++
+		}
+	}
+	return C.int(len(blocks))
+}
+
+//export cfs_statfs
+func cfs_statfs(id C.int64_t, total, used *C.uint64_t) C.int {
+	c := getClient(id)
+	if c == nil || c.mw == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	t, u := c.mw.Statfs()
+	*total, *used = C.uint64_t(t), C.uint64_t(u)
+	return 0
+}
@@ -42,6 +42,7 @@ type AdminTaskManager struct {
 	clusterID  string
 	targetAddr string
 	TaskMap    map[string]*proto.AdminTask
+	paused     map[string]bool // tasks that the operator has paused, will not be sent until resumed
 	sync.RWMutex
 	exitCh     chan struct{}
 	connPool   *util.ConnectPool
@@ -53,6 +54,7 @@ func newAdminTaskManager(targetAddr, clusterID string) (sender *AdminTaskManager
 		targetAddr: targetAddr,
 		clusterID:  clusterID,
 		TaskMap:    make(map[string]*proto.AdminTask),
+		paused:     make(map[string]bool),
 		exitCh:     make(chan struct{}, 1),
 		connPool:   util.NewConnectPoolWithTimeout(idleConnTimeout, connectTimeout),
 	}
@@ -237,6 +239,7 @@ func (sender *AdminTaskManager) DelTask(t *proto.AdminTask) {
 		log.LogDebugf("action[DelTask] delete task[%v]", t.ToString())
 	}
 	delete(sender.TaskMap, t.ID)
+	delete(sender.paused, t.ID)
 }
 
 // AddTask adds a new task to the task map.
@@ -263,12 +266,15 @@ func (sender *AdminTaskManager) getToDoTasks() (tasks []*proto.AdminTask) {
 	}
 	// send urgent task immediately
 	for _, t := range sender.TaskMap {
-		if t.IsUrgentTask() && t.CheckTaskNeedSend() == true {
+		if t.IsUrgentTask() && !sender.paused[t.ID] && t.CheckTaskNeedSend() == true {
 			tasks = append(tasks, t)
 			t.SendTime = time.Now().Unix()
 		}
 	}
 	for _, task := range sender.TaskMap {
+		if sender.paused[task.ID] {
+			continue
+		}
 		if !task.IsHeartbeatTask() && !task.IsUrgentTask() && task.CheckTaskNeedSend() {
 			tasks = append(tasks, task)
 			task.SendTime = time.Now().Unix()
@@ -279,3 +285,62 @@ func (sender *AdminTaskManager) getToDoTasks() (tasks []*proto.AdminTask) {
 	}
 	return
 }
+
+// getTaskViews returns the views of the tasks that have not finished yet, heartbeat tasks are excluded.
+func (sender *AdminTaskManager) getTaskViews() (views []*proto.AdminTaskView) {
+	sender.RLock()
+	defer sender.RUnlock()
+	views = make([]*proto.AdminTaskView, 0)
+	for _, t := range sender.TaskMap {
+		if t.IsHeartbeatTask() {
+			continue
+		}
+		views = append(views, &proto.AdminTaskView{
+			ID:          t.ID,
+			OpCode:      t.OpCode,
+			OpName:      (&proto.Packet{Opcode: t.OpCode}).GetOpMsg(),
+			NodeAddr:    sender.targetAddr,
+			PartitionID: t.PartitionID,
+			Status:      t.Status,
+			CreateTime:  t.CreateTime,
+			SendTime:    t.SendTime,
+			SendCount:   t.SendCount,
+			Paused:      sender.paused[t.ID],
+		})
+	}
+	return
+}
+
+// cancelTask removes the task, so that it will not be sent or resent to the node.
+// The task which is already running on the node will not be interrupted.
+func (sender *AdminTaskManager) cancelTask(taskID string) (err error) {
+	sender.Lock()
+	defer sender.Unlock()
+	if _, ok := sender.TaskMap[taskID]; !ok {
+		return fmt.Errorf("task[%v] not found on node[%v]", taskID, sender.targetAddr)
+	}
+	delete(sender.TaskMap, taskID)
+	delete(sender.paused, taskID)
+	log.LogWarnf("action[cancelTask] clusterID[%v] node[%v] task[%v] is canceled", sender.clusterID, sender.targetAddr, taskID)
+	return
+}
+
+// setTaskPaused pauses or resumes sending the task to the node.
+func (sender *AdminTaskManager) setTaskPaused(taskID string, paused bool) (err error) {
+	sender.Lock()
+	defer sender.Unlock()
+	t, ok := sender.TaskMap[taskID]
+	if !ok {
+		return fmt.Errorf("task[%v] not found on node[%v]", taskID, sender.targetAddr)
+	}
+	if t.IsHeartbeatTask() {
+		return fmt.Errorf("heartbeat task[%v] can not be paused", taskID)
+	}
+	if paused {
+		sender.paused[taskID] = true
+	} else {
+		delete(sender.paused, taskID)
+	}
+	log.LogWarnf("action[setTaskPaused] clusterID[%v] node[%v] task[%v] paused[%v]", sender.clusterID, sender.targetAddr, taskID, paused)
+	return
+}
@@ -17,6 +17,7 @@ package master
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"sync/atomic"
@@ -188,6 +189,83 @@ func (m *Server) listZone(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(zoneViews))
 }
 
+func (m *Server) listNodeSets(w http.ResponseWriter, r *http.Request) {
+	var (
+		infos []*proto.NodeSetInfo
+		err   error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if infos, err = m.cluster.listNodeSets(r.FormValue(zoneNameKey)); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(infos))
+}
+
+func (m *Server) getNodeSet(w http.ResponseWriter, r *http.Request) {
+	var (
+		id  uint64
+		ns  *nodeSet
+		err error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if id, err = extractNodeID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if ns, err = m.cluster.getNodeSet(id); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(newNodeSetInfo(ns, true)))
+}
+
+func (m *Server) createNodeSet(w http.ResponseWriter, r *http.Request) {
+	var (
+		zoneName string
+		ns       *nodeSet
+		err      error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if zoneName = r.FormValue(zoneNameKey); zoneName == "" {
+		err = keyNotFound(zoneNameKey)
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if ns, err = m.cluster.createNodeSet(zoneName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(newNodeSetInfo(ns, false)))
+}
+
+// migrateNodeToNodeSet moves a data node or a meta node to another node set of its zone.
+func (m *Server) migrateNodeToNodeSet(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr string
+		id       uint64
+		err      error
+	)
+	if nodeAddr, id, err = parseRequestToMigrateNode(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.migrateNodeToNodeSet(nodeAddr, id); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("migrate node[%v] to node set[%v] successfully", nodeAddr, id)))
+}
+
 func (m *Server) clusterStat(w http.ResponseWriter, r *http.Request) {
 	cs := &proto.ClusterStatInfo{
 		DataNodeStatInfo: m.cluster.dataNodeStatInfo,
@@ -215,6 +293,7 @@ func (m *Server) getCluster(w http.ResponseWriter, r *http.Request) {
 		VolStatInfo:         make([]*proto.VolStatInfo, 0),
 		BadPartitionIDs:     make([]proto.BadPartitionView, 0),
 		BadMetaPartitionIDs: make([]proto.BadPartitionView, 0),
+		Cordons:             m.cluster.getCordons(),
 	}
 
 	vols := m.cluster.allVolNames()
@@ -419,6 +498,34 @@ func (m *Server) deleteDataReplica(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+// Rebuild the raft group of a data partition from the surviving replica after the quorum is lost permanently.
+func (m *Server) resetDataPartitionRaft(w http.ResponseWriter, r *http.Request) {
+	var (
+		msg         string
+		survivor    string
+		dp          *DataPartition
+		partitionID uint64
+		err         error
+	)
+
+	if partitionID, survivor, err = extractDataPartitionIDAndAddr(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+
+	if dp, err = m.cluster.getDataPartitionByID(partitionID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataPartitionNotExists))
+		return
+	}
+
+	if err = m.cluster.resetDataPartitionRaft(dp, survivor); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg = fmt.Sprintf("data partitionID :%v  reset raft group to survivor [%v] successfully", partitionID, survivor)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
 func (m *Server) addMetaReplica(w http.ResponseWriter, r *http.Request) {
 	var (
 		msg         string
@@ -575,6 +682,7 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		msg            string
 		capacity       uint64
 		replicaNum     int
+		mpReplicaNum   int
 		followerRead   bool
 		authenticate   bool
 		enableToken    bool
@@ -582,6 +690,14 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		description    string
 		dpSelectorName string
 		dpSelectorParm string
+		maxIOPS        uint64
+		maxBandwidth   uint64
+		verifyRead     bool
+		trashDays      uint32
+		antiAffinity   bool
+		strictZones    bool
+		atime          bool
+		fileAudit      bool
 		vol            *Vol
 	)
 
@@ -598,8 +714,12 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if replicaNum != 0 && !(replicaNum == 2 || replicaNum == 3) {
-		err = fmt.Errorf("replicaNum can only be 2 and 3,received replicaNum is[%v]", replicaNum)
+	if replicaNum != 0 && (replicaNum < 2 || replicaNum > defaultMaxReplicaNum) {
+		err = fmt.Errorf("replicaNum can only be between 2 and %v,received replicaNum is[%v]", defaultMaxReplicaNum, replicaNum)
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if mpReplicaNum, err = parseMpReplicaNumToUpdateVol(r, vol); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
@@ -608,17 +728,55 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
+	if maxIOPS, maxBandwidth, err = parseQosToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if verifyRead, err = parseVerifyReadToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if trashDays, err = parseTrashDaysToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if antiAffinity, err = parseZoneAntiAffinityToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if strictZones, err = parseStrictZonesToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if atime, err = parseAtimeToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if fileAudit, err = parseFileAuditToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
 
 	newArgs := getVolVarargs(vol)
 
 	newArgs.zoneName = zoneName
 	newArgs.description = description
 	newArgs.capacity = capacity
+	newArgs.dpReplicaNum = uint8(replicaNum)
+	newArgs.mpReplicaNum = uint8(mpReplicaNum)
 	newArgs.followerRead = followerRead
 	newArgs.authenticate = authenticate
 	newArgs.enableToken = enableToken
 	newArgs.dpSelectorName = dpSelectorName
 	newArgs.dpSelectorParm = dpSelectorParm
+	newArgs.maxIOPS = maxIOPS
+	newArgs.maxBandwidth = maxBandwidth
+	newArgs.verifyRead = verifyRead
+	newArgs.trashDays = trashDays
+	newArgs.zoneAntiAffinity = antiAffinity
+	newArgs.strictZones = strictZones
+	newArgs.atime = atime
+	newArgs.fileAudit = fileAudit
 
 	if err = m.cluster.updateVol(name, authKey, newArgs); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
@@ -628,12 +786,122 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
-func (m *Server) volExpand(w http.ResponseWriter, r *http.Request) {
+func (m *Server) setVolTierRule(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		rule    *proto.TierRule
+		err     error
+		msg     string
+	)
+	if name, authKey, rule, err = parseRequestToSetVolTierRule(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setVolTierRule(name, authKey, rule); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg = fmt.Sprintf("set tier rule[%v] of vol[%v] successfully", rule.Name, name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) setVolWormRetention(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		days    uint64
+		err     error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if days, err = strconv.ParseUint(r.FormValue(retentionDaysKey), 10, 32); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(retentionDaysKey).Error()})
+		return
+	}
+	if err = m.cluster.setVolWormRetention(name, authKey, uint32(days)); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set retention days of vol[%v] to %v successfully", name, days)))
+}
+
+func (m *Server) overrideVolWorm(w http.ResponseWriter, r *http.Request) {
 	var (
 		name     string
 		authKey  string
+		duration time.Duration
+		until    int64
+		err      error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if duration, err = time.ParseDuration(r.FormValue(durationKey)); err != nil || duration < 0 {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(durationKey).Error()})
+		return
+	}
+	if until, err = m.cluster.overrideVolWorm(name, authKey, duration, r.RemoteAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(until))
+}
+
+func (m *Server) deleteVolTierRule(w http.ResponseWriter, r *http.Request) {
+	var (
+		name     string
+		authKey  string
+		ruleName string
 		err      error
 		msg      string
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if ruleName = r.FormValue(tierRuleNameKey); ruleName == "" {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: keyNotFound(tierRuleNameKey).Error()})
+		return
+	}
+	if err = m.cluster.deleteVolTierRule(name, authKey, ruleName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg = fmt.Sprintf("delete tier rule[%v] of vol[%v] successfully", ruleName, name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) getVolTierPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		name string
+		vol  *Vol
+		err  error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
+		return
+	}
+	view := &proto.VolTierPolicyView{
+		VolName: vol.Name,
+		Rules:   vol.getTierRules(),
+		Stats:   vol.getTierRuleStats(),
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(view))
+}
+
+func (m *Server) volExpand(w http.ResponseWriter, r *http.Request) {
+	var (
+		name     string
+		authKey  string
+		err      error
 		capacity int
 		vol      *Vol
 	)
@@ -645,21 +913,33 @@ func (m *Server) volExpand(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
 		return
 	}
-	if uint64(capacity) <= vol.Capacity {
-		err = fmt.Errorf("expand capacity[%v] should be larger than the old capacity[%v]", capacity, vol.Capacity)
+	oldCapacity := vol.Capacity
+	if uint64(capacity) < oldCapacity {
+		err = fmt.Errorf("expand capacity[%v] should be larger than the old capacity[%v]", capacity, oldCapacity)
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
 
-	newArgs := getVolVarargs(vol)
-	newArgs.capacity = uint64(capacity)
-
-	if err = m.cluster.updateVol(name, authKey, newArgs); err != nil {
-		sendErrReply(w, r, newErrHTTPReply(err))
+	// expanding to the current capacity is acknowledged as completed, so that the retries of CSI succeed
+	if uint64(capacity) > oldCapacity {
+		newArgs := getVolVarargs(vol)
+		newArgs.capacity = uint64(capacity)
+		if err = m.cluster.updateVol(name, authKey, newArgs); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+	} else if !matchKey(vol.Owner, authKey) {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolAuthKeyNotMatch))
 		return
 	}
-	msg = fmt.Sprintf("update vol[%v] successfully\n", name)
-	sendOkReply(w, r, newSuccessHTTPReply(msg))
+	view := &proto.VolCapacityView{
+		Name:          name,
+		OldCapacity:   oldCapacity,
+		Capacity:      vol.Capacity,
+		CapacityBytes: vol.Capacity * util.GB,
+		Completed:     vol.Capacity >= uint64(capacity),
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(view))
 }
 
 func (m *Server) volShrink(w http.ResponseWriter, r *http.Request) {
@@ -698,42 +978,39 @@ func (m *Server) volShrink(w http.ResponseWriter, r *http.Request) {
 
 func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 	var (
-		name         string
-		owner        string
-		err          error
-		msg          string
-		size         int
-		mpCount      int
-		dpReplicaNum int
-		capacity     int
-		vol          *Vol
-		followerRead bool
-		authenticate bool
-		crossZone    bool
-		enableToken  bool
-		zoneName     string
-		description  string
+		err error
+		msg string
+		vol *Vol
+		req = new(createVolReq)
 	)
 
-	if name, owner, zoneName, description, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken, err = parseRequestToCreateVol(r); err != nil {
+	if req.name, req.owner, req.zoneName, req.description, req.mpCount, req.dpReplicaNum, req.size, req.capacity, req.followerRead, req.authenticate, req.crossZone, req.enableToken, err = parseRequestToCreateVol(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if req.zoneAntiAffinity, err = extractZoneAntiAffinity(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if req.strictZones, err = extractStrictZones(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if !(dpReplicaNum == 2 || dpReplicaNum == 3) {
-		err = fmt.Errorf("replicaNum can only be 2 and 3,received replicaNum is[%v]", dpReplicaNum)
+	if !(req.dpReplicaNum == 2 || req.dpReplicaNum == 3) {
+		err = fmt.Errorf("replicaNum can only be 2 and 3,received replicaNum is[%v]", req.dpReplicaNum)
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if vol, err = m.cluster.createVol(name, owner, zoneName, description, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken); err != nil {
+	if vol, err = m.cluster.createVol(req); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
 
-	if err = m.associateVolWithUser(owner, name); err != nil {
+	if err = m.associateVolWithUser(req.owner, req.name); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	msg = fmt.Sprintf("create vol[%v] successfully, has allocate [%v] data partitions", name, len(vol.dataPartitions.partitions))
+	msg = fmt.Sprintf("create vol[%v] successfully, has allocate [%v] data partitions", req.name, len(vol.dataPartitions.partitions))
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
@@ -753,6 +1030,7 @@ func (m *Server) getVolSimpleInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	volView = newSimpleView(vol)
+	volView.RequiredZones = m.cluster.requiredZones(vol, int(vol.dpReplicaNum))
 	sendOkReply(w, r, newSuccessHTTPReply(volView))
 }
 
@@ -760,10 +1038,12 @@ func newSimpleView(vol *Vol) *proto.SimpleVolView {
 	var (
 		volInodeCount  uint64
 		volDentryCount uint64
+		volTrashSize   uint64
 	)
 	for _, mp := range vol.MetaPartitions {
 		volDentryCount = volDentryCount + mp.DentryCount
 		volInodeCount = volInodeCount + mp.InodeCount
+		volTrashSize = volTrashSize + mp.TrashSize
 	}
 	maxPartitionID := vol.maxPartitionID()
 	return &proto.SimpleVolView{
@@ -782,6 +1062,8 @@ func newSimpleView(vol *Vol) *proto.SimpleVolView {
 		NeedToLowerReplica: vol.NeedToLowerReplica,
 		Authenticate:       vol.authenticate,
 		CrossZone:          vol.crossZone,
+		ZoneAntiAffinity:   vol.zoneAntiAffinity,
+		StrictZones:        vol.strictZones,
 		EnableToken:        vol.enableToken,
 		Tokens:             vol.tokens,
 		RwDpCnt:            vol.dataPartitions.readableAndWritableCnt,
@@ -791,6 +1073,15 @@ func newSimpleView(vol *Vol) *proto.SimpleVolView {
 		Description:        vol.description,
 		DpSelectorName:     vol.dpSelectorName,
 		DpSelectorParm:     vol.dpSelectorParm,
+		MaxIOPS:            vol.maxIOPS,
+		MaxBandwidth:       vol.maxBandwidth,
+		VerifyRead:         vol.verifyRead,
+		WormRetentionDays:  vol.wormRetentionDays,
+		WormOverrideUntil:  vol.wormOverrideUntil,
+		TrashDays:          vol.trashDays,
+		TrashSize:          volTrashSize,
+		Atime:              vol.atime,
+		FileAudit:          vol.fileAudit,
 	}
 }
 
@@ -847,6 +1138,9 @@ func (m *Server) getDataNode(w http.ResponseWriter, r *http.Request) {
 		NodeSetID:                 dataNode.NodeSetID,
 		PersistenceDataPartitions: dataNode.PersistenceDataPartitions,
 		BadDisks:                  dataNode.BadDisks,
+		FailingDisks:              dataNode.FailingDisks,
+		Maintenance:               dataNode.Maintenance,
+		MaintenanceSince:          dataNode.MaintenanceSince,
 	}
 
 	sendOkReply(w, r, newSuccessHTTPReply(dataNodeInfo))
@@ -1014,6 +1308,32 @@ func (m *Server) decommissionDisk(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
+// Re-create the replicas lost with the replaced disks on the same data node.
+func (m *Server) recoverDisk(w http.ResponseWriter, r *http.Request) {
+	var (
+		req  *proto.DiskRecoveryRequest
+		view *proto.DiskRecoveryView
+		err  error
+	)
+	if req, err = parseRequestToRecoverDisk(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if view, err = m.cluster.registerDiskRecovery(req); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(view))
+}
+
+func (m *Server) getDiskRecovery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getDiskRecoveries(r.FormValue(addrKey))))
+}
+
 // handle tasks such as heartbeat，loadDataPartition，deleteDataPartition, etc.
 func (m *Server) handleDataNodeTaskResponse(w http.ResponseWriter, r *http.Request) {
 	tr, err := parseRequestToGetTaskResponse(r)
@@ -1021,8 +1341,250 @@ func (m *Server) handleDataNodeTaskResponse(w http.ResponseWriter, r *http.Reque
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("%v", http.StatusOK)))
-	m.cluster.handleDataNodeTaskResponse(tr.OperatorAddr, tr)
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("%v", http.StatusOK)))
+	m.cluster.handleDataNodeTaskResponse(tr.OperatorAddr, tr)
+}
+
+func (m *Server) listAdminTasks(w http.ResponseWriter, r *http.Request) {
+	var (
+		views []*proto.AdminTaskView
+		err   error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if views, err = m.cluster.listAdminTasks(r.FormValue(addrKey)); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(views))
+}
+
+func (m *Server) cancelAdminTask(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr string
+		taskID   string
+		err      error
+	)
+	if nodeAddr, taskID, err = parseRequestToManageAdminTask(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.cancelAdminTask(nodeAddr, taskID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("cancel task[%v] of node[%v] successfully", taskID, nodeAddr)))
+}
+
+func (m *Server) pauseAdminTask(w http.ResponseWriter, r *http.Request) {
+	m.setAdminTaskPaused(w, r, true)
+}
+
+func (m *Server) resumeAdminTask(w http.ResponseWriter, r *http.Request) {
+	m.setAdminTaskPaused(w, r, false)
+}
+
+func (m *Server) setAdminTaskPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	var (
+		nodeAddr string
+		taskID   string
+		err      error
+	)
+	if nodeAddr, taskID, err = parseRequestToManageAdminTask(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.pauseAdminTask(nodeAddr, taskID, paused); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set task[%v] of node[%v] paused to %v successfully", taskID, nodeAddr, paused)))
+}
+
+func (m *Server) setRepairLink(w http.ResponseWriter, r *http.Request) {
+	var (
+		link *proto.RepairLink
+		err  error
+	)
+	if link, err = parseRequestToSetRepairLink(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setRepairLink(link.SrcZone, link.DstZone, link.MaxBandwidth); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set repair bandwidth from zone[%v] to zone[%v] to %vMB/s successfully",
+		link.SrcZone, link.DstZone, link.MaxBandwidth)))
+}
+
+func (m *Server) listRepairLinks(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getRepairLinks()))
+}
+
+func (m *Server) setCordon(w http.ResponseWriter, r *http.Request) {
+	var (
+		cordon *proto.NodeCordon
+		err    error
+	)
+	if cordon, err = parseRequestToSetCordon(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setCordon(cordon); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("cordon %v[%v] successfully", cordon.Type, cordon.Name)))
+}
+
+func (m *Server) removeCordon(w http.ResponseWriter, r *http.Request) {
+	var (
+		cordonType string
+		name       string
+		err        error
+	)
+	if cordonType, name, err = parseCordonTarget(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.removeCordon(cordonType, name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("uncordon %v[%v] successfully", cordonType, name)))
+}
+
+func (m *Server) listCordons(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getCordons()))
+}
+
+func (m *Server) setRebuildPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		policy *proto.RebuildPolicy
+		err    error
+	)
+	if policy, err = parseRequestToSetRebuildPolicy(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setRebuildPolicy(policy); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set rebuild policy of zone[%v] to delay %vs and bandwidth %vMB/s successfully",
+		policy.Zone, policy.DelaySec, policy.MaxBandwidth)))
+}
+
+func (m *Server) removeRebuildPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		zone string
+		err  error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	zone = r.FormValue(zoneKey)
+	if err = m.cluster.removeRebuildPolicy(zone); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("remove rebuild policy of zone[%v] successfully", zone)))
+}
+
+func (m *Server) listRebuildPolicies(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getRebuildPolicies()))
+}
+
+func (m *Server) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	var (
+		filter *auditFilter
+		events []*proto.AuditEvent
+		err    error
+	)
+	if filter, err = parseRequestToListAuditEvents(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if events, err = m.cluster.listAuditEvents(filter); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(events))
+}
+
+func (m *Server) setNFSExport(w http.ResponseWriter, r *http.Request) {
+	var (
+		export *proto.NFSExport
+		err    error
+	)
+	if export, err = parseRequestToSetNFSExport(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setNFSExport(export); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("export vol[%v] as [%v] successfully", export.Volume, export.Path)))
+}
+
+func (m *Server) removeNFSExport(w http.ResponseWriter, r *http.Request) {
+	var (
+		exportPath string
+		err        error
+	)
+	if exportPath, err = extractExportPath(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.removeNFSExport(exportPath); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("remove export [%v] successfully", exportPath)))
+}
+
+func (m *Server) listNFSExports(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getNFSExports()))
+}
+
+func (m *Server) setDataNodeThrottle(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr  string
+		request   *proto.ClientThrottleRequest
+		throttles []*proto.ClientThrottle
+		err       error
+	)
+	if nodeAddr, request, err = parseRequestToSetDataNodeThrottle(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if throttles, err = m.cluster.setDataNodeClientThrottle(nodeAddr, request); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(throttles))
+}
+
+func (m *Server) listDataNodeThrottles(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr  string
+		throttles []*proto.ClientThrottle
+		err       error
+	)
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if throttles, err = m.cluster.getDataNodeClientThrottles(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(throttles))
 }
 
 func (m *Server) addMetaNode(w http.ResponseWriter, r *http.Request) {
@@ -1065,6 +1627,42 @@ func (m *Server) updateDataNode(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(id))
 }
 
+// setDataNodeMaintenance turns the maintenance of a data node on or off.
+func (m *Server) setDataNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr string
+		enable   bool
+		err      error
+	)
+	if nodeAddr, enable, err = parseRequestToSetNodeMaintenance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setDataNodeMaintenance(nodeAddr, enable); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set maintenance of data node[%v] to %v successfully", nodeAddr, enable)))
+}
+
+// setMetaNodeMaintenance turns the maintenance of a meta node on or off.
+func (m *Server) setMetaNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr string
+		enable   bool
+		err      error
+	)
+	if nodeAddr, enable, err = parseRequestToSetNodeMaintenance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setMetaNodeMaintenance(nodeAddr, enable); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set maintenance of meta node[%v] to %v successfully", nodeAddr, enable)))
+}
+
 func (m *Server) updateMetaNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		nodeAddr string
@@ -1111,10 +1709,13 @@ func (m *Server) getMetaNode(w http.ResponseWriter, r *http.Request) {
 		SelectCount:               metaNode.SelectCount,
 		Carry:                     metaNode.Carry,
 		Threshold:                 metaNode.Threshold,
+		MemFull:                   metaNode.MemFull,
 		ReportTime:                metaNode.ReportTime,
 		MetaPartitionCount:        metaNode.MetaPartitionCount,
 		NodeSetID:                 metaNode.NodeSetID,
 		PersistenceMetaPartitions: metaNode.PersistenceMetaPartitions,
+		Maintenance:               metaNode.Maintenance,
+		MaintenanceSince:          metaNode.MaintenanceSince,
 	}
 	sendOkReply(w, r, newSuccessHTTPReply(metaNodeInfo))
 }
@@ -1288,6 +1889,189 @@ func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName string, err err
 	return
 }
 
+func parseRequestToManageAdminTask(r *http.Request) (nodeAddr, taskID string, err error) {
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		return
+	}
+	if taskID = r.FormValue(taskIDKey); taskID == "" {
+		err = keyNotFound(taskIDKey)
+		return
+	}
+	return
+}
+
+// parseRequestToSetRepairLink parses the budget of a link between two zones, the bandwidth is given in MB per second.
+func parseRequestToSetRepairLink(r *http.Request) (link *proto.RepairLink, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	link = &proto.RepairLink{}
+	if link.SrcZone = r.FormValue(srcZoneKey); link.SrcZone == "" {
+		err = keyNotFound(srcZoneKey)
+		return
+	}
+	if link.DstZone = r.FormValue(dstZoneKey); link.DstZone == "" {
+		err = keyNotFound(dstZoneKey)
+		return
+	}
+	if link.MaxBandwidth, err = strconv.ParseUint(r.FormValue(maxBandwidthKey), 10, 64); err != nil {
+		err = unmatchedKey(maxBandwidthKey)
+		return
+	}
+	return
+}
+
+// parseRequestToSetRebuildPolicy parses a rebuild policy, the policy without a zone is the default of the cluster.
+func parseRequestToSetRebuildPolicy(r *http.Request) (policy *proto.RebuildPolicy, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	policy = &proto.RebuildPolicy{Zone: r.FormValue(zoneKey)}
+	if policy.DelaySec, err = strconv.ParseInt(r.FormValue(delayKey), 10, 64); err != nil || policy.DelaySec < 0 {
+		err = unmatchedKey(delayKey)
+		return
+	}
+	if policy.MaxBandwidth, err = strconv.ParseUint(r.FormValue(maxBandwidthKey), 10, 64); err != nil {
+		err = unmatchedKey(maxBandwidthKey)
+		return
+	}
+	return
+}
+
+func parseCordonTarget(r *http.Request) (cordonType, name string, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	switch cordonType = r.FormValue(cordonTypeKey); cordonType {
+	case proto.CordonTypeZone, proto.CordonTypeDataNode, proto.CordonTypeMetaNode:
+	default:
+		err = unmatchedKey(cordonTypeKey)
+		return
+	}
+	if name = r.FormValue(nameKey); name == "" {
+		err = keyNotFound(nameKey)
+		return
+	}
+	return
+}
+
+// parseRequestToSetCordon parses a cordon, the optional expiration is given in unix seconds.
+func parseRequestToSetCordon(r *http.Request) (cordon *proto.NodeCordon, err error) {
+	cordon = &proto.NodeCordon{}
+	if cordon.Type, cordon.Name, err = parseCordonTarget(r); err != nil {
+		return
+	}
+	cordon.Reason = r.FormValue(reasonKey)
+	if value := r.FormValue(untilKey); value != "" {
+		if cordon.Until, err = strconv.ParseInt(value, 10, 64); err != nil || cordon.Until < 0 {
+			err = unmatchedKey(untilKey)
+			return
+		}
+	}
+	return
+}
+
+// parseRequestToListAuditEvents parses the filter of the audit events, the time range is given in
+// unix seconds, and the latest 100 events are returned by default.
+func parseRequestToListAuditEvents(r *http.Request) (filter *auditFilter, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	filter = &auditFilter{
+		op:     r.FormValue(opKey),
+		target: r.FormValue(targetKey),
+		limit:  defaultAuditEventLimit,
+	}
+	if value := r.FormValue(fromKey); value != "" {
+		if filter.from, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = unmatchedKey(fromKey)
+			return
+		}
+	}
+	if value := r.FormValue(toKey); value != "" {
+		if filter.to, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = unmatchedKey(toKey)
+			return
+		}
+	}
+	if value := r.FormValue(limitKey); value != "" {
+		if filter.limit, err = strconv.Atoi(value); err != nil || filter.limit < 0 {
+			err = unmatchedKey(limitKey)
+			return
+		}
+	}
+	return
+}
+
+func extractExportPath(r *http.Request) (exportPath string, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if exportPath = r.FormValue(exportPathKey); exportPath == "" {
+		err = keyNotFound(exportPathKey)
+		return
+	}
+	return
+}
+
+// parseRequestToSetNFSExport parses an export, the allowed clients are given as comma separated CIDRs.
+func parseRequestToSetNFSExport(r *http.Request) (export *proto.NFSExport, err error) {
+	export = &proto.NFSExport{Clients: make([]string, 0)}
+	if export.Path, err = extractExportPath(r); err != nil {
+		return
+	}
+	if export.Volume = r.FormValue(nameKey); export.Volume == "" {
+		err = keyNotFound(nameKey)
+		return
+	}
+	for _, client := range strings.Split(r.FormValue(exportClientsKey), ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			export.Clients = append(export.Clients, client)
+		}
+	}
+	if value := r.FormValue(readOnlyKey); value != "" {
+		if export.ReadOnly, err = strconv.ParseBool(value); err != nil {
+			err = unmatchedKey(readOnlyKey)
+			return
+		}
+	}
+	if value := r.FormValue(rootSquashKey); value != "" {
+		if export.RootSquash, err = strconv.ParseBool(value); err != nil {
+			err = unmatchedKey(rootSquashKey)
+			return
+		}
+	}
+	return
+}
+
+// parseRequestToSetDataNodeThrottle parses the throttle of a client, the bandwidth is given in MB per second.
+func parseRequestToSetDataNodeThrottle(r *http.Request) (nodeAddr string, request *proto.ClientThrottleRequest, err error) {
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		return
+	}
+	request = &proto.ClientThrottleRequest{}
+	ip := net.ParseIP(r.FormValue(clientIPKey))
+	if ip == nil {
+		err = unmatchedKey(clientIPKey)
+		return
+	}
+	request.ClientIP = ip.String()
+	if value := r.FormValue(maxIOPSKey); value != "" {
+		if request.MaxIOPS, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(maxIOPSKey)
+			return
+		}
+	}
+	if value := r.FormValue(maxBandwidthKey); value != "" {
+		if request.MaxBandwidth, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(maxBandwidthKey)
+			return
+		}
+		request.MaxBandwidth = request.MaxBandwidth * util.MB
+	}
+	return
+}
+
 func parseAndExtractNodeAddr(r *http.Request) (nodeAddr string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1295,6 +2079,29 @@ func parseAndExtractNodeAddr(r *http.Request) (nodeAddr string, err error) {
 	return extractNodeAddr(r)
 }
 
+func parseRequestToSetNodeMaintenance(r *http.Request) (nodeAddr string, enable bool, err error) {
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		return
+	}
+	if enable, err = extractStatus(r); err != nil {
+		return
+	}
+	return
+}
+
+func parseRequestToMigrateNode(r *http.Request) (nodeAddr string, id uint64, err error) {
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		return
+	}
+	var value string
+	if value = r.FormValue(nodeSetIDKey); value == "" {
+		err = keyNotFound(nodeSetIDKey)
+		return
+	}
+	id, err = strconv.ParseUint(value, 10, 64)
+	return
+}
+
 func parseRequestToDecommissionNode(r *http.Request) (nodeAddr, diskPath string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1307,6 +2114,25 @@ func parseRequestToDecommissionNode(r *http.Request) (nodeAddr, diskPath string,
 	return
 }
 
+func parseRequestToRecoverDisk(r *http.Request) (req *proto.DiskRecoveryRequest, err error) {
+	var body []byte
+	if body, err = ioutil.ReadAll(r.Body); err != nil {
+		return
+	}
+	req = &proto.DiskRecoveryRequest{}
+	if err = json.Unmarshal(body, req); err != nil {
+		return
+	}
+	if req.Addr == "" {
+		err = keyNotFound(addrKey)
+		return
+	}
+	if len(req.PartitionIDs) == 0 {
+		err = fmt.Errorf("no lost partition of data node[%v]", req.Addr)
+	}
+	return
+}
+
 func parseRequestToGetTaskResponse(r *http.Request) (tr *proto.AdminTask, err error) {
 	var body []byte
 	if err = r.ParseForm(); err != nil {
@@ -1461,6 +2287,108 @@ func parseBoolFieldToUpdateVol(r *http.Request, vol *Vol) (followerRead, authent
 	return
 }
 
+// parseQosToUpdateVol returns the IOPS and bandwidth limits of the volume, a missing value keeps the current limit.
+func parseQosToUpdateVol(r *http.Request, vol *Vol) (maxIOPS, maxBandwidth uint64, err error) {
+	maxIOPS, maxBandwidth = vol.maxIOPS, vol.maxBandwidth
+	if value := r.FormValue(maxIOPSKey); value != "" {
+		if maxIOPS, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(maxIOPSKey)
+			return
+		}
+	}
+	if value := r.FormValue(maxBandwidthKey); value != "" {
+		if maxBandwidth, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(maxBandwidthKey)
+			return
+		}
+	}
+	return
+}
+
+func parseMpReplicaNumToUpdateVol(r *http.Request, vol *Vol) (mpReplicaNum int, err error) {
+	var value string
+	if value = r.FormValue(mpReplicaNumKey); value == "" {
+		return int(vol.mpReplicaNum), nil
+	}
+	if mpReplicaNum, err = strconv.Atoi(value); err != nil {
+		err = unmatchedKey(mpReplicaNumKey)
+		return
+	}
+	if mpReplicaNum < defaultReplicaNum || mpReplicaNum > defaultMaxReplicaNum {
+		err = fmt.Errorf("mpReplicaNum can only be between %v and %v,received mpReplicaNum is[%v]",
+			defaultReplicaNum, defaultMaxReplicaNum, mpReplicaNum)
+	}
+	return
+}
+
+func parseZoneAntiAffinityToUpdateVol(r *http.Request, vol *Vol) (zoneAntiAffinity bool, err error) {
+	var value string
+	if value = r.FormValue(zoneAntiAffinityKey); value == "" {
+		return vol.zoneAntiAffinity, nil
+	}
+	if zoneAntiAffinity, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(zoneAntiAffinityKey)
+	}
+	return
+}
+
+func parseStrictZonesToUpdateVol(r *http.Request, vol *Vol) (strictZones bool, err error) {
+	var value string
+	if value = r.FormValue(strictZonesKey); value == "" {
+		return vol.strictZones, nil
+	}
+	if strictZones, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(strictZonesKey)
+	}
+	return
+}
+
+func parseAtimeToUpdateVol(r *http.Request, vol *Vol) (atime bool, err error) {
+	var value string
+	if value = r.FormValue(atimeKey); value == "" {
+		return vol.atime, nil
+	}
+	if atime, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(atimeKey)
+	}
+	return
+}
+
+func parseFileAuditToUpdateVol(r *http.Request, vol *Vol) (fileAudit bool, err error) {
+	var value string
+	if value = r.FormValue(fileAuditKey); value == "" {
+		return vol.fileAudit, nil
+	}
+	if fileAudit, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(fileAuditKey)
+	}
+	return
+}
+
+func parseTrashDaysToUpdateVol(r *http.Request, vol *Vol) (trashDays uint32, err error) {
+	var value string
+	if value = r.FormValue(trashDaysKey); value == "" {
+		return vol.trashDays, nil
+	}
+	var days uint64
+	if days, err = strconv.ParseUint(value, 10, 32); err != nil {
+		err = unmatchedKey(trashDaysKey)
+		return
+	}
+	return uint32(days), nil
+}
+
+func parseVerifyReadToUpdateVol(r *http.Request, vol *Vol) (verifyRead bool, err error) {
+	var value string
+	if value = r.FormValue(verifyReadKey); value == "" {
+		return vol.verifyRead, nil
+	}
+	if verifyRead, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(verifyReadKey)
+	}
+	return
+}
+
 func parseRequestToSetVolCapacity(r *http.Request) (name, authKey string, capacity int, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1477,6 +2405,35 @@ func parseRequestToSetVolCapacity(r *http.Request) (name, authKey string, capaci
 	return
 }
 
+func parseRequestToSetVolTierRule(r *http.Request) (name, authKey string, rule *proto.TierRule, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	rule = &proto.TierRule{Enable: true}
+	if rule.Name = r.FormValue(tierRuleNameKey); rule.Name == "" {
+		err = keyNotFound(tierRuleNameKey)
+		return
+	}
+	var value string
+	if value = r.FormValue(inactiveDaysKey); value == "" {
+		err = keyNotFound(inactiveDaysKey)
+		return
+	}
+	var days uint64
+	if days, err = strconv.ParseUint(value, 10, 32); err != nil {
+		err = unmatchedKey(inactiveDaysKey)
+		return
+	}
+	rule.InactiveDays = uint32(days)
+	if value = r.FormValue(enableKey); value != "" {
+		if rule.Enable, err = strconv.ParseBool(value); err != nil {
+			err = unmatchedKey(enableKey)
+			return
+		}
+	}
+	return
+}
+
 func parseRequestToCreateVol(r *http.Request) (name, owner, zoneName, description string, mpCount, dpReplicaNum, size, capacity int, followerRead, authenticate, crossZone, enableToken bool, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1713,6 +2670,28 @@ func extractAuthenticate(r *http.Request) (authenticate bool, err error) {
 	return
 }
 
+func extractZoneAntiAffinity(r *http.Request) (zoneAntiAffinity bool, err error) {
+	var value string
+	if value = r.FormValue(zoneAntiAffinityKey); value == "" {
+		return
+	}
+	if zoneAntiAffinity, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(zoneAntiAffinityKey)
+	}
+	return
+}
+
+func extractStrictZones(r *http.Request) (strictZones bool, err error) {
+	var value string
+	if value = r.FormValue(strictZonesKey); value == "" {
+		return
+	}
+	if strictZones, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(strictZonesKey)
+	}
+	return
+}
+
 func extractCrossZone(r *http.Request) (crossZone bool, err error) {
 	var value string
 	if value = r.FormValue(crossZoneKey); value == "" {
@@ -1920,8 +2899,15 @@ func (m *Server) getDataPartitions(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
 		return
 	}
-
-	if body, err = vol.getDataPartitionsView(); err != nil {
+	if epoch, ok := extractViewEpoch(r); ok {
+		if body, err = vol.dataPartitions.getDataPartitionsDeltaView(epoch, m.cluster.getLeaderTerm()); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+		send(w, r, body)
+		return
+	}
+	if body, err = vol.getDataPartitionsView(m.cluster); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -2001,6 +2987,8 @@ func volStat(vol *Vol) (stat *proto.VolStatInfo) {
 		stat.UsedSize = stat.TotalSize
 	}
 	stat.EnableToken = vol.enableToken
+	stat.TrashDays = vol.trashDays
+	stat.Atime = vol.atime
 	log.LogDebugf("total[%v],usedSize[%v]", stat.TotalSize, stat.UsedSize)
 	return
 }
@@ -2056,6 +3044,7 @@ func (m *Server) getMetaPartition(w http.ResponseWriter, r *http.Request) {
 				ReportTime: mp.Replicas[i].ReportTime,
 				Status:     mp.Replicas[i].Status,
 				IsLeader:   mp.Replicas[i].IsLeader,
+				MemUsed:    mp.Replicas[i].MemUsed,
 			}
 		}
 		var mpInfo = &proto.MetaPartitionInfo{
@@ -2148,6 +3137,21 @@ func extractAuthKey(r *http.Request) (authKey string, err error) {
 	return
 }
 
+// extractViewEpoch returns the view epoch that the client holds, ok is false if the client asks for the legacy full view.
+func extractViewEpoch(r *http.Request) (epoch uint64, ok bool) {
+	var (
+		value string
+		err   error
+	)
+	if value = r.FormValue(viewEpochKey); value == "" {
+		return
+	}
+	if epoch, err = strconv.ParseUint(value, 10, 64); err != nil {
+		return
+	}
+	return epoch, true
+}
+
 func parseAndExtractName(r *http.Request) (name string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -30,6 +30,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/master/mocktest"
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/log"
 )
@@ -102,7 +103,15 @@ func createDefaultMasterServerForTest() *Server {
 	testServer.cluster.checkMetaNodeHeartbeat()
 	time.Sleep(5 * time.Second)
 	testServer.cluster.scheduleToUpdateStatInfo()
-	vol, err := testServer.cluster.createVol(commonVolName, "cfs", testZone2, "", 3, 3, 3, 100, false, false, false, false)
+	vol, err := testServer.cluster.createVol(&createVolReq{
+		name:         commonVolName,
+		owner:        "cfs",
+		zoneName:     testZone2,
+		mpCount:      3,
+		dpReplicaNum: 3,
+		size:         3,
+		capacity:     100,
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -225,6 +234,232 @@ func TestGetIpAndClusterName(t *testing.T) {
 	process(reqURL, t)
 }
 
+func TestCordon(t *testing.T) {
+	until := time.Now().Add(time.Hour).Unix()
+	reqURL := fmt.Sprintf("%v%v?type=%v&name=%v&reason=%v&until=%v",
+		hostAddr, proto.AdminSetCordon, proto.CordonTypeDataNode, mds1Addr, "maintenance", until)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if !server.cluster.isCordoned(proto.CordonTypeDataNode, mds1Addr) {
+		t.Errorf("data node[%v] is not cordoned", mds1Addr)
+		return
+	}
+	for i := 0; i < 10; i++ {
+		hosts, _, err := server.cluster.chooseTargetDataNodes("", nil, nil, 1, 1, testZone1)
+		if err != nil {
+			t.Errorf("choose data nodes err[%v]", err)
+			return
+		}
+		if contains(hosts, mds1Addr) {
+			t.Errorf("cordoned data node[%v] is chosen", mds1Addr)
+			return
+		}
+	}
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminGetCluster)
+	reply := process(reqURL, t)
+	if reply == nil || !strings.Contains(fmt.Sprintf("%v", reply.Data), mds1Addr) {
+		t.Errorf("cordon of data node[%v] is not shown in cluster info", mds1Addr)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?type=%v&name=%v", hostAddr, proto.AdminRemoveCordon, proto.CordonTypeDataNode, mds1Addr)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if server.cluster.isCordoned(proto.CordonTypeDataNode, mds1Addr) {
+		t.Errorf("data node[%v] is still cordoned", mds1Addr)
+		return
+	}
+
+	server.cluster.loadCordons([]*proto.NodeCordon{
+		{Type: proto.CordonTypeZone, Name: testZone1, Since: time.Now().Unix() - 60, Until: time.Now().Unix() - 1},
+	})
+	if server.cluster.isCordoned(proto.CordonTypeZone, testZone1) {
+		t.Errorf("expired cordon of zone[%v] still applies", testZone1)
+		return
+	}
+	server.cluster.removeExpiredCordons()
+	if len(server.cluster.cordons) != 0 {
+		t.Errorf("expired cordons are not removed: %v", server.cluster.cordons)
+	}
+}
+
+func TestRebuildPolicy(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?delay=%v&maxBandwidth=%v", hostAddr, proto.AdminSetRebuildPolicy, 1800, 100)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?zone=%v&delay=%v&maxBandwidth=%v", hostAddr, proto.AdminSetRebuildPolicy, testZone1, 600, 0)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if policy := server.cluster.getZoneRebuildPolicy(testZone1); policy.DelaySec != 600 || policy.MaxBandwidth != 0 {
+		t.Errorf("rebuild policy of zone[%v] is %v", testZone1, policy)
+		return
+	}
+	if policy := server.cluster.getZoneRebuildPolicy(testZone2); policy.DelaySec != 1800 || policy.MaxBandwidth != 100 {
+		t.Errorf("zone[%v] does not follow the rebuild policy of the cluster: %v", testZone2, policy)
+		return
+	}
+	if bandwidth := server.cluster.getRebuildBandwidth(testZone2); bandwidth != 100*util.MB {
+		t.Errorf("rebuild bandwidth of zone[%v] is %v", testZone2, bandwidth)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?zone=%v", hostAddr, proto.AdminRemoveRebuildPolicy, testZone1)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if policy := server.cluster.getZoneRebuildPolicy(testZone1); policy.DelaySec != 1800 {
+		t.Errorf("removed rebuild policy of zone[%v] still applies: %v", testZone1, policy)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminRemoveRebuildPolicy)
+	process(reqURL, t)
+	if len(server.cluster.getRebuildPolicies()) != 0 {
+		t.Errorf("rebuild policies are not removed: %v", server.cluster.getRebuildPolicies())
+	}
+}
+
+func TestNodeSet(t *testing.T) {
+	dataNode, err := server.cluster.dataNode(mds1Addr)
+	if err != nil {
+		t.Errorf("get data node[%v] err[%v]", mds1Addr, err)
+		return
+	}
+	oldID := dataNode.NodeSetID
+	ns, err := server.cluster.createNodeSet(dataNode.ZoneName)
+	if err != nil {
+		t.Errorf("create node set in zone[%v] err[%v]", dataNode.ZoneName, err)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?addr=%v&nodeSetId=%v", hostAddr, proto.MigrateNodeToNodeSet, mds1Addr, ns.ID)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if dataNode.NodeSetID != ns.ID || ns.dataNodeLen() != 1 {
+		t.Errorf("data node[%v] is not migrated to node set[%v]", mds1Addr, ns.ID)
+	}
+	reqURL = fmt.Sprintf("%v%v?id=%v", hostAddr, proto.GetNodeSet, ns.ID)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if err = server.cluster.migrateNodeToNodeSet(mds1Addr, oldID); err != nil || dataNode.NodeSetID != oldID {
+		t.Errorf("migrate data node[%v] back to node set[%v] err[%v]", mds1Addr, oldID, err)
+	}
+	reqURL = fmt.Sprintf("%v%v?zoneName=%v", hostAddr, proto.ListNodeSets, dataNode.ZoneName)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+}
+
+func TestNodeMaintenance(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?addr=%v&enable=true", hostAddr, proto.AdminSetDataNodeMaintenance, mds1Addr)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	dataNode, err := server.cluster.dataNode(mds1Addr)
+	if err != nil {
+		t.Errorf("get data node[%v] err[%v]", mds1Addr, err)
+		return
+	}
+	if !dataNode.inMaintenance() || dataNode.isWriteAble() {
+		t.Errorf("data node[%v] in maintenance is writable", mds1Addr)
+		return
+	}
+	for i := 0; i < 10; i++ {
+		hosts, _, err := server.cluster.chooseTargetDataNodes("", nil, nil, 1, 1, testZone1)
+		if err != nil {
+			t.Errorf("choose data nodes err[%v]", err)
+			return
+		}
+		if contains(hosts, mds1Addr) {
+			t.Errorf("data node[%v] in maintenance is chosen", mds1Addr)
+			return
+		}
+	}
+	reqURL = fmt.Sprintf("%v%v?addr=%v&enable=false", hostAddr, proto.AdminSetDataNodeMaintenance, mds1Addr)
+	process(reqURL, t)
+	if dataNode.inMaintenance() {
+		t.Errorf("data node[%v] is still in maintenance", mds1Addr)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?addr=%v&enable=true", hostAddr, proto.AdminSetMetaNodeMaintenance, mms1Addr)
+	process(reqURL, t)
+	metaNode, err := server.cluster.metaNode(mms1Addr)
+	if err != nil {
+		t.Errorf("get meta node[%v] err[%v]", mms1Addr, err)
+		return
+	}
+	if !metaNode.inMaintenance() || metaNode.isWritable() {
+		t.Errorf("meta node[%v] in maintenance is writable", mms1Addr)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?addr=%v&enable=false", hostAddr, proto.AdminSetMetaNodeMaintenance, mms1Addr)
+	process(reqURL, t)
+	if metaNode.inMaintenance() {
+		t.Errorf("meta node[%v] is still in maintenance", mms1Addr)
+	}
+}
+
+func TestNFSExport(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?path=%v&name=%v&clients=%v&readOnly=true",
+		hostAddr, proto.AdminSetNFSExport, "/common", commonVolName, "192.168.0.0/16,10.0.0.0/8")
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	exports := server.cluster.getNFSExports()
+	if len(exports) != 1 || exports[0].Volume != commonVolName || len(exports[0].Clients) != 2 || !exports[0].ReadOnly {
+		t.Errorf("unexpected exports: %v", exports)
+		return
+	}
+	if err := server.cluster.setNFSExport(&proto.NFSExport{Path: "common", Volume: commonVolName}); err == nil {
+		t.Errorf("relative export path is accepted")
+		return
+	}
+	if err := server.cluster.setNFSExport(&proto.NFSExport{Path: "/bad", Volume: commonVolName, Clients: []string{"10.0.0.1"}}); err == nil {
+		t.Errorf("invalid client CIDR is accepted")
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?path=%v", hostAddr, proto.AdminRemoveNFSExport, "/common")
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if exports = server.cluster.getNFSExports(); len(exports) != 0 {
+		t.Errorf("export is not removed: %v", exports)
+	}
+}
+
+func TestAuditEvents(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v",
+		hostAddr, proto.AdminUpdateVol, commonVolName, 3000, buildAuthKey("cfs"))
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	events, err := server.cluster.listAuditEvents(&auditFilter{op: proto.AdminUpdateVol, target: commonVolName})
+	if err != nil {
+		t.Errorf("list audit events err[%v]", err)
+		return
+	}
+	if len(events) == 0 {
+		t.Errorf("update of vol[%v] is not audited", commonVolName)
+		return
+	}
+	event := events[len(events)-1]
+	if event.Params[volCapacityKey] != "3000" {
+		t.Errorf("unexpected audit event: %v", event)
+	}
+	if event.Params[volAuthKey] != auditRedacted {
+		t.Errorf("auth key is recorded: %v", event.Params[volAuthKey])
+	}
+
+	reqURL = fmt.Sprintf("%v%v?op=%v&limit=1", hostAddr, proto.AdminListAuditEvents, "/vol/update")
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if events, _ = server.cluster.listAuditEvents(&auditFilter{limit: 1}); len(events) != 1 {
+		t.Errorf("limit of audit events: %v", len(events))
+	}
+	// listing is not audited
+	if events[0].Op == proto.AdminListAuditEvents {
+		t.Errorf("listing is audited: %v", events[0])
+	}
+	server.cluster.trimAuditEvents(time.Now().Unix() + 1)
+	if events, _ = server.cluster.listAuditEvents(&auditFilter{}); len(events) != 0 {
+		t.Errorf("audit events are not trimmed: %v", len(events))
+	}
+}
+
 func process(reqURL string, t *testing.T) (reply *proto.HTTPReply) {
 	resp, err := http.Get(reqURL)
 	if err != nil {
@@ -309,6 +544,8 @@ func TestMarkDeleteVol(t *testing.T) {
 }
 
 func TestSetVolCapacity(t *testing.T) {
+	setVolCapacity(600, proto.AdminVolExpand, t)
+	// expanding to the current capacity is acknowledged for the retries of CSI
 	setVolCapacity(600, proto.AdminVolExpand, t)
 	setVolCapacity(300, proto.AdminVolShrink, t)
 }
@@ -344,6 +581,60 @@ func TestUpdateVol(t *testing.T) {
 		return
 	}
 
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v&verifyRead=true",
+		hostAddr, proto.AdminUpdateVol, commonVol.Name, capacity, buildAuthKey("cfs"))
+	process(reqURL, t)
+	if vol.verifyRead != true {
+		t.Errorf("expect verifyRead is true, but is %v", vol.verifyRead)
+		return
+	}
+	if vols := server.cluster.getVerifyReadVols(); len(vols) != 1 || vols[0] != commonVolName {
+		t.Errorf("expect verify read vols [%v], but is %v", commonVolName, vols)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v&trashDays=7",
+		hostAddr, proto.AdminUpdateVol, commonVol.Name, capacity, buildAuthKey("cfs"))
+	process(reqURL, t)
+	if vol.trashDays != 7 {
+		t.Errorf("expect trashDays is 7, but is %v", vol.trashDays)
+		return
+	}
+	if stat := volStat(vol); stat.TrashDays != 7 {
+		t.Errorf("expect trashDays of vol stat is 7, but is %v", stat.TrashDays)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v&atime=true",
+		hostAddr, proto.AdminUpdateVol, commonVol.Name, capacity, buildAuthKey("cfs"))
+	process(reqURL, t)
+	if !vol.atime || vol.trashDays != 7 {
+		t.Errorf("expect atime is enabled and trashDays is kept, but atime is %v and trashDays is %v", vol.atime, vol.trashDays)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v&fileAudit=true",
+		hostAddr, proto.AdminUpdateVol, commonVol.Name, capacity, buildAuthKey("cfs"))
+	process(reqURL, t)
+	if !vol.fileAudit || !vol.atime {
+		t.Errorf("expect fileAudit is enabled and atime is kept, but fileAudit is %v and atime is %v", vol.fileAudit, vol.atime)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v&replicaNum=%v&mpReplicaNum=%v",
+		hostAddr, proto.AdminUpdateVol, commonVol.Name, capacity, buildAuthKey("cfs"), 5, 5)
+	process(reqURL, t)
+	if vol.dpReplicaNum != 5 || vol.mpReplicaNum != 5 {
+		t.Errorf("expect dpReplicaNum and mpReplicaNum are 5, but are %v and %v", vol.dpReplicaNum, vol.mpReplicaNum)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=%v&authKey=%v&replicaNum=%v&mpReplicaNum=%v",
+		hostAddr, proto.AdminUpdateVol, commonVol.Name, capacity, buildAuthKey("cfs"), defaultReplicaNum, defaultReplicaNum)
+	process(reqURL, t)
+	if vol.dpReplicaNum != defaultReplicaNum || vol.mpReplicaNum != defaultReplicaNum {
+		t.Errorf("expect dpReplicaNum and mpReplicaNum are %v, but are %v and %v", defaultReplicaNum, vol.dpReplicaNum, vol.mpReplicaNum)
+		return
+	}
 }
 
 func setVolCapacity(capacity uint64, url string, t *testing.T) {
@@ -715,6 +1006,87 @@ func TestGetAKInfo(t *testing.T) {
 	process(reqURL, t)
 }
 
+func TestRotateUserKey(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v", hostAddr, proto.UserRotateKey)
+	param := &proto.UserRotateKeyParam{UserID: testUserID, GracePeriod: 3600}
+	data, err := json.Marshal(param)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fmt.Println(reqURL)
+	post(reqURL, data, t)
+	userInfo, err := server.user.getUserInfo(testUserID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if userInfo.AccessKey == ak || userInfo.RetiringAccessKey != ak || userInfo.RetiringSecretKey != sk {
+		t.Errorf("expect retiring ak[%v], real ak[%v] retiring ak[%v]\n", ak, userInfo.AccessKey, userInfo.RetiringAccessKey)
+		return
+	}
+	for _, key := range []string{ak, userInfo.AccessKey} {
+		if _, err = server.user.getKeyInfo(key); err != nil {
+			t.Errorf("expect ak[%v] valid during rotation, err[%v]", key, err)
+			return
+		}
+	}
+	if _, err = server.user.rotateKey(param); err != proto.ErrKeyRotationInProgress {
+		t.Errorf("expect err[%v], real err[%v]", proto.ErrKeyRotationInProgress, err)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?user=%v", hostAddr, proto.UserRetireKey, testUserID)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if _, err = server.user.getKeyInfo(ak); err != proto.ErrAccessKeyNotExists {
+		t.Errorf("expect ak[%v] retired, err[%v]", ak, err)
+		return
+	}
+	if userInfo.RetiringAccessKey != "" {
+		t.Errorf("expect no retiring ak, real ak[%v]", userInfo.RetiringAccessKey)
+		return
+	}
+}
+
+func TestAssumeRole(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v", hostAddr, proto.UserAssumeRole)
+	param := &proto.UserAssumeRoleParam{UserID: testUserID, Duration: 3600}
+	data, err := json.Marshal(param)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fmt.Println(reqURL)
+	post(reqURL, data, t)
+	session, err := server.user.assumeRole(param)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	userInfo, err := server.user.getKeyInfo(session.AccessKey)
+	if err != nil {
+		t.Errorf("expect session ak[%v] valid, err[%v]", session.AccessKey, err)
+		return
+	}
+	if userInfo.UserID != testUserID || userInfo.SecretKeyOf(session.AccessKey) != session.SecretKey {
+		t.Errorf("expect session ak[%v] of user[%v], real user[%v]", session.AccessKey, testUserID, userInfo.UserID)
+		return
+	}
+	if _, err = server.user.assumeRole(&proto.UserAssumeRoleParam{UserID: testUserID, Duration: 1}); err != proto.ErrParamError {
+		t.Errorf("expect err[%v], real err[%v]", proto.ErrParamError, err)
+		return
+	}
+	session.ExpireTime = time.Now().Unix()
+	if err = server.user.removeExpiredSessions(testUserID); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = server.user.getKeyInfo(session.AccessKey); err != proto.ErrAccessKeyNotExists {
+		t.Errorf("expect session ak[%v] removed, err[%v]", session.AccessKey, err)
+		return
+	}
+}
+
 func TestUpdatePolicy(t *testing.T) {
 	reqURL := fmt.Sprintf("%v%v", hostAddr, proto.UserUpdatePolicy)
 	param := &proto.UserPermUpdateParam{UserID: testUserID, Volume: commonVolName, Policy: []string{proto.BuiltinPermissionWritable.String()}}
@@ -757,6 +1129,35 @@ func TestRemovePolicy(t *testing.T) {
 	}
 }
 
+func TestPrefixPolicy(t *testing.T) {
+	param := &proto.UserPrefixPolicyParam{UserID: testUserID, Volume: commonVolName, Prefix: "logs/teamA/*",
+		Effect: proto.PrefixEffectAllow, Actions: []string{proto.BuiltinPermissionWritable.String()}}
+	data, err := json.Marshal(param)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	post(fmt.Sprintf("%v%v", hostAddr, proto.UserSetPrefixPolicy), data, t)
+	userInfo, err := server.user.getUserInfo(testUserID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !userInfo.Policy.IsAuthorizedKey(commonVolName, "logs/teamA/app.log", proto.OSSPutObjectAction) {
+		t.Errorf("expect put object under prefix %v allowed, but is not", param.Prefix)
+		return
+	}
+	if userInfo.Policy.IsAuthorizedKey(commonVolName, "logs/teamB/app.log", proto.OSSPutObjectAction) {
+		t.Errorf("expect put object out of prefix %v denied, but is allowed", param.Prefix)
+		return
+	}
+	post(fmt.Sprintf("%v%v", hostAddr, proto.UserRemovePrefixPolicy), data, t)
+	if userInfo.Policy.HasPolicyOf(commonVolName) {
+		t.Errorf("expect no policy of vol %v, but is exist", commonVolName)
+		return
+	}
+}
+
 func TestTransferVol(t *testing.T) {
 	reqURL := fmt.Sprintf("%v%v", hostAddr, proto.UserTransferVol)
 	param := &proto.UserTransferVolParam{Volume: commonVolName, UserSrc: "cfs", UserDst: testUserID, Force: false}
@@ -85,6 +85,67 @@ func (m *Server) updateUser(w http.ResponseWriter, r *http.Request) {
 	_ = sendOkReply(w, r, newSuccessHTTPReply(userInfo))
 }
 
+func (m *Server) rotateUserKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		userInfo *proto.UserInfo
+		err      error
+	)
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	var param = proto.UserRotateKeyParam{}
+	if err = json.Unmarshal(bytes, &param); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if userInfo, err = m.user.rotateKey(&param); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	_ = sendOkReply(w, r, newSuccessHTTPReply(userInfo))
+}
+
+func (m *Server) retireUserKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		userID   string
+		userInfo *proto.UserInfo
+		err      error
+	)
+	if userID, err = parseUser(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if userInfo, err = m.user.retireKey(userID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	_ = sendOkReply(w, r, newSuccessHTTPReply(userInfo))
+}
+
+func (m *Server) assumeUserRole(w http.ResponseWriter, r *http.Request) {
+	var (
+		session *proto.SessionCredential
+		err     error
+	)
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	var param = proto.UserAssumeRoleParam{}
+	if err = json.Unmarshal(bytes, &param); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if session, err = m.user.assumeRole(&param); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	_ = sendOkReply(w, r, newSuccessHTTPReply(session))
+}
+
 func (m *Server) getUserAKInfo(w http.ResponseWriter, r *http.Request) {
 	var (
 		ak       string
@@ -171,6 +232,78 @@ func (m *Server) removeUserPolicy(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(userInfo))
 }
 
+func (m *Server) setUserPrefixPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		userInfo *proto.UserInfo
+		param    *proto.UserPrefixPolicyParam
+		err      error
+	)
+	if param, err = parseRequestToPrefixPolicy(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if len(param.Actions) == 0 {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: keyNotFound("actions").Error()})
+		return
+	}
+	for _, action := range param.Actions {
+		if proto.ParsePermission(action).IsNone() && proto.ParseAction(action).IsNone() {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: fmt.Sprintf("invalid action: %v", action)})
+			return
+		}
+	}
+	if _, err = m.cluster.getVol(param.Volume); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
+		return
+	}
+	if userInfo, err = m.user.setPrefixPolicy(param); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(userInfo))
+}
+
+func (m *Server) removeUserPrefixPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		userInfo *proto.UserInfo
+		param    *proto.UserPrefixPolicyParam
+		err      error
+	)
+	if param, err = parseRequestToPrefixPolicy(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if userInfo, err = m.user.removePrefixPolicy(param); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(userInfo))
+}
+
+func parseRequestToPrefixPolicy(r *http.Request) (param *proto.UserPrefixPolicyParam, err error) {
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil {
+		return
+	}
+	param = &proto.UserPrefixPolicyParam{}
+	if err = json.Unmarshal(bytes, param); err != nil {
+		return
+	}
+	if param.UserID == "" {
+		err = keyNotFound("user_id")
+		return
+	}
+	if param.Volume == "" {
+		err = keyNotFound("volume")
+		return
+	}
+	if !param.Effect.Valid() {
+		err = fmt.Errorf("invalid effect: %v", param.Effect)
+		return
+	}
+	return
+}
+
 func (m *Server) deleteUserVolPolicy(w http.ResponseWriter, r *http.Request) {
 	var (
 		vol string
@@ -0,0 +1,241 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	auditEventRetention    = 365 * 24 * time.Hour
+	defaultAuditEventLimit = 100
+	auditRedacted          = "******"
+)
+
+// auditedAPIs are the admin APIs which change the cluster, the requests of which are recorded in the
+// audit log by the leader.
+var auditedAPIs = map[string]bool{
+	proto.AdminClusterFreeze:             true,
+	proto.AddRaftNode:                    true,
+	proto.RemoveRaftNode:                 true,
+	proto.AdminCreateVol:                 true,
+	proto.AdminDeleteVol:                 true,
+	proto.AdminUpdateVol:                 true,
+	proto.AdminVolShrink:                 true,
+	proto.AdminVolExpand:                 true,
+	proto.AdminSetVolTierRule:            true,
+	proto.AdminDeleteVolTierRule:         true,
+	proto.AdminSetVolWormRetention:       true,
+	proto.AdminOverrideVolWorm:           true,
+	proto.AdminCancelTask:                true,
+	proto.AdminPauseTask:                 true,
+	proto.AdminResumeTask:                true,
+	proto.AdminSetRepairLink:             true,
+	proto.AdminSetCordon:                 true,
+	proto.AdminRemoveCordon:              true,
+	proto.AdminSetRebuildPolicy:          true,
+	proto.AdminRemoveRebuildPolicy:       true,
+	proto.AdminSetNFSExport:              true,
+	proto.AdminRemoveNFSExport:           true,
+	proto.AdminDecommissionMetaPartition: true,
+	proto.AdminCreateMetaPartition:       true,
+	proto.AdminAddMetaReplica:            true,
+	proto.AdminDeleteMetaReplica:         true,
+	proto.AdminCreateDataPartition:       true,
+	proto.AdminDecommissionDataPartition: true,
+	proto.AdminAddDataReplica:            true,
+	proto.AdminDeleteDataReplica:         true,
+	proto.AdminResetDataPartitionRaft:    true,
+	proto.DecommissionMetaNode:           true,
+	proto.DecommissionDataNode:           true,
+	proto.DecommissionDisk:               true,
+	proto.AdminRecoverDisk:               true,
+	proto.AdminSetMetaNodeThreshold:      true,
+	proto.AdminUpdateMetaNode:            true,
+	proto.AdminUpdateDataNode:            true,
+	proto.AdminSetDataNodeMaintenance:    true,
+	proto.AdminSetMetaNodeMaintenance:    true,
+	proto.AdminSetDataNodeThrottle:       true,
+	proto.AdminSetNodeInfo:               true,
+	proto.UpdateZone:                     true,
+	proto.CreateNodeSet:                  true,
+	proto.MigrateNodeToNodeSet:           true,
+	proto.UserCreate:                     true,
+	proto.UserDelete:                     true,
+	proto.UserUpdate:                     true,
+	proto.UserUpdatePolicy:               true,
+	proto.UserRemovePolicy:               true,
+	proto.UserSetPrefixPolicy:            true,
+	proto.UserRemovePrefixPolicy:         true,
+	proto.UserDeleteVolPolicy:            true,
+	proto.UserTransferVol:                true,
+	proto.UserRotateKey:                  true,
+	proto.UserRetireKey:                  true,
+	proto.TokenAddURI:                    true,
+	proto.TokenDelURI:                    true,
+	proto.TokenUpdateURI:                 true,
+}
+
+// auditRedactedParams are the parameters carrying credentials, the values of which are not recorded.
+var auditRedactedParams = map[string]bool{
+	volAuthKey: true,
+	tokenKey:   true,
+	"pwd":      true,
+	"sk":       true,
+}
+
+// auditTargetParams are the parameters naming the target of an operation, in the order of precedence.
+var auditTargetParams = []string{nameKey, addrKey, userKey, "user_id", idKey}
+
+// auditRecorder keeps a copy of the reply, from which the result of the operation is recorded.
+type auditRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (rec *auditRecorder) Write(p []byte) (int, error) {
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
+
+// serveAudited serves the admin request and records it in the audit log with the result.
+func (m *Server) serveAudited(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	event := &proto.AuditEvent{
+		Time:         time.Now().Unix(),
+		Client:       r.RemoteAddr,
+		ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		Op:           r.URL.Path,
+		Params:       auditParams(r),
+	}
+	for _, key := range auditTargetParams {
+		if event.Target = event.Params[key]; event.Target != "" {
+			break
+		}
+	}
+	rec := &auditRecorder{ResponseWriter: w}
+	next.ServeHTTP(rec, r)
+	reply := &proto.HTTPReply{}
+	if err := json.Unmarshal(rec.body.Bytes(), reply); err != nil {
+		event.Code, event.Msg = proto.ErrCodeInternalError, strings.TrimSpace(rec.body.String())
+	} else if event.Code = reply.Code; reply.Code != proto.ErrCodeSuccess {
+		event.Msg = reply.Msg
+	}
+	if err := m.cluster.addAuditEvent(event); err != nil {
+		log.LogErrorf("action[serveAudited] op[%v] target[%v] client[%v] err[%v]", event.Op, event.Target, event.Client, err)
+	}
+}
+
+// auditParams collects the query parameters and the fields of the json body of the request, and
+// redacts the credentials. The body is restored for the handler.
+func auditParams(r *http.Request) map[string]string {
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		params[key] = strings.Join(values, ",")
+	}
+	if r.Body != nil {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		fields := make(map[string]interface{})
+		if json.Unmarshal(body, &fields) == nil {
+			for key, value := range fields {
+				params[key] = fmt.Sprint(value)
+			}
+		}
+	}
+	for key := range params {
+		if auditRedactedParams[key] {
+			params[key] = auditRedacted
+		}
+	}
+	return params
+}
+
+// auditFilter selects the audit events, the zero values match all the events.
+type auditFilter struct {
+	from   int64
+	to     int64
+	op     string
+	target string
+	limit  int // the latest events are returned
+}
+
+func (f *auditFilter) match(event *proto.AuditEvent) bool {
+	return (f.from == 0 || event.Time >= f.from) && (f.to == 0 || event.Time < f.to) &&
+		(f.op == "" || strings.Contains(event.Op, f.op)) && (f.target == "" || event.Target == f.target)
+}
+
+// addAuditEvent persists the event by raft, the events are ordered by the allocated ids.
+func (c *Cluster) addAuditEvent(event *proto.AuditEvent) (err error) {
+	if event.ID, err = c.idAlloc.allocateCommonID(); err != nil {
+		return
+	}
+	return c.syncAddAuditEvent(event)
+}
+
+// listAuditEvents returns the latest events selected by the filter in the order of time.
+func (c *Cluster) listAuditEvents(filter *auditFilter) (events []*proto.AuditEvent, err error) {
+	var all []*proto.AuditEvent
+	if all, err = c.loadAuditEvents(); err != nil {
+		return
+	}
+	events = make([]*proto.AuditEvent, 0)
+	for _, event := range all {
+		if filter.match(event) {
+			events = append(events, event)
+		}
+	}
+	if filter.limit > 0 && len(events) > filter.limit {
+		events = events[len(events)-filter.limit:]
+	}
+	return
+}
+
+func (c *Cluster) scheduleToTrimAuditEvents() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.trimAuditEvents(time.Now().Add(-auditEventRetention).Unix())
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// trimAuditEvents deletes the events recorded before the given time.
+func (c *Cluster) trimAuditEvents(before int64) {
+	events, err := c.loadAuditEvents()
+	if err != nil {
+		log.LogErrorf("action[trimAuditEvents] err[%v]", err)
+		return
+	}
+	for _, event := range events {
+		if event.Time >= before {
+			return
+		}
+		if err = c.syncDeleteAuditEvent(event); err != nil {
+			log.LogErrorf("action[trimAuditEvents] id[%v] err[%v]", event.ID, err)
+			return
+		}
+	}
+}
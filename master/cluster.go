@@ -16,6 +16,7 @@ package master
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -55,6 +56,16 @@ type Cluster struct {
 	MasterSecretKey           []byte
 	lastMasterZoneForDataNode string
 	lastMasterZoneForMetaNode string
+	repairLinks               map[string]*proto.RepairLink
+	repairLinkLock            sync.RWMutex
+	cordons                   map[string]*proto.NodeCordon // cordon type and name -> cordon
+	cordonLock                sync.RWMutex
+	rebuildPolicies           map[string]*proto.RebuildPolicy // zone -> policy, the empty zone is the default of the cluster
+	rebuildPolicyLock         sync.RWMutex
+	nfsExports                map[string]*proto.NFSExport // export path -> export
+	nfsExportLock             sync.RWMutex
+	diskRecoveries            map[string]*diskRecovery // data node address -> recovery of its replaced disks
+	diskRecoveryLock          sync.RWMutex
 }
 
 func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition raftstore.Partition, cfg *clusterConfig) (c *Cluster) {
@@ -69,6 +80,11 @@ func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition
 	c.dataNodeStatInfo = new(nodeStatInfo)
 	c.metaNodeStatInfo = new(nodeStatInfo)
 	c.zoneStatInfos = make(map[string]*proto.ZoneStat)
+	c.repairLinks = make(map[string]*proto.RepairLink)
+	c.cordons = make(map[string]*proto.NodeCordon)
+	c.rebuildPolicies = make(map[string]*proto.RebuildPolicy)
+	c.nfsExports = make(map[string]*proto.NFSExport)
+	c.diskRecoveries = make(map[string]*diskRecovery)
 	c.fsm = fsm
 	c.partition = partition
 	c.idAlloc = newIDAllocator(c.fsm.store, c.partition)
@@ -87,7 +103,12 @@ func (c *Cluster) scheduleTask() {
 	c.scheduleToCheckDiskRecoveryProgress()
 	c.scheduleToCheckMetaPartitionRecoveryProgress()
 	c.scheduleToLoadMetaPartitions()
-	c.scheduleToReduceReplicaNum()
+	c.scheduleToAdjustReplicaNum()
+	c.scheduleToCheckTierPolicies()
+	c.scheduleToRecoverReplacedDisks()
+	c.scheduleToRemoveExpiredCordons()
+	c.scheduleToRebuildMissingReplicas()
+	c.scheduleToTrimAuditEvents()
 }
 
 func (c *Cluster) masterAddr() (addr string) {
@@ -163,7 +184,7 @@ func (c *Cluster) checkDataPartitions() {
 	for _, vol := range vols {
 		readWrites := vol.checkDataPartitions(c)
 		vol.dataPartitions.setReadWriteDataPartitions(readWrites, c.Name)
-		vol.dataPartitions.updateResponseCache(true, 0)
+		vol.dataPartitions.updateResponseCache(true, 0, c.getLeaderTerm())
 		msg := fmt.Sprintf("action[checkDataPartitions],vol[%v] can readWrite partitions:%v  ", vol.Name, vol.dataPartitions.readableAndWritableCnt)
 		log.LogInfo(msg)
 	}
@@ -241,6 +262,16 @@ func (c *Cluster) scheduleToCheckHeartbeat() {
 	}()
 }
 
+// getLeaderTerm returns the raft term of the current leader, which is persisted by the raft
+// and never reused by another leader.
+func (c *Cluster) getLeaderTerm() (term uint64) {
+	if c.partition == nil {
+		return
+	}
+	_, term = c.partition.LeaderTerm()
+	return
+}
+
 func (c *Cluster) checkLeaderAddr() {
 	leaderID, _ := c.partition.LeaderTerm()
 	c.leaderInfo.addr = AddrDatabase[leaderID]
@@ -248,10 +279,15 @@ func (c *Cluster) checkLeaderAddr() {
 
 func (c *Cluster) checkDataNodeHeartbeat() {
 	tasks := make([]*proto.AdminTask, 0)
+	volQos := c.getVolQosBudgets()
+	repairBudgets := c.getRepairBudgets()
+	verifyReadVols := c.getVerifyReadVols()
+	volWorms := c.getVolWorms()
 	c.dataNodes.Range(func(addr, dataNode interface{}) bool {
 		node := dataNode.(*DataNode)
 		node.checkLiveness()
-		task := node.createHeartbeatTask(c.masterAddr())
+		task := node.createHeartbeatTask(c.masterAddr(), volQos, repairBudgets[node.ZoneName], verifyReadVols,
+			c.getRebuildBandwidth(node.ZoneName), volWorms)
 		tasks = append(tasks, task)
 		return true
 	})
@@ -295,23 +331,23 @@ func (c *Cluster) checkMetaPartitions() {
 	}
 }
 
-func (c *Cluster) scheduleToReduceReplicaNum() {
+func (c *Cluster) scheduleToAdjustReplicaNum() {
 	go func() {
 		for {
 			if c.partition != nil && c.partition.IsRaftLeader() {
-				c.checkVolReduceReplicaNum()
+				c.checkVolReplicaNum()
 			}
-			time.Sleep(5 * time.Minute)
+			time.Sleep(time.Minute)
 		}
 	}()
 }
 
-func (c *Cluster) checkVolReduceReplicaNum() {
+func (c *Cluster) checkVolReplicaNum() {
 	defer func() {
 		if r := recover(); r != nil {
-			log.LogWarnf("checkVolReduceReplicaNum occurred panic,err[%v]", r)
+			log.LogWarnf("checkVolReplicaNum occurred panic,err[%v]", r)
 			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
-				"checkVolReduceReplicaNum occurred panic")
+				"checkVolReplicaNum occurred panic")
 		}
 	}()
 	vols := c.allVols()
@@ -677,7 +713,10 @@ func (c *Cluster) createDataPartition(volName string, zoneNum int) (dp *DataPart
 	vol.createDpMutex.Lock()
 	defer vol.createDpMutex.Unlock()
 	errChannel := make(chan error, vol.dpReplicaNum)
-	if targetHosts, targetPeers, err = c.chooseTargetDataNodes("", nil, nil, int(vol.dpReplicaNum), zoneNum, vol.zoneName); err != nil {
+	if targetHosts, targetPeers, err = c.chooseTargetDataNodes("", nil, nil, int(vol.dpReplicaNum), zoneNum, c.dataPartitionZone(vol)); err != nil {
+		goto errHandler
+	}
+	if err = c.checkPlacementZones(vol, "data partition", targetHosts, int(vol.dpReplicaNum), c.dataNodeZone); err != nil {
 		goto errHandler
 	}
 	if partitionID, err = c.idAlloc.allocateDataPartitionID(); err != nil {
@@ -795,6 +834,16 @@ func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []ui
 	if excludeZone != "" {
 		excludeZones = append(excludeZones, excludeZone)
 	}
+	// never place new replicas on the cordoned zones and nodes
+	excludeZones = append(excludeZones, c.cordonedNames(proto.CordonTypeZone)...)
+	excludeHosts = append(c.cordonedNames(proto.CordonTypeDataNode), excludeHosts...)
+	// the replicas are spread over the zones listed by the specified zone name
+	if strings.Contains(specifiedZone, zoneNameSeparator) {
+		var listedExcludeZones []string
+		listedExcludeZones, zoneNum = c.specifiedZones(specifiedZone, replicaNum)
+		excludeZones = append(excludeZones, listedExcludeZones...)
+		specifiedZone = ""
+	}
 	if replicaNum <= zoneNum {
 		zoneNum = replicaNum
 	}
@@ -805,6 +854,8 @@ func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []ui
 		zone, err := c.t.getZone(specifiedZone)
 		if err != nil {
 			Warn(c.Name, fmt.Sprintf("cluster[%v],specified zone[%v]is not writable", c.Name, specifiedZone))
+		} else if c.isCordoned(proto.CordonTypeZone, specifiedZone) {
+			Warn(c.Name, fmt.Sprintf("cluster[%v],specified zone[%v]is cordoned", c.Name, specifiedZone))
 		} else {
 			zones = make([]*Zone, 0)
 			zones = append(zones, zone)
@@ -1092,7 +1143,7 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 			} else {
 				excludeZone = zones[0]
 			}
-			if targetHosts, _, err = c.chooseTargetDataNodes(excludeZone, excludeNodeSets, dp.Hosts, 1, 1, ""); err != nil {
+			if targetHosts, err = c.chooseReplacementDataNode(dp, offlineAddr, excludeZone, excludeNodeSets); err != nil {
 				goto errHandler
 			}
 		}
@@ -1376,6 +1427,69 @@ func (c *Cluster) removeDataPartitionRaftMember(dp *DataPartition, removePeer pr
 	return
 }
 
+// resetDataPartitionRaft rebuilds the raft group of the data partition from the surviving replica once the other
+// replicas are lost permanently. The survivor restarts its raft instance as the only member of the group and the
+// replicas on the lost data nodes are removed, so they can be added again with addDataReplica.
+func (c *Cluster) resetDataPartitionRaft(dp *DataPartition, survivor string) (err error) {
+	defer func() {
+		if err != nil {
+			log.LogErrorf("action[resetDataPartitionRaft] vol[%v],data partition[%v],survivor[%v],err[%v]",
+				dp.VolName, dp.PartitionID, survivor, err)
+		}
+	}()
+	dp.offlineMutex.Lock()
+	defer dp.offlineMutex.Unlock()
+	dp.RLock()
+	hosts := make([]string, len(dp.Hosts))
+	copy(hosts, dp.Hosts)
+	dp.RUnlock()
+	found := false
+	for _, host := range hosts {
+		if host == survivor {
+			found = true
+			continue
+		}
+		if dataNode, e := c.dataNode(host); e == nil && dataNode.isActive {
+			err = fmt.Errorf("vol[%v],data partition[%v] replica on data node[%v] is still active, the raft group can be recovered by itself",
+				dp.VolName, dp.PartitionID, host)
+			return
+		}
+	}
+	if !found {
+		err = fmt.Errorf("vol[%v],data partition[%v] has no replica on [%v]", dp.VolName, dp.PartitionID, survivor)
+		return
+	}
+	survivorNode, err := c.dataNode(survivor)
+	if err != nil {
+		return
+	}
+	if !survivorNode.isActive {
+		err = fmt.Errorf("survivor data node[%v] is not active", survivor)
+		return
+	}
+	newPeers := []proto.Peer{{ID: survivorNode.ID, Addr: survivor}}
+	task := dp.createTaskToResetRaftMember(survivor, newPeers)
+	if _, err = survivorNode.TaskManager.syncSendAdminTask(task); err != nil {
+		return
+	}
+	dp.Lock()
+	defer dp.Unlock()
+	for _, host := range hosts {
+		if host == survivor {
+			continue
+		}
+		dp.removeReplicaByAddr(host)
+		dp.checkAndRemoveMissReplica(host)
+	}
+	if err = dp.update("resetDataPartitionRaft", dp.VolName, newPeers, []string{survivor}, c); err != nil {
+		return
+	}
+	msg := fmt.Sprintf("vol[%v],data partition[%v] raft group is reset to survivor[%v], lost replicas%v",
+		dp.VolName, dp.PartitionID, survivor, hosts)
+	Warn(c.Name, msg)
+	return
+}
+
 func (c *Cluster) updateDataPartitionOfflinePeerIDWithLock(dp *DataPartition, peerID uint64) (err error) {
 	dp.Lock()
 	defer dp.Unlock()
@@ -1503,6 +1617,7 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		vol               *Vol
 		serverAuthKey     string
 		oldDpReplicaNum   uint8
+		oldMpReplicaNum   uint8
 		oldCapacity       uint64
 		oldFollowerRead   bool
 		oldAuthenticate   bool
@@ -1511,6 +1626,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		oldDescription    string
 		oldDpSelectorName string
 		oldDpSelectorParm string
+		oldMaxIOPS        uint64
+		oldMaxBandwidth   uint64
+		oldVerifyRead     bool
+		oldTrashDays      uint32
+		oldAntiAffinity   bool
+		oldStrictZones    bool
+		oldAtime          bool
+		oldFileAudit      bool
 		volUsedSpace      uint64
 	)
 	if vol, err = c.getVol(name); err != nil {
@@ -1530,11 +1653,6 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 			volUsedSpace/util.GB)
 		goto errHandler
 	}
-	if newArgs.dpReplicaNum > vol.dpReplicaNum {
-		err = fmt.Errorf("don't support new replicaNum[%v] larger than old dpReplicaNum[%v]", newArgs.dpReplicaNum,
-			vol.dpReplicaNum)
-		goto errHandler
-	}
 	if newArgs.enableToken == true && len(vol.tokens) == 0 {
 		if err = c.createToken(vol, proto.ReadOnlyToken); err != nil {
 			goto errHandler
@@ -1544,19 +1662,13 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		}
 	}
 
-	if vol.crossZone && newArgs.zoneName != "" {
-		err = fmt.Errorf("only the vol which don't across zones,can specified zoneName")
+	if err = c.checkVolZones(newArgs.zoneName, vol.crossZone, newArgs.zoneAntiAffinity, newArgs.strictZones); err != nil {
 		goto errHandler
 	}
-	if newArgs.zoneName != "" {
-		_, err = c.t.getZone(newArgs.zoneName)
-		if err != nil {
-			goto errHandler
-		}
-	}
 
 	oldCapacity = vol.Capacity
 	oldDpReplicaNum = vol.dpReplicaNum
+	oldMpReplicaNum = vol.mpReplicaNum
 	oldFollowerRead = vol.FollowerRead
 	oldAuthenticate = vol.authenticate
 	oldEnableToken = vol.enableToken
@@ -1564,6 +1676,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 	oldDescription = vol.description
 	oldDpSelectorName = vol.dpSelectorName
 	oldDpSelectorParm = vol.dpSelectorParm
+	oldMaxIOPS = vol.maxIOPS
+	oldMaxBandwidth = vol.maxBandwidth
+	oldVerifyRead = vol.verifyRead
+	oldTrashDays = vol.trashDays
+	oldAntiAffinity = vol.zoneAntiAffinity
+	oldStrictZones = vol.strictZones
+	oldAtime = vol.atime
+	oldFileAudit = vol.fileAudit
 
 	vol.zoneName = newArgs.zoneName
 	vol.Capacity = newArgs.capacity
@@ -1573,16 +1693,28 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 	if newArgs.description != "" {
 		vol.description = newArgs.description
 	}
-	//only reduced replica num is supported
-	if newArgs.dpReplicaNum != 0 && newArgs.dpReplicaNum < vol.dpReplicaNum {
+	// the replicas of the existing partitions are adjusted by checkVolReplicaNum gradually
+	if newArgs.dpReplicaNum != 0 {
 		vol.dpReplicaNum = newArgs.dpReplicaNum
 	}
+	if newArgs.mpReplicaNum != 0 {
+		vol.mpReplicaNum = newArgs.mpReplicaNum
+	}
 	vol.dpSelectorName = newArgs.dpSelectorName
 	vol.dpSelectorParm = newArgs.dpSelectorParm
+	vol.maxIOPS = newArgs.maxIOPS
+	vol.maxBandwidth = newArgs.maxBandwidth
+	vol.verifyRead = newArgs.verifyRead
+	vol.trashDays = newArgs.trashDays
+	vol.zoneAntiAffinity = newArgs.zoneAntiAffinity
+	vol.strictZones = newArgs.strictZones
+	vol.atime = newArgs.atime
+	vol.fileAudit = newArgs.fileAudit
 
 	if err = c.syncUpdateVol(vol); err != nil {
 		vol.Capacity = oldCapacity
 		vol.dpReplicaNum = oldDpReplicaNum
+		vol.mpReplicaNum = oldMpReplicaNum
 		vol.FollowerRead = oldFollowerRead
 		vol.authenticate = oldAuthenticate
 		vol.enableToken = oldEnableToken
@@ -1590,6 +1722,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		vol.description = oldDescription
 		vol.dpSelectorName = oldDpSelectorName
 		vol.dpSelectorParm = oldDpSelectorParm
+		vol.maxIOPS = oldMaxIOPS
+		vol.maxBandwidth = oldMaxBandwidth
+		vol.verifyRead = oldVerifyRead
+		vol.trashDays = oldTrashDays
+		vol.zoneAntiAffinity = oldAntiAffinity
+		vol.strictZones = oldStrictZones
+		vol.atime = oldAtime
+		vol.fileAudit = oldFileAudit
 
 		log.LogErrorf("action[updateVol] vol[%v] err[%v]", name, err)
 		err = proto.ErrPersistenceByRaft
@@ -1605,34 +1745,32 @@ errHandler:
 
 // Create a new volume.
 // By default we create 3 meta partitions and 10 data partitions during initialization.
-func (c *Cluster) createVol(name, owner, zoneName, description string, mpCount, dpReplicaNum, size, capacity int, followerRead, authenticate, crossZone, enableToken bool) (vol *Vol, err error) {
+// The zone name of the request is set to the default zone if it is empty and the volume is not across zones.
+func (c *Cluster) createVol(req *createVolReq) (vol *Vol, err error) {
 	var (
+		name                    = req.name
 		dataPartitionSize       uint64
 		readWriteDataPartitions int
 	)
-	if size == 0 {
+	if req.size == 0 {
 		dataPartitionSize = util.DefaultDataPartitionSize
 	} else {
-		dataPartitionSize = uint64(size) * util.GB
+		dataPartitionSize = uint64(req.size) * util.GB
 	}
 
-	if crossZone && c.t.zoneLen() <= 1 {
+	if req.crossZone && c.t.zoneLen() <= 1 {
 		return nil, fmt.Errorf("cluster has one zone,can't cross zone")
 	}
-	if crossZone && zoneName != "" {
-		return nil, fmt.Errorf("only the vol which don't across zones,can specified zoneName")
+	if err = c.checkVolZones(req.zoneName, req.crossZone, req.zoneAntiAffinity, req.strictZones); err != nil {
+		return
 	}
-	if zoneName != "" {
-		if _, err = c.t.getZone(zoneName); err != nil {
-			return
-		}
-	} else if !crossZone {
-		zoneName = DefaultZoneName
+	if req.zoneName == "" && !req.crossZone {
+		req.zoneName = DefaultZoneName
 	}
-	if vol, err = c.doCreateVol(name, owner, zoneName, description, dataPartitionSize, uint64(capacity), dpReplicaNum, followerRead, authenticate, crossZone, enableToken); err != nil {
+	if vol, err = c.doCreateVol(req, dataPartitionSize); err != nil {
 		goto errHandler
 	}
-	if err = vol.initMetaPartitions(c, mpCount); err != nil {
+	if err = vol.initMetaPartitions(c, req.mpCount); err != nil {
 		vol.Status = markDelete
 		if e := vol.deleteVolFromStore(c); e != nil {
 			log.LogErrorf("action[createVol] failed,vol[%v] err[%v]", vol.Name, e)
@@ -1657,8 +1795,11 @@ errHandler:
 	return
 }
 
-func (c *Cluster) doCreateVol(name, owner, zoneName, description string, dpSize, capacity uint64, dpReplicaNum int, followerRead, authenticate, crossZone, enableToken bool) (vol *Vol, err error) {
-	var id uint64
+func (c *Cluster) doCreateVol(req *createVolReq, dpSize uint64) (vol *Vol, err error) {
+	var (
+		id   uint64
+		name = req.name
+	)
 	c.createVolMutex.Lock()
 	defer c.createVolMutex.Unlock()
 	var createTime = time.Now().Unix() // record unix seconds of volume create time
@@ -1670,14 +1811,17 @@ func (c *Cluster) doCreateVol(name, owner, zoneName, description string, dpSize,
 	if err != nil {
 		goto errHandler
 	}
-	vol = newVol(id, name, owner, zoneName, dpSize, capacity, uint8(dpReplicaNum), defaultReplicaNum, followerRead, authenticate, crossZone, enableToken, createTime, description)
+	vol = newVol(id, name, req.owner, req.zoneName, dpSize, uint64(req.capacity), uint8(req.dpReplicaNum), defaultReplicaNum,
+		req.followerRead, req.authenticate, req.crossZone, req.enableToken, createTime, req.description)
+	vol.zoneAntiAffinity = req.zoneAntiAffinity
+	vol.strictZones = req.strictZones
 	// refresh oss secure
 	vol.refreshOSSSecure()
 	if err = c.syncAddVol(vol); err != nil {
 		goto errHandler
 	}
 	c.putVol(vol)
-	if enableToken {
+	if req.enableToken {
 		if err = c.createToken(vol, proto.ReadOnlyToken); err != nil {
 			goto errHandler
 		}
@@ -1736,7 +1880,17 @@ func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []ui
 	if excludeZone != "" {
 		excludeZones = append(excludeZones, excludeZone)
 	}
+	// never place new replicas on the cordoned zones and nodes
+	excludeZones = append(excludeZones, c.cordonedNames(proto.CordonTypeZone)...)
+	excludeHosts = append(c.cordonedNames(proto.CordonTypeMetaNode), excludeHosts...)
 	zoneNum := c.decideZoneNum(crossZone)
+	// the replicas are spread over the zones listed by the specified zone name
+	if strings.Contains(specifiedZone, zoneNameSeparator) {
+		var listedExcludeZones []string
+		listedExcludeZones, zoneNum = c.specifiedZones(specifiedZone, replicaNum)
+		excludeZones = append(excludeZones, listedExcludeZones...)
+		specifiedZone = ""
+	}
 	if replicaNum < zoneNum {
 		zoneNum = replicaNum
 	}
@@ -1747,6 +1901,8 @@ func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []ui
 		zone, err := c.t.getZone(specifiedZone)
 		if err != nil {
 			Warn(c.Name, fmt.Sprintf("cluster[%v],specified zone[%v]is not writable", c.Name, specifiedZone))
+		} else if c.isCordoned(proto.CordonTypeZone, specifiedZone) {
+			Warn(c.Name, fmt.Sprintf("cluster[%v],specified zone[%v]is cordoned", c.Name, specifiedZone))
 		} else {
 			zones = make([]*Zone, 0)
 			zones = append(zones, zone)
@@ -1893,6 +2049,17 @@ func (c *Cluster) allVols() (vols map[string]*Vol) {
 	return
 }
 
+// getVerifyReadVols returns the volumes whose reads are verified by the data nodes.
+func (c *Cluster) getVerifyReadVols() (vols []string) {
+	vols = make([]string, 0)
+	for name, vol := range c.allVols() {
+		if vol.verifyRead {
+			vols = append(vols, name)
+		}
+	}
+	return
+}
+
 func (c *Cluster) getDataPartitionCount() (count int) {
 	c.volMutex.RLock()
 	defer c.volMutex.RUnlock()
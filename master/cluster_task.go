@@ -135,7 +135,7 @@ func (c *Cluster) decommissionMetaPartition(nodeAddr string, mp *MetaPartition)
 				excludeZone = zones[0]
 			}
 			// choose a meta node in other zone
-			if _, newPeers, err = c.chooseTargetMetaHosts(excludeZone, excludeNodeSets, oldHosts, 1, false, ""); err != nil {
+			if newPeers, err = c.chooseReplacementMetaNode(mp, nodeAddr, excludeZone, excludeNodeSets); err != nil {
 				goto errHandler
 			}
 		}
@@ -600,6 +600,9 @@ func (c *Cluster) handleMetaNodeTaskResponse(nodeAddr string, task *proto.AdminT
 	case proto.OpUpdateMetaPartition:
 		response := task.Response.(*proto.UpdateMetaPartitionResponse)
 		err = c.dealUpdateMetaPartitionResp(task.OperatorAddr, response)
+	case proto.OpMetaTierScan:
+		response := task.Response.(*proto.TierScanResponse)
+		err = c.dealMetaTierScanResp(task.OperatorAddr, response)
 	default:
 		err := fmt.Errorf("unknown operate code %v", task.OpCode)
 		log.LogError(err)
@@ -830,6 +833,28 @@ func (c *Cluster) handleResponseToLoadDataPartition(nodeAddr string, resp *proto
 	return
 }
 
+// checkNewBadDisks warns of the disks which the data node has taken offline or predicted to fail since its last
+// heartbeat.
+func (c *Cluster) checkNewBadDisks(dataNode *DataNode, resp *proto.DataNodeHeartbeatResponse) {
+	dataNode.RLock()
+	oldBadDisks, oldFailingDisks := dataNode.BadDisks, dataNode.FailingDisks
+	dataNode.RUnlock()
+	for _, disk := range resp.BadDisks {
+		if contains(oldBadDisks, disk) {
+			continue
+		}
+		Warn(c.Name, fmt.Sprintf("action[checkNewBadDisks] clusterID[%v] dataNode[%v] disk[%v] is offline",
+			c.Name, dataNode.Addr, disk))
+	}
+	for _, disk := range resp.FailingDisks {
+		if contains(oldFailingDisks, disk) {
+			continue
+		}
+		Warn(c.Name, fmt.Sprintf("action[checkNewBadDisks] clusterID[%v] dataNode[%v] disk[%v] is predicted to fail, "+
+			"the partitions on it should be migrated", c.Name, dataNode.Addr, disk))
+	}
+}
+
 func (c *Cluster) handleDataNodeHeartbeatResp(nodeAddr string, resp *proto.DataNodeHeartbeatResponse) (err error) {
 
 	var (
@@ -860,6 +885,7 @@ func (c *Cluster) handleDataNodeHeartbeatResp(nodeAddr string, resp *proto.DataN
 		log.LogWarnf("dataNode zone changed from [%v] to [%v]", oldZoneName, resp.ZoneName)
 	}
 
+	c.checkNewBadDisks(dataNode, resp)
 	dataNode.updateNodeMetric(resp)
 
 	if err = c.t.putDataNode(dataNode); err != nil {
@@ -1000,3 +1026,106 @@ func (c *Cluster) updateInodeIDUpperBound(mp *MetaPartition, mr *proto.MetaParti
 	}
 	return
 }
+
+func (c *Cluster) getAdminTaskManager(nodeAddr string) (sender *AdminTaskManager, err error) {
+	if dataNode, err1 := c.dataNode(nodeAddr); err1 == nil {
+		return dataNode.TaskManager, nil
+	}
+	if metaNode, err1 := c.metaNode(nodeAddr); err1 == nil {
+		return metaNode.Sender, nil
+	}
+	return nil, fmt.Errorf("node[%v] not exists", nodeAddr)
+}
+
+// listAdminTasks returns the unfinished admin tasks of the given node, or of all the nodes if nodeAddr is empty.
+func (c *Cluster) listAdminTasks(nodeAddr string) (views []*proto.AdminTaskView, err error) {
+	var sender *AdminTaskManager
+	if nodeAddr != "" {
+		if sender, err = c.getAdminTaskManager(nodeAddr); err != nil {
+			return
+		}
+		return sender.getTaskViews(), nil
+	}
+	views = make([]*proto.AdminTaskView, 0)
+	c.dataNodes.Range(func(addr, node interface{}) bool {
+		views = append(views, node.(*DataNode).TaskManager.getTaskViews()...)
+		return true
+	})
+	c.metaNodes.Range(func(addr, node interface{}) bool {
+		views = append(views, node.(*MetaNode).Sender.getTaskViews()...)
+		return true
+	})
+	return
+}
+
+// cancelAdminTask removes the task queued on the master for the node. The nodes have no way to
+// interrupt a task, so the task which has been sent and is running on the node is not affected.
+func (c *Cluster) cancelAdminTask(nodeAddr, taskID string) (err error) {
+	var sender *AdminTaskManager
+	if sender, err = c.getAdminTaskManager(nodeAddr); err != nil {
+		return
+	}
+	return sender.cancelTask(taskID)
+}
+
+// pauseAdminTask pauses or resumes sending the task queued on the master for the node,
+// the task which is already running on the node is not affected.
+func (c *Cluster) pauseAdminTask(nodeAddr, taskID string, paused bool) (err error) {
+	var sender *AdminTaskManager
+	if sender, err = c.getAdminTaskManager(nodeAddr); err != nil {
+		return
+	}
+	return sender.setTaskPaused(taskID, paused)
+}
+
+// setDataNodeClientThrottle persists the throttle of the client on the data node, which is sent to the data node
+// with every heartbeat, and sends it to the data node synchronously as well, so that it takes effect immediately.
+// All the throttles of the data node are returned.
+func (c *Cluster) setDataNodeClientThrottle(nodeAddr string, request *proto.ClientThrottleRequest) (throttles []*proto.ClientThrottle, err error) {
+	c.dnMutex.Lock()
+	defer c.dnMutex.Unlock()
+	dataNode, err := c.dataNode(nodeAddr)
+	if err != nil {
+		return nil, proto.ErrDataNodeNotExists
+	}
+	dataNode.Lock()
+	oldThrottles := dataNode.ClientThrottles
+	dataNode.ClientThrottles = make(map[string]*proto.ClientThrottle, len(oldThrottles)+1)
+	for clientIP, throttle := range oldThrottles {
+		dataNode.ClientThrottles[clientIP] = throttle
+	}
+	if request.MaxIOPS == 0 && request.MaxBandwidth == 0 {
+		delete(dataNode.ClientThrottles, request.ClientIP)
+	} else {
+		dataNode.ClientThrottles[request.ClientIP] = &proto.ClientThrottle{
+			ClientIP:     request.ClientIP,
+			MaxIOPS:      request.MaxIOPS,
+			MaxBandwidth: request.MaxBandwidth,
+		}
+	}
+	dataNode.Unlock()
+	if err = c.syncUpdateDataNode(dataNode); err != nil {
+		log.LogErrorf("action[setDataNodeClientThrottle] node[%v] clientIP[%v] err[%v]", nodeAddr, request.ClientIP, err)
+		dataNode.Lock()
+		dataNode.ClientThrottles = oldThrottles
+		dataNode.Unlock()
+		return nil, proto.ErrPersistenceByRaft
+	}
+	task := dataNode.createTaskToSetClientThrottle(request)
+	if _, err = dataNode.TaskManager.syncSendAdminTask(task); err != nil {
+		// the throttle has been persisted, and is applied with the next heartbeat
+		log.LogWarnf("action[setDataNodeClientThrottle] node[%v] clientIP[%v] is applied with the next heartbeat, err[%v]",
+			nodeAddr, request.ClientIP, err)
+		err = nil
+	}
+	return dataNode.getClientThrottles(), nil
+}
+
+// getDataNodeClientThrottles returns the throttles of the clients persisted for the data node.
+func (c *Cluster) getDataNodeClientThrottles(nodeAddr string) (throttles []*proto.ClientThrottle, err error) {
+	dataNode, err := c.dataNode(nodeAddr)
+	if err != nil {
+		return nil, proto.ErrDataNodeNotExists
+	}
+	return dataNode.getClientThrottles(), nil
+}
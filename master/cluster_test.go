@@ -177,6 +177,49 @@ func TestCheckBadDiskRecovery(t *testing.T) {
 	}
 }
 
+func TestRecoverReplacedDisk(t *testing.T) {
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	vol.RLock()
+	dp := vol.dataPartitions.partitions[0]
+	vol.RUnlock()
+	dp.RLock()
+	addr := dp.Hosts[0]
+	dp.RUnlock()
+	req := &proto.DiskRecoveryRequest{Addr: addr, Disks: []string{"/cfs"}, PartitionIDs: []uint64{dp.PartitionID}}
+	view, err := server.cluster.registerDiskRecovery(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(view.Pending) != 1 {
+		t.Errorf("expect pending partitions[%v], real[%v]", req.PartitionIDs, view.Pending)
+		return
+	}
+	server.cluster.recoverReplacedDisks()
+	views := server.cluster.getDiskRecoveries(addr)
+	if len(views) != 1 || len(views[0].Pending) != 0 || len(views[0].Recovering) != 1 {
+		t.Errorf("expect partition[%v] recovering, real views[%v]", dp.PartitionID, views)
+		return
+	}
+	server.cluster.checkDiskRecoveryProgress()
+	server.cluster.recoverReplacedDisks()
+	views = server.cluster.getDiskRecoveries(addr)
+	if len(views) != 1 || len(views[0].Recovered) != 1 {
+		t.Errorf("expect partition[%v] recovered, real views[%v]", dp.PartitionID, views)
+		return
+	}
+	dp.RLock()
+	hosts := dp.Hosts
+	dp.RUnlock()
+	if !contains(hosts, addr) {
+		t.Errorf("expect replica on node[%v], real hosts[%v]", addr, hosts)
+	}
+}
+
 func TestPanicCheckBadMetaPartitionRecovery(t *testing.T) {
 	c := buildPanicCluster()
 	vol, err := c.getVol(commonVolName)
@@ -38,6 +38,7 @@ const (
 	cfgMetaNodeReservedMem              = "metaNodeReservedMem"
 	heartbeatPortKey                    = "heartbeatPort"
 	replicaPortKey                      = "replicaPort"
+	intervalToCheckTierPolicy           = "intervalToCheckTierPolicy"
 )
 
 //default value
@@ -65,6 +66,9 @@ const (
 	defaultMaxMetaPartitionCountOnEachNode             = 10000
 	defaultReplicaNum                                  = 3
 	defaultDiffSpaceUsage                              = 1024 * 1024 * 1024
+	defaultIntervalToCheckTierPolicy                   = 60 * 60
+	defaultMaxReplicaNum                               = 5
+	defaultAdjustReplicaPartitionCount                 = 10 // partitions of a volume adjusting replicas at the same time
 )
 
 // AddrDatabase is a map that stores the address of a given host (e.g., the leader)
@@ -92,6 +96,7 @@ type clusterConfig struct {
 	heartbeatPort                       int64
 	replicaPort                         int64
 	diffSpaceUsage                      uint64
+	IntervalToCheckTierPolicy           int64 // seconds
 }
 
 func newClusterConfig() (cfg *clusterConfig) {
@@ -108,6 +113,7 @@ func newClusterConfig() (cfg *clusterConfig) {
 	cfg.MetaNodeThreshold = defaultMetaPartitionMemUsageThreshold
 	cfg.metaNodeReservedMem = defaultMetaNodeReservedMem
 	cfg.diffSpaceUsage = defaultDiffSpaceUsage
+	cfg.IntervalToCheckTierPolicy = defaultIntervalToCheckTierPolicy
 	return
 }
 
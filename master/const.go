@@ -55,6 +55,39 @@ const (
 	descriptionKey          = "description"
 	dpSelectorNameKey       = "dpSelectorName"
 	dpSelectorParmKey       = "dpSelectorParm"
+	tierRuleNameKey         = "ruleName"
+	inactiveDaysKey         = "inactiveDays"
+	viewEpochKey            = "epoch"
+	maxIOPSKey              = "maxIOPS"
+	maxBandwidthKey         = "maxBandwidth"
+	verifyReadKey           = "verifyRead"
+	trashDaysKey            = "trashDays"
+	atimeKey                = "atime"
+	fileAuditKey            = "fileAudit"
+	mpReplicaNumKey         = "mpReplicaNum"
+	clientIPKey             = "clientIP"
+	retentionDaysKey        = "retentionDays"
+	durationKey             = "duration"
+	taskIDKey               = "taskID"
+	srcZoneKey              = "srcZone"
+	dstZoneKey              = "dstZone"
+	cordonTypeKey           = "type"
+	reasonKey               = "reason"
+	zoneAntiAffinityKey     = "zoneAntiAffinity"
+	strictZonesKey          = "strictZones"
+	untilKey                = "until"
+	exportPathKey           = "path"
+	exportClientsKey        = "clients"
+	readOnlyKey             = "readOnly"
+	rootSquashKey           = "rootSquash"
+	fromKey                 = "from"
+	toKey                   = "to"
+	limitKey                = "limit"
+	opKey                   = "op"
+	targetKey               = "target"
+	zoneKey                 = "zone"
+	delayKey                = "delay"
+	nodeSetIDKey            = "nodeSetId"
 )
 
 const (
@@ -64,6 +97,7 @@ const (
 	dataNodeOfflineErr            = "dataNodeOfflineErr "
 	diskOfflineErr                = "diskOfflineErr "
 	handleDataPartitionOfflineErr = "handleDataPartitionOffLineErr "
+	replicaRebuildErr             = "replicaRebuildErr "
 )
 
 const (
@@ -140,6 +174,9 @@ const (
 	OpSyncAddToken    uint32 = 0x20
 	OpSyncDelToken    uint32 = 0x21
 	OpSyncUpdateToken uint32 = 0x22
+
+	opSyncAddAuditEvent    uint32 = 0x23
+	opSyncDeleteAuditEvent uint32 = 0x24
 )
 
 const (
@@ -171,4 +208,7 @@ const (
 	userPrefix     = keySeparator + userAcronym + keySeparator
 	volUserPrefix  = keySeparator + volUserAcronym + keySeparator
 	TokenPrefix    = keySeparator + tokenAcronym + keySeparator
+
+	auditAcronym = "audit"
+	auditPrefix  = keySeparator + auditAcronym + keySeparator
 )
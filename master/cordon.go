@@ -0,0 +1,197 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func cordonKey(cordonType, name string) string {
+	return cordonType + keySeparator + name
+}
+
+// getCordons returns the cordons which are still active.
+func (c *Cluster) getCordons() (cordons []*proto.NodeCordon) {
+	now := time.Now().Unix()
+	c.cordonLock.RLock()
+	defer c.cordonLock.RUnlock()
+	cordons = make([]*proto.NodeCordon, 0, len(c.cordons))
+	for _, cordon := range c.cordons {
+		if cordon.IsActive(now) {
+			cordons = append(cordons, cordon)
+		}
+	}
+	sort.Slice(cordons, func(i, j int) bool {
+		if cordons[i].Type != cordons[j].Type {
+			return cordons[i].Type < cordons[j].Type
+		}
+		return cordons[i].Name < cordons[j].Name
+	})
+	return
+}
+
+func (c *Cluster) loadCordons(cordons []*proto.NodeCordon) {
+	c.cordonLock.Lock()
+	defer c.cordonLock.Unlock()
+	c.cordons = make(map[string]*proto.NodeCordon, len(cordons))
+	for _, cordon := range cordons {
+		c.cordons[cordonKey(cordon.Type, cordon.Name)] = cordon
+	}
+}
+
+// cordonedNames returns the names of the zones or the addresses of the nodes cordoned by the given type.
+func (c *Cluster) cordonedNames(cordonType string) (names []string) {
+	now := time.Now().Unix()
+	c.cordonLock.RLock()
+	defer c.cordonLock.RUnlock()
+	names = make([]string, 0)
+	for _, cordon := range c.cordons {
+		if cordon.Type == cordonType && cordon.IsActive(now) {
+			names = append(names, cordon.Name)
+		}
+	}
+	return
+}
+
+func (c *Cluster) isCordoned(cordonType, name string) bool {
+	c.cordonLock.RLock()
+	defer c.cordonLock.RUnlock()
+	cordon, ok := c.cordons[cordonKey(cordonType, name)]
+	return ok && cordon.IsActive(time.Now().Unix())
+}
+
+func (c *Cluster) checkCordonTarget(cordonType, name string) (err error) {
+	switch cordonType {
+	case proto.CordonTypeZone:
+		if _, err = c.t.getZone(name); err != nil {
+			return proto.ErrZoneNotExists
+		}
+	case proto.CordonTypeDataNode:
+		if _, err = c.dataNode(name); err != nil {
+			return proto.ErrDataNodeNotExists
+		}
+	case proto.CordonTypeMetaNode:
+		if _, err = c.metaNode(name); err != nil {
+			return proto.ErrMetaNodeNotExists
+		}
+	default:
+		return proto.ErrParamError
+	}
+	return
+}
+
+// setCordon cordons a zone or a node, the allocator places no new replicas on it until the cordon
+// is removed or expires.
+func (c *Cluster) setCordon(cordon *proto.NodeCordon) (err error) {
+	if err = c.checkCordonTarget(cordon.Type, cordon.Name); err != nil {
+		return
+	}
+	cordon.Since = time.Now().Unix()
+	if cordon.Until != 0 && cordon.Until <= cordon.Since {
+		return proto.ErrParamError
+	}
+	key := cordonKey(cordon.Type, cordon.Name)
+	c.cordonLock.Lock()
+	oldCordon, existed := c.cordons[key]
+	c.cordons[key] = cordon
+	c.cordonLock.Unlock()
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setCordon] type(%v) name(%v) err[%v]", cordon.Type, cordon.Name, err)
+		c.restoreCordon(key, oldCordon, existed)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[setCordon] type(%v) name(%v) reason(%v) until(%v)", cordon.Type, cordon.Name, cordon.Reason, cordon.Until)
+	return
+}
+
+func (c *Cluster) removeCordon(cordonType, name string) (err error) {
+	key := cordonKey(cordonType, name)
+	c.cordonLock.Lock()
+	oldCordon, existed := c.cordons[key]
+	delete(c.cordons, key)
+	c.cordonLock.Unlock()
+	if !existed {
+		return
+	}
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[removeCordon] type(%v) name(%v) err[%v]", cordonType, name, err)
+		c.restoreCordon(key, oldCordon, existed)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[removeCordon] type(%v) name(%v)", cordonType, name)
+	return
+}
+
+func (c *Cluster) restoreCordon(key string, cordon *proto.NodeCordon, existed bool) {
+	c.cordonLock.Lock()
+	defer c.cordonLock.Unlock()
+	if existed {
+		c.cordons[key] = cordon
+	} else {
+		delete(c.cordons, key)
+	}
+}
+
+// removeExpiredCordons drops the expired cordons from the persisted cluster value,
+// they have already been ignored by the allocator since they expired.
+func (c *Cluster) removeExpiredCordons() {
+	now := time.Now().Unix()
+	expired := make([]*proto.NodeCordon, 0)
+	c.cordonLock.Lock()
+	for key, cordon := range c.cordons {
+		if !cordon.IsActive(now) {
+			expired = append(expired, cordon)
+			delete(c.cordons, key)
+		}
+	}
+	c.cordonLock.Unlock()
+	if len(expired) == 0 {
+		return
+	}
+
+	if err := c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[removeExpiredCordons] err[%v]", err)
+		c.cordonLock.Lock()
+		for _, cordon := range expired {
+			key := cordonKey(cordon.Type, cordon.Name)
+			if _, ok := c.cordons[key]; !ok {
+				c.cordons[key] = cordon
+			}
+		}
+		c.cordonLock.Unlock()
+		return
+	}
+	for _, cordon := range expired {
+		log.LogInfof("action[removeExpiredCordons] type(%v) name(%v) reason(%v) expired at(%v)",
+			cordon.Type, cordon.Name, cordon.Reason, time.Unix(cordon.Until, 0).Format(time.RFC3339))
+	}
+}
+
+func (c *Cluster) scheduleToRemoveExpiredCordons() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.removeExpiredCordons()
+			}
+			time.Sleep(time.Minute)
+		}
+	}()
+}
@@ -16,6 +16,7 @@ package master
 
 import (
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -43,7 +44,11 @@ type DataNode struct {
 	NodeSetID                 uint64
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string
+	FailingDisks              []string // disks predicted to fail by their SMART attributes
 	ToBeOffline               bool
+	Maintenance               bool
+	MaintenanceSince          int64
+	ClientThrottles           map[string]*proto.ClientThrottle `graphql:"-"` // the throttles of the clients by the IPs
 }
 
 func newDataNode(addr, zoneName, clusterID string) (dataNode *DataNode) {
@@ -89,6 +94,7 @@ func (dataNode *DataNode) updateNodeMetric(resp *proto.DataNodeHeartbeatResponse
 	dataNode.DataPartitionCount = resp.CreatedPartitionCnt
 	dataNode.DataPartitionReports = resp.PartitionReports
 	dataNode.BadDisks = resp.BadDisks
+	dataNode.FailingDisks = resp.FailingDisks
 	if dataNode.Total == 0 {
 		dataNode.UsageRatio = 0.0
 	} else {
@@ -102,7 +108,7 @@ func (dataNode *DataNode) isWriteAble() (ok bool) {
 	dataNode.RLock()
 	defer dataNode.RUnlock()
 
-	if dataNode.isActive == true && dataNode.AvailableSpace > 10*util.GB {
+	if dataNode.isActive == true && !dataNode.Maintenance && dataNode.AvailableSpace > 10*util.GB {
 		ok = true
 	}
 
@@ -116,6 +122,18 @@ func (dataNode *DataNode) isAvailCarryNode() (ok bool) {
 	return dataNode.Carry >= 1
 }
 
+// getClientThrottles returns the throttles of the clients on the data node, sorted by the client IPs.
+func (dataNode *DataNode) getClientThrottles() (throttles []*proto.ClientThrottle) {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	throttles = make([]*proto.ClientThrottle, 0, len(dataNode.ClientThrottles))
+	for _, throttle := range dataNode.ClientThrottles {
+		throttles = append(throttles, throttle)
+	}
+	sort.Slice(throttles, func(i, j int) bool { return throttles[i].ClientIP < throttles[j].ClientIP })
+	return
+}
+
 func (dataNode *DataNode) GetID() uint64 {
 	dataNode.RLock()
 	defer dataNode.RUnlock()
@@ -148,10 +166,23 @@ func (dataNode *DataNode) clean() {
 	dataNode.TaskManager.exitCh <- struct{}{}
 }
 
-func (dataNode *DataNode) createHeartbeatTask(masterAddr string) (task *proto.AdminTask) {
+func (dataNode *DataNode) createTaskToSetClientThrottle(request *proto.ClientThrottleRequest) (task *proto.AdminTask) {
+	task = proto.NewAdminTask(proto.OpDataNodeClientThrottle, dataNode.Addr, request)
+	return
+}
+
+func (dataNode *DataNode) createHeartbeatTask(masterAddr string, volQos map[string]*proto.VolQosBudget,
+	repairBandwidth map[string]uint64, verifyReadVols []string, rebuildBandwidth uint64,
+	volWorms map[string]*proto.VolWorm) (task *proto.AdminTask) {
 	request := &proto.HeartBeatRequest{
-		CurrTime:   time.Now().Unix(),
-		MasterAddr: masterAddr,
+		CurrTime:         time.Now().Unix(),
+		MasterAddr:       masterAddr,
+		VolQos:           volQos,
+		RepairBandwidth:  repairBandwidth,
+		VerifyReadVols:   verifyReadVols,
+		RebuildBandwidth: rebuildBandwidth,
+		VolWorms:         volWorms,
+		ClientThrottles:  dataNode.getClientThrottles(),
 	}
 	task = proto.NewAdminTask(proto.OpDataNodeHeartbeat, dataNode.Addr, request)
 	return
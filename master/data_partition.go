@@ -121,6 +121,12 @@ func (partition *DataPartition) createTaskToRemoveRaftMember(removePeer proto.Pe
 	return
 }
 
+func (partition *DataPartition) createTaskToResetRaftMember(addr string, newPeers []proto.Peer) (task *proto.AdminTask) {
+	task = proto.NewAdminTask(proto.OpResetDataPartitionRaftMember, addr, newResetDataPartitionRaftMemberRequest(partition.PartitionID, newPeers))
+	partition.resetTaskID(task)
+	return
+}
+
 func (partition *DataPartition) createTaskToCreateDataPartition(addr string, dataPartitionSize uint64, peers []proto.Peer, hosts []string, createType int) (task *proto.AdminTask) {
 
 	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, newCreateDataPartitionRequest(
@@ -48,7 +48,7 @@ func (partition *DataPartition) checkStatus(clusterName string, needLog bool, dp
 		msg := fmt.Sprintf("action[extractStatus],partitionID:%v  replicaNum:%v  liveReplicas:%v   Status:%v  RocksDBHost:%v ",
 			partition.PartitionID, partition.ReplicaNum, len(liveReplicas), partition.Status, partition.Hosts)
 		log.LogInfo(msg)
-		if time.Now().Unix()-partition.lastWarnTime > intervalToWarnDataPartition {
+		if time.Now().Unix()-partition.lastWarnTime > intervalToWarnDataPartition && !partition.missingReplicasInMaintenance(liveReplicas) {
 			Warn(clusterName, msg)
 			partition.lastWarnTime = time.Now().Unix()
 		}
@@ -101,6 +101,9 @@ func (partition *DataPartition) checkMissingReplicas(clusterID, leaderAddr strin
 	for _, replica := range partition.Replicas {
 		if partition.hasHost(replica.Addr) && replica.isMissing(dataPartitionMissSec) == true && partition.needToAlarmMissingDataPartition(replica.Addr, dataPartitionWarnInterval) {
 			dataNode := replica.getReplicaNode()
+			if dataNode != nil && dataNode.inMaintenance() {
+				continue
+			}
 			var (
 				lastReportTime time.Time
 			)
@@ -33,6 +33,7 @@ type DataPartitionMap struct {
 	lastReleasedIndex      uint64 // last released partition index
 	partitions             []*DataPartition
 	responseCache          []byte
+	viewLog                *viewChangeLog
 	volName                string
 }
 
@@ -41,6 +42,7 @@ func newDataPartitionMap(volName string) (dpMap *DataPartitionMap) {
 	dpMap.partitionMap = make(map[uint64]*DataPartition, 0)
 	dpMap.partitions = make([]*DataPartition, 0)
 	dpMap.responseCache = make([]byte, 0)
+	dpMap.viewLog = newViewChangeLog()
 	dpMap.volName = volName
 	return
 }
@@ -98,7 +100,8 @@ func (dpMap *DataPartitionMap) setDataPartitionResponseCache(responseCache []byt
 	}
 }
 
-func (dpMap *DataPartitionMap) updateResponseCache(needsUpdate bool, minPartitionID uint64) (body []byte, err error) {
+// updateResponseCache rebuilds the cached view if needed, the term is the raft term of the leader which issues the view epochs.
+func (dpMap *DataPartitionMap) updateResponseCache(needsUpdate bool, minPartitionID uint64, term uint64) (body []byte, err error) {
 	responseCache := dpMap.getDataPartitionResponseCache()
 	if responseCache == nil || needsUpdate || len(responseCache) == 0 {
 		dpResps := dpMap.getDataPartitionsView(minPartitionID)
@@ -107,8 +110,13 @@ func (dpMap *DataPartitionMap) updateResponseCache(needsUpdate bool, minPartitio
 				dpMap.volName, minPartitionID, proto.ErrNoAvailDataPartition))
 			return nil, proto.ErrNoAvailDataPartition
 		}
+		view := make(map[uint64]interface{}, len(dpResps))
+		for _, dpResp := range dpResps {
+			view[dpResp.PartitionID] = dpResp
+		}
 		cv := proto.NewDataPartitionsView()
 		cv.DataPartitions = dpResps
+		cv.ViewEpoch = dpMap.viewLog.update(term, view)
 		reply := newSuccessHTTPReply(cv)
 		if body, err = json.Marshal(reply); err != nil {
 			log.LogError(fmt.Sprintf("action[updateDpResponseCache],minPartitionID:%v,err:%v",
@@ -124,6 +132,31 @@ func (dpMap *DataPartitionMap) updateResponseCache(needsUpdate bool, minPartitio
 	return
 }
 
+// getDataPartitionsDeltaView returns the data partitions changed since the given view epoch,
+// or the full view if the delta cannot be calculated from the epoch.
+func (dpMap *DataPartitionMap) getDataPartitionsDeltaView(epoch uint64, term uint64) (body []byte, err error) {
+	if body, err = dpMap.updateResponseCache(false, 0, term); err != nil {
+		return
+	}
+	current, updated, removed, ok := dpMap.viewLog.delta(term, epoch)
+	if !ok {
+		return
+	}
+	cv := proto.NewDataPartitionsView()
+	cv.ViewEpoch = current
+	cv.IsDelta = true
+	cv.RemovedPartitions = removed
+	for _, v := range updated {
+		cv.DataPartitions = append(cv.DataPartitions, v.(*proto.DataPartitionResponse))
+	}
+	if body, err = json.Marshal(newSuccessHTTPReply(cv)); err != nil {
+		log.LogError(fmt.Sprintf("action[getDataPartitionsDeltaView],volName[%v] epoch:%v,err:%v",
+			dpMap.volName, epoch, err.Error()))
+		return nil, proto.ErrMarshalData
+	}
+	return
+}
+
 func (dpMap *DataPartitionMap) getDataPartitionsView(minPartitionID uint64) (dpResps []*proto.DataPartitionResponse) {
 	dpResps = make([]*proto.DataPartitionResponse, 0)
 	log.LogDebugf("volName[%v] DataPartitionMapLen[%v],DataPartitionsLen[%v],minPartitionID[%v]",
@@ -229,6 +262,29 @@ func (dpMap *DataPartitionMap) totalUsedSpace() (totalUsed uint64) {
 	return
 }
 
+// hostCount returns the number of data nodes that hold the partitions.
+func (dpMap *DataPartitionMap) hostCount() int {
+	hosts := make(map[string]bool, 0)
+	dpMap.RLock()
+	defer dpMap.RUnlock()
+	for _, dp := range dpMap.partitions {
+		dp.RLock()
+		for _, host := range dp.Hosts {
+			hosts[host] = true
+		}
+		dp.RUnlock()
+	}
+	return len(hosts)
+}
+
+func (dpMap *DataPartitionMap) clonePartitions() (partitions []*DataPartition) {
+	dpMap.RLock()
+	defer dpMap.RUnlock()
+	partitions = make([]*DataPartition, len(dpMap.partitions))
+	copy(partitions, dpMap.partitions)
+	return
+}
+
 func (dpMap *DataPartitionMap) setAllDataPartitionsToReadOnly() {
 	dpMap.Lock()
 	defer dpMap.Unlock()
@@ -51,11 +51,10 @@ func (c *Cluster) checkDiskRecoveryProgress() {
 			if err != nil {
 				continue
 			}
-			vol, err := c.getVol(partition.VolName)
-			if err != nil {
+			if _, err = c.getVol(partition.VolName); err != nil {
 				continue
 			}
-			if len(partition.Replicas) == 0 || len(partition.Replicas) < int(vol.dpReplicaNum) {
+			if len(partition.Replicas) == 0 || len(partition.Replicas) < int(partition.ReplicaNum) {
 				continue
 			}
 			diff = partition.getMinus()
@@ -0,0 +1,271 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// replicas re-created on a data node in one round, the others wait for the next round
+	diskRecoveryBatchSize = 16
+	// the finished recoveries are kept for a while, so that the operators could check them
+	diskRecoveryRetention = 24 * time.Hour
+)
+
+// diskRecovery tracks the re-creation of the replicas lost with the replaced disks of a data node.
+type diskRecovery struct {
+	sync.Mutex
+	addr         string
+	disks        []string
+	startTime    int64
+	finishTime   int64
+	partitionIDs []uint64
+	created      map[uint64]bool
+	failed       map[uint64]string
+}
+
+func newDiskRecovery(addr string) *diskRecovery {
+	return &diskRecovery{
+		addr:      addr,
+		startTime: time.Now().Unix(),
+		created:   make(map[uint64]bool),
+		failed:    make(map[uint64]string),
+	}
+}
+
+func (r *diskRecovery) pending() (partitionIDs []uint64) {
+	r.Lock()
+	defer r.Unlock()
+	partitionIDs = make([]uint64, 0)
+	for _, id := range r.partitionIDs {
+		if !r.created[id] {
+			partitionIDs = append(partitionIDs, id)
+		}
+	}
+	return
+}
+
+// registerDiskRecovery accepts the partitions lost with the replaced disks of a data node, the partitions
+// are merged into the unfinished recovery of the data node if there is one.
+func (c *Cluster) registerDiskRecovery(req *proto.DiskRecoveryRequest) (view *proto.DiskRecoveryView, err error) {
+	if _, err = c.dataNode(req.Addr); err != nil {
+		return
+	}
+	partitionIDs := make([]uint64, 0, len(req.PartitionIDs))
+	for _, id := range req.PartitionIDs {
+		var dp *DataPartition
+		if dp, err = c.getDataPartitionByID(id); err != nil {
+			return
+		}
+		dp.RLock()
+		hasHost := dp.hasHost(req.Addr)
+		dp.RUnlock()
+		if !hasHost {
+			return nil, fmt.Errorf("data node[%v] is not a replica of data partition[%v]", req.Addr, id)
+		}
+		partitionIDs = append(partitionIDs, id)
+	}
+
+	c.diskRecoveryLock.Lock()
+	recovery, ok := c.diskRecoveries[req.Addr]
+	if !ok || recovery.finishTime != 0 {
+		recovery = newDiskRecovery(req.Addr)
+		c.diskRecoveries[req.Addr] = recovery
+	}
+	c.diskRecoveryLock.Unlock()
+
+	recovery.Lock()
+	for _, disk := range req.Disks {
+		if !contains(recovery.disks, disk) {
+			recovery.disks = append(recovery.disks, disk)
+		}
+	}
+	for _, id := range partitionIDs {
+		if !containsID(recovery.partitionIDs, id) {
+			recovery.partitionIDs = append(recovery.partitionIDs, id)
+		}
+		// the replica is lost again if the data node reports it once more
+		delete(recovery.created, id)
+	}
+	recovery.finishTime = 0
+	recovery.Unlock()
+	msg := fmt.Sprintf("action[registerDiskRecovery] data node[%v] replaced disks%v, lost partitions%v",
+		req.Addr, req.Disks, partitionIDs)
+	log.LogWarn(msg)
+	Warn(c.Name, msg)
+	return c.diskRecoveryView(recovery), nil
+}
+
+func (c *Cluster) scheduleToRecoverReplacedDisks() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.recoverReplacedDisks()
+			}
+			time.Sleep(time.Second * defaultIntervalToCheckDataPartition)
+		}
+	}()
+}
+
+func (c *Cluster) recoverReplacedDisks() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("recoverReplacedDisks occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
+				"recoverReplacedDisks occurred panic")
+		}
+	}()
+	c.diskRecoveryLock.Lock()
+	recoveries := make([]*diskRecovery, 0, len(c.diskRecoveries))
+	for addr, recovery := range c.diskRecoveries {
+		recovery.Lock()
+		if recovery.finishTime != 0 && time.Since(time.Unix(recovery.finishTime, 0)) > diskRecoveryRetention {
+			delete(c.diskRecoveries, addr)
+		} else if recovery.finishTime == 0 {
+			recoveries = append(recoveries, recovery)
+		}
+		recovery.Unlock()
+	}
+	c.diskRecoveryLock.Unlock()
+
+	for _, recovery := range recoveries {
+		dataNode, err := c.dataNode(recovery.addr)
+		if err != nil {
+			continue
+		}
+		dataNode.RLock()
+		isActive := dataNode.isActive
+		dataNode.RUnlock()
+		if !isActive {
+			continue
+		}
+		pending := recovery.pending()
+		if len(pending) > diskRecoveryBatchSize {
+			pending = pending[:diskRecoveryBatchSize]
+		}
+		for _, id := range pending {
+			err = c.recreateDataReplica(dataNode, id)
+			recovery.Lock()
+			if err != nil {
+				recovery.failed[id] = err.Error()
+			} else {
+				recovery.created[id] = true
+				delete(recovery.failed, id)
+			}
+			recovery.Unlock()
+		}
+		view := c.diskRecoveryView(recovery)
+		if len(view.Pending) == 0 && len(view.Recovering) == 0 {
+			recovery.Lock()
+			recovery.finishTime = time.Now().Unix()
+			recovery.Unlock()
+			Warn(c.Name, fmt.Sprintf("clusterID[%v] data node[%v] replaced disks%v have recovered success",
+				c.Name, recovery.addr, view.Disks))
+		}
+	}
+}
+
+// recreateDataReplica creates the lost replica of the data partition on the data node again, the replica
+// keeps its place in the raft group and catches up the data from the other replicas.
+func (c *Cluster) recreateDataReplica(dataNode *DataNode, partitionID uint64) (err error) {
+	var dp *DataPartition
+	if dp, err = c.getDataPartitionByID(partitionID); err != nil {
+		return
+	}
+	dp.Lock()
+	if !dp.hasHost(dataNode.Addr) {
+		dp.Unlock()
+		return fmt.Errorf("data node[%v] is not a replica of data partition[%v]", dataNode.Addr, partitionID)
+	}
+	// the replica reported by the former disk is gone
+	dp.removeReplicaByAddr(dataNode.Addr)
+	dp.Unlock()
+	if err = c.createDataReplica(dp, proto.Peer{ID: dataNode.ID, Addr: dataNode.Addr}); err != nil {
+		return
+	}
+	dp.Lock()
+	replica, _ := dp.getReplica(dataNode.Addr)
+	dp.Status = proto.ReadOnly
+	dp.isRecover = true
+	dp.Unlock()
+	c.putBadDataPartitionIDs(replica, dataNode.Addr, dp.PartitionID)
+	dp.RLock()
+	c.syncUpdateDataPartition(dp)
+	dp.RUnlock()
+	log.LogWarnf("action[recreateDataReplica] clusterID[%v] partitionID[%v] re-created on node[%v]",
+		c.Name, partitionID, dataNode.Addr)
+	return
+}
+
+func (c *Cluster) diskRecoveryView(recovery *diskRecovery) (view *proto.DiskRecoveryView) {
+	recovery.Lock()
+	view = &proto.DiskRecoveryView{
+		Addr:       recovery.addr,
+		Disks:      append([]string(nil), recovery.disks...),
+		StartTime:  recovery.startTime,
+		Pending:    make([]uint64, 0),
+		Recovering: make([]uint64, 0),
+		Recovered:  make([]uint64, 0),
+		Failed:     make(map[uint64]string),
+	}
+	created := make([]uint64, 0)
+	for _, id := range recovery.partitionIDs {
+		if recovery.created[id] {
+			created = append(created, id)
+			continue
+		}
+		view.Pending = append(view.Pending, id)
+		if msg, ok := recovery.failed[id]; ok {
+			view.Failed[id] = msg
+		}
+	}
+	recovery.Unlock()
+	for _, id := range created {
+		dp, err := c.getDataPartitionByID(id)
+		if err == nil && c.isRecovering(dp, recovery.addr) {
+			view.Recovering = append(view.Recovering, id)
+		} else {
+			view.Recovered = append(view.Recovered, id)
+		}
+	}
+	return
+}
+
+// getDiskRecoveries returns the recoveries of the replaced disks, of all the data nodes if the address is empty.
+func (c *Cluster) getDiskRecoveries(addr string) (views []*proto.DiskRecoveryView) {
+	c.diskRecoveryLock.RLock()
+	recoveries := make([]*diskRecovery, 0, len(c.diskRecoveries))
+	for nodeAddr, recovery := range c.diskRecoveries {
+		if addr == "" || addr == nodeAddr {
+			recoveries = append(recoveries, recovery)
+		}
+	}
+	c.diskRecoveryLock.RUnlock()
+	views = make([]*proto.DiskRecoveryView, 0, len(recoveries))
+	for _, recovery := range recoveries {
+		views = append(views, c.diskRecoveryView(recovery))
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Addr < views[j].Addr
+	})
+	return
+}
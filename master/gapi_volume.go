@@ -66,6 +66,8 @@ func (s *VolumeService) registerObject(schema *schemabuilder.Schema) {
 			NeedToLowerReplica: vol.NeedToLowerReplica,
 			Authenticate:       vol.authenticate,
 			CrossZone:          vol.crossZone,
+			ZoneAntiAffinity:   vol.zoneAntiAffinity,
+			StrictZones:        vol.strictZones,
 			EnableToken:        vol.enableToken,
 			Tokens:             vol.tokens,
 			RwDpCnt:            vol.dataPartitions.readableAndWritableCnt,
@@ -191,6 +193,7 @@ func (s *VolumeService) createVolume(ctx context.Context, args struct {
 	Name, Owner, ZoneName, Description                 string
 	Capacity, DataPartitionSize, MpCount, DpReplicaNum uint64
 	FollowerRead, Authenticate, CrossZone, EnableToken bool
+	ZoneAntiAffinity, StrictZones                      *bool
 }) (*Vol, error) {
 	uid, per, err := permissions(ctx, ADMIN|USER)
 	if err != nil {
@@ -205,7 +208,22 @@ func (s *VolumeService) createVolume(ctx context.Context, args struct {
 		return nil, fmt.Errorf("[%s] not has permission to create volume for [%s]", uid, args.Owner)
 	}
 
-	vol, err := s.cluster.createVol(args.Name, args.Owner, args.ZoneName, args.Description, int(args.MpCount), int(args.DpReplicaNum), int(args.DataPartitionSize), int(args.Capacity), args.FollowerRead, args.Authenticate, args.CrossZone, args.EnableToken)
+	vol, err := s.cluster.createVol(&createVolReq{
+		name:             args.Name,
+		owner:            args.Owner,
+		zoneName:         args.ZoneName,
+		description:      args.Description,
+		mpCount:          int(args.MpCount),
+		dpReplicaNum:     int(args.DpReplicaNum),
+		size:             int(args.DataPartitionSize),
+		capacity:         int(args.Capacity),
+		followerRead:     args.FollowerRead,
+		authenticate:     args.Authenticate,
+		crossZone:        args.CrossZone,
+		zoneAntiAffinity: args.ZoneAntiAffinity != nil && *args.ZoneAntiAffinity,
+		strictZones:      args.StrictZones != nil && *args.StrictZones,
+		enableToken:      args.EnableToken,
+	})
 	if err != nil {
 		return nil, err
 	}
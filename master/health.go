@@ -0,0 +1,65 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/util/health"
+)
+
+// a follower lagging behind the committed raft log more than the entries is not ready
+const healthMaxAppliedLag = 10000
+
+func (m *Server) registerHealthChecks() {
+	health.RegisterLiveness("raft", m.checkRaftRunning)
+	health.RegisterReadiness("raft", m.checkRaftRunning)
+	health.RegisterReadiness("leader", m.checkLeaderReady)
+}
+
+func (m *Server) checkRaftRunning() error {
+	status := m.partition.Status()
+	if status == nil || status.Stopped {
+		return errors.New("raft partition is stopped")
+	}
+	return nil
+}
+
+// checkLeaderReady requires a leader in the raft group. The leader must have loaded the metadata, and
+// a follower must not lag far behind the leader, so that the requests proxied or served locally are
+// answered correctly.
+func (m *Server) checkLeaderReady() error {
+	if m.leaderInfo.addr == "" {
+		return errors.New("no leader")
+	}
+	if m.partition.IsRaftLeader() {
+		if !m.metaReady {
+			return errors.New("metadata of the leader is not loaded")
+		}
+		return nil
+	}
+	status := m.partition.Status()
+	if status == nil {
+		return errors.New("raft partition is stopped")
+	}
+	if status.RestoringSnapshot {
+		return errors.New("restoring snapshot")
+	}
+	if status.Commit > status.Applied+healthMaxAppliedLag {
+		return fmt.Errorf("applied index %v lags behind committed index %v", status.Applied, status.Commit)
+	}
+	return nil
+}
@@ -28,7 +28,9 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/health"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 func (m *Server) startHTTPService(modulename string, cfg *config.Config) {
@@ -56,12 +58,21 @@ func (m *Server) registerAPIMiddleware(route *mux.Router) {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
 				log.LogDebugf("action[interceptor] request, method[%v] path[%v] query[%v]", r.Method, r.URL.Path, r.URL.Query())
-				if mux.CurrentRoute(r).GetName() == proto.AdminGetIP {
+				// served by every master instead of the leader
+				switch mux.CurrentRoute(r).GetName() {
+				case proto.AdminGetIP, health.PathLiveness, health.PathReadiness:
 					next.ServeHTTP(w, r)
 					return
 				}
 				if m.partition.IsRaftLeader() {
 					if m.metaReady {
+						span := tracing.StartSpan(r.URL.Path, tracing.SpanKindServer, tracing.Extract(r.Header))
+						span.SetAttribute(tracing.AttributePeer, r.RemoteAddr)
+						defer span.Finish(nil)
+						if auditedAPIs[r.URL.Path] {
+							m.serveAudited(w, r, next)
+							return
+						}
 						next.ServeHTTP(w, r)
 						return
 					}
@@ -96,6 +107,14 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 		Methods(http.MethodGet).
 		Path(proto.AdminGetIP).
 		HandlerFunc(m.getIPAddr)
+	router.NewRoute().Name(health.PathLiveness).
+		Methods(http.MethodGet).
+		Path(health.PathLiveness).
+		HandlerFunc(health.LivenessHandler)
+	router.NewRoute().Name(health.PathReadiness).
+		Methods(http.MethodGet).
+		Path(health.PathReadiness).
+		HandlerFunc(health.ReadinessHandler)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.AdminGetCluster).
 		HandlerFunc(m.getCluster)
@@ -141,6 +160,74 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.AdminListVols).
 		HandlerFunc(m.listVols)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetVolTierRule).
+		HandlerFunc(m.setVolTierRule)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteVolTierRule).
+		HandlerFunc(m.deleteVolTierRule)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetVolTierPolicy).
+		HandlerFunc(m.getVolTierPolicy)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetVolWormRetention).
+		HandlerFunc(m.setVolWormRetention)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminOverrideVolWorm).
+		HandlerFunc(m.overrideVolWorm)
+
+	// admin task management APIs
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListTasks).
+		HandlerFunc(m.listAdminTasks)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminCancelTask).
+		HandlerFunc(m.cancelAdminTask)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminPauseTask).
+		HandlerFunc(m.pauseAdminTask)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminResumeTask).
+		HandlerFunc(m.resumeAdminTask)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetRepairLink).
+		HandlerFunc(m.setRepairLink)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListRepairLinks).
+		HandlerFunc(m.listRepairLinks)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetCordon).
+		HandlerFunc(m.setCordon)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminRemoveCordon).
+		HandlerFunc(m.removeCordon)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListCordons).
+		HandlerFunc(m.listCordons)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetRebuildPolicy).
+		HandlerFunc(m.setRebuildPolicy)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminRemoveRebuildPolicy).
+		HandlerFunc(m.removeRebuildPolicy)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListRebuildPolicies).
+		HandlerFunc(m.listRebuildPolicies)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetNFSExport).
+		HandlerFunc(m.setNFSExport)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminRemoveNFSExport).
+		HandlerFunc(m.removeNFSExport)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListNFSExports).
+		HandlerFunc(m.listNFSExports)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListAuditEvents).
+		HandlerFunc(m.listAuditEvents)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminBackupMeta).
+		HandlerFunc(m.backupMeta)
 
 	// node task response APIs
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
@@ -192,6 +279,9 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminDiagnoseDataPartition).
 		HandlerFunc(m.diagnoseDataPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminResetDataPartitionRaft).
+		HandlerFunc(m.resetDataPartitionRaft)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.ClientDataPartitions).
 		HandlerFunc(m.getDataPartitions)
@@ -221,6 +311,18 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminUpdateDataNode).
 		HandlerFunc(m.updateDataNode)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetDataNodeMaintenance).
+		HandlerFunc(m.setDataNodeMaintenance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetMetaNodeMaintenance).
+		HandlerFunc(m.setMetaNodeMaintenance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetDataNodeThrottle).
+		HandlerFunc(m.setDataNodeThrottle)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListDataNodeThrottle).
+		HandlerFunc(m.listDataNodeThrottles)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminGetInvalidNodes).
 		HandlerFunc(m.checkInvalidIDNodes)
@@ -238,6 +340,12 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.DecommissionDisk).
 		HandlerFunc(m.decommissionDisk)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.AdminRecoverDisk).
+		HandlerFunc(m.recoverDisk)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetDiskRecovery).
+		HandlerFunc(m.getDiskRecovery)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminSetNodeInfo).
 		HandlerFunc(m.setNodeInfoHandler)
@@ -261,6 +369,12 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.UserRemovePolicy).
 		HandlerFunc(m.removeUserPolicy)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserSetPrefixPolicy).
+		HandlerFunc(m.setUserPrefixPolicy)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserRemovePrefixPolicy).
+		HandlerFunc(m.removeUserPrefixPolicy)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.UserDeleteVolPolicy).
 		HandlerFunc(m.deleteUserVolPolicy)
@@ -276,6 +390,15 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.UserTransferVol).
 		HandlerFunc(m.transferUserVol)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserRotateKey).
+		HandlerFunc(m.rotateUserKey)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.UserRetireKey).
+		HandlerFunc(m.retireUserKey)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserAssumeRole).
+		HandlerFunc(m.assumeUserRole)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.UsersOfVol).
 		HandlerFunc(m.getUsersOfVol)
@@ -288,6 +411,20 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 		Path(proto.GetAllZones).
 		HandlerFunc(m.listZone)
 
+	// node set management APIs
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.ListNodeSets).
+		HandlerFunc(m.listNodeSets)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.GetNodeSet).
+		HandlerFunc(m.getNodeSet)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.CreateNodeSet).
+		HandlerFunc(m.createNodeSet)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.MigrateNodeToNodeSet).
+		HandlerFunc(m.migrateNodeToNodeSet)
+
 	// APIs for token-based client permissions control
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.TokenAddURI).
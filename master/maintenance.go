@@ -0,0 +1,138 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// A node in maintenance is expected to be down for a short planned outage. No new partition is placed
+// on it, and its missing replicas are neither alarmed nor counted as failures, so that the replicas
+// are not rebuilt elsewhere.
+
+func (dataNode *DataNode) inMaintenance() bool {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.Maintenance
+}
+
+func (metaNode *MetaNode) inMaintenance() bool {
+	metaNode.RLock()
+	defer metaNode.RUnlock()
+	return metaNode.Maintenance
+}
+
+func (c *Cluster) setDataNodeMaintenance(addr string, enable bool) (err error) {
+	c.dnMutex.Lock()
+	defer c.dnMutex.Unlock()
+	dataNode, err := c.dataNode(addr)
+	if err != nil {
+		return proto.ErrDataNodeNotExists
+	}
+	dataNode.Lock()
+	oldMaintenance, oldSince := dataNode.Maintenance, dataNode.MaintenanceSince
+	if oldMaintenance == enable {
+		dataNode.Unlock()
+		return
+	}
+	dataNode.Maintenance, dataNode.MaintenanceSince = enable, 0
+	if enable {
+		dataNode.MaintenanceSince = time.Now().Unix()
+	}
+	dataNode.Unlock()
+
+	if err = c.syncUpdateDataNode(dataNode); err != nil {
+		log.LogErrorf("action[setDataNodeMaintenance] node(%v) enable(%v) err[%v]", addr, enable, err)
+		dataNode.Lock()
+		dataNode.Maintenance, dataNode.MaintenanceSince = oldMaintenance, oldSince
+		dataNode.Unlock()
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogWarnf("action[setDataNodeMaintenance] node(%v) enable(%v)", addr, enable)
+	return
+}
+
+func (c *Cluster) setMetaNodeMaintenance(addr string, enable bool) (err error) {
+	c.mnMutex.Lock()
+	defer c.mnMutex.Unlock()
+	metaNode, err := c.metaNode(addr)
+	if err != nil {
+		return proto.ErrMetaNodeNotExists
+	}
+	metaNode.Lock()
+	oldMaintenance, oldSince := metaNode.Maintenance, metaNode.MaintenanceSince
+	if oldMaintenance == enable {
+		metaNode.Unlock()
+		return
+	}
+	metaNode.Maintenance, metaNode.MaintenanceSince = enable, 0
+	if enable {
+		metaNode.MaintenanceSince = time.Now().Unix()
+	}
+	metaNode.Unlock()
+
+	if err = c.syncUpdateMetaNode(metaNode); err != nil {
+		log.LogErrorf("action[setMetaNodeMaintenance] node(%v) enable(%v) err[%v]", addr, enable, err)
+		metaNode.Lock()
+		metaNode.Maintenance, metaNode.MaintenanceSince = oldMaintenance, oldSince
+		metaNode.Unlock()
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogWarnf("action[setMetaNodeMaintenance] node(%v) enable(%v)", addr, enable)
+	return
+}
+
+// missingReplicasInMaintenance returns true if all the replicas which are not alive are on the data
+// nodes in maintenance.
+func (partition *DataPartition) missingReplicasInMaintenance(liveReplicas []*DataReplica) bool {
+	for _, host := range partition.Hosts {
+		if containsReplica(liveReplicas, host) {
+			continue
+		}
+		replica, ok := partition.hasReplica(host)
+		if !ok || replica.dataNode == nil || !replica.dataNode.inMaintenance() {
+			return false
+		}
+	}
+	return true
+}
+
+func containsReplica(replicas []*DataReplica, addr string) bool {
+	for _, replica := range replicas {
+		if replica.Addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// missingReplicasInMaintenance returns true if all the replicas which are not alive are on the meta
+// nodes in maintenance.
+func (mp *MetaPartition) missingReplicasInMaintenance(liveReplicas []*MetaReplica) bool {
+	liveAddrs := mp.getLiveReplicasAddr(liveReplicas)
+	for _, host := range mp.Hosts {
+		if contains(liveAddrs, host) {
+			continue
+		}
+		replica, err := mp.getMetaReplica(host)
+		if err != nil || replica.metaNode == nil || !replica.metaNode.inMaintenance() {
+			return false
+		}
+	}
+	return true
+}
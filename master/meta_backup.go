@@ -0,0 +1,202 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/raftstore"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	cfgRestoreMeta = "restoreMeta"
+
+	// the number of the key-value pairs written to the store in a batch during a restore
+	restoreMetaBatchSize = 1000
+)
+
+var errMetaStoreNotEmpty = errors.New("the metadata store is not empty")
+
+// backupMeta writes a consistent snapshot of the metadata store to w as a gzip stream of json lines, the header
+// followed by the key-value pairs. The stream is not closed properly on error, so a broken backup is detected by
+// gzip on restore.
+func (mf *MetadataFsm) backupMeta(w io.Writer, clusterName string) (header *proto.MasterMetaBackup, err error) {
+	snapshot := mf.store.RocksDBSnapshot()
+	defer mf.store.ReleaseSnapshot(snapshot)
+	header = &proto.MasterMetaBackup{ClusterName: clusterName, CreateTime: time.Now().Unix()}
+	iterator := mf.store.Iterator(snapshot)
+	for iterator.SeekToFirst(); iterator.Valid(); iterator.Next() {
+		header.Keys++
+		key := iterator.Key()
+		if string(key.Data()) == applied {
+			value := iterator.Value()
+			header.Applied, _ = strconv.ParseUint(string(value.Data()), 10, 64)
+			value.Free()
+		}
+		key.Free()
+	}
+	err = iterator.Err()
+	iterator.Close()
+	if err != nil {
+		return
+	}
+
+	gw := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gw)
+	if err = encoder.Encode(header); err != nil {
+		return
+	}
+	iterator = mf.store.Iterator(snapshot)
+	defer iterator.Close()
+	for iterator.SeekToFirst(); iterator.Valid(); iterator.Next() {
+		key, value := iterator.Key(), iterator.Value()
+		cmd := &RaftCmd{K: string(key.Data()), V: value.Data()}
+		if cmd.K != applied {
+			cmd.setOpType()
+		}
+		err = encoder.Encode(cmd)
+		key.Free()
+		value.Free()
+		if err != nil {
+			return
+		}
+	}
+	if err = iterator.Err(); err != nil {
+		return
+	}
+	err = gw.Close()
+	return
+}
+
+// restoreMetaBackup loads the backup into the empty metadata store of a new master before the raft server starts, a
+// store which is not empty is never overwritten. The applied index is not restored, so the raft log of the new master
+// group starts from the beginning, which is consistent as long as every master of the group restores the same backup.
+func restoreMetaBackup(store *raftstore.RocksDBStore, walDir, clusterName, file string) (header *proto.MasterMetaBackup, err error) {
+	snapshot := store.RocksDBSnapshot()
+	iterator := store.Iterator(snapshot)
+	iterator.SeekToFirst()
+	notEmpty := iterator.Valid()
+	iterator.Close()
+	store.ReleaseSnapshot(snapshot)
+	if notEmpty {
+		return nil, errMetaStoreNotEmpty
+	}
+	walPath := path.Join(walDir, strconv.FormatUint(GroupID, 10))
+	if infos, e := ioutil.ReadDir(walPath); e == nil && len(infos) > 0 {
+		return nil, fmt.Errorf("the raft log %v is not empty", walPath)
+	}
+
+	fp, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	gr, err := gzip.NewReader(fp)
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+	decoder := json.NewDecoder(gr)
+	header = &proto.MasterMetaBackup{}
+	if err = decoder.Decode(header); err != nil {
+		return nil, fmt.Errorf("decode the header: %v", err)
+	}
+	if header.ClusterName != clusterName {
+		return nil, fmt.Errorf("the backup of cluster %v is not restored to cluster %v", header.ClusterName, clusterName)
+	}
+	var keys uint64
+	batch := make(map[string][]byte, restoreMetaBatchSize)
+	for {
+		cmd := &RaftCmd{}
+		if err = decoder.Decode(cmd); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode the key-value pair %v: %v", keys, err)
+		}
+		keys++
+		if cmd.K == applied {
+			continue
+		}
+		batch[cmd.K] = cmd.V
+		if len(batch) >= restoreMetaBatchSize {
+			if err = store.BatchPut(batch, false); err != nil {
+				return
+			}
+			batch = make(map[string][]byte, restoreMetaBatchSize)
+		}
+	}
+	if keys != header.Keys {
+		return nil, fmt.Errorf("the backup is incomplete, %v of %v key-value pairs are found", keys, header.Keys)
+	}
+	if err = store.BatchPut(batch, true); err != nil {
+		return
+	}
+	return
+}
+
+func (m *Server) restoreMeta(file string) (err error) {
+	header, err := restoreMetaBackup(m.rocksDBStore, m.walDir, m.clusterName, file)
+	if err == errMetaStoreNotEmpty {
+		log.LogWarnf("action[restoreMeta] skip restoring metadata from %v: %v", file, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("action[restoreMeta] restore metadata from %v failed: %v", file, err)
+	}
+	log.LogWarnf("action[restoreMeta] metadata of cluster[%v] is restored from %v, applied[%v] keys[%v] backup time[%v]",
+		header.ClusterName, file, header.Applied, header.Keys, time.Unix(header.CreateTime, 0).Format(time.RFC3339))
+	return
+}
+
+// Back up the metadata of the cluster, the archive of which is streamed as the response.
+func (m *Server) backupMeta(w http.ResponseWriter, r *http.Request) {
+	event := &proto.AuditEvent{
+		Time:         time.Now().Unix(),
+		Client:       r.RemoteAddr,
+		ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		Op:           r.URL.Path,
+		Target:       m.clusterName,
+		Params:       auditParams(r),
+	}
+	w.Header().Set("content-type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", metaBackupFileName(m.clusterName, time.Now())))
+	header, err := m.fsm.backupMeta(w, m.clusterName)
+	if err != nil {
+		event.Code, event.Msg = proto.ErrCodeInternalError, err.Error()
+		log.LogErrorf("action[backupMeta] client[%v] err[%v]", r.RemoteAddr, err)
+	} else {
+		log.LogWarnf("action[backupMeta] client[%v] applied[%v] keys[%v]", r.RemoteAddr, header.Applied, header.Keys)
+	}
+	if err = m.cluster.addAuditEvent(event); err != nil {
+		log.LogErrorf("action[backupMeta] client[%v] add audit event err[%v]", r.RemoteAddr, err)
+	}
+}
+
+func metaBackupFileName(clusterName string, t time.Time) string {
+	return fmt.Sprintf("%v-meta-%v.gz", clusterName, t.Format("20060102150405"))
+}
@@ -36,6 +36,7 @@ type MetaNode struct {
 	SelectCount               uint64
 	Carry                     float64
 	Threshold                 float32
+	MemFull                   bool // reported by the meta node once its memory usage reaches the threshold of its own
 	ReportTime                time.Time
 	metaPartitionInfos        []*proto.MetaPartitionReport
 	MetaPartitionCount        int
@@ -43,6 +44,8 @@ type MetaNode struct {
 	sync.RWMutex              `graphql:"-"`
 	ToBeOffline               bool
 	PersistenceMetaPartitions []uint64
+	Maintenance               bool
+	MaintenanceSince          int64
 }
 
 func newMetaNode(addr, zoneName, clusterID string) (node *MetaNode) {
@@ -88,7 +91,7 @@ func (metaNode *MetaNode) SelectNodeForWrite() {
 func (metaNode *MetaNode) isWritable() (ok bool) {
 	metaNode.RLock()
 	defer metaNode.RUnlock()
-	if metaNode.IsActive && metaNode.MaxMemAvailWeight > gConfig.metaNodeReservedMem &&
+	if metaNode.IsActive && !metaNode.Maintenance && metaNode.MaxMemAvailWeight > gConfig.metaNodeReservedMem &&
 		!metaNode.reachesThreshold() && metaNode.MetaPartitionCount < defaultMaxMetaPartitionCountOnEachNode {
 		ok = true
 	}
@@ -124,9 +127,13 @@ func (metaNode *MetaNode) updateMetric(resp *proto.MetaNodeHeartbeatResponse, th
 	metaNode.MaxMemAvailWeight = resp.Total - resp.Used
 	metaNode.ZoneName = resp.ZoneName
 	metaNode.Threshold = threshold
+	metaNode.MemFull = resp.MemFull
 }
 
 func (metaNode *MetaNode) reachesThreshold() bool {
+	if metaNode.MemFull {
+		return true
+	}
 	if metaNode.Threshold <= 0 {
 		metaNode.Threshold = defaultMetaPartitionMemUsageThreshold
 	}
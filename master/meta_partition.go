@@ -35,6 +35,8 @@ type MetaReplica struct {
 	MaxInodeID  uint64
 	InodeCount  uint64
 	DentryCount uint64
+	TrashSize   uint64
+	MemUsed     uint64
 	ReportTime  int64
 	Status      int8 // unavailable, readOnly, readWrite
 	IsLeader    bool
@@ -49,6 +51,7 @@ type MetaPartition struct {
 	MaxInodeID    uint64
 	InodeCount    uint64
 	DentryCount   uint64
+	TrashSize     uint64
 	Replicas      []*MetaReplica
 	ReplicaNum    uint8
 	Status        int8
@@ -274,7 +277,9 @@ func (mp *MetaPartition) checkStatus(clusterID string, writeLog bool, replicaNum
 		msg := fmt.Sprintf("action[checkMPStatus],id:%v,status:%v,replicaNum:%v,replicas:%v,persistenceHosts:%v",
 			mp.PartitionID, mp.Status, mp.ReplicaNum, len(liveReplicas), mp.Hosts)
 		log.LogInfo(msg)
-		Warn(clusterID, msg)
+		if !mp.missingReplicasInMaintenance(liveReplicas) {
+			Warn(clusterID, msg)
+		}
 	}
 	return
 }
@@ -344,6 +349,7 @@ func (mp *MetaPartition) updateMetaPartition(mgr *proto.MetaPartitionReport, met
 	mp.setMaxInodeID()
 	mp.setInodeCount()
 	mp.setDentryCount()
+	mp.setTrashSize()
 	mp.removeMissingReplica(metaNode.Addr)
 }
 
@@ -441,6 +447,9 @@ func (mp *MetaPartition) reportMissingReplicas(clusterID, leaderAddr string, sec
 		// reduce the alarm frequency
 		if contains(mp.Hosts, replica.Addr) && replica.isMissing() && mp.shouldReportMissingReplica(replica.Addr, interval) {
 			metaNode := replica.metaNode
+			if metaNode != nil && metaNode.inMaintenance() {
+				continue
+			}
 			var (
 				lastReportTime time.Time
 			)
@@ -623,6 +632,8 @@ func (mr *MetaReplica) updateMetric(mgr *proto.MetaPartitionReport) {
 	mr.MaxInodeID = mgr.MaxInodeID
 	mr.InodeCount = mgr.InodeCnt
 	mr.DentryCount = mgr.DentryCnt
+	mr.TrashSize = mgr.TrashSize
+	mr.MemUsed = mgr.MemUsed
 	mr.setLastReportTime()
 }
 
@@ -700,6 +711,16 @@ func (mp *MetaPartition) setDentryCount() {
 	mp.DentryCount = dentryCount
 }
 
+func (mp *MetaPartition) setTrashSize() {
+	var trashSize uint64
+	for _, r := range mp.Replicas {
+		if r.TrashSize > trashSize {
+			trashSize = r.TrashSize
+		}
+	}
+	mp.TrashSize = trashSize
+}
+
 func (mp *MetaPartition) getAllNodeSets() (nodeSets []uint64) {
 	mp.RLock()
 	defer mp.RUnlock()
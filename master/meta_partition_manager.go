@@ -159,11 +159,10 @@ func (c *Cluster) checkMetaPartitionRecoveryProgress() {
 			if err != nil {
 				continue
 			}
-			vol, err := c.getVol(partition.volName)
-			if err != nil {
+			if _, err = c.getVol(partition.volName); err != nil {
 				continue
 			}
-			if len(partition.Replicas) == 0 || len(partition.Replicas) < int(vol.mpReplicaNum) {
+			if len(partition.Replicas) == 0 || len(partition.Replicas) < int(partition.ReplicaNum) {
 				continue
 			}
 			diff = partition.getMinusOfMaxInodeID()
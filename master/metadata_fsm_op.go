@@ -17,6 +17,7 @@ package master
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -39,6 +40,10 @@ type clusterValue struct {
 	MetaNodeDeleteBatchCount    uint64
 	MetaNodeDeleteWorkerSleepMs uint64
 	DataNodeAutoRepairLimitRate uint64
+	RepairLinks                 []*bsProto.RepairLink
+	Cordons                     []*bsProto.NodeCordon
+	NFSExports                  []*bsProto.NFSExport
+	RebuildPolicies             []*bsProto.RebuildPolicy
 }
 
 func newClusterValue(c *Cluster) (cv *clusterValue) {
@@ -50,6 +55,10 @@ func newClusterValue(c *Cluster) (cv *clusterValue) {
 		MetaNodeDeleteWorkerSleepMs: c.cfg.MetaNodeDeleteWorkerSleepMs,
 		DataNodeAutoRepairLimitRate: c.cfg.DataNodeAutoRepairLimitRate,
 		DisableAutoAllocate:         c.DisableAutoAllocate,
+		RepairLinks:                 c.getRepairLinks(),
+		Cordons:                     c.getCordons(),
+		NFSExports:                  c.getNFSExports(),
+		RebuildPolicies:             c.getRebuildPolicies(),
 	}
 	return cv
 }
@@ -143,6 +152,17 @@ type volValue struct {
 	Description       string
 	DpSelectorName    string
 	DpSelectorParm    string
+	MaxIOPS           uint64
+	MaxBandwidth      uint64
+	VerifyRead        bool
+	TrashDays         uint32
+	WormRetentionDays uint32
+	WormOverrideUntil int64
+	TierRules         []*bsProto.TierRule
+	ZoneAntiAffinity  bool
+	StrictZones       bool
+	Atime             bool
+	FileAudit         bool
 }
 
 func (v *volValue) Bytes() (raw []byte, err error) {
@@ -171,6 +191,17 @@ func newVolValue(vol *Vol) (vv *volValue) {
 		Description:       vol.description,
 		DpSelectorName:    vol.dpSelectorName,
 		DpSelectorParm:    vol.dpSelectorParm,
+		MaxIOPS:           vol.maxIOPS,
+		MaxBandwidth:      vol.maxBandwidth,
+		VerifyRead:        vol.verifyRead,
+		TrashDays:         vol.trashDays,
+		WormRetentionDays: vol.wormRetentionDays,
+		WormOverrideUntil: vol.wormOverrideUntil,
+		TierRules:         vol.getTierRules(),
+		ZoneAntiAffinity:  vol.zoneAntiAffinity,
+		StrictZones:       vol.strictZones,
+		Atime:             vol.atime,
+		FileAudit:         vol.fileAudit,
 	}
 	return
 }
@@ -184,34 +215,44 @@ func newVolValueFromBytes(raw []byte) (*volValue, error) {
 }
 
 type dataNodeValue struct {
-	ID        uint64
-	NodeSetID uint64
-	Addr      string
-	ZoneName  string
+	ID               uint64
+	NodeSetID        uint64
+	Addr             string
+	ZoneName         string
+	Maintenance      bool
+	MaintenanceSince int64
+	ClientThrottles  []*bsProto.ClientThrottle
 }
 
 func newDataNodeValue(dataNode *DataNode) *dataNodeValue {
 	return &dataNodeValue{
-		ID:        dataNode.ID,
-		NodeSetID: dataNode.NodeSetID,
-		Addr:      dataNode.Addr,
-		ZoneName:  dataNode.ZoneName,
+		ID:               dataNode.ID,
+		NodeSetID:        dataNode.NodeSetID,
+		Addr:             dataNode.Addr,
+		ZoneName:         dataNode.ZoneName,
+		Maintenance:      dataNode.Maintenance,
+		MaintenanceSince: dataNode.MaintenanceSince,
+		ClientThrottles:  dataNode.getClientThrottles(),
 	}
 }
 
 type metaNodeValue struct {
-	ID        uint64
-	NodeSetID uint64
-	Addr      string
-	ZoneName  string
+	ID               uint64
+	NodeSetID        uint64
+	Addr             string
+	ZoneName         string
+	Maintenance      bool
+	MaintenanceSince int64
 }
 
 func newMetaNodeValue(metaNode *MetaNode) *metaNodeValue {
 	return &metaNodeValue{
-		ID:        metaNode.ID,
-		NodeSetID: metaNode.NodeSetID,
-		Addr:      metaNode.Addr,
-		ZoneName:  metaNode.ZoneName,
+		ID:               metaNode.ID,
+		NodeSetID:        metaNode.NodeSetID,
+		Addr:             metaNode.Addr,
+		ZoneName:         metaNode.ZoneName,
+		Maintenance:      metaNode.Maintenance,
+		MaintenanceSince: metaNode.MaintenanceSince,
 	}
 }
 
@@ -282,6 +323,8 @@ func (m *RaftCmd) setOpType() {
 		m.Op = opSyncAddVolUser
 	case tokenAcronym:
 		m.Op = OpSyncAddToken
+	case auditAcronym:
+		m.Op = opSyncAddAuditEvent
 	default:
 		log.LogWarnf("action[setOpType] unknown opCode[%v]", keyArr[1])
 	}
@@ -311,6 +354,25 @@ func (c *Cluster) syncPutTokenInfo(opType uint32, token *bsProto.Token) (err err
 	return c.submit(metadata)
 }
 
+//key=#audit#id, the id is padded so that the events are ordered by it
+func (c *Cluster) syncAddAuditEvent(event *bsProto.AuditEvent) (err error) {
+	return c.syncPutAuditEvent(opSyncAddAuditEvent, event)
+}
+
+func (c *Cluster) syncDeleteAuditEvent(event *bsProto.AuditEvent) (err error) {
+	return c.syncPutAuditEvent(opSyncDeleteAuditEvent, event)
+}
+
+func (c *Cluster) syncPutAuditEvent(opType uint32, event *bsProto.AuditEvent) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opType
+	metadata.K = fmt.Sprintf("%v%020d", auditPrefix, event.ID)
+	if metadata.V, err = json.Marshal(event); err != nil {
+		return
+	}
+	return c.submit(metadata)
+}
+
 //key=#c#name
 func (c *Cluster) syncPutCluster() (err error) {
 	metadata := new(RaftCmd)
@@ -554,6 +616,10 @@ func (c *Cluster) loadClusterValue() (err error) {
 		c.updateMetaNodeDeleteWorkerSleepMs(cv.MetaNodeDeleteWorkerSleepMs)
 		c.updateDataNodeDeleteLimitRate(cv.DataNodeDeleteLimitRate)
 		c.updateDataNodeAutoRepairLimit(cv.DataNodeAutoRepairLimitRate)
+		c.loadRepairLinks(cv.RepairLinks)
+		c.loadCordons(cv.Cordons)
+		c.loadNFSExports(cv.NFSExports)
+		c.loadRebuildPolicies(cv.RebuildPolicies)
 		log.LogInfof("action[loadClusterValue], metaNodeThreshold[%v]", cv.Threshold)
 	}
 	return
@@ -606,6 +672,12 @@ func (c *Cluster) loadDataNodes() (err error) {
 		dataNode := newDataNode(dnv.Addr, dnv.ZoneName, c.Name)
 		dataNode.ID = dnv.ID
 		dataNode.NodeSetID = dnv.NodeSetID
+		dataNode.Maintenance = dnv.Maintenance
+		dataNode.MaintenanceSince = dnv.MaintenanceSince
+		dataNode.ClientThrottles = make(map[string]*bsProto.ClientThrottle, len(dnv.ClientThrottles))
+		for _, throttle := range dnv.ClientThrottles {
+			dataNode.ClientThrottles[throttle.ClientIP] = throttle
+		}
 		olddn, ok := c.dataNodes.Load(dataNode.Addr)
 		if ok {
 			if olddn.(*DataNode).ID <= dataNode.ID {
@@ -636,6 +708,8 @@ func (c *Cluster) loadMetaNodes() (err error) {
 		metaNode := newMetaNode(mnv.Addr, mnv.ZoneName, c.Name)
 		metaNode.ID = mnv.ID
 		metaNode.NodeSetID = mnv.NodeSetID
+		metaNode.Maintenance = mnv.Maintenance
+		metaNode.MaintenanceSince = mnv.MaintenanceSince
 		oldmn, ok := c.metaNodes.Load(metaNode.Addr)
 		if ok {
 			if oldmn.(*MetaNode).ID <= metaNode.ID {
@@ -696,7 +770,11 @@ func (c *Cluster) loadMetaPartitions() (err error) {
 				mpv.Peers[i].ID = mn.(*MetaNode).ID
 			}
 		}
-		mp := newMetaPartition(mpv.PartitionID, mpv.Start, mpv.End, vol.mpReplicaNum, vol.Name, mpv.VolID)
+		replicaNum := vol.mpReplicaNum
+		if mpv.ReplicaNum != 0 {
+			replicaNum = mpv.ReplicaNum
+		}
+		mp := newMetaPartition(mpv.PartitionID, mpv.Start, mpv.End, replicaNum, vol.Name, mpv.VolID)
 		mp.setHosts(strings.Split(mpv.Hosts, underlineSeparator))
 		mp.setPeers(mpv.Peers)
 		mp.OfflinePeerID = mpv.OfflinePeerID
@@ -783,3 +861,23 @@ func (c *Cluster) loadTokens() (err error) {
 	}
 	return
 }
+
+// loadAuditEvents reads the audit events from the store in the order of ids.
+func (c *Cluster) loadAuditEvents() (events []*bsProto.AuditEvent, err error) {
+	result, err := c.fsm.store.SeekForPrefix([]byte(auditPrefix))
+	if err != nil {
+		err = fmt.Errorf("action[loadAuditEvents],err:%v", err.Error())
+		return
+	}
+	events = make([]*bsProto.AuditEvent, 0, len(result))
+	for _, value := range result {
+		event := &bsProto.AuditEvent{}
+		if err = json.Unmarshal(value, event); err != nil {
+			err = fmt.Errorf("action[loadAuditEvents],value:%v,unmarshal err:%v", string(value), err)
+			return
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return
+}
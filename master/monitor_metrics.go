@@ -16,8 +16,10 @@ package master
 
 import (
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
 )
@@ -40,6 +42,25 @@ const (
 	MetricDiskError            = "disk_error"
 	MetricDataNodesInactive    = "dataNodes_inactive"
 	MetricMetaNodesInactive    = "metaNodes_inactive"
+	MetricDataNodesMaintenance = "dataNodes_maintenance"
+	MetricMetaNodesMaintenance = "metaNodes_maintenance"
+
+	MetricVolDataPartitionsRW = "vol_dataPartitions_rw"
+	MetricVolDataPartitionsRO = "vol_dataPartitions_ro"
+	MetricVolInodeCount       = "vol_inode_count"
+	MetricVolDentryCount      = "vol_dentry_count"
+
+	MetricZoneDataNodesTotalGB  = "zone_dataNodes_total_GB"
+	MetricZoneDataNodesUsedGB   = "zone_dataNodes_used_GB"
+	MetricZoneDataNodesWritable = "zone_dataNodes_writable"
+	MetricZoneMetaNodesTotalGB  = "zone_metaNodes_total_GB"
+	MetricZoneMetaNodesUsedGB   = "zone_metaNodes_used_GB"
+	MetricZoneMetaNodesWritable = "zone_metaNodes_writable"
+
+	MetricDataNodesDecommissioning = "dataNodes_decommissioning"
+	MetricMetaNodesDecommissioning = "metaNodes_decommissioning"
+	MetricDataPartitionsRecovering = "decommission_dataPartitions_recovering"
+	MetricMetaPartitionsRecovering = "decommission_metaPartitions_recovering"
 )
 
 type monitorMetrics struct {
@@ -60,15 +81,42 @@ type monitorMetrics struct {
 	dataNodesInactive  *exporter.Gauge
 	metaNodesInactive  *exporter.Gauge
 
-	volNames map[string]struct{}
-	badDisks map[string]string
+	dataNodesMaintenance *exporter.Gauge
+	metaNodesMaintenance *exporter.Gauge
+
+	volDataPartitionsRW *exporter.GaugeVec
+	volDataPartitionsRO *exporter.GaugeVec
+	volInodeCount       *exporter.GaugeVec
+	volDentryCount      *exporter.GaugeVec
+
+	zoneDataNodesTotal    *exporter.GaugeVec
+	zoneDataNodesUsed     *exporter.GaugeVec
+	zoneDataNodesWritable *exporter.GaugeVec
+	zoneMetaNodesTotal    *exporter.GaugeVec
+	zoneMetaNodesUsed     *exporter.GaugeVec
+	zoneMetaNodesWritable *exporter.GaugeVec
+
+	dataNodesDecommissioning *exporter.Gauge
+	metaNodesDecommissioning *exporter.Gauge
+	dataPartitionsRecovering *exporter.GaugeVec
+	metaPartitionsRecovering *exporter.GaugeVec
+
+	volNames  map[string]struct{}
+	badDisks  map[string]string
+	zoneNames map[string]struct{}
+	// keys of the bad partitions in recovery, addr:disk of data partitions and addr of meta partitions
+	recoveringDataKeys map[string]struct{}
+	recoveringMetaKeys map[string]struct{}
 	//volNamesMutex sync.Mutex
 }
 
 func newMonitorMetrics(c *Cluster) *monitorMetrics {
 	return &monitorMetrics{cluster: c,
-		volNames: make(map[string]struct{}),
-		badDisks: make(map[string]string),
+		volNames:           make(map[string]struct{}),
+		badDisks:           make(map[string]string),
+		zoneNames:          make(map[string]struct{}),
+		recoveringDataKeys: make(map[string]struct{}),
+		recoveringMetaKeys: make(map[string]struct{}),
 	}
 }
 
@@ -88,6 +136,22 @@ func (mm *monitorMetrics) start() {
 	mm.diskError = exporter.NewGaugeVec(MetricDiskError, "", []string{"addr", "path"})
 	mm.dataNodesInactive = exporter.NewGauge(MetricDataNodesInactive)
 	mm.metaNodesInactive = exporter.NewGauge(MetricMetaNodesInactive)
+	mm.dataNodesMaintenance = exporter.NewGauge(MetricDataNodesMaintenance)
+	mm.metaNodesMaintenance = exporter.NewGauge(MetricMetaNodesMaintenance)
+	mm.volDataPartitionsRW = exporter.NewGaugeVec(MetricVolDataPartitionsRW, "", []string{"volName"})
+	mm.volDataPartitionsRO = exporter.NewGaugeVec(MetricVolDataPartitionsRO, "", []string{"volName"})
+	mm.volInodeCount = exporter.NewGaugeVec(MetricVolInodeCount, "", []string{"volName"})
+	mm.volDentryCount = exporter.NewGaugeVec(MetricVolDentryCount, "", []string{"volName"})
+	mm.zoneDataNodesTotal = exporter.NewGaugeVec(MetricZoneDataNodesTotalGB, "", []string{"zoneName"})
+	mm.zoneDataNodesUsed = exporter.NewGaugeVec(MetricZoneDataNodesUsedGB, "", []string{"zoneName"})
+	mm.zoneDataNodesWritable = exporter.NewGaugeVec(MetricZoneDataNodesWritable, "", []string{"zoneName"})
+	mm.zoneMetaNodesTotal = exporter.NewGaugeVec(MetricZoneMetaNodesTotalGB, "", []string{"zoneName"})
+	mm.zoneMetaNodesUsed = exporter.NewGaugeVec(MetricZoneMetaNodesUsedGB, "", []string{"zoneName"})
+	mm.zoneMetaNodesWritable = exporter.NewGaugeVec(MetricZoneMetaNodesWritable, "", []string{"zoneName"})
+	mm.dataNodesDecommissioning = exporter.NewGauge(MetricDataNodesDecommissioning)
+	mm.metaNodesDecommissioning = exporter.NewGauge(MetricMetaNodesDecommissioning)
+	mm.dataPartitionsRecovering = exporter.NewGaugeVec(MetricDataPartitionsRecovering, "", []string{"path"})
+	mm.metaPartitionsRecovering = exporter.NewGaugeVec(MetricMetaPartitionsRecovering, "", []string{"addr"})
 	go mm.statMetrics()
 }
 
@@ -130,6 +194,8 @@ func (mm *monitorMetrics) doStat() {
 	mm.setDiskErrorMetric()
 	mm.setInactiveDataNodesCount()
 	mm.setInactiveMetaNodesCount()
+	mm.setZoneMetrics()
+	mm.setDecommissionMetrics()
 }
 
 func (mm *monitorMetrics) setVolMetrics() {
@@ -159,6 +225,9 @@ func (mm *monitorMetrics) setVolMetrics() {
 		if e == nil {
 			mm.volUsage.SetWithLabelValues(usedRatio, volName)
 		}
+		if vol, err := mm.cluster.getVol(volName); err == nil {
+			mm.setVolPartitionMetrics(vol)
+		}
 
 		return true
 	})
@@ -172,6 +241,136 @@ func (mm *monitorMetrics) deleteVolMetric(volName string) {
 	mm.volTotalSpace.DeleteLabelValues(volName)
 	mm.volUsedSpace.DeleteLabelValues(volName)
 	mm.volUsage.DeleteLabelValues(volName)
+	mm.volDataPartitionsRW.DeleteLabelValues(volName)
+	mm.volDataPartitionsRO.DeleteLabelValues(volName)
+	mm.volInodeCount.DeleteLabelValues(volName)
+	mm.volDentryCount.DeleteLabelValues(volName)
+}
+
+// setVolPartitionMetrics sets the number of the read-write and read-only data partitions of the volume,
+// and the number of the inodes and dentries reported by the meta partitions.
+func (mm *monitorMetrics) setVolPartitionMetrics(vol *Vol) {
+	var rwCount, roCount int
+	vol.dataPartitions.RLock()
+	for _, dp := range vol.dataPartitions.partitionMap {
+		switch dp.Status {
+		case proto.ReadWrite:
+			rwCount++
+		case proto.ReadOnly:
+			roCount++
+		}
+	}
+	vol.dataPartitions.RUnlock()
+
+	var inodeCount, dentryCount uint64
+	vol.mpsLock.RLock()
+	for _, mp := range vol.MetaPartitions {
+		inodeCount += mp.InodeCount
+		dentryCount += mp.DentryCount
+	}
+	vol.mpsLock.RUnlock()
+
+	mm.volDataPartitionsRW.SetWithLabelValues(float64(rwCount), vol.Name)
+	mm.volDataPartitionsRO.SetWithLabelValues(float64(roCount), vol.Name)
+	mm.volInodeCount.SetWithLabelValues(float64(inodeCount), vol.Name)
+	mm.volDentryCount.SetWithLabelValues(float64(dentryCount), vol.Name)
+}
+
+func (mm *monitorMetrics) setZoneMetrics() {
+	deleteZoneNames := make(map[string]struct{})
+	for k, v := range mm.zoneNames {
+		deleteZoneNames[k] = v
+		delete(mm.zoneNames, k)
+	}
+	for zoneName, zoneStat := range mm.cluster.zoneStatInfos {
+		mm.zoneNames[zoneName] = struct{}{}
+		delete(deleteZoneNames, zoneName)
+		mm.zoneDataNodesTotal.SetWithLabelValues(zoneStat.DataNodeStat.Total, zoneName)
+		mm.zoneDataNodesUsed.SetWithLabelValues(zoneStat.DataNodeStat.Used, zoneName)
+		mm.zoneDataNodesWritable.SetWithLabelValues(float64(zoneStat.DataNodeStat.WritableNodes), zoneName)
+		mm.zoneMetaNodesTotal.SetWithLabelValues(zoneStat.MetaNodeStat.Total, zoneName)
+		mm.zoneMetaNodesUsed.SetWithLabelValues(zoneStat.MetaNodeStat.Used, zoneName)
+		mm.zoneMetaNodesWritable.SetWithLabelValues(float64(zoneStat.MetaNodeStat.WritableNodes), zoneName)
+	}
+	for zoneName := range deleteZoneNames {
+		mm.deleteZoneMetric(zoneName)
+	}
+}
+
+func (mm *monitorMetrics) deleteZoneMetric(zoneName string) {
+	mm.zoneDataNodesTotal.DeleteLabelValues(zoneName)
+	mm.zoneDataNodesUsed.DeleteLabelValues(zoneName)
+	mm.zoneDataNodesWritable.DeleteLabelValues(zoneName)
+	mm.zoneMetaNodesTotal.DeleteLabelValues(zoneName)
+	mm.zoneMetaNodesUsed.DeleteLabelValues(zoneName)
+	mm.zoneMetaNodesWritable.DeleteLabelValues(zoneName)
+}
+
+// setDecommissionMetrics sets the number of the nodes being decommissioned, and the number of the
+// partitions of the decommissioned disks and nodes which are not recovered yet.
+func (mm *monitorMetrics) setDecommissionMetrics() {
+	var dataNodesCount, metaNodesCount int64
+	mm.cluster.dataNodes.Range(func(addr, node interface{}) bool {
+		if dataNode, ok := node.(*DataNode); ok && dataNode.ToBeOffline {
+			dataNodesCount++
+		}
+		return true
+	})
+	mm.cluster.metaNodes.Range(func(addr, node interface{}) bool {
+		if metaNode, ok := node.(*MetaNode); ok && metaNode.ToBeOffline {
+			metaNodesCount++
+		}
+		return true
+	})
+	mm.dataNodesDecommissioning.Set(float64(dataNodesCount))
+	mm.metaNodesDecommissioning.Set(float64(metaNodesCount))
+	setRecoveringPartitions(mm.dataPartitionsRecovering, mm.cluster.BadDataPartitionIds, mm.recoveringDataKeys)
+	setRecoveringPartitions(mm.metaPartitionsRecovering, mm.cluster.BadMetaPartitionIds, mm.recoveringMetaKeys)
+}
+
+func setRecoveringPartitions(gauge *exporter.GaugeVec, badPartitionIDs *sync.Map, keys map[string]struct{}) {
+	deleteKeys := make(map[string]struct{})
+	for k, v := range keys {
+		deleteKeys[k] = v
+		delete(keys, k)
+	}
+	badPartitionIDs.Range(func(key, value interface{}) bool {
+		path, ok := key.(string)
+		if !ok {
+			return true
+		}
+		ids, ok := value.([]uint64)
+		if !ok {
+			return true
+		}
+		keys[path] = struct{}{}
+		delete(deleteKeys, path)
+		gauge.SetWithLabelValues(float64(len(ids)), path)
+		return true
+	})
+	for path := range deleteKeys {
+		gauge.DeleteLabelValues(path)
+	}
+}
+
+func (mm *monitorMetrics) clearZoneMetrics() {
+	for zoneName := range mm.zoneNames {
+		mm.deleteZoneMetric(zoneName)
+		delete(mm.zoneNames, zoneName)
+	}
+}
+
+func (mm *monitorMetrics) clearDecommissionMetrics() {
+	for path := range mm.recoveringDataKeys {
+		mm.dataPartitionsRecovering.DeleteLabelValues(path)
+		delete(mm.recoveringDataKeys, path)
+	}
+	for addr := range mm.recoveringMetaKeys {
+		mm.metaPartitionsRecovering.DeleteLabelValues(addr)
+		delete(mm.recoveringMetaKeys, addr)
+	}
+	mm.dataNodesDecommissioning.Set(0)
+	mm.metaNodesDecommissioning.Set(0)
 }
 
 func (mm *monitorMetrics) setDiskErrorMetric() {
@@ -204,34 +403,42 @@ func (mm *monitorMetrics) setDiskErrorMetric() {
 	}
 }
 
+// setInactiveMetaNodesCount counts the inactive meta nodes, the ones in maintenance are counted apart.
 func (mm *monitorMetrics) setInactiveMetaNodesCount() {
-	var inactiveMetaNodesCount int64
+	var inactiveMetaNodesCount, maintenanceMetaNodesCount int64
 	mm.cluster.metaNodes.Range(func(addr, node interface{}) bool {
 		metaNode, ok := node.(*MetaNode)
 		if !ok {
 			return true
 		}
-		if !metaNode.IsActive {
+		if metaNode.inMaintenance() {
+			maintenanceMetaNodesCount++
+		} else if !metaNode.IsActive {
 			inactiveMetaNodesCount++
 		}
 		return true
 	})
 	mm.metaNodesInactive.Set(float64(inactiveMetaNodesCount))
+	mm.metaNodesMaintenance.Set(float64(maintenanceMetaNodesCount))
 }
 
+// setInactiveDataNodesCount counts the inactive data nodes, the ones in maintenance are counted apart.
 func (mm *monitorMetrics) setInactiveDataNodesCount() {
-	var inactiveDataNodesCount int64
+	var inactiveDataNodesCount, maintenanceDataNodesCount int64
 	mm.cluster.dataNodes.Range(func(addr, node interface{}) bool {
 		dataNode, ok := node.(*DataNode)
 		if !ok {
 			return true
 		}
-		if !dataNode.isActive {
+		if dataNode.inMaintenance() {
+			maintenanceDataNodesCount++
+		} else if !dataNode.isActive {
 			inactiveDataNodesCount++
 		}
 		return true
 	})
 	mm.dataNodesInactive.Set(float64(inactiveDataNodesCount))
+	mm.dataNodesMaintenance.Set(float64(maintenanceDataNodesCount))
 }
 
 func (mm *monitorMetrics) clearVolMetrics() {
@@ -252,6 +459,8 @@ func (mm *monitorMetrics) clearDiskErrMetrics() {
 func (mm *monitorMetrics) resetAllMetrics() {
 	mm.clearVolMetrics()
 	mm.clearDiskErrMetrics()
+	mm.clearZoneMetrics()
+	mm.clearDecommissionMetrics()
 
 	mm.dataNodesCount.Set(0)
 	mm.metaNodesCount.Set(0)
@@ -265,4 +474,6 @@ func (mm *monitorMetrics) resetAllMetrics() {
 	//mm.diskError.Set(0)
 	mm.dataNodesInactive.Set(0)
 	mm.metaNodesInactive.Set(0)
+	mm.dataNodesMaintenance.Set(0)
+	mm.metaNodesMaintenance.Set(0)
 }
@@ -0,0 +1,110 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"net"
+	"path"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// getNFSExports returns the exports served by the NFS gateways sorted by the export path.
+func (c *Cluster) getNFSExports() (exports []*proto.NFSExport) {
+	c.nfsExportLock.RLock()
+	defer c.nfsExportLock.RUnlock()
+	exports = make([]*proto.NFSExport, 0, len(c.nfsExports))
+	for _, export := range c.nfsExports {
+		exports = append(exports, export)
+	}
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].Path < exports[j].Path
+	})
+	return
+}
+
+func (c *Cluster) loadNFSExports(exports []*proto.NFSExport) {
+	c.nfsExportLock.Lock()
+	defer c.nfsExportLock.Unlock()
+	c.nfsExports = make(map[string]*proto.NFSExport, len(exports))
+	for _, export := range exports {
+		c.nfsExports[export.Path] = export
+	}
+}
+
+func checkNFSExport(export *proto.NFSExport) (err error) {
+	if export.Path == "" || export.Path[0] != '/' || path.Clean(export.Path) != export.Path {
+		return unmatchedKey(exportPathKey)
+	}
+	for _, client := range export.Clients {
+		if _, _, err = net.ParseCIDR(client); err != nil {
+			return unmatchedKey(exportClientsKey)
+		}
+	}
+	return
+}
+
+// setNFSExport adds or replaces the export of the given path.
+func (c *Cluster) setNFSExport(export *proto.NFSExport) (err error) {
+	if err = checkNFSExport(export); err != nil {
+		return
+	}
+	if _, err = c.getVol(export.Volume); err != nil {
+		return proto.ErrVolNotExists
+	}
+	c.nfsExportLock.Lock()
+	oldExport, existed := c.nfsExports[export.Path]
+	c.nfsExports[export.Path] = export
+	c.nfsExportLock.Unlock()
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setNFSExport] path(%v) vol(%v) err[%v]", export.Path, export.Volume, err)
+		c.restoreNFSExport(export.Path, oldExport, existed)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[setNFSExport] path(%v) vol(%v) clients(%v) readOnly(%v) rootSquash(%v)",
+		export.Path, export.Volume, export.Clients, export.ReadOnly, export.RootSquash)
+	return
+}
+
+func (c *Cluster) removeNFSExport(exportPath string) (err error) {
+	c.nfsExportLock.Lock()
+	oldExport, existed := c.nfsExports[exportPath]
+	delete(c.nfsExports, exportPath)
+	c.nfsExportLock.Unlock()
+	if !existed {
+		return
+	}
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[removeNFSExport] path(%v) err[%v]", exportPath, err)
+		c.restoreNFSExport(exportPath, oldExport, existed)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[removeNFSExport] path(%v) vol(%v)", exportPath, oldExport.Volume)
+	return
+}
+
+func (c *Cluster) restoreNFSExport(exportPath string, export *proto.NFSExport, existed bool) {
+	c.nfsExportLock.Lock()
+	defer c.nfsExportLock.Unlock()
+	if existed {
+		c.nfsExports[exportPath] = export
+	} else {
+		delete(c.nfsExports, exportPath)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func newNodeSetInfo(ns *nodeSet, withNodes bool) (info *proto.NodeSetInfo) {
+	info = &proto.NodeSetInfo{
+		ID:          ns.ID,
+		ZoneName:    ns.zoneName,
+		Capacity:    ns.Capacity,
+		DataNodeLen: ns.dataNodeLen(),
+		MetaNodeLen: ns.metaNodeLen(),
+	}
+	if !withNodes {
+		return
+	}
+	info.DataNodes = make([]proto.NodeView, 0, info.DataNodeLen)
+	ns.dataNodes.Range(func(key, value interface{}) bool {
+		dataNode := value.(*DataNode)
+		info.DataNodes = append(info.DataNodes, proto.NodeView{ID: dataNode.ID, Addr: dataNode.Addr, Status: dataNode.isActive, IsWritable: dataNode.isWriteAble()})
+		return true
+	})
+	info.MetaNodes = make([]proto.NodeView, 0, info.MetaNodeLen)
+	ns.metaNodes.Range(func(key, value interface{}) bool {
+		metaNode := value.(*MetaNode)
+		info.MetaNodes = append(info.MetaNodes, proto.NodeView{ID: metaNode.ID, Addr: metaNode.Addr, Status: metaNode.IsActive, IsWritable: metaNode.isWritable()})
+		return true
+	})
+	sort.Slice(info.DataNodes, func(i, j int) bool { return info.DataNodes[i].ID < info.DataNodes[j].ID })
+	sort.Slice(info.MetaNodes, func(i, j int) bool { return info.MetaNodes[i].ID < info.MetaNodes[j].ID })
+	return
+}
+
+// listNodeSets returns the node sets of the zone, or of all the zones if the zone name is empty.
+func (c *Cluster) listNodeSets(zoneName string) (infos []*proto.NodeSetInfo, err error) {
+	var zones []*Zone
+	if zoneName == "" {
+		zones = c.t.getAllZones()
+	} else {
+		var zone *Zone
+		if zone, err = c.t.getZone(zoneName); err != nil {
+			return nil, proto.ErrZoneNotExists
+		}
+		zones = []*Zone{zone}
+	}
+	infos = make([]*proto.NodeSetInfo, 0)
+	for _, zone := range zones {
+		for _, ns := range zone.getAllNodeSet() {
+			infos = append(infos, newNodeSetInfo(ns, false))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ZoneName != infos[j].ZoneName {
+			return infos[i].ZoneName < infos[j].ZoneName
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return
+}
+
+func (c *Cluster) getNodeSet(id uint64) (ns *nodeSet, err error) {
+	for _, zone := range c.t.getAllZones() {
+		if ns, err = zone.getNodeSet(id); err == nil {
+			return
+		}
+	}
+	return nil, proto.ErrNodeSetNotExists
+}
+
+// createNodeSet adds an empty node set to the zone. The new nodes of the zone join a node set of the zone which is
+// not full, and the existing nodes are moved into the new node set by migrateNodeToNodeSet.
+func (c *Cluster) createNodeSet(zoneName string) (ns *nodeSet, err error) {
+	var zone *Zone
+	if zone, err = c.t.getZone(zoneName); err != nil {
+		return nil, proto.ErrZoneNotExists
+	}
+	if ns, err = zone.createNodeSet(c); err != nil {
+		log.LogErrorf("action[createNodeSet] zone(%v) err[%v]", zoneName, err)
+		return
+	}
+	log.LogInfof("action[createNodeSet] zone(%v) nodeSet(%v) capacity(%v)", zoneName, ns.ID, ns.Capacity)
+	return
+}
+
+// migrateNodeToNodeSet moves the data node or meta node of the address to another node set of its zone. Only the
+// partitions placed afterwards follow the new node set, the replicas on the node stay in their raft groups until
+// they are decommissioned.
+func (c *Cluster) migrateNodeToNodeSet(addr string, id uint64) (err error) {
+	if _, err = c.dataNode(addr); err == nil {
+		return c.migrateDataNodeToNodeSet(addr, id)
+	}
+	if _, err = c.metaNode(addr); err == nil {
+		return c.migrateMetaNodeToNodeSet(addr, id)
+	}
+	return fmt.Errorf("node[%v] is not found", addr)
+}
+
+// targetNodeSet returns the source and the target node sets of a node in the zone to be migrated.
+func (c *Cluster) targetNodeSet(zoneName string, srcID, dstID uint64) (src, dst *nodeSet, err error) {
+	var zone *Zone
+	if zone, err = c.t.getZone(zoneName); err != nil {
+		return nil, nil, proto.ErrZoneNotExists
+	}
+	if src, err = zone.getNodeSet(srcID); err != nil {
+		return
+	}
+	if dst, err = zone.getNodeSet(dstID); err != nil {
+		if _, e := c.getNodeSet(dstID); e == nil {
+			return nil, nil, fmt.Errorf("node set[%v] is not in zone[%v]", dstID, zoneName)
+		}
+		return nil, nil, proto.ErrNodeSetNotExists
+	}
+	return
+}
+
+func (c *Cluster) migrateDataNodeToNodeSet(addr string, id uint64) (err error) {
+	c.dnMutex.Lock()
+	defer c.dnMutex.Unlock()
+	dataNode, err := c.dataNode(addr)
+	if err != nil {
+		return proto.ErrDataNodeNotExists
+	}
+	oldID := dataNode.NodeSetID
+	if oldID == id {
+		return
+	}
+	src, dst, err := c.targetNodeSet(dataNode.ZoneName, oldID, id)
+	if err != nil {
+		return
+	}
+	if dst.dataNodeLen() >= dst.Capacity {
+		return fmt.Errorf("node set[%v] is full of %v data nodes", id, dst.Capacity)
+	}
+	dataNode.Lock()
+	dataNode.NodeSetID = id
+	dataNode.Unlock()
+	if err = c.syncUpdateDataNode(dataNode); err != nil {
+		log.LogErrorf("action[migrateDataNodeToNodeSet] node(%v) nodeSet(%v) err[%v]", addr, id, err)
+		dataNode.Lock()
+		dataNode.NodeSetID = oldID
+		dataNode.Unlock()
+		return proto.ErrPersistenceByRaft
+	}
+	src.deleteDataNode(dataNode)
+	dst.putDataNode(dataNode)
+	log.LogWarnf("action[migrateDataNodeToNodeSet] node(%v) nodeSet(%v -> %v)", addr, oldID, id)
+	return
+}
+
+func (c *Cluster) migrateMetaNodeToNodeSet(addr string, id uint64) (err error) {
+	c.mnMutex.Lock()
+	defer c.mnMutex.Unlock()
+	metaNode, err := c.metaNode(addr)
+	if err != nil {
+		return proto.ErrMetaNodeNotExists
+	}
+	oldID := metaNode.NodeSetID
+	if oldID == id {
+		return
+	}
+	src, dst, err := c.targetNodeSet(metaNode.ZoneName, oldID, id)
+	if err != nil {
+		return
+	}
+	if dst.metaNodeLen() >= dst.Capacity {
+		return fmt.Errorf("node set[%v] is full of %v meta nodes", id, dst.Capacity)
+	}
+	metaNode.Lock()
+	metaNode.NodeSetID = id
+	metaNode.Unlock()
+	if err = c.syncUpdateMetaNode(metaNode); err != nil {
+		log.LogErrorf("action[migrateMetaNodeToNodeSet] node(%v) nodeSet(%v) err[%v]", addr, id, err)
+		metaNode.Lock()
+		metaNode.NodeSetID = oldID
+		metaNode.Unlock()
+		return proto.ErrPersistenceByRaft
+	}
+	src.deleteMetaNode(metaNode)
+	dst.putMetaNode(metaNode)
+	log.LogWarnf("action[migrateMetaNodeToNodeSet] node(%v) nodeSet(%v -> %v)", addr, oldID, id)
+	return
+}
@@ -63,6 +63,14 @@ func newRemoveDataPartitionRaftMemberRequest(ID uint64, removePeer proto.Peer) (
 	return
 }
 
+func newResetDataPartitionRaftMemberRequest(ID uint64, newPeers []proto.Peer) (req *proto.ResetDataPartitionRaftMemberRequest) {
+	req = &proto.ResetDataPartitionRaftMemberRequest{
+		PartitionId: ID,
+		NewPeers:    newPeers,
+	}
+	return
+}
+
 func newLoadDataPartitionMetricRequest(ID uint64) (req *proto.LoadDataPartitionRequest) {
 	req = &proto.LoadDataPartitionRequest{
 		PartitionId: ID,
@@ -93,6 +101,8 @@ func unmarshalTaskResponse(task *proto.AdminTask) (err error) {
 		response = &proto.UpdateMetaPartitionResponse{}
 	case proto.OpDecommissionMetaPartition:
 		response = &proto.MetaPartitionDecommissionResponse{}
+	case proto.OpMetaTierScan:
+		response = &proto.TierScanResponse{}
 	default:
 		log.LogError(fmt.Sprintf("unknown operate code(%v)", task.OpCode))
 	}
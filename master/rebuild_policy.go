@@ -0,0 +1,201 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// the number of the missing replicas rebuilt in one round at most, so that the rebuild of a failed
+// data node is spread over the rounds
+const maxReplicasToRebuildPerRound = 16
+
+func (c *Cluster) getRebuildPolicies() (policies []*proto.RebuildPolicy) {
+	c.rebuildPolicyLock.RLock()
+	defer c.rebuildPolicyLock.RUnlock()
+	policies = make([]*proto.RebuildPolicy, 0, len(c.rebuildPolicies))
+	for _, policy := range c.rebuildPolicies {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Zone < policies[j].Zone
+	})
+	return
+}
+
+func (c *Cluster) loadRebuildPolicies(policies []*proto.RebuildPolicy) {
+	c.rebuildPolicyLock.Lock()
+	defer c.rebuildPolicyLock.Unlock()
+	c.rebuildPolicies = make(map[string]*proto.RebuildPolicy, len(policies))
+	for _, policy := range policies {
+		c.rebuildPolicies[policy.Zone] = policy
+	}
+}
+
+// getZoneRebuildPolicy returns the policy applied to the data nodes of the zone, which is the policy
+// of the zone if it is set, otherwise the default of the cluster.
+func (c *Cluster) getZoneRebuildPolicy(zone string) (policy proto.RebuildPolicy) {
+	c.rebuildPolicyLock.RLock()
+	defer c.rebuildPolicyLock.RUnlock()
+	if p, ok := c.rebuildPolicies[zone]; ok {
+		return *p
+	}
+	if p, ok := c.rebuildPolicies[""]; ok {
+		policy = *p
+	}
+	policy.Zone = zone
+	return
+}
+
+// setRebuildPolicy sets the rebuild policy of the zone, or the default of the cluster if the zone is empty.
+func (c *Cluster) setRebuildPolicy(policy *proto.RebuildPolicy) (err error) {
+	if policy.DelaySec < 0 {
+		return proto.ErrParamError
+	}
+	if policy.Zone != "" {
+		if _, err = c.t.getZone(policy.Zone); err != nil {
+			return proto.ErrZoneNotExists
+		}
+	}
+	c.rebuildPolicyLock.Lock()
+	oldPolicy, existed := c.rebuildPolicies[policy.Zone]
+	c.rebuildPolicies[policy.Zone] = policy
+	c.rebuildPolicyLock.Unlock()
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setRebuildPolicy] zone(%v) err[%v]", policy.Zone, err)
+		c.restoreRebuildPolicy(policy.Zone, oldPolicy, existed)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[setRebuildPolicy] zone(%v) delay(%vs) maxBandwidth(%vMB/s)",
+		policy.Zone, policy.DelaySec, policy.MaxBandwidth)
+	return
+}
+
+// removeRebuildPolicy drops the policy of the zone so that the default of the cluster applies to it,
+// removing the default of the cluster disables the rebuild of the zones without a policy.
+func (c *Cluster) removeRebuildPolicy(zone string) (err error) {
+	c.rebuildPolicyLock.Lock()
+	oldPolicy, existed := c.rebuildPolicies[zone]
+	delete(c.rebuildPolicies, zone)
+	c.rebuildPolicyLock.Unlock()
+	if !existed {
+		return
+	}
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[removeRebuildPolicy] zone(%v) err[%v]", zone, err)
+		c.restoreRebuildPolicy(zone, oldPolicy, existed)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[removeRebuildPolicy] zone(%v)", zone)
+	return
+}
+
+func (c *Cluster) restoreRebuildPolicy(zone string, policy *proto.RebuildPolicy, existed bool) {
+	c.rebuildPolicyLock.Lock()
+	defer c.rebuildPolicyLock.Unlock()
+	if existed {
+		c.rebuildPolicies[zone] = policy
+	} else {
+		delete(c.rebuildPolicies, zone)
+	}
+}
+
+// getRebuildBandwidth returns the bandwidth in bytes per second that a data node of the zone may use to
+// repair and rebuild its replicas, zero means unlimited.
+func (c *Cluster) getRebuildBandwidth(zone string) uint64 {
+	return c.getZoneRebuildPolicy(zone).MaxBandwidth * util.MB
+}
+
+// missingReplicaToRebuild returns the address of a replica that has been missing longer than the rebuild
+// delay of the zone of its data node, or an empty string if none of the replicas is to be rebuilt.
+// The replicas on the nodes in maintenance are expected to come back, so they are never rebuilt.
+func (partition *DataPartition) missingReplicaToRebuild(delayOf func(zone string) int64) (addr string, delay int64) {
+	partition.RLock()
+	defer partition.RUnlock()
+	if partition.isRecover {
+		return
+	}
+	for _, replica := range partition.Replicas {
+		dataNode := replica.getReplicaNode()
+		if dataNode == nil || dataNode.inMaintenance() || dataNode.ToBeOffline || !partition.hasHost(replica.Addr) {
+			continue
+		}
+		if delay = delayOf(dataNode.ZoneName); delay > 0 && replica.isMissing(delay) {
+			return replica.Addr, delay
+		}
+	}
+	return "", 0
+}
+
+// rebuildMissingReplicas moves the replicas missing longer than the rebuild delay to the other data nodes.
+// A replica missing for a short while, such as during a network blip or a restart, is recovered by itself,
+// so the delay keeps the cluster from copying the whole data of a node which is coming back.
+func (c *Cluster) rebuildMissingReplicas() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("rebuildMissingReplicas occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
+				"rebuildMissingReplicas occurred panic")
+		}
+	}()
+	delays := make(map[string]int64)
+	delayOf := func(zone string) int64 {
+		delay, ok := delays[zone]
+		if !ok {
+			delay = c.getZoneRebuildPolicy(zone).DelaySec
+			delays[zone] = delay
+		}
+		return delay
+	}
+	var rebuilt int
+	for _, vol := range c.allVols() {
+		for _, dp := range vol.dataPartitions.clonePartitions() {
+			if rebuilt >= maxReplicasToRebuildPerRound {
+				return
+			}
+			addr, delay := dp.missingReplicaToRebuild(delayOf)
+			if addr == "" {
+				continue
+			}
+			rebuilt++
+			if err := c.decommissionDataPartition(addr, dp, replicaRebuildErr); err != nil {
+				log.LogErrorf("action[rebuildMissingReplicas] vol[%v] partition[%v] addr[%v] err[%v]",
+					vol.Name, dp.PartitionID, addr, err)
+				continue
+			}
+			Warn(c.Name, fmt.Sprintf("action[rebuildMissingReplicas] clusterID[%v] vol[%v] partition[%v] replica on %v "+
+				"has been missing longer than %vs, rebuilt on the other data node", c.Name, vol.Name, dp.PartitionID, addr, delay))
+		}
+	}
+}
+
+func (c *Cluster) scheduleToRebuildMissingReplicas() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.rebuildMissingReplicas()
+			}
+			time.Sleep(time.Minute)
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var reloadableKeys = []string{cfgMetaNodeReservedMem, missingDataPartitionInterval, dataPartitionTimeOutSec,
+	secondsToFreeDataPartitionAfterLoad, intervalToCheckTierPolicy}
+
+// parseReloadableConfig parses the config keys which are applied again when the config is reloaded,
+// the keys absent from the config are left as they are in the cluster config.
+func parseReloadableConfig(cfg *config.Config, cc *clusterConfig) (err error) {
+	if metaNodeReservedMemory := cfg.GetString(cfgMetaNodeReservedMem); metaNodeReservedMemory != "" {
+		if cc.metaNodeReservedMem, err = strconv.ParseUint(metaNodeReservedMemory, 10, 64); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+	if cc.metaNodeReservedMem < 32*1024*1024 {
+		cc.metaNodeReservedMem = defaultMetaNodeReservedMem
+	}
+	if interval := cfg.GetString(missingDataPartitionInterval); interval != "" {
+		if cc.MissingDataPartitionInterval, err = strconv.ParseInt(interval, 10, 0); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+	if timeout := cfg.GetString(dataPartitionTimeOutSec); timeout != "" {
+		if cc.DataPartitionTimeOutSec, err = strconv.ParseInt(timeout, 10, 0); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+	if secondsToFreeDP := cfg.GetString(secondsToFreeDataPartitionAfterLoad); secondsToFreeDP != "" {
+		if cc.secondsToFreeDataPartitionAfterLoad, err = strconv.ParseInt(secondsToFreeDP, 10, 64); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+	if tierPolicyInterval := cfg.GetString(intervalToCheckTierPolicy); tierPolicyInterval != "" {
+		if cc.IntervalToCheckTierPolicy, err = strconv.ParseInt(tierPolicyInterval, 10, 64); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+	if cc.IntervalToCheckTierPolicy <= 0 {
+		cc.IntervalToCheckTierPolicy = defaultIntervalToCheckTierPolicy
+	}
+	return
+}
+
+// ReloadableKeys returns the config keys applied by Reload.
+func (m *Server) ReloadableKeys() []string {
+	return reloadableKeys
+}
+
+// Reload applies the reloadable keys of the config, the keys removed from the config fall back to the defaults.
+func (m *Server) Reload(cfg *config.Config) (err error) {
+	cc := newClusterConfig()
+	if err = parseReloadableConfig(cfg, cc); err != nil {
+		return
+	}
+	m.config.metaNodeReservedMem = cc.metaNodeReservedMem
+	m.config.MissingDataPartitionInterval = cc.MissingDataPartitionInterval
+	m.config.DataPartitionTimeOutSec = cc.DataPartitionTimeOutSec
+	m.config.secondsToFreeDataPartitionAfterLoad = cc.secondsToFreeDataPartitionAfterLoad
+	m.config.IntervalToCheckTierPolicy = cc.IntervalToCheckTierPolicy
+	log.LogInfof("action[Reload] metaNodeReservedMem(%v) missingDataPartitionInterval(%v) dataPartitionTimeOutSec(%v) "+
+		"secondsToFreeDataPartitionAfterLoad(%v) intervalToCheckTierPolicy(%v)", cc.metaNodeReservedMem,
+		cc.MissingDataPartitionInterval, cc.DataPartitionTimeOutSec, cc.secondsToFreeDataPartitionAfterLoad,
+		cc.IntervalToCheckTierPolicy)
+	return
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func repairLinkKey(srcZone, dstZone string) string {
+	return srcZone + keySeparator + dstZone
+}
+
+func (c *Cluster) getRepairLinks() (links []*proto.RepairLink) {
+	c.repairLinkLock.RLock()
+	defer c.repairLinkLock.RUnlock()
+	links = make([]*proto.RepairLink, 0, len(c.repairLinks))
+	for _, link := range c.repairLinks {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].SrcZone != links[j].SrcZone {
+			return links[i].SrcZone < links[j].SrcZone
+		}
+		return links[i].DstZone < links[j].DstZone
+	})
+	return
+}
+
+func (c *Cluster) loadRepairLinks(links []*proto.RepairLink) {
+	c.repairLinkLock.Lock()
+	defer c.repairLinkLock.Unlock()
+	c.repairLinks = make(map[string]*proto.RepairLink, len(links))
+	for _, link := range links {
+		c.repairLinks[repairLinkKey(link.SrcZone, link.DstZone)] = link
+	}
+}
+
+// setRepairLink sets the bandwidth budget of the repair traffic from the source zone to the destination zone,
+// the budget is removed if the bandwidth is zero.
+func (c *Cluster) setRepairLink(srcZone, dstZone string, maxBandwidth uint64) (err error) {
+	if maxBandwidth != 0 {
+		if _, err = c.t.getZone(srcZone); err != nil {
+			return proto.ErrZoneNotExists
+		}
+		if _, err = c.t.getZone(dstZone); err != nil {
+			return proto.ErrZoneNotExists
+		}
+	}
+	key := repairLinkKey(srcZone, dstZone)
+	c.repairLinkLock.Lock()
+	oldLink, existed := c.repairLinks[key]
+	if maxBandwidth == 0 {
+		delete(c.repairLinks, key)
+	} else {
+		c.repairLinks[key] = &proto.RepairLink{SrcZone: srcZone, DstZone: dstZone, MaxBandwidth: maxBandwidth}
+	}
+	c.repairLinkLock.Unlock()
+
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setRepairLink] src(%v) dst(%v) err[%v]", srcZone, dstZone, err)
+		c.repairLinkLock.Lock()
+		if existed {
+			c.repairLinks[key] = oldLink
+		} else {
+			delete(c.repairLinks, key)
+		}
+		c.repairLinkLock.Unlock()
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[setRepairLink] src(%v) dst(%v) maxBandwidth(%vMB/s)", srcZone, dstZone, maxBandwidth)
+	return
+}
+
+// getRepairBudgets splits the budget of each link evenly among the data nodes of the destination zone,
+// and returns the budgets of one data node by the destination zone and then the source zone.
+func (c *Cluster) getRepairBudgets() (budgets map[string]map[string]uint64) {
+	budgets = make(map[string]map[string]uint64)
+	for _, link := range c.getRepairLinks() {
+		zone, err := c.t.getZone(link.DstZone)
+		if err != nil {
+			continue
+		}
+		nodeCount := uint64(zone.dataNodeCount())
+		if nodeCount == 0 {
+			continue
+		}
+		if budgets[link.DstZone] == nil {
+			budgets[link.DstZone] = make(map[string]uint64)
+		}
+		budgets[link.DstZone][link.SrcZone] = divideQosLimit(link.MaxBandwidth*util.MB, nodeCount)
+	}
+	return
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 // configuration keys
@@ -104,6 +105,12 @@ func (m *Server) Start(cfg *config.Config) (err error) {
 	if m.rocksDBStore, err = raftstore.NewRocksDBStore(m.storeDir, LRUCacheSize, WriteBufferSize); err != nil {
 		return
 	}
+	if file := cfg.GetString(cfgRestoreMeta); file != "" {
+		if err = m.restoreMeta(file); err != nil {
+			log.LogError(errors.Stack(err))
+			return
+		}
+	}
 
 	if err = m.createRaftServer(); err != nil {
 		log.LogError(errors.Stack(err))
@@ -111,13 +118,18 @@ func (m *Server) Start(cfg *config.Config) (err error) {
 	}
 	m.initCluster()
 	m.initUser()
+	m.registerHealthChecks()
 	m.cluster.partition = m.partition
 	m.cluster.idAlloc.partition = m.partition
 	MasterSecretKey := cfg.GetString(SecretKey)
 	if m.cluster.MasterSecretKey, err = cryptoutil.Base64Decode(MasterSecretKey); err != nil {
 		return fmt.Errorf("action[Start] failed %v, err: master service Key invalid = %s", proto.ErrInvalidCfg, MasterSecretKey)
 	}
+	if err = tracing.Init(ModuleName, cfg); err != nil {
+		return fmt.Errorf("action[Start] failed, err: bad tracing config: %v", err)
+	}
 	m.cluster.scheduleTask()
+	m.user.scheduleToRetireExpiredKeys()
 	m.startHTTPService(ModuleName, cfg)
 	exporter.RegistConsul(m.clusterName, ModuleName, cfg)
 	metricsService := newMonitorMetrics(m.cluster)
@@ -134,6 +146,7 @@ func (m *Server) Shutdown() {
 			log.LogErrorf("action[Shutdown] failed, err: %v", err)
 		}
 	}
+	tracing.Stop()
 	m.wg.Done()
 }
 
@@ -178,14 +191,8 @@ func (m *Server) checkConfig(cfg *config.Config) (err error) {
 		m.config.nodeSetCapacity = defaultNodeSetCapacity
 	}
 
-	metaNodeReservedMemory := cfg.GetString(cfgMetaNodeReservedMem)
-	if metaNodeReservedMemory != "" {
-		if m.config.metaNodeReservedMem, err = strconv.ParseUint(metaNodeReservedMemory, 10, 64); err != nil {
-			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
-		}
-	}
-	if m.config.metaNodeReservedMem < 32*1024*1024 {
-		m.config.metaNodeReservedMem = defaultMetaNodeReservedMem
+	if err = parseReloadableConfig(cfg, m.config); err != nil {
+		return
 	}
 
 	retainLogs := cfg.GetString(CfgRetainLogs)
@@ -199,20 +206,6 @@ func (m *Server) checkConfig(cfg *config.Config) (err error) {
 	}
 	syslog.Println("retainLogs=", m.retainLogs)
 
-	missingDataPartitionInterval := cfg.GetString(missingDataPartitionInterval)
-	if missingDataPartitionInterval != "" {
-		if m.config.MissingDataPartitionInterval, err = strconv.ParseInt(missingDataPartitionInterval, 10, 0); err != nil {
-			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
-		}
-	}
-
-	dataPartitionTimeOutSec := cfg.GetString(dataPartitionTimeOutSec)
-	if dataPartitionTimeOutSec != "" {
-		if m.config.DataPartitionTimeOutSec, err = strconv.ParseInt(dataPartitionTimeOutSec, 10, 0); err != nil {
-			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
-		}
-	}
-
 	numberOfDataPartitionsToLoad := cfg.GetString(NumberOfDataPartitionsToLoad)
 	if numberOfDataPartitionsToLoad != "" {
 		if m.config.numberOfDataPartitionsToLoad, err = strconv.Atoi(numberOfDataPartitionsToLoad); err != nil {
@@ -222,11 +215,6 @@ func (m *Server) checkConfig(cfg *config.Config) (err error) {
 	if m.config.numberOfDataPartitionsToLoad <= 40 {
 		m.config.numberOfDataPartitionsToLoad = 40
 	}
-	if secondsToFreeDP := cfg.GetString(secondsToFreeDataPartitionAfterLoad); secondsToFreeDP != "" {
-		if m.config.secondsToFreeDataPartitionAfterLoad, err = strconv.ParseInt(secondsToFreeDP, 10, 64); err != nil {
-			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
-		}
-	}
 	m.tickInterval = int(cfg.GetFloat(cfgTickInterval))
 	m.electionTick = int(cfg.GetFloat(cfgElectionTick))
 	if m.tickInterval <= 300 {
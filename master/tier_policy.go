@@ -0,0 +1,261 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (vol *Vol) getTierRules() (rules []*proto.TierRule) {
+	vol.tierLock.RLock()
+	defer vol.tierLock.RUnlock()
+	rules = make([]*proto.TierRule, 0, len(vol.tierRules))
+	for _, rule := range vol.tierRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	return
+}
+
+func (vol *Vol) getTierRule(name string) (rule *proto.TierRule, ok bool) {
+	vol.tierLock.RLock()
+	defer vol.tierLock.RUnlock()
+	rule, ok = vol.tierRules[name]
+	return
+}
+
+func (vol *Vol) putTierRule(rule *proto.TierRule) {
+	vol.tierLock.Lock()
+	defer vol.tierLock.Unlock()
+	vol.tierRules[rule.Name] = rule
+}
+
+func (vol *Vol) deleteTierRule(name string) {
+	vol.tierLock.Lock()
+	defer vol.tierLock.Unlock()
+	delete(vol.tierRules, name)
+	delete(vol.tierScanResults, name)
+}
+
+func (vol *Vol) tierRuleCount() int {
+	vol.tierLock.RLock()
+	defer vol.tierLock.RUnlock()
+	return len(vol.tierRules)
+}
+
+func (vol *Vol) updateTierScanResult(resp *proto.TierScanResponse) {
+	vol.tierLock.Lock()
+	defer vol.tierLock.Unlock()
+	if _, ok := vol.tierRules[resp.RuleName]; !ok {
+		return
+	}
+	results, ok := vol.tierScanResults[resp.RuleName]
+	if !ok {
+		results = make(map[uint64]*proto.TierScanResponse, 0)
+		vol.tierScanResults[resp.RuleName] = results
+	}
+	results[resp.PartitionID] = resp
+}
+
+func (vol *Vol) getTierRuleStats() (stats []*proto.TierRuleStat) {
+	vol.tierLock.RLock()
+	defer vol.tierLock.RUnlock()
+	stats = make([]*proto.TierRuleStat, 0, len(vol.tierScanResults))
+	for ruleName, results := range vol.tierScanResults {
+		stat := &proto.TierRuleStat{RuleName: ruleName, ScannedPartitions: len(results), UpdateTime: time.Now().Unix()}
+		for _, resp := range results {
+			stat.CandidateInodes += resp.CandidateInodes
+			stat.CandidateBytes += resp.CandidateBytes
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].RuleName < stats[j].RuleName
+	})
+	return
+}
+
+// checkTierPolicy sends the tasks to count the candidates of the enabled rules to the leaders of the meta
+// partitions. The candidates are reported only, the data is not migrated.
+func (vol *Vol) checkTierPolicy(c *Cluster) {
+	rules := vol.getTierRules()
+	if len(rules) == 0 {
+		return
+	}
+	tasks := make([]*proto.AdminTask, 0)
+	vol.mpsLock.RLock()
+	for _, mp := range vol.MetaPartitions {
+		for _, rule := range rules {
+			if !rule.Enable {
+				continue
+			}
+			if task := mp.createTaskToScanTierCandidates(c.Name, rule); task != nil {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	vol.mpsLock.RUnlock()
+	c.addMetaNodeTasks(tasks)
+}
+
+func (mp *MetaPartition) createTaskToScanTierCandidates(clusterID string, rule *proto.TierRule) (t *proto.AdminTask) {
+	mp.RLock()
+	defer mp.RUnlock()
+	mr, err := mp.getMetaReplicaLeader()
+	if err != nil {
+		log.LogWarnf("action[createTaskToScanTierCandidates] clusterID[%v] vol[%v] meta partition[%v] no leader",
+			clusterID, mp.volName, mp.PartitionID)
+		return
+	}
+	req := &proto.TierScanRequest{
+		PartitionID: mp.PartitionID,
+		VolName:     mp.volName,
+		RuleName:    rule.Name,
+		InactiveSec: rule.InactiveSeconds(),
+	}
+	t = proto.NewAdminTask(proto.OpMetaTierScan, mr.Addr, req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	t.ID = fmt.Sprintf("%v_rule[%v]", t.ID, rule.Name)
+	return
+}
+
+func (c *Cluster) scheduleToCheckTierPolicies() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.checkTierPolicies()
+			}
+			time.Sleep(time.Second * time.Duration(c.cfg.IntervalToCheckTierPolicy))
+		}
+	}()
+}
+
+func (c *Cluster) checkTierPolicies() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("checkTierPolicies occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
+				"checkTierPolicies occurred panic")
+		}
+	}()
+	vols := c.copyVols()
+	for _, vol := range vols {
+		if vol.Status == markDelete {
+			continue
+		}
+		vol.checkTierPolicy(c)
+	}
+}
+
+func (c *Cluster) dealMetaTierScanResp(nodeAddr string, resp *proto.TierScanResponse) (err error) {
+	if resp.Status == proto.TaskFailed {
+		msg := fmt.Sprintf("action[dealMetaTierScanResp],clusterID[%v] nodeAddr %v vol[%v] mp[%v] rule[%v] scan failed,err %v",
+			c.Name, nodeAddr, resp.VolName, resp.PartitionID, resp.RuleName, resp.Result)
+		log.LogError(msg)
+		return
+	}
+	vol, err := c.getVol(resp.VolName)
+	if err != nil {
+		return
+	}
+	vol.updateTierScanResult(resp)
+	log.LogInfof("action[dealMetaTierScanResp] vol[%v] mp[%v] rule[%v] candidateInodes[%v] candidateBytes[%v]",
+		resp.VolName, resp.PartitionID, resp.RuleName, resp.CandidateInodes, resp.CandidateBytes)
+	return
+}
+
+func (c *Cluster) setVolTierRule(name, authKey string, rule *proto.TierRule) (err error) {
+	var (
+		vol     *Vol
+		oldRule *proto.TierRule
+		exist   bool
+	)
+	if err = rule.Validate(); err != nil {
+		goto errHandler
+	}
+	if vol, err = c.getVol(name); err != nil {
+		err = proto.ErrVolNotExists
+		goto errHandler
+	}
+	vol.Lock()
+	defer vol.Unlock()
+	if !matchKey(vol.Owner, authKey) {
+		return proto.ErrVolAuthKeyNotMatch
+	}
+	oldRule, exist = vol.getTierRule(rule.Name)
+	if !exist && vol.tierRuleCount() >= proto.MaxTierRulesPerVol {
+		err = fmt.Errorf("vol[%v] has too many tier rules, at most %v", name, proto.MaxTierRulesPerVol)
+		goto errHandler
+	}
+	vol.putTierRule(rule)
+	if err = c.syncUpdateVol(vol); err != nil {
+		if exist {
+			vol.putTierRule(oldRule)
+		} else {
+			vol.deleteTierRule(rule.Name)
+		}
+		log.LogErrorf("action[setVolTierRule] vol[%v] err[%v]", name, err)
+		err = proto.ErrPersistenceByRaft
+		goto errHandler
+	}
+	return
+errHandler:
+	err = fmt.Errorf("action[setVolTierRule], clusterID[%v] name:%v, err:%v ", c.Name, name, err.Error())
+	log.LogError(errors.Stack(err))
+	Warn(c.Name, err.Error())
+	return
+}
+
+func (c *Cluster) deleteVolTierRule(name, authKey, ruleName string) (err error) {
+	var (
+		vol     *Vol
+		oldRule *proto.TierRule
+		exist   bool
+	)
+	if vol, err = c.getVol(name); err != nil {
+		err = proto.ErrVolNotExists
+		goto errHandler
+	}
+	vol.Lock()
+	defer vol.Unlock()
+	if !matchKey(vol.Owner, authKey) {
+		return proto.ErrVolAuthKeyNotMatch
+	}
+	if oldRule, exist = vol.getTierRule(ruleName); !exist {
+		err = fmt.Errorf("tier rule[%v] not exists", ruleName)
+		goto errHandler
+	}
+	vol.deleteTierRule(ruleName)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.putTierRule(oldRule)
+		log.LogErrorf("action[deleteVolTierRule] vol[%v] err[%v]", name, err)
+		err = proto.ErrPersistenceByRaft
+		goto errHandler
+	}
+	return
+errHandler:
+	err = fmt.Errorf("action[deleteVolTierRule], clusterID[%v] name:%v, err:%v ", c.Name, name, err.Error())
+	log.LogError(errors.Stack(err))
+	Warn(c.Name, err.Error())
+	return
+}
@@ -17,11 +17,19 @@ import (
 const (
 	accessKeyLength     = 16
 	secretKeyLength     = 32
+	sessionTokenLength  = 64
 	RootUserID          = "root"
 	DefaultRootPasswd   = "ChubaoFSRoot"
 	DefaultUserPassword = "ChubaoFSUser"
 )
 
+const (
+	defaultSessionDuration = 3600      // seconds
+	minSessionDuration     = 900       // seconds
+	maxSessionDuration     = 12 * 3600 // seconds
+	maxSessionsPerUser     = 64
+)
+
 type User struct {
 	fsm            *MetadataFsm
 	partition      raftstore.Partition
@@ -136,6 +144,22 @@ func (u *User) deleteKey(userID string) (err error) {
 	if err = u.syncDeleteAKUser(akUser); err != nil {
 		return
 	}
+	if userInfo.RetiringAccessKey != "" {
+		if value, exist := u.AKStore.Load(userInfo.RetiringAccessKey); exist {
+			if err = u.syncDeleteAKUser(value.(*proto.AKUser)); err != nil {
+				return
+			}
+			u.AKStore.Delete(userInfo.RetiringAccessKey)
+		}
+	}
+	for _, session := range userInfo.Sessions {
+		if value, exist := u.AKStore.Load(session.AccessKey); exist {
+			if err = u.syncDeleteAKUser(value.(*proto.AKUser)); err != nil {
+				return
+			}
+			u.AKStore.Delete(session.AccessKey)
+		}
+	}
 	u.userStore.Delete(userID)
 	u.AKStore.Delete(akUser.AccessKey)
 	// delete userID from related policy in volUserStore
@@ -225,6 +249,284 @@ func (u *User) updateKey(param *proto.UserUpdateParam) (userInfo *proto.UserInfo
 	return
 }
 
+// rotateKey makes a new key pair the current one of the user, the former key pair keeps valid
+// during the grace period so that the clients could switch to the new one gradually.
+func (u *User) rotateKey(param *proto.UserRotateKeyParam) (userInfo *proto.UserInfo, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+	if param.GracePeriod < 0 {
+		err = proto.ErrParamError
+		return
+	}
+	var accessKey = param.AccessKey
+	if accessKey != "" && !proto.IsValidAK(accessKey) {
+		err = proto.ErrInvalidAccessKey
+		return
+	}
+	var secretKey = param.SecretKey
+	if secretKey == "" {
+		secretKey = util.RandomString(secretKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+	} else if !proto.IsValidSK(secretKey) {
+		err = proto.ErrInvalidSecretKey
+		return
+	}
+
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	if value, exist := u.userStore.Load(param.UserID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.UserType == proto.UserTypeRoot {
+		err = proto.ErrNoPermission
+		return
+	}
+	if userInfo.RetiringAccessKey != "" {
+		err = proto.ErrKeyRotationInProgress
+		return
+	}
+	if accessKey == "" {
+		accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+		_, exist := u.AKStore.Load(accessKey)
+		for exist {
+			accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+			_, exist = u.AKStore.Load(accessKey)
+		}
+	} else if _, exist := u.AKStore.Load(accessKey); exist {
+		err = proto.ErrDuplicateAccessKey
+		return
+	}
+	var akUserBef *proto.AKUser
+	if akUserBef, err = u.getAKUser(userInfo.AccessKey); err != nil {
+		return
+	}
+	akUserAft := &proto.AKUser{AccessKey: accessKey, UserID: userInfo.UserID, Password: akUserBef.Password}
+	if err = u.syncAddAKUser(akUserAft); err != nil {
+		return
+	}
+	userInfo.RetiringAccessKey, userInfo.RetiringSecretKey = userInfo.AccessKey, userInfo.SecretKey
+	userInfo.RetiringExpireTime = time.Now().Unix() + param.GracePeriod
+	userInfo.AccessKey, userInfo.SecretKey = accessKey, secretKey
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.AccessKey, userInfo.SecretKey = userInfo.RetiringAccessKey, userInfo.RetiringSecretKey
+		userInfo.RetiringAccessKey, userInfo.RetiringSecretKey, userInfo.RetiringExpireTime = "", "", 0
+		_ = u.syncDeleteAKUser(akUserAft)
+		return
+	}
+	u.AKStore.Store(accessKey, akUserAft)
+	log.LogInfof("action[rotateKey], userID: %v, accesskey[%v], retiring accesskey[%v], expire[%v]",
+		userInfo.UserID, userInfo.AccessKey, userInfo.RetiringAccessKey, userInfo.RetiringExpireTime)
+	return
+}
+
+// retireKey invalidates the retiring key pair of the user at once.
+func (u *User) retireKey(userID string) (userInfo *proto.UserInfo, err error) {
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	if value, exist := u.userStore.Load(userID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.RetiringAccessKey == "" {
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	var retiringAK = userInfo.RetiringAccessKey
+	if value, exist := u.AKStore.Load(retiringAK); exist {
+		if err = u.syncDeleteAKUser(value.(*proto.AKUser)); err != nil {
+			return
+		}
+	}
+	userInfo.RetiringAccessKey, userInfo.RetiringSecretKey, userInfo.RetiringExpireTime = "", "", 0
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		return
+	}
+	u.AKStore.Delete(retiringAK)
+	log.LogInfof("action[retireKey], userID: %v, accesskey[%v]", userID, retiringAK)
+	return
+}
+
+func (u *User) scheduleToRetireExpiredKeys() {
+	go func() {
+		for {
+			if u.partition != nil && u.partition.IsRaftLeader() {
+				u.retireExpiredKeys()
+			}
+			time.Sleep(time.Minute)
+		}
+	}()
+}
+
+func (u *User) retireExpiredKeys() {
+	var now = time.Now().Unix()
+	var expired = make([]string, 0)
+	var expiredSessions = make([]string, 0)
+	u.userStore.Range(func(key, value interface{}) bool {
+		userInfo := value.(*proto.UserInfo)
+		userInfo.Mu.RLock()
+		if userInfo.RetiringAccessKey != "" && userInfo.RetiringExpireTime <= now {
+			expired = append(expired, userInfo.UserID)
+		}
+		for _, session := range userInfo.Sessions {
+			if session.Expired(now) {
+				expiredSessions = append(expiredSessions, userInfo.UserID)
+				break
+			}
+		}
+		userInfo.Mu.RUnlock()
+		return true
+	})
+	for _, userID := range expired {
+		if _, err := u.retireKey(userID); err != nil && err != proto.ErrAccessKeyNotExists {
+			log.LogErrorf("action[retireExpiredKeys] retire key of user[%v] failed, err[%v]", userID, err)
+		}
+	}
+	for _, userID := range expiredSessions {
+		if err := u.removeExpiredSessions(userID); err != nil && err != proto.ErrUserNotExists {
+			log.LogErrorf("action[retireExpiredKeys] remove expired sessions of user[%v] failed, err[%v]", userID, err)
+		}
+	}
+}
+
+// assumeRole issues a session credential of the user which expires after the duration. The session
+// credential has the same permissions as the user and is valid only with its session token.
+func (u *User) assumeRole(param *proto.UserAssumeRoleParam) (session *proto.SessionCredential, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+	var duration = param.Duration
+	if duration == 0 {
+		duration = defaultSessionDuration
+	}
+	if duration < minSessionDuration || duration > maxSessionDuration {
+		err = proto.ErrParamError
+		return
+	}
+
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	var userInfo *proto.UserInfo
+	if value, exist := u.userStore.Load(param.UserID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.UserType == proto.UserTypeRoot {
+		err = proto.ErrNoPermission
+		return
+	}
+	var now, liveSessions = time.Now().Unix(), 0
+	for _, session := range userInfo.Sessions {
+		if !session.Expired(now) {
+			liveSessions++
+		}
+	}
+	if liveSessions >= maxSessionsPerUser {
+		err = proto.ErrTooManySessions
+		return
+	}
+	var accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+	_, exist := u.AKStore.Load(accessKey)
+	for exist {
+		accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+		_, exist = u.AKStore.Load(accessKey)
+	}
+	var akUserBef *proto.AKUser
+	if akUserBef, err = u.getAKUser(userInfo.AccessKey); err != nil {
+		return
+	}
+	session = &proto.SessionCredential{
+		AccessKey:    accessKey,
+		SecretKey:    util.RandomString(secretKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter),
+		SessionToken: util.RandomString(sessionTokenLength, util.Numeric|util.LowerLetter|util.UpperLetter),
+		ExpireTime:   now + duration,
+	}
+	akUser := &proto.AKUser{AccessKey: accessKey, UserID: userInfo.UserID, Password: akUserBef.Password}
+	if err = u.syncAddAKUser(akUser); err != nil {
+		return
+	}
+	userInfo.Sessions = append(userInfo.Sessions, session)
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.Sessions = userInfo.Sessions[:len(userInfo.Sessions)-1]
+		_ = u.syncDeleteAKUser(akUser)
+		return
+	}
+	u.AKStore.Store(accessKey, akUser)
+	log.LogInfof("action[assumeRole], userID: %v, session accesskey[%v], expire[%v]",
+		userInfo.UserID, accessKey, session.ExpireTime)
+	return
+}
+
+// removeExpiredSessions drops the expired session credentials of the user.
+func (u *User) removeExpiredSessions(userID string) (err error) {
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	var userInfo *proto.UserInfo
+	if value, exist := u.userStore.Load(userID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	var now = time.Now().Unix()
+	var live = make([]*proto.SessionCredential, 0, len(userInfo.Sessions))
+	var expired = make([]*proto.SessionCredential, 0)
+	for _, session := range userInfo.Sessions {
+		if session.Expired(now) {
+			expired = append(expired, session)
+		} else {
+			live = append(live, session)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	for _, session := range expired {
+		if value, exist := u.AKStore.Load(session.AccessKey); exist {
+			if err = u.syncDeleteAKUser(value.(*proto.AKUser)); err != nil {
+				return
+			}
+			u.AKStore.Delete(session.AccessKey)
+		}
+	}
+	var former = userInfo.Sessions
+	userInfo.Sessions = live
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.Sessions = former
+		return
+	}
+	log.LogInfof("action[removeExpiredSessions], userID: %v, expired sessions[%v]", userID, len(expired))
+	return
+}
+
 func (u *User) getKeyInfo(ak string) (userInfo *proto.UserInfo, err error) {
 	var akUser *proto.AKUser
 	if akUser, err = u.getAKUser(ak); err != nil {
@@ -285,13 +587,62 @@ func (u *User) removePolicy(params *proto.UserPermRemoveParam) (userInfo *proto.
 		err = proto.ErrPersistenceByRaft
 		return
 	}
-	if err = u.removeUserFromVol(params.UserID, params.Volume); err != nil {
-		return
+	if !userInfo.Policy.HasPolicyOf(params.Volume) {
+		if err = u.removeUserFromVol(params.UserID, params.Volume); err != nil {
+			return
+		}
 	}
 	log.LogInfof("action[removePolicy], userID: %v, volume: %v", params.UserID, params.Volume)
 	return
 }
 
+func (u *User) setPrefixPolicy(params *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error) {
+	if userInfo, err = u.getUserInfo(params.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.Policy.IsOwn(params.Volume) {
+		err = proto.ErrIsOwner
+		return
+	}
+	userInfo.Policy.SetPrefixRule(params.Volume, &proto.PrefixRule{Prefix: params.Prefix, Effect: params.Effect, Actions: params.Actions})
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	if err = u.addUserToVol(params.UserID, params.Volume); err != nil {
+		return
+	}
+	log.LogInfof("action[setPrefixPolicy], userID: %v, volume: %v, prefix: %v, effect: %v",
+		params.UserID, params.Volume, params.Prefix, params.Effect)
+	return
+}
+
+func (u *User) removePrefixPolicy(params *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error) {
+	if userInfo, err = u.getUserInfo(params.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if !userInfo.Policy.RemovePrefixRule(params.Volume, params.Prefix, params.Effect) {
+		err = proto.ErrHaveNoPolicy
+		return
+	}
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	if !userInfo.Policy.HasPolicyOf(params.Volume) && !userInfo.Policy.IsOwn(params.Volume) {
+		if err = u.removeUserFromVol(params.UserID, params.Volume); err != nil {
+			return
+		}
+	}
+	log.LogInfof("action[removePrefixPolicy], userID: %v, volume: %v, prefix: %v, effect: %v",
+		params.UserID, params.Volume, params.Prefix, params.Effect)
+	return
+}
+
 func (u *User) addOwnVol(userID, volName string) (userInfo *proto.UserInfo, err error) {
 	if userInfo, err = u.getUserInfo(userID); err != nil {
 		return
@@ -300,6 +651,7 @@ func (u *User) addOwnVol(userID, volName string) (userInfo *proto.UserInfo, err
 	defer userInfo.Mu.Unlock()
 	userInfo.Policy.AddOwnVol(volName)
 	userInfo.Policy.RemoveAuthorizedVol(volName)
+	userInfo.Policy.RemovePrefixRules(volName)
 	if err = u.syncUpdateUserInfo(userInfo); err != nil {
 		err = proto.ErrPersistenceByRaft
 		return
@@ -352,6 +704,7 @@ func (u *User) deleteVolPolicy(volName string) (err error) {
 		userInfo.Mu.Lock()
 		userInfo.Policy.RemoveOwnVol(volName)
 		userInfo.Policy.RemoveAuthorizedVol(volName)
+		userInfo.Policy.RemovePrefixRules(volName)
 		if err = u.syncUpdateUserInfo(userInfo); err != nil {
 			err = proto.ErrPersistenceByRaft
 			userInfo.Mu.Unlock()
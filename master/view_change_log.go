@@ -0,0 +1,116 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// number of view changes kept in memory to calculate the delta for the clients
+const maxViewChangeEpochs = 128
+
+type viewChange struct {
+	epoch        uint64
+	partitionIDs []uint64
+}
+
+// viewChangeLog records which partitions of a view have changed in each epoch,
+// so that a client holding the view of an earlier epoch only needs to download the delta.
+// The high 32 bits of the epoch are the raft term of the leader which issued it, the log
+// starts over in each term, so the epochs issued by any other leader result in a full view.
+type viewChangeLog struct {
+	sync.RWMutex
+	term      uint64
+	epoch     uint64
+	baseEpoch uint64 // the oldest epoch from which the delta can be calculated
+	snapshot  map[uint64]interface{}
+	changes   []*viewChange
+}
+
+func newViewChangeLog() (l *viewChangeLog) {
+	l = new(viewChangeLog)
+	l.snapshot = make(map[uint64]interface{}, 0)
+	l.changes = make([]*viewChange, 0)
+	return
+}
+
+// update compares the view with the last snapshot, and moves to the next epoch if anything has changed.
+func (l *viewChangeLog) update(term uint64, view map[uint64]interface{}) (epoch uint64) {
+	l.Lock()
+	defer l.Unlock()
+	if term != l.term || l.epoch == 0 {
+		l.term = term
+		l.epoch = term<<32 + 1
+		l.baseEpoch = l.epoch
+		l.snapshot = view
+		l.changes = make([]*viewChange, 0)
+		return l.epoch
+	}
+	changed := make([]uint64, 0)
+	for id, v := range view {
+		if old, ok := l.snapshot[id]; !ok || !reflect.DeepEqual(old, v) {
+			changed = append(changed, id)
+		}
+	}
+	for id := range l.snapshot {
+		if _, ok := view[id]; !ok {
+			changed = append(changed, id)
+		}
+	}
+	l.snapshot = view
+	if len(changed) == 0 {
+		return l.epoch
+	}
+	l.epoch++
+	l.changes = append(l.changes, &viewChange{epoch: l.epoch, partitionIDs: changed})
+	if len(l.changes) > maxViewChangeEpochs {
+		l.baseEpoch = l.changes[0].epoch
+		l.changes = l.changes[1:]
+	}
+	return l.epoch
+}
+
+// delta returns the partitions that have been updated or removed since the given epoch.
+// The returned ok is false if the epoch is unknown or the log is left from an earlier term
+// of the leader, then the client needs the full view.
+func (l *viewChangeLog) delta(term, since uint64) (epoch uint64, updated []interface{}, removed []uint64, ok bool) {
+	l.RLock()
+	defer l.RUnlock()
+	if term != l.term || l.epoch == 0 || since < l.baseEpoch || since > l.epoch {
+		return
+	}
+	ids := make(map[uint64]bool, 0)
+	for _, change := range l.changes {
+		if change.epoch <= since {
+			continue
+		}
+		for _, id := range change.partitionIDs {
+			ids[id] = true
+		}
+	}
+	updated = make([]interface{}, 0)
+	removed = make([]uint64, 0)
+	for id := range ids {
+		if v, exist := l.snapshot[id]; exist {
+			updated = append(updated, v)
+		} else {
+			removed = append(removed, id)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	return l.epoch, updated, removed, true
+}
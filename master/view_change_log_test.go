@@ -0,0 +1,84 @@
+package master
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestViewChangeLog(t *testing.T) {
+	const term = 3
+	l := newViewChangeLog()
+	view := map[uint64]interface{}{
+		1: &proto.DataPartitionResponse{PartitionID: 1, Status: proto.ReadWrite},
+		2: &proto.DataPartitionResponse{PartitionID: 2, Status: proto.ReadWrite},
+	}
+	first := l.update(term, view)
+	if first>>32 != term {
+		t.Errorf("epoch should start from the term, expect[%v] actual[%v]", term, first>>32)
+		return
+	}
+	if epoch := l.update(term, copyView(view)); epoch != first {
+		t.Errorf("epoch should not change if the view is unchanged, expect[%v] actual[%v]", first, epoch)
+		return
+	}
+
+	changed := copyView(view)
+	changed[2] = &proto.DataPartitionResponse{PartitionID: 2, Status: proto.ReadOnly}
+	delete(changed, 1)
+	changed[3] = &proto.DataPartitionResponse{PartitionID: 3, Status: proto.ReadWrite}
+	second := l.update(term, changed)
+	if second != first+1 {
+		t.Errorf("epoch should move forward, expect[%v] actual[%v]", first+1, second)
+		return
+	}
+
+	epoch, updated, removed, ok := l.delta(term, first)
+	if !ok || epoch != second {
+		t.Errorf("delta since epoch[%v] should be available, ok[%v] epoch[%v]", first, ok, epoch)
+		return
+	}
+	if len(updated) != 2 || len(removed) != 1 || removed[0] != 1 {
+		t.Errorf("unexpected delta, updated[%v] removed[%v]", len(updated), removed)
+		return
+	}
+	if _, updated, removed, _ = l.delta(term, second); len(updated) != 0 || len(removed) != 0 {
+		t.Errorf("delta of the current epoch should be empty, updated[%v] removed[%v]", len(updated), removed)
+		return
+	}
+	if _, _, _, ok = l.delta(term, 0); ok {
+		t.Errorf("delta since unknown epoch should not be available")
+		return
+	}
+
+	for i := 0; i <= maxViewChangeEpochs; i++ {
+		changed = copyView(changed)
+		changed[uint64(i+100)] = &proto.DataPartitionResponse{PartitionID: uint64(i + 100)}
+		l.update(term, changed)
+	}
+	if _, _, _, ok = l.delta(term, first); ok {
+		t.Errorf("delta since expired epoch[%v] should not be available", first)
+		return
+	}
+
+	latest := l.update(term, changed)
+	if _, _, _, ok = l.delta(term+1, latest); ok {
+		t.Errorf("delta should not be available before the log starts over in the new term")
+		return
+	}
+	if epoch := l.update(term+1, changed); epoch>>32 != term+1 {
+		t.Errorf("epoch should start over in the new term, actual[%v]", epoch)
+		return
+	}
+	if _, _, _, ok = l.delta(term+1, latest); ok {
+		t.Errorf("delta since epoch[%v] of the former term should not be available", latest)
+	}
+}
+
+func copyView(view map[uint64]interface{}) map[uint64]interface{} {
+	newView := make(map[uint64]interface{}, len(view))
+	for id, v := range view {
+		newView[id] = v
+	}
+	return newView
+}
@@ -26,15 +26,42 @@ import (
 )
 
 type VolVarargs struct {
-	zoneName       string
-	description    string
-	capacity       uint64 //GB
-	dpReplicaNum   uint8
-	followerRead   bool
-	authenticate   bool
-	enableToken    bool
-	dpSelectorName string
-	dpSelectorParm string
+	zoneName         string
+	description      string
+	capacity         uint64 //GB
+	dpReplicaNum     uint8
+	mpReplicaNum     uint8
+	followerRead     bool
+	authenticate     bool
+	enableToken      bool
+	dpSelectorName   string
+	dpSelectorParm   string
+	maxIOPS          uint64
+	maxBandwidth     uint64
+	verifyRead       bool
+	trashDays        uint32
+	zoneAntiAffinity bool
+	strictZones      bool
+	atime            bool
+	fileAudit        bool
+}
+
+// createVolReq holds the options of a volume to create.
+type createVolReq struct {
+	name             string
+	owner            string
+	zoneName         string
+	description      string
+	mpCount          int
+	dpReplicaNum     int
+	size             int //GB, the size of a data partition
+	capacity         int //GB
+	followerRead     bool
+	authenticate     bool
+	crossZone        bool
+	zoneAntiAffinity bool
+	strictZones      bool
+	enableToken      bool
 }
 
 // Vol represents a set of meta partitionMap and data partitionMap
@@ -55,6 +82,8 @@ type Vol struct {
 	authenticate       bool
 	crossZone          bool
 	zoneName           string
+	zoneAntiAffinity   bool // the replicas of a partition are placed in different zones listed by zoneName
+	strictZones        bool // a partition is never placed with its replicas spanning fewer zones than required
 	enableToken        bool
 	tokens             map[string]*proto.Token
 	tokensLock         sync.RWMutex
@@ -69,6 +98,17 @@ type Vol struct {
 	description        string
 	dpSelectorName     string
 	dpSelectorParm     string
+	maxIOPS            uint64 // 0 means unlimited
+	maxBandwidth       uint64 // MB per second, 0 means unlimited
+	verifyRead         bool   // the data nodes verify the block checksums of every read
+	trashDays          uint32 // deleted files are kept in the trash for the days before they are purged
+	atime              bool   // the access time of the inodes is updated by the clients with the relatime policy
+	fileAudit          bool   // the meta nodes record the file operations to the audit log
+	wormRetentionDays  uint32 // files cannot be modified or deleted within the days after creation
+	wormOverrideUntil  int64  // the retention is suspended until the time
+	tierRules          map[string]*proto.TierRule
+	tierScanResults    map[string]map[uint64]*proto.TierScanResponse
+	tierLock           sync.RWMutex
 	sync.RWMutex
 }
 
@@ -103,6 +143,8 @@ func newVol(id uint64, name, owner, zoneName string, dpSize, capacity uint64, dp
 	vol.enableToken = enableToken
 	vol.tokens = make(map[string]*proto.Token, 0)
 	vol.description = description
+	vol.tierRules = make(map[string]*proto.TierRule, 0)
+	vol.tierScanResults = make(map[string]map[uint64]*proto.TierScanResponse, 0)
 	return
 }
 
@@ -127,6 +169,19 @@ func newVolFromVolValue(vv *volValue) (vol *Vol) {
 	vol.Status = vv.Status
 	vol.dpSelectorName = vv.DpSelectorName
 	vol.dpSelectorParm = vv.DpSelectorParm
+	vol.maxIOPS = vv.MaxIOPS
+	vol.maxBandwidth = vv.MaxBandwidth
+	vol.verifyRead = vv.VerifyRead
+	vol.trashDays = vv.TrashDays
+	vol.zoneAntiAffinity = vv.ZoneAntiAffinity
+	vol.strictZones = vv.StrictZones
+	vol.atime = vv.Atime
+	vol.fileAudit = vv.FileAudit
+	vol.wormRetentionDays = vv.WormRetentionDays
+	vol.wormOverrideUntil = vv.WormOverrideUntil
+	for _, rule := range vv.TierRules {
+		vol.tierRules[rule.Name] = rule
+	}
 	return vol
 }
 
@@ -191,8 +246,8 @@ func (vol *Vol) maxPartitionID() (maxPartitionID uint64) {
 	return
 }
 
-func (vol *Vol) getDataPartitionsView() (body []byte, err error) {
-	return vol.dataPartitions.updateResponseCache(false, 0)
+func (vol *Vol) getDataPartitionsView(c *Cluster) (body []byte, err error) {
+	return vol.dataPartitions.updateResponseCache(false, 0, c.getLeaderTerm())
 }
 
 func (vol *Vol) getDataPartitionByID(partitionID uint64) (dp *DataPartition, err error) {
@@ -283,25 +338,6 @@ func (vol *Vol) releaseDataPartitions(releaseCount int, afterLoadSeconds int64)
 	log.LogInfo(msg)
 }
 
-func (vol *Vol) checkReplicaNum(c *Cluster) {
-	if !vol.NeedToLowerReplica {
-		return
-	}
-	var err error
-	dps := vol.cloneDataPartitionMap()
-	for _, dp := range dps {
-		host := dp.getToBeDecommissionHost(int(vol.dpReplicaNum))
-		if host == "" {
-			continue
-		}
-		if err = dp.removeOneReplicaByHost(c, host); err != nil {
-			log.LogErrorf("action[checkReplicaNum],vol[%v],err[%v]", vol.Name, err)
-			continue
-		}
-	}
-	vol.NeedToLowerReplica = false
-}
-
 func (vol *Vol) checkMetaPartitions(c *Cluster) {
 	var tasks []*proto.AdminTask
 	vol.checkSplitMetaPartition(c)
@@ -679,6 +715,8 @@ func (vol *Vol) String() string {
 }
 
 func (vol *Vol) doSplitMetaPartition(c *Cluster, mp *MetaPartition, end uint64) (nextMp *MetaPartition, err error) {
+	// the zone is chosen before the lock, since it reads the hosts of all the meta partitions including this one
+	zone := c.metaPartitionZone(vol)
 	mp.Lock()
 	defer mp.Unlock()
 	if err = mp.canSplit(end); err != nil {
@@ -693,7 +731,7 @@ func (vol *Vol) doSplitMetaPartition(c *Cluster, mp *MetaPartition, end uint64)
 		return
 	}
 	cmdMap[updateMpRaftCmd.K] = updateMpRaftCmd
-	if nextMp, err = vol.doCreateMetaPartition(c, mp.End+1, defaultMaxMetaPartitionInodeID); err != nil {
+	if nextMp, err = vol.doCreateMetaPartition(c, mp.End+1, defaultMaxMetaPartitionInodeID, zone); err != nil {
 		Warn(c.Name, fmt.Sprintf("action[updateEnd] clusterID[%v] partitionID[%v] create meta partition err[%v]",
 			c.Name, mp.PartitionID, err))
 		log.LogErrorf("action[updateEnd] partitionID[%v] err[%v]", mp.PartitionID, err)
@@ -737,7 +775,7 @@ func (vol *Vol) createMetaPartition(c *Cluster, start, end uint64) (err error) {
 	vol.createMpMutex.Lock()
 	defer vol.createMpMutex.Unlock()
 	var mp *MetaPartition
-	if mp, err = vol.doCreateMetaPartition(c, start, end); err != nil {
+	if mp, err = vol.doCreateMetaPartition(c, start, end, c.metaPartitionZone(vol)); err != nil {
 		return
 	}
 	if err = c.syncAddMetaPartition(mp); err != nil {
@@ -747,7 +785,9 @@ func (vol *Vol) createMetaPartition(c *Cluster, start, end uint64) (err error) {
 	return
 }
 
-func (vol *Vol) doCreateMetaPartition(c *Cluster, start, end uint64) (mp *MetaPartition, err error) {
+// doCreateMetaPartition creates a meta partition in the zone chosen by metaPartitionZone, which is chosen by
+// the caller since the caller may hold the lock of a meta partition of the volume.
+func (vol *Vol) doCreateMetaPartition(c *Cluster, start, end uint64, zone string) (mp *MetaPartition, err error) {
 	var (
 		hosts       []string
 		partitionID uint64
@@ -755,10 +795,13 @@ func (vol *Vol) doCreateMetaPartition(c *Cluster, start, end uint64) (mp *MetaPa
 		wg          sync.WaitGroup
 	)
 	errChannel := make(chan error, vol.mpReplicaNum)
-	if hosts, peers, err = c.chooseTargetMetaHosts("", nil, nil, int(vol.mpReplicaNum), vol.crossZone, vol.zoneName); err != nil {
+	if hosts, peers, err = c.chooseTargetMetaHosts("", nil, nil, int(vol.mpReplicaNum), vol.crossZone, zone); err != nil {
 		log.LogErrorf("action[doCreateMetaPartition] chooseTargetMetaHosts err[%v]", err)
 		return nil, errors.NewError(err)
 	}
+	if err = c.checkPlacementZones(vol, "meta partition", hosts, int(vol.mpReplicaNum), c.metaNodeZone); err != nil {
+		return nil, errors.NewError(err)
+	}
 	log.LogInfof("target meta hosts:%v,peers:%v", hosts, peers)
 	if partitionID, err = c.idAlloc.allocateMetaPartitionID(); err != nil {
 		return nil, errors.NewError(err)
@@ -813,14 +856,23 @@ func (vol *Vol) doCreateMetaPartition(c *Cluster, start, end uint64) (mp *MetaPa
 
 func getVolVarargs(vol *Vol) *VolVarargs {
 	return &VolVarargs{
-		zoneName:       vol.zoneName,
-		description:    vol.description,
-		capacity:       vol.Capacity,
-		dpReplicaNum:   vol.dpReplicaNum,
-		followerRead:   vol.FollowerRead,
-		authenticate:   vol.authenticate,
-		enableToken:    vol.enableToken,
-		dpSelectorName: vol.dpSelectorName,
-		dpSelectorParm: vol.dpSelectorParm,
+		zoneName:         vol.zoneName,
+		description:      vol.description,
+		capacity:         vol.Capacity,
+		dpReplicaNum:     vol.dpReplicaNum,
+		mpReplicaNum:     vol.mpReplicaNum,
+		followerRead:     vol.FollowerRead,
+		authenticate:     vol.authenticate,
+		enableToken:      vol.enableToken,
+		dpSelectorName:   vol.dpSelectorName,
+		dpSelectorParm:   vol.dpSelectorParm,
+		maxIOPS:          vol.maxIOPS,
+		maxBandwidth:     vol.maxBandwidth,
+		verifyRead:       vol.verifyRead,
+		trashDays:        vol.trashDays,
+		zoneAntiAffinity: vol.zoneAntiAffinity,
+		strictZones:      vol.strictZones,
+		atime:            vol.atime,
+		fileAudit:        vol.fileAudit,
 	}
 }
@@ -0,0 +1,302 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The zone name of a volume may list several zones separated by commas. The partitions of the volume are spread
+// over the listed zones, each of them placed in one zone, so that the clients in every zone have partitions nearby.
+// With the zone anti-affinity, the replicas of every partition are placed in different zones of the list instead.
+// A volume with the strict zones never places a partition whose replicas span fewer zones than required, while
+// the others fall back to the zones available and report the placement.
+
+const zoneNameSeparator = ","
+
+func parseZoneNames(zoneName string) (names []string) {
+	names = make([]string, 0)
+	for _, name := range strings.Split(zoneName, zoneNameSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// checkVolZones validates the zone placement of a volume.
+func (c *Cluster) checkVolZones(zoneName string, crossZone, zoneAntiAffinity, strictZones bool) (err error) {
+	if crossZone && zoneName != "" {
+		return fmt.Errorf("only the vol which don't across zones,can specified zoneName")
+	}
+	names := parseZoneNames(zoneName)
+	for i, name := range names {
+		if _, err = c.t.getZone(name); err != nil {
+			return
+		}
+		if contains(names[:i], name) {
+			return fmt.Errorf("zone[%v] is specified more than once", name)
+		}
+	}
+	if zoneAntiAffinity && len(names) < 2 {
+		return fmt.Errorf("zone anti-affinity requires at least 2 zones, but zoneName is [%v]", zoneName)
+	}
+	if strictZones && !crossZone && !zoneAntiAffinity {
+		return fmt.Errorf("strict zones requires the vol to cross zones or to enable zone anti-affinity")
+	}
+	return
+}
+
+// zonesExcept returns the names of the zones of the cluster which are not in the given names.
+func (c *Cluster) zonesExcept(names []string) (excluded []string) {
+	excluded = make([]string, 0)
+	for _, zone := range c.t.getAllZones() {
+		if !contains(names, zone.name) {
+			excluded = append(excluded, zone.name)
+		}
+	}
+	return
+}
+
+// placementZone returns the zone name passed to chooseTargetDataNodes or chooseTargetMetaHosts to place a new
+// partition of the volume. The zone holding the fewest partitions of the volume is chosen among the listed zones
+// if the partitions are spread over them, where the zone of a partition is the zone of its first replica.
+func (c *Cluster) placementZone(vol *Vol, partitionHosts [][]string, zoneOfHost func(addr string) string) string {
+	names := parseZoneNames(vol.zoneName)
+	if len(names) <= 1 || vol.zoneAntiAffinity {
+		return vol.zoneName
+	}
+	counts := make(map[string]int, len(names))
+	for _, hosts := range partitionHosts {
+		if len(hosts) > 0 {
+			counts[zoneOfHost(hosts[0])]++
+		}
+	}
+	var zone string
+	for _, name := range names {
+		if _, err := c.t.getZone(name); err != nil || c.isCordoned(proto.CordonTypeZone, name) {
+			continue
+		}
+		if zone == "" || counts[name] < counts[zone] {
+			zone = name
+		}
+	}
+	if zone == "" {
+		return vol.zoneName
+	}
+	return zone
+}
+
+// replicaZone returns the zone name passed to chooseTargetDataNodes or chooseTargetMetaHosts to add a replica
+// to the partition on the hosts, which keeps the partition in its zone, or in the listed zones it is not yet
+// placed in with the zone anti-affinity.
+func (c *Cluster) replicaZone(vol *Vol, hosts []string, zoneOfHost func(addr string) string) string {
+	names := parseZoneNames(vol.zoneName)
+	if len(names) <= 1 || len(hosts) == 0 {
+		return vol.zoneName
+	}
+	if !vol.zoneAntiAffinity {
+		if zone := zoneOfHost(hosts[0]); contains(names, zone) {
+			return zone
+		}
+		return vol.zoneName
+	}
+	unused := make([]string, 0, len(names))
+	for _, name := range names {
+		used := false
+		for _, host := range hosts {
+			if zoneOfHost(host) == name {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) == 0 {
+		return vol.zoneName
+	}
+	return strings.Join(unused, zoneNameSeparator)
+}
+
+func (c *Cluster) dataNodeZone(addr string) string {
+	dataNode, err := c.dataNode(addr)
+	if err != nil {
+		return ""
+	}
+	return dataNode.ZoneName
+}
+
+func (c *Cluster) metaNodeZone(addr string) string {
+	metaNode, err := c.metaNode(addr)
+	if err != nil {
+		return ""
+	}
+	return metaNode.ZoneName
+}
+
+func (c *Cluster) dataPartitionZone(vol *Vol) string {
+	dps := vol.cloneDataPartitionMap()
+	partitionHosts := make([][]string, 0, len(dps))
+	for _, dp := range dps {
+		dp.RLock()
+		partitionHosts = append(partitionHosts, append([]string(nil), dp.Hosts...))
+		dp.RUnlock()
+	}
+	return c.placementZone(vol, partitionHosts, c.dataNodeZone)
+}
+
+func (c *Cluster) metaPartitionZone(vol *Vol) string {
+	mps := vol.cloneMetaPartitionMap()
+	partitionHosts := make([][]string, 0, len(mps))
+	for _, mp := range mps {
+		mp.RLock()
+		partitionHosts = append(partitionHosts, append([]string(nil), mp.Hosts...))
+		mp.RUnlock()
+	}
+	return c.placementZone(vol, partitionHosts, c.metaNodeZone)
+}
+
+// specifiedZones limits the zones to place the replicas in to the zones listed by the specified zone name. It
+// returns the zones to exclude from the allocation and the number of zones to spread the replicas over.
+func (c *Cluster) specifiedZones(specifiedZone string, replicaNum int) (excludeZones []string, zoneNum int) {
+	names := parseZoneNames(specifiedZone)
+	zoneNum = len(names)
+	if zoneNum > replicaNum {
+		zoneNum = replicaNum
+	}
+	return c.zonesExcept(names), zoneNum
+}
+
+// requiredZones returns the number of distinct zones the replicas of a partition of the volume are expected to
+// span, which is the number of the listed zones with the zone anti-affinity, or the number of zones chosen for
+// a volume across zones, but never more than the number of the replicas.
+func (c *Cluster) requiredZones(vol *Vol, replicaNum int) (zoneNum int) {
+	switch {
+	case vol.zoneAntiAffinity:
+		zoneNum = len(parseZoneNames(vol.zoneName))
+	case vol.crossZone:
+		zoneNum = c.decideZoneNum(true)
+	default:
+		return 1
+	}
+	if zoneNum > replicaNum {
+		zoneNum = replicaNum
+	}
+	return
+}
+
+// replicaZones returns the distinct zones of the hosts in the order they are first seen.
+func replicaZones(hosts []string, zoneOfHost func(addr string) string) (zones []string) {
+	zones = make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if zone := zoneOfHost(host); !contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return
+}
+
+// checkPlacementZones verifies that the replicas on the hosts span the zones required by the volume. With the
+// strict zones the placement is rejected, otherwise it is accepted on a best-effort basis and only reported.
+func (c *Cluster) checkPlacementZones(vol *Vol, partitionType string, hosts []string, replicaNum int, zoneOfHost func(addr string) string) (err error) {
+	required := c.requiredZones(vol, replicaNum)
+	if required > len(hosts) {
+		required = len(hosts)
+	}
+	zones := replicaZones(hosts, zoneOfHost)
+	if len(zones) >= required {
+		return
+	}
+	msg := fmt.Sprintf("vol[%v] %v replicas on hosts%v span zones%v, but %v zones are required", vol.Name, partitionType, hosts, zones, required)
+	if vol.strictZones {
+		log.LogErrorf("action[checkPlacementZones] %v", msg)
+		return errors.New(msg)
+	}
+	Warn(c.Name, fmt.Sprintf("action[checkPlacementZones] clusterID[%v] %v, placed on a best-effort basis", c.Name, msg))
+	return
+}
+
+// replacementZone returns the zone name passed to chooseTargetDataNodes or chooseTargetMetaHosts to replace a
+// replica of the partition in another zone, which lists the zones not holding the remaining replicas if the
+// volume spreads the replicas over the zones, so that the replacement does not join the zone of another replica.
+func (c *Cluster) replacementZone(vol *Vol, remainingHosts []string, zoneOfHost func(addr string) string) string {
+	if vol.zoneAntiAffinity {
+		return c.replicaZone(vol, remainingHosts, zoneOfHost)
+	}
+	if !vol.crossZone {
+		return ""
+	}
+	unused := c.zonesExcept(replicaZones(remainingHosts, zoneOfHost))
+	if len(unused) == 0 {
+		return ""
+	}
+	return strings.Join(unused, zoneNameSeparator)
+}
+
+// hostsExcept returns the hosts other than the address.
+func hostsExcept(hosts []string, addr string) (remaining []string) {
+	remaining = make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host != addr {
+			remaining = append(remaining, host)
+		}
+	}
+	return
+}
+
+// chooseReplacementDataNode chooses a data node in another zone to replace the replica of the data partition on
+// the offline address, keeping the replicas spread over the zones required by the volume.
+func (c *Cluster) chooseReplacementDataNode(dp *DataPartition, offlineAddr, excludeZone string, excludeNodeSets []uint64) (targetHosts []string, err error) {
+	var vol *Vol
+	if vol, err = c.getVol(dp.VolName); err != nil {
+		return
+	}
+	remainingHosts := hostsExcept(dp.Hosts, offlineAddr)
+	specifiedZone := c.replacementZone(vol, remainingHosts, c.dataNodeZone)
+	if targetHosts, _, err = c.chooseTargetDataNodes(excludeZone, excludeNodeSets, dp.Hosts, 1, 1, specifiedZone); err != nil {
+		return
+	}
+	err = c.checkPlacementZones(vol, "data partition", append(remainingHosts, targetHosts...), int(vol.dpReplicaNum), c.dataNodeZone)
+	return
+}
+
+// chooseReplacementMetaNode chooses a meta node in another zone to replace the replica of the meta partition on
+// the offline address, keeping the replicas spread over the zones required by the volume.
+func (c *Cluster) chooseReplacementMetaNode(mp *MetaPartition, offlineAddr, excludeZone string, excludeNodeSets []uint64) (peers []proto.Peer, err error) {
+	var (
+		vol         *Vol
+		targetHosts []string
+	)
+	if vol, err = c.getVol(mp.volName); err != nil {
+		return
+	}
+	mp.RLock()
+	hosts := append([]string(nil), mp.Hosts...)
+	mp.RUnlock()
+	remainingHosts := hostsExcept(hosts, offlineAddr)
+	specifiedZone := c.replacementZone(vol, remainingHosts, c.metaNodeZone)
+	if targetHosts, peers, err = c.chooseTargetMetaHosts(excludeZone, excludeNodeSets, hosts, 1, false, specifiedZone); err != nil {
+		return
+	}
+	err = c.checkPlacementZones(vol, "meta partition", append(remainingHosts, targetHosts...), int(vol.mpReplicaNum), c.metaNodeZone)
+	return
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+)
+
+func TestVolZonePlacement(t *testing.T) {
+	c := server.cluster
+	listed := testZone1 + "," + testZone2
+	if err := c.checkVolZones(listed, false, true, false); err != nil {
+		t.Errorf("check zones[%v] err[%v]", listed, err)
+	}
+	if err := c.checkVolZones(testZone1, false, true, false); err == nil {
+		t.Errorf("zone anti-affinity is allowed with one zone")
+	}
+	if err := c.checkVolZones(testZone1+","+testZone1, false, false, false); err == nil {
+		t.Errorf("duplicate zones are allowed")
+	}
+	if err := c.checkVolZones(listed, true, false, false); err == nil {
+		t.Errorf("zones are allowed for the vol across zones")
+	}
+	if err := c.checkVolZones(testZone1, false, false, true); err == nil {
+		t.Errorf("strict zones are allowed for the vol in one zone")
+	}
+
+	zoneOfHost := func(addr string) string { return addr[:len(testZone1)] }
+	vol := &Vol{zoneName: listed}
+	partitionHosts := [][]string{{testZone1 + "-a"}, {testZone1 + "-b"}, {testZone2 + "-a"}}
+	if zone := c.placementZone(vol, partitionHosts, zoneOfHost); zone != testZone2 {
+		t.Errorf("expect partition placed in zone[%v], but is [%v]", testZone2, zone)
+	}
+	if zone := c.replicaZone(vol, []string{testZone2 + "-a"}, zoneOfHost); zone != testZone2 {
+		t.Errorf("expect replica placed in zone[%v], but is [%v]", testZone2, zone)
+	}
+	vol.zoneAntiAffinity = true
+	if zone := c.placementZone(vol, partitionHosts, zoneOfHost); zone != listed {
+		t.Errorf("expect partition placed in zones[%v], but is [%v]", listed, zone)
+	}
+	if zone := c.replicaZone(vol, []string{testZone2 + "-a"}, zoneOfHost); zone != testZone1 {
+		t.Errorf("expect replica placed in zone[%v], but is [%v]", testZone1, zone)
+	}
+
+	hosts, _, err := c.chooseTargetDataNodes("", nil, nil, 2, 1, listed)
+	if err != nil {
+		t.Errorf("choose data nodes in zones[%v] err[%v]", listed, err)
+		return
+	}
+	zones := make(map[string]bool)
+	for _, host := range hosts {
+		zones[c.dataNodeZone(host)] = true
+	}
+	if !zones[testZone1] || !zones[testZone2] {
+		t.Errorf("expect replicas placed in zones[%v], but hosts are %v", listed, hosts)
+	}
+}
+
+func TestStrictZonePlacement(t *testing.T) {
+	c := server.cluster
+	listed := testZone1 + "," + testZone2
+	zoneOfHost := func(addr string) string { return addr[:len(testZone1)] }
+	vol := &Vol{Name: "strictZonesVol", zoneName: listed, zoneAntiAffinity: true}
+	if n := c.requiredZones(vol, 3); n != 2 {
+		t.Errorf("expect 2 required zones, but is %v", n)
+	}
+	if n := c.requiredZones(vol, 1); n != 1 {
+		t.Errorf("expect required zones capped by the replicas, but is %v", n)
+	}
+	sameZone := []string{testZone1 + "-a", testZone1 + "-b", testZone1 + "-a"}
+	if zones := replicaZones(sameZone, zoneOfHost); len(zones) != 1 || zones[0] != testZone1 {
+		t.Errorf("expect replicas in zone[%v], but are in %v", testZone1, zones)
+	}
+	if err := c.checkPlacementZones(vol, "data partition", sameZone, 3, zoneOfHost); err != nil {
+		t.Errorf("best-effort placement is rejected: %v", err)
+	}
+	vol.strictZones = true
+	if err := c.checkPlacementZones(vol, "data partition", sameZone, 3, zoneOfHost); err == nil {
+		t.Errorf("strict placement in one zone is accepted")
+	}
+	spread := []string{testZone1 + "-a", testZone2 + "-a", testZone1 + "-b"}
+	if err := c.checkPlacementZones(vol, "data partition", spread, 3, zoneOfHost); err != nil {
+		t.Errorf("strict placement over zones%v is rejected: %v", listed, err)
+	}
+	if zone := c.replacementZone(vol, []string{testZone2 + "-a"}, zoneOfHost); zone != testZone1 {
+		t.Errorf("expect replacement in zone[%v], but is [%v]", testZone1, zone)
+	}
+}
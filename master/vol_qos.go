@@ -0,0 +1,61 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// getVolQosBudgets returns the budgets of the volumes that have IOPS or bandwidth limits,
+// which are sent to all the data nodes along with the heartbeat.
+func (c *Cluster) getVolQosBudgets() (budgets map[string]*proto.VolQosBudget) {
+	budgets = make(map[string]*proto.VolQosBudget, 0)
+	for name, vol := range c.allVols() {
+		if budget := vol.qosBudget(); budget != nil {
+			budgets[name] = budget
+		}
+	}
+	return
+}
+
+// qosBudget splits the limits of the volume evenly among the data nodes that hold its partitions. Each data
+// node enforces its own share, so the whole limit is only reached when the IO is spread over all the nodes.
+func (vol *Vol) qosBudget() (budget *proto.VolQosBudget) {
+	// the limits are updated under the lock of the volume by updateVol
+	vol.RLock()
+	maxIOPS, maxBandwidth := vol.maxIOPS, vol.maxBandwidth
+	vol.RUnlock()
+	if maxIOPS == 0 && maxBandwidth == 0 {
+		return nil
+	}
+	nodeCount := uint64(vol.dataPartitions.hostCount())
+	if nodeCount == 0 {
+		nodeCount = 1
+	}
+	budget = &proto.VolQosBudget{
+		MaxIOPS:      divideQosLimit(maxIOPS, nodeCount),
+		MaxBandwidth: divideQosLimit(maxBandwidth*util.MB, nodeCount),
+	}
+	return
+}
+
+// divideQosLimit rounds up so that a limited volume never gets a zero (unlimited) budget.
+func divideQosLimit(limit, count uint64) uint64 {
+	if limit == 0 {
+		return 0
+	}
+	return (limit + count - 1) / count
+}
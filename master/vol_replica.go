@@ -0,0 +1,173 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// checkReplicaNum adjusts the replicas of the existing partitions to the replica number of the volume.
+// Only the partitions whose replicas are all alive are adjusted, one replica at a time, and at most
+// defaultAdjustReplicaPartitionCount partitions of the volume are adjusted or recovering at the same time.
+func (vol *Vol) checkReplicaNum(c *Cluster) {
+	var (
+		adjusting bool
+		err       error
+	)
+	dps := vol.cloneDataPartitionMap()
+	mps := vol.cloneMetaPartitionMap()
+	budget := defaultAdjustReplicaPartitionCount
+	for _, dp := range dps {
+		if dp.isRecover {
+			budget--
+		}
+	}
+	for _, mp := range mps {
+		if mp.IsRecover {
+			budget--
+		}
+	}
+	for _, dp := range dps {
+		if dp.hostsCount() == int(vol.dpReplicaNum) {
+			continue
+		}
+		adjusting = true
+		if budget <= 0 {
+			break
+		}
+		if err = c.adjustDataReplicaNum(vol, dp); err != nil {
+			log.LogWarnf("action[checkReplicaNum],vol[%v],data partition[%v],err[%v]", vol.Name, dp.PartitionID, err)
+			continue
+		}
+		budget--
+	}
+	for _, mp := range mps {
+		if mp.hostsCount() == int(vol.mpReplicaNum) {
+			continue
+		}
+		adjusting = true
+		if budget <= 0 {
+			break
+		}
+		if err = c.adjustMetaReplicaNum(vol, mp); err != nil {
+			log.LogWarnf("action[checkReplicaNum],vol[%v],meta partition[%v],err[%v]", vol.Name, mp.PartitionID, err)
+			continue
+		}
+		budget--
+	}
+	vol.NeedToLowerReplica = adjusting
+}
+
+func (partition *DataPartition) hostsCount() int {
+	partition.RLock()
+	defer partition.RUnlock()
+	return len(partition.Hosts)
+}
+
+func (mp *MetaPartition) hostsCount() int {
+	mp.RLock()
+	defer mp.RUnlock()
+	return len(mp.Hosts)
+}
+
+// adjustDataReplicaNum adds or removes one replica of the data partition towards the replica number of the volume.
+func (c *Cluster) adjustDataReplicaNum(vol *Vol, dp *DataPartition) (err error) {
+	dp.RLock()
+	hosts := make([]string, len(dp.Hosts))
+	copy(hosts, dp.Hosts)
+	liveReplicas := dp.getLiveReplicasFromHosts(c.cfg.DataPartitionTimeOutSec)
+	isRecover := dp.isRecover
+	dp.RUnlock()
+	if isRecover {
+		return fmt.Errorf("data partition is recovering")
+	}
+	if len(liveReplicas) < len(hosts) {
+		return fmt.Errorf("live replicas[%v] less than hosts[%v]", len(liveReplicas), hosts)
+	}
+	if len(hosts) > int(vol.dpReplicaNum) {
+		host := dp.getToBeDecommissionHost(int(vol.dpReplicaNum))
+		if err = dp.removeOneReplicaByHost(c, host); err != nil {
+			return
+		}
+		log.LogWarnf("action[adjustDataReplicaNum],vol[%v],data partition[%v],remove replica[%v]", vol.Name, dp.PartitionID, host)
+		return
+	}
+	var targetHosts []string
+	if targetHosts, _, err = c.chooseTargetDataNodes("", nil, hosts, 1, 1, c.replicaZone(vol, hosts, c.dataNodeZone)); err != nil {
+		return
+	}
+	if err = c.checkPlacementZones(vol, "data partition", append(hosts, targetHosts...), int(vol.dpReplicaNum), c.dataNodeZone); err != nil {
+		return
+	}
+	if err = c.addDataReplica(dp, targetHosts[0]); err != nil {
+		return
+	}
+	dp.Lock()
+	dp.ReplicaNum = uint8(len(dp.Hosts))
+	dp.isRecover = true
+	err = c.syncUpdateDataPartition(dp)
+	dp.Unlock()
+	c.putBadDataPartitionIDs(nil, targetHosts[0], dp.PartitionID)
+	log.LogWarnf("action[adjustDataReplicaNum],vol[%v],data partition[%v],add replica[%v]", vol.Name, dp.PartitionID, targetHosts[0])
+	return
+}
+
+// adjustMetaReplicaNum adds or removes one replica of the meta partition towards the replica number of the volume.
+func (c *Cluster) adjustMetaReplicaNum(vol *Vol, mp *MetaPartition) (err error) {
+	mp.RLock()
+	hosts := make([]string, len(mp.Hosts))
+	copy(hosts, mp.Hosts)
+	liveReplicas := mp.getLiveReplicas()
+	isRecover := mp.IsRecover
+	mp.RUnlock()
+	if isRecover {
+		return fmt.Errorf("meta partition is recovering")
+	}
+	if len(liveReplicas) < len(hosts) {
+		return fmt.Errorf("live replicas[%v] less than hosts[%v]", len(liveReplicas), hosts)
+	}
+	if len(hosts) > int(vol.mpReplicaNum) {
+		host := hosts[len(hosts)-1]
+		if err = c.deleteMetaReplica(mp, host, false); err != nil {
+			return
+		}
+		mp.Lock()
+		mp.ReplicaNum = uint8(len(mp.Hosts))
+		err = c.syncUpdateMetaPartition(mp)
+		mp.Unlock()
+		log.LogWarnf("action[adjustMetaReplicaNum],vol[%v],meta partition[%v],remove replica[%v]", vol.Name, mp.PartitionID, host)
+		return
+	}
+	var targetHosts []string
+	if targetHosts, _, err = c.chooseTargetMetaHosts("", nil, hosts, 1, false, c.replicaZone(vol, hosts, c.metaNodeZone)); err != nil {
+		return
+	}
+	if err = c.checkPlacementZones(vol, "meta partition", append(hosts, targetHosts...), int(vol.mpReplicaNum), c.metaNodeZone); err != nil {
+		return
+	}
+	if err = c.addMetaReplica(mp, targetHosts[0]); err != nil {
+		return
+	}
+	mp.Lock()
+	mp.ReplicaNum = uint8(len(mp.Hosts))
+	mp.IsRecover = true
+	err = c.syncUpdateMetaPartition(mp)
+	mp.Unlock()
+	c.putBadMetaPartitions(targetHosts[0], mp.PartitionID)
+	log.LogWarnf("action[adjustMetaReplicaNum],vol[%v],meta partition[%v],add replica[%v]", vol.Name, mp.PartitionID, targetHosts[0])
+	return
+}
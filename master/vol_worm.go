@@ -0,0 +1,122 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// setVolWormRetention sets the number of days during which the files of the volume cannot be modified or deleted.
+// The retention can only be extended, the files which have been protected must stay protected.
+func (c *Cluster) setVolWormRetention(name, authKey string, days uint32) (err error) {
+	var (
+		vol     *Vol
+		oldDays uint32
+	)
+	if vol, err = c.getVol(name); err != nil {
+		err = proto.ErrVolNotExists
+		goto errHandler
+	}
+	vol.Lock()
+	defer vol.Unlock()
+	if !matchKey(vol.Owner, authKey) {
+		return proto.ErrVolAuthKeyNotMatch
+	}
+	if days < vol.wormRetentionDays {
+		err = fmt.Errorf("retention days can not be reduced from %v to %v", vol.wormRetentionDays, days)
+		goto errHandler
+	}
+	oldDays = vol.wormRetentionDays
+	vol.wormRetentionDays = days
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.wormRetentionDays = oldDays
+		log.LogErrorf("action[setVolWormRetention] vol[%v] err[%v]", name, err)
+		err = proto.ErrPersistenceByRaft
+		goto errHandler
+	}
+	log.LogWarnf("action[setVolWormRetention] audit: vol[%v] retention days changed from %v to %v", name, oldDays, days)
+	return
+errHandler:
+	err = fmt.Errorf("action[setVolWormRetention], clusterID[%v] name:%v, err:%v ", c.Name, name, err.Error())
+	log.LogError(errors.Stack(err))
+	Warn(c.Name, err.Error())
+	return
+}
+
+// overrideVolWorm suspends the retention of the volume for the given duration, a zero duration ends the override.
+// Every override is recorded in the audit log of the master, and every operation allowed by it is
+// recorded by the meta nodes.
+func (c *Cluster) overrideVolWorm(name, authKey string, duration time.Duration, operator string) (until int64, err error) {
+	var (
+		vol      *Vol
+		oldUntil int64
+	)
+	if vol, err = c.getVol(name); err != nil {
+		err = proto.ErrVolNotExists
+		goto errHandler
+	}
+	vol.Lock()
+	defer vol.Unlock()
+	if !matchKey(vol.Owner, authKey) {
+		return 0, proto.ErrVolAuthKeyNotMatch
+	}
+	if vol.wormRetentionDays == 0 {
+		err = fmt.Errorf("vol[%v] has no retention policy", name)
+		goto errHandler
+	}
+	oldUntil = vol.wormOverrideUntil
+	if duration > 0 {
+		until = time.Now().Add(duration).Unix()
+	}
+	vol.wormOverrideUntil = until
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.wormOverrideUntil = oldUntil
+		log.LogErrorf("action[overrideVolWorm] vol[%v] err[%v]", name, err)
+		err = proto.ErrPersistenceByRaft
+		goto errHandler
+	}
+	log.LogWarnf("action[overrideVolWorm] audit: operator[%v] vol[%v] retention overridden until[%v]",
+		operator, name, time.Unix(until, 0).Format(proto.TimeFormat))
+	Warn(c.Name, fmt.Sprintf("retention of vol[%v] is overridden by [%v] until [%v]",
+		name, operator, time.Unix(until, 0).Format(proto.TimeFormat)))
+	return
+errHandler:
+	err = fmt.Errorf("action[overrideVolWorm], clusterID[%v] name:%v, err:%v ", c.Name, name, err.Error())
+	log.LogError(errors.Stack(err))
+	Warn(c.Name, err.Error())
+	return
+}
+
+// getVolWorms returns the retention policies of the volumes which have one, which are sent to the data nodes
+// in the heartbeats, so that the data nodes refuse the overwrites of the data in the retention period.
+func (c *Cluster) getVolWorms() (worms map[string]*proto.VolWorm) {
+	worms = make(map[string]*proto.VolWorm)
+	for name, vol := range c.allVols() {
+		vol.RLock()
+		if vol.wormRetentionDays > 0 {
+			worms[name] = &proto.VolWorm{
+				RetentionSeconds: int64(vol.wormRetentionDays) * 24 * 3600,
+				OverrideUntil:    vol.wormOverrideUntil,
+			}
+		}
+		vol.RUnlock()
+	}
+	return
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
 )
 
 // APIResponse defines the structure of the response to an HTTP request
@@ -59,6 +60,13 @@ func (m *MetaNode) registerAPIHandler() (err error) {
 	http.HandleFunc("/getDirectory", m.getDirectoryHandler)
 	http.HandleFunc("/getAllDentry", m.getAllDentriesHandler)
 	http.HandleFunc("/getParams", m.getParamsHandler)
+	http.HandleFunc("/getConfig", m.getConfigHandler)
+	http.HandleFunc("/setConfig", m.setConfigHandler)
+	http.HandleFunc("/getSlowOps", m.getSlowOpsHandler)
+	http.HandleFunc("/getMemUsage", m.getMemUsageHandler)
+	http.HandleFunc("/getRaftStatus", m.getRaftStatusHandler)
+	http.HandleFunc("/exportPartition", m.exportPartitionHandler)
+	http.HandleFunc("/importPartition", m.importPartitionHandler)
 	return
 }
 
@@ -84,6 +92,29 @@ func (m *MetaNode) getPartitionsHandler(w http.ResponseWriter,
 	}
 }
 
+// getSlowOpsHandler lists the latest slow operations finished since the unix time of the param.
+func (m *MetaNode) getSlowOpsHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	resp := NewAPIResponse(http.StatusBadRequest, "")
+	defer func() {
+		data, _ := resp.Marshal()
+		if _, err := w.Write(data); err != nil {
+			log.LogErrorf("[getSlowOpsHandler] response %s", err)
+		}
+	}()
+	var since int64
+	if value := r.FormValue("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			resp.Msg = err.Error()
+			return
+		}
+	}
+	resp.Data = slowop.Recent(since)
+	resp.Code = http.StatusOK
+	resp.Msg = http.StatusText(http.StatusOK)
+}
+
 func (m *MetaNode) getPartitionByIDHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	resp := NewAPIResponse(http.StatusBadRequest, "")
@@ -116,6 +147,99 @@ func (m *MetaNode) getPartitionByIDHandler(w http.ResponseWriter, r *http.Reques
 	resp.Msg = http.StatusText(http.StatusOK)
 }
 
+// getRaftStatusHandler returns the raft status of the meta partition on this meta node.
+func (m *MetaNode) getRaftStatusHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	resp := NewAPIResponse(http.StatusBadRequest, "")
+	defer func() {
+		data, _ := resp.Marshal()
+		if _, err := w.Write(data); err != nil {
+			log.LogErrorf("[getRaftStatusHandler] response %s", err)
+		}
+	}()
+	pid, err := strconv.ParseUint(r.FormValue("pid"), 10, 64)
+	if err != nil {
+		resp.Msg = err.Error()
+		return
+	}
+	if _, err = m.metadataManager.GetPartition(pid); err != nil {
+		resp.Code = http.StatusNotFound
+		resp.Msg = err.Error()
+		return
+	}
+	resp.Data = m.raftStore.RaftStatus(pid)
+	resp.Code = http.StatusOK
+	resp.Msg = http.StatusText(http.StatusOK)
+}
+
+// exportPartitionHandler streams the export of the meta partition, or responds an error in json if the partition
+// is not found.
+func (m *MetaNode) exportPartitionHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	resp := NewAPIResponse(http.StatusBadRequest, "")
+	pid, err := strconv.ParseUint(r.FormValue("pid"), 10, 64)
+	if err != nil {
+		resp.Msg = err.Error()
+		data, _ := resp.Marshal()
+		w.Write(data)
+		return
+	}
+	mp, err := m.metadataManager.GetPartition(pid)
+	if err != nil {
+		resp.Code = http.StatusNotFound
+		resp.Msg = err.Error()
+		data, _ := resp.Marshal()
+		w.Write(data)
+		return
+	}
+	w.Header().Set("content-type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=mp-%v.gz", pid))
+	export, err := mp.ExportMeta(w)
+	if err != nil {
+		log.LogErrorf("[exportPartitionHandler] partition(%v) client(%v) err(%v)", pid, r.RemoteAddr, err)
+		return
+	}
+	log.LogWarnf("[exportPartitionHandler] partition(%v) client(%v) applyID(%v) inodes(%v) dentries(%v)",
+		pid, r.RemoteAddr, export.ApplyID, export.Inodes, export.Dentries)
+}
+
+// importPartitionHandler loads the export in the request body into the empty meta partition led by this meta node.
+func (m *MetaNode) importPartitionHandler(w http.ResponseWriter, r *http.Request) {
+	resp := NewAPIResponse(http.StatusBadRequest, "")
+	defer func() {
+		data, _ := resp.Marshal()
+		if _, err := w.Write(data); err != nil {
+			log.LogErrorf("[importPartitionHandler] response %s", err)
+		}
+	}()
+	if r.Method != http.MethodPost {
+		resp.Code = http.StatusMethodNotAllowed
+		resp.Msg = http.StatusText(http.StatusMethodNotAllowed)
+		return
+	}
+	pid, err := strconv.ParseUint(r.URL.Query().Get("pid"), 10, 64)
+	if err != nil {
+		resp.Msg = err.Error()
+		return
+	}
+	mp, err := m.metadataManager.GetPartition(pid)
+	if err != nil {
+		resp.Code = http.StatusNotFound
+		resp.Msg = err.Error()
+		return
+	}
+	imported, err := mp.ImportMeta(r.Body)
+	resp.Data = imported
+	if err != nil {
+		resp.Code = http.StatusInternalServerError
+		resp.Msg = err.Error()
+		log.LogErrorf("[importPartitionHandler] partition(%v) client(%v) err(%v)", pid, r.RemoteAddr, err)
+		return
+	}
+	resp.Code = http.StatusOK
+	resp.Msg = http.StatusText(http.StatusOK)
+}
+
 func (m *MetaNode) getAllInodesHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
 
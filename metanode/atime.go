@@ -0,0 +1,48 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// updateAtime records whether the volume tracks the access time, fetched from the master.
+func (v *Vol) updateAtime(view *proto.SimpleVolView) {
+	v.Lock()
+	defer v.Unlock()
+	v.atime = view.Atime
+}
+
+func (v *Vol) atimeEnabled() bool {
+	v.RLock()
+	defer v.RUnlock()
+	return v.atime
+}
+
+// relatimeNotDue returns if the request only updates the access time with the relatime policy and
+// the update is not due, so that the leader replies without submitting it to the raft.
+func (mp *metaPartition) relatimeNotDue(req *SetattrRequest) bool {
+	if req.Valid != proto.AttrRelatime {
+		return false
+	}
+	item := mp.inodeTree.Get(NewInode(req.Inode, 0))
+	if item == nil {
+		return false
+	}
+	ino := item.(*Inode)
+	ino.RLock()
+	defer ino.RUnlock()
+	return !proto.RelatimeDue(ino.AccessTime, ino.ModifyTime, ino.CreateTime, req.AccessTime)
+}
@@ -0,0 +1,51 @@
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestSetAttrRelatime(t *testing.T) {
+	mp := &metaPartition{
+		config:    &MetaPartitionConfig{PartitionId: 1},
+		inodeTree: NewBtree(),
+	}
+	ino := &Inode{Inode: 10, Type: proto.Mode(0644), NLink: 1, CreateTime: 100, ModifyTime: 200, AccessTime: 300}
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	notDue := func(atime int64) bool {
+		return mp.relatimeNotDue(&SetattrRequest{Inode: 10, Valid: proto.AttrRelatime, AccessTime: atime})
+	}
+
+	// the access time is later than the modify time and not older than a day
+	if !notDue(400) {
+		t.Fatalf("relatime due within a day")
+	}
+	ino.SetAttr(&SetattrRequest{Valid: proto.AttrRelatime, AccessTime: 400})
+	if ino.AccessTime != 300 {
+		t.Fatalf("access time updated within a day: %v", ino.AccessTime)
+	}
+	// the access time is updated once a day
+	now := 300 + int64(proto.RelatimeInterval)
+	if notDue(now) {
+		t.Fatalf("relatime not due after a day")
+	}
+	ino.SetAttr(&SetattrRequest{Valid: proto.AttrRelatime, AccessTime: now})
+	if ino.AccessTime != now {
+		t.Fatalf("access time not updated after a day: %v", ino.AccessTime)
+	}
+	// the access time is updated after the inode is modified
+	ino.ModifyTime = now + 10
+	if notDue(now + 20) {
+		t.Fatalf("relatime not due after modified")
+	}
+	// the access time is never moved backwards
+	ino.SetAttr(&SetattrRequest{Valid: proto.AttrRelatime, AccessTime: now - 10})
+	if ino.AccessTime != now {
+		t.Fatalf("access time moved backwards: %v", ino.AccessTime)
+	}
+	// the other attributes are always submitted
+	if mp.relatimeNotDue(&SetattrRequest{Inode: 10, Valid: proto.AttrRelatime | proto.AttrMode, AccessTime: 400}) {
+		t.Fatalf("setattr with the other attributes is skipped")
+	}
+}
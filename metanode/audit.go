@@ -0,0 +1,210 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/auditlog"
+)
+
+// auditLogger records the file operations of the volumes with the file audit enabled, nil if no
+// audit sink is configured.
+var auditLogger *auditlog.Logger
+
+// auditedOps are the names of the audited operations. The fuse clients list the extents of a file
+// when it is opened, which is recorded as the open of the file.
+var auditedOps = map[uint8]string{
+	proto.OpMetaExtentsList:       "open",
+	proto.OpMetaCreateDentry:      "create",
+	proto.OpMetaDeleteDentry:      "unlink",
+	proto.OpMetaBatchDeleteDentry: "unlink",
+	proto.OpMetaUpdateDentry:      "replace",
+	proto.OpMetaLinkInode:         "link",
+	proto.OpMetaSetattr:           "setattr",
+	proto.OpMetaTruncate:          "truncate",
+	proto.OpMetaSetXAttr:          "setxattr",
+	proto.OpMetaRemoveXAttr:       "removexattr",
+	proto.OpMetaTxPrepare:         "tx",
+}
+
+// auditRequest holds the fields of the requests of the audited operations.
+type auditRequest struct {
+	VolName     string          `json:"vol"`
+	PartitionID uint64          `json:"pid"`
+	ParentID    uint64          `json:"pino"`
+	Inode       uint64          `json:"ino"`
+	Name        string          `json:"name"`
+	Mode        uint32          `json:"mode"`
+	Uid         uint32          `json:"uid"`
+	Gid         uint32          `json:"gid"`
+	Flags       uint32          `json:"flags"`
+	Valid       uint32          `json:"valid"`
+	Size        uint64          `json:"sz"`
+	Key         string          `json:"key"`
+	Dens        []proto.Dentry  `json:"dens"`
+	PrimaryID   uint64          `json:"primary"`
+	Items       []*proto.TxItem `json:"items"`
+}
+
+func (v *Vol) updateFileAudit(view *proto.SimpleVolView) {
+	v.Lock()
+	defer v.Unlock()
+	v.fileAudit = view.FileAudit
+}
+
+func (v *Vol) fileAuditEnabled() bool {
+	v.RLock()
+	defer v.RUnlock()
+	return v.fileAudit
+}
+
+// auditOperation records the operation served by the leader to the audit log. The request proxied
+// by a follower is recorded with the address of the follower as the client.
+func (m *metadataManager) auditOperation(opcode uint8, data []byte, p *Packet, remoteAddr string) {
+	if auditLogger == nil {
+		return
+	}
+	if _, ok := auditedOps[opcode]; !ok {
+		return
+	}
+	req := new(auditRequest)
+	if err := json.Unmarshal(data, req); err != nil {
+		return
+	}
+	mp, err := m.getPartition(req.PartitionID)
+	if err != nil {
+		return
+	}
+	partition, ok := mp.(*metaPartition)
+	if !ok || partition.vol == nil || !partition.vol.fileAuditEnabled() {
+		return
+	}
+	if _, ok = mp.IsLeader(); !ok {
+		return
+	}
+	for _, e := range auditEvents(opcode, req) {
+		if !auditLogger.Sampled(e.Op) {
+			continue
+		}
+		e.Client = remoteAddr
+		e.Vol = req.VolName
+		e.Result = p.GetResultMsg()
+		auditLogger.Log(e)
+	}
+}
+
+// auditEvents converts the request into the audit events.
+func auditEvents(opcode uint8, req *auditRequest) []*auditlog.Event {
+	op := auditedOps[opcode]
+	switch opcode {
+	case proto.OpMetaCreateDentry:
+		if proto.IsDir(req.Mode) {
+			op = "mkdir"
+		}
+		return []*auditlog.Event{{Op: op, ParentID: req.ParentID, Name: req.Name, Inode: req.Inode}}
+	case proto.OpMetaDeleteDentry:
+		return []*auditlog.Event{{Op: op, ParentID: req.ParentID, Name: req.Name}}
+	case proto.OpMetaBatchDeleteDentry:
+		events := make([]*auditlog.Event, 0, len(req.Dens))
+		for _, d := range req.Dens {
+			events = append(events, &auditlog.Event{Op: op, ParentID: req.ParentID, Name: d.Name, Inode: d.Inode})
+		}
+		return events
+	case proto.OpMetaUpdateDentry:
+		return []*auditlog.Event{{Op: op, ParentID: req.ParentID, Name: req.Name, Inode: req.Inode}}
+	case proto.OpMetaSetattr:
+		return auditSetAttr(req)
+	case proto.OpMetaTruncate:
+		return []*auditlog.Event{{Op: op, Inode: req.Inode, Detail: fmt.Sprintf("size=%v", req.Size)}}
+	case proto.OpMetaSetXAttr, proto.OpMetaRemoveXAttr:
+		return []*auditlog.Event{{Op: op, Inode: req.Inode, Detail: fmt.Sprintf("key=%v", req.Key)}}
+	case proto.OpMetaTxPrepare:
+		return auditTransaction(req)
+	default:
+		return []*auditlog.Event{{Op: op, Inode: req.Inode}}
+	}
+}
+
+// auditSetAttr names the setattr by the attributes changed. The access time updated with the
+// relatime policy is not recorded.
+func auditSetAttr(req *auditRequest) []*auditlog.Event {
+	var op string
+	var details []string
+	if req.Valid&proto.AttrMode != 0 {
+		op = "chmod"
+		details = append(details, fmt.Sprintf("mode=%o", req.Mode&0777))
+	}
+	if req.Valid&(proto.AttrUid|proto.AttrGid) != 0 {
+		if op == "" {
+			op = "chown"
+		}
+		details = append(details, fmt.Sprintf("uid=%v gid=%v", req.Uid, req.Gid))
+	}
+	if req.Valid&proto.AttrFlags != 0 {
+		if op == "" {
+			op = "chattr"
+		}
+		details = append(details, fmt.Sprintf("flags=%v", req.Flags))
+	}
+	if op == "" {
+		if req.Valid&(proto.AttrModifyTime|proto.AttrAccessTime) == 0 {
+			return nil
+		}
+		op = "utimes"
+	}
+	return []*auditlog.Event{{Op: op, Inode: req.Inode, Detail: strings.Join(details, " ")}}
+}
+
+// auditTransaction records the transaction in the primary partition only. A rename deletes the source
+// dentry and creates the destination dentry, and a recursive removal deletes the dentry and marks the
+// directory for removal.
+func auditTransaction(req *auditRequest) []*auditlog.Event {
+	if req.PrimaryID != req.PartitionID {
+		return nil
+	}
+	var src, dst *proto.TxItem
+	var removed bool
+	for _, item := range req.Items {
+		switch {
+		case item.Op == proto.TxOpDeleteDentry:
+			src = item
+		case item.Op == proto.TxOpCreateDentry:
+			dst = item
+		case item.Op == proto.TxOpSetXAttr && item.Key == proto.XAttrKeyRemoveTime:
+			removed = true
+		}
+	}
+	switch {
+	case src != nil && dst != nil:
+		return []*auditlog.Event{{Op: "rename", ParentID: src.ParentID, Name: src.Name, Inode: src.Inode,
+			Detail: fmt.Sprintf("dst=%v/%v", dst.ParentID, dst.Name)}}
+	case src != nil && removed:
+		return []*auditlog.Event{{Op: "rmtree", ParentID: src.ParentID, Name: src.Name, Inode: src.Inode}}
+	}
+	events := make([]*auditlog.Event, 0, len(req.Items))
+	for _, item := range req.Items {
+		switch item.Op {
+		case proto.TxOpCreateDentry:
+			events = append(events, &auditlog.Event{Op: "create", ParentID: item.ParentID, Name: item.Name, Inode: item.Inode})
+		case proto.TxOpDeleteDentry:
+			events = append(events, &auditlog.Event{Op: "unlink", ParentID: item.ParentID, Name: item.Name, Inode: item.Inode})
+		}
+	}
+	return events
+}
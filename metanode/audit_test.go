@@ -0,0 +1,47 @@
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestAuditEvents(t *testing.T) {
+	events := auditEvents(proto.OpMetaSetattr, &auditRequest{Inode: 10, Mode: 0640, Valid: proto.AttrMode})
+	if len(events) != 1 || events[0].Op != "chmod" || events[0].Detail != "mode=640" {
+		t.Fatalf("chmod: %v", events)
+	}
+	if events = auditEvents(proto.OpMetaSetattr, &auditRequest{Inode: 10, Valid: proto.AttrRelatime}); len(events) != 0 {
+		t.Fatalf("relatime is recorded: %v", events)
+	}
+
+	rename := &auditRequest{
+		PartitionID: 1,
+		PrimaryID:   1,
+		Items: []*proto.TxItem{
+			{Op: proto.TxOpDeleteDentry, ParentID: 1, Name: "a", Inode: 10},
+			{Op: proto.TxOpCreateDentry, ParentID: 2, Name: "b", Inode: 10},
+		},
+	}
+	if events = auditEvents(proto.OpMetaTxPrepare, rename); len(events) != 1 || events[0].Op != "rename" ||
+		events[0].Name != "a" || events[0].Detail != "dst=2/b" {
+		t.Fatalf("rename: %v", events)
+	}
+	// the participants do not record the transaction
+	rename.PartitionID = 2
+	if events = auditEvents(proto.OpMetaTxPrepare, rename); len(events) != 0 {
+		t.Fatalf("rename in participant: %v", events)
+	}
+
+	remove := &auditRequest{
+		PartitionID: 1,
+		PrimaryID:   1,
+		Items: []*proto.TxItem{
+			{Op: proto.TxOpDeleteDentry, ParentID: 1, Name: "dir", Inode: 11},
+			{Op: proto.TxOpSetXAttr, Inode: 11, Key: proto.XAttrKeyRemoveTime},
+		},
+	}
+	if events = auditEvents(proto.OpMetaTxPrepare, remove); len(events) != 1 || events[0].Op != "rmtree" || events[0].Name != "dir" {
+		t.Fatalf("remove recursively: %v", events)
+	}
+}
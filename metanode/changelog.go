@@ -0,0 +1,189 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	defaultChangelogReadLimit = 1000
+	maxChangelogReadLimit     = 10000
+
+	// prefix of the extended attributes maintained by the meta nodes, e.g. of the trash and the directory summary
+	internalXAttrPrefix = "cfs."
+)
+
+// events kept in the changelog of every meta partition, 0 disables the changelog
+var changelogCapacity int
+
+// changelog keeps the latest events of the changes applied to the meta partition in memory. The events are recorded
+// only while a raft log is applied, so the changes restored from the disk or a snapshot are not recorded, and the
+// events are kept continuous after base, the applied index the changelog starts from.
+// A nil changelog records nothing.
+type changelog struct {
+	sync.Mutex
+	events []*proto.ChangeEvent // ring of the events
+	head   int                  // index of the oldest event
+	size   int
+	base   uint64
+	seq    uint64 // index of the raft log being applied, 0 if none
+	txID   string // transaction of the items being committed
+}
+
+func newChangelog(capacity int) *changelog {
+	if capacity <= 0 {
+		return nil
+	}
+	return &changelog{events: make([]*proto.ChangeEvent, capacity)}
+}
+
+// reset drops the events and restarts the changelog from the applied index.
+func (c *changelog) reset(applied uint64) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for i := range c.events {
+		c.events[i] = nil
+	}
+	c.head, c.size, c.base = 0, 0, applied
+}
+
+// begin starts recording the events of the raft log applied, which is called by the apply only.
+func (c *changelog) begin(index uint64) {
+	if c == nil {
+		return
+	}
+	c.seq = index
+}
+
+func (c *changelog) end() {
+	if c == nil {
+		return
+	}
+	c.seq, c.txID = 0, ""
+}
+
+// setTx marks the following events as a part of the transaction committed.
+func (c *changelog) setTx(txID string) {
+	if c == nil {
+		return
+	}
+	c.txID = txID
+}
+
+func (c *changelog) record(op string, ino, parentID uint64, name string, mode uint32) {
+	if c == nil || c.seq == 0 {
+		return
+	}
+	event := &proto.ChangeEvent{
+		Seq:      c.seq,
+		Time:     time.Now().Unix(),
+		Op:       op,
+		Inode:    ino,
+		ParentID: parentID,
+		Name:     name,
+		Type:     mode,
+		TxID:     c.txID,
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.size == len(c.events) {
+		// the events of the sequence evicted are incomplete, so the reader must start after it
+		if oldest := c.events[c.head]; oldest.Seq > c.base {
+			c.base = oldest.Seq
+		}
+		c.events[c.head] = event
+		c.head = (c.head + 1) % len(c.events)
+		return
+	}
+	c.events[(c.head+c.size)%len(c.events)] = event
+	c.size++
+}
+
+func (c *changelog) recordXAttrs(op string, extend *Extend) {
+	if c == nil || c.seq == 0 {
+		return
+	}
+	extend.Range(func(key, value []byte) bool {
+		if !strings.HasPrefix(string(key), internalXAttrPrefix) {
+			c.record(op, extend.inode, 0, string(key), 0)
+		}
+		return true
+	})
+}
+
+// read returns the events after the cursor up to the applied index. The events of a sequence are never split
+// across two reads, so a read may return more events than the limit.
+func (c *changelog) read(cursor, applied uint64, limit int) (resp *proto.ReadChangelogResponse) {
+	resp = &proto.ReadChangelogResponse{Applied: applied, Next: cursor}
+	c.Lock()
+	defer c.Unlock()
+	resp.Oldest = c.base
+	if cursor < c.base {
+		resp.Expired = true
+		return
+	}
+	if cursor >= applied {
+		return
+	}
+	resp.Next = applied
+	for i := 0; i < c.size; i++ {
+		event := c.events[(c.head+i)%len(c.events)]
+		if event.Seq <= cursor {
+			continue
+		}
+		if event.Seq > applied {
+			break
+		}
+		if len(resp.Events) >= limit && event.Seq != resp.Events[len(resp.Events)-1].Seq {
+			resp.Next = resp.Events[len(resp.Events)-1].Seq
+			break
+		}
+		resp.Events = append(resp.Events, event)
+	}
+	return
+}
+
+// ReadChangelog replies the events of the changes after the cursor of the request.
+func (mp *metaPartition) ReadChangelog(req *proto.ReadChangelogRequest, p *Packet) (err error) {
+	if mp.changelog == nil {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte("changelog is disabled"))
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultChangelogReadLimit
+	}
+	if limit > maxChangelogReadLimit {
+		limit = maxChangelogReadLimit
+	}
+	resp := mp.changelog.read(req.Cursor, atomic.LoadUint64(&mp.applyID), limit)
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
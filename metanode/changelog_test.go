@@ -0,0 +1,98 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestChangelogRead(t *testing.T) {
+	c := newChangelog(4)
+	c.reset(10)
+	for seq := uint64(11); seq <= 13; seq++ {
+		c.begin(seq)
+		c.record(proto.ChangeWrite, seq, 0, "", 0)
+		if seq == 12 {
+			c.record(proto.ChangeWrite, 100, 0, "", 0)
+		}
+		c.end()
+	}
+
+	resp := c.read(10, 14, 2)
+	if resp.Expired || len(resp.Events) != 3 || resp.Next != 12 {
+		t.Fatalf("the events of a sequence are split: %+v", resp)
+	}
+	if resp = c.read(resp.Next, 14, 2); len(resp.Events) != 1 || resp.Events[0].Seq != 13 || resp.Next != 14 {
+		t.Fatalf("read from 12: %+v", resp)
+	}
+	if resp = c.read(14, 14, 2); len(resp.Events) != 0 || resp.Next != 14 {
+		t.Fatalf("read from the applied index: %+v", resp)
+	}
+
+	// the events of seq 11 and one of seq 12 are evicted, so the changelog is continuous after 12 only
+	c.begin(14)
+	c.record(proto.ChangeWrite, 14, 0, "", 0)
+	c.record(proto.ChangeWrite, 14, 0, "", 0)
+	c.end()
+	if resp = c.read(11, 14, 10); !resp.Expired || resp.Oldest != 12 {
+		t.Fatalf("expired cursor: %+v", resp)
+	}
+	if resp = c.read(12, 14, 10); resp.Expired || len(resp.Events) != 3 || resp.Next != 14 {
+		t.Fatalf("read from 12 after eviction: %+v", resp)
+	}
+
+	// the changes out of the apply are not recorded
+	c.record(proto.ChangeWrite, 15, 0, "", 0)
+	if resp = c.read(14, 15, 10); len(resp.Events) != 0 {
+		t.Fatalf("event recorded out of the apply: %+v", resp)
+	}
+}
+
+func TestChangelogRecordChanges(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, Start: 1, End: 100},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+		txTree:     NewBtree(),
+		changelog:  newChangelog(100),
+	}
+	mp.inodeTree.ReplaceOrInsert(NewInode(proto.RootIno, proto.Mode(os.ModeDir|0755)), true)
+	mp.inodeTree.ReplaceOrInsert(NewInode(10, proto.Mode(0644)), true)
+	apply := func(index uint64, f func()) {
+		mp.changelog.begin(index)
+		f()
+		mp.changelog.end()
+		mp.applyID = index
+	}
+	dentry := &Dentry{ParentId: proto.RootIno, Name: "f", Inode: 10, Type: proto.Mode(0644)}
+	apply(1, func() { mp.fsmCreateDentry(dentry, false) })
+	apply(2, func() { mp.fsmSetAttr(&SetattrRequest{Inode: 10, Valid: proto.AttrAccessTime, AccessTime: 1}) })
+	apply(3, func() { mp.fsmSetAttr(&SetattrRequest{Inode: 10, Valid: proto.AttrMode, Mode: proto.Mode(0600)}) })
+	apply(4, func() {
+		extend := NewExtend(10)
+		extend.Put([]byte("user.k"), []byte("v"))
+		extend.Put([]byte(proto.XAttrKeyDirFiles), []byte("1"))
+		mp.fsmSetXAttr(extend)
+	})
+	apply(5, func() { mp.fsmDeleteDentry(&Dentry{ParentId: proto.RootIno, Name: "f"}, false) })
+
+	resp := mp.changelog.read(0, mp.applyID, 100)
+	expected := []proto.ChangeEvent{
+		{Seq: 1, Op: proto.ChangeCreate, Inode: 10, ParentID: proto.RootIno, Name: "f", Type: proto.Mode(0644)},
+		{Seq: 3, Op: proto.ChangeSetAttr, Inode: 10},
+		{Seq: 4, Op: proto.ChangeSetXAttr, Inode: 10, Name: "user.k"},
+		{Seq: 5, Op: proto.ChangeUnlink, Inode: 10, ParentID: proto.RootIno, Name: "f", Type: proto.Mode(0644)},
+	}
+	if len(resp.Events) != len(expected) || resp.Next != 5 {
+		t.Fatalf("events: %+v", resp)
+	}
+	for i, event := range resp.Events {
+		e := *event
+		e.Time = 0
+		if e != expected[i] {
+			t.Fatalf("event %v: expected %+v, got %+v", i, expected[i], e)
+		}
+	}
+}
@@ -117,6 +117,15 @@ const (
 	opFSMDeleteDentryBatch
 	opFSMUnlinkInodeBatch
 	opFSMEvictInodeBatch
+
+	opFSMSetLock
+	opFSMRenewLock
+	opFSMExtentPunch
+	opFSMTxPrepare
+	opFSMTxCommit
+	opFSMTxAbort
+	opFSMTxForget
+	opFSMImportItems
 )
 
 var (
@@ -147,6 +156,10 @@ const (
 	cfgDeleteBatchCount  = "deleteBatchCount"
 	cfgTotalMem          = "totalMem"
 	cfgZoneName          = "zoneName"
+	cfgChangelogCapacity = "changelogCapacity" // events kept in the changelog of every meta partition
+
+	cfgMemUsageThreshold    = "memUsageThreshold"    // ratio of totalMem above which no partition is created
+	cfgMemReadOnlyThreshold = "memReadOnlyThreshold" // ratio of totalMem above which the partitions are read-only
 
 	metaNodeDeleteBatchCountKey = "batchCount"
 )
@@ -163,3 +176,8 @@ const (
 	MB
 	GB
 )
+
+const (
+	// MetricDeadlineExceeded counts the requests failed fast since their deadlines have passed.
+	MetricDeadlineExceeded = "deadlineExceeded"
+)
@@ -47,6 +47,12 @@ func NewDataPartitionsView() *DataPartitionsView {
 type Vol struct {
 	sync.RWMutex
 	dataPartitionView map[uint64]*DataPartition
+	wormLoaded        bool  // the retention policy has been fetched from the master
+	wormRetention     int64 // in seconds
+	wormOverrideUntil int64
+	trashDays         uint32
+	atime             bool
+	fileAudit         bool
 }
 
 // NewVol returns a new volume instance.
@@ -0,0 +1,51 @@
+package metanode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestReadDirLimit(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1},
+		dentryTree: NewBtree(),
+	}
+	for i := 0; i < 5; i++ {
+		mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: fmt.Sprintf("f%v", i), Inode: uint64(10 + i), Type: proto.Mode(0644)}, true)
+	}
+	// the entries of the other directories are not read
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 2, Name: "a", Inode: 20, Type: proto.Mode(0644)}, true)
+
+	if resp := mp.readDir(&ReadDirReq{ParentID: 1}); len(resp.Children) != 5 || resp.NextMarker != "" {
+		t.Fatalf("read all: children(%v) next(%v)", resp.Children, resp.NextMarker)
+	}
+
+	var names []string
+	req := &ReadDirReq{ParentID: 1, Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("too many pages: names(%v)", names)
+		}
+		resp := mp.readDir(req)
+		if len(resp.Children) > 2 {
+			t.Fatalf("page over limit: children(%v)", resp.Children)
+		}
+		for _, child := range resp.Children {
+			names = append(names, child.Name)
+		}
+		if resp.NextMarker == "" {
+			break
+		}
+		req.Marker = resp.NextMarker
+	}
+	if fmt.Sprint(names) != "[f0 f1 f2 f3 f4]" {
+		t.Fatalf("paged names: %v", names)
+	}
+
+	if resp := mp.readDir(&ReadDirReq{ParentID: 1, Marker: "f1x", Limit: 2}); len(resp.Children) != 2 ||
+		resp.Children[0].Name != "f2" || resp.NextMarker != "f4" {
+		t.Fatalf("read from marker: children(%v) next(%v)", resp.Children, resp.NextMarker)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	dirSummaryInterval  = 10 * time.Minute
+	dirSummaryBatchSize = 1000
+)
+
+// dirSummaryWorker maintains the recursive statistics of the directories on the leader. The summary of
+// a directory is computed from the sizes of the files in it and the summaries of its subdirectories,
+// and recorded in the extend attributes of it, so that the changes are propagated to the ancestors
+// pass by pass.
+func (mp *metaPartition) dirSummaryWorker() {
+	t := time.NewTicker(dirSummaryInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok {
+				continue
+			}
+			mp.updateDirSummaries()
+		}
+	}
+}
+
+// updateDirSummaries summarizes the directories in the descending order of inodes. The children are
+// mostly allocated larger inodes than their parents, so that the changes in a partition are mostly
+// propagated to the ancestors in one pass.
+func (mp *metaPartition) updateDirSummaries() {
+	dirs := make([]uint64, 0)
+	mp.inodeTree.GetTree().Ascend(func(i BtreeItem) bool {
+		if ino := i.(*Inode); proto.IsDir(ino.Type) && !ino.ShouldDelete() {
+			dirs = append(dirs, ino.Inode)
+		}
+		return true
+	})
+	if len(dirs) == 0 {
+		return
+	}
+	views, err := masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName)
+	if err != nil {
+		log.LogWarnf("updateDirSummaries: vol(%v) mp(%v) get meta partitions failed: %v", mp.config.VolName, mp.config.PartitionId, err)
+		return
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		select {
+		case <-mp.stopC:
+			return
+		default:
+		}
+		if _, ok := mp.IsLeader(); !ok {
+			return
+		}
+		if err = mp.updateDirSummary(dirs[i], views); err != nil {
+			log.LogWarnf("updateDirSummaries: vol(%v) mp(%v) dir(%v) err(%v)", mp.config.VolName, mp.config.PartitionId, dirs[i], err)
+		}
+	}
+}
+
+// updateDirSummary records the summary of the directory if it is changed.
+func (mp *metaPartition) updateDirSummary(dir uint64, views []*proto.MetaPartitionView) (err error) {
+	summary, err := mp.summarizeDir(dir, views)
+	if err != nil {
+		return
+	}
+	if stored, ok := mp.dirSummary(dir); ok && *stored == *summary {
+		return
+	}
+	extend := NewExtend(dir)
+	for key, value := range summary.XAttrs() {
+		extend.Put([]byte(key), []byte(value))
+	}
+	_, err = mp.putExtend(opFSMSetXAttr, extend)
+	return
+}
+
+// dirSummary returns the recorded summary of a directory of this partition.
+func (mp *metaPartition) dirSummary(dir uint64) (*proto.DirSummary, bool) {
+	item := mp.extendTree.Get(NewExtend(dir))
+	if item == nil {
+		return nil, false
+	}
+	xattrs := make(map[string]string)
+	for _, key := range proto.DirSummaryKeys {
+		if value, exist := item.(*Extend).Get([]byte(key)); exist {
+			xattrs[key] = string(value)
+		}
+	}
+	return proto.DirSummaryFromXAttrs(xattrs)
+}
+
+// summarizeDir computes the summary of the directory from its children. The subdirectory which is
+// not summarized yet is counted as empty.
+func (mp *metaPartition) summarizeDir(dir uint64, views []*proto.MetaPartitionView) (summary *proto.DirSummary, err error) {
+	summary = new(proto.DirSummary)
+	files := make(map[uint64][]uint64) // partition id -> inodes
+	subdirs := make(map[uint64][]uint64)
+	mp.dentryTree.AscendRange(&Dentry{ParentId: dir}, &Dentry{ParentId: dir + 1}, func(i BtreeItem) bool {
+		d := i.(*Dentry)
+		pid := inodePartition(d.Inode, views)
+		if proto.IsDir(d.Type) {
+			summary.Subdirs++
+			if pid != 0 {
+				subdirs[pid] = append(subdirs[pid], d.Inode)
+			}
+		} else {
+			summary.Files++
+			if pid != 0 {
+				files[pid] = append(files[pid], d.Inode)
+			}
+		}
+		return true
+	})
+	for pid, inodes := range files {
+		var bytes uint64
+		if bytes, err = mp.sumFileSizes(pid, inodes); err != nil {
+			return
+		}
+		summary.Bytes += bytes
+	}
+	for pid, inodes := range subdirs {
+		if err = mp.addSubdirSummaries(summary, pid, inodes); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) sumFileSizes(pid uint64, inodes []uint64) (bytes uint64, err error) {
+	if pid == mp.config.PartitionId {
+		for _, ino := range inodes {
+			if item := mp.inodeTree.Get(NewInode(ino, 0)); item != nil {
+				bytes += item.(*Inode).Size
+			}
+		}
+		return
+	}
+	for start := 0; start < len(inodes); start += dirSummaryBatchSize {
+		end := start + dirSummaryBatchSize
+		if end > len(inodes) {
+			end = len(inodes)
+		}
+		resp := new(proto.BatchInodeGetResponse)
+		if err = mp.sendToPartition(pid, proto.OpMetaBatchInodeGet, &proto.BatchInodeGetRequest{
+			VolName:     mp.config.VolName,
+			PartitionID: pid,
+			Inodes:      inodes[start:end],
+		}, resp); err != nil {
+			return
+		}
+		for _, info := range resp.Infos {
+			bytes += info.Size
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) addSubdirSummaries(summary *proto.DirSummary, pid uint64, inodes []uint64) (err error) {
+	if pid == mp.config.PartitionId {
+		for _, ino := range inodes {
+			if sub, ok := mp.dirSummary(ino); ok {
+				summary.Add(sub)
+			}
+		}
+		return
+	}
+	for start := 0; start < len(inodes); start += dirSummaryBatchSize {
+		end := start + dirSummaryBatchSize
+		if end > len(inodes) {
+			end = len(inodes)
+		}
+		resp := new(proto.BatchGetXAttrResponse)
+		if err = mp.sendToPartition(pid, proto.OpMetaBatchGetXAttr, &proto.BatchGetXAttrRequest{
+			VolName:     mp.config.VolName,
+			PartitionId: pid,
+			Inodes:      inodes[start:end],
+			Keys:        proto.DirSummaryKeys,
+		}, resp); err != nil {
+			return
+		}
+		for _, info := range resp.XAttrs {
+			if sub, ok := proto.DirSummaryFromXAttrs(info.XAttrs); ok {
+				summary.Add(sub)
+			}
+		}
+	}
+	return
+}
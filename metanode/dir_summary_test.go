@@ -0,0 +1,47 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestSummarizeDir(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+	}
+	views := []*proto.MetaPartitionView{{PartitionID: 1, Start: 1, End: 100}}
+	dirMode := proto.Mode(os.ModeDir | 0755)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 1, Type: dirMode, NLink: 3}, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 2, Type: dirMode, NLink: 2}, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 3, Type: dirMode, NLink: 2}, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 10, Type: proto.Mode(0644), NLink: 1, Size: 100}, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 11, Type: proto.Mode(0644), NLink: 1, Size: 20}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 2, Type: dirMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "b", Inode: 3, Type: dirMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "f", Inode: 10, Type: proto.Mode(0644)}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 2, Name: "g", Inode: 11, Type: proto.Mode(0644)}, true)
+
+	summary, err := mp.summarizeDir(2, views)
+	if err != nil || *summary != (proto.DirSummary{Files: 1, Bytes: 20}) {
+		t.Fatalf("summarize dir: summary(%v) err(%v)", summary, err)
+	}
+	extend := NewExtend(2)
+	for key, value := range summary.XAttrs() {
+		extend.Put([]byte(key), []byte(value))
+	}
+	mp.fsmSetXAttr(extend)
+	if stored, ok := mp.dirSummary(2); !ok || *stored != *summary {
+		t.Fatalf("recorded summary: summary(%v) ok(%v)", stored, ok)
+	}
+
+	// the subdirectory which is not summarized yet is counted as empty
+	summary, err = mp.summarizeDir(1, views)
+	if err != nil || *summary != (proto.DirSummary{Files: 2, Subdirs: 2, Bytes: 120}) {
+		t.Fatalf("summarize root: summary(%v) err(%v)", summary, err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/util/health"
+)
+
+// at most the partitions are listed in the reason of the failed check
+const healthMaxReportedPartitions = 10
+
+func (m *MetaNode) registerHealthChecks() {
+	health.RegisterReadiness("partitions", m.checkPartitionsReady)
+}
+
+// checkPartitionsReady requires the raft of every meta partition on the node to be running with a
+// leader, and not restoring a snapshot.
+func (m *MetaNode) checkPartitionsReady() error {
+	var (
+		total    int
+		notReady []uint64
+	)
+	m.metadataManager.Range(func(id uint64, mp MetaPartition) bool {
+		total++
+		status := m.raftStore.RaftStatus(id)
+		if status == nil || status.Stopped || status.RestoringSnapshot || status.Leader == 0 {
+			notReady = append(notReady, id)
+		}
+		return true
+	})
+	if len(notReady) == 0 {
+		return nil
+	}
+	reported := notReady
+	if len(reported) > healthMaxReportedPartitions {
+		reported = reported[:healthMaxReportedPartitions]
+	}
+	return fmt.Errorf("%v of %v partitions are not ready: %v", len(notReady), total, reported)
+}
@@ -416,6 +416,16 @@ func (i *Inode) ExtentsTruncate(length uint64, ct int64) (delExtents []proto.Ext
 	return
 }
 
+// ExtentsPunch removes the file range from the extents, the size of the file is not changed.
+func (i *Inode) ExtentsPunch(offset, size uint64, ct int64) (delExtents []proto.ExtentKey) {
+	i.Lock()
+	delExtents = i.Extents.Punch(offset, size)
+	i.ModifyTime = ct
+	i.Generation++
+	i.Unlock()
+	return
+}
+
 // IncNLink increases the nLink value by one.
 func (i *Inode) IncNLink() {
 	i.Lock()
@@ -499,6 +509,12 @@ func (i *Inode) SetAttr(req *SetattrRequest) {
 	if req.Valid&proto.AttrAccessTime != 0 {
 		i.AccessTime = req.AccessTime
 	}
+	if req.Valid&proto.AttrRelatime != 0 && proto.RelatimeDue(i.AccessTime, i.ModifyTime, i.CreateTime, req.AccessTime) {
+		i.AccessTime = req.AccessTime
+	}
+	if req.Valid&proto.AttrFlags != 0 {
+		i.Flag = i.Flag&^int32(proto.InodeFlagsMask) | int32(req.Flags&proto.InodeFlagsMask)
+	}
 	if req.Valid&proto.AttrModifyTime != 0 {
 		i.ModifyTime = req.ModifyTime
 	}
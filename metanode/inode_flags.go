@@ -0,0 +1,155 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// inodeFlags returns the immutable and the append-only flags of the inode.
+func (i *Inode) inodeFlags() uint32 {
+	i.RLock()
+	defer i.RUnlock()
+	return uint32(i.Flag) & proto.InodeFlagsMask
+}
+
+// checkInodeFlags refuses the operation on an immutable file, and the operation which modifies the
+// existing content of an append-only file. A nil modifies means the operation always modifies the inode.
+func (mp *metaPartition) checkInodeFlags(ino *Inode, op string, modifies func(ino *Inode) bool, p *Packet) (err error) {
+	flags := ino.inodeFlags()
+	if flags&proto.InodeFlagImmutable == 0 && (flags&proto.InodeFlagAppendOnly == 0 || modifies != nil && !modifies(ino)) {
+		return
+	}
+	err = fmt.Errorf("inode(%v) of vol(%v) is immutable or append-only, flags(%v)", ino.Inode, mp.config.VolName, flags)
+	log.LogWarnf("action[checkInodeFlags] op(%v) refused: %v", op, err)
+	p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+	return
+}
+
+// checkSetAttr refuses to set the flags of the inode which is not a regular file, and to change the
+// other attributes of a flagged file, except for the access time updated with the relatime policy.
+func (mp *metaPartition) checkSetAttr(req *SetattrRequest, p *Packet) (err error) {
+	item := mp.inodeTree.Get(NewInode(req.Inode, 0))
+	if item == nil {
+		return
+	}
+	ino := item.(*Inode)
+	if req.Valid&proto.AttrFlags != 0 && !proto.IsRegular(ino.Type) {
+		err = fmt.Errorf("inode(%v) of vol(%v) is not a regular file", ino.Inode, mp.config.VolName)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if req.Valid&^(proto.AttrFlags|proto.AttrRelatime) == 0 {
+		return
+	}
+	return mp.checkInodeFlags(ino, "SetAttr", nil, p)
+}
+
+// sealForFlags seals the extents of the file on the data nodes before the immutable or the append-only
+// flag is set, so that the data nodes refuse to overwrite its data no matter how the file is opened.
+// The flags are not set if the extents can not be sealed.
+func (mp *metaPartition) sealForFlags(req *SetattrRequest, p *Packet) (err error) {
+	if req.Valid&proto.AttrFlags == 0 || req.Flags&proto.InodeFlagsMask == 0 {
+		return
+	}
+	if err = mp.sealExtents(mp.inodeExtents(req.Inode), false); err != nil {
+		err = fmt.Errorf("seal extents of inode(%v) of vol(%v): %v", req.Inode, mp.config.VolName, err)
+		log.LogWarnf("action[sealForFlags] %v", err)
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+	}
+	return
+}
+
+// resealForFlags seals the extents again after the flags are set, which covers the extent keys appended
+// between the seal and the change of the flags, or unseals them after both the flags are cleared.
+// A failed unseal leaves the data sealed, and is retried by clearing the flags again.
+func (mp *metaPartition) resealForFlags(req *SetattrRequest) {
+	if req.Valid&proto.AttrFlags == 0 {
+		return
+	}
+	unseal := req.Flags&proto.InodeFlagsMask == 0
+	if err := mp.sealExtents(mp.inodeExtents(req.Inode), unseal); err != nil {
+		log.LogErrorf("action[resealForFlags] inode(%v) of vol(%v) unseal(%v) err(%v)", req.Inode, mp.config.VolName, unseal, err)
+	}
+}
+
+// sealAppended seals the extent keys appended to an append-only file, so that the appended data can not
+// be overwritten either. The append is refused if the extent keys can not be sealed.
+func (mp *metaPartition) sealAppended(inode uint64, eks []proto.ExtentKey, p *Packet) (err error) {
+	item := mp.inodeTree.Get(NewInode(inode, 0))
+	if item == nil || item.(*Inode).inodeFlags()&proto.InodeFlagAppendOnly == 0 {
+		return
+	}
+	if err = mp.sealExtents(eks, false); err != nil {
+		err = fmt.Errorf("seal extents appended to inode(%v) of vol(%v): %v", inode, mp.config.VolName, err)
+		log.LogWarnf("action[sealAppended] %v", err)
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+	}
+	return
+}
+
+func (mp *metaPartition) inodeExtents(inode uint64) (eks []proto.ExtentKey) {
+	item := mp.inodeTree.Get(NewInode(inode, 0))
+	if item == nil {
+		return
+	}
+	return item.(*Inode).Extents.CopyExtents()
+}
+
+// sealExtents seals or unseals the extent keys on all the replicas of their data partitions.
+func (mp *metaPartition) sealExtents(eks []proto.ExtentKey, unseal bool) (err error) {
+	partitions := make(map[uint64][]proto.ExtentKey)
+	for _, ek := range eks {
+		partitions[ek.PartitionId] = append(partitions[ek.PartitionId], ek)
+	}
+	for partitionID, keys := range partitions {
+		if err = mp.doSealExtentsByPartition(partitionID, &proto.SealExtentsRequest{Unseal: unseal, Extents: keys}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) doSealExtentsByPartition(partitionID uint64, req *proto.SealExtentsRequest) (err error) {
+	dp := mp.vol.GetPartition(partitionID)
+	if dp == nil {
+		return fmt.Errorf("unknown data partition(%v) in vol", partitionID)
+	}
+	conn, err := mp.config.ConnPool.GetConnect(dp.Hosts[0])
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	if err != nil {
+		return fmt.Errorf("get conn to data partition(%v): %v", partitionID, err)
+	}
+	p := NewPacketToSealExtents(dp, req)
+	if err = p.WriteToConn(conn); err != nil {
+		return fmt.Errorf("write to data node %v: %v", p.GetUniqueLogId(), err)
+	}
+	if err = p.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		return fmt.Errorf("read response from data node %v: %v", p.GetUniqueLogId(), err)
+	}
+	if p.ResultCode != proto.OpOk {
+		err = fmt.Errorf("%v response: %v", p.GetUniqueLogId(), p.GetResultMsg())
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestInodeFlags(t *testing.T) {
+	mp := &metaPartition{
+		config:    &MetaPartitionConfig{PartitionId: 1},
+		inodeTree: NewBtree(),
+	}
+	file := &Inode{Inode: 10, Type: proto.Mode(0644), NLink: 1, Size: 2000}
+	mp.inodeTree.ReplaceOrInsert(file, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 11, Type: proto.Mode(os.ModeDir | 0755), NLink: 2}, true)
+
+	if err := mp.checkSetAttr(&SetattrRequest{Inode: 11, Valid: proto.AttrFlags, Flags: proto.InodeFlagImmutable}, &Packet{}); err == nil {
+		t.Fatalf("set flags of a directory")
+	}
+	file.SetAttr(&SetattrRequest{Valid: proto.AttrFlags, Flags: proto.InodeFlagAppendOnly | DeleteMarkFlag})
+	if file.Flag != int32(proto.InodeFlagAppendOnly) {
+		t.Fatalf("append-only flag: %v", file.Flag)
+	}
+	// the data is only appended to an append-only file
+	if err := mp.checkInodeFlags(file, "ExtentAppend", overwrites(proto.ExtentKey{FileOffset: 2000, Size: 1000}), &Packet{}); err != nil {
+		t.Fatalf("append to append-only file: %v", err)
+	}
+	p := &Packet{}
+	if err := mp.checkInodeFlags(file, "ExtentAppend", overwrites(proto.ExtentKey{FileOffset: 0, Size: 1000}), p); err == nil || p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("overwrite append-only file: err(%v) result(%v)", err, p.ResultCode)
+	}
+	if err := mp.checkInodeFlags(file, "UnlinkInode", nil, &Packet{}); err == nil {
+		t.Fatalf("unlink append-only file")
+	}
+	if err := mp.checkSetAttr(&SetattrRequest{Inode: 10, Valid: proto.AttrMode}, &Packet{}); err == nil {
+		t.Fatalf("chmod append-only file")
+	}
+	if err := mp.checkSetAttr(&SetattrRequest{Inode: 10, Valid: proto.AttrRelatime}, &Packet{}); err != nil {
+		t.Fatalf("update access time of append-only file: %v", err)
+	}
+
+	file.SetAttr(&SetattrRequest{Valid: proto.AttrFlags, Flags: proto.InodeFlagImmutable})
+	if err := mp.checkInodeFlags(file, "ExtentAppend", overwrites(proto.ExtentKey{FileOffset: 2000, Size: 1000}), &Packet{}); err == nil {
+		t.Fatalf("append to immutable file")
+	}
+	// the flags are always allowed to be cleared
+	if err := mp.checkSetAttr(&SetattrRequest{Inode: 10, Valid: proto.AttrFlags}, &Packet{}); err != nil {
+		t.Fatalf("clear flags: %v", err)
+	}
+	file.SetAttr(&SetattrRequest{Valid: proto.AttrFlags})
+	if err := mp.checkInodeFlags(file, "UnlinkInode", nil, &Packet{}); err != nil {
+		t.Fatalf("unlink file without flags: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// The advisory file locks are kept in memory by every replica of the meta partition, and changed through raft
+// so that they survive the change of the leader. They are persisted in the snapshots as well, so that a replica
+// restored from a snapshot holds the same locks. Every lock has a lease renewed by its session, and the locks
+// of a crashed client are released once their leases expire.
+
+type lockEntry struct {
+	proto.FileLock
+	expire int64 // unix seconds
+}
+
+// LockOp defines the raft command of the lock operations. The time is decided by the leader,
+// so that the replicas release the same expired locks.
+type LockOp struct {
+	Inode   uint64         `json:"ino"`
+	Lock    proto.FileLock `json:"lock"`
+	Session string         `json:"sess"`
+	Now     int64          `json:"now"`
+}
+
+// Marshal marshals the lock operation into bytes.
+func (op *LockOp) Marshal() ([]byte, error) {
+	return json.Marshal(op)
+}
+
+// Unmarshal unmarshals the lock operation from bytes.
+func (op *LockOp) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, op)
+}
+
+// LockRecord is a lock of an inode in the snapshots.
+type LockRecord struct {
+	Inode  uint64         `json:"ino"`
+	Lock   proto.FileLock `json:"lock"`
+	Expire int64          `json:"expire"`
+}
+
+type lockTable struct {
+	sync.RWMutex
+	inodes map[uint64][]*lockEntry
+}
+
+func newLockTable() *lockTable {
+	return &lockTable{inodes: make(map[uint64][]*lockEntry)}
+}
+
+// set acquires or releases the lock of the inode and returns the conflicting lock if the lock can not be acquired.
+// The range of the lock replaces the overlapping parts of the other locks of its owner, which may be split,
+// as fcntl(2) does.
+func (t *lockTable) set(ino uint64, lock *proto.FileLock, now, lease int64) (conflict *proto.FileLock) {
+	t.Lock()
+	defer t.Unlock()
+	entries := make([]*lockEntry, 0, len(t.inodes[ino])+2)
+	for _, e := range t.inodes[ino] {
+		if e.expire >= now {
+			entries = append(entries, e)
+		}
+	}
+	if lock.Type != proto.FileLockUnlock {
+		for _, e := range entries {
+			if e.Conflicts(lock) {
+				conflict = &proto.FileLock{}
+				*conflict = e.FileLock
+				t.store(ino, entries)
+				return
+			}
+		}
+	}
+	kept := make([]*lockEntry, 0, len(entries)+2)
+	for _, e := range entries {
+		if !e.SameOwner(lock) || e.End < lock.Start || lock.End < e.Start {
+			kept = append(kept, e)
+			continue
+		}
+		if e.Start < lock.Start {
+			left := *e
+			left.End = lock.Start - 1
+			kept = append(kept, &left)
+		}
+		if e.End > lock.End {
+			right := *e
+			right.Start = lock.End + 1
+			kept = append(kept, &right)
+		}
+	}
+	if lock.Type != proto.FileLockUnlock {
+		kept = append(kept, &lockEntry{FileLock: *lock, expire: now + lease})
+	}
+	t.store(ino, kept)
+	return
+}
+
+// The caller should grab the lock of the table.
+func (t *lockTable) store(ino uint64, entries []*lockEntry) {
+	if len(entries) == 0 {
+		delete(t.inodes, ino)
+		return
+	}
+	t.inodes[ino] = entries
+}
+
+// get returns a lock of the inode conflicting with the given one, or nil if there is none.
+func (t *lockTable) get(ino uint64, lock *proto.FileLock, now int64) *proto.FileLock {
+	t.RLock()
+	defer t.RUnlock()
+	for _, e := range t.inodes[ino] {
+		if e.expire >= now && e.Conflicts(lock) {
+			conflict := e.FileLock
+			return &conflict
+		}
+	}
+	return nil
+}
+
+// renew extends the leases of the locks held by the session, and returns the number of them.
+func (t *lockTable) renew(session string, now, lease int64) (count int) {
+	t.Lock()
+	defer t.Unlock()
+	for ino, entries := range t.inodes {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.Session == session {
+				e.expire = now + lease
+				count++
+			}
+			if e.expire >= now {
+				kept = append(kept, e)
+			}
+		}
+		t.store(ino, kept)
+	}
+	return
+}
+
+// records returns the copies of all the locks, which are stored in the snapshots.
+func (t *lockTable) records() []*LockRecord {
+	t.RLock()
+	defer t.RUnlock()
+	records := make([]*LockRecord, 0, len(t.inodes))
+	for ino, entries := range t.inodes {
+		for _, e := range entries {
+			records = append(records, &LockRecord{Inode: ino, Lock: e.FileLock, Expire: e.expire})
+		}
+	}
+	return records
+}
+
+// lockTableFromRecords returns the lock table restored from the records of a snapshot.
+func lockTableFromRecords(records []*LockRecord) *lockTable {
+	t := newLockTable()
+	for _, r := range records {
+		t.inodes[r.Inode] = append(t.inodes[r.Inode], &lockEntry{FileLock: r.Lock, expire: r.Expire})
+	}
+	return t
+}
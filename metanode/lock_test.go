@@ -0,0 +1,97 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestLock(session string, start, end uint64, lockType uint8) *proto.FileLock {
+	return &proto.FileLock{Session: session, Owner: 1, Start: start, End: end, Type: lockType}
+}
+
+func TestLockTableRange(t *testing.T) {
+	table := newLockTable()
+	const ino, now, lease = 100, 1000, 30
+
+	if conflict := table.set(ino, newTestLock("a", 0, 99, proto.FileLockRead), now, lease); conflict != nil {
+		t.Fatalf("read lock should be acquired: conflict(%v)", conflict)
+	}
+	if conflict := table.set(ino, newTestLock("b", 50, 149, proto.FileLockRead), now, lease); conflict != nil {
+		t.Fatalf("read locks should be shared: conflict(%v)", conflict)
+	}
+	if conflict := table.set(ino, newTestLock("b", 60, 69, proto.FileLockWrite), now, lease); conflict == nil || conflict.Session != "a" {
+		t.Fatalf("write lock should conflict with the read lock of another session: conflict(%v)", conflict)
+	}
+
+	// unlocking the middle of the range splits the lock of session a
+	table.set(ino, newTestLock("a", 40, 79, proto.FileLockUnlock), now, lease)
+	if conflict := table.set(ino, newTestLock("b", 60, 69, proto.FileLockWrite), now, lease); conflict != nil {
+		t.Fatalf("write lock should be acquired in the unlocked range: conflict(%v)", conflict)
+	}
+	if conflict := table.get(ino, newTestLock("c", 30, 39, proto.FileLockWrite), now); conflict == nil || conflict.Session != "a" {
+		t.Fatalf("head of the split lock should be kept: conflict(%v)", conflict)
+	}
+	if conflict := table.get(ino, newTestLock("c", 80, 89, proto.FileLockWrite), now); conflict == nil {
+		t.Fatalf("tail of the split lock should be kept")
+	}
+
+	// flock and fcntl locks do not conflict with each other
+	flock := newTestLock("c", 0, math.MaxUint64, proto.FileLockWrite)
+	flock.Flock = true
+	if conflict := table.set(ino, flock, now, lease); conflict != nil {
+		t.Fatalf("flock should not conflict with fcntl locks: conflict(%v)", conflict)
+	}
+}
+
+func TestLockTableLease(t *testing.T) {
+	table := newLockTable()
+	const ino, now, lease = 100, 1000, 30
+
+	table.set(ino, newTestLock("a", 0, math.MaxUint64, proto.FileLockWrite), now, lease)
+	if count := table.renew("a", now+20, lease); count != 1 {
+		t.Fatalf("expect 1 lock renewed but is %v", count)
+	}
+	if conflict := table.set(ino, newTestLock("b", 0, 0, proto.FileLockRead), now+40, lease); conflict == nil {
+		t.Fatalf("renewed lock should be kept")
+	}
+	if conflict := table.set(ino, newTestLock("b", 0, 0, proto.FileLockRead), now+60, lease); conflict != nil {
+		t.Fatalf("expired lock should be released: conflict(%v)", conflict)
+	}
+	if count := table.renew("a", now+60, lease); count != 0 {
+		t.Fatalf("expect no lock renewed but is %v", count)
+	}
+}
+
+func TestLockTableRecords(t *testing.T) {
+	table := newLockTable()
+	const ino, now, lease = 100, 1000, 30
+
+	table.set(ino, newTestLock("a", 0, 99, proto.FileLockWrite), now, lease)
+	table.set(ino+1, newTestLock("a", 0, math.MaxUint64, proto.FileLockRead), now, lease)
+	restored := lockTableFromRecords(table.records())
+	if conflict := restored.get(ino, newTestLock("b", 50, 59, proto.FileLockRead), now); conflict == nil || conflict.Session != "a" {
+		t.Fatalf("restored write lock should conflict: conflict(%v)", conflict)
+	}
+	if conflict := restored.get(ino+1, newTestLock("b", 0, 0, proto.FileLockWrite), now); conflict == nil {
+		t.Fatalf("restored read lock should conflict with the write lock")
+	}
+	if conflict := restored.get(ino, newTestLock("b", 50, 59, proto.FileLockRead), now+lease+1); conflict != nil {
+		t.Fatalf("lease of the restored lock should expire: conflict(%v)", conflict)
+	}
+}
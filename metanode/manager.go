@@ -27,6 +27,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/chubaofs/chubaofs/cmd/common"
 	"github.com/chubaofs/chubaofs/proto"
@@ -35,6 +36,8 @@ import (
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 const partitionPrefix = "partition_"
@@ -47,6 +50,7 @@ type MetadataManager interface {
 	//CreatePartition(id string, start, end uint64, peers []proto.Peer) error
 	HandleMetadataOperation(conn net.Conn, p *Packet, remoteAddr string) error
 	GetPartition(id uint64) (MetaPartition, error)
+	Range(f func(i uint64, p MetaPartition) bool)
 }
 
 // MetadataManagerConfig defines the configures in the metadata manager.
@@ -75,7 +79,42 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 	remoteAddr string) (err error) {
 	metric := exporter.NewTPCnt(p.GetOpMsg())
 	defer metric.Set(err)
+	start := time.Now()
+	defer func() {
+		if latency := time.Since(start); slowop.IsSlow(p.GetOpMsg(), latency) {
+			slowop.Record(&proto.SlowOp{
+				Op:          p.GetOpMsg(),
+				PartitionID: p.PartitionID,
+				Peer:        remoteAddr,
+				LatencyUs:   int64(latency / time.Microsecond),
+				Result:      p.GetResultMsg(),
+			})
+		}
+	}()
+
+	span := tracing.StartSpan(p.GetOpMsg(), tracing.SpanKindServer, p.Trace)
+	span.SetAttribute(tracing.AttributePeer, remoteAddr)
+	span.SetAttribute(tracing.AttributePartitionID, p.PartitionID)
+	// The request proxied to the leader is traced as a child of the span.
+	p.Trace = span.Context()
+	defer func() {
+		span.SetAttribute(tracing.AttributeResult, p.GetResultMsg())
+		span.Finish(err)
+	}()
+
+	if p.DeadlineExceeded() {
+		// The client has stopped waiting, fail fast instead of processing the request.
+		// The response is still sent to keep the requests and responses of the connection in order.
+		exporter.NewCounter(MetricDeadlineExceeded).Add(1)
+		p.PacketErrorWithBody(proto.OpAgain, []byte(proto.ErrDeadlineExceeded.Error()))
+		err = m.respondToClient(conn, p)
+		log.LogWarnf("HandleMetadataOperation: deadline exceeded: remote(%v) op(%v) req(%v)",
+			remoteAddr, p.GetOpMsg(), p.GetReqID())
+		return
+	}
 
+	opcode, data := p.Opcode, p.Data
+	defer m.auditOperation(opcode, data, p, remoteAddr)
 	switch p.Opcode {
 	case proto.OpMetaCreateInode:
 		err = m.opCreateInode(conn, p, remoteAddr)
@@ -133,6 +172,8 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 		err = m.opRemoveMetaPartitionRaftMember(conn, p, remoteAddr)
 	case proto.OpMetaPartitionTryToLeader:
 		err = m.opMetaPartitionTryToLeader(conn, p, remoteAddr)
+	case proto.OpMetaTierScan:
+		err = m.opMetaTierScan(conn, p, remoteAddr)
 	case proto.OpMetaBatchInodeGet:
 		err = m.opMetaBatchInodeGet(conn, p, remoteAddr)
 	case proto.OpMetaDeleteInode:
@@ -152,6 +193,27 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 		err = m.opMetaRemoveXAttr(conn, p, remoteAddr)
 	case proto.OpMetaListXAttr:
 		err = m.opMetaListXAttr(conn, p, remoteAddr)
+	case proto.OpMetaSetLock:
+		err = m.opMetaSetLock(conn, p, remoteAddr)
+	case proto.OpMetaGetLock:
+		err = m.opMetaGetLock(conn, p, remoteAddr)
+	case proto.OpMetaRenewLock:
+		err = m.opMetaRenewLock(conn, p, remoteAddr)
+	case proto.OpMetaPunchHole:
+		err = m.opMetaExtentsPunch(conn, p, remoteAddr)
+	case proto.OpMetaReadChangelog:
+		err = m.opMetaReadChangelog(conn, p, remoteAddr)
+	// operations for metadata transactions
+	case proto.OpMetaTxPrepare:
+		err = m.opMetaTxPrepare(conn, p, remoteAddr)
+	case proto.OpMetaTxCommit:
+		err = m.opMetaTxCommit(conn, p, remoteAddr)
+	case proto.OpMetaTxAbort:
+		err = m.opMetaTxAbort(conn, p, remoteAddr)
+	case proto.OpMetaTxGet:
+		err = m.opMetaTxGet(conn, p, remoteAddr)
+	case proto.OpMetaTxForget:
+		err = m.opMetaTxForget(conn, p, remoteAddr)
 	// operations for multipart session
 	case proto.OpCreateMultipart:
 		err = m.opCreateMultipart(conn, p, remoteAddr)
@@ -164,6 +226,9 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 	case proto.OpGetMultipart:
 		err = m.opGetMultipart(conn, p, remoteAddr)
 	default:
+		// reply the unknown opcode, so that the client may fall back to the older operations
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(proto.ErrUnknownOpcode.Error()))
+		m.respondToClient(conn, p)
 		err = fmt.Errorf("%s unknown Opcode: %d, reqId: %d", remoteAddr,
 			p.Opcode, p.GetReqID())
 	}
@@ -389,6 +454,11 @@ func (m *metadataManager) createPartition(request *proto.CreateMetaPartitionRequ
 		err = oldMp.IsEquareCreateMetaPartitionRequst(request)
 		return
 	}
+	if used, e := util.GetProcessMemory(os.Getpid()); e == nil && reachesMemThreshold(used) {
+		err = errors.NewErrorf("[createPartition] memory used(%v) reaches the threshold(%v) of totalMem(%v)",
+			used, memUsageThreshold, configTotalMem)
+		return
+	}
 
 	partition := NewMetaPartition(mpc, m)
 	if err = partition.PersistMetadata(); err != nil {
@@ -0,0 +1,138 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The memory taken by an item of the trees, including the slot of the item in a node of the btree.
+const btreeItemMemSize = 16
+
+var (
+	inodeMemSize     = uint64(unsafe.Sizeof(Inode{})+unsafe.Sizeof(SortedExtents{})) + btreeItemMemSize
+	extentKeyMemSize = uint64(unsafe.Sizeof(proto.ExtentKey{}))
+	dentryMemSize    = uint64(unsafe.Sizeof(Dentry{})) + btreeItemMemSize
+	extendMemSize    = uint64(unsafe.Sizeof(Extend{})) + btreeItemMemSize
+	multipartMemSize = uint64(unsafe.Sizeof(Multipart{})) + btreeItemMemSize
+	partMemSize      = uint64(unsafe.Sizeof(Part{}))
+)
+
+// updateMemUsed estimates the memory taken by the items of this partition. The estimate is
+// lower than the real usage since the allocator and the garbage not collected yet are not
+// counted, but it tells which partitions take most of the memory of the meta node.
+func (mp *metaPartition) updateMemUsed() {
+	var size uint64
+	mp.inodeTree.GetTree().Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		ino.RLock()
+		size += inodeMemSize + uint64(len(ino.LinkTarget))
+		if ino.Extents != nil {
+			size += uint64(ino.Extents.Len()) * extentKeyMemSize
+		}
+		ino.RUnlock()
+		return true
+	})
+	mp.dentryTree.GetTree().Ascend(func(i BtreeItem) bool {
+		size += dentryMemSize + uint64(len(i.(*Dentry).Name))
+		return true
+	})
+	mp.extendTree.GetTree().Ascend(func(i BtreeItem) bool {
+		extend := i.(*Extend)
+		extend.mu.RLock()
+		for key, value := range extend.dataMap {
+			size += uint64(len(key) + len(value))
+		}
+		extend.mu.RUnlock()
+		size += extendMemSize
+		return true
+	})
+	mp.multipartTree.GetTree().Ascend(func(i BtreeItem) bool {
+		multipart := i.(*Multipart)
+		multipart.mu.RLock()
+		size += multipartMemSize + uint64(len(multipart.id)+len(multipart.key)) + uint64(len(multipart.parts))*partMemSize
+		multipart.mu.RUnlock()
+		return true
+	})
+	atomic.StoreUint64(&mp.memUsed, size)
+	log.LogDebugf("action[updateMemUsed] vol(%v) mp(%v) mem used(%v)", mp.config.VolName, mp.config.PartitionId, size)
+}
+
+// GetMemUsed returns the memory estimated to be taken by the items of this partition.
+func (mp *metaPartition) GetMemUsed() uint64 {
+	return atomic.LoadUint64(&mp.memUsed)
+}
+
+// reachesMemThreshold tells whether the memory used by the meta node reaches the threshold
+// above which no partition is created on it, so that the node stops growing before it runs
+// out of memory.
+func reachesMemThreshold(used uint64) bool {
+	return memUsageThreshold > 0 && float64(used) > float64(configTotalMem)*memUsageThreshold
+}
+
+// reachesMemReadOnlyThreshold tells whether the memory used by the meta node reaches the
+// threshold above which its partitions are reported read-only.
+func reachesMemReadOnlyThreshold(used uint64) bool {
+	return float64(used) > float64(configTotalMem)*memReadOnlyThreshold
+}
+
+// getMemUsageHandler returns the memory usage of the meta node and the memory estimated to be
+// taken by the partitions, ordered by the memory taken.
+func (m *MetaNode) getMemUsageHandler(w http.ResponseWriter, r *http.Request) {
+	resp := NewAPIResponse(http.StatusInternalServerError, "")
+	defer func() {
+		data, _ := resp.Marshal()
+		if _, err := w.Write(data); err != nil {
+			log.LogErrorf("[getMemUsageHandler] response %s", err)
+		}
+	}()
+	used, err := util.GetProcessMemory(os.Getpid())
+	if err != nil {
+		resp.Msg = err.Error()
+		return
+	}
+	usage := &proto.MetaNodeMemUsage{
+		Total:             configTotalMem,
+		Used:              used,
+		UsageThreshold:    memUsageThreshold,
+		ReadOnlyThreshold: memReadOnlyThreshold,
+		MemFull:           reachesMemThreshold(used),
+		Partitions:        make([]*proto.MetaPartitionMemUsage, 0),
+	}
+	m.metadataManager.Range(func(id uint64, partition MetaPartition) bool {
+		usage.Partitions = append(usage.Partitions, &proto.MetaPartitionMemUsage{
+			PartitionID: id,
+			VolName:     partition.GetBaseConfig().VolName,
+			InodeCnt:    uint64(partition.GetInodeTree().Len()),
+			DentryCnt:   uint64(partition.GetDentryTree().Len()),
+			MemUsed:     partition.GetMemUsed(),
+		})
+		return true
+	})
+	sort.Slice(usage.Partitions, func(i, j int) bool {
+		return usage.Partitions[i].MemUsed > usage.Partitions[j].MemUsed
+	})
+	resp.Data = usage
+	resp.Code = http.StatusOK
+	resp.Msg = http.StatusText(http.StatusOK)
+}
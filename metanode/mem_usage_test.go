@@ -0,0 +1,73 @@
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+)
+
+func TestUpdateMemUsed(t *testing.T) {
+	mp := &metaPartition{
+		config:        &MetaPartitionConfig{},
+		inodeTree:     NewBtree(),
+		dentryTree:    NewBtree(),
+		extendTree:    NewBtree(),
+		multipartTree: NewBtree(),
+	}
+	mp.updateMemUsed()
+	if used := mp.GetMemUsed(); used != 0 {
+		t.Fatalf("unexpected mem used of an empty partition: %v", used)
+	}
+
+	ino := NewInode(2, proto.Mode(0644))
+	ino.Extents.Append(proto.ExtentKey{FileOffset: 0, PartitionId: 1, ExtentId: 1, Size: 100})
+	ino.Extents.Append(proto.ExtentKey{FileOffset: 100, PartitionId: 1, ExtentId: 2, Size: 100})
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "file", Inode: 2}, true)
+	extend := NewExtend(2)
+	extend.Put([]byte("key"), []byte("value"))
+	mp.extendTree.ReplaceOrInsert(extend, true)
+
+	mp.updateMemUsed()
+	expected := inodeMemSize + 2*extentKeyMemSize + dentryMemSize + uint64(len("file")) + extendMemSize + uint64(len("keyvalue"))
+	if used := mp.GetMemUsed(); used != expected {
+		t.Fatalf("unexpected mem used: expect(%v) actual(%v)", expected, used)
+	}
+}
+
+func TestReachesMemThreshold(t *testing.T) {
+	defer func(total uint64, threshold float64) {
+		configTotalMem, memUsageThreshold = total, threshold
+	}(configTotalMem, memUsageThreshold)
+	configTotalMem = 1000
+
+	memUsageThreshold = 0
+	if reachesMemThreshold(2000) {
+		t.Fatalf("the threshold should be disabled")
+	}
+	memUsageThreshold = 0.8
+	if reachesMemThreshold(800) {
+		t.Fatalf("used(800) should not reach the threshold")
+	}
+	if !reachesMemThreshold(801) {
+		t.Fatalf("used(801) should reach the threshold")
+	}
+	if reachesMemReadOnlyThreshold(1000) || !reachesMemReadOnlyThreshold(1101) {
+		t.Fatalf("unexpected read-only threshold(%v)", memReadOnlyThreshold)
+	}
+}
+
+func TestParseMemThresholds(t *testing.T) {
+	usage, readOnly, err := parseMemThresholds(config.LoadConfigString(`{}`))
+	if err != nil || usage != 0 || readOnly != MaxUsedMemFactor {
+		t.Fatalf("unexpected default thresholds: usage(%v) readOnly(%v) err(%v)", usage, readOnly, err)
+	}
+	usage, readOnly, err = parseMemThresholds(config.LoadConfigString(`{"memUsageThreshold": 0.8, "memReadOnlyThreshold": 0.9}`))
+	if err != nil || usage != 0.8 || readOnly != 0.9 {
+		t.Fatalf("unexpected thresholds: usage(%v) readOnly(%v) err(%v)", usage, readOnly, err)
+	}
+	if _, _, err = parseMemThresholds(config.LoadConfigString(`{"memUsageThreshold": 0.95, "memReadOnlyThreshold": 0.9}`)); err == nil {
+		t.Fatalf("usage threshold above the read-only threshold should fail")
+	}
+}
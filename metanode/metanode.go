@@ -29,10 +29,13 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/raftstore"
 	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/auditlog"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 var (
@@ -40,6 +43,9 @@ var (
 	masterClient   *masterSDK.MasterClient
 	configTotalMem uint64
 	serverPort     string
+
+	memUsageThreshold    float64            // ratio of configTotalMem above which no partition is created, 0 if disabled
+	memReadOnlyThreshold = MaxUsedMemFactor // ratio of configTotalMem above which the partitions are read-only
 )
 
 // The MetaNode manages the dentry and inode information of the meta partitions on a meta node.
@@ -113,12 +119,22 @@ func doStart(s common.Server, cfg *config.Config) (err error) {
 	if err = m.register(); err != nil {
 		return
 	}
+	if auditLogger, err = auditlog.NewLogger(m.localAddr+":"+m.listen, cfg); err != nil {
+		return fmt.Errorf("bad audit log config: %v", err)
+	}
+	if err = tracing.Init(cfg.GetString("role"), cfg); err != nil {
+		return fmt.Errorf("bad tracing config: %v", err)
+	}
+	if err = slowop.Init(cfg); err != nil {
+		return fmt.Errorf("bad slow op config: %v", err)
+	}
 	if err = m.startRaftServer(); err != nil {
 		return
 	}
 	if err = m.startMetaManager(); err != nil {
 		return
 	}
+	m.registerHealthChecks()
 	if err = m.registerAPIHandler(); err != nil {
 		return
 	}
@@ -151,6 +167,11 @@ func doShutdown(s common.Server) {
 	m.stopServer()
 	m.stopMetaManager()
 	m.stopRaftServer()
+	if auditLogger != nil {
+		auditLogger.Close()
+		auditLogger = nil
+	}
+	tracing.Stop()
 }
 
 // Sync blocks the invoker's goroutine until the meta node shuts down.
@@ -181,6 +202,11 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 	if deleteBatchCount > 1 {
 		updateDeleteBatchCount(uint64(deleteBatchCount))
 	}
+	changelogCapacity = int(cfg.GetInt64(cfgChangelogCapacity))
+
+	if memUsageThreshold, memReadOnlyThreshold, err = parseMemThresholds(cfg); err != nil {
+		return
+	}
 
 	total, _, err := util.GetMemInfo()
 	if err == nil && configTotalMem > total-util.GB {
@@ -0,0 +1,138 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/slowop"
+)
+
+// Keys of the runtime tunable parameters of the meta node.
+const (
+	NodeConfigDeleteBatchCount    = "deleteBatchCount"          // extents deleted in one batch by the free list
+	NodeConfigDeleteWorkerSleepMs = "deleteWorkerSleepMs"       // sleep time between two batches of deletion, 0 for no sleep
+	NodeConfigSlowOpThresholdMs   = slowop.ConfigKeyThresholdMs // suffixed with ".OpName" for the threshold of an operation
+)
+
+// NodeConfigValueDefault drops the value set at runtime, so that the parameter follows the cluster-wide setting again.
+const NodeConfigValueDefault = "default"
+
+var (
+	// values set by the operator at runtime, they are not persisted
+	nodeConfigOverrides = make(map[string]string)
+	nodeConfigLock      sync.RWMutex
+)
+
+func isNodeConfigOverridden(key string) bool {
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	_, ok := nodeConfigOverrides[key]
+	return ok
+}
+
+func nodeConfigs() (configs []*proto.NodeConfig) {
+	values := map[string]string{
+		NodeConfigDeleteBatchCount:    strconv.FormatUint(DeleteBatchCount(), 10),
+		NodeConfigDeleteWorkerSleepMs: strconv.FormatUint(atomic.LoadUint64(&deleteWorkerSleepMs), 10),
+	}
+	for key, value := range slowop.Thresholds() {
+		values[key] = value
+	}
+	nodeConfigLock.RLock()
+	defer nodeConfigLock.RUnlock()
+	configs = make([]*proto.NodeConfig, 0, len(values))
+	for key, value := range values {
+		_, overridden := nodeConfigOverrides[key]
+		configs = append(configs, &proto.NodeConfig{Key: key, Value: value, Overridden: overridden})
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Key < configs[j].Key
+	})
+	return
+}
+
+// setNodeConfig applies the value immediately, the cluster-wide settings are fetched from the master
+// again if the value is reset to the default.
+func (m *MetaNode) setNodeConfig(key, value string) (err error) {
+	if key != NodeConfigDeleteBatchCount && key != NodeConfigDeleteWorkerSleepMs && !slowop.IsThresholdKey(key) {
+		return fmt.Errorf("unknown config key: %v", key)
+	}
+	if value == NodeConfigValueDefault {
+		nodeConfigLock.Lock()
+		delete(nodeConfigOverrides, key)
+		nodeConfigLock.Unlock()
+		if slowop.IsThresholdKey(key) {
+			err = slowop.ResetThreshold(key)
+		} else {
+			m.updateNodeInfo()
+		}
+		log.LogInfof("action[setNodeConfig] key(%v) is reset to default", key)
+		return
+	}
+	var val uint64
+	if val, err = strconv.ParseUint(value, 10, 64); err != nil {
+		return
+	}
+	switch key {
+	case NodeConfigDeleteBatchCount:
+		updateDeleteBatchCount(val)
+	case NodeConfigDeleteWorkerSleepMs:
+		updateDeleteWorkerSleepMs(val)
+	default:
+		if err = slowop.SetThreshold(key, val); err != nil {
+			return
+		}
+	}
+	nodeConfigLock.Lock()
+	nodeConfigOverrides[key] = value
+	nodeConfigLock.Unlock()
+	log.LogInfof("action[setNodeConfig] key(%v) value(%v)", key, value)
+	return
+}
+
+func (m *MetaNode) getConfigHandler(w http.ResponseWriter, r *http.Request) {
+	resp := NewAPIResponse(http.StatusOK, http.StatusText(http.StatusOK))
+	resp.Data = nodeConfigs()
+	data, _ := resp.Marshal()
+	if _, err := w.Write(data); err != nil {
+		log.LogErrorf("[getConfigHandler] response %s", err)
+	}
+}
+
+func (m *MetaNode) setConfigHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	resp := NewAPIResponse(http.StatusBadRequest, "")
+	defer func() {
+		data, _ := resp.Marshal()
+		if _, err := w.Write(data); err != nil {
+			log.LogErrorf("[setConfigHandler] response %s", err)
+		}
+	}()
+	if err := m.setNodeConfig(r.FormValue("key"), r.FormValue("value")); err != nil {
+		resp.Msg = err.Error()
+		return
+	}
+	resp.Data = nodeConfigs()
+	resp.Code = http.StatusOK
+	resp.Msg = http.StatusText(http.StatusOK)
+}
@@ -70,6 +70,11 @@ func (m *MetaNode) updateNodeInfo() {
 		log.LogErrorf("[updateNodeInfo] %s", err.Error())
 		return
 	}
-	updateDeleteBatchCount(clusterInfo.MetaNodeDeleteBatchCount)
-	updateDeleteWorkerSleepMs(clusterInfo.MetaNodeDeleteWorkerSleepMs)
+	// the values set on the node at runtime take precedence over the cluster-wide settings
+	if !isNodeConfigOverridden(NodeConfigDeleteBatchCount) {
+		updateDeleteBatchCount(clusterInfo.MetaNodeDeleteBatchCount)
+	}
+	if !isNodeConfigOverridden(NodeConfigDeleteWorkerSleepMs) {
+		updateDeleteWorkerSleepMs(clusterInfo.MetaNodeDeleteWorkerSleepMs)
+	}
 }
@@ -76,3 +76,20 @@ func NewPacketToFreeInodeOnRaftFollower(partitionID uint64, freeInodes []byte) *
 
 	return p
 }
+
+// NewPacketToSealExtents returns a new packet to seal or unseal the extents on all the replicas.
+func NewPacketToSealExtents(dp *DataPartition, req *proto.SealExtentsRequest) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpSealExtents
+	p.ExtentType = proto.NormalExtentType
+	p.PartitionID = dp.PartitionID
+	p.Data, _ = json.Marshal(req)
+	p.Size = uint32(len(p.Data))
+	p.ReqID = proto.GenerateRequestID()
+	p.RemainingFollowers = uint8(len(dp.Hosts) - 1)
+	p.Arg = ([]byte)(dp.GetAllAddrs())
+	p.ArgLen = uint32(len(p.Arg))
+
+	return p
+}
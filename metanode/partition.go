@@ -23,6 +23,7 @@ import (
 	"sync/atomic"
 
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -117,6 +118,8 @@ type OpInode interface {
 	EvictInodeBatch(req *BatchEvictInodeReq, p *Packet) (err error)
 	SetAttr(reqData []byte, p *Packet) (err error)
 	GetInodeTree() *BTree
+	GetTrashSize() uint64
+	ScanTierCandidates(req *proto.TierScanRequest) (resp *proto.TierScanResponse)
 	DeleteInode(req *proto.DeleteInodeRequest, p *Packet) (err error)
 	DeleteInodeBatch(req *proto.DeleteInodeBatchRequest, p *Packet) (err error)
 }
@@ -145,9 +148,26 @@ type OpExtent interface {
 	ExtentAppend(req *proto.AppendExtentKeyRequest, p *Packet) (err error)
 	ExtentsList(req *proto.GetExtentsRequest, p *Packet) (err error)
 	ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error)
+	ExtentsPunch(req *proto.PunchHoleRequest, p *Packet) (err error)
 	BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error)
 }
 
+// OpTransaction defines the interface for the metadata transaction operations.
+type OpTransaction interface {
+	TxPrepare(req *proto.TxPrepareRequest, p *Packet) (err error)
+	TxCommit(req *proto.TxRequest, p *Packet) (err error)
+	TxAbort(req *proto.TxRequest, p *Packet) (err error)
+	TxGet(req *proto.TxRequest, p *Packet) (err error)
+	TxForget(req *proto.TxRequest, p *Packet) (err error)
+}
+
+// OpLock defines the interface for the advisory file lock operations.
+type OpLock interface {
+	SetLock(req *proto.SetLockRequest, p *Packet) (err error)
+	GetLock(req *proto.GetLockRequest, p *Packet) (err error)
+	RenewLock(req *proto.RenewLockRequest, p *Packet) (err error)
+}
+
 type OpMultipart interface {
 	GetMultipart(req *proto.GetMultipartRequest, p *Packet) (err error)
 	CreateMultipart(req *proto.CreateMultipartRequest, p *Packet) (err error)
@@ -164,6 +184,8 @@ type OpMeta interface {
 	OpPartition
 	OpExtend
 	OpMultipart
+	OpLock
+	OpTransaction
 }
 
 // OpPartition defines the interface for the partition operations.
@@ -181,6 +203,10 @@ type OpPartition interface {
 	TryToLeader(groupID uint64) error
 	CanRemoveRaftMember(peer proto.Peer) error
 	IsEquareCreateMetaPartitionRequst(request *proto.CreateMetaPartitionRequest) (err error)
+	ExportMeta(w io.Writer) (export *proto.MetaPartitionExport, err error)
+	ImportMeta(r io.Reader) (imported *proto.MetaPartitionExport, err error)
+	ReadChangelog(req *proto.ReadChangelogRequest, p *Packet) (err error)
+	GetMemUsed() uint64
 }
 
 // MetaPartition defines the interface for the meta partition operations.
@@ -204,9 +230,13 @@ type metaPartition struct {
 	size                   uint64 // For partition all file size
 	applyID                uint64 // Inode/Dentry max applyID, this index will be update after restoring from the dumped data.
 	dentryTree             *BTree
-	inodeTree              *BTree // btree for inodes
-	extendTree             *BTree // btree for inode extend (XAttr) management
-	multipartTree          *BTree // collection for multipart management
+	inodeTree              *BTree          // btree for inodes
+	extendTree             *BTree          // btree for inode extend (XAttr) management
+	multipartTree          *BTree          // collection for multipart management
+	txTree                 *BTree          // records of the metadata transactions
+	txLocks                txLockIndex     // index of the dentries and inodes locked by the prepared transactions
+	removeMarks            removeMarkIndex // index of the inodes marked for removal
+	locks                  *lockTable      // advisory file locks
 	raftPartition          raftstore.Partition
 	stopC                  chan bool
 	storeChan              chan *storeMsg
@@ -218,6 +248,9 @@ type metaPartition struct {
 	vol                    *Vol
 	manager                *metadataManager
 	isLoadingMetaPartition bool
+	trashSize              uint64     // total size of the soft-deleted files, updated by the vol worker
+	memUsed                uint64     // memory estimated to be taken by the items, updated by the vol worker
+	changelog              *changelog // latest events of the changes, nil if disabled
 }
 
 func (mp *metaPartition) ForceSetMetaPartitionToLoadding() {
@@ -282,7 +315,12 @@ func (mp *metaPartition) onStart() (err error) {
 			mp.config.PartitionId, err.Error())
 		return
 	}
+	mp.changelog.reset(mp.applyID)
 	mp.startSchedule(mp.applyID)
+	go mp.txResolveWorker()
+	go mp.removeWorker()
+	go mp.purgeTrashWorker()
+	go mp.dirSummaryWorker()
 	if err = mp.startFreeList(); err != nil {
 		err = errors.NewErrorf("[onStart] start free list id=%d: %s",
 			mp.config.PartitionId, err.Error())
@@ -380,6 +418,8 @@ func NewMetaPartition(conf *MetaPartitionConfig, manager *metadataManager) MetaP
 		inodeTree:     NewBtree(),
 		extendTree:    NewBtree(),
 		multipartTree: NewBtree(),
+		txTree:        NewBtree(),
+		locks:         newLockTable(),
 		stopC:         make(chan bool),
 		storeChan:     make(chan *storeMsg, 100),
 		freeList:      newFreeList(),
@@ -387,6 +427,7 @@ func NewMetaPartition(conf *MetaPartitionConfig, manager *metadataManager) MetaP
 		extReset:      make(chan struct{}),
 		vol:           NewVol(),
 		manager:       manager,
+		changelog:     newChangelog(changelogCapacity),
 	}
 	return mp
 }
@@ -446,6 +487,12 @@ func (mp *metaPartition) LoadSnapshot(snapshotPath string) (err error) {
 	if err = mp.loadMultipart(snapshotPath); err != nil {
 		return
 	}
+	if err = mp.loadTransaction(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadLocks(snapshotPath); err != nil {
+		return
+	}
 	err = mp.loadApplyID(snapshotPath)
 	return
 }
@@ -467,6 +514,12 @@ func (mp *metaPartition) load() (err error) {
 	if err = mp.loadMultipart(snapshotPath); err != nil {
 		return
 	}
+	if err = mp.loadTransaction(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadLocks(snapshotPath); err != nil {
+		return
+	}
 	err = mp.loadApplyID(snapshotPath)
 	return
 }
@@ -494,6 +547,8 @@ func (mp *metaPartition) store(sm *storeMsg) (err error) {
 		mp.storeDentry,
 		mp.storeExtend,
 		mp.storeMultipart,
+		mp.storeTransaction,
+		mp.storeLocks,
 	}
 	for _, storeFunc := range storeFuncs {
 		var crc uint32
@@ -658,7 +713,7 @@ func (mp *metaPartition) Reset() (err error) {
 	mp.applyID = 0
 
 	// remove files
-	filenames := []string{applyIDFile, dentryFile, inodeFile, extendFile, multipartFile}
+	filenames := []string{applyIDFile, dentryFile, inodeFile, extendFile, multipartFile, transactionFile}
 	for _, filename := range filenames {
 		filepath := path.Join(mp.config.RootDir, filename)
 		if err = os.Remove(filepath); err != nil {
@@ -0,0 +1,288 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// the number of the items imported by a raft proposal
+	importBatchItems = 1000
+)
+
+// ExportMeta writes the inodes, the dentries, the extended attributes and the multipart uploads of the partition to
+// w as a gzip stream of json lines, the header followed by the items in the format of the snapshot. The transactions
+// in progress and the extents to be deleted are not exported.
+func (mp *metaPartition) ExportMeta(w io.Writer) (export *proto.MetaPartitionExport, err error) {
+	applyID := atomic.LoadUint64(&mp.applyID)
+	inodeTree := mp.inodeTree.GetTree()
+	dentryTree := mp.dentryTree.GetTree()
+	extendTree := mp.extendTree.GetTree()
+	multipartTree := mp.multipartTree.GetTree()
+	export = &proto.MetaPartitionExport{
+		PartitionID: mp.config.PartitionId,
+		VolName:     mp.config.VolName,
+		Start:       mp.config.Start,
+		End:         mp.config.End,
+		Cursor:      mp.GetCursor(),
+		ApplyID:     applyID,
+		Inodes:      uint64(inodeTree.Len()),
+		Dentries:    uint64(dentryTree.Len()),
+		Extends:     uint64(extendTree.Len()),
+		Multiparts:  uint64(multipartTree.Len()),
+		CreateTime:  time.Now().Unix(),
+	}
+	gw := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gw)
+	if err = encoder.Encode(export); err != nil {
+		return
+	}
+	inodeTree.Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		err = encoder.Encode(NewMetaItem(opFSMCreateInode, ino.MarshalKey(), ino.MarshalValue()))
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+	dentryTree.Ascend(func(i BtreeItem) bool {
+		dentry := i.(*Dentry)
+		err = encoder.Encode(NewMetaItem(opFSMCreateDentry, dentry.MarshalKey(), dentry.MarshalValue()))
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+	extendTree.Ascend(func(i BtreeItem) bool {
+		var raw []byte
+		if raw, err = i.(*Extend).Bytes(); err != nil {
+			return false
+		}
+		err = encoder.Encode(NewMetaItem(opFSMSetXAttr, nil, raw))
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+	multipartTree.Ascend(func(i BtreeItem) bool {
+		var raw []byte
+		if raw, err = i.(*Multipart).Bytes(); err != nil {
+			return false
+		}
+		err = encoder.Encode(NewMetaItem(opFSMCreateMultipart, nil, raw))
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+	err = gw.Close()
+	return
+}
+
+// ImportMeta loads an export made by ExportMeta into the partition, which must be led by this node and hold nothing
+// but the root inode. The items are validated and proposed to raft in batches, so the followers load them too.
+func (mp *metaPartition) ImportMeta(r io.Reader) (imported *proto.MetaPartitionExport, err error) {
+	if _, ok := mp.IsLeader(); !ok {
+		return nil, ErrNoLeader
+	}
+	if err = mp.checkEmptyForImport(); err != nil {
+		return
+	}
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+	decoder := json.NewDecoder(gr)
+	header := &proto.MetaPartitionExport{}
+	if err = decoder.Decode(header); err != nil {
+		return nil, fmt.Errorf("decode the header: %v", err)
+	}
+	imported = &proto.MetaPartitionExport{
+		PartitionID: mp.config.PartitionId,
+		VolName:     mp.config.VolName,
+		Start:       mp.config.Start,
+		End:         mp.config.End,
+		CreateTime:  header.CreateTime,
+	}
+	batch := make([]*MetaItem, 0, importBatchItems)
+	for {
+		item := &MetaItem{}
+		if err = decoder.Decode(item); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("decode the item %v: %v", imported.Inodes+imported.Dentries+imported.Extends+imported.Multiparts, err)
+		}
+		if err = mp.validateImportItem(item, imported); err != nil {
+			return
+		}
+		if batch = append(batch, item); len(batch) >= importBatchItems {
+			if err = mp.submitImportItems(batch); err != nil {
+				return
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err = mp.submitImportItems(batch); err != nil {
+			return
+		}
+	}
+	if header.Cursor > mp.GetCursor() && header.Cursor <= mp.config.End {
+		cursor := make([]byte, 8)
+		binary.BigEndian.PutUint64(cursor, header.Cursor)
+		if _, err = mp.submit(opFSMSyncCursor, cursor); err != nil {
+			return
+		}
+	}
+	imported.Cursor = mp.GetCursor()
+	imported.ApplyID = atomic.LoadUint64(&mp.applyID)
+	if imported.Inodes != header.Inodes || imported.Dentries != header.Dentries ||
+		imported.Extends != header.Extends || imported.Multiparts != header.Multiparts {
+		err = fmt.Errorf("the export is incomplete, imported inodes(%v/%v) dentries(%v/%v) extends(%v/%v) multiparts(%v/%v)",
+			imported.Inodes, header.Inodes, imported.Dentries, header.Dentries, imported.Extends, header.Extends,
+			imported.Multiparts, header.Multiparts)
+		return
+	}
+	log.LogWarnf("action[ImportMeta] partition(%v) imported the export of partition(%v) range[%v,%v] inodes(%v) dentries(%v) extends(%v) multiparts(%v)",
+		mp.config.PartitionId, header.PartitionID, header.Start, header.End, imported.Inodes, imported.Dentries,
+		imported.Extends, imported.Multiparts)
+	return
+}
+
+// checkEmptyForImport refuses to import into a partition holding any metadata except the root inode.
+func (mp *metaPartition) checkEmptyForImport() error {
+	inodes := mp.inodeTree.Len()
+	if inodes == 1 && mp.inodeTree.Get(NewInode(proto.RootIno, 0)) != nil {
+		inodes = 0
+	}
+	if inodes > 0 || mp.dentryTree.Len() > 0 || mp.extendTree.Len() > 0 || mp.multipartTree.Len() > 0 {
+		return fmt.Errorf("partition(%v) is not empty, inodes(%v) dentries(%v) extends(%v) multiparts(%v)",
+			mp.config.PartitionId, mp.inodeTree.Len(), mp.dentryTree.Len(), mp.extendTree.Len(), mp.multipartTree.Len())
+	}
+	return nil
+}
+
+// validateImportItem checks the item can be applied by the followers, and that the inode it belongs to is in the
+// range of the partition.
+func (mp *metaPartition) validateImportItem(item *MetaItem, imported *proto.MetaPartitionExport) (err error) {
+	var ino uint64
+	switch item.Op {
+	case opFSMCreateInode:
+		inode := NewInode(0, 0)
+		if err = inode.UnmarshalKey(item.K); err != nil {
+			return
+		}
+		if err = inode.UnmarshalValue(item.V); err != nil {
+			return
+		}
+		ino = inode.Inode
+		imported.Inodes++
+	case opFSMCreateDentry:
+		dentry := &Dentry{}
+		if err = dentry.UnmarshalKey(item.K); err != nil {
+			return
+		}
+		if err = dentry.UnmarshalValue(item.V); err != nil {
+			return
+		}
+		ino = dentry.ParentId
+		imported.Dentries++
+	case opFSMSetXAttr:
+		var extend *Extend
+		if extend, err = NewExtendFromBytes(item.V); err != nil {
+			return
+		}
+		ino = extend.inode
+		imported.Extends++
+	case opFSMCreateMultipart:
+		MultipartFromBytes(item.V)
+		imported.Multiparts++
+		return
+	default:
+		return fmt.Errorf("unknown op(%v) of the item", item.Op)
+	}
+	if ino < mp.config.Start || ino > mp.config.End {
+		return fmt.Errorf("inode(%v) is out of the range[%v,%v] of partition(%v)", ino, mp.config.Start,
+			mp.config.End, mp.config.PartitionId)
+	}
+	return
+}
+
+func (mp *metaPartition) submitImportItems(items []*MetaItem) (err error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return
+	}
+	_, err = mp.submit(opFSMImportItems, data)
+	return
+}
+
+// fsmImportItems inserts the items imported, replacing the root inode created with the partition.
+func (mp *metaPartition) fsmImportItems(data []byte) (err error) {
+	items := make([]*MetaItem, 0)
+	if err = json.Unmarshal(data, &items); err != nil {
+		return
+	}
+	for _, item := range items {
+		switch item.Op {
+		case opFSMCreateInode:
+			ino := NewInode(0, 0)
+			if err = ino.UnmarshalKey(item.K); err != nil {
+				return
+			}
+			if err = ino.UnmarshalValue(item.V); err != nil {
+				return
+			}
+			if mp.config.Cursor < ino.Inode {
+				mp.config.Cursor = ino.Inode
+			}
+			mp.inodeTree.ReplaceOrInsert(ino, true)
+			mp.checkAndInsertFreeList(ino)
+		case opFSMCreateDentry:
+			dentry := &Dentry{}
+			if err = dentry.UnmarshalKey(item.K); err != nil {
+				return
+			}
+			if err = dentry.UnmarshalValue(item.V); err != nil {
+				return
+			}
+			mp.dentryTree.ReplaceOrInsert(dentry, true)
+		case opFSMSetXAttr:
+			var extend *Extend
+			if extend, err = NewExtendFromBytes(item.V); err != nil {
+				return
+			}
+			mp.extendTree.ReplaceOrInsert(extend, true)
+			mp.removeMarks.update(extend)
+		case opFSMCreateMultipart:
+			mp.multipartTree.ReplaceOrInsert(MultipartFromBytes(item.V), true)
+		default:
+			log.LogWarnf("action[fsmImportItems] partition(%v) skip unknown op(%v)", mp.config.PartitionId, item.Op)
+		}
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package metanode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newExportTestPartition(id uint64) *metaPartition {
+	return &metaPartition{
+		config:        &MetaPartitionConfig{PartitionId: id, VolName: "vol", Start: 1, End: 100},
+		inodeTree:     NewBtree(),
+		dentryTree:    NewBtree(),
+		extendTree:    NewBtree(),
+		multipartTree: NewBtree(),
+		freeList:      newFreeList(),
+	}
+}
+
+func TestExportImportItems(t *testing.T) {
+	src := newExportTestPartition(1)
+	src.config.Cursor = 20
+	dirMode := proto.Mode(os.ModeDir | 0755)
+	root := NewInode(proto.RootIno, dirMode)
+	root.NLink = 3
+	file := NewInode(10, proto.Mode(0644))
+	file.Size = 100
+	src.inodeTree.ReplaceOrInsert(root, true)
+	src.inodeTree.ReplaceOrInsert(file, true)
+	src.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "f", Inode: 10, Type: proto.Mode(0644)}, true)
+	extend := NewExtend(10)
+	extend.Put([]byte("user.k"), []byte("v"))
+	src.extendTree.ReplaceOrInsert(extend, true)
+
+	buf := &bytes.Buffer{}
+	export, err := src.ExportMeta(buf)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if export.Inodes != 2 || export.Dentries != 1 || export.Extends != 1 || export.Cursor != 20 {
+		t.Fatalf("export header: %+v", export)
+	}
+
+	dst := newExportTestPartition(2)
+	dst.inodeTree.ReplaceOrInsert(NewInode(proto.RootIno, dirMode), true)
+	if err = dst.checkEmptyForImport(); err != nil {
+		t.Fatalf("partition with the root inode only: %v", err)
+	}
+	gr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	decoder := json.NewDecoder(gr)
+	header := &proto.MetaPartitionExport{}
+	if err = decoder.Decode(header); err != nil || *header != *export {
+		t.Fatalf("decode header: header(%+v) err(%v)", header, err)
+	}
+	imported := &proto.MetaPartitionExport{}
+	items := make([]*MetaItem, 0)
+	for {
+		item := &MetaItem{}
+		if err = decoder.Decode(item); err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("decode item: %v", err)
+		}
+		if err = dst.validateImportItem(item, imported); err != nil {
+			t.Fatalf("validate item: %v", err)
+		}
+		items = append(items, item)
+	}
+	data, _ := json.Marshal(items)
+	if err = dst.fsmImportItems(data); err != nil {
+		t.Fatalf("import items: %v", err)
+	}
+	if imported.Inodes != 2 || imported.Dentries != 1 || imported.Extends != 1 {
+		t.Fatalf("imported: %+v", imported)
+	}
+	if dst.GetCursor() != 10 || dst.inodeTree.Get(NewInode(10, 0)).(*Inode).Size != 100 {
+		t.Fatalf("imported inodes: cursor(%v)", dst.GetCursor())
+	}
+	if root := dst.inodeTree.Get(NewInode(proto.RootIno, 0)).(*Inode); root.NLink != 3 {
+		t.Fatalf("root inode is not replaced: %+v", root)
+	}
+	if dst.dentryTree.Get(&Dentry{ParentId: 1, Name: "f"}) == nil {
+		t.Fatalf("dentry is not imported")
+	}
+	if value, _ := dst.extendTree.Get(NewExtend(10)).(*Extend).Get([]byte("user.k")); string(value) != "v" {
+		t.Fatalf("xattr is not imported: %v", value)
+	}
+	if err = dst.checkEmptyForImport(); err == nil {
+		t.Fatalf("import into a partition which is not empty")
+	}
+
+	outOfRange := newExportTestPartition(3)
+	outOfRange.config.Start, outOfRange.config.End = 50, 100
+	if err = outOfRange.validateImportItem(items[0], &proto.MetaPartitionExport{}); err == nil {
+		t.Fatalf("inode out of range is accepted")
+	}
+}
@@ -30,6 +30,7 @@ import (
 const (
 	AsyncDeleteInterval           = 10 * time.Second
 	UpdateVolTicket               = 2 * time.Minute
+	UpdateVolRetryInterval        = 5 * time.Second
 	BatchCounts                   = 128
 	OpenRWAppendOpt               = os.O_CREATE | os.O_RDWR | os.O_APPEND
 	TempFileValidTime             = 86400 //units: sec
@@ -61,6 +62,19 @@ func (mp *metaPartition) updateVolView(convert func(view *proto.DataPartitionsVi
 		return
 	}
 	mp.vol.UpdatePartitions(convert(dataView))
+	volView, err := masterClient.AdminAPI().GetVolumeSimpleInfo(volName)
+	if err != nil {
+		err = fmt.Errorf("updateVolWorker: get volume simple info fail: volume(%v) err(%v)",
+			volName, err)
+		log.LogError(err.Error())
+		return
+	}
+	mp.vol.updateWorm(volView)
+	mp.vol.updateTrash(volView)
+	mp.vol.updateAtime(volView)
+	mp.vol.updateFileAudit(volView)
+	mp.updateTrashSize()
+	mp.updateMemUsed()
 	return nil
 }
 
@@ -80,7 +94,16 @@ func (mp *metaPartition) updateVolWorker() {
 		}
 		return newView
 	}
-	mp.updateVolView(convert)
+	// the view is fetched again soon if it fails at first, since the files of the volume
+	// with retention can not be modified before the view is fetched
+	for mp.updateVolView(convert) != nil {
+		select {
+		case <-mp.stopC:
+			t.Stop()
+			return
+		case <-time.After(UpdateVolRetryInterval):
+		}
+	}
 	for {
 		select {
 		case <-mp.stopC:
@@ -45,6 +45,8 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 	if err = msg.UnmarshalJson(command); err != nil {
 		return
 	}
+	mp.changelog.begin(index)
+	defer mp.changelog.end()
 
 	switch msg.Op {
 	case opFSMCreateInode:
@@ -74,6 +76,12 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		resp = mp.fsmExtentsTruncate(ino)
+	case opFSMExtentPunch:
+		op := &ExtentPunchOp{}
+		if err = op.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmExtentsPunch(op)
 	case opFSMCreateLinkInode:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
@@ -140,6 +148,8 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		dentryTree := mp.getDentryTree()
 		extendTree := mp.extendTree.GetTree()
 		multipartTree := mp.multipartTree.GetTree()
+		txTree := mp.txTree.GetTree()
+		locks := mp.locks.records()
 		msg := &storeMsg{
 			command:       opFSMStoreTick,
 			applyIndex:    index,
@@ -147,6 +157,8 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			dentryTree:    dentryTree,
 			extendTree:    extendTree,
 			multipartTree: multipartTree,
+			txTree:        txTree,
+			locks:         locks,
 		}
 		mp.storeChan <- msg
 	case opFSMInternalDeleteInode:
@@ -169,6 +181,37 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		err = mp.fsmRemoveXAttr(extend)
+	case opFSMSetLock:
+		op := &LockOp{}
+		if err = op.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmSetLock(op)
+	case opFSMRenewLock:
+		op := &LockOp{}
+		if err = op.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmRenewLock(op)
+	case opFSMTxPrepare:
+		var tx *Transaction
+		if tx, err = TransactionFromBytes(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmTxPrepare(tx)
+	case opFSMTxCommit, opFSMTxAbort, opFSMTxForget:
+		op := &TxOp{}
+		if err = op.Unmarshal(msg.V); err != nil {
+			return
+		}
+		switch msg.Op {
+		case opFSMTxCommit:
+			resp = mp.fsmTxCommit(op)
+		case opFSMTxAbort:
+			resp = mp.fsmTxAbort(op)
+		default:
+			resp = mp.fsmTxForget(op)
+		}
 	case opFSMCreateMultipart:
 		var multipart *Multipart
 		multipart = MultipartFromBytes(msg.V)
@@ -187,6 +230,10 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		if cursor > mp.config.Cursor {
 			mp.config.Cursor = cursor
 		}
+	case opFSMImportItems:
+		if err = mp.fsmImportItems(msg.V); err == nil {
+			mp.changelog.reset(index)
+		}
 	}
 
 	return
@@ -249,6 +296,8 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 		dentryTree    = NewBtree()
 		extendTree    = NewBtree()
 		multipartTree = NewBtree()
+		txTree        = NewBtree()
+		locks         = newLockTable()
 	)
 	defer func() {
 		if err == io.EOF {
@@ -257,7 +306,12 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			mp.dentryTree = dentryTree
 			mp.extendTree = extendTree
 			mp.multipartTree = multipartTree
+			mp.txTree = txTree
+			mp.txLocks = newTxLockIndex(txTree)
+			mp.removeMarks.reset(extendTree)
+			mp.locks = locks
 			mp.config.Cursor = cursor
+			mp.changelog.reset(appIndexID)
 			err = nil
 			// store message
 			mp.storeChan <- &storeMsg{
@@ -267,6 +321,8 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 				dentryTree:    mp.dentryTree,
 				extendTree:    mp.extendTree,
 				multipartTree: mp.multipartTree,
+				txTree:        mp.txTree,
+				locks:         mp.locks.records(),
 			}
 			mp.extReset <- struct{}{}
 			log.LogDebugf("ApplySnapshot: finish with EOF: partitionID(%v) applyID(%v)", mp.config.PartitionId, mp.applyID)
@@ -323,6 +379,20 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			var multipart = MultipartFromBytes(snap.V)
 			multipartTree.ReplaceOrInsert(multipart, true)
 			log.LogDebugf("ApplySnapshot: create multipart: partitionID(%v) multipart(%v)", mp.config.PartitionId, multipart)
+		case opFSMTxPrepare:
+			var tx *Transaction
+			if tx, err = TransactionFromBytes(snap.V); err != nil {
+				return
+			}
+			txTree.ReplaceOrInsert(tx, true)
+			log.LogDebugf("ApplySnapshot: create transaction: partitionID(%v) tx(%v)", mp.config.PartitionId, tx)
+		case opFSMSetLock:
+			var records []*LockRecord
+			if err = json.Unmarshal(snap.V, &records); err != nil {
+				return
+			}
+			locks = lockTableFromRecords(records)
+			log.LogDebugf("ApplySnapshot: restore locks: partitionID(%v) numLocks(%v)", mp.config.PartitionId, len(records))
 		case opExtentFileSnapshot:
 			fileName := string(snap.K)
 			fileName = path.Join(mp.config.RootDir, fileName)
@@ -37,6 +37,10 @@ func NewDentryResponse() *DentryResponse {
 func (mp *metaPartition) fsmCreateDentry(dentry *Dentry,
 	forceUpdate bool) (status uint8) {
 	status = proto.OpOk
+	if !forceUpdate && mp.txLocked(dentry.ParentId, dentry.Name) {
+		status = proto.OpAgain
+		return
+	}
 	item := mp.inodeTree.CopyGet(NewInode(dentry.ParentId, 0))
 	var parIno *Inode
 	if !forceUpdate {
@@ -73,6 +77,7 @@ func (mp *metaPartition) fsmCreateDentry(dentry *Dentry,
 			parIno.IncNLink()
 			parIno.SetMtime()
 		}
+		mp.changelog.record(proto.ChangeCreate, dentry.Inode, dentry.ParentId, dentry.Name, dentry.Type)
 	}
 
 	return
@@ -95,6 +100,10 @@ func (mp *metaPartition) fsmDeleteDentry(dentry *Dentry, checkInode bool) (
 	resp *DentryResponse) {
 	resp = NewDentryResponse()
 	resp.Status = proto.OpOk
+	if mp.txLocked(dentry.ParentId, dentry.Name) {
+		resp.Status = proto.OpAgain
+		return
+	}
 
 	var item interface{}
 	if checkInode {
@@ -128,6 +137,7 @@ func (mp *metaPartition) fsmDeleteDentry(dentry *Dentry, checkInode bool) (
 			})
 	}
 	resp.Msg = item.(*Dentry)
+	mp.changelog.record(proto.ChangeUnlink, resp.Msg.Inode, resp.Msg.ParentId, resp.Msg.Name, resp.Msg.Type)
 	return
 }
 
@@ -144,6 +154,10 @@ func (mp *metaPartition) fsmUpdateDentry(dentry *Dentry) (
 	resp *DentryResponse) {
 	resp = NewDentryResponse()
 	resp.Status = proto.OpOk
+	if mp.txLocked(dentry.ParentId, dentry.Name) {
+		resp.Status = proto.OpAgain
+		return
+	}
 	mp.dentryTree.CopyFind(dentry, func(item BtreeItem) {
 		if item == nil {
 			resp.Status = proto.OpNotExistErr
@@ -152,6 +166,7 @@ func (mp *metaPartition) fsmUpdateDentry(dentry *Dentry) (
 		d := item.(*Dentry)
 		d.Inode, dentry.Inode = dentry.Inode, d.Inode
 		resp.Msg = dentry
+		mp.changelog.record(proto.ChangeReplace, d.Inode, d.ParentId, d.Name, d.Type)
 	})
 	return
 }
@@ -164,12 +179,17 @@ func (mp *metaPartition) readDir(req *ReadDirReq) (resp *ReadDirResp) {
 	resp = &ReadDirResp{}
 	begDentry := &Dentry{
 		ParentId: req.ParentID,
+		Name:     req.Marker,
 	}
 	endDentry := &Dentry{
 		ParentId: req.ParentID + 1,
 	}
 	mp.dentryTree.AscendRange(begDentry, endDentry, func(i BtreeItem) bool {
 		d := i.(*Dentry)
+		if req.Limit > 0 && uint64(len(resp.Children)) >= req.Limit {
+			resp.NextMarker = d.Name
+			return false
+		}
 		resp.Children = append(resp.Children, proto.Dentry{
 			Inode: d.Inode,
 			Type:  d.Type,
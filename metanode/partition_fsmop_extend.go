@@ -14,6 +14,8 @@
 
 package metanode
 
+import "github.com/chubaofs/chubaofs/proto"
+
 type ExtendOpResult struct {
 	Status uint8
 	Extend *Extend
@@ -29,6 +31,8 @@ func (mp *metaPartition) fsmSetXAttr(extend *Extend) (err error) {
 		e = treeItem.(*Extend)
 	}
 	e.Merge(extend, true)
+	mp.removeMarks.update(e)
+	mp.changelog.recordXAttrs(proto.ChangeSetXAttr, extend)
 	return
 }
 
@@ -42,5 +46,7 @@ func (mp *metaPartition) fsmRemoveXAttr(extend *Extend) (err error) {
 		e.Remove(key)
 		return true
 	})
+	mp.removeMarks.update(e)
+	mp.changelog.recordXAttrs(proto.ChangeRemoveXAttr, extend)
 	return
 }
@@ -76,8 +76,12 @@ func (mp *metaPartition) getInode(ino *Inode) (resp *InodeResponse) {
 	/*
 	 * FIXME: not protected by lock yet, since nothing is depending on atime.
 	 * Shall add inode lock in the future.
+	 * The access time of the volume tracking it is updated by the clients with
+	 * the relatime policy, except for the unlinked inodes which are delayed to delete.
 	 */
-	i.AccessTime = Now.GetCurrentTime().Unix()
+	if mp.vol == nil || !mp.vol.atimeEnabled() || i.NLink == 0 {
+		i.AccessTime = Now.GetCurrentTime().Unix()
+	}
 	resp.Msg = i
 	return
 }
@@ -110,6 +114,10 @@ func (mp *metaPartition) Ascend(f func(i BtreeItem) bool) {
 func (mp *metaPartition) fsmUnlinkInode(ino *Inode) (resp *InodeResponse) {
 	resp = NewInodeResponse()
 	resp.Status = proto.OpOk
+	if mp.txInodeLocked(ino.Inode) {
+		resp.Status = proto.OpAgain
+		return
+	}
 	item := mp.inodeTree.CopyGet(ino)
 	if item == nil {
 		resp.Status = proto.OpNotExistErr
@@ -197,6 +205,7 @@ func (mp *metaPartition) internalDeleteInode(ino *Inode) {
 	mp.inodeTree.Delete(ino)
 	mp.freeList.Remove(ino.Inode)
 	mp.extendTree.Delete(&Extend{inode: ino.Inode}) // Also delete extend attribute.
+	mp.removeMarks.remove(ino.Inode)
 	return
 }
 
@@ -216,6 +225,7 @@ func (mp *metaPartition) fsmAppendExtents(ino *Inode) (status uint8) {
 	delExtents := ino2.AppendExtents(eks, ino.ModifyTime)
 	log.LogInfof("fsmAppendExtents inode(%v) exts(%v)", ino2.Inode, delExtents)
 	mp.extDelCh <- delExtents
+	mp.changelog.record(proto.ChangeWrite, ino2.Inode, 0, "", 0)
 	return
 }
 
@@ -243,6 +253,34 @@ func (mp *metaPartition) fsmExtentsTruncate(ino *Inode) (resp *InodeResponse) {
 	// now we should delete the extent
 	log.LogInfof("fsmExtentsTruncate inode(%v) exts(%v)", i.Inode, delExtents)
 	mp.extDelCh <- delExtents
+	mp.changelog.record(proto.ChangeTruncate, i.Inode, 0, "", 0)
+	return
+}
+
+func (mp *metaPartition) fsmExtentsPunch(op *ExtentPunchOp) (resp *InodeResponse) {
+	resp = NewInodeResponse()
+
+	resp.Status = proto.OpOk
+	item := mp.inodeTree.CopyGet(NewInode(op.Inode, 0))
+	if item == nil {
+		resp.Status = proto.OpNotExistErr
+		return
+	}
+	i := item.(*Inode)
+	if i.ShouldDelete() {
+		resp.Status = proto.OpNotExistErr
+		return
+	}
+	if proto.IsDir(i.Type) {
+		resp.Status = proto.OpArgMismatchErr
+		return
+	}
+
+	delExtents := i.ExtentsPunch(op.Offset, op.Size, op.ModifyTime)
+
+	log.LogInfof("fsmExtentsPunch inode(%v) offset(%v) size(%v) exts(%v)", i.Inode, op.Offset, op.Size, delExtents)
+	mp.extDelCh <- delExtents
+	mp.changelog.record(proto.ChangeWrite, i.Inode, 0, "", 0)
 	return
 }
 
@@ -250,6 +288,10 @@ func (mp *metaPartition) fsmEvictInode(ino *Inode) (resp *InodeResponse) {
 	resp = NewInodeResponse()
 
 	resp.Status = proto.OpOk
+	if mp.txInodeLocked(ino.Inode) {
+		resp.Status = proto.OpAgain
+		return
+	}
 	item := mp.inodeTree.CopyGet(ino)
 	if item == nil {
 		resp.Status = proto.OpNotExistErr
@@ -262,6 +304,7 @@ func (mp *metaPartition) fsmEvictInode(ino *Inode) (resp *InodeResponse) {
 	if proto.IsDir(i.Type) {
 		if i.IsEmptyDir() {
 			i.SetDeleteMark()
+			mp.changelog.record(proto.ChangeEvict, i.Inode, 0, "", 0)
 		}
 		return
 	}
@@ -269,6 +312,7 @@ func (mp *metaPartition) fsmEvictInode(ino *Inode) (resp *InodeResponse) {
 	if i.IsTempFile() {
 		i.SetDeleteMark()
 		mp.freeList.Push(i.Inode)
+		mp.changelog.record(proto.ChangeEvict, i.Inode, 0, "", 0)
 	}
 	return
 }
@@ -303,5 +347,9 @@ func (mp *metaPartition) fsmSetAttr(req *SetattrRequest) (err error) {
 		return
 	}
 	ino.SetAttr(req)
+	// the access times are set on reads, which are not the changes
+	if req.Valid&^(proto.AttrAccessTime|proto.AttrRelatime) != 0 {
+		mp.changelog.record(proto.ChangeSetAttr, ino.Inode, 0, "", 0)
+	}
 	return
 }
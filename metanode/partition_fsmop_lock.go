@@ -0,0 +1,31 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+var fileLockLeaseSeconds = int64(proto.FileLockLease.Seconds())
+
+// fsmSetLock returns the conflicting lock, or nil if the lock is acquired or released.
+func (mp *metaPartition) fsmSetLock(op *LockOp) *proto.FileLock {
+	return mp.locks.set(op.Inode, &op.Lock, op.Now, fileLockLeaseSeconds)
+}
+
+// fsmRenewLock returns the number of the locks held by the session.
+func (mp *metaPartition) fsmRenewLock(op *LockOp) int {
+	return mp.locks.renew(op.Session, op.Now, fileLockLeaseSeconds)
+}
@@ -0,0 +1,265 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// fsmTxPrepare validates the items of the transaction and locks them, together with the parent inodes
+// of the dentries. Nothing the items depend on is changed by the other operations before the transaction
+// is finished, so the items validated here can be applied when the transaction is committed. A prepare
+// which is retried, or refused by the recorded outcome, replies the state of the recorded transaction.
+func (mp *metaPartition) fsmTxPrepare(tx *Transaction) (resp *TxResponse) {
+	resp = &TxResponse{Status: proto.OpOk}
+	if stored := mp.getTransaction(tx.TxID); stored != nil {
+		resp.State = stored.State
+		resp.Items = stored.Items
+		return
+	}
+	for _, item := range tx.Items {
+		if (item.IsDentryOp() && (mp.txLocked(item.ParentID, item.Name) || mp.txInodeItemLocked(item.ParentID))) ||
+			(!item.IsDentryOp() && mp.txInodeLocked(item.Inode)) {
+			resp.Status = proto.OpAgain
+			return
+		}
+		if resp.Status = mp.prepareTxItem(item); resp.Status != proto.OpOk {
+			return
+		}
+	}
+	tx.State = proto.TxStatePrepared
+	mp.txTree.ReplaceOrInsert(tx, true)
+	mp.txLocks.add(tx)
+	resp.State = tx.State
+	resp.Items = tx.Items
+	return
+}
+
+func (mp *metaPartition) prepareTxItem(item *proto.TxItem) (status uint8) {
+	if !item.IsDentryOp() {
+		return mp.prepareTxInodeItem(item)
+	}
+	dentry, status := mp.getDentry(&Dentry{ParentId: item.ParentID, Name: item.Name})
+	switch item.Op {
+	case proto.TxOpDeleteDentry:
+		if status != proto.OpOk || dentry.Inode != item.Inode {
+			return proto.OpNotExistErr
+		}
+		item.Type = dentry.Type
+		return proto.OpOk
+	case proto.TxOpCreateDentry:
+		parent := mp.inodeTree.Get(NewInode(item.ParentID, 0))
+		if parent == nil || parent.(*Inode).ShouldDelete() {
+			return proto.OpNotExistErr
+		}
+		if !proto.IsDir(parent.(*Inode).Type) {
+			return proto.OpArgMismatchErr
+		}
+		if status != proto.OpOk {
+			return proto.OpOk
+		}
+		if !item.Overwrite {
+			return proto.OpExistErr
+		}
+		if proto.OsModeType(dentry.Type) != proto.OsModeType(item.Type) {
+			return proto.OpArgMismatchErr
+		}
+		item.OldInode = dentry.Inode
+		return proto.OpOk
+	default:
+		return proto.OpArgMismatchErr
+	}
+}
+
+func (mp *metaPartition) prepareTxInodeItem(item *proto.TxItem) (status uint8) {
+	switch item.Op {
+	case proto.TxOpLinkInode, proto.TxOpUnlinkInode, proto.TxOpSetXAttr, proto.TxOpRemoveXAttr:
+	default:
+		return proto.OpArgMismatchErr
+	}
+	ino := mp.inodeTree.Get(NewInode(item.Inode, 0))
+	if ino == nil || ino.(*Inode).ShouldDelete() {
+		return proto.OpNotExistErr
+	}
+	return proto.OpOk
+}
+
+// fsmTxCommit applies the items of the prepared transaction. The items are checked before any of them
+// is applied, and none is applied if any can not be. The primary aborts the transaction in that case,
+// while the other partitions can not change the decision of the primary, which never happens as the
+// items are locked since they are prepared. The transaction unknown to the primary is never prepared,
+// so it is recorded as aborted, while the one unknown to the other partitions is already finished.
+func (mp *metaPartition) fsmTxCommit(op *TxOp) (resp *TxResponse) {
+	resp = &TxResponse{Status: proto.OpOk}
+	tx := mp.getTransaction(op.TxID)
+	if tx == nil {
+		resp.State = mp.finishUnknownTx(op, proto.TxStateCommitted)
+		return
+	}
+	resp.State = tx.State
+	resp.Items = tx.Items
+	if tx.State != proto.TxStatePrepared {
+		return
+	}
+	for _, item := range tx.Items {
+		if status := mp.checkTxItem(item); status != proto.OpOk {
+			log.LogErrorf("fsmTxCommit: mp(%v) tx(%v) item(%v) can not be applied, status(%v)",
+				mp.config.PartitionId, tx, item, status)
+			if tx.PrimaryID == mp.config.PartitionId {
+				mp.finishTx(tx, proto.TxStateAborted, op.Now)
+				resp.State = proto.TxStateAborted
+				return
+			}
+			mp.finishTx(tx, proto.TxStateCommitted, op.Now)
+			resp.Status = status
+			resp.State = proto.TxStateCommitted
+			return
+		}
+	}
+	// unlock the items before they are applied
+	mp.finishTx(tx, proto.TxStateCommitted, op.Now)
+	mp.changelog.setTx(tx.TxID)
+	for _, item := range tx.Items {
+		dentry := &Dentry{
+			ParentId: item.ParentID,
+			Name:     item.Name,
+			Inode:    item.Inode,
+			Type:     item.Type,
+		}
+		var status uint8
+		switch item.Op {
+		case proto.TxOpCreateDentry:
+			if item.OldInode == 0 {
+				status = mp.fsmCreateDentry(dentry, false)
+			} else {
+				status = mp.fsmUpdateDentry(dentry).Status
+			}
+		case proto.TxOpDeleteDentry:
+			status = mp.fsmDeleteDentry(dentry, true).Status
+		case proto.TxOpLinkInode:
+			status = mp.fsmCreateLinkInode(NewInode(item.Inode, 0)).Status
+		case proto.TxOpUnlinkInode:
+			status = mp.fsmUnlinkInode(NewInode(item.Inode, 0)).Status
+		case proto.TxOpSetXAttr:
+			extend := NewExtend(item.Inode)
+			extend.Put([]byte(item.Key), []byte(item.Value))
+			_ = mp.fsmSetXAttr(extend)
+		case proto.TxOpRemoveXAttr:
+			extend := NewExtend(item.Inode)
+			extend.Put([]byte(item.Key), nil)
+			_ = mp.fsmRemoveXAttr(extend)
+		}
+		if status != proto.OpOk {
+			log.LogErrorf("fsmTxCommit: mp(%v) tx(%v) item(%v) status(%v)", mp.config.PartitionId, tx, item, status)
+		}
+	}
+	resp.State = proto.TxStateCommitted
+	return
+}
+
+// checkTxItem returns if the prepared item can be applied.
+func (mp *metaPartition) checkTxItem(item *proto.TxItem) (status uint8) {
+	switch item.Op {
+	case proto.TxOpCreateDentry:
+		parent := mp.inodeTree.Get(NewInode(item.ParentID, 0))
+		if parent == nil || parent.(*Inode).ShouldDelete() || !proto.IsDir(parent.(*Inode).Type) {
+			return proto.OpNotExistErr
+		}
+		dentry, status := mp.getDentry(&Dentry{ParentId: item.ParentID, Name: item.Name})
+		if item.OldInode == 0 {
+			if status == proto.OpOk && dentry.Inode != item.Inode {
+				return proto.OpExistErr
+			}
+			return proto.OpOk
+		}
+		if status != proto.OpOk || dentry.Inode != item.OldInode {
+			return proto.OpNotExistErr
+		}
+	case proto.TxOpDeleteDentry:
+		dentry, status := mp.getDentry(&Dentry{ParentId: item.ParentID, Name: item.Name})
+		if status != proto.OpOk || dentry.Inode != item.Inode {
+			return proto.OpNotExistErr
+		}
+	case proto.TxOpLinkInode, proto.TxOpUnlinkInode:
+		ino := mp.inodeTree.Get(NewInode(item.Inode, 0))
+		if ino == nil || ino.(*Inode).ShouldDelete() {
+			return proto.OpNotExistErr
+		}
+	}
+	return proto.OpOk
+}
+
+// fsmTxAbort unlocks the items of the prepared transaction. The committed transaction is not aborted.
+func (mp *metaPartition) fsmTxAbort(op *TxOp) (resp *TxResponse) {
+	resp = &TxResponse{Status: proto.OpOk}
+	tx := mp.getTransaction(op.TxID)
+	if tx == nil {
+		resp.State = mp.finishUnknownTx(op, proto.TxStateAborted)
+		return
+	}
+	resp.State = tx.State
+	resp.Items = tx.Items
+	if tx.State == proto.TxStatePrepared {
+		mp.finishTx(tx, proto.TxStateAborted, op.Now)
+		resp.State = proto.TxStateAborted
+	}
+	return
+}
+
+// fsmTxForget removes the outcome of the transaction, which is known by all of its partitions.
+func (mp *metaPartition) fsmTxForget(op *TxOp) (resp *TxResponse) {
+	resp = &TxResponse{Status: proto.OpOk}
+	tx := mp.getTransaction(op.TxID)
+	if tx == nil {
+		return
+	}
+	resp.State = tx.State
+	if tx.State == proto.TxStatePrepared {
+		resp.Status = proto.OpArgMismatchErr
+		return
+	}
+	mp.txTree.Delete(tx)
+	return
+}
+
+// finishTx records the outcome of the transaction in the primary, and drops it in the other partitions.
+func (mp *metaPartition) finishTx(tx *Transaction, state uint8, now int64) {
+	mp.txLocks.remove(tx)
+	if tx.PrimaryID != mp.config.PartitionId {
+		mp.txTree.Delete(tx)
+		return
+	}
+	finished := tx.Copy().(*Transaction)
+	finished.State = state
+	finished.Time = now
+	mp.txTree.ReplaceOrInsert(finished, true)
+}
+
+// finishUnknownTx records the transaction unknown to the primary as aborted, so that the transaction
+// prepared later is refused. The other partitions reply the outcome they are told.
+func (mp *metaPartition) finishUnknownTx(op *TxOp, state uint8) uint8 {
+	if op.PrimaryID != mp.config.PartitionId {
+		return state
+	}
+	mp.txTree.ReplaceOrInsert(&Transaction{
+		TxID:         op.TxID,
+		PrimaryID:    op.PrimaryID,
+		Participants: op.Participants,
+		State:        proto.TxStateAborted,
+		Time:         op.Now,
+	}, true)
+	return proto.TxStateAborted
+}
@@ -129,6 +129,8 @@ type MetaItemIterator struct {
 	dentryTree    *BTree
 	extendTree    *BTree
 	multipartTree *BTree
+	txTree        *BTree
+	locks         []*LockRecord
 
 	filenames []string
 
@@ -148,6 +150,8 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 	si.dentryTree = mp.dentryTree.GetTree()
 	si.extendTree = mp.extendTree.GetTree()
 	si.multipartTree = mp.multipartTree.GetTree()
+	si.txTree = mp.txTree.GetTree()
+	si.locks = mp.locks.records()
 	si.dataCh = make(chan interface{})
 	si.errorCh = make(chan error, 1)
 	si.closeCh = make(chan struct{})
@@ -228,6 +232,17 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 		if checkClose() {
 			return
 		}
+		// process transactions
+		iter.txTree.Ascend(func(i BtreeItem) bool {
+			return produceItem(i)
+		})
+		if checkClose() {
+			return
+		}
+		// process locks
+		if !produceItem(iter.locks) {
+			return
+		}
 		// process extent del files
 		var err error
 		var raw []byte
@@ -309,6 +324,22 @@ func (si *MetaItemIterator) Next() (data []byte, err error) {
 			return
 		}
 		snap = NewMetaItem(opFSMCreateMultipart, nil, raw)
+	case *Transaction:
+		var raw []byte
+		if raw, err = typedItem.Bytes(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMTxPrepare, nil, raw)
+	case []*LockRecord:
+		var raw []byte
+		if raw, err = json.Marshal(typedItem); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMSetLock, nil, raw)
 	case *fileData:
 		snap = NewMetaItem(opExtentFileSnapshot, []byte(typedItem.filename), typedItem.data)
 	default:
@@ -28,6 +28,9 @@ func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err erro
 		p.PacketErrorWithBody(proto.OpExistErr, []byte(err.Error()))
 		return
 	}
+	if err = mp.checkWormByDentry(req.ParentID, req.Name, "UpdateDentry", p); err != nil {
+		return
+	}
 
 	dentry := &Dentry{
 		ParentId: req.ParentID,
@@ -50,6 +53,9 @@ func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err erro
 
 // DeleteDentry deletes a dentry.
 func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err error) {
+	if err = mp.checkWormByDentry(req.ParentID, req.Name, "DeleteDentry", p); err != nil {
+		return
+	}
 	dentry := &Dentry{
 		ParentId: req.ParentID,
 		Name:     req.Name,
@@ -80,6 +86,11 @@ func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err erro
 
 // DeleteDentry deletes a dentry.
 func (mp *metaPartition) DeleteDentryBatch(req *BatchDeleteDentryReq, p *Packet) (err error) {
+	for _, d := range req.Dens {
+		if err = mp.checkWormByDentry(req.ParentID, d.Name, "DeleteDentryBatch", p); err != nil {
+			return
+		}
+	}
 
 	db := make(DentryBatch, 0, len(req.Dens))
 
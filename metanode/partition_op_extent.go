@@ -23,6 +23,12 @@ import (
 
 // ExtentAppend appends an extent.
 func (mp *metaPartition) ExtentAppend(req *proto.AppendExtentKeyRequest, p *Packet) (err error) {
+	if err = mp.checkWormByID(req.Inode, "ExtentAppend", overwrites(req.Extent), p); err != nil {
+		return
+	}
+	if err = mp.sealAppended(req.Inode, []proto.ExtentKey{req.Extent}, p); err != nil {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	ext := req.Extent
 	ino.Extents.Append(ext)
@@ -71,6 +77,9 @@ func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (e
 
 // ExtentsTruncate truncates an extent.
 func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error) {
+	if err = mp.checkWormByID(req.Inode, "ExtentsTruncate", resizes(req.Size), p); err != nil {
+		return
+	}
 	ino := NewInode(req.Inode, proto.Mode(os.ModePerm))
 	ino.Size = req.Size
 	val, err := ino.Marshal()
@@ -88,7 +97,57 @@ func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (er
 	return
 }
 
+// ExtentPunchOp defines the raft command to punch a hole in the extents of an inode.
+type ExtentPunchOp struct {
+	Inode      uint64 `json:"ino"`
+	Offset     uint64 `json:"off"`
+	Size       uint64 `json:"sz"`
+	ModifyTime int64  `json:"mt"`
+}
+
+// Marshal marshals the punch operation into bytes.
+func (op *ExtentPunchOp) Marshal() ([]byte, error) {
+	return json.Marshal(op)
+}
+
+// Unmarshal unmarshals the punch operation from bytes.
+func (op *ExtentPunchOp) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, op)
+}
+
+// ExtentsPunch removes a file range from the extents, the removed data reads as zeros.
+func (mp *metaPartition) ExtentsPunch(req *proto.PunchHoleRequest, p *Packet) (err error) {
+	if err = mp.checkWormByID(req.Inode, "ExtentsPunch", punches(req.Offset), p); err != nil {
+		return
+	}
+	op := &ExtentPunchOp{
+		Inode:      req.Inode,
+		Offset:     req.Offset,
+		Size:       req.Size,
+		ModifyTime: Now.GetCurrentTime().Unix(),
+	}
+	val, err := op.Marshal()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMExtentPunch, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	msg := resp.(*InodeResponse)
+	p.PacketErrorWithBody(msg.Status, nil)
+	return
+}
+
 func (mp *metaPartition) BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error) {
+	if err = mp.checkWormByID(req.Inode, "BatchExtentAppend", overwrites(req.Extents...), p); err != nil {
+		return
+	}
+	if err = mp.sealAppended(req.Inode, req.Extents, p); err != nil {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	extents := req.Extents
 	for _, extent := range extents {
@@ -17,6 +17,7 @@ package metanode
 import (
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
@@ -42,6 +43,7 @@ func replyInfo(info *proto.InodeInfo, ino *Inode) bool {
 	info.CreateTime = time.Unix(ino.CreateTime, 0)
 	info.AccessTime = time.Unix(ino.AccessTime, 0)
 	info.ModifyTime = time.Unix(ino.ModifyTime, 0)
+	info.Flags = uint32(ino.Flag) & proto.InodeFlagsMask
 	return true
 }
 
@@ -89,6 +91,9 @@ func (mp *metaPartition) CreateInode(req *CreateInoReq, p *Packet) (err error) {
 
 // DeleteInode deletes an inode.
 func (mp *metaPartition) UnlinkInode(req *UnlinkInoReq, p *Packet) (err error) {
+	if err = mp.checkWormByID(req.Inode, "UnlinkInode", nil, p); err != nil {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	val, err := ino.Marshal()
 	if err != nil {
@@ -119,6 +124,11 @@ func (mp *metaPartition) UnlinkInode(req *UnlinkInoReq, p *Packet) (err error) {
 
 // DeleteInode deletes an inode.
 func (mp *metaPartition) UnlinkInodeBatch(req *BatchUnlinkInoReq, p *Packet) (err error) {
+	for _, id := range req.Inodes {
+		if err = mp.checkWormByID(id, "UnlinkInodeBatch", nil, p); err != nil {
+			return
+		}
+	}
 
 	if len(req.Inodes) == 0 {
 		return nil
@@ -219,6 +229,11 @@ func (mp *metaPartition) InodeGetBatch(req *InodeGetReqBatch, p *Packet) (err er
 
 // CreateInodeLink creates an inode link (e.g., soft link).
 func (mp *metaPartition) CreateInodeLink(req *LinkInodeReq, p *Packet) (err error) {
+	if item := mp.inodeTree.Get(NewInode(req.Inode, 0)); item != nil {
+		if err = mp.checkInodeFlags(item.(*Inode), "CreateInodeLink", nil, p); err != nil {
+			return
+		}
+	}
 	ino := NewInode(req.Inode, 0)
 	val, err := ino.Marshal()
 	if err != nil {
@@ -306,11 +321,27 @@ func (mp *metaPartition) EvictInodeBatch(req *BatchEvictInodeReq, p *Packet) (er
 
 // SetAttr set the inode attributes.
 func (mp *metaPartition) SetAttr(reqData []byte, p *Packet) (err error) {
+	req := &SetattrRequest{}
+	if err = json.Unmarshal(reqData, req); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	if mp.relatimeNotDue(req) {
+		p.PacketOkReply()
+		return
+	}
+	if err = mp.checkSetAttr(req, p); err != nil {
+		return
+	}
+	if err = mp.sealForFlags(req, p); err != nil {
+		return
+	}
 	_, err = mp.submit(opFSMSetAttr, reqData)
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
 		return
 	}
+	mp.resealForFlags(req)
 	p.PacketOkReply()
 	return
 }
@@ -320,6 +351,34 @@ func (mp *metaPartition) GetInodeTree() *BTree {
 	return mp.inodeTree.GetTree()
 }
 
+// ScanTierCandidates walks the inode tree and counts the regular files that
+// have not been accessed within the inactive period of the tier rule.
+func (mp *metaPartition) ScanTierCandidates(req *proto.TierScanRequest) (resp *proto.TierScanResponse) {
+	resp = &proto.TierScanResponse{
+		PartitionID: req.PartitionID,
+		VolName:     req.VolName,
+		RuleName:    req.RuleName,
+		Status:      proto.TaskSucceeds,
+	}
+	if req.InactiveSec <= 0 {
+		resp.Status = proto.TaskFailed
+		resp.Result = fmt.Sprintf("invalid inactive seconds[%v]", req.InactiveSec)
+		return
+	}
+	deadline := Now.GetCurrentTime().Unix() - req.InactiveSec
+	mp.GetInodeTree().Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		ino.RLock()
+		if ino.Flag&DeleteMarkFlag == 0 && proto.IsRegular(ino.Type) && ino.AccessTime < deadline {
+			resp.CandidateInodes++
+			resp.CandidateBytes += ino.Size
+		}
+		ino.RUnlock()
+		return true
+	})
+	return
+}
+
 func (mp *metaPartition) DeleteInode(req *proto.DeleteInodeRequest, p *Packet) (err error) {
 	var bytes = make([]byte, 8)
 	binary.BigEndian.PutUint64(bytes, req.Inode)
@@ -0,0 +1,104 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// SetLock acquires or releases an advisory lock of the inode. It replies OpExistErr with the conflicting lock
+// if the lock is held by another owner, since OpAgain makes the client retry.
+func (mp *metaPartition) SetLock(req *proto.SetLockRequest, p *Packet) (err error) {
+	switch req.Lock.Type {
+	case proto.FileLockRead, proto.FileLockWrite, proto.FileLockUnlock:
+	default:
+		err = fmt.Errorf("invalid lock type %v", req.Lock.Type)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if req.Lock.Session == "" || req.Lock.Start > req.Lock.End {
+		err = fmt.Errorf("invalid lock %v", req.Lock)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	op := &LockOp{
+		Inode: req.Inode,
+		Lock:  req.Lock,
+		Now:   Now.GetCurrentTime().Unix(),
+	}
+	val, err := op.Marshal()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMSetLock, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	if conflict := resp.(*proto.FileLock); conflict != nil {
+		var reply []byte
+		if reply, err = json.Marshal(&proto.GetLockResponse{Lock: conflict}); err != nil {
+			p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+			return
+		}
+		p.PacketErrorWithBody(proto.OpExistErr, reply)
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+// GetLock replies a lock of the inode conflicting with the given one, if any.
+func (mp *metaPartition) GetLock(req *proto.GetLockRequest, p *Packet) (err error) {
+	resp := &proto.GetLockResponse{
+		Lock: mp.locks.get(req.Inode, &req.Lock, Now.GetCurrentTime().Unix()),
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+// RenewLock extends the leases of the locks held by the session, and replies the number of them.
+func (mp *metaPartition) RenewLock(req *proto.RenewLockRequest, p *Packet) (err error) {
+	op := &LockOp{
+		Session: req.Session,
+		Now:     Now.GetCurrentTime().Unix(),
+	}
+	val, err := op.Marshal()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMRenewLock, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	reply, err := json.Marshal(&proto.RenewLockResponse{Locks: resp.(int)})
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
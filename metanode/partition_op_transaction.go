@@ -0,0 +1,119 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// TxPrepare prepares the items of a transaction, and replies the items with the replaced inodes filled.
+func (mp *metaPartition) TxPrepare(req *proto.TxPrepareRequest, p *Packet) (err error) {
+	if req.TxID == "" || len(req.Items) == 0 {
+		err = fmt.Errorf("invalid transaction %v", req.TxID)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	for _, item := range req.Items {
+		if item.Op == proto.TxOpDeleteDentry || item.Overwrite {
+			if err = mp.checkWormByDentry(item.ParentID, item.Name, "TxPrepare", p); err != nil {
+				return
+			}
+		}
+		if item.Op == proto.TxOpUnlinkInode {
+			if err = mp.checkWormByID(item.Inode, "TxPrepare", nil, p); err != nil {
+				return
+			}
+		}
+	}
+	tx := &Transaction{
+		TxID:         req.TxID,
+		PrimaryID:    req.PrimaryID,
+		Participants: req.Participants,
+		Items:        req.Items,
+		Time:         Now.GetCurrentTime().Unix(),
+	}
+	val, err := tx.Bytes()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMTxPrepare, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	mp.replyTx(resp.(*TxResponse), p)
+	return
+}
+
+// TxCommit commits a prepared transaction, and replies the state of it.
+func (mp *metaPartition) TxCommit(req *proto.TxRequest, p *Packet) (err error) {
+	return mp.submitTxRequest(opFSMTxCommit, req, p)
+}
+
+// TxAbort aborts a prepared transaction, and replies the state of it.
+func (mp *metaPartition) TxAbort(req *proto.TxRequest, p *Packet) (err error) {
+	return mp.submitTxRequest(opFSMTxAbort, req, p)
+}
+
+// TxForget removes the outcome of a transaction from the primary partition.
+func (mp *metaPartition) TxForget(req *proto.TxRequest, p *Packet) (err error) {
+	return mp.submitTxRequest(opFSMTxForget, req, p)
+}
+
+// TxGet replies the state of a transaction. The transaction unknown to the primary partition is aborted.
+func (mp *metaPartition) TxGet(req *proto.TxRequest, p *Packet) (err error) {
+	if tx := mp.getTransaction(req.TxID); tx != nil {
+		mp.replyTx(&TxResponse{Status: proto.OpOk, State: tx.State, Items: tx.Items}, p)
+		return
+	}
+	if req.PrimaryID != mp.config.PartitionId {
+		p.PacketErrorWithBody(proto.OpNotExistErr, nil)
+		return
+	}
+	return mp.submitTxRequest(opFSMTxAbort, req, p)
+}
+
+func (mp *metaPartition) submitTxRequest(opFSM uint32, req *proto.TxRequest, p *Packet) (err error) {
+	op := &TxOp{
+		TxID:         req.TxID,
+		PrimaryID:    req.PrimaryID,
+		Participants: req.Participants,
+		Now:          Now.GetCurrentTime().Unix(),
+	}
+	resp, err := mp.submitTxOp(opFSM, op)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	mp.replyTx(resp, p)
+	return
+}
+
+func (mp *metaPartition) replyTx(resp *TxResponse, p *Packet) {
+	if resp.Status != proto.OpOk {
+		p.PacketErrorWithBody(resp.Status, nil)
+		return
+	}
+	reply, err := json.Marshal(&proto.TxResponse{State: resp.State, Items: resp.Items})
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+}
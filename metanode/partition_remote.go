@@ -0,0 +1,79 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// sendToPartition sends the request to the given partition of the volume, which may be held by another
+// meta node, and unmarshals the reply into resp unless it is nil. The request sent to a follower is
+// proxied to the leader. The request is only sent to the next host if it is not replied, since the
+// operations may not be idempotent.
+func (mp *metaPartition) sendToPartition(partitionID uint64, opcode uint8, req, resp interface{}) (err error) {
+	partition, err := masterClient.ClientAPI().GetMetaPartition(partitionID)
+	if err != nil {
+		return
+	}
+	for _, host := range partition.Hosts {
+		var replied bool
+		if replied, err = mp.sendToHost(host, opcode, req, resp); err == nil || replied {
+			return
+		}
+		log.LogWarnf("sendToPartition: mp(%v) host(%v) op(%v) req(%v) err(%v)", partitionID, host, opcode, req, err)
+	}
+	if err == nil {
+		err = fmt.Errorf("no host of meta partition %v", partitionID)
+	}
+	return
+}
+
+func (mp *metaPartition) sendToHost(host string, opcode uint8, req, resp interface{}) (replied bool, err error) {
+	conn, err := mp.config.ConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	p := proto.NewPacketReqID()
+	p.Opcode = opcode
+	if err = p.MarshalData(req); err != nil {
+		return
+	}
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	if err = p.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		return
+	}
+	replied = true
+	if p.ResultCode != proto.OpOk {
+		err = fmt.Errorf("result(%v)", p.GetResultMsg())
+		return
+	}
+	if resp != nil {
+		err = json.Unmarshal(p.Data, resp)
+	}
+	return
+}
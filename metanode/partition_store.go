@@ -42,6 +42,8 @@ const (
 	dentryFile      = "dentry"
 	extendFile      = "extend"
 	multipartFile   = "multipart"
+	transactionFile = "transaction"
+	lockFile        = "lock"
 	applyIDFile     = "apply"
 	SnapshotSign    = ".sign"
 	metadataFile    = "meta"
@@ -295,6 +297,68 @@ func (mp *metaPartition) loadMultipart(rootDir string) error {
 	return nil
 }
 
+func (mp *metaPartition) loadTransaction(rootDir string) (err error) {
+	filename := path.Join(rootDir, transactionFile)
+	if _, err = os.Stat(filename); err != nil {
+		return nil
+	}
+	fp, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = fp.Close()
+	}()
+	var mem mmap.MMap
+	if mem, err = mmap.Map(fp, mmap.RDONLY, 0); err != nil {
+		return
+	}
+	defer func() {
+		_ = mem.Unmap()
+	}()
+	var offset, n int
+	// read number of transactions
+	var numTxs uint64
+	numTxs, n = binary.Uvarint(mem)
+	offset += n
+	for i := uint64(0); i < numTxs; i++ {
+		// read length
+		var numBytes uint64
+		numBytes, n = binary.Uvarint(mem[offset:])
+		offset += n
+		var tx *Transaction
+		if tx, err = TransactionFromBytes(mem[offset : offset+int(numBytes)]); err != nil {
+			return
+		}
+		log.LogDebugf("loadTransaction: create transaction from bytes: partitionID(%v) tx(%v)", mp.config.PartitionId, tx)
+		mp.txTree.ReplaceOrInsert(tx, true)
+		offset += int(numBytes)
+	}
+	mp.txLocks = newTxLockIndex(mp.txTree)
+	log.LogInfof("loadTransaction: load complete: partitionID(%v) numTxs(%v) filename(%v)",
+		mp.config.PartitionId, numTxs, filename)
+	return nil
+}
+
+func (mp *metaPartition) loadLocks(rootDir string) (err error) {
+	filename := path.Join(rootDir, lockFile)
+	if _, err = os.Stat(filename); err != nil {
+		return nil
+	}
+	var data []byte
+	if data, err = ioutil.ReadFile(filename); err != nil {
+		return
+	}
+	var records []*LockRecord
+	if err = json.Unmarshal(data, &records); err != nil {
+		return
+	}
+	mp.locks = lockTableFromRecords(records)
+	log.LogInfof("loadLocks: load complete: partitionID(%v) numLocks(%v) filename(%v)",
+		mp.config.PartitionId, len(records), filename)
+	return nil
+}
+
 func (mp *metaPartition) loadApplyID(rootDir string) (err error) {
 	filename := path.Join(rootDir, applyIDFile)
 	if _, err = os.Stat(filename); err != nil {
@@ -604,3 +668,96 @@ func (mp *metaPartition) storeMultipart(rootDir string, sm *storeMsg) (crc uint3
 		mp.config.PartitionId, mp.config.VolName, multipartTree.Len(), crc)
 	return
 }
+
+func (mp *metaPartition) storeTransaction(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	var txTree = sm.txTree
+	var fp = path.Join(rootDir, transactionFile)
+	var f *os.File
+	f, err = os.OpenFile(fp, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.O_CREATE, 0755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := f.Close()
+		if err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	var writer = bufio.NewWriterSize(f, 4*1024*1024)
+	var crc32 = crc32.NewIEEE()
+	var varintTmp = make([]byte, binary.MaxVarintLen64)
+	var n int
+	// write number of transactions
+	n = binary.PutUvarint(varintTmp, uint64(txTree.Len()))
+	if _, err = writer.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	if _, err = crc32.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	txTree.Ascend(func(i BtreeItem) bool {
+		tx := i.(*Transaction)
+		var raw []byte
+		if raw, err = tx.Bytes(); err != nil {
+			return false
+		}
+		// write length
+		n = binary.PutUvarint(varintTmp, uint64(len(raw)))
+		if _, err = writer.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		// write raw
+		if _, err = writer.Write(raw); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(raw); err != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	if err = writer.Flush(); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	crc = crc32.Sum32()
+	log.LogInfof("storeTransaction: store complete: partitionID(%v) volume(%v) numTxs(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, txTree.Len(), crc)
+	return
+}
+
+func (mp *metaPartition) storeLocks(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	var data []byte
+	if data, err = json.Marshal(sm.locks); err != nil {
+		return
+	}
+	var fp = path.Join(rootDir, lockFile)
+	var f *os.File
+	if f, err = os.OpenFile(fp, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.O_CREATE, 0755); err != nil {
+		return
+	}
+	defer func() {
+		closeErr := f.Close()
+		if err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	crc = crc32.ChecksumIEEE(data)
+	log.LogInfof("storeLocks: store complete: partitionID(%v) volume(%v) numLocks(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, len(sm.locks), crc)
+	return
+}
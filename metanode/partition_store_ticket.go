@@ -31,6 +31,8 @@ type storeMsg struct {
 	dentryTree    *BTree
 	extendTree    *BTree
 	multipartTree *BTree
+	txTree        *BTree
+	locks         []*LockRecord
 }
 
 func (mp *metaPartition) startSchedule(curIndex uint64) {
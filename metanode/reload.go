@@ -0,0 +1,52 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func parseMemThresholds(cfg *config.Config) (usage, readOnly float64, err error) {
+	readOnly = MaxUsedMemFactor
+	if threshold := cfg.GetFloat(cfgMemReadOnlyThreshold); threshold > 0 {
+		readOnly = threshold
+	}
+	if threshold := cfg.GetFloat(cfgMemUsageThreshold); threshold > 0 {
+		if threshold > readOnly {
+			return 0, 0, fmt.Errorf("bad memUsageThreshold config, it should not be greater than memReadOnlyThreshold(%v)", readOnly)
+		}
+		usage = threshold
+	}
+	return
+}
+
+// ReloadableKeys returns the config keys applied by Reload.
+func (m *MetaNode) ReloadableKeys() []string {
+	return []string{cfgMemUsageThreshold, cfgMemReadOnlyThreshold}
+}
+
+// Reload applies the reloadable keys of the config.
+func (m *MetaNode) Reload(cfg *config.Config) (err error) {
+	var usage, readOnly float64
+	if usage, readOnly, err = parseMemThresholds(cfg); err != nil {
+		return
+	}
+	memUsageThreshold, memReadOnlyThreshold = usage, readOnly
+	log.LogInfof("action[Reload] memUsageThreshold(%v) memReadOnlyThreshold(%v)", usage, readOnly)
+	return
+}
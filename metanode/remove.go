@@ -0,0 +1,349 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	removeInterval  = 10 * time.Second
+	removeBatchSize = 1000
+)
+
+// removeWorker removes the subtrees of the directories marked for removal on the leader. A marked
+// directory has been detached from its parent by the client. The children of it are removed in
+// batches, and the child directories are marked in turn, so that they are removed by the partitions
+// holding them. The directory itself is unlinked once it is empty.
+func (mp *metaPartition) removeWorker() {
+	t := time.NewTicker(removeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+		}
+		// keep removing while it makes progress, since the child directories may be marked in this partition
+		for {
+			if _, ok := mp.IsLeader(); !ok {
+				break
+			}
+			if !mp.removeMarkedDirs() {
+				break
+			}
+			select {
+			case <-mp.stopC:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// removeMarkIndex indexes the inodes marked for removal, so that the remove worker does not scan all
+// the extended attributes of the partition. The index is changed as the raft logs are applied, and
+// rebuilt whenever the tree of the extended attributes is replaced. It is locked since the remove
+// worker reads it out of the apply.
+type removeMarkIndex struct {
+	sync.Mutex
+	inodes map[uint64]struct{}
+}
+
+// reset rebuilds the index from the tree of the extended attributes.
+func (x *removeMarkIndex) reset(extendTree *BTree) {
+	inodes := make(map[uint64]struct{})
+	extendTree.Ascend(func(i BtreeItem) bool {
+		extend := i.(*Extend)
+		if _, exist := extend.Get([]byte(proto.XAttrKeyRemoveTime)); exist {
+			inodes[extend.inode] = struct{}{}
+		}
+		return true
+	})
+	x.Lock()
+	x.inodes = inodes
+	x.Unlock()
+}
+
+// update indexes the inode if the extended attributes have the mark, otherwise drops it.
+func (x *removeMarkIndex) update(extend *Extend) {
+	_, marked := extend.Get([]byte(proto.XAttrKeyRemoveTime))
+	x.Lock()
+	defer x.Unlock()
+	if !marked {
+		delete(x.inodes, extend.inode)
+		return
+	}
+	if x.inodes == nil {
+		x.inodes = make(map[uint64]struct{})
+	}
+	x.inodes[extend.inode] = struct{}{}
+}
+
+func (x *removeMarkIndex) remove(ino uint64) {
+	x.Lock()
+	delete(x.inodes, ino)
+	x.Unlock()
+}
+
+// list returns the marked inodes in order.
+func (x *removeMarkIndex) list() (inodes []uint64) {
+	x.Lock()
+	inodes = make([]uint64, 0, len(x.inodes))
+	for ino := range x.inodes {
+		inodes = append(inodes, ino)
+	}
+	x.Unlock()
+	sort.Slice(inodes, func(i, j int) bool { return inodes[i] < inodes[j] })
+	return
+}
+
+// markedDirs returns the directories marked for removal. The mark of the inode which has been
+// unlinked is dropped.
+func (mp *metaPartition) markedDirs() (dirs []uint64) {
+	stale := make([]uint64, 0)
+	for _, ino := range mp.removeMarks.list() {
+		item := mp.inodeTree.Get(NewInode(ino, 0))
+		if item == nil {
+			stale = append(stale, ino)
+		} else if proto.IsDir(item.(*Inode).Type) {
+			dirs = append(dirs, ino)
+		}
+	}
+	for _, ino := range stale {
+		if err := mp.unmarkRemove(ino); err != nil {
+			log.LogWarnf("markedDirs: vol(%v) mp(%v) ino(%v) unmark failed: %v", mp.config.VolName, mp.config.PartitionId, ino, err)
+		}
+	}
+	return
+}
+
+// removeMarkedDirs removes a batch of the children of each marked directory. It returns if any
+// child or directory is removed.
+func (mp *metaPartition) removeMarkedDirs() (progress bool) {
+	dirs := mp.markedDirs()
+	if len(dirs) == 0 {
+		return
+	}
+	views, err := masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName)
+	if err != nil {
+		log.LogWarnf("removeMarkedDirs: vol(%v) mp(%v) get meta partitions failed: %v", mp.config.VolName, mp.config.PartitionId, err)
+		return
+	}
+	for _, dir := range dirs {
+		removed, err := mp.removeDirBatch(dir, views)
+		if err != nil {
+			log.LogWarnf("removeMarkedDirs: vol(%v) mp(%v) dir(%v) err(%v)", mp.config.VolName, mp.config.PartitionId, dir, err)
+		}
+		progress = progress || removed
+	}
+	return
+}
+
+func (mp *metaPartition) removeDirBatch(dir uint64, views []*proto.MetaPartitionView) (removed bool, err error) {
+	children := make(DentryBatch, 0)
+	mp.dentryTree.AscendRange(&Dentry{ParentId: dir}, &Dentry{ParentId: dir + 1}, func(i BtreeItem) bool {
+		d := i.(*Dentry)
+		children = append(children, &Dentry{ParentId: d.ParentId, Name: d.Name, Inode: d.Inode, Type: d.Type})
+		return len(children) < removeBatchSize
+	})
+	if len(children) == 0 {
+		return true, mp.finishRemoveDir(dir)
+	}
+
+	dentries := make(DentryBatch, 0, len(children))
+	now := Now.GetCurrentTime().Unix()
+	for _, d := range children {
+		if proto.IsDir(d.Type) {
+			// the child directory is marked before it is detached, so that it is never left behind
+			if err = mp.markRemove(d.Inode, views, now); err != nil {
+				log.LogWarnf("removeDirBatch: vol(%v) mp(%v) mark dir(%v) failed: %v", mp.config.VolName, mp.config.PartitionId, d.Inode, err)
+				continue
+			}
+		} else if mp.wormRetained(d.Inode, now) {
+			continue
+		}
+		dentries = append(dentries, d)
+	}
+	if len(dentries) == 0 {
+		return
+	}
+	val, err := dentries.Marshal()
+	if err != nil {
+		return
+	}
+	r, err := mp.submit(opFSMDeleteDentryBatch, val)
+	if err != nil {
+		return
+	}
+
+	files := make(map[uint64][]uint64) // partition id -> inodes
+	for i, resp := range r.([]*DentryResponse) {
+		if resp.Status != proto.OpOk {
+			continue
+		}
+		removed = true
+		if d := dentries[i]; !proto.IsDir(d.Type) {
+			if pid := inodePartition(d.Inode, views); pid != 0 {
+				files[pid] = append(files[pid], d.Inode)
+			}
+		}
+	}
+	// the deleted files are evicted to the free list, which deletes the extents of them
+	for pid, inodes := range files {
+		if e := mp.unlinkAndEvict(pid, inodes); e != nil {
+			err = e
+		}
+	}
+	log.LogDebugf("removeDirBatch: vol(%v) mp(%v) dir(%v) children(%v) removed(%v)",
+		mp.config.VolName, mp.config.PartitionId, dir, len(children), len(dentries))
+	return
+}
+
+// finishRemoveDir unlinks the empty directory, and then drops the mark of it.
+func (mp *metaPartition) finishRemoveDir(dir uint64) (err error) {
+	val, err := InodeBatch{NewInode(dir, 0)}.Marshal()
+	if err != nil {
+		return
+	}
+	r, err := mp.submit(opFSMUnlinkInodeBatch, val)
+	if err != nil {
+		return
+	}
+	if status := r.([]*InodeResponse)[0].Status; status != proto.OpOk {
+		return fmt.Errorf("unlink dir status(%v)", status)
+	}
+	log.LogInfof("finishRemoveDir: vol(%v) mp(%v) dir(%v) removed", mp.config.VolName, mp.config.PartitionId, dir)
+	return mp.unmarkRemove(dir)
+}
+
+func (mp *metaPartition) markRemove(ino uint64, views []*proto.MetaPartitionView, now int64) (err error) {
+	value := strconv.FormatInt(now, 10)
+	pid := inodePartition(ino, views)
+	if pid == 0 {
+		return fmt.Errorf("no meta partition of inode %v", ino)
+	}
+	if pid == mp.config.PartitionId {
+		extend := NewExtend(ino)
+		extend.Put([]byte(proto.XAttrKeyRemoveTime), []byte(value))
+		_, err = mp.putExtend(opFSMSetXAttr, extend)
+		return
+	}
+	req := &proto.SetXAttrRequest{
+		VolName:     mp.config.VolName,
+		PartitionId: pid,
+		Inode:       ino,
+		Key:         proto.XAttrKeyRemoveTime,
+		Value:       value,
+	}
+	return mp.sendToPartition(pid, proto.OpMetaSetXAttr, req, nil)
+}
+
+func (mp *metaPartition) unmarkRemove(ino uint64) (err error) {
+	extend := NewExtend(ino)
+	extend.Put([]byte(proto.XAttrKeyRemoveTime), nil)
+	_, err = mp.putExtend(opFSMRemoveXAttr, extend)
+	return
+}
+
+// wormRetained returns if the file is in the retention period, or is immutable or append-only. Only the
+// inodes held by this meta node are checked, otherwise the unlink is refused by the partition of the
+// inode, so that the file data is retained anyway.
+func (mp *metaPartition) wormRetained(ino uint64, now int64) bool {
+	inode := mp.manager.getLocalInode(ino)
+	if inode == nil {
+		return false
+	}
+	if inode.inodeFlags() != 0 {
+		return true
+	}
+	protected, _ := mp.vol.wormProtected(inode, now)
+	return protected
+}
+
+func (mp *metaPartition) unlinkAndEvict(pid uint64, inodes []uint64) (err error) {
+	if pid != mp.config.PartitionId {
+		var unlinked []uint64
+		if unlinked, err = mp.unlinkRemote(pid, inodes); len(unlinked) == 0 {
+			return
+		}
+		if e := mp.sendToPartition(pid, proto.OpMetaBatchEvictInode, &proto.BatchEvictInodeRequest{
+			VolName:     mp.config.VolName,
+			PartitionID: pid,
+			Inodes:      unlinked,
+		}, nil); e != nil {
+			err = e
+		}
+		return
+	}
+	batch := make(InodeBatch, 0, len(inodes))
+	for _, ino := range inodes {
+		batch = append(batch, NewInode(ino, 0))
+	}
+	val, err := batch.Marshal()
+	if err != nil {
+		return
+	}
+	if _, err = mp.submit(opFSMUnlinkInodeBatch, val); err != nil {
+		return
+	}
+	_, err = mp.submit(opFSMEvictInodeBatch, val)
+	return
+}
+
+// unlinkRemote unlinks the inodes of another partition, and returns the unlinked ones. The inodes are
+// unlinked one by one if the volume retains the files, since the batch is refused if any inode is
+// in the retention period.
+func (mp *metaPartition) unlinkRemote(pid uint64, inodes []uint64) (unlinked []uint64, err error) {
+	if !mp.vol.wormEnabled() {
+		if err = mp.sendToPartition(pid, proto.OpMetaBatchUnlinkInode, &proto.BatchUnlinkInodeRequest{
+			VolName:     mp.config.VolName,
+			PartitionID: pid,
+			Inodes:      inodes,
+		}, nil); err != nil {
+			return
+		}
+		return inodes, nil
+	}
+	for _, ino := range inodes {
+		if e := mp.sendToPartition(pid, proto.OpMetaUnlinkInode, &proto.UnlinkInodeRequest{
+			VolName:     mp.config.VolName,
+			PartitionID: pid,
+			Inode:       ino,
+		}, nil); e != nil {
+			err = e
+			continue
+		}
+		unlinked = append(unlinked, ino)
+	}
+	return
+}
+
+// inodePartition returns the id of the partition holding the inode, or 0 if it is not found.
+func inodePartition(ino uint64, views []*proto.MetaPartitionView) uint64 {
+	for _, view := range views {
+		if ino >= view.Start && ino <= view.End {
+			return view.PartitionID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestMarkedDirs(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+		freeList:   newFreeList(),
+	}
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 2, Type: proto.Mode(os.ModeDir | 0755), NLink: 3}, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 3, Type: proto.Mode(os.ModeDir | 0755), NLink: 2}, true)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 4, Type: proto.Mode(0644), NLink: 1}, true)
+	for _, ino := range []uint64{2, 4} {
+		extend := NewExtend(ino)
+		extend.Put([]byte(proto.XAttrKeyRemoveTime), []byte("1600000000"))
+		mp.fsmSetXAttr(extend)
+	}
+	other := NewExtend(3)
+	other.Put([]byte(proto.XAttrKeyTrashTime), []byte("1600000000"))
+	mp.fsmSetXAttr(other)
+
+	// the mark of a file is left alone, only the mark of an unlinked inode is dropped
+	if dirs := mp.markedDirs(); len(dirs) != 1 || dirs[0] != 2 {
+		t.Fatalf("unexpected marked dirs: %v", dirs)
+	}
+
+	// the index is rebuilt from the tree
+	mp.removeMarks.reset(NewBtree())
+	if dirs := mp.markedDirs(); len(dirs) != 0 {
+		t.Fatalf("unexpected marked dirs of the empty tree: %v", dirs)
+	}
+	mp.removeMarks.reset(mp.extendTree)
+	if dirs := mp.markedDirs(); len(dirs) != 1 || dirs[0] != 2 {
+		t.Fatalf("unexpected marked dirs after the index is rebuilt: %v", dirs)
+	}
+
+	// the unmarked and the deleted inodes are dropped from the index
+	unmark := NewExtend(2)
+	unmark.Put([]byte(proto.XAttrKeyRemoveTime), nil)
+	mp.fsmRemoveXAttr(unmark)
+	if dirs := mp.markedDirs(); len(dirs) != 0 {
+		t.Fatalf("unexpected marked dirs after the unmark: %v", dirs)
+	}
+	mark := NewExtend(3)
+	mark.Put([]byte(proto.XAttrKeyRemoveTime), []byte("1600000000"))
+	mp.fsmSetXAttr(mark)
+	mp.internalDeleteInode(&Inode{Inode: 3})
+	if inodes := mp.removeMarks.list(); len(inodes) != 1 || inodes[0] != 4 {
+		t.Fatalf("unexpected marked inodes after the delete: %v", inodes)
+	}
+}
+
+func TestInodePartition(t *testing.T) {
+	views := []*proto.MetaPartitionView{
+		{PartitionID: 1, Start: 0, End: 1000},
+		{PartitionID: 2, Start: 1001, End: 2000},
+	}
+	for ino, pid := range map[uint64]uint64{1: 1, 1000: 1, 1001: 2, 2000: 2, 2001: 0} {
+		if actual := inodePartition(ino, views); actual != pid {
+			t.Fatalf("inode(%v): expect partition(%v) actual(%v)", ino, pid, actual)
+		}
+	}
+}
@@ -17,6 +17,7 @@ package metanode
 import (
 	"io"
 	"net"
+	"sync"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
@@ -80,12 +81,50 @@ func (m *MetaNode) serveConn(conn net.Conn, stopC chan uint8) {
 			}
 			return
 		}
+		if p.Opcode == proto.OpMuxConn {
+			m.serveMuxConn(conn, p, stopC, remoteAddr)
+			return
+		}
 		if err := m.handlePacket(conn, p, remoteAddr); err != nil {
 			log.LogErrorf("serve handlePacket fail: %v", err)
 		}
 	}
 }
 
+// serveMuxConn handles the requests of the multiplexed connection concurrently, see proto.MuxConn.
+func (m *MetaNode) serveMuxConn(conn net.Conn, p *Packet, stopC chan uint8, remoteAddr string) {
+	p.PacketOkReply()
+	if err := p.WriteToConn(conn); err != nil {
+		log.LogErrorf("serveMuxConn: remote(%v) err(%v)", remoteAddr, err)
+		return
+	}
+	var writeMu sync.Mutex
+	inflight := make(chan struct{}, proto.MuxMaxInflight)
+	for {
+		select {
+		case <-stopC:
+			return
+		default:
+		}
+		p := &Packet{}
+		if err := p.ReadFromConn(conn, proto.NoReadDeadlineTime); err != nil {
+			if err != io.EOF {
+				log.LogError("serve MetaNode: ", err.Error())
+			}
+			return
+		}
+		inflight <- struct{}{}
+		go func() {
+			defer func() { <-inflight }()
+			replyConn := proto.NewMuxReplyConn(conn, &writeMu)
+			if err := m.handlePacket(replyConn, p, remoteAddr); err != nil {
+				log.LogErrorf("serve handlePacket fail: %v", err)
+			}
+			replyConn.Flush()
+		}()
+	}
+}
+
 func (m *MetaNode) handlePacket(conn net.Conn, p *Packet,
 	remoteAddr string) (err error) {
 	// Handle request
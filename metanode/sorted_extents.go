@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
 )
 
 type SortedExtents struct {
@@ -29,6 +30,13 @@ func (se *SortedExtents) String() string {
 	return string(data)
 }
 
+// MarshalJSON exports the extent keys, so that the inodes dumped by the api handlers can be verified by fsck.
+func (se *SortedExtents) MarshalJSON() ([]byte, error) {
+	se.RLock()
+	defer se.RUnlock()
+	return json.Marshal(se.eks)
+}
+
 func (se *SortedExtents) MarshalBinary() ([]byte, error) {
 	var data []byte
 
@@ -113,6 +121,7 @@ func (se *SortedExtents) Append(ek proto.ExtentKey) (deleteExtents []proto.Exten
 			deleteExtents = append(deleteExtents, key)
 		}
 	}
+	deleteExtents = se.unreferenced(deleteExtents)
 	return
 }
 
@@ -137,6 +146,7 @@ func (se *SortedExtents) Truncate(offset uint64) (deleteExtents []proto.ExtentKe
 		copy(deleteExtents, se.eks[endIndex:])
 		se.eks = se.eks[:endIndex]
 	}
+	deleteExtents = se.unreferenced(deleteExtents)
 
 	numKeys := len(se.eks)
 	if numKeys > 0 {
@@ -148,6 +158,96 @@ func (se *SortedExtents) Truncate(offset uint64) (deleteExtents []proto.ExtentKe
 	return
 }
 
+// Punch removes the file range [offset, offset+size) from the extent keys, and the keys
+// across the boundaries of the range are split. The size of the file is not changed.
+func (se *SortedExtents) Punch(offset, size uint64) (deleteExtents []proto.ExtentKey) {
+	end := offset + size
+
+	se.Lock()
+	defer se.Unlock()
+
+	eks := make([]proto.ExtentKey, 0, len(se.eks)+1)
+	deleteExtents = make([]proto.ExtentKey, 0)
+	for _, key := range se.eks {
+		keyEnd := key.FileOffset + uint64(key.Size)
+		if keyEnd <= offset || key.FileOffset >= end {
+			eks = append(eks, key)
+			continue
+		}
+		if key.FileOffset < offset {
+			eks = append(eks, sliceExtentKey(key, key.FileOffset, offset))
+		}
+		if piece := sliceExtentKey(key, maxUint64(key.FileOffset, offset), minUint64(keyEnd, end)); punchable(piece, keyEnd) {
+			deleteExtents = append(deleteExtents, piece)
+		}
+		if keyEnd > end {
+			eks = append(eks, sliceExtentKey(key, end, keyEnd))
+		}
+	}
+	se.eks = eks
+	deleteExtents = se.unreferenced(deleteExtents)
+	return
+}
+
+// unreferenced filters out the keys of the normal extents which are still referenced by the
+// remaining keys. A normal extent can only be deleted as a whole on the data node, so the
+// space of a partially punched or overwritten normal extent is released with its last key.
+// The keys of the tiny extents are always kept, since the data node punches the exact range.
+func (se *SortedExtents) unreferenced(eks []proto.ExtentKey) []proto.ExtentKey {
+	result := eks[:0]
+	for _, ek := range eks {
+		if !storage.IsTinyExtent(ek.ExtentId) && se.references(ek.PartitionId, ek.ExtentId) {
+			continue
+		}
+		result = append(result, ek)
+	}
+	return result
+}
+
+func (se *SortedExtents) references(partitionID, extentID uint64) bool {
+	for _, key := range se.eks {
+		if key.PartitionId == partitionID && key.ExtentId == extentID {
+			return true
+		}
+	}
+	return false
+}
+
+// punchable returns if the piece of a key can be deleted on the data node. The data node only
+// punches page aligned ranges of a tiny extent, so that an unaligned piece is kept in place
+// instead of clobbering the data of the remaining keys.
+func punchable(piece proto.ExtentKey, keyEnd uint64) bool {
+	if !storage.IsTinyExtent(piece.ExtentId) {
+		return true
+	}
+	if piece.ExtentOffset%storage.PageSize != 0 {
+		return false
+	}
+	return piece.FileOffset+uint64(piece.Size) == keyEnd || piece.Size%storage.PageSize == 0
+}
+
+// sliceExtentKey returns the part of the key in the file range [start, end).
+func sliceExtentKey(key proto.ExtentKey, start, end uint64) proto.ExtentKey {
+	key.ExtentOffset += start - key.FileOffset
+	key.FileOffset = start
+	key.Size = uint32(end - start)
+	return key
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func (se *SortedExtents) Len() int {
 	se.RLock()
 	defer se.RUnlock()
@@ -84,3 +84,64 @@ func TestTruncate01(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// The keys across the boundaries of the hole are split, and the normal extent
+// is not deleted while it is still referenced.
+func TestPunch01(t *testing.T) {
+	se := NewSortedExtents()
+	se.Append(proto.ExtentKey{FileOffset: 0, Size: 1000, ExtentId: 1001})
+	se.Append(proto.ExtentKey{FileOffset: 1000, Size: 1000, ExtentId: 1002})
+	se.Append(proto.ExtentKey{FileOffset: 2000, Size: 1000, ExtentId: 1003})
+	delExtents := se.Punch(500, 2000)
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 1 || delExtents[0].ExtentId != 1002 ||
+		len(se.eks) != 2 || se.Size() != 3000 ||
+		se.eks[0].Size != 500 || se.eks[1].FileOffset != 2500 ||
+		se.eks[1].ExtentOffset != 500 || se.eks[1].Size != 500 {
+		t.Fail()
+	}
+	delExtents = se.Punch(0, 500)
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 1 || delExtents[0].ExtentId != 1001 || len(se.eks) != 1 {
+		t.Fail()
+	}
+	// punch in the middle of a normal extent
+	delExtents = se.Punch(2600, 100)
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 0 || len(se.eks) != 2 || se.eks[1].ExtentOffset != 700 {
+		t.Fail()
+	}
+	// the extent is deleted once the last key referencing it is overwritten
+	delExtents = se.Append(proto.ExtentKey{FileOffset: 2500, Size: 150, ExtentId: 1004})
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 0 {
+		t.Fail()
+	}
+	delExtents = se.Append(proto.ExtentKey{FileOffset: 2000, Size: 1000, ExtentId: 1005})
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 2 || len(se.eks) != 1 {
+		t.Fail()
+	}
+}
+
+// Only the page aligned pieces of the tiny extents are deleted.
+func TestPunch02(t *testing.T) {
+	se := NewSortedExtents()
+	se.Append(proto.ExtentKey{FileOffset: 0, Size: 10000, ExtentId: 1, ExtentOffset: 4096})
+	delExtents := se.Punch(4096, 4096)
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 1 || delExtents[0].ExtentOffset != 8192 || delExtents[0].Size != 4096 ||
+		len(se.eks) != 2 {
+		t.Fail()
+	}
+	delExtents = se.Punch(100, 1000)
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 0 || len(se.eks) != 3 {
+		t.Fail()
+	}
+	delExtents = se.Punch(9000, 5000)
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(delExtents) != 0 || len(se.eks) != 3 || se.Size() != 9000 {
+		t.Fail()
+	}
+}
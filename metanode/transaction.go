@@ -0,0 +1,306 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/btree"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// txTimeout is the time in seconds a transaction may stay prepared. The primary partition aborts
+	// the transaction prepared for longer, and the other partitions ask the primary for the outcome.
+	// It is also the time the primary waits for the client to finish a decided transaction.
+	txTimeout         = 60
+	txResolveInterval = 10 * time.Second
+)
+
+// Transaction is the record of a metadata transaction in a meta partition. The dentries and the inodes
+// of the items are locked while the transaction is prepared, and the items are applied once it is
+// committed. The other partitions drop the record once the transaction is committed or aborted,
+// while the primary partition keeps the outcome until it is known by all the partitions of the
+// transaction, which is the recovery log of the transaction.
+type Transaction struct {
+	TxID         string          `json:"id"`
+	PrimaryID    uint64          `json:"primary"`
+	Participants []uint64        `json:"parts"` // the other partitions, recorded by the primary only
+	State        uint8           `json:"state"`
+	Items        []*proto.TxItem `json:"items"`
+	Time         int64           `json:"time"` // the time the transaction is prepared or decided
+}
+
+// Less tests whether the current transaction item is less than the given one.
+// This method is necessary for B-Tree item implementation.
+func (tx *Transaction) Less(than btree.Item) bool {
+	t, ok := than.(*Transaction)
+	return ok && tx.TxID < t.TxID
+}
+
+// Copy returns a copy of the transaction.
+func (tx *Transaction) Copy() btree.Item {
+	newTx := *tx
+	newTx.Participants = append([]uint64(nil), tx.Participants...)
+	newTx.Items = append([]*proto.TxItem(nil), tx.Items...)
+	return &newTx
+}
+
+// Bytes marshals the transaction into bytes.
+func (tx *Transaction) Bytes() ([]byte, error) {
+	return json.Marshal(tx)
+}
+
+// TransactionFromBytes unmarshals a transaction from bytes.
+func TransactionFromBytes(raw []byte) (tx *Transaction, err error) {
+	tx = new(Transaction)
+	err = json.Unmarshal(raw, tx)
+	return
+}
+
+func (tx *Transaction) String() string {
+	return fmt.Sprintf("Transaction{id(%v) primary(%v) parts(%v) state(%v) time(%v)}",
+		tx.TxID, tx.PrimaryID, tx.Participants, tx.State, tx.Time)
+}
+
+// TxOp defines the raft command to commit, abort or forget a transaction. The time is decided by
+// the leader. The primary and the participants are recorded if the transaction is unknown to the
+// primary partition, so that the transaction prepared later is refused.
+type TxOp struct {
+	TxID         string   `json:"tx"`
+	PrimaryID    uint64   `json:"primary"`
+	Participants []uint64 `json:"parts"`
+	Now          int64    `json:"now"`
+}
+
+// Marshal marshals the transaction operation into bytes.
+func (op *TxOp) Marshal() ([]byte, error) {
+	return json.Marshal(op)
+}
+
+// Unmarshal unmarshals the transaction operation from bytes.
+func (op *TxOp) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, op)
+}
+
+// TxResponse defines the response of the transaction operations applied to the partition.
+type TxResponse struct {
+	Status uint8
+	State  uint8
+	Items  []*proto.TxItem
+}
+
+type txDentryKey struct {
+	parentID uint64
+	name     string
+}
+
+// txLockIndex indexes the dentries and the inodes locked by the prepared transactions, and the parent
+// inodes of the dentries, which are locked as well so that the parents are not unlinked before the
+// dentries are created or deleted. The index is changed only as the raft logs are applied, and rebuilt
+// whenever the records of the transactions are loaded.
+type txLockIndex struct {
+	dentries map[txDentryKey]string // -> id of the transaction
+	parents  map[uint64]int         // parent inode -> number of the locked dentries in it
+	inodes   map[uint64]int         // inode -> number of the items changing it
+}
+
+func newTxLockIndex(txTree *BTree) (x txLockIndex) {
+	txTree.Ascend(func(i BtreeItem) bool {
+		x.add(i.(*Transaction))
+		return true
+	})
+	return x
+}
+
+func (x *txLockIndex) add(tx *Transaction) {
+	if tx.State != proto.TxStatePrepared {
+		return
+	}
+	if x.dentries == nil {
+		x.dentries = make(map[txDentryKey]string)
+		x.parents = make(map[uint64]int)
+		x.inodes = make(map[uint64]int)
+	}
+	for _, item := range tx.Items {
+		if item.IsDentryOp() {
+			x.dentries[txDentryKey{parentID: item.ParentID, name: item.Name}] = tx.TxID
+			x.parents[item.ParentID]++
+		} else {
+			x.inodes[item.Inode]++
+		}
+	}
+}
+
+func (x *txLockIndex) remove(tx *Transaction) {
+	if tx.State != proto.TxStatePrepared {
+		return
+	}
+	for _, item := range tx.Items {
+		if !item.IsDentryOp() {
+			decTxLockCount(x.inodes, item.Inode)
+			continue
+		}
+		key := txDentryKey{parentID: item.ParentID, name: item.Name}
+		if x.dentries[key] != tx.TxID {
+			continue
+		}
+		delete(x.dentries, key)
+		decTxLockCount(x.parents, item.ParentID)
+	}
+}
+
+func decTxLockCount(counts map[uint64]int, inode uint64) {
+	if counts[inode]--; counts[inode] <= 0 {
+		delete(counts, inode)
+	}
+}
+
+// txLocked returns if the dentry is locked by a prepared transaction.
+func (mp *metaPartition) txLocked(parentID uint64, name string) bool {
+	_, ok := mp.txLocks.dentries[txDentryKey{parentID: parentID, name: name}]
+	return ok
+}
+
+// txInodeLocked returns if the inode is locked by a prepared transaction, or is the parent of a locked
+// dentry, so that it is not unlinked or evicted before the transaction is finished.
+func (mp *metaPartition) txInodeLocked(inode uint64) bool {
+	return mp.txLocks.parents[inode] > 0 || mp.txInodeItemLocked(inode)
+}
+
+// txInodeItemLocked returns if the inode is changed by a prepared transaction.
+func (mp *metaPartition) txInodeItemLocked(inode uint64) bool {
+	return mp.txLocks.inodes[inode] > 0
+}
+
+func (mp *metaPartition) getTransaction(txID string) *Transaction {
+	item := mp.txTree.Get(&Transaction{TxID: txID})
+	if item == nil {
+		return nil
+	}
+	return item.(*Transaction)
+}
+
+// txResolveWorker finishes the transactions left by the failed clients on the leader.
+func (mp *metaPartition) txResolveWorker() {
+	t := time.NewTicker(txResolveInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok {
+				continue
+			}
+			mp.resolveTransactions()
+		}
+	}
+}
+
+func (mp *metaPartition) resolveTransactions() {
+	now := Now.GetCurrentTime().Unix()
+	txs := make([]*Transaction, 0)
+	mp.txTree.Ascend(func(i BtreeItem) bool {
+		if tx := i.(*Transaction); now-tx.Time >= txTimeout {
+			txs = append(txs, tx)
+		}
+		return true
+	})
+	for _, tx := range txs {
+		if err := mp.resolveTransaction(tx, now); err != nil {
+			log.LogWarnf("resolveTransaction: mp(%v) tx(%v) err(%v)", mp.config.PartitionId, tx, err)
+		}
+	}
+}
+
+// resolveTransaction aborts the transaction prepared in the primary partition for too long, and
+// sends the outcome to the other partitions before the outcome is forgotten. The other partitions
+// ask the primary for the outcome of the transaction.
+func (mp *metaPartition) resolveTransaction(tx *Transaction, now int64) (err error) {
+	pid := mp.config.PartitionId
+	op := &TxOp{TxID: tx.TxID, PrimaryID: tx.PrimaryID, Participants: tx.Participants, Now: now}
+	if tx.PrimaryID != pid {
+		var resp *proto.TxResponse
+		req := &proto.TxRequest{
+			VolName:      mp.config.VolName,
+			PartitionID:  tx.PrimaryID,
+			TxID:         tx.TxID,
+			PrimaryID:    tx.PrimaryID,
+			Participants: []uint64{pid},
+		}
+		if resp, err = mp.sendTxRequest(tx.PrimaryID, proto.OpMetaTxGet, req); err != nil {
+			return
+		}
+		switch resp.State {
+		case proto.TxStateCommitted:
+			_, err = mp.submitTxOp(opFSMTxCommit, op)
+		case proto.TxStateAborted:
+			_, err = mp.submitTxOp(opFSMTxAbort, op)
+		}
+		log.LogWarnf("resolveTransaction: mp(%v) tx(%v) state of primary(%v) err(%v)", pid, tx, resp.State, err)
+		return
+	}
+
+	switch tx.State {
+	case proto.TxStatePrepared:
+		_, err = mp.submitTxOp(opFSMTxAbort, op)
+		log.LogWarnf("resolveTransaction: mp(%v) tx(%v) aborted for timeout, err(%v)", pid, tx, err)
+		return
+	case proto.TxStateCommitted, proto.TxStateAborted:
+		opcode := proto.OpMetaTxCommit
+		if tx.State == proto.TxStateAborted {
+			opcode = proto.OpMetaTxAbort
+		}
+		for _, part := range tx.Participants {
+			req := &proto.TxRequest{
+				VolName:     mp.config.VolName,
+				PartitionID: part,
+				TxID:        tx.TxID,
+				PrimaryID:   tx.PrimaryID,
+			}
+			if _, err = mp.sendTxRequest(part, opcode, req); err != nil {
+				return
+			}
+		}
+		_, err = mp.submitTxOp(opFSMTxForget, op)
+		log.LogInfof("resolveTransaction: mp(%v) tx(%v) finished, err(%v)", pid, tx, err)
+	}
+	return
+}
+
+func (mp *metaPartition) submitTxOp(opFSM uint32, op *TxOp) (resp *TxResponse, err error) {
+	val, err := op.Marshal()
+	if err != nil {
+		return
+	}
+	r, err := mp.submit(opFSM, val)
+	if err != nil {
+		return
+	}
+	resp = r.(*TxResponse)
+	return
+}
+
+func (mp *metaPartition) sendTxRequest(partitionID uint64, opcode uint8, req *proto.TxRequest) (resp *proto.TxResponse, err error) {
+	resp = new(proto.TxResponse)
+	if err = mp.sendToPartition(partitionID, opcode, req, resp); err != nil {
+		return nil, err
+	}
+	return
+}
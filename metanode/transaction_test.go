@@ -0,0 +1,213 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestTxPartition(pid uint64) *metaPartition {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: pid},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+		txTree:     NewBtree(),
+	}
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 1, Type: proto.Mode(os.ModeDir | 0755), NLink: 2}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 10, Type: proto.Mode(0644)}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "b", Inode: 11, Type: proto.Mode(0644)}, true)
+	return mp
+}
+
+func newTestRenameTx(txID string, primary uint64, overwrite bool) *Transaction {
+	return &Transaction{
+		TxID:      txID,
+		PrimaryID: primary,
+		Items: []*proto.TxItem{
+			{Op: proto.TxOpDeleteDentry, ParentID: 1, Name: "a", Inode: 10},
+			{Op: proto.TxOpCreateDentry, ParentID: 1, Name: "b", Inode: 10, Type: proto.Mode(0644), Overwrite: overwrite},
+		},
+	}
+}
+
+func TestTxCommit(t *testing.T) {
+	mp := newTestTxPartition(1)
+	if resp := mp.fsmTxPrepare(newTestRenameTx("tx1", 1, false)); resp.Status != proto.OpExistErr {
+		t.Fatalf("prepare without overwrite: expect(%v) actual(%v)", proto.OpExistErr, resp.Status)
+	}
+	resp := mp.fsmTxPrepare(newTestRenameTx("tx2", 1, true))
+	if resp.Status != proto.OpOk || resp.State != proto.TxStatePrepared {
+		t.Fatalf("prepare: status(%v) state(%v)", resp.Status, resp.State)
+	}
+	if oldInode := resp.Items[1].OldInode; oldInode != 11 {
+		t.Fatalf("replaced inode: expect(11) actual(%v)", oldInode)
+	}
+	// the dentries of the prepared transaction are locked
+	if status := mp.fsmDeleteDentry(&Dentry{ParentId: 1, Name: "b"}, false).Status; status != proto.OpAgain {
+		t.Fatalf("delete locked dentry: expect(%v) actual(%v)", proto.OpAgain, status)
+	}
+	if resp = mp.fsmTxPrepare(newTestRenameTx("tx3", 1, true)); resp.Status != proto.OpAgain {
+		t.Fatalf("prepare locked dentry: expect(%v) actual(%v)", proto.OpAgain, resp.Status)
+	}
+
+	op := &TxOp{TxID: "tx2", PrimaryID: 1, Now: 100}
+	for i := 0; i < 2; i++ {
+		if resp = mp.fsmTxCommit(op); resp.Status != proto.OpOk || resp.State != proto.TxStateCommitted {
+			t.Fatalf("commit(%v): status(%v) state(%v)", i, resp.Status, resp.State)
+		}
+	}
+	if _, status := mp.getDentry(&Dentry{ParentId: 1, Name: "a"}); status != proto.OpNotExistErr {
+		t.Fatalf("src dentry still exists: status(%v)", status)
+	}
+	if d, status := mp.getDentry(&Dentry{ParentId: 1, Name: "b"}); status != proto.OpOk || d.Inode != 10 {
+		t.Fatalf("dst dentry: status(%v) dentry(%v)", status, d)
+	}
+	// the primary keeps the outcome until it is forgotten
+	if tx := mp.getTransaction("tx2"); tx == nil || tx.State != proto.TxStateCommitted || tx.Time != 100 {
+		t.Fatalf("committed transaction: %v", tx)
+	}
+	if resp = mp.fsmTxAbort(op); resp.State != proto.TxStateCommitted {
+		t.Fatalf("abort committed transaction: state(%v)", resp.State)
+	}
+	mp.fsmTxForget(op)
+	if tx := mp.getTransaction("tx2"); tx != nil {
+		t.Fatalf("forgotten transaction: %v", tx)
+	}
+}
+
+func TestTxAbort(t *testing.T) {
+	primary, participant := newTestTxPartition(1), newTestTxPartition(2)
+	tx := newTestRenameTx("tx1", 1, true)
+	if resp := participant.fsmTxPrepare(tx); resp.Status != proto.OpOk {
+		t.Fatalf("prepare participant: status(%v)", resp.Status)
+	}
+	// the primary records the unknown transaction as aborted, and refuses to prepare it later
+	op := &TxOp{TxID: "tx1", PrimaryID: 1, Participants: []uint64{2}, Now: 100}
+	if resp := primary.fsmTxAbort(op); resp.State != proto.TxStateAborted {
+		t.Fatalf("abort unknown transaction in primary: state(%v)", resp.State)
+	}
+	if resp := primary.fsmTxPrepare(newTestRenameTx("tx1", 1, true)); resp.State != proto.TxStateAborted {
+		t.Fatalf("prepare aborted transaction: state(%v)", resp.State)
+	}
+	if resp := participant.fsmTxAbort(op); resp.State != proto.TxStateAborted {
+		t.Fatalf("abort participant: state(%v)", resp.State)
+	}
+	if participant.getTransaction("tx1") != nil {
+		t.Fatalf("participant keeps the aborted transaction")
+	}
+	if d, status := participant.getDentry(&Dentry{ParentId: 1, Name: "b"}); status != proto.OpOk || d.Inode != 11 {
+		t.Fatalf("aborted dst dentry: status(%v) dentry(%v)", status, d)
+	}
+	if status := participant.fsmDeleteDentry(&Dentry{ParentId: 1, Name: "a"}, false).Status; status != proto.OpOk {
+		t.Fatalf("delete unlocked dentry: status(%v)", status)
+	}
+}
+
+func TestTxInodeItems(t *testing.T) {
+	mp := newTestTxPartition(1)
+	mp.inodeTree.ReplaceOrInsert(&Inode{Inode: 12, Type: proto.Mode(0644), NLink: 1}, true)
+	tx := &Transaction{
+		TxID:      "tx1",
+		PrimaryID: 1,
+		Items: []*proto.TxItem{
+			{Op: proto.TxOpCreateDentry, ParentID: 1, Name: "c", Inode: 12, Type: proto.Mode(0644)},
+			{Op: proto.TxOpLinkInode, Inode: 12},
+			{Op: proto.TxOpSetXAttr, Inode: 12, Key: "user.k", Value: "v"},
+		},
+	}
+	if resp := mp.fsmTxPrepare(tx); resp.Status != proto.OpOk {
+		t.Fatalf("prepare: status(%v)", resp.Status)
+	}
+	// the index rebuilt from the records is the same as the one changed by prepare
+	if x := newTxLockIndex(mp.txTree); x.inodes[12] != 2 || x.parents[1] != 1 || mp.txLocks.inodes[12] != 2 {
+		t.Fatalf("lock index: rebuilt(%v) prepared(%v)", x, mp.txLocks)
+	}
+	// the inode of the prepared transaction is neither unlinked nor evicted
+	if status := mp.fsmUnlinkInode(NewInode(12, 0)).Status; status != proto.OpAgain {
+		t.Fatalf("unlink locked inode: expect(%v) actual(%v)", proto.OpAgain, status)
+	}
+	if status := mp.fsmEvictInode(NewInode(12, 0)).Status; status != proto.OpAgain {
+		t.Fatalf("evict locked inode: expect(%v) actual(%v)", proto.OpAgain, status)
+	}
+	if item := mp.extendTree.Get(NewExtend(12)); item != nil {
+		t.Fatalf("xattr is set before commit")
+	}
+
+	if resp := mp.fsmTxCommit(&TxOp{TxID: "tx1", PrimaryID: 1, Now: 100}); resp.State != proto.TxStateCommitted {
+		t.Fatalf("commit: status(%v) state(%v)", resp.Status, resp.State)
+	}
+	if d, status := mp.getDentry(&Dentry{ParentId: 1, Name: "c"}); status != proto.OpOk || d.Inode != 12 {
+		t.Fatalf("created dentry: status(%v) dentry(%v)", status, d)
+	}
+	if nlink := mp.inodeTree.Get(NewInode(12, 0)).(*Inode).NLink; nlink != 2 {
+		t.Fatalf("nlink: expect(2) actual(%v)", nlink)
+	}
+	item := mp.extendTree.Get(NewExtend(12))
+	if item == nil {
+		t.Fatalf("xattr is not set after commit")
+	}
+	if value, ok := item.(*Extend).Get([]byte("user.k")); !ok || string(value) != "v" {
+		t.Fatalf("xattr: value(%v) exist(%v)", string(value), ok)
+	}
+	if status := mp.fsmUnlinkInode(NewInode(12, 0)).Status; status != proto.OpOk {
+		t.Fatalf("unlink unlocked inode: status(%v)", status)
+	}
+	if len(mp.txLocks.inodes) != 0 || len(mp.txLocks.parents) != 0 || len(mp.txLocks.dentries) != 0 {
+		t.Fatalf("committed transaction is still indexed: %v", mp.txLocks)
+	}
+
+	// the transaction is refused if any item fails to prepare
+	tx = &Transaction{
+		TxID:      "tx2",
+		PrimaryID: 1,
+		Items: []*proto.TxItem{
+			{Op: proto.TxOpSetXAttr, Inode: 12, Key: "user.k", Value: "w"},
+			{Op: proto.TxOpUnlinkInode, Inode: 13},
+		},
+	}
+	if resp := mp.fsmTxPrepare(tx); resp.Status != proto.OpNotExistErr {
+		t.Fatalf("prepare with missing inode: expect(%v) actual(%v)", proto.OpNotExistErr, resp.Status)
+	}
+	if mp.getTransaction("tx2") != nil || mp.txInodeLocked(12) {
+		t.Fatalf("refused transaction is recorded")
+	}
+}
+
+func TestTxParentLocked(t *testing.T) {
+	mp := newTestTxPartition(1)
+	if resp := mp.fsmTxPrepare(newTestRenameTx("tx1", 1, true)); resp.Status != proto.OpOk {
+		t.Fatalf("prepare: status(%v)", resp.Status)
+	}
+	// the parent of the locked dentries is neither unlinked nor changed by another transaction
+	if status := mp.fsmUnlinkInode(NewInode(1, 0)).Status; status != proto.OpAgain {
+		t.Fatalf("unlink locked parent: expect(%v) actual(%v)", proto.OpAgain, status)
+	}
+	tx := &Transaction{TxID: "tx2", PrimaryID: 1, Items: []*proto.TxItem{{Op: proto.TxOpUnlinkInode, Inode: 1}}}
+	if resp := mp.fsmTxPrepare(tx); resp.Status != proto.OpAgain {
+		t.Fatalf("prepare unlink of locked parent: expect(%v) actual(%v)", proto.OpAgain, resp.Status)
+	}
+	mp.fsmTxAbort(&TxOp{TxID: "tx1", PrimaryID: 1, Now: 100})
+	if mp.txInodeLocked(1) || mp.txLocked(1, "a") {
+		t.Fatalf("aborted transaction is still locked")
+	}
+}
+
+func TestTxCommitChecked(t *testing.T) {
+	mp := newTestTxPartition(1)
+	if resp := mp.fsmTxPrepare(newTestRenameTx("tx1", 1, true)); resp.Status != proto.OpOk {
+		t.Fatalf("prepare: status(%v)", resp.Status)
+	}
+	// the replaced dentry is changed behind the lock, none of the items is applied
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "b", Inode: 12, Type: proto.Mode(0644)}, true)
+	if resp := mp.fsmTxCommit(&TxOp{TxID: "tx1", PrimaryID: 1, Now: 100}); resp.State != proto.TxStateAborted {
+		t.Fatalf("commit unapplicable transaction in primary: state(%v)", resp.State)
+	}
+	if d, status := mp.getDentry(&Dentry{ParentId: 1, Name: "a"}); status != proto.OpOk || d.Inode != 10 {
+		t.Fatalf("src dentry of aborted transaction: status(%v) dentry(%v)", status, d)
+	}
+	if d, status := mp.getDentry(&Dentry{ParentId: 1, Name: "b"}); status != proto.OpOk || d.Inode != 12 {
+		t.Fatalf("dst dentry of aborted transaction: status(%v) dentry(%v)", status, d)
+	}
+}
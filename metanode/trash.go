@@ -0,0 +1,237 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	purgeTrashInterval  = time.Hour
+	purgeTrashBatchSize = 1000
+)
+
+// updateTrash records the trash days of the volume fetched from the master.
+func (v *Vol) updateTrash(view *proto.SimpleVolView) {
+	v.Lock()
+	defer v.Unlock()
+	v.trashDays = view.TrashDays
+}
+
+func (v *Vol) trashEnabled() bool {
+	return v.getTrashDays() > 0
+}
+
+func (v *Vol) getTrashDays() uint32 {
+	v.RLock()
+	defer v.RUnlock()
+	return v.trashDays
+}
+
+// updateTrashSize sums up the size of the soft-deleted files of this partition, which are
+// marked by the clients with the delete time attribute when they are moved to the trash.
+// The files deleted before the trash is disabled are still counted until they are purged,
+// so the partitions of the volumes which never enable the trash are not scanned.
+func (mp *metaPartition) updateTrashSize() {
+	if !mp.vol.trashEnabled() && atomic.LoadUint64(&mp.trashSize) == 0 {
+		return
+	}
+	var size uint64
+	inodeTree := mp.inodeTree.GetTree()
+	mp.extendTree.GetTree().Ascend(func(i BtreeItem) bool {
+		extend := i.(*Extend)
+		if _, exist := extend.Get([]byte(proto.XAttrKeyTrashTime)); !exist {
+			return true
+		}
+		if item := inodeTree.Get(NewInode(extend.inode, 0)); item != nil {
+			ino := item.(*Inode)
+			ino.RLock()
+			size += ino.Size
+			ino.RUnlock()
+		}
+		return true
+	})
+	atomic.StoreUint64(&mp.trashSize, size)
+	log.LogDebugf("action[updateTrashSize] vol(%v) mp(%v) trash size(%v)", mp.config.VolName, mp.config.PartitionId, size)
+}
+
+// GetTrashSize returns the total size of the soft-deleted files of this partition.
+func (mp *metaPartition) GetTrashSize() uint64 {
+	return atomic.LoadUint64(&mp.trashSize)
+}
+
+// purgeTrashWorker purges the files which have been kept in the trash for the trash days of the volume.
+// It only runs on the leader of the partition holding the root directory, which holds the dentry of the
+// trash as well, so that the trash is purged once no matter how many clients mount the volume. The files
+// are kept if the trash is disabled, until they are purged by the operator.
+func (mp *metaPartition) purgeTrashWorker() {
+	if mp.config.Start > proto.RootIno {
+		return
+	}
+	t := time.NewTicker(purgeTrashInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+		}
+		if _, ok := mp.IsLeader(); !ok {
+			continue
+		}
+		days := mp.vol.getTrashDays()
+		if days == 0 {
+			continue
+		}
+		expiration := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+		if purged, err := mp.purgeTrash(expiration); err != nil {
+			log.LogWarnf("purgeTrashWorker: vol(%v) mp(%v) purged(%v) err(%v)", mp.config.VolName, mp.config.PartitionId, purged, err)
+		} else if purged > 0 {
+			log.LogInfof("purgeTrashWorker: vol(%v) mp(%v) purged(%v)", mp.config.VolName, mp.config.PartitionId, purged)
+		}
+	}
+}
+
+// purgeTrash deletes the files deleted before the expiration in batches. The trash directory may be
+// held by another partition, whose dentries are read and deleted remotely.
+func (mp *metaPartition) purgeTrash(expiration time.Time) (purged int, err error) {
+	trash, status := mp.getDentry(&Dentry{ParentId: proto.RootIno, Name: proto.TrashDirName})
+	if status != proto.OpOk || !proto.IsDir(trash.Type) {
+		return
+	}
+	views, err := masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName)
+	if err != nil {
+		return
+	}
+	pid := inodePartition(trash.Inode, views)
+	if pid == 0 {
+		return 0, fmt.Errorf("no meta partition of trash %v", trash.Inode)
+	}
+	req := &proto.ReadDirRequest{
+		VolName:     mp.config.VolName,
+		PartitionID: pid,
+		ParentID:    trash.Inode,
+		Limit:       purgeTrashBatchSize,
+	}
+	for {
+		resp := new(proto.ReadDirResponse)
+		if pid == mp.config.PartitionId {
+			resp = mp.readDir(req)
+		} else if err = mp.sendToPartition(pid, proto.OpMetaReadDir, req, resp); err != nil {
+			return
+		}
+		expired := expiredTrashEntries(resp.Children, expiration)
+		if len(expired) > 0 {
+			var deleted int
+			deleted, err = mp.deleteTrashEntries(pid, trash.Inode, expired, views)
+			purged += deleted
+			if err != nil {
+				return
+			}
+		}
+		if resp.NextMarker == "" {
+			return
+		}
+		req.Marker = resp.NextMarker
+	}
+}
+
+// expiredTrashEntries returns the files deleted before the expiration, the entries not created by the
+// trash are kept.
+func expiredTrashEntries(children []proto.Dentry, expiration time.Time) (expired []proto.Dentry) {
+	for _, d := range children {
+		if proto.IsDir(d.Type) {
+			continue
+		}
+		if _, deleteTime, ok := proto.ParseTrashEntryName(d.Name); ok && !deleteTime.After(expiration) {
+			expired = append(expired, d)
+		}
+	}
+	return
+}
+
+// deleteTrashEntries deletes the dentries of the expired files from the trash, and then unlinks and
+// evicts the inodes of them, the files in the retention period of the volume are skipped.
+func (mp *metaPartition) deleteTrashEntries(pid, trashIno uint64, entries []proto.Dentry,
+	views []*proto.MetaPartitionView) (deleted int, err error) {
+	now := Now.GetCurrentTime().Unix()
+	dentries := make(DentryBatch, 0, len(entries))
+	for _, d := range entries {
+		if mp.wormRetained(d.Inode, now) {
+			continue
+		}
+		dentries = append(dentries, &Dentry{ParentId: trashIno, Name: d.Name, Inode: d.Inode, Type: d.Type})
+	}
+	if len(dentries) == 0 {
+		return
+	}
+	ok, err := mp.deleteDentries(pid, trashIno, dentries)
+	files := make(map[uint64][]uint64) // partition id -> inodes
+	for i, d := range dentries {
+		if !ok[i] {
+			continue
+		}
+		deleted++
+		if ipid := inodePartition(d.Inode, views); ipid != 0 {
+			files[ipid] = append(files[ipid], d.Inode)
+		}
+	}
+	// the purged files are evicted to the free list, which deletes the extents of them
+	for ipid, inodes := range files {
+		if e := mp.unlinkAndEvict(ipid, inodes); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// deleteDentries deletes the dentries of the directory held by the given partition, and returns if
+// each of them is deleted. The dentries are deleted as many as possible if an error is returned.
+func (mp *metaPartition) deleteDentries(pid, parentID uint64, dentries DentryBatch) (ok []bool, err error) {
+	ok = make([]bool, len(dentries))
+	if pid == mp.config.PartitionId {
+		var val []byte
+		if val, err = dentries.Marshal(); err != nil {
+			return
+		}
+		var r interface{}
+		if r, err = mp.submit(opFSMDeleteDentryBatch, val); err != nil {
+			return
+		}
+		for i, resp := range r.([]*DentryResponse) {
+			ok[i] = resp.Status == proto.OpOk
+		}
+		return
+	}
+	// the batch is replied with an error if any dentry is not deleted, so the dentries are deleted one by one
+	for i, d := range dentries {
+		if e := mp.sendToPartition(pid, proto.OpMetaDeleteDentry, &proto.DeleteDentryRequest{
+			VolName:     mp.config.VolName,
+			PartitionID: pid,
+			ParentID:    parentID,
+			Name:        d.Name,
+		}, nil); e != nil {
+			err = e
+			continue
+		}
+		ok[i] = true
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestUpdateTrashSize(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+		vol:        NewVol(),
+	}
+	for i := uint64(1); i <= 3; i++ {
+		mp.inodeTree.ReplaceOrInsert(&Inode{Inode: i, Size: i * 100}, true)
+	}
+	trashed := NewExtend(2)
+	trashed.Put([]byte(proto.XAttrKeyTrashTime), []byte("1600000000"))
+	other := NewExtend(3)
+	other.Put([]byte("oss:etag"), []byte("d41d8cd98f00b204e9800998ecf8427e"))
+	mp.extendTree.ReplaceOrInsert(trashed, true)
+	mp.extendTree.ReplaceOrInsert(other, true)
+
+	mp.updateTrashSize()
+	if size := mp.GetTrashSize(); size != 0 {
+		t.Fatalf("trash size should not be counted while the trash is disabled: size(%v)", size)
+	}
+	mp.vol.updateTrash(&proto.SimpleVolView{TrashDays: 7})
+	mp.updateTrashSize()
+	if size := mp.GetTrashSize(); size != 200 {
+		t.Fatalf("unexpected trash size: expect(200) actual(%v)", size)
+	}
+	// the size is still counted after the trash is disabled, until the files are purged
+	mp.vol.updateTrash(&proto.SimpleVolView{})
+	mp.updateTrashSize()
+	if size := mp.GetTrashSize(); size != 200 {
+		t.Fatalf("unexpected trash size after disabled: expect(200) actual(%v)", size)
+	}
+	trashed.Remove([]byte(proto.XAttrKeyTrashTime))
+	mp.updateTrashSize()
+	if size := mp.GetTrashSize(); size != 0 {
+		t.Fatalf("unexpected trash size after purged: expect(0) actual(%v)", size)
+	}
+}
+
+func TestExpiredTrashEntries(t *testing.T) {
+	expiration := time.Unix(1600000000, 0)
+	children := []proto.Dentry{
+		{Name: proto.TrashEntryName(2, expiration.Add(-time.Hour)), Inode: 2, Type: proto.Mode(0644)},
+		{Name: proto.TrashEntryName(3, expiration), Inode: 3, Type: proto.Mode(0644)},
+		{Name: proto.TrashEntryName(4, expiration.Add(time.Second)), Inode: 4, Type: proto.Mode(0644)},
+		{Name: "notes.txt", Inode: 5, Type: proto.Mode(0644)},
+		{Name: proto.TrashEntryName(6, expiration.Add(-time.Hour)), Inode: 6, Type: proto.Mode(os.ModeDir | 0755)},
+	}
+	expired := expiredTrashEntries(children, expiration)
+	if len(expired) != 2 || expired[0].Inode != 2 || expired[1].Inode != 3 {
+		t.Fatalf("unexpected expired entries: %v", expired)
+	}
+	if inode, deleteTime, ok := proto.ParseTrashEntryName(children[0].Name); !ok || inode != 2 || !deleteTime.Equal(expiration.Add(-time.Hour)) {
+		t.Fatalf("unexpected parsed entry: inode(%v) time(%v) ok(%v)", inode, deleteTime, ok)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const secondsPerDay = 24 * 3600
+
+// updateWorm records the retention policy of the volume fetched from the master.
+func (v *Vol) updateWorm(view *proto.SimpleVolView) {
+	v.Lock()
+	defer v.Unlock()
+	v.wormLoaded = true
+	v.wormRetention = int64(view.WormRetentionDays) * secondsPerDay
+	v.wormOverrideUntil = view.WormOverrideUntil
+}
+
+func (v *Vol) wormPolicyLoaded() bool {
+	v.RLock()
+	defer v.RUnlock()
+	return v.wormLoaded
+}
+
+func (v *Vol) wormEnabled() bool {
+	v.RLock()
+	defer v.RUnlock()
+	return v.wormRetention > 0
+}
+
+// wormProtected returns if the inode is still in the retention period,
+// and if the retention is currently overridden by the operator.
+func (v *Vol) wormProtected(ino *Inode, now int64) (protected, overridden bool) {
+	v.RLock()
+	retention, overrideUntil := v.wormRetention, v.wormOverrideUntil
+	v.RUnlock()
+	if retention == 0 || !proto.IsRegular(ino.Type) {
+		return
+	}
+	ino.RLock()
+	createTime := ino.CreateTime
+	ino.RUnlock()
+	if createTime+retention <= now {
+		return
+	}
+	if overrideUntil > now {
+		return false, true
+	}
+	return true, false
+}
+
+// checkWorm refuses the operation which modifies or deletes a file in the retention period.
+// The operations allowed by an override are recorded in the audit log. The operations are refused
+// with OpAgain until the retention policy of the volume is fetched from the master, so that the files
+// are not left unprotected after the meta node starts.
+func (mp *metaPartition) checkWorm(ino *Inode, op string, p *Packet) (err error) {
+	if proto.IsRegular(ino.Type) && !mp.vol.wormPolicyLoaded() {
+		err = fmt.Errorf("retention policy of vol(%v) is not loaded", mp.config.VolName)
+		log.LogWarnf("action[checkWorm] op(%v) ino(%v) refused: %v", op, ino.Inode, err)
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	protected, overridden := mp.vol.wormProtected(ino, Now.GetCurrentTime().Unix())
+	if overridden {
+		log.LogWarnf("action[checkWorm] audit: vol(%v) mp(%v) ino(%v) op(%v) allowed by retention override, req(%v)",
+			mp.config.VolName, mp.config.PartitionId, ino.Inode, op, p.GetReqID())
+		return
+	}
+	if protected {
+		err = fmt.Errorf("inode(%v) of vol(%v) is in the retention period", ino.Inode, mp.config.VolName)
+		log.LogWarnf("action[checkWorm] op(%v) refused: %v", op, err)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+	}
+	return
+}
+
+// checkWormByID checks the inode of this partition if the operation modifies the existing content of it.
+// A nil modifies means the operation always modifies the inode. The inode which does not exist is
+// left to the operation itself.
+func (mp *metaPartition) checkWormByID(inode uint64, op string, modifies func(ino *Inode) bool, p *Packet) (err error) {
+	item := mp.inodeTree.Get(NewInode(inode, 0))
+	if item == nil {
+		return
+	}
+	ino := item.(*Inode)
+	if err = mp.checkInodeFlags(ino, op, modifies, p); err != nil {
+		return
+	}
+	if modifies != nil && !modifies(ino) {
+		return
+	}
+	return mp.checkWorm(ino, op, p)
+}
+
+// overwrites returns if any of the extent keys starts before the end of the file, which means the
+// existing content is rewritten instead of written once. The extent key which only grows the last
+// extent key of the file refers to the same data, so it is not an overwrite.
+func overwrites(extents ...proto.ExtentKey) func(ino *Inode) bool {
+	return func(ino *Inode) bool {
+		ino.RLock()
+		defer ino.RUnlock()
+		for _, ek := range extents {
+			if ek.FileOffset < ino.Size && !extendsLast(ino, ek) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// extendsLast returns if the extent key is the last extent key of the inode grown by the append.
+func extendsLast(ino *Inode, ek proto.ExtentKey) (extends bool) {
+	if ino.Extents == nil {
+		return
+	}
+	ino.Extents.Range(func(last proto.ExtentKey) bool {
+		extends = last.FileOffset == ek.FileOffset && last.PartitionId == ek.PartitionId &&
+			last.ExtentId == ek.ExtentId && last.ExtentOffset == ek.ExtentOffset &&
+			last.Size <= ek.Size && last.FileOffset+uint64(last.Size) >= ino.Size
+		return !extends
+	})
+	return
+}
+
+// resizes returns if the truncation changes the size of the file.
+func resizes(size uint64) func(ino *Inode) bool {
+	return func(ino *Inode) bool {
+		ino.RLock()
+		defer ino.RUnlock()
+		return ino.Size != size
+	}
+}
+
+// punches returns if the hole punched from the offset removes the data of the file.
+func punches(offset uint64) func(ino *Inode) bool {
+	return func(ino *Inode) bool {
+		ino.RLock()
+		defer ino.RUnlock()
+		return offset < ino.Size
+	}
+}
+
+// checkWormByDentry checks the inode that the dentry points to before the dentry is deleted or replaced.
+// The inode is only checked if it is held by this meta node, otherwise the unlink of the inode
+// will be refused by its own partition, so that the file data is retained anyway.
+func (mp *metaPartition) checkWormByDentry(parentID uint64, name, op string, p *Packet) (err error) {
+	dentry, status := mp.getDentry(&Dentry{ParentId: parentID, Name: name})
+	if status != proto.OpOk || !proto.IsRegular(dentry.Type) {
+		return
+	}
+	if ino := mp.manager.getLocalInode(dentry.Inode); ino != nil {
+		if err = mp.checkInodeFlags(ino, op, nil, p); err != nil {
+			return
+		}
+		return mp.checkWorm(ino, op, p)
+	}
+	return
+}
+
+// getLocalInode returns the inode if it belongs to one of the partitions on this meta node.
+func (m *metadataManager) getLocalInode(inode uint64) (ino *Inode) {
+	m.Range(func(id uint64, partition MetaPartition) bool {
+		conf := partition.GetBaseConfig()
+		if inode < conf.Start || inode > conf.End {
+			return true
+		}
+		if item := partition.GetInodeTree().Get(NewInode(inode, 0)); item != nil {
+			ino = item.(*Inode)
+		}
+		return false
+	})
+	return
+}
@@ -0,0 +1,53 @@
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestWormProtected(t *testing.T) {
+	var now int64 = 100 * secondsPerDay
+	v := NewVol()
+	file := &Inode{Inode: 1, Type: proto.Mode(0644), CreateTime: now - secondsPerDay}
+	dir := &Inode{Inode: 2, Type: proto.Mode(os.ModeDir | 0755), CreateTime: now - secondsPerDay}
+
+	if protected, _ := v.wormProtected(file, now); protected {
+		t.Fatalf("file should not be protected without retention")
+	}
+	v.updateWorm(&proto.SimpleVolView{WormRetentionDays: 2})
+	if protected, _ := v.wormProtected(file, now); !protected {
+		t.Fatalf("file should be protected in the retention period")
+	}
+	if protected, _ := v.wormProtected(dir, now); protected {
+		t.Fatalf("directory should not be protected")
+	}
+	if protected, _ := v.wormProtected(file, now+secondsPerDay); protected {
+		t.Fatalf("file should not be protected after the retention period")
+	}
+	v.updateWorm(&proto.SimpleVolView{WormRetentionDays: 2, WormOverrideUntil: now + 60})
+	if protected, overridden := v.wormProtected(file, now); protected || !overridden {
+		t.Fatalf("file should be allowed by the override: protected(%v) overridden(%v)", protected, overridden)
+	}
+}
+
+func TestWormOverwrites(t *testing.T) {
+	ino := &Inode{Inode: 1, Size: 2000, Extents: NewSortedExtents()}
+	ino.Extents.Append(proto.ExtentKey{FileOffset: 0, PartitionId: 1, ExtentId: 1, Size: 2000})
+	if overwrites(proto.ExtentKey{FileOffset: 0, PartitionId: 1, ExtentId: 1, Size: 3000})(ino) {
+		t.Fatalf("extending the last extent should not be an overwrite")
+	}
+	if overwrites(proto.ExtentKey{FileOffset: 2000, Size: 1000})(ino) {
+		t.Fatalf("appending should not be an overwrite")
+	}
+	if !overwrites(proto.ExtentKey{FileOffset: 0, PartitionId: 2, ExtentId: 2, Size: 1000})(ino) {
+		t.Fatalf("rewriting the head of the file should be an overwrite")
+	}
+	if !overwrites(proto.ExtentKey{FileOffset: 1500, PartitionId: 2, ExtentId: 2, Size: 1000})(ino) {
+		t.Fatalf("rewriting the tail of the file should be an overwrite")
+	}
+	if resizes(2000)(ino) || !resizes(0)(ino) {
+		t.Fatalf("unexpected resizes result")
+	}
+}
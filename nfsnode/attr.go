@@ -0,0 +1,254 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	fileHandleVersion = 1
+	fileHandleLen     = 17
+)
+
+// fileHandle identifies an inode of an export. It is encoded as a version byte followed by
+// the export ID and the inode number, so that the handles stay valid across restarts.
+type fileHandle struct {
+	exportID uint64
+	ino      uint64
+}
+
+func (fh fileHandle) encode() []byte {
+	b := make([]byte, fileHandleLen)
+	b[0] = fileHandleVersion
+	binary.BigEndian.PutUint64(b[1:9], fh.exportID)
+	binary.BigEndian.PutUint64(b[9:17], fh.ino)
+	return b
+}
+
+func decodeFileHandle(b []byte) (fh fileHandle, ok bool) {
+	if len(b) != fileHandleLen || b[0] != fileHandleVersion {
+		return
+	}
+	fh.exportID = binary.BigEndian.Uint64(b[1:9])
+	fh.ino = binary.BigEndian.Uint64(b[9:17])
+	return fh, fh.ino != 0
+}
+
+func fileType(mode uint32) uint32 {
+	osMode := proto.OsMode(mode)
+	switch {
+	case osMode.IsDir():
+		return nf3Dir
+	case osMode&os.ModeSymlink != 0:
+		return nf3Lnk
+	case osMode&os.ModeNamedPipe != 0:
+		return nf3Fifo
+	case osMode&os.ModeSocket != 0:
+		return nf3Sock
+	case osMode&os.ModeCharDevice != 0:
+		return nf3Chr
+	case osMode&os.ModeDevice != 0:
+		return nf3Blk
+	}
+	return nf3Reg
+}
+
+func writeTime(w *xdrWriter, t time.Time) {
+	w.uint32(uint32(t.Unix()))
+	w.uint32(uint32(t.Nanosecond()))
+}
+
+// writeFattr encodes the fattr3 of the inode, the size includes the data not flushed yet.
+func writeFattr(w *xdrWriter, export *nfsExport, vol *volume, info *proto.InodeInfo) {
+	size := vol.fileSize(info)
+	w.uint32(fileType(info.Mode))
+	w.uint32(nfsMode(info.Mode))
+	w.uint32(info.Nlink)
+	w.uint32(info.Uid)
+	w.uint32(info.Gid)
+	w.uint64(size)
+	w.uint64(size) // used
+	w.uint32(0)    // rdev
+	w.uint32(0)
+	w.uint64(export.id) // fsid
+	w.uint64(info.Inode)
+	writeTime(w, info.AccessTime)
+	writeTime(w, info.ModifyTime)
+	writeTime(w, info.CreateTime)
+}
+
+func writePostOpAttr(w *xdrWriter, export *nfsExport, vol *volume, info *proto.InodeInfo) {
+	if info == nil {
+		w.bool(false)
+		return
+	}
+	w.bool(true)
+	writeFattr(w, export, vol, info)
+}
+
+// writeWccData encodes the attributes of an object before and after an operation,
+// either of them is omitted if it is nil.
+func writeWccData(w *xdrWriter, export *nfsExport, vol *volume, pre, post *proto.InodeInfo) {
+	if pre == nil {
+		w.bool(false)
+	} else {
+		w.bool(true)
+		w.uint64(pre.Size)
+		writeTime(w, pre.ModifyTime)
+		writeTime(w, pre.CreateTime)
+	}
+	writePostOpAttr(w, export, vol, post)
+}
+
+func writePostOpFileHandle(w *xdrWriter, export *nfsExport, ino uint64) {
+	w.bool(true)
+	w.opaque(fileHandle{exportID: export.id, ino: ino}.encode())
+}
+
+// sattr is the decoded sattr3, the attributes to set on an inode.
+type sattr struct {
+	setMode  bool
+	mode     uint32
+	setUid   bool
+	uid      uint32
+	setGid   bool
+	gid      uint32
+	setSize  bool
+	size     uint64
+	atimeHow uint32
+	atime    time.Time
+	mtimeHow uint32
+	mtime    time.Time
+}
+
+func readTime(r *xdrReader) time.Time {
+	sec := r.uint32()
+	nsec := r.uint32()
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+func readSattr(r *xdrReader) *sattr {
+	sa := &sattr{}
+	if sa.setMode = r.bool(); sa.setMode {
+		sa.mode = r.uint32()
+	}
+	if sa.setUid = r.bool(); sa.setUid {
+		sa.uid = r.uint32()
+	}
+	if sa.setGid = r.bool(); sa.setGid {
+		sa.gid = r.uint32()
+	}
+	if sa.setSize = r.bool(); sa.setSize {
+		sa.size = r.uint64()
+	}
+	if sa.atimeHow = r.uint32(); sa.atimeHow == nfsSetToClientTime {
+		sa.atime = readTime(r)
+	}
+	if sa.mtimeHow = r.uint32(); sa.mtimeHow == nfsSetToClientTime {
+		sa.mtime = readTime(r)
+	}
+	if sa.atimeHow > nfsSetToClientTime || sa.mtimeHow > nfsSetToClientTime {
+		r.err = errXDRGarbage
+	}
+	return sa
+}
+
+// apply updates the attributes of the inode info and returns the valid bits to set through the meta SDK.
+func (sa *sattr) apply(info *proto.InodeInfo) (valid uint32) {
+	now := time.Now()
+	if sa.setMode {
+		info.Mode = inodeMode(sa.mode, proto.OsModeType(info.Mode))
+		valid |= proto.AttrMode
+	}
+	if sa.setUid {
+		info.Uid = sa.uid
+		valid |= proto.AttrUid
+	}
+	if sa.setGid {
+		info.Gid = sa.gid
+		valid |= proto.AttrGid
+	}
+	switch sa.atimeHow {
+	case nfsSetToServerTime:
+		info.AccessTime = now
+		valid |= proto.AttrAccessTime
+	case nfsSetToClientTime:
+		info.AccessTime = sa.atime
+		valid |= proto.AttrAccessTime
+	}
+	switch sa.mtimeHow {
+	case nfsSetToServerTime:
+		info.ModifyTime = now
+		valid |= proto.AttrModifyTime
+	case nfsSetToClientTime:
+		info.ModifyTime = sa.mtime
+		valid |= proto.AttrModifyTime
+	}
+	return
+}
+
+// nfsStatus converts the errors of the meta and data SDKs to the NFS status.
+func nfsStatus(err error) uint32 {
+	if err == nil {
+		return nfs3OK
+	}
+	errno, ok := err.(syscall.Errno)
+	if !ok {
+		return nfs3ErrIO
+	}
+	switch errno {
+	case syscall.EPERM:
+		return nfs3ErrPerm
+	case syscall.ENOENT:
+		return nfs3ErrNoent
+	case syscall.EACCES:
+		return nfs3ErrAcces
+	case syscall.EEXIST:
+		return nfs3ErrExist
+	case syscall.EXDEV:
+		return nfs3ErrXdev
+	case syscall.ENOTDIR:
+		return nfs3ErrNotdir
+	case syscall.EISDIR:
+		return nfs3ErrIsdir
+	case syscall.EINVAL:
+		return nfs3ErrInval
+	case syscall.EFBIG:
+		return nfs3ErrFbig
+	case syscall.ENOSPC, syscall.ENOMEM:
+		return nfs3ErrNospc
+	case syscall.EROFS:
+		return nfs3ErrRofs
+	case syscall.EMLINK:
+		return nfs3ErrMlink
+	case syscall.ENAMETOOLONG:
+		return nfs3ErrNametoolong
+	case syscall.ENOTEMPTY:
+		return nfs3ErrNotempty
+	case syscall.EDQUOT:
+		return nfs3ErrDquot
+	case syscall.EAGAIN:
+		return nfs3ErrJukebox
+	case syscall.ENOTSUP:
+		return nfs3ErrNotsupp
+	}
+	return nfs3ErrIO
+}
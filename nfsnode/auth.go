@@ -0,0 +1,124 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"os"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// The anonymous user the squashed root user and the AUTH_NONE callers are mapped to.
+const (
+	anonymousUid = 65534
+	anonymousGid = 65534
+)
+
+// Permission bits checked against the mode of an inode.
+const (
+	permRead  = 4
+	permWrite = 2
+	permExec  = 1
+)
+
+// credential is the identity of the caller carried by AUTH_UNIX.
+type credential struct {
+	uid  uint32
+	gid  uint32
+	gids []uint32
+}
+
+var anonymous = &credential{uid: anonymousUid, gid: anonymousGid}
+
+// effectiveCredential applies the AUTH_NONE and root squash mapping of the export.
+func effectiveCredential(cred *credential, export *nfsExport) *credential {
+	if cred == nil {
+		return anonymous
+	}
+	if cred.uid == 0 && export.RootSquash {
+		return anonymous
+	}
+	return cred
+}
+
+func (cred *credential) isRoot() bool {
+	return cred.uid == 0
+}
+
+func (cred *credential) inGroup(gid uint32) bool {
+	if cred.gid == gid {
+		return true
+	}
+	for _, g := range cred.gids {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// permitted checks the permission bits of the inode the same way as the local file systems do,
+// the root user is granted reading and writing and is granted executing if anyone may execute.
+func (cred *credential) permitted(info *proto.InodeInfo, perm uint32) bool {
+	mode := uint32(proto.OsMode(info.Mode).Perm())
+	if cred.isRoot() {
+		if perm&permExec == 0 || proto.IsDir(info.Mode) {
+			return true
+		}
+		return mode&0111 != 0
+	}
+	var granted uint32
+	switch {
+	case cred.uid == info.Uid:
+		granted = mode >> 6
+	case cred.inGroup(info.Gid):
+		granted = mode >> 3
+	default:
+		granted = mode
+	}
+	return granted&perm == perm
+}
+
+// nfsMode converts the mode of an inode to the mode of NFS, which carries the permission
+// and the set-user-ID, set-group-ID and sticky bits.
+func nfsMode(mode uint32) uint32 {
+	osMode := proto.OsMode(mode)
+	nm := uint32(osMode.Perm())
+	if osMode&os.ModeSetuid != 0 {
+		nm |= 04000
+	}
+	if osMode&os.ModeSetgid != 0 {
+		nm |= 02000
+	}
+	if osMode&os.ModeSticky != 0 {
+		nm |= 01000
+	}
+	return nm
+}
+
+// inodeMode converts the NFS mode to the mode of an inode of the given type.
+func inodeMode(nm uint32, fileType os.FileMode) uint32 {
+	osMode := fileType | os.FileMode(nm&0777)
+	if nm&04000 != 0 {
+		osMode |= os.ModeSetuid
+	}
+	if nm&02000 != 0 {
+		osMode |= os.ModeSetgid
+	}
+	if nm&01000 != 0 {
+		osMode |= os.ModeSticky
+	}
+	return proto.Mode(osMode)
+}
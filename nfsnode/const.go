@@ -0,0 +1,181 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+// Programs served by the NFS node.
+const (
+	progPortmap = 100000
+	progNFS     = 100003
+	progMount   = 100005
+
+	versPortmap = 2
+	versNFS     = 3
+	versMount   = 3
+)
+
+// Procedures of NFS version 3 (RFC 1813).
+const (
+	nfsProcNull        = 0
+	nfsProcGetattr     = 1
+	nfsProcSetattr     = 2
+	nfsProcLookup      = 3
+	nfsProcAccess      = 4
+	nfsProcReadlink    = 5
+	nfsProcRead        = 6
+	nfsProcWrite       = 7
+	nfsProcCreate      = 8
+	nfsProcMkdir       = 9
+	nfsProcSymlink     = 10
+	nfsProcMknod       = 11
+	nfsProcRemove      = 12
+	nfsProcRmdir       = 13
+	nfsProcRename      = 14
+	nfsProcLink        = 15
+	nfsProcReaddir     = 16
+	nfsProcReaddirplus = 17
+	nfsProcFsstat      = 18
+	nfsProcFsinfo      = 19
+	nfsProcPathconf    = 20
+	nfsProcCommit      = 21
+)
+
+// nfsstat3
+const (
+	nfs3OK             = 0
+	nfs3ErrPerm        = 1
+	nfs3ErrNoent       = 2
+	nfs3ErrIO          = 5
+	nfs3ErrNxio        = 6
+	nfs3ErrAcces       = 13
+	nfs3ErrExist       = 17
+	nfs3ErrXdev        = 18
+	nfs3ErrNotdir      = 20
+	nfs3ErrIsdir       = 21
+	nfs3ErrInval       = 22
+	nfs3ErrFbig        = 27
+	nfs3ErrNospc       = 28
+	nfs3ErrRofs        = 30
+	nfs3ErrMlink       = 31
+	nfs3ErrNametoolong = 63
+	nfs3ErrNotempty    = 66
+	nfs3ErrDquot       = 69
+	nfs3ErrStale       = 70
+	nfs3ErrBadhandle   = 10001
+	nfs3ErrNotSync     = 10002
+	nfs3ErrBadCookie   = 10003
+	nfs3ErrNotsupp     = 10004
+	nfs3ErrToosmall    = 10005
+	nfs3ErrServerfault = 10006
+	nfs3ErrJukebox     = 10008
+)
+
+// ftype3
+const (
+	nf3Reg  = 1
+	nf3Dir  = 2
+	nf3Blk  = 3
+	nf3Chr  = 4
+	nf3Lnk  = 5
+	nf3Sock = 6
+	nf3Fifo = 7
+)
+
+// stable_how
+const (
+	nfsUnstable = 0
+	nfsDataSync = 1
+	nfsFileSync = 2
+)
+
+// createmode3
+const (
+	nfsCreateUnchecked = 0
+	nfsCreateGuarded   = 1
+	nfsCreateExclusive = 2
+)
+
+// time_how
+const (
+	nfsDontChange      = 0
+	nfsSetToServerTime = 1
+	nfsSetToClientTime = 2
+)
+
+// Bits of ACCESS3.
+const (
+	access3Read    = 0x0001
+	access3Lookup  = 0x0002
+	access3Modify  = 0x0004
+	access3Extend  = 0x0008
+	access3Delete  = 0x0010
+	access3Execute = 0x0020
+)
+
+// Properties of FSINFO3.
+const (
+	fsf3Link        = 0x0001
+	fsf3Symlink     = 0x0002
+	fsf3Homogeneous = 0x0008
+	fsf3CanSetTime  = 0x0010
+)
+
+// Procedures of MOUNT version 3.
+const (
+	mountProcNull    = 0
+	mountProcMnt     = 1
+	mountProcDump    = 2
+	mountProcUmnt    = 3
+	mountProcUmntAll = 4
+	mountProcExport  = 5
+)
+
+// mountstat3
+const (
+	mnt3OK             = 0
+	mnt3ErrNoent       = 2
+	mnt3ErrAcces       = 13
+	mnt3ErrNotdir      = 20
+	mnt3ErrInval       = 22
+	mnt3ErrNametoolong = 63
+	mnt3ErrServerfault = 10006
+)
+
+// Procedures of PMAP version 2 (RFC 1833).
+const (
+	pmapProcNull    = 0
+	pmapProcSet     = 1
+	pmapProcUnset   = 2
+	pmapProcGetport = 3
+	pmapProcDump    = 4
+)
+
+const (
+	ipProtoTCP = 6
+	ipProtoUDP = 17
+)
+
+// Limits of the NFS node.
+const (
+	nfsMaxPathLen   = 1024
+	nfsMaxNameLen   = 255
+	nfsMaxHandleLen = 64
+	nfsMaxIOSize    = 1 << 20
+	nfsPrefIOSize   = 1 << 20
+	nfsDirPrefSize  = 64 << 10
+	nfsMaxFileSize  = 1<<63 - 1
+	nfsMaxReaddir   = 1 << 20
+	nfsMaxLinks     = 1<<32 - 1
+	nfsMaxFiles     = 1 << 40
+)
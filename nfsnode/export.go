@@ -0,0 +1,223 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"hash/fnv"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const exportRefreshInterval = 30 * time.Second
+
+// nfsExport is an export configured through the master, identified in the file handles by the hash of its path.
+type nfsExport struct {
+	proto.NFSExport
+	id      uint64
+	clients []*net.IPNet
+}
+
+func exportID(exportPath string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(exportPath))
+	return h.Sum64()
+}
+
+func newNFSExport(export *proto.NFSExport) (e *nfsExport, err error) {
+	e = &nfsExport{NFSExport: *export, id: exportID(export.Path)}
+	for _, client := range export.Clients {
+		var ipNet *net.IPNet
+		if _, ipNet, err = net.ParseCIDR(client); err != nil {
+			return nil, err
+		}
+		e.clients = append(e.clients, ipNet)
+	}
+	return
+}
+
+// allows returns whether the client of the address may mount and access the export.
+func (e *nfsExport) allows(ip net.IP) bool {
+	if len(e.clients) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range e.clients {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportManager keeps the exports in sync with the master and opens the exported volumes on demand.
+type exportManager struct {
+	mc       *master.MasterClient
+	masters  []string
+	exports  map[string]*nfsExport // export path -> export
+	byID     map[uint64]*nfsExport
+	volumes  map[string]*volume
+	mu       sync.RWMutex
+	openLock sync.Mutex
+	stopC    chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newExportManager(masters []string) *exportManager {
+	return &exportManager{
+		mc:      master.NewMasterClient(masters, false),
+		masters: masters,
+		exports: make(map[string]*nfsExport),
+		byID:    make(map[uint64]*nfsExport),
+		volumes: make(map[string]*volume),
+		stopC:   make(chan struct{}),
+	}
+}
+
+// load replaces the exports and closes the volumes which are no longer exported.
+func (m *exportManager) load(exports []*proto.NFSExport) {
+	newExports := make(map[string]*nfsExport, len(exports))
+	newByID := make(map[uint64]*nfsExport, len(exports))
+	exported := make(map[string]bool)
+	for _, export := range exports {
+		e, err := newNFSExport(export)
+		if err != nil {
+			log.LogWarnf("load: skip export(%v) vol(%v) err(%v)", export.Path, export.Volume, err)
+			continue
+		}
+		newExports[e.Path] = e
+		newByID[e.id] = e
+		exported[e.Volume] = true
+	}
+	unused := make([]*volume, 0)
+	m.mu.Lock()
+	m.exports = newExports
+	m.byID = newByID
+	for name, vol := range m.volumes {
+		if !exported[name] {
+			unused = append(unused, vol)
+			delete(m.volumes, name)
+		}
+	}
+	m.mu.Unlock()
+	for _, vol := range unused {
+		log.LogInfof("load: close unexported vol(%v)", vol.name)
+		vol.close()
+	}
+}
+
+func (m *exportManager) refresh() (err error) {
+	var exports []*proto.NFSExport
+	if exports, err = m.mc.AdminAPI().ListNFSExports(); err != nil {
+		log.LogErrorf("refresh: list exports from master err(%v)", err)
+		return
+	}
+	m.load(exports)
+	return
+}
+
+func (m *exportManager) start() (err error) {
+	if err = m.refresh(); err != nil {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(exportRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.refresh()
+			case <-m.stopC:
+				return
+			}
+		}
+	}()
+	return
+}
+
+func (m *exportManager) stop() {
+	close(m.stopC)
+	m.wg.Wait()
+	m.mu.Lock()
+	volumes := m.volumes
+	m.volumes = make(map[string]*volume)
+	m.mu.Unlock()
+	for _, vol := range volumes {
+		vol.close()
+	}
+}
+
+func (m *exportManager) exportByPath(exportPath string) *nfsExport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.exports[exportPath]
+}
+
+func (m *exportManager) exportByID(id uint64) *nfsExport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.byID[id]
+}
+
+// list returns the exports sorted by the export path.
+func (m *exportManager) list() (exports []*nfsExport) {
+	m.mu.RLock()
+	exports = make([]*nfsExport, 0, len(m.exports))
+	for _, e := range m.exports {
+		exports = append(exports, e)
+	}
+	m.mu.RUnlock()
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].Path < exports[j].Path
+	})
+	return
+}
+
+// volume returns the opened volume of the export, the volume is opened at the first access.
+func (m *exportManager) volume(export *nfsExport) (vol *volume, err error) {
+	m.mu.RLock()
+	vol = m.volumes[export.Volume]
+	m.mu.RUnlock()
+	if vol != nil {
+		return
+	}
+
+	m.openLock.Lock()
+	defer m.openLock.Unlock()
+	m.mu.RLock()
+	vol = m.volumes[export.Volume]
+	m.mu.RUnlock()
+	if vol != nil {
+		return
+	}
+	if vol, err = openVolume(export.Volume, m.masters); err != nil {
+		log.LogErrorf("volume: open vol(%v) err(%v)", export.Volume, err)
+		return
+	}
+	m.mu.Lock()
+	m.volumes[export.Volume] = vol
+	m.mu.Unlock()
+	log.LogInfof("volume: vol(%v) opened", export.Volume)
+	return
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestExports(t *testing.T) {
+	m := newExportManager([]string{"127.0.0.1:17010"})
+	m.load([]*proto.NFSExport{
+		{Path: "/ltptest", Volume: "ltptest", Clients: []string{"192.168.0.0/16"}},
+		{Path: "/data/archive", Volume: "archive", ReadOnly: true},
+		{Path: "/bad", Volume: "bad", Clients: []string{"10.0.0.1"}},
+	})
+	if exports := m.list(); len(exports) != 2 || exports[0].Path != "/data/archive" {
+		t.Fatalf("unexpected exports %v", exports)
+	}
+
+	export := m.exportByPath("/ltptest")
+	if export == nil || m.exportByID(exportID("/ltptest")) != export {
+		t.Fatalf("export is not found by path and id")
+	}
+	if !export.allows(net.ParseIP("192.168.1.10")) || export.allows(net.ParseIP("10.1.1.1")) {
+		t.Errorf("unexpected client matching")
+	}
+	if !m.exportByPath("/data/archive").allows(net.ParseIP("10.1.1.1")) {
+		t.Errorf("export without clients denies a client")
+	}
+
+	s := &mountService{exports: m}
+	for dirPath, expected := range map[string][2]string{
+		"/ltptest":             {"/ltptest", ""},
+		"/ltptest/":            {"/ltptest", ""},
+		"/ltptest/a/b":         {"/ltptest", "a/b"},
+		"/data/archive/2020":   {"/data/archive", "2020"},
+		"/data":                {"", ""},
+		"/ltptest2":            {"", ""},
+		"/data/archive/../../": {"", ""},
+	} {
+		e, subdir := s.findExport(dirPath)
+		var exportPath string
+		if e != nil {
+			exportPath = e.Path
+		}
+		if exportPath != expected[0] || subdir != expected[1] {
+			t.Errorf("findExport(%v): got (%v, %v) want %v", dirPath, exportPath, subdir, expected)
+		}
+	}
+}
+
+func TestFileHandle(t *testing.T) {
+	fh := fileHandle{exportID: exportID("/ltptest"), ino: 1 << 33}
+	decoded, ok := decodeFileHandle(fh.encode())
+	if !ok || decoded != fh {
+		t.Errorf("decode: got %v(%v) want %v", decoded, ok, fh)
+	}
+	if _, ok = decodeFileHandle(fh.encode()[1:]); ok {
+		t.Errorf("short handle is decoded")
+	}
+	b := fh.encode()
+	b[0] = 0
+	if _, ok = decodeFileHandle(b); ok {
+		t.Errorf("handle of unknown version is decoded")
+	}
+}
+
+func TestPermission(t *testing.T) {
+	info := &proto.InodeInfo{Mode: inodeMode(0750, 0), Uid: 1000, Gid: 100}
+	owner := &credential{uid: 1000, gid: 1000}
+	member := &credential{uid: 1001, gid: 1001, gids: []uint32{100}}
+	other := &credential{uid: 1002, gid: 1002}
+	root := &credential{}
+	cases := []struct {
+		cred     *credential
+		perm     uint32
+		expected bool
+	}{
+		{owner, permRead | permWrite | permExec, true},
+		{member, permRead | permExec, true},
+		{member, permWrite, false},
+		{other, permRead, false},
+		{root, permRead | permWrite, true},
+		{root, permExec, true},
+	}
+	for i, c := range cases {
+		if got := c.cred.permitted(info, c.perm); got != c.expected {
+			t.Errorf("case %v: got %v want %v", i, got, c.expected)
+		}
+	}
+	if root.permitted(&proto.InodeInfo{Mode: inodeMode(0644, 0)}, permExec) {
+		t.Errorf("root may execute a file nobody may execute")
+	}
+
+	squashed := effectiveCredential(root, &nfsExport{NFSExport: proto.NFSExport{RootSquash: true}})
+	if squashed.uid != anonymousUid || effectiveCredential(nil, &nfsExport{}) != anonymous {
+		t.Errorf("root or AUTH_NONE caller is not mapped to the anonymous user")
+	}
+
+	mode := inodeMode(04755, os.ModeDir)
+	if !proto.IsDir(mode) || nfsMode(mode) != 04755 {
+		t.Errorf("mode conversion: got %o", nfsMode(mode))
+	}
+}
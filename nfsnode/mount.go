@@ -0,0 +1,134 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"path"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// mountService implements MOUNT version 3, which hands out the root file handles of the exports.
+// The mounts are not tracked, so DUMP always returns an empty list.
+type mountService struct {
+	exports *exportManager
+}
+
+func (s *mountService) program() *rpcProgram {
+	return &rpcProgram{
+		prog: progMount,
+		vers: versMount,
+		procs: map[uint32]rpcProcedure{
+			mountProcNull:    rpcNull,
+			mountProcMnt:     s.mnt,
+			mountProcDump:    s.dump,
+			mountProcUmnt:    s.umnt,
+			mountProcUmntAll: rpcNull,
+			mountProcExport:  s.export,
+		},
+	}
+}
+
+// findExport returns the export of the mounted path and the subdirectory of the export to mount.
+func (s *mountService) findExport(dirPath string) (export *nfsExport, subdir string) {
+	dirPath = path.Clean("/" + dirPath)
+	for prefix := dirPath; ; prefix = path.Dir(prefix) {
+		if export = s.exports.exportByPath(prefix); export != nil {
+			return export, strings.TrimPrefix(dirPath[len(prefix):], "/")
+		}
+		if prefix == "/" {
+			return nil, ""
+		}
+	}
+}
+
+func (s *mountService) mnt(call *rpcCall, w *xdrWriter) error {
+	dirPath := call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+
+	ino, export, status := s.resolveMount(call, dirPath)
+	w.uint32(status)
+	if status != mnt3OK {
+		log.LogWarnf("mnt: client(%v) path(%v) status(%v)", call.remote, dirPath, status)
+		return nil
+	}
+	w.opaque(fileHandle{exportID: export.id, ino: ino}.encode())
+	w.uint32(1)
+	w.uint32(authFlavorUnix)
+	log.LogInfof("mnt: client(%v) path(%v) vol(%v) ino(%v)", call.remote, dirPath, export.Volume, ino)
+	return nil
+}
+
+func (s *mountService) resolveMount(call *rpcCall, dirPath string) (ino uint64, export *nfsExport, status uint32) {
+	export, subdir := s.findExport(dirPath)
+	if export == nil {
+		return 0, nil, mnt3ErrNoent
+	}
+	if !export.allows(call.remote) {
+		return 0, nil, mnt3ErrAcces
+	}
+	vol, err := s.exports.volume(export)
+	if err != nil {
+		return 0, nil, mnt3ErrServerfault
+	}
+	ino = proto.RootIno
+	if subdir == "" {
+		return ino, export, mnt3OK
+	}
+	for _, name := range strings.Split(subdir, "/") {
+		child, mode, err := vol.mw.Lookup_ll(ino, name)
+		if err != nil {
+			return 0, nil, mnt3ErrNoent
+		}
+		if !proto.IsDir(mode) {
+			return 0, nil, mnt3ErrNotdir
+		}
+		vol.setParent(child, ino)
+		ino = child
+	}
+	return ino, export, mnt3OK
+}
+
+func (s *mountService) dump(call *rpcCall, w *xdrWriter) error {
+	w.bool(false)
+	return nil
+}
+
+func (s *mountService) umnt(call *rpcCall, w *xdrWriter) error {
+	dirPath := call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+	log.LogInfof("umnt: client(%v) path(%v)", call.remote, dirPath)
+	return nil
+}
+
+func (s *mountService) export(call *rpcCall, w *xdrWriter) error {
+	for _, export := range s.exports.list() {
+		w.bool(true)
+		w.string(export.Path)
+		for _, client := range export.Clients {
+			w.bool(true)
+			w.string(client)
+		}
+		w.bool(false)
+	}
+	w.bool(false)
+	return nil
+}
@@ -0,0 +1,988 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// nfsService implements NFS version 3 on top of the exported volumes.
+type nfsService struct {
+	exports *exportManager
+	// the clients resend the unstable writes if the verifier changes, which happens on restart
+	verifier []byte
+}
+
+func newNFSService(exports *exportManager) *nfsService {
+	s := &nfsService{exports: exports, verifier: make([]byte, 8)}
+	binary.BigEndian.PutUint64(s.verifier, uint64(time.Now().UnixNano()))
+	return s
+}
+
+func (s *nfsService) program() *rpcProgram {
+	return &rpcProgram{
+		prog: progNFS,
+		vers: versNFS,
+		procs: map[uint32]rpcProcedure{
+			nfsProcNull:        rpcNull,
+			nfsProcGetattr:     s.getattr,
+			nfsProcSetattr:     s.setattr,
+			nfsProcLookup:      s.lookup,
+			nfsProcAccess:      s.access,
+			nfsProcReadlink:    s.readlink,
+			nfsProcRead:        s.read,
+			nfsProcWrite:       s.write,
+			nfsProcCreate:      s.create,
+			nfsProcMkdir:       s.mkdir,
+			nfsProcSymlink:     s.symlink,
+			nfsProcMknod:       s.mknod,
+			nfsProcRemove:      s.remove,
+			nfsProcRmdir:       s.rmdir,
+			nfsProcRename:      s.rename,
+			nfsProcLink:        s.link,
+			nfsProcReaddir:     s.readdir,
+			nfsProcReaddirplus: s.readdirplus,
+			nfsProcFsstat:      s.fsstat,
+			nfsProcFsinfo:      s.fsinfo,
+			nfsProcPathconf:    s.pathconf,
+			nfsProcCommit:      s.commit,
+		},
+	}
+}
+
+func rpcNull(call *rpcCall, w *xdrWriter) error {
+	return nil
+}
+
+// nfsRequest is the export, volume, caller and inode resolved from the file handle of a call.
+type nfsRequest struct {
+	export *nfsExport
+	vol    *volume
+	cred   *credential
+	ino    uint64
+}
+
+func (s *nfsService) resolve(call *rpcCall, handle []byte) (req *nfsRequest, status uint32) {
+	fh, ok := decodeFileHandle(handle)
+	if !ok {
+		return nil, nfs3ErrBadhandle
+	}
+	export := s.exports.exportByID(fh.exportID)
+	if export == nil {
+		return nil, nfs3ErrStale
+	}
+	if !export.allows(call.remote) {
+		log.LogWarnf("resolve: export(%v) denies client(%v)", export.Path, call.remote)
+		return nil, nfs3ErrAcces
+	}
+	vol, err := s.exports.volume(export)
+	if err != nil {
+		return nil, nfs3ErrIO
+	}
+	req = &nfsRequest{
+		export: export,
+		vol:    vol,
+		cred:   effectiveCredential(call.cred, export),
+		ino:    fh.ino,
+	}
+	return req, nfs3OK
+}
+
+// inodeGet returns the inode referred by a file handle, an inode which no longer exists is stale.
+func (req *nfsRequest) inodeGet(ino uint64) (info *proto.InodeInfo, status uint32) {
+	info, err := req.vol.mw.InodeGet_ll(ino)
+	if err == syscall.ENOENT || (err == nil && info == nil) {
+		return nil, nfs3ErrStale
+	}
+	if err != nil {
+		return nil, nfsStatus(err)
+	}
+	return info, nfs3OK
+}
+
+// attr returns the current attributes of the inode, or nil if they are not available.
+func (req *nfsRequest) attr(ino uint64) *proto.InodeInfo {
+	info, _ := req.inodeGet(ino)
+	return info
+}
+
+func (req *nfsRequest) writeAttr(w *xdrWriter, info *proto.InodeInfo) {
+	if req == nil {
+		w.bool(false)
+		return
+	}
+	writePostOpAttr(w, req.export, req.vol, info)
+}
+
+func (req *nfsRequest) writeWcc(w *xdrWriter, pre, post *proto.InodeInfo) {
+	if req == nil {
+		writeWccData(w, nil, nil, nil, nil)
+		return
+	}
+	writeWccData(w, req.export, req.vol, pre, post)
+}
+
+func (req *nfsRequest) isOwner(info *proto.InodeInfo) bool {
+	return req.cred.isRoot() || req.cred.uid == info.Uid
+}
+
+func checkName(name string) uint32 {
+	if len(name) > nfsMaxNameLen {
+		return nfs3ErrNametoolong
+	}
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return nfs3ErrInval
+	}
+	return nfs3OK
+}
+
+// resolveDirUpdate resolves the directory of a call which modifies its entries and checks the permission.
+func (s *nfsService) resolveDirUpdate(call *rpcCall, handle []byte, name string) (req *nfsRequest, dir *proto.InodeInfo, status uint32) {
+	if req, status = s.resolve(call, handle); status != nfs3OK {
+		return
+	}
+	if dir, status = req.inodeGet(req.ino); status != nfs3OK {
+		return
+	}
+	if !proto.IsDir(dir.Mode) {
+		status = nfs3ErrNotdir
+		return
+	}
+	if req.export.ReadOnly {
+		status = nfs3ErrRofs
+		return
+	}
+	if status = checkName(name); status != nfs3OK {
+		return
+	}
+	if !req.cred.permitted(dir, permWrite|permExec) {
+		status = nfs3ErrAcces
+	}
+	return
+}
+
+// checkSticky checks the restricted deletion of the entries in a directory with the sticky bit.
+func (req *nfsRequest) checkSticky(dir *proto.InodeInfo, child uint64) uint32 {
+	if proto.OsMode(dir.Mode)&os.ModeSticky == 0 || req.isOwner(dir) {
+		return nfs3OK
+	}
+	info, status := req.inodeGet(child)
+	if status != nfs3OK {
+		return status
+	}
+	if !req.isOwner(info) {
+		return nfs3ErrAcces
+	}
+	return nfs3OK
+}
+
+func (s *nfsService) getattr(call *rpcCall, w *xdrWriter) error {
+	handle := call.args.opaque(nfsMaxHandleLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+	var info *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	w.uint32(status)
+	if status == nfs3OK {
+		writeFattr(w, req.export, req.vol, info)
+	}
+	return nil
+}
+
+// checkSattr checks whether the caller may set the attributes, the same as chmod, chown, truncate and utimes.
+func (req *nfsRequest) checkSattr(info *proto.InodeInfo, sa *sattr) uint32 {
+	if sa.setMode && !req.isOwner(info) {
+		return nfs3ErrPerm
+	}
+	if sa.setUid && sa.uid != info.Uid && !req.cred.isRoot() {
+		return nfs3ErrPerm
+	}
+	if sa.setGid && sa.gid != info.Gid && !req.cred.isRoot() && !(req.isOwner(info) && req.cred.inGroup(sa.gid)) {
+		return nfs3ErrPerm
+	}
+	if sa.setSize {
+		if proto.IsDir(info.Mode) {
+			return nfs3ErrIsdir
+		}
+		if !proto.IsRegular(info.Mode) {
+			return nfs3ErrInval
+		}
+		if !req.isOwner(info) && !req.cred.permitted(info, permWrite) {
+			return nfs3ErrAcces
+		}
+	}
+	if (sa.atimeHow == nfsSetToClientTime || sa.mtimeHow == nfsSetToClientTime) && !req.isOwner(info) {
+		return nfs3ErrPerm
+	}
+	if (sa.atimeHow == nfsSetToServerTime || sa.mtimeHow == nfsSetToServerTime) &&
+		!req.isOwner(info) && !req.cred.permitted(info, permWrite) {
+		return nfs3ErrAcces
+	}
+	return nfs3OK
+}
+
+// applySattr sets the attributes of the inode, the size is changed through the stream of the file.
+func (req *nfsRequest) applySattr(info *proto.InodeInfo, sa *sattr) uint32 {
+	ino := info.Inode
+	if sa.setSize {
+		if _, err := req.vol.acquireStream(ino); err != nil {
+			return nfsStatus(err)
+		}
+		err := req.vol.ec.Flush(ino)
+		if err == nil {
+			err = req.vol.ec.Truncate(ino, int(sa.size))
+		}
+		req.vol.releaseStream(ino, false)
+		if err != nil {
+			log.LogErrorf("applySattr: vol(%v) ino(%v) truncate(%v) err(%v)", req.vol.name, ino, sa.size, err)
+			return nfsStatus(err)
+		}
+		req.vol.ec.RefreshExtentsCache(ino)
+	}
+	attr := *info
+	if valid := sa.apply(&attr); valid != 0 {
+		err := req.vol.mw.Setattr(ino, valid, attr.Mode, attr.Uid, attr.Gid, attr.AccessTime.Unix(), attr.ModifyTime.Unix())
+		if err != nil {
+			log.LogErrorf("applySattr: vol(%v) ino(%v) valid(%v) err(%v)", req.vol.name, ino, valid, err)
+			return nfsStatus(err)
+		}
+	}
+	return nfs3OK
+}
+
+func (s *nfsService) setattr(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	sa := readSattr(r)
+	guard := r.bool()
+	var guardCtime time.Time
+	if guard {
+		guardCtime = readTime(r)
+	}
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var pre, post *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		pre, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK && req.export.ReadOnly {
+		status = nfs3ErrRofs
+	}
+	if status == nfs3OK && guard && guardCtime.Unix() != pre.CreateTime.Unix() {
+		status = nfs3ErrNotSync
+	}
+	if status == nfs3OK {
+		status = req.checkSattr(pre, sa)
+	}
+	if status == nfs3OK {
+		status = req.applySattr(pre, sa)
+	}
+	if req != nil {
+		post = req.attr(req.ino)
+	}
+	w.uint32(status)
+	req.writeWcc(w, pre, post)
+	return nil
+}
+
+// lookupName resolves a name in the directory including "." and "..".
+func (req *nfsRequest) lookupName(dir uint64, name string) (ino uint64, status uint32) {
+	switch name {
+	case ".":
+		return dir, nfs3OK
+	case "..":
+		if dir == proto.RootIno {
+			return dir, nfs3OK
+		}
+		parent, ok := req.vol.getParent(dir)
+		if !ok {
+			return 0, nfs3ErrNoent
+		}
+		return parent, nfs3OK
+	}
+	ino, mode, err := req.vol.mw.Lookup_ll(dir, name)
+	if err != nil {
+		return 0, nfsStatus(err)
+	}
+	if proto.IsDir(mode) {
+		req.vol.setParent(ino, dir)
+	}
+	return ino, nfs3OK
+}
+
+func (s *nfsService) lookup(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	name := r.string(nfsMaxPathLen)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var (
+		dir, info *proto.InodeInfo
+		ino       uint64
+	)
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		dir, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK && !proto.IsDir(dir.Mode) {
+		status = nfs3ErrNotdir
+	}
+	if status == nfs3OK && len(name) > nfsMaxNameLen {
+		status = nfs3ErrNametoolong
+	}
+	if status == nfs3OK && !req.cred.permitted(dir, permExec) {
+		status = nfs3ErrAcces
+	}
+	if status == nfs3OK {
+		ino, status = req.lookupName(req.ino, name)
+	}
+	if status == nfs3OK {
+		if info, status = req.inodeGet(ino); status == nfs3ErrStale {
+			status = nfs3ErrNoent
+		}
+	}
+	w.uint32(status)
+	if status == nfs3OK {
+		w.opaque(fileHandle{exportID: req.export.id, ino: ino}.encode())
+		req.writeAttr(w, info)
+	}
+	req.writeAttr(w, dir)
+	return nil
+}
+
+func (s *nfsService) access(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	requested := r.uint32()
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var info *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	if status != nfs3OK {
+		return nil
+	}
+	var granted uint32
+	cred := req.cred
+	if cred.permitted(info, permRead) {
+		granted |= access3Read
+	}
+	writable := !req.export.ReadOnly && cred.permitted(info, permWrite)
+	if proto.IsDir(info.Mode) {
+		if cred.permitted(info, permExec) {
+			granted |= access3Lookup
+			if writable {
+				granted |= access3Modify | access3Extend | access3Delete
+			}
+		}
+	} else {
+		if writable {
+			granted |= access3Modify | access3Extend
+		}
+		if cred.permitted(info, permExec) {
+			granted |= access3Execute
+		}
+	}
+	w.uint32(requested & granted)
+	return nil
+}
+
+func (s *nfsService) readlink(call *rpcCall, w *xdrWriter) error {
+	handle := call.args.opaque(nfsMaxHandleLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+
+	var info *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK && !proto.IsSymlink(info.Mode) {
+		status = nfs3ErrInval
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	if status == nfs3OK {
+		w.string(string(info.Target))
+	}
+	return nil
+}
+
+// checkFileIO checks the file read or written by a call, the owner is always allowed to access
+// its file the same as the local file systems allow it to access the file opened before chmod.
+func (req *nfsRequest) checkFileIO(info *proto.InodeInfo, perm uint32) uint32 {
+	if proto.IsDir(info.Mode) {
+		return nfs3ErrIsdir
+	}
+	if !proto.IsRegular(info.Mode) {
+		return nfs3ErrInval
+	}
+	if !req.isOwner(info) && !req.cred.permitted(info, perm) {
+		return nfs3ErrAcces
+	}
+	return nfs3OK
+}
+
+func (s *nfsService) read(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	offset := r.uint64()
+	count := r.uint32()
+	if r.err != nil {
+		return errXDRGarbage
+	}
+	if count > nfsMaxIOSize {
+		count = nfsMaxIOSize
+	}
+
+	var (
+		info *proto.InodeInfo
+		data []byte
+		eof  bool
+	)
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK {
+		status = req.checkFileIO(info, permRead)
+	}
+	if status == nfs3OK {
+		data, eof, status = req.readFile(info, offset, int(count))
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	if status == nfs3OK {
+		w.uint32(uint32(len(data)))
+		w.bool(eof)
+		w.opaque(data)
+	}
+	return nil
+}
+
+func (req *nfsRequest) readFile(info *proto.InodeInfo, offset uint64, count int) (data []byte, eof bool, status uint32) {
+	vol, ino := req.vol, info.Inode
+	opened, err := vol.acquireStream(ino)
+	if err != nil {
+		return nil, false, nfsStatus(err)
+	}
+	defer vol.releaseStream(ino, false)
+	if !opened {
+		vol.refreshExtents(info)
+	}
+	size := vol.fileSize(info)
+	if offset >= size {
+		return nil, true, nfs3OK
+	}
+	if uint64(count) > size-offset {
+		count = int(size - offset)
+	}
+	data = make([]byte, count)
+	n, err := vol.ec.Read(ino, data, int(offset), count)
+	if err != nil && err != io.EOF {
+		log.LogErrorf("readFile: vol(%v) ino(%v) offset(%v) count(%v) err(%v)", vol.name, ino, offset, count, err)
+		return nil, false, nfs3ErrIO
+	}
+	data = data[:n]
+	return data, offset+uint64(n) >= size, nfs3OK
+}
+
+func (s *nfsService) write(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	offset := r.uint64()
+	count := r.uint32()
+	stable := r.uint32()
+	data := r.opaque(nfsMaxIOSize)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var pre, post *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		pre, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK && req.export.ReadOnly {
+		status = nfs3ErrRofs
+	}
+	if status == nfs3OK {
+		status = req.checkFileIO(pre, permWrite)
+	}
+	if status == nfs3OK && int(count) > len(data) {
+		status = nfs3ErrInval
+	}
+	committed := uint32(nfsFileSync)
+	if status == nfs3OK {
+		data = data[:count]
+		if stable == nfsUnstable {
+			committed = nfsUnstable
+		}
+		status = req.writeFile(pre.Inode, offset, data, committed == nfsFileSync)
+	}
+	if status == nfs3OK {
+		// the size is taken from the open stream, the other attributes are not changed by writing
+		attr := *pre
+		attr.ModifyTime = time.Now()
+		post = &attr
+	}
+	w.uint32(status)
+	req.writeWcc(w, pre, post)
+	if status == nfs3OK {
+		w.uint32(count)
+		w.uint32(committed)
+		w.fixedOpaque(s.verifier)
+	}
+	return nil
+}
+
+func (req *nfsRequest) writeFile(ino uint64, offset uint64, data []byte, sync bool) uint32 {
+	vol := req.vol
+	if _, err := vol.acquireStream(ino); err != nil {
+		return nfsStatus(err)
+	}
+	_, err := vol.ec.Write(ino, int(offset), data, 0)
+	vol.releaseStream(ino, err == nil)
+	if err != nil {
+		log.LogErrorf("writeFile: vol(%v) ino(%v) offset(%v) size(%v) err(%v)", vol.name, ino, offset, len(data), err)
+		return nfs3ErrIO
+	}
+	if sync {
+		if err = vol.flushStream(ino); err != nil {
+			log.LogErrorf("writeFile: vol(%v) ino(%v) flush err(%v)", vol.name, ino, err)
+			return nfs3ErrIO
+		}
+	}
+	return nfs3OK
+}
+
+func (s *nfsService) commit(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	r.uint64() // offset
+	r.uint32() // count
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var pre, post *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		pre, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK {
+		if err := req.vol.flushStream(req.ino); err != nil {
+			log.LogErrorf("commit: vol(%v) ino(%v) err(%v)", req.vol.name, req.ino, err)
+			status = nfs3ErrIO
+		}
+	}
+	if status == nfs3OK {
+		post = req.attr(req.ino)
+	}
+	w.uint32(status)
+	req.writeWcc(w, pre, post)
+	if status == nfs3OK {
+		w.fixedOpaque(s.verifier)
+	}
+	return nil
+}
+
+// writeCreated encodes the result of CREATE, MKDIR and SYMLINK.
+func writeCreated(w *xdrWriter, req *nfsRequest, status uint32, info, dirPre *proto.InodeInfo) {
+	var dirPost *proto.InodeInfo
+	if req != nil {
+		dirPost = req.attr(req.ino)
+	}
+	w.uint32(status)
+	if status == nfs3OK {
+		writePostOpFileHandle(w, req.export, info.Inode)
+		req.writeAttr(w, info)
+	}
+	req.writeWcc(w, dirPre, dirPost)
+}
+
+// newInode creates an inode owned by the caller in the directory.
+func (req *nfsRequest) newInode(dir *proto.InodeInfo, name string, mode uint32, target []byte) (info *proto.InodeInfo, status uint32) {
+	gid := req.cred.gid
+	if proto.OsMode(dir.Mode)&os.ModeSetgid != 0 {
+		gid = dir.Gid
+		if proto.IsDir(mode) {
+			mode |= proto.Mode(os.ModeSetgid)
+		}
+	}
+	info, err := req.vol.mw.Create_ll(dir.Inode, name, mode, req.cred.uid, gid, target)
+	if err != nil {
+		return nil, nfsStatus(err)
+	}
+	if proto.IsDir(mode) {
+		req.vol.setParent(info.Inode, dir.Inode)
+	}
+	return info, nfs3OK
+}
+
+// setCreatedAttr applies the initial attributes other than the mode to a created inode.
+func (req *nfsRequest) setCreatedAttr(info *proto.InodeInfo, sa *sattr) (*proto.InodeInfo, uint32) {
+	sa.setMode = false
+	sa.setSize = sa.setSize && sa.size != 0
+	if !sa.setUid && !sa.setGid && !sa.setSize && sa.atimeHow == nfsDontChange && sa.mtimeHow == nfsDontChange {
+		return info, nfs3OK
+	}
+	if status := req.checkSattr(info, sa); status != nfs3OK {
+		return info, status
+	}
+	if status := req.applySattr(info, sa); status != nfs3OK {
+		return info, status
+	}
+	if attr := req.attr(info.Inode); attr != nil {
+		info = attr
+	}
+	return info, nfs3OK
+}
+
+func exclusiveTimes(verifier []byte) (atime, mtime int64) {
+	return int64(binary.BigEndian.Uint32(verifier[4:8])), int64(binary.BigEndian.Uint32(verifier[0:4]))
+}
+
+func (s *nfsService) create(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	name := r.string(nfsMaxPathLen)
+	how := r.uint32()
+	var (
+		sa       = &sattr{}
+		verifier []byte
+	)
+	switch how {
+	case nfsCreateUnchecked, nfsCreateGuarded:
+		sa = readSattr(r)
+	case nfsCreateExclusive:
+		verifier = r.fixedOpaque(8)
+	default:
+		return errXDRGarbage
+	}
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var dir, info *proto.InodeInfo
+	req, dir, status := s.resolveDirUpdate(call, handle, name)
+	if status == nfs3OK {
+		perm := uint32(0644)
+		if sa.setMode {
+			perm = sa.mode
+		}
+		info, status = req.newInode(dir, name, inodeMode(perm, 0), nil)
+		switch {
+		case status == nfs3OK && how == nfsCreateExclusive:
+			// the verifier is kept in the times until the client sets the attributes,
+			// so that a retransmitted exclusive create finds its own file
+			atime, mtime := exclusiveTimes(verifier)
+			req.vol.mw.Setattr(info.Inode, proto.AttrAccessTime|proto.AttrModifyTime, 0, 0, 0, atime, mtime)
+			info.AccessTime, info.ModifyTime = time.Unix(atime, 0), time.Unix(mtime, 0)
+		case status == nfs3OK:
+			info, status = req.setCreatedAttr(info, sa)
+		case status == nfs3ErrExist && how != nfsCreateGuarded:
+			info, status = req.recreate(dir.Inode, name, how, sa, verifier)
+		}
+	}
+	writeCreated(w, req, status, info, dir)
+	return nil
+}
+
+// recreate handles an unchecked or exclusive create of an existing file.
+func (req *nfsRequest) recreate(dir uint64, name string, how uint32, sa *sattr, verifier []byte) (info *proto.InodeInfo, status uint32) {
+	ino, _, err := req.vol.mw.Lookup_ll(dir, name)
+	if err != nil {
+		return nil, nfsStatus(err)
+	}
+	if info, status = req.inodeGet(ino); status != nfs3OK {
+		return
+	}
+	if how == nfsCreateExclusive {
+		atime, mtime := exclusiveTimes(verifier)
+		if !proto.IsRegular(info.Mode) || info.AccessTime.Unix() != atime || info.ModifyTime.Unix() != mtime {
+			return nil, nfs3ErrExist
+		}
+		return info, nfs3OK
+	}
+	if !proto.IsRegular(info.Mode) {
+		return nil, nfs3ErrExist
+	}
+	sa.setMode = false
+	if status = req.checkSattr(info, sa); status != nfs3OK {
+		return nil, status
+	}
+	if status = req.applySattr(info, sa); status != nfs3OK {
+		return nil, status
+	}
+	if attr := req.attr(ino); attr != nil {
+		info = attr
+	}
+	return info, nfs3OK
+}
+
+func (s *nfsService) mkdir(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	name := r.string(nfsMaxPathLen)
+	sa := readSattr(r)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var dir, info *proto.InodeInfo
+	req, dir, status := s.resolveDirUpdate(call, handle, name)
+	if status == nfs3OK {
+		perm := uint32(0755)
+		if sa.setMode {
+			perm = sa.mode
+		}
+		info, status = req.newInode(dir, name, inodeMode(perm, os.ModeDir), nil)
+	}
+	if status == nfs3OK {
+		info, status = req.setCreatedAttr(info, sa)
+	}
+	writeCreated(w, req, status, info, dir)
+	return nil
+}
+
+func (s *nfsService) symlink(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	name := r.string(nfsMaxPathLen)
+	sa := readSattr(r)
+	target := r.string(nfsMaxPathLen)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var dir, info *proto.InodeInfo
+	req, dir, status := s.resolveDirUpdate(call, handle, name)
+	if status == nfs3OK {
+		info, status = req.newInode(dir, name, inodeMode(0777, os.ModeSymlink), []byte(target))
+	}
+	if status == nfs3OK {
+		info, status = req.setCreatedAttr(info, sa)
+	}
+	writeCreated(w, req, status, info, dir)
+	return nil
+}
+
+// mknod is not supported since the volumes keep no device numbers.
+func (s *nfsService) mknod(call *rpcCall, w *xdrWriter) error {
+	w.uint32(nfs3ErrNotsupp)
+	writeWccData(w, nil, nil, nil, nil)
+	return nil
+}
+
+// unlinkInode releases an inode which has no more links after removing its entry.
+func (req *nfsRequest) unlinkInode(info *proto.InodeInfo) {
+	if info == nil || info.Nlink != 0 || proto.IsDir(info.Mode) {
+		return
+	}
+	req.vol.dropStream(info.Inode)
+	if err := req.vol.mw.Evict(info.Inode); err != nil {
+		log.LogWarnf("unlinkInode: vol(%v) ino(%v) err(%v)", req.vol.name, info.Inode, err)
+	}
+}
+
+func (s *nfsService) remove(call *rpcCall, w *xdrWriter) error {
+	return s.removeEntry(call, w, false)
+}
+
+func (s *nfsService) rmdir(call *rpcCall, w *xdrWriter) error {
+	return s.removeEntry(call, w, true)
+}
+
+func (s *nfsService) removeEntry(call *rpcCall, w *xdrWriter, isDir bool) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	name := r.string(nfsMaxPathLen)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var (
+		dir  *proto.InodeInfo
+		ino  uint64
+		mode uint32
+		err  error
+	)
+	req, dir, status := s.resolveDirUpdate(call, handle, name)
+	if status == nfs3ErrInval && isDir && name == ".." {
+		status = nfs3ErrNotempty
+	}
+	if status == nfs3OK {
+		if ino, mode, err = req.vol.mw.Lookup_ll(dir.Inode, name); err != nil {
+			status = nfsStatus(err)
+		}
+	}
+	if status == nfs3OK {
+		switch {
+		case isDir && !proto.IsDir(mode):
+			status = nfs3ErrNotdir
+		case !isDir && proto.IsDir(mode):
+			status = nfs3ErrIsdir
+		default:
+			status = req.checkSticky(dir, ino)
+		}
+	}
+	if status == nfs3OK {
+		var info *proto.InodeInfo
+		if info, err = req.vol.mw.Delete_ll(dir.Inode, name, isDir); err != nil {
+			status = nfsStatus(err)
+		} else if isDir {
+			req.vol.deleteParent(ino)
+		} else {
+			req.unlinkInode(info)
+		}
+	}
+	var dirPost *proto.InodeInfo
+	if req != nil {
+		dirPost = req.attr(req.ino)
+	}
+	w.uint32(status)
+	req.writeWcc(w, dir, dirPost)
+	return nil
+}
+
+func (s *nfsService) rename(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	fromHandle := r.opaque(nfsMaxHandleLen)
+	fromName := r.string(nfsMaxPathLen)
+	toHandle := r.opaque(nfsMaxHandleLen)
+	toName := r.string(nfsMaxPathLen)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var (
+		fromDir, toDir *proto.InodeInfo
+		toReq          *nfsRequest
+	)
+	fromReq, fromDir, status := s.resolveDirUpdate(call, fromHandle, fromName)
+	if status == nfs3OK {
+		toReq, toDir, status = s.resolveDirUpdate(call, toHandle, toName)
+	}
+	if status == nfs3OK && toReq.export.Volume != fromReq.export.Volume {
+		status = nfs3ErrXdev
+	}
+	var srcIno, dstIno uint64
+	if status == nfs3OK {
+		var err error
+		if srcIno, _, err = fromReq.vol.mw.Lookup_ll(fromDir.Inode, fromName); err != nil {
+			status = nfsStatus(err)
+		} else {
+			status = fromReq.checkSticky(fromDir, srcIno)
+		}
+	}
+	if status == nfs3OK {
+		dstIno, _, _ = toReq.vol.mw.Lookup_ll(toDir.Inode, toName)
+		if dstIno != 0 && dstIno != srcIno {
+			status = toReq.checkSticky(toDir, dstIno)
+		}
+	}
+	if status == nfs3OK && srcIno != dstIno {
+		if err := fromReq.vol.mw.Rename_ll(fromDir.Inode, fromName, toDir.Inode, toName); err != nil {
+			status = nfsStatus(err)
+		} else {
+			if _, ok := fromReq.vol.getParent(srcIno); ok {
+				fromReq.vol.setParent(srcIno, toDir.Inode)
+			}
+			if dstIno != 0 {
+				fromReq.vol.dropStream(dstIno)
+			}
+		}
+	}
+	var fromPost, toPost *proto.InodeInfo
+	if fromReq != nil {
+		fromPost = fromReq.attr(fromReq.ino)
+	}
+	if toReq != nil {
+		toPost = toReq.attr(toReq.ino)
+	}
+	w.uint32(status)
+	fromReq.writeWcc(w, fromDir, fromPost)
+	toReq.writeWcc(w, toDir, toPost)
+	return nil
+}
+
+func (s *nfsService) link(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	dirHandle := r.opaque(nfsMaxHandleLen)
+	name := r.string(nfsMaxPathLen)
+	if r.err != nil {
+		return errXDRGarbage
+	}
+
+	var (
+		info, dir *proto.InodeInfo
+		dirReq    *nfsRequest
+	)
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	if status == nfs3OK && proto.IsDir(info.Mode) {
+		status = nfs3ErrIsdir
+	}
+	if status == nfs3OK {
+		dirReq, dir, status = s.resolveDirUpdate(call, dirHandle, name)
+	}
+	if status == nfs3OK && dirReq.export.Volume != req.export.Volume {
+		status = nfs3ErrXdev
+	}
+	if status == nfs3OK {
+		if _, err := req.vol.mw.Link(dir.Inode, name, info.Inode); err != nil {
+			status = nfsStatus(err)
+		}
+	}
+	var dirPost *proto.InodeInfo
+	if req != nil {
+		info = req.attr(req.ino)
+	}
+	if dirReq != nil {
+		dirPost = dirReq.attr(dirReq.ino)
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	dirReq.writeWcc(w, dir, dirPost)
+	return nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	// bytes of a READDIR reply other than the entries
+	readdirReplyBaseLen = 4 + 4 + 84 + 8 + 4 + 4
+	// bytes of the attributes and the handle of a READDIRPLUS entry
+	readdirplusEntryExtraLen = 4 + 84 + 4 + 4 + fileHandleLen + 3
+)
+
+// dirEntry is an entry of a directory, the cookie of an entry is its position in the directory
+// listed with "." and "..". The cookie verifier is not used and always zero.
+type dirEntry struct {
+	name   string
+	ino    uint64
+	cookie uint64
+}
+
+func (e *dirEntry) encodedLen() int {
+	return 4 + 8 + 4 + len(e.name) + xdrPad(len(e.name)) + 8
+}
+
+func (req *nfsRequest) dirEntries(dir *proto.InodeInfo) (entries []dirEntry, status uint32) {
+	dentries, err := req.vol.mw.ReadDir_ll(dir.Inode)
+	if err != nil {
+		return nil, nfsStatus(err)
+	}
+	parent := dir.Inode
+	if dir.Inode != proto.RootIno {
+		if ino, ok := req.vol.getParent(dir.Inode); ok {
+			parent = ino
+		}
+	}
+	entries = make([]dirEntry, 0, len(dentries)+2)
+	entries = append(entries, dirEntry{name: ".", ino: dir.Inode, cookie: 1})
+	entries = append(entries, dirEntry{name: "..", ino: parent, cookie: 2})
+	for i, dentry := range dentries {
+		entries = append(entries, dirEntry{name: dentry.Name, ino: dentry.Inode, cookie: uint64(i) + 3})
+		if proto.IsDir(dentry.Type) {
+			req.vol.setParent(dentry.Inode, dir.Inode)
+		}
+	}
+	return entries, nfs3OK
+}
+
+// resolveReaddir resolves the directory listed by READDIR and READDIRPLUS and its entries after the cookie.
+func (s *nfsService) resolveReaddir(call *rpcCall, handle []byte, cookie uint64) (req *nfsRequest, dir *proto.InodeInfo, entries []dirEntry, status uint32) {
+	if req, status = s.resolve(call, handle); status != nfs3OK {
+		return
+	}
+	if dir, status = req.inodeGet(req.ino); status != nfs3OK {
+		return
+	}
+	if !proto.IsDir(dir.Mode) {
+		status = nfs3ErrNotdir
+		return
+	}
+	if !req.cred.permitted(dir, permRead) {
+		status = nfs3ErrAcces
+		return
+	}
+	if entries, status = req.dirEntries(dir); status != nfs3OK {
+		return
+	}
+	if cookie >= uint64(len(entries)) {
+		entries = nil
+	} else {
+		entries = entries[cookie:]
+	}
+	return
+}
+
+func (s *nfsService) readdir(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	cookie := r.uint64()
+	r.fixedOpaque(8) // cookie verifier
+	count := r.uint32()
+	if r.err != nil {
+		return errXDRGarbage
+	}
+	if count > nfsMaxReaddir {
+		count = nfsMaxReaddir
+	}
+
+	req, dir, entries, status := s.resolveReaddir(call, handle, cookie)
+	size, n := readdirReplyBaseLen, 0
+	for ; status == nfs3OK && n < len(entries); n++ {
+		if size += entries[n].encodedLen(); size > int(count) {
+			break
+		}
+	}
+	if status == nfs3OK && n == 0 && len(entries) > 0 {
+		status = nfs3ErrToosmall
+	}
+	w.uint32(status)
+	req.writeAttr(w, dir)
+	if status != nfs3OK {
+		return nil
+	}
+	w.fixedOpaque(make([]byte, 8))
+	for _, entry := range entries[:n] {
+		w.bool(true)
+		w.uint64(entry.ino)
+		w.string(entry.name)
+		w.uint64(entry.cookie)
+	}
+	w.bool(false)
+	w.bool(n == len(entries))
+	return nil
+}
+
+func (s *nfsService) readdirplus(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	handle := r.opaque(nfsMaxHandleLen)
+	cookie := r.uint64()
+	r.fixedOpaque(8) // cookie verifier
+	dirCount := r.uint32()
+	maxCount := r.uint32()
+	if r.err != nil {
+		return errXDRGarbage
+	}
+	if maxCount > nfsMaxReaddir {
+		maxCount = nfsMaxReaddir
+	}
+
+	req, dir, entries, status := s.resolveReaddir(call, handle, cookie)
+	size, dirSize, n := readdirReplyBaseLen, 0, 0
+	for ; status == nfs3OK && n < len(entries); n++ {
+		entryLen := entries[n].encodedLen()
+		size += entryLen + readdirplusEntryExtraLen
+		dirSize += entryLen
+		if size > int(maxCount) || dirSize > int(dirCount) {
+			break
+		}
+	}
+	if status == nfs3OK && n == 0 && len(entries) > 0 {
+		status = nfs3ErrToosmall
+	}
+	w.uint32(status)
+	req.writeAttr(w, dir)
+	if status != nfs3OK {
+		return nil
+	}
+	eof := n == len(entries)
+	entries = entries[:n]
+	inodes := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		inodes = append(inodes, entry.ino)
+	}
+	infos := make(map[uint64]*proto.InodeInfo, len(entries))
+	for _, info := range req.vol.mw.BatchInodeGet(inodes) {
+		infos[info.Inode] = info
+	}
+	w.fixedOpaque(make([]byte, 8))
+	for _, entry := range entries {
+		w.bool(true)
+		w.uint64(entry.ino)
+		w.string(entry.name)
+		w.uint64(entry.cookie)
+		req.writeAttr(w, infos[entry.ino])
+		writePostOpFileHandle(w, req.export, entry.ino)
+	}
+	w.bool(false)
+	w.bool(eof)
+	return nil
+}
+
+func (s *nfsService) fsstat(call *rpcCall, w *xdrWriter) error {
+	handle := call.args.opaque(nfsMaxHandleLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+
+	var info *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	if status != nfs3OK {
+		return nil
+	}
+	total, used := req.vol.mw.Statfs()
+	var free uint64
+	if total > used {
+		free = total - used
+	}
+	w.uint64(total)
+	w.uint64(free)
+	w.uint64(free)
+	// the volumes have no limit of the inode count
+	w.uint64(nfsMaxFiles)
+	w.uint64(nfsMaxFiles)
+	w.uint64(nfsMaxFiles)
+	w.uint32(0) // invarsec
+	return nil
+}
+
+func (s *nfsService) fsinfo(call *rpcCall, w *xdrWriter) error {
+	handle := call.args.opaque(nfsMaxHandleLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+
+	var info *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	if status != nfs3OK {
+		return nil
+	}
+	w.uint32(nfsMaxIOSize)  // rtmax
+	w.uint32(nfsPrefIOSize) // rtpref
+	w.uint32(4096)          // rtmult
+	w.uint32(nfsMaxIOSize)  // wtmax
+	w.uint32(nfsPrefIOSize) // wtpref
+	w.uint32(4096)          // wtmult
+	w.uint32(nfsDirPrefSize)
+	w.uint64(nfsMaxFileSize)
+	// the times are kept in seconds
+	w.uint32(1)
+	w.uint32(0)
+	w.uint32(fsf3Link | fsf3Symlink | fsf3Homogeneous | fsf3CanSetTime)
+	return nil
+}
+
+func (s *nfsService) pathconf(call *rpcCall, w *xdrWriter) error {
+	handle := call.args.opaque(nfsMaxHandleLen)
+	if call.args.err != nil {
+		return errXDRGarbage
+	}
+
+	var info *proto.InodeInfo
+	req, status := s.resolve(call, handle)
+	if status == nfs3OK {
+		info, status = req.inodeGet(req.ino)
+	}
+	w.uint32(status)
+	req.writeAttr(w, info)
+	if status != nfs3OK {
+		return nil
+	}
+	w.uint32(nfsMaxLinks)
+	w.uint32(nfsMaxNameLen)
+	w.bool(true)  // no_trunc
+	w.bool(true)  // chown_restricted
+	w.bool(false) // case_insensitive
+	w.bool(true)  // case_preserving
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+// portMapping is a registration of PMAP, which tells the port of a program.
+type portMapping struct {
+	prog uint32
+	vers uint32
+	prot uint32
+	port uint32
+}
+
+// portmapService implements a minimal PMAP version 2 for the clients which look up the ports
+// of NFS and MOUNT through the port mapper. The mappings are fixed, SET and UNSET are refused.
+// The clients of rpcbind version 3 and 4 fall back to version 2 on the version mismatch.
+type portmapService struct {
+	mappings []portMapping
+}
+
+func newPortmapService(nfsPort, portmapPort uint32) *portmapService {
+	return &portmapService{
+		mappings: []portMapping{
+			{prog: progPortmap, vers: versPortmap, prot: ipProtoTCP, port: portmapPort},
+			{prog: progPortmap, vers: versPortmap, prot: ipProtoUDP, port: portmapPort},
+			{prog: progNFS, vers: versNFS, prot: ipProtoTCP, port: nfsPort},
+			{prog: progMount, vers: versMount, prot: ipProtoTCP, port: nfsPort},
+			{prog: progMount, vers: versMount, prot: ipProtoUDP, port: nfsPort},
+		},
+	}
+}
+
+func (s *portmapService) program() *rpcProgram {
+	return &rpcProgram{
+		prog: progPortmap,
+		vers: versPortmap,
+		procs: map[uint32]rpcProcedure{
+			pmapProcNull:    rpcNull,
+			pmapProcSet:     s.refuse,
+			pmapProcUnset:   s.refuse,
+			pmapProcGetport: s.getport,
+			pmapProcDump:    s.dump,
+		},
+	}
+}
+
+func (s *portmapService) refuse(call *rpcCall, w *xdrWriter) error {
+	w.bool(false)
+	return nil
+}
+
+func (s *portmapService) getport(call *rpcCall, w *xdrWriter) error {
+	r := call.args
+	prog := r.uint32()
+	vers := r.uint32()
+	prot := r.uint32()
+	r.uint32() // port
+	if r.err != nil {
+		return errXDRGarbage
+	}
+	var port uint32
+	for _, m := range s.mappings {
+		if m.prog == prog && m.vers == vers && m.prot == prot {
+			port = m.port
+			break
+		}
+	}
+	w.uint32(port)
+	return nil
+}
+
+func (s *portmapService) dump(call *rpcCall, w *xdrWriter) error {
+	for _, m := range s.mappings {
+		w.bool(true)
+		w.uint32(m.prog)
+		w.uint32(m.vers)
+		w.uint32(m.prot)
+		w.uint32(m.port)
+	}
+	w.bool(false)
+	return nil
+}
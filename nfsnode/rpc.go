@@ -0,0 +1,411 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ONC RPC version 2 (RFC 5531).
+const (
+	rpcVersion = 2
+
+	rpcMsgCall  = 0
+	rpcMsgReply = 1
+
+	rpcMsgAccepted = 0
+	rpcMsgDenied   = 1
+
+	// accept_stat
+	rpcSuccess      = 0
+	rpcProgUnavail  = 1
+	rpcProgMismatch = 2
+	rpcProcUnavail  = 3
+	rpcGarbageArgs  = 4
+	rpcSystemErr    = 5
+
+	// reject_stat
+	rpcMismatch  = 0
+	rpcAuthError = 1
+
+	// auth_stat
+	rpcAuthBadCred = 1
+	rpcAuthTooWeak = 5
+
+	authFlavorNone = 0
+	authFlavorUnix = 1
+)
+
+const (
+	rpcMaxAuthBytes = 400
+	rpcMaxGroups    = 16
+	rpcMaxHostname  = 255
+
+	// the record marking of RPC over TCP, the highest bit of a fragment header marks the last fragment
+	rpcLastFragment  = 1 << 31
+	rpcMaxRecordSize = 4 << 20
+	rpcMaxDatagram   = 64 << 10
+
+	// every connection processes at most this number of calls at the same time
+	rpcConnConcurrency = 32
+
+	// length of the header of an accepted reply with the success status
+	rpcSuccessHeaderLen = 24
+)
+
+// rpcCall is a decoded RPC call, the arguments are decoded by the procedure.
+type rpcCall struct {
+	xid    uint32
+	prog   uint32
+	vers   uint32
+	proc   uint32
+	cred   *credential // nil if the caller authenticates with AUTH_NONE
+	remote net.IP
+	args   *xdrReader
+}
+
+// rpcProcedure decodes the arguments and encodes the results of a call. It returns errXDRGarbage
+// if the arguments can not be decoded, any other error is replied as a system error.
+type rpcProcedure func(call *rpcCall, w *xdrWriter) error
+
+type rpcProgram struct {
+	prog  uint32
+	vers  uint32
+	procs map[uint32]rpcProcedure
+}
+
+// rpcServer serves the registered programs over TCP and UDP. Only one version of each program is served.
+type rpcServer struct {
+	programs    map[uint32]*rpcProgram
+	listeners   []net.Listener
+	packetConns []net.PacketConn
+	conns       map[net.Conn]struct{}
+	closed      bool
+	mu          sync.Mutex
+	wg          sync.WaitGroup
+}
+
+func newRPCServer() *rpcServer {
+	return &rpcServer{
+		programs: make(map[uint32]*rpcProgram),
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *rpcServer) register(program *rpcProgram) {
+	s.programs[program.prog] = program
+}
+
+func decodeCredential(flavor uint32, body []byte) (cred *credential, err error) {
+	switch flavor {
+	case authFlavorNone:
+		return nil, nil
+	case authFlavorUnix:
+		r := newXDRReader(body)
+		r.uint32() // stamp
+		r.string(rpcMaxHostname)
+		cred = &credential{uid: r.uint32(), gid: r.uint32()}
+		count := r.uint32()
+		if count > rpcMaxGroups {
+			return nil, errXDRGarbage
+		}
+		cred.gids = make([]uint32, 0, count)
+		for i := uint32(0); i < count; i++ {
+			cred.gids = append(cred.gids, r.uint32())
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		return cred, nil
+	}
+	return nil, errors.New("unsupported auth flavor")
+}
+
+func writeReplyHeader(w *xdrWriter, xid uint32, replyStat uint32) {
+	w.uint32(xid)
+	w.uint32(rpcMsgReply)
+	w.uint32(replyStat)
+}
+
+func acceptedReply(xid, acceptStat uint32) *xdrWriter {
+	w := newXDRWriter(rpcSuccessHeaderLen + 8)
+	writeReplyHeader(w, xid, rpcMsgAccepted)
+	w.uint32(authFlavorNone)
+	w.opaque(nil)
+	w.uint32(acceptStat)
+	return w
+}
+
+func deniedReply(xid, rejectStat uint32) *xdrWriter {
+	w := newXDRWriter(24)
+	writeReplyHeader(w, xid, rpcMsgDenied)
+	w.uint32(rejectStat)
+	return w
+}
+
+// dispatch decodes a call message and returns the encoded reply, or nil if the message is dropped.
+func (s *rpcServer) dispatch(msg []byte, remote net.IP) []byte {
+	r := newXDRReader(msg)
+	call := &rpcCall{remote: remote, args: r}
+	call.xid = r.uint32()
+	if r.uint32() != rpcMsgCall || r.err != nil {
+		return nil
+	}
+	rpcVers := r.uint32()
+	call.prog = r.uint32()
+	call.vers = r.uint32()
+	call.proc = r.uint32()
+	credFlavor := r.uint32()
+	credBody := r.opaque(rpcMaxAuthBytes)
+	r.uint32() // verifier flavor
+	r.opaque(rpcMaxAuthBytes)
+	if r.err != nil {
+		return nil
+	}
+	if rpcVers != rpcVersion {
+		w := deniedReply(call.xid, rpcMismatch)
+		w.uint32(rpcVersion)
+		w.uint32(rpcVersion)
+		return w.bytes()
+	}
+	var err error
+	if call.cred, err = decodeCredential(credFlavor, credBody); err != nil {
+		w := deniedReply(call.xid, rpcAuthError)
+		if err == errXDRGarbage {
+			w.uint32(rpcAuthBadCred)
+		} else {
+			w.uint32(rpcAuthTooWeak)
+		}
+		return w.bytes()
+	}
+
+	program, ok := s.programs[call.prog]
+	if !ok {
+		return acceptedReply(call.xid, rpcProgUnavail).bytes()
+	}
+	if call.vers != program.vers {
+		w := acceptedReply(call.xid, rpcProgMismatch)
+		w.uint32(program.vers)
+		w.uint32(program.vers)
+		return w.bytes()
+	}
+	procedure, ok := program.procs[call.proc]
+	if !ok {
+		return acceptedReply(call.xid, rpcProcUnavail).bytes()
+	}
+	w := acceptedReply(call.xid, rpcSuccess)
+	if err = procedure(call, w); err != nil {
+		if err == errXDRGarbage {
+			return acceptedReply(call.xid, rpcGarbageArgs).bytes()
+		}
+		log.LogErrorf("dispatch: prog(%v) vers(%v) proc(%v) remote(%v) err(%v)",
+			call.prog, call.vers, call.proc, remote, err)
+		return acceptedReply(call.xid, rpcSystemErr).bytes()
+	}
+	return w.bytes()
+}
+
+func remoteIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// serveTCP listens on the address and serves the calls of the accepted connections.
+func (s *rpcServer) serveTCP(addr string) (err error) {
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", addr); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !s.isClosed() {
+					log.LogErrorf("serveTCP: addr(%v) accept err(%v)", addr, err)
+				}
+				return
+			}
+			if !s.trackConn(conn, true) {
+				conn.Close()
+				return
+			}
+			go s.serveConn(conn)
+		}
+	}()
+	return
+}
+
+// serveUDP serves the calls received on the address one by one, the calls are expected to be light.
+func (s *rpcServer) serveUDP(addr string) (err error) {
+	var pc net.PacketConn
+	if pc, err = net.ListenPacket("udp", addr); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.packetConns = append(s.packetConns, pc)
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		buf := make([]byte, rpcMaxDatagram)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				if !s.isClosed() {
+					log.LogErrorf("serveUDP: addr(%v) read err(%v)", addr, err)
+				}
+				return
+			}
+			reply := s.dispatch(buf[:n], remoteIP(from))
+			if reply == nil {
+				continue
+			}
+			if _, err = pc.WriteTo(reply, from); err != nil {
+				log.LogWarnf("serveUDP: addr(%v) remote(%v) write err(%v)", addr, from, err)
+			}
+		}
+	}()
+	return
+}
+
+func readRecord(r io.Reader) (record []byte, err error) {
+	var header [4]byte
+	for {
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		fragment := binary.BigEndian.Uint32(header[:])
+		size := int(fragment &^ rpcLastFragment)
+		if len(record)+size > rpcMaxRecordSize {
+			return nil, errors.New("rpc record too large")
+		}
+		offset := len(record)
+		record = append(record, make([]byte, size)...)
+		if _, err = io.ReadFull(r, record[offset:]); err != nil {
+			return
+		}
+		if fragment&rpcLastFragment != 0 {
+			return
+		}
+	}
+}
+
+func writeRecord(w io.Writer, record []byte) (err error) {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(record))|rpcLastFragment)
+	if _, err = w.Write(header[:]); err != nil {
+		return
+	}
+	_, err = w.Write(record)
+	return
+}
+
+// serveConn reads the call records of a connection and processes them concurrently,
+// the replies are written back in the order they complete.
+func (s *rpcServer) serveConn(conn net.Conn) {
+	defer func() {
+		s.trackConn(conn, false)
+		conn.Close()
+	}()
+	var (
+		remote  = remoteIP(conn.RemoteAddr())
+		reader  = bufio.NewReaderSize(conn, 64<<10)
+		writeMu sync.Mutex
+		limit   = make(chan struct{}, rpcConnConcurrency)
+		wg      sync.WaitGroup
+	)
+	defer wg.Wait()
+	for {
+		record, err := readRecord(reader)
+		if err != nil {
+			if err != io.EOF && !s.isClosed() {
+				log.LogWarnf("serveConn: remote(%v) err(%v)", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		limit <- struct{}{}
+		wg.Add(1)
+		go func(record []byte) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			reply := s.dispatch(record, remote)
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := writeRecord(conn, reply); err != nil {
+				log.LogWarnf("serveConn: remote(%v) write err(%v)", conn.RemoteAddr(), err)
+				conn.Close()
+			}
+		}(record)
+	}
+}
+
+func (s *rpcServer) trackConn(conn net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+	return true
+}
+
+func (s *rpcServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// close stops the listeners and closes the open connections.
+func (s *rpcServer) close() {
+	s.mu.Lock()
+	s.closed = true
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	for _, pc := range s.packetConns {
+		pc.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestXDR(t *testing.T) {
+	w := newXDRWriter(0)
+	w.uint32(7)
+	w.uint64(1 << 40)
+	w.bool(true)
+	w.string("abcde")
+	w.fixedOpaque([]byte{1, 2})
+	if w.len() != 4+8+4+4+8+4 {
+		t.Fatalf("unexpected encoded length %v", w.len())
+	}
+
+	r := newXDRReader(w.bytes())
+	if v := r.uint32(); v != 7 {
+		t.Errorf("uint32: got %v", v)
+	}
+	if v := r.uint64(); v != 1<<40 {
+		t.Errorf("uint64: got %v", v)
+	}
+	if v := r.bool(); !v {
+		t.Errorf("bool: got %v", v)
+	}
+	if v := r.string(16); v != "abcde" {
+		t.Errorf("string: got %v", v)
+	}
+	if v := r.fixedOpaque(2); !bytes.Equal(v, []byte{1, 2}) {
+		t.Errorf("fixed opaque: got %v", v)
+	}
+	if r.err != nil || len(r.remaining()) != 0 {
+		t.Errorf("unexpected err(%v) remaining(%v)", r.err, r.remaining())
+	}
+
+	r = newXDRReader(w.bytes()[:14])
+	r.uint32()
+	r.uint64()
+	if r.uint32(); r.err != errXDRGarbage {
+		t.Errorf("short buffer is decoded")
+	}
+	r = newXDRReader(w.bytes()[16:])
+	if r.string(4); r.err != errXDRGarbage {
+		t.Errorf("string longer than the limit is decoded")
+	}
+}
+
+func encodeCall(xid, prog, vers, proc uint32, cred *credential, args func(w *xdrWriter)) []byte {
+	w := newXDRWriter(0)
+	w.uint32(xid)
+	w.uint32(rpcMsgCall)
+	w.uint32(rpcVersion)
+	w.uint32(prog)
+	w.uint32(vers)
+	w.uint32(proc)
+	if cred == nil {
+		w.uint32(authFlavorNone)
+		w.opaque(nil)
+	} else {
+		body := newXDRWriter(0)
+		body.uint32(0)
+		body.string("client")
+		body.uint32(cred.uid)
+		body.uint32(cred.gid)
+		body.uint32(uint32(len(cred.gids)))
+		for _, gid := range cred.gids {
+			body.uint32(gid)
+		}
+		w.uint32(authFlavorUnix)
+		w.opaque(body.bytes())
+	}
+	w.uint32(authFlavorNone)
+	w.opaque(nil)
+	if args != nil {
+		args(w)
+	}
+	return w.bytes()
+}
+
+// decodeReply returns the accept status and the results of an accepted reply.
+func decodeReply(t *testing.T, xid uint32, reply []byte) (acceptStat uint32, results *xdrReader) {
+	r := newXDRReader(reply)
+	if v := r.uint32(); v != xid {
+		t.Fatalf("xid: got %v want %v", v, xid)
+	}
+	if v := r.uint32(); v != rpcMsgReply {
+		t.Fatalf("message type: got %v", v)
+	}
+	if v := r.uint32(); v != rpcMsgAccepted {
+		t.Fatalf("reply status: got %v", v)
+	}
+	r.uint32()
+	r.opaque(rpcMaxAuthBytes)
+	acceptStat = r.uint32()
+	if r.err != nil {
+		t.Fatalf("decode reply err(%v)", r.err)
+	}
+	return acceptStat, r
+}
+
+func TestRPCDispatch(t *testing.T) {
+	s := newRPCServer()
+	var caller *credential
+	s.register(&rpcProgram{prog: 200000, vers: 1, procs: map[uint32]rpcProcedure{
+		0: rpcNull,
+		1: func(call *rpcCall, w *xdrWriter) error {
+			v := call.args.uint32()
+			if call.args.err != nil {
+				return errXDRGarbage
+			}
+			caller = call.cred
+			w.uint32(v + 1)
+			return nil
+		},
+	}})
+	s.register(newPortmapService(2049, 111).program())
+
+	cred := &credential{uid: 1000, gid: 100, gids: []uint32{100, 200}}
+	reply := s.dispatch(encodeCall(1, 200000, 1, 1, cred, func(w *xdrWriter) { w.uint32(41) }), nil)
+	stat, results := decodeReply(t, 1, reply)
+	if stat != rpcSuccess || results.uint32() != 42 {
+		t.Errorf("unexpected result of the call")
+	}
+	if caller == nil || caller.uid != 1000 || caller.gid != 100 || len(caller.gids) != 2 {
+		t.Errorf("unexpected credential %v", caller)
+	}
+
+	if stat, _ = decodeReply(t, 2, s.dispatch(encodeCall(2, 200000, 1, 1, nil, nil), nil)); stat != rpcGarbageArgs {
+		t.Errorf("missing arguments: got %v", stat)
+	}
+	if stat, _ = decodeReply(t, 3, s.dispatch(encodeCall(3, 200000, 1, 9, nil, nil), nil)); stat != rpcProcUnavail {
+		t.Errorf("unknown procedure: got %v", stat)
+	}
+	if stat, _ = decodeReply(t, 4, s.dispatch(encodeCall(4, 300000, 1, 0, nil, nil), nil)); stat != rpcProgUnavail {
+		t.Errorf("unknown program: got %v", stat)
+	}
+	stat, results = decodeReply(t, 5, s.dispatch(encodeCall(5, progPortmap, 4, 0, nil, nil), nil))
+	if low, high := results.uint32(), results.uint32(); stat != rpcProgMismatch || low != versPortmap || high != versPortmap {
+		t.Errorf("rpcbind version 4: got %v(%v-%v)", stat, low, high)
+	}
+
+	getport := func(xid, prog, vers, prot uint32) uint32 {
+		reply := s.dispatch(encodeCall(xid, progPortmap, versPortmap, pmapProcGetport, nil, func(w *xdrWriter) {
+			w.uint32(prog)
+			w.uint32(vers)
+			w.uint32(prot)
+			w.uint32(0)
+		}), nil)
+		_, results := decodeReply(t, xid, reply)
+		return results.uint32()
+	}
+	if port := getport(6, progNFS, versNFS, ipProtoTCP); port != 2049 {
+		t.Errorf("port of nfs over tcp: got %v", port)
+	}
+	if port := getport(7, progNFS, versNFS, ipProtoUDP); port != 0 {
+		t.Errorf("port of nfs over udp: got %v", port)
+	}
+
+	if reply = s.dispatch([]byte{0, 0, 0, 8, 0, 0, 0, 1}, nil); reply != nil {
+		t.Errorf("reply message is answered")
+	}
+}
+
+func TestRPCOverTCP(t *testing.T) {
+	s := newRPCServer()
+	s.register(&rpcProgram{prog: 200000, vers: 1, procs: map[uint32]rpcProcedure{0: rpcNull}})
+	if err := s.serveTCP("127.0.0.1:0"); err != nil {
+		t.Fatalf("serve err(%v)", err)
+	}
+	defer s.close()
+
+	conn, err := net.Dial("tcp", s.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatalf("dial err(%v)", err)
+	}
+	defer conn.Close()
+	// send the call in two fragments
+	call := encodeCall(9, 200000, 1, 0, nil, nil)
+	var buf bytes.Buffer
+	w := newXDRWriter(0)
+	w.uint32(8)
+	buf.Write(w.bytes())
+	buf.Write(call[:8])
+	if err = writeRecord(&buf, call[8:]); err != nil {
+		t.Fatalf("write record err(%v)", err)
+	}
+	if _, err = conn.Write(buf.Bytes()); err != nil {
+		t.Fatalf("write err(%v)", err)
+	}
+	reply, err := readRecord(conn)
+	if err != nil {
+		t.Fatalf("read reply err(%v)", err)
+	}
+	if stat, _ := decodeReply(t, 9, reply); stat != rpcSuccess {
+		t.Errorf("null call: got %v", stat)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration items that act on the NfsNode.
+const (
+	// String type configuration item, used to configure the port NFS and MOUNT are served on over TCP and UDP.
+	// Example:
+	//		{
+	//			"listen": "2049"
+	//		}
+	configListen = proto.ListenPort
+
+	// String array configuration item, used to configure the hostname or IP address of the cluster master node.
+	// The NfsNode loads the exports from the master and opens the exported volumes through the master.
+	// Example:
+	//		{
+	//			"masterAddr":[
+	//				"master1.chubao.io",
+	//				"master2.chubao.io",
+	//				"master3.chubao.io"
+	//			]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// String type configuration item, used to configure the port of the built-in port mapper, which is
+	// disabled by default. Enable it on the hosts without rpcbind, so that the clients can mount the exports
+	// without giving the ports in the mount options.
+	// Example:
+	//		{
+	//			"portmapListen": "111"
+	//		}
+	configPortmapListen = "portmapListen"
+)
+
+// Default of configuration value
+const (
+	defaultListen = "2049"
+)
+
+var (
+	// Regular expression used to verify the configuration of the service listening port.
+	// A valid service listening port configuration is a string containing only numbers.
+	regexpListen = regexp.MustCompile("^(\\d)+$")
+)
+
+// NfsNode serves the volumes exported through the master to the NFS version 3 clients.
+type NfsNode struct {
+	listen        string
+	portmapListen string
+	masters       []string
+	exports       *exportManager
+	server        *rpcServer
+	portmapServer *rpcServer
+
+	control common.Control
+}
+
+func NewServer() *NfsNode {
+	return &NfsNode{}
+}
+
+func (n *NfsNode) Start(cfg *config.Config) (err error) {
+	return n.control.Start(n, cfg, handleStart)
+}
+
+func (n *NfsNode) Shutdown() {
+	n.control.Shutdown(n, handleShutdown)
+}
+
+func (n *NfsNode) Sync() {
+	n.control.Sync()
+}
+
+func (n *NfsNode) loadConfig(cfg *config.Config) (err error) {
+	// parse listen
+	listen := cfg.GetString(configListen)
+	if len(listen) == 0 {
+		listen = defaultListen
+	}
+	if match := regexpListen.MatchString(listen); !match {
+		err = errors.New("invalid listen configuration")
+		return
+	}
+	n.listen = listen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
+
+	// parse port mapper listen
+	portmapListen := cfg.GetString(configPortmapListen)
+	if len(portmapListen) != 0 && !regexpListen.MatchString(portmapListen) {
+		err = errors.New("invalid portmapListen configuration")
+		return
+	}
+	n.portmapListen = portmapListen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configPortmapListen, portmapListen)
+
+	// parse master config
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	n.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+	return
+}
+
+func handleStart(s common.Server, cfg *config.Config) (err error) {
+	n, ok := s.(*NfsNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = n.loadConfig(cfg); err != nil {
+		return
+	}
+
+	n.exports = newExportManager(n.masters)
+	if err = n.exports.start(); err != nil {
+		return
+	}
+
+	if err = n.startRPCServers(); err != nil {
+		log.LogErrorf("handleStart: start rpc servers fail: err(%v)", err)
+		n.stopRPCServers()
+		n.exports.stop()
+		return
+	}
+
+	exporter.Init(cfg.GetString("role"), cfg)
+
+	log.LogInfo("nfs subsystem start success")
+	return
+}
+
+func handleShutdown(s common.Server) {
+	n, ok := s.(*NfsNode)
+	if !ok {
+		return
+	}
+	n.stopRPCServers()
+	n.exports.stop()
+}
+
+func (n *NfsNode) startRPCServers() (err error) {
+	addr := net.JoinHostPort("", n.listen)
+	n.server = newRPCServer()
+	n.server.register(newNFSService(n.exports).program())
+	n.server.register((&mountService{exports: n.exports}).program())
+	if err = n.server.serveTCP(addr); err != nil {
+		return
+	}
+	if err = n.server.serveUDP(addr); err != nil {
+		return
+	}
+	log.LogInfof("startRPCServers: nfs and mount are served on port(%v)", n.listen)
+
+	if n.portmapListen == "" {
+		return
+	}
+	nfsPort, _ := strconv.ParseUint(n.listen, 10, 32)
+	portmapPort, _ := strconv.ParseUint(n.portmapListen, 10, 32)
+	addr = net.JoinHostPort("", n.portmapListen)
+	n.portmapServer = newRPCServer()
+	n.portmapServer.register(newPortmapService(uint32(nfsPort), uint32(portmapPort)).program())
+	if err = n.portmapServer.serveTCP(addr); err != nil {
+		return
+	}
+	if err = n.portmapServer.serveUDP(addr); err != nil {
+		return
+	}
+	log.LogInfof("startRPCServers: port mapper is served on port(%v)", n.portmapListen)
+	return
+}
+
+func (n *NfsNode) stopRPCServers() {
+	if n.server != nil {
+		n.server.close()
+	}
+	if n.portmapServer != nil {
+		n.portmapServer.close()
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// NFS has no open and close, the stream of a file is kept open until it has been idle for a while.
+	streamIdleTimeout = 30 * time.Second
+	streamCheckPeriod = 10 * time.Second
+
+	// the parents of at most this number of directories are remembered to resolve ".."
+	maxParentEntries = 1 << 20
+)
+
+type openStream struct {
+	refs     int
+	writes   uint64 // number of the completed writes
+	flushed  uint64 // number of the writes persisted by the last flush
+	lastUsed time.Time
+}
+
+func (s *openStream) dirty() bool {
+	return s.writes != s.flushed
+}
+
+// volume is an exported volume opened through the meta and data SDKs.
+type volume struct {
+	name       string
+	mw         *meta.MetaWrapper
+	ec         *stream.ExtentClient
+	streams    map[uint64]*openStream
+	streamLock sync.Mutex
+	parents    map[uint64]uint64 // directory inode -> parent inode
+	parentLock sync.RWMutex
+	stopC      chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+}
+
+func openVolume(name string, masters []string) (vol *volume, err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        name,
+		Masters:       masters,
+		ValidateOwner: false,
+	}
+	var mw *meta.MetaWrapper
+	if mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:             name,
+		Masters:            masters,
+		FollowerRead:       true,
+		OnAppendExtentKey:  mw.AppendExtentKey,
+		OnGetExtents:       mw.GetExtents,
+		OnTruncate:         mw.Truncate,
+		OnUpdateModifyTime: mw.UpdateModifyTime,
+	}
+	var ec *stream.ExtentClient
+	if ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		mw.Close()
+		return
+	}
+	vol = &volume{
+		name:    name,
+		mw:      mw,
+		ec:      ec,
+		streams: make(map[uint64]*openStream),
+		parents: make(map[uint64]uint64),
+		stopC:   make(chan struct{}),
+	}
+	vol.wg.Add(1)
+	go vol.scheduleToCloseIdleStreams()
+	return
+}
+
+// acquireStream opens the stream of the file if it is not open, the stream stays open until
+// it is released and becomes idle. It returns whether the stream is newly opened.
+func (v *volume) acquireStream(ino uint64) (opened bool, err error) {
+	v.streamLock.Lock()
+	defer v.streamLock.Unlock()
+	s, ok := v.streams[ino]
+	if !ok {
+		if err = v.ec.OpenStream(ino); err != nil {
+			return
+		}
+		s = &openStream{}
+		v.streams[ino] = s
+		opened = true
+	}
+	s.refs++
+	s.lastUsed = time.Now()
+	return
+}
+
+func (v *volume) releaseStream(ino uint64, written bool) {
+	v.streamLock.Lock()
+	defer v.streamLock.Unlock()
+	if s, ok := v.streams[ino]; ok {
+		s.refs--
+		if written {
+			s.writes++
+		}
+		s.lastUsed = time.Now()
+	}
+}
+
+// flushStream persists the data written to the open stream of the file.
+func (v *volume) flushStream(ino uint64) (err error) {
+	v.streamLock.Lock()
+	s, ok := v.streams[ino]
+	if !ok || !s.dirty() {
+		v.streamLock.Unlock()
+		return
+	}
+	// the writes completed before the flush are persisted by it
+	writes := s.writes
+	s.refs++
+	v.streamLock.Unlock()
+
+	err = v.ec.Flush(ino)
+
+	v.streamLock.Lock()
+	s.refs--
+	if err == nil && writes > s.flushed {
+		s.flushed = writes
+	}
+	v.streamLock.Unlock()
+	return
+}
+
+// fileSize returns the size of the file including the data not flushed yet.
+func (v *volume) fileSize(info *proto.InodeInfo) uint64 {
+	if !proto.IsRegular(info.Mode) {
+		return info.Size
+	}
+	v.streamLock.Lock()
+	s, ok := v.streams[info.Inode]
+	dirty := ok && s.dirty()
+	v.streamLock.Unlock()
+	if !dirty {
+		return info.Size
+	}
+	if size, _, valid := v.ec.FileSize(info.Inode); valid {
+		return uint64(size)
+	}
+	return info.Size
+}
+
+// refreshExtents reloads the extents of an open stream which are modified by others.
+func (v *volume) refreshExtents(info *proto.InodeInfo) {
+	v.streamLock.Lock()
+	s, ok := v.streams[info.Inode]
+	dirty := ok && s.dirty()
+	v.streamLock.Unlock()
+	if !ok || dirty {
+		return
+	}
+	if _, gen, valid := v.ec.FileSize(info.Inode); valid && gen < info.Generation {
+		v.ec.RefreshExtentsCache(info.Inode)
+	}
+}
+
+// dropStream closes the stream of a removed file without waiting for it to become idle.
+func (v *volume) dropStream(ino uint64) {
+	v.streamLock.Lock()
+	_, ok := v.streams[ino]
+	delete(v.streams, ino)
+	v.streamLock.Unlock()
+	if ok {
+		v.ec.CloseStream(ino)
+	}
+}
+
+func (v *volume) closeIdleStreams(all bool) {
+	now := time.Now()
+	idle := make([]uint64, 0)
+	v.streamLock.Lock()
+	for ino, s := range v.streams {
+		if all || (s.refs == 0 && now.Sub(s.lastUsed) > streamIdleTimeout) {
+			idle = append(idle, ino)
+			delete(v.streams, ino)
+		}
+	}
+	v.streamLock.Unlock()
+	for _, ino := range idle {
+		if err := v.ec.CloseStream(ino); err != nil {
+			log.LogErrorf("closeIdleStreams: vol(%v) ino(%v) err(%v)", v.name, ino, err)
+		}
+	}
+}
+
+func (v *volume) scheduleToCloseIdleStreams() {
+	defer v.wg.Done()
+	ticker := time.NewTicker(streamCheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			v.closeIdleStreams(false)
+		case <-v.stopC:
+			return
+		}
+	}
+}
+
+func (v *volume) setParent(ino, parent uint64) {
+	v.parentLock.Lock()
+	defer v.parentLock.Unlock()
+	if len(v.parents) >= maxParentEntries {
+		v.parents = make(map[uint64]uint64)
+	}
+	v.parents[ino] = parent
+}
+
+func (v *volume) getParent(ino uint64) (parent uint64, ok bool) {
+	v.parentLock.RLock()
+	defer v.parentLock.RUnlock()
+	parent, ok = v.parents[ino]
+	return
+}
+
+func (v *volume) deleteParent(ino uint64) {
+	v.parentLock.Lock()
+	defer v.parentLock.Unlock()
+	delete(v.parents, ino)
+}
+
+// close flushes and closes all the open streams and releases the SDK clients.
+func (v *volume) close() {
+	v.closeOnce.Do(func() {
+		close(v.stopC)
+		v.wg.Wait()
+		v.closeIdleStreams(true)
+		v.ec.Close()
+		v.mw.Close()
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// XDR (RFC 4506) encodes every item in units of 4 bytes in big endian,
+// the variable length opaque data and strings are prefixed by their lengths and padded to 4 bytes.
+
+var errXDRGarbage = errors.New("xdr: garbage arguments")
+
+func xdrPad(n int) int {
+	return (4 - n%4) % 4
+}
+
+// xdrReader decodes the XDR items one by one, the first decode error sticks
+// and all the following reads return zero values.
+type xdrReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func newXDRReader(buf []byte) *xdrReader {
+	return &xdrReader{buf: buf}
+}
+
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf)-r.off < n {
+		r.err = errXDRGarbage
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *xdrReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *xdrReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *xdrReader) bool() bool {
+	return r.uint32() != 0
+}
+
+func (r *xdrReader) fixedOpaque(n int) []byte {
+	b := r.next(n)
+	r.next(xdrPad(n))
+	return b
+}
+
+// opaque reads variable length opaque data no longer than max bytes.
+func (r *xdrReader) opaque(max int) []byte {
+	n := r.uint32()
+	if r.err != nil {
+		return nil
+	}
+	if n > uint32(max) {
+		r.err = errXDRGarbage
+		return nil
+	}
+	return r.fixedOpaque(int(n))
+}
+
+func (r *xdrReader) string(max int) string {
+	return string(r.opaque(max))
+}
+
+// remaining returns the bytes not decoded yet.
+func (r *xdrReader) remaining() []byte {
+	if r.err != nil {
+		return nil
+	}
+	return r.buf[r.off:]
+}
+
+// xdrWriter encodes the XDR items into a growing buffer.
+type xdrWriter struct {
+	buf []byte
+}
+
+func newXDRWriter(capacity int) *xdrWriter {
+	return &xdrWriter{buf: make([]byte, 0, capacity)}
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	w.buf = append(w.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (w *xdrWriter) uint64(v uint64) {
+	w.uint32(uint32(v >> 32))
+	w.uint32(uint32(v))
+}
+
+func (w *xdrWriter) bool(v bool) {
+	if v {
+		w.uint32(1)
+	} else {
+		w.uint32(0)
+	}
+}
+
+func (w *xdrWriter) fixedOpaque(b []byte) {
+	w.buf = append(w.buf, b...)
+	for i := xdrPad(len(b)); i > 0; i-- {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.fixedOpaque(b)
+}
+
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+func (w *xdrWriter) len() int {
+	return len(w.buf)
+}
+
+func (w *xdrWriter) bytes() []byte {
+	return w.buf
+}
@@ -0,0 +1,267 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/spf13/cobra"
+)
+
+const readBufferSize = 1024 * 1024
+
+func newExportCmd() *cobra.Command {
+	var optChecksum bool
+	var c = &cobra.Command{
+		Use:   "export",
+		Short: "export a directory subtree as a manifest and an optional data tar stream",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := Export(optChecksum); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	c.Flags().BoolVar(&optChecksum, "checksum", false, "compute the checksums of files even if the data is not exported")
+	return c
+}
+
+type exporter struct {
+	vol      *volume
+	manifest *manifestWriter
+	data     *tar.Writer
+	checksum bool
+	links    map[uint64]string // source inode of hard links to the first path
+	buf      []byte
+	files    int
+	bytes    uint64
+}
+
+func Export(checksum bool) (err error) {
+	var vol *volume
+	if vol, err = openVolume(); err != nil {
+		return
+	}
+	defer vol.close()
+
+	var rootIno uint64
+	if rootIno, err = vol.mw.GetRootIno(SubDir); err != nil {
+		return
+	}
+
+	var mfile *os.File
+	if mfile, err = os.Create(ManifestFile); err != nil {
+		return
+	}
+	defer mfile.Close()
+
+	e := &exporter{
+		vol:      vol,
+		checksum: checksum || DataFile != "",
+		links:    make(map[uint64]string),
+		buf:      make([]byte, readBufferSize),
+	}
+	if e.manifest, err = newManifestWriter(mfile, &ManifestHeader{
+		Version:    ManifestVersion,
+		Cluster:    vol.mw.Cluster(),
+		Volume:     VolName,
+		Path:       SubDir,
+		CreateTime: time.Now().Unix(),
+		WithData:   DataFile != "",
+	}); err != nil {
+		return
+	}
+
+	if DataFile != "" {
+		var dfile = os.Stdout
+		if DataFile != "-" {
+			if dfile, err = os.Create(DataFile); err != nil {
+				return
+			}
+			defer dfile.Close()
+		}
+		e.data = tar.NewWriter(dfile)
+	}
+
+	var root *proto.InodeInfo
+	if root, err = vol.mw.InodeGet_ll(rootIno); err != nil {
+		return
+	}
+	if err = e.walk(RootEntryPath, root); err != nil {
+		return
+	}
+	if e.data != nil {
+		if err = e.data.Close(); err != nil {
+			return
+		}
+	}
+	if err = e.manifest.flush(); err != nil {
+		return
+	}
+	progress("Exported %v files, %v bytes\n", e.files, e.bytes)
+	return
+}
+
+// walk exports the entry and then its children in the order of names.
+func (e *exporter) walk(p string, info *proto.InodeInfo) (err error) {
+	if err = e.export(p, info); err != nil {
+		return
+	}
+	if !proto.IsDir(info.Mode) {
+		return
+	}
+	var dentries []proto.Dentry
+	if dentries, err = e.vol.mw.ReadDir_ll(info.Inode); err != nil {
+		return fmt.Errorf("read dir %v: %v", p, err)
+	}
+	sort.Slice(dentries, func(i, j int) bool {
+		return dentries[i].Name < dentries[j].Name
+	})
+	for _, dentry := range dentries {
+		var child *proto.InodeInfo
+		if child, err = e.vol.mw.InodeGet_ll(dentry.Inode); err != nil {
+			return fmt.Errorf("get inode of %v: %v", path.Join(p, dentry.Name), err)
+		}
+		if err = e.walk(path.Join(p, dentry.Name), child); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (e *exporter) export(p string, info *proto.InodeInfo) (err error) {
+	entry := &ManifestEntry{
+		Path:       p,
+		Mode:       uint32(proto.OsMode(info.Mode)),
+		Uid:        info.Uid,
+		Gid:        info.Gid,
+		Size:       info.Size,
+		ModifyTime: info.ModifyTime.Unix(),
+		AccessTime: info.AccessTime.Unix(),
+		Inode:      info.Inode,
+	}
+	if entry.XAttrs, err = e.xattrs(info.Inode); err != nil {
+		return fmt.Errorf("get xattrs of %v: %v", p, err)
+	}
+	switch {
+	case proto.IsDir(info.Mode):
+		entry.Type = EntryTypeDir
+	case proto.IsSymlink(info.Mode):
+		entry.Type = EntryTypeSymlink
+		entry.Target = string(info.Target)
+	default:
+		entry.Type = EntryTypeFile
+		if first, ok := e.links[info.Inode]; ok {
+			entry.LinkTo = first
+			return e.manifest.write(entry)
+		}
+		if info.Nlink > 1 {
+			e.links[info.Inode] = p
+		}
+		if _, _, entry.Extents, err = e.vol.mw.GetExtents(info.Inode); err != nil {
+			return fmt.Errorf("get extents of %v: %v", p, err)
+		}
+		if e.checksum {
+			if entry.MD5, err = e.exportData(p, info); err != nil {
+				return
+			}
+		}
+		e.files++
+		e.bytes += info.Size
+	}
+	return e.manifest.write(entry)
+}
+
+func (e *exporter) xattrs(ino uint64) (xattrs map[string]string, err error) {
+	var names []string
+	if names, err = e.vol.mw.XAttrsList_ll(ino); err != nil || len(names) == 0 {
+		return
+	}
+	xattrs = make(map[string]string, len(names))
+	for _, name := range names {
+		var info *proto.XAttrInfo
+		if info, err = e.vol.mw.XAttrGet_ll(ino, name); err != nil {
+			return
+		}
+		xattrs[name] = info.XAttrs[name]
+	}
+	return
+}
+
+// exportData reads the file, writes it to the tar stream if required, and returns the checksum.
+func (e *exporter) exportData(p string, info *proto.InodeInfo) (checksum string, err error) {
+	var (
+		h = md5.New()
+		w io.Writer
+	)
+	w = h
+	if e.data != nil {
+		if err = e.data.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     p,
+			Size:     int64(info.Size),
+			Mode:     int64(proto.OsMode(info.Mode).Perm()),
+			Uid:      int(info.Uid),
+			Gid:      int(info.Gid),
+			ModTime:  info.ModifyTime,
+		}); err != nil {
+			return
+		}
+		w = io.MultiWriter(h, e.data)
+	}
+	if err = e.readFile(info.Inode, info.Size, w); err != nil {
+		return "", fmt.Errorf("read data of %v: %v", p, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (e *exporter) readFile(ino, size uint64, w io.Writer) (err error) {
+	if err = e.vol.ec.OpenStream(ino); err != nil {
+		return
+	}
+	defer func() {
+		e.vol.ec.CloseStream(ino)
+		e.vol.ec.EvictStream(ino)
+	}()
+	var offset uint64
+	for offset < size {
+		n := len(e.buf)
+		if rest := size - offset; uint64(n) > rest {
+			n = int(rest)
+		}
+		var read int
+		if read, err = e.vol.ec.Read(ino, e.buf, int(offset), n); err != nil && err != io.EOF {
+			return
+		}
+		if read == 0 {
+			// the tar entry must have the size in the header, the file is changed during the export
+			return fmt.Errorf("file is truncated at %v, expected size %v", offset, size)
+		}
+		if _, err = w.Write(e.buf[:read]); err != nil {
+			return
+		}
+		offset += uint64(read)
+	}
+	return nil
+}
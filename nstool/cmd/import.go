@@ -0,0 +1,266 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   "import",
+		Short: "recreate a directory subtree from a manifest and its data tar stream",
+		Long: `Recreate a directory subtree from a manifest and its data tar stream.
+The subtree is created under the path, which is created if it does not exist.
+Without the data, only directories and symlinks are recreated and files are skipped.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := Import(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	return c
+}
+
+type importer struct {
+	vol     *volume
+	data    *tar.Reader
+	inodes  map[string]uint64 // path in the manifest to the inode in the target volume
+	dirs    []*ManifestEntry
+	files   int
+	skipped int
+	bytes   uint64
+}
+
+func Import() (err error) {
+	var vol *volume
+	if vol, err = openVolume(); err != nil {
+		return
+	}
+	defer vol.close()
+
+	var mfile *os.File
+	if mfile, err = os.Open(ManifestFile); err != nil {
+		return
+	}
+	defer mfile.Close()
+	var (
+		manifest *manifestReader
+		header   *ManifestHeader
+	)
+	if manifest, header, err = newManifestReader(mfile); err != nil {
+		return
+	}
+	if DataFile != "" && !header.WithData {
+		return fmt.Errorf("manifest is exported without data")
+	}
+
+	im := &importer{
+		vol:    vol,
+		inodes: make(map[string]uint64),
+	}
+	if DataFile != "" {
+		var dfile = os.Stdin
+		if DataFile != "-" {
+			if dfile, err = os.Open(DataFile); err != nil {
+				return
+			}
+			defer dfile.Close()
+		}
+		im.data = tar.NewReader(dfile)
+	}
+	if im.inodes[RootEntryPath], err = im.mkdirAll(SubDir); err != nil {
+		return
+	}
+
+	for {
+		var entry *ManifestEntry
+		if entry, err = manifest.next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		if err = im.restore(entry); err != nil {
+			return fmt.Errorf("import %v: %v", entry.Path, err)
+		}
+	}
+	// the times of directories are changed by their children, so they are set at last
+	for i := len(im.dirs) - 1; i >= 0; i-- {
+		if err = im.setattr(im.inodes[im.dirs[i].Path], im.dirs[i]); err != nil {
+			return fmt.Errorf("import %v: %v", im.dirs[i].Path, err)
+		}
+	}
+	progress("Imported %v files, %v bytes, %v files skipped\n", im.files, im.bytes, im.skipped)
+	return nil
+}
+
+func (im *importer) mkdirAll(dir string) (ino uint64, err error) {
+	ino = proto.RootIno
+	for _, name := range strings.Split(dir, "/") {
+		if name == "" {
+			continue
+		}
+		var (
+			child uint64
+			mode  uint32
+			info  *proto.InodeInfo
+		)
+		if child, mode, err = im.vol.mw.Lookup_ll(ino, name); err == nil {
+			if !proto.IsDir(mode) {
+				return 0, fmt.Errorf("%v is not a directory", name)
+			}
+			ino = child
+			continue
+		}
+		if err != syscall.ENOENT {
+			return
+		}
+		if info, err = im.vol.mw.Create_ll(ino, name, proto.Mode(os.ModeDir|0755), 0, 0, nil); err != nil {
+			return
+		}
+		ino = info.Inode
+	}
+	return
+}
+
+func (im *importer) restore(entry *ManifestEntry) (err error) {
+	var info *proto.InodeInfo
+	if entry.Path == RootEntryPath {
+		im.dirs = append(im.dirs, entry)
+		return im.setxattrs(im.inodes[RootEntryPath], entry)
+	}
+	parent, ok := im.inodes[path.Dir(entry.Path)]
+	if !ok {
+		return fmt.Errorf("parent is not imported")
+	}
+	name := path.Base(entry.Path)
+	mode := proto.Mode(os.FileMode(entry.Mode))
+	switch entry.Type {
+	case EntryTypeDir:
+		if info, err = im.vol.mw.Create_ll(parent, name, mode, entry.Uid, entry.Gid, nil); err != nil {
+			return
+		}
+		im.inodes[entry.Path] = info.Inode
+		im.dirs = append(im.dirs, entry)
+		return im.setxattrs(info.Inode, entry)
+	case EntryTypeSymlink:
+		if info, err = im.vol.mw.Create_ll(parent, name, mode, entry.Uid, entry.Gid, []byte(entry.Target)); err != nil {
+			return
+		}
+	case EntryTypeFile:
+		if entry.LinkTo != "" {
+			target, ok := im.inodes[entry.LinkTo]
+			if !ok {
+				im.skipped++
+				return nil
+			}
+			_, err = im.vol.mw.Link(parent, name, target)
+			return
+		}
+		if im.data == nil {
+			im.skipped++
+			return nil
+		}
+		if info, err = im.vol.mw.Create_ll(parent, name, mode, entry.Uid, entry.Gid, nil); err != nil {
+			return
+		}
+		im.inodes[entry.Path] = info.Inode
+		if err = im.writeFile(info.Inode, entry); err != nil {
+			return
+		}
+		im.files++
+		im.bytes += entry.Size
+	default:
+		return fmt.Errorf("unknown entry type: %v", entry.Type)
+	}
+	if err = im.setxattrs(info.Inode, entry); err != nil {
+		return
+	}
+	return im.setattr(info.Inode, entry)
+}
+
+// writeFile writes the data of the next tar entry, which must be the same file as the manifest entry.
+func (im *importer) writeFile(ino uint64, entry *ManifestEntry) (err error) {
+	var hdr *tar.Header
+	if hdr, err = im.data.Next(); err != nil {
+		return fmt.Errorf("read data: %v", err)
+	}
+	if hdr.Name != entry.Path || uint64(hdr.Size) != entry.Size {
+		return fmt.Errorf("data %v(%v bytes) does not match the manifest", hdr.Name, hdr.Size)
+	}
+	if err = im.vol.ec.OpenStream(ino); err != nil {
+		return
+	}
+	defer func() {
+		im.vol.ec.CloseStream(ino)
+		im.vol.ec.EvictStream(ino)
+	}()
+	var (
+		h      = md5.New()
+		buf    = make([]byte, readBufferSize)
+		offset int
+	)
+	for {
+		n, readErr := im.data.Read(buf)
+		if n > 0 {
+			if _, err = im.vol.ec.Write(ino, offset, buf[:n], 0); err != nil {
+				return
+			}
+			h.Write(buf[:n])
+			offset += n
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if err = im.vol.ec.Flush(ino); err != nil {
+		return
+	}
+	if checksum := hex.EncodeToString(h.Sum(nil)); entry.MD5 != "" && checksum != entry.MD5 {
+		return fmt.Errorf("checksum mismatch: expected %v, got %v", entry.MD5, checksum)
+	}
+	return
+}
+
+func (im *importer) setxattrs(ino uint64, entry *ManifestEntry) (err error) {
+	for name, value := range entry.XAttrs {
+		if err = im.vol.mw.XAttrSet_ll(ino, []byte(name), []byte(value)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (im *importer) setattr(ino uint64, entry *ManifestEntry) error {
+	valid := proto.AttrMode | proto.AttrUid | proto.AttrGid | proto.AttrModifyTime | proto.AttrAccessTime
+	return im.vol.mw.Setattr(ino, valid, proto.Mode(os.FileMode(entry.Mode)), entry.Uid, entry.Gid,
+		entry.AccessTime, entry.ModifyTime)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// The manifest is a JSON lines file. The first line is the header, and each of the
+// following lines is an entry of the subtree. Entries are in the pre-order of the
+// tree, so that a directory always comes before its children.
+const (
+	ManifestVersion = 1
+
+	EntryTypeDir     = "dir"
+	EntryTypeFile    = "file"
+	EntryTypeSymlink = "symlink"
+
+	RootEntryPath = "."
+)
+
+type ManifestHeader struct {
+	Version    int    `json:"version"`
+	Cluster    string `json:"cluster"`
+	Volume     string `json:"volume"`
+	Path       string `json:"path"`
+	CreateTime int64  `json:"createTime"`
+	WithData   bool   `json:"withData"`
+}
+
+type ManifestEntry struct {
+	Path       string            `json:"path"`
+	Type       string            `json:"type"`
+	Mode       uint32            `json:"mode"` // os.FileMode
+	Uid        uint32            `json:"uid"`
+	Gid        uint32            `json:"gid"`
+	Size       uint64            `json:"size"`
+	ModifyTime int64             `json:"mtime"`
+	AccessTime int64             `json:"atime"`
+	Inode      uint64            `json:"ino"`              // inode in the source volume
+	LinkTo     string            `json:"linkTo,omitempty"` // path of the previous hard link
+	Target     string            `json:"target,omitempty"` // target of the symlink
+	XAttrs     map[string]string `json:"xattrs,omitempty"`
+	MD5        string            `json:"md5,omitempty"`
+	Extents    []proto.ExtentKey `json:"extents,omitempty"` // extent locations in the source cluster
+}
+
+type manifestWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newManifestWriter(w io.Writer, header *ManifestHeader) (mw *manifestWriter, err error) {
+	bw := bufio.NewWriter(w)
+	mw = &manifestWriter{w: bw, enc: json.NewEncoder(bw)}
+	if err = mw.enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (mw *manifestWriter) write(entry *ManifestEntry) error {
+	return mw.enc.Encode(entry)
+}
+
+func (mw *manifestWriter) flush() error {
+	return mw.w.Flush()
+}
+
+type manifestReader struct {
+	dec *json.Decoder
+}
+
+func newManifestReader(r io.Reader) (mr *manifestReader, header *ManifestHeader, err error) {
+	mr = &manifestReader{dec: json.NewDecoder(bufio.NewReader(r))}
+	header = &ManifestHeader{}
+	if err = mr.dec.Decode(header); err != nil {
+		return nil, nil, fmt.Errorf("decode manifest header: %v", err)
+	}
+	if header.Version != ManifestVersion {
+		return nil, nil, fmt.Errorf("unsupported manifest version: %v", header.Version)
+	}
+	return
+}
+
+// next returns the next entry, or io.EOF at the end of the manifest.
+func (mr *manifestReader) next() (entry *ManifestEntry, err error) {
+	if !mr.dec.More() {
+		return nil, io.EOF
+	}
+	entry = &ManifestEntry{}
+	if err = mr.dec.Decode(entry); err != nil {
+		return nil, fmt.Errorf("decode manifest entry: %v", err)
+	}
+	return
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestManifest(t *testing.T) {
+	entries := []*ManifestEntry{
+		{Path: RootEntryPath, Type: EntryTypeDir, Mode: uint32(os.ModeDir | 0755)},
+		{Path: "a", Type: EntryTypeFile, Mode: 0644, Size: 3, MD5: "900150983cd24fb0d6963f7d28e17f72",
+			Extents: []proto.ExtentKey{{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 3}},
+			XAttrs:  map[string]string{"user.k": "v"}},
+		{Path: "b", Type: EntryTypeFile, Mode: 0644, LinkTo: "a"},
+		{Path: "c", Type: EntryTypeSymlink, Mode: uint32(os.ModeSymlink | 0777), Target: "a"},
+	}
+	buf := bytes.NewBuffer(nil)
+	w, err := newManifestWriter(buf, &ManifestHeader{Version: ManifestVersion, Volume: "ltptest", Path: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err = w.write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, header, err := newManifestReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Volume != "ltptest" {
+		t.Fatalf("unexpected header: %+v", header)
+	}
+	for _, expected := range entries {
+		entry, err := r.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(entry, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, entry)
+		}
+	}
+	if _, err = r.next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestManifestVersion(t *testing.T) {
+	if _, _, err := newManifestReader(bytes.NewBufferString(`{"version":2}`)); err == nil {
+		t.Fatalf("unsupported version should be refused")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+var (
+	MasterAddr   string
+	VolName      string
+	Owner        string
+	SubDir       string
+	ManifestFile string
+	DataFile     string
+)
+
+func NewRootCmd() *cobra.Command {
+	var c = &cobra.Command{
+		Use:   path.Base(os.Args[0]),
+		Short: "ChubaoFS namespace export and import tool",
+		Args:  cobra.MinimumNArgs(0),
+	}
+
+	c.AddCommand(
+		newExportCmd(),
+		newImportCmd(),
+	)
+
+	c.PersistentFlags().StringVarP(&MasterAddr, "master", "m", "", "master addresses, separated by comma")
+	c.PersistentFlags().StringVarP(&VolName, "vol", "v", "", "volume name")
+	c.PersistentFlags().StringVarP(&Owner, "owner", "o", "", "owner of the volume")
+	c.PersistentFlags().StringVarP(&SubDir, "path", "p", "/", "directory of the subtree in the volume")
+	c.PersistentFlags().StringVarP(&ManifestFile, "manifest", "f", "", "manifest file")
+	c.PersistentFlags().StringVarP(&DataFile, "data", "d", "", "tar file of the data, '-' for stdin or stdout")
+	return c
+}
+
+// volume is the client of a volume which reads and writes both metadata and data.
+type volume struct {
+	mw *meta.MetaWrapper
+	ec *stream.ExtentClient
+}
+
+func openVolume() (v *volume, err error) {
+	if MasterAddr == "" || VolName == "" || Owner == "" || ManifestFile == "" {
+		return nil, fmt.Errorf("Lack of mandatory args: master(%v) vol(%v) owner(%v) manifest(%v)",
+			MasterAddr, VolName, Owner, ManifestFile)
+	}
+	masters := strings.Split(MasterAddr, ",")
+	v = &volume{}
+	if v.mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:        VolName,
+		Owner:         Owner,
+		Masters:       masters,
+		ValidateOwner: true,
+	}); err != nil {
+		return nil, err
+	}
+	if v.ec, err = stream.NewExtentClient(&stream.ExtentConfig{
+		Volume:            VolName,
+		Masters:           masters,
+		FollowerRead:      true,
+		OnAppendExtentKey: v.mw.AppendExtentKey,
+		OnGetExtents:      v.mw.GetExtents,
+		OnTruncate:        v.mw.Truncate,
+	}); err != nil {
+		v.mw.Close()
+		return nil, err
+	}
+	return
+}
+
+func (v *volume) close() {
+	v.ec.Close()
+	v.mw.Close()
+}
+
+// progress prints to stderr, since the data may be written to stdout.
+func progress(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+}
@@ -205,6 +205,25 @@ func (acp *AccessControlPolicy) IsAllowed(param *RequestParam, isOwner bool) boo
 	return false
 }
 
+// IsAnonymousAllowed checks whether the action of anonymous request is granted to AllUsers,
+// e.g. the bucket is configured with the public-read or public-read-write standard ACL.
+func (acp *AccessControlPolicy) IsAnonymousAllowed(param *RequestParam) bool {
+	for _, grant := range acp.Acl.Grants {
+		if grant.Grantee.URI != aclRoleURIMap[allUsersRole] {
+			continue
+		}
+		for _, actions := range []proto.Actions{
+			aclBucketPermissionActions[grant.Permission],
+			aclObjectPermissionActions[grant.Permission],
+		} {
+			if len(actions) > 0 && IsIntersectionActions(actions, param.Action()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 var (
 	aclGrantKeyPermissionMap = map[string]Permission{
 		"x-amz-grant-full-control": FullControlPermission,
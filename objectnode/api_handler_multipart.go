@@ -192,6 +192,12 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = NoSuchUpload
 		return
 	}
+	if ec := payloadErrorCode(err); ec != nil {
+		log.LogWarnf("uploadPartHandler: verify payload fail: requestID(%v) volume(%v) path(%v) uploadId(%v) part(%v) remote(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), uploadId, partNumberInt, getRequestIP(r), err)
+		errorCode = ec
+		return
+	}
 	if err == io.ErrUnexpectedEOF {
 		log.LogWarnf("uploadPartHandler: write part fail cause unexpected EOF: requestID(%v) volume(%v) path(%v) uploadId(%v) part(%v) remote(%v) err(%v)",
 			GetRequestID(r), vol.Name(), param.Object(), uploadId, partNumberInt, getRequestIP(r), err)
@@ -474,6 +480,8 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 	}
 	log.LogDebugf("completeMultipartUploadHandler: complete multipart, requestID(%v) uploadID(%v) path(%v)",
 		GetRequestID(r), uploadId, param.Object())
+	o.notifyEvent(r, vol, EventObjectCreatedCompleteMultipartUpload, EventObject{
+		Key: param.Object(), Size: fsFileInfo.Size, ETag: fsFileInfo.ETag, VersionID: fsFileInfo.VersionID})
 
 	// write response
 	completeResult := CompleteMultipartResult{
@@ -493,6 +501,9 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
 	if _, err = w.Write(bytes); err != nil {
 		log.LogErrorf("completeMultipartUploadHandler: write response body fail, requestID(%v) err(%v)", GetRequestID(r), err)
 		return
@@ -134,7 +134,16 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 
 	// get object meta
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(param.Object())
+	var versionID = r.URL.Query().Get(ParamVersionId)
+	if versionID != "" {
+		fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID)
+	} else {
+		fileInfo, err = vol.ObjectMeta(param.Object())
+	}
+	if err == syscall.ENOENT && versionID != "" {
+		errorCode = NoSuchVersion
+		return
+	}
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
 		return
@@ -145,6 +154,14 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if len(fileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fileInfo.VersionID}
+	}
+	if fileInfo.DeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
+		return
+	}
 
 	// parse request header
 	match := r.Header.Get(HeaderNameIfMatch)
@@ -314,7 +331,11 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 			size = rangeUpper - rangeLower + 1
 		}
 	}
-	err = vol.ReadFile(param.Object(), w, offset, size)
+	if versionID != "" {
+		err = vol.ReadFileVersion(param.Object(), versionID, w, offset, size)
+	} else {
+		err = vol.ReadFile(param.Object(), w, offset, size)
+	}
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
 		return
@@ -364,7 +385,16 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 
 	// get object meta
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(param.Object())
+	var versionID = r.URL.Query().Get(ParamVersionId)
+	if versionID != "" {
+		fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID)
+	} else {
+		fileInfo, err = vol.ObjectMeta(param.Object())
+	}
+	if err == syscall.ENOENT && versionID != "" {
+		errorCode = NoSuchVersion
+		return
+	}
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
 		return
@@ -375,6 +405,14 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if len(fileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fileInfo.VersionID}
+	}
+	if fileInfo.DeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
+		return
+	}
 
 	// parse request header
 	match := r.Header.Get(HeaderNameIfMatch)
@@ -568,15 +606,27 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 	var objectKeys = make([]string, 0, len(deleteReq.Objects))
 	for _, object := range deleteReq.Objects {
 		objectKeys = append(objectKeys, object.Key)
-		err = vol.DeletePath(object.Key)
-		log.LogWarnf("deleteObjectsHandler: delete: requestID(%v) volume(%v) path(%v)",
-			GetRequestID(r), vol.Name(), object.Key)
+		if isTrashKey(object.Key) {
+			deletedErrors = append(deletedErrors, Error{Key: object.Key, VersionId: object.VersionId,
+				Code: AccessDenied.ErrorCode, Message: AccessDenied.ErrorMessage})
+			continue
+		}
+		var result *DeleteObjectResult
+		result, err = vol.DeleteObject(object.Key, object.VersionId)
+		log.LogWarnf("deleteObjectsHandler: delete: requestID(%v) volume(%v) path(%v) version(%v)",
+			GetRequestID(r), vol.Name(), object.Key, object.VersionId)
 		if err != nil {
-			deletedErrors = append(deletedErrors, Error{Key: object.Key, Message: err.Error()})
+			deletedErrors = append(deletedErrors, Error{Key: object.Key, VersionId: object.VersionId, Message: err.Error()})
 			log.LogErrorf("deleteObjectsHandler: delete object failed: requestID(%v) volume(%v) path(%v) err(%v)",
 				GetRequestID(r), vol.Name(), object.Key, err)
 		} else {
-			deletedObjects = append(deletedObjects, Deleted{Key: object.Key})
+			var deleted = Deleted{Key: object.Key, VersionId: object.VersionId}
+			if result.DeleteMarker {
+				deleted.DeleteMarker = "true"
+				deleted.DeleteMarkerVersionId = result.VersionID
+			}
+			deletedObjects = append(deletedObjects, deleted)
+			o.notifyEvent(r, vol, deleteEventName(result), EventObject{Key: object.Key, VersionID: result.VersionID})
 			log.LogDebugf("deleteObjectsHandler: delete object success: requestID(%v) volume(%v) path(%v)", GetRequestID(r),
 				vol.Name(), object.Key)
 		}
@@ -682,6 +732,13 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	if len(metadataDirective) == 0 {
 		metadataDirective = MetadataDirectiveCopy
 	}
+	// only COPY and REPLACE are defined, the data is copied by the object node with either of them
+	if metadataDirective != MetadataDirectiveCopy && metadataDirective != MetadataDirectiveReplace {
+		log.LogErrorf("copyObjectHandler: invalid metadata directive: requestID(%v) directive(%v)",
+			GetRequestID(r), metadataDirective)
+		errorCode = InvalidArgument
+		return
+	}
 	var opt = &PutFileOption{
 		MIMEType:     contentType,
 		Disposition:  contentDisposition,
@@ -691,8 +748,16 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sourceBucket, sourceObject := parseCopySourceInfo(r)
+	if isTrashKey(sourceObject) {
+		errorCode = AccessDenied
+		return
+	}
 
 	// check permission, must have read permission to source bucket
+	if param.AccessKey() == "" {
+		errorCode = AccessDenied
+		return
+	}
 	var userInfo *proto.UserInfo
 	if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err != nil {
 		log.LogErrorf("copyObjectHandler: get user info from master error: requestID(%v), accessKey(%v), err(%v)",
@@ -708,9 +773,17 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get object meta
+	var sourceVol *Volume
+	if sourceVol, err = o.getVol(sourceBucket); err != nil {
+		log.LogErrorf("copyObjectHandler: load source volume fail: vol(%v) requestID(%v) err(%v)",
+			sourceBucket, getRequestIP(r), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	// get source object meta
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(sourceObject)
+	fileInfo, err = sourceVol.ObjectMeta(sourceObject)
 	if err != nil {
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
@@ -767,15 +840,6 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// open source object stream
-	var sourceVol *Volume
-	if sourceVol, err = o.getVol(sourceBucket); err != nil {
-		log.LogErrorf("copyObjectHandler: load source volume fail: vol(%v) requestID(%v) err(%v)",
-			sourceBucket, getRequestIP(r), err)
-		errorCode = NoSuchBucket
-		return
-	}
-
 	fsFileInfo, err := vol.CopyFile(sourceVol, sourceObject, param.Object(), metadataDirective, opt)
 	if err != nil && err != syscall.EINVAL && err != syscall.EFBIG {
 		log.LogErrorf("copyObjectHandler: Volume copy file fail: requestID(%v) Volume(%v) source(%v) target(%v) err(%v)",
@@ -811,7 +875,12 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
 	_, _ = w.Write(bytes)
+	o.notifyEvent(r, vol, EventObjectCreatedCopy, EventObject{
+		Key: param.Object(), Size: fsFileInfo.Size, ETag: fsFileInfo.ETag, VersionID: fsFileInfo.VersionID})
 	return
 }
 
@@ -1183,6 +1252,12 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = ObjectModeConflict
 		return
 	}
+	if ec := payloadErrorCode(err); ec != nil {
+		log.LogWarnf("putObjectHandler: verify payload fail: requestID(%v) volume(%v) path(%v) remote(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), getRequestIP(r), err)
+		errorCode = ec
+		return
+	}
 	if err == io.ErrUnexpectedEOF {
 		log.LogWarnf("putObjectHandler: put object fail cause unexpected EOF: requestID(%v) volume(%v) path(%v) remote(%v) err(%v)",
 			GetRequestID(r), vol.Name(), param.Object(), getRequestIP(r), err)
@@ -1220,6 +1295,11 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// set response header
 	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fsFileInfo.ETag)}
 	w.Header()[HeaderNameContentLength] = []string{"0"}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
+	o.notifyEvent(r, vol, EventObjectCreatedPut, EventObject{
+		Key: param.Object(), Size: fsFileInfo.Size, ETag: fsFileInfo.ETag, VersionID: fsFileInfo.VersionID})
 	return
 }
 
@@ -1258,17 +1338,27 @@ func (o *ObjectNode) deleteObjectHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var versionID = r.URL.Query().Get(ParamVersionId)
+
 	// Audit deletion
-	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v)",
-		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object())
+	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v) version(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), versionID)
 
-	err = vol.DeletePath(param.Object())
+	var result *DeleteObjectResult
+	result, err = vol.DeleteObject(param.Object(), versionID)
 	if err != nil {
 		log.LogErrorf("deleteObjectHandler: Volume delete file fail: "+
 			"requestID(%v) volume(%v) path(%v) err(%v)", GetRequestID(r), vol.Name(), param.Object(), err)
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if len(result.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{result.VersionID}
+	}
+	if result.DeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+	}
+	o.notifyEvent(r, vol, deleteEventName(result), EventObject{Key: param.Object(), VersionID: result.VersionID})
 
 	w.WriteHeader(http.StatusNoContent)
 	return
@@ -1429,7 +1519,11 @@ func (o *ObjectNode) deleteObjectTaggingHandler(w http.ResponseWriter, r *http.R
 	if err = vol.DeleteXAttr(param.object, XAttrKeyOSSTagging); err != nil {
 		log.LogErrorf("deleteObjectTaggingHandler: volume delete tagging fail: requestID(%v) volume(%v) object(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), param.Object(), err)
-		errorCode = InternalErrorCode(err)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+		} else {
+			errorCode = InternalErrorCode(err)
+		}
 		return
 	}
 
@@ -25,6 +25,7 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -100,6 +101,13 @@ func (o *ObjectNode) traceMiddleware(next http.Handler) http.Handler {
 		var startTime = time.Now()
 		metric := exporter.NewTPCnt(fmt.Sprintf("action_%v", action.Name()))
 		defer metric.Set(err)
+		span := tracing.StartSpan(action.Name(), tracing.SpanKindServer, tracing.Extract(r.Header))
+		span.SetAttribute(tracing.AttributePeer, getRequestIP(r))
+		span.SetAttribute("cfs.request.id", requestID)
+		defer func() {
+			span.SetAttribute(tracing.AttributeResult, GetStatusCodeFromContext(r))
+			span.Finish(nil)
+		}()
 
 		// Check action is whether enabled.
 		if !action.IsNone() && !o.disabledActions.Contains(action) {
@@ -167,6 +175,10 @@ func (o *ObjectNode) authMiddleware(next http.Handler) http.Handler {
 			} else if isUrlUsingSignatureAlgorithmV4(r) {
 				// using signature algorithm version 4 in url parameter
 				pass, err = o.validateUrlBySignatureAlgorithmV4(r)
+			} else if r.Header.Get(HeaderNameAuthorization) == "" {
+				// anonymous request without any signature, whether it is allowed depends on
+				// the bucket policy and ACL, see policyCheck.
+				pass = true
 			}
 
 			if err != nil {
@@ -205,12 +217,14 @@ func (o *ObjectNode) policyCheckMiddleware(next http.Handler) http.Handler {
 
 // ContentMiddleware returns a middleware handler to process reader for content.
 // If the request contains the "X-amz-Decoded-Content-Length" header, it means that the data
-// in the request body is chunked. Use ChunkedReader to parse the data.
+// in the request body is chunked. Use ChunkedReader to parse the data, unless the body has
+// been wrapped with SignedChunkedReader while verifying the streaming payload signature.
 // Workflow:
 //   request → [pre-handle] → [next handler] → response
 func (o *ObjectNode) contentMiddleware(next http.Handler) http.Handler {
 	var handlerFunc http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if len(r.Header) > 0 && len(r.Header.Get(http.CanonicalHeaderKey(HeaderNameXAmzDecodeContentLength))) > 0 {
+		if _, signed := r.Body.(*signedChunkedReader); !signed &&
+			len(r.Header) > 0 && len(r.Header.Get(http.CanonicalHeaderKey(HeaderNameXAmzDecodeContentLength))) > 0 {
 			r.Body = NewClosableChunkedReader(r.Body)
 			log.LogDebugf("contentMiddleware: chunk reader inited: requestID(%v)", GetRequestID(r))
 		}
@@ -240,52 +254,97 @@ func (o *ObjectNode) expectMiddleware(next http.Handler) http.Handler {
 }
 
 // CORSMiddleware returns a middleware handler to support CORS request.
-// This handler will write following header into response:
-//   Access-Control-Allow-Origin [*]
-//   Access-Control-Allow-Headers [*]
-//   Access-Control-Allow-Methods [*]
-//   Access-Control-Max-Age [0]
+// Requests carrying an Origin header are evaluated against the CORS configuration of the
+// bucket, the first matching rule decides the response headers:
+//   Access-Control-Allow-Origin [origin]
+//   Access-Control-Allow-Methods [rule methods]
+//   Access-Control-Allow-Headers [requested headers]
+//   Access-Control-Expose-Headers [rule expose headers]
+//   Access-Control-Max-Age [rule max age]
+// A preflight request (OPTIONS with Access-Control-Request-Method) is answered here directly,
+// since browsers never sign it. It is rejected with 403 if the bucket has no CORS configuration
+// or no rule allows it.
 // Workflow:
 //   request → [pre-handle] → [next handler] → response
 func (o *ObjectNode) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		var err error
+		var origin = r.Header.Get(Origin)
 		var param = ParseRequestParam(r)
-		if param.Bucket() == "" {
+		if origin == "" || param.Bucket() == "" {
 			next.ServeHTTP(w, r)
 			return
 		}
+		var method = r.Method
+		var isPreflight = r.Method == http.MethodOptions && r.Header.Get(HeaderNameAccessControlRequestMethod) != ""
+		if isPreflight {
+			method = r.Header.Get(HeaderNameAccessControlRequestMethod)
+		}
+
 		var vol *Volume
 		if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+			if isPreflight {
+				_ = NoSuchBucket.ServeResponse(w, r)
+				return
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		var setupCORSHeader = func(volume *Volume, writer http.ResponseWriter, request *http.Request) {
-			origin := request.Header.Get(Origin)
-			method := request.Header.Get(HeaderNameAccessControlRequestMethod)
-			headerStr := request.Header.Get(HeaderNameAccessControlRequestHeaders)
-			if origin == "" || method == "" {
+		var cors *CORSConfiguration
+		if cors, err = vol.metaLoader.loadCors(); err != nil {
+			log.LogErrorf("corsMiddleware: load cors configuration fail: requestID(%v) volume(%v) err(%v)",
+				GetRequestID(r), vol.Name(), err)
+		}
+		var rule *CORSRule
+		if cors != nil {
+			var headers []string
+			if headerStr := r.Header.Get(HeaderNameAccessControlRequestHeaders); isPreflight && headerStr != "" {
+				headers = strings.Split(headerStr, ",")
+			}
+			rule = cors.match(origin, method, headers)
+		}
+		w.Header().Add(HeaderNameVary, Origin)
+
+		if isPreflight {
+			w.Header().Add(HeaderNameVary, HeaderNameAccessControlRequestMethod)
+			w.Header().Add(HeaderNameVary, HeaderNameAccessControlRequestHeaders)
+			if cors == nil || len(cors.CORSRule) == 0 {
+				_ = CORSNotEnabled.ServeResponse(w, r)
 				return
 			}
-			cors, _ := volume.metaLoader.loadCors()
-			if cors != nil {
-				headers := strings.Split(headerStr, ",")
-				for _, corsRule := range cors.CORSRule {
-					if corsRule.match(origin, method, headers) {
-						// write access control allow headers
-						writer.Header()[HeaderNameAccessControlAllowOrigin] = []string{origin}
-						writer.Header()[HeaderNameAccessControlMaxAge] = []string{strconv.Itoa(int(corsRule.MaxAgeSeconds))}
-						writer.Header()[HeaderNameAccessControlAllowMethods] = []string{strings.Join(corsRule.AllowedMethod, ",")}
-						writer.Header()[HeaderNameAccessControlAllowHeaders] = []string{strings.Join(corsRule.AllowedHeader, ",")}
-						writer.Header()[HeaderNamrAccessControlExposeHeaders] = []string{strings.Join(corsRule.ExposeHeader, ",")}
-						return
-					}
-				}
+			if rule == nil {
+				log.LogDebugf("corsMiddleware: preflight not allowed: requestID(%v) volume(%v) origin(%v) method(%v)",
+					GetRequestID(r), vol.Name(), origin, method)
+				_ = CORSNotAllowed.ServeResponse(w, r)
+				return
+			}
+			w.Header()[HeaderNameAccessControlAllowOrigin] = []string{origin}
+			w.Header()[HeaderNameAccessControlAllowMethods] = []string{strings.Join(rule.AllowedMethod, ", ")}
+			if headerStr := r.Header.Get(HeaderNameAccessControlRequestHeaders); headerStr != "" {
+				w.Header()[HeaderNameAccessControlAllowHeaders] = []string{headerStr}
+			}
+			if len(rule.ExposeHeader) > 0 {
+				w.Header()[HeaderNamrAccessControlExposeHeaders] = []string{strings.Join(rule.ExposeHeader, ", ")}
+			}
+			if rule.MaxAgeSeconds > 0 {
+				w.Header()[HeaderNameAccessControlMaxAge] = []string{strconv.Itoa(int(rule.MaxAgeSeconds))}
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if rule != nil {
+			w.Header()[HeaderNameAccessControlAllowOrigin] = []string{origin}
+			w.Header()[HeaderNameAccessControlAllowMethods] = []string{strings.Join(rule.AllowedMethod, ", ")}
+			if len(rule.ExposeHeader) > 0 {
+				w.Header()[HeaderNamrAccessControlExposeHeaders] = []string{strings.Join(rule.ExposeHeader, ", ")}
+			}
+			if rule.MaxAgeSeconds > 0 {
+				w.Header()[HeaderNameAccessControlMaxAge] = []string{strconv.Itoa(int(rule.MaxAgeSeconds))}
 			}
 		}
-		setupCORSHeader(vol, w, r)
 		next.ServeHTTP(w, r)
 		return
 	})
@@ -14,7 +14,14 @@
 
 package objectnode
 
-import "net/http"
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
 
 //https://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#ConstructingTheAuthenticationHeader
 
@@ -62,3 +69,25 @@ func parseRequestAuthInfo(r *http.Request) *RequestAuthInfo {
 
 	return auth
 }
+
+// isValidSessionRequest checks the session credential issued by AssumeRole. If the access key
+// belongs to a session credential, the request must carry the paired session token before the
+// credential expires. Requests signed with long-lived access keys are always valid here.
+func isValidSessionRequest(r *http.Request, userInfo *proto.UserInfo, accessKey string) bool {
+	session := userInfo.SessionOf(accessKey)
+	if session == nil {
+		return true
+	}
+	if session.Expired(time.Now().Unix()) {
+		return false
+	}
+	token := r.Header.Get(XAmzSecurityToken)
+	if token == "" {
+		token = r.URL.Query().Get(XAmzSecurityToken)
+	}
+	if token == "" {
+		// signature v2 presigned url carries the token in lower case
+		token = r.URL.Query().Get(strings.ToLower(XAmzSecurityToken))
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(session.SessionToken)) == 1
+}
@@ -182,7 +182,12 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV2(r *http.Request) (bool
 	var secretKey string
 	var bucket = mux.Vars(r)["bucket"]
 	if userInfo, err := o.getUserInfoByAccessKey(accessKey); err == nil {
-		secretKey = userInfo.SecretKey
+		if !isValidSessionRequest(r, userInfo, accessKey) {
+			log.LogDebugf("validateHeaderBySignatureAlgorithmV2: invalid session credential: requestID(%v) remote(%v) accessKey(%v)",
+				GetRequestID(r), getRequestIP(r), accessKey)
+			return false, nil
+		}
+		secretKey = userInfo.SecretKeyOf(accessKey)
 	} else if (err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists) &&
 		len(bucket) > 0 && GetActionFromContext(r) != proto.OSSCreateBucketAction {
 		// In order to be directly compatible with the signature verification of version 1.5
@@ -296,7 +301,12 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV2(r *http.Request) (bool, e
 	var secretKey string
 	var bucket = mux.Vars(r)["bucket"]
 	if userInfo, err := o.getUserInfoByAccessKey(accessKey); err == nil {
-		secretKey = userInfo.SecretKey
+		if !isValidSessionRequest(r, userInfo, accessKey) {
+			log.LogDebugf("validateUrlBySignatureAlgorithmV2: invalid session credential: requestID(%v) remote(%v) accessKey(%v)",
+				GetRequestID(r), getRequestIP(r), accessKey)
+			return false, nil
+		}
+		secretKey = userInfo.SecretKeyOf(accessKey)
 	} else if (err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists) &&
 		len(bucket) > 0 && GetActionFromContext(r) != proto.OSSCreateBucketAction {
 		// In order to be directly compatible with the signature verification of version 1.5
@@ -18,6 +18,8 @@
 package objectnode
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"net/http"
@@ -34,9 +36,9 @@ import (
 )
 
 const (
-	SignatureExpires    = time.Hour * 24 * 7     // Signature is valid for seven days after the specified date.
-	MaxPresignedExpires = 3 * 365 * 24 * 60 * 60 //10years
-	DateFormatISO8601   = "20060102T150405Z"     //"yyyyMMddTHHmmssZ"
+	SignatureExpires    = time.Hour * 24 * 7 // Signature is valid for seven days after the specified date.
+	MaxPresignedExpires = 7 * 24 * 60 * 60   // Presigned URL is valid for at most seven days.
+	DateFormatISO8601   = "20060102T150405Z" //"yyyyMMddTHHmmssZ"
 	MaxSkewTime         = 15 * time.Minute
 
 	XAmzContentSha256 = "X-Amz-Content-Sha256"
@@ -46,6 +48,7 @@ const (
 	XAmzAlgorithm     = "X-Amz-Algorithm"
 	XAmzDate          = "X-Amz-Date"
 	XAmzExpires       = "X-Amz-Expires"
+	XAmzSecurityToken = "X-Amz-Security-Token"
 
 	SignatureV4Algorithm = "AWS4-HMAC-SHA256"
 	SignatureV4Request   = "aws4-request"
@@ -115,7 +118,12 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV4(r *http.Request) (bool
 	var secretKey string
 	var bucket = mux.Vars(r)["bucket"]
 	if userInfo, err := o.getUserInfoByAccessKey(accessKey); err == nil {
-		secretKey = userInfo.SecretKey
+		if !isValidSessionRequest(r, userInfo, accessKey) {
+			log.LogDebugf("validateHeaderBySignatureAlgorithmV4: invalid session credential: requestID(%v) remote(%v) accessKey(%v)",
+				GetRequestID(r), getRequestIP(r), accessKey)
+			return false, nil
+		}
+		secretKey = userInfo.SecretKeyOf(accessKey)
 	} else if (err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists) &&
 		len(bucket) > 0 && GetActionFromContext(r) != proto.OSSCreateBucketAction {
 		// In order to be directly compatible with the signature verification of version 1.5
@@ -144,9 +152,28 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV4(r *http.Request) (bool
 		return false, nil
 	}
 
+	// The signature of header only covers the declared payload hash, so that the payload
+	// itself is verified while the handler reading the body.
+	switch contentHash := getContentHash(r.Header); {
+	case contentHash == StreamingContentSHA256:
+		signingKey := buildSigningKey(SCHEME, secretKey, req.Credential.Date, req.Credential.Region, SERVICE, TERMINATOR)
+		scope := buildScope(req.Credential.Date, req.Credential.Region, SERVICE, TERMINATOR)
+		r.Body = NewSignedChunkedReader(r.Body, signingKey, getStartTime(r.Header), scope, req.Signature)
+	case isSHA256Hex(contentHash) && r.Body != nil:
+		r.Body = NewContentSHA256Reader(r.Body, contentHash)
+	}
+
 	return true, nil
 }
 
+func isSHA256Hex(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
 // url: "127.0.0.1:9000/umptest/b.tinyExtents
 //      ?X-Amz-Algorithm=AWS4-HMAC-SHA256_CMD
@@ -175,7 +202,12 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV4(r *http.Request) (pass bo
 	var secretKey string
 	var bucket = mux.Vars(r)["bucket"]
 	if userInfo, err := o.getUserInfoByAccessKey(accessKey); err == nil {
-		secretKey = userInfo.SecretKey
+		if !isValidSessionRequest(r, userInfo, accessKey) {
+			log.LogDebugf("validateUrlBySignatureAlgorithmV4: invalid session credential: requestID(%v) remote(%v) accessKey(%v)",
+				GetRequestID(r), getRequestIP(r), accessKey)
+			return false, nil
+		}
+		secretKey = userInfo.SecretKeyOf(accessKey)
 	} else if (err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists) &&
 		len(bucket) > 0 && GetActionFromContext(r) != proto.OSSCreateBucketAction {
 		// In order to be directly compatible with the signature verification of version 1.5
@@ -207,6 +239,9 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV4(r *http.Request) (pass bo
 	canonicalHeaderStr := buildCanonicalHeaderString(r.Host, canonicalHeader, req.SignedHeaders)
 	headerNames := getCanonicalHeaderNames(req.SignedHeaders)
 	payload := UnsignedPayload
+	if hashContent := req.Query().Get(XAmzContentSha256); hashContent != "" {
+		payload = hashContent
+	}
 	canonicalQuery := createCanonicalQueryV4(req)
 	canonicalRequestString := createCanonicalRequestString(r.Method, getCanonicalURI(r), canonicalQuery, canonicalHeaderStr, headerNames, payload)
 
@@ -224,7 +259,11 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV4(r *http.Request) (pass bo
 	newSignature := hex.EncodeToString(sign(stringToSign, signingKey))
 
 	//compare newSignature with request signature
-	pass = newSignature == req.Signature
+	pass = subtle.ConstantTimeCompare([]byte(newSignature), []byte(req.Signature)) == 1
+	if !pass {
+		log.LogDebugf("validateUrlBySignatureAlgorithmV4: invalid signature: requestID(%v) client(%v) server(%v)",
+			GetRequestID(r), req.Signature, newSignature)
+	}
 	return
 }
 
@@ -274,12 +313,15 @@ func (req *signatureRequestV4) GetExpires() (time.Duration, error) {
 
 //
 func (req *signatureRequestV4) isValid() (bool, error) {
+	if req.Algorithm != SignatureV4Algorithm {
+		return false, errors.New("algorithm is invalid ")
+	}
 	expires, err := req.GetExpires()
 	if err != nil {
 		return false, errors.New("expires is invalid ")
 	}
-	if expires < 0 {
-		return false, errors.New("expires < 0 ")
+	if expires <= 0 {
+		return false, errors.New("expires <= 0 ")
 	}
 	if expires.Seconds() > MaxPresignedExpires {
 		return false, errors.New("expires > MaxPresignedExpires ")
@@ -287,7 +329,10 @@ func (req *signatureRequestV4) isValid() (bool, error) {
 	utcNow := time.Now().UTC()
 	ts, err1 := req.GetTimestamp()
 	if err1 != nil {
-		return false, errors.New("expires is invalid ")
+		return false, errors.New("date is invalid ")
+	}
+	if ts.Format("20060102") != req.Credential.Date {
+		return false, errors.New("date mismatch credential scope ")
 	}
 	if ts.After(utcNow.Add(MaxSkewTime)) {
 		return false, errors.New("req date invalid ")
@@ -315,7 +360,7 @@ func (req *signatureRequestV4) parseRequestHeaderV4(r *http.Request) (err error)
 		}
 		credentialSignatureStr := util.SubString(authorizationValue, credentialIndex+len(credentialFlag), len(authorizationValue))
 		authorizationValues := strings.Split(credentialSignatureStr, ",")
-		if len(authorizationValues) < 2 {
+		if len(authorizationValues) < 3 {
 			log.LogInfof("decode signature error: %v  ", authorizationValue)
 			return errors.New("request header authorization parse error")
 		}
@@ -425,35 +470,19 @@ func parseRequestV4(r *http.Request) (req *signatureRequestV4, err error) {
 	return
 }
 
-// create canonical query not contain X-Amz-Signature query
+// create canonical query which contains all the query parameters except X-Amz-Signature,
+// such as X-Amz-Security-Token of session credential and x-amz-meta-* of user metadata.
 func createCanonicalQueryV4(req *signatureRequestV4) string {
 	newQuery := make(url.Values)
-	newQuery.Set(XAmzAlgorithm, req.Algorithm)
-	newQuery.Set(XAmzExpires, req.Expires)
-	newQuery.Set(XAmzDate, req.Timestamp)
-	newQuery.Set(XAmzSignedHeaders, req.Query().Get(XAmzSignedHeaders))
-	newQuery.Set(XAmzCredential, req.Query().Get(XAmzCredential))
-	hashContent := req.Query().Get(XAmzContentSha256)
-	if hashContent != "" {
-		newQuery.Set(XAmzContentSha256, hashContent)
-	} else {
-	}
-
 	for k, v := range req.Query() {
-		key := strings.ToLower(k)
-		if strings.Contains(key, "x-amz-meta-") {
-			newQuery.Set(k, v[0])
+		if k == XAmzSignature {
 			continue
 		}
-		if strings.Contains(key, "x-amz-server-side-") {
-			newQuery.Set(k, v[0])
-		}
-		if strings.HasPrefix(key, "x-amz") {
-			continue
-		}
-		newQuery[k] = v
+		values := make([]string, len(v))
+		copy(values, v)
+		sort.Strings(values)
+		newQuery[k] = values
 	}
-
 	return newQuery.Encode()
 }
 
@@ -15,8 +15,30 @@
 package objectnode
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"hash"
 	"io"
 	"net/http/httputil"
+	"strconv"
+	"strings"
+)
+
+const (
+	StreamingContentSHA256 = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	StreamingPayloadScheme = "AWS4-HMAC-SHA256-PAYLOAD"
+	MaxSignedChunkSize     = 16 * 1024 * 1024
+
+	chunkSignatureFlag = "chunk-signature="
+)
+
+var (
+	ErrChunkSignatureMismatch = errors.New("chunk signature does not match")
+	ErrMalformedChunk         = errors.New("malformed aws-chunked encoding")
+	ErrContentSHA256Mismatch  = errors.New("content SHA256 does not match")
 )
 
 // ClosableChunkReader wraps the chunked reader from the "httputil" package provided by Go
@@ -42,3 +64,140 @@ func NewClosableChunkedReader(source io.ReadCloser) io.ReadCloser {
 		Reader: httputil.NewChunkedReader(source),
 	}
 }
+
+// SignedChunkedReader parses the body of request uploaded with aws-chunked encoding and
+// signature V4 streaming payload, and verifies the signature of every chunk which is chained
+// with the signature of the previous chunk. The data of a chunk is only returned to the caller
+// after the signature of the chunk has been verified.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+type signedChunkedReader struct {
+	src           io.ReadCloser
+	reader        *bufio.Reader
+	signingKey    []byte
+	timestamp     string
+	scope         string
+	prevSignature string
+	chunk         []byte
+	remain        []byte
+	err           error
+}
+
+func (r *signedChunkedReader) Read(p []byte) (n int, err error) {
+	for len(r.remain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.readChunk()
+	}
+	n = copy(p, r.remain)
+	r.remain = r.remain[n:]
+	return
+}
+
+// Each chunk is encoded as "<hex size>;chunk-signature=<signature>\r\n<data>\r\n",
+// and the last chunk has zero size.
+func (r *signedChunkedReader) readChunk() (err error) {
+	var line string
+	if line, err = r.reader.ReadString('\n'); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	line = strings.TrimRight(line, "\r\n")
+	var sep = strings.Index(line, ";")
+	if sep < 0 || !strings.HasPrefix(line[sep+1:], chunkSignatureFlag) {
+		return ErrMalformedChunk
+	}
+	var size int64
+	if size, err = strconv.ParseInt(line[:sep], 16, 64); err != nil || size < 0 || size > MaxSignedChunkSize {
+		return ErrMalformedChunk
+	}
+	var signature = line[sep+1+len(chunkSignatureFlag):]
+
+	if int64(cap(r.chunk)) < size+2 {
+		r.chunk = make([]byte, size+2)
+	}
+	var data = r.chunk[:size+2]
+	if _, err = io.ReadFull(r.reader, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		return ErrMalformedChunk
+	}
+	data = data[:size]
+
+	if subtle.ConstantTimeCompare([]byte(r.chunkSignature(data)), []byte(signature)) != 1 {
+		return ErrChunkSignatureMismatch
+	}
+	r.prevSignature = signature
+	if size == 0 {
+		return io.EOF
+	}
+	r.remain = data
+	return nil
+}
+
+func (r *signedChunkedReader) chunkSignature(data []byte) string {
+	var sum = sha256.Sum256(data)
+	var stringToSign = strings.Join([]string{
+		StreamingPayloadScheme,
+		r.timestamp,
+		r.scope,
+		r.prevSignature,
+		calcHash(""),
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+	return hex.EncodeToString(sign(stringToSign, r.signingKey))
+}
+
+func (r *signedChunkedReader) Close() error {
+	return r.src.Close()
+}
+
+// NewSignedChunkedReader returns an instance of the io.ReadCloser interface used to parse
+// and verify the chunk data signed with the seed signature of request.
+func NewSignedChunkedReader(source io.ReadCloser, signingKey []byte, timestamp, scope, seedSignature string) io.ReadCloser {
+	return &signedChunkedReader{
+		src:           source,
+		reader:        bufio.NewReader(source),
+		signingKey:    signingKey,
+		timestamp:     timestamp,
+		scope:         scope,
+		prevSignature: seedSignature,
+	}
+}
+
+// ContentSHA256Reader verifies the SHA256 value of the payload declared in the
+// X-Amz-Content-Sha256 header when the whole body has been read.
+type contentSHA256Reader struct {
+	src    io.ReadCloser
+	expect string
+	hash   hash.Hash
+}
+
+func (r *contentSHA256Reader) Read(p []byte) (n int, err error) {
+	n, err = r.src.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.expect {
+		err = ErrContentSHA256Mismatch
+	}
+	return
+}
+
+func (r *contentSHA256Reader) Close() error {
+	return r.src.Close()
+}
+
+// NewContentSHA256Reader returns an instance of the io.ReadCloser interface which fails
+// with ErrContentSHA256Mismatch at the end of data if the payload does not match.
+func NewContentSHA256Reader(source io.ReadCloser, expect string) io.ReadCloser {
+	return &contentSHA256Reader{
+		src:    source,
+		expect: strings.ToLower(expect),
+		hash:   sha256.New(),
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// Sample from https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+const (
+	testStreamingSecretKey     = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+	testStreamingDate          = "20130524"
+	testStreamingTimestamp     = "20130524T000000Z"
+	testStreamingRegion        = "us-east-1"
+	testStreamingSeedSignature = "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9"
+)
+
+func newTestSignedChunkedBody(signatures []string) []byte {
+	var buf bytes.Buffer
+	for i, size := range []int{65536, 1024, 0} {
+		buf.WriteString(fmt.Sprintf("%x;chunk-signature=%s\r\n", size, signatures[i]))
+		buf.Write(bytes.Repeat([]byte{'a'}, size))
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+func newTestSignedChunkedReader(body []byte) *signedChunkedReader {
+	signingKey := buildSigningKey(SCHEME, testStreamingSecretKey, testStreamingDate, testStreamingRegion, SERVICE, TERMINATOR)
+	scope := buildScope(testStreamingDate, testStreamingRegion, SERVICE, TERMINATOR)
+	return NewSignedChunkedReader(ioutil.NopCloser(bytes.NewReader(body)), signingKey,
+		testStreamingTimestamp, scope, testStreamingSeedSignature).(*signedChunkedReader)
+}
+
+func TestSignedChunkedReader(t *testing.T) {
+	var signatures = []string{
+		"ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648",
+		"0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497",
+		"b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9",
+	}
+	data, err := ioutil.ReadAll(newTestSignedChunkedReader(newTestSignedChunkedBody(signatures)))
+	if err != nil {
+		t.Fatalf("read signed chunks fail: err(%v)", err)
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte{'a'}, 65536+1024)) {
+		t.Fatalf("decoded data mismatch: size(%v)", len(data))
+	}
+
+	// tampered signature of the second chunk
+	signatures[1] = strings.Repeat("0", 64)
+	if _, err = ioutil.ReadAll(newTestSignedChunkedReader(newTestSignedChunkedBody(signatures))); err != ErrChunkSignatureMismatch {
+		t.Fatalf("expect signature mismatch: err(%v)", err)
+	}
+
+	// malformed chunk header
+	if _, err = ioutil.ReadAll(newTestSignedChunkedReader([]byte("zz;chunk-signature=00\r\n"))); err != ErrMalformedChunk {
+		t.Fatalf("expect malformed chunk: err(%v)", err)
+	}
+}
+
+func TestContentSHA256Reader(t *testing.T) {
+	var payload = []byte("Welcome to Amazon S3.")
+	var sum = sha256.Sum256(payload)
+	var expect = hex.EncodeToString(sum[:])
+	if _, err := ioutil.ReadAll(NewContentSHA256Reader(ioutil.NopCloser(bytes.NewReader(payload)), expect)); err != nil {
+		t.Fatalf("read payload fail: err(%v)", err)
+	}
+	if _, err := ioutil.ReadAll(NewContentSHA256Reader(ioutil.NopCloser(bytes.NewReader(payload[1:])), expect)); err != ErrContentSHA256Mismatch {
+		t.Fatalf("expect content SHA256 mismatch: err(%v)", err)
+	}
+}
@@ -50,6 +50,7 @@ const (
 	HeaderNameAccessControlAllowMethods   = "Access-Control-Allow-Methods"
 	HeaderNameAccessControlAllowHeaders   = "Access-Control-Allow-Headers"
 	HeaderNamrAccessControlExposeHeaders  = "Access-Control-Expose-Headers"
+	HeaderNameVary                        = "Vary"
 
 	HeaderNameXAmzStartDate           = "x-amz-date"
 	HeaderNameXAmzRequestId           = "x-amz-request-id"
@@ -66,6 +67,8 @@ const (
 	HeaderNameXAmzMetadataDirective   = "x-amz-metadata-directive"
 	HeaderNameXAmzBucketRegion        = "x-amz-bucket-region"
 	HeaderNameXAmzTaggingCount        = "x-amz-tagging-count"
+	HeaderNameXAmzVersionId           = "x-amz-version-id"
+	HeaderNameXAmzDeleteMarker        = "x-amz-delete-marker"
 
 	HeaderNameIfMatch           = "If-Match"
 	HeaderNameIfNoneMatch       = "If-None-Match"
@@ -78,6 +81,7 @@ const (
 	HeaderValueAcceptRange          = "bytes"
 	HeaderValueTypeStream           = "application/octet-stream"
 	HeaderValueContentTypeXML       = "application/xml"
+	HeaderValueContentTypeJSON      = "application/json"
 	HeaderValueContentTypeDirectory = "application/directory"
 )
 
@@ -98,6 +102,9 @@ const (
 	ParamStartAfter = "start-after"
 	ParamKey        = "key"
 
+	ParamVersionId       = "versionId"
+	ParamVersionIdMarker = "version-id-marker"
+
 	ParamMaxParts       = "max-parts"
 	ParamUploadIdMarker = "upload-id-marker"
 	ParamPartNoMarker   = "part-number-marker"
@@ -132,6 +139,11 @@ const (
 	XAttrKeyOSSCORS         = "oss:cors"
 	XAttrKeyOSSCacheControl = "oss:cache"
 	XAttrKeyOSSExpires      = "oss:expires"
+	XAttrKeyOSSVersioning   = "oss:versioning"
+	XAttrKeyOSSVersionID    = "oss:version"
+	XAttrKeyOSSDeleteMarker = "oss:delete-marker"
+	XAttrKeyOSSLifecycle    = "oss:lifecycle"
+	XAttrKeyOSSNotification = "oss:notification"
 
 	// Deprecated
 	XAttrKeyOSSETagDeprecated = "oss:tag"
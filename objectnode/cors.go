@@ -4,10 +4,15 @@ package objectnode
 
 import (
 	"encoding/xml"
+	"strings"
 
 	"github.com/chubaofs/chubaofs/util/errors"
 )
 
+const (
+	MaxCORSRules = 100
+)
+
 var methodsRequest = []string{"GET", "PUT", "HEAD", "POST", "DELETE", "*"}
 
 type CORSConfiguration struct {
@@ -24,34 +29,88 @@ type CORSRule struct {
 }
 
 func (rule *CORSRule) match(origin, method string, headers []string) bool {
-	// todo if "*" are used in some text
-	if !contains(rule.AllowedOrigin, "*") && !contains(rule.AllowedOrigin, origin) {
+	if !rule.matchOrigin(origin) {
 		return false
 	}
 	if !contains(rule.AllowedMethod, "*") && !contains(rule.AllowedMethod, method) {
 		return false
 	}
-	if contains(rule.AllowedHeader, "*") {
-		return true
-	}
 	for _, header := range headers {
-		if !contains(rule.AllowedHeader, header) {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+		if !rule.matchHeader(header) {
 			return false
 		}
 	}
 	return true
 }
 
+func (rule *CORSRule) matchOrigin(origin string) bool {
+	for _, allowed := range rule.AllowedOrigin {
+		if corsWildcardMatch(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header names are case-insensitive, so are the AllowedHeader patterns.
+func (rule *CORSRule) matchHeader(header string) bool {
+	for _, allowed := range rule.AllowedHeader {
+		if corsWildcardMatch(strings.ToLower(allowed), strings.ToLower(header)) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsWildcardMatch reports whether value matches pattern, which may contain at most one
+// wildcard '*' matching any sequence of characters, e.g. "http://*.example.com".
+func corsWildcardMatch(pattern, value string) bool {
+	var index = strings.IndexByte(pattern, '*')
+	if index < 0 {
+		return pattern == value
+	}
+	var prefix, suffix = pattern[:index], pattern[index+1:]
+	return len(value) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(value, prefix) &&
+		strings.HasSuffix(value, suffix)
+}
+
+// match returns the first rule which allows the request, or nil if none of them does.
+func (corsConfig *CORSConfiguration) match(origin, method string, headers []string) *CORSRule {
+	for _, rule := range corsConfig.CORSRule {
+		if rule.match(origin, method, headers) {
+			return rule
+		}
+	}
+	return nil
+}
+
 func (corsConfig *CORSConfiguration) validate() bool {
-	if len(corsConfig.CORSRule) > 100 {
+	if len(corsConfig.CORSRule) == 0 || len(corsConfig.CORSRule) > MaxCORSRules {
 		return false
 	}
 	for _, rule := range corsConfig.CORSRule {
+		if rule == nil || len(rule.AllowedOrigin) == 0 || len(rule.AllowedMethod) == 0 {
+			return false
+		}
 		for _, method := range rule.AllowedMethod {
 			if !contains(methodsRequest, method) {
 				return false
 			}
 		}
+		for _, origin := range rule.AllowedOrigin {
+			if strings.Count(origin, "*") > 1 {
+				return false
+			}
+		}
+		for _, header := range rule.AllowedHeader {
+			if strings.Count(header, "*") > 1 {
+				return false
+			}
+		}
 	}
 	return true
 }
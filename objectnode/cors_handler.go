@@ -124,6 +124,6 @@ func (o *ObjectNode) deleteBucketCorsHandler(w http.ResponseWriter, r *http.Requ
 // Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTOPTIONSobject.html
 func (o *ObjectNode) optionsObjectHandler(w http.ResponseWriter, r *http.Request) {
 	log.LogInfof("optionsObjectHandler: OPTIONS object, requestID(%v) remote(%v)", GetRequestID(r), r.RemoteAddr)
-	// Already done in methods 'corsMiddleware'.
+	// CORS preflight requests are answered in 'corsMiddleware' and never reach here.
 	return
 }
@@ -0,0 +1,76 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+)
+
+func TestCORSRule_Match(t *testing.T) {
+	var rule = &CORSRule{
+		AllowedOrigin: []string{"https://*.example.com", "http://localhost:8080"},
+		AllowedMethod: []string{"GET", "PUT"},
+		AllowedHeader: []string{"Content-Type", "x-amz-*"},
+	}
+	var cases = []struct {
+		origin  string
+		method  string
+		headers []string
+		expect  bool
+	}{
+		{"https://app.example.com", "PUT", []string{"content-type", " X-Amz-Date"}, true},
+		{"http://localhost:8080", "GET", nil, true},
+		{"https://example.com", "GET", nil, false},
+		{"http://app.example.com", "GET", nil, false},
+		{"https://app.example.com", "DELETE", nil, false},
+		{"https://app.example.com", "PUT", []string{"authorization"}, false},
+	}
+	for i, c := range cases {
+		if got := rule.match(c.origin, c.method, c.headers); got != c.expect {
+			t.Fatalf("case %v: match(%v, %v, %v) expect %v but %v", i, c.origin, c.method, c.headers, c.expect, got)
+		}
+	}
+}
+
+func TestParseCorsConfig(t *testing.T) {
+	var valid = `<CORSConfiguration>
+<CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>GET</AllowedMethod><MaxAgeSeconds>300</MaxAgeSeconds></CORSRule>
+<CORSRule><AllowedOrigin>https://*.example.com</AllowedOrigin><AllowedMethod>PUT</AllowedMethod><AllowedHeader>*</AllowedHeader></CORSRule>
+</CORSConfiguration>`
+	config, err := parseCorsConfig([]byte(valid))
+	if err != nil {
+		t.Fatalf("parse valid configuration fail: err(%v)", err)
+	}
+	if rule := config.match("https://a.example.com", "PUT", []string{"content-md5"}); rule != config.CORSRule[1] {
+		t.Fatalf("expect second rule matched but %v", rule)
+	}
+	if rule := config.match("https://a.example.com", "GET", nil); rule != config.CORSRule[0] || rule.MaxAgeSeconds != 300 {
+		t.Fatalf("expect first rule matched but %v", rule)
+	}
+
+	var invalids = []string{
+		`<CORSConfiguration></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin></CORSRule></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>PATCH</AllowedMethod></CORSRule></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedOrigin>http://*.*.com</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule>`,
+	}
+	for i, invalid := range invalids {
+		if _, err = parseCorsConfig([]byte(invalid)); err == nil {
+			t.Fatalf("case %v: expect invalid configuration", i)
+		}
+	}
+}
@@ -33,6 +33,8 @@ type FSFileInfo struct {
 	CacheControl string
 	Expires      string
 	Metadata   map[string]string `graphql:"-"` // User-defined metadata
+	VersionID    string            // Only available if the versioning of the bucket is configured
+	DeleteMarker bool
 }
 
 type Prefixes []string
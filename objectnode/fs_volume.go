@@ -174,6 +174,24 @@ func (v *Volume) loadOSSMeta() {
 		return
 	}
 	v.metaLoader.storeCors(cors)
+
+	var versioning *VersioningConfiguration
+	if versioning, err = v.loadBucketVersioning(); err != nil {
+		return
+	}
+	v.metaLoader.storeVersioning(versioning)
+
+	var lifecycle *LifecycleConfiguration
+	if lifecycle, err = v.loadBucketLifecycle(); err != nil {
+		return
+	}
+	v.metaLoader.storeLifecycle(lifecycle)
+
+	var notification *NotificationConfiguration
+	if notification, err = v.loadBucketNotification(); err != nil {
+		return
+	}
+	v.metaLoader.storeNotification(notification)
 }
 
 func (v *Volume) Name() string {
@@ -236,6 +254,51 @@ func (v *Volume) loadBucketCors() (configuration *CORSConfiguration, err error)
 	return configuration, nil
 }
 
+func (v *Volume) loadBucketVersioning() (config *VersioningConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSVersioning); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	config = &VersioningConfiguration{}
+	if err = json.Unmarshal(raw, config); err != nil {
+		return
+	}
+	return config, nil
+}
+
+func (v *Volume) loadBucketLifecycle() (config *LifecycleConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSLifecycle); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	config = &LifecycleConfiguration{}
+	if err = json.Unmarshal(raw, config); err != nil {
+		return
+	}
+	return config, nil
+}
+
+func (v *Volume) loadBucketNotification() (config *NotificationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSNotification); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	config = &NotificationConfiguration{}
+	if err = json.Unmarshal(raw, config); err != nil {
+		return
+	}
+	return config, nil
+}
+
 func (v *Volume) getInodeFromPath(path string) (inode uint64, err error) {
 	if path == "/" {
 		return volumeRootInode, nil
@@ -612,7 +675,7 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	}
 
 	// apply new inode to dentry
-	err = v.applyInodeToDEntry(parentId, lastPathItem.Name, invisibleTempDataInode.Inode)
+	fsInfo.VersionID, err = v.applyInodeToDEntry(path, parentId, lastPathItem.Name, invisibleTempDataInode.Inode)
 	if err != nil {
 		log.LogErrorf("PutObject: apply new inode to dentry fail: parentID(%v) name(%v) inode(%v) err(%v)",
 			parentId, lastPathItem.Name, invisibleTempDataInode.Inode, err)
@@ -621,9 +684,19 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	return fsInfo, nil
 }
 
-func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64) (err error) {
+// applyInodeToDEntry makes the inode the object of the path, and returns the version ID of the object
+// if the versioning of the bucket is configured. The replaced object is kept as a noncurrent version.
+func (v *Volume) applyInodeToDEntry(path string, parentId uint64, name string, inode uint64) (versionID string, err error) {
+	var versioning = v.versioningConfig()
+	if versioning.IsConfigured() {
+		if versionID, err = v.prepareObjectVersion(versioning, path, inode); err != nil {
+			return
+		}
+	}
+
+	var existInode uint64
 	var existMode uint32
-	_, existMode, err = v.mw.Lookup_ll(parentId, name)
+	existInode, existMode, err = v.mw.Lookup_ll(parentId, name)
 	if err != nil && err != syscall.ENOENT {
 		log.LogErrorf("applyInodeToDEntry: meta lookup fail: parentID(%v) name(%v) err(%v)", parentId, name, err)
 		return
@@ -644,6 +717,14 @@ func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64)
 			err = syscall.EINVAL
 			return
 		}
+		if versioning.IsConfigured() && existInode != inode {
+			// The extra link of the replaced inode is released after the dentry is updated.
+			if _, err = v.archiveVersion(versioning, path, existInode); err != nil {
+				log.LogErrorf("applyInodeToDEntry: archive replaced version fail: volume(%v) path(%v) inode(%v) err(%v)",
+					v.name, path, existInode, err)
+				return
+			}
+		}
 		if err = v.applyInodeToExistDentry(parentId, name, inode); err != nil {
 			log.LogErrorf("applyInodeToDEntry: apply inode to exist dentry fail: parentID(%v) name(%v) inode(%v) err(%v)",
 				parentId, name, inode, err)
@@ -686,7 +767,7 @@ func (v *Volume) DeletePath(path string) (err error) {
 	if mode.IsDir() {
 		// Check if the directory is empty and cannot delete non-empty directories.
 		var dentries []proto.Dentry
-		dentries, err = v.mw.ReadDir_ll(ino)
+		dentries, _, err = v.mw.ReadDirLimit_ll(ino, "", 1)
 		if err != nil || len(dentries) > 0 {
 			return
 		}
@@ -1026,7 +1107,7 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 	}
 
 	// apply new inode to dentry
-	err = v.applyInodeToDEntry(parentId, filename, completeInodeInfo.Inode)
+	fInfo.VersionID, err = v.applyInodeToDEntry(path, parentId, filename, completeInodeInfo.Inode)
 	if err != nil {
 		log.LogErrorf("CompleteMultipart: apply new inode to dentry fail, parent id (%v), file name(%v), inode(%v)",
 			parentId, filename, completeInodeInfo.Inode)
@@ -1191,7 +1272,22 @@ func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) er
 	if mode.IsDir() {
 		return nil
 	}
+	return v.readInode(path, ino, writer, offset, size)
+}
 
+// ReadFileVersion reads the data of the specified version of the object.
+func (v *Volume) ReadFileVersion(path, versionID string, writer io.Writer, offset, size uint64) error {
+	ino, mode, err := v.lookupObjectVersion(path, versionID)
+	if err != nil {
+		return err
+	}
+	if mode.IsDir() {
+		return nil
+	}
+	return v.readInode(path, ino, writer, offset, size)
+}
+
+func (v *Volume) readInode(path string, ino uint64, writer io.Writer, offset, size uint64) (err error) {
 	// read file data
 	var inoInfo *proto.InodeInfo
 	if inoInfo, err = v.mw.InodeGet_ll(ino); err != nil {
@@ -1269,7 +1365,20 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		}
 		break
 	}
+	if info, err = v.inodeObjectMeta(path, mode, inoInfo); err != nil {
+		return
+	}
+	if !mode.IsDir() && v.versioningConfig().IsConfigured() {
+		if info.VersionID, err = v.inodeVersionID(inode); err != nil {
+			log.LogErrorf("ObjectMeta: get version ID fail: volume(%v) path(%v) inode(%v) err(%v)", v.name, path, inode, err)
+			return
+		}
+	}
+	return
+}
 
+func (v *Volume) inodeObjectMeta(path string, mode os.FileMode, inoInfo *proto.InodeInfo) (info *FSFileInfo, err error) {
+	var inode = inoInfo.Inode
 	var (
 		etagValue    ETagValue
 		mimeType     string
@@ -1527,15 +1636,16 @@ func (v *Volume) listFilesV1(prefix, marker, delimiter string, maxKeys uint64) (
 	// Check this value when adding key to contents or common prefix,
 	// return if it reach to max keys
 	var rc uint64
-	// recursion scan
-	infos, prefixMap, nextMarker, _, err = v.recursiveScan(infos, prefixMap, parentId, maxKeys, rc, dirs, prefix, marker, delimiter)
+	// recursion scan, the inodes of the results are got in batches while scanning
+	iget := v.mw.NewBatchIgetPipeline()
+	infos, prefixMap, nextMarker, _, err = v.recursiveScan(infos, prefixMap, iget, parentId, maxKeys, rc, dirs, prefix, marker, delimiter)
 	if err != nil {
 		log.LogErrorf("listFilesV1: volume list dir fail: Volume(%v) err(%v)", v.name, err)
 		return
 	}
 
 	// Supplementary file information, such as file modification time, MIME type, Etag information, etc.
-	if err = v.supplyListFileInfo(infos); err != nil {
+	if err = v.supplyListFileInfo(infos, iget); err != nil {
 		log.LogDebugf("listFilesV1: supply list file info fail, err(%v)", err)
 		return
 	}
@@ -1578,15 +1688,16 @@ func (v *Volume) listFilesV2(prefix, startAfter, contToken, delimiter string, ma
 	// Check this value when adding key to contents or common prefix,
 	// return if it reach to max keys
 	var rc uint64
-	// recursion scan
-	infos, prefixMap, nextMarker, _, err = v.recursiveScan(infos, prefixMap, parentId, maxKeys, rc, dirs, prefix, marker, delimiter)
+	// recursion scan, the inodes of the results are got in batches while scanning
+	iget := v.mw.NewBatchIgetPipeline()
+	infos, prefixMap, nextMarker, _, err = v.recursiveScan(infos, prefixMap, iget, parentId, maxKeys, rc, dirs, prefix, marker, delimiter)
 	if err != nil {
 		log.LogErrorf("listFilesV2: Volume list dir fail, Volume(%v) err(%v)", v.name, err)
 		return
 	}
 
 	// Supplementary file information, such as file modification time, MIME type, Etag information, etc.
-	err = v.supplyListFileInfo(infos)
+	err = v.supplyListFileInfo(infos, iget)
 	if err != nil {
 		log.LogDebugf("listFilesV2: supply list file info fail, err(%v)", err)
 		return
@@ -1651,7 +1762,7 @@ func (v *Volume) findParentId(prefix string) (inode uint64, prefixDirs []string,
 // Recursive scan of the directory starting from the given parentID. Match files and directories
 // that match the prefix and delimiter criteria. Stop when the number of matches reaches a threshold
 // or all files and directories are scanned.
-func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, parentId, maxKeys, rc uint64,
+func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, iget *meta.BatchIgetPipeline, parentId, maxKeys, rc uint64,
 	dirs []string, prefix, marker, delimiter string) ([]*FSFileInfo, PrefixMap, string, uint64, error) {
 	var err error
 	var nextMarker string
@@ -1681,6 +1792,7 @@ func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, par
 				return fileInfos, prefixMap, currentPath, rc, nil
 			}
 			fileInfos = append(fileInfos, fileInfo)
+			iget.Add(fileInfo.Inode)
 			rc++
 		}
 	}
@@ -1689,89 +1801,115 @@ func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, par
 	// parallel operations that may delete the current directory.
 	// If got the syscall.ENOENT error when invoke readdir, it means that the above situation has occurred.
 	// At this time, stops process and returns success.
-	var children []proto.Dentry
-	children, err = v.mw.ReadDir_ll(parentId)
-	if err != nil && err != syscall.ENOENT {
-		return fileInfos, prefixMap, "", 0, err
+	//
+	// The children are read page by page, so that the scanning of a large directory stops once
+	// the results reach maxKeys. The children whose names are less than the prefix are skipped.
+	var dirPath, from string
+	if len(dirs) > 0 {
+		dirPath = currentPath
 	}
-	if err == syscall.ENOENT {
-		return fileInfos, prefixMap, "", 0, nil
+	if strings.HasPrefix(prefix, dirPath) {
+		from = prefix[len(dirPath):]
+		if idx := strings.Index(from, pathSep); idx >= 0 {
+			from = from[:idx]
+		}
 	}
-
-	for _, child := range children {
-		var path = strings.Join(append(dirs, child.Name), pathSep)
-		if os.FileMode(child.Type).IsDir() {
-			path += pathSep
+	for {
+		var children []proto.Dentry
+		var next string
+		children, next, err = v.mw.ReadDirLimit_ll(parentId, from, MaxKeys)
+		if err != nil && err != syscall.ENOENT {
+			return fileInfos, prefixMap, "", 0, err
 		}
-		if prefix != "" && !strings.HasPrefix(path, prefix) {
-			continue
+		if err == syscall.ENOENT {
+			return fileInfos, prefixMap, "", 0, nil
 		}
 
-		if marker != "" {
-			if !os.FileMode(child.Type).IsDir() && path < marker {
+		for _, child := range children {
+			// the soft-deleted files in the trash are not objects of the bucket
+			if parentId == rootIno && child.Name == proto.TrashDirName {
 				continue
 			}
-			if os.FileMode(child.Type).IsDir() && path < marker {
-				fileInfos, prefixMap, nextMarker, rc, err = v.recursiveScan(fileInfos, prefixMap, child.Inode, maxKeys, rc, append(dirs, child.Name), prefix, marker, delimiter)
-				if err != nil {
-					return fileInfos, prefixMap, nextMarker, rc, err
-				}
-				if rc >= maxKeys && nextMarker != "" {
-					return fileInfos, prefixMap, nextMarker, rc, err
-				}
+			var path = strings.Join(append(dirs, child.Name), pathSep)
+			if os.FileMode(child.Type).IsDir() {
+				path += pathSep
+			}
+			if prefix != "" && !strings.HasPrefix(path, prefix) {
 				continue
 			}
-		}
 
-		if delimiter != "" {
-			var nonPrefixPart = strings.Replace(path, prefix, "", 1)
-			if idx := strings.Index(nonPrefixPart, delimiter); idx >= 0 {
-				var commonPrefix = prefix + util.SubString(nonPrefixPart, 0, idx) + delimiter
-				if prefixMap.contain(commonPrefix) {
+			if marker != "" {
+				if !os.FileMode(child.Type).IsDir() && path < marker {
 					continue
 				}
-				if rc >= maxKeys {
-					return fileInfos, prefixMap, commonPrefix, rc, nil
+				if os.FileMode(child.Type).IsDir() && path < marker {
+					fileInfos, prefixMap, nextMarker, rc, err = v.recursiveScan(fileInfos, prefixMap, iget, child.Inode, maxKeys, rc, append(dirs, child.Name), prefix, marker, delimiter)
+					if err != nil {
+						return fileInfos, prefixMap, nextMarker, rc, err
+					}
+					if rc >= maxKeys && nextMarker != "" {
+						return fileInfos, prefixMap, nextMarker, rc, err
+					}
+					continue
 				}
-				prefixMap.AddPrefix(commonPrefix)
-				rc++
-				continue
 			}
-		}
 
-		fileInfo := &FSFileInfo{
-			Inode: child.Inode,
-			Path:  path,
-		}
-		if rc >= maxKeys {
-			return fileInfos, prefixMap, path, rc, nil
-		}
-		fileInfos = append(fileInfos, fileInfo)
-		rc++
+			if delimiter != "" {
+				var nonPrefixPart = strings.Replace(path, prefix, "", 1)
+				if idx := strings.Index(nonPrefixPart, delimiter); idx >= 0 {
+					var commonPrefix = prefix + util.SubString(nonPrefixPart, 0, idx) + delimiter
+					if prefixMap.contain(commonPrefix) {
+						continue
+					}
+					if rc >= maxKeys {
+						return fileInfos, prefixMap, commonPrefix, rc, nil
+					}
+					prefixMap.AddPrefix(commonPrefix)
+					rc++
+					continue
+				}
+			}
 
-		if os.FileMode(child.Type).IsDir() {
-			fileInfos, prefixMap, nextMarker, rc, err = v.recursiveScan(fileInfos, prefixMap, child.Inode, maxKeys, rc, append(dirs, child.Name), prefix, marker, delimiter)
-			if err != nil {
-				return fileInfos, prefixMap, nextMarker, rc, err
+			fileInfo := &FSFileInfo{
+				Inode: child.Inode,
+				Path:  path,
+			}
+			if rc >= maxKeys {
+				return fileInfos, prefixMap, path, rc, nil
 			}
-			if rc >= maxKeys && nextMarker != "" {
-				return fileInfos, prefixMap, nextMarker, rc, err
+			fileInfos = append(fileInfos, fileInfo)
+			iget.Add(fileInfo.Inode)
+			rc++
+
+			if os.FileMode(child.Type).IsDir() {
+				fileInfos, prefixMap, nextMarker, rc, err = v.recursiveScan(fileInfos, prefixMap, iget, child.Inode, maxKeys, rc, append(dirs, child.Name), prefix, marker, delimiter)
+				if err != nil {
+					return fileInfos, prefixMap, nextMarker, rc, err
+				}
+				if rc >= maxKeys && nextMarker != "" {
+					return fileInfos, prefixMap, nextMarker, rc, err
+				}
 			}
 		}
+		if next == "" {
+			break
+		}
+		from = next
 	}
 	return fileInfos, prefixMap, nextMarker, rc, nil
 }
 
 // This method is used to supplement file metadata. Supplement the specified file
 // information with Size, ModifyTIme, Mode, Etag, and MIME type information.
-func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
+// The inodes of the file information are added to the pipeline by the scan.
+func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo, iget *meta.BatchIgetPipeline) (err error) {
 	var inodes []uint64
 	for _, fileInfo := range fileInfos {
 		inodes = append(inodes, fileInfo.Inode)
 	}
 
 	// Get size information in batches, then update to fileInfos
-	inodeInfos := v.mw.BatchInodeGet(inodes)
+	inodeInfos := iget.Wait()
 	sort.SliceStable(inodeInfos, func(i, j int) bool {
 		return inodeInfos[i].Inode < inodeInfos[j].Inode
 	})
@@ -1944,6 +2082,9 @@ func (v *Volume) ListParts(path, uploadId string, maxParts, partNumberMarker uin
 	return parts, nextMarker, isTruncated, nil
 }
 
+// CopyFile copies the object of the source volume to the target path of this volume. The data is read from the
+// source and written to a new inode in this volume by the object node, within a volume as well, since the
+// extents can not be shared by two inodes.
 func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective string, opt *PutFileOption) (info *FSFileInfo, err error) {
 	defer func() {
 		log.LogInfof("Audit: copy file: source path(%v) target path(%v) err(%v)",
@@ -2044,7 +2185,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		pathItems  []PathItem
 		tLastName  string
 	)
-	if _, _, _, tMode, err = v.recursiveLookupTarget(targetPath); err != nil && err != syscall.ENOENT {
+	if _, tInode, _, tMode, err = v.recursiveLookupTarget(targetPath); err != nil && err != syscall.ENOENT {
 		log.LogErrorf("CopyFile: look up target path failed, target path(%v), err(%v)", targetPath, err)
 		return
 	}
@@ -2097,6 +2238,11 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 	}
 	tLastName = pathItems[len(pathItems)-1].Name
 
+	// copying the object onto itself with its metadata kept changes nothing, unless a new version is created.
+	if sv.name == v.name && tInode == sInode && metaDirective != MetadataDirectiveReplace && !v.versioningConfig().IsConfigured() {
+		return v.ObjectMeta(targetPath)
+	}
+
 	// create target file inode and set target inode to be source file inode
 	if tInodeInfo, err = v.mw.InodeCreate_ll(uint32(sMode), 0, 0, nil); err != nil {
 		return
@@ -2215,7 +2361,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		// set tar xattr
 		if len(xattrs) > 0 {
 			for xk, xv := range xattrs[0].XAttrs {
-				if xk == XAttrKeyOSSETag {
+				if xk == XAttrKeyOSSETag || xk == XAttrKeyOSSVersionID {
 					continue
 				}
 				if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(xk), []byte(xv)); err != nil {
@@ -2279,7 +2425,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 	}
 
 	// apply new inode to dentry
-	err = v.applyInodeToDEntry(tParentId, tLastName, tInodeInfo.Inode)
+	info.VersionID, err = v.applyInodeToDEntry(targetPath, tParentId, tLastName, tInodeInfo.Inode)
 	if err != nil {
 		log.LogErrorf("CopyFile: apply inode to new dentry fail: path(%v) parentID(%v) name(%v) inode(%v) err(%v)",
 			targetPath, tParentId, tLastName, tInodeInfo.Inode, err)
@@ -2351,6 +2497,7 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		v.metaLoader = &cacheMetaLoader{om: new(OSSMeta)}
 		go v.syncOSSMeta()
 	}
+	go v.lifecycleWorker()
 
 	return v, nil
 }
@@ -20,9 +20,15 @@ type ossMetaLoader interface {
 	loadPolicy() (p *Policy, err error)
 	loadACL() (p *AccessControlPolicy, err error)
 	loadCors() (cors *CORSConfiguration, err error)
+	loadVersioning() (config *VersioningConfiguration, err error)
+	loadLifecycle() (config *LifecycleConfiguration, err error)
+	loadNotification() (config *NotificationConfiguration, err error)
 	storePolicy(p *Policy)
 	storeACL(p *AccessControlPolicy)
 	storeCors(cors *CORSConfiguration)
+	storeVersioning(config *VersioningConfiguration)
+	storeLifecycle(config *LifecycleConfiguration)
+	storeNotification(config *NotificationConfiguration)
 }
 
 type strictMetaLoader struct {
@@ -35,12 +41,18 @@ type cacheMetaLoader struct {
 
 // OSSMeta is bucket policy and ACL metadata.
 type OSSMeta struct {
-	policy     *Policy
-	acl        *AccessControlPolicy
-	corsConfig *CORSConfiguration
-	policyLock sync.RWMutex
-	aclLock    sync.RWMutex
-	corsLock   sync.RWMutex
+	policy           *Policy
+	acl              *AccessControlPolicy
+	corsConfig       *CORSConfiguration
+	versioning       *VersioningConfiguration
+	lifecycle        *LifecycleConfiguration
+	notification     *NotificationConfiguration
+	policyLock       sync.RWMutex
+	aclLock          sync.RWMutex
+	corsLock         sync.RWMutex
+	versioningLock   sync.RWMutex
+	lifecycleLock    sync.RWMutex
+	notificationLock sync.RWMutex
 }
 
 func (c *cacheMetaLoader) loadPolicy() (p *Policy, err error) {
@@ -85,6 +97,48 @@ func (c *cacheMetaLoader) storeCors(cors *CORSConfiguration) {
 	return
 }
 
+func (c *cacheMetaLoader) loadVersioning() (config *VersioningConfiguration, err error) {
+	c.om.versioningLock.RLock()
+	config = c.om.versioning
+	c.om.versioningLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeVersioning(config *VersioningConfiguration) {
+	c.om.versioningLock.Lock()
+	c.om.versioning = config
+	c.om.versioningLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadLifecycle() (config *LifecycleConfiguration, err error) {
+	c.om.lifecycleLock.RLock()
+	config = c.om.lifecycle
+	c.om.lifecycleLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeLifecycle(config *LifecycleConfiguration) {
+	c.om.lifecycleLock.Lock()
+	c.om.lifecycle = config
+	c.om.lifecycleLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadNotification() (config *NotificationConfiguration, err error) {
+	c.om.notificationLock.RLock()
+	config = c.om.notification
+	c.om.notificationLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeNotification(config *NotificationConfiguration) {
+	c.om.notificationLock.Lock()
+	c.om.notification = config
+	c.om.notificationLock.Unlock()
+	return
+}
+
 func (s *strictMetaLoader) loadPolicy() (p *Policy, err error) {
 	return s.v.loadBucketPolicy()
 }
@@ -102,3 +156,21 @@ func (s *strictMetaLoader) loadCors() (cors *CORSConfiguration, err error) {
 }
 
 func (s *strictMetaLoader) storeCors(cors *CORSConfiguration) {}
+
+func (s *strictMetaLoader) loadVersioning() (config *VersioningConfiguration, err error) {
+	return s.v.loadBucketVersioning()
+}
+
+func (s *strictMetaLoader) storeVersioning(config *VersioningConfiguration) {}
+
+func (s *strictMetaLoader) loadLifecycle() (config *LifecycleConfiguration, err error) {
+	return s.v.loadBucketLifecycle()
+}
+
+func (s *strictMetaLoader) storeLifecycle(config *LifecycleConfiguration) {}
+
+func (s *strictMetaLoader) loadNotification() (config *NotificationConfiguration, err error) {
+	return s.v.loadBucketNotification()
+}
+
+func (s *strictMetaLoader) storeNotification(config *NotificationConfiguration) {}
@@ -0,0 +1,687 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/hex"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The noncurrent versions of the objects are kept as dentries in the archive directory of the bucket,
+// which is an inode without any dentry referring to it, so the versions are invisible to the file
+// system interface and the object listing. The archive directory contains a subdirectory for each
+// object key which has noncurrent versions, and the dentries in the subdirectory are named with the
+// version IDs. The current version of an object is the one referred by the dentry of the object path,
+// its version ID is recorded in the extended attributes of the inode.
+//
+//	archive
+//	 ├── <hex encoded key>
+//	 │    ├── <version ID>  -> inode of the noncurrent version
+//	 │    └── <version ID>  -> inode of the delete marker
+//	 └── ...
+//
+// A delete marker is an empty inode with the delete marker extended attribute. It is always kept as
+// a noncurrent version, the object is taken as deleted if there is no current version.
+
+type FSVersionInfo struct {
+	Path         string
+	VersionID    string
+	IsLatest     bool
+	DeleteMarker bool
+	Size         int64
+	ModifyTime   time.Time
+	ETag         string
+	Inode        uint64
+}
+
+type ListObjectVersionsOption struct {
+	Prefix          string
+	Delimiter       string
+	KeyMarker       string
+	VersionIDMarker string
+	MaxKeys         uint64
+}
+
+type ListObjectVersionsResult struct {
+	Versions            []*FSVersionInfo
+	CommonPrefixes      []string
+	NextKeyMarker       string
+	NextVersionIDMarker string
+	Truncated           bool
+}
+
+type DeleteObjectResult struct {
+	VersionID    string
+	DeleteMarker bool
+}
+
+func encodeArchiveKey(path string) string {
+	return hex.EncodeToString([]byte(path))
+}
+
+func decodeArchiveKey(name string) (path string, err error) {
+	var raw []byte
+	if raw, err = hex.DecodeString(name); err != nil {
+		return
+	}
+	return string(raw), nil
+}
+
+func (v *Volume) versioningConfig() *VersioningConfiguration {
+	config, _ := v.metaLoader.loadVersioning()
+	return config
+}
+
+// PutBucketVersioning changes the versioning status of the bucket, the archive directory is
+// created when the versioning is configured for the first time.
+func (v *Volume) PutBucketVersioning(status string) (config *VersioningConfiguration, err error) {
+	defer func() {
+		log.LogInfof("Audit: PutBucketVersioning: volume(%v) status(%v) err(%v)", v.name, status, err)
+	}()
+	var current *VersioningConfiguration
+	if current, err = v.loadBucketVersioning(); err != nil {
+		return
+	}
+	config = &VersioningConfiguration{Status: status}
+	if current != nil {
+		config.ArchiveInode = current.ArchiveInode
+	}
+	if config.ArchiveInode == 0 {
+		var info *proto.InodeInfo
+		if info, err = v.mw.InodeCreate_ll(uint32(DefaultDirMode), 0, 0, nil); err != nil {
+			log.LogErrorf("PutBucketVersioning: create archive directory fail: volume(%v) err(%v)", v.name, err)
+			return
+		}
+		config.ArchiveInode = info.Inode
+	}
+	if err = storeBucketVersioning(v, config); err != nil {
+		return
+	}
+	v.metaLoader.storeVersioning(config)
+	return
+}
+
+// lookupVersionDir returns the archive subdirectory keeping the noncurrent versions of the object.
+func (v *Volume) lookupVersionDir(config *VersioningConfiguration, path string, create bool) (ino uint64, err error) {
+	var name = encodeArchiveKey(path)
+	if ino, _, err = v.mw.Lookup_ll(config.ArchiveInode, name); err != syscall.ENOENT || !create {
+		return
+	}
+	var info *proto.InodeInfo
+	if info, err = v.mw.Create_ll(config.ArchiveInode, name, uint32(DefaultDirMode), 0, 0, nil); err == syscall.EEXIST {
+		ino, _, err = v.mw.Lookup_ll(config.ArchiveInode, name)
+		return
+	}
+	if err != nil {
+		log.LogErrorf("lookupVersionDir: create version directory fail: volume(%v) path(%v) err(%v)", v.name, path, err)
+		return
+	}
+	return info.Inode, nil
+}
+
+func (v *Volume) inodeVersionID(inode uint64) (versionID string, err error) {
+	var info *proto.XAttrInfo
+	if info, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSVersionID); err != nil {
+		return
+	}
+	if versionID = string(info.Get(XAttrKeyOSSVersionID)); versionID == "" {
+		versionID = NullVersionID
+	}
+	return
+}
+
+func (v *Volume) isDeleteMarker(inode uint64) (marker bool, err error) {
+	var info *proto.XAttrInfo
+	if info, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSDeleteMarker); err != nil {
+		return
+	}
+	return len(info.Get(XAttrKeyOSSDeleteMarker)) > 0, nil
+}
+
+// nextVersionID returns the version ID of the new version of the object. The new version is a null
+// version if the versioning is suspended, which replaces the noncurrent null version of the object.
+func (v *Volume) nextVersionID(config *VersioningConfiguration, path string) (versionID string, err error) {
+	if config.IsEnabled() {
+		return newVersionID(), nil
+	}
+	if err = v.purgeArchivedVersion(config, path, NullVersionID); err == syscall.ENOENT {
+		err = nil
+	}
+	return NullVersionID, err
+}
+
+// prepareObjectVersion assigns the version ID to the inode which is going to be the current version of the object.
+func (v *Volume) prepareObjectVersion(config *VersioningConfiguration, path string, inode uint64) (versionID string, err error) {
+	if versionID, err = v.nextVersionID(config, path); err != nil || versionID == NullVersionID {
+		return
+	}
+	if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSVersionID), []byte(versionID)); err != nil {
+		log.LogErrorf("prepareObjectVersion: store version ID fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, inode, err)
+	}
+	return
+}
+
+// archiveVersion keeps the inode as a noncurrent version of the object. The inode is linked to the
+// archive, so the dentry of the object path can be removed or updated without releasing the data.
+// It returns false if the inode is a null version replaced while the versioning is suspended.
+func (v *Volume) archiveVersion(config *VersioningConfiguration, path string, inode uint64) (archived bool, err error) {
+	var versionID string
+	if versionID, err = v.inodeVersionID(inode); err != nil {
+		return
+	}
+	if versionID == NullVersionID && config.IsSuspended() {
+		return false, nil
+	}
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, true); err != nil {
+		return
+	}
+	if _, err = v.mw.InodeLink_ll(inode); err != nil {
+		log.LogErrorf("archiveVersion: link inode fail: volume(%v) path(%v) inode(%v) err(%v)", v.name, path, inode, err)
+		return
+	}
+	if err = v.mw.DentryCreate_ll(dir, versionID, inode, DefaultFileMode); err != nil {
+		log.LogErrorf("archiveVersion: create version dentry fail: volume(%v) path(%v) version(%v) inode(%v) err(%v)",
+			v.name, path, versionID, inode, err)
+		_, _ = v.mw.InodeUnlink_ll(inode)
+		return
+	}
+	log.LogDebugf("archiveVersion: volume(%v) path(%v) version(%v) inode(%v)", v.name, path, versionID, inode)
+	return true, nil
+}
+
+// purgeArchivedVersion permanently deletes a noncurrent version of the object.
+func (v *Volume) purgeArchivedVersion(config *VersioningConfiguration, path, versionID string) (err error) {
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, false); err != nil {
+		return
+	}
+	var info *proto.InodeInfo
+	if info, err = v.mw.Delete_ll(dir, versionID, false); err != nil {
+		return
+	}
+	log.LogWarnf("purgeArchivedVersion: volume(%v) path(%v) version(%v) inode(%v)", v.name, path, versionID, info.Inode)
+	if err = v.ec.EvictStream(info.Inode); err != nil {
+		log.LogWarnf("purgeArchivedVersion: evict stream fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, info.Inode, err)
+	}
+	if err = v.mw.Evict(info.Inode); err != nil {
+		log.LogWarnf("purgeArchivedVersion: evict inode fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, info.Inode, err)
+	}
+	return nil
+}
+
+// archivedVersions returns the noncurrent versions of the object from the newest to the oldest.
+func (v *Volume) archivedVersions(config *VersioningConfiguration, path string) (versions []*FSVersionInfo, err error) {
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, false); err == syscall.ENOENT {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	var dentries []proto.Dentry
+	if dentries, err = v.mw.ReadDir_ll(dir); err == syscall.ENOENT {
+		return nil, nil
+	}
+	if err != nil || len(dentries) == 0 {
+		return
+	}
+	var inodes = make([]uint64, 0, len(dentries))
+	for _, dentry := range dentries {
+		inodes = append(inodes, dentry.Inode)
+	}
+	var infos = make(map[uint64]*proto.InodeInfo)
+	for _, info := range v.mw.BatchInodeGet(inodes) {
+		infos[info.Inode] = info
+	}
+	var xattrs = make(map[uint64]*proto.XAttrInfo)
+	var batchXAttrs []*proto.XAttrInfo
+	if batchXAttrs, err = v.mw.BatchGetXAttr(inodes, []string{XAttrKeyOSSETag, XAttrKeyOSSDeleteMarker}); err != nil {
+		return
+	}
+	for _, xattr := range batchXAttrs {
+		xattrs[xattr.Inode] = xattr
+	}
+	for _, dentry := range dentries {
+		var info, ok = infos[dentry.Inode]
+		if !ok {
+			log.LogWarnf("archivedVersions: inode of version not found: volume(%v) path(%v) version(%v) inode(%v)",
+				v.name, path, dentry.Name, dentry.Inode)
+			continue
+		}
+		var version = &FSVersionInfo{
+			Path:       path,
+			VersionID:  dentry.Name,
+			Size:       int64(info.Size),
+			ModifyTime: info.ModifyTime,
+			Inode:      info.Inode,
+		}
+		if xattr, ok := xattrs[dentry.Inode]; ok {
+			version.DeleteMarker = len(xattr.Get(XAttrKeyOSSDeleteMarker)) > 0
+			if etagValue := ParseETagValue(string(xattr.Get(XAttrKeyOSSETag))); etagValue.Valid() {
+				version.ETag = etagValue.ETag()
+			}
+		}
+		versions = append(versions, version)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if !versions[i].ModifyTime.Equal(versions[j].ModifyTime) {
+			return versions[i].ModifyTime.After(versions[j].ModifyTime)
+		}
+		return versions[i].VersionID < versions[j].VersionID
+	})
+	return
+}
+
+// promoteLatestVersion makes the newest noncurrent version the current version of the object if the
+// current version has been deleted, unless the newest one is a delete marker.
+func (v *Volume) promoteLatestVersion(config *VersioningConfiguration, path string) (err error) {
+	if _, _, _, _, err = v.recursiveLookupTarget(path); err != syscall.ENOENT {
+		return
+	}
+	var versions []*FSVersionInfo
+	if versions, err = v.archivedVersions(config, path); err != nil {
+		return
+	}
+	if len(versions) == 0 {
+		// Remove the empty version directory, it fails harmlessly if a version is archived concurrently.
+		_, _ = v.mw.Delete_ll(config.ArchiveInode, encodeArchiveKey(path), true)
+		return nil
+	}
+	var latest = versions[0]
+	if latest.DeleteMarker {
+		return nil
+	}
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, false); err != nil {
+		return
+	}
+	var parentID uint64
+	if parentID, err = v.recursiveMakeDirectory(path); err != nil {
+		return
+	}
+	var pathItems = NewPathIterator(path).ToSlice()
+	var name = pathItems[len(pathItems)-1].Name
+	if _, err = v.mw.InodeLink_ll(latest.Inode); err != nil {
+		return
+	}
+	if err = v.mw.DentryCreate_ll(parentID, name, latest.Inode, DefaultFileMode); err != nil {
+		log.LogErrorf("promoteLatestVersion: create dentry fail: volume(%v) path(%v) version(%v) inode(%v) err(%v)",
+			v.name, path, latest.VersionID, latest.Inode, err)
+		_, _ = v.mw.InodeUnlink_ll(latest.Inode)
+		return
+	}
+	if _, err = v.mw.Delete_ll(dir, latest.VersionID, false); err != nil {
+		log.LogErrorf("promoteLatestVersion: delete version dentry fail: volume(%v) path(%v) version(%v) err(%v)",
+			v.name, path, latest.VersionID, err)
+		return
+	}
+	log.LogInfof("promoteLatestVersion: volume(%v) path(%v) version(%v) inode(%v)",
+		v.name, path, latest.VersionID, latest.Inode)
+	return
+}
+
+// lookupObjectVersion returns the inode of the specified version of the object.
+func (v *Volume) lookupObjectVersion(path, versionID string) (inode uint64, mode os.FileMode, err error) {
+	var config = v.versioningConfig()
+	if _, inode, _, mode, err = v.recursiveLookupTarget(path); err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil {
+		var current = NullVersionID
+		if !mode.IsDir() && config.IsConfigured() {
+			if current, err = v.inodeVersionID(inode); err != nil {
+				return
+			}
+		}
+		if current == versionID {
+			return
+		}
+	}
+	if !config.IsConfigured() {
+		return 0, 0, syscall.ENOENT
+	}
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, false); err != nil {
+		return
+	}
+	var rawMode uint32
+	if inode, rawMode, err = v.mw.Lookup_ll(dir, versionID); err != nil {
+		return
+	}
+	return inode, os.FileMode(rawMode), nil
+}
+
+// ObjectVersionMeta returns the meta of the specified version of the object. Only the path,
+// version ID and the delete marker flag are returned for a delete marker.
+func (v *Volume) ObjectVersionMeta(path, versionID string) (info *FSFileInfo, err error) {
+	var inode uint64
+	var mode os.FileMode
+	if inode, mode, err = v.lookupObjectVersion(path, versionID); err != nil {
+		return
+	}
+	var marker bool
+	if marker, err = v.isDeleteMarker(inode); err != nil {
+		return
+	}
+	if marker {
+		return &FSFileInfo{Path: path, VersionID: versionID, DeleteMarker: true}, nil
+	}
+	var inoInfo *proto.InodeInfo
+	if inoInfo, err = v.mw.InodeGet_ll(inode); err != nil {
+		return
+	}
+	if info, err = v.inodeObjectMeta(path, mode, inoInfo); err != nil {
+		return
+	}
+	info.VersionID = versionID
+	return
+}
+
+// DeleteObject deletes the object from the bucket. If the bucket is versioned, a delete marker is
+// created instead unless a version is specified, then the version is deleted permanently.
+func (v *Volume) DeleteObject(path, versionID string) (result *DeleteObjectResult, err error) {
+	var config = v.versioningConfig()
+	if !config.IsConfigured() {
+		result = &DeleteObjectResult{}
+		if versionID != "" && versionID != NullVersionID {
+			return
+		}
+		err = v.DeletePath(path)
+		return
+	}
+	defer func() {
+		log.LogInfof("Audit: DeleteObject: volume(%v) path(%v) version(%v) err(%v)", v.name, path, versionID, err)
+	}()
+	if versionID == "" {
+		return v.createDeleteMarker(config, path)
+	}
+	return v.deleteObjectVersion(config, path, versionID)
+}
+
+func (v *Volume) createDeleteMarker(config *VersioningConfiguration, path string) (result *DeleteObjectResult, err error) {
+	var parent, ino uint64
+	var name string
+	var mode os.FileMode
+	parent, ino, name, mode, err = v.recursiveLookupTarget(path)
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil && mode.IsDir() {
+		// Directories are not versioned.
+		return &DeleteObjectResult{}, v.DeletePath(path)
+	}
+	if err == nil {
+		var archived bool
+		if archived, err = v.archiveVersion(config, path, ino); err != nil {
+			return
+		}
+		if !archived {
+			if err = v.DeletePath(path); err != nil {
+				return
+			}
+		} else if _, err = v.mw.Delete_ll(parent, name, false); err != nil && err != syscall.ENOENT {
+			log.LogErrorf("createDeleteMarker: delete current version fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, ino, err)
+			return
+		}
+	}
+
+	var versionID string
+	if versionID, err = v.nextVersionID(config, path); err != nil {
+		return
+	}
+	var marker *proto.InodeInfo
+	if marker, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_, _ = v.mw.InodeUnlink_ll(marker.Inode)
+			_ = v.mw.Evict(marker.Inode)
+		}
+	}()
+	if err = v.mw.XAttrSet_ll(marker.Inode, []byte(XAttrKeyOSSDeleteMarker), []byte("true")); err != nil {
+		return
+	}
+	if versionID != NullVersionID {
+		if err = v.mw.XAttrSet_ll(marker.Inode, []byte(XAttrKeyOSSVersionID), []byte(versionID)); err != nil {
+			return
+		}
+	}
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, true); err != nil {
+		return
+	}
+	if err = v.mw.DentryCreate_ll(dir, versionID, marker.Inode, DefaultFileMode); err != nil {
+		log.LogErrorf("createDeleteMarker: create delete marker fail: volume(%v) path(%v) version(%v) err(%v)",
+			v.name, path, versionID, err)
+		return
+	}
+	return &DeleteObjectResult{VersionID: versionID, DeleteMarker: true}, nil
+}
+
+func (v *Volume) deleteObjectVersion(config *VersioningConfiguration, path, versionID string) (result *DeleteObjectResult, err error) {
+	result = &DeleteObjectResult{VersionID: versionID}
+	var ino uint64
+	var mode os.FileMode
+	if _, ino, _, mode, err = v.recursiveLookupTarget(path); err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil && !mode.IsDir() {
+		var current string
+		if current, err = v.inodeVersionID(ino); err != nil {
+			return
+		}
+		if current == versionID {
+			if err = v.DeletePath(path); err != nil {
+				return
+			}
+			err = v.promoteLatestVersion(config, path)
+			return
+		}
+	}
+	var dir uint64
+	if dir, err = v.lookupVersionDir(config, path, false); err == syscall.ENOENT {
+		return result, nil
+	}
+	if err != nil {
+		return
+	}
+	if ino, _, err = v.mw.Lookup_ll(dir, versionID); err == syscall.ENOENT {
+		return result, nil
+	}
+	if err != nil {
+		return
+	}
+	if result.DeleteMarker, err = v.isDeleteMarker(ino); err != nil {
+		return
+	}
+	if err = v.purgeArchivedVersion(config, path, versionID); err != nil && err != syscall.ENOENT {
+		return
+	}
+	err = v.promoteLatestVersion(config, path)
+	return
+}
+
+// ListObjectVersions lists the versions of the objects, the current version of an object is followed
+// by its noncurrent versions from the newest to the oldest.
+func (v *Volume) ListObjectVersions(opt *ListObjectVersionsOption) (result *ListObjectVersionsResult, err error) {
+	var config = v.versioningConfig()
+	result = &ListObjectVersionsResult{}
+
+	// Keys which are collapsed into a common prefix, or have been listed by the previous page.
+	var skipKey = func(key string) bool {
+		if key < opt.KeyMarker || (key == opt.KeyMarker && opt.VersionIDMarker == "") {
+			return true
+		}
+		return opt.Delimiter != "" && strings.HasSuffix(opt.KeyMarker, opt.Delimiter) &&
+			key != opt.KeyMarker && strings.HasPrefix(key, opt.KeyMarker)
+	}
+	var commonPrefix = func(key string) string {
+		if opt.Delimiter == "" {
+			return ""
+		}
+		var nonPrefixPart = strings.TrimPrefix(key, opt.Prefix)
+		if idx := strings.Index(nonPrefixPart, opt.Delimiter); idx >= 0 {
+			return opt.Prefix + util.SubString(nonPrefixPart, 0, idx) + opt.Delimiter
+		}
+		return ""
+	}
+
+	type versionListItem struct {
+		current  *FSFileInfo
+		archived bool
+		prefix   bool
+	}
+	var items = make(map[string]*versionListItem)
+
+	// The current versions are listed like the objects, the keys after the last listed one are unknown
+	// if the listing is truncated.
+	var files []*FSFileInfo
+	var prefixes Prefixes
+	var upperKey string
+	if files, prefixes, upperKey, err = v.listFilesV1(opt.Prefix, opt.KeyMarker, opt.Delimiter, opt.MaxKeys+1); err != nil {
+		return
+	}
+	for _, file := range files {
+		if file.Mode != 0 && !skipKey(file.Path) {
+			items[file.Path] = &versionListItem{current: file}
+		}
+	}
+	for _, prefix := range prefixes {
+		if !skipKey(prefix) {
+			items[prefix] = &versionListItem{prefix: true}
+		}
+	}
+	if config.IsConfigured() {
+		var dentries []proto.Dentry
+		if dentries, err = v.mw.ReadDir_ll(config.ArchiveInode); err != nil {
+			return
+		}
+		for _, dentry := range dentries {
+			var key, decodeErr = decodeArchiveKey(dentry.Name)
+			if decodeErr != nil {
+				log.LogWarnf("ListObjectVersions: invalid version directory: volume(%v) name(%v)", v.name, dentry.Name)
+				continue
+			}
+			if !strings.HasPrefix(key, opt.Prefix) {
+				continue
+			}
+			var isPrefix bool
+			if prefix := commonPrefix(key); prefix != "" {
+				key, isPrefix = prefix, true
+			}
+			if skipKey(key) || (upperKey != "" && key >= upperKey) {
+				continue
+			}
+			var item, ok = items[key]
+			if !ok {
+				item = &versionListItem{prefix: isPrefix}
+				items[key] = item
+			}
+			item.archived = !item.prefix
+		}
+	}
+
+	var keys = make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var count uint64
+	var emit = func(key, versionID string) bool {
+		if count >= opt.MaxKeys {
+			result.Truncated = true
+			return false
+		}
+		count++
+		result.NextKeyMarker, result.NextVersionIDMarker = key, versionID
+		return true
+	}
+	for _, key := range keys {
+		var item = items[key]
+		if item.prefix {
+			if !emit(key, "") {
+				break
+			}
+			result.CommonPrefixes = append(result.CommonPrefixes, key)
+			continue
+		}
+		var versions []*FSVersionInfo
+		if item.current != nil {
+			var current = &FSVersionInfo{
+				Path:       key,
+				VersionID:  NullVersionID,
+				Size:       item.current.Size,
+				ModifyTime: item.current.ModifyTime,
+				ETag:       item.current.ETag,
+				Inode:      item.current.Inode,
+			}
+			if !item.current.Mode.IsDir() && config.IsConfigured() {
+				if current.VersionID, err = v.inodeVersionID(item.current.Inode); err != nil {
+					return
+				}
+			}
+			versions = append(versions, current)
+		}
+		if item.archived {
+			var archived []*FSVersionInfo
+			if archived, err = v.archivedVersions(config, key); err != nil {
+				return
+			}
+			versions = append(versions, archived...)
+		}
+		if len(versions) > 0 {
+			versions[0].IsLatest = true
+		}
+		if key == opt.KeyMarker {
+			for i, version := range versions {
+				if version.VersionID == opt.VersionIDMarker {
+					versions = versions[i+1:]
+					break
+				}
+			}
+		}
+		for _, version := range versions {
+			if !emit(key, version.VersionID) {
+				break
+			}
+			result.Versions = append(result.Versions, version)
+		}
+		if result.Truncated {
+			break
+		}
+	}
+	if !result.Truncated && upperKey != "" {
+		result.Truncated = true
+	}
+	if !result.Truncated {
+		result.NextKeyMarker, result.NextVersionIDMarker = "", ""
+	}
+	return
+}
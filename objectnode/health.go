@@ -0,0 +1,56 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/health"
+)
+
+// the result of checking the master is reused within the interval, so that the probes of many
+// object nodes do not load the master
+const healthMasterCheckInterval = 10 * time.Second
+
+type masterHealth struct {
+	sync.Mutex
+	checkTime time.Time
+	err       error
+}
+
+func (o *ObjectNode) registerHealthChecks() {
+	var mh = &masterHealth{}
+	health.RegisterReadiness("master", func() error {
+		return mh.check(o)
+	})
+}
+
+// check requires the master to be reachable, which authenticates the users and serves the views of
+// the volumes.
+func (mh *masterHealth) check(o *ObjectNode) error {
+	mh.Lock()
+	defer mh.Unlock()
+	if time.Since(mh.checkTime) < healthMasterCheckInterval {
+		return mh.err
+	}
+	mh.err = nil
+	if _, err := o.mc.AdminAPI().GetClusterInfo(); err != nil {
+		mh.err = fmt.Errorf("master is unreachable: %v", err)
+	}
+	mh.checkTime = time.Now()
+	return mh.err
+}
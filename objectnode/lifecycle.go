@@ -0,0 +1,329 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	LifecycleStatusEnabled  = "Enabled"
+	LifecycleStatusDisabled = "Disabled"
+
+	// LifecycleConfigLimitSize is the max size of the lifecycle configuration in request body.
+	LifecycleConfigLimitSize = 64 * 1024
+
+	lifecycleMaxRules    = 1000
+	lifecycleMaxIDLength = 255
+
+	// LifecycleScanInterval is the interval of scanning the bucket for the expired objects.
+	LifecycleScanInterval = time.Hour
+	lifecycleScanPageSize = 1000
+)
+
+var (
+	ErrInvalidLifecycleConfig = errors.New("invalid lifecycle configuration")
+)
+
+// LifecycleConfiguration is the lifecycle rules of a bucket. Only the expiration of the current
+// objects is supported, which is filtered by the key prefix and the object tags.
+type LifecycleConfiguration struct {
+	XMLName xml.Name         `xml:"LifecycleConfiguration" json:"-"`
+	Rules   []*LifecycleRule `xml:"Rule" json:"rules"`
+}
+
+type LifecycleRule struct {
+	ID         string               `xml:"ID,omitempty" json:"id"`
+	Status     string               `xml:"Status" json:"status"`
+	Prefix     string               `xml:"Prefix,omitempty" json:"prefix,omitempty"` // deprecated, replaced by filter
+	Filter     *LifecycleFilter     `xml:"Filter,omitempty" json:"filter,omitempty"`
+	Expiration *LifecycleExpiration `xml:"Expiration,omitempty" json:"expiration,omitempty"`
+	Transition *struct{}            `xml:"Transition,omitempty" json:"-"` // unsupported
+}
+
+type LifecycleFilter struct {
+	Prefix string        `xml:"Prefix,omitempty" json:"prefix,omitempty"`
+	Tag    *Tag          `xml:"Tag,omitempty" json:"tag,omitempty"`
+	And    *LifecycleAnd `xml:"And,omitempty" json:"and,omitempty"`
+}
+
+type LifecycleAnd struct {
+	Prefix string `xml:"Prefix,omitempty" json:"prefix,omitempty"`
+	Tags   []Tag  `xml:"Tag" json:"tags,omitempty"`
+}
+
+type LifecycleExpiration struct {
+	Days int    `xml:"Days,omitempty" json:"days,omitempty"`
+	Date string `xml:"Date,omitempty" json:"date,omitempty"`
+}
+
+func (c *LifecycleConfiguration) validate() error {
+	if len(c.Rules) == 0 || len(c.Rules) > lifecycleMaxRules {
+		return ErrInvalidLifecycleConfig
+	}
+	var ids = make(map[string]struct{})
+	for _, rule := range c.Rules {
+		if rule.ID != "" {
+			if _, exist := ids[rule.ID]; exist {
+				return ErrInvalidLifecycleConfig
+			}
+			ids[rule.ID] = struct{}{}
+		}
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rule *LifecycleRule) validate() error {
+	if len(rule.ID) > lifecycleMaxIDLength {
+		return ErrInvalidLifecycleConfig
+	}
+	if rule.Status != LifecycleStatusEnabled && rule.Status != LifecycleStatusDisabled {
+		return ErrInvalidLifecycleConfig
+	}
+	if rule.Transition != nil || rule.Expiration == nil {
+		return ErrInvalidLifecycleConfig
+	}
+	if filter := rule.Filter; filter != nil {
+		if rule.Prefix != "" {
+			return ErrInvalidLifecycleConfig
+		}
+		// The filter is either a prefix, a tag, or a conjunction of them.
+		if filter.Tag != nil && (filter.Prefix != "" || filter.And != nil) ||
+			filter.And != nil && filter.Prefix != "" {
+			return ErrInvalidLifecycleConfig
+		}
+		if filter.Tag != nil && !validLifecycleTag(filter.Tag) {
+			return ErrInvalidLifecycleConfig
+		}
+		if filter.And != nil {
+			var keys = make(map[string]struct{})
+			for i := range filter.And.Tags {
+				var tag = &filter.And.Tags[i]
+				if _, exist := keys[tag.Key]; exist || !validLifecycleTag(tag) {
+					return ErrInvalidLifecycleConfig
+				}
+				keys[tag.Key] = struct{}{}
+			}
+		}
+	}
+	var expiration = rule.Expiration
+	if (expiration.Days > 0) == (expiration.Date != "") || expiration.Days < 0 {
+		return ErrInvalidLifecycleConfig
+	}
+	if expiration.Date != "" {
+		// The date must be at midnight UTC.
+		date, err := time.Parse(time.RFC3339, expiration.Date)
+		if err != nil || !date.Equal(date.UTC().Truncate(24*time.Hour)) {
+			return ErrInvalidLifecycleConfig
+		}
+	}
+	return nil
+}
+
+func validLifecycleTag(tag *Tag) bool {
+	return tag.Key != "" && len(tag.Key) <= TaggingKeyMaxLength && len(tag.Value) <= TaggingValueMaxLength
+}
+
+func (rule *LifecycleRule) prefix() string {
+	if rule.Filter == nil {
+		return rule.Prefix
+	}
+	if rule.Filter.And != nil {
+		return rule.Filter.And.Prefix
+	}
+	return rule.Filter.Prefix
+}
+
+func (rule *LifecycleRule) tags() []Tag {
+	if rule.Filter == nil {
+		return nil
+	}
+	if rule.Filter.Tag != nil {
+		return []Tag{*rule.Filter.Tag}
+	}
+	if rule.Filter.And != nil {
+		return rule.Filter.And.Tags
+	}
+	return nil
+}
+
+// Match returns true if the object is selected by the filter of the rule,
+// the tags of the object are the ones stored in the tagging xattr.
+func (rule *LifecycleRule) Match(key string, tags url.Values) bool {
+	if !strings.HasPrefix(key, rule.prefix()) {
+		return false
+	}
+	for _, tag := range rule.tags() {
+		if values, exist := tags[tag.Key]; !exist || len(values) == 0 || values[0] != tag.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpirationTime returns the time the object modified at the given time expires. As S3 does,
+// the expiration by days is rounded up to the next midnight UTC.
+func (rule *LifecycleRule) ExpirationTime(modifyTime time.Time) time.Time {
+	if rule.Expiration.Date != "" {
+		date, _ := time.Parse(time.RFC3339, rule.Expiration.Date)
+		return date
+	}
+	var expiration = modifyTime.UTC().Add(time.Duration(rule.Expiration.Days) * 24 * time.Hour)
+	var midnight = expiration.Truncate(24 * time.Hour)
+	if midnight.Before(expiration) {
+		midnight = midnight.Add(24 * time.Hour)
+	}
+	return midnight
+}
+
+func (c *LifecycleConfiguration) enabledRules() (rules []*LifecycleRule, filterTags bool) {
+	if c == nil {
+		return
+	}
+	for _, rule := range c.Rules {
+		if rule.Status == LifecycleStatusEnabled {
+			rules = append(rules, rule)
+			filterTags = filterTags || len(rule.tags()) > 0
+		}
+	}
+	return
+}
+
+// Expired returns the first enabled rule the object is expired by at the given time.
+func (c *LifecycleConfiguration) Expired(key string, tags url.Values, modifyTime, now time.Time) *LifecycleRule {
+	rules, _ := c.enabledRules()
+	for _, rule := range rules {
+		if rule.Match(key, tags) && !now.Before(rule.ExpirationTime(modifyTime)) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func parseLifecycleConfig(data []byte) (config *LifecycleConfiguration, err error) {
+	config = &LifecycleConfiguration{}
+	if err = xml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if err = config.validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketLifecycle(vol *Volume, config *LifecycleConfiguration) (err error) {
+	var data []byte
+	if data, err = json.Marshal(config); err != nil {
+		return
+	}
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSLifecycle, data)
+}
+
+func deleteBucketLifecycle(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSLifecycle)
+}
+
+func (v *Volume) lifecycleConfig() *LifecycleConfiguration {
+	config, _ := v.metaLoader.loadLifecycle()
+	return config
+}
+
+// lifecycleWorker expires the objects of the bucket by the lifecycle rules periodically.
+// Every object node which has loaded the volume runs the worker, an object can only be
+// deleted once, so the expiration is not repeated.
+func (v *Volume) lifecycleWorker() {
+	t := time.NewTicker(LifecycleScanInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-v.closeCh:
+			return
+		case <-t.C:
+			if expired, err := v.expireObjects(time.Now()); err != nil {
+				log.LogWarnf("lifecycleWorker: volume(%v) expired(%v) err(%v)", v.name, expired, err)
+			} else if expired > 0 {
+				log.LogInfof("lifecycleWorker: volume(%v) expired(%v)", v.name, expired)
+			}
+		}
+	}
+}
+
+// expireObjects scans the bucket and deletes the objects expired at the given time.
+func (v *Volume) expireObjects(now time.Time) (expired int, err error) {
+	var config = v.lifecycleConfig()
+	rules, filterTags := config.enabledRules()
+	if len(rules) == 0 {
+		return
+	}
+	var opt = &ListFilesV1Option{MaxKeys: lifecycleScanPageSize}
+	for {
+		select {
+		case <-v.closeCh:
+			return
+		default:
+		}
+		var result *ListFilesV1Result
+		if result, err = v.ListFilesV1(opt); err != nil {
+			return
+		}
+		var files = make([]*FSFileInfo, 0, len(result.Files))
+		var inodes = make([]uint64, 0, len(result.Files))
+		for _, file := range result.Files {
+			if !file.Mode.IsDir() && !strings.HasSuffix(file.Path, pathSep) {
+				files = append(files, file)
+				inodes = append(inodes, file.Inode)
+			}
+		}
+		var tags = make(map[uint64]url.Values)
+		if filterTags && len(inodes) > 0 {
+			xattrs, err := v.mw.BatchGetXAttr(inodes, []string{XAttrKeyOSSTagging})
+			if err != nil {
+				return expired, err
+			}
+			for _, xattr := range xattrs {
+				if values, err := url.ParseQuery(string(xattr.Get(XAttrKeyOSSTagging))); err == nil {
+					tags[xattr.Inode] = values
+				}
+			}
+		}
+		for _, file := range files {
+			var rule = config.Expired(file.Path, tags[file.Inode], file.ModifyTime, now)
+			if rule == nil {
+				continue
+			}
+			if _, err = v.DeleteObject(file.Path, ""); err != nil {
+				log.LogErrorf("expireObjects: delete fail: volume(%v) path(%v) rule(%v) err(%v)",
+					v.name, file.Path, rule.ID, err)
+				return
+			}
+			log.LogInfof("Audit: expireObjects: volume(%v) path(%v) rule(%v)", v.name, file.Path, rule.ID)
+			expired++
+		}
+		if !result.Truncated {
+			return
+		}
+		opt.Marker = result.NextMarker
+	}
+}
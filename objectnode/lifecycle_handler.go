@@ -0,0 +1,156 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycleConfiguration.html
+func (o *ObjectNode) getBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketLifecycleHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var config *LifecycleConfiguration
+	if config, err = vol.metaLoader.loadLifecycle(); err != nil {
+		log.LogErrorf("getBucketLifecycleHandler: load lifecycle fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if config == nil {
+		ec = NoSuchLifecycleConfiguration
+		return
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(config); err != nil {
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycleConfiguration.html
+func (o *ObjectNode) putBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketLifecycleHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadAll(io.LimitReader(r.Body, LifecycleConfigLimitSize+1)); err != nil && err != io.EOF {
+		log.LogErrorf("putBucketLifecycleHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		return
+	}
+	if len(data) > LifecycleConfigLimitSize {
+		err = nil
+		ec = MaxContentLength
+		return
+	}
+	var config *LifecycleConfiguration
+	if config, err = parseLifecycleConfig(data); err != nil {
+		log.LogWarnf("putBucketLifecycleHandler: invalid lifecycle configuration: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		err = nil
+		ec = MalformedXML
+		return
+	}
+	if err = storeBucketLifecycle(vol, config); err != nil {
+		log.LogErrorf("putBucketLifecycleHandler: store lifecycle fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	vol.metaLoader.storeLifecycle(config)
+	log.LogInfof("Audit: PutBucketLifecycle: volume(%v) rules(%v)", param.Bucket(), len(config.Rules))
+	return
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketLifecycle.html
+func (o *ObjectNode) deleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("deleteBucketLifecycleHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	if err = deleteBucketLifecycle(vol); err != nil {
+		log.LogErrorf("deleteBucketLifecycleHandler: delete lifecycle fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	vol.metaLoader.storeLifecycle(nil)
+	log.LogInfof("Audit: DeleteBucketLifecycle: volume(%v)", param.Bucket())
+
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseLifecycleConfig(t *testing.T) {
+	var samples = []struct {
+		data  string
+		valid bool
+	}{
+		{data: `<LifecycleConfiguration><Rule><ID>r1</ID><Status>Enabled</Status><Filter><Prefix>logs/</Prefix></Filter><Expiration><Days>30</Days></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Prefix>logs/</Prefix><Expiration><Date>2020-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		{data: `<LifecycleConfiguration><Rule><Status>Disabled</Status><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><And><Prefix>a/</Prefix><Tag><Key>k1</Key><Value>v</Value></Tag><Tag><Key>k2</Key><Value>v</Value></Tag></And></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		// no rules
+		{data: `<LifecycleConfiguration></LifecycleConfiguration>`},
+		// invalid status
+		{data: `<LifecycleConfiguration><Rule><Status>On</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`},
+		// no expiration
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status></Rule></LifecycleConfiguration>`},
+		// both days and date
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days><Date>2020-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`},
+		// date not at midnight
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Date>2020-01-01T08:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`},
+		// duplicated rule ID
+		{data: `<LifecycleConfiguration><Rule><ID>r</ID><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule><Rule><ID>r</ID><Status>Enabled</Status><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`},
+		// prefix and tag in filter without and
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><Prefix>a/</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`},
+		// duplicated tag key
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><And><Tag><Key>k</Key><Value>1</Value></Tag><Tag><Key>k</Key><Value>2</Value></Tag></And></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`},
+		// transition is unsupported
+		{data: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>1</Days><StorageClass>GLACIER</StorageClass></Transition><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`},
+		{data: `<LifecycleConfiguration>`},
+	}
+	for i, sample := range samples {
+		_, err := parseLifecycleConfig([]byte(sample.data))
+		if sample.valid && err != nil {
+			t.Fatalf("sample(%v) expect valid but not: err(%v)", i, err)
+		}
+		if !sample.valid && err == nil {
+			t.Fatalf("sample(%v) expect error but not", i)
+		}
+	}
+}
+
+func TestLifecycleRuleMatch(t *testing.T) {
+	var rule = &LifecycleRule{
+		Status: LifecycleStatusEnabled,
+		Filter: &LifecycleFilter{And: &LifecycleAnd{
+			Prefix: "logs/",
+			Tags:   []Tag{{Key: "env", Value: "test"}, {Key: "team", Value: "a"}},
+		}},
+		Expiration: &LifecycleExpiration{Days: 1},
+	}
+	var samples = []struct {
+		key   string
+		tags  string
+		match bool
+	}{
+		{key: "logs/1", tags: "env=test&team=a", match: true},
+		{key: "logs/1", tags: "env=test&team=a&other=x", match: true},
+		{key: "logs/1", tags: "env=test"},
+		{key: "logs/1", tags: "env=prod&team=a"},
+		{key: "data/1", tags: "env=test&team=a"},
+		{key: "logs/1"},
+	}
+	for i, sample := range samples {
+		tags, _ := url.ParseQuery(sample.tags)
+		if match := rule.Match(sample.key, tags); match != sample.match {
+			t.Fatalf("sample(%v) match mismatch: expect(%v) actual(%v)", i, sample.match, match)
+		}
+	}
+}
+
+func TestLifecycleExpiration(t *testing.T) {
+	var config = &LifecycleConfiguration{Rules: []*LifecycleRule{
+		{ID: "disabled", Status: LifecycleStatusDisabled, Expiration: &LifecycleExpiration{Days: 1}},
+		{ID: "days", Status: LifecycleStatusEnabled, Prefix: "a/", Expiration: &LifecycleExpiration{Days: 2}},
+		{ID: "date", Status: LifecycleStatusEnabled, Prefix: "b/", Expiration: &LifecycleExpiration{Date: "2020-03-01T00:00:00Z"}},
+	}}
+	var modifyTime = time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+	// expiration by days is rounded up to the next midnight UTC
+	var expect = time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC)
+	if expiration := config.Rules[1].ExpirationTime(modifyTime); !expiration.Equal(expect) {
+		t.Fatalf("expiration time mismatch: expect(%v) actual(%v)", expect, expiration)
+	}
+	if rule := config.Expired("a/1", nil, modifyTime, expect.Add(-time.Second)); rule != nil {
+		t.Fatalf("expect not expired but expired by rule(%v)", rule.ID)
+	}
+	if rule := config.Expired("a/1", nil, modifyTime, expect); rule == nil || rule.ID != "days" {
+		t.Fatalf("expect expired by rule(days) but not")
+	}
+	if rule := config.Expired("b/1", nil, modifyTime, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)); rule == nil || rule.ID != "date" {
+		t.Fatalf("expect expired by rule(date) but not")
+	}
+	if rule := config.Expired("c/1", nil, modifyTime, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)); rule != nil {
+		t.Fatalf("expect not expired but expired by rule(%v)", rule.ID)
+	}
+}
+
+func TestTaggingValidate(t *testing.T) {
+	var tagging = NewTagging()
+	tagging.TagSet = []Tag{{Key: "k", Value: "1"}, {Key: "k", Value: "2"}}
+	if ok, _ := tagging.Validate(); ok {
+		t.Fatalf("expect duplicated tag keys invalid but not")
+	}
+	tagging.TagSet = []Tag{{Key: "", Value: "1"}}
+	if ok, _ := tagging.Validate(); ok {
+		t.Fatalf("expect empty tag key invalid but not")
+	}
+	tagging.TagSet = []Tag{{Key: "k1", Value: "1"}, {Key: "k2", Value: ""}}
+	if ok, _ := tagging.Validate(); !ok {
+		t.Fatalf("expect tagging valid but not")
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	EventObjectCreatedPut                     = "ObjectCreated:Put"
+	EventObjectCreatedCopy                    = "ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipartUpload = "ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemovedDelete                  = "ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated     = "ObjectRemoved:DeleteMarkerCreated"
+
+	// NotificationConfigLimitSize is the max size of the notification configuration in request body.
+	NotificationConfigLimitSize = 64 * 1024
+
+	notificationMaxRules     = 100
+	notificationEventPrefix  = "s3:"
+	notificationARNPartition = "cfs"
+	notificationEventVersion = "2.1"
+	notificationEventSource  = "cfs:s3"
+	notificationFilterPrefix = "prefix"
+	notificationFilterSuffix = "suffix"
+)
+
+var (
+	ErrInvalidNotificationConfig = errors.New("invalid notification configuration")
+
+	notificationEvents = map[string]struct{}{
+		EventObjectCreatedPut:                     {},
+		EventObjectCreatedCopy:                    {},
+		EventObjectCreatedCompleteMultipartUpload: {},
+		EventObjectRemovedDelete:                  {},
+		EventObjectRemovedDeleteMarkerCreated:     {},
+		"ObjectCreated:*":                         {},
+		"ObjectRemoved:*":                         {},
+	}
+)
+
+// NotificationConfiguration is the event notification rules of a bucket. The events are published
+// to the targets configured in the object nodes, which are referred by the ARNs in the form of
+// "arn:cfs:sqs:REGION:ID:TYPE" in the queue and topic rules.
+type NotificationConfiguration struct {
+	XMLName   xml.Name            `xml:"NotificationConfiguration" json:"-"`
+	Queues    []*NotificationRule `xml:"QueueConfiguration" json:"queues,omitempty"`
+	Topics    []*NotificationRule `xml:"TopicConfiguration" json:"topics,omitempty"`
+	Functions []*struct{}         `xml:"CloudFunctionConfiguration" json:"-"` // unsupported
+}
+
+type NotificationRule struct {
+	ID     string              `xml:"Id,omitempty" json:"id,omitempty"`
+	Queue  string              `xml:"Queue,omitempty" json:"queue,omitempty"`
+	Topic  string              `xml:"Topic,omitempty" json:"topic,omitempty"`
+	Events []string            `xml:"Event" json:"events"`
+	Filter *NotificationFilter `xml:"Filter,omitempty" json:"filter,omitempty"`
+}
+
+type NotificationFilter struct {
+	Key *NotificationKeyFilter `xml:"S3Key,omitempty" json:"key,omitempty"`
+}
+
+type NotificationKeyFilter struct {
+	Rules []*NotificationFilterRule `xml:"FilterRule" json:"rules"`
+}
+
+type NotificationFilterRule struct {
+	Name  string `xml:"Name" json:"name"`
+	Value string `xml:"Value" json:"value"`
+}
+
+func (c *NotificationConfiguration) rules() []*NotificationRule {
+	if c == nil {
+		return nil
+	}
+	return append(append([]*NotificationRule(nil), c.Queues...), c.Topics...)
+}
+
+func (rule *NotificationRule) arn() string {
+	if rule.Queue != "" {
+		return rule.Queue
+	}
+	return rule.Topic
+}
+
+// validate checks the rules, and the targets referred by the rules must be configured in the
+// object node.
+func (c *NotificationConfiguration) validate(n *notifier) error {
+	var rules = c.rules()
+	if len(c.Functions) > 0 || len(rules) > notificationMaxRules {
+		return ErrInvalidNotificationConfig
+	}
+	var ids = make(map[string]struct{})
+	for _, rule := range rules {
+		if rule.ID != "" {
+			if _, exist := ids[rule.ID]; exist {
+				return ErrInvalidNotificationConfig
+			}
+			ids[rule.ID] = struct{}{}
+		}
+		if err := rule.validate(); err != nil {
+			return err
+		}
+		if n.target(rule.arn()) == nil {
+			return fmt.Errorf("unknown notification target: %v", rule.arn())
+		}
+	}
+	return nil
+}
+
+func (rule *NotificationRule) validate() error {
+	if (rule.Queue == "") == (rule.Topic == "") || len(rule.Events) == 0 {
+		return ErrInvalidNotificationConfig
+	}
+	for _, event := range rule.Events {
+		if _, exist := notificationEvents[strings.TrimPrefix(event, notificationEventPrefix)]; !exist ||
+			!strings.HasPrefix(event, notificationEventPrefix) {
+			return ErrInvalidNotificationConfig
+		}
+	}
+	if rule.Filter == nil || rule.Filter.Key == nil {
+		return nil
+	}
+	var names = make(map[string]struct{})
+	for _, filter := range rule.Filter.Key.Rules {
+		var name = strings.ToLower(filter.Name)
+		if _, exist := names[name]; exist || name != notificationFilterPrefix && name != notificationFilterSuffix {
+			return ErrInvalidNotificationConfig
+		}
+		names[name] = struct{}{}
+	}
+	return nil
+}
+
+// Match returns true if the event of the object is selected by the rule.
+func (rule *NotificationRule) Match(eventName, key string) bool {
+	var matched bool
+	for _, event := range rule.Events {
+		var pattern = strings.TrimPrefix(event, notificationEventPrefix)
+		if pattern == eventName || strings.HasSuffix(pattern, ":*") && strings.HasPrefix(eventName, strings.TrimSuffix(pattern, "*")) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	if rule.Filter == nil || rule.Filter.Key == nil {
+		return true
+	}
+	for _, filter := range rule.Filter.Key.Rules {
+		switch strings.ToLower(filter.Name) {
+		case notificationFilterPrefix:
+			if !strings.HasPrefix(key, filter.Value) {
+				return false
+			}
+		case notificationFilterSuffix:
+			if !strings.HasSuffix(key, filter.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseNotificationARN parses the target ID and type from the ARN "arn:cfs:sqs:REGION:ID:TYPE",
+// the service may also be "sns" for the topic rules.
+func parseNotificationARN(arn string) (id, kind string, err error) {
+	var parts = strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[1] != notificationARNPartition ||
+		parts[2] != "sqs" && parts[2] != "sns" || parts[4] == "" || parts[5] == "" {
+		return "", "", fmt.Errorf("invalid notification target: %v", arn)
+	}
+	return parts[4], parts[5], nil
+}
+
+func parseNotificationConfig(data []byte) (config *NotificationConfiguration, err error) {
+	config = &NotificationConfiguration{}
+	if err = xml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketNotification(vol *Volume, config *NotificationConfiguration) (err error) {
+	var data []byte
+	if data, err = json.Marshal(config); err != nil {
+		return
+	}
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSNotification, data)
+}
+
+func deleteBucketNotification(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSNotification)
+}
+
+// EventRecord is the message of an event published to the targets, in the format of the S3
+// event notifications.
+type EventRecord struct {
+	EventVersion      string            `json:"eventVersion"`
+	EventSource       string            `json:"eventSource"`
+	AwsRegion         string            `json:"awsRegion"`
+	EventTime         string            `json:"eventTime"`
+	EventName         string            `json:"eventName"`
+	UserIdentity      EventIdentity     `json:"userIdentity"`
+	RequestParameters map[string]string `json:"requestParameters"`
+	ResponseElements  map[string]string `json:"responseElements"`
+	S3                EventS3           `json:"s3"`
+}
+
+type EventIdentity struct {
+	PrincipalID string `json:"principalId"`
+}
+
+type EventS3 struct {
+	SchemaVersion   string      `json:"s3SchemaVersion"`
+	ConfigurationID string      `json:"configurationId"`
+	Bucket          EventBucket `json:"bucket"`
+	Object          EventObject `json:"object"`
+}
+
+type EventBucket struct {
+	Name          string        `json:"name"`
+	OwnerIdentity EventIdentity `json:"ownerIdentity"`
+	ARN           string        `json:"arn"`
+}
+
+type EventObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+type eventMessage struct {
+	Records []*EventRecord `json:"Records"`
+}
+
+// deleteEventName returns the event name of the deletion, a delete marker is created by the deletion
+// in a versioned bucket without the version ID.
+func deleteEventName(result *DeleteObjectResult) string {
+	if result != nil && result.DeleteMarker {
+		return EventObjectRemovedDeleteMarkerCreated
+	}
+	return EventObjectRemovedDelete
+}
+
+// notifyEvent publishes the event of the object to the targets of the notification rules of the bucket
+// matched. The events are published asynchronously, the failure never fails the request.
+func (o *ObjectNode) notifyEvent(r *http.Request, vol *Volume, eventName string, object EventObject) {
+	if o.notifier == nil {
+		return
+	}
+	config, err := vol.metaLoader.loadNotification()
+	if err != nil || config == nil {
+		return
+	}
+	var now = time.Now().UTC()
+	for _, rule := range config.rules() {
+		if !rule.Match(eventName, object.Key) {
+			continue
+		}
+		var target = o.notifier.target(rule.arn())
+		if target == nil {
+			log.LogWarnf("notifyEvent: target not found: requestID(%v) volume(%v) rule(%v) target(%v)",
+				GetRequestID(r), vol.Name(), rule.ID, rule.arn())
+			continue
+		}
+		object.Sequencer = fmt.Sprintf("%016X", now.UnixNano())
+		var record = &EventRecord{
+			EventVersion:      notificationEventVersion,
+			EventSource:       notificationEventSource,
+			AwsRegion:         o.region,
+			EventTime:         now.Format(time.RFC3339Nano),
+			EventName:         eventName,
+			UserIdentity:      EventIdentity{PrincipalID: ParseRequestParam(r).AccessKey()},
+			RequestParameters: map[string]string{"sourceIPAddress": getRequestIP(r)},
+			ResponseElements:  map[string]string{"x-amz-request-id": GetRequestID(r)},
+			S3: EventS3{
+				SchemaVersion:   "1.0",
+				ConfigurationID: rule.ID,
+				Bucket: EventBucket{
+					Name:          vol.Name(),
+					OwnerIdentity: EventIdentity{PrincipalID: vol.Owner()},
+					ARN:           "arn:aws:s3:::" + vol.Name(),
+				},
+				Object: object,
+			},
+		}
+		var data []byte
+		if data, err = json.Marshal(&eventMessage{Records: []*EventRecord{record}}); err != nil {
+			continue
+		}
+		target.publish(object.Key, data)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+func (o *ObjectNode) getBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketNotificationHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var config *NotificationConfiguration
+	if config, err = vol.metaLoader.loadNotification(); err != nil {
+		log.LogErrorf("getBucketNotificationHandler: load notification fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if config == nil {
+		// An empty configuration is returned if the notification is not configured.
+		config = &NotificationConfiguration{}
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(config); err != nil {
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+func (o *ObjectNode) putBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadAll(io.LimitReader(r.Body, NotificationConfigLimitSize+1)); err != nil && err != io.EOF {
+		log.LogErrorf("putBucketNotificationHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		return
+	}
+	if len(data) > NotificationConfigLimitSize {
+		err = nil
+		ec = MaxContentLength
+		return
+	}
+	var config *NotificationConfiguration
+	if config, err = parseNotificationConfig(data); err != nil {
+		log.LogWarnf("putBucketNotificationHandler: invalid notification configuration: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		err = nil
+		ec = MalformedXML
+		return
+	}
+	if err = config.validate(o.notifier); err != nil {
+		log.LogWarnf("putBucketNotificationHandler: invalid notification configuration: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		err = nil
+		ec = InvalidArgument
+		return
+	}
+
+	// An empty configuration disables the notification of the bucket.
+	if len(config.rules()) == 0 {
+		if err = deleteBucketNotification(vol); err != nil {
+			log.LogErrorf("putBucketNotificationHandler: delete notification fail: requestID(%v) volume(%v) err(%v)",
+				GetRequestID(r), param.Bucket(), err)
+			return
+		}
+		vol.metaLoader.storeNotification(nil)
+		log.LogInfof("Audit: PutBucketNotification: volume(%v) disabled", param.Bucket())
+		return
+	}
+	if err = storeBucketNotification(vol, config); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: store notification fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	vol.metaLoader.storeNotification(config)
+	log.LogInfof("Audit: PutBucketNotification: volume(%v) rules(%v)", param.Bucket(), len(config.rules()))
+	return
+}
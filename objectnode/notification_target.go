@@ -0,0 +1,340 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	NotificationTargetWebhook = "webhook"
+	NotificationTargetKafka   = "kafka"
+	NotificationTargetNats    = "nats"
+
+	notificationQueueSize    = 10000
+	notificationRetries      = 3
+	notificationRetryBackoff = time.Second
+	notificationTimeout      = 10 * time.Second
+	kafkaRestContentType     = "application/vnd.kafka.json.v2+json"
+)
+
+// NotificationTargetConfig is the config of a target the events are published to. The kafka target
+// produces the events through the kafka rest proxy at the endpoint, and the topic is the subject of
+// the nats target.
+type NotificationTargetConfig struct {
+	ID       string
+	Type     string
+	Endpoint string
+	Topic    string
+}
+
+// publisher delivers the messages to a target, which is called by one goroutine.
+type publisher interface {
+	publish(key string, data []byte) error
+	close()
+}
+
+type notificationMessage struct {
+	key  string
+	data []byte
+}
+
+// notificationTarget queues the messages of a target and delivers them in order. The messages are
+// dropped if the target falls behind, so that the requests are never blocked by the target.
+type notificationTarget struct {
+	config    NotificationTargetConfig
+	publisher publisher
+	msgC      chan *notificationMessage
+	stopC     chan struct{}
+	wg        sync.WaitGroup
+}
+
+type notifier struct {
+	targets map[string]*notificationTarget // target ID -> target
+}
+
+func parseNotificationTargets(items []interface{}) (configs []NotificationTargetConfig, err error) {
+	for _, item := range items {
+		var fields, ok = item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid notification target: %v", item)
+		}
+		var config NotificationTargetConfig
+		config.ID, _ = fields["id"].(string)
+		config.Type, _ = fields["type"].(string)
+		config.Endpoint, _ = fields["endpoint"].(string)
+		config.Topic, _ = fields["topic"].(string)
+		if config.ID == "" || config.Endpoint == "" {
+			return nil, fmt.Errorf("invalid notification target: %v", item)
+		}
+		configs = append(configs, config)
+	}
+	return
+}
+
+func newNotifier(configs []NotificationTargetConfig) (n *notifier, err error) {
+	n = &notifier{targets: make(map[string]*notificationTarget)}
+	for _, config := range configs {
+		var p publisher
+		switch config.Type {
+		case NotificationTargetWebhook:
+			p = newWebhookPublisher(config.Endpoint)
+		case NotificationTargetKafka:
+			if config.Topic == "" {
+				return nil, fmt.Errorf("topic of kafka target(%v) not set", config.ID)
+			}
+			p = newKafkaPublisher(config.Endpoint, config.Topic)
+		case NotificationTargetNats:
+			if config.Topic == "" {
+				return nil, fmt.Errorf("topic of nats target(%v) not set", config.ID)
+			}
+			p = newNatsPublisher(config.Endpoint, config.Topic)
+		default:
+			return nil, fmt.Errorf("unknown type of notification target(%v): %v", config.ID, config.Type)
+		}
+		if _, exist := n.targets[config.ID]; exist {
+			return nil, fmt.Errorf("duplicate notification target: %v", config.ID)
+		}
+		n.targets[config.ID] = newNotificationTarget(config, p)
+	}
+	return
+}
+
+// target returns the target referred by the ARN, nil if it is not configured.
+func (n *notifier) target(arn string) *notificationTarget {
+	id, kind, err := parseNotificationARN(arn)
+	if err != nil || n == nil {
+		return nil
+	}
+	if target := n.targets[id]; target != nil && target.config.Type == kind {
+		return target
+	}
+	return nil
+}
+
+func (n *notifier) stop() {
+	for _, target := range n.targets {
+		target.stop()
+	}
+}
+
+func newNotificationTarget(config NotificationTargetConfig, p publisher) *notificationTarget {
+	t := &notificationTarget{
+		config:    config,
+		publisher: p,
+		msgC:      make(chan *notificationMessage, notificationQueueSize),
+		stopC:     make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go t.publishWorker()
+	return t
+}
+
+func (t *notificationTarget) publish(key string, data []byte) {
+	select {
+	case t.msgC <- &notificationMessage{key: key, data: data}:
+	default:
+		log.LogWarnf("notification: target(%v) falls behind, event dropped: key(%v)", t.config.ID, key)
+	}
+}
+
+func (t *notificationTarget) stop() {
+	close(t.stopC)
+	t.wg.Wait()
+	t.publisher.close()
+}
+
+func (t *notificationTarget) publishWorker() {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-t.stopC:
+			return
+		case msg := <-t.msgC:
+			var err error
+			for i := 0; i < notificationRetries; i++ {
+				if err = t.publisher.publish(msg.key, msg.data); err == nil {
+					break
+				}
+				select {
+				case <-t.stopC:
+					return
+				case <-time.After(notificationRetryBackoff):
+				}
+			}
+			if err != nil {
+				log.LogErrorf("notification: publish to target(%v) fail: key(%v) err(%v)", t.config.ID, msg.key, err)
+			}
+		}
+	}
+}
+
+// webhookPublisher posts the messages to the endpoint.
+type webhookPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newWebhookPublisher(endpoint string) *webhookPublisher {
+	return &webhookPublisher{endpoint: endpoint, client: &http.Client{Timeout: notificationTimeout}}
+}
+
+func (p *webhookPublisher) publish(key string, data []byte) error {
+	return postNotification(p.client, p.endpoint, "application/json", data)
+}
+
+func (p *webhookPublisher) close() {}
+
+// kafkaPublisher produces the messages keyed by the object keys through the kafka rest proxy, so
+// that no kafka client is required.
+type kafkaPublisher struct {
+	url    string
+	client *http.Client
+}
+
+type kafkaRestRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaRestRequest struct {
+	Records []kafkaRestRecord `json:"records"`
+}
+
+func newKafkaPublisher(endpoint, topic string) *kafkaPublisher {
+	return &kafkaPublisher{
+		url:    strings.TrimSuffix(endpoint, "/") + "/topics/" + topic,
+		client: &http.Client{Timeout: notificationTimeout},
+	}
+}
+
+func (p *kafkaPublisher) publish(key string, data []byte) error {
+	body, err := json.Marshal(&kafkaRestRequest{Records: []kafkaRestRecord{{Key: key, Value: data}}})
+	if err != nil {
+		return err
+	}
+	return postNotification(p.client, p.url, kafkaRestContentType, body)
+}
+
+func (p *kafkaPublisher) close() {}
+
+func postNotification(client *http.Client, url, contentType string, data []byte) error {
+	resp, err := client.Post(url, contentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status(%v) body(%v)", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// natsPublisher publishes the messages to the subject of a nats server with the text protocol.
+// The connection is established on demand and the pings of the server are answered in the background.
+type natsPublisher struct {
+	addr    string
+	subject string
+	mu      sync.Mutex
+	conn    net.Conn
+}
+
+func newNatsPublisher(addr, subject string) *natsPublisher {
+	return &natsPublisher{addr: strings.TrimPrefix(addr, "nats://"), subject: subject}
+}
+
+func (p *natsPublisher) connect() (conn net.Conn, err error) {
+	if conn, err = net.DialTimeout("tcp", p.addr, notificationTimeout); err != nil {
+		return
+	}
+	var reader = bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(notificationTimeout))
+	var line string
+	if line, err = reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting of nats server(%v): %q err(%v)", p.addr, line, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	if _, err = conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false}\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go p.readLoop(conn, reader)
+	return
+}
+
+func (p *natsPublisher) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			p.mu.Lock()
+			if p.conn == conn {
+				p.conn = nil
+			}
+			p.mu.Unlock()
+			conn.Close()
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			p.mu.Lock()
+			_, _ = conn.Write([]byte("PONG\r\n"))
+			p.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.LogWarnf("notification: nats server(%v) error: %v", p.addr, strings.TrimSpace(line))
+		}
+	}
+}
+
+func (p *natsPublisher) publish(key string, data []byte) (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		if p.conn, err = p.connect(); err != nil {
+			return
+		}
+	}
+	var buf = new(bytes.Buffer)
+	fmt.Fprintf(buf, "PUB %v %v\r\n", p.subject, len(data))
+	buf.Write(data)
+	buf.WriteString("\r\n")
+	p.conn.SetWriteDeadline(time.Now().Add(notificationTimeout))
+	if _, err = p.conn.Write(buf.Bytes()); err != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	return
+}
+
+func (p *natsPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationConfiguration(t *testing.T) {
+	n, err := newNotifier([]NotificationTargetConfig{
+		{ID: "1", Type: NotificationTargetWebhook, Endpoint: "http://127.0.0.1:1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.stop()
+
+	var data = `<NotificationConfiguration>
+  <QueueConfiguration>
+    <Id>images</Id>
+    <Queue>arn:cfs:sqs:cfs_dev:1:webhook</Queue>
+    <Event>s3:ObjectCreated:*</Event>
+    <Filter><S3Key>
+      <FilterRule><Name>prefix</Name><Value>images/</Value></FilterRule>
+      <FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule>
+    </S3Key></Filter>
+  </QueueConfiguration>
+  <TopicConfiguration>
+    <Topic>arn:cfs:sns:cfs_dev:1:webhook</Topic>
+    <Event>s3:ObjectRemoved:Delete</Event>
+  </TopicConfiguration>
+</NotificationConfiguration>`
+	config, err := parseNotificationConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = config.validate(n); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var rules = config.rules()
+	if len(rules) != 2 {
+		t.Fatalf("rules: %v", rules)
+	}
+	if !rules[0].Match(EventObjectCreatedPut, "images/a.jpg") || !rules[0].Match(EventObjectCreatedCompleteMultipartUpload, "images/b.jpg") {
+		t.Fatalf("created images are not matched")
+	}
+	if rules[0].Match(EventObjectCreatedPut, "images/a.png") || rules[0].Match(EventObjectRemovedDelete, "images/a.jpg") {
+		t.Fatalf("unexpected match of created images")
+	}
+	if !rules[1].Match(EventObjectRemovedDelete, "a") || rules[1].Match(EventObjectRemovedDeleteMarkerCreated, "a") {
+		t.Fatalf("unexpected match of deletion")
+	}
+
+	var invalid = []string{
+		strings.Replace(data, "arn:cfs:sqs:cfs_dev:1:webhook", "arn:cfs:sqs:cfs_dev:2:webhook", 1),
+		strings.Replace(data, "arn:cfs:sqs:cfs_dev:1:webhook", "arn:cfs:sqs:cfs_dev:1:kafka", 1),
+		strings.Replace(data, "s3:ObjectCreated:*", "s3:ObjectRestore:*", 1),
+		strings.Replace(data, "<Name>suffix</Name>", "<Name>prefix</Name>", 1),
+	}
+	for i, data := range invalid {
+		if config, err = parseNotificationConfig([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err = config.validate(n); err == nil {
+			t.Fatalf("invalid configuration(%v) is accepted", i)
+		}
+	}
+}
+
+func TestWebhookTarget(t *testing.T) {
+	var received = make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		received <- data
+	}))
+	defer server.Close()
+
+	n, err := newNotifier([]NotificationTargetConfig{{ID: "1", Type: NotificationTargetWebhook, Endpoint: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.stop()
+	n.target("arn:cfs:sqs::1:webhook").publish("a", []byte(`{"Records":[]}`))
+	select {
+	case data := <-received:
+		if string(data) != `{"Records":[]}` {
+			t.Fatalf("unexpected message: %v", string(data))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message is not received")
+	}
+}
+
+func TestNatsPublisher(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var received = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {}\r\n"))
+		reader := bufio.NewReader(conn)
+		var lines []string
+		for len(lines) < 3 {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines = append(lines, strings.TrimSpace(line))
+		}
+		received <- strings.Join(lines, "|")
+	}()
+
+	p := newNatsPublisher("nats://"+ln.Addr().String(), "events")
+	defer p.close()
+	var data, _ = json.Marshal(map[string]string{"key": "a"})
+	if err = p.publish("a", data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case lines := <-received:
+		if !strings.HasPrefix(lines, "CONNECT ") || !strings.HasSuffix(lines, `|PUB events 11|{"key":"a"}`) {
+			t.Fatalf("unexpected protocol lines: %v", lines)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message is not received")
+	}
+}
@@ -89,40 +89,20 @@ func parseArn(str string) (*Arn, error) {
 	return arn, nil
 }
 
-// write bucket policy into store and update vol policy meta
-func storeBucketPolicy(bytes []byte, vol *Volume) (*Policy, error) {
-	policy := &Policy{}
-	err2 := json.Unmarshal(bytes, policy)
-	if err2 != nil {
-		log.LogErrorf("policy unmarshal err: %v", err2)
-		return nil, err2
+// write validated bucket policy into store and update vol policy meta
+func storeBucketPolicy(data []byte, policy *Policy, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPolicy, data); err != nil {
+		return
 	}
-
-	// validate policy
-	ok, err3 := policy.Validate(vol.name)
-	if err3 != nil {
-		log.LogErrorf("policy validate err: %v", err2)
-		return nil, err3
-	}
-	if !ok {
-		return nil, errors.New("policy is invalid")
-	}
-
-	// put policy bytes into store
-	err4 := vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPolicy, bytes)
-	if err4 != nil {
-		return nil, err4
-	}
-
 	vol.metaLoader.storePolicy(policy)
-
-	return policy, nil
+	return
 }
 
 func deleteBucketPolicy(vol *Volume) (err error) {
 	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSPolicy); err != nil {
 		return err
 	}
+	vol.metaLoader.storePolicy(nil)
 	return nil
 }
 
@@ -135,6 +115,9 @@ func ParsePolicy(r io.Reader, bucket string) (*Policy, error) {
 	}
 
 	if ok, err := policy.Validate(bucket); !ok {
+		if err == nil {
+			err = errors.New("policy is invalid")
+		}
 		return nil, err
 	}
 
@@ -163,6 +146,16 @@ func (p Policy) Validate(bucket string) (bool, error) {
 	return true, nil
 }
 
+// IsDenied reports whether any deny statement of the policy matches the request.
+func (p *Policy) IsDenied(params *RequestParam) bool {
+	for _, s := range p.Statements {
+		if s.Effect == Deny && !s.IsAllowed(params) {
+			return true
+		}
+	}
+	return false
+}
+
 // check policy is allowed for request
 // https://docs.aws.amazon.com/zh_cn/IAM/latest/UserGuide/reference_policies_evaluation-logic.html
 func (p *Policy) IsAllowed(params *RequestParam, isOwner bool) bool {
@@ -214,6 +207,25 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 
 		param := ParseRequestParam(r)
 
+		// The soft-deleted files in the trash are only accessible to the operators with the cli,
+		// no matter who owns the bucket.
+		if isTrashKey(param.Object()) || isTrashKey(r.URL.Query().Get(ParamPrefix)) {
+			log.LogDebugf("policyCheck: trash not accessible: requestID(%v) volume(%v) object(%v) action(%v)",
+				GetRequestID(r), param.Bucket(), param.Object(), param.Action())
+			return
+		}
+
+		// Anonymous request can only access the bucket which is opened to public by
+		// bucket policy or ACL.
+		if param.AccessKey() == "" {
+			allowed, ec = o.anonymousCheck(param)
+			if !allowed {
+				log.LogDebugf("policyCheck: anonymous request not allowed: requestID(%v) volume(%v) action(%v)",
+					GetRequestID(r), param.Bucket(), param.Action())
+			}
+			return
+		}
+
 		if param.Bucket() == "" {
 			log.LogDebugf("policyCheck: no bucket specified: requestID(%v)", GetRequestID(r))
 			allowed = true
@@ -251,7 +263,7 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 			}
 			var userPolicy = userInfo.Policy
 			isOwner = userPolicy.IsOwn(param.Bucket())
-			if !isOwner && !userPolicy.IsAuthorized(param.Bucket(), "", param.Action()) {
+			if !isOwner && !userPolicy.IsAuthorizedKey(param.Bucket(), policyCheckKey(param), param.Action()) {
 				log.LogDebugf("policyCheck: user no permission: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
 					GetRequestID(r), userInfo.UserID, param.AccessKey(), param.Bucket(), param.Action())
 				allowed = false
@@ -315,3 +327,57 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 			GetRequestID(r), userInfo, param.AccessKey(), param.Bucket(), param.Action())
 	}
 }
+
+// anonymousCheck checks the request without any credential. It is allowed only if the bucket
+// policy grants the action to the wildcard principal or the bucket ACL grants it to AllUsers,
+// and an explicit deny in bucket policy always takes precedence.
+func (o *ObjectNode) anonymousCheck(param *RequestParam) (allowed bool, ec *ErrorCode) {
+	if param.Bucket() == "" || param.Action() == proto.OSSCreateBucketAction {
+		return false, AccessDenied
+	}
+	var (
+		vol    *Volume
+		policy *Policy
+		acl    *AccessControlPolicy
+		err    error
+	)
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		if err == proto.ErrVolNotExists {
+			return false, NoSuchBucket
+		}
+		return false, InternalErrorCode(err)
+	}
+	if policy, err = vol.metaLoader.loadPolicy(); err != nil {
+		return false, InternalErrorCode(err)
+	}
+	if policy != nil && !policy.IsEmpty() {
+		if policy.IsDenied(param) {
+			return false, AccessDenied
+		}
+		if policy.IsAllowed(param, false) {
+			return true, nil
+		}
+	}
+	if acl, err = vol.metaLoader.loadACL(); err != nil {
+		return false, InternalErrorCode(err)
+	}
+	if acl != nil && acl.IsAnonymousAllowed(param) {
+		return true, nil
+	}
+	return false, AccessDenied
+}
+
+// policyCheckKey returns the object key checked against the prefix rules of the user policy,
+// which is the prefix to list for the list actions.
+func policyCheckKey(param *RequestParam) string {
+	if param.Action() == proto.OSSListObjectsAction {
+		return param.GetVar("prefix")
+	}
+	return param.Object()
+}
+
+// isTrashKey returns if the key or the prefix is in the trash directory under the root of the volume.
+func isTrashKey(key string) bool {
+	key = strings.TrimPrefix(key, "/")
+	return key == proto.TrashDirName || strings.HasPrefix(key, proto.TrashDirName+"/")
+}
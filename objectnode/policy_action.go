@@ -53,7 +53,7 @@ func (s Statement) checkActions(p *RequestParam) bool {
 	if s.Actions.Empty() {
 		return true
 	}
-	if s.Actions.ContainsWithAny(p.Action().String()) {
+	if containsAction(&s.Actions, p.Action()) {
 		return true
 	}
 	return false
@@ -63,12 +63,24 @@ func (s Statement) checkNotActions(p *RequestParam) bool {
 	if s.NotActions.Empty() {
 		return true
 	}
-	if s.NotActions.ContainsWithAny(p.Action().String()) {
+	if containsAction(&s.NotActions, p.Action()) {
 		return false
 	}
 	return true
 }
 
+// S3ActionPrefix is the prefix of the actions written in bucket policy, such as "s3:GetObject".
+const S3ActionPrefix = "s3:"
+
+// containsAction matches the action both in the internal form (action:oss:GetObject)
+// and in the S3 form (s3:GetObject) used by the bucket policy of S3 clients.
+func containsAction(actions *StringSet, action proto.Action) bool {
+	if actions.Contains("*") {
+		return true
+	}
+	return actions.ContainsWithAny(action.String()) || actions.ContainsWithAny(S3ActionPrefix+action.Name())
+}
+
 //
 func IsIntersectionActions(actions proto.Actions, action proto.Action) bool {
 	if len(actions) == 0 {
@@ -15,6 +15,7 @@
 package objectnode
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -29,7 +30,9 @@ func (o *ObjectNode) getBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		err error
 		ec  *ErrorCode
 	)
-	defer o.errorResponse(w, r, err, ec)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
 
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
@@ -48,6 +51,10 @@ func (o *ObjectNode) getBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		ec = InternalErrorCode(err)
 		return
 	}
+	if policy == nil || policy.IsEmpty() {
+		ec = NoSuchBucketPolicy
+		return
+	}
 
 	var policyData []byte
 	policyData, err = json.Marshal(policy)
@@ -56,6 +63,7 @@ func (o *ObjectNode) getBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	w.Header().Set(HeaderNameContentType, HeaderValueContentTypeJSON)
 	_, _ = w.Write(policyData)
 
 	return
@@ -67,7 +75,9 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		err error
 		ec  *ErrorCode
 	)
-	defer o.errorResponse(w, r, err, ec)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
 
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
@@ -87,8 +97,8 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var bytes []byte
-	bytes, err = ioutil.ReadAll(r.Body)
+	var data []byte
+	data, err = ioutil.ReadAll(io.LimitReader(r.Body, BucketPolicyLimitSize+1))
 	if err != nil && err != io.EOF {
 		log.LogErrorf("putBucketPolicyHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
 		ec = &ErrorCode{
@@ -98,10 +108,21 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
+	if len(data) > BucketPolicyLimitSize {
+		err = nil
+		ec = MaxContentLength
+		return
+	}
 
 	var policy *Policy
-	policy, err = storeBucketPolicy(bytes, vol)
-	if err != nil {
+	if policy, err = ParsePolicy(bytes.NewReader(data), param.Bucket()); err != nil {
+		log.LogWarnf("putBucketPolicyHandler: invalid policy: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		err = nil
+		ec = MalformedPolicy
+		return
+	}
+	if err = storeBucketPolicy(data, policy, vol); err != nil {
 		log.LogErrorf("putBucketPolicyHandler: store policy fail: requestID(%v) err(%v)", GetRequestID(r), err)
 		ec = InternalErrorCode(err)
 		return
@@ -110,30 +131,33 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 	log.LogInfof("putBucketPolicyHandler: put bucket policy: requestID(%v) volume(%v) policy(%v)",
 		GetRequestID(r), param.Bucket(), policy)
 
+	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketPolicy.html
 func (o *ObjectNode) deleteBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
-	log.LogInfof("Delete bucket policy...")
-
 	var err error
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		_ = InvalidBucketName.ServeResponse(w, r)
 		return
 	}
 	var vol *Volume
-	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+	if vol, err = o.getVol(param.Bucket()); err != nil {
 		_ = NoSuchBucket.ServeResponse(w, r)
 		return
 	}
 
 	if err = deleteBucketPolicy(vol); err != nil {
+		log.LogErrorf("deleteBucketPolicyHandler: delete policy fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
 		_ = InternalErrorCode(err).ServeResponse(w, r)
 		return
 	}
-	vol.metaLoader.storePolicy(nil)
+
+	log.LogInfof("deleteBucketPolicyHandler: delete bucket policy: requestID(%v) volume(%v)",
+		GetRequestID(r), param.Bucket())
 
 	w.WriteHeader(http.StatusNoContent)
 	return
@@ -17,6 +17,13 @@
 
 package objectnode
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/access-policy-language-overview.html
 
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/example-bucket-policies.html
@@ -31,6 +38,35 @@ const (
 	Deny         = "Deny"
 )
 
+const (
+	PrincipalAWS      = "AWS"
+	PrincipalAll      = "*"
+	ResourceArnPrefix = "arn:aws:s3:::"
+)
+
+// UnmarshalJSON accepts the wildcard form "Principal": "*" which means everyone, anonymous
+// users included, as well as the map form "Principal": {"AWS": [...]}.
+func (p *Principal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s != PrincipalAll {
+			return fmt.Errorf("invalid principal: %v", s)
+		}
+		var set StringSet
+		if err = set.UnmarshalJSON(b); err != nil {
+			return err
+		}
+		*p = Principal{PrincipalAWS: set}
+		return nil
+	}
+	var m map[string]StringSet
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	*p = m
+	return nil
+}
+
 type Statement struct {
 	Sid          string    `json:"Sid,omitempty"`
 	Effect       Effect    `json:"Effect"`
@@ -47,8 +83,20 @@ func (s *Statement) Validate(bucket string) (bool, error) {
 }
 
 func (s *Statement) isValid(bucket string) (bool, error) {
-	//TODO:
-
+	if s.Effect != Allow && s.Effect != Deny {
+		return false, fmt.Errorf("invalid effect: %v", s.Effect)
+	}
+	if s.Actions.Empty() && s.NotActions.Empty() {
+		return false, errors.New("statement must contain action or not action")
+	}
+	for _, resources := range []StringSet{s.Resources, s.NotResources} {
+		for resource := range resources.values {
+			resource = strings.TrimPrefix(resource, ResourceArnPrefix)
+			if resource != bucket && !strings.HasPrefix(resource, bucket+"/") {
+				return false, fmt.Errorf("resource %v is out of bucket %v", resource, bucket)
+			}
+		}
+	}
 	return true, nil
 }
 
@@ -85,7 +133,9 @@ func (s Statement) check(p *RequestParam) bool {
 
 func (s Statement) checkPrincipal(p *RequestParam) bool {
 	if len(s.Principal) == 0 {
-		return true
+		// A statement without principal never grants anything to anonymous users,
+		// they must be allowed explicitly by the wildcard principal.
+		return p.AccessKey() != "" || s.Effect == Deny
 	}
 	for _, principal := range s.Principal {
 		if principal.ContainsWild(p.AccessKey()) {
@@ -100,7 +150,7 @@ func (s Statement) checkResources(p *RequestParam) bool {
 	if s.Resources.Empty() {
 		return true
 	}
-	if s.Resources.ContainsRegex(p.resource) {
+	if containsResource(&s.Resources, p.resource) {
 		return true
 	}
 	return false
@@ -110,8 +160,17 @@ func (s Statement) checkNotResources(p *RequestParam) bool {
 	if s.NotResources.Empty() {
 		return true
 	}
-	if s.NotResources.ContainsRegex(p.resource) {
+	if containsResource(&s.NotResources, p.resource) {
 		return false
 	}
 	return true
 }
+
+// containsResource matches the requested resource against the resources of statement
+// which are written either as ARN (arn:aws:s3:::bucket/key) or as plain bucket/key.
+func containsResource(resources *StringSet, resource string) bool {
+	if resources.Contains("*") {
+		return true
+	}
+	return resources.ContainsWildcard(resource) || resources.ContainsWildcard(ResourceArnPrefix+resource)
+}
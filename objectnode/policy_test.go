@@ -14,6 +14,13 @@
 
 package objectnode
 
+import (
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
 /*
 
 https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/example-bucket-policies.html
@@ -66,3 +73,161 @@ https://docs.aws.amazon.com/zh_cn/IAM/latest/UserGuide/reference_policies_elemen
 }
 
 */
+
+func newTestRequestParam(accessKey, bucket, object string, action proto.Action) *RequestParam {
+	var param = &RequestParam{
+		bucket:    bucket,
+		object:    object,
+		resource:  bucket,
+		action:    action,
+		accessKey: accessKey,
+	}
+	if len(object) > 0 {
+		param.resource = bucket + "/" + object
+	}
+	return param
+}
+
+func TestPolicy_IsAllowedAnonymous(t *testing.T) {
+	var policyText = `
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "PublicRead",
+      "Effect": "Allow",
+      "Principal": "*",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::examplebucket/public/*"]
+    },
+    {
+      "Sid": "DenySecret",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:GetObject",
+      "Resource": "arn:aws:s3:::examplebucket/public/secret/*"
+    },
+    {
+      "Sid": "NoPrincipal",
+      "Effect": "Allow",
+      "Action": "s3:*",
+      "Resource": "arn:aws:s3:::examplebucket/*"
+    }
+  ]
+}`
+	var policy *Policy
+	var err error
+	if policy, err = ParsePolicy(strings.NewReader(policyText), "examplebucket"); err != nil {
+		t.Fatalf("parse policy fail: err(%v)", err)
+	}
+
+	type sample struct {
+		accessKey string
+		object    string
+		action    proto.Action
+		denied    bool
+		allowed   bool
+	}
+	var samples = []sample{
+		{object: "public/index.html", action: proto.OSSGetObjectAction, allowed: true},
+		{object: "public/a/b/c.png", action: proto.OSSGetObjectAction, allowed: true},
+		{object: "public/index.html", action: proto.OSSPutObjectAction},
+		{object: "private/index.html", action: proto.OSSGetObjectAction},
+		{object: "public/secret/key", action: proto.OSSGetObjectAction, denied: true},
+		{accessKey: "ak", object: "private/index.html", action: proto.OSSPutObjectAction, allowed: true},
+		{accessKey: "ak", object: "public/secret/key", action: proto.OSSGetObjectAction, denied: true},
+	}
+	for _, s := range samples {
+		param := newTestRequestParam(s.accessKey, "examplebucket", s.object, s.action)
+		if denied := policy.IsDenied(param); denied != s.denied {
+			t.Fatalf("deny result mismatch: sample(%v) actual(%v)", s, denied)
+		}
+		if allowed := policy.IsAllowed(param, false); allowed != s.allowed {
+			t.Fatalf("allow result mismatch: sample(%v) actual(%v)", s, allowed)
+		}
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	var samples = map[string]bool{
+		`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`:  true,
+		`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::otherbucket/*"}]}`:    false,
+		`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Resource":"arn:aws:s3:::examplebucket/*"}]}`:                          false,
+		`{"Version":"2012-10-17","Statement":[{"Effect":"Grant","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`:  false,
+		`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"ak","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`: false,
+		`{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`:                         false,
+	}
+	for text, valid := range samples {
+		if _, err := ParsePolicy(strings.NewReader(text), "examplebucket"); (err == nil) != valid {
+			t.Fatalf("validate result mismatch: policy(%v) expect(%v) err(%v)", text, valid, err)
+		}
+	}
+}
+
+func TestAccessControlPolicy_IsAnonymousAllowed(t *testing.T) {
+	var acp = &AccessControlPolicy{}
+	acp.SetBucketStandardACL(newTestRequestParam("ak", "examplebucket", "", proto.OSSPutBucketAclAction), string(PublicReadACL))
+
+	type sample struct {
+		object  string
+		action  proto.Action
+		allowed bool
+	}
+	var samples = []sample{
+		{action: proto.OSSListObjectsAction, allowed: true},
+		{object: "index.html", action: proto.OSSGetObjectAction, allowed: true},
+		{object: "index.html", action: proto.OSSPutObjectAction},
+		{object: "index.html", action: proto.OSSDeleteObjectAction},
+		{action: proto.OSSPutBucketAclAction},
+	}
+	for _, s := range samples {
+		param := newTestRequestParam("", "examplebucket", s.object, s.action)
+		if allowed := acp.IsAnonymousAllowed(param); allowed != s.allowed {
+			t.Fatalf("result mismatch: sample(%v) actual(%v)", s, allowed)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	type sample struct {
+		pattern string
+		key     string
+		match   bool
+	}
+	var samples = []sample{
+		{pattern: "bucket/*", key: "bucket/a/b", match: true},
+		{pattern: "bucket/*", key: "bucket", match: false},
+		{pattern: "bucket/*", key: "bucket2/a", match: false},
+		{pattern: "bucket/*.png", key: "bucket/a/b.png", match: true},
+		{pattern: "bucket/*.png", key: "bucket/a/b.jpg", match: false},
+		{pattern: "bucket/?.png", key: "bucket/a.png", match: true},
+		{pattern: "bucket/?.png", key: "bucket/ab.png", match: false},
+		{pattern: "*", key: "", match: true},
+		{pattern: "bucket", key: "bucket", match: true},
+	}
+	for _, s := range samples {
+		if match := wildcardMatch(s.pattern, s.key); match != s.match {
+			t.Fatalf("result mismatch: sample(%v) actual(%v)", s, match)
+		}
+	}
+}
+
+func TestIsTrashKey(t *testing.T) {
+	type sample struct {
+		key   string
+		trash bool
+	}
+	var samples = []sample{
+		{key: ".Trash", trash: true},
+		{key: ".Trash/", trash: true},
+		{key: "/.Trash/1600000000_8", trash: true},
+		{key: ".Trashed/a", trash: false},
+		{key: "a/.Trash/b", trash: false},
+		{key: "", trash: false},
+	}
+	for _, s := range samples {
+		if trash := isTrashKey(s.key); trash != s.trash {
+			t.Fatalf("result mismatch: sample(%v) actual(%v)", s, trash)
+		}
+	}
+}
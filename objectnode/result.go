@@ -252,11 +252,16 @@ func (t Tagging) Validate() (bool, *ErrorCode) {
 	if len(t.TagSet) > TaggingCounts {
 		return false, TagsGreaterThen10
 	}
+	var keys = make(map[string]struct{}, len(t.TagSet))
 	for _, tag := range t.TagSet {
 		log.LogDebugf("Validate: key : (%v), value : (%v)", tag.Key, tag.Value)
-		if len(tag.Key) > TaggingKeyMaxLength {
+		if len(tag.Key) == 0 || len(tag.Key) > TaggingKeyMaxLength {
 			return false, InvalidTagKey
 		}
+		if _, exist := keys[tag.Key]; exist {
+			return false, InvalidTag
+		}
+		keys[tag.Key] = struct{}{}
 		if len(tag.Value) > TaggingValueMaxLength {
 			return false, InvalidTagValue
 		}
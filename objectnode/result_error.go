@@ -89,6 +89,14 @@ var (
 	InvalidRange                        = &ErrorCode{ErrorCode: "InvalidRange", ErrorMessage: "The requested range cannot be satisfied.", StatusCode: http.StatusRequestedRangeNotSatisfiable}
 	MissingContentLength                = &ErrorCode{ErrorCode: "MissingContentLength", ErrorMessage: "You must provide the Content-Length HTTP header.", StatusCode: http.StatusLengthRequired}
 	NoSuchBucket                        = &ErrorCode{ErrorCode: "NoSuchBucket", ErrorMessage: "The specified bucket does not exist.", StatusCode: http.StatusNotFound}
+	NoSuchBucketPolicy                  = &ErrorCode{ErrorCode: "NoSuchBucketPolicy", ErrorMessage: "The bucket policy does not exist.", StatusCode: http.StatusNotFound}
+	SignatureDoesNotMatch               = &ErrorCode{ErrorCode: "SignatureDoesNotMatch", ErrorMessage: "The request signature we calculated does not match the signature you provided.", StatusCode: http.StatusForbidden}
+	XAmzContentSHA256Mismatch           = &ErrorCode{ErrorCode: "XAmzContentSHA256Mismatch", ErrorMessage: "The provided 'x-amz-content-sha256' header does not match what was computed.", StatusCode: http.StatusBadRequest}
+	IncompleteBody                      = &ErrorCode{ErrorCode: "IncompleteBody", ErrorMessage: "The request body is not in a valid aws-chunked encoding.", StatusCode: http.StatusBadRequest}
+	MalformedPolicy                     = &ErrorCode{ErrorCode: "MalformedPolicy", ErrorMessage: "Policies must be valid JSON and the first byte must be '{'.", StatusCode: http.StatusBadRequest}
+	NoSuchVersion                       = &ErrorCode{ErrorCode: "NoSuchVersion", ErrorMessage: "The specified version does not exist.", StatusCode: http.StatusNotFound}
+	MethodNotAllowed                    = &ErrorCode{ErrorCode: "MethodNotAllowed", ErrorMessage: "The specified method is not allowed against this resource.", StatusCode: http.StatusMethodNotAllowed}
+	IllegalVersioningConfiguration      = &ErrorCode{ErrorCode: "IllegalVersioningConfigurationException", ErrorMessage: "The versioning configuration specified in the request is invalid.", StatusCode: http.StatusBadRequest}
 	NoSuchKey                           = &ErrorCode{ErrorCode: "NoSuchKey", ErrorMessage: "The specified key does not exist.", StatusCode: http.StatusNotFound}
 	PreconditionFailed                  = &ErrorCode{ErrorCode: "PreconditionFailed", ErrorMessage: "At least one of the preconditions you specified did not hold.", StatusCode: http.StatusPreconditionFailed}
 	MaxContentLength                    = &ErrorCode{ErrorCode: "MaxContentLength", ErrorMessage: "Content-Length is bigger than 20KB.", StatusCode: http.StatusLengthRequired}
@@ -104,6 +112,20 @@ var (
 	TagsGreaterThen10                   = &ErrorCode{ErrorCode: "BadRequest", ErrorMessage: "Object tags cannot be greater than 10", StatusCode: http.StatusBadRequest}
 	InvalidTagKey                       = &ErrorCode{ErrorCode: "InvalidTag", ErrorMessage: "The TagKey you have provided is invalid", StatusCode: http.StatusBadRequest}
 	InvalidTagValue                     = &ErrorCode{ErrorCode: "InvalidTag", ErrorMessage: "The TagValue you have provided is invalid", StatusCode: http.StatusBadRequest}
+	InvalidTag                          = &ErrorCode{ErrorCode: "InvalidTag", ErrorMessage: "Cannot provide multiple Tags with the same key", StatusCode: http.StatusBadRequest}
+	NoSuchLifecycleConfiguration        = &ErrorCode{ErrorCode: "NoSuchLifecycleConfiguration", ErrorMessage: "The lifecycle configuration does not exist.", StatusCode: http.StatusNotFound}
+	MalformedXML                        = &ErrorCode{ErrorCode: "MalformedXML", ErrorMessage: "The XML you provided was not well-formed or did not validate against our published schema.", StatusCode: http.StatusBadRequest}
+	NotImplemented                      = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "A header or parameter you provided implies functionality that is not implemented.", StatusCode: http.StatusNotImplemented}
+	MissingRequiredParameter            = &ErrorCode{ErrorCode: "MissingRequiredParameter", ErrorMessage: "The SelectRequest entity is missing a required parameter.", StatusCode: http.StatusBadRequest}
+	InvalidExpressionType               = &ErrorCode{ErrorCode: "InvalidExpressionType", ErrorMessage: "The ExpressionType is invalid. Only SQL expressions are supported.", StatusCode: http.StatusBadRequest}
+	ObjectSerializationConflict         = &ErrorCode{ErrorCode: "ObjectSerializationConflict", ErrorMessage: "InputSerialization and OutputSerialization must each specify exactly one format.", StatusCode: http.StatusBadRequest}
+	InvalidCompressionFormat            = &ErrorCode{ErrorCode: "InvalidCompressionFormat", ErrorMessage: "The file is not in a supported compression format. Only GZIP and BZIP2 are supported.", StatusCode: http.StatusBadRequest}
+	InvalidFileHeaderInfo               = &ErrorCode{ErrorCode: "InvalidFileHeaderInfo", ErrorMessage: "The FileHeaderInfo is invalid. Only NONE, USE, and IGNORE are supported.", StatusCode: http.StatusBadRequest}
+	InvalidJsonType                     = &ErrorCode{ErrorCode: "InvalidJsonType", ErrorMessage: "The JsonType is invalid. Only DOCUMENT and LINES are supported.", StatusCode: http.StatusBadRequest}
+	InvalidQuoteFields                  = &ErrorCode{ErrorCode: "InvalidQuoteFields", ErrorMessage: "The QuoteFields is invalid. Only ALWAYS and ASNEEDED are supported.", StatusCode: http.StatusBadRequest}
+	InvalidRequestParameter             = &ErrorCode{ErrorCode: "InvalidRequestParameter", ErrorMessage: "The value of a parameter in SelectRequest element is invalid.", StatusCode: http.StatusBadRequest}
+	CORSNotEnabled                      = &ErrorCode{ErrorCode: "AccessForbidden", ErrorMessage: "CORSResponse: CORS is not enabled for this bucket.", StatusCode: http.StatusForbidden}
+	CORSNotAllowed                      = &ErrorCode{ErrorCode: "AccessForbidden", ErrorMessage: "CORSResponse: This CORS request is not allowed. This is usually because the evaluation of Origin, request method / Access-Control-Request-Method or Access-Control-Request-Headers are not whitelisted by the resource's CORS spec.", StatusCode: http.StatusForbidden}
 )
 
 func HttpStatusErrorCode(code int) *ErrorCode {
@@ -116,6 +138,20 @@ func HttpStatusErrorCode(code int) *ErrorCode {
 	}
 }
 
+// payloadErrorCode returns the error code for the error occurred while verifying the payload
+// of request, or nil if it is not a payload verification error.
+func payloadErrorCode(err error) *ErrorCode {
+	switch err {
+	case ErrChunkSignatureMismatch:
+		return SignatureDoesNotMatch
+	case ErrContentSHA256Mismatch:
+		return XAmzContentSHA256Mismatch
+	case ErrMalformedChunk:
+		return IncompleteBody
+	}
+	return nil
+}
+
 func InternalErrorCode(err error) *ErrorCode {
 	var errorMessage string
 	if err != nil {
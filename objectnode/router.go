@@ -223,27 +223,31 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycle.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketLifecycleAction)).
 			Methods(http.MethodGet).
 			Queries("lifecycle", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketLifecycleHandler)
+
+		// Get bucket notification
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketNotificationAction)).
+			Methods(http.MethodGet).
+			Queries("notification", "").
+			HandlerFunc(o.getBucketNotificationHandler)
 
 		// Get bucket versioning
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketVersioningAction)).
 			Methods(http.MethodGet).
 			Queries("versioning", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketVersioningHandler)
 
 		// List object versions
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSListObjectVersionsAction)).
 			Methods(http.MethodGet).
 			Queries("versions", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.listObjectVersionsHandler)
 
 		// List objects version 1
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjects.html
@@ -269,6 +273,15 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			Queries("uploadId", "{uploadId:.*}").
 			HandlerFunc(o.completeMultipartUploadHandler)
 
+		// Select object content
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+		// Notes: authorized as GetObject, the same permission required by Amazon S3
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectAction)).
+			Methods(http.MethodPost).
+			Path("/{object:.+}").
+			Queries("select", "", "select-type", "2").
+			HandlerFunc(o.selectObjectContentHandler)
+
 		// Restore object
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_RestoreObject.html
 		// Notes: unsupported operation
@@ -434,19 +447,24 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycle.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketLifecycleAction)).
 			Methods(http.MethodPut).
 			Queries("lifecycle", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketLifecycleHandler)
+
+		// Put bucket notification
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketNotificationAction)).
+			Methods(http.MethodPut).
+			Queries("notification", "").
+			HandlerFunc(o.putBucketNotificationHandler)
 
 		// Put bucket versioning
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketVersioningAction)).
 			Methods(http.MethodPut).
 			Queries("versioning", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketVersioningHandler)
 
 		// Create bucket
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
@@ -543,11 +561,10 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Delete bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketLifecycle.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketLifecycleAction)).
 			Methods(http.MethodDelete).
 			Queries("lifecycle", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketLifecycleHandler)
 
 		// Delete bucket
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucket.html
@@ -0,0 +1,670 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	SelectRequestLimitSize   = 256 * 1024
+	SelectRecordsMessageSize = 256 * 1024 // flush the records event when the output buffer exceeds this size
+	SelectKeepAliveInterval  = 5 * time.Second
+
+	SelectExpressionTypeSQL = "SQL"
+
+	SelectCompressionNone  = "NONE"
+	SelectCompressionGZIP  = "GZIP"
+	SelectCompressionBZIP2 = "BZIP2"
+
+	SelectFileHeaderNone   = "NONE"
+	SelectFileHeaderUse    = "USE"
+	SelectFileHeaderIgnore = "IGNORE"
+
+	SelectJSONTypeDocument = "DOCUMENT"
+	SelectJSONTypeLines    = "LINES"
+
+	SelectQuoteFieldsAsNeeded = "ASNEEDED"
+	SelectQuoteFieldsAlways   = "ALWAYS"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+type SelectObjectContentRequest struct {
+	XMLName             xml.Name                  `xml:"SelectObjectContentRequest"`
+	Expression          string                    `xml:"Expression"`
+	ExpressionType      string                    `xml:"ExpressionType"`
+	RequestProgress     *SelectRequestProgress    `xml:"RequestProgress"`
+	InputSerialization  SelectInputSerialization  `xml:"InputSerialization"`
+	OutputSerialization SelectOutputSerialization `xml:"OutputSerialization"`
+	ScanRange           *struct{}                 `xml:"ScanRange"`
+}
+
+type SelectRequestProgress struct {
+	Enabled bool `xml:"Enabled"`
+}
+
+type SelectInputSerialization struct {
+	CompressionType string          `xml:"CompressionType"`
+	CSV             *SelectCSVInput `xml:"CSV"`
+	JSON            *SelectJSON     `xml:"JSON"`
+	Parquet         *struct{}       `xml:"Parquet"`
+}
+
+type SelectCSVInput struct {
+	AllowQuotedRecordDelimiter bool   `xml:"AllowQuotedRecordDelimiter"`
+	Comments                   string `xml:"Comments"`
+	FieldDelimiter             string `xml:"FieldDelimiter"`
+	FileHeaderInfo             string `xml:"FileHeaderInfo"`
+	QuoteCharacter             string `xml:"QuoteCharacter"`
+	QuoteEscapeCharacter       string `xml:"QuoteEscapeCharacter"`
+	RecordDelimiter            string `xml:"RecordDelimiter"`
+}
+
+type SelectOutputSerialization struct {
+	CSV  *SelectCSVOutput `xml:"CSV"`
+	JSON *SelectJSON      `xml:"JSON"`
+}
+
+type SelectCSVOutput struct {
+	FieldDelimiter       string `xml:"FieldDelimiter"`
+	QuoteCharacter       string `xml:"QuoteCharacter"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter"`
+	QuoteFields          string `xml:"QuoteFields"`
+	RecordDelimiter      string `xml:"RecordDelimiter"`
+}
+
+// SelectJSON is used by both the JSON input and output serialization,
+// Type is only used by input and RecordDelimiter is only used by output.
+type SelectJSON struct {
+	Type            string `xml:"Type"`
+	RecordDelimiter string `xml:"RecordDelimiter"`
+}
+
+type SelectStats struct {
+	BytesScanned   int64
+	BytesProcessed int64
+	BytesReturned  int64
+}
+
+func parseSelectRequest(data []byte) (req *SelectObjectContentRequest, err error) {
+	req = &SelectObjectContentRequest{}
+	if err = xml.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	var input, output = &req.InputSerialization, &req.OutputSerialization
+	if input.CompressionType == "" {
+		input.CompressionType = SelectCompressionNone
+	}
+	if input.CSV != nil {
+		var csvInput = input.CSV
+		if csvInput.FieldDelimiter == "" {
+			csvInput.FieldDelimiter = ","
+		}
+		if csvInput.RecordDelimiter == "" {
+			csvInput.RecordDelimiter = "\n"
+		}
+		if csvInput.QuoteCharacter == "" {
+			csvInput.QuoteCharacter = "\""
+		}
+		if csvInput.QuoteEscapeCharacter == "" {
+			csvInput.QuoteEscapeCharacter = "\""
+		}
+		if csvInput.FileHeaderInfo == "" {
+			csvInput.FileHeaderInfo = SelectFileHeaderNone
+		}
+		csvInput.FileHeaderInfo = strings.ToUpper(csvInput.FileHeaderInfo)
+	}
+	if input.JSON != nil && input.JSON.Type == "" {
+		input.JSON.Type = SelectJSONTypeDocument
+	}
+	if output.CSV != nil {
+		var csvOutput = output.CSV
+		if csvOutput.FieldDelimiter == "" {
+			csvOutput.FieldDelimiter = ","
+		}
+		if csvOutput.RecordDelimiter == "" {
+			csvOutput.RecordDelimiter = "\n"
+		}
+		if csvOutput.QuoteCharacter == "" {
+			csvOutput.QuoteCharacter = "\""
+		}
+		if csvOutput.QuoteEscapeCharacter == "" {
+			csvOutput.QuoteEscapeCharacter = csvOutput.QuoteCharacter
+		}
+		if csvOutput.QuoteFields == "" {
+			csvOutput.QuoteFields = SelectQuoteFieldsAsNeeded
+		}
+	}
+	if output.JSON != nil && output.JSON.RecordDelimiter == "" {
+		output.JSON.RecordDelimiter = "\n"
+	}
+	return
+}
+
+// Validate checks the request and returns the error code responded to the client.
+func (req *SelectObjectContentRequest) Validate() *ErrorCode {
+	if req.Expression == "" {
+		return MissingRequiredParameter
+	}
+	if !strings.EqualFold(req.ExpressionType, SelectExpressionTypeSQL) {
+		return InvalidExpressionType
+	}
+	if req.ScanRange != nil || req.InputSerialization.Parquet != nil {
+		return NotImplemented
+	}
+	var input, output = &req.InputSerialization, &req.OutputSerialization
+	if (input.CSV == nil) == (input.JSON == nil) || (output.CSV == nil) == (output.JSON == nil) {
+		return ObjectSerializationConflict
+	}
+	switch strings.ToUpper(input.CompressionType) {
+	case SelectCompressionNone, SelectCompressionGZIP, SelectCompressionBZIP2:
+	default:
+		return InvalidCompressionFormat
+	}
+	if csvInput := input.CSV; csvInput != nil {
+		switch csvInput.FileHeaderInfo {
+		case SelectFileHeaderNone, SelectFileHeaderUse, SelectFileHeaderIgnore:
+		default:
+			return InvalidFileHeaderInfo
+		}
+		// The CSV parser only supports the standard quoting and record delimiters.
+		if utf8.RuneCountInString(csvInput.FieldDelimiter) != 1 || csvInput.FieldDelimiter == "\"" ||
+			csvInput.FieldDelimiter == "\n" || csvInput.FieldDelimiter == "\r" ||
+			csvInput.Comments != "" && (utf8.RuneCountInString(csvInput.Comments) != 1 || csvInput.Comments == csvInput.FieldDelimiter) ||
+			csvInput.RecordDelimiter != "\n" && csvInput.RecordDelimiter != "\r\n" ||
+			csvInput.QuoteCharacter != "\"" || csvInput.QuoteEscapeCharacter != "\"" {
+			return InvalidRequestParameter
+		}
+	}
+	if jsonInput := input.JSON; jsonInput != nil {
+		switch strings.ToUpper(jsonInput.Type) {
+		case SelectJSONTypeDocument, SelectJSONTypeLines:
+		default:
+			return InvalidJsonType
+		}
+	}
+	if csvOutput := output.CSV; csvOutput != nil {
+		switch strings.ToUpper(csvOutput.QuoteFields) {
+		case SelectQuoteFieldsAsNeeded, SelectQuoteFieldsAlways:
+		default:
+			return InvalidQuoteFields
+		}
+	}
+	return nil
+}
+
+// newSelectRecordReader creates the record reader for the input serialization over the
+// decompressed data. The reader returns io.EOF after the last record.
+func newSelectRecordReader(input *SelectInputSerialization, r io.Reader) func() (selectRecord, error) {
+	if input.CSV != nil {
+		return newSelectCSVReader(input.CSV, r)
+	}
+	return newSelectJSONReader(r)
+}
+
+type selectCSVRecord struct {
+	fields []string
+	header map[string]int
+}
+
+func (rec *selectCSVRecord) Field(path []string) interface{} {
+	if len(path) != 1 {
+		return nil
+	}
+	var name = path[0]
+	if index, ok := rec.header[name]; ok {
+		if index < len(rec.fields) {
+			return rec.fields[index]
+		}
+		return nil
+	}
+	if strings.HasPrefix(name, "_") {
+		if index, err := strconv.Atoi(name[1:]); err == nil && index > 0 && index <= len(rec.fields) {
+			return rec.fields[index-1]
+		}
+	}
+	return nil
+}
+
+func (rec *selectCSVRecord) Columns() (names []string, values []interface{}) {
+	names = make([]string, len(rec.fields))
+	values = make([]interface{}, len(rec.fields))
+	for i, field := range rec.fields {
+		names[i] = "_" + strconv.Itoa(i+1)
+		values[i] = field
+	}
+	for name, index := range rec.header {
+		if index < len(names) {
+			names[index] = name
+		}
+	}
+	return
+}
+
+func newSelectCSVReader(config *SelectCSVInput, r io.Reader) func() (selectRecord, error) {
+	var reader = csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(config.FieldDelimiter)
+	if config.Comments != "" {
+		reader.Comment, _ = utf8.DecodeRuneInString(config.Comments)
+	}
+	reader.FieldsPerRecord = -1
+	var header map[string]int
+	var first = true
+	return func() (selectRecord, error) {
+		fields, err := reader.Read()
+		if err != nil && err != io.EOF {
+			if _, ok := err.(*csv.ParseError); ok {
+				err = &selectError{Code: "CSVParsingError", Message: err.Error()}
+			}
+			return nil, err
+		}
+		if err == io.EOF {
+			return nil, err
+		}
+		if first && config.FileHeaderInfo != SelectFileHeaderNone {
+			first = false
+			if config.FileHeaderInfo == SelectFileHeaderUse {
+				header = make(map[string]int, len(fields))
+				for i, name := range fields {
+					if _, ok := header[name]; !ok {
+						header[name] = i
+					}
+				}
+			}
+			if fields, err = reader.Read(); err != nil {
+				if _, ok := err.(*csv.ParseError); ok {
+					err = &selectError{Code: "CSVParsingError", Message: err.Error()}
+				}
+				return nil, err
+			}
+		}
+		first = false
+		return &selectCSVRecord{fields: fields, header: header}, nil
+	}
+}
+
+type selectJSONRecord struct {
+	value interface{}
+}
+
+func (rec *selectJSONRecord) Field(path []string) interface{} {
+	var value = rec.value
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = object[name]; !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+func (rec *selectJSONRecord) Columns() (names []string, values []interface{}) {
+	object, ok := rec.value.(map[string]interface{})
+	if !ok {
+		return []string{"_1"}, []interface{}{rec.value}
+	}
+	names = make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values = make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = object[name]
+	}
+	return
+}
+
+// newSelectJSONReader reads a stream of JSON values, both JSON lines and documents
+// containing one or more values are supported. The elements of a top level array are
+// read as individual records.
+func newSelectJSONReader(r io.Reader) func() (selectRecord, error) {
+	var decoder = json.NewDecoder(bufio.NewReader(r))
+	decoder.UseNumber()
+	var pending []interface{}
+	return func() (selectRecord, error) {
+		for len(pending) == 0 {
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				switch err.(type) {
+				case *json.SyntaxError, *json.UnmarshalTypeError:
+					return nil, &selectError{Code: "JSONParsingError", Message: err.Error()}
+				}
+				if err == io.ErrUnexpectedEOF {
+					return nil, &selectError{Code: "JSONParsingError", Message: "unexpected end of JSON input"}
+				}
+				return nil, err
+			}
+			if array, ok := value.([]interface{}); ok {
+				pending = array
+				continue
+			}
+			return &selectJSONRecord{value: value}, nil
+		}
+		var value = pending[0]
+		pending = pending[1:]
+		return &selectJSONRecord{value: value}, nil
+	}
+}
+
+// newSelectRecordWriter creates the function serializing the result rows to the output buffer.
+func newSelectRecordWriter(output *SelectOutputSerialization) func(buf *bytes.Buffer, names []string, values []interface{}) {
+	if config := output.CSV; config != nil {
+		var always = strings.EqualFold(config.QuoteFields, SelectQuoteFieldsAlways)
+		var escaped = config.QuoteEscapeCharacter + config.QuoteCharacter
+		return func(buf *bytes.Buffer, names []string, values []interface{}) {
+			for i, value := range values {
+				if i > 0 {
+					buf.WriteString(config.FieldDelimiter)
+				}
+				var field = selectFormatValue(value)
+				if always || strings.Contains(field, config.FieldDelimiter) || strings.Contains(field, config.QuoteCharacter) ||
+					strings.Contains(field, config.RecordDelimiter) || strings.ContainsAny(field, "\r\n") {
+					buf.WriteString(config.QuoteCharacter)
+					buf.WriteString(strings.ReplaceAll(field, config.QuoteCharacter, escaped))
+					buf.WriteString(config.QuoteCharacter)
+					continue
+				}
+				buf.WriteString(field)
+			}
+			buf.WriteString(config.RecordDelimiter)
+		}
+	}
+	var delimiter = output.JSON.RecordDelimiter
+	return func(buf *bytes.Buffer, names []string, values []interface{}) {
+		buf.WriteByte('{')
+		for i, value := range values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(names[i])
+			buf.Write(name)
+			buf.WriteByte(':')
+			writeSelectJSONValue(buf, value)
+		}
+		buf.WriteByte('}')
+		buf.WriteString(delimiter)
+	}
+}
+
+func writeSelectJSONValue(buf *bytes.Buffer, value interface{}) {
+	switch val := value.(type) {
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			buf.WriteString("null")
+			return
+		}
+		buf.WriteString(strconv.FormatFloat(val, 'f', -1, 64))
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		buf.WriteString("null")
+		return
+	}
+	buf.Write(data)
+}
+
+// Event stream encoding of the response.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTSelectObjectAppendix.html
+//
+//	| total length (4) | headers length (4) | prelude CRC (4) | headers | payload | message CRC (4) |
+//
+// Each header is encoded as
+//
+//	| name length (1) | name | value type (1), always 7 (string) | value length (2) | value |
+const selectEventHeaderValueTypeString = 7
+
+type selectEventHeader struct {
+	name  string
+	value string
+}
+
+func encodeSelectEvent(headers []selectEventHeader, payload []byte) []byte {
+	var headerBuf bytes.Buffer
+	for _, header := range headers {
+		headerBuf.WriteByte(byte(len(header.name)))
+		headerBuf.WriteString(header.name)
+		headerBuf.WriteByte(selectEventHeaderValueTypeString)
+		_ = binary.Write(&headerBuf, binary.BigEndian, uint16(len(header.value)))
+		headerBuf.WriteString(header.value)
+	}
+	var totalLength = 12 + headerBuf.Len() + len(payload) + 4
+	var message = make([]byte, 0, totalLength)
+	message = appendUint32(message, uint32(totalLength))
+	message = appendUint32(message, uint32(headerBuf.Len()))
+	message = appendUint32(message, crc32.ChecksumIEEE(message))
+	message = append(message, headerBuf.Bytes()...)
+	message = append(message, payload...)
+	message = appendUint32(message, crc32.ChecksumIEEE(message))
+	return message
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func selectRecordsEvent(payload []byte) []byte {
+	return encodeSelectEvent([]selectEventHeader{
+		{name: ":event-type", value: "Records"},
+		{name: ":content-type", value: "application/octet-stream"},
+		{name: ":message-type", value: "event"},
+	}, payload)
+}
+
+func selectStatsEvent(eventType string, stats *SelectStats) []byte {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	// the root element of the payload is named after the event type, "Stats" or "Progress"
+	sb.WriteString(fmt.Sprintf("<%v><BytesScanned>%v</BytesScanned><BytesProcessed>%v</BytesProcessed>"+
+		"<BytesReturned>%v</BytesReturned></%v>",
+		eventType, stats.BytesScanned, stats.BytesProcessed, stats.BytesReturned, eventType))
+	return encodeSelectEvent([]selectEventHeader{
+		{name: ":event-type", value: eventType},
+		{name: ":content-type", value: "text/xml"},
+		{name: ":message-type", value: "event"},
+	}, []byte(sb.String()))
+}
+
+func selectContinuationEvent() []byte {
+	return encodeSelectEvent([]selectEventHeader{
+		{name: ":event-type", value: "Cont"},
+		{name: ":message-type", value: "event"},
+	}, nil)
+}
+
+func selectEndEvent() []byte {
+	return encodeSelectEvent([]selectEventHeader{
+		{name: ":event-type", value: "End"},
+		{name: ":message-type", value: "event"},
+	}, nil)
+}
+
+func selectErrorEvent(code, message string) []byte {
+	return encodeSelectEvent([]selectEventHeader{
+		{name: ":error-code", value: code},
+		{name: ":error-message", value: message},
+		{name: ":message-type", value: "error"},
+	}, nil)
+}
+
+type selectCountingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *selectCountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	r.n += int64(n)
+	return
+}
+
+// selectExecutor runs the query over the object data, and writes the result to the
+// writer as a stream of events.
+type selectExecutor struct {
+	query    *selectQuery
+	request  *SelectObjectContentRequest
+	writer   io.Writer
+	lastSent time.Time
+	stats    SelectStats
+}
+
+func (e *selectExecutor) send(message []byte) (err error) {
+	if _, err = e.writer.Write(message); err != nil {
+		return
+	}
+	if flusher, ok := e.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	e.lastSent = time.Now()
+	return
+}
+
+// execute scans the data and streams the matched records. Errors occurred after the
+// scanning started are reported by an error event, the returned error is only used for logging.
+func (e *selectExecutor) execute(data io.Reader) (err error) {
+	var scanned = &selectCountingReader{r: data}
+	var processed *selectCountingReader
+	var buf bytes.Buffer
+	defer func() {
+		e.stats.BytesScanned = scanned.n
+		if processed != nil {
+			e.stats.BytesProcessed = processed.n
+		}
+		if buf.Len() > 0 {
+			// records matched before the error are still sent
+			if sendErr := e.send(selectRecordsEvent(buf.Bytes())); sendErr != nil {
+				if err == nil {
+					err = sendErr
+				}
+				return
+			}
+			e.stats.BytesReturned += int64(buf.Len())
+		}
+		if err != nil {
+			var code, message = "InternalError", "We encountered an internal error. Please try again."
+			if se, ok := err.(*selectError); ok {
+				code, message = se.Code, se.Message
+			}
+			_ = e.send(selectErrorEvent(code, message))
+			return
+		}
+		if err = e.send(selectStatsEvent("Stats", &e.stats)); err != nil {
+			return
+		}
+		err = e.send(selectEndEvent())
+	}()
+
+	var decompressed io.Reader
+	if decompressed, err = e.decompressor(scanned); err != nil {
+		return
+	}
+	processed = &selectCountingReader{r: decompressed}
+	var read = newSelectRecordReader(&e.request.InputSerialization, processed)
+	var write = newSelectRecordWriter(&e.request.OutputSerialization)
+	var progress = e.request.RequestProgress != nil && e.request.RequestProgress.Enabled
+	var query = e.query
+	var returned int64
+	e.lastSent = time.Now()
+	for records := 0; query.limit < 0 || returned < query.limit || query.isAggregation(); records++ {
+		var rec selectRecord
+		if rec, err = read(); err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+		var matched bool
+		if matched, err = query.match(rec); err != nil {
+			return
+		}
+		if matched && query.isAggregation() {
+			if err = query.accumulate(rec); err != nil {
+				return
+			}
+		} else if matched {
+			var names []string
+			var values []interface{}
+			if names, values, err = query.project(rec); err != nil {
+				return
+			}
+			write(&buf, names, values)
+			returned++
+		}
+		if buf.Len() >= SelectRecordsMessageSize {
+			if err = e.send(selectRecordsEvent(buf.Bytes())); err != nil {
+				return
+			}
+			e.stats.BytesReturned += int64(buf.Len())
+			buf.Reset()
+		}
+		// keep the connection alive while scanning large objects with few matched records
+		if records%1024 == 0 && time.Since(e.lastSent) >= SelectKeepAliveInterval {
+			if progress {
+				e.stats.BytesScanned, e.stats.BytesProcessed = scanned.n, processed.n
+				err = e.send(selectStatsEvent("Progress", &e.stats))
+			} else {
+				err = e.send(selectContinuationEvent())
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	if query.isAggregation() && query.limit != 0 {
+		var names []string
+		var values []interface{}
+		if names, values, err = query.project(nil); err != nil {
+			return
+		}
+		write(&buf, names, values)
+	}
+	return
+}
+
+func (e *selectExecutor) decompressor(r io.Reader) (io.Reader, error) {
+	switch strings.ToUpper(e.request.InputSerialization.CompressionType) {
+	case SelectCompressionGZIP:
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, &selectError{Code: "InvalidCompressionFormat", Message: err.Error()}
+		}
+		return reader, nil
+	case SelectCompressionBZIP2:
+		return bzip2.NewReader(r), nil
+	}
+	return r, nil
+}
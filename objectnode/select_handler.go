@@ -0,0 +1,118 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Select object content
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+func (o *ObjectNode) selectObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		ec = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("selectObjectContentHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadAll(io.LimitReader(r.Body, SelectRequestLimitSize+1)); err != nil && err != io.EOF {
+		log.LogErrorf("selectObjectContentHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		return
+	}
+	if len(data) > SelectRequestLimitSize {
+		err = nil
+		ec = MaxContentLength
+		return
+	}
+	var request *SelectObjectContentRequest
+	if request, err = parseSelectRequest(data); err != nil {
+		log.LogWarnf("selectObjectContentHandler: invalid select request: requestID(%v) err(%v)", GetRequestID(r), err)
+		err = nil
+		ec = MalformedXML
+		return
+	}
+	if ec = request.Validate(); ec != nil {
+		return
+	}
+	var query *selectQuery
+	if query, err = parseSelectQuery(request.Expression); err != nil {
+		log.LogWarnf("selectObjectContentHandler: parse expression fail: requestID(%v) expression(%v) err(%v)",
+			GetRequestID(r), request.Expression, err)
+		ec = &ErrorCode{ErrorCode: "ParseSelectFailure", ErrorMessage: err.Error(), StatusCode: http.StatusBadRequest}
+		err = nil
+		return
+	}
+
+	var fileInfo *FSFileInfo
+	if fileInfo, err = vol.ObjectMeta(param.Object()); err != nil && err != syscall.ENOENT {
+		log.LogErrorf("selectObjectContentHandler: get object meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), param.Object(), err)
+		return
+	}
+	if err == syscall.ENOENT || fileInfo.Mode.IsDir() {
+		err = nil
+		ec = NoSuchKey
+		return
+	}
+
+	// The object data is streamed through a pipe, so the records are scanned while being read
+	// from data nodes without buffering the whole object.
+	var pr, pw = io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(vol.ReadFile(param.Object(), pw, 0, uint64(fileInfo.Size)))
+	}()
+	defer func() {
+		// stops the reading if the scanning finished early, e.g. LIMIT reached or client gone
+		_ = pr.Close()
+	}()
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueTypeStream}
+	w.WriteHeader(http.StatusOK)
+	var executor = &selectExecutor{query: query, request: request, writer: w}
+	if execErr := executor.execute(pr); execErr != nil {
+		log.LogWarnf("selectObjectContentHandler: execute select fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), param.Object(), execErr)
+	}
+	log.LogInfof("Audit: SelectObjectContent: requestID(%v) volume(%v) path(%v) expression(%v) scanned(%v) returned(%v)",
+		GetRequestID(r), param.Bucket(), param.Object(), request.Expression,
+		executor.stats.BytesScanned, executor.stats.BytesReturned)
+	return
+}
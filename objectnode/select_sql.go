@@ -0,0 +1,1151 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This file implements the subset of the S3 Select SQL dialect supported by object node:
+//
+//   SELECT <* | expr [[AS] name], ...> FROM S3Object[[*]] [[AS] alias] [WHERE expr] [LIMIT n]
+//
+// Expressions support column references, string/number/boolean literals, comparison, LIKE, IN,
+// BETWEEN, IS [NOT] NULL, AND/OR/NOT, arithmetic, CAST and the functions LOWER, UPPER, TRIM,
+// CHAR_LENGTH and COALESCE. The aggregate functions COUNT, SUM, AVG, MIN and MAX are supported
+// for queries which only project aggregates.
+
+const (
+	selectTokenEOF = iota
+	selectTokenIdent
+	selectTokenQuotedIdent
+	selectTokenString
+	selectTokenNumber
+	selectTokenOp
+)
+
+var selectKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true, "MISSING": true,
+	"LIKE": true, "ESCAPE": true, "IN": true, "BETWEEN": true, "TRUE": true, "FALSE": true,
+	"CAST": true,
+}
+
+type selectToken struct {
+	kind int
+	text string
+}
+
+func (t selectToken) isKeyword(word string) bool {
+	return t.kind == selectTokenIdent && strings.EqualFold(t.text, word)
+}
+
+func (t selectToken) isOp(op string) bool {
+	return t.kind == selectTokenOp && t.text == op
+}
+
+func (t selectToken) String() string {
+	switch t.kind {
+	case selectTokenEOF:
+		return "end of expression"
+	case selectTokenString:
+		return "'" + t.text + "'"
+	case selectTokenQuotedIdent:
+		return "\"" + t.text + "\""
+	}
+	return t.text
+}
+
+func lexSelectExpression(s string) (tokens []selectToken, err error) {
+	var i int
+	for i < len(s) {
+		var c = s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '\'' || c == '"':
+			var sb strings.Builder
+			var j = i + 1
+			for {
+				if j >= len(s) {
+					return nil, fmt.Errorf("unterminated quoted text at position %v", i)
+				}
+				if s[j] == c {
+					// a doubled quote character is an escaped quote
+					if j+1 < len(s) && s[j+1] == c {
+						sb.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				sb.WriteByte(s[j])
+				j++
+			}
+			var kind = selectTokenString
+			if c == '"' {
+				kind = selectTokenQuotedIdent
+			}
+			tokens = append(tokens, selectToken{kind: kind, text: sb.String()})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			var j = i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				(s[j] == '-' || s[j] == '+') && (s[j-1] == 'e' || s[j-1] == 'E')) {
+				j++
+			}
+			tokens = append(tokens, selectToken{kind: selectTokenNumber, text: s[i:j]})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			var j = i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, selectToken{kind: selectTokenIdent, text: s[i:j]})
+			i = j
+		default:
+			if i+1 < len(s) {
+				switch op := s[i : i+2]; op {
+				case "<=", ">=", "<>", "!=":
+					tokens = append(tokens, selectToken{kind: selectTokenOp, text: op})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("*,().=<>+-/%[]", rune(c)) {
+				return nil, fmt.Errorf("unexpected character '%c' at position %v", c, i)
+			}
+			tokens = append(tokens, selectToken{kind: selectTokenOp, text: string(c)})
+			i++
+		}
+	}
+	tokens = append(tokens, selectToken{kind: selectTokenEOF})
+	return
+}
+
+// selectError is an error occurred while evaluating the query. The code is reported to
+// the client in the error event of the response.
+type selectError struct {
+	Code    string
+	Message string
+}
+
+func (e *selectError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// selectRecord is a record of the input object.
+type selectRecord interface {
+	// Field returns the value of the column specified by path, or nil if it does not exist.
+	Field(path []string) interface{}
+	// Columns returns all the columns of the record, used by "SELECT *".
+	Columns() (names []string, values []interface{})
+}
+
+type selectExpr interface {
+	eval(rec selectRecord) (interface{}, error)
+}
+
+type selectLiteral struct {
+	value interface{}
+}
+
+func (e *selectLiteral) eval(rec selectRecord) (interface{}, error) {
+	return e.value, nil
+}
+
+type selectColumn struct {
+	path []string
+}
+
+func (e *selectColumn) eval(rec selectRecord) (interface{}, error) {
+	if rec == nil {
+		return nil, nil
+	}
+	return rec.Field(e.path), nil
+}
+
+type selectUnary struct {
+	op string
+	x  selectExpr
+}
+
+func (e *selectUnary) eval(rec selectRecord) (interface{}, error) {
+	v, err := e.x.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	if e.op == "NOT" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, nil
+		}
+		return !b, nil
+	}
+	n, ok := selectToNumber(v)
+	if !ok {
+		return nil, nil
+	}
+	return -n, nil
+}
+
+type selectBinary struct {
+	op   string
+	l, r selectExpr
+}
+
+func (e *selectBinary) eval(rec selectRecord) (interface{}, error) {
+	l, err := e.l.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "AND":
+		if b, ok := l.(bool); ok && !b {
+			return false, nil
+		}
+	case "OR":
+		if b, ok := l.(bool); ok && b {
+			return true, nil
+		}
+	}
+	r, err := e.r.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "AND", "OR":
+		// the left operand is known not to decide the result here
+		_, lok := l.(bool)
+		rb, rok := r.(bool)
+		if rok && (e.op == "AND") != rb {
+			return rb, nil
+		}
+		if !lok || !rok {
+			return nil, nil
+		}
+		return rb, nil
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		c, ok := selectCompare(l, r)
+		if !ok {
+			return nil, nil
+		}
+		switch e.op {
+		case "=":
+			return c == 0, nil
+		case "!=", "<>":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+	ln, lok := selectToNumber(l)
+	rn, rok := selectToNumber(r)
+	if !lok || !rok {
+		return nil, nil
+	}
+	switch e.op {
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	}
+	if rn == 0 {
+		return nil, &selectError{Code: "DivisionByZero", Message: "division by zero"}
+	}
+	if e.op == "%" {
+		return math.Mod(ln, rn), nil
+	}
+	return ln / rn, nil
+}
+
+type selectIsNull struct {
+	x   selectExpr
+	not bool
+}
+
+func (e *selectIsNull) eval(rec selectRecord) (interface{}, error) {
+	v, err := e.x.eval(rec)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.not, nil
+}
+
+type selectIn struct {
+	x    selectExpr
+	list []selectExpr
+	not  bool
+}
+
+func (e *selectIn) eval(rec selectRecord) (interface{}, error) {
+	v, err := e.x.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	for _, item := range e.list {
+		var iv interface{}
+		if iv, err = item.eval(rec); err != nil {
+			return nil, err
+		}
+		if c, ok := selectCompare(v, iv); ok && c == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+type selectBetween struct {
+	x, low, high selectExpr
+	not          bool
+}
+
+func (e *selectBetween) eval(rec selectRecord) (interface{}, error) {
+	var values [3]interface{}
+	for i, expr := range []selectExpr{e.x, e.low, e.high} {
+		var err error
+		if values[i], err = expr.eval(rec); err != nil {
+			return nil, err
+		}
+	}
+	lc, lok := selectCompare(values[0], values[1])
+	hc, hok := selectCompare(values[0], values[2])
+	if !lok || !hok {
+		return nil, nil
+	}
+	return (lc >= 0 && hc <= 0) != e.not, nil
+}
+
+type selectLike struct {
+	x       selectExpr
+	pattern selectExpr
+	escape  string
+	re      *regexp.Regexp // compiled once if the pattern is a literal
+	not     bool
+}
+
+func (e *selectLike) eval(rec selectRecord) (interface{}, error) {
+	v, err := e.x.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var re = e.re
+	if re == nil {
+		var p interface{}
+		if p, err = e.pattern.eval(rec); err != nil || p == nil {
+			return nil, err
+		}
+		if re, err = compileSelectLikePattern(selectFormatValue(p), e.escape); err != nil {
+			return nil, &selectError{Code: "InvalidLikePattern", Message: err.Error()}
+		}
+	}
+	return re.MatchString(selectFormatValue(v)) != e.not, nil
+}
+
+func compileSelectLikePattern(pattern, escape string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	var escaped bool
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+		case escape != "" && string(c) == escape:
+			escaped = true
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("pattern %q ends with escape character", pattern)
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+type selectCast struct {
+	x   selectExpr
+	typ string
+}
+
+func (e *selectCast) eval(rec selectRecord) (interface{}, error) {
+	v, err := e.x.eval(rec)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var failed = &selectError{Code: "CastFailed", Message: fmt.Sprintf("cannot cast %v to %v", selectFormatValue(v), e.typ)}
+	switch e.typ {
+	case "STRING", "VARCHAR", "CHAR":
+		return selectFormatValue(v), nil
+	}
+	// empty CSV fields are missing values
+	if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	switch e.typ {
+	case "BOOL", "BOOLEAN":
+		switch val := v.(type) {
+		case bool:
+			return val, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(val))
+			if err != nil {
+				return nil, failed
+			}
+			return b, nil
+		}
+		return nil, failed
+	}
+	n, ok := selectToNumber(v)
+	if !ok {
+		return nil, failed
+	}
+	if e.typ == "INT" || e.typ == "INTEGER" {
+		return math.Trunc(n), nil
+	}
+	return n, nil
+}
+
+type selectFunc struct {
+	name string
+	args []selectExpr
+}
+
+func (e *selectFunc) eval(rec selectRecord) (interface{}, error) {
+	var values = make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		var err error
+		if values[i], err = arg.eval(rec); err != nil {
+			return nil, err
+		}
+	}
+	if e.name == "COALESCE" {
+		for _, v := range values {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	}
+	if values[0] == nil {
+		return nil, nil
+	}
+	var s = selectFormatValue(values[0])
+	switch e.name {
+	case "LOWER":
+		return strings.ToLower(s), nil
+	case "UPPER":
+		return strings.ToUpper(s), nil
+	case "TRIM":
+		return strings.TrimSpace(s), nil
+	}
+	// CHAR_LENGTH and CHARACTER_LENGTH
+	return float64(len([]rune(s))), nil
+}
+
+// selectAggregate is an aggregate function accumulating the values of all matched records.
+type selectAggregate struct {
+	name  string
+	arg   selectExpr // nil for COUNT(*)
+	count int64
+	sum   float64
+	value interface{}
+}
+
+func (e *selectAggregate) accumulate(rec selectRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	v, err := e.arg.eval(rec)
+	if err != nil || v == nil {
+		return err
+	}
+	// empty CSV fields are missing values
+	if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+		return nil
+	}
+	switch e.name {
+	case "SUM", "AVG":
+		n, ok := selectToNumber(v)
+		if !ok {
+			return &selectError{Code: "CastFailed", Message: fmt.Sprintf("cannot cast %v to number in %v", selectFormatValue(v), e.name)}
+		}
+		e.sum += n
+	case "MIN", "MAX":
+		if e.value == nil {
+			e.value = v
+			break
+		}
+		c, ok := selectCompare(v, e.value)
+		if !ok {
+			return &selectError{Code: "CastFailed", Message: fmt.Sprintf("cannot compare %v with %v in %v",
+				selectFormatValue(v), selectFormatValue(e.value), e.name)}
+		}
+		if e.name == "MIN" && c < 0 || e.name == "MAX" && c > 0 {
+			e.value = v
+		}
+	}
+	e.count++
+	return nil
+}
+
+func (e *selectAggregate) eval(rec selectRecord) (interface{}, error) {
+	switch e.name {
+	case "COUNT":
+		return float64(e.count), nil
+	case "SUM":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return e.sum, nil
+	case "AVG":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return e.sum / float64(e.count), nil
+	}
+	return e.value, nil
+}
+
+type selectProjection struct {
+	expr selectExpr
+	name string
+}
+
+// selectQuery is a parsed S3 Select SQL expression.
+type selectQuery struct {
+	projections []*selectProjection // nil for "SELECT *"
+	where       selectExpr
+	limit       int64 // negative if no limit specified
+	aggregates  []*selectAggregate
+}
+
+func (q *selectQuery) isAggregation() bool {
+	return len(q.aggregates) > 0
+}
+
+// match reports whether the record satisfies the WHERE clause.
+func (q *selectQuery) match(rec selectRecord) (bool, error) {
+	if q.where == nil {
+		return true, nil
+	}
+	v, err := q.where.eval(rec)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	return ok && b, nil
+}
+
+// accumulate feeds a matched record to all the aggregate functions.
+func (q *selectQuery) accumulate(rec selectRecord) error {
+	for _, agg := range q.aggregates {
+		if err := agg.accumulate(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// project evaluates the projections for a matched record. The record is nil while
+// projecting the result of an aggregation.
+func (q *selectQuery) project(rec selectRecord) (names []string, values []interface{}, err error) {
+	if q.projections == nil {
+		names, values = rec.Columns()
+		return
+	}
+	names = make([]string, len(q.projections))
+	values = make([]interface{}, len(q.projections))
+	for i, p := range q.projections {
+		if values[i], err = p.expr.eval(rec); err != nil {
+			return
+		}
+		names[i] = p.name
+	}
+	return
+}
+
+type selectParser struct {
+	tokens      []selectToken
+	pos         int
+	columns     []*selectColumn
+	aggregates  []*selectAggregate
+	inAggregate bool
+	bareColumn  bool // a column is referenced outside of aggregate functions
+}
+
+func parseSelectQuery(expression string) (query *selectQuery, err error) {
+	var p = &selectParser{}
+	if p.tokens, err = lexSelectExpression(expression); err != nil {
+		return
+	}
+	if !p.peek().isKeyword("SELECT") {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	query = &selectQuery{limit: -1}
+	if p.peek().isOp("*") {
+		p.pos++
+	} else {
+		for {
+			var projection = &selectProjection{}
+			if projection.expr, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+			if projection.name, err = p.parseAlias(); err != nil {
+				return nil, err
+			}
+			if projection.name == "" {
+				if column, ok := projection.expr.(*selectColumn); ok {
+					projection.name = column.path[len(column.path)-1]
+				} else {
+					projection.name = "_" + strconv.Itoa(len(query.projections)+1)
+				}
+			}
+			query.projections = append(query.projections, projection)
+			if !p.peek().isOp(",") {
+				break
+			}
+			p.pos++
+		}
+	}
+	if len(p.aggregates) > 0 && p.bareColumn {
+		return nil, fmt.Errorf("columns must be used in aggregate functions when the query contains aggregations")
+	}
+	query.aggregates = p.aggregates
+
+	if !p.peek().isKeyword("FROM") {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	if !p.peek().isKeyword("S3Object") {
+		return nil, fmt.Errorf("FROM clause must be S3Object, found %v", p.peek())
+	}
+	p.pos++
+	if p.peek().isOp("[") {
+		if !p.peekAt(1).isOp("*") || !p.peekAt(2).isOp("]") {
+			return nil, p.unexpected()
+		}
+		p.pos += 3
+	}
+	var alias string
+	if alias, err = p.parseAlias(); err != nil {
+		return
+	}
+	if p.peek().isKeyword("WHERE") {
+		p.pos++
+		p.bareColumn = false
+		var aggregates = len(p.aggregates)
+		if query.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if len(p.aggregates) != aggregates {
+			return nil, fmt.Errorf("aggregate functions are not allowed in WHERE clause")
+		}
+	}
+	if p.peek().isKeyword("LIMIT") {
+		p.pos++
+		var t = p.next()
+		if t.kind != selectTokenNumber {
+			return nil, fmt.Errorf("LIMIT requires a non-negative integer, found %v", t)
+		}
+		if query.limit, err = strconv.ParseInt(t.text, 10, 64); err != nil || query.limit < 0 {
+			return nil, fmt.Errorf("LIMIT requires a non-negative integer, found %v", t)
+		}
+	}
+	if p.peek().kind != selectTokenEOF {
+		return nil, p.unexpected()
+	}
+	// strip the table name or alias from column references, e.g. "s._1" and "S3Object.name"
+	for _, column := range p.columns {
+		if len(column.path) > 1 && (strings.EqualFold(column.path[0], "S3Object") ||
+			alias != "" && strings.EqualFold(column.path[0], alias)) {
+			column.path = column.path[1:]
+		}
+	}
+	return
+}
+
+func (p *selectParser) peek() selectToken {
+	return p.peekAt(0)
+}
+
+func (p *selectParser) peekAt(offset int) selectToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *selectParser) next() selectToken {
+	var t = p.peek()
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *selectParser) unexpected() error {
+	return fmt.Errorf("unexpected %v", p.peek())
+}
+
+func (p *selectParser) expectOp(op string) error {
+	if !p.peek().isOp(op) {
+		return fmt.Errorf("expected '%v', found %v", op, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// parseAlias parses an optional "[AS] name".
+func (p *selectParser) parseAlias() (string, error) {
+	var t = p.peek()
+	if t.isKeyword("AS") {
+		p.pos++
+		t = p.next()
+		if t.kind != selectTokenIdent && t.kind != selectTokenQuotedIdent || t.kind == selectTokenIdent && selectKeywords[strings.ToUpper(t.text)] {
+			return "", fmt.Errorf("expected alias name after AS, found %v", t)
+		}
+		return t.text, nil
+	}
+	if t.kind == selectTokenQuotedIdent || t.kind == selectTokenIdent && !selectKeywords[strings.ToUpper(t.text)] {
+		p.pos++
+		return t.text, nil
+	}
+	return "", nil
+}
+
+func (p *selectParser) parseExpr() (selectExpr, error) {
+	return p.parseOr()
+}
+
+func (p *selectParser) parseOr() (selectExpr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("OR") {
+		p.pos++
+		var r selectExpr
+		if r, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		l = &selectBinary{op: "OR", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *selectParser) parseAnd() (selectExpr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("AND") {
+		p.pos++
+		var r selectExpr
+		if r, err = p.parseNot(); err != nil {
+			return nil, err
+		}
+		l = &selectBinary{op: "AND", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *selectParser) parseNot() (selectExpr, error) {
+	if p.peek().isKeyword("NOT") {
+		p.pos++
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &selectUnary{op: "NOT", x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *selectParser) parseComparison() (x selectExpr, err error) {
+	if x, err = p.parseAdditive(); err != nil {
+		return
+	}
+	var t = p.peek()
+	switch {
+	case t.kind == selectTokenOp && (t.text == "=" || t.text == "!=" || t.text == "<>" ||
+		t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		p.pos++
+		var r selectExpr
+		if r, err = p.parseAdditive(); err != nil {
+			return
+		}
+		return &selectBinary{op: t.text, l: x, r: r}, nil
+	case t.isKeyword("IS"):
+		p.pos++
+		var not bool
+		if p.peek().isKeyword("NOT") {
+			p.pos++
+			not = true
+		}
+		if !p.peek().isKeyword("NULL") && !p.peek().isKeyword("MISSING") {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		return &selectIsNull{x: x, not: not}, nil
+	}
+	var not bool
+	if t.isKeyword("NOT") {
+		not = true
+		p.pos++
+		t = p.peek()
+	}
+	switch {
+	case t.isKeyword("LIKE"):
+		p.pos++
+		var like = &selectLike{x: x, not: not}
+		if like.pattern, err = p.parseAdditive(); err != nil {
+			return
+		}
+		if p.peek().isKeyword("ESCAPE") {
+			p.pos++
+			var e = p.next()
+			if e.kind != selectTokenString || len([]rune(e.text)) != 1 {
+				return nil, fmt.Errorf("ESCAPE requires a single character string, found %v", e)
+			}
+			like.escape = e.text
+		}
+		if literal, ok := like.pattern.(*selectLiteral); ok {
+			if like.re, err = compileSelectLikePattern(selectFormatValue(literal.value), like.escape); err != nil {
+				return
+			}
+		}
+		return like, nil
+	case t.isKeyword("IN"):
+		p.pos++
+		if err = p.expectOp("("); err != nil {
+			return
+		}
+		var in = &selectIn{x: x, not: not}
+		for {
+			var item selectExpr
+			if item, err = p.parseExpr(); err != nil {
+				return
+			}
+			in.list = append(in.list, item)
+			if !p.peek().isOp(",") {
+				break
+			}
+			p.pos++
+		}
+		if err = p.expectOp(")"); err != nil {
+			return
+		}
+		return in, nil
+	case t.isKeyword("BETWEEN"):
+		p.pos++
+		var between = &selectBetween{x: x, not: not}
+		if between.low, err = p.parseAdditive(); err != nil {
+			return
+		}
+		if !p.peek().isKeyword("AND") {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		if between.high, err = p.parseAdditive(); err != nil {
+			return
+		}
+		return between, nil
+	}
+	if not {
+		return nil, p.unexpected()
+	}
+	return
+}
+
+func (p *selectParser) parseAdditive() (selectExpr, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOp("+") || p.peek().isOp("-") {
+		var op = p.next().text
+		var r selectExpr
+		if r, err = p.parseMultiplicative(); err != nil {
+			return nil, err
+		}
+		l = &selectBinary{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *selectParser) parseMultiplicative() (selectExpr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOp("*") || p.peek().isOp("/") || p.peek().isOp("%") {
+		var op = p.next().text
+		var r selectExpr
+		if r, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		l = &selectBinary{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *selectParser) parseUnary() (selectExpr, error) {
+	if p.peek().isOp("-") {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &selectUnary{op: "-", x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *selectParser) parsePrimary() (x selectExpr, err error) {
+	var t = p.next()
+	switch t.kind {
+	case selectTokenNumber:
+		var n float64
+		if n, err = strconv.ParseFloat(t.text, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %v", t)
+		}
+		return &selectLiteral{value: n}, nil
+	case selectTokenString:
+		return &selectLiteral{value: t.text}, nil
+	case selectTokenOp:
+		if t.text != "(" {
+			break
+		}
+		if x, err = p.parseExpr(); err != nil {
+			return
+		}
+		if err = p.expectOp(")"); err != nil {
+			return
+		}
+		return
+	case selectTokenQuotedIdent:
+		return p.parseColumn(t)
+	case selectTokenIdent:
+		var name = strings.ToUpper(t.text)
+		switch name {
+		case "TRUE", "FALSE":
+			return &selectLiteral{value: name == "TRUE"}, nil
+		case "NULL", "MISSING":
+			return &selectLiteral{}, nil
+		case "CAST":
+			return p.parseCast()
+		}
+		if selectKeywords[name] {
+			break
+		}
+		if p.peek().isOp("(") {
+			return p.parseFunc(name)
+		}
+		return p.parseColumn(t)
+	}
+	p.pos--
+	return nil, p.unexpected()
+}
+
+func (p *selectParser) parseColumn(t selectToken) (selectExpr, error) {
+	var column = &selectColumn{path: []string{t.text}}
+	for p.peek().isOp(".") {
+		p.pos++
+		var seg = p.next()
+		if seg.kind != selectTokenIdent && seg.kind != selectTokenQuotedIdent {
+			return nil, fmt.Errorf("expected column name after '.', found %v", seg)
+		}
+		column.path = append(column.path, seg.text)
+	}
+	p.columns = append(p.columns, column)
+	if !p.inAggregate {
+		p.bareColumn = true
+	}
+	return column, nil
+}
+
+func (p *selectParser) parseCast() (x selectExpr, err error) {
+	if err = p.expectOp("("); err != nil {
+		return
+	}
+	var cast = &selectCast{}
+	if cast.x, err = p.parseExpr(); err != nil {
+		return
+	}
+	if !p.peek().isKeyword("AS") {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	var t = p.next()
+	cast.typ = strings.ToUpper(t.text)
+	switch cast.typ {
+	case "INT", "INTEGER", "FLOAT", "DECIMAL", "NUMERIC", "STRING", "VARCHAR", "CHAR", "BOOL", "BOOLEAN":
+	default:
+		return nil, fmt.Errorf("unsupported CAST type %v", t)
+	}
+	if err = p.expectOp(")"); err != nil {
+		return
+	}
+	return cast, nil
+}
+
+func (p *selectParser) parseFunc(name string) (x selectExpr, err error) {
+	p.pos++ // skip '('
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if p.inAggregate {
+			return nil, fmt.Errorf("aggregate function %v can not be nested", name)
+		}
+		var agg = &selectAggregate{name: name}
+		if name == "COUNT" && p.peek().isOp("*") {
+			p.pos++
+		} else {
+			p.inAggregate = true
+			agg.arg, err = p.parseExpr()
+			p.inAggregate = false
+			if err != nil {
+				return
+			}
+		}
+		if err = p.expectOp(")"); err != nil {
+			return
+		}
+		p.aggregates = append(p.aggregates, agg)
+		return agg, nil
+	case "LOWER", "UPPER", "TRIM", "CHAR_LENGTH", "CHARACTER_LENGTH", "COALESCE":
+	default:
+		return nil, fmt.Errorf("unsupported function %v", name)
+	}
+	var fn = &selectFunc{name: name}
+	for !p.peek().isOp(")") {
+		var arg selectExpr
+		if arg, err = p.parseExpr(); err != nil {
+			return
+		}
+		fn.args = append(fn.args, arg)
+		if !p.peek().isOp(",") {
+			break
+		}
+		p.pos++
+	}
+	if err = p.expectOp(")"); err != nil {
+		return
+	}
+	if len(fn.args) == 0 || name != "COALESCE" && len(fn.args) != 1 {
+		return nil, fmt.Errorf("invalid number of arguments for function %v", name)
+	}
+	return fn, nil
+}
+
+// selectToNumber converts the value to a number. Values read from CSV objects are strings,
+// so strings containing numbers are converted as well.
+func selectToNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case json.Number:
+		n, err := val.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func selectIsNumber(v interface{}) bool {
+	switch v.(type) {
+	case float64, json.Number:
+		return true
+	}
+	return false
+}
+
+// selectCompare compares two values, it returns false if the values are not comparable.
+func selectCompare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if selectIsNumber(a) || selectIsNumber(b) {
+		an, aok := selectToNumber(a)
+		bn, bok := selectToNumber(b)
+		if !aok || !bok {
+			return 0, false
+		}
+		switch {
+		case an < bn:
+			return -1, true
+		case an > bn:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0, true
+			case !av:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+// selectFormatValue formats the value as the text written to CSV output.
+func selectFormatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+type selectTestEvent struct {
+	headers map[string]string
+	payload []byte
+}
+
+func decodeSelectEvents(t *testing.T, data []byte) (events []selectTestEvent) {
+	for len(data) > 0 {
+		if len(data) < 16 {
+			t.Fatalf("truncated event message: remain(%v)", len(data))
+		}
+		var total = binary.BigEndian.Uint32(data[0:4])
+		var headerLen = binary.BigEndian.Uint32(data[4:8])
+		if crc32.ChecksumIEEE(data[0:8]) != binary.BigEndian.Uint32(data[8:12]) {
+			t.Fatalf("prelude CRC mismatch")
+		}
+		if crc32.ChecksumIEEE(data[:total-4]) != binary.BigEndian.Uint32(data[total-4:total]) {
+			t.Fatalf("message CRC mismatch")
+		}
+		var event = selectTestEvent{headers: make(map[string]string)}
+		var headers = data[12 : 12+headerLen]
+		for len(headers) > 0 {
+			var nameLen = int(headers[0])
+			var name = string(headers[1 : 1+nameLen])
+			if headers[1+nameLen] != selectEventHeaderValueTypeString {
+				t.Fatalf("unexpected header value type %v", headers[1+nameLen])
+			}
+			var valueLen = int(binary.BigEndian.Uint16(headers[2+nameLen : 4+nameLen]))
+			event.headers[name] = string(headers[4+nameLen : 4+nameLen+valueLen])
+			headers = headers[4+nameLen+valueLen:]
+		}
+		event.payload = data[12+headerLen : total-4]
+		events = append(events, event)
+		data = data[total:]
+	}
+	return
+}
+
+func runSelectTest(t *testing.T, request string, data []byte) (records string, events []selectTestEvent) {
+	req, err := parseSelectRequest([]byte(request))
+	if err != nil {
+		t.Fatalf("parse request fail: err(%v)", err)
+	}
+	if ec := req.Validate(); ec != nil {
+		t.Fatalf("validate request fail: code(%v)", ec.ErrorCode)
+	}
+	query, err := parseSelectQuery(req.Expression)
+	if err != nil {
+		t.Fatalf("parse expression fail: expression(%v) err(%v)", req.Expression, err)
+	}
+	var out bytes.Buffer
+	var executor = &selectExecutor{query: query, request: req, writer: &out}
+	_ = executor.execute(bytes.NewReader(data))
+	events = decodeSelectEvents(t, out.Bytes())
+	for _, event := range events {
+		if event.headers[":event-type"] == "Records" {
+			records += string(event.payload)
+		}
+	}
+	return
+}
+
+func selectRequestXML(expression, input, output string) string {
+	return "<SelectObjectContentRequest><Expression>" + expression + "</Expression>" +
+		"<ExpressionType>SQL</ExpressionType><InputSerialization>" + input + "</InputSerialization>" +
+		"<OutputSerialization>" + output + "</OutputSerialization></SelectObjectContentRequest>"
+}
+
+const selectTestCSV = "name,age,city\n" +
+	"alice,30,beijing\n" +
+	"bob,25,\"shang,hai\"\n" +
+	"carol,41,shenzhen\n" +
+	"dave,,beijing\n"
+
+func TestSelectCSV(t *testing.T) {
+	var csvInput = "<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>"
+	var samples = []struct {
+		expression string
+		input      string
+		output     string
+		expected   string
+	}{
+		{expression: "SELECT * FROM S3Object", input: "<CSV><FileHeaderInfo>IGNORE</FileHeaderInfo></CSV>", output: "<CSV/>",
+			expected: "alice,30,beijing\nbob,25,\"shang,hai\"\ncarol,41,shenzhen\ndave,,beijing\n"},
+		{expression: "SELECT s._1 FROM S3Object s LIMIT 2", input: "<CSV/>", output: "<CSV/>",
+			expected: "name\nalice\n"},
+		{expression: "SELECT s.name, s.city FROM S3Object s WHERE s.age &gt; 28", input: csvInput, output: "<CSV/>",
+			expected: "alice,beijing\ncarol,shenzhen\n"},
+		{expression: "SELECT name FROM S3Object WHERE city = 'beijing' AND age IS NOT NULL", input: csvInput, output: "<CSV/>",
+			expected: "alice\ndave\n"},
+		{expression: "SELECT name FROM S3Object WHERE name LIKE '_a%' OR city IN ('shang,hai')", input: csvInput, output: "<CSV/>",
+			expected: "bob\ncarol\ndave\n"},
+		{expression: "SELECT name FROM S3Object WHERE CAST(age AS INT) BETWEEN 25 AND 30", input: csvInput, output: "<CSV/>",
+			expected: "alice\nbob\n"},
+		{expression: "SELECT UPPER(name) AS n, age + 1 FROM S3Object WHERE NOT (age &lt; 30)", input: csvInput, output: "<JSON/>",
+			expected: "{\"n\":\"ALICE\",\"_2\":31}\n{\"n\":\"CAROL\",\"_2\":42}\n"},
+		{expression: "SELECT COUNT(*), SUM(age), MAX(age), AVG(CAST(age AS FLOAT)) FROM S3Object", input: csvInput, output: "<CSV/>",
+			expected: "4,96,41,32\n"},
+		{expression: "SELECT city FROM S3Object WHERE name = 'bob'", input: csvInput, output: "<CSV><QuoteFields>ALWAYS</QuoteFields></CSV>",
+			expected: "\"shang,hai\"\n"},
+	}
+	for i, sample := range samples {
+		records, events := runSelectTest(t, selectRequestXML(sample.expression, sample.input, sample.output), []byte(selectTestCSV))
+		if records != sample.expected {
+			t.Fatalf("sample(%v) result mismatch: expression(%v) expect(%q) actual(%q)", i, sample.expression, sample.expected, records)
+		}
+		var last = events[len(events)-1]
+		if last.headers[":event-type"] != "End" || events[len(events)-2].headers[":event-type"] != "Stats" {
+			t.Fatalf("sample(%v) expect stats and end events at last", i)
+		}
+	}
+}
+
+func TestSelectJSON(t *testing.T) {
+	var data = `{"id": 1, "user": {"name": "alice"}, "tags": ["a"]}
+{"id": 2, "user": {"name": "bob"}}
+{"id": 12345678901234567890, "user": {"name": "carol"}}`
+	var samples = []struct {
+		expression string
+		output     string
+		expected   string
+	}{
+		{expression: "SELECT s.user.name FROM S3Object[*] s WHERE s.id &gt;= 2", output: "<JSON/>",
+			expected: "{\"name\":\"bob\"}\n{\"name\":\"carol\"}\n"},
+		{expression: "SELECT s.id FROM S3Object s WHERE s.tags IS NULL", output: "<CSV/>",
+			expected: "2\n12345678901234567890\n"},
+		{expression: "SELECT * FROM S3Object s WHERE s.id = 1", output: "<JSON/>",
+			expected: "{\"id\":1,\"tags\":[\"a\"],\"user\":{\"name\":\"alice\"}}\n"},
+		{expression: "SELECT COUNT(s.tags) AS c FROM S3Object s", output: "<JSON/>",
+			expected: "{\"c\":1}\n"},
+	}
+	for i, sample := range samples {
+		records, _ := runSelectTest(t, selectRequestXML(sample.expression, "<JSON><Type>LINES</Type></JSON>", sample.output), []byte(data))
+		if records != sample.expected {
+			t.Fatalf("sample(%v) result mismatch: expression(%v) expect(%q) actual(%q)", i, sample.expression, sample.expected, records)
+		}
+	}
+
+	// a JSON document with a top level array
+	records, _ := runSelectTest(t, selectRequestXML("SELECT s.a FROM S3Object s", "<JSON><Type>DOCUMENT</Type></JSON>", "<CSV/>"),
+		[]byte(`[{"a": "x"}, {"a": "y"}]`))
+	if records != "x\ny\n" {
+		t.Fatalf("result of document mismatch: actual(%q)", records)
+	}
+}
+
+func TestSelectCompressedAndErrors(t *testing.T) {
+	var buf bytes.Buffer
+	var gw = gzip.NewWriter(&buf)
+	_, _ = gw.Write([]byte(selectTestCSV))
+	_ = gw.Close()
+	records, events := runSelectTest(t, selectRequestXML("SELECT COUNT(*) FROM S3Object",
+		"<CompressionType>GZIP</CompressionType><CSV/>", "<CSV/>"), buf.Bytes())
+	if records != "5\n" {
+		t.Fatalf("result of gzip object mismatch: actual(%q)", records)
+	}
+	var stats = string(events[len(events)-2].payload)
+	if !strings.Contains(stats, "<BytesProcessed>82</BytesProcessed>") {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+
+	// errors occurred while scanning are reported by error event
+	_, events = runSelectTest(t, selectRequestXML("SELECT * FROM S3Object", "<JSON><Type>LINES</Type></JSON>", "<JSON/>"),
+		[]byte("{\"a\": 1}\n{\"a\": "))
+	var last = events[len(events)-1]
+	if last.headers[":message-type"] != "error" || last.headers[":error-code"] != "JSONParsingError" {
+		t.Fatalf("expect JSON parsing error event: headers(%v)", last.headers)
+	}
+	_, events = runSelectTest(t, selectRequestXML("SELECT CAST(name AS INT) FROM S3Object", "<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>", "<CSV/>"),
+		[]byte(selectTestCSV))
+	if last = events[len(events)-1]; last.headers[":error-code"] != "CastFailed" {
+		t.Fatalf("expect cast failed error event: headers(%v)", last.headers)
+	}
+}
+
+func TestParseSelectQueryInvalid(t *testing.T) {
+	var expressions = []string{
+		"",
+		"SELECT",
+		"SELECT * FROM",
+		"SELECT * FROM table",
+		"SELECT a FROM S3Object WHERE",
+		"SELECT a, COUNT(*) FROM S3Object",
+		"SELECT a FROM S3Object WHERE COUNT(*) > 1",
+		"SELECT COUNT(SUM(a)) FROM S3Object",
+		"SELECT a FROM S3Object LIMIT -1",
+		"SELECT 'a FROM S3Object",
+		"SELECT UNKNOWN(a) FROM S3Object",
+		"SELECT CAST(a AS DATE) FROM S3Object",
+		"SELECT a FROM S3Object WHERE a NOT 1",
+		"SELECT a FROM S3Object extra tokens",
+	}
+	for _, expression := range expressions {
+		if _, err := parseSelectQuery(expression); err == nil {
+			t.Fatalf("expect error for expression(%v)", expression)
+		}
+	}
+}
+
+func TestValidateSelectRequest(t *testing.T) {
+	var samples = []struct {
+		request string
+		code    *ErrorCode
+	}{
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CSV/>", "<JSON/>")},
+		{request: "<SelectObjectContentRequest><Expression>SELECT * FROM S3Object</Expression><ExpressionType>XPATH</ExpressionType>" +
+			"<InputSerialization><CSV/></InputSerialization><OutputSerialization><CSV/></OutputSerialization></SelectObjectContentRequest>",
+			code: InvalidExpressionType},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CSV/><JSON/>", "<CSV/>"), code: ObjectSerializationConflict},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CSV/>", ""), code: ObjectSerializationConflict},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<Parquet/>", "<CSV/>"), code: NotImplemented},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CompressionType>ZSTD</CompressionType><CSV/>", "<CSV/>"), code: InvalidCompressionFormat},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CSV><FileHeaderInfo>FIRST</FileHeaderInfo></CSV>", "<CSV/>"), code: InvalidFileHeaderInfo},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CSV><QuoteCharacter>'</QuoteCharacter></CSV>", "<CSV/>"), code: InvalidRequestParameter},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<JSON><Type>ARRAY</Type></JSON>", "<CSV/>"), code: InvalidJsonType},
+		{request: selectRequestXML("SELECT * FROM S3Object", "<CSV/>", "<CSV><QuoteFields>NEVER</QuoteFields></CSV>"), code: InvalidQuoteFields},
+	}
+	for i, sample := range samples {
+		req, err := parseSelectRequest([]byte(sample.request))
+		if err != nil {
+			t.Fatalf("sample(%v) parse request fail: err(%v)", i, err)
+		}
+		if code := req.Validate(); code != sample.code {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v) actual(%v)", i, sample.code, code)
+		}
+	}
+}
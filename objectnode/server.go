@@ -30,6 +30,7 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/master"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 	"github.com/gorilla/mux"
 )
 
@@ -79,6 +80,19 @@ const (
 
 	disabledActions               = "disabledActions"
 	configSignatureIgnoredActions = "signatureIgnoredActions"
+
+	// Object array configuration item, used to configure the targets which the event notifications of the
+	// buckets are published to. The type is one of "webhook", "kafka" and "nats". The kafka target produces
+	// the events through the kafka rest proxy at the endpoint, and the topic is the subject of the nats target.
+	// A target is referred by the ARN "arn:cfs:sqs:REGION:ID:TYPE" in the notification configurations.
+	// Example:
+	//		{
+	//			"notificationTargets": [
+	//				{"id": "1", "type": "kafka", "endpoint": "http://kafka-rest.chubao.io:8082", "topic": "events"},
+	//				{"id": "2", "type": "webhook", "endpoint": "http://hook.chubao.io/events"}
+	//			]
+	//		}
+	configNotificationTargets = "notificationTargets"
 )
 
 // Default of configuration value
@@ -107,6 +121,8 @@ type ObjectNode struct {
 	signatureIgnoredActions proto.Actions // signature ignored actions
 	disabledActions         proto.Actions // disabled actions
 
+	notifier *notifier // publishes the event notifications of the buckets, nil if no target is configured
+
 	encodedRegion []byte
 
 	control common.Control
@@ -172,6 +188,18 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 		}
 	}
 
+	// parse notification targets
+	var targets []NotificationTargetConfig
+	if targets, err = parseNotificationTargets(cfg.GetSlice(configNotificationTargets)); err != nil {
+		return
+	}
+	if len(targets) > 0 {
+		if o.notifier, err = newNotifier(targets); err != nil {
+			return
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v)", configNotificationTargets, targets)
+	}
+
 	// parse strict config
 	strict := cfg.GetBool(configStrict)
 	log.LogInfof("loadConfig: strict: %v", strict)
@@ -207,11 +235,17 @@ func handleStart(s common.Server, cfg *config.Config) (err error) {
 	o.updateRegion(ci.Cluster)
 	log.LogInfof("handleStart: get cluster information: region(%v)", o.region)
 
+	if err = tracing.Init(cfg.GetString("role"), cfg); err != nil {
+		log.LogErrorf("handleStart: init tracing fail: err(%v)", err)
+		return
+	}
+
 	// start rest api
 	if err = o.startMuxRestAPI(); err != nil {
 		log.LogInfof("handleStart: start rest api fail: err(%v)", err)
 		return
 	}
+	o.registerHealthChecks()
 
 	exporter.Init(cfg.GetString("role"), cfg)
 	exporter.RegistConsul(ci.Cluster, cfg.GetString("role"), cfg)
@@ -226,6 +260,10 @@ func handleShutdown(s common.Server) {
 		return
 	}
 	o.shutdownRestAPI()
+	if o.notifier != nil {
+		o.notifier.stop()
+	}
+	tracing.Stop()
 }
 
 func (o *ObjectNode) startMuxRestAPI() (err error) {
@@ -116,6 +116,17 @@ func (ss *StringSet) ContainsRegex(val string) bool {
 	return false
 }
 
+// ContainsWildcard reports whether val matches any value in the set, which may contain
+// the '*' (any sequence of characters) and '?' (any single character) wildcards.
+func (ss *StringSet) ContainsWildcard(val string) bool {
+	for k := range ss.values {
+		if wildcardMatch(k, val) {
+			return true
+		}
+	}
+	return false
+}
+
 func (ss *StringSet) ContainsWild(val string) bool {
 	if ss.Contains("*") {
 		return true
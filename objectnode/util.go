@@ -222,6 +222,36 @@ func patternMatch(pattern, key string) bool {
 	return matched
 }
 
+// wildcardMatch matches the whole key against the pattern in which '*' matches any
+// sequence of characters and '?' matches any single character.
+func wildcardMatch(pattern, key string) bool {
+	var p, k = 0, 0
+	var starP, starK = -1, 0
+	for k < len(key) {
+		if p < len(pattern) && (pattern[p] == '?' || pattern[p] == key[k]) {
+			p++
+			k++
+			continue
+		}
+		if p < len(pattern) && pattern[p] == '*' {
+			starP, starK = p, k
+			p++
+			continue
+		}
+		if starP >= 0 {
+			p = starP + 1
+			starK++
+			k = starK
+			continue
+		}
+		return false
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
 func wrapUnescapedQuot(src string) string {
 	return "\"" + src + "\""
 }
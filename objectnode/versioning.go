@@ -0,0 +1,123 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	VersioningStatusEnabled   = "Enabled"
+	VersioningStatusSuspended = "Suspended"
+
+	// NullVersionID is the version ID of the objects written while the versioning of the bucket
+	// is not enabled.
+	NullVersionID = "null"
+)
+
+var (
+	ErrInvalidVersioningStatus = errors.New("invalid versioning status")
+)
+
+// VersioningConfiguration is the versioning state of a bucket.
+// The noncurrent versions of the objects are kept as dentries of an invisible directory, which is
+// created when the versioning of the bucket is configured for the first time. Once configured, the
+// versioning of the bucket can only be suspended but never be disabled.
+type VersioningConfiguration struct {
+	XMLName      xml.Name `xml:"VersioningConfiguration" json:"-"`
+	Status       string   `xml:"Status,omitempty" json:"status"`
+	ArchiveInode uint64   `xml:"-" json:"archive"`
+}
+
+func (c *VersioningConfiguration) IsEnabled() bool {
+	return c != nil && c.Status == VersioningStatusEnabled
+}
+
+func (c *VersioningConfiguration) IsSuspended() bool {
+	return c != nil && c.Status == VersioningStatusSuspended
+}
+
+// IsConfigured returns true if the versioning of the bucket is enabled or suspended,
+// the noncurrent versions must be kept in both cases.
+func (c *VersioningConfiguration) IsConfigured() bool {
+	return c != nil && c.ArchiveInode != 0
+}
+
+type ObjectVersion struct {
+	XMLName      xml.Name     `xml:"Version"`
+	Key          string       `xml:"Key"`
+	VersionId    string       `xml:"VersionId"`
+	IsLatest     bool         `xml:"IsLatest"`
+	LastModified string       `xml:"LastModified"`
+	ETag         string       `xml:"ETag"`
+	Size         int64        `xml:"Size"`
+	StorageClass string       `xml:"StorageClass"`
+	Owner        *BucketOwner `xml:"Owner,omitempty"`
+}
+
+type DeleteMarkerEntry struct {
+	XMLName      xml.Name     `xml:"DeleteMarker"`
+	Key          string       `xml:"Key"`
+	VersionId    string       `xml:"VersionId"`
+	IsLatest     bool         `xml:"IsLatest"`
+	LastModified string       `xml:"LastModified"`
+	Owner        *BucketOwner `xml:"Owner,omitempty"`
+}
+
+type ListVersionsResult struct {
+	XMLName             xml.Name             `xml:"ListVersionsResult"`
+	Name                string               `xml:"Name"`
+	Prefix              string               `xml:"Prefix"`
+	KeyMarker           string               `xml:"KeyMarker"`
+	VersionIdMarker     string               `xml:"VersionIdMarker"`
+	NextKeyMarker       string               `xml:"NextKeyMarker,omitempty"`
+	NextVersionIdMarker string               `xml:"NextVersionIdMarker,omitempty"`
+	MaxKeys             int                  `xml:"MaxKeys"`
+	Delimiter           string               `xml:"Delimiter,omitempty"`
+	IsTruncated         bool                 `xml:"IsTruncated"`
+	Versions            []*ObjectVersion     `xml:"Version"`
+	DeleteMarkers       []*DeleteMarkerEntry `xml:"DeleteMarker"`
+	CommonPrefixes      []*CommonPrefix      `xml:"CommonPrefixes"`
+}
+
+func parseVersioningConfig(data []byte) (config *VersioningConfiguration, err error) {
+	config = &VersioningConfiguration{}
+	if err = xml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if config.Status != VersioningStatusEnabled && config.Status != VersioningStatusSuspended {
+		return nil, ErrInvalidVersioningStatus
+	}
+	return
+}
+
+func storeBucketVersioning(vol *Volume, config *VersioningConfiguration) (err error) {
+	var data []byte
+	if data, err = json.Marshal(config); err != nil {
+		return
+	}
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSVersioning, data)
+}
+
+// newVersionID generates the version ID of a new object version. The version IDs of the later
+// versions are smaller, so the dentries of the versions are sorted from the newest to the oldest.
+func newVersionID() string {
+	return fmt.Sprintf("%016x", uint64(math.MaxUint64)-uint64(time.Now().UnixNano()))
+}
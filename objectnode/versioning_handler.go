@@ -0,0 +1,222 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// VersioningConfigLimitSize is the max size of the versioning configuration in request body.
+	VersioningConfigLimitSize = 1024
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
+func (o *ObjectNode) getBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketVersioningHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	// The status is omitted if the versioning has never been configured.
+	var output = &VersioningConfiguration{}
+	if config := vol.versioningConfig(); config != nil {
+		output.Status = config.Status
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(output); err != nil {
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
+func (o *ObjectNode) putBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadAll(io.LimitReader(r.Body, VersioningConfigLimitSize+1)); err != nil && err != io.EOF {
+		log.LogErrorf("putBucketVersioningHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		return
+	}
+	if len(data) > VersioningConfigLimitSize {
+		err = nil
+		ec = MaxContentLength
+		return
+	}
+	var config *VersioningConfiguration
+	if config, err = parseVersioningConfig(data); err != nil {
+		log.LogWarnf("putBucketVersioningHandler: invalid versioning configuration: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		err = nil
+		ec = IllegalVersioningConfiguration
+		return
+	}
+	if _, err = vol.PutBucketVersioning(config.Status); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: put versioning fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	return
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
+func (o *ObjectNode) listObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
+		ec = NoSuchBucket
+		return
+	}
+
+	var query = r.URL.Query()
+	var option = &ListObjectVersionsOption{
+		Prefix:          query.Get(ParamPrefix),
+		Delimiter:       query.Get(ParamPartDelimiter),
+		KeyMarker:       query.Get(ParamKeyMarker),
+		VersionIDMarker: query.Get(ParamVersionIdMarker),
+		MaxKeys:         MaxKeys,
+	}
+	var encodingType = query.Get(ParamEncodingType)
+	if encodingType != "" && encodingType != "url" {
+		ec = InvalidArgument
+		return
+	}
+	if maxKeys := query.Get(ParamMaxKeys); maxKeys != "" {
+		var maxKeysInt uint64
+		if maxKeysInt, err = strconv.ParseUint(maxKeys, 10, 16); err != nil || maxKeysInt == 0 {
+			err = nil
+			ec = InvalidArgument
+			return
+		}
+		if maxKeysInt < MaxKeys {
+			option.MaxKeys = maxKeysInt
+		}
+	}
+
+	var result *ListObjectVersionsResult
+	if result, err = vol.ListObjectVersions(option); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: list versions fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+
+	var owner = NewBucketOwner(vol)
+	var output = &ListVersionsResult{
+		Name:                param.Bucket(),
+		Prefix:              option.Prefix,
+		KeyMarker:           option.KeyMarker,
+		VersionIdMarker:     option.VersionIDMarker,
+		NextKeyMarker:       encodeKey(result.NextKeyMarker, encodingType),
+		NextVersionIdMarker: result.NextVersionIDMarker,
+		MaxKeys:             int(option.MaxKeys),
+		Delimiter:           option.Delimiter,
+		IsTruncated:         result.Truncated,
+	}
+	for _, version := range result.Versions {
+		if version.DeleteMarker {
+			output.DeleteMarkers = append(output.DeleteMarkers, &DeleteMarkerEntry{
+				Key:          encodeKey(version.Path, encodingType),
+				VersionId:    version.VersionID,
+				IsLatest:     version.IsLatest,
+				LastModified: formatTimeISO(version.ModifyTime),
+				Owner:        owner,
+			})
+			continue
+		}
+		output.Versions = append(output.Versions, &ObjectVersion{
+			Key:          encodeKey(version.Path, encodingType),
+			VersionId:    version.VersionID,
+			IsLatest:     version.IsLatest,
+			LastModified: formatTimeISO(version.ModifyTime),
+			ETag:         wrapUnescapedQuot(version.ETag),
+			Size:         version.Size,
+			StorageClass: StorageClassStandard,
+			Owner:        owner,
+		})
+	}
+	for _, prefix := range result.CommonPrefixes {
+		output.CommonPrefixes = append(output.CommonPrefixes, &CommonPrefix{Prefix: encodeKey(prefix, encodingType)})
+	}
+
+	var data []byte
+	if data, err = MarshalXMLEntity(output); err != nil {
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseVersioningConfig(t *testing.T) {
+	var samples = []struct {
+		data   string
+		status string
+		valid  bool
+	}{
+		{data: `<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`, status: VersioningStatusEnabled, valid: true},
+		{data: `<VersioningConfiguration><Status>Suspended</Status></VersioningConfiguration>`, status: VersioningStatusSuspended, valid: true},
+		{data: `<VersioningConfiguration><Status>Disabled</Status></VersioningConfiguration>`},
+		{data: `<VersioningConfiguration></VersioningConfiguration>`},
+		{data: `<VersioningConfiguration>`},
+	}
+	for i, sample := range samples {
+		config, err := parseVersioningConfig([]byte(sample.data))
+		if !sample.valid {
+			if err == nil {
+				t.Fatalf("sample(%v) expect error but not", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("sample(%v) parse fail: err(%v)", i, err)
+		}
+		if config.Status != sample.status {
+			t.Fatalf("sample(%v) status mismatch: expect(%v) actual(%v)", i, sample.status, config.Status)
+		}
+	}
+}
+
+func TestNewVersionID(t *testing.T) {
+	var older = newVersionID()
+	time.Sleep(time.Millisecond)
+	var newer = newVersionID()
+	if len(older) != 16 || len(newer) != 16 {
+		t.Fatalf("version id length mismatch: older(%v) newer(%v)", older, newer)
+	}
+	if newer >= older {
+		t.Fatalf("newer version id not sorted before older: older(%v) newer(%v)", older, newer)
+	}
+}
+
+func TestArchiveKey(t *testing.T) {
+	var keys = []string{"a", "a/b", "a/b/c.txt", "b", "中文/文件"}
+	var prev string
+	for _, key := range keys {
+		var name = encodeArchiveKey(key)
+		decoded, err := decodeArchiveKey(name)
+		if err != nil {
+			t.Fatalf("decode archive key fail: key(%v) err(%v)", key, err)
+		}
+		if decoded != key {
+			t.Fatalf("archive key mismatch: expect(%v) actual(%v)", key, decoded)
+		}
+		if name <= prev {
+			t.Fatalf("archive key order not preserved: key(%v)", key)
+		}
+		prev = name
+	}
+	if _, err := decodeArchiveKey("not-hex"); err == nil {
+		t.Fatalf("expect decode error for invalid name")
+	}
+}
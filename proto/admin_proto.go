@@ -23,6 +23,7 @@ const (
 	AdminCreateDataPartition       = "/dataPartition/create"
 	AdminDecommissionDataPartition = "/dataPartition/decommission"
 	AdminDiagnoseDataPartition     = "/dataPartition/diagnose"
+	AdminResetDataPartitionRaft    = "/dataPartition/resetRaft"
 	AdminDeleteDataReplica         = "/dataReplica/delete"
 	AdminAddDataReplica            = "/dataReplica/add"
 	AdminDeleteVol                 = "/vol/delete"
@@ -39,6 +40,28 @@ const (
 	AdminListVols                  = "/vol/list"
 	AdminSetNodeInfo               = "/admin/setNodeInfo"
 	AdminGetNodeInfo               = "/admin/getNodeInfo"
+	AdminSetVolTierRule            = "/vol/tierRule/set"
+	AdminDeleteVolTierRule         = "/vol/tierRule/delete"
+	AdminGetVolTierPolicy          = "/vol/tierPolicy/get"
+	AdminSetVolWormRetention       = "/vol/worm/setRetention"
+	AdminOverrideVolWorm           = "/vol/worm/override"
+	AdminListTasks                 = "/admin/task/list"
+	AdminCancelTask                = "/admin/task/cancel"
+	AdminPauseTask                 = "/admin/task/pause"
+	AdminResumeTask                = "/admin/task/resume"
+	AdminSetRepairLink             = "/admin/repairLink/set"
+	AdminListRepairLinks           = "/admin/repairLink/list"
+	AdminSetCordon                 = "/admin/cordon/set"
+	AdminRemoveCordon              = "/admin/cordon/remove"
+	AdminListCordons               = "/admin/cordon/list"
+	AdminSetRebuildPolicy          = "/admin/rebuildPolicy/set"
+	AdminRemoveRebuildPolicy       = "/admin/rebuildPolicy/remove"
+	AdminListRebuildPolicies       = "/admin/rebuildPolicy/list"
+	AdminSetNFSExport              = "/admin/nfsExport/set"
+	AdminRemoveNFSExport           = "/admin/nfsExport/remove"
+	AdminListNFSExports            = "/admin/nfsExport/list"
+	AdminListAuditEvents           = "/admin/audit/list"
+	AdminBackupMeta                = "/admin/backupMeta"
 
 	//graphql master api
 	AdminClusterAPI = "/api/cluster"
@@ -68,12 +91,18 @@ const (
 	AddDataNode                    = "/dataNode/add"
 	DecommissionDataNode           = "/dataNode/decommission"
 	DecommissionDisk               = "/disk/decommission"
+	AdminRecoverDisk               = "/disk/recover"
+	AdminGetDiskRecovery           = "/disk/recoverProgress"
 	GetDataNode                    = "/dataNode/get"
 	AddMetaNode                    = "/metaNode/add"
 	DecommissionMetaNode           = "/metaNode/decommission"
 	GetMetaNode                    = "/metaNode/get"
 	AdminUpdateMetaNode            = "/metaNode/update"
 	AdminUpdateDataNode            = "/dataNode/update"
+	AdminSetDataNodeMaintenance    = "/dataNode/maintenance"
+	AdminSetMetaNodeMaintenance    = "/metaNode/maintenance"
+	AdminSetDataNodeThrottle       = "/dataNode/throttle/set"
+	AdminListDataNodeThrottle      = "/dataNode/throttle/list"
 	AdminGetInvalidNodes           = "/invalid/nodes"
 	AdminLoadMetaPartition         = "/metaPartition/load"
 	AdminDiagnoseMetaPartition     = "/metaPartition/diagnose"
@@ -89,6 +118,12 @@ const (
 	UpdateZone      = "/zone/update"
 	GetAllZones     = "/zone/list"
 
+	// node set management
+	ListNodeSets         = "/nodeSet/list"
+	GetNodeSet           = "/nodeSet/get"
+	CreateNodeSet        = "/nodeSet/create"
+	MigrateNodeToNodeSet = "/nodeSet/migrateNode"
+
 	//token
 	TokenGetURI    = "/token/get"
 	TokenAddURI    = "/token/add"
@@ -100,17 +135,22 @@ const (
 	ForceDelete         = "Force-Delete"
 
 	// APIs for user management
-	UserCreate          = "/user/create"
-	UserDelete          = "/user/delete"
-	UserUpdate          = "/user/update"
-	UserUpdatePolicy    = "/user/updatePolicy"
-	UserRemovePolicy    = "/user/removePolicy"
-	UserDeleteVolPolicy = "/user/deleteVolPolicy"
-	UserGetInfo         = "/user/info"
-	UserGetAKInfo       = "/user/akInfo"
-	UserTransferVol     = "/user/transferVol"
-	UserList            = "/user/list"
-	UsersOfVol          = "/vol/users"
+	UserCreate             = "/user/create"
+	UserDelete             = "/user/delete"
+	UserUpdate             = "/user/update"
+	UserUpdatePolicy       = "/user/updatePolicy"
+	UserRemovePolicy       = "/user/removePolicy"
+	UserSetPrefixPolicy    = "/user/setPrefixPolicy"
+	UserRemovePrefixPolicy = "/user/removePrefixPolicy"
+	UserDeleteVolPolicy    = "/user/deleteVolPolicy"
+	UserGetInfo            = "/user/info"
+	UserGetAKInfo          = "/user/akInfo"
+	UserTransferVol        = "/user/transferVol"
+	UserRotateKey          = "/user/rotateKey"
+	UserRetireKey          = "/user/retireKey"
+	UserAssumeRole         = "/user/assumeRole"
+	UserList               = "/user/list"
+	UsersOfVol             = "/vol/users"
 	//graphql api for header
 	HeadAuthorized  = "Authorization"
 	ParamAuthorized = "_authorization"
@@ -205,6 +245,13 @@ type RemoveDataPartitionRaftMemberRequest struct {
 	RemovePeer  Peer
 }
 
+// ResetDataPartitionRaftMemberRequest defines the request of resetting the raft members of a data partition
+// which has lost its quorum permanently.
+type ResetDataPartitionRaftMemberRequest struct {
+	PartitionId uint64
+	NewPeers    []Peer
+}
+
 // AddMetaPartitionRaftMemberRequest defines the request of add raftMember a meta partition.
 type AddMetaPartitionRaftMemberRequest struct {
 	PartitionId uint64
@@ -261,6 +308,100 @@ type LoadMetaPartitionMetricResponse struct {
 type HeartBeatRequest struct {
 	CurrTime   int64
 	MasterAddr string
+	VolQos     map[string]*VolQosBudget // only sent to the data nodes
+	// RepairBandwidth maps the source zones to the bandwidth in bytes per second that the data node
+	// may use to repair its replicas from the nodes of the zone, only sent to the data nodes.
+	RepairBandwidth map[string]uint64
+	// VerifyReadVols are the volumes whose reads are verified against the block checksums, only sent to the data nodes.
+	VerifyReadVols []string
+	// RebuildBandwidth is the bandwidth in bytes per second that the data node may use to repair and rebuild
+	// its replicas in total, zero means unlimited, only sent to the data nodes.
+	RebuildBandwidth uint64
+	// VolWorms are the retention policies of the volumes which have one, only sent to the data nodes.
+	VolWorms map[string]*VolWorm
+	// ClientThrottles are all the throttles of the clients on the data node, only sent to the data nodes.
+	ClientThrottles []*ClientThrottle
+}
+
+// VolWorm defines the retention policy of a volume that the data nodes enforce on the overwrites.
+type VolWorm struct {
+	RetentionSeconds int64
+	OverrideUntil    int64
+}
+
+// RepairLink defines the bandwidth budget of the repair traffic from the data nodes of one zone to another.
+// The budget is shared by all the data nodes of the destination zone.
+type RepairLink struct {
+	SrcZone      string
+	DstZone      string
+	MaxBandwidth uint64 // MB per second
+}
+
+// RebuildPolicy defines how the master rebuilds the replicas of the data partitions missing from the data nodes.
+// The policy without a zone is the default of the cluster, and the policy of a zone overrides it.
+type RebuildPolicy struct {
+	Zone         string
+	DelaySec     int64  // the replicas missing longer than the delay are rebuilt, zero disables the rebuild
+	MaxBandwidth uint64 // MB per second of each data node, zero means unlimited
+}
+
+// AuditEvent records an admin operation served by the master, which is persisted by raft.
+type AuditEvent struct {
+	ID           uint64
+	Time         int64             // unix seconds
+	Client       string            // the remote address of the request
+	ForwardedFor string            // the X-Forwarded-For header, set if the request is proxied by a follower
+	Op           string            // the path of the admin API
+	Target       string            // the volume, node or user operated
+	Params       map[string]string // the parameters with the secrets redacted
+	Code         int32
+	Msg          string // the error message if the operation failed
+}
+
+// MasterMetaBackup is the header of a backup of the master metadata. The backup is a gzip stream of json lines, the
+// header followed by the key-value pairs of the metadata store.
+type MasterMetaBackup struct {
+	ClusterName string
+	Applied     uint64 // the raft index applied to the store when it is backed up
+	Keys        uint64 // the number of the key-value pairs following the header
+	CreateTime  int64  // unix seconds
+}
+
+// Types of the targets a cordon applies to.
+const (
+	CordonTypeZone     = "zone"
+	CordonTypeDataNode = "dataNode"
+	CordonTypeMetaNode = "metaNode"
+)
+
+// NodeCordon keeps the new replicas away from a zone or a node during a planned maintenance window.
+// The existing replicas are not moved. A cordon with a non-zero Until expires by itself at that time.
+type NodeCordon struct {
+	Type   string
+	Name   string // zone name or node address
+	Reason string
+	Since  int64 // unix seconds
+	Until  int64 // unix seconds, zero means until removed
+}
+
+// IsActive returns whether the cordon still applies at the given time.
+func (cordon *NodeCordon) IsActive(now int64) bool {
+	return cordon.Until == 0 || now < cordon.Until
+}
+
+// NFSExport publishes a volume through the NFS gateways, the clients mount the volume by the export path.
+type NFSExport struct {
+	Path       string // export path, such as /ltptest
+	Volume     string
+	Clients    []string // CIDRs of the clients allowed to mount the export, empty allows every client
+	ReadOnly   bool
+	RootSquash bool // map the root user of the clients to the anonymous user
+}
+
+// VolQosBudget defines the share of the IOPS and bandwidth limits of a volume on one data node.
+type VolQosBudget struct {
+	MaxIOPS      uint64
+	MaxBandwidth uint64 // bytes per second
 }
 
 // PartitionReport defines the partition report.
@@ -276,6 +417,249 @@ type PartitionReport struct {
 	NeedCompare     bool
 }
 
+// NodeConfig defines a runtime tunable parameter of a data node or a meta node.
+type NodeConfig struct {
+	Key        string
+	Value      string
+	Overridden bool // set on the node at runtime, which takes precedence over the cluster-wide setting and the config file
+}
+
+// DataNodeDrainStatus is the progress of draining a data node before it exits.
+type DataNodeDrainStatus struct {
+	Phase          string
+	StartTime      int64
+	InflightWrites int64
+	RaftLeaders    int // data partitions of which the node is still the raft leader
+}
+
+// DiskInfo is the state of a disk of a data node.
+type DiskInfo struct {
+	Path          string     `json:"path"`
+	Total         uint64     `json:"total"`
+	Used          uint64     `json:"used"`
+	Available     uint64     `json:"available"`
+	Unallocated   uint64     `json:"unallocated"`
+	Allocated     uint64     `json:"allocated"`
+	Status        int        `json:"status"`
+	RestSize      uint64     `json:"restSize"`
+	Partitions    int        `json:"partitions"`
+	ReadErrCnt    uint64     `json:"readErrCnt"`
+	WriteErrCnt   uint64     `json:"writeErrCnt"`
+	MaxErrCnt     int        `json:"maxErrCnt"` // io errors to take the disk offline
+	OfflineReason string     `json:"offlineReason,omitempty"`
+	OfflineTime   int64      `json:"offlineTime,omitempty"`
+	ManualOffline bool       `json:"manualOffline,omitempty"` // taken offline by the operator
+	Smart         *DiskSmart `json:"smart,omitempty"`
+}
+
+// DiskSmart is the SMART health of a disk of a data node.
+type DiskSmart struct {
+	Device           string
+	UpdateTime       int64
+	Passed           bool // overall health self-assessment of the disk
+	PredictedFailure bool
+	Reason           string            `json:",omitempty"` // why the disk is predicted to fail
+	Attributes       []*SmartAttribute `json:",omitempty"`
+	Err              string            `json:",omitempty"` // the SMART attributes failed to be collected
+}
+
+// SmartAttribute is a SMART attribute of a disk.
+type SmartAttribute struct {
+	ID        int
+	Name      string
+	Value     int
+	Worst     int
+	Threshold int
+	Raw       int64
+}
+
+// SlowOp is an operation of a data node or a meta node which took longer than the slow threshold.
+type SlowOp struct {
+	Time        int64 // unix time when the operation finished
+	Op          string
+	PartitionID uint64
+	Disk        string `json:",omitempty"` // disk of the data partition, only on the data nodes
+	Peer        string
+	LatencyUs   int64
+	Result      string
+}
+
+// MetaNodeMemUsage is the memory usage of a meta node and the memory estimated to be taken by its meta partitions.
+type MetaNodeMemUsage struct {
+	Total             uint64  // the totalMem configured
+	Used              uint64  // resident memory of the meta node
+	UsageThreshold    float64 // ratio of Total above which no partition is created on the node, 0 if disabled
+	ReadOnlyThreshold float64 // ratio of Total above which the partitions are read-only
+	MemFull           bool
+	Partitions        []*MetaPartitionMemUsage
+}
+
+// MetaPartitionMemUsage is the memory estimated to be taken by a meta partition.
+type MetaPartitionMemUsage struct {
+	PartitionID uint64
+	VolName     string
+	InodeCnt    uint64
+	DentryCnt   uint64
+	MemUsed     uint64 // estimated by the items of the partition, updated periodically
+}
+
+// DiskRecoveryRequest registers the replaced disks of a data node. The replicas of the partitions
+// lost with the former disks are re-created on the same data node by the master.
+type DiskRecoveryRequest struct {
+	Addr         string
+	Disks        []string
+	PartitionIDs []uint64
+}
+
+// DiskRecoveryView defines the progress of recovering the replicas lost with the replaced disks of a data node.
+type DiskRecoveryView struct {
+	Addr       string
+	Disks      []string
+	StartTime  int64
+	Pending    []uint64          // waiting to be re-created on the data node
+	Recovering []uint64          // re-created and catching up the data from the other replicas
+	Recovered  []uint64          // caught up with the other replicas
+	Failed     map[uint64]string // the last error of re-creating the pending replicas, they are retried
+}
+
+// ClientThrottle defines the IO limits of one client on a data node, a zero limit means unlimited.
+type ClientThrottle struct {
+	ClientIP     string
+	MaxIOPS      uint64
+	MaxBandwidth uint64 // bytes per second
+}
+
+// ClientThrottleRequest defines the request to throttle a client on a data node, which is sent by the master
+// once the throttle is persisted, so that it takes effect before the next heartbeat.
+// The throttle is removed if both limits are zero. The data node replies with all the throttles it holds.
+type ClientThrottleRequest struct {
+	ClientIP     string
+	MaxIOPS      uint64
+	MaxBandwidth uint64 // bytes per second
+}
+
+// OrphanExtentScanRequest defines the request to scan the extents of a data partition which are not referenced
+// by any inode. The referenced extents are collected from the meta partitions of the volume before the request.
+type OrphanExtentScanRequest struct {
+	PartitionID uint64
+	Referenced  []uint64
+	Reclaim     bool // delete the orphaned extents which have been found for the grace period
+}
+
+// OrphanExtent defines an extent which is not referenced by any inode.
+type OrphanExtent struct {
+	ExtentID  uint64
+	Size      uint64
+	FirstSeen int64 // unix time when the extent is found orphaned for the first time
+}
+
+// OrphanExtentScanResponse defines the response to the orphan extent scan.
+type OrphanExtentScanResponse struct {
+	PartitionID uint64
+	GracePeriod int64 // in seconds
+	Candidates  []*OrphanExtent
+	Reclaimed   []*OrphanExtent
+}
+
+// DataPartitionWarmUpRequest defines the request to pre-read the extents of a data partition into the
+// page cache. The hottest extents recorded by the read heat statistics are warmed up if no extent is specified.
+type DataPartitionWarmUpRequest struct {
+	PartitionID uint64
+	ExtentIDs   []uint64
+	Count       int // number of the hottest extents to warm up, 0 for the default
+}
+
+// ExtentHeat defines the read heat of an extent, which is the decayed size of data read from it.
+type ExtentHeat struct {
+	ExtentID uint64
+	Heat     uint64
+}
+
+// DataPartitionWarmUpStatus defines the progress of warming up a data partition.
+type DataPartitionWarmUpStatus struct {
+	PartitionID   uint64
+	Running       bool
+	Extents       int
+	WarmedExtents int
+	WarmedSize    uint64
+	StartTime     int64
+	EndTime       int64
+	Err           string
+}
+
+// DataPartitionScrubStatus defines the progress of scrubbing a replica of a data partition, which verifies the
+// blocks of its extents against their crc.
+type DataPartitionScrubStatus struct {
+	PartitionID     uint64
+	Running         bool
+	Extents         int
+	ScrubbedExtents int
+	ScrubbedSize    uint64
+	CorruptBlocks   int // blocks mismatching their crc in the current or the last round
+	RepairedBlocks  int // corrupt blocks repaired from the other replicas
+	StartTime       int64
+	EndTime         int64
+	LastFinishTime  int64 // when the last complete round finished, the next one is due an interval later
+	Err             string
+}
+
+// DataPartitionVerifyReport defines the result of comparing the normal extents of the replicas of a data partition,
+// which is coordinated by the leader.
+type DataPartitionVerifyReport struct {
+	PartitionID uint64
+	Leader      string
+	Hosts       []string
+	Extents     int // normal extents found on any replica
+	Verified    int // extents consistent on all the replicas
+	Unverified  int // extents modified recently, of which the crc is not computed yet
+	Mismatches  []*ExtentMismatch
+	HostErrors  map[string]string // key: address of the replica failed to report its extents, value: the error
+	VerifyTime  int64
+}
+
+// ExtentMismatch defines an extent inconsistent among the replicas of a data partition.
+type ExtentMismatch struct {
+	ExtentID uint64
+	Reason   string // missing, size or crc
+	Replicas []*ExtentReplica
+}
+
+// ExtentReplica defines an extent on a replica.
+type ExtentReplica struct {
+	Addr       string
+	Missing    bool
+	Size       uint64
+	Crc        uint32
+	ModifyTime int64
+}
+
+// DataPartitionResync defines the re-sync of a replica of a data partition from the leader.
+type DataPartitionResync struct {
+	PartitionID     uint64
+	Leader          string
+	Replica         string
+	CreatedExtents  int // extents missing on the replica
+	ResetExtents    int // extents truncated on the replica and copied from the beginning
+	RepairedExtents int
+	StartTime       int64
+}
+
+// MetaPartitionExport is the header of an export of the metadata of a meta partition. The export is a gzip stream of
+// json lines, the header followed by the inodes, the dentries, the extended attributes and the multipart uploads.
+type MetaPartitionExport struct {
+	PartitionID uint64
+	VolName     string
+	Start       uint64
+	End         uint64
+	Cursor      uint64 // the max inode ID allocated
+	ApplyID     uint64
+	Inodes      uint64
+	Dentries    uint64
+	Extends     uint64
+	Multiparts  uint64
+	CreateTime  int64 // unix seconds
+}
+
 // DataNodeHeartbeatResponse defines the response to the data node heartbeat.
 type DataNodeHeartbeatResponse struct {
 	Total               uint64
@@ -290,6 +674,7 @@ type DataNodeHeartbeatResponse struct {
 	Status              uint8
 	Result              string
 	BadDisks            []string
+	FailingDisks        []string // disks predicted to fail by their SMART attributes
 }
 
 // MetaPartitionReport defines the meta partition report.
@@ -303,6 +688,8 @@ type MetaPartitionReport struct {
 	VolName     string
 	InodeCnt    uint64
 	DentryCnt   uint64
+	TrashSize   uint64 // total size of the soft-deleted files held by the partition
+	MemUsed     uint64 // memory estimated to be taken by the items of the partition
 }
 
 // MetaNodeHeartbeatResponse defines the response to the meta node heartbeat request.
@@ -311,6 +698,7 @@ type MetaNodeHeartbeatResponse struct {
 	Total                uint64
 	Used                 uint64
 	MetaPartitionReports []*MetaPartitionReport
+	MemFull              bool // the memory usage reaches the threshold of the meta node, no partition is created on it
 	Status               uint8
 	Result               string
 }
@@ -403,7 +791,10 @@ type DataPartitionResponse struct {
 
 // DataPartitionsView defines the view of a data partition
 type DataPartitionsView struct {
-	DataPartitions []*DataPartitionResponse
+	DataPartitions    []*DataPartitionResponse
+	ViewEpoch         uint64
+	IsDelta           bool     // only the partitions changed since the requested epoch are included
+	RemovedPartitions []uint64 // partitions removed since the requested epoch, only used in delta view
 }
 
 func NewDataPartitionsView() (dataPartitionsView *DataPartitionsView) {
@@ -492,12 +883,69 @@ type SimpleVolView struct {
 	NeedToLowerReplica bool
 	Authenticate       bool
 	CrossZone          bool
+	ZoneAntiAffinity   bool // the replicas of a partition are placed in different zones listed by ZoneName
+	StrictZones        bool // a partition is never placed with its replicas spanning fewer zones than required
+	RequiredZones      int  // the number of zones the replicas of a data partition are required to span
 	CreateTime         string
 	EnableToken        bool
 	Tokens             map[string]*Token `graphql:"-"`
 	Description        string
 	DpSelectorName     string
 	DpSelectorParm     string
+	MaxIOPS            uint64
+	MaxBandwidth       uint64 // MB per second
+	VerifyRead         bool
+	WormRetentionDays  uint32
+	WormOverrideUntil  int64
+	TrashDays          uint32
+	TrashSize          uint64
+	Atime              bool // the access time of the inodes is updated with the relatime policy
+	FileAudit          bool // the file operations are recorded to the audit log of the meta nodes
+}
+
+// UpdateVolRequest defines the settings of a volume to update. All the settings are sent to the master,
+// so a request is made from the current view of the volume and only the changed fields are modified.
+type UpdateVolRequest struct {
+	Name             string
+	AuthKey          string
+	Capacity         uint64 // GB
+	DpReplicaNum     int
+	MpReplicaNum     int // the replicas of the meta partitions are left unchanged if zero
+	FollowerRead     bool
+	Authenticate     bool
+	EnableToken      bool
+	ZoneName         string
+	MaxIOPS          uint64
+	MaxBandwidth     uint64 // MB per second
+	VerifyRead       bool
+	TrashDays        uint32
+	ZoneAntiAffinity bool
+	StrictZones      bool
+	Atime            bool
+	FileAudit        bool
+}
+
+// NewUpdateVolRequest returns the request which keeps the current settings of the volume.
+func NewUpdateVolRequest(vv *SimpleVolView, authKey string) *UpdateVolRequest {
+	return &UpdateVolRequest{
+		Name:             vv.Name,
+		AuthKey:          authKey,
+		Capacity:         vv.Capacity,
+		DpReplicaNum:     int(vv.DpReplicaNum),
+		MpReplicaNum:     int(vv.MpReplicaNum),
+		FollowerRead:     vv.FollowerRead,
+		Authenticate:     vv.Authenticate,
+		EnableToken:      vv.EnableToken,
+		ZoneName:         vv.ZoneName,
+		MaxIOPS:          vv.MaxIOPS,
+		MaxBandwidth:     vv.MaxBandwidth,
+		VerifyRead:       vv.VerifyRead,
+		TrashDays:        vv.TrashDays,
+		ZoneAntiAffinity: vv.ZoneAntiAffinity,
+		StrictZones:      vv.StrictZones,
+		Atime:            vv.Atime,
+		FileAudit:        vv.FileAudit,
+	}
 }
 
 // MasterAPIAccessResp defines the response for getting meta partition
@@ -540,7 +988,29 @@ type NodeSetView struct {
 	DataNodes   []NodeView
 }
 
+// NodeSetInfo defines the view of a node set. The replicas of a partition are placed on the nodes of one node set,
+// so the capacity of the node sets bounds the number of the nodes that a node shares the raft groups with.
+type NodeSetInfo struct {
+	ID          uint64
+	ZoneName    string
+	Capacity    int
+	DataNodeLen int
+	MetaNodeLen int
+	DataNodes   []NodeView `json:",omitempty"`
+	MetaNodes   []NodeView `json:",omitempty"`
+}
+
 // TopologyView provides the view of the topology view of the cluster
 type TopologyView struct {
 	Zones []*ZoneView
 }
+
+// VolCapacityView defines the result of resizing a volume. The new capacity is effective on all the clients
+// once Completed is returned, and there is nothing to be done on the nodes mounting the volume.
+type VolCapacityView struct {
+	Name          string
+	OldCapacity   uint64 // GB
+	Capacity      uint64 // GB
+	CapacityBytes uint64
+	Completed     bool
+}
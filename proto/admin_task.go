@@ -43,6 +43,20 @@ type AdminTask struct {
 	Response     interface{}
 }
 
+// AdminTaskView defines the view of an admin task which is waiting for the response of the node.
+type AdminTaskView struct {
+	ID          string
+	OpCode      uint8
+	OpName      string
+	NodeAddr    string
+	PartitionID uint64
+	Status      int8
+	CreateTime  int64
+	SendTime    int64
+	SendCount   uint8
+	Paused      bool
+}
+
 // ToString returns the string format of the task.
 func (t *AdminTask) ToString() (msg string) {
 	msg = fmt.Sprintf("ID[%v] Status[%d] LastSendTime[%v]  SendCount[%v] Request[%v] Response[%v]",
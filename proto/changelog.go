@@ -0,0 +1,62 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// Operations recorded in the changelog of the meta partitions.
+const (
+	ChangeCreate      = "create"      // a dentry is created
+	ChangeUnlink      = "unlink"      // a dentry is deleted
+	ChangeReplace     = "replace"     // a dentry is pointed to another inode
+	ChangeWrite       = "write"       // extents are added to or punched from the inode
+	ChangeTruncate    = "truncate"    // the inode is truncated
+	ChangeSetAttr     = "setattr"     // the mode, owner, flags or modification time of the inode are changed
+	ChangeSetXAttr    = "setxattr"    // an extended attribute of the inode is set
+	ChangeRemoveXAttr = "removexattr" // an extended attribute of the inode is removed
+	ChangeEvict       = "evict"       // the inode without links is marked to be deleted
+)
+
+// ChangeEvent is an event of the changelog of a meta partition. The sequence number is the raft index of the change,
+// so it is the same on all the replicas, and the events of a change, e.g. a batch unlink, share the sequence number.
+type ChangeEvent struct {
+	Seq      uint64 `json:"seq"`
+	Time     int64  `json:"t"`
+	Op       string `json:"op"`
+	Inode    uint64 `json:"ino"`
+	ParentID uint64 `json:"pino,omitempty"`
+	Name     string `json:"name,omitempty"` // name of the dentry, or key of the extended attribute
+	Type     uint32 `json:"type,omitempty"` // mode of the dentry
+	TxID     string `json:"tx,omitempty"`   // the transaction of the change, e.g. the unlink and the create of a rename
+}
+
+// ReadChangelogRequest reads the events of the changelog of a meta partition after the cursor.
+type ReadChangelogRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Cursor      uint64 `json:"cursor"`
+	Limit       int    `json:"limit"`
+}
+
+// ReadChangelogResponse replies the events in the order of the sequence numbers. The events older than the cursor
+// are dropped if Expired is set, and the changes since the cursor must be found by a listing instead.
+type ReadChangelogResponse struct {
+	Events  []*ChangeEvent `json:"events"`
+	Next    uint64         `json:"next"`    // the cursor to read the following events from
+	Oldest  uint64         `json:"oldest"`  // the oldest cursor the changelog keeps the events after
+	Applied uint64         `json:"applied"` // the latest change applied, the cursor to start from after a listing
+	Expired bool           `json:"expired"`
+}
+
+// ChangelogCursor is the cursor of the changelog of a volume, the cursor of every meta partition by its ID.
+type ChangelogCursor map[uint64]uint64
@@ -28,6 +28,7 @@ var (
 	ErrVolNotExists           = errors.New("vol not exists")
 	ErrMetaPartitionNotExists = errors.New("meta partition not exists")
 	ErrDataPartitionNotExists = errors.New("data partition not exists")
+	ErrDeadlineExceeded       = errors.New("deadline exceeded")
 	ErrDataNodeNotExists      = errors.New("data node not exists")
 	ErrMetaNodeNotExists      = errors.New("meta node not exists")
 	ErrDuplicateVol           = errors.New("duplicate vol")
@@ -81,6 +82,10 @@ var (
 	ErrInvalidAccessKey                = errors.New("invalid access key")
 	ErrInvalidSecretKey                = errors.New("invalid secret key")
 	ErrIsOwner                         = errors.New("user owns the volume")
+	ErrKeyRotationInProgress           = errors.New("key rotation in progress")
+	ErrTooManySessions                 = errors.New("too many session credentials")
+	ErrNodeSetNotExists                = errors.New("node set not exists")
+	ErrUnknownOpcode                   = errors.New("unknown opcode")
 )
 
 // http response error code and error message definitions
@@ -144,6 +149,9 @@ const (
 	ErrCodeInvalidAccessKey
 	ErrCodeInvalidSecretKey
 	ErrCodeIsOwner
+	ErrCodeKeyRotationInProgress
+	ErrCodeTooManySessions
+	ErrCodeNodeSetNotExists
 )
 
 // Err2CodeMap error map to code
@@ -205,6 +213,9 @@ var Err2CodeMap = map[error]int32{
 	ErrInvalidAccessKey:                ErrCodeInvalidAccessKey,
 	ErrInvalidSecretKey:                ErrCodeInvalidSecretKey,
 	ErrIsOwner:                         ErrCodeIsOwner,
+	ErrKeyRotationInProgress:           ErrCodeKeyRotationInProgress,
+	ErrTooManySessions:                 ErrCodeTooManySessions,
+	ErrNodeSetNotExists:                ErrCodeNodeSetNotExists,
 }
 
 func ParseErrorCode(code int32) error {
@@ -273,6 +284,9 @@ var code2ErrMap = map[int32]error{
 	ErrCodeInvalidAccessKey:                ErrInvalidAccessKey,
 	ErrCodeInvalidSecretKey:                ErrInvalidSecretKey,
 	ErrCodeIsOwner:                         ErrIsOwner,
+	ErrCodeKeyRotationInProgress:           ErrKeyRotationInProgress,
+	ErrCodeTooManySessions:                 ErrTooManySessions,
+	ErrCodeNodeSetNotExists:                ErrNodeSetNotExists,
 }
 
 type GeneralResp struct {
@@ -214,3 +214,10 @@ type TinyExtentDeleteRecord struct {
 	Size         uint32
 	CRC          uint32
 }
+
+// SealExtentsRequest defines the request to seal the ranges of the extent keys on all the replicas, so that
+// the data of the immutable and the append-only files is not overwritten, or to unseal them.
+type SealExtentsRequest struct {
+	Unseal  bool
+	Extents []ExtentKey
+}
@@ -17,6 +17,7 @@ package proto
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,9 +26,101 @@ const (
 	RootIno = uint64(1)
 )
 
+const (
+	// TrashDirName is the directory under the root which keeps the soft-deleted files of the volume.
+	TrashDirName = ".Trash"
+
+	// The extended attributes of a soft-deleted file recording where and when it was deleted.
+	XAttrKeyTrashParent = "cfs.trash.parent"
+	XAttrKeyTrashName   = "cfs.trash.name"
+	XAttrKeyTrashTime   = "cfs.trash.time"
+
+	// XAttrKeyRemoveTime marks a directory detached from the tree, the subtree of which is removed
+	// by the meta nodes.
+	XAttrKeyRemoveTime = "cfs.remove.time"
+
+	// The extended attributes of a directory recording the recursive statistics of it, which are
+	// maintained by the meta nodes asynchronously.
+	XAttrKeyDirFiles   = "cfs.dir.rfiles"
+	XAttrKeyDirSubdirs = "cfs.dir.rsubdirs"
+	XAttrKeyDirBytes   = "cfs.dir.rbytes"
+)
+
+const trashEntrySeparator = "_"
+
+// TrashEntryName returns the name of the file in the trash, which is "<delete time>_<inode>".
+func TrashEntryName(inode uint64, deleteTime time.Time) string {
+	return fmt.Sprintf("%d%s%d", deleteTime.Unix(), trashEntrySeparator, inode)
+}
+
+// ParseTrashEntryName returns false if the name is not created by the trash,
+// such entries are neither listed nor purged.
+func ParseTrashEntryName(name string) (inode uint64, deleteTime time.Time, ok bool) {
+	parts := strings.Split(name, trashEntrySeparator)
+	if len(parts) != 2 {
+		return
+	}
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+	if inode, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return
+	}
+	return inode, time.Unix(sec, 0), true
+}
+
+// DirSummaryKeys are the extended attributes of the recursive statistics of a directory.
+var DirSummaryKeys = []string{XAttrKeyDirFiles, XAttrKeyDirSubdirs, XAttrKeyDirBytes}
+
+// DirSummary is the recursive statistics of a directory, which counts the files, the subdirectories
+// and the bytes of the files in the subtree of it.
+type DirSummary struct {
+	Files   uint64 `json:"files"`
+	Subdirs uint64 `json:"subdirs"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// Add adds the summary of a subdirectory.
+func (s *DirSummary) Add(sub *DirSummary) {
+	s.Files += sub.Files
+	s.Subdirs += sub.Subdirs
+	s.Bytes += sub.Bytes
+}
+
+// XAttrs returns the summary as extended attributes.
+func (s *DirSummary) XAttrs() map[string]string {
+	return map[string]string{
+		XAttrKeyDirFiles:   strconv.FormatUint(s.Files, 10),
+		XAttrKeyDirSubdirs: strconv.FormatUint(s.Subdirs, 10),
+		XAttrKeyDirBytes:   strconv.FormatUint(s.Bytes, 10),
+	}
+}
+
+// DirSummaryFromXAttrs parses the summary from extended attributes. It returns false if the summary
+// is not recorded.
+func DirSummaryFromXAttrs(xattrs map[string]string) (s *DirSummary, ok bool) {
+	s = new(DirSummary)
+	for key, field := range map[string]*uint64{
+		XAttrKeyDirFiles:   &s.Files,
+		XAttrKeyDirSubdirs: &s.Subdirs,
+		XAttrKeyDirBytes:   &s.Bytes,
+	} {
+		value, err := strconv.ParseUint(xattrs[key], 10, 64)
+		if err != nil {
+			return s, false
+		}
+		*field = value
+	}
+	return s, true
+}
+
 const (
 	FlagsSyncWrite int = 1 << iota
 	FlagsAppend
+	// FlagsDirectWrite makes the stream flush its packet buffer, including the written data, to the data nodes
+	// and sync the extent keys to the meta node before the write returns.
+	FlagsDirectWrite
 )
 
 // Mode returns the fileMode.
@@ -73,6 +166,7 @@ type InodeInfo struct {
 	CreateTime time.Time `json:"ct"`
 	AccessTime time.Time `json:"at"`
 	Target     []byte    `json:"tgt"`
+	Flags      uint32    `json:"flags"`
 
 	expiration int64
 }
@@ -289,15 +383,20 @@ type BatchInodeGetResponse struct {
 }
 
 // ReadDirRequest defines the request to read dir.
+// The entries are read from the marker in the order of names, at most Limit of them if it is not 0.
 type ReadDirRequest struct {
 	VolName     string `json:"vol"`
 	PartitionID uint64 `json:"pid"`
 	ParentID    uint64 `json:"pino"`
+	Marker      string `json:"marker"`
+	Limit       uint64 `json:"limit"`
 }
 
 // ReadDirResponse defines the response to the request of reading dir.
+// NextMarker is the name of the first entry which is not read, or empty if all the entries are read.
 type ReadDirResponse struct {
-	Children []Dentry `json:"children"`
+	Children   []Dentry `json:"children"`
+	NextMarker string   `json:"next"`
 }
 
 // BatchAppendExtentKeyRequest defines the request to append an extent key.
@@ -330,6 +429,15 @@ type TruncateRequest struct {
 	Size        uint64 `json:"sz"`
 }
 
+// PunchHoleRequest defines the request to remove a file range from the extents of the inode.
+type PunchHoleRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Inode       uint64 `json:"ino"`
+	Offset      uint64 `json:"off"`
+	Size        uint64 `json:"sz"`
+}
+
 // SetAttrRequest defines the request to set attribute.
 type SetAttrRequest struct {
 	VolName     string `json:"vol"`
@@ -340,6 +448,7 @@ type SetAttrRequest struct {
 	Gid         uint32 `json:"gid"`
 	ModifyTime  int64  `json:"mt"`
 	AccessTime  int64  `json:"at"`
+	Flags       uint32 `json:"flags"`
 	Valid       uint32 `json:"valid"`
 }
 
@@ -349,8 +458,34 @@ const (
 	AttrGid
 	AttrModifyTime
 	AttrAccessTime
+	// AttrRelatime sets the access time only if it is due with the relatime policy.
+	AttrRelatime
+	AttrFlags
 )
 
+// The flags of regular files like the ones set by chattr, which are enforced by the meta nodes.
+// The lowest bit is reserved for the delete mark of the meta nodes.
+const (
+	// InodeFlagImmutable refuses to modify, link, rename or delete the file, or change its attributes.
+	InodeFlagImmutable uint32 = 1 << (iota + 1)
+	// InodeFlagAppendOnly only allows to append data to the file, and refuses the other modifications
+	// like an immutable file.
+	InodeFlagAppendOnly
+
+	InodeFlagsMask = InodeFlagImmutable | InodeFlagAppendOnly
+)
+
+// RelatimeInterval is the interval in seconds the access time is updated at most once with the
+// relatime policy, unless the inode is modified after the last access.
+const RelatimeInterval = 24 * 60 * 60
+
+// RelatimeDue returns if the access time should be updated by an access at now with the relatime
+// policy, that is, the access time is not later than the modify or the create time, or it is older
+// than a day. The access time is never moved backwards.
+func RelatimeDue(atime, mtime, ctime, now int64) bool {
+	return now > atime && (atime <= mtime || atime <= ctime || now-atime >= RelatimeInterval)
+}
+
 // DeleteInodeRequest defines the request to delete an inode.
 type DeleteInodeRequest struct {
 	VolName     string `json:"vol"`
@@ -381,6 +516,139 @@ type SetXAttrRequest struct {
 	Value       string `json:"val"`
 }
 
+// Types of the advisory file locks.
+const (
+	FileLockRead uint8 = iota + 1
+	FileLockWrite
+	FileLockUnlock
+)
+
+// FileLockLease is the time for which a lock is kept by the meta node without being renewed by its session,
+// so that the locks of a crashed client are released.
+const FileLockLease = 30 * time.Second
+
+// FileLock defines an advisory lock of the byte range [Start, End] of an inode,
+// taken by flock(2) if Flock is set, or by fcntl(2) otherwise.
+type FileLock struct {
+	Session string `json:"sess"`  // identifies the mounted client
+	Owner   uint64 `json:"owner"` // the lock owner given by the kernel, which is unique in the session
+	Pid     uint32 `json:"pid"`
+	Start   uint64 `json:"start"`
+	End     uint64 `json:"end"`
+	Type    uint8  `json:"type"`
+	Flock   bool   `json:"flock"`
+}
+
+// Conflicts returns if the lock can not be held together with the other one.
+func (l *FileLock) Conflicts(other *FileLock) bool {
+	if l.Flock != other.Flock || l.SameOwner(other) {
+		return false
+	}
+	if l.End < other.Start || other.End < l.Start {
+		return false
+	}
+	return l.Type == FileLockWrite || other.Type == FileLockWrite
+}
+
+// SameOwner returns if the locks are taken by the same owner by the same way.
+func (l *FileLock) SameOwner(other *FileLock) bool {
+	return l.Session == other.Session && l.Owner == other.Owner && l.Flock == other.Flock
+}
+
+// SetLockRequest acquires or releases the lock, fails with OpExistErr if it conflicts with the locks of the other owners.
+type SetLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionId uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	Lock        FileLock `json:"lock"`
+}
+
+// GetLockRequest looks for a lock conflicting with the given one.
+type GetLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionId uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	Lock        FileLock `json:"lock"`
+}
+
+type GetLockResponse struct {
+	Lock *FileLock `json:"lock"` // nil if there is no conflicting lock
+}
+
+// RenewLockRequest extends the leases of all the locks held by the session in the partition.
+type RenewLockRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	Session     string `json:"sess"`
+}
+
+type RenewLockResponse struct {
+	Locks int `json:"locks"` // the number of the locks held by the session in the partition
+}
+
+// The states of a metadata transaction.
+const (
+	TxStatePrepared uint8 = iota + 1
+	TxStateCommitted
+	TxStateAborted
+)
+
+// The operations of the items of a metadata transaction. The dentry operations are applied in the
+// partition of the parent inode, and the inode operations in the partition of the inode.
+const (
+	TxOpCreateDentry uint8 = iota + 1
+	TxOpDeleteDentry
+	TxOpLinkInode
+	TxOpUnlinkInode
+	TxOpSetXAttr
+	TxOpRemoveXAttr
+)
+
+// TxItem defines an operation of a metadata transaction in a meta partition.
+type TxItem struct {
+	Op        uint8  `json:"op"`
+	ParentID  uint64 `json:"pino"`
+	Name      string `json:"name"`
+	Inode     uint64 `json:"ino"`
+	Type      uint32 `json:"type"`
+	Overwrite bool   `json:"ow"`   // the created dentry replaces the existing dentry of a regular file
+	OldInode  uint64 `json:"oino"` // the inode of the replaced dentry, filled by the meta node
+	Key       string `json:"key"`  // the key of the extend attribute
+	Value     string `json:"val"`  // the value of the extend attribute
+}
+
+// IsDentryOp returns if the item operates a dentry, otherwise it operates an inode.
+func (item *TxItem) IsDentryOp() bool {
+	return item.Op == TxOpCreateDentry || item.Op == TxOpDeleteDentry
+}
+
+// TxPrepareRequest defines the request to prepare the items of a transaction in a meta partition.
+// The primary partition decides the outcome of the transaction, so it records the other partitions
+// of the transaction to finish them in case the client fails.
+type TxPrepareRequest struct {
+	VolName      string    `json:"vol"`
+	PartitionID  uint64    `json:"pid"`
+	TxID         string    `json:"tx"`
+	PrimaryID    uint64    `json:"primary"`
+	Participants []uint64  `json:"parts"`
+	Items        []*TxItem `json:"items"`
+}
+
+// TxRequest defines the request to commit, abort, get or forget a transaction.
+type TxRequest struct {
+	VolName      string   `json:"vol"`
+	PartitionID  uint64   `json:"pid"`
+	TxID         string   `json:"tx"`
+	PrimaryID    uint64   `json:"primary"`
+	Participants []uint64 `json:"parts"`
+}
+
+// TxResponse defines the response of the transaction requests.
+type TxResponse struct {
+	State uint8     `json:"state"`
+	Items []*TxItem `json:"items"`
+}
+
 type GetXAttrRequest struct {
 	VolName     string `json:"vol"`
 	PartitionId uint64 `json:"pid"`
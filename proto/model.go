@@ -35,10 +35,13 @@ type MetaNodeInfo struct {
 	SelectCount               uint64
 	Carry                     float64
 	Threshold                 float32
+	MemFull                   bool // the memory usage reaches the threshold of the meta node
 	ReportTime                time.Time
 	MetaPartitionCount        int
 	NodeSetID                 uint64
 	PersistenceMetaPartitions []uint64
+	Maintenance               bool
+	MaintenanceSince          int64
 }
 
 // DataNode stores all the information about a data node
@@ -59,6 +62,9 @@ type DataNodeInfo struct {
 	NodeSetID                 uint64
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string
+	FailingDisks              []string
+	Maintenance               bool
+	MaintenanceSince          int64
 }
 
 // MetaPartition defines the structure of a meta partition
@@ -88,6 +94,7 @@ type MetaReplicaInfo struct {
 	ReportTime int64
 	Status     int8 // unavailable, readOnly, readWrite
 	IsLeader   bool
+	MemUsed    uint64 // memory estimated to be taken by the replica
 }
 
 // ClusterView provides the view of a cluster.
@@ -107,6 +114,7 @@ type ClusterView struct {
 	BadMetaPartitionIDs []BadPartitionView
 	MetaNodes           []NodeView
 	DataNodes           []NodeView
+	Cordons             []*NodeCordon
 }
 
 // NodeView provides the view of the data or meta node.
@@ -154,6 +162,8 @@ type VolStatInfo struct {
 	UsedSize    uint64
 	UsedRatio   string
 	EnableToken bool
+	TrashDays   uint32
+	Atime       bool
 }
 
 // DataPartition represents the structure of storing the file contents.
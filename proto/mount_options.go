@@ -47,6 +47,18 @@ const (
 	EnableXattr
 	NearRead
 	EnablePosixACL
+	WriteStreams
+	WriteStripeSize
+	MaxInflightPackets
+	PacketRetryLimit
+	ReadDeadline
+	IcacheSize
+	ReadCacheDir
+	ReadCacheSize
+	ReadAheadSize
+	EnableFileLock
+	ConnMux
+	DentryCacheTTL
 
 	MaxMountOption
 )
@@ -112,6 +124,18 @@ func InitMountOptions(opts []MountOption) {
 	opts[MaxCPUs] = MountOption{"maxcpus", "The maximum number of CPUs that can be executing", "", int64(-1)}
 	opts[EnableXattr] = MountOption{"enableXattr", "Enable xattr support", "", false}
 	opts[EnablePosixACL] = MountOption{"enablePosixACL", "enable posix ACL support", "", false}
+	opts[WriteStreams] = MountOption{"writeStreams", "Max extent handlers of a file that can write concurrently", "", int64(-1)}
+	opts[WriteStripeSize] = MountOption{"writeStripeSize", "Size in MB of the sequential data of a file written to an extent before writing the next one concurrently", "", int64(-1)}
+	opts[MaxInflightPackets] = MountOption{"maxInflightPackets", "Max in-flight packets of an extent handler", "", int64(-1)}
+	opts[PacketRetryLimit] = MountOption{"packetRetryLimit", "Max retry times of a failed packet", "", int64(-1)}
+	opts[ReadDeadline] = MountOption{"readDeadline", "Send read requests with deadlines", "", false}
+	opts[IcacheSize] = MountOption{"icacheSize", "Max Inodes in Inode Cache", "", int64(-1)}
+	opts[ReadCacheDir] = MountOption{"readCacheDir", "Local Read Cache Directory", "", ""}
+	opts[ReadCacheSize] = MountOption{"readCacheSize", "Local Read Cache Size in MB", "", int64(-1)}
+	opts[ReadAheadSize] = MountOption{"readAheadSize", "Max read-ahead window in MB of the sequential reads of a file, 0 disables read-ahead", "", int64(-1)}
+	opts[EnableFileLock] = MountOption{"enableFileLock", "Enable flock and fcntl locks across mounts", "", false}
+	opts[ConnMux] = MountOption{"connMux", "Multiplex requests over shared connections to meta and data nodes", "", false}
+	opts[DentryCacheTTL] = MountOption{"dentryCacheTTL", "Dentry Cache Expiration Time in seconds", "", int64(-1)}
 
 	for i := 0; i < MaxMountOption; i++ {
 		flag.StringVar(&opts[i].cmdlineValue, opts[i].keyword, "", opts[i].description)
@@ -209,36 +233,113 @@ func (opt *MountOption) GetInt64() int64 {
 }
 
 type MountOptions struct {
-	Config         *config.Config
-	MountPoint     string
-	Volname        string
-	Owner          string
-	Master         string
-	Logpath        string
-	Loglvl         string
-	Profport       string
-	IcacheTimeout  int64
-	LookupValid    int64
-	AttrValid      int64
-	ReadRate       int64
-	WriteRate      int64
-	EnSyncWrite    int64
-	AutoInvalData  int64
-	UmpDatadir     string
-	Rdonly         bool
-	WriteCache     bool
-	KeepCache      bool
-	FollowerRead   bool
-	Authenticate   bool
-	TicketMess     auth.TicketMess
-	TokenKey       string
-	AccessKey      string
-	SecretKey      string
-	DisableDcache  bool
-	SubDir         string
-	FsyncOnClose   bool
-	MaxCPUs        int64
-	EnableXattr    bool
-	NearRead       bool
-	EnablePosixACL bool
+	Config             *config.Config
+	MountPoint         string
+	Volname            string
+	Owner              string
+	Master             string
+	Logpath            string
+	Loglvl             string
+	Profport           string
+	IcacheTimeout      int64
+	LookupValid        int64
+	AttrValid          int64
+	ReadRate           int64
+	WriteRate          int64
+	EnSyncWrite        int64
+	AutoInvalData      int64
+	UmpDatadir         string
+	Rdonly             bool
+	WriteCache         bool
+	KeepCache          bool
+	FollowerRead       bool
+	Authenticate       bool
+	TicketMess         auth.TicketMess
+	TokenKey           string
+	AccessKey          string
+	SecretKey          string
+	DisableDcache      bool
+	SubDir             string
+	FsyncOnClose       bool
+	MaxCPUs            int64
+	EnableXattr        bool
+	NearRead           bool
+	EnablePosixACL     bool
+	WriteStreams       int64
+	WriteStripeSize    int64 // unit: MB
+	MaxInflightPackets int64
+	PacketRetryLimit   int64
+	ReadDeadline       bool
+	IcacheSize         int64
+	ReadCacheDir       string
+	ReadCacheSize      int64 // unit: MB
+	ReadAheadSize      int64 // unit: MB
+	EnableFileLock     bool
+	ConnMux            bool
+	DentryCacheTTL     int64 // unit: second
+}
+
+// The control paths of a fuse client to get and change its config at runtime, and to get its statistics.
+const (
+	ClientGetConfigPath = "/conf/get"
+	ClientSetConfigPath = "/conf/set"
+	ClientStatPath      = "/stat"
+)
+
+// The keys to change the config of a fuse client, same as the keywords of the mount options.
+const (
+	ClientConfigReadRate      = "readRate"
+	ClientConfigWriteRate     = "writeRate"
+	ClientConfigIcacheTimeout = "icacheTimeout"
+	ClientConfigIcacheSize    = "icacheSize"
+	ClientConfigLogLevel      = "logLevel"
+	ClientConfigFollowerRead  = "followerRead"
+)
+
+// ClientConfig is the part of the mount options of a fuse client that can be changed without remounting.
+type ClientConfig struct {
+	ReadRate      int64 // 0 means unlimited
+	WriteRate     int64 // 0 means unlimited
+	IcacheTimeout int64 // unit: second
+	IcacheSize    int64
+	LogLevel      string
+	FollowerRead  bool
+}
+
+// ClientStat is the statistics of the caches and the streams of a fuse client.
+type ClientStat struct {
+	InodeCache ClientInodeCacheStat
+	Streams    ClientStreamStat
+	ReadCache  ClientReadCacheStat
+}
+
+type ClientInodeCacheStat struct {
+	Inodes    int
+	MaxInodes int
+	Evictions uint64 // inodes evicted before they expire since the client is mounted
+}
+
+type ClientStreamStat struct {
+	OpenStreams     int   // files with streams, which are opened or not yet evicted
+	DirtyStreams    int   // streams with written data not yet flushed
+	DirtyHandlers   int   // extent handlers with written data not yet flushed
+	InflightPackets int64 // packets sent to the data nodes and not yet replied
+	Flushes         uint64
+	FlushErrors     uint64
+	FlushLatency    []ClientLatencyBucket
+	ReadAheadHits   uint64 // bytes of the reads served by the read-ahead
+}
+
+// ClientLatencyBucket counts the operations that take no longer than the bound, "+Inf" for the rest.
+type ClientLatencyBucket struct {
+	Bound string
+	Count uint64
+}
+
+type ClientReadCacheStat struct {
+	Enabled   bool
+	Size      int64
+	Capacity  int64
+	HitBytes  uint64
+	MissBytes uint64
 }
@@ -0,0 +1,552 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The multiplexed connections
+//
+// A client switches a new connection to the multiplexed mode by an OpMuxConn request. Once the server replies OK,
+// the requests of many streams are sent over the connection without waiting for the replies of each other, and the
+// server handles them concurrently. Each reply packet is written as a whole, while the replies of different
+// requests may arrive in any order, so the client routes the replies to the streams by the request IDs.
+// The servers unaware of OpMuxConn do not reply OK, in which case the client keeps using a connection per request.
+
+const (
+	// MuxMaxInflight is the max number of the requests of a multiplexed connection handled by a server at a time.
+	MuxMaxInflight = 1024
+
+	// MuxMaxArgLen is the max length of the arg of a packet received by a multiplexed connection.
+	MuxMaxArgLen = 64 * util.KB
+	// MuxMaxDataPacketSize bounds the packets received from the data nodes, which carry a block at most.
+	MuxMaxDataPacketSize = util.PacketHeaderSize + MuxMaxArgLen + util.BlockSize
+	// MuxMaxMetaPacketSize bounds the packets received from the meta nodes, whose replies such as the
+	// dentries of a large directory are not limited to a block.
+	MuxMaxMetaPacketSize = util.PacketHeaderSize + MuxMaxArgLen + 256*util.MB
+
+	muxConnectTimeout   = time.Second
+	muxRetryUnsupported = 10 * time.Minute // how long an address refusing the multiplexed connection is not tried
+)
+
+// ErrMuxNotSupported is returned by the MuxPool if the server does not support the multiplexed connections.
+var ErrMuxNotSupported = errors.New("multiplexed connection not supported")
+
+var errMuxStreamClosed = errors.New("multiplexed stream closed")
+
+type muxTimeoutError struct{}
+
+func (muxTimeoutError) Error() string   { return "i/o timeout" }
+func (muxTimeoutError) Timeout() bool   { return true }
+func (muxTimeoutError) Temporary() bool { return true }
+
+// packetLen returns the length of the packet starting with the header.
+func packetLen(header []byte) int {
+	opcode, resultCode := header[2], header[3]
+	size := binary.BigEndian.Uint32(header[9:13])
+	argLen := binary.BigEndian.Uint32(header[13:17])
+	if (opcode == OpRead || opcode == OpStreamRead || opcode == OpExtentRepairRead || opcode == OpStreamFollowerRead) &&
+		resultCode == OpInitResultCode {
+		size = 0
+	}
+	return util.PacketHeaderSize + int(argLen) + int(size)
+}
+
+func packetReqID(header []byte) int64 {
+	return int64(binary.BigEndian.Uint64(header[41:49]))
+}
+
+// MuxConn is a multiplexed connection of a client, shared by the streams the requests are sent with.
+type MuxConn struct {
+	sync.Mutex
+	conn          *net.TCPConn
+	writeMu       sync.Mutex
+	maxPacketSize int                  // the connection is failed if a longer packet is received
+	streams       map[int64]*MuxStream // the streams waiting for the replies, by the request IDs
+	err           error                // the connection is broken once set
+}
+
+// DialMuxConn connects to the address and switches the connection to the multiplexed mode, on which the
+// packets longer than maxPacketSize are refused. ErrMuxNotSupported is returned if the server refuses the
+// multiplexed mode.
+func DialMuxConn(addr string, timeout time.Duration, maxPacketSize int) (mc *MuxConn, err error) {
+	conn, err := util.DailTimeOut(addr, timeout)
+	if err != nil {
+		return
+	}
+	req := NewPacketReqID()
+	req.Opcode = OpMuxConn
+	if err = req.WriteToConn(conn); err != nil {
+		conn.Close()
+		return
+	}
+	// the servers unaware of OpMuxConn close the connection or never reply
+	reply := NewPacket()
+	if err = reply.ReadFromConn(conn, ReadDeadlineTime); err != nil || reply.ReqID != req.ReqID ||
+		reply.ResultCode != OpOk {
+		log.LogWarnf("DialMuxConn: addr(%v) reply(%v) err(%v)", addr, reply, err)
+		conn.Close()
+		return nil, ErrMuxNotSupported
+	}
+	conn.SetReadDeadline(time.Time{})
+	mc = &MuxConn{conn: conn, maxPacketSize: maxPacketSize, streams: make(map[int64]*MuxStream)}
+	go mc.receive()
+	return
+}
+
+// NewStream returns a new stream over the connection.
+func (mc *MuxConn) NewStream() *MuxStream {
+	return &MuxStream{mc: mc, notify: make(chan struct{}, 1)}
+}
+
+func (mc *MuxConn) receive() {
+	var (
+		r     = bufio.NewReaderSize(mc.conn, 64*util.KB)
+		reply []byte
+		err   error
+	)
+	for {
+		header := make([]byte, util.PacketHeaderSize)
+		if _, err = io.ReadFull(r, header); err != nil {
+			break
+		}
+		if header[0] != ProtoMagic {
+			err = syscall.EBADMSG
+			break
+		}
+		// a corrupted header must not make the client allocate gigabytes
+		length := packetLen(header)
+		if length > mc.maxPacketSize {
+			err = fmt.Errorf("packet length(%v) exceeds the limit(%v)", length, mc.maxPacketSize)
+			break
+		}
+		reply = make([]byte, length)
+		copy(reply, header)
+		if _, err = io.ReadFull(r, reply[util.PacketHeaderSize:]); err != nil {
+			break
+		}
+		mc.Lock()
+		s := mc.streams[packetReqID(header)]
+		mc.Unlock()
+		// the stream has given up the request if not found
+		if s != nil {
+			s.put(reply)
+		}
+	}
+	mc.fail(err)
+}
+
+func (mc *MuxConn) fail(err error) {
+	mc.Lock()
+	if mc.err != nil {
+		mc.Unlock()
+		return
+	}
+	log.LogWarnf("MuxConn: remote(%v) err(%v)", mc.conn.RemoteAddr(), err)
+	mc.err = err
+	streams := mc.streams
+	mc.streams = make(map[int64]*MuxStream)
+	mc.Unlock()
+	mc.conn.Close()
+	for _, s := range streams {
+		s.fail(err)
+	}
+}
+
+// Broken returns true if the connection can not be used any more.
+func (mc *MuxConn) Broken() bool {
+	mc.Lock()
+	defer mc.Unlock()
+	return mc.err != nil
+}
+
+// Close closes the connection, and fails the streams waiting for the replies.
+func (mc *MuxConn) Close() {
+	mc.fail(errMuxStreamClosed)
+}
+
+// send sends the requests of the stream. The requests are registered before sent, so that no reply is missed.
+func (mc *MuxConn) send(s *MuxStream, data []byte) (err error) {
+	mc.Lock()
+	if mc.err != nil {
+		err = mc.err
+		mc.Unlock()
+		return
+	}
+	for off := 0; off+util.PacketHeaderSize <= len(data); off += packetLen(data[off:]) {
+		reqID := packetReqID(data[off:])
+		mc.streams[reqID] = s
+		s.reqIDs = append(s.reqIDs, reqID)
+	}
+	mc.Unlock()
+
+	mc.writeMu.Lock()
+	mc.conn.SetWriteDeadline(time.Now().Add(WriteDeadlineTime * time.Second))
+	_, err = mc.conn.Write(data)
+	mc.writeMu.Unlock()
+	if err != nil {
+		mc.fail(err)
+	}
+	return
+}
+
+func (mc *MuxConn) unregister(s *MuxStream) {
+	mc.Lock()
+	for _, reqID := range s.reqIDs {
+		if mc.streams[reqID] == s {
+			delete(mc.streams, reqID)
+		}
+	}
+	mc.Unlock()
+}
+
+// MuxStream is a net.Conn the requests are sent with over a multiplexed connection, which is used by one goroutine
+// at a time like a connection of the ConnectPool. The requests written are sent together once the replies are read.
+type MuxStream struct {
+	mc     *MuxConn
+	wbuf   []byte
+	reqIDs []int64 // the requests registered to the connection
+
+	mu       sync.Mutex
+	replies  [][]byte // the replies received but not read yet
+	rbuf     []byte   // the unread part of the reply being read
+	err      error
+	deadline time.Time
+	notify   chan struct{}
+}
+
+func (s *MuxStream) put(reply []byte) {
+	s.mu.Lock()
+	s.replies = append(s.replies, reply)
+	s.mu.Unlock()
+	s.wakeup()
+}
+
+func (s *MuxStream) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.wakeup()
+}
+
+func (s *MuxStream) wakeup() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *MuxStream) Write(b []byte) (n int, err error) {
+	s.wbuf = append(s.wbuf, b...)
+	return len(b), nil
+}
+
+func (s *MuxStream) Read(b []byte) (n int, err error) {
+	if len(s.wbuf) > 0 {
+		err = s.mc.send(s, s.wbuf)
+		s.wbuf = s.wbuf[:0]
+		if err != nil {
+			return
+		}
+	}
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		s.mu.Lock()
+		if len(s.rbuf) == 0 && len(s.replies) > 0 {
+			s.rbuf = s.replies[0]
+			s.replies[0] = nil
+			s.replies = s.replies[1:]
+		}
+		if len(s.rbuf) > 0 {
+			n = copy(b, s.rbuf)
+			s.rbuf = s.rbuf[n:]
+			s.mu.Unlock()
+			return
+		}
+		if s.err != nil {
+			err = s.err
+			s.mu.Unlock()
+			return
+		}
+		deadline := s.deadline
+		s.mu.Unlock()
+
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, muxTimeoutError{}
+			}
+			if timer == nil {
+				timer = time.NewTimer(d)
+			} else {
+				timer.Reset(d)
+			}
+			expired = timer.C
+		}
+		select {
+		case <-s.notify:
+			if timer != nil && !timer.Stop() {
+				<-timer.C
+			}
+		case <-expired:
+			timer = nil
+			return 0, muxTimeoutError{}
+		}
+	}
+}
+
+// Close unregisters the requests of the stream, and the replies not read yet are dropped.
+func (s *MuxStream) Close() error {
+	s.mc.unregister(s)
+	s.wbuf, s.reqIDs = nil, nil
+	return nil
+}
+
+func (s *MuxStream) LocalAddr() net.Addr {
+	return s.mc.conn.LocalAddr()
+}
+
+func (s *MuxStream) RemoteAddr() net.Addr {
+	return s.mc.conn.RemoteAddr()
+}
+
+func (s *MuxStream) SetDeadline(t time.Time) error {
+	return s.SetReadDeadline(t)
+}
+
+func (s *MuxStream) SetReadDeadline(t time.Time) error {
+	s.mu.Lock()
+	s.deadline = t
+	s.mu.Unlock()
+	return nil
+}
+
+// SetWriteDeadline does nothing since the requests are sent by the reads.
+func (s *MuxStream) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// MuxPool keeps a multiplexed connection to every address.
+type MuxPool struct {
+	sync.Mutex
+	conns         map[string]*MuxConn
+	unsupported   map[string]time.Time // the addresses refusing the multiplexed connection, and when refused
+	maxPacketSize int
+}
+
+// NewMuxPool returns a pool whose connections refuse the packets longer than maxPacketSize, which is
+// MuxMaxDataPacketSize or MuxMaxMetaPacketSize by the nodes connected.
+func NewMuxPool(maxPacketSize int) *MuxPool {
+	return &MuxPool{
+		conns:         make(map[string]*MuxConn),
+		unsupported:   make(map[string]time.Time),
+		maxPacketSize: maxPacketSize,
+	}
+}
+
+// GetStream returns a new stream over the multiplexed connection to the address, which is connected if none.
+// ErrMuxNotSupported is returned if the server does not support the multiplexed connections, in which case the
+// caller falls back to a connection of its own.
+func (p *MuxPool) GetStream(addr string) (s *MuxStream, err error) {
+	p.Lock()
+	if refused, ok := p.unsupported[addr]; ok {
+		if time.Since(refused) < muxRetryUnsupported {
+			p.Unlock()
+			return nil, ErrMuxNotSupported
+		}
+		delete(p.unsupported, addr)
+	}
+	mc := p.conns[addr]
+	p.Unlock()
+	if mc != nil && !mc.Broken() {
+		return mc.NewStream(), nil
+	}
+
+	if mc, err = DialMuxConn(addr, muxConnectTimeout, p.maxPacketSize); err != nil {
+		if err == ErrMuxNotSupported {
+			p.Lock()
+			p.unsupported[addr] = time.Now()
+			p.Unlock()
+		}
+		return
+	}
+	p.Lock()
+	// another connection may be made at the same time
+	if exist := p.conns[addr]; exist != nil && exist != mc && !exist.Broken() {
+		p.Unlock()
+		mc.Close()
+		return exist.NewStream(), nil
+	}
+	p.conns[addr] = mc
+	p.Unlock()
+	return mc.NewStream(), nil
+}
+
+// PutConnect releases the connection got from the pool, which is a stream of the MuxPool or a connection of the
+// ConnectPool.
+func (p *MuxPool) PutConnect(cp *util.ConnectPool, conn net.Conn, forceClose bool) {
+	if s, ok := conn.(*MuxStream); ok {
+		s.Close()
+		return
+	}
+	cp.PutConnect(conn.(*net.TCPConn), forceClose)
+}
+
+// GetConnect returns a stream to the address if the multiplexed connections are enabled by a non-nil pool and
+// supported by the server, otherwise a connection of the ConnectPool.
+func (p *MuxPool) GetConnect(cp *util.ConnectPool, addr string) (net.Conn, error) {
+	if p != nil {
+		s, err := p.GetStream(addr)
+		if err == nil {
+			return s, nil
+		}
+		if err != ErrMuxNotSupported {
+			return nil, err
+		}
+	}
+	conn, err := cp.GetConnect(addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close closes all the connections.
+func (p *MuxPool) Close() {
+	p.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*MuxConn)
+	p.Unlock()
+	for _, mc := range conns {
+		mc.Close()
+	}
+}
+
+// MuxReplyConn is the connection a request of a multiplexed connection is handled with by the server. The replies
+// written by the handler are assembled into packets, and each packet is written to the connection as soon as it is
+// complete, so that the packets are not interleaved with the replies of the other requests, and the replies of a
+// long read are sent while the data is being read.
+type MuxReplyConn struct {
+	net.Conn
+	writeMu *sync.Mutex // serializes the writes to the connection
+	mu      sync.Mutex
+	buf     []byte // the packet being assembled
+}
+
+func NewMuxReplyConn(conn net.Conn, writeMu *sync.Mutex) *MuxReplyConn {
+	return &MuxReplyConn{Conn: conn, writeMu: writeMu}
+}
+
+// Write writes the complete packets to the connection, and keeps the rest until the packet is completed by the
+// following writes.
+func (c *MuxReplyConn) Write(b []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.buf) == 0 {
+		// write the complete packets without copying them
+		var size int
+		if size, err = c.writePackets(b); err != nil {
+			return size, err
+		}
+		c.buf = append(c.buf, b[size:]...)
+		return len(b), nil
+	}
+	c.buf = append(c.buf, b...)
+	size, err := c.writePackets(c.buf)
+	if err != nil {
+		c.buf = nil
+		return
+	}
+	c.buf = c.buf[:copy(c.buf, c.buf[size:])]
+	return len(b), nil
+}
+
+// writePackets writes the complete packets at the beginning of b, and returns the size written.
+func (c *MuxReplyConn) writePackets(b []byte) (size int, err error) {
+	for len(b)-size >= util.PacketHeaderSize {
+		if b[size] != ProtoMagic {
+			return size, syscall.EBADMSG
+		}
+		n := packetLen(b[size:])
+		if len(b)-size < n {
+			break
+		}
+		if _, err = c.write(b[size : size+n]); err != nil {
+			return
+		}
+		size += n
+	}
+	return
+}
+
+// Flush checks that the handler has written the complete packets, the incomplete one is dropped since it would
+// corrupt the replies of the other requests.
+func (c *MuxReplyConn) Flush() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.buf) > 0 {
+		err = io.ErrShortWrite
+		log.LogWarnf("MuxReplyConn: remote(%v) incomplete reply of %v bytes dropped", c.Conn.RemoteAddr(), len(c.buf))
+		c.buf = nil
+	}
+	return
+}
+
+func (c *MuxReplyConn) write(b []byte) (n int, err error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.Conn.SetWriteDeadline(time.Now().Add(WriteDeadlineTime * time.Second))
+	return c.Conn.Write(b)
+}
+
+// SetWriteDeadline does nothing since the deadline of the shared connection is set by the writes.
+func (c *MuxReplyConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// SetDeadline does nothing since the requests are read from the shared connection by the server loop.
+func (c *MuxReplyConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline does nothing, see SetDeadline.
+func (c *MuxReplyConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// Close does nothing since the shared connection is closed by the server loop.
+func (c *MuxReplyConn) Close() error {
+	return nil
+}
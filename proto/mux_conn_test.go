@@ -0,0 +1,376 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// recordConn records the writes to the connection.
+type recordConn struct {
+	net.Conn
+	sync.Mutex
+	writes [][]byte
+}
+
+func (c *recordConn) Write(b []byte) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.writes = append(c.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (c *recordConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *recordConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func marshalTestPacket(t *testing.T, p *Packet) []byte {
+	conn := &recordConn{}
+	if err := p.WriteToConn(conn); err != nil {
+		t.Fatalf("write packet: %v", err)
+	}
+	return bytes.Join(conn.writes, nil)
+}
+
+func newTestPacket(data string) *Packet {
+	p := NewPacketReqID()
+	p.Opcode = OpMetaLookup
+	p.Arg = []byte("arg")
+	p.ArgLen = uint32(len(p.Arg))
+	p.Data = []byte(data)
+	p.Size = uint32(len(p.Data))
+	return p
+}
+
+func TestMuxReplyConnWritesPackets(t *testing.T) {
+	var writeMu sync.Mutex
+	conn := &recordConn{}
+	rc := NewMuxReplyConn(conn, &writeMu)
+
+	// a packet written in pieces is written to the connection once complete
+	first := marshalTestPacket(t, newTestPacket("first"))
+	if err := newTestPacket("first").WriteToConn(rc); err != nil {
+		t.Fatalf("write first: %v", err)
+	}
+	if len(conn.writes) != 1 || len(conn.writes[0]) != len(first) {
+		t.Fatalf("packet should be written at once, writes(%v)", len(conn.writes))
+	}
+	conn.writes = nil
+	for i := 0; i < len(first); i += 7 {
+		end := i + 7
+		if end > len(first) {
+			end = len(first)
+		}
+		if len(conn.writes) != 0 {
+			t.Fatalf("incomplete packet should not be written, writes(%v)", len(conn.writes))
+		}
+		rc.Write(first[i:end])
+	}
+	if len(conn.writes) != 1 || !bytes.Equal(conn.writes[0], first) {
+		t.Fatalf("packet should be written once completed, writes(%v)", len(conn.writes))
+	}
+
+	// the packets written together are written one by one
+	conn.writes = nil
+	second, third := marshalTestPacket(t, newTestPacket("second")), marshalTestPacket(t, newTestPacket("third"))
+	rc.Write(append(append([]byte(nil), second...), third[:util.PacketHeaderSize]...))
+	if len(conn.writes) != 1 || !bytes.Equal(conn.writes[0], second) {
+		t.Fatalf("complete packet should be written, writes(%v)", len(conn.writes))
+	}
+	rc.Write(third[util.PacketHeaderSize:])
+	if len(conn.writes) != 2 || !bytes.Equal(conn.writes[1], third) {
+		t.Fatalf("packet completed by the following write should be written, writes(%v)", len(conn.writes))
+	}
+	if err := rc.Flush(); err != nil {
+		t.Fatalf("flush complete packets: %v", err)
+	}
+
+	// the incomplete packet is dropped
+	conn.writes = nil
+	rc.Write(first[:len(first)-1])
+	if err := rc.Flush(); err != io.ErrShortWrite || len(conn.writes) != 0 {
+		t.Fatalf("incomplete packet should be dropped, err(%v) writes(%v)", err, len(conn.writes))
+	}
+	bad := append([]byte(nil), first...)
+	bad[0] = 0
+	if _, err := rc.Write(bad); err != syscall.EBADMSG {
+		t.Fatalf("packet without the magic should be refused, err(%v)", err)
+	}
+}
+
+// startMuxServer serves the multiplexed connections with the handler, or closes the connections if the
+// multiplexed mode is not supported.
+func startMuxServer(t *testing.T, supported bool, handler func(req *Packet, conn net.Conn)) (addr string, stop func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+			go serveTestMuxConn(conn, supported, handler)
+		}
+	}()
+	stop = func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	return ln.Addr().String(), stop
+}
+
+func serveTestMuxConn(conn net.Conn, supported bool, handler func(req *Packet, conn net.Conn)) {
+	defer conn.Close()
+	req := NewPacket()
+	if err := req.ReadFromConn(conn, NoReadDeadlineTime); err != nil || req.Opcode != OpMuxConn || !supported {
+		return
+	}
+	req.PacketOkReply()
+	if err := req.WriteToConn(conn); err != nil {
+		return
+	}
+	var writeMu sync.Mutex
+	for {
+		req := NewPacket()
+		if err := req.ReadFromConn(conn, NoReadDeadlineTime); err != nil {
+			return
+		}
+		go func() {
+			rc := NewMuxReplyConn(conn, &writeMu)
+			handler(req, rc)
+			rc.Flush()
+		}()
+	}
+}
+
+// echoReply replies the data of the request after a delay varying with the data, so the replies are out of order.
+func echoReply(req *Packet, conn net.Conn) {
+	time.Sleep(time.Duration(len(req.Data)%5) * 10 * time.Millisecond)
+	req.PacketOkWithBody(req.Data)
+	req.WriteToConn(conn)
+}
+
+func TestMuxConnInterleave(t *testing.T) {
+	addr, stop := startMuxServer(t, true, echoReply)
+	defer stop()
+	mc, err := DialMuxConn(addr, time.Second, MuxMaxDataPacketSize)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer mc.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := mc.NewStream()
+			defer s.Close()
+			// two requests are sent together and replied in any order
+			reqs := map[int64]string{}
+			for j := 0; j < 2; j++ {
+				req := newTestPacket(fmt.Sprintf("stream %v request %v%v", i, j, bytes.Repeat([]byte("."), i+j)))
+				reqs[req.ReqID] = string(req.Data)
+				if err := req.WriteToConn(s); err != nil {
+					errs <- err
+					return
+				}
+			}
+			for n := len(reqs); n > 0; n-- {
+				reply := NewPacket()
+				if err := reply.ReadFromConn(s, 5); err != nil {
+					errs <- err
+					return
+				}
+				if data, ok := reqs[reply.ReqID]; !ok || data != string(reply.Data) {
+					errs <- fmt.Errorf("stream %v got reply(%v) data(%s)", i, reply.ReqID, reply.Data)
+					return
+				}
+				delete(reqs, reply.ReqID)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("stream failed: %v", err)
+	}
+}
+
+func TestMuxStreamClose(t *testing.T) {
+	release := make(chan struct{})
+	addr, stop := startMuxServer(t, true, func(req *Packet, conn net.Conn) {
+		if string(req.Data) == "wait" {
+			<-release
+		}
+		echoReply(req, conn)
+	})
+	defer stop()
+	mc, err := DialMuxConn(addr, time.Second, MuxMaxDataPacketSize)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer mc.Close()
+
+	s := mc.NewStream()
+	newTestPacket("wait").WriteToConn(s)
+	s.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err = s.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("read should time out")
+	} else if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("read should time out, err(%v)", err)
+	}
+	// the reply of the closed stream is dropped
+	s.Close()
+	mc.Lock()
+	registered := len(mc.streams)
+	mc.Unlock()
+	if registered != 0 {
+		t.Fatalf("requests of the closed stream should be unregistered, registered(%v)", registered)
+	}
+	close(release)
+
+	s = mc.NewStream()
+	defer s.Close()
+	req := newTestPacket("after close")
+	req.WriteToConn(s)
+	reply := NewPacket()
+	if err = reply.ReadFromConn(s, 5); err != nil || reply.ReqID != req.ReqID {
+		t.Fatalf("new stream should get its own reply, reply(%v) err(%v)", reply, err)
+	}
+}
+
+func TestMuxConnBroken(t *testing.T) {
+	addr, stop := startMuxServer(t, true, func(req *Packet, conn net.Conn) {})
+	mc, err := DialMuxConn(addr, time.Second, MuxMaxDataPacketSize)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	s := mc.NewStream()
+	newTestPacket("never replied").WriteToConn(s)
+	result := make(chan error, 1)
+	go func() {
+		_, err := s.Read(make([]byte, 1))
+		result <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	select {
+	case err = <-result:
+		if err == nil {
+			t.Fatalf("read should fail once the connection is broken")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read is not woken up by the broken connection")
+	}
+	if !mc.Broken() {
+		t.Fatalf("connection should be broken")
+	}
+	s = mc.NewStream()
+	newTestPacket("after broken").WriteToConn(s)
+	if _, err = s.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("request should fail on the broken connection")
+	}
+}
+
+func TestMuxConnPacketTooLarge(t *testing.T) {
+	addr, stop := startMuxServer(t, true, echoReply)
+	defer stop()
+	mc, err := DialMuxConn(addr, time.Second, util.PacketHeaderSize+64)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer mc.Close()
+
+	s := mc.NewStream()
+	defer s.Close()
+	newTestPacket(string(bytes.Repeat([]byte("."), 128))).WriteToConn(s)
+	reply := NewPacket()
+	if err = reply.ReadFromConn(s, 5); err == nil {
+		t.Fatalf("reply longer than the limit should be refused")
+	}
+	if !mc.Broken() {
+		t.Fatalf("connection should be broken by the reply longer than the limit")
+	}
+}
+
+func TestMuxPool(t *testing.T) {
+	addr, stop := startMuxServer(t, true, echoReply)
+	defer stop()
+	pool := NewMuxPool(MuxMaxDataPacketSize)
+	defer pool.Close()
+	cp := util.NewConnectPool()
+
+	first, err := pool.GetStream(addr)
+	if err != nil {
+		t.Fatalf("get stream: %v", err)
+	}
+	second, err := pool.GetStream(addr)
+	if err != nil || second.mc != first.mc {
+		t.Fatalf("streams should share the connection, err(%v)", err)
+	}
+	pool.PutConnect(cp, first, false)
+	pool.PutConnect(cp, second, false)
+	first.mc.Close()
+	third, err := pool.GetStream(addr)
+	if err != nil || third.mc == first.mc {
+		t.Fatalf("broken connection should be replaced, err(%v)", err)
+	}
+
+	// the server unaware of the multiplexed mode is served with a connection of the pool
+	unsupportedAddr, stopUnsupported := startMuxServer(t, false, nil)
+	defer stopUnsupported()
+	if _, err = DialMuxConn(unsupportedAddr, time.Second, MuxMaxDataPacketSize); err != ErrMuxNotSupported {
+		t.Fatalf("dial should be refused, err(%v)", err)
+	}
+	conn, err := pool.GetConnect(cp, unsupportedAddr)
+	if err != nil {
+		t.Fatalf("get connect: %v", err)
+	}
+	if _, ok := conn.(*net.TCPConn); !ok {
+		t.Fatalf("connection of the pool should be returned, conn(%T)", conn)
+	}
+	pool.PutConnect(cp, conn, true)
+	if _, err = pool.GetStream(unsupportedAddr); err != ErrMuxNotSupported {
+		t.Fatalf("refused address should not be tried again, err(%v)", err)
+	}
+}
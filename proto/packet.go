@@ -28,6 +28,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/buf"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 var (
@@ -98,6 +99,11 @@ const (
 	OpMetaRemoveXAttr     uint8 = 0x37
 	OpMetaListXAttr       uint8 = 0x38
 	OpMetaBatchGetXAttr   uint8 = 0x39
+	OpMetaSetLock         uint8 = 0x3A
+	OpMetaGetLock         uint8 = 0x3B
+	OpMetaRenewLock       uint8 = 0x3C
+	OpMetaPunchHole       uint8 = 0x3D
+	OpMetaReadChangelog   uint8 = 0x3E
 
 	// Operations: Master -> MetaNode
 	OpCreateMetaPartition           uint8 = 0x40
@@ -109,6 +115,14 @@ const (
 	OpAddMetaPartitionRaftMember    uint8 = 0x46
 	OpRemoveMetaPartitionRaftMember uint8 = 0x47
 	OpMetaPartitionTryToLeader      uint8 = 0x48
+	OpMetaTierScan                  uint8 = 0x49
+
+	// Operations: Client -> MetaNode, and MetaNode -> MetaNode for recovery
+	OpMetaTxPrepare uint8 = 0x50
+	OpMetaTxCommit  uint8 = 0x51
+	OpMetaTxAbort   uint8 = 0x52
+	OpMetaTxGet     uint8 = 0x53
+	OpMetaTxForget  uint8 = 0x54
 
 	// Operations: Master -> DataNode
 	OpCreateDataPartition           uint8 = 0x60
@@ -121,6 +135,8 @@ const (
 	OpAddDataPartitionRaftMember    uint8 = 0x67
 	OpRemoveDataPartitionRaftMember uint8 = 0x68
 	OpDataPartitionTryToLeader      uint8 = 0x69
+	OpDataNodeClientThrottle        uint8 = 0x6A
+	OpResetDataPartitionRaftMember  uint8 = 0x6B
 
 	// Operations: MultipartInfo
 	OpCreateMultipart  uint8 = 0x70
@@ -130,6 +146,7 @@ const (
 	OpListMultiparts   uint8 = 0x74
 
 	OpBatchDeleteExtent uint8 = 0x75 // SDK to MetaNode
+	OpSealExtents       uint8 = 0x76 // MetaNode to DataNode
 
 	//Operations: MetaNode Leader -> MetaNode Follower
 	OpMetaBatchDeleteInode  uint8 = 0x90
@@ -138,6 +155,7 @@ const (
 	OpMetaBatchEvictInode   uint8 = 0x93
 
 	// Commons
+	OpCrcErr           uint8 = 0xF2
 	OpIntraGroupNetErr uint8 = 0xF3
 	OpArgMismatchErr   uint8 = 0xF4
 	OpNotExistErr      uint8 = 0xF5
@@ -152,7 +170,8 @@ const (
 	OpNotEmtpy         uint8 = 0xFE
 	OpOk               uint8 = 0xF0
 
-	OpPing uint8 = 0xFF
+	OpPing    uint8 = 0xFF
+	OpMuxConn uint8 = 0xEF // switches the connection to the multiplexed mode, see MuxConn
 )
 
 const (
@@ -169,6 +188,23 @@ const (
 	NormalExtentType = 1
 )
 
+// PacketDeadlineFlag in the extent type of the header marks that the request carries a deadline.
+// The time remaining before the deadline is appended to the arg in nanoseconds, so that the clock
+// offsets between the hosts do not matter. Metanodes unaware of the flag ignore it, but datanodes
+// reject it as an incorrect store mode.
+const (
+	PacketDeadlineFlag uint8 = 0x80
+	packetDeadlineLen        = 8
+)
+
+// PacketTraceFlag in the extent type of the header marks that the request carries a trace context,
+// which is appended to the arg after the deadline. It is only sent by the clients with the tracing
+// enabled, which requires all the datanodes to support it like the deadline.
+const (
+	PacketTraceFlag uint8 = 0x40
+	packetTraceLen        = tracing.ContextLen
+)
+
 const (
 	NormalCreateDataPartition         = 0
 	DecommissionedCreateDataPartition = 1
@@ -192,6 +228,8 @@ type Packet struct {
 	Arg                []byte // for create or append ops, the data contains the address
 	Data               []byte
 	StartT             int64
+	Deadline           int64               // unix nano time of the local clock when the request expires, 0 means no deadline
+	Trace              tracing.SpanContext // context of the span the request is sent by, propagated if valid
 	mesg               string
 	HasPrepare         bool
 }
@@ -255,6 +293,8 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpNotifyReplicasToRepair"
 	case OpExtentRepairRead:
 		m = "OpExtentRepairRead"
+	case OpCrcErr:
+		m = "CrcErr"
 	case OpIntraGroupNetErr:
 		m = "IntraGroupNetErr"
 	case OpMetaCreateInode:
@@ -335,6 +375,8 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpReadTinyDeleteRecord"
 	case OpPing:
 		m = "OpPing"
+	case OpMuxConn:
+		m = "OpMuxConn"
 	case OpTinyExtentRepairRead:
 		m = "OpTinyExtentRepairRead"
 	case OpGetMaxExtentIDAndPartitionSize:
@@ -351,8 +393,24 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpRemoveMetaPartitionRaftMember"
 	case OpMetaPartitionTryToLeader:
 		m = "OpMetaPartitionTryToLeader"
+	case OpMetaTierScan:
+		m = "OpMetaTierScan"
+	case OpMetaTxPrepare:
+		m = "OpMetaTxPrepare"
+	case OpMetaTxCommit:
+		m = "OpMetaTxCommit"
+	case OpMetaTxAbort:
+		m = "OpMetaTxAbort"
+	case OpMetaTxGet:
+		m = "OpMetaTxGet"
+	case OpMetaTxForget:
+		m = "OpMetaTxForget"
 	case OpDataPartitionTryToLeader:
 		m = "OpDataPartitionTryToLeader"
+	case OpDataNodeClientThrottle:
+		m = "OpDataNodeClientThrottle"
+	case OpResetDataPartitionRaftMember:
+		m = "OpResetDataPartitionRaftMember"
 	case OpMetaDeleteInode:
 		m = "OpMetaDeleteInode"
 	case OpMetaBatchDeleteInode:
@@ -369,6 +427,16 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpMetaListXAttr"
 	case OpMetaBatchGetXAttr:
 		m = "OpMetaBatchGetXAttr"
+	case OpMetaSetLock:
+		m = "OpMetaSetLock"
+	case OpMetaGetLock:
+		m = "OpMetaGetLock"
+	case OpMetaRenewLock:
+		m = "OpMetaRenewLock"
+	case OpMetaPunchHole:
+		m = "OpMetaPunchHole"
+	case OpMetaReadChangelog:
+		m = "OpMetaReadChangelog"
 	case OpCreateMultipart:
 		m = "OpCreateMultipart"
 	case OpGetMultipart:
@@ -381,6 +449,8 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpListMultiparts"
 	case OpBatchDeleteExtent:
 		m = "OpBatchDeleteExtent"
+	case OpSealExtents:
+		m = "OpSealExtents"
 	}
 	return
 }
@@ -430,14 +500,23 @@ func (p *Packet) GetReqID() int64 {
 
 // MarshalHeader marshals the packet header.
 func (p *Packet) MarshalHeader(out []byte) {
+	var argLen = p.ArgLen
 	out[0] = p.Magic
 	out[1] = p.ExtentType
+	if p.Deadline != 0 {
+		out[1] |= PacketDeadlineFlag
+		argLen += packetDeadlineLen
+	}
+	if p.Trace.IsValid() {
+		out[1] |= PacketTraceFlag
+		argLen += packetTraceLen
+	}
 	out[2] = p.Opcode
 	out[3] = p.ResultCode
 	out[4] = p.RemainingFollowers
 	binary.BigEndian.PutUint32(out[5:9], p.CRC)
 	binary.BigEndian.PutUint32(out[9:13], p.Size)
-	binary.BigEndian.PutUint32(out[13:17], p.ArgLen)
+	binary.BigEndian.PutUint32(out[13:17], argLen)
 	binary.BigEndian.PutUint64(out[17:25], p.PartitionID)
 	binary.BigEndian.PutUint64(out[25:33], p.ExtentID)
 	binary.BigEndian.PutUint64(out[33:41], uint64(p.ExtentOffset))
@@ -469,6 +548,60 @@ func (p *Packet) UnmarshalHeader(in []byte) error {
 	return nil
 }
 
+// marshalDeadline returns the time remaining before the deadline, which is written after the arg.
+func (p *Packet) marshalDeadline() []byte {
+	var remaining = p.Deadline - time.Now().UnixNano()
+	if remaining <= 0 {
+		remaining = 1
+	}
+	var out = make([]byte, packetDeadlineLen)
+	binary.BigEndian.PutUint64(out, uint64(remaining))
+	return out
+}
+
+// UnmarshalDeadline takes the deadline off the arg if the request carries one,
+// it must be called once the arg has been read.
+func (p *Packet) UnmarshalDeadline() {
+	if p.ExtentType&PacketDeadlineFlag == 0 {
+		return
+	}
+	p.ExtentType &^= PacketDeadlineFlag
+	if p.ArgLen < packetDeadlineLen || len(p.Arg) < int(p.ArgLen) {
+		// the arg has been dropped by a server unaware of the deadline
+		return
+	}
+	p.ArgLen -= packetDeadlineLen
+	var remaining = int64(binary.BigEndian.Uint64(p.Arg[p.ArgLen : p.ArgLen+packetDeadlineLen]))
+	p.Arg = p.Arg[:p.ArgLen]
+	p.Deadline = time.Now().UnixNano() + remaining
+}
+
+// UnmarshalTrace takes the trace context off the arg if the request carries one, it must be called
+// once the arg has been read and before UnmarshalDeadline.
+func (p *Packet) UnmarshalTrace() {
+	if p.ExtentType&PacketTraceFlag == 0 {
+		return
+	}
+	p.ExtentType &^= PacketTraceFlag
+	if p.ArgLen < packetTraceLen || len(p.Arg) < int(p.ArgLen) {
+		return
+	}
+	p.ArgLen -= packetTraceLen
+	p.Trace = tracing.UnmarshalContext(p.Arg[p.ArgLen : p.ArgLen+packetTraceLen])
+	p.Arg = p.Arg[:p.ArgLen]
+}
+
+// SetTimeout sets the deadline of the request to the given time from now.
+func (p *Packet) SetTimeout(timeout time.Duration) {
+	p.Deadline = time.Now().Add(timeout).UnixNano()
+}
+
+// DeadlineExceeded returns true if the request carries a deadline which has passed,
+// the client has stopped waiting for the response.
+func (p *Packet) DeadlineExceeded() bool {
+	return p.Deadline != 0 && time.Now().UnixNano() > p.Deadline
+}
+
 // MarshalData marshals the packet data.
 func (p *Packet) MarshalData(v interface{}) error {
 	data, err := json.Marshal(v)
@@ -494,7 +627,13 @@ func (p *Packet) WriteToNoDeadLineConn(c net.Conn) (err error) {
 
 	p.MarshalHeader(header)
 	if _, err = c.Write(header); err == nil {
-		if _, err = c.Write(p.Arg[:int(p.ArgLen)]); err == nil {
+		if _, err = c.Write(p.Arg[:int(p.ArgLen)]); err == nil && p.Deadline != 0 {
+			_, err = c.Write(p.marshalDeadline())
+		}
+		if err == nil && p.Trace.IsValid() {
+			_, err = c.Write(p.Trace.Marshal())
+		}
+		if err == nil {
 			if p.Data != nil {
 				_, err = c.Write(p.Data[:p.Size])
 			}
@@ -506,6 +645,17 @@ func (p *Packet) WriteToNoDeadLineConn(c net.Conn) (err error) {
 
 // WriteToConn writes through the given connection.
 func (p *Packet) WriteToConn(c net.Conn) (err error) {
+	if err = p.WriteHeaderToConn(c); err == nil {
+		if p.Data != nil && p.Size != 0 {
+			_, err = c.Write(p.Data[:p.Size])
+		}
+	}
+
+	return
+}
+
+// WriteHeaderToConn writes the packet except the data, which is written by the caller then, e.g. by sendfile.
+func (p *Packet) WriteHeaderToConn(c net.Conn) (err error) {
 	c.SetWriteDeadline(time.Now().Add(WriteDeadlineTime * time.Second))
 	header, err := Buffers.Get(util.PacketHeaderSize)
 	if err != nil {
@@ -515,10 +665,11 @@ func (p *Packet) WriteToConn(c net.Conn) (err error) {
 
 	p.MarshalHeader(header)
 	if _, err = c.Write(header); err == nil {
-		if _, err = c.Write(p.Arg[:int(p.ArgLen)]); err == nil {
-			if p.Data != nil && p.Size != 0 {
-				_, err = c.Write(p.Data[:p.Size])
-			}
+		if _, err = c.Write(p.Arg[:int(p.ArgLen)]); err == nil && p.Deadline != 0 {
+			_, err = c.Write(p.marshalDeadline())
+		}
+		if err == nil && p.Trace.IsValid() {
+			_, err = c.Write(p.Trace.Marshal())
 		}
 	}
 
@@ -561,6 +712,8 @@ func (p *Packet) ReadFromConn(c net.Conn, timeoutSec int) (err error) {
 			return err
 		}
 	}
+	p.UnmarshalTrace()
+	p.UnmarshalDeadline()
 
 	if p.Size < 0 {
 		return syscall.EBADMSG
@@ -637,7 +790,7 @@ func (p *Packet) GetUniqueLogId() (m string) {
 			return m
 		}
 	} else if p.Opcode == OpReadTinyDeleteRecord || p.Opcode == OpNotifyReplicasToRepair || p.Opcode == OpDataNodeHeartbeat ||
-		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent {
+		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent || p.Opcode == OpSealExtents {
 		p.mesg += fmt.Sprintf("Opcode(%v)", p.GetOpMsg())
 		return
 	} else if p.Opcode == OpBroadcastMinAppliedID || p.Opcode == OpGetAppliedId {
@@ -668,7 +821,7 @@ func (p *Packet) setPacketPrefix() {
 			return
 		}
 	} else if p.Opcode == OpReadTinyDeleteRecord || p.Opcode == OpNotifyReplicasToRepair || p.Opcode == OpDataNodeHeartbeat ||
-		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent {
+		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent || p.Opcode == OpSealExtents {
 		p.mesg += fmt.Sprintf("Opcode(%v)", p.GetOpMsg())
 		return
 	} else if p.Opcode == OpBroadcastMinAppliedID || p.Opcode == OpGetAppliedId {
@@ -114,14 +114,18 @@ const (
 	OSSDeleteBucketTaggingAction Action = OSSActionPrefix + "DeleteBucketTagging"
 
 	// Bucket lifecycle actions
-	OSSGetBucketLifecycleAction    Action = OSSActionPrefix + "GetBucketLifecycle"    // unsupported
-	OSSPutBucketLifecycleAction    Action = OSSActionPrefix + "PutBucketLifecycle"    // unsupported
-	OSSDeleteBucketLifecycleAction Action = OSSActionPrefix + "DeleteBucketLifecycle" // unsupported
+	OSSGetBucketLifecycleAction    Action = OSSActionPrefix + "GetBucketLifecycle"
+	OSSPutBucketLifecycleAction    Action = OSSActionPrefix + "PutBucketLifecycle"
+	OSSDeleteBucketLifecycleAction Action = OSSActionPrefix + "DeleteBucketLifecycle"
+
+	// Bucket notification actions
+	OSSGetBucketNotificationAction Action = OSSActionPrefix + "GetBucketNotification"
+	OSSPutBucketNotificationAction Action = OSSActionPrefix + "PutBucketNotification"
 
 	// Object storage version actions
-	OSSGetBucketVersioningAction Action = OSSActionPrefix + "GetBucketVersioning" // unsupported
-	OSSPutBucketVersioningAction Action = OSSActionPrefix + "PutBucketVersioning" // unsupported
-	OSSListObjectVersionsAction  Action = OSSActionPrefix + "ListObjectVersions"  // unsupported
+	OSSGetBucketVersioningAction Action = OSSActionPrefix + "GetBucketVersioning"
+	OSSPutBucketVersioningAction Action = OSSActionPrefix + "PutBucketVersioning"
+	OSSListObjectVersionsAction  Action = OSSActionPrefix + "ListObjectVersions"
 
 	// Object legal hold actions
 	OSSGetObjectLegalHoldAction Action = OSSActionPrefix + "GetObjectLegalHold" // unsupported
@@ -209,6 +213,8 @@ var (
 		OSSGetBucketLifecycleAction,
 		OSSPutBucketLifecycleAction,
 		OSSDeleteBucketLifecycleAction,
+		OSSGetBucketNotificationAction,
+		OSSPutBucketNotificationAction,
 		OSSGetBucketVersioningAction,
 		OSSPutBucketVersioningAction,
 		OSSListObjectVersionsAction,
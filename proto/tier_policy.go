@@ -0,0 +1,87 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	MaxTierRulesPerVol     = 16
+	MaxTierRuleInactiveDay = 3650
+)
+
+var tierRuleNameRegexp = regexp.MustCompile("^[a-zA-Z0-9_-]{1,64}$")
+
+// TierRule defines a rule of the per-volume tiering policy. Files that have not been accessed for
+// InactiveDays are counted as the cold files of the rule. The rules only report the cold files, so that
+// the operators can plan the capacity, no data is migrated.
+type TierRule struct {
+	Name         string
+	InactiveDays uint32
+	Enable       bool
+}
+
+// Validate checks if the tier rule is legal.
+func (r *TierRule) Validate() error {
+	if !tierRuleNameRegexp.MatchString(r.Name) {
+		return fmt.Errorf("invalid tier rule name[%v]", r.Name)
+	}
+	if r.InactiveDays == 0 || r.InactiveDays > MaxTierRuleInactiveDay {
+		return fmt.Errorf("inactive days[%v] of tier rule should be in range [1, %v]", r.InactiveDays, MaxTierRuleInactiveDay)
+	}
+	return nil
+}
+
+// InactiveSeconds returns the access time threshold of the rule in seconds.
+func (r *TierRule) InactiveSeconds() int64 {
+	return int64(r.InactiveDays) * 24 * 60 * 60
+}
+
+// TierRuleStat defines the cold files that a tier rule has matched in a volume.
+type TierRuleStat struct {
+	RuleName          string
+	CandidateInodes   uint64
+	CandidateBytes    uint64
+	ScannedPartitions int
+	UpdateTime        int64
+}
+
+// VolTierPolicyView defines the view of the tiering policy of a volume.
+type VolTierPolicyView struct {
+	VolName string
+	Rules   []*TierRule
+	Stats   []*TierRuleStat
+}
+
+// TierScanRequest defines the request to scan a meta partition for the inodes matching a tier rule.
+type TierScanRequest struct {
+	PartitionID uint64
+	VolName     string
+	RuleName    string
+	InactiveSec int64
+}
+
+// TierScanResponse defines the response to the request of scanning a meta partition for tier candidates.
+type TierScanResponse struct {
+	PartitionID     uint64
+	VolName         string
+	RuleName        string
+	CandidateInodes uint64
+	CandidateBytes  uint64
+	Status          uint8
+	Result          string
+}
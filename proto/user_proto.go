@@ -17,6 +17,7 @@ package proto
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -94,15 +95,56 @@ type AKUser struct {
 }
 
 type UserInfo struct {
-	UserID      string       `json:"user_id" graphql:"user_id"`
-	AccessKey   string       `json:"access_key" graphql:"access_key"`
-	SecretKey   string       `json:"secret_key" graphql:"secret_key"`
-	Policy      *UserPolicy  `json:"policy" graphql:"policy"`
-	UserType    UserType     `json:"user_type" graphql:"user_type"`
-	CreateTime  string       `json:"create_time" graphql:"create_time"`
-	Description string       `json:"description" graphql:"description"`
-	Mu          sync.RWMutex `json:"-" graphql:"-"`
-	EMPTY       bool         //graphql need ???
+	UserID             string               `json:"user_id" graphql:"user_id"`
+	AccessKey          string               `json:"access_key" graphql:"access_key"`
+	SecretKey          string               `json:"secret_key" graphql:"secret_key"`
+	Policy             *UserPolicy          `json:"policy" graphql:"policy"`
+	UserType           UserType             `json:"user_type" graphql:"user_type"`
+	CreateTime         string               `json:"create_time" graphql:"create_time"`
+	Description        string               `json:"description" graphql:"description"`
+	RetiringAccessKey  string               `json:"retiring_access_key" graphql:"retiring_access_key"`
+	RetiringSecretKey  string               `json:"retiring_secret_key" graphql:"retiring_secret_key"`
+	RetiringExpireTime int64                `json:"retiring_expire_time" graphql:"retiring_expire_time"`
+	Sessions           []*SessionCredential `json:"sessions" graphql:"sessions"`
+	Mu                 sync.RWMutex         `json:"-" graphql:"-"`
+	EMPTY              bool                 //graphql need ???
+}
+
+// SecretKeyOf returns the secret key paired with the access key, which is either the current
+// access key, the retiring one or a session one of the user. The retiring key pair is kept during
+// the rotation of keys until RetiringExpireTime (unix seconds).
+func (i *UserInfo) SecretKeyOf(accessKey string) string {
+	if i.RetiringAccessKey != "" && accessKey == i.RetiringAccessKey {
+		return i.RetiringSecretKey
+	}
+	if session := i.SessionOf(accessKey); session != nil {
+		return session.SecretKey
+	}
+	return i.SecretKey
+}
+
+// SessionOf returns the session credential issued to the user with the access key,
+// or nil if the access key is a long-lived one.
+func (i *UserInfo) SessionOf(accessKey string) *SessionCredential {
+	for _, session := range i.Sessions {
+		if session.AccessKey == accessKey {
+			return session
+		}
+	}
+	return nil
+}
+
+// SessionCredential is a temporary key pair issued by AssumeRole. Requests signed with it must carry
+// the session token, and the credential is no longer accepted after ExpireTime (unix seconds).
+type SessionCredential struct {
+	AccessKey    string `json:"access_key" graphql:"access_key"`
+	SecretKey    string `json:"secret_key" graphql:"secret_key"`
+	SessionToken string `json:"session_token" graphql:"session_token"`
+	ExpireTime   int64  `json:"expire_time" graphql:"expire_time"`
+}
+
+func (s *SessionCredential) Expired(now int64) bool {
+	return s.ExpireTime <= now
 }
 
 func (i *UserInfo) String() string {
@@ -124,15 +166,54 @@ type VolUser struct {
 }
 
 type UserPolicy struct {
-	OwnVols        []string            `json:"own_vols" graphql:"own_vols"`
-	AuthorizedVols map[string][]string `json:"authorized_vols" graphql:"-"` // mapping: volume -> actions
+	OwnVols        []string                 `json:"own_vols" graphql:"own_vols"`
+	AuthorizedVols map[string][]string      `json:"authorized_vols" graphql:"-"` // mapping: volume -> actions
+	PrefixPolicies map[string][]*PrefixRule `json:"prefix_policies" graphql:"-"` // mapping: volume -> prefix rules
 	mu             sync.RWMutex
 }
 
+type PrefixEffect string
+
+const (
+	PrefixEffectAllow PrefixEffect = "Allow"
+	PrefixEffectDeny  PrefixEffect = "Deny"
+)
+
+func (e PrefixEffect) Valid() bool {
+	return e == PrefixEffectAllow || e == PrefixEffectDeny
+}
+
+// PrefixRule allows or denies the actions on the objects whose keys start with the prefix.
+// A trailing "*" of the prefix is ignored, so "logs/teamA/*" and "logs/teamA/" are the same.
+// The actions accept both the builtin permissions and the actions of the volume policies.
+type PrefixRule struct {
+	Prefix  string       `json:"prefix"`
+	Effect  PrefixEffect `json:"effect"`
+	Actions []string     `json:"actions"`
+}
+
+func (rule *PrefixRule) MatchKey(key string) bool {
+	prefix := strings.TrimPrefix(strings.TrimSuffix(rule.Prefix, "*"), "/")
+	return strings.HasPrefix(strings.TrimPrefix(key, "/"), prefix)
+}
+
+func (rule *PrefixRule) MatchAction(action Action) bool {
+	for _, value := range rule.Actions {
+		if perm := ParsePermission(value); !perm.IsNone() && perm.IsBuiltin() && BuiltinPermissionActions(perm).Contains(action) {
+			return true
+		}
+		if act := ParseAction(value); act == action {
+			return true
+		}
+	}
+	return false
+}
+
 func NewUserPolicy() *UserPolicy {
 	return &UserPolicy{
 		OwnVols:        make([]string, 0),
 		AuthorizedVols: make(map[string][]string),
+		PrefixPolicies: make(map[string][]*PrefixRule),
 	}
 }
 
@@ -172,6 +253,82 @@ func (policy *UserPolicy) IsAuthorized(volume, subdir string, action Action) boo
 	return false
 }
 
+// IsAuthorizedKey checks the action on the object key against the prefix rules of the volume,
+// a matching deny rule wins over the allow rules. The volume policies are checked if no rule matches.
+func (policy *UserPolicy) IsAuthorizedKey(volume, key string, action Action) bool {
+	if matched, allowed := policy.evaluatePrefixRules(volume, key, action); matched {
+		return allowed
+	}
+	return policy.IsAuthorized(volume, "", action)
+}
+
+func (policy *UserPolicy) evaluatePrefixRules(volume, key string, action Action) (matched, allowed bool) {
+	policy.mu.RLock()
+	defer policy.mu.RUnlock()
+	for _, rule := range policy.PrefixPolicies[volume] {
+		if !rule.MatchKey(key) || !rule.MatchAction(action) {
+			continue
+		}
+		if rule.Effect == PrefixEffectDeny {
+			return true, false
+		}
+		matched, allowed = true, true
+	}
+	return
+}
+
+// SetPrefixRule adds the rule to the volume, or replaces the rule with the same prefix and effect.
+func (policy *UserPolicy) SetPrefixRule(volume string, rule *PrefixRule) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	if policy.PrefixPolicies == nil {
+		policy.PrefixPolicies = make(map[string][]*PrefixRule)
+	}
+	rules := policy.PrefixPolicies[volume]
+	for i, r := range rules {
+		if r.Prefix == rule.Prefix && r.Effect == rule.Effect {
+			rules[i] = rule
+			return
+		}
+	}
+	policy.PrefixPolicies[volume] = append(rules, rule)
+}
+
+// RemovePrefixRule removes the rule with the prefix and effect from the volume, and returns if it exists.
+func (policy *UserPolicy) RemovePrefixRule(volume, prefix string, effect PrefixEffect) bool {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	rules := policy.PrefixPolicies[volume]
+	for i, r := range rules {
+		if r.Prefix == prefix && r.Effect == effect {
+			rules = append(rules[:i], rules[i+1:]...)
+			if len(rules) == 0 {
+				delete(policy.PrefixPolicies, volume)
+			} else {
+				policy.PrefixPolicies[volume] = rules
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (policy *UserPolicy) RemovePrefixRules(volume string) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	delete(policy.PrefixPolicies, volume)
+}
+
+// HasPolicyOf returns if the user has any volume policy or prefix rule of the volume.
+func (policy *UserPolicy) HasPolicyOf(volume string) bool {
+	policy.mu.RLock()
+	defer policy.mu.RUnlock()
+	if _, exist := policy.AuthorizedVols[volume]; exist {
+		return true
+	}
+	return len(policy.PrefixPolicies[volume]) > 0
+}
+
 func (policy *UserPolicy) AddOwnVol(volume string) {
 	policy.mu.Lock()
 	defer policy.mu.Unlock()
@@ -296,6 +453,9 @@ func CleanPolicy(policy *UserPolicy) (newUserPolicy *UserPolicy) {
 		}
 		newUserPolicy.AuthorizedVols[vol] = newAPI
 	}
+	for vol, rules := range policy.PrefixPolicies {
+		newUserPolicy.PrefixPolicies[vol] = append([]*PrefixRule(nil), rules...)
+	}
 	return
 }
 
@@ -323,6 +483,14 @@ func (param *UserPermUpdateParam) SetPolicy(policy string) {
 	param.Policy = append(param.Policy, policy)
 }
 
+type UserPrefixPolicyParam struct {
+	UserID  string       `json:"user_id"`
+	Volume  string       `json:"volume"`
+	Prefix  string       `json:"prefix"`
+	Effect  PrefixEffect `json:"effect"`
+	Actions []string     `json:"actions"`
+}
+
 type UserPermRemoveParam struct {
 	UserID string `json:"user_id"`
 	Volume string `json:"volume"`
@@ -347,3 +515,15 @@ type UserUpdateParam struct {
 	Password    string   `json:"password"`
 	Description string   `json:"description"`
 }
+
+type UserRotateKeyParam struct {
+	UserID      string `json:"user_id"`
+	AccessKey   string `json:"ak"`
+	SecretKey   string `json:"sk"`
+	GracePeriod int64  `json:"grace_period"` // seconds the former key pair stays valid
+}
+
+type UserAssumeRoleParam struct {
+	UserID   string `json:"user_id"`
+	Duration int64  `json:"duration"` // seconds the session credential stays valid
+}
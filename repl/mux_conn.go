@@ -0,0 +1,84 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package repl
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ServeMuxConn serves the multiplexed connection switched by the request, see proto.MuxConn. The requests are
+// handled concurrently, except the packets to forward to the followers, which are refused since they must be kept
+// in order on a connection of their own.
+func ServeMuxConn(conn *net.TCPConn, request *Packet, prepareFunc func(p *Packet) error,
+	operatorFunc func(p *Packet, c net.Conn) error, postFunc func(p *Packet) error) {
+	defer conn.Close()
+	remoteAddr := conn.RemoteAddr().String()
+	request.PacketOkReply()
+	if err := request.WriteToConn(conn); err != nil {
+		log.LogErrorf("action[ServeMuxConn] remote(%v) err(%v)", remoteAddr, err)
+		return
+	}
+	var writeMu sync.Mutex
+	inflight := make(chan struct{}, proto.MuxMaxInflight)
+	for {
+		request := NewPacket()
+		if err := request.ReadFromConnFromCli(conn, proto.NoReadDeadlineTime); err != nil {
+			if err != io.EOF {
+				log.LogErrorf("action[ServeMuxConn] remote(%v) err(%v)", remoteAddr, err)
+			}
+			return
+		}
+		inflight <- struct{}{}
+		go func() {
+			defer func() { <-inflight }()
+			replyConn := proto.NewMuxReplyConn(conn, &writeMu)
+			serveMuxRequest(request, replyConn, prepareFunc, operatorFunc, postFunc)
+			replyConn.Flush()
+		}()
+	}
+}
+
+func serveMuxRequest(request *Packet, conn net.Conn, prepareFunc func(p *Packet) error,
+	operatorFunc func(p *Packet, c net.Conn) error, postFunc func(p *Packet) error) {
+	defer request.clean()
+	operated := false
+	if err := request.resolveFollowersAddr(); err == nil {
+		if request.IsForwardPacket() {
+			request.PackErrorBody(ActionPreparePkt, "forward packet on multiplexed connection")
+		} else if err = prepareFunc(request); err == nil {
+			operatorFunc(request, conn)
+			operated = true
+		}
+	}
+	if request.IsErrPacket() {
+		log.LogErrorf("%v", request.LogMessage(ActionWriteToClient, conn.RemoteAddr().String(),
+			request.StartT, fmt.Errorf("%v", string(request.Data[:request.Size]))))
+	}
+
+	postFunc(request)
+	// the replies of the reads are written by the operator, unless refused before
+	if !request.NeedReply && operated {
+		return
+	}
+	if err := request.WriteToConn(conn); err != nil {
+		log.LogErrorf("%v", request.LogMessage(ActionWriteToClient, conn.RemoteAddr().String(), request.StartT, err))
+	}
+}
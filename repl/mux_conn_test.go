@@ -0,0 +1,162 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package repl
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const testReadParts = 3
+
+// startTestMuxServer serves the multiplexed connections like a data node. The reads are replied with several
+// packets by the operator, the first of which is written before the release.
+func startTestMuxServer(t *testing.T, release chan struct{}) (addr string, stop func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	prepare := func(p *Packet) error {
+		return nil
+	}
+	operate := func(p *Packet, c net.Conn) error {
+		if !p.IsReadOperation() {
+			p.PacketOkWithBody(p.Data)
+			return nil
+		}
+		for i := 0; i < testReadParts; i++ {
+			if i == 1 {
+				<-release
+			}
+			reply := NewPacket()
+			reply.Opcode = p.Opcode
+			reply.ReqID = p.ReqID
+			reply.PacketOkWithBody([]byte(fmt.Sprintf("part %v", i)))
+			if err := reply.WriteToConn(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	post := func(p *Packet) error {
+		if p.IsReadOperation() {
+			p.NeedReply = false
+		}
+		return nil
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				request := NewPacket()
+				if err := request.ReadFromConnFromCli(conn, proto.NoReadDeadlineTime); err != nil {
+					conn.Close()
+					return
+				}
+				ServeMuxConn(conn.(*net.TCPConn), request, prepare, operate, post)
+			}()
+		}
+	}()
+	return ln.Addr().String(), func() { ln.Close() }
+}
+
+func newTestRequest(opcode uint8, data string) *proto.Packet {
+	p := proto.NewPacketReqID()
+	p.Opcode = opcode
+	if data != "" {
+		p.Data = []byte(data)
+		p.Size = uint32(len(p.Data))
+	}
+	return p
+}
+
+func TestServeMuxConn(t *testing.T) {
+	release := make(chan struct{})
+	addr, stop := startTestMuxServer(t, release)
+	defer stop()
+	mc, err := proto.DialMuxConn(addr, time.Second, proto.MuxMaxDataPacketSize)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer mc.Close()
+
+	// the replies of a read are sent as soon as they are written
+	read := mc.NewStream()
+	defer read.Close()
+	readReq := newTestRequest(proto.OpStreamRead, "")
+	readReq.Size = 100
+	if err = readReq.WriteToConn(read); err != nil {
+		t.Fatalf("send read: %v", err)
+	}
+	reply := proto.NewPacket()
+	if err = reply.ReadFromConn(read, 5); err != nil || reply.ReqID != readReq.ReqID || string(reply.Data) != "part 0" {
+		t.Fatalf("first part of the read should be received before the read completes, reply(%v) err(%v)", reply, err)
+	}
+
+	// the other requests are handled while the read is blocked
+	echo := mc.NewStream()
+	defer echo.Close()
+	echoReq := newTestRequest(proto.OpWrite, "echo")
+	echoReq.WriteToConn(echo)
+	reply = proto.NewPacket()
+	if err = reply.ReadFromConn(echo, 5); err != nil || reply.ReqID != echoReq.ReqID || string(reply.Data) != "echo" {
+		t.Fatalf("unexpected reply of the concurrent request, reply(%v) err(%v)", reply, err)
+	}
+
+	close(release)
+	for i := 1; i < testReadParts; i++ {
+		reply = proto.NewPacket()
+		if err = reply.ReadFromConn(read, 5); err != nil || string(reply.Data) != fmt.Sprintf("part %v", i) {
+			t.Fatalf("unexpected part %v of the read, reply(%v) err(%v)", i, reply, err)
+		}
+	}
+}
+
+func TestServeMuxConnRefuseForward(t *testing.T) {
+	addr, stop := startTestMuxServer(t, make(chan struct{}))
+	defer stop()
+	mc, err := proto.DialMuxConn(addr, time.Second, proto.MuxMaxDataPacketSize)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer mc.Close()
+
+	s := mc.NewStream()
+	defer s.Close()
+	req := newTestRequest(proto.OpWrite, "data")
+	req.Arg = []byte("127.0.0.1:1" + proto.AddrSplit)
+	req.ArgLen = uint32(len(req.Arg))
+	req.RemainingFollowers = 1
+	req.WriteToConn(s)
+	reply := proto.NewPacket()
+	if err = reply.ReadFromConn(s, 5); err != nil || reply.ReqID != req.ReqID || reply.ResultCode == proto.OpOk {
+		t.Fatalf("forward packet should be refused, reply(%v) err(%v)", reply, err)
+	}
+
+	// the connection is still usable
+	req = newTestRequest(proto.OpWrite, "after refused")
+	req.WriteToConn(s)
+	reply = proto.NewPacket()
+	if err = reply.ReadFromConn(s, 5); err != nil || reply.ResultCode != proto.OpOk {
+		t.Fatalf("unexpected reply after the refused packet, reply(%v) err(%v)", reply, err)
+	}
+}
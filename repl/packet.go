@@ -74,6 +74,8 @@ func (p *FollowerPacket) identificationErrorResultCode(errLog string, errMsg str
 	if strings.Contains(errLog, ActionReceiveFromFollower) || strings.Contains(errLog, ActionSendToFollowers) ||
 		strings.Contains(errLog, ConnIsNullErr) {
 		p.ResultCode = proto.OpIntraGroupNetErr
+	} else if strings.Contains(errMsg, storage.CrcMismatchError.Error()) {
+		p.ResultCode = proto.OpCrcErr
 	} else if strings.Contains(errMsg, storage.ParameterMismatchError.Error()) ||
 		strings.Contains(errMsg, ErrorUnknownOp.Error()) {
 		p.ResultCode = proto.OpArgMismatchErr
@@ -84,9 +86,13 @@ func (p *FollowerPacket) identificationErrorResultCode(errLog string, errMsg str
 		p.ResultCode = proto.OpNotExistErr
 	} else if strings.Contains(errMsg, storage.NoSpaceError.Error()) {
 		p.ResultCode = proto.OpDiskNoSpaceErr
-	} else if strings.Contains(errMsg, storage.TryAgainError.Error()) {
+	} else if strings.Contains(errMsg, storage.NotPermittedError.Error()) {
+		p.ResultCode = proto.OpNotPerm
+	} else if strings.Contains(errMsg, storage.TryAgainError.Error()) ||
+		strings.Contains(errMsg, proto.ErrDeadlineExceeded.Error()) {
 		p.ResultCode = proto.OpAgain
-	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) {
+	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) ||
+		strings.Contains(errMsg, storage.BlockCrcMismatchError.Error()) {
 		p.ResultCode = proto.OpTryOtherAddr
 	} else {
 		p.ResultCode = proto.OpIntraGroupNetErr
@@ -127,6 +133,8 @@ func copyPacket(src *Packet, dst *FollowerPacket) {
 	dst.ExtentID = src.ExtentID
 	dst.ExtentOffset = src.ExtentOffset
 	dst.ReqID = src.ReqID
+	dst.Deadline = src.Deadline
+	dst.Trace = src.Trace
 	dst.Data = src.OrgBuffer
 
 }
@@ -290,6 +298,8 @@ func (p *Packet) identificationErrorResultCode(errLog string, errMsg string) {
 	if strings.Contains(errLog, ActionReceiveFromFollower) || strings.Contains(errLog, ActionSendToFollowers) ||
 		strings.Contains(errLog, ConnIsNullErr) {
 		p.ResultCode = proto.OpIntraGroupNetErr
+	} else if strings.Contains(errMsg, storage.CrcMismatchError.Error()) {
+		p.ResultCode = proto.OpCrcErr
 	} else if strings.Contains(errMsg, storage.ParameterMismatchError.Error()) ||
 		strings.Contains(errMsg, ErrorUnknownOp.Error()) {
 		p.ResultCode = proto.OpArgMismatchErr
@@ -300,7 +310,10 @@ func (p *Packet) identificationErrorResultCode(errLog string, errMsg string) {
 		p.ResultCode = proto.OpNotExistErr
 	} else if strings.Contains(errMsg, storage.NoSpaceError.Error()) {
 		p.ResultCode = proto.OpDiskNoSpaceErr
-	} else if strings.Contains(errMsg, storage.TryAgainError.Error()) {
+	} else if strings.Contains(errMsg, storage.NotPermittedError.Error()) {
+		p.ResultCode = proto.OpNotPerm
+	} else if strings.Contains(errMsg, storage.TryAgainError.Error()) ||
+		strings.Contains(errMsg, proto.ErrDeadlineExceeded.Error()) {
 		p.ResultCode = proto.OpAgain
 	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) {
 		p.ResultCode = proto.OpTryOtherAddr
@@ -349,6 +362,8 @@ func (p *Packet) ReadFromConnFromCli(c net.Conn, deadlineTime time.Duration) (er
 			return
 		}
 	}
+	p.UnmarshalTrace()
+	p.UnmarshalDeadline()
 
 	if p.Size < 0 {
 		return
@@ -370,7 +385,9 @@ func (p *Packet) IsMasterCommand() bool {
 		proto.OpDecommissionDataPartition,
 		proto.OpAddDataPartitionRaftMember,
 		proto.OpRemoveDataPartitionRaftMember,
-		proto.OpDataPartitionTryToLeader:
+		proto.OpDataPartitionTryToLeader,
+		proto.OpDataNodeClientThrottle,
+		proto.OpResetDataPartitionRaftMember:
 		return true
 	}
 	return false
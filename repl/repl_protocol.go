@@ -53,9 +53,9 @@ type ReplProtocol struct {
 	followerConnects map[string]*FollowerTransport
 	lock             sync.RWMutex
 
-	prepareFunc  func(p *Packet) error                 // prepare packet
-	operatorFunc func(p *Packet, c *net.TCPConn) error // operator
-	postFunc     func(p *Packet) error                 // post-processing packet
+	prepareFunc  func(p *Packet) error             // prepare packet
+	operatorFunc func(p *Packet, c net.Conn) error // operator
+	postFunc     func(p *Packet) error             // post-processing packet
 
 	isError int32
 	replId  int64
@@ -188,7 +188,7 @@ func (ft *FollowerTransport) Write(p *FollowerPacket) {
 }
 
 func NewReplProtocol(inConn *net.TCPConn, prepareFunc func(p *Packet) error,
-	operatorFunc func(p *Packet, c *net.TCPConn) error, postFunc func(p *Packet) error) *ReplProtocol {
+	operatorFunc func(p *Packet, c net.Conn) error, postFunc func(p *Packet) error) *ReplProtocol {
 	rp := new(ReplProtocol)
 	rp.packetList = list.New()
 	rp.ackCh = make(chan struct{}, RequestChanSize)
@@ -212,6 +212,11 @@ func NewReplProtocol(inConn *net.TCPConn, prepareFunc func(p *Packet) error,
 // ServerConn keeps reading data from the socket to analyze the follower address, execute the prepare function,
 // and throw the packets to the to-be-processed channel.
 func (rp *ReplProtocol) ServerConn() {
+	rp.ServerConnFrom(nil)
+}
+
+// ServerConnFrom is ServerConn with the first packet of the connection read by the caller, if not nil.
+func (rp *ReplProtocol) ServerConnFrom(request *Packet) {
 	var (
 		err error
 	)
@@ -224,6 +229,11 @@ func (rp *ReplProtocol) ServerConn() {
 		}
 		rp.exitedMu.Unlock()
 	}()
+	if request != nil {
+		if err = rp.prepare(request); err != nil {
+			return
+		}
+	}
 	for {
 		select {
 		case <-rp.exitC:
@@ -270,6 +280,10 @@ func (rp *ReplProtocol) readPkgAndPrepare() (err error) {
 	}
 	log.LogDebugf("action[readPkgAndPrepare] packet(%v) from remote(%v) ",
 		request.GetUniqueLogId(), rp.sourceConn.RemoteAddr().String())
+	return rp.prepare(request)
+}
+
+func (rp *ReplProtocol) prepare(request *Packet) (err error) {
 	if err = request.resolveFollowersAddr(); err != nil {
 		err = rp.putResponse(request)
 		return
@@ -0,0 +1,258 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blockcache
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The blocks of the extents are cached in the files of a local directory, usually on an SSD, named by
+// "<partition id>/<extent id>_<extent offset>". The least recently used blocks are evicted once the total
+// size exceeds the capacity, and the cached blocks are loaded again when the client is restarted.
+
+const (
+	// BlockSize is the size of the cached blocks, the blocks are aligned to it in the extents.
+	BlockSize = util.MB
+
+	tmpFileSuffix = ".tmp"
+)
+
+type blockKey struct {
+	partitionID uint64
+	extentID    uint64
+	offset      uint64 // the offset of the block in the extent
+}
+
+func (k blockKey) fileName() string {
+	return fmt.Sprintf("%v/%v_%v", k.partitionID, k.extentID, k.offset)
+}
+
+// BlockCache defines the struct of the local block cache.
+type BlockCache struct {
+	sync.Mutex
+	dir      string
+	capacity int64
+	size     int64
+	lruList  *list.List
+	blocks   map[blockKey]*list.Element
+	epoch    uint64 // increased on every invalidation
+	tmpSeq   uint64
+}
+
+// NewBlockCache returns a new block cache of the given capacity in bytes in the directory,
+// with the blocks already in the directory loaded.
+func NewBlockCache(dir string, capacity int64) (c *BlockCache, err error) {
+	if capacity < BlockSize {
+		return nil, fmt.Errorf("capacity of block cache [%v] is less than a block", capacity)
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	c = &BlockCache{
+		dir:      dir,
+		capacity: capacity,
+		lruList:  list.New(),
+		blocks:   make(map[blockKey]*list.Element),
+	}
+	if err = c.load(); err != nil {
+		return nil, err
+	}
+	log.LogInfof("NewBlockCache: dir(%v) capacity(%v) loaded blocks(%v)", dir, capacity, len(c.blocks))
+	return
+}
+
+type loadedBlock struct {
+	key     blockKey
+	modTime int64
+}
+
+// load puts the blocks in the directory into the cache with the most recently written ones in the front,
+// and removes the blocks beyond the capacity and the files left by the unfinished writes.
+func (c *BlockCache) load() (err error) {
+	var partitionDirs []os.FileInfo
+	if partitionDirs, err = ioutil.ReadDir(c.dir); err != nil {
+		return
+	}
+	loaded := make([]*loadedBlock, 0)
+	for _, partitionDir := range partitionDirs {
+		partitionID, e := strconv.ParseUint(partitionDir.Name(), 10, 64)
+		if e != nil || !partitionDir.IsDir() {
+			continue
+		}
+		files, e := ioutil.ReadDir(path.Join(c.dir, partitionDir.Name()))
+		if e != nil {
+			return e
+		}
+		for _, file := range files {
+			key, ok := parseBlockFileName(partitionID, file.Name())
+			if !ok || file.Size() != BlockSize {
+				_ = os.Remove(path.Join(c.dir, partitionDir.Name(), file.Name()))
+				continue
+			}
+			loaded = append(loaded, &loadedBlock{key: key, modTime: file.ModTime().UnixNano()})
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].modTime > loaded[j].modTime
+	})
+	for _, block := range loaded {
+		if c.size+BlockSize > c.capacity {
+			_ = os.Remove(path.Join(c.dir, block.key.fileName()))
+			continue
+		}
+		c.blocks[block.key] = c.lruList.PushBack(block.key)
+		c.size += BlockSize
+	}
+	return
+}
+
+func parseBlockFileName(partitionID uint64, name string) (key blockKey, ok bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) != 2 {
+		return
+	}
+	var err error
+	key.partitionID = partitionID
+	if key.extentID, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return
+	}
+	if key.offset, err = strconv.ParseUint(parts[1], 10, 64); err != nil || key.offset%BlockSize != 0 {
+		return
+	}
+	return key, true
+}
+
+// Epoch returns the count of the invalidations, which is taken before reading a block from the data nodes
+// and passed to Put, so that the block is not cached if it may be overwritten during the read.
+func (c *BlockCache) Epoch() uint64 {
+	return atomic.LoadUint64(&c.epoch)
+}
+
+// Get reads the cached block at the offset of the extent into the data, which is BlockSize long.
+// It returns false if the block is not cached.
+func (c *BlockCache) Get(partitionID, extentID, offset uint64, data []byte) bool {
+	key := blockKey{partitionID: partitionID, extentID: extentID, offset: offset}
+	c.Lock()
+	element, ok := c.blocks[key]
+	if ok {
+		c.lruList.MoveToFront(element)
+	}
+	c.Unlock()
+	if !ok {
+		return false
+	}
+	file, err := os.Open(path.Join(c.dir, key.fileName()))
+	if err == nil {
+		_, err = io.ReadFull(file, data[:BlockSize])
+		_ = file.Close()
+	}
+	if err != nil {
+		log.LogWarnf("BlockCache Get: failed to read block(%v) err(%v)", key.fileName(), err)
+		c.Lock()
+		c.remove(key)
+		c.Unlock()
+		return false
+	}
+	return true
+}
+
+// Put caches the block at the offset of the extent, unless the cache has been invalidated since the epoch.
+func (c *BlockCache) Put(partitionID, extentID, offset uint64, data []byte, epoch uint64) {
+	key := blockKey{partitionID: partitionID, extentID: extentID, offset: offset}
+	c.Lock()
+	_, ok := c.blocks[key]
+	c.Unlock()
+	if ok || c.Epoch() != epoch {
+		return
+	}
+
+	blockPath := path.Join(c.dir, key.fileName())
+	tmpPath := fmt.Sprintf("%v_%v%v", blockPath, atomic.AddUint64(&c.tmpSeq, 1), tmpFileSuffix)
+	if err := os.MkdirAll(path.Dir(blockPath), 0755); err != nil {
+		log.LogWarnf("BlockCache Put: failed to create dir of block(%v) err(%v)", key.fileName(), err)
+		return
+	}
+	if err := ioutil.WriteFile(tmpPath, data[:BlockSize], 0644); err != nil {
+		log.LogWarnf("BlockCache Put: failed to write block(%v) err(%v)", key.fileName(), err)
+		_ = os.Remove(tmpPath)
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok = c.blocks[key]; ok || c.Epoch() != epoch {
+		_ = os.Remove(tmpPath)
+		return
+	}
+	if err := os.Rename(tmpPath, blockPath); err != nil {
+		log.LogWarnf("BlockCache Put: failed to rename block(%v) err(%v)", key.fileName(), err)
+		_ = os.Remove(tmpPath)
+		return
+	}
+	c.blocks[key] = c.lruList.PushFront(key)
+	c.size += BlockSize
+	for c.size > c.capacity {
+		c.remove(c.lruList.Back().Value.(blockKey))
+	}
+}
+
+// Invalidate removes the cached blocks overlapping the range of the extent, which is being overwritten.
+func (c *BlockCache) Invalidate(partitionID, extentID, offset, size uint64) {
+	c.Lock()
+	defer c.Unlock()
+	atomic.AddUint64(&c.epoch, 1)
+	for blockOffset := offset / BlockSize * BlockSize; blockOffset < offset+size; blockOffset += BlockSize {
+		c.remove(blockKey{partitionID: partitionID, extentID: extentID, offset: blockOffset})
+	}
+}
+
+// Size returns the total size of the cached blocks.
+func (c *BlockCache) Size() int64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.size
+}
+
+// Capacity returns the capacity of the cache in bytes.
+func (c *BlockCache) Capacity() int64 {
+	return c.capacity
+}
+
+// The caller should grab the lock of the cache.
+func (c *BlockCache) remove(key blockKey) {
+	element, ok := c.blocks[key]
+	if !ok {
+		return
+	}
+	c.lruList.Remove(element)
+	delete(c.blocks, key)
+	c.size -= BlockSize
+	if err := os.Remove(path.Join(c.dir, key.fileName())); err != nil && !os.IsNotExist(err) {
+		log.LogWarnf("BlockCache remove: failed to remove block(%v) err(%v)", key.fileName(), err)
+	}
+}
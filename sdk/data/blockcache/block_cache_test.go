@@ -0,0 +1,76 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blockcache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func newTestBlock(b byte) []byte {
+	return bytes.Repeat([]byte{b}, BlockSize)
+}
+
+func TestBlockCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockcache")
+	if err != nil {
+		t.Fatalf("create temp dir err[%v]", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewBlockCache(dir, 2*BlockSize)
+	if err != nil {
+		t.Fatalf("new block cache err[%v]", err)
+	}
+	data := make([]byte, BlockSize)
+	if c.Get(1, 100, 0, data) {
+		t.Fatalf("get a block not cached")
+	}
+	c.Put(1, 100, 0, newTestBlock('a'), c.Epoch())
+	c.Put(1, 100, BlockSize, newTestBlock('b'), c.Epoch())
+	if !c.Get(1, 100, 0, data) || !bytes.Equal(data, newTestBlock('a')) {
+		t.Fatalf("get cached block failed")
+	}
+
+	// the block at BlockSize is the least recently used one
+	c.Put(2, 200, 0, newTestBlock('c'), c.Epoch())
+	if c.Get(1, 100, BlockSize, data) {
+		t.Errorf("least recently used block is not evicted")
+	}
+	if c.Size() != 2*BlockSize {
+		t.Errorf("expect size [%v] but is [%v]", 2*BlockSize, c.Size())
+	}
+
+	epoch := c.Epoch()
+	c.Invalidate(1, 100, BlockSize-1, 2)
+	if c.Get(1, 100, 0, data) {
+		t.Errorf("overwritten block is not invalidated")
+	}
+	c.Put(1, 100, 0, newTestBlock('d'), epoch)
+	if c.Get(1, 100, 0, data) {
+		t.Errorf("block read before invalidation is cached")
+	}
+
+	// the blocks are kept after restart
+	c, err = NewBlockCache(dir, 2*BlockSize)
+	if err != nil {
+		t.Fatalf("reload block cache err[%v]", err)
+	}
+	if !c.Get(2, 200, 0, data) || !bytes.Equal(data, newTestBlock('c')) {
+		t.Errorf("block is not loaded after restart")
+	}
+}
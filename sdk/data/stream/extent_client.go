@@ -17,12 +17,15 @@ package stream
 import (
 	"fmt"
 	"sync"
+	"syscall"
 	"time"
 
 	"golang.org/x/time/rate"
 
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/blockcache"
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
@@ -31,7 +34,9 @@ import (
 type AppendExtentKeyFunc func(inode uint64, key proto.ExtentKey) error
 type GetExtentsFunc func(inode uint64) (uint64, uint64, []proto.ExtentKey, error)
 type TruncateFunc func(inode, size uint64) error
+type PunchHoleFunc func(inode, offset, size uint64) error
 type EvictIcacheFunc func(inode uint64)
+type UpdateModifyTimeFunc func(inode uint64, mtime int64) error
 
 const (
 	MaxMountRetryLimit = 5
@@ -42,6 +47,12 @@ const (
 
 	defaultWriteLimitRate  = rate.Inf
 	defaultWriteLimitBurst = 128
+
+	defaultWriteStreams       = 1
+	defaultWriteStripeSize    = 16 * util.MB
+	defaultMaxInflightPackets = 128
+	maxInflightPacketsLimit   = 1024 // capacity of the request channel of the extent handler
+	defaultPacketRetryLimit   = 32
 )
 
 var (
@@ -51,6 +62,7 @@ var (
 	flushRequestPool   *sync.Pool
 	releaseRequestPool *sync.Pool
 	truncRequestPool   *sync.Pool
+	punchRequestPool   *sync.Pool
 	evictRequestPool   *sync.Pool
 )
 
@@ -71,22 +83,36 @@ func init() {
 	truncRequestPool = &sync.Pool{New: func() interface{} {
 		return &TruncRequest{}
 	}}
+	punchRequestPool = &sync.Pool{New: func() interface{} {
+		return &PunchRequest{}
+	}}
 	evictRequestPool = &sync.Pool{New: func() interface{} {
 		return &EvictRequest{}
 	}}
 }
 
 type ExtentConfig struct {
-	Volume            string
-	Masters           []string
-	FollowerRead      bool
-	NearRead          bool
-	ReadRate          int64
-	WriteRate         int64
-	OnAppendExtentKey AppendExtentKeyFunc
-	OnGetExtents      GetExtentsFunc
-	OnTruncate        TruncateFunc
-	OnEvictIcache     EvictIcacheFunc
+	Volume             string
+	Masters            []string
+	FollowerRead       bool
+	NearRead           bool
+	ReadRate           int64
+	WriteRate          int64
+	WriteStreams       int64 // extent handlers of a file that can have in-flight packets at the same time
+	WriteStripeSize    int64 // bytes of the sequential writes to an extent handler before the next one is opened
+	MaxInflightPackets int64 // packets that an extent handler can send before receiving the replies
+	PacketRetryLimit   int64 // times a failed packet is resent before the write fails
+	ReadDeadline       bool  // send the read requests with deadlines, requires all the datanodes to support it
+	ReadCacheDir       string
+	ReadCacheSize      int64 // the capacity of the local block cache in bytes, 0 disables the cache
+	ReadAheadSize      int64 // the max read-ahead window in bytes, 0 uses the default and negative disables read-ahead
+	ConnMux            bool  // multiplex the requests other than the writes over a shared connection to every datanode
+	OnAppendExtentKey  AppendExtentKeyFunc
+	OnGetExtents       GetExtentsFunc
+	OnTruncate         TruncateFunc
+	OnPunchHole        PunchHoleFunc
+	OnEvictIcache      EvictIcacheFunc
+	OnUpdateModifyTime UpdateModifyTimeFunc // the overwrites do not update the modify time of the files if it is nil
 }
 
 // ExtentClient defines the struct of the extent client.
@@ -97,11 +123,23 @@ type ExtentClient struct {
 	readLimiter  *rate.Limiter
 	writeLimiter *rate.Limiter
 
-	dataWrapper     *wrapper.Wrapper
-	appendExtentKey AppendExtentKeyFunc
-	getExtents      GetExtentsFunc
-	truncate        TruncateFunc
-	evictIcache     EvictIcacheFunc //May be null, must check before using
+	writeStreams       int
+	writeStripeSize    int // the data of a handler is limited to an extent unless the file is written by streams
+	maxInflightPackets int
+	packetRetryLimit   int
+	readDeadline       bool
+	readAheadSize      int // 0 if read-ahead is disabled
+
+	blockCache *blockcache.BlockCache // may be nil if the local block cache is disabled
+	stat       *streamStat
+
+	dataWrapper      *wrapper.Wrapper
+	appendExtentKey  AppendExtentKeyFunc
+	getExtents       GetExtentsFunc
+	truncate         TruncateFunc
+	punchHole        PunchHoleFunc
+	evictIcache      EvictIcacheFunc      //May be null, must check before using
+	updateModifyTime UpdateModifyTimeFunc //May be null, must check before using
 }
 
 // NewExtentClient returns a new extent client.
@@ -122,10 +160,13 @@ retry:
 	}
 
 	client.streamers = make(map[uint64]*Streamer)
+	client.stat = newStreamStat()
 	client.appendExtentKey = config.OnAppendExtentKey
 	client.getExtents = config.OnGetExtents
 	client.truncate = config.OnTruncate
+	client.punchHole = config.OnPunchHole
 	client.evictIcache = config.OnEvictIcache
+	client.updateModifyTime = config.OnUpdateModifyTime
 	client.dataWrapper.InitFollowerRead(config.FollowerRead)
 	client.dataWrapper.SetNearRead(config.NearRead)
 
@@ -144,6 +185,47 @@ retry:
 	client.readLimiter = rate.NewLimiter(readLimit, defaultReadLimitBurst)
 	client.writeLimiter = rate.NewLimiter(writeLimit, defaultWriteLimitBurst)
 
+	client.writeStreams = defaultWriteStreams
+	if config.WriteStreams > 0 {
+		client.writeStreams = int(config.WriteStreams)
+	}
+	client.writeStripeSize = util.ExtentSize
+	if client.writeStreams > 1 {
+		client.writeStripeSize = defaultWriteStripeSize
+		if config.WriteStripeSize > 0 {
+			client.writeStripeSize = util.Min(int(config.WriteStripeSize), util.ExtentSize)
+		}
+	}
+	client.maxInflightPackets = defaultMaxInflightPackets
+	if config.MaxInflightPackets > 0 {
+		client.maxInflightPackets = int(config.MaxInflightPackets)
+		if client.maxInflightPackets > maxInflightPacketsLimit {
+			client.maxInflightPackets = maxInflightPacketsLimit
+		}
+	}
+	client.packetRetryLimit = defaultPacketRetryLimit
+	if config.PacketRetryLimit > 0 {
+		client.packetRetryLimit = int(config.PacketRetryLimit)
+	}
+	client.readDeadline = config.ReadDeadline
+	client.readAheadSize = defaultReadAheadSize
+	if config.ReadAheadSize > 0 {
+		client.readAheadSize = util.Max(int(config.ReadAheadSize), readAheadMinWindow)
+	} else if config.ReadAheadSize < 0 {
+		client.readAheadSize = 0
+	}
+	if config.ReadCacheDir != "" && config.ReadCacheSize > 0 {
+		if client.blockCache, err = blockcache.NewBlockCache(config.ReadCacheDir, config.ReadCacheSize); err != nil {
+			return nil, errors.Trace(err, "Init block cache failed!")
+		}
+	}
+	if config.ConnMux && StreamMuxPool == nil {
+		StreamMuxPool = proto.NewMuxPool(proto.MuxMaxDataPacketSize)
+	}
+
+	log.LogInfof("NewExtentClient: writeStreams(%v) writeStripeSize(%v) maxInflightPackets(%v) packetRetryLimit(%v) readDeadline(%v) readAheadSize(%v) connMux(%v)",
+		client.writeStreams, client.writeStripeSize, client.maxInflightPackets, client.packetRetryLimit, client.readDeadline, client.readAheadSize, config.ConnMux)
+
 	return
 }
 
@@ -230,7 +312,8 @@ func (client *ExtentClient) Write(inode uint64, offset int, data []byte, flags i
 	})
 
 	write, err = s.IssueWriteRequest(offset, data, flags)
-	if err != nil {
+	// the refusal of the retention is returned as is, so that the caller gets EPERM
+	if err != nil && err != syscall.EPERM {
 		err = errors.Trace(err, prefix)
 		log.LogError(errors.Stack(err))
 		exporter.Warning(err.Error())
@@ -253,6 +336,34 @@ func (client *ExtentClient) Truncate(inode uint64, size int) error {
 	return err
 }
 
+// PartitionHosts returns the addresses of the replicas of the data partition, the nearest first if near read is enabled.
+// PunchHole removes the file range from the extents, the range reads as zeros afterwards.
+func (client *ExtentClient) PunchHole(inode uint64, offset, size int) error {
+	prefix := fmt.Sprintf("PunchHole{ino(%v)offset(%v)size(%v)}", inode, offset, size)
+	s := client.GetStreamer(inode)
+	if s == nil {
+		return fmt.Errorf("Prefix(%v): stream is not opened yet", prefix)
+	}
+
+	err := s.IssuePunchRequest(offset, size)
+	if err != nil {
+		err = errors.Trace(err, prefix)
+		log.LogError(errors.Stack(err))
+	}
+	return err
+}
+
+func (client *ExtentClient) PartitionHosts(partitionID uint64) ([]string, error) {
+	dp, err := client.dataWrapper.GetDataPartition(partitionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(dp.NearHosts) > 0 {
+		return dp.NearHosts, nil
+	}
+	return dp.Hosts, nil
+}
+
 func (client *ExtentClient) Flush(inode uint64) error {
 	s := client.GetStreamer(inode)
 	if s == nil {
@@ -281,7 +392,11 @@ func (client *ExtentClient) Read(inode uint64, data []byte, offset int, size int
 		return
 	}
 
-	read, err = s.read(data, offset, size)
+	if s.readahead != nil {
+		read, err = s.readahead.read(data, offset, size)
+	} else {
+		read, err = s.read(data, offset, size)
+	}
 	return
 }
 
@@ -325,6 +440,11 @@ func setRate(lim *rate.Limiter, val int) string {
 	return "unlimited"
 }
 
+// SetFollowerRead enables or disables the follower read of the client config.
+func (client *ExtentClient) SetFollowerRead(enable bool) {
+	client.dataWrapper.SetFollowerRead(enable)
+}
+
 func (client *ExtentClient) Close() error {
 	// release streamers
 	var inodes []uint64
@@ -17,6 +17,7 @@ package stream
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 )
 
 // DirtyExtentList defines the struct of the dirty extent list.
@@ -59,3 +60,14 @@ func (dl *DirtyExtentList) Len() int {
 	defer dl.RUnlock()
 	return dl.list.Len()
 }
+
+// stat returns the number of the dirty extent handlers and their in-flight packets.
+func (dl *DirtyExtentList) stat() (handlers int, inflight int64) {
+	dl.RLock()
+	defer dl.RUnlock()
+	for e := dl.list.Front(); e != nil; e = e.Next() {
+		handlers++
+		inflight += int64(atomic.LoadInt32(&e.Value.(*ExtentHandler).inflight))
+	}
+	return
+}
@@ -24,7 +24,9 @@ import (
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 // State machines
@@ -81,6 +83,10 @@ type ExtentHandler struct {
 	// To wake up *waitForFlush*.
 	empty chan struct{}
 
+	// Limits the packets sent by the stream writer but not yet replied.
+	// Acquired in *flushPacket*, and released in the receiver.
+	window chan struct{}
+
 	// Created and updated in *receiver* ONLY.
 	// Not protected by lock, therefore can be used ONLY when there is no
 	// pending and new packets.
@@ -116,6 +122,7 @@ func NewExtentHandler(stream *Streamer, offset int, storeMode int) *ExtentHandle
 		fileOffset:   offset,
 		storeMode:    storeMode,
 		empty:        make(chan struct{}, 1024),
+		window:       make(chan struct{}, stream.client.maxInflightPackets),
 		request:      make(chan *Packet, 1024),
 		reply:        make(chan *Packet, 1024),
 		doneSender:   make(chan struct{}),
@@ -152,7 +159,7 @@ func (eh *ExtentHandler) write(data []byte, offset, size int, direct bool) (ek *
 	// If this write request is not continuous, and cannot be merged
 	// into the extent handler, just close it and return error.
 	// In this case, the caller should try to create a new extent handler.
-	if eh.fileOffset+eh.size != offset || eh.size+size > util.ExtentSize ||
+	if eh.fileOffset+eh.size != offset || eh.size+size > eh.stream.client.writeStripeSize ||
 		(eh.storeMode == proto.TinyExtentType && eh.size+size > blksize) {
 
 		err = errors.New("ExtentHandler: full or incontinuous")
@@ -235,6 +242,10 @@ func (eh *ExtentHandler) sender() {
 			packet.ArgLen = uint32(len(packet.Arg))
 			packet.RemainingFollowers = uint8(len(eh.dp.Hosts) - 1)
 			packet.StartT = time.Now().UnixNano()
+			packet.span = tracing.StartSpan(packet.GetOpMsg(), tracing.SpanKindClient, tracing.SpanContext{})
+			packet.span.SetAttribute(tracing.AttributePeer, eh.dp.Hosts[0])
+			packet.span.SetAttribute(tracing.AttributePartitionID, packet.PartitionID)
+			packet.Trace = packet.span.Context()
 
 			//log.LogDebugf("ExtentHandler sender: extent allocated, eh(%v) dp(%v) extID(%v) packet(%v)", eh, eh.dp, eh.extID, packet.GetUniqueLogId())
 
@@ -275,7 +286,14 @@ func (eh *ExtentHandler) receiver() {
 }
 
 func (eh *ExtentHandler) processReply(packet *Packet) {
+	// Only the packet flushed by this handler holds a slot of the window, the packet resent by recovery
+	// does not acquire a slot of the recovery handler. Cleared before the packet is handed over to recovery.
+	inWindow := packet.inWindow
+	packet.inWindow = false
 	defer func() {
+		if inWindow {
+			<-eh.window
+		}
 		if atomic.AddInt32(&eh.inflight, -1) <= 0 {
 			eh.empty <- struct{}{}
 		}
@@ -299,6 +317,11 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 
 	reply := NewReply(packet.ReqID, packet.PartitionID, packet.ExtentID)
 	err := reply.ReadFromConn(eh.conn, proto.ReadDeadlineTime)
+	if err == nil {
+		packet.span.SetAttribute(tracing.AttributeResult, reply.GetResultMsg())
+	}
+	packet.span.Finish(err)
+	packet.span = nil
 	if err != nil {
 		eh.processReplyError(packet, err.Error())
 		return
@@ -307,6 +330,9 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 	log.LogDebugf("processReply: get reply, eh(%v) packet(%v) reply(%v)", eh, packet, reply)
 
 	if reply.ResultCode != proto.OpOk {
+		if reply.ResultCode == proto.OpCrcErr {
+			exporter.NewCounter(MetricWriteCrcErrors).Add(1)
+		}
 		errmsg := fmt.Sprintf("reply NOK: reply(%v)", reply)
 		eh.processReplyError(packet, errmsg)
 		return
@@ -447,7 +473,7 @@ func (eh *ExtentHandler) waitForFlush() {
 
 func (eh *ExtentHandler) recoverPacket(packet *Packet) error {
 	packet.errCount++
-	if packet.errCount >= MaxPacketErrorCount {
+	if packet.errCount >= eh.stream.client.packetRetryLimit {
 		return errors.New(fmt.Sprintf("recoverPacket failed: reach max error limit, eh(%v) packet(%v)", eh, packet))
 	}
 
@@ -588,6 +614,8 @@ func (eh *ExtentHandler) flushPacket() {
 		return
 	}
 
+	eh.window <- struct{}{}
+	eh.packet.inWindow = true
 	eh.pushToRequest(eh.packet)
 	eh.packet = nil
 }
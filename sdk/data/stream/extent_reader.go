@@ -20,9 +20,23 @@ import (
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
 	"hash/crc32"
 	"net"
+	"time"
+)
+
+const (
+	MetricVerifiedReadBytes  = "verifiedReadBytes"
+	MetricVerifiedReadErrors = "verifiedReadErrors"
+	MetricReadCrcErrors      = "readCrcErrors"
+	MetricReadCacheHitBytes  = "readCacheHitBytes"
+	MetricReadCacheMissBytes = "readCacheMissBytes"
+)
+
+var (
+	ErrReadCrcMismatch = errors.New("checkStreamReply: inconsistent CRC")
 )
 
 // ExtentReader defines the struct of the extent reader.
@@ -31,15 +45,19 @@ type ExtentReader struct {
 	key          *proto.ExtentKey
 	dp           *wrapper.DataPartition
 	followerRead bool
+	verifyRead   bool
+	readDeadline bool
 }
 
 // NewExtentReader returns a new extent reader.
-func NewExtentReader(inode uint64, key *proto.ExtentKey, dp *wrapper.DataPartition, followerRead bool) *ExtentReader {
+func NewExtentReader(inode uint64, key *proto.ExtentKey, dp *wrapper.DataPartition, followerRead, verifyRead, readDeadline bool) *ExtentReader {
 	return &ExtentReader{
 		inode:        inode,
 		key:          key,
 		dp:           dp,
 		followerRead: followerRead,
+		verifyRead:   verifyRead,
+		readDeadline: readDeadline,
 	}
 }
 
@@ -55,11 +73,16 @@ func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error)
 	size := req.Size
 
 	reqPacket := NewReadPacket(reader.key, offset, size, reader.inode, req.FileOffset, reader.followerRead)
+	if reader.readDeadline {
+		// Every block of the reply is waited for the read deadline at most.
+		blocks := (size + util.ReadBlockSize - 1) / util.ReadBlockSize
+		reqPacket.timeout = time.Duration(blocks*proto.ReadDeadlineTime) * time.Second
+	}
 	sc := NewStreamConn(reader.dp, reader.followerRead)
 
 	log.LogDebugf("ExtentReader Read enter: size(%v) req(%v) reqPacket(%v)", size, req, reqPacket)
 
-	err = sc.Send(reqPacket, func(conn *net.TCPConn) (error, bool) {
+	err = sc.Send(reqPacket, func(conn net.Conn) (error, bool) {
 		readBytes = 0
 		for readBytes < size {
 			replyPacket := NewReply(reqPacket.ReqID, reader.dp.PartitionID, reqPacket.ExtentID)
@@ -79,6 +102,14 @@ func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error)
 			}
 
 			e = reader.checkStreamReply(reqPacket, replyPacket)
+			if e == ErrReadCrcMismatch {
+				// The block is corrupted on the wire or by the replica, read it from another replica.
+				exporter.NewCounter(MetricReadCrcErrors).Add(1)
+				if reader.verifyRead {
+					exporter.NewCounter(MetricVerifiedReadErrors).Add(1)
+				}
+				return TryOtherAddrError, false
+			}
 			if e != nil {
 				// Dont change the error message, since the caller will
 				// check if it is NotLeaderErr.
@@ -92,6 +123,8 @@ func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error)
 
 	if err != nil {
 		log.LogErrorf("Extent Reader Read: err(%v) req(%v) reqPacket(%v)", err, req, reqPacket)
+	} else if reader.verifyRead {
+		exporter.NewCounter(MetricVerifiedReadBytes).Add(int64(readBytes))
 	}
 
 	log.LogDebugf("ExtentReader Read exit: req(%v) reqPacket(%v) readBytes(%v) err(%v)", req, reqPacket, readBytes, err)
@@ -118,8 +151,9 @@ func (reader *ExtentReader) checkStreamReply(request *Packet, reply *Packet) (er
 	}
 	expectCrc := crc32.ChecksumIEEE(reply.Data[:reply.Size])
 	if reply.CRC != expectCrc {
-		err = errors.New(fmt.Sprintf("checkStreamReply: inconsistent CRC, expectCRC(%v) replyCRC(%v)", expectCrc, reply.CRC))
-		return
+		log.LogWarnf("checkStreamReply: inconsistent CRC, expectCRC(%v) replyCRC(%v) req(%v) reply(%v)",
+			expectCrc, reply.CRC, request, reply)
+		return ErrReadCrcMismatch
 	}
 	return nil
 }
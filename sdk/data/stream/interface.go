@@ -0,0 +1,33 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+// Client is the interface of ExtentClient used to read and write files. Programs which take the
+// interface instead of *ExtentClient can be unit tested with the in-memory fake in package streamtest.
+type Client interface {
+	OpenStream(inode uint64) error
+	CloseStream(inode uint64) error
+	EvictStream(inode uint64) error
+	RefreshExtentsCache(inode uint64) error
+	FileSize(inode uint64) (size int, gen uint64, valid bool)
+	SetFileSize(inode uint64, size int)
+	Write(inode uint64, offset int, data []byte, flags int) (write int, err error)
+	Truncate(inode uint64, size int) error
+	Flush(inode uint64) error
+	Read(inode uint64, data []byte, offset int, size int) (read int, err error)
+	Close() error
+}
+
+var _ Client = (*ExtentClient)(nil)
@@ -20,6 +20,7 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
 	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/tracing"
 	"hash/crc32"
 	"io"
 	"net"
@@ -31,6 +32,9 @@ type Packet struct {
 	proto.Packet
 	inode    uint64
 	errCount int
+	inWindow bool          // the packet holds a slot of the window of the handler which flushed it
+	timeout  time.Duration // the deadline of the request is set to the timeout from every send if positive
+	span     *tracing.Span // span of the write request from the send until the reply is received
 }
 
 // String returns the string format of the packet.
@@ -160,6 +164,8 @@ func (p *Packet) readFromConn(c net.Conn, deadlineTime time.Duration) (err error
 			return
 		}
 	}
+	p.UnmarshalTrace()
+	p.UnmarshalDeadline()
 
 	if p.Size < 0 {
 		return
@@ -20,10 +20,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 var (
@@ -35,7 +37,7 @@ const (
 	StreamSendSleepInterval = 100 * time.Millisecond
 )
 
-type GetReplyFunc func(conn *net.TCPConn) (err error, again bool)
+type GetReplyFunc func(conn net.Conn) (err error, again bool)
 
 // StreamConn defines the struct of the stream connection.
 type StreamConn struct {
@@ -45,6 +47,9 @@ type StreamConn struct {
 
 var (
 	StreamConnPool = util.NewConnectPool()
+	// StreamMuxPool is nil unless the multiplexed connections are enabled by an extent client. The writes are
+	// always sent over the connections of StreamConnPool, since the packets of an extent handler are ordered.
+	StreamMuxPool *proto.MuxPool
 )
 
 // NewStreamConn returns a new stream connection.
@@ -98,15 +103,15 @@ func (sc *StreamConn) Send(req *Packet, getReply GetReplyFunc) (err error) {
 }
 
 func (sc *StreamConn) sendToPartition(req *Packet, getReply GetReplyFunc) (err error) {
-	conn, err := StreamConnPool.GetConnect(sc.currAddr)
+	conn, err := StreamMuxPool.GetConnect(StreamConnPool, sc.currAddr)
 	if err == nil {
 		err = sc.sendToConn(conn, req, getReply)
 		if err == nil {
-			StreamConnPool.PutConnect(conn, false)
+			StreamMuxPool.PutConnect(StreamConnPool, conn, false)
 			return
 		}
 		log.LogWarnf("sendToPartition: send to curr addr failed, addr(%v) reqPacket(%v) err(%v)", sc.currAddr, req, err)
-		StreamConnPool.PutConnect(conn, true)
+		StreamMuxPool.PutConnect(StreamConnPool, conn, true)
 		if err != TryOtherAddrError {
 			return
 		}
@@ -118,7 +123,7 @@ func (sc *StreamConn) sendToPartition(req *Packet, getReply GetReplyFunc) (err e
 
 	for _, addr := range hosts {
 		log.LogWarnf("sendToPartition: try addr(%v) reqPacket(%v)", addr, req)
-		conn, err = StreamConnPool.GetConnect(addr)
+		conn, err = StreamMuxPool.GetConnect(StreamConnPool, addr)
 		if err != nil {
 			log.LogWarnf("sendToPartition: failed to get connection to addr(%v) reqPacket(%v) err(%v)", addr, req, err)
 			continue
@@ -127,10 +132,10 @@ func (sc *StreamConn) sendToPartition(req *Packet, getReply GetReplyFunc) (err e
 		sc.dp.LeaderAddr = addr
 		err = sc.sendToConn(conn, req, getReply)
 		if err == nil {
-			StreamConnPool.PutConnect(conn, false)
+			StreamMuxPool.PutConnect(StreamConnPool, conn, false)
 			return
 		}
-		StreamConnPool.PutConnect(conn, true)
+		StreamMuxPool.PutConnect(StreamConnPool, conn, true)
 		if err != TryOtherAddrError {
 			return
 		}
@@ -139,9 +144,19 @@ func (sc *StreamConn) sendToPartition(req *Packet, getReply GetReplyFunc) (err e
 	return errors.New(fmt.Sprintf("sendToPatition Failed: sc(%v) reqPacket(%v)", sc, req))
 }
 
-func (sc *StreamConn) sendToConn(conn *net.TCPConn, req *Packet, getReply GetReplyFunc) (err error) {
+func (sc *StreamConn) sendToConn(conn net.Conn, req *Packet, getReply GetReplyFunc) (err error) {
+	span := tracing.StartSpan(req.GetOpMsg(), tracing.SpanKindClient, tracing.SpanContext{})
+	span.SetAttribute(tracing.AttributePeer, sc.currAddr)
+	span.SetAttribute(tracing.AttributePartitionID, req.PartitionID)
+	req.Trace = span.Context()
+	defer func() {
+		span.Finish(err)
+	}()
 	for i := 0; i < StreamSendMaxRetry; i++ {
 		log.LogDebugf("sendToConn: send to addr(%v), reqPacket(%v)", sc.currAddr, req)
+		if req.timeout > 0 {
+			req.SetTimeout(req.timeout)
+		}
 		err = req.WriteToConn(conn)
 		if err != nil {
 			msg := fmt.Sprintf("sendToConn: failed to write to addr(%v) err(%v)", sc.currAddr, err)
@@ -0,0 +1,171 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultReadAheadSize = 4 * util.MB
+	readAheadMinWindow   = 128 * util.KB
+)
+
+// readAhead prefetches the data following the sequential reads of a file, so that a sequential scan does not wait
+// for a round-trip to the data nodes on every read. The window starts small and is doubled by every prefetch up to
+// the max window, and it is dropped once a random read is detected, so the random reads are not slowed down.
+type readAhead struct {
+	sync.Mutex
+	stream    *Streamer
+	maxWindow int
+	window    int    // size of the next prefetch, 0 if the reads are not sequential
+	next      int    // the offset following the latest sequential read
+	buf       []byte // the prefetched data from bufOffset
+	bufOffset int
+	fetching  *prefetch // the prefetch in flight, nil if none
+}
+
+type prefetch struct {
+	offset int
+	data   []byte
+	n      int
+	err    error
+	done   chan struct{}
+}
+
+func newReadAhead(stream *Streamer, maxWindow int) *readAhead {
+	return &readAhead{stream: stream, maxWindow: maxWindow}
+}
+
+func (ra *readAhead) buffered(offset int) bool {
+	return offset >= ra.bufOffset && offset < ra.bufOffset+len(ra.buf)
+}
+
+func (ra *readAhead) fetched(offset int) bool {
+	f := ra.fetching
+	return f != nil && offset >= f.offset && offset < f.offset+len(f.data)
+}
+
+// read serves the read from the prefetched data, and reads the rest from the data nodes. The reads falling in the
+// prefetched data are taken as sequential too, since the kernel may send the sequential reads out of order.
+func (ra *readAhead) read(data []byte, offset, size int) (total int, err error) {
+	ra.Lock()
+	if offset != ra.next && !ra.buffered(offset) && !ra.fetched(offset) {
+		if ra.window > 0 {
+			log.LogDebugf("readAhead: random read, ino(%v) offset(%v) next(%v)", ra.stream.inode, offset, ra.next)
+		}
+		ra.window = 0
+		ra.next = offset + size
+		ra.drop()
+		ra.Unlock()
+		return ra.stream.read(data, offset, size)
+	}
+	if offset+size > ra.next {
+		ra.next = offset + size
+	}
+	if ra.window == 0 {
+		ra.window = util.Min(util.Max(readAheadMinWindow, 2*size), ra.maxWindow)
+	}
+
+	for total < size {
+		cur := offset + total
+		if ra.buffered(cur) {
+			total += copy(data[total:size], ra.buf[cur-ra.bufOffset:])
+			continue
+		}
+		if !ra.fetched(cur) {
+			break
+		}
+		f := ra.fetching
+		ra.Unlock()
+		<-f.done
+		ra.Lock()
+		// the prefetch may have been taken by another read, or dropped by a random read or a change of the file
+		if ra.fetching == f {
+			ra.fetching = nil
+			if f.n > 0 && (f.err == nil || f.err == io.EOF) {
+				ra.buf, ra.bufOffset = f.data[:f.n], f.offset
+			}
+		}
+		if !ra.buffered(cur) {
+			break
+		}
+	}
+	if total > 0 {
+		atomic.AddUint64(&ra.stream.client.stat.readAheadHitBytes, uint64(total))
+	}
+	ra.prefetch()
+	ra.Unlock()
+
+	if total < size {
+		var n int
+		n, err = ra.stream.read(data[total:size], offset+total, size-total)
+		total += n
+	}
+	return
+}
+
+// prefetch starts to read the next window if the prefetched data after the sequential reads is running out.
+// The lock is held by the caller.
+func (ra *readAhead) prefetch() {
+	if ra.fetching != nil {
+		return
+	}
+	start := ra.next
+	if ra.buffered(start) {
+		end := ra.bufOffset + len(ra.buf)
+		if end-start >= ra.window/2 {
+			return
+		}
+		start = end
+	}
+	filesize, _ := ra.stream.extents.Size()
+	size := util.Min(ra.window, filesize-start)
+	if size <= 0 {
+		return
+	}
+
+	f := &prefetch{offset: start, data: make([]byte, size), done: make(chan struct{})}
+	ra.fetching = f
+	ra.window = util.Min(ra.window*2, ra.maxWindow)
+	go func() {
+		f.n, f.err = ra.stream.read(f.data, f.offset, len(f.data))
+		if f.err != nil && f.err != io.EOF {
+			log.LogWarnf("readAhead: ino(%v) offset(%v) size(%v) err(%v)", ra.stream.inode, f.offset, len(f.data), f.err)
+		}
+		close(f.done)
+	}()
+}
+
+// drop drops the prefetched data, and the prefetch in flight is ignored once it is done.
+func (ra *readAhead) drop() {
+	ra.buf, ra.bufOffset = nil, 0
+	ra.fetching = nil
+}
+
+// invalidate drops the prefetched data once the file is changed.
+func (ra *readAhead) invalidate() {
+	if ra == nil {
+		return
+	}
+	ra.Lock()
+	ra.drop()
+	ra.Unlock()
+}
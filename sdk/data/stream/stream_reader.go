@@ -19,8 +19,12 @@ import (
 	"golang.org/x/net/context"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/blockcache"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
@@ -48,6 +52,10 @@ type Streamer struct {
 	done    chan struct{}    // stream writer is being closed
 
 	writeLock sync.Mutex
+
+	readahead *readAhead // nil if read-ahead is disabled
+
+	mtime int64 // the modify time updated by the overwrites last
 }
 
 // NewStreamer returns a new streamer.
@@ -59,6 +67,9 @@ func NewStreamer(client *ExtentClient, inode uint64) *Streamer {
 	s.request = make(chan interface{}, 64)
 	s.done = make(chan struct{})
 	s.dirtylist = NewDirtyExtentList()
+	if client.readAheadSize > 0 {
+		s.readahead = newReadAhead(s, client.readAheadSize)
+	}
 	go s.server()
 	return s
 }
@@ -70,6 +81,7 @@ func (s *Streamer) String() string {
 
 // TODO should we call it RefreshExtents instead?
 func (s *Streamer) GetExtents() error {
+	defer s.readahead.invalidate()
 	return s.extents.Refresh(s.inode, s.client.getExtents)
 }
 
@@ -80,7 +92,7 @@ func (s *Streamer) GetExtentReader(ek *proto.ExtentKey) (*ExtentReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	reader := NewExtentReader(s.inode, ek, partition, s.client.dataWrapper.FollowerRead())
+	reader := NewExtentReader(s.inode, ek, partition, s.client.dataWrapper.FollowerRead(), s.client.dataWrapper.VerifyRead(), s.client.readDeadline)
 	return reader, nil
 }
 
@@ -94,6 +106,7 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 
 	ctx := context.Background()
 	s.client.readLimiter.Wait(ctx)
+	s.client.dataWrapper.WaitQos(ctx, size)
 
 	requests = s.extents.PrepareReadRequests(offset, size, data)
 	for _, req := range requests {
@@ -145,7 +158,7 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 			if err != nil {
 				break
 			}
-			readBytes, err = reader.Read(req)
+			readBytes, err = s.readExtent(reader, req)
 			log.LogDebugf("Stream read: ino(%v) req(%v) readBytes(%v) err(%v)", s.inode, req, readBytes, err)
 			total += readBytes
 			if err != nil || readBytes < req.Size {
@@ -158,3 +171,46 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 	}
 	return
 }
+
+// readExtent reads the request from the local block cache if it is enabled. The blocks covered by the request
+// are read from the data nodes and cached on a miss. The requests covering the blocks partially beyond the range
+// of the extent key, such as the tail of a file, are always read from the data nodes since the blocks may still grow.
+func (s *Streamer) readExtent(reader *ExtentReader, req *ExtentRequest) (readBytes int, err error) {
+	cache := s.client.blockCache
+	if cache == nil {
+		return reader.Read(req)
+	}
+	ek := req.ExtentKey
+	start := uint64(req.FileOffset) - ek.FileOffset + ek.ExtentOffset
+	end := start + uint64(req.Size)
+	firstBlock := start / blockcache.BlockSize * blockcache.BlockSize
+	lastBlock := (end + blockcache.BlockSize - 1) / blockcache.BlockSize * blockcache.BlockSize
+	if firstBlock < ek.ExtentOffset || lastBlock > ek.ExtentOffset+uint64(ek.Size) {
+		return reader.Read(req)
+	}
+
+	block := make([]byte, blockcache.BlockSize)
+	for blockOffset := firstBlock; blockOffset < lastBlock; blockOffset += blockcache.BlockSize {
+		if cache.Get(ek.PartitionId, ek.ExtentId, blockOffset, block) {
+			exporter.NewCounter(MetricReadCacheHitBytes).Add(int64(blockcache.BlockSize))
+			atomic.AddUint64(&s.client.stat.readCacheHitBytes, blockcache.BlockSize)
+		} else {
+			epoch := cache.Epoch()
+			blockReq := NewExtentRequest(int(ek.FileOffset+blockOffset-ek.ExtentOffset), blockcache.BlockSize, block, ek)
+			if n, e := reader.Read(blockReq); e != nil || n < blockcache.BlockSize {
+				// fall back to reading the rest of the request without the cache
+				restReq := NewExtentRequest(req.FileOffset+readBytes, req.Size-readBytes, req.Data[readBytes:], ek)
+				n, err = reader.Read(restReq)
+				return readBytes + n, err
+			}
+			cache.Put(ek.PartitionId, ek.ExtentId, blockOffset, block, epoch)
+			exporter.NewCounter(MetricReadCacheMissBytes).Add(int64(blockcache.BlockSize))
+			atomic.AddUint64(&s.client.stat.readCacheMissBytes, blockcache.BlockSize)
+		}
+		copyStart := util.Max(int(start), int(blockOffset))
+		copyEnd := util.Min(int(end), int(blockOffset)+blockcache.BlockSize)
+		copy(req.Data[copyStart-int(start):copyEnd-int(start)], block[copyStart-int(blockOffset):copyEnd-int(blockOffset)])
+		readBytes += copyEnd - copyStart
+	}
+	return
+}
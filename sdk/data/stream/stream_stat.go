@@ -0,0 +1,124 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// the upper bounds of the buckets of the flush latency histogram
+var flushLatencyBounds = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+type latencyHistogram struct {
+	counts []uint64 // the last one counts the latencies beyond all the bounds
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(flushLatencyBounds)+1)}
+}
+
+func (h *latencyHistogram) observe(latency time.Duration) {
+	i := 0
+	for i < len(flushLatencyBounds) && latency > flushLatencyBounds[i] {
+		i++
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+}
+
+func (h *latencyHistogram) buckets() []proto.ClientLatencyBucket {
+	buckets := make([]proto.ClientLatencyBucket, 0, len(h.counts))
+	for i := range h.counts {
+		bound := "+Inf"
+		if i < len(flushLatencyBounds) {
+			bound = flushLatencyBounds[i].String()
+		}
+		buckets = append(buckets, proto.ClientLatencyBucket{Bound: bound, Count: atomic.LoadUint64(&h.counts[i])})
+	}
+	return buckets
+}
+
+type streamStat struct {
+	flushes            uint64
+	flushErrors        uint64
+	flushLatency       *latencyHistogram
+	readCacheHitBytes  uint64
+	readCacheMissBytes uint64
+	readAheadHitBytes  uint64
+}
+
+func newStreamStat() *streamStat {
+	return &streamStat{flushLatency: newLatencyHistogram()}
+}
+
+func (st *streamStat) observeFlush(start time.Time, err error) {
+	atomic.AddUint64(&st.flushes, 1)
+	if err != nil {
+		atomic.AddUint64(&st.flushErrors, 1)
+	}
+	st.flushLatency.observe(time.Since(start))
+}
+
+// StreamStat returns the statistics of the streams of the client.
+func (client *ExtentClient) StreamStat() *proto.ClientStreamStat {
+	stat := &proto.ClientStreamStat{
+		Flushes:       atomic.LoadUint64(&client.stat.flushes),
+		FlushErrors:   atomic.LoadUint64(&client.stat.flushErrors),
+		FlushLatency:  client.stat.flushLatency.buckets(),
+		ReadAheadHits: atomic.LoadUint64(&client.stat.readAheadHitBytes),
+	}
+	client.streamerLock.Lock()
+	streamers := make([]*Streamer, 0, len(client.streamers))
+	for _, s := range client.streamers {
+		streamers = append(streamers, s)
+	}
+	client.streamerLock.Unlock()
+	stat.OpenStreams = len(streamers)
+	for _, s := range streamers {
+		handlers, inflight := s.dirtylist.stat()
+		if handlers > 0 {
+			stat.DirtyStreams++
+		}
+		stat.DirtyHandlers += handlers
+		stat.InflightPackets += inflight
+	}
+	return stat
+}
+
+// ReadCacheStat returns the statistics of the local block cache of the client.
+func (client *ExtentClient) ReadCacheStat() *proto.ClientReadCacheStat {
+	stat := &proto.ClientReadCacheStat{
+		HitBytes:  atomic.LoadUint64(&client.stat.readCacheHitBytes),
+		MissBytes: atomic.LoadUint64(&client.stat.readCacheMissBytes),
+	}
+	if client.blockCache != nil {
+		stat.Enabled = true
+		stat.Size = client.blockCache.Size()
+		stat.Capacity = client.blockCache.Capacity()
+	}
+	return stat
+}
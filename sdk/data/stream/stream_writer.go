@@ -15,6 +15,7 @@
 package stream
 
 import (
+	"container/list"
 	"fmt"
 	"golang.org/x/net/context"
 	"hash/crc32"
@@ -27,14 +28,17 @@ import (
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
 const (
 	MaxSelectDataPartitionForWrite = 32
 	MaxNewHandlerRetry             = 3
-	MaxPacketErrorCount            = 32
-	MaxDirtyListLen                = 0
+)
+
+const (
+	MetricWriteCrcErrors = "writeCrcErrors"
 )
 
 const (
@@ -82,6 +86,14 @@ type TruncRequest struct {
 	done chan struct{}
 }
 
+// PunchRequest defines a punch hole request.
+type PunchRequest struct {
+	offset int
+	size   int
+	err    error
+	done   chan struct{}
+}
+
 // EvictRequest defines an evict request.
 type EvictRequest struct {
 	err  error
@@ -153,6 +165,18 @@ func (s *Streamer) IssueTruncRequest(size int) error {
 	return err
 }
 
+func (s *Streamer) IssuePunchRequest(offset, size int) error {
+	request := punchRequestPool.Get().(*PunchRequest)
+	request.offset = offset
+	request.size = size
+	request.done = make(chan struct{}, 1)
+	s.request <- request
+	<-request.done
+	err := request.err
+	punchRequestPool.Put(request)
+	return err
+}
+
 func (s *Streamer) IssueEvictRequest() error {
 	request := evictRequestPool.Get().(*EvictRequest)
 	request.done = make(chan struct{}, 1)
@@ -222,6 +246,9 @@ func (s *Streamer) abortRequest(request interface{}) {
 	case *TruncRequest:
 		request.err = syscall.EAGAIN
 		request.done <- struct{}{}
+	case *PunchRequest:
+		request.err = syscall.EAGAIN
+		request.done <- struct{}{}
 	case *FlushRequest:
 		request.err = syscall.EAGAIN
 		request.done <- struct{}{}
@@ -246,6 +273,9 @@ func (s *Streamer) handleRequest(request interface{}) {
 	case *TruncRequest:
 		request.err = s.truncate(request.size)
 		request.done <- struct{}{}
+	case *PunchRequest:
+		request.err = s.punch(request.offset, request.size)
+		request.done <- struct{}{}
 	case *FlushRequest:
 		request.err = s.flush()
 		request.done <- struct{}{}
@@ -272,9 +302,12 @@ func (s *Streamer) write(data []byte, offset, size, flags int) (total int, err e
 	}
 
 	log.LogDebugf("Streamer write enter: ino(%v) offset(%v) size(%v)", s.inode, offset, size)
+	// the data prefetched during the write is dropped as well
+	defer s.readahead.invalidate()
 
 	ctx := context.Background()
 	s.client.writeLimiter.Wait(ctx)
+	s.client.dataWrapper.WaitQos(ctx, size)
 
 	requests := s.extents.PrepareWriteRequests(offset, size, data)
 	log.LogDebugf("Streamer write: ino(%v) prepared requests(%v)", s.inode, requests)
@@ -293,10 +326,12 @@ func (s *Streamer) write(data []byte, offset, size, flags int) (total int, err e
 		break
 	}
 
+	var overwritten bool
 	for _, req := range requests {
 		var writeSize int
 		if req.ExtentKey != nil {
 			writeSize, err = s.doOverwrite(req, direct)
+			overwritten = overwritten || writeSize > 0
 		} else {
 			writeSize, err = s.doWrite(req.Data, req.FileOffset, req.Size, direct)
 		}
@@ -306,10 +341,20 @@ func (s *Streamer) write(data []byte, offset, size, flags int) (total int, err e
 		}
 		total += writeSize
 	}
+	if overwritten {
+		s.touchModifyTime()
+	}
 	if filesize, _ := s.extents.Size(); offset+total > filesize {
 		s.extents.SetSize(uint64(offset+total), false)
 		log.LogDebugf("Streamer write: ino(%v) filesize changed to (%v)", s.inode, offset+total)
 	}
+	// The flush is done in the same request, so that the direct write is not interleaved with the other writes
+	// before its data reaches the data nodes.
+	if err == nil && flags&proto.FlagsDirectWrite != 0 {
+		if err = s.flush(); err != nil {
+			log.LogErrorf("Streamer write: ino(%v) failed to flush direct write, err(%v)", s.inode, err)
+		}
+	}
 	log.LogDebugf("Streamer write exit: ino(%v) offset(%v) size(%v) done total(%v) err(%v)", s.inode, offset, size, total, err)
 	return
 }
@@ -341,6 +386,11 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		return
 	}
 
+	// the cached blocks are invalidated both before and after the overwrite,
+	// so that the blocks read during the overwrite are not cached
+	s.invalidateBlockCache(req.ExtentKey, offset-ekFileOffset+ekExtOffset, size)
+	defer s.invalidateBlockCache(req.ExtentKey, offset-ekFileOffset+ekExtOffset, size)
+
 	sc := NewStreamConn(dp, false)
 
 	for total < size {
@@ -354,7 +404,7 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		reqPacket.CRC = crc32.ChecksumIEEE(reqPacket.Data[:packSize])
 
 		replyPacket := new(Packet)
-		err = sc.Send(reqPacket, func(conn *net.TCPConn) (error, bool) {
+		err = sc.Send(reqPacket, func(conn net.Conn) (error, bool) {
 			e := replyPacket.ReadFromConn(conn, proto.ReadDeadlineTime)
 			if e != nil {
 				log.LogWarnf("Stream Writer doOverwrite: ino(%v) failed to read from connect, req(%v) err(%v)", s.inode, reqPacket, e)
@@ -366,6 +416,13 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 				return nil, true
 			}
 
+			if replyPacket.ResultCode == proto.OpCrcErr {
+				// The data is corrupted on the way to the leader, send it again.
+				log.LogWarnf("Stream Writer doOverwrite: ino(%v) crc mismatch, req(%v) reply(%v)", s.inode, reqPacket, replyPacket)
+				exporter.NewCounter(MetricWriteCrcErrors).Add(1)
+				return nil, true
+			}
+
 			if replyPacket.ResultCode == proto.OpTryOtherAddr {
 				e = TryOtherAddrError
 			}
@@ -376,6 +433,13 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		reqPacket.Data = nil
 		log.LogDebugf("doOverwrite: ino(%v) req(%v) reqPacket(%v) err(%v) replyPacket(%v)", s.inode, req, reqPacket, err, replyPacket)
 
+		if err == nil && replyPacket.ResultCode == proto.OpNotPerm {
+			// the data is in the retention period of the volume
+			log.LogWarnf("doOverwrite: ino(%v) req(%v) refused: %v", s.inode, req, replyPacket.GetResultMsg())
+			err = syscall.EPERM
+			break
+		}
+
 		if err != nil || replyPacket.ResultCode != proto.OpOk {
 			err = errors.New(fmt.Sprintf("doOverwrite: failed or reply NOK: err(%v) ino(%v) req(%v) replyPacket(%v)", err, s.inode, req, replyPacket))
 			break
@@ -392,7 +456,55 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 	return
 }
 
+// touchModifyTime updates the modify time of the file after it is overwritten, since the overwrites are only
+// sent to the data nodes. The modify time is updated at most once a second, which is its precision.
+func (s *Streamer) touchModifyTime() {
+	if s.client.updateModifyTime == nil {
+		return
+	}
+	now := time.Now().Unix()
+	if now == s.mtime {
+		return
+	}
+	if err := s.client.updateModifyTime(s.inode, now); err != nil {
+		log.LogWarnf("touchModifyTime: ino(%v) err(%v)", s.inode, err)
+		return
+	}
+	s.mtime = now
+}
+
+func (s *Streamer) invalidateBlockCache(ek *proto.ExtentKey, extentOffset, size int) {
+	if s.client.blockCache != nil {
+		s.client.blockCache.Invalidate(ek.PartitionId, ek.ExtentId, uint64(extentOffset), uint64(size))
+	}
+}
+
+// doWrite splits the new data into the stripes of the extent handlers, so that a large write is sent to
+// several extents concurrently if the file is written by streams.
 func (s *Streamer) doWrite(data []byte, offset, size int, direct bool) (total int, err error) {
+	var write int
+	for total < size {
+		write = util.Min(size-total, s.stripeRoom(offset+total))
+		if write, err = s.doWriteStripe(data[total:total+write], offset+total, write, direct); err != nil {
+			return
+		}
+		total += write
+	}
+	return
+}
+
+// stripeRoom returns the size of the data at offset that can be written to the open handler,
+// or to a new handler if the open handler cannot take it.
+func (s *Streamer) stripeRoom(offset int) int {
+	eh := s.handler
+	if eh != nil && eh.getStatus() == ExtentStatusOpen && eh.storeMode == proto.NormalExtentType &&
+		eh.fileOffset+eh.size == offset && eh.size < s.client.writeStripeSize {
+		return s.client.writeStripeSize - eh.size
+	}
+	return s.client.writeStripeSize
+}
+
+func (s *Streamer) doWriteStripe(data []byte, offset, size int, direct bool) (total int, err error) {
 	var (
 		ek        *proto.ExtentKey
 		storeMode int
@@ -437,33 +549,47 @@ func (s *Streamer) doWrite(data []byte, offset, size int, direct bool) (total in
 }
 
 func (s *Streamer) flush() (err error) {
+	if s.dirtylist.Len() > 0 {
+		start := time.Now()
+		defer func() {
+			s.client.stat.observeFlush(start, err)
+		}()
+	}
 	for {
 		element := s.dirtylist.Get()
 		if element == nil {
 			break
 		}
-		eh := element.Value.(*ExtentHandler)
-
-		log.LogDebugf("Streamer flush begin: eh(%v)", eh)
-		err = eh.flush()
-		if err != nil {
-			log.LogErrorf("Streamer flush failed: eh(%v)", eh)
+		if err = s.flushHandler(element); err != nil {
 			return
 		}
-		eh.stream.dirtylist.Remove(element)
-		if eh.getStatus() == ExtentStatusOpen {
-			s.dirty = false
-			log.LogDebugf("Streamer flush handler open: eh(%v)", eh)
-		} else {
-			// TODO unhandled error
-			eh.cleanup()
-			log.LogDebugf("Streamer flush handler cleaned up: eh(%v)", eh)
-		}
-		log.LogDebugf("Streamer flush end: eh(%v)", eh)
 	}
 	return
 }
 
+// flushHandler waits for the packets of the dirty handler to be replied, and removes it from the dirty list.
+func (s *Streamer) flushHandler(element *list.Element) (err error) {
+	eh := element.Value.(*ExtentHandler)
+
+	log.LogDebugf("Streamer flush begin: eh(%v)", eh)
+	err = eh.flush()
+	if err != nil {
+		log.LogErrorf("Streamer flush failed: eh(%v)", eh)
+		return
+	}
+	eh.stream.dirtylist.Remove(element)
+	if eh.getStatus() == ExtentStatusOpen {
+		s.dirty = false
+		log.LogDebugf("Streamer flush handler open: eh(%v)", eh)
+	} else {
+		// TODO unhandled error
+		eh.cleanup()
+		log.LogDebugf("Streamer flush handler cleaned up: eh(%v)", eh)
+	}
+	log.LogDebugf("Streamer flush end: eh(%v)", eh)
+	return
+}
+
 func (s *Streamer) traverse() (err error) {
 	s.traversed++
 	length := s.dirtylist.Len()
@@ -502,22 +628,27 @@ func (s *Streamer) traverse() (err error) {
 	return
 }
 
+// closeOpenHandler sends the last packet of the open handler without waiting for the replies, so the packets of
+// the closed handlers are in flight while the next handler is written. Once the closed handlers reach the write
+// streams, the oldest ones are flushed to make room for the next handler.
 func (s *Streamer) closeOpenHandler() {
-	if s.handler != nil {
-		s.handler.setClosed()
-		if s.dirtylist.Len() < MaxDirtyListLen {
-			s.handler.flushPacket()
-		} else {
-			// TODO unhandled error
-			s.handler.flush()
-		}
+	if s.handler == nil {
+		return
+	}
+	s.handler.setClosed()
+	s.handler.flushPacket()
+	if !s.dirty {
+		// in case the current handler is not on the dirty list and will not get cleaned up
+		// TODO unhandled error
+		s.handler.cleanup()
+	}
+	s.handler = nil
 
-		if !s.dirty {
-			// in case the current handler is not on the dirty list and will not get cleaned up
-			// TODO unhandled error
-			s.handler.cleanup()
+	for s.dirtylist.Len() >= s.client.writeStreams {
+		// the failure is returned by the following flush of the streamer
+		if err := s.flushHandler(s.dirtylist.Get()); err != nil {
+			break
 		}
-		s.handler = nil
 	}
 }
 
@@ -562,6 +693,7 @@ func (s *Streamer) abort() {
 }
 
 func (s *Streamer) truncate(size int) error {
+	defer s.readahead.invalidate()
 	s.closeOpenHandler()
 	err := s.flush()
 	if err != nil {
@@ -582,6 +714,24 @@ func (s *Streamer) truncate(size int) error {
 	return s.GetExtents()
 }
 
+// punch flushes the dirty data before the hole is punched, otherwise the data in the hole
+// would be appended to the extents after the punch.
+func (s *Streamer) punch(offset, size int) error {
+	defer s.readahead.invalidate()
+	s.closeOpenHandler()
+	err := s.flush()
+	if err != nil {
+		return err
+	}
+
+	err = s.client.punchHole(s.inode, uint64(offset), uint64(size))
+	if err != nil {
+		return err
+	}
+
+	return s.GetExtents()
+}
+
 func (s *Streamer) tinySizeLimit() int {
 	return util.DefaultTinySizeLimit
 }
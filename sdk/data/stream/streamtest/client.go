@@ -0,0 +1,229 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package streamtest provides an in-memory fake of stream.ExtentClient, so that programs taking the
+// stream.Client interface can be unit tested without data nodes.
+package streamtest
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+)
+
+// PartitionID is the data partition in the extent keys appended by the fake.
+const PartitionID = 1
+
+var _ stream.Client = (*ExtentClient)(nil)
+
+// ExtentClient is the fake of stream.ExtentClient. The data of the files is kept in memory by the
+// client, and every write appends a new extent key through the callback of the config, so a fake
+// meta wrapper sees the same sizes and extents as it does with a real client. The data is not shared
+// with other clients.
+type ExtentClient struct {
+	sync.Mutex
+	streams     map[uint64]*fakeStream
+	data        map[uint64][]byte
+	maxExtentID uint64
+
+	appendExtentKey stream.AppendExtentKeyFunc
+	getExtents      stream.GetExtentsFunc
+	truncate        stream.TruncateFunc
+}
+
+type fakeStream struct {
+	refCount int
+	size     int
+}
+
+// NewExtentClient returns a fake using the callbacks of the config, the other options are ignored.
+func NewExtentClient(config *stream.ExtentConfig) (client *ExtentClient, err error) {
+	if config.OnAppendExtentKey == nil || config.OnGetExtents == nil || config.OnTruncate == nil {
+		return nil, fmt.Errorf("NewExtentClient: callbacks are required")
+	}
+	return &ExtentClient{
+		streams:         make(map[uint64]*fakeStream),
+		data:            make(map[uint64][]byte),
+		appendExtentKey: config.OnAppendExtentKey,
+		getExtents:      config.OnGetExtents,
+		truncate:        config.OnTruncate,
+	}, nil
+}
+
+func (client *ExtentClient) getStream(inode uint64, op string) (*fakeStream, error) {
+	s, ok := client.streams[inode]
+	if !ok {
+		return nil, fmt.Errorf("%v: stream is not opened yet, ino(%v)", op, inode)
+	}
+	return s, nil
+}
+
+// OpenStream loads the size of the file, the data written by other clients is read as zeros.
+func (client *ExtentClient) OpenStream(inode uint64) error {
+	client.Lock()
+	defer client.Unlock()
+	if s, ok := client.streams[inode]; ok {
+		s.refCount++
+		return nil
+	}
+	_, size, _, err := client.getExtents(inode)
+	if err != nil {
+		return err
+	}
+	client.streams[inode] = &fakeStream{refCount: 1, size: int(size)}
+	return nil
+}
+
+func (client *ExtentClient) CloseStream(inode uint64) error {
+	client.Lock()
+	defer client.Unlock()
+	if s, ok := client.streams[inode]; ok && s.refCount > 0 {
+		s.refCount--
+	}
+	return nil
+}
+
+// EvictStream drops the stream if it is closed, the data is kept.
+func (client *ExtentClient) EvictStream(inode uint64) error {
+	client.Lock()
+	defer client.Unlock()
+	if s, ok := client.streams[inode]; ok && s.refCount <= 0 {
+		delete(client.streams, inode)
+	}
+	return nil
+}
+
+func (client *ExtentClient) RefreshExtentsCache(inode uint64) error {
+	client.Lock()
+	defer client.Unlock()
+	s, ok := client.streams[inode]
+	if !ok {
+		return nil
+	}
+	_, size, _, err := client.getExtents(inode)
+	if err != nil {
+		return err
+	}
+	s.size = int(size)
+	return nil
+}
+
+func (client *ExtentClient) FileSize(inode uint64) (size int, gen uint64, valid bool) {
+	client.Lock()
+	defer client.Unlock()
+	s, ok := client.streams[inode]
+	if !ok {
+		return
+	}
+	return s.size, 0, true
+}
+
+func (client *ExtentClient) SetFileSize(inode uint64, size int) {
+	client.Lock()
+	defer client.Unlock()
+	if s, ok := client.streams[inode]; ok {
+		s.size = size
+	}
+}
+
+// Write appends the extent key of the data at once, there is nothing left to flush.
+func (client *ExtentClient) Write(inode uint64, offset int, data []byte, flags int) (write int, err error) {
+	client.Lock()
+	defer client.Unlock()
+	var s *fakeStream
+	if s, err = client.getStream(inode, "Write"); err != nil {
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	client.maxExtentID++
+	ek := proto.ExtentKey{
+		FileOffset:  uint64(offset),
+		PartitionId: PartitionID,
+		ExtentId:    client.maxExtentID,
+		Size:        uint32(len(data)),
+	}
+	if err = client.appendExtentKey(inode, ek); err != nil {
+		return
+	}
+	buf := client.data[inode]
+	if end := offset + len(data); end > len(buf) {
+		buf = append(buf, make([]byte, end-len(buf))...)
+	}
+	copy(buf[offset:], data)
+	client.data[inode] = buf
+	if end := offset + len(data); end > s.size {
+		s.size = end
+	}
+	return len(data), nil
+}
+
+func (client *ExtentClient) Truncate(inode uint64, size int) (err error) {
+	client.Lock()
+	defer client.Unlock()
+	var s *fakeStream
+	if s, err = client.getStream(inode, "Truncate"); err != nil {
+		return
+	}
+	if err = client.truncate(inode, uint64(size)); err != nil {
+		return
+	}
+	if buf := client.data[inode]; len(buf) > size {
+		client.data[inode] = buf[:size]
+	}
+	s.size = size
+	return
+}
+
+func (client *ExtentClient) Flush(inode uint64) (err error) {
+	client.Lock()
+	defer client.Unlock()
+	_, err = client.getStream(inode, "Flush")
+	return
+}
+
+// Read returns io.EOF if the read reaches the end of the file, and zeros for the holes.
+func (client *ExtentClient) Read(inode uint64, data []byte, offset int, size int) (read int, err error) {
+	if size == 0 {
+		return
+	}
+	client.Lock()
+	defer client.Unlock()
+	var s *fakeStream
+	if s, err = client.getStream(inode, "Read"); err != nil {
+		return
+	}
+	if offset > s.size {
+		return
+	}
+	if offset+size > s.size {
+		size = s.size - offset
+		err = io.EOF
+	}
+	for i := range data[:size] {
+		data[i] = 0
+	}
+	if buf := client.data[inode]; offset < len(buf) {
+		copy(data[:size], buf[offset:])
+	}
+	return size, err
+}
+
+func (client *ExtentClient) Close() error {
+	return nil
+}
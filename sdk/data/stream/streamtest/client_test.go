@@ -0,0 +1,130 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package streamtest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta/metatest"
+)
+
+func newTestVolume(t *testing.T) (*metatest.MetaWrapper, *ExtentClient) {
+	mw := metatest.NewMetaWrapper("vol", "owner")
+	ec, err := NewExtentClient(&stream.ExtentConfig{
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+	})
+	if err != nil {
+		t.Fatalf("new extent client: %v", err)
+	}
+	return mw, ec
+}
+
+func TestWriteRead(t *testing.T) {
+	mw, ec := newTestVolume(t)
+	info, err := mw.Create_ll(proto.RootIno, "file", proto.Mode(0644), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	ino := info.Inode
+	if _, err = ec.Write(ino, 0, []byte("hello"), 0); err == nil {
+		t.Fatalf("write without opening the stream")
+	}
+	if err = ec.OpenStream(ino); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err = ec.Write(ino, 0, []byte("hello world"), 0); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err = ec.Write(ino, 6, []byte("there"), 0); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if err = ec.Flush(ino); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	_, size, extents, err := mw.GetExtents(ino)
+	if err != nil || size != 11 || len(extents) != 2 {
+		t.Fatalf("get extents: size %v extents %v err %v", size, extents, err)
+	}
+	buf := make([]byte, 16)
+	n, err := ec.Read(ino, buf, 0, len(buf))
+	if err != io.EOF || !bytes.Equal(buf[:n], []byte("hello there")) {
+		t.Fatalf("read: %q %v", buf[:n], err)
+	}
+
+	if err = ec.Truncate(ino, 5); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if info, err = mw.InodeGet_ll(ino); err != nil || info.Size != 5 {
+		t.Fatalf("inode after truncate: %v %v", info, err)
+	}
+	ec.CloseStream(ino)
+	ec.EvictStream(ino)
+}
+
+func TestNamespace(t *testing.T) {
+	mw, _ := newTestVolume(t)
+	dir, err := mw.Create_ll(proto.RootIno, "dir", proto.Mode(os.ModeDir|0755), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err = mw.Create_ll(proto.RootIno, "dir", proto.Mode(os.ModeDir|0755), 0, 0, nil); err != syscall.EEXIST {
+		t.Fatalf("mkdir existing: expected %v, got %v", syscall.EEXIST, err)
+	}
+	file, err := mw.Create_ll(dir.Inode, "a", proto.Mode(0644), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err = mw.Link(dir.Inode, "b", file.Inode); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if _, err = mw.Delete_ll(proto.RootIno, "dir", true); err != syscall.ENOTEMPTY {
+		t.Fatalf("rmdir: expected %v, got %v", syscall.ENOTEMPTY, err)
+	}
+	if err = mw.Rename_ll(dir.Inode, "a", dir.Inode, "b"); err != nil {
+		t.Fatalf("rename over a file: %v", err)
+	}
+	dentries, err := mw.ReadDir_ll(dir.Inode)
+	if err != nil || len(dentries) != 1 || dentries[0].Name != "b" {
+		t.Fatalf("readdir: %v %v", dentries, err)
+	}
+	if info, err := mw.InodeGet_ll(file.Inode); err != nil || info.Nlink != 1 {
+		t.Fatalf("inode after rename: %v %v", info, err)
+	}
+
+	if info, err := mw.Delete_ll(dir.Inode, "b", false); err != nil || info.Nlink != 0 {
+		t.Fatalf("unlink: %v %v", info, err)
+	}
+	if err = mw.Evict(file.Inode); err != nil {
+		t.Fatalf("evict: %v", err)
+	}
+	if _, err = mw.InodeGet_ll(file.Inode); err != syscall.ENOENT {
+		t.Fatalf("get evicted inode: expected %v, got %v", syscall.ENOENT, err)
+	}
+	if _, err = mw.Delete_ll(proto.RootIno, "dir", true); err != nil {
+		t.Fatalf("rmdir: %v", err)
+	}
+	if root, err := mw.InodeGet_ll(proto.RootIno); err != nil || root.Nlink != 2 {
+		t.Fatalf("root: %v %v", root, err)
+	}
+}
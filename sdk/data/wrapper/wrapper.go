@@ -15,6 +15,7 @@
 package wrapper
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strings"
@@ -23,9 +24,11 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	masterSDK "github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/iputil"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/qos"
 )
 
 var (
@@ -50,9 +53,12 @@ type Wrapper struct {
 	dpSelectorChanged     bool
 	dpSelectorName        string
 	dpSelectorParm        string
+	qosLimiter            *qos.Limiter
+	verifyRead            bool
 	mc                    *masterSDK.MasterClient
 	stopOnce              sync.Once
 	stopC                 chan struct{}
+	dpViewEpoch           uint64 // epoch of the data partition view held by the client
 
 	dpSelector DataPartitionSelector
 
@@ -68,6 +74,7 @@ func NewDataPartitionWrapper(volName string, masters []string) (w *Wrapper, err
 	w.volName = volName
 	w.partitions = make(map[uint64]*DataPartition)
 	w.HostsStatus = make(map[string]bool)
+	w.qosLimiter = qos.NewLimiter(0, 0)
 	if err = w.updateClusterInfo(); err != nil {
 		err = errors.Trace(err, "NewDataPartitionWrapper:")
 		return
@@ -101,10 +108,26 @@ func (w *Wrapper) InitFollowerRead(clientConfig bool) {
 	w.followerRead = w.followerReadClientCfg || w.followerRead
 }
 
+// SetFollowerRead changes the follower read of the client config at runtime,
+// the follower read of the volume takes effect again once it is disabled.
+func (w *Wrapper) SetFollowerRead(clientConfig bool) {
+	w.followerReadClientCfg = clientConfig
+	if clientConfig {
+		w.followerRead = true
+		return
+	}
+	w.updateSimpleVolView()
+}
+
 func (w *Wrapper) FollowerRead() bool {
 	return w.followerRead
 }
 
+// VerifyRead returns if the reads of the volume are verified against the checksums.
+func (w *Wrapper) VerifyRead() bool {
+	return w.verifyRead
+}
+
 func (w *Wrapper) updateClusterInfo() (err error) {
 	var info *proto.ClusterInfo
 	if info, err = w.mc.AdminAPI().GetClusterInfo(); err != nil {
@@ -127,6 +150,8 @@ func (w *Wrapper) getSimpleVolView() (err error) {
 	w.followerRead = view.FollowerRead
 	w.dpSelectorName = view.DpSelectorName
 	w.dpSelectorParm = view.DpSelectorParm
+	w.updateQos(view.MaxIOPS, view.MaxBandwidth)
+	w.verifyRead = view.VerifyRead
 
 	log.LogInfof("getSimpleVolView: get volume simple info: ID(%v) name(%v) owner(%v) status(%v) capacity(%v) "+
 		"metaReplicas(%v) dataReplicas(%v) mpCnt(%v) dpCnt(%v) followerRead(%v) createTime(%v) dpSelectorName(%v) "+
@@ -174,17 +199,49 @@ func (w *Wrapper) updateSimpleVolView() (err error) {
 		w.Unlock()
 	}
 
+	w.updateQos(view.MaxIOPS, view.MaxBandwidth)
+
+	if w.verifyRead != view.VerifyRead {
+		log.LogInfof("updateSimpleVolView: update verifyRead from old(%v) to new(%v)",
+			w.verifyRead, view.VerifyRead)
+		w.verifyRead = view.VerifyRead
+	}
+
 	return nil
 }
 
+// updateQos applies the IOPS and bandwidth limits of the volume to this client.
+// The limits cap a single client, the aggregated traffic is enforced by the data nodes, each of which
+// enforces an even share of the limits.
+func (w *Wrapper) updateQos(maxIOPS, maxBandwidth uint64) {
+	maxBandwidth = maxBandwidth * util.MB
+	if oldIOPS, oldBandwidth := w.qosLimiter.Limits(); oldIOPS != maxIOPS || oldBandwidth != maxBandwidth {
+		log.LogInfof("updateQos: volume(%v) maxIOPS(%v -> %v) maxBandwidth(%v -> %v)",
+			w.volName, oldIOPS, maxIOPS, oldBandwidth, maxBandwidth)
+		w.qosLimiter.Update(maxIOPS, maxBandwidth)
+	}
+}
+
+// WaitQos blocks until one IO of the given size is allowed by the limits of the volume.
+func (w *Wrapper) WaitQos(ctx context.Context, size int) error {
+	return w.qosLimiter.Wait(ctx, size)
+}
+
 func (w *Wrapper) updateDataPartition(isInit bool) (err error) {
 
-	var dpv *proto.DataPartitionsView
-	if dpv, err = w.mc.ClientAPI().GetDataPartitions(w.volName); err != nil {
+	var (
+		dpv   *proto.DataPartitionsView
+		epoch uint64
+	)
+	if !isInit {
+		epoch = w.dpViewEpoch
+	}
+	if dpv, err = w.mc.ClientAPI().GetDataPartitionsDelta(w.volName, epoch); err != nil {
 		log.LogErrorf("updateDataPartition: get data partitions fail: volume(%v) err(%v)", w.volName, err)
 		return
 	}
-	log.LogInfof("updateDataPartition: get data partitions: volume(%v) partitions(%v)", w.volName, len(dpv.DataPartitions))
+	log.LogInfof("updateDataPartition: get data partitions: volume(%v) partitions(%v) delta(%v) epoch(%v -> %v)",
+		w.volName, len(dpv.DataPartitions), dpv.IsDelta, epoch, dpv.ViewEpoch)
 
 	var convert = func(response *proto.DataPartitionResponse) *DataPartition {
 		return &DataPartition{
@@ -201,11 +258,15 @@ func (w *Wrapper) updateDataPartition(isInit bool) (err error) {
 		}
 		log.LogInfof("updateDataPartition: dp(%v)", dp)
 		w.replaceOrInsertPartition(dp)
-		if dp.Status == proto.ReadWrite {
+		if !dpv.IsDelta && dp.Status == proto.ReadWrite {
 			dp.MetricsRefresh()
 			rwPartitionGroups = append(rwPartitionGroups, dp)
 		}
 	}
+	if dpv.IsDelta {
+		w.removePartitions(dpv.RemovedPartitions)
+		rwPartitionGroups = w.getReadWritePartitions()
+	}
 
 	// isInit used to identify whether this call is caused by mount action
 	if isInit || (len(rwPartitionGroups) >= MinWriteAbleDataPartitionCnt) {
@@ -213,6 +274,9 @@ func (w *Wrapper) updateDataPartition(isInit bool) (err error) {
 	} else {
 		err = errors.New("updateDataPartition: no writable data partition")
 	}
+	if err == nil {
+		w.dpViewEpoch = dpv.ViewEpoch
+	}
 
 	log.LogInfof("updateDataPartition: finish")
 	return err
@@ -243,6 +307,28 @@ func (w *Wrapper) replaceOrInsertPartition(dp *DataPartition) {
 	}
 }
 
+func (w *Wrapper) removePartitions(partitionIDs []uint64) {
+	w.Lock()
+	defer w.Unlock()
+	for _, id := range partitionIDs {
+		delete(w.partitions, id)
+		log.LogInfof("partition: removed (%v)", id)
+	}
+}
+
+func (w *Wrapper) getReadWritePartitions() (partitions []*DataPartition) {
+	w.RLock()
+	defer w.RUnlock()
+	partitions = make([]*DataPartition, 0)
+	for _, dp := range w.partitions {
+		if dp.Status == proto.ReadWrite {
+			dp.MetricsRefresh()
+			partitions = append(partitions, dp)
+		}
+	}
+	return
+}
+
 // GetDataPartition returns the data partition based on the given partition ID.
 func (w *Wrapper) GetDataPartition(partitionID uint64) (*DataPartition, error) {
 	w.RLock()
@@ -16,8 +16,11 @@ package master
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 )
@@ -76,6 +79,65 @@ func (api *AdminAPI) Topo() (topo *proto.TopologyView, err error ){
 	return
 }
 
+// ListNodeSets returns the node sets of the zone, or of all the zones if the zone name is empty.
+func (api *AdminAPI) ListNodeSets(zoneName string) (nodeSets []*proto.NodeSetInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.ListNodeSets)
+	if zoneName != "" {
+		request.addParam("zoneName", zoneName)
+	}
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	nodeSets = make([]*proto.NodeSetInfo, 0)
+	if err = json.Unmarshal(buf, &nodeSets); err != nil {
+		return
+	}
+	return
+}
+
+// GetNodeSet returns the node set with its data nodes and meta nodes.
+func (api *AdminAPI) GetNodeSet(nodeSetID uint64) (nodeSet *proto.NodeSetInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.GetNodeSet)
+	request.addParam("id", strconv.FormatUint(nodeSetID, 10))
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	nodeSet = &proto.NodeSetInfo{}
+	if err = json.Unmarshal(buf, nodeSet); err != nil {
+		return
+	}
+	return
+}
+
+// CreateNodeSet adds an empty node set to the zone.
+func (api *AdminAPI) CreateNodeSet(zoneName string) (nodeSet *proto.NodeSetInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.CreateNodeSet)
+	request.addParam("zoneName", zoneName)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	nodeSet = &proto.NodeSetInfo{}
+	if err = json.Unmarshal(buf, nodeSet); err != nil {
+		return
+	}
+	return
+}
+
+// MigrateNodeToNodeSet moves the data node or meta node of the address to another node set of its zone.
+// Only the partitions placed afterwards follow the new node set.
+func (api *AdminAPI) MigrateNodeToNodeSet(nodeAddr string, nodeSetID uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.MigrateNodeToNodeSet)
+	request.addParam("addr", nodeAddr)
+	request.addParam("nodeSetId", strconv.FormatUint(nodeSetID, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) GetDataPartition(volName string, partitionID uint64) (partition *proto.DataPartitionInfo, err error) {
 	var buf []byte
 	var request = newAPIRequest(http.MethodGet, proto.AdminGetDataPartition)
@@ -167,6 +229,16 @@ func (api *AdminAPI) DeleteDataReplica(dataPartitionID uint64, nodeAddr string)
 	return
 }
 
+func (api *AdminAPI) ResetDataPartitionRaft(dataPartitionID uint64, survivor string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminResetDataPartitionRaft)
+	request.addParam("id", strconv.FormatUint(dataPartitionID, 10))
+	request.addParam("addr", survivor)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) AddDataReplica(dataPartitionID uint64, nodeAddr string) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminAddDataReplica)
 	request.addParam("id", strconv.FormatUint(dataPartitionID, 10))
@@ -207,22 +279,325 @@ func (api *AdminAPI) DeleteVolume(volName, authKey string) (err error) {
 	return
 }
 
+// UpdateVolume updates the capacity, the replica number, the zone and the permissions of the volume, the other
+// settings of the volume are kept.
 func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int, followerRead, authenticate, enableToken bool, authKey, zoneName string) (err error) {
+	var vv *proto.SimpleVolView
+	if vv, err = api.GetVolumeSimpleInfo(volName); err != nil {
+		return
+	}
+	req := proto.NewUpdateVolRequest(vv, authKey)
+	req.Capacity = capacity
+	req.DpReplicaNum = replicas
+	req.FollowerRead = followerRead
+	req.Authenticate = authenticate
+	req.EnableToken = enableToken
+	req.ZoneName = zoneName
+	return api.UpdateVolumeWithRequest(req)
+}
+
+// UpdateVolumeWithRequest updates all the settings of the volume in the request, see NewUpdateVolRequest.
+func (api *AdminAPI) UpdateVolumeWithRequest(req *proto.UpdateVolRequest) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminUpdateVol)
+	request.addParam("name", req.Name)
+	request.addParam("authKey", req.AuthKey)
+	request.addParam("capacity", strconv.FormatUint(req.Capacity, 10))
+	request.addParam("replicaNum", strconv.Itoa(req.DpReplicaNum))
+	if req.MpReplicaNum > 0 {
+		request.addParam("mpReplicaNum", strconv.Itoa(req.MpReplicaNum))
+	}
+	request.addParam("followerRead", strconv.FormatBool(req.FollowerRead))
+	request.addParam("enableToken", strconv.FormatBool(req.EnableToken))
+	request.addParam("authenticate", strconv.FormatBool(req.Authenticate))
+	request.addParam("zoneName", req.ZoneName)
+	request.addParam("maxIOPS", strconv.FormatUint(req.MaxIOPS, 10))
+	request.addParam("maxBandwidth", strconv.FormatUint(req.MaxBandwidth, 10))
+	request.addParam("verifyRead", strconv.FormatBool(req.VerifyRead))
+	request.addParam("trashDays", strconv.FormatUint(uint64(req.TrashDays), 10))
+	request.addParam("zoneAntiAffinity", strconv.FormatBool(req.ZoneAntiAffinity))
+	request.addParam("strictZones", strconv.FormatBool(req.StrictZones))
+	request.addParam("atime", strconv.FormatBool(req.Atime))
+	request.addParam("fileAudit", strconv.FormatBool(req.FileAudit))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetVolTierRule(volName, authKey string, rule *proto.TierRule) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetVolTierRule)
 	request.addParam("name", volName)
 	request.addParam("authKey", authKey)
-	request.addParam("capacity", strconv.FormatUint(capacity, 10))
-	request.addParam("replicaNum", strconv.Itoa(replicas))
-	request.addParam("followerRead", strconv.FormatBool(followerRead))
-	request.addParam("enableToken", strconv.FormatBool(enableToken))
-	request.addParam("authenticate", strconv.FormatBool(authenticate))
-	request.addParam("zoneName", zoneName)
+	request.addParam("ruleName", rule.Name)
+	request.addParam("inactiveDays", strconv.FormatUint(uint64(rule.InactiveDays), 10))
+	request.addParam("enable", strconv.FormatBool(rule.Enable))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteVolTierRule(volName, authKey, ruleName string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteVolTierRule)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("ruleName", ruleName)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetVolTierPolicy(volName string) (view *proto.VolTierPolicyView, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetVolTierPolicy)
+	request.addParam("name", volName)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.VolTierPolicyView{}
+	if err = json.Unmarshal(buf, &view); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetVolWormRetention(volName, authKey string, days uint32) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetVolWormRetention)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("retentionDays", strconv.FormatUint(uint64(days), 10))
 	if _, err = api.mc.serveRequest(request); err != nil {
 		return
 	}
 	return
 }
 
+// OverrideVolWorm allows files in the retention period to be modified or deleted for the duration,
+// and returns the unix time when the override ends. A zero duration ends the override.
+func (api *AdminAPI) OverrideVolWorm(volName, authKey string, duration time.Duration) (until int64, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminOverrideVolWorm)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("duration", duration.String())
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	if err = json.Unmarshal(buf, &until); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListTasks(nodeAddr string) (views []*proto.AdminTaskView, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListTasks)
+	request.addParam("addr", nodeAddr)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	views = make([]*proto.AdminTaskView, 0)
+	if err = json.Unmarshal(buf, &views); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) CancelTask(nodeAddr, taskID string) (err error) {
+	return api.manageTask(proto.AdminCancelTask, nodeAddr, taskID)
+}
+
+func (api *AdminAPI) PauseTask(nodeAddr, taskID string) (err error) {
+	return api.manageTask(proto.AdminPauseTask, nodeAddr, taskID)
+}
+
+func (api *AdminAPI) ResumeTask(nodeAddr, taskID string) (err error) {
+	return api.manageTask(proto.AdminResumeTask, nodeAddr, taskID)
+}
+
+func (api *AdminAPI) manageTask(path, nodeAddr, taskID string) (err error) {
+	var request = newAPIRequest(http.MethodGet, path)
+	request.addParam("addr", nodeAddr)
+	request.addParam("taskID", taskID)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+// SetRepairLink sets the repair bandwidth budget from the source zone to the destination zone in MB/s,
+// a zero bandwidth removes the budget.
+func (api *AdminAPI) SetRepairLink(srcZone, dstZone string, maxBandwidth uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetRepairLink)
+	request.addParam("srcZone", srcZone)
+	request.addParam("dstZone", dstZone)
+	request.addParam("maxBandwidth", strconv.FormatUint(maxBandwidth, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListRepairLinks() (links []*proto.RepairLink, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListRepairLinks)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	links = make([]*proto.RepairLink, 0)
+	if err = json.Unmarshal(buf, &links); err != nil {
+		return
+	}
+	return
+}
+
+// SetCordon keeps the new replicas away from a zone or a node until the cordon is removed,
+// or until the given unix time if it is not zero.
+func (api *AdminAPI) SetCordon(cordonType, name, reason string, until int64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetCordon)
+	request.addParam("type", cordonType)
+	request.addParam("name", name)
+	request.addParam("reason", reason)
+	if until != 0 {
+		request.addParam("until", strconv.FormatInt(until, 10))
+	}
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) RemoveCordon(cordonType, name string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminRemoveCordon)
+	request.addParam("type", cordonType)
+	request.addParam("name", name)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListCordons() (cordons []*proto.NodeCordon, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListCordons)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	cordons = make([]*proto.NodeCordon, 0)
+	if err = json.Unmarshal(buf, &cordons); err != nil {
+		return
+	}
+	return
+}
+
+// SetRebuildPolicy sets how the replicas missing from the data nodes of the zone are rebuilt, the policy
+// without a zone is the default of the cluster.
+func (api *AdminAPI) SetRebuildPolicy(policy *proto.RebuildPolicy) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetRebuildPolicy)
+	request.addParam("zone", policy.Zone)
+	request.addParam("delay", strconv.FormatInt(policy.DelaySec, 10))
+	request.addParam("maxBandwidth", strconv.FormatUint(policy.MaxBandwidth, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) RemoveRebuildPolicy(zone string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminRemoveRebuildPolicy)
+	request.addParam("zone", zone)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListRebuildPolicies() (policies []*proto.RebuildPolicy, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListRebuildPolicies)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	policies = make([]*proto.RebuildPolicy, 0)
+	if err = json.Unmarshal(buf, &policies); err != nil {
+		return
+	}
+	return
+}
+
+// SetNFSExport publishes the volume through the NFS gateways under the export path,
+// the clients are given as CIDRs and an empty list allows every client.
+func (api *AdminAPI) SetNFSExport(export *proto.NFSExport) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetNFSExport)
+	request.addParam("path", export.Path)
+	request.addParam("name", export.Volume)
+	request.addParam("clients", strings.Join(export.Clients, ","))
+	request.addParam("readOnly", strconv.FormatBool(export.ReadOnly))
+	request.addParam("rootSquash", strconv.FormatBool(export.RootSquash))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) RemoveNFSExport(exportPath string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminRemoveNFSExport)
+	request.addParam("path", exportPath)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListNFSExports() (exports []*proto.NFSExport, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListNFSExports)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	exports = make([]*proto.NFSExport, 0)
+	if err = json.Unmarshal(buf, &exports); err != nil {
+		return
+	}
+	return
+}
+
+// ListAuditEvents returns the latest admin operations recorded by the master in the order of time.
+// The events are filtered by the time range in unix seconds, the path of the admin API containing
+// op and the target, the zero values of which match all the events.
+func (api *AdminAPI) ListAuditEvents(from, to int64, op, target string, limit int) (events []*proto.AuditEvent, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListAuditEvents)
+	if from != 0 {
+		request.addParam("from", strconv.FormatInt(from, 10))
+	}
+	if to != 0 {
+		request.addParam("to", strconv.FormatInt(to, 10))
+	}
+	request.addParam("op", op)
+	request.addParam("target", target)
+	if limit != 0 {
+		request.addParam("limit", strconv.Itoa(limit))
+	}
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	events = make([]*proto.AuditEvent, 0)
+	if err = json.Unmarshal(buf, &events); err != nil {
+		return
+	}
+	return
+}
+
+// BackupMeta writes the backup of the master metadata to w, which is a gzip stream of json lines, the
+// proto.MasterMetaBackup header followed by the key-value pairs of the metadata store.
+func (api *AdminAPI) BackupMeta(w io.Writer) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminBackupMeta)
+	request.addHeader("isTimeOut", "false")
+	return api.mc.serveStreamRequest(request, w)
+}
+
 func (api *AdminAPI) VolShrink(volName string, capacity uint64, authKey string) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminVolShrink)
 	request.addParam("name", volName)
@@ -234,19 +609,29 @@ func (api *AdminAPI) VolShrink(volName string, capacity uint64, authKey string)
 	return
 }
 
-func (api *AdminAPI) VolExpand(volName string, capacity uint64, authKey string) (err error) {
+// VolExpand expands the capacity of the volume online. Expanding a volume to its current capacity succeeds
+// as well, so the request can be retried until the returned view is completed.
+func (api *AdminAPI) VolExpand(volName string, capacity uint64, authKey string) (view *proto.VolCapacityView, err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminVolExpand)
 	request.addParam("name", volName)
 	request.addParam("authKey", authKey)
 	request.addParam("capacity", strconv.FormatUint(capacity, 10))
-	if _, err = api.mc.serveRequest(request); err != nil {
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.VolCapacityView{}
+	if err = json.Unmarshal(buf, view); err != nil {
 		return
 	}
 	return
 }
 
+// CreateVolume creates a volume. The zone name may list several zones separated by commas, the partitions are
+// spread over them, or their replicas are placed in different zones of them with the zone anti-affinity.
+// With the strict zones the master refuses to place a partition whose replicas span fewer zones than required.
 func (api *AdminAPI) CreateVolume(volName, owner string, mpCount int,
-	dpSize uint64, capacity uint64, replicas int, followerRead bool, zoneName string) (err error) {
+	dpSize uint64, capacity uint64, replicas int, followerRead bool, zoneName string, zoneAntiAffinity, strictZones bool) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminCreateVol)
 	request.addParam("name", volName)
 	request.addParam("owner", owner)
@@ -255,6 +640,8 @@ func (api *AdminAPI) CreateVolume(volName, owner string, mpCount int,
 	request.addParam("capacity", strconv.FormatUint(capacity, 10))
 	request.addParam("followerRead", strconv.FormatBool(followerRead))
 	request.addParam("zoneName", zoneName)
+	request.addParam("zoneAntiAffinity", strconv.FormatBool(zoneAntiAffinity))
+	request.addParam("strictZones", strconv.FormatBool(strictZones))
 	if _, err = api.mc.serveRequest(request); err != nil {
 		return
 	}
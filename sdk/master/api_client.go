@@ -153,3 +153,20 @@ func (api *ClientAPI) GetDataPartitions(volName string) (view *proto.DataPartiti
 	}
 	return
 }
+
+// GetDataPartitionsDelta returns the data partitions changed since the given view epoch.
+// The full view is returned if the epoch is zero or unknown to the master.
+func (api *ClientAPI) GetDataPartitionsDelta(volName string, epoch uint64) (view *proto.DataPartitionsView, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.ClientDataPartitions)
+	request.addParam("name", volName)
+	request.addParam("epoch", strconv.FormatUint(epoch, 10))
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.DataPartitionsView{}
+	if err = json.Unmarshal(data, view); err != nil {
+		return
+	}
+	return
+}
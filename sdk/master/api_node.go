@@ -124,3 +124,84 @@ func (api *NodeAPI) MetaNodeDecommission(nodeAddr string) (err error) {
 	}
 	return
 }
+
+// SetDataNodeMaintenance turns the maintenance of the data node on or off.
+func (api *NodeAPI) SetDataNodeMaintenance(nodeAddr string, enable bool) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetDataNodeMaintenance)
+	request.addParam("addr", nodeAddr)
+	request.addParam("enable", strconv.FormatBool(enable))
+	_, err = api.mc.serveRequest(request)
+	return
+}
+
+// SetMetaNodeMaintenance turns the maintenance of the meta node on or off.
+func (api *NodeAPI) SetMetaNodeMaintenance(nodeAddr string, enable bool) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetMetaNodeMaintenance)
+	request.addParam("addr", nodeAddr)
+	request.addParam("enable", strconv.FormatBool(enable))
+	_, err = api.mc.serveRequest(request)
+	return
+}
+
+// SetDataNodeThrottle throttles the IO of the client on the data node, zero limits remove the throttle.
+// The bandwidth is given in MB per second.
+func (api *NodeAPI) SetDataNodeThrottle(nodeAddr, clientIP string, maxIOPS, maxBandwidth uint64) (throttles []*proto.ClientThrottle, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetDataNodeThrottle)
+	request.addParam("addr", nodeAddr)
+	request.addParam("clientIP", clientIP)
+	request.addParam("maxIOPS", strconv.FormatUint(maxIOPS, 10))
+	request.addParam("maxBandwidth", strconv.FormatUint(maxBandwidth, 10))
+	return api.serveThrottleRequest(request)
+}
+
+func (api *NodeAPI) ListDataNodeThrottles(nodeAddr string) (throttles []*proto.ClientThrottle, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListDataNodeThrottle)
+	request.addParam("addr", nodeAddr)
+	return api.serveThrottleRequest(request)
+}
+
+func (api *NodeAPI) serveThrottleRequest(request *request) (throttles []*proto.ClientThrottle, err error) {
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	throttles = make([]*proto.ClientThrottle, 0)
+	if err = json.Unmarshal(buf, &throttles); err != nil {
+		return
+	}
+	return
+}
+
+// RecoverDisk registers the replaced disks of a data node, the master re-creates the lost replicas on it.
+func (api *NodeAPI) RecoverDisk(req *proto.DiskRecoveryRequest) (view *proto.DiskRecoveryView, err error) {
+	var encoded []byte
+	if encoded, err = json.Marshal(req); err != nil {
+		return
+	}
+	var request = newAPIRequest(http.MethodPost, proto.AdminRecoverDisk)
+	request.addBody(encoded)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.DiskRecoveryView{}
+	if err = json.Unmarshal(buf, view); err != nil {
+		return
+	}
+	return
+}
+
+// GetDiskRecoveries returns the progress of recovering the replaced disks, of all the data nodes if the address is empty.
+func (api *NodeAPI) GetDiskRecoveries(nodeAddr string) (views []*proto.DiskRecoveryView, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetDiskRecovery)
+	request.addParam("addr", nodeAddr)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	views = make([]*proto.DiskRecoveryView, 0)
+	if err = json.Unmarshal(buf, &views); err != nil {
+		return
+	}
+	return
+}
@@ -120,6 +120,44 @@ func (api *UserAPI) RemovePolicy(param *proto.UserPermRemoveParam) (userInfo *pr
 	return
 }
 
+// SetPrefixPolicy adds the prefix rule to the volume of the user, or replaces the one with the same prefix and effect.
+func (api *UserAPI) SetPrefixPolicy(param *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error) {
+	var request = newAPIRequest(http.MethodPost, proto.UserSetPrefixPolicy)
+	var reqBody []byte
+	if reqBody, err = json.Marshal(param); err != nil {
+		return
+	}
+	request.addBody(reqBody)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	userInfo = &proto.UserInfo{}
+	if err = json.Unmarshal(data, userInfo); err != nil {
+		return
+	}
+	return
+}
+
+// RemovePrefixPolicy removes the prefix rule with the prefix and effect of the param, the actions are ignored.
+func (api *UserAPI) RemovePrefixPolicy(param *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error) {
+	var request = newAPIRequest(http.MethodPost, proto.UserRemovePrefixPolicy)
+	var reqBody []byte
+	if reqBody, err = json.Marshal(param); err != nil {
+		return
+	}
+	request.addBody(reqBody)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	userInfo = &proto.UserInfo{}
+	if err = json.Unmarshal(data, userInfo); err != nil {
+		return
+	}
+	return
+}
+
 func (api *UserAPI) DeleteVolPolicy(vol string) (err error) {
 	var request = newAPIRequest(http.MethodPost, proto.UserDeleteVolPolicy)
 	request.addParam("name", vol)
@@ -147,6 +185,59 @@ func (api *UserAPI) TransferVol(param *proto.UserTransferVolParam) (userInfo *pr
 	return
 }
 
+// RotateKeys replaces the key pair of the user, the former key pair keeps valid for the grace period.
+func (api *UserAPI) RotateKeys(param *proto.UserRotateKeyParam) (userInfo *proto.UserInfo, err error) {
+	var request = newAPIRequest(http.MethodPost, proto.UserRotateKey)
+	var reqBody []byte
+	if reqBody, err = json.Marshal(param); err != nil {
+		return
+	}
+	request.addBody(reqBody)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	userInfo = &proto.UserInfo{}
+	if err = json.Unmarshal(data, userInfo); err != nil {
+		return
+	}
+	return
+}
+
+// RetireKeys invalidates the former key pair of the user before the grace period ends.
+func (api *UserAPI) RetireKeys(userID string) (userInfo *proto.UserInfo, err error) {
+	var request = newAPIRequest(http.MethodPost, proto.UserRetireKey)
+	request.addParam("user", userID)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	userInfo = &proto.UserInfo{}
+	if err = json.Unmarshal(data, userInfo); err != nil {
+		return
+	}
+	return
+}
+
+// AssumeRole issues a temporary session credential of the user, which is valid for the duration.
+func (api *UserAPI) AssumeRole(param *proto.UserAssumeRoleParam) (session *proto.SessionCredential, err error) {
+	var request = newAPIRequest(http.MethodPost, proto.UserAssumeRole)
+	var reqBody []byte
+	if reqBody, err = json.Marshal(param); err != nil {
+		return
+	}
+	request.addBody(reqBody)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	session = &proto.SessionCredential{}
+	if err = json.Unmarshal(data, session); err != nil {
+		return
+	}
+	return
+}
+
 func (api *UserAPI) ListUsers(keywords string) (users []*proto.UserInfo, err error) {
 	var request = newAPIRequest(http.MethodGet, proto.UserList)
 	request.addParam("keywords", keywords)
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 const (
@@ -170,6 +172,59 @@ func (c *MasterClient) serveRequest(r *request) (repsData []byte, err error) {
 	return
 }
 
+// serveStreamRequest copies the body of the response to w, which is not a json reply unless the request fails.
+func (c *MasterClient) serveStreamRequest(r *request, w io.Writer) (err error) {
+	leaderAddr, nodes := c.prepareRequest()
+	host := leaderAddr
+	for i := -1; i < len(nodes); i++ {
+		if i == -1 {
+			if host == "" {
+				continue
+			}
+		} else {
+			host = nodes[i]
+		}
+		var resp *http.Response
+		var schema string
+		if c.useSSL {
+			schema = "https"
+		} else {
+			schema = "http"
+		}
+		var url = fmt.Sprintf("%s://%s%s", schema, host, r.path)
+		if resp, err = c.httpRequest(r.method, url, r.params, r.header, r.body); err != nil {
+			log.LogErrorf("serveStreamRequest: send http request fail: method(%v) url(%v) err(%v)", r.method, url, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			repsData, _ := ioutil.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			log.LogErrorf("serveStreamRequest: unknown status: host(%v) uri(%v) status(%v) body(%s).",
+				host, url, resp.StatusCode, strings.Replace(string(repsData), "\n", "", -1))
+			continue
+		}
+		if leaderAddr != host {
+			c.setLeader(host)
+		}
+		defer resp.Body.Close()
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			var body = &struct {
+				Code int32  `json:"code"`
+				Msg  string `json:"msg"`
+			}{}
+			if err = json.NewDecoder(resp.Body).Decode(body); err != nil {
+				return fmt.Errorf("unmarshal response body err:%v", err)
+			}
+			log.LogWarnf("serveStreamRequest: code[%v], msg[%v]", body.Code, body.Msg)
+			return proto.ParseErrorCode(body.Code)
+		}
+		_, err = io.Copy(w, resp.Body)
+		return
+	}
+	err = ErrNoValidMaster
+	return
+}
+
 // Nodes returns all master addresses.
 func (c *MasterClient) Nodes() (nodes []string) {
 	c.RLock()
@@ -212,7 +267,14 @@ func (c *MasterClient) httpRequest(method, url string, param, header map[string]
 	for k, v := range header {
 		req.Header.Set(k, v)
 	}
+	span := tracing.StartSpan(req.URL.Path, tracing.SpanKindClient, tracing.SpanContext{})
+	span.SetAttribute(tracing.AttributePeer, req.URL.Host)
+	tracing.Inject(req.Header, span.Context())
 	resp, err = client.Do(req)
+	if resp != nil {
+		span.SetAttribute(tracing.AttributeResult, resp.Status)
+	}
+	span.Finish(err)
 	return
 }
 
@@ -0,0 +1,123 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"io"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// Admin is the interface of AdminAPI. Programs which take the interface instead of *AdminAPI can be
+// unit tested with the in-memory fake in package mastertest.
+type Admin interface {
+	GetCluster() (cv *proto.ClusterView, err error)
+	GetClusterStat() (cs *proto.ClusterStatInfo, err error)
+	ListZones() (zoneViews []*proto.ZoneView, err error)
+	Topo() (topo *proto.TopologyView, err error)
+	ListNodeSets(zoneName string) (nodeSets []*proto.NodeSetInfo, err error)
+	GetNodeSet(nodeSetID uint64) (nodeSet *proto.NodeSetInfo, err error)
+	CreateNodeSet(zoneName string) (nodeSet *proto.NodeSetInfo, err error)
+	MigrateNodeToNodeSet(nodeAddr string, nodeSetID uint64) (err error)
+	GetDataPartition(volName string, partitionID uint64) (partition *proto.DataPartitionInfo, err error)
+	DiagnoseDataPartition() (diagnosis *proto.DataPartitionDiagnosis, err error)
+	DiagnoseMetaPartition() (diagnosis *proto.MetaPartitionDiagnosis, err error)
+	LoadDataPartition(volName string, partitionID uint64) (err error)
+	CreateDataPartition(volName string, count int) (err error)
+	DecommissionDataPartition(dataPartitionID uint64, nodeAddr string) (err error)
+	DecommissionMetaPartition(metaPartitionID uint64, nodeAddr string) (err error)
+	DeleteDataReplica(dataPartitionID uint64, nodeAddr string) (err error)
+	AddDataReplica(dataPartitionID uint64, nodeAddr string) (err error)
+	ResetDataPartitionRaft(dataPartitionID uint64, survivor string) (err error)
+	DeleteMetaReplica(metaPartitionID uint64, nodeAddr string) (err error)
+	AddMetaReplica(metaPartitionID uint64, nodeAddr string) (err error)
+	DeleteVolume(volName, authKey string) (err error)
+	UpdateVolume(volName string, capacity uint64, replicas int, followerRead, authenticate, enableToken bool, authKey, zoneName string) (err error)
+	UpdateVolumeWithRequest(req *proto.UpdateVolRequest) (err error)
+	SetVolTierRule(volName, authKey string, rule *proto.TierRule) (err error)
+	DeleteVolTierRule(volName, authKey, ruleName string) (err error)
+	GetVolTierPolicy(volName string) (view *proto.VolTierPolicyView, err error)
+	SetVolWormRetention(volName, authKey string, days uint32) (err error)
+	OverrideVolWorm(volName, authKey string, duration time.Duration) (until int64, err error)
+	ListTasks(nodeAddr string) (views []*proto.AdminTaskView, err error)
+	CancelTask(nodeAddr, taskID string) (err error)
+	PauseTask(nodeAddr, taskID string) (err error)
+	ResumeTask(nodeAddr, taskID string) (err error)
+	SetRepairLink(srcZone, dstZone string, maxBandwidth uint64) (err error)
+	ListRepairLinks() (links []*proto.RepairLink, err error)
+	SetCordon(cordonType, name, reason string, until int64) (err error)
+	RemoveCordon(cordonType, name string) (err error)
+	ListCordons() (cordons []*proto.NodeCordon, err error)
+	SetRebuildPolicy(policy *proto.RebuildPolicy) (err error)
+	RemoveRebuildPolicy(zone string) (err error)
+	ListRebuildPolicies() (policies []*proto.RebuildPolicy, err error)
+	SetNFSExport(export *proto.NFSExport) (err error)
+	RemoveNFSExport(exportPath string) (err error)
+	ListNFSExports() (exports []*proto.NFSExport, err error)
+	ListAuditEvents(from, to int64, op, target string, limit int) (events []*proto.AuditEvent, err error)
+	BackupMeta(w io.Writer) (err error)
+	VolShrink(volName string, capacity uint64, authKey string) (err error)
+	VolExpand(volName string, capacity uint64, authKey string) (view *proto.VolCapacityView, err error)
+	CreateVolume(volName, owner string, mpCount int, dpSize uint64, capacity uint64, replicas int, followerRead bool, zoneName string, zoneAntiAffinity, strictZones bool) (err error)
+	CreateDefaultVolume(volName, owner string) (err error)
+	GetVolumeSimpleInfo(volName string) (vv *proto.SimpleVolView, err error)
+	GetClusterInfo() (ci *proto.ClusterInfo, err error)
+	CreateMetaPartition(volName string, inodeStart uint64) (err error)
+	ListVols(keywords string) (volsInfo []*proto.VolInfo, err error)
+	IsFreezeCluster(isFreeze bool) (err error)
+	SetMetaNodeThreshold(threshold float64) (err error)
+	SetDeleteParas(batchCount, markDeleteRate, deleteWorkerSleepMs, autoRepairRate string) (err error)
+	GetDeleteParas() (delParas map[string]string, err error)
+}
+
+// Client is the interface of ClientAPI.
+type Client interface {
+	GetVolume(volName string, authKey string) (vv *proto.VolView, err error)
+	GetVolumeWithoutAuthKey(volName string) (vv *proto.VolView, err error)
+	GetVolumeWithAuthnode(volName string, authKey string, token string, decoder Decoder) (vv *proto.VolView, err error)
+	GetVolumeStat(volName string) (info *proto.VolStatInfo, err error)
+	GetToken(volName, tokenKey string) (token *proto.Token, err error)
+	GetMetaPartition(partitionID uint64) (partition *proto.MetaPartitionInfo, err error)
+	GetMetaPartitions(volName string) (views []*proto.MetaPartitionView, err error)
+	GetDataPartitions(volName string) (view *proto.DataPartitionsView, err error)
+	GetDataPartitionsDelta(volName string, epoch uint64) (view *proto.DataPartitionsView, err error)
+}
+
+// User is the interface of UserAPI.
+type User interface {
+	CreateUser(param *proto.UserCreateParam) (userInfo *proto.UserInfo, err error)
+	DeleteUser(userID string) (err error)
+	UpdateUser(param *proto.UserUpdateParam) (userInfo *proto.UserInfo, err error)
+	GetAKInfo(accesskey string) (userInfo *proto.UserInfo, err error)
+	GetUserInfo(userID string) (userInfo *proto.UserInfo, err error)
+	UpdatePolicy(param *proto.UserPermUpdateParam) (userInfo *proto.UserInfo, err error)
+	RemovePolicy(param *proto.UserPermRemoveParam) (userInfo *proto.UserInfo, err error)
+	SetPrefixPolicy(param *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error)
+	RemovePrefixPolicy(param *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error)
+	DeleteVolPolicy(vol string) (err error)
+	TransferVol(param *proto.UserTransferVolParam) (userInfo *proto.UserInfo, err error)
+	RotateKeys(param *proto.UserRotateKeyParam) (userInfo *proto.UserInfo, err error)
+	RetireKeys(userID string) (userInfo *proto.UserInfo, err error)
+	AssumeRole(param *proto.UserAssumeRoleParam) (session *proto.SessionCredential, err error)
+	ListUsers(keywords string) (users []*proto.UserInfo, err error)
+	ListUsersOfVol(vol string) (users []string, err error)
+}
+
+var (
+	_ Admin  = (*AdminAPI)(nil)
+	_ Client = (*ClientAPI)(nil)
+	_ User   = (*UserAPI)(nil)
+)
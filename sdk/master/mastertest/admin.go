@@ -0,0 +1,927 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mastertest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// AdminAPI is the fake of master.AdminAPI.
+type AdminAPI struct {
+	c *Cluster
+}
+
+func (api *AdminAPI) GetCluster() (cv *proto.ClusterView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	cv = &proto.ClusterView{
+		Name:                api.c.name,
+		LeaderAddr:          "127.0.0.1:17010",
+		DisableAutoAlloc:    api.c.disableAutoAlloc,
+		MetaNodeThreshold:   api.c.metaNodeThreshold,
+		MaxDataPartitionID:  api.c.maxDataPartitionID,
+		MaxMetaPartitionID:  api.c.maxMetaPartitionID,
+		MaxMetaNodeID:       uint64(len(MetaNodes)),
+		DataNodeStatInfo:    &proto.NodeStatInfo{},
+		MetaNodeStatInfo:    &proto.NodeStatInfo{},
+		VolStatInfo:         make([]*proto.VolStatInfo, 0),
+		BadPartitionIDs:     make([]proto.BadPartitionView, 0),
+		BadMetaPartitionIDs: make([]proto.BadPartitionView, 0),
+		MetaNodes:           nodeViews(MetaNodes, 0),
+		DataNodes:           nodeViews(DataNodes, uint64(len(MetaNodes))),
+		Cordons:             api.c.activeCordons(),
+	}
+	for _, vol := range api.c.vols {
+		cv.VolStatInfo = append(cv.VolStatInfo, api.c.volStat(vol))
+	}
+	return
+}
+
+func nodeViews(addrs []string, firstID uint64) []proto.NodeView {
+	views := make([]proto.NodeView, 0, len(addrs))
+	for i, addr := range addrs {
+		views = append(views, proto.NodeView{Addr: addr, Status: true, ID: firstID + uint64(i) + 1, IsWritable: true})
+	}
+	return views
+}
+
+func (api *AdminAPI) GetClusterStat() (cs *proto.ClusterStatInfo, err error) {
+	return &proto.ClusterStatInfo{
+		DataNodeStatInfo: &proto.NodeStatInfo{},
+		MetaNodeStatInfo: &proto.NodeStatInfo{},
+		ZoneStatInfo:     make(map[string]*proto.ZoneStat),
+	}, nil
+}
+
+func (api *AdminAPI) ListZones() (zoneViews []*proto.ZoneView, err error) {
+	topo, _ := api.Topo()
+	return topo.Zones, nil
+}
+
+func (api *AdminAPI) Topo() (topo *proto.TopologyView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	zone := &proto.ZoneView{
+		Name:    defaultZoneName,
+		Status:  "available",
+		NodeSet: make(map[uint64]*proto.NodeSetView),
+	}
+	for _, id := range api.c.nodeSets {
+		info := api.c.nodeSetInfo(id, true)
+		zone.NodeSet[id] = &proto.NodeSetView{
+			DataNodeLen: info.DataNodeLen,
+			MetaNodeLen: info.MetaNodeLen,
+			MetaNodes:   append(make([]proto.NodeView, 0), info.MetaNodes...),
+			DataNodes:   append(make([]proto.NodeView, 0), info.DataNodes...),
+		}
+	}
+	return &proto.TopologyView{Zones: []*proto.ZoneView{zone}}, nil
+}
+
+func (api *AdminAPI) ListNodeSets(zoneName string) (nodeSets []*proto.NodeSetInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	if zoneName != "" && zoneName != defaultZoneName {
+		return nil, proto.ErrZoneNotExists
+	}
+	nodeSets = make([]*proto.NodeSetInfo, 0, len(api.c.nodeSets))
+	for _, id := range api.c.nodeSets {
+		nodeSets = append(nodeSets, api.c.nodeSetInfo(id, false))
+	}
+	return
+}
+
+func (api *AdminAPI) GetNodeSet(nodeSetID uint64) (nodeSet *proto.NodeSetInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	if !api.c.hasNodeSet(nodeSetID) {
+		return nil, proto.ErrNodeSetNotExists
+	}
+	return api.c.nodeSetInfo(nodeSetID, true), nil
+}
+
+func (api *AdminAPI) CreateNodeSet(zoneName string) (nodeSet *proto.NodeSetInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if zoneName != defaultZoneName {
+		return nil, proto.ErrZoneNotExists
+	}
+	id := api.c.nodeSets[len(api.c.nodeSets)-1] + 1
+	api.c.nodeSets = append(api.c.nodeSets, id)
+	return api.c.nodeSetInfo(id, false), nil
+}
+
+func (api *AdminAPI) MigrateNodeToNodeSet(nodeAddr string, nodeSetID uint64) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var isDataNode bool
+	switch {
+	case contains(DataNodes, nodeAddr):
+		isDataNode = true
+	case contains(MetaNodes, nodeAddr):
+	default:
+		return fmt.Errorf("node[%v] is not found", nodeAddr)
+	}
+	if !api.c.hasNodeSet(nodeSetID) {
+		return proto.ErrNodeSetNotExists
+	}
+	if api.c.nodeSetID(nodeAddr) == nodeSetID {
+		return
+	}
+	info := api.c.nodeSetInfo(nodeSetID, false)
+	if (isDataNode && info.DataNodeLen >= info.Capacity) || (!isDataNode && info.MetaNodeLen >= info.Capacity) {
+		return fmt.Errorf("node set[%v] is full", nodeSetID)
+	}
+	api.c.nodeSetOfNode[nodeAddr] = nodeSetID
+	return
+}
+
+func (api *AdminAPI) GetDataPartition(volName string, partitionID uint64) (partition *proto.DataPartitionInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	if partition, err = api.c.findDataPartition(partitionID); err != nil {
+		return
+	}
+	if volName != "" && partition.VolName != volName {
+		return nil, proto.ErrDataPartitionNotExists
+	}
+	return
+}
+
+func (api *AdminAPI) DiagnoseDataPartition() (diagnosis *proto.DataPartitionDiagnosis, err error) {
+	return &proto.DataPartitionDiagnosis{
+		InactiveDataNodes:           make([]string, 0),
+		CorruptDataPartitionIDs:     make([]uint64, 0),
+		LackReplicaDataPartitionIDs: make([]uint64, 0),
+		BadDataPartitionIDs:         make([]proto.BadPartitionView, 0),
+	}, nil
+}
+
+func (api *AdminAPI) DiagnoseMetaPartition() (diagnosis *proto.MetaPartitionDiagnosis, err error) {
+	return &proto.MetaPartitionDiagnosis{
+		InactiveMetaNodes:           make([]string, 0),
+		CorruptMetaPartitionIDs:     make([]uint64, 0),
+		LackReplicaMetaPartitionIDs: make([]uint64, 0),
+		BadMetaPartitionIDs:         make([]proto.BadPartitionView, 0),
+	}, nil
+}
+
+func (api *AdminAPI) LoadDataPartition(volName string, partitionID uint64) (err error) {
+	_, err = api.GetDataPartition(volName, partitionID)
+	return
+}
+
+func (api *AdminAPI) CreateDataPartition(volName string, count int) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	for i := 0; i < count; i++ {
+		api.c.createDataPartition(vol)
+	}
+	return
+}
+
+// DecommissionDataPartition replaces the replica on the node with one on a node not hosting the partition.
+func (api *AdminAPI) DecommissionDataPartition(dataPartitionID uint64, nodeAddr string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var dp *proto.DataPartitionInfo
+	if dp, err = api.c.findDataPartition(dataPartitionID); err != nil {
+		return
+	}
+	var target string
+	if target, err = replaceHost(dp.Hosts, DataNodes, nodeAddr, proto.ErrNoDataNodeToCreateDataPartition); err != nil {
+		return
+	}
+	for _, replica := range dp.Replicas {
+		if replica.Addr == nodeAddr {
+			replica.Addr = target
+		}
+	}
+	api.c.viewEpoch++
+	return
+}
+
+// DecommissionMetaPartition replaces the replica on the node with one on a node not hosting the partition.
+func (api *AdminAPI) DecommissionMetaPartition(metaPartitionID uint64, nodeAddr string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var mp *proto.MetaPartitionInfo
+	if mp, err = api.c.findMetaPartition(metaPartitionID); err != nil {
+		return
+	}
+	var target string
+	if target, err = replaceHost(mp.Hosts, MetaNodes, nodeAddr, proto.ErrNoMetaNodeToCreateMetaPartition); err != nil {
+		return
+	}
+	for _, replica := range mp.Replicas {
+		if replica.Addr == nodeAddr {
+			replica.Addr = target
+		}
+	}
+	api.c.viewEpoch++
+	return
+}
+
+func replaceHost(hosts, nodes []string, addr string, errNoNode error) (target string, err error) {
+	index := indexOf(hosts, addr)
+	if index < 0 {
+		return "", proto.ErrParamError
+	}
+	for _, node := range nodes {
+		if indexOf(hosts, node) < 0 {
+			hosts[index] = node
+			return node, nil
+		}
+	}
+	return "", errNoNode
+}
+
+func indexOf(hosts []string, addr string) int {
+	for i, host := range hosts {
+		if host == addr {
+			return i
+		}
+	}
+	return -1
+}
+
+func (api *AdminAPI) DeleteDataReplica(dataPartitionID uint64, nodeAddr string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var dp *proto.DataPartitionInfo
+	if dp, err = api.c.findDataPartition(dataPartitionID); err != nil {
+		return
+	}
+	index := indexOf(dp.Hosts, nodeAddr)
+	if index < 0 {
+		return proto.ErrParamError
+	}
+	dp.Hosts = append(dp.Hosts[:index], dp.Hosts[index+1:]...)
+	for i, replica := range dp.Replicas {
+		if replica.Addr == nodeAddr {
+			dp.Replicas = append(dp.Replicas[:i], dp.Replicas[i+1:]...)
+			break
+		}
+	}
+	api.c.viewEpoch++
+	return
+}
+
+func (api *AdminAPI) AddDataReplica(dataPartitionID uint64, nodeAddr string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var dp *proto.DataPartitionInfo
+	if dp, err = api.c.findDataPartition(dataPartitionID); err != nil {
+		return
+	}
+	if indexOf(dp.Hosts, nodeAddr) >= 0 {
+		return proto.ErrParamError
+	}
+	dp.Hosts = append(dp.Hosts, nodeAddr)
+	dp.Replicas = append(dp.Replicas, &proto.DataReplica{
+		Addr:       nodeAddr,
+		ReportTime: time.Now().Unix(),
+		Status:     proto.ReadWrite,
+	})
+	api.c.viewEpoch++
+	return
+}
+
+func (api *AdminAPI) ResetDataPartitionRaft(dataPartitionID uint64, survivor string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var dp *proto.DataPartitionInfo
+	if dp, err = api.c.findDataPartition(dataPartitionID); err != nil {
+		return
+	}
+	if indexOf(dp.Hosts, survivor) < 0 {
+		return proto.ErrParamError
+	}
+	dp.Hosts = []string{survivor}
+	replicas := make([]*proto.DataReplica, 0, 1)
+	for _, replica := range dp.Replicas {
+		if replica.Addr == survivor {
+			replicas = append(replicas, replica)
+		}
+	}
+	dp.Replicas = replicas
+	peers := make([]proto.Peer, 0, 1)
+	for _, peer := range dp.Peers {
+		if peer.Addr == survivor {
+			peers = append(peers, peer)
+		}
+	}
+	dp.Peers = peers
+	api.c.viewEpoch++
+	return
+}
+
+func (api *AdminAPI) DeleteMetaReplica(metaPartitionID uint64, nodeAddr string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var mp *proto.MetaPartitionInfo
+	if mp, err = api.c.findMetaPartition(metaPartitionID); err != nil {
+		return
+	}
+	index := indexOf(mp.Hosts, nodeAddr)
+	if index < 0 {
+		return proto.ErrParamError
+	}
+	mp.Hosts = append(mp.Hosts[:index], mp.Hosts[index+1:]...)
+	for i, replica := range mp.Replicas {
+		if replica.Addr == nodeAddr {
+			mp.Replicas = append(mp.Replicas[:i], mp.Replicas[i+1:]...)
+			break
+		}
+	}
+	api.c.viewEpoch++
+	return
+}
+
+func (api *AdminAPI) AddMetaReplica(metaPartitionID uint64, nodeAddr string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var mp *proto.MetaPartitionInfo
+	if mp, err = api.c.findMetaPartition(metaPartitionID); err != nil {
+		return
+	}
+	if indexOf(mp.Hosts, nodeAddr) >= 0 {
+		return proto.ErrParamError
+	}
+	mp.Hosts = append(mp.Hosts, nodeAddr)
+	mp.Replicas = append(mp.Replicas, &proto.MetaReplicaInfo{
+		Addr:       nodeAddr,
+		ReportTime: time.Now().Unix(),
+		Status:     proto.ReadWrite,
+	})
+	api.c.viewEpoch++
+	return
+}
+
+// DeleteVolume removes the volume at once, and the policies of the users on the volume.
+func (api *AdminAPI) DeleteVolume(volName, authKey string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	delete(api.c.vols, volName)
+	if owner, ok := api.c.users[vol.view.Owner]; ok {
+		owner.Policy.RemoveOwnVol(volName)
+	}
+	api.c.deleteVolPolicy(volName)
+	return
+}
+
+// UpdateVolume updates the capacity, the replica number, the zone and the permissions of the volume, the other
+// settings of the volume are kept.
+func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int, followerRead, authenticate, enableToken bool, authKey, zoneName string) (err error) {
+	var vv *proto.SimpleVolView
+	if vv, err = api.GetVolumeSimpleInfo(volName); err != nil {
+		return
+	}
+	req := proto.NewUpdateVolRequest(vv, authKey)
+	req.Capacity = capacity
+	req.DpReplicaNum = replicas
+	req.FollowerRead = followerRead
+	req.Authenticate = authenticate
+	req.EnableToken = enableToken
+	req.ZoneName = zoneName
+	return api.UpdateVolumeWithRequest(req)
+}
+
+// UpdateVolumeWithRequest updates all the settings of the volume in the request, see NewUpdateVolRequest.
+func (api *AdminAPI) UpdateVolumeWithRequest(req *proto.UpdateVolRequest) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(req.Name, req.AuthKey); err != nil {
+		return
+	}
+	if req.Capacity == 0 || req.DpReplicaNum <= 0 || req.DpReplicaNum > len(DataNodes) || req.MpReplicaNum > len(MetaNodes) {
+		return proto.ErrParamError
+	}
+	vol.view.Capacity = req.Capacity
+	vol.view.DpReplicaNum = uint8(req.DpReplicaNum)
+	if req.MpReplicaNum > 0 {
+		vol.view.MpReplicaNum = uint8(req.MpReplicaNum)
+	}
+	vol.view.FollowerRead = req.FollowerRead
+	vol.view.Authenticate = req.Authenticate
+	vol.view.EnableToken = req.EnableToken
+	if req.ZoneName != "" {
+		vol.view.ZoneName = req.ZoneName
+	}
+	vol.view.MaxIOPS = req.MaxIOPS
+	vol.view.MaxBandwidth = req.MaxBandwidth
+	vol.view.VerifyRead = req.VerifyRead
+	vol.view.TrashDays = req.TrashDays
+	vol.view.ZoneAntiAffinity = req.ZoneAntiAffinity
+	vol.view.StrictZones = req.StrictZones
+	vol.view.Atime = req.Atime
+	vol.view.FileAudit = req.FileAudit
+	return
+}
+
+func (api *AdminAPI) SetVolTierRule(volName, authKey string, rule *proto.TierRule) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	if err = rule.Validate(); err != nil {
+		return proto.ErrParamError
+	}
+	r := *rule
+	for i, existing := range vol.tierRules {
+		if existing.Name == rule.Name {
+			vol.tierRules[i] = &r
+			return
+		}
+	}
+	vol.tierRules = append(vol.tierRules, &r)
+	return
+}
+
+func (api *AdminAPI) DeleteVolTierRule(volName, authKey, ruleName string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	for i, existing := range vol.tierRules {
+		if existing.Name == ruleName {
+			vol.tierRules = append(vol.tierRules[:i], vol.tierRules[i+1:]...)
+			return
+		}
+	}
+	return proto.ErrParamError
+}
+
+func (api *AdminAPI) GetVolTierPolicy(volName string) (view *proto.VolTierPolicyView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	view = &proto.VolTierPolicyView{VolName: volName, Rules: make([]*proto.TierRule, 0, len(vol.tierRules))}
+	for _, rule := range vol.tierRules {
+		r := *rule
+		view.Rules = append(view.Rules, &r)
+	}
+	return
+}
+
+// SetVolWormRetention refuses to shorten the retention, as the master does.
+func (api *AdminAPI) SetVolWormRetention(volName, authKey string, days uint32) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	if days < vol.view.WormRetentionDays {
+		return proto.ErrParamError
+	}
+	vol.view.WormRetentionDays = days
+	return
+}
+
+func (api *AdminAPI) OverrideVolWorm(volName, authKey string, duration time.Duration) (until int64, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	if vol.view.WormRetentionDays == 0 || duration <= 0 {
+		return 0, proto.ErrParamError
+	}
+	vol.view.WormOverrideUntil = time.Now().Add(duration).Unix()
+	return vol.view.WormOverrideUntil, nil
+}
+
+// ListTasks returns no tasks, since the fake cluster never sends admin tasks.
+func (api *AdminAPI) ListTasks(nodeAddr string) (views []*proto.AdminTaskView, err error) {
+	return make([]*proto.AdminTaskView, 0), nil
+}
+
+func (api *AdminAPI) CancelTask(nodeAddr, taskID string) (err error) {
+	return proto.ErrParamError
+}
+
+func (api *AdminAPI) PauseTask(nodeAddr, taskID string) (err error) {
+	return proto.ErrParamError
+}
+
+func (api *AdminAPI) ResumeTask(nodeAddr, taskID string) (err error) {
+	return proto.ErrParamError
+}
+
+func (api *AdminAPI) SetRepairLink(srcZone, dstZone string, maxBandwidth uint64) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	key := srcZone + "/" + dstZone
+	if maxBandwidth == 0 {
+		delete(api.c.repairLinks, key)
+		return
+	}
+	if srcZone != proto.DefaultZoneName || dstZone != proto.DefaultZoneName {
+		return proto.ErrZoneNotExists
+	}
+	api.c.repairLinks[key] = &proto.RepairLink{SrcZone: srcZone, DstZone: dstZone, MaxBandwidth: maxBandwidth}
+	return
+}
+
+func (api *AdminAPI) ListRepairLinks() (links []*proto.RepairLink, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	links = make([]*proto.RepairLink, 0, len(api.c.repairLinks))
+	for _, link := range api.c.repairLinks {
+		l := *link
+		links = append(links, &l)
+	}
+	return
+}
+
+func (api *AdminAPI) SetCordon(cordonType, name, reason string, until int64) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var nodes []string
+	switch cordonType {
+	case proto.CordonTypeZone:
+		nodes = []string{proto.DefaultZoneName}
+	case proto.CordonTypeDataNode:
+		nodes = DataNodes
+	case proto.CordonTypeMetaNode:
+		nodes = MetaNodes
+	default:
+		return proto.ErrParamError
+	}
+	var found bool
+	for _, node := range nodes {
+		found = found || node == name
+	}
+	if !found {
+		return proto.ErrParamError
+	}
+	now := time.Now().Unix()
+	if until != 0 && until <= now {
+		return proto.ErrParamError
+	}
+	api.c.cordons[cordonType+"/"+name] = &proto.NodeCordon{Type: cordonType, Name: name, Reason: reason, Since: now, Until: until}
+	return
+}
+
+func (api *AdminAPI) RemoveCordon(cordonType, name string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	delete(api.c.cordons, cordonType+"/"+name)
+	return
+}
+
+func (api *AdminAPI) ListCordons() (cordons []*proto.NodeCordon, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	return api.c.activeCordons(), nil
+}
+
+func (c *Cluster) activeCordons() (cordons []*proto.NodeCordon) {
+	now := time.Now().Unix()
+	cordons = make([]*proto.NodeCordon, 0, len(c.cordons))
+	for _, cordon := range c.cordons {
+		if cordon.IsActive(now) {
+			nc := *cordon
+			cordons = append(cordons, &nc)
+		}
+	}
+	sort.Slice(cordons, func(i, j int) bool {
+		if cordons[i].Type != cordons[j].Type {
+			return cordons[i].Type < cordons[j].Type
+		}
+		return cordons[i].Name < cordons[j].Name
+	})
+	return
+}
+
+func (api *AdminAPI) SetRebuildPolicy(policy *proto.RebuildPolicy) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if policy.DelaySec < 0 {
+		return proto.ErrParamError
+	}
+	if policy.Zone != "" && policy.Zone != proto.DefaultZoneName {
+		return proto.ErrZoneNotExists
+	}
+	p := *policy
+	api.c.rebuildPolicies[policy.Zone] = &p
+	return
+}
+
+func (api *AdminAPI) RemoveRebuildPolicy(zone string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	delete(api.c.rebuildPolicies, zone)
+	return
+}
+
+func (api *AdminAPI) ListRebuildPolicies() (policies []*proto.RebuildPolicy, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	policies = make([]*proto.RebuildPolicy, 0, len(api.c.rebuildPolicies))
+	for _, policy := range api.c.rebuildPolicies {
+		p := *policy
+		policies = append(policies, &p)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Zone < policies[j].Zone
+	})
+	return
+}
+
+func (api *AdminAPI) SetNFSExport(export *proto.NFSExport) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if export.Path == "" || export.Path[0] != '/' {
+		return proto.ErrParamError
+	}
+	for _, client := range export.Clients {
+		if _, _, err = net.ParseCIDR(client); err != nil {
+			return proto.ErrParamError
+		}
+	}
+	if _, ok := api.c.vols[export.Volume]; !ok {
+		return proto.ErrVolNotExists
+	}
+	ne := *export
+	api.c.nfsExports[export.Path] = &ne
+	return
+}
+
+func (api *AdminAPI) RemoveNFSExport(exportPath string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	delete(api.c.nfsExports, exportPath)
+	return
+}
+
+func (api *AdminAPI) ListNFSExports() (exports []*proto.NFSExport, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	exports = make([]*proto.NFSExport, 0, len(api.c.nfsExports))
+	for _, export := range api.c.nfsExports {
+		ne := *export
+		exports = append(exports, &ne)
+	}
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].Path < exports[j].Path
+	})
+	return
+}
+
+// ListAuditEvents returns no events, the fake master does not audit the operations.
+func (api *AdminAPI) ListAuditEvents(from, to int64, op, target string, limit int) (events []*proto.AuditEvent, err error) {
+	return make([]*proto.AuditEvent, 0), nil
+}
+
+// BackupMeta writes a backup without any key-value pair, the fake master keeps no metadata store.
+func (api *AdminAPI) BackupMeta(w io.Writer) (err error) {
+	api.c.RLock()
+	header := &proto.MasterMetaBackup{ClusterName: api.c.name, CreateTime: time.Now().Unix()}
+	api.c.RUnlock()
+	gw := gzip.NewWriter(w)
+	if err = json.NewEncoder(gw).Encode(header); err != nil {
+		return
+	}
+	return gw.Close()
+}
+
+func (api *AdminAPI) VolShrink(volName string, capacity uint64, authKey string) (err error) {
+	_, err = api.resizeVol(volName, capacity, authKey, false)
+	return
+}
+
+func (api *AdminAPI) VolExpand(volName string, capacity uint64, authKey string) (view *proto.VolCapacityView, err error) {
+	return api.resizeVol(volName, capacity, authKey, true)
+}
+
+func (api *AdminAPI) resizeVol(volName string, capacity uint64, authKey string, expand bool) (view *proto.VolCapacityView, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	if expand && capacity < vol.view.Capacity || !expand && capacity >= vol.view.Capacity {
+		return nil, proto.ErrParamError
+	}
+	view = &proto.VolCapacityView{
+		Name:          volName,
+		OldCapacity:   vol.view.Capacity,
+		Capacity:      capacity,
+		CapacityBytes: capacity * util.GB,
+		Completed:     true,
+	}
+	vol.view.Capacity = capacity
+	return
+}
+
+// CreateVolume creates the volume and its partitions, and the owner if it does not exist, as the master does.
+func (api *AdminAPI) CreateVolume(volName, owner string, mpCount int,
+	dpSize uint64, capacity uint64, replicas int, followerRead bool, zoneName string, zoneAntiAffinity, strictZones bool) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if volName == "" || owner == "" || replicas <= 0 || replicas > len(DataNodes) {
+		return proto.ErrParamError
+	}
+	if _, ok := api.c.vols[volName]; ok {
+		return proto.ErrDuplicateVol
+	}
+	ownerInfo, ok := api.c.users[owner]
+	if !ok {
+		if ownerInfo, err = api.c.createUser(&proto.UserCreateParam{ID: owner, Type: proto.UserTypeNormal}); err != nil {
+			return
+		}
+	}
+	if mpCount < defaultMetaPartitionCount {
+		mpCount = defaultMetaPartitionCount
+	}
+	if zoneName == "" {
+		zoneName = defaultZoneName
+	}
+	api.c.maxVolID++
+	now := time.Now()
+	vol := &fakeVol{
+		view: &proto.SimpleVolView{
+			ID:               api.c.maxVolID,
+			Name:             volName,
+			Owner:            owner,
+			ZoneName:         zoneName,
+			ZoneAntiAffinity: zoneAntiAffinity,
+			StrictZones:      strictZones,
+			DpReplicaNum:     uint8(replicas),
+			MpReplicaNum:     defaultReplicaNum,
+			Status:           volStatusNormal,
+			Capacity:         capacity,
+			FollowerRead:     followerRead,
+			CreateTime:       now.Format(proto.TimeFormat),
+			Tokens:           make(map[string]*proto.Token),
+		},
+		createTime: now.Unix(),
+	}
+	var start, end uint64
+	for i := 0; i < mpCount; i++ {
+		if i != 0 {
+			start = end + 1
+		}
+		end = metaPartitionInodeIDStep * uint64(i+1)
+		if i == mpCount-1 {
+			end = maxMetaPartitionInodeID
+		}
+		api.c.createMetaPartition(vol, start, end)
+	}
+	for i := 0; i < defaultDataPartitionCount; i++ {
+		api.c.createDataPartition(vol)
+	}
+	api.c.vols[volName] = vol
+	ownerInfo.Policy.AddOwnVol(volName)
+	return
+}
+
+func (api *AdminAPI) CreateDefaultVolume(volName, owner string) (err error) {
+	return api.CreateVolume(volName, owner, defaultMetaPartitionCount, defaultDataPartitionSize, defaultCapacity,
+		defaultReplicaNum, false, "", false, false)
+}
+
+func (api *AdminAPI) GetVolumeSimpleInfo(volName string) (vv *proto.SimpleVolView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	view := *vol.view
+	view.Tokens = make(map[string]*proto.Token, len(vol.view.Tokens))
+	for key, token := range vol.view.Tokens {
+		view.Tokens[key] = token
+	}
+	return &view, nil
+}
+
+func (api *AdminAPI) GetClusterInfo() (ci *proto.ClusterInfo, err error) {
+	return &proto.ClusterInfo{Cluster: api.c.name, Ip: "127.0.0.1"}, nil
+}
+
+// CreateMetaPartition splits the last meta partition of the volume at the inode.
+func (api *AdminAPI) CreateMetaPartition(volName string, inodeStart uint64) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	last := vol.metaPartitions[len(vol.metaPartitions)-1]
+	if inodeStart <= last.Start || inodeStart >= last.End {
+		return proto.ErrParamError
+	}
+	end := last.End
+	last.End = inodeStart - 1
+	api.c.createMetaPartition(vol, inodeStart, end)
+	return
+}
+
+func (api *AdminAPI) ListVols(keywords string) (volsInfo []*proto.VolInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	volsInfo = make([]*proto.VolInfo, 0)
+	for name, vol := range api.c.vols {
+		if !strings.Contains(name, keywords) {
+			continue
+		}
+		volsInfo = append(volsInfo, &proto.VolInfo{
+			Name:       name,
+			Owner:      vol.view.Owner,
+			CreateTime: vol.createTime,
+			Status:     vol.view.Status,
+			TotalSize:  vol.view.Capacity << 30,
+			UsedSize:   vol.usedSize,
+		})
+	}
+	return
+}
+
+func (api *AdminAPI) IsFreezeCluster(isFreeze bool) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	api.c.disableAutoAlloc = isFreeze
+	return
+}
+
+func (api *AdminAPI) SetMetaNodeThreshold(threshold float64) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	api.c.metaNodeThreshold = float32(threshold)
+	return
+}
+
+func (api *AdminAPI) SetDeleteParas(batchCount, markDeleteRate, deleteWorkerSleepMs, autoRepairRate string) (err error) {
+	paras := map[string]string{
+		"batchCount":          batchCount,
+		"markDeleteRate":      markDeleteRate,
+		"deleteWorkerSleepMs": deleteWorkerSleepMs,
+		"autoRepairRate":      autoRepairRate,
+	}
+	api.c.Lock()
+	defer api.c.Unlock()
+	for key, value := range paras {
+		if value == "" {
+			continue
+		}
+		if _, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return proto.ErrParamError
+		}
+		api.c.deleteParas[key] = value
+	}
+	return
+}
+
+func (api *AdminAPI) GetDeleteParas() (delParas map[string]string, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	delParas = make(map[string]string, len(api.c.deleteParas))
+	for key, value := range api.c.deleteParas {
+		delParas[key] = value
+	}
+	return
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mastertest
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+)
+
+// ClientAPI is the fake of master.ClientAPI.
+type ClientAPI struct {
+	c *Cluster
+}
+
+func (api *ClientAPI) GetVolume(volName string, authKey string) (vv *proto.VolView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getOwnedVol(volName, authKey); err != nil {
+		return
+	}
+	return api.c.volView(vol), nil
+}
+
+func (api *ClientAPI) GetVolumeWithoutAuthKey(volName string) (vv *proto.VolView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	return api.c.volView(vol), nil
+}
+
+// GetVolumeWithAuthnode ignores the token and the decoder, since the fake cluster has no authnode.
+func (api *ClientAPI) GetVolumeWithAuthnode(volName string, authKey string, token string, decoder master.Decoder) (vv *proto.VolView, err error) {
+	return api.GetVolume(volName, authKey)
+}
+
+func (api *ClientAPI) GetVolumeStat(volName string) (info *proto.VolStatInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	return api.c.volStat(vol), nil
+}
+
+func (api *ClientAPI) GetToken(volName, tokenKey string) (token *proto.Token, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	var ok bool
+	if token, ok = vol.view.Tokens[tokenKey]; !ok {
+		return nil, proto.ErrTokenNotFound
+	}
+	return
+}
+
+func (api *ClientAPI) GetMetaPartition(partitionID uint64) (partition *proto.MetaPartitionInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	return api.c.findMetaPartition(partitionID)
+}
+
+func (api *ClientAPI) GetMetaPartitions(volName string) (views []*proto.MetaPartitionView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	return metaPartitionViews(vol), nil
+}
+
+func (api *ClientAPI) GetDataPartitions(volName string) (view *proto.DataPartitionsView, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(volName); err != nil {
+		return
+	}
+	return &proto.DataPartitionsView{
+		DataPartitions: dataPartitionResponses(vol),
+		ViewEpoch:      api.c.viewEpoch,
+	}, nil
+}
+
+// GetDataPartitionsDelta always returns the full view, which the clients must accept anyway.
+func (api *ClientAPI) GetDataPartitionsDelta(volName string, epoch uint64) (view *proto.DataPartitionsView, err error) {
+	return api.GetDataPartitions(volName)
+}
+
+func (c *Cluster) volView(vol *fakeVol) *proto.VolView {
+	view := &proto.VolView{
+		Name:           vol.view.Name,
+		Owner:          vol.view.Owner,
+		Status:         vol.view.Status,
+		FollowerRead:   vol.view.FollowerRead,
+		MetaPartitions: metaPartitionViews(vol),
+		DataPartitions: dataPartitionResponses(vol),
+		OSSSecure:      &proto.OSSSecure{},
+		CreateTime:     vol.createTime,
+	}
+	if owner, ok := c.users[vol.view.Owner]; ok {
+		view.OSSSecure.AccessKey = owner.AccessKey
+		view.OSSSecure.SecretKey = owner.SecretKey
+	}
+	return view
+}
+
+func metaPartitionViews(vol *fakeVol) []*proto.MetaPartitionView {
+	views := make([]*proto.MetaPartitionView, 0, len(vol.metaPartitions))
+	for _, mp := range vol.metaPartitions {
+		view := &proto.MetaPartitionView{
+			PartitionID: mp.PartitionID,
+			Start:       mp.Start,
+			End:         mp.End,
+			MaxInodeID:  mp.MaxInodeID,
+			InodeCount:  mp.InodeCount,
+			DentryCount: mp.DentryCount,
+			IsRecover:   mp.IsRecover,
+			Members:     append([]string(nil), mp.Hosts...),
+			Status:      mp.Status,
+		}
+		if len(mp.Hosts) > 0 {
+			view.LeaderAddr = mp.Hosts[0]
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func dataPartitionResponses(vol *fakeVol) []*proto.DataPartitionResponse {
+	responses := make([]*proto.DataPartitionResponse, 0, len(vol.dataPartitions))
+	for _, dp := range vol.dataPartitions {
+		response := &proto.DataPartitionResponse{
+			PartitionID: dp.PartitionID,
+			Status:      dp.Status,
+			ReplicaNum:  dp.ReplicaNum,
+			Hosts:       append([]string(nil), dp.Hosts...),
+		}
+		if len(dp.Hosts) > 0 {
+			response.LeaderAddr = dp.Hosts[0]
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
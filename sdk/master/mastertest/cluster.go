@@ -0,0 +1,330 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package mastertest provides in-memory fakes of the master APIs in package master, so that programs
+// taking the master.Admin, master.Client and master.User interfaces can be unit tested without a cluster.
+package mastertest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+)
+
+const (
+	defaultCapacity                 = 10 // GB
+	defaultReplicaNum               = 3
+	defaultMetaPartitionCount       = 3
+	defaultDataPartitionCount       = 10
+	defaultDataPartitionSize        = 120 // GB
+	metaPartitionInodeIDStep        = 1 << 24
+	maxMetaPartitionInodeID         = 1<<63 - 1
+	accessKeyLength                 = 16
+	secretKeyLength                 = 32
+	sessionTokenLength              = 64
+	defaultSessionDuration          = 3600 // seconds
+	minSessionDuration              = 900
+	maxSessionDuration              = 12 * 3600
+	maxSessionsPerUser              = 64
+	volStatusNormal           uint8 = 0
+	defaultZoneName                 = "default"
+	nodeSetCapacity                 = 18
+)
+
+var (
+	// DataNodes are the addresses of the data nodes in the fake cluster.
+	DataNodes = []string{"192.168.0.11:17310", "192.168.0.12:17310", "192.168.0.13:17310"}
+	// MetaNodes are the addresses of the meta nodes in the fake cluster.
+	MetaNodes = []string{"192.168.0.21:17210", "192.168.0.22:17210", "192.168.0.23:17210"}
+)
+
+var (
+	_ master.Admin  = (*AdminAPI)(nil)
+	_ master.Client = (*ClientAPI)(nil)
+	_ master.User   = (*UserAPI)(nil)
+)
+
+// Cluster is the in-memory state shared by the fake APIs. The replicas of every partition are placed
+// on DataNodes or MetaNodes, and the first one is the leader. Nothing is persisted.
+type Cluster struct {
+	sync.RWMutex
+	name               string
+	vols               map[string]*fakeVol
+	users              map[string]*proto.UserInfo
+	accessKeys         map[string]string // access key to user ID
+	maxVolID           uint64
+	maxDataPartitionID uint64
+	maxMetaPartitionID uint64
+	viewEpoch          uint64
+	disableAutoAlloc   bool
+	metaNodeThreshold  float32
+	deleteParas        map[string]string
+	repairLinks        map[string]*proto.RepairLink
+	cordons            map[string]*proto.NodeCordon
+	rebuildPolicies    map[string]*proto.RebuildPolicy
+	nfsExports         map[string]*proto.NFSExport
+	nodeSets           []uint64          // IDs of the node sets of the only zone
+	nodeSetOfNode      map[string]uint64 // the nodes not in the map are in the first node set
+	rand               *rand.Rand
+
+	adminAPI  *AdminAPI
+	clientAPI *ClientAPI
+	userAPI   *UserAPI
+}
+
+type fakeVol struct {
+	view           *proto.SimpleVolView
+	createTime     int64
+	usedSize       uint64
+	metaPartitions []*proto.MetaPartitionInfo
+	dataPartitions []*proto.DataPartitionInfo
+	tierRules      []*proto.TierRule
+}
+
+// NewCluster returns an empty fake cluster.
+func NewCluster(name string) *Cluster {
+	c := &Cluster{
+		name:              name,
+		vols:              make(map[string]*fakeVol),
+		users:             make(map[string]*proto.UserInfo),
+		accessKeys:        make(map[string]string),
+		metaNodeThreshold: 0.75,
+		deleteParas:       make(map[string]string),
+		repairLinks:       make(map[string]*proto.RepairLink),
+		cordons:           make(map[string]*proto.NodeCordon),
+		rebuildPolicies:   make(map[string]*proto.RebuildPolicy),
+		nfsExports:        make(map[string]*proto.NFSExport),
+		nodeSets:          []uint64{1},
+		nodeSetOfNode:     make(map[string]uint64),
+		rand:              rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	c.adminAPI = &AdminAPI{c: c}
+	c.clientAPI = &ClientAPI{c: c}
+	c.userAPI = &UserAPI{c: c}
+	return c
+}
+
+func (c *Cluster) AdminAPI() *AdminAPI {
+	return c.adminAPI
+}
+
+func (c *Cluster) ClientAPI() *ClientAPI {
+	return c.clientAPI
+}
+
+func (c *Cluster) UserAPI() *UserAPI {
+	return c.userAPI
+}
+
+// SetVolUsedSize sets the used size of the volume reported by the stat APIs.
+func (c *Cluster) SetVolUsedSize(volName string, size uint64) error {
+	c.Lock()
+	defer c.Unlock()
+	vol, ok := c.vols[volName]
+	if !ok {
+		return proto.ErrVolNotExists
+	}
+	vol.usedSize = size
+	return nil
+}
+
+// AuthKey returns the auth key of the owner expected by the APIs changing a volume.
+func AuthKey(owner string) string {
+	h := md5.New()
+	h.Write([]byte(owner))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cluster) getVol(name string) (*fakeVol, error) {
+	vol, ok := c.vols[name]
+	if !ok {
+		return nil, proto.ErrVolNotExists
+	}
+	return vol, nil
+}
+
+func (c *Cluster) getOwnedVol(name, authKey string) (*fakeVol, error) {
+	vol, err := c.getVol(name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(authKey) != AuthKey(vol.view.Owner) {
+		return nil, proto.ErrVolAuthKeyNotMatch
+	}
+	return vol, nil
+}
+
+func (c *Cluster) randomString(length int) string {
+	const letters = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = letters[c.rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func (c *Cluster) createMetaPartition(vol *fakeVol, start, end uint64) {
+	c.maxMetaPartitionID++
+	c.viewEpoch++
+	mp := &proto.MetaPartitionInfo{
+		PartitionID: c.maxMetaPartitionID,
+		Start:       start,
+		End:         end,
+		MaxInodeID:  start,
+		VolName:     vol.view.Name,
+		ReplicaNum:  vol.view.MpReplicaNum,
+		Status:      proto.ReadWrite,
+		Hosts:       hosts(MetaNodes, int(vol.view.MpReplicaNum)),
+		MissNodes:   make(map[string]int64),
+	}
+	for i, host := range mp.Hosts {
+		mp.Replicas = append(mp.Replicas, &proto.MetaReplicaInfo{
+			Addr:       host,
+			ReportTime: time.Now().Unix(),
+			Status:     proto.ReadWrite,
+			IsLeader:   i == 0,
+		})
+	}
+	vol.metaPartitions = append(vol.metaPartitions, mp)
+	vol.view.MpCnt = len(vol.metaPartitions)
+	vol.view.MaxMetaPartitionID = mp.PartitionID
+}
+
+func (c *Cluster) createDataPartition(vol *fakeVol) {
+	c.maxDataPartitionID++
+	c.viewEpoch++
+	dp := &proto.DataPartitionInfo{
+		PartitionID:             c.maxDataPartitionID,
+		LastLoadedTime:          time.Now().Unix(),
+		ReplicaNum:              vol.view.DpReplicaNum,
+		Status:                  proto.ReadWrite,
+		Hosts:                   hosts(DataNodes, int(vol.view.DpReplicaNum)),
+		MissingNodes:            make(map[string]int64),
+		VolName:                 vol.view.Name,
+		VolID:                   vol.view.ID,
+		FileInCoreMap:           make(map[string]*proto.FileInCore),
+		FilesWithMissingReplica: make(map[string]int64),
+	}
+	for i, host := range dp.Hosts {
+		dp.Replicas = append(dp.Replicas, &proto.DataReplica{
+			Addr:       host,
+			ReportTime: time.Now().Unix(),
+			Status:     proto.ReadWrite,
+			Total:      defaultDataPartitionSize << 30,
+			IsLeader:   i == 0,
+		})
+	}
+	vol.dataPartitions = append(vol.dataPartitions, dp)
+	vol.view.DpCnt = len(vol.dataPartitions)
+	vol.view.RwDpCnt = len(vol.dataPartitions)
+}
+
+func hosts(nodes []string, replicaNum int) []string {
+	if replicaNum > len(nodes) {
+		replicaNum = len(nodes)
+	}
+	return append([]string(nil), nodes[:replicaNum]...)
+}
+
+func (c *Cluster) findDataPartition(partitionID uint64) (*proto.DataPartitionInfo, error) {
+	for _, vol := range c.vols {
+		for _, dp := range vol.dataPartitions {
+			if dp.PartitionID == partitionID {
+				return dp, nil
+			}
+		}
+	}
+	return nil, proto.ErrDataPartitionNotExists
+}
+
+func (c *Cluster) findMetaPartition(partitionID uint64) (*proto.MetaPartitionInfo, error) {
+	for _, vol := range c.vols {
+		for _, mp := range vol.metaPartitions {
+			if mp.PartitionID == partitionID {
+				return mp, nil
+			}
+		}
+	}
+	return nil, proto.ErrMetaPartitionNotExists
+}
+
+func (c *Cluster) volStat(vol *fakeVol) *proto.VolStatInfo {
+	total := vol.view.Capacity << 30
+	stat := &proto.VolStatInfo{
+		Name:        vol.view.Name,
+		TotalSize:   total,
+		UsedSize:    vol.usedSize,
+		EnableToken: vol.view.EnableToken,
+		TrashDays:   vol.view.TrashDays,
+		Atime:       vol.view.Atime,
+	}
+	if total > 0 {
+		stat.UsedRatio = fmt.Sprintf("%.2f", float64(vol.usedSize)/float64(total))
+	}
+	return stat
+}
+
+func contains(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cluster) nodeSetID(addr string) uint64 {
+	if id, ok := c.nodeSetOfNode[addr]; ok {
+		return id
+	}
+	return c.nodeSets[0]
+}
+
+func (c *Cluster) hasNodeSet(id uint64) bool {
+	for _, nodeSetID := range c.nodeSets {
+		if nodeSetID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeSetInfo returns the view of the node set, the nodes are included if withNodes is true.
+func (c *Cluster) nodeSetInfo(id uint64, withNodes bool) *proto.NodeSetInfo {
+	info := &proto.NodeSetInfo{ID: id, ZoneName: defaultZoneName, Capacity: nodeSetCapacity}
+	for _, node := range nodeViews(DataNodes, uint64(len(MetaNodes))) {
+		if c.nodeSetID(node.Addr) == id {
+			info.DataNodeLen++
+			if withNodes {
+				info.DataNodes = append(info.DataNodes, node)
+			}
+		}
+	}
+	for _, node := range nodeViews(MetaNodes, 0) {
+		if c.nodeSetID(node.Addr) == id {
+			info.MetaNodeLen++
+			if withNodes {
+				info.MetaNodes = append(info.MetaNodes, node)
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,236 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mastertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestVolumeLifecycle(t *testing.T) {
+	c := NewCluster("test")
+	if err := c.AdminAPI().CreateDefaultVolume("vol", "owner"); err != nil {
+		t.Fatalf("create volume: %v", err)
+	}
+	if err := c.AdminAPI().CreateDefaultVolume("vol", "owner"); err != proto.ErrDuplicateVol {
+		t.Fatalf("create duplicate volume: expected %v, got %v", proto.ErrDuplicateVol, err)
+	}
+
+	owner, err := c.UserAPI().GetUserInfo("owner")
+	if err != nil {
+		t.Fatalf("get owner: %v", err)
+	}
+	if !owner.Policy.IsOwn("vol") {
+		t.Fatalf("owner does not own the volume: %v", owner.Policy.OwnVols)
+	}
+
+	if _, err = c.ClientAPI().GetVolume("vol", "wrong"); err != proto.ErrVolAuthKeyNotMatch {
+		t.Fatalf("get volume with wrong key: expected %v, got %v", proto.ErrVolAuthKeyNotMatch, err)
+	}
+	view, err := c.ClientAPI().GetVolume("vol", AuthKey("owner"))
+	if err != nil {
+		t.Fatalf("get volume: %v", err)
+	}
+	if len(view.MetaPartitions) != defaultMetaPartitionCount || len(view.DataPartitions) != defaultDataPartitionCount {
+		t.Fatalf("unexpected partitions: meta %v, data %v", len(view.MetaPartitions), len(view.DataPartitions))
+	}
+	if view.OSSSecure.AccessKey != owner.AccessKey {
+		t.Fatalf("unexpected access key: expected %v, got %v", owner.AccessKey, view.OSSSecure.AccessKey)
+	}
+	last := view.MetaPartitions[len(view.MetaPartitions)-1]
+	if view.MetaPartitions[0].Start != 0 || last.End != maxMetaPartitionInodeID {
+		t.Fatalf("meta partitions do not cover all inodes: %v-%v", view.MetaPartitions[0].Start, last.End)
+	}
+
+	dp := view.DataPartitions[0]
+	if err = c.AdminAPI().DecommissionDataPartition(dp.PartitionID, dp.Hosts[0]); err != proto.ErrNoDataNodeToCreateDataPartition {
+		t.Fatalf("decommission without spare nodes: expected %v, got %v", proto.ErrNoDataNodeToCreateDataPartition, err)
+	}
+	if info, err := c.AdminAPI().GetDataPartition("", dp.PartitionID); err != nil || info.VolName != "vol" {
+		t.Fatalf("get data partition: %v %v", info, err)
+	}
+
+	if err = c.UserAPI().DeleteUser("owner"); err != proto.ErrOwnVolExists {
+		t.Fatalf("delete owner: expected %v, got %v", proto.ErrOwnVolExists, err)
+	}
+	if err = c.AdminAPI().DeleteVolume("vol", AuthKey("owner")); err != nil {
+		t.Fatalf("delete volume: %v", err)
+	}
+	if _, err = c.AdminAPI().GetVolumeSimpleInfo("vol"); err != proto.ErrVolNotExists {
+		t.Fatalf("get deleted volume: expected %v, got %v", proto.ErrVolNotExists, err)
+	}
+	if err = c.UserAPI().DeleteUser("owner"); err != nil {
+		t.Fatalf("delete owner: %v", err)
+	}
+}
+
+func TestUserPolicy(t *testing.T) {
+	c := NewCluster("test")
+	if err := c.AdminAPI().CreateDefaultVolume("vol", "owner"); err != nil {
+		t.Fatalf("create volume: %v", err)
+	}
+	user, err := c.UserAPI().CreateUser(&proto.UserCreateParam{ID: "user", Type: proto.UserTypeNormal})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if !proto.IsValidAK(user.AccessKey) || !proto.IsValidSK(user.SecretKey) {
+		t.Fatalf("invalid keys: %v %v", user.AccessKey, user.SecretKey)
+	}
+	if _, err = c.UserAPI().UpdatePolicy(&proto.UserPermUpdateParam{UserID: "owner", Volume: "vol",
+		Policy: []string{proto.BuiltinPermissionReadOnly.String()}}); err != proto.ErrIsOwner {
+		t.Fatalf("update policy of owner: expected %v, got %v", proto.ErrIsOwner, err)
+	}
+	if _, err = c.UserAPI().UpdatePolicy(&proto.UserPermUpdateParam{UserID: "user", Volume: "vol",
+		Policy: []string{proto.BuiltinPermissionReadOnly.String()}}); err != nil {
+		t.Fatalf("update policy: %v", err)
+	}
+
+	info, err := c.UserAPI().GetAKInfo(user.AccessKey)
+	if err != nil {
+		t.Fatalf("get user by access key: %v", err)
+	}
+	if !info.Policy.IsAuthorized("vol", "", proto.OSSGetObjectAction) {
+		t.Fatalf("user is not authorized: %v", info.Policy.AuthorizedVols)
+	}
+	// the returned information is a copy
+	info.Policy.RemoveAuthorizedVol("vol")
+	users, err := c.UserAPI().ListUsersOfVol("vol")
+	if err != nil || len(users) != 2 {
+		t.Fatalf("list users of volume: %v %v", users, err)
+	}
+
+	if _, err = c.UserAPI().TransferVol(&proto.UserTransferVolParam{Volume: "vol", UserSrc: "owner", UserDst: "user"}); err != nil {
+		t.Fatalf("transfer volume: %v", err)
+	}
+	if _, err = c.ClientAPI().GetVolume("vol", AuthKey("user")); err != nil {
+		t.Fatalf("get volume by the new owner: %v", err)
+	}
+}
+
+func TestPrefixPolicy(t *testing.T) {
+	c := NewCluster("test")
+	if err := c.AdminAPI().CreateDefaultVolume("vol", "owner"); err != nil {
+		t.Fatalf("create volume: %v", err)
+	}
+	if _, err := c.UserAPI().CreateUser(&proto.UserCreateParam{ID: "user", Type: proto.UserTypeNormal}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := c.UserAPI().UpdatePolicy(&proto.UserPermUpdateParam{UserID: "user", Volume: "vol",
+		Policy: []string{proto.BuiltinPermissionReadOnly.String()}}); err != nil {
+		t.Fatalf("update policy: %v", err)
+	}
+	if _, err := c.UserAPI().SetPrefixPolicy(&proto.UserPrefixPolicyParam{UserID: "user", Volume: "vol", Prefix: "logs/teamA/*",
+		Effect: proto.PrefixEffectAllow, Actions: []string{proto.BuiltinPermissionWritable.String()}}); err != nil {
+		t.Fatalf("set allow prefix policy: %v", err)
+	}
+	info, err := c.UserAPI().SetPrefixPolicy(&proto.UserPrefixPolicyParam{UserID: "user", Volume: "vol", Prefix: "logs/teamA/secret/",
+		Effect: proto.PrefixEffectDeny, Actions: []string{proto.OSSGetObjectAction.String()}})
+	if err != nil {
+		t.Fatalf("set deny prefix policy: %v", err)
+	}
+	var cases = []struct {
+		key     string
+		action  proto.Action
+		allowed bool
+	}{
+		{"logs/teamA/app.log", proto.OSSPutObjectAction, true},
+		{"logs/teamB/app.log", proto.OSSPutObjectAction, false},
+		{"logs/teamB/app.log", proto.OSSGetObjectAction, true},
+		{"logs/teamA/secret/key", proto.OSSGetObjectAction, false},
+		{"logs/teamA/secret/key", proto.OSSPutObjectAction, true},
+	}
+	for _, tc := range cases {
+		if allowed := info.Policy.IsAuthorizedKey("vol", tc.key, tc.action); allowed != tc.allowed {
+			t.Fatalf("authorize %v on %v: got %v, want %v", tc.action, tc.key, allowed, tc.allowed)
+		}
+	}
+	if info, err = c.UserAPI().RemovePrefixPolicy(&proto.UserPrefixPolicyParam{UserID: "user", Volume: "vol",
+		Prefix: "logs/teamA/*", Effect: proto.PrefixEffectAllow}); err != nil {
+		t.Fatalf("remove prefix policy: %v", err)
+	}
+	if info.Policy.IsAuthorizedKey("vol", "logs/teamA/app.log", proto.OSSPutObjectAction) {
+		t.Fatalf("user is authorized after the prefix policy is removed")
+	}
+	if _, err = c.UserAPI().RemovePrefixPolicy(&proto.UserPrefixPolicyParam{UserID: "user", Volume: "vol",
+		Prefix: "logs/teamA/*", Effect: proto.PrefixEffectAllow}); err != proto.ErrHaveNoPolicy {
+		t.Fatalf("remove absent prefix policy: got %v, want %v", err, proto.ErrHaveNoPolicy)
+	}
+}
+
+func TestRotateKeys(t *testing.T) {
+	c := NewCluster("test")
+	user, err := c.UserAPI().CreateUser(&proto.UserCreateParam{ID: "user", Type: proto.UserTypeNormal})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	rotated, err := c.UserAPI().RotateKeys(&proto.UserRotateKeyParam{UserID: "user", GracePeriod: 3600})
+	if err != nil {
+		t.Fatalf("rotate keys: %v", err)
+	}
+	if rotated.RetiringAccessKey != user.AccessKey || rotated.AccessKey == user.AccessKey {
+		t.Fatalf("unexpected keys after rotation: %+v", rotated)
+	}
+	info, err := c.UserAPI().GetAKInfo(user.AccessKey)
+	if err != nil {
+		t.Fatalf("get retiring access key: %v", err)
+	}
+	if sk := info.SecretKeyOf(user.AccessKey); sk != user.SecretKey {
+		t.Fatalf("secret key of retiring access key: got %v, want %v", sk, user.SecretKey)
+	}
+	if _, err = c.UserAPI().RotateKeys(&proto.UserRotateKeyParam{UserID: "user"}); err != proto.ErrKeyRotationInProgress {
+		t.Fatalf("rotate keys twice: got %v, want %v", err, proto.ErrKeyRotationInProgress)
+	}
+	if _, err = c.UserAPI().RetireKeys("user"); err != nil {
+		t.Fatalf("retire keys: %v", err)
+	}
+	if _, err = c.UserAPI().GetAKInfo(user.AccessKey); err != proto.ErrAccessKeyNotExists {
+		t.Fatalf("get retired access key: got %v, want %v", err, proto.ErrAccessKeyNotExists)
+	}
+	if _, err = c.UserAPI().GetAKInfo(rotated.AccessKey); err != nil {
+		t.Fatalf("get new access key: %v", err)
+	}
+}
+
+func TestAssumeRole(t *testing.T) {
+	c := NewCluster("test")
+	if _, err := c.UserAPI().CreateUser(&proto.UserCreateParam{ID: "user", Type: proto.UserTypeNormal}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	session, err := c.UserAPI().AssumeRole(&proto.UserAssumeRoleParam{UserID: "user"})
+	if err != nil {
+		t.Fatalf("assume role: %v", err)
+	}
+	if session.SessionToken == "" || session.ExpireTime <= time.Now().Unix() {
+		t.Fatalf("unexpected session credential: %+v", session)
+	}
+	info, err := c.UserAPI().GetAKInfo(session.AccessKey)
+	if err != nil {
+		t.Fatalf("get session access key: %v", err)
+	}
+	if info.UserID != "user" || info.SecretKeyOf(session.AccessKey) != session.SecretKey {
+		t.Fatalf("unexpected user of session access key: %+v", info)
+	}
+	if _, err = c.UserAPI().AssumeRole(&proto.UserAssumeRoleParam{UserID: "user", Duration: 1}); err != proto.ErrParamError {
+		t.Fatalf("assume role with short duration: got %v, want %v", err, proto.ErrParamError)
+	}
+	if err = c.UserAPI().DeleteUser("user"); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if _, err = c.UserAPI().GetAKInfo(session.AccessKey); err != proto.ErrAccessKeyNotExists {
+		t.Fatalf("get session access key of deleted user: got %v, want %v", err, proto.ErrAccessKeyNotExists)
+	}
+}
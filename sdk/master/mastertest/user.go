@@ -0,0 +1,457 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mastertest
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// UserAPI is the fake of master.UserAPI. The returned user information is a copy, as it is
+// decoded from the response of the master.
+type UserAPI struct {
+	c *Cluster
+}
+
+func (api *UserAPI) CreateUser(param *proto.UserCreateParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if userInfo, err = api.c.createUser(param); err != nil {
+		return
+	}
+	return copyUserInfo(userInfo), nil
+}
+
+func (c *Cluster) createUser(param *proto.UserCreateParam) (userInfo *proto.UserInfo, err error) {
+	if param.ID == "" {
+		return nil, proto.ErrInvalidUserID
+	}
+	if !param.Type.Valid() {
+		return nil, proto.ErrInvalidUserType
+	}
+	if _, ok := c.users[param.ID]; ok {
+		return nil, proto.ErrDuplicateUserID
+	}
+	accessKey, secretKey := param.AccessKey, param.SecretKey
+	if accessKey == "" {
+		accessKey = c.randomString(accessKeyLength)
+		for c.accessKeys[accessKey] != "" {
+			accessKey = c.randomString(accessKeyLength)
+		}
+	} else if !proto.IsValidAK(accessKey) {
+		return nil, proto.ErrInvalidAccessKey
+	} else if c.accessKeys[accessKey] != "" {
+		return nil, proto.ErrDuplicateAccessKey
+	}
+	if secretKey == "" {
+		secretKey = c.randomString(secretKeyLength)
+	} else if !proto.IsValidSK(secretKey) {
+		return nil, proto.ErrInvalidSecretKey
+	}
+	userInfo = &proto.UserInfo{
+		UserID:      param.ID,
+		AccessKey:   accessKey,
+		SecretKey:   secretKey,
+		Policy:      proto.NewUserPolicy(),
+		UserType:    param.Type,
+		CreateTime:  time.Now().Format(proto.TimeFormat),
+		Description: param.Description,
+	}
+	c.users[userInfo.UserID] = userInfo
+	c.accessKeys[accessKey] = userInfo.UserID
+	return
+}
+
+func (api *UserAPI) DeleteUser(userID string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var userInfo *proto.UserInfo
+	if userInfo, err = api.c.getUser(userID); err != nil {
+		return
+	}
+	if len(userInfo.Policy.OwnVols) > 0 {
+		return proto.ErrOwnVolExists
+	}
+	if userInfo.UserType == proto.UserTypeRoot {
+		return proto.ErrNoPermission
+	}
+	delete(api.c.users, userID)
+	delete(api.c.accessKeys, userInfo.AccessKey)
+	if userInfo.RetiringAccessKey != "" {
+		delete(api.c.accessKeys, userInfo.RetiringAccessKey)
+	}
+	for _, session := range userInfo.Sessions {
+		delete(api.c.accessKeys, session.AccessKey)
+	}
+	return
+}
+
+func (api *UserAPI) UpdateUser(param *proto.UserUpdateParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if param.UserID == "" {
+		return nil, proto.ErrInvalidUserID
+	}
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if userInfo.UserType == proto.UserTypeRoot {
+		return nil, proto.ErrNoPermission
+	}
+	if param.AccessKey != "" {
+		if !proto.IsValidAK(param.AccessKey) {
+			return nil, proto.ErrInvalidAccessKey
+		}
+		if api.c.accessKeys[param.AccessKey] != "" {
+			return nil, proto.ErrDuplicateAccessKey
+		}
+	}
+	if param.SecretKey != "" && !proto.IsValidSK(param.SecretKey) {
+		return nil, proto.ErrInvalidSecretKey
+	}
+	if param.AccessKey != "" {
+		delete(api.c.accessKeys, userInfo.AccessKey)
+		userInfo.AccessKey = param.AccessKey
+		api.c.accessKeys[userInfo.AccessKey] = userInfo.UserID
+	}
+	if param.SecretKey != "" {
+		userInfo.SecretKey = param.SecretKey
+	}
+	if param.Type.Valid() {
+		userInfo.UserType = param.Type
+	}
+	if param.Description != "" {
+		userInfo.Description = param.Description
+	}
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) GetAKInfo(accesskey string) (userInfo *proto.UserInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	userID, ok := api.c.accessKeys[accesskey]
+	if !ok {
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	userInfo = api.c.users[userID]
+	// the master retires the expired key pairs periodically, the fake does it on lookup
+	if accesskey == userInfo.RetiringAccessKey && userInfo.RetiringExpireTime <= time.Now().Unix() {
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	if session := userInfo.SessionOf(accesskey); session != nil && session.Expired(time.Now().Unix()) {
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) RotateKeys(param *proto.UserRotateKeyParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if param.UserID == "" {
+		return nil, proto.ErrInvalidUserID
+	}
+	if param.GracePeriod < 0 {
+		return nil, proto.ErrParamError
+	}
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if userInfo.UserType == proto.UserTypeRoot {
+		return nil, proto.ErrNoPermission
+	}
+	if userInfo.RetiringAccessKey != "" {
+		if userInfo.RetiringExpireTime > time.Now().Unix() {
+			return nil, proto.ErrKeyRotationInProgress
+		}
+		api.c.retireKeys(userInfo)
+	}
+	accessKey, secretKey := param.AccessKey, param.SecretKey
+	if accessKey == "" {
+		accessKey = api.c.randomString(accessKeyLength)
+		for api.c.accessKeys[accessKey] != "" {
+			accessKey = api.c.randomString(accessKeyLength)
+		}
+	} else if !proto.IsValidAK(accessKey) {
+		return nil, proto.ErrInvalidAccessKey
+	} else if api.c.accessKeys[accessKey] != "" {
+		return nil, proto.ErrDuplicateAccessKey
+	}
+	if secretKey == "" {
+		secretKey = api.c.randomString(secretKeyLength)
+	} else if !proto.IsValidSK(secretKey) {
+		return nil, proto.ErrInvalidSecretKey
+	}
+	userInfo.RetiringAccessKey, userInfo.RetiringSecretKey = userInfo.AccessKey, userInfo.SecretKey
+	userInfo.RetiringExpireTime = time.Now().Unix() + param.GracePeriod
+	userInfo.AccessKey, userInfo.SecretKey = accessKey, secretKey
+	api.c.accessKeys[accessKey] = userInfo.UserID
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) RetireKeys(userID string) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if userInfo, err = api.c.getUser(userID); err != nil {
+		return
+	}
+	if userInfo.RetiringAccessKey == "" {
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	api.c.retireKeys(userInfo)
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) AssumeRole(param *proto.UserAssumeRoleParam) (session *proto.SessionCredential, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if param.UserID == "" {
+		return nil, proto.ErrInvalidUserID
+	}
+	duration := param.Duration
+	if duration == 0 {
+		duration = defaultSessionDuration
+	}
+	if duration < minSessionDuration || duration > maxSessionDuration {
+		return nil, proto.ErrParamError
+	}
+	var userInfo *proto.UserInfo
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if userInfo.UserType == proto.UserTypeRoot {
+		return nil, proto.ErrNoPermission
+	}
+	now := time.Now().Unix()
+	live := userInfo.Sessions[:0]
+	for _, s := range userInfo.Sessions {
+		if s.Expired(now) {
+			delete(api.c.accessKeys, s.AccessKey)
+			continue
+		}
+		live = append(live, s)
+	}
+	userInfo.Sessions = live
+	if len(userInfo.Sessions) >= maxSessionsPerUser {
+		return nil, proto.ErrTooManySessions
+	}
+	accessKey := api.c.randomString(accessKeyLength)
+	for api.c.accessKeys[accessKey] != "" {
+		accessKey = api.c.randomString(accessKeyLength)
+	}
+	session = &proto.SessionCredential{
+		AccessKey:    accessKey,
+		SecretKey:    api.c.randomString(secretKeyLength),
+		SessionToken: api.c.randomString(sessionTokenLength),
+		ExpireTime:   now + duration,
+	}
+	userInfo.Sessions = append(userInfo.Sessions, session)
+	api.c.accessKeys[accessKey] = userInfo.UserID
+	copied := *session
+	return &copied, nil
+}
+
+func (c *Cluster) retireKeys(userInfo *proto.UserInfo) {
+	delete(c.accessKeys, userInfo.RetiringAccessKey)
+	userInfo.RetiringAccessKey, userInfo.RetiringSecretKey, userInfo.RetiringExpireTime = "", "", 0
+}
+
+func (api *UserAPI) GetUserInfo(userID string) (userInfo *proto.UserInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	if userInfo, err = api.c.getUser(userID); err != nil {
+		return
+	}
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) UpdatePolicy(param *proto.UserPermUpdateParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if _, err = api.c.getVol(param.Volume); err != nil {
+		return
+	}
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if userInfo.Policy.IsOwn(param.Volume) {
+		return nil, proto.ErrIsOwner
+	}
+	userInfo.Policy.AddAuthorizedVol(param.Volume, param.Policy)
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) RemovePolicy(param *proto.UserPermRemoveParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if userInfo.Policy.IsOwn(param.Volume) {
+		return nil, proto.ErrIsOwner
+	}
+	userInfo.Policy.RemoveAuthorizedVol(param.Volume)
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) SetPrefixPolicy(param *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if !param.Effect.Valid() {
+		return nil, proto.ErrParamError
+	}
+	if _, err = api.c.getVol(param.Volume); err != nil {
+		return
+	}
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if userInfo.Policy.IsOwn(param.Volume) {
+		return nil, proto.ErrIsOwner
+	}
+	var actions = append([]string(nil), param.Actions...)
+	userInfo.Policy.SetPrefixRule(param.Volume, &proto.PrefixRule{Prefix: param.Prefix, Effect: param.Effect, Actions: actions})
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) RemovePrefixPolicy(param *proto.UserPrefixPolicyParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	if userInfo, err = api.c.getUser(param.UserID); err != nil {
+		return
+	}
+	if !userInfo.Policy.RemovePrefixRule(param.Volume, param.Prefix, param.Effect) {
+		return nil, proto.ErrHaveNoPolicy
+	}
+	return copyUserInfo(userInfo), nil
+}
+
+func (api *UserAPI) DeleteVolPolicy(vol string) (err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	api.c.deleteVolPolicy(vol)
+	return
+}
+
+func (c *Cluster) deleteVolPolicy(vol string) {
+	for _, userInfo := range c.users {
+		userInfo.Policy.RemoveOwnVol(vol)
+		userInfo.Policy.RemoveAuthorizedVol(vol)
+		userInfo.Policy.RemovePrefixRules(vol)
+	}
+}
+
+// TransferVol moves the ownership of the volume, the owner of the volume is changed as well.
+func (api *UserAPI) TransferVol(param *proto.UserTransferVolParam) (userInfo *proto.UserInfo, err error) {
+	api.c.Lock()
+	defer api.c.Unlock()
+	var vol *fakeVol
+	if vol, err = api.c.getVol(param.Volume); err != nil {
+		return
+	}
+	var src, dst *proto.UserInfo
+	if src, err = api.c.getUser(param.UserSrc); err != nil && (err != proto.ErrUserNotExists || !param.Force) {
+		return
+	}
+	if dst, err = api.c.getUser(param.UserDst); err != nil {
+		return
+	}
+	if src != nil {
+		isOwned := src.Policy.IsOwn(param.Volume)
+		if !isOwned && !param.Force && param.UserSrc != param.UserDst {
+			return nil, proto.ErrHaveNoPolicy
+		}
+		src.Policy.RemoveOwnVol(param.Volume)
+	}
+	dst.Policy.AddOwnVol(param.Volume)
+	dst.Policy.RemoveAuthorizedVol(param.Volume)
+	dst.Policy.RemovePrefixRules(param.Volume)
+	vol.view.Owner = dst.UserID
+	return copyUserInfo(dst), nil
+}
+
+func (api *UserAPI) ListUsers(keywords string) (users []*proto.UserInfo, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	users = make([]*proto.UserInfo, 0)
+	for userID, userInfo := range api.c.users {
+		if strings.Contains(userID, keywords) {
+			users = append(users, copyUserInfo(userInfo))
+		}
+	}
+	return
+}
+
+func (api *UserAPI) ListUsersOfVol(vol string) (users []string, err error) {
+	api.c.RLock()
+	defer api.c.RUnlock()
+	users = make([]string, 0)
+	for userID, userInfo := range api.c.users {
+		if userInfo.Policy.HasPolicyOf(vol) || userInfo.Policy.IsOwn(vol) {
+			users = append(users, userID)
+		}
+	}
+	if len(users) == 0 {
+		return nil, proto.ErrHaveNoPolicy
+	}
+	sort.Strings(users)
+	return
+}
+
+func (c *Cluster) getUser(userID string) (*proto.UserInfo, error) {
+	userInfo, ok := c.users[userID]
+	if !ok {
+		return nil, proto.ErrUserNotExists
+	}
+	return userInfo, nil
+}
+
+func copyUserInfo(info *proto.UserInfo) *proto.UserInfo {
+	policy := proto.NewUserPolicy()
+	policy.OwnVols = append(policy.OwnVols, info.Policy.OwnVols...)
+	for vol, actions := range info.Policy.AuthorizedVols {
+		policy.AuthorizedVols[vol] = append([]string(nil), actions...)
+	}
+	for vol, rules := range info.Policy.PrefixPolicies {
+		for _, rule := range rules {
+			policy.PrefixPolicies[vol] = append(policy.PrefixPolicies[vol], &proto.PrefixRule{
+				Prefix:  rule.Prefix,
+				Effect:  rule.Effect,
+				Actions: append([]string(nil), rule.Actions...),
+			})
+		}
+	}
+	var sessions []*proto.SessionCredential
+	for _, session := range info.Sessions {
+		copied := *session
+		sessions = append(sessions, &copied)
+	}
+	return &proto.UserInfo{
+		UserID:      info.UserID,
+		AccessKey:   info.AccessKey,
+		SecretKey:   info.SecretKey,
+		Policy:      policy,
+		UserType:    info.UserType,
+		CreateTime:  info.CreateTime,
+		Description: info.Description,
+
+		RetiringAccessKey:  info.RetiringAccessKey,
+		RetiringSecretKey:  info.RetiringSecretKey,
+		RetiringExpireTime: info.RetiringExpireTime,
+		Sessions:           sessions,
+	}
+}
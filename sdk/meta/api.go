@@ -18,6 +18,7 @@ import (
 	"fmt"
 	syslog "log"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,10 +33,6 @@ import (
 
 // Low-level API, i.e. work with inode
 
-const (
-	BatchIgetRespBuf = 1000
-)
-
 const (
 	OpenRetryInterval = 5 * time.Millisecond
 	OpenRetryLimit    = 1000
@@ -187,42 +184,9 @@ func (mw *MetaWrapper) doInodeGet(inode uint64) (*proto.InodeInfo, error) {
 }
 
 func (mw *MetaWrapper) BatchInodeGet(inodes []uint64) []*proto.InodeInfo {
-	var wg sync.WaitGroup
-
-	batchInfos := make([]*proto.InodeInfo, 0)
-	resp := make(chan []*proto.InodeInfo, BatchIgetRespBuf)
-	candidates := make(map[uint64][]uint64)
-
-	// Target partition does not have to be very accurate.
-	for _, ino := range inodes {
-		mp := mw.getPartitionByInode(ino)
-		if mp == nil {
-			continue
-		}
-		if _, ok := candidates[mp.PartitionID]; !ok {
-			candidates[mp.PartitionID] = make([]uint64, 0, 256)
-		}
-		candidates[mp.PartitionID] = append(candidates[mp.PartitionID], ino)
-	}
-
-	for id, inos := range candidates {
-		mp := mw.getPartitionByID(id)
-		if mp == nil {
-			continue
-		}
-		wg.Add(1)
-		go mw.batchIget(&wg, mp, inos, resp)
-	}
-
-	go func() {
-		wg.Wait()
-		close(resp)
-	}()
-
-	for infos := range resp {
-		batchInfos = append(batchInfos, infos...)
-	}
-	return batchInfos
+	p := mw.NewBatchIgetPipeline()
+	p.Add(inodes...)
+	return p.Wait()
 }
 
 // InodeDelete_ll is a low-level api that removes specified inode immediately
@@ -301,7 +265,19 @@ func (mw *MetaWrapper) BatchGetXAttr(inodes []uint64, keys []string) ([]*proto.X
  * Note that the return value of InodeInfo might be nil without error,
  * and the caller should make sure InodeInfo is valid before using it.
  */
+// Delete_ll deletes the dentry and unlinks the inode. If the trash of the volume is enabled,
+// the files are moved into the trash instead, and purged after the trash days.
 func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*proto.InodeInfo, error) {
+	if !isDir && mw.TrashDays() > 0 {
+		info, moved, err := mw.moveToTrash(parentID, name)
+		if moved || err != nil {
+			return info, err
+		}
+	}
+	return mw.delete(parentID, name, isDir)
+}
+
+func (mw *MetaWrapper) delete(parentID uint64, name string, isDir bool) (*proto.InodeInfo, error) {
 	var (
 		status int
 		inode  uint64
@@ -355,15 +331,101 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 	}
 
 	status, info, err = mw.iunlink(mp, inode)
+	if err == nil && (status == statusNotPerm || status == statusAgain) {
+		// the inode of another partition is in the retention period or has the flags against the removal,
+		// so the dentry is restored, otherwise the inode is orphaned.
+		mw.restoreDentry(parentMP, mp, parentID, name, inode)
+		return nil, statusToErrno(status)
+	}
 	if err != nil || status != statusOK {
 		return nil, nil
 	}
 	return info, nil
 }
 
+// restoreDentry creates the dentry deleted before the unlink of its inode is refused.
+func (mw *MetaWrapper) restoreDentry(parentMP, mp *MetaPartition, parentID uint64, name string, inode uint64) {
+	status, info, err := mw.iget(mp, inode)
+	if err != nil || status != statusOK {
+		log.LogErrorf("restoreDentry: failed to get inode, parentID(%v) name(%v) ino(%v) status(%v) err(%v)",
+			parentID, name, inode, status, err)
+		return
+	}
+	if status, err = mw.dcreate(parentMP, parentID, name, inode, info.Mode); err != nil || status != statusOK {
+		log.LogErrorf("restoreDentry: failed to create dentry, parentID(%v) name(%v) ino(%v) status(%v) err(%v)",
+			parentID, name, inode, status, err)
+		return
+	}
+	log.LogWarnf("restoreDentry: unlink refused, dentry restored, parentID(%v) name(%v) ino(%v)", parentID, name, inode)
+}
+
+// RemoveRecursive removes the directory and everything in it. The directory is detached from its parent
+// and marked at once, and the subtree is removed by the meta nodes in the background, bypassing the trash.
+func (mw *MetaWrapper) RemoveRecursive(parentID uint64, name string) error {
+	parentMP := mw.getPartitionByInode(parentID)
+	if parentMP == nil {
+		log.LogErrorf("RemoveRecursive: No parent partition, parentID(%v) name(%v)", parentID, name)
+		return syscall.ENOENT
+	}
+	status, inode, mode, err := mw.lookup(parentMP, parentID, name)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	if !proto.IsDir(mode) {
+		return syscall.ENOTDIR
+	}
+
+	tx := mw.BeginTx()
+	if err = tx.DeleteDentry(parentID, name, inode); err != nil {
+		return err
+	}
+	if err = tx.SetXAttr(inode, []byte(proto.XAttrKeyRemoveTime), []byte(strconv.FormatInt(time.Now().Unix(), 10))); err != nil {
+		return err
+	}
+	if _, err = tx.Commit(); err != nil {
+		log.LogErrorf("RemoveRecursive: %v parentID(%v) name(%v) ino(%v) err(%v)", tx, parentID, name, inode, err)
+		if err == proto.ErrUnknownOpcode {
+			return syscall.EOPNOTSUPP
+		}
+		return err
+	}
+	log.LogInfof("RemoveRecursive: parentID(%v) name(%v) ino(%v) is detached for removal", parentID, name, inode)
+	return nil
+}
+
+// GetDirSummary_ll returns the recursive statistics of the directory, which are maintained by the meta nodes
+// asynchronously and may fall behind the changes of the subtree. It returns ENODATA if the directory is
+// not summarized yet.
+func (mw *MetaWrapper) GetDirSummary_ll(inode uint64) (*proto.DirSummary, error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("GetDirSummary_ll: no such partition, ino(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+	xattrs, err := mw.batchGetXAttr(mp, []uint64{inode}, proto.DirSummaryKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range xattrs {
+		if summary, ok := proto.DirSummaryFromXAttrs(info.XAttrs); ok && info.Inode == inode {
+			return summary, nil
+		}
+	}
+	return nil, syscall.ENODATA
+}
+
 func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
-	var oldInode uint64
+	return mw.rename(srcParentID, srcName, dstParentID, dstName, true)
+}
+
+// RenameNoReplace renames the entry only if the destination does not exist, otherwise it fails with EEXIST
+// and leaves both entries untouched. The destination appears at once when the dentry is created, which makes
+// it suitable to commit the outputs like HDFS does.
+func (mw *MetaWrapper) RenameNoReplace(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
+	return mw.rename(srcParentID, srcName, dstParentID, dstName, false)
+}
 
+func (mw *MetaWrapper) rename(srcParentID uint64, srcName string, dstParentID uint64, dstName string, overwrite bool) (err error) {
 	srcParentMP := mw.getPartitionByInode(srcParentID)
 	if srcParentMP == nil {
 		return syscall.ENOENT
@@ -378,12 +440,56 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
+	if srcParentID == dstParentID && srcName == dstName {
+		return nil
+	}
+	if srcParentMP.PartitionID == dstParentMP.PartitionID {
+		return mw.renameByLink(srcParentMP, dstParentMP, srcParentID, srcName, dstParentID, dstName, inode, mode, overwrite)
+	}
+
+	// The dentries are moved in a transaction decided by the src parent partition,
+	// so that the rename is atomic even if the parents are in different partitions.
+	tx := mw.BeginTx()
+	if err = tx.DeleteDentry(srcParentID, srcName, inode); err != nil {
+		return
+	}
+	// Note that only regular files are allowed to be overwritten.
+	if err = tx.CreateDentry(dstParentID, dstName, inode, mode, overwrite); err != nil {
+		return
+	}
+	items, err := tx.Commit()
+	if err == proto.ErrUnknownOpcode {
+		log.LogWarnf("rename: transaction is not supported by the meta nodes, fall back to link and unlink, "+
+			"src(%v_%v) dst(%v_%v)", srcParentID, srcName, dstParentID, dstName)
+		return mw.renameByLink(srcParentMP, dstParentMP, srcParentID, srcName, dstParentID, dstName, inode, mode, overwrite)
+	}
+	if err != nil {
+		log.LogErrorf("rename: %v src(%v_%v) dst(%v_%v) err(%v)", tx, srcParentID, srcName, dstParentID, dstName, err)
+		return
+	}
+
+	for _, item := range items {
+		if item.OldInode == 0 {
+			continue
+		}
+		mw.evictReplaced(item.OldInode)
+	}
+
+	return nil
+}
+
+// renameByLink renames the entry by linking the inode to the destination and unlinking it from the source,
+// which is done in a single partition in most cases, and is not atomic across the partitions.
+func (mw *MetaWrapper) renameByLink(srcParentMP, dstParentMP *MetaPartition, srcParentID uint64, srcName string,
+	dstParentID uint64, dstName string, inode uint64, mode uint32, overwrite bool) (err error) {
+	var oldInode uint64
+
 	srcMP := mw.getPartitionByInode(inode)
 	if srcMP == nil {
 		return syscall.ENOENT
 	}
 
-	status, _, err = mw.ilink(srcMP, inode)
+	status, _, err := mw.ilink(srcMP, inode)
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
@@ -395,7 +501,7 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	}
 
 	// Note that only regular files are allowed to be overwritten.
-	if status == statusExist && proto.IsRegular(mode) {
+	if status == statusExist && overwrite && proto.IsRegular(mode) {
 		status, oldInode, err = mw.dupdate(dstParentMP, dstParentID, dstName, inode)
 		if err != nil {
 			return syscall.EAGAIN
@@ -430,28 +536,59 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	mw.iunlink(srcMP, inode)
 
 	if oldInode != 0 {
-		inodeMP := mw.getPartitionByInode(oldInode)
-		if inodeMP != nil {
-			mw.iunlink(inodeMP, oldInode)
-			// evict oldInode to avoid oldInode becomes orphan inode
-			mw.ievict(inodeMP, oldInode)
-		}
+		mw.evictReplaced(oldInode)
 	}
 
 	return nil
 }
 
+// evictReplaced unlinks and evicts the inode replaced by a rename.
+func (mw *MetaWrapper) evictReplaced(inode uint64) {
+	inodeMP := mw.getPartitionByInode(inode)
+	if inodeMP != nil {
+		mw.iunlink(inodeMP, inode)
+		// evict oldInode to avoid oldInode becomes orphan inode
+		mw.ievict(inodeMP, inode)
+	}
+}
+
+// ReadDir_ll returns all the entries of the directory in the order of names. The entries are read
+// page by page, so that the reply of the meta node is bounded for large directories.
 func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
+	var (
+		children []proto.Dentry
+		marker   string
+	)
+	for {
+		page, next, err := mw.ReadDirLimit_ll(parentID, marker, ReadDirPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if children == nil {
+			children = page
+		} else {
+			children = append(children, page...)
+		}
+		if next == "" {
+			return children, nil
+		}
+		marker = next
+	}
+}
+
+// ReadDirLimit_ll returns at most limit entries of the directory from the marker in the order of names,
+// and the marker of the next page, which is empty if all the entries are read. The limit of 0 reads all.
+func (mw *MetaWrapper) ReadDirLimit_ll(parentID uint64, marker string, limit uint64) ([]proto.Dentry, string, error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
-		return nil, syscall.ENOENT
+		return nil, "", syscall.ENOENT
 	}
 
-	status, children, err := mw.readdir(parentMP, parentID)
+	status, children, next, err := mw.readdir(parentMP, parentID, marker, limit)
 	if err != nil || status != statusOK {
-		return nil, statusToErrno(status)
+		return nil, "", statusToErrno(status)
 	}
-	return children, nil
+	return children, next, nil
 }
 
 func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error {
@@ -544,6 +681,21 @@ func (mw *MetaWrapper) Truncate(inode, size uint64) error {
 
 }
 
+// PunchHole removes the file range from the extents of the inode, the size of the file is not changed.
+func (mw *MetaWrapper) PunchHole(inode, offset, size uint64) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("PunchHole: No inode partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.punchHole(mp, inode, offset, size)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	return nil
+}
+
 func (mw *MetaWrapper) Link(parentID uint64, name string, ino uint64) (*proto.InodeInfo, error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
@@ -598,7 +750,16 @@ func (mw *MetaWrapper) Setattr(inode uint64, valid, mode, uid, gid uint32, atime
 		return syscall.EINVAL
 	}
 
-	status, err := mw.setattr(mp, inode, valid, mode, uid, gid, atime, mtime)
+	req := &proto.SetAttrRequest{
+		Inode:      inode,
+		Valid:      valid,
+		Mode:       mode,
+		Uid:        uid,
+		Gid:        gid,
+		AccessTime: atime,
+		ModifyTime: mtime,
+	}
+	status, err := mw.setattr(mp, req)
 	if err != nil || status != statusOK {
 		log.LogErrorf("Setattr: ino(%v) err(%v) status(%v)", inode, err, status)
 		return statusToErrno(status)
@@ -607,6 +768,32 @@ func (mw *MetaWrapper) Setattr(inode uint64, valid, mode, uid, gid uint32, atime
 	return nil
 }
 
+// UpdateModifyTime sets the modify time of the inode, which is called after the file is overwritten.
+func (mw *MetaWrapper) UpdateModifyTime(inode uint64, mtime int64) error {
+	return mw.Setattr(inode, proto.AttrModifyTime, 0, 0, 0, 0, mtime)
+}
+
+// SetInodeFlags_ll sets the immutable and the append-only flags of a regular file.
+func (mw *MetaWrapper) SetInodeFlags_ll(inode uint64, flags uint32) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("SetInodeFlags_ll: No such partition, ino(%v)", inode)
+		return syscall.EINVAL
+	}
+
+	req := &proto.SetAttrRequest{
+		Inode: inode,
+		Valid: proto.AttrFlags,
+		Flags: flags & proto.InodeFlagsMask,
+	}
+	status, err := mw.setattr(mp, req)
+	if err != nil || status != statusOK {
+		log.LogErrorf("SetInodeFlags_ll: ino(%v) flags(%v) err(%v) status(%v)", inode, flags, err, status)
+		return statusToErrno(status)
+	}
+	return nil
+}
+
 func (mw *MetaWrapper) InodeCreate_ll(mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
 	var (
 		status       int
@@ -0,0 +1,33 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// AtimeEnabled returns if the volume updates the access time of the inodes with the relatime policy.
+func (mw *MetaWrapper) AtimeEnabled() bool {
+	return atomic.LoadUint32(&mw.atime) == 1
+}
+
+// UpdateAtime_ll updates the access time of the inode to the given time if it is due with the relatime
+// policy, which is checked by the meta node again, so that the inode is not written if it is not due.
+func (mw *MetaWrapper) UpdateAtime_ll(inode uint64, atime time.Time) error {
+	return mw.Setattr(inode, proto.AttrRelatime, 0, 0, 0, atime.Unix(), 0)
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	// BatchIgetLimit is the max number of inodes got by a request.
+	BatchIgetLimit = 256
+	// BatchIgetConcurrency is the max number of requests of a BatchIgetPipeline in flight.
+	BatchIgetConcurrency = 16
+)
+
+// BatchIgetPipeline gets the inodes of a listing in batches while the listing goes on. The inodes added are grouped
+// by the meta partitions, and a batch is sent once BatchIgetLimit inodes of a partition are added, so that the
+// batches are got concurrently with each other and with the listing. Add and Wait are called by one goroutine.
+type BatchIgetPipeline struct {
+	mw      *MetaWrapper
+	pending map[uint64][]uint64 // the inodes not sent yet, by the partition IDs
+	limit   chan struct{}
+	wg      sync.WaitGroup
+
+	mu    sync.Mutex
+	infos []*proto.InodeInfo
+}
+
+func (mw *MetaWrapper) NewBatchIgetPipeline() *BatchIgetPipeline {
+	return &BatchIgetPipeline{
+		mw:      mw,
+		pending: make(map[uint64][]uint64),
+		limit:   make(chan struct{}, BatchIgetConcurrency),
+	}
+}
+
+// Add adds the inodes to get, and it blocks if BatchIgetConcurrency requests are in flight.
+func (p *BatchIgetPipeline) Add(inodes ...uint64) {
+	for _, ino := range inodes {
+		// Target partition does not have to be very accurate.
+		mp := p.mw.getPartitionByInode(ino)
+		if mp == nil {
+			continue
+		}
+		batch := append(p.pending[mp.PartitionID], ino)
+		if len(batch) < BatchIgetLimit {
+			p.pending[mp.PartitionID] = batch
+			continue
+		}
+		delete(p.pending, mp.PartitionID)
+		p.send(mp, batch)
+	}
+}
+
+func (p *BatchIgetPipeline) send(mp *MetaPartition, inodes []uint64) {
+	p.limit <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.limit
+			p.wg.Done()
+		}()
+		infos := p.mw.batchIget(mp, inodes)
+		p.mu.Lock()
+		p.infos = append(p.infos, infos...)
+		p.mu.Unlock()
+	}()
+}
+
+// Wait sends the inodes left and returns the inodes got, in no particular order. The inodes failed to get are
+// missing from the result.
+func (p *BatchIgetPipeline) Wait() []*proto.InodeInfo {
+	for id, inodes := range p.pending {
+		if mp := p.mw.getPartitionByID(id); mp != nil {
+			p.send(mp, inodes)
+		}
+	}
+	p.pending = make(map[uint64][]uint64)
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	infos := p.infos
+	p.infos = nil
+	return infos
+}
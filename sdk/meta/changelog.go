@@ -0,0 +1,84 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"math"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ErrChangelogExpired is returned if the events after the cursor are dropped by a meta partition, e.g. restarted or
+// too many changes since the cursor, in which case the changes must be found by a listing of the volume, started
+// after a new cursor is taken by ChangelogCursor.
+var ErrChangelogExpired = errors.New("changelog cursor expired")
+
+func (mw *MetaWrapper) sortedPartitions() []*MetaPartition {
+	mw.RLock()
+	partitions := make([]*MetaPartition, 0, len(mw.partitions))
+	for _, mp := range mw.partitions {
+		partitions = append(partitions, mp)
+	}
+	mw.RUnlock()
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].PartitionID < partitions[j].PartitionID
+	})
+	return partitions
+}
+
+// ChangelogCursor returns the cursor of the latest changes of the volume, the events after which are read by
+// ReadChangelog.
+func (mw *MetaWrapper) ChangelogCursor() (cursor proto.ChangelogCursor, err error) {
+	cursor = make(proto.ChangelogCursor)
+	for _, mp := range mw.sortedPartitions() {
+		status, resp, err := mw.readChangelog(mp, math.MaxUint64, 1)
+		if err != nil || status != statusOK {
+			log.LogErrorf("ChangelogCursor: partitionID(%v) err(%v) status(%v)", mp.PartitionID, err, status)
+			return nil, statusToErrno(status)
+		}
+		cursor[mp.PartitionID] = resp.Applied
+	}
+	return
+}
+
+// ReadChangelog reads the events of the changes of the volume after the cursor, up to about limit events of every
+// meta partition, and returns the cursor to read the following events from. The events of a meta partition are in
+// the order of the changes, while the events of different meta partitions are not ordered. The meta partitions
+// created after the cursor are read from the beginning.
+func (mw *MetaWrapper) ReadChangelog(cursor proto.ChangelogCursor, limit int) (events []*proto.ChangeEvent, next proto.ChangelogCursor, err error) {
+	next = make(proto.ChangelogCursor, len(cursor))
+	for pid, seq := range cursor {
+		next[pid] = seq
+	}
+	for _, mp := range mw.sortedPartitions() {
+		status, resp, err := mw.readChangelog(mp, next[mp.PartitionID], limit)
+		if err != nil || status != statusOK {
+			log.LogErrorf("ReadChangelog: partitionID(%v) cursor(%v) err(%v) status(%v)",
+				mp.PartitionID, next[mp.PartitionID], err, status)
+			return nil, nil, statusToErrno(status)
+		}
+		if resp.Expired {
+			log.LogWarnf("ReadChangelog: partitionID(%v) cursor(%v) oldest(%v) expired",
+				mp.PartitionID, next[mp.PartitionID], resp.Oldest)
+			return nil, nil, ErrChangelogExpired
+		}
+		events = append(events, resp.Events...)
+		next[mp.PartitionID] = resp.Next
+	}
+	return
+}
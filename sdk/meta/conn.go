@@ -24,6 +24,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 const (
@@ -33,7 +34,7 @@ const (
 )
 
 type MetaConn struct {
-	conn *net.TCPConn
+	conn net.Conn
 	id   uint64 //PartitionID
 	addr string //MetaNode addr
 }
@@ -46,7 +47,7 @@ func (mc *MetaConn) String() string {
 }
 
 func (mw *MetaWrapper) getConn(partitionID uint64, addr string) (*MetaConn, error) {
-	conn, err := mw.conns.GetConnect(addr)
+	conn, err := mw.muxConns.GetConnect(mw.conns, addr)
 	if err != nil {
 		log.LogWarnf("GetConnect conn: addr(%v) err(%v)", addr, err)
 		return nil, err
@@ -56,7 +57,7 @@ func (mw *MetaWrapper) getConn(partitionID uint64, addr string) (*MetaConn, erro
 }
 
 func (mw *MetaWrapper) putConn(mc *MetaConn, err error) {
-	mw.conns.PutConnect(mc.conn, err != nil)
+	mw.muxConns.PutConnect(mw.conns, mc.conn, err != nil)
 }
 
 func (mw *MetaWrapper) sendToMetaPartition(mp *MetaPartition, req *proto.Packet) (*proto.Packet, error) {
@@ -124,6 +125,18 @@ out:
 }
 
 func (mc *MetaConn) send(req *proto.Packet) (resp *proto.Packet, err error) {
+	span := tracing.StartSpan(req.GetOpMsg(), tracing.SpanKindClient, tracing.SpanContext{})
+	span.SetAttribute(tracing.AttributePeer, mc.addr)
+	span.SetAttribute(tracing.AttributePartitionID, req.PartitionID)
+	req.Trace = span.Context()
+	defer func() {
+		if resp != nil {
+			span.SetAttribute(tracing.AttributeResult, resp.GetResultMsg())
+		}
+		span.Finish(err)
+	}()
+	// The response is not waited longer than the read deadline, so is the request processed.
+	req.SetTimeout(proto.ReadDeadlineTime * time.Second)
 	err = req.WriteToConn(mc.conn)
 	if err != nil {
 		return nil, errors.Trace(err, "Failed to write to conn, req(%v)", req)
@@ -0,0 +1,65 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// Wrapper is the interface of MetaWrapper. Programs which take the interface instead of *MetaWrapper
+// can be unit tested with the in-memory fake in package metatest.
+type Wrapper interface {
+	GetRootIno(subdir string) (uint64, error)
+	Statfs() (total, used uint64)
+	Create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error)
+	Lookup_ll(parentID uint64, name string) (inode uint64, mode uint32, err error)
+	InodeGet_ll(inode uint64) (*proto.InodeInfo, error)
+	BatchInodeGet(inodes []uint64) []*proto.InodeInfo
+	InodeDelete_ll(inode uint64) error
+	BatchGetXAttr(inodes []uint64, keys []string) ([]*proto.XAttrInfo, error)
+	Delete_ll(parentID uint64, name string, isDir bool) (*proto.InodeInfo, error)
+	Rename_ll(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error)
+	ReadDir_ll(parentID uint64) ([]proto.Dentry, error)
+	ReadDirLimit_ll(parentID uint64, marker string, limit uint64) ([]proto.Dentry, string, error)
+	DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error
+	DentryUpdate_ll(parentID uint64, name string, inode uint64) (oldInode uint64, err error)
+	AppendExtentKey(inode uint64, ek proto.ExtentKey) error
+	AppendExtentKeys(inode uint64, eks []proto.ExtentKey) error
+	GetExtents(inode uint64) (gen uint64, size uint64, extents []proto.ExtentKey, err error)
+	Truncate(inode, size uint64) error
+	Link(parentID uint64, name string, ino uint64) (*proto.InodeInfo, error)
+	Evict(inode uint64) error
+	Setattr(inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) error
+	InodeCreate_ll(mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error)
+	InodeLink_ll(inode uint64) (*proto.InodeInfo, error)
+	InodeUnlink_ll(inode uint64) (*proto.InodeInfo, error)
+	InitMultipart_ll(path string, extend map[string]string) (multipartId string, err error)
+	GetMultipart_ll(path, multipartId string) (info *proto.MultipartInfo, err error)
+	AddMultipartPart_ll(path, multipartId string, partId uint16, size uint64, md5 string, inode uint64) (err error)
+	RemoveMultipart_ll(path, multipartID string) (err error)
+	ListMultipart_ll(prefix, delimiter, keyMarker string, multipartIdMarker string, maxUploads uint64) (sessionResponse []*proto.MultipartInfo, err error)
+	XAttrSet_ll(inode uint64, name, value []byte) error
+	XAttrGet_ll(inode uint64, name string) (*proto.XAttrInfo, error)
+	XAttrDel_ll(inode uint64, name string) error
+	XAttrsList_ll(inode uint64) ([]string, error)
+	Owner() string
+	OSSSecure() (accessKey, secretKey string)
+	VolCreateTime() int64
+	Close() error
+	Cluster() string
+	LocalIP() string
+}
+
+var _ Wrapper = (*MetaWrapper)(nil)
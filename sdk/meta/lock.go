@@ -0,0 +1,169 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// RenewLockInterval is the interval to renew the leases of the advisory locks held by the client,
+// which is well within the lease so that a renewal can be retried before the locks are released.
+const RenewLockInterval = proto.FileLockLease / 3
+
+// lockSession identifies the advisory locks of the client, and records the partitions in which
+// they may be held.
+type lockSession struct {
+	sync.Mutex
+	id         string
+	partitions map[uint64]uint64    // partition id -> count of the locks acquired in it
+	renewed    map[uint64]time.Time // partition id -> time of the last successful renewal
+}
+
+func newLockSession(localIP string) *lockSession {
+	return &lockSession{
+		id:         fmt.Sprintf("%v:%v:%v", localIP, os.Getpid(), time.Now().UnixNano()),
+		partitions: make(map[uint64]uint64),
+		renewed:    make(map[uint64]time.Time),
+	}
+}
+
+func (s *lockSession) add(pid uint64) {
+	s.Lock()
+	if _, ok := s.partitions[pid]; !ok {
+		s.renewed[pid] = time.Now()
+	}
+	s.partitions[pid]++
+	s.Unlock()
+}
+
+func (s *lockSession) snapshot() map[uint64]uint64 {
+	s.Lock()
+	defer s.Unlock()
+	partitions := make(map[uint64]uint64, len(s.partitions))
+	for pid, count := range s.partitions {
+		partitions[pid] = count
+	}
+	return partitions
+}
+
+// remove forgets the partition which holds no lock of the session, unless a lock is acquired in it after the count.
+func (s *lockSession) remove(pid, count uint64) {
+	s.Lock()
+	if s.partitions[pid] == count {
+		delete(s.partitions, pid)
+		delete(s.renewed, pid)
+	}
+	s.Unlock()
+}
+
+// renewFailed returns if the leases of the locks in the partition may have expired since the last successful
+// renewal, which is reported only once.
+func (s *lockSession) renewFailed(pid uint64) (expired bool) {
+	s.Lock()
+	defer s.Unlock()
+	last, ok := s.renewed[pid]
+	if !ok || time.Since(last) <= proto.FileLockLease {
+		return false
+	}
+	// report once per lease
+	s.renewed[pid] = time.Now()
+	return true
+}
+
+func (s *lockSession) renewSucceeded(pid uint64) {
+	s.Lock()
+	if _, ok := s.partitions[pid]; ok {
+		s.renewed[pid] = time.Now()
+	}
+	s.Unlock()
+}
+
+// SetLock_ll acquires or releases the advisory lock of the inode without waiting. The session of the lock
+// is filled by the meta wrapper. It returns EAGAIN and the conflicting lock if the lock is held by another owner.
+func (mw *MetaWrapper) SetLock_ll(inode uint64, lock *proto.FileLock) (conflict *proto.FileLock, err error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("SetLock_ll: no such partition, ino(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+	lock.Session = mw.locks.id
+	if lock.Type != proto.FileLockUnlock {
+		mw.locks.add(mp.PartitionID)
+	}
+	status, conflict, err := mw.setLock(mp, inode, lock)
+	if err != nil {
+		return nil, statusToErrno(statusError)
+	}
+	if status == statusExist {
+		return conflict, syscall.EAGAIN
+	}
+	if status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return nil, nil
+}
+
+// GetLock_ll returns a lock of the inode conflicting with the given one, or nil if there is none.
+func (mw *MetaWrapper) GetLock_ll(inode uint64, lock *proto.FileLock) (conflict *proto.FileLock, err error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("GetLock_ll: no such partition, ino(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+	lock.Session = mw.locks.id
+	status, conflict, err := mw.getLock(mp, inode, lock)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return conflict, nil
+}
+
+func (mw *MetaWrapper) renewLockWorker() {
+	t := time.NewTicker(RenewLockInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mw.closeCh:
+			return
+		case <-t.C:
+			for pid, count := range mw.locks.snapshot() {
+				mp := mw.getPartitionByID(pid)
+				if mp == nil {
+					continue
+				}
+				status, locks, err := mw.renewLock(mp, mw.locks.id)
+				if err != nil || status != statusOK {
+					log.LogWarnf("renewLockWorker: volume(%v) mp(%v) status(%v) err(%v)", mw.volname, pid, status, err)
+					if mw.locks.renewFailed(pid) {
+						log.LogErrorf("renewLockWorker: volume(%v) mp(%v) session(%v) locks may be lost, "+
+							"not renewed within the lease(%v)", mw.volname, pid, mw.locks.id, proto.FileLockLease)
+					}
+					continue
+				}
+				mw.locks.renewSucceeded(pid)
+				if locks == 0 {
+					mw.locks.remove(pid, count)
+				}
+			}
+		}
+	}
+}
@@ -57,6 +57,9 @@ const (
 	 * i.e. only one force update request is allowed every 5 sec.
 	 */
 	MinForceUpdateMetaPartitionsInterval = 5
+
+	// ReadDirPageSize is the number of entries read from a meta node at a time.
+	ReadDirPageSize = 10000
 )
 
 type AsyncTaskErrorFunc func(err error)
@@ -75,6 +78,8 @@ type MetaConfig struct {
 	TicketMess       auth.TicketMess
 	ValidateOwner    bool
 	OnAsyncTaskError AsyncTaskErrorFunc
+	// ConnMux multiplexes the requests over a shared connection to every meta node, see proto.MuxConn.
+	ConnMux bool
 }
 
 type MetaWrapper struct {
@@ -89,6 +94,7 @@ type MetaWrapper struct {
 	mc              *masterSDK.MasterClient
 	ac              *authSDK.AuthClient
 	conns           *util.ConnectPool
+	muxConns        *proto.MuxPool // nil if the multiplexed connections are disabled
 
 	// Callback handler for handling asynchronous task errors.
 	onAsyncTaskError AsyncTaskErrorFunc
@@ -108,6 +114,9 @@ type MetaWrapper struct {
 
 	totalSize uint64
 	usedSize  uint64
+	trashIno  uint64 // the trash directory restricted by this client
+	trashDays uint32
+	atime     uint32 // 1 if the access time is updated with the relatime policy
 
 	authenticate bool
 	Ticket       auth.Ticket
@@ -125,6 +134,12 @@ type MetaWrapper struct {
 	// Used to trigger and throttle instant partition updates
 	forceUpdate      chan struct{}
 	forceUpdateLimit *rate.Limiter
+
+	// Identifies and renews the advisory locks held by the client
+	locks *lockSession
+
+	// Sequence of the metadata transactions started by the client
+	txSeq uint64
 }
 
 //the ticket from authnode
@@ -161,6 +176,9 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 	mw.mc = masterSDK.NewMasterClient(config.Masters, false)
 	mw.onAsyncTaskError = config.OnAsyncTaskError
 	mw.conns = util.NewConnectPool()
+	if config.ConnMux {
+		mw.muxConns = proto.NewMuxPool(proto.MuxMaxMetaPacketSize)
+	}
 	mw.partitions = make(map[uint64]*MetaPartition)
 	mw.ranges = btree.New(32)
 	mw.rwPartitions = make([]*MetaPartition, 0)
@@ -189,7 +207,10 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 		return nil, err
 	}
 
+	mw.locks = newLockSession(mw.localIP)
+
 	go mw.refresh()
+	go mw.renewLockWorker()
 	return mw, nil
 }
 
@@ -225,6 +246,9 @@ func (mw *MetaWrapper) Close() error {
 	mw.closeOnce.Do(func() {
 		close(mw.closeCh)
 		mw.conns.Close()
+		if mw.muxConns != nil {
+			mw.muxConns.Close()
+		}
 	})
 	return nil
 }
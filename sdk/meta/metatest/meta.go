@@ -0,0 +1,628 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package metatest provides an in-memory fake of meta.MetaWrapper, so that programs taking the
+// meta.Wrapper interface can be unit tested without meta nodes.
+package metatest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+)
+
+const defaultCapacity = 10 << 30
+
+var _ meta.Wrapper = (*MetaWrapper)(nil)
+
+// MetaWrapper is the fake of meta.MetaWrapper. It keeps the inodes and dentries of one volume in
+// memory and follows the semantics of the meta nodes: the link count of a directory is two plus
+// the number of its entries, an unlinked file stays readable until it is evicted, and only regular
+// files can be overwritten by a rename.
+type MetaWrapper struct {
+	sync.RWMutex
+	cluster    string
+	volume     string
+	owner      string
+	accessKey  string
+	secretKey  string
+	createTime int64
+	capacity   uint64
+
+	inodes       map[uint64]*inode
+	dentries     map[uint64]map[string]*proto.Dentry // parent inode to the entries by name
+	maxInode     uint64
+	multiparts   map[string]*proto.MultipartInfo // multipart ID to the upload session
+	maxMultipart uint64
+}
+
+type inode struct {
+	info    proto.InodeInfo
+	extents []proto.ExtentKey
+	xattrs  map[string]string
+}
+
+// NewMetaWrapper returns a fake with an empty root directory.
+func NewMetaWrapper(volume, owner string) *MetaWrapper {
+	mw := &MetaWrapper{
+		cluster:    "metatest",
+		volume:     volume,
+		owner:      owner,
+		createTime: time.Now().Unix(),
+		capacity:   defaultCapacity,
+		inodes:     make(map[uint64]*inode),
+		dentries:   make(map[uint64]map[string]*proto.Dentry),
+		maxInode:   proto.RootIno,
+		multiparts: make(map[string]*proto.MultipartInfo),
+	}
+	mw.inodes[proto.RootIno] = newInode(proto.RootIno, proto.Mode(os.ModeDir|0755), 0, 0, nil)
+	return mw
+}
+
+// SetOSSSecure sets the keys of the owner returned by OSSSecure.
+func (mw *MetaWrapper) SetOSSSecure(accessKey, secretKey string) {
+	mw.Lock()
+	defer mw.Unlock()
+	mw.accessKey, mw.secretKey = accessKey, secretKey
+}
+
+// SetCapacity sets the total size returned by Statfs.
+func (mw *MetaWrapper) SetCapacity(capacity uint64) {
+	mw.Lock()
+	defer mw.Unlock()
+	mw.capacity = capacity
+}
+
+func newInode(ino uint64, mode, uid, gid uint32, target []byte) *inode {
+	now := time.Now()
+	i := &inode{
+		info: proto.InodeInfo{
+			Inode:      ino,
+			Mode:       mode,
+			Nlink:      1,
+			Uid:        uid,
+			Gid:        gid,
+			Generation: 1,
+			ModifyTime: now,
+			CreateTime: now,
+			AccessTime: now,
+			Target:     target,
+		},
+		xattrs: make(map[string]string),
+	}
+	if proto.IsDir(mode) {
+		i.info.Nlink = 2
+	}
+	return i
+}
+
+func (i *inode) copyInfo() *proto.InodeInfo {
+	info := i.info
+	info.Target = append([]byte(nil), i.info.Target...)
+	return &info
+}
+
+func (mw *MetaWrapper) getInode(ino uint64) (*inode, error) {
+	i, ok := mw.inodes[ino]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	return i, nil
+}
+
+func (mw *MetaWrapper) createInode(mode, uid, gid uint32, target []byte) *inode {
+	mw.maxInode++
+	i := newInode(mw.maxInode, mode, uid, gid, target)
+	mw.inodes[i.info.Inode] = i
+	return i
+}
+
+func (mw *MetaWrapper) createDentry(parentID uint64, name string, ino uint64, mode uint32) error {
+	parent, err := mw.getInode(parentID)
+	if err != nil {
+		return err
+	}
+	if !proto.IsDir(parent.info.Mode) {
+		return syscall.EINVAL
+	}
+	children := mw.dentries[parentID]
+	if children == nil {
+		children = make(map[string]*proto.Dentry)
+		mw.dentries[parentID] = children
+	}
+	if d, ok := children[name]; ok {
+		if proto.OsModeType(d.Type) != proto.OsModeType(mode) {
+			return syscall.EINVAL
+		}
+		return syscall.EEXIST
+	}
+	children[name] = &proto.Dentry{Name: name, Inode: ino, Type: mode}
+	parent.info.Nlink++
+	parent.info.ModifyTime = time.Now()
+	return nil
+}
+
+func (mw *MetaWrapper) deleteDentry(parentID uint64, name string) (*proto.Dentry, error) {
+	d, ok := mw.dentries[parentID][name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	delete(mw.dentries[parentID], name)
+	if parent, ok := mw.inodes[parentID]; ok {
+		parent.info.Nlink--
+		parent.info.ModifyTime = time.Now()
+	}
+	return d, nil
+}
+
+func (mw *MetaWrapper) unlinkInode(ino uint64) (*proto.InodeInfo, error) {
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	if proto.IsDir(i.info.Mode) && i.info.Nlink <= 2 {
+		delete(mw.inodes, ino)
+		delete(mw.dentries, ino)
+	}
+	if i.info.Nlink > 0 {
+		i.info.Nlink--
+	}
+	return i.copyInfo(), nil
+}
+
+func (mw *MetaWrapper) GetRootIno(subdir string) (uint64, error) {
+	rootIno := proto.RootIno
+	for _, dir := range strings.Split(subdir, "/") {
+		if dir == "" {
+			continue
+		}
+		child, mode, err := mw.Lookup_ll(rootIno, dir)
+		if err != nil {
+			return 0, fmt.Errorf("GetRootIno: Lookup failed, subdir(%v) dir(%v) err(%v)", subdir, dir, err)
+		}
+		if !proto.IsDir(mode) {
+			return 0, fmt.Errorf("GetRootIno: not directory, subdir(%v) dir(%v)", subdir, dir)
+		}
+		rootIno = child
+	}
+	return rootIno, nil
+}
+
+// Statfs returns the capacity and the total size of the files.
+func (mw *MetaWrapper) Statfs() (total, used uint64) {
+	mw.RLock()
+	defer mw.RUnlock()
+	for _, i := range mw.inodes {
+		if proto.IsRegular(i.info.Mode) {
+			used += i.info.Size
+		}
+	}
+	return mw.capacity, used
+}
+
+func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	if _, err := mw.getInode(parentID); err != nil {
+		return nil, err
+	}
+	i := mw.createInode(mode, uid, gid, target)
+	if err := mw.createDentry(parentID, name, i.info.Inode, mode); err != nil {
+		delete(mw.inodes, i.info.Inode)
+		return nil, err
+	}
+	return i.copyInfo(), nil
+}
+
+func (mw *MetaWrapper) Lookup_ll(parentID uint64, name string) (inode uint64, mode uint32, err error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	d, ok := mw.dentries[parentID][name]
+	if !ok {
+		return 0, 0, syscall.ENOENT
+	}
+	return d.Inode, d.Type, nil
+}
+
+func (mw *MetaWrapper) InodeGet_ll(ino uint64) (*proto.InodeInfo, error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	return i.copyInfo(), nil
+}
+
+func (mw *MetaWrapper) BatchInodeGet(inodes []uint64) []*proto.InodeInfo {
+	mw.RLock()
+	defer mw.RUnlock()
+	infos := make([]*proto.InodeInfo, 0, len(inodes))
+	for _, ino := range inodes {
+		if i, ok := mw.inodes[ino]; ok {
+			infos = append(infos, i.copyInfo())
+		}
+	}
+	return infos
+}
+
+func (mw *MetaWrapper) InodeDelete_ll(ino uint64) error {
+	mw.Lock()
+	defer mw.Unlock()
+	if _, err := mw.getInode(ino); err != nil {
+		return err
+	}
+	delete(mw.inodes, ino)
+	return nil
+}
+
+func (mw *MetaWrapper) BatchGetXAttr(inodes []uint64, keys []string) ([]*proto.XAttrInfo, error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	xattrs := make([]*proto.XAttrInfo, 0, len(inodes))
+	for _, ino := range inodes {
+		i, ok := mw.inodes[ino]
+		if !ok {
+			continue
+		}
+		info := &proto.XAttrInfo{Inode: ino, XAttrs: make(map[string]string)}
+		for _, key := range keys {
+			if value, ok := i.xattrs[key]; ok {
+				info.XAttrs[key] = value
+			}
+		}
+		xattrs = append(xattrs, info)
+	}
+	return xattrs, nil
+}
+
+// Delete_ll returns nil without error if the entry does not exist, as MetaWrapper does.
+func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*proto.InodeInfo, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	d, ok := mw.dentries[parentID][name]
+	if !ok {
+		if _, err := mw.getInode(parentID); err != nil || isDir {
+			return nil, syscall.ENOENT
+		}
+		return nil, nil
+	}
+	if isDir {
+		if !proto.IsDir(d.Type) {
+			return nil, syscall.EINVAL
+		}
+		if i, ok := mw.inodes[d.Inode]; ok && i.info.Nlink > 2 {
+			return nil, syscall.ENOTEMPTY
+		}
+	}
+	if _, err := mw.deleteDentry(parentID, name); err != nil {
+		return nil, err
+	}
+	info, err := mw.unlinkInode(d.Inode)
+	if err != nil {
+		return nil, nil
+	}
+	return info, nil
+}
+
+func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
+	mw.Lock()
+	defer mw.Unlock()
+	if _, err = mw.getInode(dstParentID); err != nil {
+		return
+	}
+	src, ok := mw.dentries[srcParentID][srcName]
+	if !ok {
+		return syscall.ENOENT
+	}
+	if srcParentID == dstParentID && srcName == dstName {
+		return nil
+	}
+	var oldInode uint64
+	if dst, ok := mw.dentries[dstParentID][dstName]; ok {
+		if !proto.IsRegular(src.Type) || proto.OsModeType(dst.Type) != proto.OsModeType(src.Type) {
+			return syscall.EEXIST
+		}
+		oldInode = dst.Inode
+		dst.Inode = src.Inode
+	} else if err = mw.createDentry(dstParentID, dstName, src.Inode, src.Type); err != nil {
+		return
+	}
+	if _, err = mw.deleteDentry(srcParentID, srcName); err != nil {
+		return
+	}
+	if oldInode != 0 {
+		// the overwritten inode is evicted at once, so that it does not become an orphan
+		if _, err = mw.unlinkInode(oldInode); err == nil {
+			mw.evict(oldInode)
+		}
+	}
+	return nil
+}
+
+// ReadDir_ll returns the entries in the order of names.
+func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	if _, err := mw.getInode(parentID); err != nil {
+		return nil, err
+	}
+	children := make([]proto.Dentry, 0, len(mw.dentries[parentID]))
+	for _, d := range mw.dentries[parentID] {
+		children = append(children, *d)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	return children, nil
+}
+
+// ReadDirLimit_ll returns at most limit entries from the marker in the order of names, and the marker of the next page.
+func (mw *MetaWrapper) ReadDirLimit_ll(parentID uint64, marker string, limit uint64) ([]proto.Dentry, string, error) {
+	children, err := mw.ReadDir_ll(parentID)
+	if err != nil {
+		return nil, "", err
+	}
+	i := sort.Search(len(children), func(i int) bool { return children[i].Name >= marker })
+	children = children[i:]
+	if limit == 0 || uint64(len(children)) <= limit {
+		return children, "", nil
+	}
+	return children[:limit], children[limit].Name, nil
+}
+
+func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error {
+	mw.Lock()
+	defer mw.Unlock()
+	return mw.createDentry(parentID, name, inode, mode)
+}
+
+func (mw *MetaWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint64) (oldInode uint64, err error) {
+	mw.Lock()
+	defer mw.Unlock()
+	d, ok := mw.dentries[parentID][name]
+	if !ok {
+		return 0, syscall.ENOENT
+	}
+	oldInode, d.Inode = d.Inode, inode
+	return
+}
+
+func (mw *MetaWrapper) AppendExtentKey(ino uint64, ek proto.ExtentKey) error {
+	return mw.AppendExtentKeys(ino, []proto.ExtentKey{ek})
+}
+
+// AppendExtentKeys replaces the existing extent keys fully covered by the new ones, and extends the
+// file if the new keys end beyond it.
+func (mw *MetaWrapper) AppendExtentKeys(ino uint64, eks []proto.ExtentKey) error {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return err
+	}
+	for _, ek := range eks {
+		extents := i.extents[:0]
+		for _, existing := range i.extents {
+			if existing.FileOffset >= ek.FileOffset && existing.FileOffset+uint64(existing.Size) <= ek.FileOffset+uint64(ek.Size) {
+				continue
+			}
+			extents = append(extents, existing)
+		}
+		i.extents = append(extents, ek)
+		if end := ek.FileOffset + uint64(ek.Size); end > i.info.Size {
+			i.info.Size = end
+		}
+	}
+	sort.SliceStable(i.extents, func(a, b int) bool {
+		return i.extents[a].FileOffset < i.extents[b].FileOffset
+	})
+	i.info.Generation++
+	i.info.ModifyTime = time.Now()
+	return nil
+}
+
+func (mw *MetaWrapper) GetExtents(ino uint64) (gen uint64, size uint64, extents []proto.ExtentKey, err error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return i.info.Generation, i.info.Size, append([]proto.ExtentKey(nil), i.extents...), nil
+}
+
+func (mw *MetaWrapper) Truncate(ino, size uint64) error {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return err
+	}
+	extents := i.extents[:0]
+	for _, ek := range i.extents {
+		if ek.FileOffset >= size {
+			continue
+		}
+		if end := ek.FileOffset + uint64(ek.Size); end > size {
+			ek.Size = uint32(size - ek.FileOffset)
+		}
+		extents = append(extents, ek)
+	}
+	i.extents = extents
+	i.info.Size = size
+	i.info.Generation++
+	i.info.ModifyTime = time.Now()
+	return nil
+}
+
+func (mw *MetaWrapper) Link(parentID uint64, name string, ino uint64) (*proto.InodeInfo, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	if err = mw.createDentry(parentID, name, ino, i.info.Mode); err != nil {
+		return nil, err
+	}
+	i.info.Nlink++
+	return i.copyInfo(), nil
+}
+
+// Evict removes the inode if it is not linked by any entry.
+func (mw *MetaWrapper) Evict(ino uint64) error {
+	mw.Lock()
+	defer mw.Unlock()
+	if _, err := mw.getInode(ino); err != nil {
+		return syscall.EINVAL
+	}
+	mw.evict(ino)
+	return nil
+}
+
+func (mw *MetaWrapper) evict(ino uint64) {
+	if i, ok := mw.inodes[ino]; ok && i.info.Nlink == 0 && !proto.IsDir(i.info.Mode) {
+		delete(mw.inodes, ino)
+	}
+}
+
+func (mw *MetaWrapper) Setattr(ino uint64, valid, mode, uid, gid uint32, atime, mtime int64) error {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return err
+	}
+	if valid&proto.AttrMode != 0 {
+		i.info.Mode = mode
+	}
+	if valid&proto.AttrUid != 0 {
+		i.info.Uid = uid
+	}
+	if valid&proto.AttrGid != 0 {
+		i.info.Gid = gid
+	}
+	if valid&proto.AttrAccessTime != 0 {
+		i.info.AccessTime = time.Unix(atime, 0)
+	}
+	if valid&proto.AttrModifyTime != 0 {
+		i.info.ModifyTime = time.Unix(mtime, 0)
+	}
+	return nil
+}
+
+func (mw *MetaWrapper) InodeCreate_ll(mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	return mw.createInode(mode, uid, gid, target).copyInfo(), nil
+}
+
+func (mw *MetaWrapper) InodeLink_ll(ino uint64) (*proto.InodeInfo, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	i.info.Nlink++
+	return i.copyInfo(), nil
+}
+
+func (mw *MetaWrapper) InodeUnlink_ll(ino uint64) (*proto.InodeInfo, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	return mw.unlinkInode(ino)
+}
+
+func (mw *MetaWrapper) XAttrSet_ll(ino uint64, name, value []byte) error {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return err
+	}
+	i.xattrs[string(name)] = string(value)
+	return nil
+}
+
+// XAttrGet_ll returns an empty value if the attribute does not exist, as MetaWrapper does.
+func (mw *MetaWrapper) XAttrGet_ll(ino uint64, name string) (*proto.XAttrInfo, error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.XAttrInfo{Inode: ino, XAttrs: map[string]string{name: i.xattrs[name]}}, nil
+}
+
+func (mw *MetaWrapper) XAttrDel_ll(ino uint64, name string) error {
+	mw.Lock()
+	defer mw.Unlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return err
+	}
+	delete(i.xattrs, name)
+	return nil
+}
+
+func (mw *MetaWrapper) XAttrsList_ll(ino uint64) ([]string, error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	i, err := mw.getInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(i.xattrs))
+	for name := range i.xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (mw *MetaWrapper) Owner() string {
+	return mw.owner
+}
+
+func (mw *MetaWrapper) OSSSecure() (accessKey, secretKey string) {
+	mw.RLock()
+	defer mw.RUnlock()
+	return mw.accessKey, mw.secretKey
+}
+
+func (mw *MetaWrapper) VolCreateTime() int64 {
+	return mw.createTime
+}
+
+func (mw *MetaWrapper) Close() error {
+	return nil
+}
+
+func (mw *MetaWrapper) Cluster() string {
+	return mw.cluster
+}
+
+func (mw *MetaWrapper) LocalIP() string {
+	return "127.0.0.1"
+}
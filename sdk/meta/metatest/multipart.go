@@ -0,0 +1,140 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metatest
+
+import (
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// partitionID is encoded in the multipart IDs, since all the sessions are kept by one partition.
+const partitionID = 1
+
+func (mw *MetaWrapper) getMultipart(path, multipartID string) (*proto.MultipartInfo, error) {
+	info, ok := mw.multiparts[multipartID]
+	if !ok || info.Path != path {
+		return nil, syscall.ENOENT
+	}
+	return info, nil
+}
+
+func copyMultipartInfo(info *proto.MultipartInfo) *proto.MultipartInfo {
+	c := &proto.MultipartInfo{
+		ID:       info.ID,
+		Path:     info.Path,
+		InitTime: info.InitTime,
+		Parts:    make([]*proto.MultipartPartInfo, 0, len(info.Parts)),
+		Extend:   make(map[string]string, len(info.Extend)),
+	}
+	for _, part := range info.Parts {
+		p := *part
+		c.Parts = append(c.Parts, &p)
+	}
+	for key, value := range info.Extend {
+		c.Extend[key] = value
+	}
+	return c
+}
+
+func (mw *MetaWrapper) InitMultipart_ll(path string, extend map[string]string) (multipartId string, err error) {
+	mw.Lock()
+	defer mw.Unlock()
+	multipartId = util.CreateMultipartID(partitionID).String()
+	for mw.multiparts[multipartId] != nil {
+		multipartId = util.CreateMultipartID(partitionID).String()
+	}
+	info := &proto.MultipartInfo{
+		ID:       multipartId,
+		Path:     path,
+		InitTime: time.Now(),
+		Parts:    make([]*proto.MultipartPartInfo, 0),
+		Extend:   make(map[string]string, len(extend)),
+	}
+	for key, value := range extend {
+		info.Extend[key] = value
+	}
+	mw.multiparts[multipartId] = info
+	return
+}
+
+func (mw *MetaWrapper) GetMultipart_ll(path, multipartId string) (info *proto.MultipartInfo, err error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	if info, err = mw.getMultipart(path, multipartId); err != nil {
+		return
+	}
+	return copyMultipartInfo(info), nil
+}
+
+// AddMultipartPart_ll replaces the part with the same ID, and keeps the parts in the order of IDs.
+func (mw *MetaWrapper) AddMultipartPart_ll(path, multipartId string, partId uint16, size uint64, md5 string, inode uint64) (err error) {
+	mw.Lock()
+	defer mw.Unlock()
+	var info *proto.MultipartInfo
+	if info, err = mw.getMultipart(path, multipartId); err != nil {
+		return
+	}
+	part := &proto.MultipartPartInfo{ID: partId, Inode: inode, MD5: md5, Size: size, UploadTime: time.Now()}
+	for i, existing := range info.Parts {
+		if existing.ID == partId {
+			info.Parts[i] = part
+			return
+		}
+	}
+	info.Parts = append(info.Parts, part)
+	sort.Slice(info.Parts, func(i, j int) bool {
+		return info.Parts[i].ID < info.Parts[j].ID
+	})
+	return
+}
+
+func (mw *MetaWrapper) RemoveMultipart_ll(path, multipartID string) (err error) {
+	mw.Lock()
+	defer mw.Unlock()
+	if _, err = mw.getMultipart(path, multipartID); err != nil {
+		return
+	}
+	delete(mw.multiparts, multipartID)
+	return
+}
+
+// ListMultipart_ll returns at most maxUploads+1 sessions after the markers in the order of paths and
+// IDs, so that the caller can tell whether the result is truncated, as MetaWrapper does with one partition.
+func (mw *MetaWrapper) ListMultipart_ll(prefix, delimiter, keyMarker string, multipartIdMarker string, maxUploads uint64) (sessionResponse []*proto.MultipartInfo, err error) {
+	mw.RLock()
+	defer mw.RUnlock()
+	sessions := make([]*proto.MultipartInfo, 0)
+	for _, info := range mw.multiparts {
+		if !strings.HasPrefix(info.Path, prefix) {
+			continue
+		}
+		if info.Path < keyMarker || info.Path == keyMarker && info.ID < multipartIdMarker {
+			continue
+		}
+		sessions = append(sessions, copyMultipartInfo(info))
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Path < sessions[j].Path || sessions[i].Path == sessions[j].Path && sessions[i].ID < sessions[j].ID
+	})
+	if uint64(len(sessions)) > maxUploads+1 {
+		sessions = sessions[:maxUploads+1]
+	}
+	return sessions, nil
+}
@@ -16,7 +16,6 @@ package meta
 
 import (
 	"fmt"
-	"sync"
 
 	"github.com/chubaofs/chubaofs/util/errors"
 
@@ -364,8 +363,7 @@ func (mw *MetaWrapper) iget(mp *MetaPartition, inode uint64) (status int, info *
 	return statusOK, resp.Info, nil
 }
 
-func (mw *MetaWrapper) batchIget(wg *sync.WaitGroup, mp *MetaPartition, inodes []uint64, respCh chan []*proto.InodeInfo) {
-	defer wg.Done()
+func (mw *MetaWrapper) batchIget(mp *MetaPartition, inodes []uint64) []*proto.InodeInfo {
 	var (
 		err error
 	)
@@ -379,7 +377,7 @@ func (mw *MetaWrapper) batchIget(wg *sync.WaitGroup, mp *MetaPartition, inodes [
 	packet.Opcode = proto.OpMetaBatchInodeGet
 	err = packet.MarshalData(req)
 	if err != nil {
-		return
+		return nil
 	}
 
 	metric := exporter.NewTPCnt(packet.GetOpMsg())
@@ -388,37 +386,31 @@ func (mw *MetaWrapper) batchIget(wg *sync.WaitGroup, mp *MetaPartition, inodes [
 	packet, err = mw.sendToMetaPartition(mp, packet)
 	if err != nil {
 		log.LogErrorf("batchIget: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
-		return
+		return nil
 	}
 
 	status := parseStatus(packet.ResultCode)
 	if status != statusOK {
 		log.LogErrorf("batchIget: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
-		return
+		return nil
 	}
 
 	resp := new(proto.BatchInodeGetResponse)
 	err = packet.UnmarshalData(resp)
 	if err != nil {
 		log.LogErrorf("batchIget: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
-		return
-	}
-
-	if len(resp.Infos) == 0 {
-		return
-	}
-
-	select {
-	case respCh <- resp.Infos:
-	default:
+		return nil
 	}
+	return resp.Infos
 }
 
-func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64) (status int, children []proto.Dentry, err error) {
+func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64, marker string, limit uint64) (status int, children []proto.Dentry, next string, err error) {
 	req := &proto.ReadDirRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
 		ParentID:    parentID,
+		Marker:      marker,
+		Limit:       limit,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -452,7 +444,7 @@ func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64) (status int,
 		return
 	}
 	log.LogDebugf("readdir: packet(%v) mp(%v) req(%v)", packet, mp, *req)
-	return statusOK, resp.Children, nil
+	return statusOK, resp.Children, resp.NextMarker, nil
 }
 
 func (mw *MetaWrapper) appendExtentKey(mp *MetaPartition, inode uint64, extent proto.ExtentKey) (status int, err error) {
@@ -564,6 +556,138 @@ func (mw *MetaWrapper) truncate(mp *MetaPartition, inode, size uint64) (status i
 	return statusOK, nil
 }
 
+func (mw *MetaWrapper) punchHole(mp *MetaPartition, inode, offset, size uint64) (status int, err error) {
+	req := &proto.PunchHoleRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Offset:      offset,
+		Size:        size,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaPunchHole
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("punchHole: ino(%v) offset(%v) size(%v) err(%v)", inode, offset, size, err)
+		return
+	}
+
+	log.LogDebugf("punchHole enter: packet(%v) mp(%v) req(%v)", packet, mp, string(packet.Data))
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("punchHole: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("punchHole: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("punchHole exit: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return statusOK, nil
+}
+
+func (mw *MetaWrapper) txPrepare(mp *MetaPartition, req *proto.TxPrepareRequest) (status int, resp *proto.TxResponse, err error) {
+	req.VolName = mw.volname
+	req.PartitionID = mp.PartitionID
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaTxPrepare
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("txPrepare: tx(%v) err(%v)", req.TxID, err)
+		return
+	}
+
+	log.LogDebugf("txPrepare enter: packet(%v) mp(%v) req(%v)", packet, mp, string(packet.Data))
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("txPrepare: packet(%v) mp(%v) tx(%v) err(%v)", packet, mp, req.TxID, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		if isUnknownOpcode(packet) {
+			err = proto.ErrUnknownOpcode
+		}
+		log.LogErrorf("txPrepare: packet(%v) mp(%v) tx(%v) result(%v)", packet, mp, req.TxID, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.TxResponse)
+	err = packet.UnmarshalData(resp)
+	if err != nil {
+		log.LogErrorf("txPrepare: packet(%v) mp(%v) tx(%v) err(%v) PacketData(%v)", packet, mp, req.TxID, err, string(packet.Data))
+		return
+	}
+
+	log.LogDebugf("txPrepare exit: packet(%v) mp(%v) tx(%v) state(%v)", packet, mp, req.TxID, resp.State)
+	return statusOK, resp, nil
+}
+
+// isUnknownOpcode returns if the request is refused by a meta node which does not know the opcode.
+func isUnknownOpcode(packet *proto.Packet) bool {
+	return packet.ResultCode == proto.OpArgMismatchErr && string(packet.Data) == proto.ErrUnknownOpcode.Error()
+}
+
+// txRequest sends the request to commit, abort or forget a transaction.
+func (mw *MetaWrapper) txRequest(mp *MetaPartition, opcode uint8, req *proto.TxRequest) (status int, resp *proto.TxResponse, err error) {
+	req.VolName = mw.volname
+	req.PartitionID = mp.PartitionID
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = opcode
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("txRequest: tx(%v) err(%v)", req.TxID, err)
+		return
+	}
+
+	log.LogDebugf("txRequest enter: packet(%v) mp(%v) req(%v)", packet, mp, string(packet.Data))
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("txRequest: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		if isUnknownOpcode(packet) {
+			err = proto.ErrUnknownOpcode
+		}
+		log.LogErrorf("txRequest: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.TxResponse)
+	if len(packet.Data) > 0 {
+		err = packet.UnmarshalData(resp)
+		if err != nil {
+			log.LogErrorf("txRequest: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+			return
+		}
+	}
+
+	log.LogDebugf("txRequest exit: packet(%v) mp(%v) req(%v) state(%v)", packet, mp, *req, resp.State)
+	return statusOK, resp, nil
+}
+
 func (mw *MetaWrapper) ilink(mp *MetaPartition, inode uint64) (status int, info *proto.InodeInfo, err error) {
 	req := &proto.LinkInodeRequest{
 		VolName:     mw.volname,
@@ -611,18 +735,9 @@ func (mw *MetaWrapper) ilink(mp *MetaPartition, inode uint64) (status int, info
 	return statusOK, resp.Info, nil
 }
 
-func (mw *MetaWrapper) setattr(mp *MetaPartition, inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) (status int, err error) {
-	req := &proto.SetAttrRequest{
-		VolName:     mw.volname,
-		PartitionID: mp.PartitionID,
-		Inode:       inode,
-		Valid:       valid,
-		Mode:        mode,
-		Uid:         uid,
-		Gid:         gid,
-		AccessTime:  atime,
-		ModifyTime:  mtime,
-	}
+func (mw *MetaWrapper) setattr(mp *MetaPartition, req *proto.SetAttrRequest) (status int, err error) {
+	req.VolName = mw.volname
+	req.PartitionID = mp.PartitionID
 
 	packet := proto.NewPacketReqID()
 	packet.Opcode = proto.OpMetaSetattr
@@ -1125,3 +1240,169 @@ func (mw *MetaWrapper) batchGetXAttr(mp *MetaPartition, inodes []uint64, keys []
 
 	return resp.XAttrs, nil
 }
+
+func (mw *MetaWrapper) setLock(mp *MetaPartition, inode uint64, lock *proto.FileLock) (status int, conflict *proto.FileLock, err error) {
+	req := &proto.SetLockRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Inode:       inode,
+		Lock:        *lock,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaSetLock
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("setLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("setLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status == statusExist {
+		resp := new(proto.GetLockResponse)
+		if err = packet.UnmarshalData(resp); err != nil {
+			log.LogErrorf("setLock: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+			return
+		}
+		conflict = resp.Lock
+		log.LogDebugf("setLock: packet(%v) mp(%v) req(%v) conflict(%v)", packet, mp, *req, conflict)
+		return
+	}
+	if status != statusOK {
+		log.LogErrorf("setLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("setLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
+
+func (mw *MetaWrapper) getLock(mp *MetaPartition, inode uint64, lock *proto.FileLock) (status int, conflict *proto.FileLock, err error) {
+	req := &proto.GetLockRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Inode:       inode,
+		Lock:        *lock,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaGetLock
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("getLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("getLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("getLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.GetLockResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("getLock: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	conflict = resp.Lock
+	log.LogDebugf("getLock: packet(%v) mp(%v) req(%v) conflict(%v)", packet, mp, *req, conflict)
+	return
+}
+
+func (mw *MetaWrapper) renewLock(mp *MetaPartition, session string) (status int, locks int, err error) {
+	req := &proto.RenewLockRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Session:     session,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaRenewLock
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("renewLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("renewLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("renewLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.RenewLockResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("renewLock: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	locks = resp.Locks
+	log.LogDebugf("renewLock: packet(%v) mp(%v) req(%v) locks(%v)", packet, mp, *req, locks)
+	return
+}
+
+func (mw *MetaWrapper) readChangelog(mp *MetaPartition, cursor uint64, limit int) (status int, resp *proto.ReadChangelogResponse, err error) {
+	req := &proto.ReadChangelogRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Cursor:      cursor,
+		Limit:       limit,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaReadChangelog
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("readChangelog: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("readChangelog: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("readChangelog: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.ReadChangelogResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("readChangelog: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	log.LogDebugf("readChangelog: packet(%v) mp(%v) req(%v) events(%v) next(%v)", packet, mp, *req, len(resp.Events), resp.Next)
+	return
+}
@@ -0,0 +1,225 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Tx is a metadata transaction, the operations of which are applied atomically even if they are in
+// different meta partitions. The partition of the first operation is the primary of the transaction,
+// which decides the outcome of it, and the other partitions, the participants, are prepared before it.
+// The operations are validated when the transaction is committed, and none of them is applied if any
+// fails. The meta nodes finish the transaction in case the client fails in the middle of it.
+//
+// A Tx is not safe for concurrent use, and it can only be committed once.
+type Tx struct {
+	mw         *MetaWrapper
+	id         string
+	primary    *MetaPartition
+	partitions map[uint64]*MetaPartition
+	items      map[uint64][]*proto.TxItem // partition id -> items
+	done       bool
+}
+
+// BeginTx starts a metadata transaction.
+func (mw *MetaWrapper) BeginTx() *Tx {
+	return &Tx{
+		mw:         mw,
+		id:         fmt.Sprintf("%v:%v", mw.locks.id, atomic.AddUint64(&mw.txSeq, 1)),
+		partitions: make(map[uint64]*MetaPartition),
+		items:      make(map[uint64][]*proto.TxItem),
+	}
+}
+
+// CreateDentry adds the creation of a dentry. The existing dentry of a regular file is replaced if
+// overwrite is set, and the replaced inode is filled in the item returned by Commit.
+func (tx *Tx) CreateDentry(parentID uint64, name string, inode uint64, mode uint32, overwrite bool) error {
+	return tx.add(parentID, &proto.TxItem{
+		Op:        proto.TxOpCreateDentry,
+		ParentID:  parentID,
+		Name:      name,
+		Inode:     inode,
+		Type:      mode,
+		Overwrite: overwrite && proto.IsRegular(mode),
+	})
+}
+
+// DeleteDentry adds the deletion of a dentry, which must point to the inode.
+func (tx *Tx) DeleteDentry(parentID uint64, name string, inode uint64) error {
+	return tx.add(parentID, &proto.TxItem{Op: proto.TxOpDeleteDentry, ParentID: parentID, Name: name, Inode: inode})
+}
+
+// Link adds the increment of the link count of an inode.
+func (tx *Tx) Link(inode uint64) error {
+	return tx.add(inode, &proto.TxItem{Op: proto.TxOpLinkInode, Inode: inode})
+}
+
+// Unlink adds the decrement of the link count of an inode.
+func (tx *Tx) Unlink(inode uint64) error {
+	return tx.add(inode, &proto.TxItem{Op: proto.TxOpUnlinkInode, Inode: inode})
+}
+
+// SetXAttr adds the setting of an extend attribute of an inode.
+func (tx *Tx) SetXAttr(inode uint64, name, value []byte) error {
+	return tx.add(inode, &proto.TxItem{Op: proto.TxOpSetXAttr, Inode: inode, Key: string(name), Value: string(value)})
+}
+
+// RemoveXAttr adds the removal of an extend attribute of an inode.
+func (tx *Tx) RemoveXAttr(inode uint64, name string) error {
+	return tx.add(inode, &proto.TxItem{Op: proto.TxOpRemoveXAttr, Inode: inode, Key: name})
+}
+
+// Commit applies the operations of the transaction atomically. It returns the items of the operations
+// as prepared by the meta nodes, in which the replaced inodes are filled.
+func (tx *Tx) Commit() ([]*proto.TxItem, error) {
+	if tx.done {
+		return nil, syscall.EINVAL
+	}
+	tx.done = true
+	if tx.primary == nil {
+		return nil, nil
+	}
+	return tx.mw.commitTx(tx)
+}
+
+// add adds the item to the partition which holds the given inode.
+func (tx *Tx) add(inode uint64, item *proto.TxItem) error {
+	if tx.done {
+		return syscall.EINVAL
+	}
+	mp := tx.mw.getPartitionByInode(inode)
+	if mp == nil {
+		return syscall.ENOENT
+	}
+	if tx.primary == nil {
+		tx.primary = mp
+	}
+	tx.partitions[mp.PartitionID] = mp
+	tx.items[mp.PartitionID] = append(tx.items[mp.PartitionID], item)
+	return nil
+}
+
+func (tx *Tx) participants() []uint64 {
+	pids := make([]uint64, 0, len(tx.partitions))
+	for pid := range tx.partitions {
+		if pid != tx.primary.PartitionID {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	return pids
+}
+
+func (tx *Tx) String() string {
+	return fmt.Sprintf("tx(%v) primary(%v) participants(%v)", tx.id, tx.primary.PartitionID, tx.participants())
+}
+
+// commitTx prepares the participants and then the primary, commits the primary and then the participants.
+// It returns the prepared items, in which the replaced inodes are filled. The transaction is aborted if
+// any partition fails to prepare, and proto.ErrUnknownOpcode is returned if any meta node does not
+// support the transactions.
+func (mw *MetaWrapper) commitTx(tx *Tx) (items []*proto.TxItem, err error) {
+	participants := tx.participants()
+	for _, pid := range participants {
+		var prepared []*proto.TxItem
+		if prepared, err = mw.prepareTx(tx, pid, nil); err != nil {
+			mw.abortTx(tx)
+			return
+		}
+		items = append(items, prepared...)
+	}
+	prepared, err := mw.prepareTx(tx, tx.primary.PartitionID, participants)
+	if err != nil {
+		mw.abortTx(tx)
+		return
+	}
+	items = append(items, prepared...)
+
+	// the transaction is committed once the primary is committed
+	req := &proto.TxRequest{TxID: tx.id, PrimaryID: tx.primary.PartitionID, Participants: participants}
+	status, resp, err := mw.txRequest(tx.primary, proto.OpMetaTxCommit, req)
+	if err != nil || status != statusOK {
+		log.LogWarnf("commitTx: commit primary failed, %v status(%v) err(%v)", tx, status, err)
+		return nil, syscall.EAGAIN
+	}
+	if resp.State != proto.TxStateCommitted {
+		log.LogWarnf("commitTx: %v is already finished as state(%v)", tx, resp.State)
+		mw.abortTx(tx)
+		return nil, syscall.EAGAIN
+	}
+
+	// the meta nodes finish the transaction if any participant fails to commit
+	for _, pid := range participants {
+		req = &proto.TxRequest{TxID: tx.id, PrimaryID: tx.primary.PartitionID}
+		if status, _, err = mw.txRequest(tx.partitions[pid], proto.OpMetaTxCommit, req); err != nil || status != statusOK {
+			log.LogWarnf("commitTx: commit participant(%v) failed, %v status(%v) err(%v)", pid, tx, status, err)
+			return items, nil
+		}
+	}
+	req = &proto.TxRequest{TxID: tx.id, PrimaryID: tx.primary.PartitionID}
+	if status, _, err = mw.txRequest(tx.primary, proto.OpMetaTxForget, req); err != nil || status != statusOK {
+		log.LogWarnf("commitTx: forget failed, %v status(%v) err(%v)", tx, status, err)
+	}
+	return items, nil
+}
+
+func (mw *MetaWrapper) prepareTx(tx *Tx, pid uint64, participants []uint64) (items []*proto.TxItem, err error) {
+	req := &proto.TxPrepareRequest{
+		TxID:         tx.id,
+		PrimaryID:    tx.primary.PartitionID,
+		Participants: participants,
+		Items:        tx.items[pid],
+	}
+	status, resp, err := mw.txPrepare(tx.partitions[pid], req)
+	if err == proto.ErrUnknownOpcode {
+		return nil, err
+	}
+	if err != nil {
+		return nil, syscall.EAGAIN
+	}
+	if status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	if resp.State != proto.TxStatePrepared {
+		log.LogWarnf("prepareTx: %v is already finished as state(%v) in partition(%v)", tx, resp.State, pid)
+		return nil, syscall.EAGAIN
+	}
+	return resp.Items, nil
+}
+
+// abortTx aborts the primary and then the participants. The primary records the transaction which
+// is unknown to it as aborted, so that the participants which fail to abort are aborted by the meta nodes.
+// The primary which does not support the transactions never commits it, so the participants are aborted.
+func (mw *MetaWrapper) abortTx(tx *Tx) {
+	participants := tx.participants()
+	req := &proto.TxRequest{TxID: tx.id, PrimaryID: tx.primary.PartitionID, Participants: participants}
+	if status, _, err := mw.txRequest(tx.primary, proto.OpMetaTxAbort, req); err != proto.ErrUnknownOpcode && (err != nil || status != statusOK) {
+		log.LogWarnf("abortTx: abort primary failed, %v status(%v) err(%v)", tx, status, err)
+		return
+	}
+	for _, pid := range participants {
+		req = &proto.TxRequest{TxID: tx.id, PrimaryID: tx.primary.PartitionID}
+		if status, _, err := mw.txRequest(tx.partitions[pid], proto.OpMetaTxAbort, req); err != nil || status != statusOK {
+			log.LogWarnf("abortTx: abort participant(%v) failed, %v status(%v) err(%v)", pid, tx, status, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The soft-deleted files of a volume are kept in the trash directory under the root, which is
+// only accessible to root. Each file is renamed to "<delete time>_<inode>" in the trash, and the
+// directory and name it was deleted from are recorded in the extended attributes of the inode,
+// so that it can be restored before it is purged after the trash days of the volume. The expired
+// files are purged by the meta node holding the root directory.
+
+const (
+	trashDirMode = os.ModeDir | 0700
+)
+
+var trashXAttrKeys = []string{proto.XAttrKeyTrashTime, proto.XAttrKeyTrashParent, proto.XAttrKeyTrashName}
+
+// TrashEntry is a soft-deleted file in the trash.
+type TrashEntry struct {
+	Name       string // name in the trash directory
+	Inode      uint64
+	Mode       uint32
+	Size       uint64
+	ParentID   uint64 // the directory which the file was deleted from
+	OrigName   string // the name which the file was deleted with
+	DeleteTime time.Time
+}
+
+// TrashDays returns the days that the deleted files are kept in the trash, 0 means the trash is disabled.
+func (mw *MetaWrapper) TrashDays() uint32 {
+	return atomic.LoadUint32(&mw.trashDays)
+}
+
+// lookupTrash returns the inode of the trash directory, which is created if required. The trash
+// created by the older clients is accessible to everyone, its mode is fixed once by each client.
+func (mw *MetaWrapper) lookupTrash(create bool) (inode uint64, err error) {
+	var mode uint32
+	inode, mode, err = mw.Lookup_ll(proto.RootIno, proto.TrashDirName)
+	if err == syscall.ENOENT && create {
+		var info *proto.InodeInfo
+		info, err = mw.Create_ll(proto.RootIno, proto.TrashDirName, proto.Mode(trashDirMode), 0, 0, nil)
+		if err == nil {
+			atomic.StoreUint64(&mw.trashIno, info.Inode)
+			return info.Inode, nil
+		}
+		if err != syscall.EEXIST {
+			return
+		}
+		// created by another client at the same time
+		inode, mode, err = mw.Lookup_ll(proto.RootIno, proto.TrashDirName)
+	}
+	if err != nil {
+		return
+	}
+	if !proto.IsDir(mode) {
+		log.LogErrorf("lookupTrash: trash is not a directory: volume(%v) inode(%v) mode(%v)", mw.volname, inode, mode)
+		return 0, syscall.ENOTDIR
+	}
+	if create && atomic.LoadUint64(&mw.trashIno) != inode {
+		if err = mw.restrictTrash(inode); err != nil {
+			return
+		}
+		atomic.StoreUint64(&mw.trashIno, inode)
+	}
+	return
+}
+
+// restrictTrash makes the trash directory only accessible to root.
+func (mw *MetaWrapper) restrictTrash(inode uint64) (err error) {
+	var info *proto.InodeInfo
+	if info, err = mw.InodeGet_ll(inode); err != nil {
+		return
+	}
+	if info.Mode == proto.Mode(trashDirMode) && info.Uid == 0 && info.Gid == 0 {
+		return
+	}
+	log.LogWarnf("lookupTrash: restrict trash: volume(%v) inode(%v) mode(%v) uid(%v) gid(%v)",
+		mw.volname, inode, proto.OsMode(info.Mode), info.Uid, info.Gid)
+	return mw.Setattr(inode, proto.AttrMode|proto.AttrUid|proto.AttrGid, proto.Mode(trashDirMode), 0, 0, 0, 0)
+}
+
+// moveToTrash moves the file into the trash instead of deleting it. The inode stays linked by
+// the trash, so its data is not released until the file is purged. The files deleted from
+// the trash itself are not moved, neither are the dentries whose inodes are missing, which are
+// deleted as usual.
+func (mw *MetaWrapper) moveToTrash(parentID uint64, name string) (info *proto.InodeInfo, moved bool, err error) {
+	var trashIno uint64
+	if trashIno, err = mw.lookupTrash(true); err != nil {
+		log.LogErrorf("moveToTrash: lookup trash fail: volume(%v) err(%v)", mw.volname, err)
+		return
+	}
+	if parentID == trashIno {
+		return nil, false, nil
+	}
+	var inode uint64
+	if inode, _, err = mw.Lookup_ll(parentID, name); err != nil {
+		if err == syscall.ENOENT {
+			return nil, false, nil
+		}
+		return
+	}
+	if _, err = mw.InodeGet_ll(inode); err != nil {
+		if err == syscall.ENOENT {
+			log.LogWarnf("moveToTrash: inode missing, delete the dentry: volume(%v) parentID(%v) name(%v) inode(%v)",
+				mw.volname, parentID, name, inode)
+			return nil, false, nil
+		}
+		return
+	}
+	var now = time.Now()
+	var attrs = map[string]string{
+		proto.XAttrKeyTrashParent: strconv.FormatUint(parentID, 10),
+		proto.XAttrKeyTrashName:   name,
+		proto.XAttrKeyTrashTime:   strconv.FormatInt(now.Unix(), 10),
+	}
+	for key, value := range attrs {
+		if err = mw.XAttrSet_ll(inode, []byte(key), []byte(value)); err != nil {
+			mw.removeTrashXAttrs(inode)
+			return
+		}
+	}
+	if err = mw.Rename_ll(parentID, name, trashIno, proto.TrashEntryName(inode, now)); err != nil {
+		log.LogErrorf("moveToTrash: move to trash fail: volume(%v) parentID(%v) name(%v) inode(%v) err(%v)",
+			mw.volname, parentID, name, inode, err)
+		mw.removeTrashXAttrs(inode)
+		return
+	}
+	log.LogDebugf("moveToTrash: volume(%v) parentID(%v) name(%v) inode(%v)", mw.volname, parentID, name, inode)
+	if info, err = mw.InodeGet_ll(inode); err != nil {
+		return nil, true, nil
+	}
+	return info, true, nil
+}
+
+// ListTrash returns the soft-deleted files in the trash, the earliest deleted file first.
+func (mw *MetaWrapper) ListTrash() (entries []*TrashEntry, err error) {
+	var trashIno uint64
+	if trashIno, err = mw.lookupTrash(false); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	var dentries []proto.Dentry
+	if dentries, err = mw.ReadDir_ll(trashIno); err != nil {
+		return
+	}
+	var inodes = make([]uint64, 0, len(dentries))
+	var byInode = make(map[uint64]*TrashEntry)
+	for _, dentry := range dentries {
+		if _, deleteTime, ok := proto.ParseTrashEntryName(dentry.Name); ok {
+			entry := &TrashEntry{Name: dentry.Name, Inode: dentry.Inode, Mode: dentry.Type, DeleteTime: deleteTime}
+			entries = append(entries, entry)
+			inodes = append(inodes, dentry.Inode)
+			byInode[dentry.Inode] = entry
+		}
+	}
+	for _, info := range mw.BatchInodeGet(inodes) {
+		if entry, ok := byInode[info.Inode]; ok {
+			entry.Size = info.Size
+		}
+	}
+	var xattrs []*proto.XAttrInfo
+	if xattrs, err = mw.BatchGetXAttr(inodes, []string{proto.XAttrKeyTrashParent, proto.XAttrKeyTrashName}); err != nil {
+		return
+	}
+	for _, xattr := range xattrs {
+		if entry, ok := byInode[xattr.Inode]; ok {
+			entry.ParentID, _ = strconv.ParseUint(string(xattr.Get(proto.XAttrKeyTrashParent)), 10, 64)
+			entry.OrigName = string(xattr.Get(proto.XAttrKeyTrashName))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].DeleteTime.Before(entries[j].DeleteTime)
+	})
+	return
+}
+
+// RestoreTrash moves the file in the trash back to the directory and the name it was deleted with.
+// It fails with EEXIST if the name has been used again, and with ENOENT if the directory has been deleted.
+func (mw *MetaWrapper) RestoreTrash(name string) (err error) {
+	var trashIno, inode uint64
+	if trashIno, err = mw.lookupTrash(false); err != nil {
+		return
+	}
+	if inode, _, err = mw.Lookup_ll(trashIno, name); err != nil {
+		return
+	}
+	var parent, origName *proto.XAttrInfo
+	if parent, err = mw.XAttrGet_ll(inode, proto.XAttrKeyTrashParent); err != nil {
+		return
+	}
+	if origName, err = mw.XAttrGet_ll(inode, proto.XAttrKeyTrashName); err != nil {
+		return
+	}
+	var parentID uint64
+	if parentID, err = strconv.ParseUint(string(parent.Get(proto.XAttrKeyTrashParent)), 10, 64); err != nil {
+		return syscall.EINVAL
+	}
+	var dstName = string(origName.Get(proto.XAttrKeyTrashName))
+	var parentInfo *proto.InodeInfo
+	if parentInfo, err = mw.InodeGet_ll(parentID); err != nil {
+		return
+	}
+	if !proto.IsDir(parentInfo.Mode) {
+		return syscall.ENOTDIR
+	}
+	if _, _, err = mw.Lookup_ll(parentID, dstName); err == nil {
+		return syscall.EEXIST
+	} else if err != syscall.ENOENT {
+		return
+	}
+	if err = mw.Rename_ll(trashIno, name, parentID, dstName); err != nil {
+		return
+	}
+	mw.removeTrashXAttrs(inode)
+	log.LogInfof("RestoreTrash: volume(%v) name(%v) inode(%v) restored to parentID(%v) name(%v)",
+		mw.volname, name, inode, parentID, dstName)
+	return
+}
+
+// removeTrashXAttrs removes the attributes recording where and when the file was deleted.
+func (mw *MetaWrapper) removeTrashXAttrs(inode uint64) {
+	for _, key := range trashXAttrKeys {
+		if err := mw.XAttrDel_ll(inode, key); err != nil {
+			log.LogWarnf("removeTrashXAttrs: remove xattr fail: volume(%v) inode(%v) key(%v) err(%v)",
+				mw.volname, inode, key, err)
+		}
+	}
+}
+
+// PurgeTrash deletes the files which have been kept in the trash for the trash days of the volume,
+// which is run by the operators, the expired files are purged by the meta node anyway. With all set,
+// every file in the trash is deleted regardless of the delete time.
+func (mw *MetaWrapper) PurgeTrash(all bool) (purged int, err error) {
+	var trashIno uint64
+	if trashIno, err = mw.lookupTrash(false); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	var dentries []proto.Dentry
+	if dentries, err = mw.ReadDir_ll(trashIno); err != nil {
+		return
+	}
+	var expiration = time.Now().Add(-time.Duration(mw.TrashDays()) * 24 * time.Hour)
+	for _, dentry := range dentries {
+		_, deleteTime, ok := proto.ParseTrashEntryName(dentry.Name)
+		if !ok || (!all && deleteTime.After(expiration)) {
+			continue
+		}
+		var info *proto.InodeInfo
+		if info, err = mw.delete(trashIno, dentry.Name, false); err != nil {
+			log.LogErrorf("PurgeTrash: delete fail: volume(%v) name(%v) err(%v)", mw.volname, dentry.Name, err)
+			return
+		}
+		if info != nil && info.Nlink == 0 {
+			_ = mw.Evict(info.Inode)
+		}
+		purged++
+	}
+	return
+}
@@ -20,7 +20,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -30,7 +29,6 @@ import (
 	"github.com/chubaofs/chubaofs/util/cryptoutil"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
-	"github.com/jacobsa/daemonize"
 )
 
 const (
@@ -136,6 +134,12 @@ func (mw *MetaWrapper) updateVolStatInfo() (err error) {
 	}
 	atomic.StoreUint64(&mw.totalSize, info.TotalSize)
 	atomic.StoreUint64(&mw.usedSize, info.UsedSize)
+	atomic.StoreUint32(&mw.trashDays, info.TrashDays)
+	if info.Atime {
+		atomic.StoreUint32(&mw.atime, 1)
+	} else {
+		atomic.StoreUint32(&mw.atime, 0)
+	}
 	log.LogInfof("VolStatInfo: info(%v)", info)
 	return
 }
@@ -144,24 +148,16 @@ func (mw *MetaWrapper) updateMetaPartitions() error {
 	view, err := mw.fetchVolumeView()
 	if err != nil {
 		log.LogInfof("error: %v", err.Error())
-		switch err {
-		case proto.ErrExpiredTicket:
-			// TODO: bad logic, remove later (Mofei Zhang)
+		// The SDK never terminates the process by itself. An invalid ticket is reported to
+		// the caller through the error, and the refresh task passes it to OnAsyncTaskError.
+		if err == proto.ErrExpiredTicket {
 			if e := mw.updateTicket(); e != nil {
-				log.LogFlush()
-				daemonize.SignalOutcome(err)
-				os.Exit(1)
+				log.LogWarnf("updateTicket: update expired ticket fail: volume(%v) err(%v)", mw.volname, e)
+				return err
 			}
 			log.LogInfof("updateTicket: ok!")
-			return err
-		case proto.ErrInvalidTicket:
-			// TODO: bad logic, remove later (Mofei Zhang)
-			log.LogFlush()
-			daemonize.SignalOutcome(err)
-			os.Exit(1)
-		default:
-			return err
 		}
+		return err
 	}
 
 	rwPartitions := make([]*MetaPartition, 0)
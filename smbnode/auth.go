@@ -0,0 +1,142 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"sync"
+	"time"
+
+	authSDK "github.com/chubaofs/chubaofs/sdk/auth"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const userCacheTTL = time.Minute
+
+// userStore provides the NT hashes of the users to verify their NTLM responses.
+type userStore interface {
+	ntHash(user string) ([]byte, error)
+}
+
+type cachedUser struct {
+	hash    []byte
+	expires time.Time
+}
+
+// authnodeUserStore takes the secret keys of the users registered on the authnode as their passwords,
+// so that the users log on the shares with the same keys they access the object storage with.
+type authnodeUserStore struct {
+	api       *authSDK.API
+	clientID  string
+	clientKey string
+	cache     map[string]*cachedUser
+	mu        sync.Mutex
+}
+
+func newAuthnodeUserStore(authNodes []string, enableHTTPS bool, certFile, clientID, clientKey string) *authnodeUserStore {
+	return &authnodeUserStore{
+		api:       authSDK.NewAuthClient(authNodes, enableHTTPS, certFile).API(),
+		clientID:  clientID,
+		clientKey: clientKey,
+		cache:     make(map[string]*cachedUser),
+	}
+}
+
+func (s *authnodeUserStore) ntHash(user string) ([]byte, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[user]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.hash, nil
+	}
+	info, err := s.api.AdminGetKey(s.clientID, s.clientKey, user)
+	if err != nil {
+		log.LogWarnf("ntHash: get key of user(%v) from authnode err(%v)", user, err)
+		return nil, err
+	}
+	hash := ntHash(info.SecretKey)
+	s.mu.Lock()
+	s.cache[user] = &cachedUser{hash: hash, expires: time.Now().Add(userCacheTTL)}
+	s.mu.Unlock()
+	return hash, nil
+}
+
+// sessionAuth runs the authentication of a session setup, either in raw NTLMSSP or wrapped in SPNEGO.
+// Kerberos is not offered, the clients preferring it fall back to NTLM through SPNEGO.
+type sessionAuth struct {
+	ntlm      *ntlmServer
+	spnego    bool
+	mechTypes []byte // the mechanism list of the client protected by the mechListMIC
+}
+
+func newSessionAuth(domain, computer string, users userStore) *sessionAuth {
+	return &sessionAuth{ntlm: newNTLMServer(domain, computer, users)}
+}
+
+// step processes a security token of the client and returns the token to reply,
+// done is true once the user has been authenticated.
+func (a *sessionAuth) step(token []byte) (reply []byte, done bool, err error) {
+	switch {
+	case isNTLMMessage(token) && !a.spnego:
+		return a.ntlmStep(token)
+	case len(token) > 0 && token[0] == tagApplication:
+		var mechToken []byte
+		if a.mechTypes, mechToken, err = parseNegTokenInit(token); err != nil {
+			return
+		}
+		if !hasMechanism(a.mechTypes, oidNTLMSSP) {
+			return nil, false, errNoMechanism
+		}
+		a.spnego = true
+		if !isNTLMMessage(mechToken) {
+			// the optimistic token is of another mechanism, ask the client to start over with NTLMSSP
+			return negTokenResp(negAcceptIncomplete, oidNTLMSSP, nil, nil), false, nil
+		}
+		if reply, _, err = a.ntlmStep(mechToken); err != nil {
+			return
+		}
+		return negTokenResp(negAcceptIncomplete, oidNTLMSSP, reply, nil), false, nil
+	case len(token) > 0 && token[0] == tagNegTokResp && a.spnego:
+		var responseToken, mechListMIC []byte
+		if responseToken, mechListMIC, err = parseNegTokenResp(token); err != nil {
+			return
+		}
+		if reply, done, err = a.ntlmStep(responseToken); err != nil {
+			return
+		}
+		if !done {
+			return negTokenResp(negAcceptIncomplete, oidNTLMSSP, reply, nil), false, nil
+		}
+		var serverMIC []byte
+		if len(mechListMIC) > 0 {
+			if !a.ntlm.verifyMIC(a.mechTypes, mechListMIC) {
+				return nil, false, errNTLMLogonFailure
+			}
+			serverMIC = a.ntlm.mic(a.mechTypes)
+		}
+		return negTokenResp(negAcceptCompleted, nil, nil, serverMIC), true, nil
+	}
+	return nil, false, errInvalidSPNEGO
+}
+
+func (a *sessionAuth) ntlmStep(token []byte) (reply []byte, done bool, err error) {
+	if a.ntlm.challMsg == nil {
+		reply, err = a.ntlm.challengeMessage(token)
+		return
+	}
+	if err = a.ntlm.authenticate(token); err != nil {
+		return
+	}
+	return nil, true, nil
+}
@@ -0,0 +1,362 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var errInvalidMessage = errors.New("invalid SMB message")
+
+// smbConn is a connection of a client, whose requests are processed in order.
+type smbConn struct {
+	node     *SmbNode
+	conn     net.Conn
+	dialect  uint16
+	sessions map[uint64]*smbSession
+	lastID   uint64 // the last ID allocated to a session, tree or open
+}
+
+type smbSession struct {
+	id           uint64
+	auth         *sessionAuth
+	user         string
+	valid        bool
+	signingKey   []byte
+	signRequired bool
+	trees        map[uint32]*smbTree
+}
+
+type smbTree struct {
+	id    uint32
+	share *smbShare // nil for IPC$
+	vol   *smbVolume
+	opens map[uint64]*smbOpen
+}
+
+type smbRequest struct {
+	msg       []byte // the message from the SMB2 header to the next message of the compound
+	body      []byte
+	command   uint16
+	flags     uint32
+	messageID uint64
+	treeID    uint32
+	sessionID uint64
+	credits   uint16
+	session   *smbSession
+	tree      *smbTree
+	chain     *compoundState
+}
+
+// compoundState passes the handles of a request to the related requests following it in a compound.
+type compoundState struct {
+	sessionID uint64
+	treeID    uint32
+	fileID    []byte
+	status    ntStatus
+}
+
+type smbResponse struct {
+	msg    []byte
+	signer *smbSession
+}
+
+func newSMBConn(node *SmbNode, conn net.Conn) *smbConn {
+	return &smbConn{
+		node:     node,
+		conn:     conn,
+		sessions: make(map[uint64]*smbSession),
+	}
+}
+
+func (c *smbConn) nextID() uint64 {
+	c.lastID++
+	return c.lastID
+}
+
+// readFrame reads a message framed by the 4-byte header of the direct TCP transport.
+func readFrame(r io.Reader) (frame []byte, err error) {
+	var hdr [4]byte
+	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+		return
+	}
+	length := int(hdr[1])<<16 | int(hdr[2])<<8 | int(hdr[3])
+	if hdr[0] != 0 || length > maxFrameSize {
+		return nil, errInvalidMessage
+	}
+	frame = make([]byte, length)
+	_, err = io.ReadFull(r, frame)
+	return
+}
+
+func writeFrame(w io.Writer, frame []byte) (err error) {
+	msg := make([]byte, 4+len(frame))
+	msg[1], msg[2], msg[3] = byte(len(frame)>>16), byte(len(frame)>>8), byte(len(frame))
+	copy(msg[4:], frame)
+	_, err = w.Write(msg)
+	return
+}
+
+func (c *smbConn) serve() {
+	defer c.close()
+	for {
+		frame, err := readFrame(c.conn)
+		if err != nil {
+			if err != io.EOF {
+				log.LogDebugf("serve: read from client(%v) err(%v)", c.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		var reply []byte
+		switch {
+		case len(frame) >= smb1HeaderSize && string(frame[:4]) == smb1ProtocolID:
+			reply, err = c.negotiateSMB1(frame)
+		case len(frame) >= smb2HeaderSize && string(frame[:4]) == smb2ProtocolID:
+			reply, err = c.process(frame)
+		default:
+			err = errInvalidMessage
+		}
+		if err == nil && len(reply) > 0 {
+			err = writeFrame(c.conn, reply)
+		}
+		if err != nil {
+			log.LogWarnf("serve: client(%v) err(%v)", c.conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+func (c *smbConn) close() {
+	for _, sess := range c.sessions {
+		c.logoffSession(sess)
+	}
+	c.conn.Close()
+}
+
+// process processes the requests of a message, which may be a compound of several requests.
+func (c *smbConn) process(frame []byte) (reply []byte, err error) {
+	var (
+		chain     compoundState
+		responses []*smbResponse
+	)
+	for off := 0; ; {
+		msg := frame[off:]
+		if len(msg) < smb2HeaderSize || string(msg[:4]) != smb2ProtocolID || le.Uint16(msg[4:]) != smb2HeaderSize {
+			return nil, errInvalidMessage
+		}
+		next := int(le.Uint32(msg[20:]))
+		if next != 0 {
+			if next < smb2HeaderSize || next > len(msg) || next%8 != 0 {
+				return nil, errInvalidMessage
+			}
+			msg = msg[:next]
+		}
+		if resp := c.handle(msg, &chain); resp != nil {
+			responses = append(responses, resp)
+		}
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+	for i, resp := range responses {
+		msg := resp.msg
+		if i < len(responses)-1 {
+			msg = append(msg, make([]byte, align8(len(msg))-len(msg))...)
+			le.PutUint32(msg[20:], uint32(len(msg)))
+		}
+		if resp.signer != nil {
+			sign(msg, resp.signer.signingKey)
+		}
+		reply = append(reply, msg...)
+	}
+	return
+}
+
+func (c *smbConn) handle(msg []byte, chain *compoundState) *smbResponse {
+	req := &smbRequest{
+		msg:       msg,
+		body:      msg[smb2HeaderSize:],
+		command:   le.Uint16(msg[12:]),
+		credits:   le.Uint16(msg[14:]),
+		flags:     le.Uint32(msg[16:]),
+		messageID: le.Uint64(msg[24:]),
+		treeID:    le.Uint32(msg[36:]),
+		sessionID: le.Uint64(msg[40:]),
+		chain:     chain,
+	}
+	if req.command == smb2Cancel {
+		// nothing is pending to be cancelled
+		return nil
+	}
+
+	var (
+		status ntStatus
+		body   []byte
+	)
+	if req.flags&flagRelatedOperations != 0 {
+		req.sessionID = chain.sessionID
+		req.treeID = chain.treeID
+		status = chain.status
+	} else {
+		chain.fileID = nil
+	}
+	if status == statusSuccess {
+		status, body = c.dispatch(req)
+	}
+	chain.sessionID = req.sessionID
+	chain.treeID = req.treeID
+	chain.status = status
+	if body == nil {
+		body = errorBody(nil)
+	}
+
+	resp := &smbResponse{msg: c.responseHeader(req, status)}
+	resp.msg = append(resp.msg, body...)
+	if sess := req.session; sess != nil && sess.signingKey != nil && status != statusMoreProcessingRequired &&
+		(sess.signRequired || req.flags&flagSigned != 0) {
+		resp.signer = sess
+	}
+	return resp
+}
+
+func (c *smbConn) responseHeader(req *smbRequest, status ntStatus) []byte {
+	credits := req.credits
+	if credits == 0 {
+		credits = 1
+	} else if credits > maxCredits {
+		credits = maxCredits
+	}
+	hdr := make([]byte, smb2HeaderSize)
+	copy(hdr, smb2ProtocolID)
+	le.PutUint16(hdr[4:], smb2HeaderSize)
+	copy(hdr[6:8], req.msg[6:8])
+	le.PutUint32(hdr[8:], uint32(status))
+	le.PutUint16(hdr[12:], req.command)
+	le.PutUint16(hdr[14:], credits)
+	le.PutUint32(hdr[16:], flagServerToRedir|req.flags&flagRelatedOperations)
+	le.PutUint64(hdr[24:], req.messageID)
+	copy(hdr[32:36], req.msg[32:36])
+	le.PutUint32(hdr[36:], req.treeID)
+	le.PutUint64(hdr[40:], req.sessionID)
+	return hdr
+}
+
+// errorBody returns the body of an error response carrying the error data.
+func errorBody(data []byte) []byte {
+	body := make([]byte, 8, 9+len(data))
+	le.PutUint16(body[0:], 9)
+	le.PutUint32(body[4:], uint32(len(data)))
+	if len(data) == 0 {
+		return append(body, 0)
+	}
+	return append(body, data...)
+}
+
+func (c *smbConn) dispatch(req *smbRequest) (ntStatus, []byte) {
+	switch req.command {
+	case smb2Negotiate:
+		return c.negotiate(req)
+	case smb2Echo:
+		// no session is required, but the response is signed in the session of the request
+		if sess := c.sessions[req.sessionID]; sess != nil && sess.valid && verifySignature(sess, req) {
+			req.session = sess
+		}
+		return c.echo(req)
+	}
+	if c.dialect == 0 || c.dialect == dialectWildcard {
+		return statusInvalidParameter, nil
+	}
+	if req.command == smb2SessionSetup {
+		return c.sessionSetup(req)
+	}
+
+	sess := c.sessions[req.sessionID]
+	if sess == nil || !sess.valid {
+		return statusUserSessionDeleted, nil
+	}
+	req.session = sess
+	if !verifySignature(sess, req) {
+		return statusAccessDenied, nil
+	}
+	switch req.command {
+	case smb2Logoff:
+		return c.logoff(req)
+	case smb2TreeConnect:
+		return c.treeConnect(req)
+	}
+
+	tree := sess.trees[req.treeID]
+	if tree == nil {
+		return statusNetworkNameDeleted, nil
+	}
+	req.tree = tree
+	switch req.command {
+	case smb2TreeDisconnect:
+		return c.treeDisconnect(req)
+	case smb2Create:
+		return c.create(req)
+	case smb2Close:
+		return c.closeFile(req)
+	case smb2Flush:
+		return c.flush(req)
+	case smb2Read:
+		return c.read(req)
+	case smb2Write:
+		return c.write(req)
+	case smb2Lock:
+		return c.lock(req)
+	case smb2Ioctl:
+		return c.ioctl(req)
+	case smb2QueryDirectory:
+		return c.queryDirectory(req)
+	case smb2QueryInfo:
+		return c.queryInfo(req)
+	case smb2SetInfo:
+		return c.setInfo(req)
+	}
+	return statusNotSupported, nil
+}
+
+func verifySignature(sess *smbSession, req *smbRequest) bool {
+	if req.flags&flagSigned == 0 {
+		return !sess.signRequired
+	}
+	if sess.signingKey == nil {
+		return false
+	}
+	msg := append([]byte(nil), req.msg...)
+	expected := signature(msg, sess.signingKey)
+	return hmac.Equal(expected, req.msg[48:64])
+}
+
+// signature computes the signature of the message with the dialects 2.0.2 and 2.1.
+func signature(msg, key []byte) []byte {
+	copy(msg[48:64], make([]byte, 16))
+	h := hmac.New(sha256.New, key)
+	h.Write(msg)
+	return h.Sum(nil)[:16]
+}
+
+func sign(msg, key []byte) {
+	le.PutUint32(msg[16:], le.Uint32(msg[16:])|flagSigned)
+	copy(msg[48:64], signature(msg, key))
+}
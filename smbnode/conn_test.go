@@ -0,0 +1,276 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type testUserStore map[string]string
+
+func (s testUserStore) ntHash(user string) ([]byte, error) {
+	password, ok := s[user]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	return ntHash(password), nil
+}
+
+type testClient struct {
+	t         *testing.T
+	conn      net.Conn
+	messageID uint64
+	sessionID uint64
+	treeID    uint32
+	key       []byte
+}
+
+func newTestClient(t *testing.T) *testClient {
+	node := &SmbNode{
+		domain:    "TEST",
+		computer:  "SERVER",
+		startTime: time.Now(),
+		users:     testUserStore{"alice": "secret"},
+		shares:    newShareManager(nil, map[string]*smbShare{}),
+	}
+	client, server := net.Pipe()
+	go newSMBConn(node, server).serve()
+	return &testClient{t: t, conn: client}
+}
+
+func (c *testClient) header(command uint16, flags uint32) []byte {
+	hdr := make([]byte, smb2HeaderSize)
+	copy(hdr, smb2ProtocolID)
+	le.PutUint16(hdr[4:], smb2HeaderSize)
+	le.PutUint16(hdr[12:], command)
+	le.PutUint16(hdr[14:], 1)
+	le.PutUint32(hdr[16:], flags)
+	le.PutUint64(hdr[24:], c.messageID)
+	le.PutUint32(hdr[36:], c.treeID)
+	le.PutUint64(hdr[40:], c.sessionID)
+	c.messageID++
+	return hdr
+}
+
+func (c *testClient) roundTrip(frame []byte) []byte {
+	if err := writeFrame(c.conn, frame); err != nil {
+		c.t.Fatal(err)
+	}
+	reply, err := readFrame(c.conn)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return reply
+}
+
+func (c *testClient) request(command uint16, body []byte, signed bool) (ntStatus, []byte) {
+	msg := append(c.header(command, 0), body...)
+	if signed {
+		sign(msg, c.key)
+	}
+	reply := c.roundTrip(msg)
+	if len(reply) < smb2HeaderSize || le.Uint16(reply[12:]) != command || le.Uint32(reply[16:])&flagServerToRedir == 0 {
+		c.t.Fatalf("invalid response %x", reply)
+	}
+	if signed {
+		expected := signature(append([]byte(nil), reply...), c.key)
+		if !bytes.Equal(reply[48:64], expected) {
+			c.t.Fatalf("invalid signature of command(%v)", command)
+		}
+	}
+	switch command {
+	case smb2SessionSetup:
+		c.sessionID = le.Uint64(reply[40:])
+	case smb2TreeConnect:
+		c.treeID = le.Uint32(reply[36:])
+	}
+	return ntStatus(le.Uint32(reply[8:])), reply[smb2HeaderSize:]
+}
+
+func (c *testClient) negotiate() {
+	body := make([]byte, 36)
+	le.PutUint16(body[0:], 36)
+	le.PutUint16(body[2:], 2)
+	le.PutUint16(body[4:], signingEnabled)
+	body = append(body, 0x02, 0x02, 0x10, 0x02)
+	status, resp := c.request(smb2Negotiate, body, false)
+	if status != statusSuccess || le.Uint16(resp[4:]) != dialect210 {
+		c.t.Fatalf("negotiate: status(%x) dialect(%x)", status, le.Uint16(resp[4:]))
+	}
+	token := resp[le.Uint16(resp[56:])-smb2HeaderSize:]
+	if mechTypes, _, err := parseNegTokenInit(token); err != nil || !hasMechanism(mechTypes, oidNTLMSSP) {
+		c.t.Fatalf("negotiate: invalid token %x", token)
+	}
+}
+
+func sessionSetupBody(token []byte) []byte {
+	body := make([]byte, 24, 24+len(token))
+	le.PutUint16(body[0:], 25)
+	body[3] = signingEnabled | signingRequired
+	le.PutUint16(body[12:], smb2HeaderSize+24)
+	le.PutUint16(body[14:], uint16(len(token)))
+	return append(body, token...)
+}
+
+// logon runs the NTLMv2 authentication wrapped in SPNEGO like Windows.
+func (c *testClient) logon(user, password string) ntStatus {
+	negotiate := make([]byte, 32)
+	copy(negotiate, ntlmSignature)
+	le.PutUint32(negotiate[8:], ntlmNegotiateMessage)
+	le.PutUint32(negotiate[12:], ntlmNegotiateUnicode|ntlmNegotiateNTLM|ntlmNegotiateSign|
+		ntlmNegotiateExtendedSessionSecurity|ntlmNegotiate128|ntlmNegotiateKeyExch|ntlmNegotiateAlwaysSign)
+	mechTypes := derEncode(tagSequence, derEncode(tagOID, []byte{0x2a, 0x86, 0x48, 0x86, 0xf7, 0x12, 0x01, 0x02, 0x02}),
+		derEncode(tagOID, oidNTLMSSP))
+	init := derEncode(tagApplication, derEncode(tagOID, oidSPNEGO), derEncode(tagNegTokInit, derEncode(tagSequence,
+		derEncode(0xa0, mechTypes), derEncode(0xa2, derEncode(tagOctetString, negotiate)))))
+	c.sessionID = 0
+	status, resp := c.request(smb2SessionSetup, sessionSetupBody(init), false)
+	if status != statusMoreProcessingRequired {
+		c.t.Fatalf("session setup: status(%x)", status)
+	}
+	challenge, _, err := parseNegTokenResp(resp[8:])
+	if err != nil || !isNTLMMessage(challenge) {
+		c.t.Fatalf("session setup: invalid challenge token %x", resp[8:])
+	}
+	info, _ := ntlmField(challenge, 40)
+
+	blob := append([]byte{1, 1, 0, 0, 0, 0, 0, 0}, make([]byte, 8)...)
+	blob = append(blob, bytes.Repeat([]byte{0xaa}, 8)...)
+	blob = append(blob, make([]byte, 4)...)
+	blob = append(append(blob, info...), make([]byte, 4)...)
+	ntowf := hmacMD5(ntHash(password), encodeUTF16(strings.ToUpper(user)+"TEST"))
+	proof := hmacMD5(ntowf, challenge[24:32], blob)
+	exportedKey := bytes.Repeat([]byte{0x55}, 16)
+	encryptedKey := make([]byte, 16)
+	cipher, _ := rc4.NewCipher(hmacMD5(ntowf, proof))
+	cipher.XORKeyStream(encryptedKey, exportedKey)
+
+	fields := [][]byte{nil, append(proof, blob...), encodeUTF16("TEST"), encodeUTF16(user), encodeUTF16("CLIENT"), encryptedKey}
+	authenticate := make([]byte, 64)
+	copy(authenticate, ntlmSignature)
+	le.PutUint32(authenticate[8:], ntlmAuthenticateMessage)
+	for i, field := range fields {
+		putNTLMField(authenticate, 12+8*i, len(field), len(authenticate))
+		authenticate = append(authenticate, field...)
+	}
+	le.PutUint32(authenticate[60:], le.Uint32(challenge[20:]))
+
+	clientSeal, _ := rc4.NewCipher(md5Sum(exportedKey, []byte(clientSealingMagic)))
+	mic := ntlmMAC(md5Sum(exportedKey, []byte(clientSigningMagic)), clientSeal, 0, mechTypes)
+	c.key = exportedKey
+	if status, resp = c.request(smb2SessionSetup, sessionSetupBody(negTokenResp(negAcceptIncomplete, nil, authenticate, mic)), false); status != statusSuccess {
+		return status
+	}
+	if _, serverMIC, err := parseNegTokenResp(resp[8:]); err != nil {
+		c.t.Fatalf("session setup: invalid final token err(%v)", err)
+	} else {
+		serverSeal, _ := rc4.NewCipher(md5Sum(exportedKey, []byte(serverSealingMagic)))
+		if !bytes.Equal(serverMIC, ntlmMAC(md5Sum(exportedKey, []byte(serverSigningMagic)), serverSeal, 0, mechTypes)) {
+			c.t.Fatalf("session setup: invalid mechListMIC %x", serverMIC)
+		}
+	}
+	return status
+}
+
+func (c *testClient) treeConnect(path string) ntStatus {
+	name := encodeUTF16(path)
+	body := make([]byte, 8, 8+len(name))
+	le.PutUint16(body[0:], 9)
+	le.PutUint16(body[4:], smb2HeaderSize+8)
+	le.PutUint16(body[6:], uint16(len(name)))
+	status, resp := c.request(smb2TreeConnect, append(body, name...), true)
+	if status == statusSuccess {
+		if resp[2] != shareTypePipe {
+			c.t.Fatalf("tree connect: share type(%v)", resp[2])
+		}
+	}
+	return status
+}
+
+func TestSessionSetup(t *testing.T) {
+	c := newTestClient(t)
+	defer c.conn.Close()
+	c.negotiate()
+	if status := c.logon("alice", "wrong"); status != statusLogonFailure {
+		t.Fatalf("logon with wrong password: status(%x)", status)
+	}
+	if status := c.logon("bob", "secret"); status != statusLogonFailure {
+		t.Fatalf("logon of unknown user: status(%x)", status)
+	}
+	if status := c.logon("alice", "secret"); status != statusSuccess {
+		t.Fatalf("logon: status(%x)", status)
+	}
+
+	// the signing is required by the client
+	if status, _ := c.request(smb2Echo, []byte{4, 0, 0, 0}, true); status != statusSuccess {
+		t.Fatalf("echo: status(%x)", status)
+	}
+	if status, _ := c.request(smb2TreeConnect, make([]byte, 8), false); status != statusAccessDenied {
+		t.Fatalf("unsigned request: status(%x)", status)
+	}
+	if status := c.treeConnect("\\\\SERVER\\nosuch"); status != statusBadNetworkName {
+		t.Fatalf("tree connect to unknown share: status(%x)", status)
+	}
+	if status := c.treeConnect("\\\\SERVER\\IPC$"); status != statusSuccess {
+		t.Fatalf("tree connect to IPC$: status(%x)", status)
+	}
+	create := make([]byte, 56)
+	le.PutUint16(create[0:], 57)
+	le.PutUint32(create[36:], fileOpen)
+	if status, _ := c.request(smb2Create, create, true); status != statusObjectNameNotFound {
+		t.Fatalf("create on IPC$: status(%x)", status)
+	}
+	if status, _ := c.request(smb2Logoff, []byte{4, 0, 0, 0}, true); status != statusSuccess {
+		t.Fatalf("logoff: status(%x)", status)
+	}
+	if status, _ := c.request(smb2TreeDisconnect, []byte{4, 0, 0, 0}, false); status != statusUserSessionDeleted {
+		t.Fatalf("request after logoff: status(%x)", status)
+	}
+}
+
+func TestCompound(t *testing.T) {
+	c := newTestClient(t)
+	defer c.conn.Close()
+
+	// the SMB1 negotiate is upgraded to SMB2 with the wildcard dialect
+	smb1 := make([]byte, smb1HeaderSize+3)
+	copy(smb1, smb1ProtocolID)
+	smb1[4] = smb1Negotiate
+	smb1 = append(smb1, "\x02NT LM 0.12\x00\x02SMB 2.002\x00\x02SMB 2.???\x00"...)
+	reply := c.roundTrip(smb1)
+	if string(reply[:4]) != smb2ProtocolID || le.Uint16(reply[smb2HeaderSize+4:]) != dialectWildcard {
+		t.Fatalf("smb1 negotiate: invalid response %x", reply[:smb2HeaderSize+8])
+	}
+	c.messageID = 1
+	c.negotiate()
+
+	first := append(c.header(smb2Echo, 0), 4, 0, 0, 0, 0, 0, 0, 0)
+	le.PutUint32(first[20:], uint32(len(first)))
+	second := append(c.header(smb2Echo, flagRelatedOperations), 4, 0, 0, 0)
+	reply = c.roundTrip(append(first, second...))
+	next := le.Uint32(reply[20:])
+	if next == 0 || next%8 != 0 || int(next)+smb2HeaderSize > len(reply) {
+		t.Fatalf("compound: invalid next command(%v) of reply length(%v)", next, len(reply))
+	}
+	if le.Uint64(reply[24:]) != 2 || le.Uint64(reply[next+24:]) != 3 || le.Uint32(reply[next+16:])&flagRelatedOperations == 0 {
+		t.Fatalf("compound: invalid responses %x", reply)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import "encoding/binary"
+
+var le = binary.LittleEndian
+
+const (
+	smb2HeaderSize = 64
+	smb2ProtocolID = "\xfeSMB"
+	smb1ProtocolID = "\xffSMB"
+	smb1HeaderSize = 32
+	smb1Negotiate  = 0x72
+
+	dialect202      = 0x0202
+	dialect210      = 0x0210
+	dialectWildcard = 0x02ff
+
+	// the gateway does not negotiate the large MTU, so the reads and writes are at most 64KB
+	maxTransactSize = 64 * 1024
+	maxReadSize     = 64 * 1024
+	maxWriteSize    = 64 * 1024
+	maxFrameSize    = 1 << 20
+	maxCredits      = 128
+)
+
+// SMB2 commands
+const (
+	smb2Negotiate uint16 = iota
+	smb2SessionSetup
+	smb2Logoff
+	smb2TreeConnect
+	smb2TreeDisconnect
+	smb2Create
+	smb2Close
+	smb2Flush
+	smb2Read
+	smb2Write
+	smb2Lock
+	smb2Ioctl
+	smb2Cancel
+	smb2Echo
+	smb2QueryDirectory
+	smb2ChangeNotify
+	smb2QueryInfo
+	smb2SetInfo
+	smb2OplockBreak
+)
+
+// SMB2 header flags
+const (
+	flagServerToRedir     = 0x00000001
+	flagAsyncCommand      = 0x00000002
+	flagRelatedOperations = 0x00000004
+	flagSigned            = 0x00000008
+)
+
+// security modes of negotiate and session setup
+const (
+	signingEnabled  = 0x01
+	signingRequired = 0x02
+)
+
+// NT status codes
+type ntStatus uint32
+
+const (
+	statusSuccess                ntStatus = 0x00000000
+	statusBufferOverflow         ntStatus = 0x80000005
+	statusNoMoreFiles            ntStatus = 0x80000006
+	statusNotImplemented         ntStatus = 0xC0000002
+	statusInvalidInfoClass       ntStatus = 0xC0000003
+	statusInfoLengthMismatch     ntStatus = 0xC0000004
+	statusInvalidParameter       ntStatus = 0xC000000D
+	statusNoSuchFile             ntStatus = 0xC000000F
+	statusInvalidDeviceRequest   ntStatus = 0xC0000010
+	statusEndOfFile              ntStatus = 0xC0000011
+	statusMoreProcessingRequired ntStatus = 0xC0000016
+	statusAccessDenied           ntStatus = 0xC0000022
+	statusBufferTooSmall         ntStatus = 0xC0000023
+	statusObjectNameInvalid      ntStatus = 0xC0000033
+	statusObjectNameNotFound     ntStatus = 0xC0000034
+	statusObjectNameCollision    ntStatus = 0xC0000035
+	statusObjectPathNotFound     ntStatus = 0xC000003A
+	statusFileLockConflict       ntStatus = 0xC0000054
+	statusLockNotGranted         ntStatus = 0xC0000055
+	statusDeletePending          ntStatus = 0xC0000056
+	statusLogonFailure           ntStatus = 0xC000006D
+	statusRangeNotLocked         ntStatus = 0xC000007E
+	statusDiskFull               ntStatus = 0xC000007F
+	statusFileIsADirectory       ntStatus = 0xC00000BA
+	statusNotSupported           ntStatus = 0xC00000BB
+	statusNetworkNameDeleted     ntStatus = 0xC00000C9
+	statusBadNetworkName         ntStatus = 0xC00000CC
+	statusInternalError          ntStatus = 0xC00000E5
+	statusDirectoryNotEmpty      ntStatus = 0xC0000101
+	statusNotADirectory          ntStatus = 0xC0000103
+	statusFileClosed             ntStatus = 0xC0000128
+	statusFSDriverRequired       ntStatus = 0xC000019C
+	statusUserSessionDeleted     ntStatus = 0xC0000203
+	statusNotFound               ntStatus = 0xC0000225
+	statusInsufficientResources  ntStatus = 0xC000009A
+)
+
+// share types and flags of tree connect
+const (
+	shareTypeDisk = 0x01
+	shareTypePipe = 0x02
+
+	shareFlagNoCaching = 0x00000030
+)
+
+// access masks
+const (
+	fileReadData        = 0x00000001
+	fileWriteData       = 0x00000002
+	fileAppendData      = 0x00000004
+	fileReadEA          = 0x00000008
+	fileWriteEA         = 0x00000010
+	fileExecute         = 0x00000020
+	fileReadAttributes  = 0x00000080
+	fileWriteAttributes = 0x00000100
+	accessDelete        = 0x00010000
+	readControl         = 0x00020000
+	writeDAC            = 0x00040000
+	writeOwner          = 0x00080000
+	accessSynchronize   = 0x00100000
+	maximumAllowed      = 0x02000000
+	genericAll          = 0x10000000
+	genericExecute      = 0x20000000
+	genericWrite        = 0x40000000
+	genericRead         = 0x80000000
+
+	fileAllAccess  = 0x001F01FF
+	fileReadAccess = 0x001200A9
+
+	writeAccessMask = fileWriteData | fileAppendData | fileWriteEA | fileWriteAttributes | accessDelete |
+		writeDAC | writeOwner | genericAll | genericWrite
+)
+
+// create dispositions, options and actions
+const (
+	fileSupersede   = 0
+	fileOpen        = 1
+	fileCreate      = 2
+	fileOpenIf      = 3
+	fileOverwrite   = 4
+	fileOverwriteIf = 5
+
+	fileDirectoryFile    = 0x00000001
+	fileNonDirectoryFile = 0x00000040
+	fileDeleteOnClose    = 0x00001000
+
+	fileSuperseded  = 0
+	fileOpened      = 1
+	fileCreated     = 2
+	fileOverwritten = 3
+)
+
+// file attributes
+const (
+	fileAttributeReadonly  = 0x00000001
+	fileAttributeHidden    = 0x00000002
+	fileAttributeDirectory = 0x00000010
+	fileAttributeArchive   = 0x00000020
+)
+
+const (
+	closePostQueryAttrib = 0x0001
+
+	writeThrough = 0x00000001
+
+	lockShared          = 0x00000001
+	lockExclusive       = 0x00000002
+	lockUnlock          = 0x00000004
+	lockFailImmediately = 0x00000010
+
+	fsctlDFSGetReferrals   = 0x00060194
+	fsctlDFSGetReferralsEx = 0x000601B0
+)
+
+// query directory flags
+const (
+	restartScans      = 0x01
+	returnSingleEntry = 0x02
+	indexSpecified    = 0x04
+	reopen            = 0x10
+)
+
+// information types
+const (
+	infoFile       = 0x01
+	infoFilesystem = 0x02
+	infoSecurity   = 0x03
+	infoQuota      = 0x04
+)
+
+// file information classes
+const (
+	fileDirectoryInformation       = 1
+	fileFullDirectoryInformation   = 2
+	fileBothDirectoryInformation   = 3
+	fileBasicInformation           = 4
+	fileStandardInformation        = 5
+	fileInternalInformation        = 6
+	fileEaInformation              = 7
+	fileAccessInformation          = 8
+	fileRenameInformation          = 10
+	fileLinkInformation            = 11
+	fileNamesInformation           = 12
+	fileDispositionInformation     = 13
+	filePositionInformation        = 14
+	fileModeInformation            = 16
+	fileAlignmentInformation       = 17
+	fileAllInformation             = 18
+	fileAllocationInformation      = 19
+	fileEndOfFileInformation       = 20
+	fileStreamInformation          = 22
+	fileNetworkOpenInformation     = 34
+	fileAttributeTagInformation    = 35
+	fileIDBothDirectoryInformation = 37
+	fileIDFullDirectoryInformation = 38
+)
+
+// file system information classes
+const (
+	fileFsVolumeInformation     = 1
+	fileFsSizeInformation       = 3
+	fileFsDeviceInformation     = 4
+	fileFsAttributeInformation  = 5
+	fileFsFullSizeInformation   = 7
+	fileFsSectorSizeInformation = 11
+)
+
+// security information
+const (
+	ownerSecurityInformation = 0x00000001
+	groupSecurityInformation = 0x00000002
+	daclSecurityInformation  = 0x00000004
+)
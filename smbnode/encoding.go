@@ -0,0 +1,70 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"time"
+	"unicode/utf16"
+)
+
+// the number of 100 nanoseconds between 1601-01-01 and 1970-01-01
+const filetimeEpochDelta = 116444736000000000
+
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		le.PutUint16(b[2*i:], u)
+	}
+	return b
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = le.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func filetime(t time.Time) uint64 {
+	if t.IsZero() || t.Unix() < 0 {
+		return 0
+	}
+	return uint64(t.UnixNano()/100) + filetimeEpochDelta
+}
+
+func filetimeToUnix(ft uint64) int64 {
+	if ft < filetimeEpochDelta {
+		return 0
+	}
+	return int64((ft - filetimeEpochDelta) / 10000000)
+}
+
+func align8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// buffer returns the variable part of a message located by the offset from the beginning of the SMB2 header,
+// or nil if it is beyond the message.
+func buffer(msg []byte, offset, length int) []byte {
+	if length == 0 {
+		return []byte{}
+	}
+	if offset < smb2HeaderSize || offset+length > len(msg) {
+		return nil
+	}
+	return msg[offset : offset+length]
+}
@@ -0,0 +1,502 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// smbOpen is a file or directory opened by CREATE.
+type smbOpen struct {
+	id            uint64
+	tree          *smbTree
+	ino           uint64
+	parent        uint64 // zero for the root of the share
+	name          string
+	path          string // the path in the share, empty for the root
+	isDir         bool
+	access        uint32
+	deleteOnClose bool
+
+	// the state of QUERY_DIRECTORY
+	listing  []*dirEntry
+	pos      int
+	pattern  string
+	returned bool
+}
+
+func ntStatusOf(err error) ntStatus {
+	if err == nil {
+		return statusSuccess
+	}
+	errno, ok := err.(syscall.Errno)
+	if !ok {
+		return statusInternalError
+	}
+	switch errno {
+	case syscall.ENOENT:
+		return statusObjectNameNotFound
+	case syscall.EEXIST:
+		return statusObjectNameCollision
+	case syscall.ENOTEMPTY:
+		return statusDirectoryNotEmpty
+	case syscall.ENOTDIR:
+		return statusNotADirectory
+	case syscall.EISDIR:
+		return statusFileIsADirectory
+	case syscall.EPERM, syscall.EACCES, syscall.EROFS:
+		return statusAccessDenied
+	case syscall.ENOSPC:
+		return statusDiskFull
+	case syscall.EINVAL:
+		return statusInvalidParameter
+	case syscall.ENAMETOOLONG:
+		return statusObjectNameInvalid
+	case syscall.ENOMEM:
+		return statusInsufficientResources
+	}
+	return statusInternalError
+}
+
+// splitPath splits a path in the share into its components. The streams and the relative components
+// are not supported.
+func splitPath(path string) (components []string, status ntStatus) {
+	for _, name := range strings.Split(path, "\\") {
+		switch {
+		case name == "":
+			continue
+		case name == "." || name == "..", strings.ContainsAny(name, ":/*?\"<>|"), len(name) > 255:
+			return nil, statusObjectNameInvalid
+		}
+		components = append(components, name)
+	}
+	return components, statusSuccess
+}
+
+// lookup looks up the name in the directory, falling back to a case insensitive match like Windows.
+// It returns the name of the entry found.
+func (t *smbTree) lookup(parent uint64, name string) (ino uint64, found string, err error) {
+	if ino, _, err = t.vol.mw.Lookup_ll(parent, name); err != syscall.ENOENT {
+		return ino, name, err
+	}
+	dentries, err := t.vol.mw.ReadDir_ll(parent)
+	if err != nil {
+		return
+	}
+	for _, dentry := range dentries {
+		if strings.EqualFold(dentry.Name, name) {
+			return dentry.Inode, dentry.Name, nil
+		}
+	}
+	return 0, "", syscall.ENOENT
+}
+
+// resolve walks the components from the root of the volume, it returns the parent directory and the name
+// of the last component, with the inode of the last component or nil if it does not exist.
+func (t *smbTree) resolve(components []string) (parent uint64, name string, info *proto.InodeInfo, status ntStatus) {
+	ino := proto.RootIno
+	for i, component := range components {
+		parent = ino
+		var err error
+		if ino, name, err = t.lookup(parent, component); err != nil {
+			if err == syscall.ENOENT && i == len(components)-1 {
+				return parent, component, nil, statusSuccess
+			}
+			if err == syscall.ENOENT {
+				return 0, "", nil, statusObjectPathNotFound
+			}
+			return 0, "", nil, ntStatusOf(err)
+		}
+		if i < len(components)-1 {
+			if info, err = t.vol.mw.InodeGet_ll(ino); err != nil {
+				return 0, "", nil, ntStatusOf(err)
+			}
+			if !proto.IsDir(info.Mode) {
+				return 0, "", nil, statusObjectPathNotFound
+			}
+		}
+	}
+	var err error
+	if info, err = t.vol.mw.InodeGet_ll(ino); err != nil {
+		return 0, "", nil, ntStatusOf(err)
+	}
+	return
+}
+
+// fileOpen returns the open of the file ID at the offset of the request body, the ID of all ones
+// refers to the file of the previous request in a compound.
+func (c *smbConn) fileOpen(req *smbRequest, off int) (*smbOpen, ntStatus) {
+	if len(req.body) < off+16 {
+		return nil, statusInvalidParameter
+	}
+	fileID := req.body[off : off+16]
+	if req.flags&flagRelatedOperations != 0 && le.Uint64(fileID) == ^uint64(0) && le.Uint64(fileID[8:]) == ^uint64(0) {
+		if req.chain.fileID == nil {
+			return nil, statusInvalidParameter
+		}
+		fileID = req.chain.fileID
+	}
+	open := req.tree.opens[le.Uint64(fileID[8:])]
+	if open == nil || le.Uint64(fileID) != open.id {
+		return nil, statusFileClosed
+	}
+	req.chain.fileID = fileID
+	return open, statusSuccess
+}
+
+func (c *smbConn) create(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 56 {
+		return statusInvalidParameter, nil
+	}
+	tree := req.tree
+	if tree.share == nil {
+		// no named pipe is served on IPC$
+		return statusObjectNameNotFound, nil
+	}
+	access := le.Uint32(b[24:])
+	disposition := le.Uint32(b[36:])
+	options := le.Uint32(b[40:])
+	nameBuf := buffer(req.msg, int(le.Uint16(b[44:])), int(le.Uint16(b[46:])))
+	if nameBuf == nil || disposition > fileOverwriteIf {
+		return statusInvalidParameter, nil
+	}
+	components, status := splitPath(decodeUTF16(nameBuf))
+	if status != statusSuccess {
+		return status, nil
+	}
+	wantDir := options&fileDirectoryFile != 0
+	wantFile := options&fileNonDirectoryFile != 0
+	if wantDir && wantFile {
+		return statusInvalidParameter, nil
+	}
+	if tree.share.readOnly && (access&writeAccessMask != 0 || options&fileDeleteOnClose != 0 ||
+		(disposition != fileOpen && disposition != fileOpenIf)) {
+		return statusAccessDenied, nil
+	}
+
+	parent, name, info, status := tree.resolve(components)
+	if status != statusSuccess {
+		return status, nil
+	}
+	vol := tree.vol
+	action := uint32(fileOpened)
+	truncate := false
+	if info != nil {
+		isDir := proto.IsDir(info.Mode)
+		switch {
+		case disposition == fileCreate:
+			return statusObjectNameCollision, nil
+		case isDir && wantFile:
+			return statusFileIsADirectory, nil
+		case !isDir && wantDir:
+			return statusNotADirectory, nil
+		case !isDir && !proto.IsRegular(info.Mode):
+			// the symbolic links and special files are listed but can not be opened
+			return statusAccessDenied, nil
+		case vol.files.isDeletePending(info.Inode):
+			return statusDeletePending, nil
+		}
+		switch disposition {
+		case fileSupersede, fileOverwrite, fileOverwriteIf:
+			if isDir {
+				return statusInvalidParameter, nil
+			}
+			truncate = true
+			action = fileOverwritten
+			if disposition == fileSupersede {
+				action = fileSuperseded
+			}
+		}
+	} else {
+		if disposition == fileOpen || disposition == fileOverwrite {
+			return statusObjectNameNotFound, nil
+		}
+		mode := proto.Mode(0644)
+		if wantDir {
+			mode = proto.Mode(os.ModeDir | 0755)
+		}
+		var err error
+		if info, err = vol.mw.Create_ll(parent, name, mode, tree.share.uid, tree.share.gid, nil); err != nil {
+			return ntStatusOf(err), nil
+		}
+		action = fileCreated
+	}
+
+	open := &smbOpen{
+		id:     c.nextID(),
+		tree:   tree,
+		ino:    info.Inode,
+		parent: parent,
+		name:   name,
+		path:   strings.Join(components, "\\"),
+		isDir:  proto.IsDir(info.Mode),
+		access: access,
+	}
+	if options&fileDeleteOnClose != 0 {
+		if len(components) == 0 {
+			return statusAccessDenied, nil
+		}
+		open.deleteOnClose = true
+	}
+	if !open.isDir {
+		if err := vol.ec.OpenStream(open.ino); err != nil {
+			log.LogErrorf("create: vol(%v) ino(%v) open stream err(%v)", vol.name, open.ino, err)
+			return statusInternalError, nil
+		}
+		if truncate {
+			if err := vol.ec.Truncate(open.ino, 0); err != nil {
+				vol.ec.CloseStream(open.ino)
+				return ntStatusOf(err), nil
+			}
+			info.Size = 0
+		}
+	}
+	vol.files.open(open.ino)
+	tree.opens[open.id] = open
+
+	attr := newSMBAttr(info, vol, tree.share, open.name)
+	body := make([]byte, 88, 89)
+	le.PutUint16(body[0:], 89)
+	le.PutUint32(body[4:], action)
+	attr.putTimes(body[8:])
+	le.PutUint64(body[40:], attr.alloc)
+	le.PutUint64(body[48:], attr.size)
+	le.PutUint32(body[56:], attr.attributes)
+	le.PutUint64(body[64:], open.id)
+	le.PutUint64(body[72:], open.id)
+	req.chain.fileID = body[64:80]
+	return statusSuccess, append(body, 0)
+}
+
+func (c *smbConn) closeFile(req *smbRequest) (ntStatus, []byte) {
+	if len(req.body) < 24 {
+		return statusInvalidParameter, nil
+	}
+	open, status := c.fileOpen(req, 8)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if status = c.closeOpen(open); status != statusSuccess {
+		return status, nil
+	}
+	body := make([]byte, 60)
+	le.PutUint16(body[0:], 60)
+	if le.Uint16(req.body[2:])&closePostQueryAttrib != 0 {
+		if info, err := open.tree.vol.mw.InodeGet_ll(open.ino); err == nil {
+			le.PutUint16(body[2:], closePostQueryAttrib)
+			attr := newSMBAttr(info, open.tree.vol, open.tree.share, open.name)
+			attr.putTimes(body[8:])
+			le.PutUint64(body[40:], attr.alloc)
+			le.PutUint64(body[48:], attr.size)
+			le.PutUint32(body[56:], attr.attributes)
+		}
+	}
+	return statusSuccess, body
+}
+
+// closeOpen closes the open and deletes the file if it is the last open of a file pending deletion.
+func (c *smbConn) closeOpen(open *smbOpen) (status ntStatus) {
+	tree := open.tree
+	vol := tree.vol
+	delete(tree.opens, open.id)
+	if !open.isDir {
+		if err := vol.ec.CloseStream(open.ino); err != nil {
+			log.LogErrorf("closeOpen: vol(%v) ino(%v) close stream err(%v)", vol.name, open.ino, err)
+			status = ntStatusOf(err)
+		}
+	}
+	if open.deleteOnClose {
+		vol.files.setDeletePending(open.ino, true)
+	}
+	if !vol.files.close(open.ino, open.id) {
+		return
+	}
+	info, err := vol.mw.Delete_ll(open.parent, open.name, open.isDir)
+	if err != nil {
+		log.LogWarnf("closeOpen: vol(%v) delete parent(%v) name(%v) err(%v)", vol.name, open.parent, open.name, err)
+		return
+	}
+	if info != nil && info.Nlink == 0 && !open.isDir {
+		if err = vol.mw.Evict(info.Inode); err != nil {
+			log.LogWarnf("closeOpen: vol(%v) evict ino(%v) err(%v)", vol.name, info.Inode, err)
+		}
+	}
+	return
+}
+
+func (c *smbConn) flush(req *smbRequest) (ntStatus, []byte) {
+	open, status := c.fileOpen(req, 8)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if !open.isDir {
+		if err := open.tree.vol.ec.Flush(open.ino); err != nil {
+			return ntStatusOf(err), nil
+		}
+	}
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+// fileSize returns the size of the open file, which may be ahead of the inode with the data being written.
+func (v *smbVolume) fileSize(ino uint64) (size uint64, status ntStatus) {
+	if s, _, valid := v.ec.FileSize(ino); valid {
+		return uint64(s), statusSuccess
+	}
+	info, err := v.mw.InodeGet_ll(ino)
+	if err != nil {
+		return 0, ntStatusOf(err)
+	}
+	return info.Size, statusSuccess
+}
+
+func (c *smbConn) read(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 48 {
+		return statusInvalidParameter, nil
+	}
+	length := le.Uint32(b[4:])
+	offset := le.Uint64(b[8:])
+	minCount := le.Uint32(b[32:])
+	open, status := c.fileOpen(req, 16)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if open.isDir {
+		return statusInvalidDeviceRequest, nil
+	}
+	if length > maxReadSize {
+		return statusInvalidParameter, nil
+	}
+	vol := open.tree.vol
+	if !vol.files.checkIO(open.ino, open.id, offset, uint64(length), false) {
+		return statusFileLockConflict, nil
+	}
+	size, status := vol.fileSize(open.ino)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if offset >= size {
+		return statusEndOfFile, nil
+	}
+	if uint64(length) > size-offset {
+		length = uint32(size - offset)
+	}
+	body := make([]byte, 16+length)
+	n, err := vol.ec.Read(open.ino, body[16:], int(offset), int(length))
+	if err != nil && n <= 0 {
+		log.LogErrorf("read: vol(%v) ino(%v) offset(%v) size(%v) err(%v)", vol.name, open.ino, offset, length, err)
+		return statusInternalError, nil
+	}
+	if uint32(n) < minCount || n == 0 {
+		return statusEndOfFile, nil
+	}
+	le.PutUint16(body[0:], 17)
+	body[2] = smb2HeaderSize + 16
+	le.PutUint32(body[4:], uint32(n))
+	return statusSuccess, body[:16+n]
+}
+
+func (c *smbConn) write(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 48 {
+		return statusInvalidParameter, nil
+	}
+	length := le.Uint32(b[4:])
+	offset := le.Uint64(b[8:])
+	flags := le.Uint32(b[44:])
+	data := buffer(req.msg, int(le.Uint16(b[2:])), int(length))
+	if data == nil || length > maxWriteSize {
+		return statusInvalidParameter, nil
+	}
+	open, status := c.fileOpen(req, 16)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if open.isDir {
+		return statusInvalidDeviceRequest, nil
+	}
+	if open.access&(fileWriteData|fileAppendData|genericWrite|genericAll|maximumAllowed) == 0 ||
+		open.tree.share.readOnly {
+		return statusAccessDenied, nil
+	}
+	vol := open.tree.vol
+	if offset == ^uint64(0) {
+		// writes at the end of the file opened for appending
+		if offset, status = vol.fileSize(open.ino); status != statusSuccess {
+			return status, nil
+		}
+	}
+	if !vol.files.checkIO(open.ino, open.id, offset, uint64(length), true) {
+		return statusFileLockConflict, nil
+	}
+	n, err := vol.ec.Write(open.ino, int(offset), data, 0)
+	if err == nil && flags&writeThrough != 0 {
+		err = vol.ec.Flush(open.ino)
+	}
+	if err != nil {
+		log.LogErrorf("write: vol(%v) ino(%v) offset(%v) size(%v) err(%v)", vol.name, open.ino, offset, length, err)
+		return ntStatusOf(err), nil
+	}
+	body := make([]byte, 16)
+	le.PutUint16(body[0:], 17)
+	le.PutUint32(body[4:], uint32(n))
+	return statusSuccess, body
+}
+
+func (c *smbConn) lock(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 24 {
+		return statusInvalidParameter, nil
+	}
+	count := int(le.Uint16(b[2:]))
+	if count == 0 || len(b) < 24+24*count {
+		return statusInvalidParameter, nil
+	}
+	open, status := c.fileOpen(req, 8)
+	if status != statusSuccess {
+		return status, nil
+	}
+	unlock := le.Uint32(b[24+16:])&lockUnlock != 0
+	ranges := make([]*byteRange, 0, count)
+	for i := 0; i < count; i++ {
+		elem := b[24+24*i:]
+		flags := le.Uint32(elem[16:])
+		if (flags&lockUnlock != 0) != unlock {
+			return statusInvalidParameter, nil
+		}
+		ranges = append(ranges, &byteRange{
+			owner:     open.id,
+			offset:    le.Uint64(elem[0:]),
+			length:    le.Uint64(elem[8:]),
+			exclusive: flags&lockExclusive != 0,
+		})
+	}
+	if unlock {
+		status = open.tree.vol.files.unlock(open.ino, ranges)
+	} else {
+		// the blocking locks are not waited for but fail like the ones failing immediately
+		status = open.tree.vol.files.lock(open.ino, ranges)
+	}
+	if status != statusSuccess {
+		return status, nil
+	}
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
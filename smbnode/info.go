@@ -0,0 +1,714 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	bytesPerSector     = 512
+	sectorsPerUnit     = 8
+	allocationUnitSize = bytesPerSector * sectorsPerUnit
+
+	fileDeviceDisk           = 0x00000007
+	fileRemoteDevice         = 0x00000010
+	fileCasePreservedNames   = 0x00000002
+	fileUnicodeOnDisk        = 0x00000004
+	maximumComponentNameSize = 255
+)
+
+// smbAttr is the attributes of an inode as presented to the clients.
+type smbAttr struct {
+	ino        uint64
+	size       uint64
+	alloc      uint64
+	created    uint64
+	accessed   uint64
+	written    uint64
+	attributes uint32
+	nlink      uint32
+	dir        bool
+}
+
+func newSMBAttr(info *proto.InodeInfo, vol *smbVolume, share *smbShare, name string) *smbAttr {
+	a := &smbAttr{
+		ino:      info.Inode,
+		size:     info.Size,
+		created:  filetime(info.CreateTime),
+		accessed: filetime(info.AccessTime),
+		written:  filetime(info.ModifyTime),
+		nlink:    info.Nlink,
+		dir:      proto.IsDir(info.Mode),
+	}
+	if a.dir {
+		a.size = 0
+		a.attributes = fileAttributeDirectory
+	} else {
+		if size, _, valid := vol.ec.FileSize(info.Inode); valid {
+			a.size = uint64(size)
+		}
+		a.attributes = fileAttributeArchive
+		if info.Mode&0200 == 0 || share.readOnly {
+			a.attributes |= fileAttributeReadonly
+		}
+	}
+	if strings.HasPrefix(name, ".") && name != "." && name != ".." {
+		a.attributes |= fileAttributeHidden
+	}
+	a.alloc = (a.size + allocationUnitSize - 1) / allocationUnitSize * allocationUnitSize
+	return a
+}
+
+// putTimes puts the creation, last access, last write and change times.
+func (a *smbAttr) putTimes(b []byte) {
+	le.PutUint64(b[0:], a.created)
+	le.PutUint64(b[8:], a.accessed)
+	le.PutUint64(b[16:], a.written)
+	le.PutUint64(b[24:], a.written)
+}
+
+// directoryEntry encodes the entry of the directory information class, or returns nil if the class is unknown.
+func (a *smbAttr) directoryEntry(class byte, name string) []byte {
+	fileName := encodeUTF16(name)
+	var fixed int
+	switch class {
+	case fileNamesInformation:
+		e := make([]byte, 12, 12+len(fileName))
+		le.PutUint32(e[8:], uint32(len(fileName)))
+		return append(e, fileName...)
+	case fileDirectoryInformation:
+		fixed = 64
+	case fileFullDirectoryInformation:
+		fixed = 68
+	case fileIDFullDirectoryInformation:
+		fixed = 80
+	case fileBothDirectoryInformation:
+		fixed = 94
+	case fileIDBothDirectoryInformation:
+		fixed = 104
+	default:
+		return nil
+	}
+	e := make([]byte, fixed, fixed+len(fileName))
+	a.putTimes(e[8:])
+	le.PutUint64(e[40:], a.size)
+	le.PutUint64(e[48:], a.alloc)
+	le.PutUint32(e[56:], a.attributes)
+	le.PutUint32(e[60:], uint32(len(fileName)))
+	switch class {
+	case fileIDFullDirectoryInformation:
+		le.PutUint64(e[72:], a.ino)
+	case fileIDBothDirectoryInformation:
+		le.PutUint64(e[96:], a.ino)
+	}
+	return append(e, fileName...)
+}
+
+type dirEntry struct {
+	name string
+	attr *smbAttr
+}
+
+func (t *smbTree) listDir(open *smbOpen) (entries []*dirEntry, status ntStatus) {
+	vol := t.vol
+	dentries, err := vol.mw.ReadDir_ll(open.ino)
+	if err != nil {
+		return nil, ntStatusOf(err)
+	}
+	self, err := vol.mw.InodeGet_ll(open.ino)
+	if err != nil {
+		return nil, ntStatusOf(err)
+	}
+	parent := self
+	if open.parent != 0 {
+		if parent, err = vol.mw.InodeGet_ll(open.parent); err != nil {
+			parent = self
+		}
+	}
+	inodes := make([]uint64, 0, len(dentries))
+	for _, dentry := range dentries {
+		inodes = append(inodes, dentry.Inode)
+	}
+	infos := make(map[uint64]*proto.InodeInfo, len(dentries))
+	for _, info := range vol.mw.BatchInodeGet(inodes) {
+		infos[info.Inode] = info
+	}
+
+	entries = make([]*dirEntry, 0, len(dentries)+2)
+	entries = append(entries, &dirEntry{name: ".", attr: newSMBAttr(self, vol, t.share, ".")})
+	entries = append(entries, &dirEntry{name: "..", attr: newSMBAttr(parent, vol, t.share, "..")})
+	for _, dentry := range dentries {
+		if info, ok := infos[dentry.Inode]; ok {
+			entries = append(entries, &dirEntry{name: dentry.Name, attr: newSMBAttr(info, vol, t.share, dentry.Name)})
+		}
+	}
+	return entries, statusSuccess
+}
+
+// matchPattern matches the name against the search pattern case insensitively. The DOS wildcards are
+// taken as their plain counterparts.
+func matchPattern(pattern, name string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	return matchWildcard([]rune(strings.ToUpper(pattern)), []rune(strings.ToUpper(name)))
+}
+
+func matchWildcard(p, n []rune) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*', '<':
+			for i := len(n); i >= 0; i-- {
+				if matchWildcard(p[1:], n[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?', '>':
+			if len(n) == 0 {
+				return p[0] == '>' && matchWildcard(p[1:], n)
+			}
+		case '"':
+			if len(n) == 0 {
+				return matchWildcard(p[1:], n)
+			}
+			if n[0] != '.' {
+				return false
+			}
+		default:
+			if len(n) == 0 || p[0] != n[0] {
+				return false
+			}
+		}
+		p, n = p[1:], n[1:]
+	}
+	return len(n) == 0
+}
+
+func (c *smbConn) queryDirectory(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 32 {
+		return statusInvalidParameter, nil
+	}
+	class, flags := b[2], b[3]
+	pattern := buffer(req.msg, int(le.Uint16(b[24:])), int(le.Uint16(b[26:])))
+	outLen := int(le.Uint32(b[28:]))
+	if pattern == nil {
+		return statusInvalidParameter, nil
+	}
+	if outLen > maxTransactSize {
+		outLen = maxTransactSize
+	}
+	open, status := c.fileOpen(req, 8)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if !open.isDir {
+		return statusInvalidParameter, nil
+	}
+	if open.listing == nil || flags&(restartScans|reopen) != 0 {
+		if open.listing, status = req.tree.listDir(open); status != statusSuccess {
+			return status, nil
+		}
+		open.pos = 0
+		open.pattern = decodeUTF16(pattern)
+		open.returned = false
+	}
+
+	var (
+		out  []byte
+		last = -1
+	)
+	for ; open.pos < len(open.listing); open.pos++ {
+		entry := open.listing[open.pos]
+		if !matchPattern(open.pattern, entry.name) {
+			continue
+		}
+		e := entry.attr.directoryEntry(class, entry.name)
+		if e == nil {
+			return statusInvalidInfoClass, nil
+		}
+		off := align8(len(out))
+		if off+len(e) > outLen {
+			break
+		}
+		out = append(out, make([]byte, off-len(out))...)
+		if last >= 0 {
+			le.PutUint32(out[last:], uint32(off-last))
+		}
+		last = off
+		out = append(out, e...)
+		if flags&returnSingleEntry != 0 {
+			open.pos++
+			break
+		}
+	}
+	if last < 0 {
+		switch {
+		case open.pos < len(open.listing):
+			return statusInfoLengthMismatch, nil
+		case open.returned:
+			return statusNoMoreFiles, nil
+		}
+		open.returned = true
+		return statusNoSuchFile, nil
+	}
+	open.returned = true
+	return statusSuccess, infoResponse(out)
+}
+
+// infoResponse returns the body of a query response carrying the output buffer.
+func infoResponse(out []byte) []byte {
+	body := make([]byte, 8, 8+len(out))
+	le.PutUint16(body[0:], 9)
+	le.PutUint16(body[2:], smb2HeaderSize+8)
+	le.PutUint32(body[4:], uint32(len(out)))
+	return append(body, out...)
+}
+
+func (c *smbConn) queryInfo(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 40 {
+		return statusInvalidParameter, nil
+	}
+	infoType, class := b[2], b[3]
+	outLen := int(le.Uint32(b[4:]))
+	additional := le.Uint32(b[16:])
+	open, status := c.fileOpen(req, 24)
+	if status != statusSuccess {
+		return status, nil
+	}
+
+	var (
+		out      []byte
+		variable bool
+	)
+	switch infoType {
+	case infoFile:
+		out, variable, status = open.fileInformation(class)
+	case infoFilesystem:
+		out, variable, status = open.fsInformation(class)
+	case infoSecurity:
+		out = securityDescriptor(open.tree.share, additional)
+		if len(out) > outLen {
+			var needed [4]byte
+			le.PutUint32(needed[:], uint32(len(out)))
+			return statusBufferTooSmall, errorBody(needed[:])
+		}
+	default:
+		return statusNotSupported, nil
+	}
+	if status != statusSuccess {
+		return status, nil
+	}
+	if len(out) > outLen {
+		if !variable {
+			return statusInfoLengthMismatch, nil
+		}
+		return statusBufferOverflow, infoResponse(out[:outLen])
+	}
+	return statusSuccess, infoResponse(out)
+}
+
+func (o *smbOpen) fileInformation(class byte) (out []byte, variable bool, status ntStatus) {
+	vol := o.tree.vol
+	info, err := vol.mw.InodeGet_ll(o.ino)
+	if err != nil {
+		return nil, false, ntStatusOf(err)
+	}
+	attr := newSMBAttr(info, vol, o.tree.share, o.name)
+	basic := make([]byte, 40)
+	attr.putTimes(basic)
+	le.PutUint32(basic[32:], attr.attributes)
+	standard := make([]byte, 24)
+	le.PutUint64(standard[0:], attr.alloc)
+	le.PutUint64(standard[8:], attr.size)
+	le.PutUint32(standard[16:], attr.nlink)
+	if vol.files.isDeletePending(o.ino) || o.deleteOnClose {
+		standard[20] = 1
+	}
+	if attr.dir {
+		standard[21] = 1
+	}
+	internal := make([]byte, 8)
+	le.PutUint64(internal, attr.ino)
+
+	switch class {
+	case fileBasicInformation:
+		out = basic
+	case fileStandardInformation:
+		out = standard
+	case fileInternalInformation:
+		out = internal
+	case fileEaInformation, fileModeInformation, fileAlignmentInformation:
+		out = make([]byte, 4)
+	case fileAccessInformation:
+		out = make([]byte, 4)
+		le.PutUint32(out, o.access)
+	case filePositionInformation:
+		out = make([]byte, 8)
+	case fileAllInformation:
+		name := encodeUTF16("\\" + o.path)
+		out = append(append(append(basic, standard...), internal...), make([]byte, 28)...)
+		le.PutUint32(out[76:], o.access)
+		le.PutUint32(out[96:], uint32(len(name)))
+		out = append(out, name...)
+		variable = true
+	case fileNetworkOpenInformation:
+		out = make([]byte, 56)
+		attr.putTimes(out)
+		le.PutUint64(out[32:], attr.alloc)
+		le.PutUint64(out[40:], attr.size)
+		le.PutUint32(out[48:], attr.attributes)
+	case fileAttributeTagInformation:
+		out = make([]byte, 8)
+		le.PutUint32(out, attr.attributes)
+	case fileStreamInformation:
+		variable = true
+		if attr.dir {
+			out = []byte{}
+			break
+		}
+		name := encodeUTF16("::$DATA")
+		out = make([]byte, 24, 24+len(name))
+		le.PutUint32(out[4:], uint32(len(name)))
+		le.PutUint64(out[8:], attr.size)
+		le.PutUint64(out[16:], attr.alloc)
+		out = append(out, name...)
+	default:
+		return nil, false, statusInvalidInfoClass
+	}
+	return out, variable, statusSuccess
+}
+
+func (o *smbOpen) fsInformation(class byte) (out []byte, variable bool, status ntStatus) {
+	total, used := o.tree.vol.mw.Statfs()
+	var free uint64
+	if total > used {
+		free = total - used
+	}
+	switch class {
+	case fileFsVolumeInformation:
+		label := encodeUTF16(o.tree.share.name)
+		h := fnv.New32a()
+		h.Write([]byte(o.tree.vol.name))
+		out = make([]byte, 18, 18+len(label))
+		le.PutUint32(out[8:], h.Sum32())
+		le.PutUint32(out[12:], uint32(len(label)))
+		out = append(out, label...)
+		variable = true
+	case fileFsSizeInformation:
+		out = make([]byte, 24)
+		le.PutUint64(out[0:], total/allocationUnitSize)
+		le.PutUint64(out[8:], free/allocationUnitSize)
+		le.PutUint32(out[16:], sectorsPerUnit)
+		le.PutUint32(out[20:], bytesPerSector)
+	case fileFsFullSizeInformation:
+		out = make([]byte, 32)
+		le.PutUint64(out[0:], total/allocationUnitSize)
+		le.PutUint64(out[8:], free/allocationUnitSize)
+		le.PutUint64(out[16:], free/allocationUnitSize)
+		le.PutUint32(out[24:], sectorsPerUnit)
+		le.PutUint32(out[28:], bytesPerSector)
+	case fileFsDeviceInformation:
+		out = make([]byte, 8)
+		le.PutUint32(out[0:], fileDeviceDisk)
+		le.PutUint32(out[4:], fileRemoteDevice)
+	case fileFsAttributeInformation:
+		// the file system claims to be NTFS for the applications checking the features by its name
+		name := encodeUTF16("NTFS")
+		out = make([]byte, 12, 12+len(name))
+		le.PutUint32(out[0:], fileCasePreservedNames|fileUnicodeOnDisk)
+		le.PutUint32(out[4:], maximumComponentNameSize)
+		le.PutUint32(out[8:], uint32(len(name)))
+		out = append(out, name...)
+		variable = true
+	case fileFsSectorSizeInformation:
+		out = make([]byte, 28)
+		for i := 0; i < 16; i += 4 {
+			le.PutUint32(out[i:], bytesPerSector)
+		}
+		le.PutUint32(out[16:], 0x3)
+	default:
+		return nil, false, statusInvalidInfoClass
+	}
+	return out, variable, statusSuccess
+}
+
+// securityDescriptor returns a self-relative security descriptor, which presents the owner of the files
+// created through the share as a Unix user and grants everyone the access of the share.
+func securityDescriptor(share *smbShare, sections uint32) []byte {
+	sd := make([]byte, 20)
+	sd[0] = 1
+	control := uint16(0x8000) // self relative
+	if sections&ownerSecurityInformation != 0 {
+		le.PutUint32(sd[4:], uint32(len(sd)))
+		sd = append(sd, unixSID(1, share.uid)...)
+	}
+	if sections&groupSecurityInformation != 0 {
+		le.PutUint32(sd[8:], uint32(len(sd)))
+		sd = append(sd, unixSID(2, share.gid)...)
+	}
+	if sections&daclSecurityInformation != 0 {
+		control |= 0x0004 // DACL present
+		mask := uint32(fileAllAccess)
+		if share.readOnly {
+			mask = fileReadAccess
+		}
+		acl := make([]byte, 28)
+		acl[0] = 2
+		le.PutUint16(acl[2:], 28)
+		le.PutUint16(acl[4:], 1)
+		// an access allowed ACE for S-1-1-0 (everyone)
+		le.PutUint16(acl[10:], 20)
+		le.PutUint32(acl[12:], mask)
+		copy(acl[16:], []byte{1, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0})
+		le.PutUint32(sd[16:], uint32(len(sd)))
+		sd = append(sd, acl...)
+	}
+	le.PutUint16(sd[2:], control)
+	return sd
+}
+
+// unixSID returns the SID S-1-22-kind-id Samba maps the Unix users (kind 1) and groups (kind 2) to.
+func unixSID(kind, id uint32) []byte {
+	sid := []byte{1, 2, 0, 0, 0, 0, 0, 22, 0, 0, 0, 0, 0, 0, 0, 0}
+	le.PutUint32(sid[8:], kind)
+	le.PutUint32(sid[12:], id)
+	return sid
+}
+
+func (c *smbConn) setInfo(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 32 {
+		return statusInvalidParameter, nil
+	}
+	infoType, class := b[2], b[3]
+	data := buffer(req.msg, int(le.Uint16(b[8:])), int(le.Uint32(b[4:])))
+	if data == nil {
+		return statusInvalidParameter, nil
+	}
+	open, status := c.fileOpen(req, 16)
+	if status != statusSuccess {
+		return status, nil
+	}
+	if infoType != infoFile {
+		return statusNotSupported, nil
+	}
+	if class == filePositionInformation || class == fileModeInformation {
+		return statusSuccess, []byte{2, 0}
+	}
+	if open.tree.share.readOnly {
+		return statusAccessDenied, nil
+	}
+
+	switch class {
+	case fileBasicInformation:
+		if len(data) < 36 {
+			return statusInfoLengthMismatch, nil
+		}
+		status = open.setBasicInfo(le.Uint64(data[8:]), le.Uint64(data[16:]), le.Uint32(data[32:]))
+	case fileRenameInformation, fileLinkInformation:
+		if len(data) < 20 || len(data) < 20+int(le.Uint32(data[16:])) {
+			return statusInfoLengthMismatch, nil
+		}
+		target := decodeUTF16(data[20 : 20+le.Uint32(data[16:])])
+		if class == fileRenameInformation {
+			status = open.rename(target, data[0] != 0)
+		} else {
+			status = open.link(target, data[0] != 0)
+		}
+	case fileDispositionInformation:
+		if len(data) < 1 {
+			return statusInfoLengthMismatch, nil
+		}
+		status = open.setDeletePending(data[0] != 0)
+	case fileEndOfFileInformation:
+		if len(data) < 8 {
+			return statusInfoLengthMismatch, nil
+		}
+		status = open.truncate(le.Uint64(data), false)
+	case fileAllocationInformation:
+		if len(data) < 8 {
+			return statusInfoLengthMismatch, nil
+		}
+		status = open.truncate(le.Uint64(data), true)
+	default:
+		return statusInvalidInfoClass, nil
+	}
+	if status != statusSuccess {
+		return status, nil
+	}
+	return statusSuccess, []byte{2, 0}
+}
+
+// settableTime returns whether the time of a basic information is to be set, zero and the negative values
+// leave the time unchanged.
+func settableTime(ft uint64) bool {
+	return ft != 0 && int64(ft) > 0
+}
+
+func (o *smbOpen) setBasicInfo(accessed, written uint64, attributes uint32) ntStatus {
+	vol := o.tree.vol
+	info, err := vol.mw.InodeGet_ll(o.ino)
+	if err != nil {
+		return ntStatusOf(err)
+	}
+	var valid uint32
+	mode := info.Mode
+	if settableTime(accessed) {
+		valid |= proto.AttrAccessTime
+	}
+	if settableTime(written) {
+		valid |= proto.AttrModifyTime
+	}
+	if attributes != 0 && !o.isDir {
+		if attributes&fileAttributeReadonly != 0 {
+			mode &^= 0222
+		} else if mode&0200 == 0 {
+			mode |= 0200
+		}
+		if mode != info.Mode {
+			valid |= proto.AttrMode
+		}
+	}
+	if valid == 0 {
+		return statusSuccess
+	}
+	if !o.isDir {
+		// the pending writes would otherwise update the modification time after it is set
+		if err = vol.ec.Flush(o.ino); err != nil {
+			return ntStatusOf(err)
+		}
+	}
+	atime, mtime := filetimeToUnix(accessed), filetimeToUnix(written)
+	return ntStatusOf(vol.mw.Setattr(o.ino, valid, mode, 0, 0, atime, mtime))
+}
+
+// target resolves the target of a rename or a link, it returns the inode of the existing target which
+// is to be replaced.
+func (o *smbOpen) target(path string, replace bool) (components []string, parent uint64, name string, existing *proto.InodeInfo, status ntStatus) {
+	if components, status = splitPath(path); status != statusSuccess {
+		return
+	}
+	if len(components) == 0 {
+		status = statusInvalidParameter
+		return
+	}
+	if parent, name, existing, status = o.tree.resolve(components); status != statusSuccess {
+		return
+	}
+	if existing == nil {
+		return
+	}
+	if existing.Inode == o.ino {
+		// changes the case of the name
+		name = components[len(components)-1]
+		existing = nil
+		return
+	}
+	switch {
+	case !replace:
+		status = statusObjectNameCollision
+	case proto.IsDir(existing.Mode) || o.isDir:
+		status = statusAccessDenied
+	case o.tree.vol.files.isOpen(existing.Inode):
+		status = statusAccessDenied
+	}
+	return
+}
+
+func (o *smbOpen) rename(path string, replace bool) ntStatus {
+	if o.parent == 0 {
+		return statusAccessDenied
+	}
+	components, parent, name, _, status := o.target(path, replace)
+	if status != statusSuccess {
+		return status
+	}
+	if parent == o.parent && name == o.name {
+		return statusSuccess
+	}
+	if err := o.tree.vol.mw.Rename_ll(o.parent, o.name, parent, name); err != nil {
+		return ntStatusOf(err)
+	}
+	o.parent, o.name, o.path = parent, name, strings.Join(components, "\\")
+	return statusSuccess
+}
+
+func (o *smbOpen) link(path string, replace bool) ntStatus {
+	if o.isDir {
+		return statusFileIsADirectory
+	}
+	_, parent, name, existing, status := o.target(path, replace)
+	if status != statusSuccess {
+		return status
+	}
+	mw := o.tree.vol.mw
+	if existing != nil {
+		info, err := mw.Delete_ll(parent, name, false)
+		if err != nil {
+			return ntStatusOf(err)
+		}
+		if info != nil && info.Nlink == 0 {
+			mw.Evict(info.Inode)
+		}
+	}
+	_, err := mw.Link(parent, name, o.ino)
+	return ntStatusOf(err)
+}
+
+func (o *smbOpen) setDeletePending(pending bool) ntStatus {
+	if pending {
+		if o.parent == 0 {
+			return statusAccessDenied
+		}
+		if o.isDir {
+			dentries, err := o.tree.vol.mw.ReadDir_ll(o.ino)
+			if err != nil {
+				return ntStatusOf(err)
+			}
+			if len(dentries) > 0 {
+				return statusDirectoryNotEmpty
+			}
+		}
+	} else {
+		o.deleteOnClose = false
+	}
+	o.tree.vol.files.setDeletePending(o.ino, pending)
+	return statusSuccess
+}
+
+// truncate sets the end of the file, or shrinks it to the allocation size.
+func (o *smbOpen) truncate(size uint64, allocation bool) ntStatus {
+	if o.isDir {
+		return statusInvalidParameter
+	}
+	vol := o.tree.vol
+	if allocation {
+		current, status := vol.fileSize(o.ino)
+		if status != statusSuccess || size >= current {
+			return status
+		}
+	}
+	if err := vol.ec.Flush(o.ino); err != nil {
+		return ntStatusOf(err)
+	}
+	return ntStatusOf(vol.ec.Truncate(o.ino, int(size)))
+}
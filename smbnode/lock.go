@@ -0,0 +1,178 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"math"
+	"sync"
+)
+
+// byteRange is a byte-range lock held by an open.
+type byteRange struct {
+	owner     uint64
+	offset    uint64
+	length    uint64
+	exclusive bool
+}
+
+func (r *byteRange) end() uint64 {
+	if r.length > math.MaxUint64-r.offset {
+		return math.MaxUint64
+	}
+	return r.offset + r.length
+}
+
+func (r *byteRange) overlaps(o *byteRange) bool {
+	return r.length != 0 && o.length != 0 && r.offset < o.end() && o.offset < r.end()
+}
+
+// fileState is the state of a file shared by all its opens through the gateway.
+type fileState struct {
+	opens         int
+	deletePending bool
+	locks         []*byteRange
+}
+
+// fileTable keeps the states of the open files of a volume. The byte-range locks are mandatory like on
+// Windows, but they are only known to this gateway: a share must not be served by several gateways
+// at the same time for the locks to take effect.
+type fileTable struct {
+	files map[uint64]*fileState
+	mu    sync.Mutex
+}
+
+func newFileTable() *fileTable {
+	return &fileTable{files: make(map[uint64]*fileState)}
+}
+
+func (t *fileTable) open(ino uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[ino]
+	if !ok {
+		f = &fileState{}
+		t.files[ino] = f
+	}
+	f.opens++
+}
+
+// close releases the locks of the open and returns whether the file is to be deleted
+// since it is the last open of a file pending deletion.
+func (t *fileTable) close(ino, owner uint64) (deleteFile bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[ino]
+	if !ok {
+		return false
+	}
+	locks := f.locks[:0]
+	for _, l := range f.locks {
+		if l.owner != owner {
+			locks = append(locks, l)
+		}
+	}
+	f.locks = locks
+	if f.opens--; f.opens > 0 {
+		return false
+	}
+	delete(t.files, ino)
+	return f.deletePending
+}
+
+func (t *fileTable) setDeletePending(ino uint64, pending bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.files[ino]; ok {
+		f.deletePending = pending
+	}
+}
+
+func (t *fileTable) isDeletePending(ino uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[ino]
+	return ok && f.deletePending
+}
+
+// lock acquires all the ranges for the owner or none of them.
+func (t *fileTable) lock(ino uint64, ranges []*byteRange) ntStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[ino]
+	if !ok {
+		return statusFileClosed
+	}
+	acquired := len(f.locks)
+	for _, r := range ranges {
+		for _, l := range f.locks {
+			// an open may only stack the shared locks on its own ranges
+			if l.overlaps(r) && (l.exclusive || r.exclusive) {
+				f.locks = f.locks[:acquired]
+				return statusLockNotGranted
+			}
+		}
+		f.locks = append(f.locks, r)
+	}
+	return statusSuccess
+}
+
+// unlock releases the ranges which must exactly match the locks held by the owner.
+func (t *fileTable) unlock(ino uint64, ranges []*byteRange) ntStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[ino]
+	if !ok {
+		return statusFileClosed
+	}
+	for _, r := range ranges {
+		found := false
+		for i, l := range f.locks {
+			if l.owner == r.owner && l.offset == r.offset && l.length == r.length {
+				f.locks = append(f.locks[:i], f.locks[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return statusRangeNotLocked
+		}
+	}
+	return statusSuccess
+}
+
+// checkIO returns whether the open may read or write the range, which conflicts with the exclusive locks
+// of the other opens, and with all of their locks for writing.
+func (t *fileTable) checkIO(ino, owner, offset, length uint64, write bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[ino]
+	if !ok {
+		return true
+	}
+	r := &byteRange{offset: offset, length: length}
+	for _, l := range f.locks {
+		if l.owner != owner && l.overlaps(r) && (write || l.exclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *fileTable) isOpen(ino uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.files[ino]
+	return ok
+}
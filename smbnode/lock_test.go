@@ -0,0 +1,131 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"math"
+	"testing"
+)
+
+func TestByteRangeLocks(t *testing.T) {
+	files := newFileTable()
+	files.open(1)
+	files.open(1)
+	lock := func(owner, offset, length uint64, exclusive bool) ntStatus {
+		return files.lock(1, []*byteRange{{owner: owner, offset: offset, length: length, exclusive: exclusive}})
+	}
+
+	if status := lock(10, 0, 100, true); status != statusSuccess {
+		t.Fatalf("lock: status(%x)", status)
+	}
+	if status := lock(11, 50, 10, false); status != statusLockNotGranted {
+		t.Fatalf("shared lock on exclusive range: status(%x)", status)
+	}
+	if status := lock(10, 50, 10, true); status != statusLockNotGranted {
+		t.Fatalf("exclusive lock on own exclusive range: status(%x)", status)
+	}
+	if status := lock(11, 100, math.MaxUint64, false); status != statusSuccess {
+		t.Fatalf("lock to the end: status(%x)", status)
+	}
+	if status := lock(10, 200, 10, false); status != statusSuccess {
+		t.Fatalf("shared lock on shared range: status(%x)", status)
+	}
+	if status := lock(11, 0, 0, true); status != statusSuccess {
+		t.Fatalf("zero length lock: status(%x)", status)
+	}
+
+	// the locks of a request are acquired all or none
+	status := files.lock(1, []*byteRange{{owner: 12, offset: 300, length: 1}, {owner: 12, offset: 0, length: 1}})
+	if status != statusLockNotGranted || len(files.files[1].locks) != 4 {
+		t.Fatalf("partial lock: status(%x) locks(%v)", status, len(files.files[1].locks))
+	}
+
+	if files.checkIO(1, 11, 10, 1, false) || !files.checkIO(1, 10, 10, 1, true) {
+		t.Fatalf("io on exclusive range")
+	}
+	if !files.checkIO(1, 10, 150, 1, false) || files.checkIO(1, 10, 150, 1, true) {
+		t.Fatalf("io on shared range")
+	}
+
+	if status := files.unlock(1, []*byteRange{{owner: 11, offset: 0, length: 100}}); status != statusRangeNotLocked {
+		t.Fatalf("unlock range of others: status(%x)", status)
+	}
+	if status := files.unlock(1, []*byteRange{{owner: 10, offset: 0, length: 100}}); status != statusSuccess {
+		t.Fatalf("unlock: status(%x)", status)
+	}
+	if !files.checkIO(1, 11, 10, 1, true) {
+		t.Fatalf("io on unlocked range")
+	}
+
+	files.setDeletePending(1, true)
+	if files.close(1, 11) || len(files.files[1].locks) != 1 {
+		t.Fatalf("close: locks(%v)", len(files.files[1].locks))
+	}
+	if !files.close(1, 10) || files.isOpen(1) {
+		t.Fatalf("last close of file pending deletion")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"*", "a.txt", true},
+		{"", "a.txt", true},
+		{"*.TXT", "a.txt", true},
+		{"*.txt", "a.doc", false},
+		{"a?c", "ABC", true},
+		{"a?c", "ac", false},
+		{"<.doc", "report.doc", true},
+		{"file>", "file", true},
+		{"name\"*", "name.txt", true},
+		{"a.txt", "a.txt.bak", false},
+	}
+	for _, c := range cases {
+		if matchPattern(c.pattern, c.name) != c.match {
+			t.Errorf("match(%q, %q) expected %v", c.pattern, c.name, c.match)
+		}
+	}
+}
+
+func TestParseShares(t *testing.T) {
+	shares, err := parseShares([]interface{}{
+		map[string]interface{}{"name": "Data", "volume": "vol1", "users": []interface{}{"alice"}, "uid": float64(1000)},
+		map[string]interface{}{"name": "public", "volume": "vol2", "readOnly": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newShareManager(nil, shares)
+	data := m.share("DATA")
+	if data == nil || data.volume != "vol1" || data.uid != 1000 || !data.allows("alice") || data.allows("bob") {
+		t.Fatalf("share data: %v", data)
+	}
+	if public := m.share("public"); public == nil || !public.readOnly || !public.allows("bob") {
+		t.Fatalf("share public: %v", public)
+	}
+	invalid := [][]interface{}{
+		{map[string]interface{}{"name": "data"}},
+		{map[string]interface{}{"name": "a$", "volume": "vol1"}},
+		{map[string]interface{}{"name": "a", "volume": "vol1"}, map[string]interface{}{"name": "A", "volume": "vol2"}},
+		{"data"},
+	}
+	for _, items := range invalid {
+		if _, err = parseShares(items); err == nil {
+			t.Errorf("invalid shares %v are parsed", items)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"errors"
+	"strings"
+	"time"
+)
+
+const ntlmSignature = "NTLMSSP\x00"
+
+// NTLM message types
+const (
+	ntlmNegotiateMessage    = 1
+	ntlmChallengeMessage    = 2
+	ntlmAuthenticateMessage = 3
+)
+
+// NTLM negotiate flags
+const (
+	ntlmNegotiateUnicode                 = 0x00000001
+	ntlmRequestTarget                    = 0x00000004
+	ntlmNegotiateSign                    = 0x00000010
+	ntlmNegotiateSeal                    = 0x00000020
+	ntlmNegotiateNTLM                    = 0x00000200
+	ntlmNegotiateAlwaysSign              = 0x00008000
+	ntlmTargetTypeServer                 = 0x00020000
+	ntlmNegotiateExtendedSessionSecurity = 0x00080000
+	ntlmNegotiateTargetInfo              = 0x00800000
+	ntlmNegotiateVersion                 = 0x02000000
+	ntlmNegotiate128                     = 0x20000000
+	ntlmNegotiateKeyExch                 = 0x40000000
+	ntlmNegotiate56                      = 0x80000000
+)
+
+// AV pair IDs of the target info
+const (
+	avEOL             = 0
+	avNbComputerName  = 1
+	avNbDomainName    = 2
+	avDNSComputerName = 3
+	avDNSDomainName   = 4
+	avFlags           = 6
+	avTimestamp       = 7
+
+	avFlagMICPresent = 0x00000002
+)
+
+const (
+	clientSigningMagic = "session key to client-to-server signing key magic constant\x00"
+	serverSigningMagic = "session key to server-to-client signing key magic constant\x00"
+	clientSealingMagic = "session key to client-to-server sealing key magic constant\x00"
+	serverSealingMagic = "session key to server-to-client sealing key magic constant\x00"
+)
+
+// the version of the server in the challenge, Windows 7 with NTLM revision 15
+var ntlmVersion = []byte{6, 1, 0xb1, 0x1d, 0, 0, 0, 0x0f}
+
+var (
+	errNTLMInvalidMessage = errors.New("invalid NTLM message")
+	errNTLMUnsupported    = errors.New("unsupported NTLM authentication")
+	errNTLMLogonFailure   = errors.New("NTLM logon failure")
+)
+
+// ntlmServer is the server side of an NTLMv2 authentication.
+type ntlmServer struct {
+	domain    string
+	computer  string
+	users     userStore
+	challenge [8]byte
+	negotiate []byte
+	challMsg  []byte
+
+	user          string
+	flags         uint32
+	sessionKey    []byte
+	clientSignKey []byte
+	serverSignKey []byte
+	clientSeal    *rc4.Cipher
+	serverSeal    *rc4.Cipher
+}
+
+func newNTLMServer(domain, computer string, users userStore) *ntlmServer {
+	return &ntlmServer{domain: domain, computer: computer, users: users}
+}
+
+func isNTLMMessage(token []byte) bool {
+	return len(token) >= 12 && string(token[:8]) == ntlmSignature
+}
+
+func ntlmField(msg []byte, off int) ([]byte, error) {
+	if off+8 > len(msg) {
+		return nil, errNTLMInvalidMessage
+	}
+	length := int(le.Uint16(msg[off:]))
+	start := int(le.Uint32(msg[off+4:]))
+	if length == 0 {
+		return []byte{}, nil
+	}
+	if start+length > len(msg) {
+		return nil, errNTLMInvalidMessage
+	}
+	return msg[start : start+length], nil
+}
+
+func putNTLMField(msg []byte, off, length, start int) {
+	le.PutUint16(msg[off:], uint16(length))
+	le.PutUint16(msg[off+2:], uint16(length))
+	le.PutUint32(msg[off+4:], uint32(start))
+}
+
+func appendAVPair(b []byte, id uint16, value []byte) []byte {
+	var hdr [4]byte
+	le.PutUint16(hdr[0:], id)
+	le.PutUint16(hdr[2:], uint16(len(value)))
+	return append(append(b, hdr[:]...), value...)
+}
+
+// challengeMessage answers the negotiate message of the client.
+func (s *ntlmServer) challengeMessage(negotiate []byte) (msg []byte, err error) {
+	if !isNTLMMessage(negotiate) || le.Uint32(negotiate[8:]) != ntlmNegotiateMessage || len(negotiate) < 16 {
+		return nil, errNTLMInvalidMessage
+	}
+	clientFlags := le.Uint32(negotiate[12:])
+	if clientFlags&ntlmNegotiateUnicode == 0 {
+		return nil, errNTLMUnsupported
+	}
+	s.flags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign |
+		ntlmTargetTypeServer | ntlmNegotiateTargetInfo | ntlmNegotiateVersion
+	s.flags |= clientFlags & (ntlmNegotiateSign | ntlmNegotiateSeal | ntlmNegotiateExtendedSessionSecurity |
+		ntlmNegotiate128 | ntlmNegotiateKeyExch | ntlmNegotiate56)
+	if _, err = rand.Read(s.challenge[:]); err != nil {
+		return
+	}
+
+	targetName := encodeUTF16(s.domain)
+	var timestamp [8]byte
+	le.PutUint64(timestamp[:], filetime(time.Now()))
+	info := appendAVPair(nil, avNbDomainName, targetName)
+	info = appendAVPair(info, avNbComputerName, encodeUTF16(s.computer))
+	info = appendAVPair(info, avDNSDomainName, encodeUTF16(strings.ToLower(s.domain)))
+	info = appendAVPair(info, avDNSComputerName, encodeUTF16(strings.ToLower(s.computer)))
+	info = appendAVPair(info, avTimestamp, timestamp[:])
+	info = appendAVPair(info, avEOL, nil)
+
+	msg = make([]byte, 56, 56+len(targetName)+len(info))
+	copy(msg, ntlmSignature)
+	le.PutUint32(msg[8:], ntlmChallengeMessage)
+	putNTLMField(msg, 12, len(targetName), 56)
+	le.PutUint32(msg[20:], s.flags)
+	copy(msg[24:32], s.challenge[:])
+	putNTLMField(msg, 40, len(info), 56+len(targetName))
+	copy(msg[48:], ntlmVersion)
+	msg = append(msg, targetName...)
+	msg = append(msg, info...)
+
+	s.negotiate = append([]byte(nil), negotiate...)
+	s.challMsg = msg
+	return
+}
+
+// authenticate verifies the NTLMv2 response of the authenticate message and derives the session keys.
+func (s *ntlmServer) authenticate(msg []byte) (err error) {
+	if s.challMsg == nil || !isNTLMMessage(msg) || le.Uint32(msg[8:]) != ntlmAuthenticateMessage || len(msg) < 64 {
+		return errNTLMInvalidMessage
+	}
+	var ntResponse, domain, user, encryptedKey []byte
+	if ntResponse, err = ntlmField(msg, 20); err != nil {
+		return
+	}
+	if domain, err = ntlmField(msg, 28); err != nil {
+		return
+	}
+	if user, err = ntlmField(msg, 36); err != nil {
+		return
+	}
+	if encryptedKey, err = ntlmField(msg, 52); err != nil {
+		return
+	}
+	flags := le.Uint32(msg[60:])
+	// anonymous logons and NTLMv1 responses are refused
+	if len(user) == 0 || len(ntResponse) < 44 {
+		return errNTLMUnsupported
+	}
+
+	userName := decodeUTF16(user)
+	hash, err := s.users.ntHash(userName)
+	if err != nil {
+		return
+	}
+	ntowf := hmacMD5(hash, encodeUTF16(strings.ToUpper(userName)+decodeUTF16(domain)))
+	blob := ntResponse[16:]
+	proof := hmacMD5(ntowf, s.challenge[:], blob)
+	if !hmac.Equal(proof, ntResponse[:16]) {
+		return errNTLMLogonFailure
+	}
+	sessionKey := hmacMD5(ntowf, proof)
+	if flags&ntlmNegotiateKeyExch != 0 && len(encryptedKey) == 16 {
+		var c *rc4.Cipher
+		if c, err = rc4.NewCipher(sessionKey); err != nil {
+			return
+		}
+		c.XORKeyStream(sessionKey, encryptedKey)
+	}
+	if ntlmMICPresent(blob) {
+		if len(msg) < 88 {
+			return errNTLMInvalidMessage
+		}
+		zeroed := append([]byte(nil), msg...)
+		copy(zeroed[72:88], make([]byte, 16))
+		if !hmac.Equal(hmacMD5(sessionKey, s.negotiate, s.challMsg, zeroed), msg[72:88]) {
+			return errNTLMLogonFailure
+		}
+	}
+
+	s.user = userName
+	s.flags = flags
+	s.sessionKey = sessionKey
+	s.clientSignKey = md5Sum(sessionKey, []byte(clientSigningMagic))
+	s.serverSignKey = md5Sum(sessionKey, []byte(serverSigningMagic))
+	if flags&ntlmNegotiateKeyExch != 0 {
+		sealKey := sessionKey
+		if flags&ntlmNegotiate128 == 0 {
+			if flags&ntlmNegotiate56 != 0 {
+				sealKey = sealKey[:7]
+			} else {
+				sealKey = sealKey[:5]
+			}
+		}
+		s.clientSeal, _ = rc4.NewCipher(md5Sum(sealKey, []byte(clientSealingMagic)))
+		s.serverSeal, _ = rc4.NewCipher(md5Sum(sealKey, []byte(serverSealingMagic)))
+	}
+	return
+}
+
+// ntlmMICPresent returns whether the client has computed the MIC of the authenticate message
+// as indicated by the flags in the target info of its NTLMv2 response.
+func ntlmMICPresent(blob []byte) bool {
+	if len(blob) < 28 {
+		return false
+	}
+	info := blob[28:]
+	for len(info) >= 4 {
+		id, length := le.Uint16(info), int(le.Uint16(info[2:]))
+		if id == avEOL || 4+length > len(info) {
+			break
+		}
+		if id == avFlags && length == 4 {
+			return le.Uint32(info[4:])&avFlagMICPresent != 0
+		}
+		info = info[4+length:]
+	}
+	return false
+}
+
+// verifyMIC verifies the signature of the client made on the data with the first sequence number,
+// which SPNEGO uses to protect the mechanism list.
+func (s *ntlmServer) verifyMIC(data, mic []byte) bool {
+	return bytes.Equal(ntlmMAC(s.clientSignKey, s.clientSeal, 0, data), mic)
+}
+
+// mic signs the data with the first sequence number of the server.
+func (s *ntlmServer) mic(data []byte) []byte {
+	return ntlmMAC(s.serverSignKey, s.serverSeal, 0, data)
+}
+
+// ntlmMAC computes the message signature with the extended session security.
+func ntlmMAC(signKey []byte, seal *rc4.Cipher, seq uint32, data []byte) []byte {
+	var seqNum [4]byte
+	le.PutUint32(seqNum[:], seq)
+	checksum := hmacMD5(signKey, seqNum[:], data)[:8]
+	sig := make([]byte, 16)
+	le.PutUint32(sig[0:], 1)
+	if seal != nil {
+		seal.XORKeyStream(sig[4:12], checksum)
+	} else {
+		copy(sig[4:12], checksum)
+	}
+	copy(sig[12:], seqNum[:])
+	return sig
+}
+
+// ntHash returns the NT hash of the password.
+func ntHash(password string) []byte {
+	sum := md4Sum(encodeUTF16(password))
+	return sum[:]
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	h := hmac.New(md5.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func md5Sum(data ...[]byte) []byte {
+	h := md5.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMD4(t *testing.T) {
+	// the test suite of RFC 1320
+	cases := map[string]string{
+		"":               "31d6cfe0d16ae931b73c59d7e0c089c0",
+		"a":              "bde52cb31de33e46245e05fbdbd6fb24",
+		"abc":            "a448017aaf21d8525fc10ae87aa6729d",
+		"message digest": "d9130a8164549fe818874806e1c7014b",
+		"12345678901234567890123456789012345678901234567890123456789012345678901234567890": "e33b4ddc9c38f2199c3e7b164fcc0536",
+	}
+	for data, expected := range cases {
+		sum := md4Sum([]byte(data))
+		if hex.EncodeToString(sum[:]) != expected {
+			t.Errorf("md4(%q) = %x, expected %v", data, sum, expected)
+		}
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNTLMv2Response(t *testing.T) {
+	// the NTLMv2 authentication example of MS-NLMP 4.2.4
+	hash := ntHash("Password")
+	if !bytes.Equal(hash, mustDecodeHex(t, "a4f49c406510bdcab6824ee7c30fd852")) {
+		t.Fatalf("nt hash %x", hash)
+	}
+	ntowf := hmacMD5(hash, encodeUTF16("USER"+"Domain"))
+	if !bytes.Equal(ntowf, mustDecodeHex(t, "0c868a403bfd7a93a3001ef22ef02e3f")) {
+		t.Fatalf("ntowfv2 %x", ntowf)
+	}
+	info := appendAVPair(nil, avNbDomainName, encodeUTF16("Domain"))
+	info = appendAVPair(info, avNbComputerName, encodeUTF16("Server"))
+	info = appendAVPair(info, avEOL, nil)
+	blob := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+	blob = append(blob, make([]byte, 8)...)
+	blob = append(blob, bytes.Repeat([]byte{0xaa}, 8)...)
+	blob = append(blob, make([]byte, 4)...)
+	blob = append(blob, info...)
+	blob = append(blob, make([]byte, 4)...)
+	proof := hmacMD5(ntowf, mustDecodeHex(t, "0123456789abcdef"), blob)
+	if !bytes.Equal(proof, mustDecodeHex(t, "68cd0ab851e51c96aabc927bebef6a1c")) {
+		t.Fatalf("nt proof %x", proof)
+	}
+	if key := hmacMD5(ntowf, proof); !bytes.Equal(key, mustDecodeHex(t, "8de40ccadbc14a82f15cb0ad0de95ca3")) {
+		t.Fatalf("session base key %x", key)
+	}
+}
+
+func TestSPNEGO(t *testing.T) {
+	mechTypes, mechToken, err := parseNegTokenInit(spnegoInitToken())
+	if err != nil || mechToken != nil || !hasMechanism(mechTypes, oidNTLMSSP) || hasMechanism(mechTypes, oidSPNEGO) {
+		t.Fatalf("init token: mechTypes(%x) mechToken(%x) err(%v)", mechTypes, mechToken, err)
+	}
+	long := bytes.Repeat([]byte{1}, 300)
+	responseToken, mic, err := parseNegTokenResp(negTokenResp(negAcceptIncomplete, oidNTLMSSP, long, []byte{2}))
+	if err != nil || !bytes.Equal(responseToken, long) || !bytes.Equal(mic, []byte{2}) {
+		t.Fatalf("response token(%x) mic(%x) err(%v)", responseToken, mic, err)
+	}
+	if _, _, err = parseNegTokenResp(negTokenResp(negAcceptIncomplete, nil, nil, nil)[:4]); err == nil {
+		t.Fatalf("truncated token is parsed")
+	}
+	if _, _, err = parseNegTokenInit([]byte{tagApplication, 0x84, 0xff, 0xff, 0xff, 0xff}); err == nil {
+		t.Fatalf("oversized token is parsed")
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration items that act on the SmbNode.
+const (
+	// String type configuration item, used to configure the port SMB is served on over TCP.
+	// Example:
+	//		{
+	//			"listen": "445"
+	//		}
+	configListen = proto.ListenPort
+
+	// String array configuration item, used to configure the hostname or IP address of the cluster master node.
+	// The SmbNode opens the shared volumes through the master.
+	// Example:
+	//		{
+	//			"masterAddr":[
+	//				"master1.chubao.io",
+	//				"master2.chubao.io",
+	//				"master3.chubao.io"
+	//			]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// String array configuration item, used to configure the authnodes the users are authenticated with.
+	// The secret key of a user registered on the authnode is taken as its password.
+	// Example:
+	//		{
+	//			"authNodes": ["authnode1.chubao.io:8080", "authnode2.chubao.io:8080"]
+	//		}
+	configAuthNodes = "authNodes"
+
+	// String type configuration items, used to configure the ID and the key the SmbNode gets the keys
+	// of the users from the authnode with, which must have the capability to get the keys.
+	configClientID  = "clientID"
+	configClientKey = "clientKey"
+
+	// Bool and string type configuration items, used to configure the HTTPS access to the authnodes.
+	configEnableHTTPS = "enableHTTPS"
+	configCertFile    = "certFile"
+
+	// String type configuration items, used to configure the NetBIOS domain and computer names
+	// presented to the clients during the authentication.
+	// Example:
+	//		{
+	//			"domain": "WORKGROUP",
+	//			"serverName": "CHUBAOFS"
+	//		}
+	configDomain     = "domain"
+	configServerName = "serverName"
+
+	// Object array configuration item, used to configure the shares.
+	// Example:
+	//		{
+	//			"shares": [
+	//				{"name": "data", "volume": "vol1", "users": ["alice", "bob"], "uid": 1000, "gid": 1000},
+	//				{"name": "public", "volume": "vol2", "readOnly": true}
+	//			]
+	//		}
+	configShares = "shares"
+)
+
+// Default of configuration value
+const (
+	defaultListen = "445"
+	defaultDomain = "WORKGROUP"
+)
+
+var (
+	// Regular expression used to verify the configuration of the service listening port.
+	// A valid service listening port configuration is a string containing only numbers.
+	regexpListen = regexp.MustCompile("^(\\d)+$")
+)
+
+// SmbNode serves the shared volumes to the SMB 2 clients such as Windows.
+type SmbNode struct {
+	listen    string
+	masters   []string
+	domain    string
+	computer  string
+	guid      [16]byte
+	startTime time.Time
+	users     userStore
+	shares    *shareManager
+	listener  net.Listener
+	conns     map[*smbConn]bool
+	connLock  sync.Mutex
+	wg        sync.WaitGroup
+
+	control common.Control
+}
+
+func NewServer() *SmbNode {
+	return &SmbNode{}
+}
+
+func (s *SmbNode) Start(cfg *config.Config) (err error) {
+	return s.control.Start(s, cfg, handleStart)
+}
+
+func (s *SmbNode) Shutdown() {
+	s.control.Shutdown(s, handleShutdown)
+}
+
+func (s *SmbNode) Sync() {
+	s.control.Sync()
+}
+
+func (s *SmbNode) loadConfig(cfg *config.Config) (err error) {
+	// parse listen
+	listen := cfg.GetString(configListen)
+	if len(listen) == 0 {
+		listen = defaultListen
+	}
+	if match := regexpListen.MatchString(listen); !match {
+		err = errors.New("invalid listen configuration")
+		return
+	}
+	s.listen = listen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
+
+	// parse master config
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	s.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+
+	// parse authnode config
+	authNodes := cfg.GetStringSlice(configAuthNodes)
+	if len(authNodes) == 0 {
+		return config.NewIllegalConfigError(configAuthNodes)
+	}
+	clientID, clientKey := cfg.GetString(configClientID), cfg.GetString(configClientKey)
+	if clientID == "" || clientKey == "" {
+		return config.NewIllegalConfigError(configClientKey)
+	}
+	enableHTTPS := cfg.GetBool(configEnableHTTPS)
+	s.users = newAuthnodeUserStore(authNodes, enableHTTPS, cfg.GetString(configCertFile), clientID, clientKey)
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configAuthNodes, strings.Join(authNodes, ","), configClientID, clientID)
+
+	// parse names
+	if s.domain = strings.ToUpper(cfg.GetString(configDomain)); s.domain == "" {
+		s.domain = defaultDomain
+	}
+	if s.computer = strings.ToUpper(cfg.GetString(configServerName)); s.computer == "" {
+		hostname, _ := os.Hostname()
+		s.computer = strings.ToUpper(strings.Split(hostname, ".")[0])
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configDomain, s.domain, configServerName, s.computer)
+
+	// parse shares
+	shares, err := parseShares(cfg.GetSlice(configShares))
+	if err != nil {
+		return
+	}
+	if len(shares) == 0 {
+		return config.NewIllegalConfigError(configShares)
+	}
+	s.shares = newShareManager(masters, shares)
+	for _, share := range shares {
+		log.LogInfof("loadConfig: setup share(%v) vol(%v) readOnly(%v)", share.name, share.volume, share.readOnly)
+	}
+	return
+}
+
+func handleStart(server common.Server, cfg *config.Config) (err error) {
+	s, ok := server.(*SmbNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = s.loadConfig(cfg); err != nil {
+		return
+	}
+	if _, err = rand.Read(s.guid[:]); err != nil {
+		return
+	}
+	s.startTime = time.Now()
+	s.conns = make(map[*smbConn]bool)
+
+	if s.listener, err = net.Listen("tcp", net.JoinHostPort("", s.listen)); err != nil {
+		log.LogErrorf("handleStart: listen on port(%v) err(%v)", s.listen, err)
+		return
+	}
+	s.wg.Add(1)
+	go s.serve()
+
+	exporter.Init(cfg.GetString("role"), cfg)
+
+	log.LogInfof("smb subsystem start success, served on port(%v)", s.listen)
+	return
+}
+
+func handleShutdown(server common.Server) {
+	s, ok := server.(*SmbNode)
+	if !ok {
+		return
+	}
+	s.listener.Close()
+	s.connLock.Lock()
+	for conn := range s.conns {
+		conn.conn.Close()
+	}
+	s.connLock.Unlock()
+	s.wg.Wait()
+	s.shares.close()
+}
+
+func (s *SmbNode) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			log.LogInfof("serve: stop accepting err(%v)", err)
+			return
+		}
+		c := newSMBConn(s, conn)
+		s.connLock.Lock()
+		s.conns[c] = true
+		s.connLock.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+			s.connLock.Lock()
+			delete(s.conns, c)
+			s.connLock.Unlock()
+		}()
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// smbShare is a share configured on the SmbNode, which serves a volume to the users allowed.
+type smbShare struct {
+	name     string
+	volume   string
+	readOnly bool
+	uid      uint32
+	gid      uint32
+	users    map[string]bool // empty for all the authenticated users
+}
+
+func (s *smbShare) allows(user string) bool {
+	return len(s.users) == 0 || s.users[user]
+}
+
+// parseShares parses the shares of the configuration, which is an array of objects such as
+// {"name": "data", "volume": "vol1", "readOnly": false, "users": ["alice"], "uid": 1000, "gid": 1000}.
+func parseShares(items []interface{}) (shares map[string]*smbShare, err error) {
+	shares = make(map[string]*smbShare)
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid share configuration")
+		}
+		share := &smbShare{users: make(map[string]bool)}
+		share.name, _ = m["name"].(string)
+		share.volume, _ = m["volume"].(string)
+		share.readOnly, _ = m["readOnly"].(bool)
+		if uid, ok := m["uid"].(float64); ok {
+			share.uid = uint32(uid)
+		}
+		if gid, ok := m["gid"].(float64); ok {
+			share.gid = uint32(gid)
+		}
+		if users, ok := m["users"].([]interface{}); ok {
+			for _, user := range users {
+				if name, ok := user.(string); ok {
+					share.users[name] = true
+				}
+			}
+		}
+		key := strings.ToLower(share.name)
+		if share.name == "" || share.volume == "" || strings.ContainsAny(share.name, "\\/$") {
+			return nil, fmt.Errorf("invalid share(%v) vol(%v)", share.name, share.volume)
+		}
+		if _, exist := shares[key]; exist {
+			return nil, fmt.Errorf("duplicate share(%v)", share.name)
+		}
+		shares[key] = share
+	}
+	return
+}
+
+// smbVolume is a shared volume opened through the meta and data SDKs.
+type smbVolume struct {
+	name  string
+	mw    *meta.MetaWrapper
+	ec    *stream.ExtentClient
+	files *fileTable
+}
+
+func openVolume(name string, masters []string) (vol *smbVolume, err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        name,
+		Masters:       masters,
+		ValidateOwner: false,
+	}
+	var mw *meta.MetaWrapper
+	if mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:             name,
+		Masters:            masters,
+		FollowerRead:       true,
+		OnAppendExtentKey:  mw.AppendExtentKey,
+		OnGetExtents:       mw.GetExtents,
+		OnTruncate:         mw.Truncate,
+		OnUpdateModifyTime: mw.UpdateModifyTime,
+	}
+	var ec *stream.ExtentClient
+	if ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		mw.Close()
+		return
+	}
+	vol = &smbVolume{name: name, mw: mw, ec: ec, files: newFileTable()}
+	return
+}
+
+func (v *smbVolume) close() {
+	v.ec.Close()
+	v.mw.Close()
+}
+
+// shareManager looks up the shares and opens the shared volumes on demand.
+type shareManager struct {
+	masters []string
+	shares  map[string]*smbShare // lower-cased share name -> share
+	volumes map[string]*smbVolume
+	mu      sync.Mutex
+}
+
+func newShareManager(masters []string, shares map[string]*smbShare) *shareManager {
+	return &shareManager{
+		masters: masters,
+		shares:  shares,
+		volumes: make(map[string]*smbVolume),
+	}
+}
+
+// share returns the share of the name, which is case insensitive.
+func (m *shareManager) share(name string) *smbShare {
+	return m.shares[strings.ToLower(name)]
+}
+
+func (m *shareManager) volume(name string) (vol *smbVolume, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if vol = m.volumes[name]; vol != nil {
+		return
+	}
+	if vol, err = openVolume(name, m.masters); err != nil {
+		log.LogErrorf("volume: open vol(%v) err(%v)", name, err)
+		return
+	}
+	m.volumes[name] = vol
+	log.LogInfof("volume: vol(%v) opened", name)
+	return
+}
+
+func (m *shareManager) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, vol := range m.volumes {
+		vol.close()
+		delete(m.volumes, name)
+	}
+}
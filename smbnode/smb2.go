@@ -0,0 +1,237 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (c *smbConn) negotiate(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 36 {
+		return statusInvalidParameter, nil
+	}
+	count := int(le.Uint16(b[2:]))
+	if count == 0 || len(b) < 36+2*count {
+		return statusInvalidParameter, nil
+	}
+	var dialect uint16
+	for i := 0; i < count; i++ {
+		switch d := le.Uint16(b[36+2*i:]); {
+		case d == dialect210:
+			dialect = d
+		case d == dialect202 && dialect == 0:
+			dialect = d
+		}
+	}
+	if dialect == 0 {
+		return statusNotSupported, nil
+	}
+	c.dialect = dialect
+	return statusSuccess, c.negotiateResponse(dialect)
+}
+
+// negotiateSMB1 answers the SMB1 negotiate of the clients starting with the multi-protocol negotiation,
+// the wildcard dialect makes the clients supporting SMB 2.1 send a SMB2 negotiate.
+func (c *smbConn) negotiateSMB1(frame []byte) (reply []byte, err error) {
+	if frame[4] != smb1Negotiate || c.dialect != 0 || len(frame) < smb1HeaderSize+3 {
+		return nil, errInvalidMessage
+	}
+	var dialect uint16
+	for _, d := range bytes.Split(frame[smb1HeaderSize+3:], []byte{0}) {
+		switch string(bytes.TrimPrefix(d, []byte{0x02})) {
+		case "SMB 2.???":
+			dialect = dialectWildcard
+		case "SMB 2.002":
+			if dialect == 0 {
+				dialect = dialect202
+			}
+		}
+	}
+	if dialect == 0 {
+		return nil, errInvalidMessage
+	}
+	c.dialect = dialect
+	req := &smbRequest{msg: make([]byte, smb2HeaderSize), command: smb2Negotiate}
+	reply = append(c.responseHeader(req, statusSuccess), c.negotiateResponse(dialect)...)
+	return
+}
+
+func (c *smbConn) negotiateResponse(dialect uint16) []byte {
+	token := spnegoInitToken()
+	body := make([]byte, 64, 64+len(token))
+	le.PutUint16(body[0:], 65)
+	le.PutUint16(body[2:], signingEnabled)
+	le.PutUint16(body[4:], dialect)
+	copy(body[8:24], c.node.guid[:])
+	le.PutUint32(body[28:], maxTransactSize)
+	le.PutUint32(body[32:], maxReadSize)
+	le.PutUint32(body[36:], maxWriteSize)
+	le.PutUint64(body[40:], filetime(time.Now()))
+	le.PutUint64(body[48:], filetime(c.node.startTime))
+	le.PutUint16(body[56:], smb2HeaderSize+64)
+	le.PutUint16(body[58:], uint16(len(token)))
+	return append(body, token...)
+}
+
+func (c *smbConn) echo(req *smbRequest) (ntStatus, []byte) {
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+func (c *smbConn) sessionSetup(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 24 {
+		return statusInvalidParameter, nil
+	}
+	securityMode := b[3]
+	token := buffer(req.msg, int(le.Uint16(b[12:])), int(le.Uint16(b[14:])))
+	if token == nil {
+		return statusInvalidParameter, nil
+	}
+
+	sess := c.sessions[req.sessionID]
+	if req.sessionID == 0 {
+		sess = &smbSession{id: c.nextID(), trees: make(map[uint32]*smbTree)}
+		c.sessions[sess.id] = sess
+		req.sessionID = sess.id
+	} else if sess == nil {
+		return statusUserSessionDeleted, nil
+	}
+	if sess.auth == nil {
+		// a valid session starts over to reauthenticate
+		sess.auth = newSessionAuth(c.node.domain, c.node.computer, c.node.users)
+	}
+
+	reply, done, err := sess.auth.step(token)
+	if err != nil {
+		log.LogWarnf("sessionSetup: client(%v) user(%v) err(%v)", c.conn.RemoteAddr(), sess.auth.ntlm.user, err)
+		sess.auth = nil
+		if !sess.valid {
+			delete(c.sessions, sess.id)
+		}
+		return statusLogonFailure, nil
+	}
+	status := statusMoreProcessingRequired
+	if done {
+		ntlm := sess.auth.ntlm
+		if sess.valid && sess.user != ntlm.user {
+			sess.auth = nil
+			return statusAccessDenied, nil
+		}
+		sess.user = ntlm.user
+		sess.signingKey = ntlm.sessionKey
+		sess.signRequired = securityMode&signingRequired != 0
+		sess.valid = true
+		sess.auth = nil
+		req.session = sess
+		status = statusSuccess
+		log.LogInfof("sessionSetup: client(%v) user(%v) logged on", c.conn.RemoteAddr(), sess.user)
+	}
+
+	body := make([]byte, 8, 8+len(reply))
+	le.PutUint16(body[0:], 9)
+	le.PutUint16(body[4:], smb2HeaderSize+8)
+	le.PutUint16(body[6:], uint16(len(reply)))
+	return status, append(body, reply...)
+}
+
+func (c *smbConn) logoff(req *smbRequest) (ntStatus, []byte) {
+	c.logoffSession(req.session)
+	delete(c.sessions, req.session.id)
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+func (c *smbConn) logoffSession(sess *smbSession) {
+	for _, tree := range sess.trees {
+		c.disconnectTree(tree)
+	}
+	sess.trees = make(map[uint32]*smbTree)
+	sess.valid = false
+}
+
+func (c *smbConn) treeConnect(req *smbRequest) (ntStatus, []byte) {
+	b := req.body
+	if len(b) < 8 {
+		return statusInvalidParameter, nil
+	}
+	path := buffer(req.msg, int(le.Uint16(b[4:])), int(le.Uint16(b[6:])))
+	if path == nil {
+		return statusInvalidParameter, nil
+	}
+	name := decodeUTF16(path)
+	if i := strings.LastIndex(name, "\\"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	tree := &smbTree{id: uint32(c.nextID()), opens: make(map[uint64]*smbOpen)}
+	shareType := byte(shareTypePipe)
+	access := uint32(fileReadAccess)
+	if !strings.EqualFold(name, "IPC$") {
+		share := c.node.shares.share(name)
+		if share == nil {
+			return statusBadNetworkName, nil
+		}
+		if !share.allows(req.session.user) {
+			log.LogWarnf("treeConnect: user(%v) is not allowed to share(%v)", req.session.user, share.name)
+			return statusAccessDenied, nil
+		}
+		vol, err := c.node.shares.volume(share.volume)
+		if err != nil {
+			return statusBadNetworkName, nil
+		}
+		tree.share = share
+		tree.vol = vol
+		shareType = shareTypeDisk
+		if !share.readOnly {
+			access = fileAllAccess
+		}
+	}
+	req.session.trees[tree.id] = tree
+	req.treeID = tree.id
+
+	body := make([]byte, 16)
+	le.PutUint16(body[0:], 16)
+	body[2] = shareType
+	le.PutUint32(body[4:], shareFlagNoCaching)
+	le.PutUint32(body[12:], access)
+	return statusSuccess, body
+}
+
+func (c *smbConn) treeDisconnect(req *smbRequest) (ntStatus, []byte) {
+	c.disconnectTree(req.tree)
+	delete(req.session.trees, req.tree.id)
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+func (c *smbConn) disconnectTree(tree *smbTree) {
+	for _, open := range tree.opens {
+		c.closeOpen(open)
+	}
+}
+
+func (c *smbConn) ioctl(req *smbRequest) (ntStatus, []byte) {
+	if len(req.body) < 56 {
+		return statusInvalidParameter, nil
+	}
+	switch le.Uint32(req.body[4:]) {
+	case fsctlDFSGetReferrals, fsctlDFSGetReferralsEx:
+		return statusFSDriverRequired, nil
+	}
+	return statusNotSupported, nil
+}
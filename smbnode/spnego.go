@@ -0,0 +1,201 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"errors"
+)
+
+// DER tags used by SPNEGO (RFC 4178)
+const (
+	tagOID         = 0x06
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagApplication = 0x60
+	tagNegTokInit  = 0xa0
+	tagNegTokResp  = 0xa1
+)
+
+// negotiation states of negTokenResp
+const (
+	negAcceptCompleted  = 0
+	negAcceptIncomplete = 1
+)
+
+var (
+	oidSPNEGO  = []byte{0x2b, 0x06, 0x01, 0x05, 0x05, 0x02}
+	oidNTLMSSP = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x0a}
+
+	errInvalidSPNEGO = errors.New("invalid SPNEGO token")
+	errNoMechanism   = errors.New("no supported security mechanism")
+)
+
+// derElement reads a DER element and returns its tag, contents, the whole element and the bytes following it.
+func derElement(b []byte) (tag byte, body, raw, rest []byte, err error) {
+	if len(b) < 2 {
+		err = errInvalidSPNEGO
+		return
+	}
+	tag = b[0]
+	length, n := int(b[1]), 2
+	if length&0x80 != 0 {
+		octets := length & 0x7f
+		if octets == 0 || octets > 4 || len(b) < 2+octets {
+			err = errInvalidSPNEGO
+			return
+		}
+		length = 0
+		for _, c := range b[2 : 2+octets] {
+			length = length<<8 | int(c)
+		}
+		n += octets
+	}
+	if length < 0 || n+length > len(b) {
+		err = errInvalidSPNEGO
+		return
+	}
+	return tag, b[n : n+length], b[:n+length], b[n+length:], nil
+}
+
+func derEncode(tag byte, contents ...[]byte) []byte {
+	length := 0
+	for _, c := range contents {
+		length += len(c)
+	}
+	b := []byte{tag}
+	switch {
+	case length < 0x80:
+		b = append(b, byte(length))
+	case length < 0x100:
+		b = append(b, 0x81, byte(length))
+	case length < 0x10000:
+		b = append(b, 0x82, byte(length>>8), byte(length))
+	default:
+		b = append(b, 0x83, byte(length>>16), byte(length>>8), byte(length))
+	}
+	for _, c := range contents {
+		b = append(b, c...)
+	}
+	return b
+}
+
+// spnegoInitToken returns the token of the negotiate response, which offers NTLMSSP as the only mechanism.
+func spnegoInitToken() []byte {
+	mechTypes := derEncode(tagSequence, derEncode(tagOID, oidNTLMSSP))
+	negTokenInit := derEncode(tagNegTokInit, derEncode(tagSequence, derEncode(0xa0, mechTypes)))
+	return derEncode(tagApplication, derEncode(tagOID, oidSPNEGO), negTokenInit)
+}
+
+// parseNegTokenInit returns the encoded mechanism list and the optimistic mechanism token of a negTokenInit.
+func parseNegTokenInit(token []byte) (mechTypes, mechToken []byte, err error) {
+	tag, body, _, _, err := derElement(token)
+	if err != nil || tag != tagApplication {
+		return nil, nil, errInvalidSPNEGO
+	}
+	tag, oid, _, body, err := derElement(body)
+	if err != nil || tag != tagOID || !bytes.Equal(oid, oidSPNEGO) {
+		return nil, nil, errInvalidSPNEGO
+	}
+	if tag, body, _, _, err = derElement(body); err != nil || tag != tagNegTokInit {
+		return nil, nil, errInvalidSPNEGO
+	}
+	if tag, body, _, _, err = derElement(body); err != nil || tag != tagSequence {
+		return nil, nil, errInvalidSPNEGO
+	}
+	for len(body) > 0 {
+		var field []byte
+		if tag, field, _, body, err = derElement(body); err != nil {
+			return
+		}
+		switch tag {
+		case 0xa0:
+			if _, _, mechTypes, _, err = derElement(field); err != nil {
+				return
+			}
+		case 0xa2:
+			if _, mechToken, _, _, err = derElement(field); err != nil {
+				return
+			}
+		}
+	}
+	if mechTypes == nil {
+		err = errInvalidSPNEGO
+	}
+	return
+}
+
+// hasMechanism returns whether the encoded mechanism list contains the mechanism.
+func hasMechanism(mechTypes, oid []byte) bool {
+	_, list, _, _, err := derElement(mechTypes)
+	if err != nil {
+		return false
+	}
+	for len(list) > 0 {
+		var tag byte
+		var mech []byte
+		if tag, mech, _, list, err = derElement(list); err != nil {
+			return false
+		}
+		if tag == tagOID && bytes.Equal(mech, oid) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNegTokenResp returns the response token and the mechanism list MIC of a negTokenResp.
+func parseNegTokenResp(token []byte) (responseToken, mechListMIC []byte, err error) {
+	tag, body, _, _, err := derElement(token)
+	if err != nil || tag != tagNegTokResp {
+		return nil, nil, errInvalidSPNEGO
+	}
+	if tag, body, _, _, err = derElement(body); err != nil || tag != tagSequence {
+		return nil, nil, errInvalidSPNEGO
+	}
+	for len(body) > 0 {
+		var field []byte
+		if tag, field, _, body, err = derElement(body); err != nil {
+			return
+		}
+		switch tag {
+		case 0xa2:
+			if _, responseToken, _, _, err = derElement(field); err != nil {
+				return
+			}
+		case 0xa3:
+			if _, mechListMIC, _, _, err = derElement(field); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// negTokenResp encodes a negTokenResp, the mechanism is given when it is selected by the response.
+func negTokenResp(state byte, mech, responseToken, mechListMIC []byte) []byte {
+	fields := [][]byte{derEncode(0xa0, derEncode(tagEnumerated, []byte{state}))}
+	if mech != nil {
+		fields = append(fields, derEncode(0xa1, derEncode(tagOID, mech)))
+	}
+	if responseToken != nil {
+		fields = append(fields, derEncode(0xa2, derEncode(tagOctetString, responseToken)))
+	}
+	if mechListMIC != nil {
+		fields = append(fields, derEncode(0xa3, derEncode(tagOctetString, mechListMIC)))
+	}
+	return derEncode(tagNegTokResp, derEncode(tagSequence, fields...))
+}
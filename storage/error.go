@@ -33,8 +33,15 @@ var (
 	ExtentIsFullError         = errors.New("extent is full")
 	BrokenExtentError         = errors.New("extent has been broken")
 	BrokenDiskError           = errors.New("disk has broken")
+	BlockCrcMismatchError     = errors.New("block crc mismatch")
+	NotPermittedError         = errors.New("operation not permitted")
 )
 
+func NewBlockCrcMismatchErr(msg string) (err error) {
+	err = fmt.Errorf("%v: %s", BlockCrcMismatchError, msg)
+	return
+}
+
 func NewParameterMismatchErr(msg string) (err error) {
 	err = fmt.Errorf("parameter mismatch error: %s", msg)
 	return
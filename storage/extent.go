@@ -277,6 +277,47 @@ func (e *Extent) ReadTiny(data []byte, offset, size int64, isRepairRead bool) (c
 	return
 }
 
+// VerifyBlockCrc verifies the data read from the extent against the crc of the blocks, which is only
+// recorded by the writes of whole blocks. The blocks partially covered by the read are read again as a whole.
+// It returns the number of bytes verified.
+func (e *Extent) VerifyBlockCrc(data []byte, offset, size int64) (verified int64, err error) {
+	if IsTinyExtent(e.extentID) {
+		return
+	}
+	var blockData []byte
+	for blockNo := offset / util.BlockSize; blockNo*util.BlockSize < offset+size; blockNo++ {
+		blockCrc := e.blockCrc(int(blockNo))
+		if blockCrc == 0 {
+			continue
+		}
+		var block []byte
+		blockOffset := blockNo * util.BlockSize
+		if blockOffset >= offset && blockOffset+util.BlockSize <= offset+size {
+			block = data[blockOffset-offset : blockOffset-offset+util.BlockSize]
+		} else {
+			if blockData == nil {
+				blockData = make([]byte, util.BlockSize)
+			}
+			if _, err = e.file.ReadAt(blockData, blockOffset); err != nil {
+				return
+			}
+			block = blockData
+		}
+		// the block may be overwritten during the read, which resets the crc
+		if crc := crc32.ChecksumIEEE(block); crc != blockCrc && e.blockCrc(int(blockNo)) == blockCrc {
+			err = NewBlockCrcMismatchErr(fmt.Sprintf("extent(%v) block(%v) expectCrc(%v) actualCrc(%v)",
+				e.extentID, blockNo, blockCrc, crc))
+			return
+		}
+		verified += util.BlockSize
+	}
+	return
+}
+
+func (e *Extent) blockCrc(blockNo int) uint32 {
+	return binary.BigEndian.Uint32(e.header[blockNo*util.PerBlockCrcSize : (blockNo+1)*util.PerBlockCrcSize])
+}
+
 func (e *Extent) checkOffsetAndSize(offset, size int64) error {
 	if offset+size > util.BlockSize*util.BlockCount {
 		return NewParameterMismatchErr(fmt.Sprintf("offset=%v size=%v", offset, size))
@@ -291,6 +332,21 @@ func (e *Extent) checkOffsetAndSize(offset, size int64) error {
 	return nil
 }
 
+// truncate removes all the data and the block crc of the extent.
+func (e *Extent) truncate() (err error) {
+	e.Lock()
+	defer e.Unlock()
+	if err = e.file.Truncate(0); err != nil {
+		return
+	}
+	for i := range e.header {
+		e.header[i] = 0
+	}
+	e.dataSize = 0
+	atomic.StoreInt64(&e.modifyTime, time.Now().Unix())
+	return
+}
+
 // Flush synchronizes data to the disk.
 func (e *Extent) Flush() (err error) {
 	err = e.file.Sync()
@@ -358,6 +358,52 @@ func (s *ExtentStore) Read(extentID uint64, offset, size int64, nbuf []byte, isR
 	return
 }
 
+// BlockFile returns the file of a normal extent and the crc recorded of the block at the offset, so that the block
+// can be sent from the file directly without being read. The crc is 0 if the block is not written as a whole or not
+// in the file, in which case the block must be read to get its crc.
+func (s *ExtentStore) BlockFile(extentID uint64, offset int64) (file *os.File, crc uint32, err error) {
+	var e *Extent
+	if IsTinyExtent(extentID) || offset%util.BlockSize != 0 {
+		err = NewParameterMismatchErr(fmt.Sprintf("extent(%v) offset(%v)", extentID, offset))
+		return
+	}
+	s.eiMutex.RLock()
+	ei := s.extentInfoMap[extentID]
+	s.eiMutex.RUnlock()
+	if e, err = s.extentWithHeader(ei); err != nil {
+		return
+	}
+	if err = s.checkOffsetAndSize(extentID, offset, util.BlockSize); err != nil {
+		return
+	}
+	if offset+util.BlockSize > e.Size() {
+		return e.file, 0, nil
+	}
+	return e.file, e.blockCrc(int(offset / util.BlockSize)), nil
+}
+
+// VerifyRead verifies the data read from the extent against the crc of its blocks.
+func (s *ExtentStore) VerifyRead(extentID uint64, offset, size int64, data []byte) (verified int64, err error) {
+	var e *Extent
+	if e, err = s.extentWithHeaderByExtentID(extentID); err != nil {
+		return
+	}
+	return e.VerifyBlockCrc(data, offset, size)
+}
+
+// BlockCrc returns the crc recorded of a block of a normal extent, which is 0 if the block is not written as a whole.
+func (s *ExtentStore) BlockCrc(extentID uint64, blockNo int) (crc uint32, err error) {
+	var e *Extent
+	if e, err = s.extentWithHeaderByExtentID(extentID); err != nil {
+		return
+	}
+	if IsTinyExtent(extentID) || blockNo < 0 || blockNo >= util.BlockCount {
+		err = NewParameterMismatchErr(fmt.Sprintf("extent(%v) block(%v)", extentID, blockNo))
+		return
+	}
+	return e.blockCrc(blockNo), nil
+}
+
 func (s *ExtentStore) tinyDelete(extentID uint64, offset, size int64) (err error) {
 	e, err := s.extentWithHeaderByExtentID(extentID)
 	if err != nil {
@@ -415,6 +461,32 @@ func (s *ExtentStore) MarkDelete(extentID uint64, offset, size int64) (err error
 	return
 }
 
+// Reset truncates the given normal extent to empty and clears the crc of its blocks, so that the extent can be
+// repaired from another replica from the beginning.
+func (s *ExtentStore) Reset(extentID uint64) (err error) {
+	var (
+		e  *Extent
+		ei *ExtentInfo
+	)
+	if IsTinyExtent(extentID) {
+		return ParameterMismatchError
+	}
+	s.eiMutex.RLock()
+	ei = s.extentInfoMap[extentID]
+	s.eiMutex.RUnlock()
+	if e, err = s.extentWithHeader(ei); err != nil {
+		return
+	}
+	if err = e.truncate(); err != nil {
+		return
+	}
+	if err = s.DeleteBlockCrc(extentID); err != nil {
+		return
+	}
+	ei.UpdateExtentInfo(e, 0)
+	return
+}
+
 func (s *ExtentStore) PutNormalExtentToDeleteCache(extentID uint64) {
 	s.hasDeleteNormalExtentsCache.Store(extentID, time.Now().Unix())
 }
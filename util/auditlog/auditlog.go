@@ -0,0 +1,222 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package auditlog records the audit events of the file operations to a configurable sink.
+package auditlog
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ConfigKeyAuditSink        = "auditSink"        // sink of the audit events, disabled if empty
+	ConfigKeyAuditFile        = "auditFile"        // path of the audit file
+	ConfigKeyAuditFileMaxSize = "auditFileMaxSize" // the audit file is rotated beyond the size, unit: MB
+	ConfigKeyAuditSyslogAddr  = "auditSyslogAddr"  // syslog server addr over udp, the local syslog is used if empty
+	ConfigKeyAuditKafkaURL    = "auditKafkaURL"    // url of the kafka rest proxy
+	ConfigKeyAuditKafkaTopic  = "auditKafkaTopic"  // kafka topic of the audit events
+	ConfigKeyAuditSampleRates = "auditSampleRates" // sample rates of the operations, such as "open:0.01,setattr:0.1"
+
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkKafka  = "kafka"
+
+	defaultFileMaxSize = 1024 // MB
+	queueSize          = 4096
+	batchSize          = 256
+	flushInterval      = time.Second
+)
+
+// Event is the audit record of a file operation.
+type Event struct {
+	Time     string `json:"time"`
+	Node     string `json:"node"`
+	Client   string `json:"client"`
+	Vol      string `json:"vol"`
+	Op       string `json:"op"`
+	ParentID uint64 `json:"pino,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Inode    uint64 `json:"ino,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Result   string `json:"result"`
+}
+
+// Sink is the destination the audit events are written to. The events are written by one goroutine
+// in batches.
+type Sink interface {
+	Write(events []*Event) error
+	Close() error
+}
+
+// Logger samples the audit events and writes them to the sink asynchronously. The events are dropped
+// if the sink falls behind, so that the file operations are never blocked by the audit log.
+type Logger struct {
+	node    string
+	sink    Sink
+	rates   map[string]float64
+	eventC  chan *Event
+	stopC   chan struct{}
+	doneC   chan struct{}
+	dropped uint64
+}
+
+// NewLogger creates the logger with the sink specified by the config. It returns nil if no sink is
+// configured.
+func NewLogger(node string, cfg *config.Config) (l *Logger, err error) {
+	var sink Sink
+	kind := cfg.GetString(ConfigKeyAuditSink)
+	switch kind {
+	case "":
+		return nil, nil
+	case SinkFile:
+		path := cfg.GetString(ConfigKeyAuditFile)
+		if path == "" {
+			return nil, fmt.Errorf("%v not set", ConfigKeyAuditFile)
+		}
+		maxSize := cfg.GetInt64(ConfigKeyAuditFileMaxSize)
+		if maxSize <= 0 {
+			maxSize = defaultFileMaxSize
+		}
+		sink, err = newFileSink(path, maxSize<<20)
+	case SinkSyslog:
+		sink, err = newSyslogSink(cfg.GetString(ConfigKeyAuditSyslogAddr))
+	case SinkKafka:
+		url, topic := cfg.GetString(ConfigKeyAuditKafkaURL), cfg.GetString(ConfigKeyAuditKafkaTopic)
+		if url == "" || topic == "" {
+			return nil, fmt.Errorf("%v or %v not set", ConfigKeyAuditKafkaURL, ConfigKeyAuditKafkaTopic)
+		}
+		sink = newKafkaSink(url, topic)
+	default:
+		return nil, fmt.Errorf("unknown %v: %v", ConfigKeyAuditSink, kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rates, err := parseSampleRates(cfg.GetString(ConfigKeyAuditSampleRates))
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	return newLogger(node, sink, rates), nil
+}
+
+func newLogger(node string, sink Sink, rates map[string]float64) *Logger {
+	l := &Logger{
+		node:   node,
+		sink:   sink,
+		rates:  rates,
+		eventC: make(chan *Event, queueSize),
+		stopC:  make(chan struct{}),
+		doneC:  make(chan struct{}),
+	}
+	go l.writeWorker()
+	return l
+}
+
+// parseSampleRates parses the sample rates in the form of "op:rate,op:rate". The operations not listed
+// are all recorded.
+func parseSampleRates(value string) (rates map[string]float64, err error) {
+	rates = make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid %v: %v", ConfigKeyAuditSampleRates, item)
+		}
+		var rate float64
+		if rate, err = strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid %v: %v", ConfigKeyAuditSampleRates, item)
+		}
+		rates[strings.TrimSpace(kv[0])] = rate
+	}
+	return
+}
+
+// Sampled returns if the operation is picked by its sample rate.
+func (l *Logger) Sampled(op string) bool {
+	rate, ok := l.rates[op]
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+// Log queues the event, which is dropped if the queue is full.
+func (l *Logger) Log(e *Event) {
+	e.Node = l.node
+	if e.Time == "" {
+		e.Time = time.Now().Format(time.RFC3339Nano)
+	}
+	select {
+	case l.eventC <- e:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+// Close writes the queued events and closes the sink.
+func (l *Logger) Close() {
+	close(l.stopC)
+	<-l.doneC
+	l.sink.Close()
+}
+
+func (l *Logger) writeWorker() {
+	defer close(l.doneC)
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	batch := make([]*Event, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := l.sink.Write(batch); err != nil {
+			log.LogWarnf("auditlog: write %v events failed: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case e := <-l.eventC:
+			if batch = append(batch, e); len(batch) >= batchSize {
+				flush()
+			}
+		case <-t.C:
+			flush()
+			if dropped := atomic.SwapUint64(&l.dropped, 0); dropped > 0 {
+				log.LogWarnf("auditlog: %v events dropped for the sink falls behind", dropped)
+			}
+		case <-l.stopC:
+			for {
+				select {
+				case e := <-l.eventC:
+					batch = append(batch, e)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
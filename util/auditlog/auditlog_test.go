@@ -0,0 +1,110 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSampleRates(t *testing.T) {
+	rates, err := parseSampleRates(" open:0.01, setattr:0 ")
+	if err != nil || len(rates) != 2 || rates["open"] != 0.01 || rates["setattr"] != 0 {
+		t.Fatalf("rates(%v) err(%v)", rates, err)
+	}
+	for _, value := range []string{"open", "open:x", "open:2"} {
+		if _, err = parseSampleRates(value); err == nil {
+			t.Fatalf("invalid rates %q are accepted", value)
+		}
+	}
+	l := &Logger{rates: rates}
+	if !l.Sampled("unlink") || l.Sampled("setattr") {
+		t.Fatalf("unexpected sampling")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	sink, err := newFileSink(path, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newLogger("192.168.0.1:17210", sink, nil)
+	l.Log(&Event{Client: "192.168.0.2:50000", Vol: "ltptest", Op: "unlink", ParentID: 1, Name: "a", Result: "OpOk"})
+	l.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatalf("no event is written")
+	}
+	e := new(Event)
+	if err = json.Unmarshal(scanner.Bytes(), e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Node != "192.168.0.1:17210" || e.Op != "unlink" || e.Name != "a" || e.Time == "" {
+		t.Fatalf("unexpected event: %v", e)
+	}
+
+	// the file is rotated once it grows beyond the max size
+	if sink, err = newFileSink(path, 150); err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Write([]*Event{{Op: "open", Inode: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+	if files, _ := filepath.Glob(path + ".*"); len(files) != 1 {
+		t.Fatalf("rotated files: %v", files)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var req kafkaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/audit" || r.Header.Get("Content-Type") != kafkaContentType {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+	}))
+	defer server.Close()
+
+	sink := newKafkaSink(server.URL+"/", "audit")
+	if err := sink.Write([]*Event{{Op: "rename"}, {Op: "chmod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Records) != 2 || req.Records[1].Value.Op != "chmod" {
+		t.Fatalf("unexpected records: %v", req.Records)
+	}
+	if err := newKafkaSink(server.URL, "other").Write([]*Event{{Op: "open"}}); err == nil {
+		t.Fatalf("expect error of unknown topic")
+	}
+}
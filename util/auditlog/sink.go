@@ -0,0 +1,175 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/syslog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	syslogTag            = "chubaofs-audit"
+	kafkaContentType     = "application/vnd.kafka.json.v2+json"
+	kafkaRequestTimeout  = 10 * time.Second
+	rotatedFileTimestamp = "20060102150405"
+)
+
+// fileSink appends the events to a local file in json lines, and renames the file with a timestamp
+// suffix once it grows beyond the max size.
+type fileSink struct {
+	path    string
+	maxSize int64
+	size    int64
+	file    *os.File
+}
+
+func newFileSink(path string, maxSize int64) (s *fileSink, err error) {
+	s = &fileSink{path: path, maxSize: maxSize}
+	if err = s.open(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (s *fileSink) open() (err error) {
+	if s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640); err != nil {
+		return
+	}
+	var info os.FileInfo
+	if info, err = s.file.Stat(); err != nil {
+		s.file.Close()
+		return
+	}
+	s.size = info.Size()
+	return
+}
+
+func (s *fileSink) rotate() (err error) {
+	s.file.Close()
+	if err = os.Rename(s.path, s.path+"."+time.Now().Format(rotatedFileTimestamp)); err != nil {
+		return
+	}
+	return s.open()
+}
+
+func (s *fileSink) Write(events []*Event) (err error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, e := range events {
+		if err = enc.Encode(e); err != nil {
+			return
+		}
+	}
+	if s.size > 0 && s.size+int64(buf.Len()) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return
+		}
+	}
+	n, err := s.file.Write(buf.Bytes())
+	s.size += int64(n)
+	return
+}
+
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// syslogSink sends every event as a json message to the syslog.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func newSyslogSink(addr string) (s *syslogSink, err error) {
+	var network string
+	if addr != "" {
+		network = "udp"
+	}
+	s = new(syslogSink)
+	if s.writer, err = syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_USER, syslogTag); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (s *syslogSink) Write(events []*Event) (err error) {
+	for _, e := range events {
+		var data []byte
+		if data, err = json.Marshal(e); err != nil {
+			return
+		}
+		if err = s.writer.Info(string(data)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
+
+// kafkaSink produces the events to a kafka topic through the kafka rest proxy, so that no kafka
+// client is required.
+type kafkaSink struct {
+	url    string
+	client *http.Client
+}
+
+type kafkaRecord struct {
+	Value *Event `json:"value"`
+}
+
+type kafkaRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+func newKafkaSink(url, topic string) *kafkaSink {
+	return &kafkaSink{
+		url:    strings.TrimSuffix(url, "/") + "/topics/" + topic,
+		client: &http.Client{Timeout: kafkaRequestTimeout},
+	}
+}
+
+func (s *kafkaSink) Write(events []*Event) (err error) {
+	req := &kafkaRequest{Records: make([]kafkaRecord, 0, len(events))}
+	for _, e := range events {
+		req.Records = append(req.Records, kafkaRecord{Value: e})
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	resp, err := s.client.Post(s.url, kafkaContentType, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("status(%v) body(%v)", resp.StatusCode, string(body))
+	}
+	return
+}
+
+func (s *kafkaSink) Close() error {
+	return nil
+}
@@ -21,7 +21,10 @@ import (
 	"log"
 	"os"
 	"path"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -60,23 +63,121 @@ func LoadConfigString(s string) *Config {
 	return result
 }
 
+// parse decodes the file as YAML if its extension is .yaml or .yml, and as JSON otherwise.
 func (c *Config) parse(fileName string) error {
-	jsonFileBytes, err := ioutil.ReadFile(fileName)
-	c.Raw = jsonFileBytes
-	if err == nil {
-		err = json.Unmarshal(jsonFileBytes, &c.data)
+	fileBytes, err := ioutil.ReadFile(fileName)
+	c.Raw = fileBytes
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".yaml", ".yml":
+		c.data, err = parseYAML(fileBytes)
+	default:
+		err = json.Unmarshal(fileBytes, &c.data)
 	}
 	return err
 }
 
+// OverrideByEnv sets the config keys by the environment variables with the prefix, such as
+// CFS_CONFIG_MASTER_ADDR for masterAddr. The name after the prefix matches the key present in the config
+// regardless of the case and the underscores, or is converted to the key in camel case if no key matches.
+// The values are kept as strings, which are converted by the getters. It returns the keys overridden in
+// sorted order.
+func (c *Config) OverrideByEnv(prefix string, environ []string) (keys []string) {
+	present := make(map[string]string, len(c.data))
+	for key := range c.data {
+		present[normalizeKey(key)] = key
+	}
+	for _, env := range environ {
+		idx := strings.Index(env, "=")
+		if idx < 0 || !strings.HasPrefix(env[:idx], prefix) || idx == len(prefix) {
+			continue
+		}
+		name, value := env[len(prefix):idx], env[idx+1:]
+		key, ok := present[normalizeKey(name)]
+		if !ok {
+			key = camelCaseKey(name)
+		}
+		c.data[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.Replace(key, "_", "", -1))
+}
+
+func camelCaseKey(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(strings.ToLower(name), "_") {
+		if word == "" {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString(word)
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// Get returns the value decoded from json for the config key, which is used to check the type of the value.
+func (c *Config) Get(key string) (value interface{}, present bool) {
+	value, present = c.data[key]
+	return
+}
+
+// DiffKeys returns the keys of which the values are different from the other config, in sorted order.
+func (c *Config) DiffKeys(other *Config) (keys []string) {
+	for key, value := range c.data {
+		if otherValue, present := other.data[key]; !present || !reflect.DeepEqual(value, otherValue) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range other.data {
+		if _, present := c.data[key]; !present {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+// CopyKeys returns a copy of the config with the values of the keys taken from the other config, the keys
+// absent from the other config are removed from the copy.
+func (c *Config) CopyKeys(other *Config, keys []string) *Config {
+	result := newConfig()
+	result.Raw = c.Raw
+	for key, value := range c.data {
+		result.data[key] = value
+	}
+	for _, key := range keys {
+		if value, present := other.data[key]; present {
+			result.data[key] = value
+		} else {
+			delete(result.data, key)
+		}
+	}
+	return result
+}
+
 // GetString returns a string for the config key.
 func (c *Config) GetString(key string) string {
 	x, present := c.data[key]
 	if !present {
 		return ""
 	}
-	if result, isString := x.(string); isString {
+	switch result := x.(type) {
+	case string:
 		return result
+	case float64:
+		return strconv.FormatFloat(result, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(result)
 	}
 	return ""
 }
@@ -90,6 +191,11 @@ func (c *Config) GetFloat(key string) float64 {
 	if result, isFloat := x.(float64); isFloat {
 		return result
 	}
+	if result, isString := x.(string); isString {
+		if r, err := strconv.ParseFloat(result, 64); err == nil {
+			return r
+		}
+	}
 	return 0
 }
 
@@ -152,12 +258,22 @@ func (c *Config) GetInt64(key string) int64 {
 	return 0
 }
 
-// GetSlice returns an array for the config key.
+// GetSlice returns an array for the config key, a string is split by commas such as the value of an
+// environment variable.
 func (c *Config) GetSlice(key string) []interface{} {
 	result, present := c.data[key]
-	if !present {
+	if !present || result == nil {
 		return []interface{}(nil)
 	}
+	if str, isString := result.(string); isString {
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(str, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
 	return result.([]interface{})
 }
 
@@ -0,0 +1,40 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCopyKeys(t *testing.T) {
+	current := LoadConfigString(`{"logLevel": "info", "logDir": "/cfs/log", "exporterPort": 9500, "warnLogDir": "/cfs/warn"}`)
+	reloaded := LoadConfigString(`{"logLevel": "debug", "logDir": "/cfs/log2", "consulAddr": "http://consul"}`)
+
+	// only the applied keys are advanced, the other changes stay pending
+	result := current.CopyKeys(reloaded, []string{"logLevel", "consulAddr", "warnLogDir"})
+	if keys := reloaded.DiffKeys(result); !reflect.DeepEqual(keys, []string{"exporterPort", "logDir"}) {
+		t.Fatalf("unexpected pending keys: %v", keys)
+	}
+	if result.GetString("logLevel") != "debug" || result.GetString("consulAddr") != "http://consul" {
+		t.Errorf("applied keys are not copied: %v", result.data)
+	}
+	if _, present := result.Get("warnLogDir"); present {
+		t.Errorf("key removed from the reloaded config should be removed")
+	}
+	if current.GetString("logLevel") != "info" {
+		t.Errorf("the original config should be kept")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The config files are flat maps of scalars and lists, so only the block mappings and sequences, the flow
+// sequences, the quoted and plain scalars and the comments of YAML are supported. The values are decoded
+// into the same types as from JSON, the numbers are float64.
+
+var yamlNumber = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+func parseYAML(data []byte) (result map[string]interface{}, err error) {
+	var lines []*yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("yaml line %v: tabs are not allowed for indentation", i+1)
+		}
+		indent := len(raw) - len(text)
+		if text = stripYAMLComment(text); text == "" || text == "---" {
+			continue
+		}
+		lines = append(lines, &yamlLine{num: i + 1, indent: indent, text: text})
+	}
+	result = make(map[string]interface{})
+	if len(lines) == 0 {
+		return
+	}
+	var (
+		value interface{}
+		next  int
+	)
+	if value, next, err = parseYAMLBlock(lines, 0, lines[0].indent); err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("yaml line %v: bad indentation", lines[next].num)
+	}
+	var ok bool
+	if result, ok = value.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("yaml: the document should be a mapping")
+	}
+	return
+}
+
+// stripYAMLComment removes the comment which starts with a '#' at the beginning or after a space, out of quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return text
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func parseYAMLBlock(lines []*yamlLine, i, indent int) (value interface{}, next int, err error) {
+	if isYAMLSequenceItem(lines[i].text) {
+		return parseYAMLSequence(lines, i, indent)
+	}
+	return parseYAMLMapping(lines, i, indent)
+}
+
+func parseYAMLMapping(lines []*yamlLine, i, indent int) (value interface{}, next int, err error) {
+	mapping := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent && !isYAMLSequenceItem(lines[i].text) {
+		line := lines[i]
+		key, rest, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, exist := mapping[key]; exist {
+			return nil, 0, fmt.Errorf("yaml line %v: duplicated key %v", line.num, key)
+		}
+		i++
+		if rest != "" {
+			if mapping[key], err = parseYAMLFlow(line.num, rest); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		// the value is a nested block, a sequence may be at the same indentation as the key
+		switch {
+		case i < len(lines) && lines[i].indent > indent:
+			mapping[key], i, err = parseYAMLBlock(lines, i, lines[i].indent)
+		case i < len(lines) && lines[i].indent == indent && isYAMLSequenceItem(lines[i].text):
+			mapping[key], i, err = parseYAMLSequence(lines, i, indent)
+		default:
+			mapping[key] = nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, 0, fmt.Errorf("yaml line %v: bad indentation", lines[i].num)
+	}
+	return mapping, i, nil
+}
+
+func parseYAMLSequence(lines []*yamlLine, i, indent int) (value interface{}, next int, err error) {
+	sequence := make([]interface{}, 0)
+	for i < len(lines) && lines[i].indent == indent && isYAMLSequenceItem(lines[i].text) {
+		line := lines[i]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		var item interface{}
+		switch {
+		case rest == "":
+			i++
+			if i < len(lines) && lines[i].indent > indent {
+				item, i, err = parseYAMLBlock(lines, i, lines[i].indent)
+			}
+		case isYAMLSequenceItem(rest) || isYAMLMappingEntry(rest):
+			// the item is a nested block which starts on the line of the dash
+			lines[i] = &yamlLine{num: line.num, indent: line.indent + len(line.text) - len(rest), text: rest}
+			item, i, err = parseYAMLBlock(lines, i, lines[i].indent)
+		default:
+			item, err = parseYAMLFlow(line.num, rest)
+			i++
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		sequence = append(sequence, item)
+	}
+	return sequence, i, nil
+}
+
+func isYAMLMappingEntry(text string) bool {
+	if text[0] == '[' || text[0] == '{' {
+		return false
+	}
+	_, _, err := splitYAMLKey(&yamlLine{text: text})
+	return err == nil
+}
+
+// splitYAMLKey splits the line of a mapping entry into the key and the rest after the colon.
+func splitYAMLKey(line *yamlLine) (key, rest string, err error) {
+	text := line.text
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", fmt.Errorf("yaml line %v: unterminated quote", line.num)
+		}
+		if key, err = unquoteYAML(text[:end+1]); err != nil {
+			return "", "", fmt.Errorf("yaml line %v: %v", line.num, err)
+		}
+		text = strings.TrimLeft(text[end+1:], " ")
+		if !strings.HasPrefix(text, ":") {
+			return "", "", fmt.Errorf("yaml line %v: expect a mapping entry", line.num)
+		}
+		return key, strings.TrimLeft(text[1:], " "), nil
+	}
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", fmt.Errorf("yaml line %v: expect a mapping entry", line.num)
+		}
+		idx = len(text) - 1
+	}
+	return strings.TrimRight(text[:idx], " "), strings.TrimLeft(text[idx+1:], " "), nil
+}
+
+// closingQuote returns the index of the quote which closes the quoted scalar at the beginning of the text.
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteYAML(text string) (string, error) {
+	if text[0] == '\'' {
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	}
+	return strconv.Unquote(text)
+}
+
+// parseYAMLFlow parses the value on the line of the key or the dash, which is a scalar or a flow sequence.
+func parseYAMLFlow(num int, text string) (value interface{}, err error) {
+	switch text[0] {
+	case '[':
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("yaml line %v: unterminated flow sequence", num)
+		}
+		sequence := make([]interface{}, 0)
+		body := strings.TrimSpace(text[1 : len(text)-1])
+		for body != "" {
+			var item string
+			if body[0] == '"' || body[0] == '\'' {
+				end := closingQuote(body)
+				if end < 0 {
+					return nil, fmt.Errorf("yaml line %v: unterminated quote", num)
+				}
+				item, body = body[:end+1], strings.TrimLeft(body[end+1:], " ")
+				if body != "" && body[0] != ',' {
+					return nil, fmt.Errorf("yaml line %v: expect a comma after %v", num, item)
+				}
+			} else if idx := strings.Index(body, ","); idx >= 0 {
+				item, body = strings.TrimSpace(body[:idx]), body[idx:]
+			} else {
+				item, body = body, ""
+			}
+			body = strings.TrimLeft(strings.TrimPrefix(body, ","), " ")
+			if item == "" {
+				return nil, fmt.Errorf("yaml line %v: empty item of flow sequence", num)
+			}
+			var v interface{}
+			if v, err = parseYAMLScalar(num, item); err != nil {
+				return
+			}
+			sequence = append(sequence, v)
+		}
+		return sequence, nil
+	case '{', '|', '>', '&', '*', '!':
+		return nil, fmt.Errorf("yaml line %v: %q is not supported", num, text[0])
+	}
+	return parseYAMLScalar(num, text)
+}
+
+func parseYAMLScalar(num int, text string) (value interface{}, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("yaml line %v: bad quoted scalar %v", num, text)
+		}
+		if value, err = unquoteYAML(text); err != nil {
+			return nil, fmt.Errorf("yaml line %v: %v", num, err)
+		}
+		return
+	}
+	switch text {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if yamlNumber.MatchString(text) {
+		return strconv.ParseFloat(text, 64)
+	}
+	return text, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		expect map[string]interface{}
+	}{
+		{
+			name:   "empty",
+			data:   "# only comments\n---\n",
+			expect: map[string]interface{}{},
+		},
+		{
+			name: "scalars",
+			data: "role: master\nport: 17010\nratio: 0.5\nenable: true\ndisable: False\nnothing: ~\nempty:\n",
+			expect: map[string]interface{}{
+				"role": "master", "port": float64(17010), "ratio": 0.5, "enable": true, "disable": false,
+				"nothing": nil, "empty": nil,
+			},
+		},
+		{
+			name: "comments",
+			data: "# header\nlogDir: /cfs/log # trailing\nurl: http://host/#anchor\nquoted: \"a # b\"\n",
+			expect: map[string]interface{}{
+				"logDir": "/cfs/log", "url": "http://host/#anchor", "quoted": "a # b",
+			},
+		},
+		{
+			name: "quoting",
+			data: "double: \"a\\tb: c\"\nsingle: 'it''s'\nnumber: \"17010\"\n'quoted key': yes\nescaped: \"\\\"x\\\"\"\n",
+			expect: map[string]interface{}{
+				"double": "a\tb: c", "single": "it's", "number": "17010", "quoted key": "yes", "escaped": `"x"`,
+			},
+		},
+		{
+			name: "nesting",
+			data: "rocksdb:\n  dir: /data\n  options:\n    cache: 128\n    sync: true\nport: 17010\n",
+			expect: map[string]interface{}{
+				"rocksdb": map[string]interface{}{
+					"dir":     "/data",
+					"options": map[string]interface{}{"cache": float64(128), "sync": true},
+				},
+				"port": float64(17010),
+			},
+		},
+		{
+			name: "block lists",
+			data: "masterAddr:\n  - 192.168.0.11:17010\n  - 192.168.0.12:17010\ndisks:\n- /data0:10737418240\n- \"/data1:0\"\n",
+			expect: map[string]interface{}{
+				"masterAddr": []interface{}{"192.168.0.11:17010", "192.168.0.12:17010"},
+				"disks":      []interface{}{"/data0:10737418240", "/data1:0"},
+			},
+		},
+		{
+			name: "flow lists",
+			data: "masterAddr: [192.168.0.11:17010, \"192.168.0.12:17010\" , 'a,b']\nempty: []\nnumbers: [1, 2.5]\n",
+			expect: map[string]interface{}{
+				"masterAddr": []interface{}{"192.168.0.11:17010", "192.168.0.12:17010", "a,b"},
+				"empty":      []interface{}{},
+				"numbers":    []interface{}{float64(1), 2.5},
+			},
+		},
+		{
+			name: "lists of mappings",
+			data: "peers:\n  - id: 1\n    addr: 192.168.0.11\n  -\n    id: 2\n    addr: 192.168.0.12\n  - - nested\n",
+			expect: map[string]interface{}{
+				"peers": []interface{}{
+					map[string]interface{}{"id": float64(1), "addr": "192.168.0.11"},
+					map[string]interface{}{"id": float64(2), "addr": "192.168.0.12"},
+					[]interface{}{"nested"},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		actual, err := parseYAML([]byte(c.data))
+		if err != nil {
+			t.Errorf("%v: parse failed: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.expect) {
+			t.Errorf("%v: expect %#v actual %#v", c.name, c.expect, actual)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "tab indentation", data: "a:\n\tb: 1\n", err: "line 2: tabs are not allowed"},
+		{name: "bad indentation", data: "a: 1\n  b: 2\n", err: "line 2: bad indentation"},
+		{name: "dedent below the document", data: "  a: 1\nb: 2\n", err: "line 2: bad indentation"},
+		{name: "duplicated key", data: "a: 1\na: 2\n", err: "line 2: duplicated key a"},
+		{name: "not a mapping entry", data: "a: 1\nplain\n", err: "line 2: expect a mapping entry"},
+		{name: "document is a list", data: "- a\n- b\n", err: "the document should be a mapping"},
+		{name: "unterminated quote", data: "a: \"b\n", err: "line 1: bad quoted scalar"},
+		{name: "unterminated quoted key", data: "\"a: b\n", err: "line 1: unterminated quote"},
+		{name: "bad escape", data: "a: \"\\q\"\n", err: "line 1:"},
+		{name: "unterminated flow sequence", data: "a: [1, 2\n", err: "line 1: unterminated flow sequence"},
+		{name: "empty flow item", data: "a: [1, , 2]\n", err: "line 1: empty item of flow sequence"},
+		{name: "missing comma", data: "a: [\"x\" \"y\"]\n", err: "line 1: expect a comma"},
+		{name: "flow mapping", data: "a: {b: 1}\n", err: "line 1: '{' is not supported"},
+		{name: "block scalar", data: "a: |\n  text\n", err: "line 1: '|' is not supported"},
+		{name: "anchor", data: "a: &x 1\n", err: "line 1: '&' is not supported"},
+	}
+	for _, c := range cases {
+		_, err := parseYAML([]byte(c.data))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%v: expect error %q actual %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestOverrideByEnv(t *testing.T) {
+	c := &Config{data: map[string]interface{}{"masterAddr": []interface{}{"a"}, "logLevel": "info"}}
+	keys := c.OverrideByEnv("CFS_CONFIG_", []string{
+		"CFS_CONFIG_MASTER_ADDR=192.168.0.11:17010,192.168.0.12:17010",
+		"CFS_CONFIG_LOGLEVEL=debug",
+		"CFS_CONFIG_WAL_DIR=/cfs/wal",
+		"CFS_CONFIG_=ignored",
+		"CFS_SRC_PATH=/go/src",
+		"PATH=/usr/bin",
+	})
+	if !reflect.DeepEqual(keys, []string{"logLevel", "masterAddr", "walDir"}) {
+		t.Fatalf("unexpected keys overridden: %v", keys)
+	}
+	if addrs := c.GetStringSlice("masterAddr"); !reflect.DeepEqual(addrs, []string{"192.168.0.11:17010", "192.168.0.12:17010"}) {
+		t.Errorf("unexpected masterAddr: %v", addrs)
+	}
+	if c.GetString("logLevel") != "debug" || c.GetString("walDir") != "/cfs/wal" {
+		t.Errorf("unexpected config: %v", c.data)
+	}
+	if _, present := c.Get("srcPath"); present {
+		t.Errorf("variable without the prefix should be ignored")
+	}
+}
@@ -46,7 +46,7 @@ func Warning(detail string) (a *Alarm) {
 	key := fmt.Sprintf("%v_%v_warning", clustername, modulename)
 	ump.Alarm(key, detail)
 	log.LogCritical(key, detail)
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	a = AlarmPool.Get().(*Alarm)
@@ -0,0 +1,118 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/config"
+)
+
+const (
+	ConfigKeyExporterBackend      = "exporterBackend"      // metrics backend
+	ConfigKeyExporterPushInterval = "exporterPushInterval" // push interval of the otlp backend, unit: second
+	ConfigKeyStatsdAddr           = "statsdAddr"           // statsd server addr
+	ConfigKeyOtlpEndpoint         = "otlpEndpoint"         // otlp/http metrics endpoint
+
+	BackendPrometheus = "prometheus"
+	BackendStatsd     = "statsd"
+	BackendOtlp       = "otlp"
+
+	defaultPushInterval = 10 * time.Second
+)
+
+// Backend is the metrics system which the collected metrics are exported to.
+// The metrics are reported by the collectors of one goroutine per metric type,
+// the implementations should be safe for concurrent use.
+type Backend interface {
+	AddCounter(name string, labels map[string]string, val float64)
+	SetGauge(name string, labels map[string]string, val float64)
+	DeleteGauge(name string, labels map[string]string)
+	Stop()
+}
+
+var (
+	backend     Backend
+	backendLock sync.RWMutex
+)
+
+// ReloadableKeys are the config keys applied by Reload.
+var ReloadableKeys = []string{ConfigKeyExporterBackend, ConfigKeyStatsdAddr, ConfigKeyOtlpEndpoint, ConfigKeyExporterPushInterval}
+
+func currentBackend() Backend {
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+	return backend
+}
+
+func setBackend(b Backend) {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+	backend = b
+}
+
+// Reload replaces the push backend by the one of the config reloaded. The prometheus backend is
+// served on the exporter port, so switching from or to it requires a restart.
+func Reload(cfg *config.Config) (err error) {
+	if !enabled {
+		return fmt.Errorf("exporter is not enabled")
+	}
+	old := currentBackend()
+	kind := cfg.GetString(ConfigKeyExporterBackend)
+	_, wasProm := old.(*promBackend)
+	isProm := kind == "" || kind == BackendPrometheus
+	if wasProm && isProm {
+		return nil
+	}
+	if wasProm || isProm {
+		return fmt.Errorf("switching from or to the prometheus backend requires restart")
+	}
+	var b Backend
+	if b, err = newBackend(modulename, cfg); err != nil {
+		return
+	}
+	setBackend(b)
+	old.Stop()
+	return nil
+}
+
+// newBackend creates the backend specified by the config, prometheus is used by default.
+func newBackend(role string, cfg *config.Config) (b Backend, err error) {
+	kind := cfg.GetString(ConfigKeyExporterBackend)
+	switch kind {
+	case "", BackendPrometheus:
+		return newPromBackend(), nil
+	case BackendStatsd:
+		addr := cfg.GetString(ConfigKeyStatsdAddr)
+		if addr == "" {
+			return nil, fmt.Errorf("%v not set", ConfigKeyStatsdAddr)
+		}
+		return newStatsdBackend(addr)
+	case BackendOtlp:
+		endpoint := cfg.GetString(ConfigKeyOtlpEndpoint)
+		if endpoint == "" {
+			return nil, fmt.Errorf("%v not set", ConfigKeyOtlpEndpoint)
+		}
+		interval := defaultPushInterval
+		if sec := cfg.GetInt64(ConfigKeyExporterPushInterval); sec > 0 {
+			interval = time.Duration(sec) * time.Second
+		}
+		return newOtlpBackend(endpoint, AppName+"_"+role, interval), nil
+	default:
+		return nil, fmt.Errorf("unknown %v: %v", ConfigKeyExporterBackend, kind)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exporter
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/config"
+)
+
+func TestStatsdBackend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	b, err := newStatsdBackend(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddCounter("cfs_datanode_read_count", map[string]string{"vol": "ltptest", "disk": "/data0", "addr": "192.168.0.1:17310"}, 3)
+	b.SetGauge("cfs_datanode_start_time", nil, 1.5)
+	b.Stop()
+
+	buf := make([]byte, statsdMaxPacketSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "cfs_datanode_read_count:3|c|#addr:192.168.0.1:17310,disk:/data0,vol:ltptest\ncfs_datanode_start_time:1.5|g\n"
+	if got := string(buf[:n]); got != expected {
+		t.Fatalf("unexpected statsd packet: %q", got)
+	}
+}
+
+func TestOtlpBackend(t *testing.T) {
+	b := &otlpBackend{service: "cfs_metanode", points: make(map[string]*otlpPoint)}
+	b.AddCounter("op_count", map[string]string{"op": "create"}, 1)
+	b.AddCounter("op_count", map[string]string{"op": "create"}, 2)
+	b.AddCounter("op_count", map[string]string{"op": "unlink"}, 1)
+	b.SetGauge("inode_count", nil, 10)
+	b.SetGauge("inode_count", nil, 20)
+	b.SetGauge("dentry_count", nil, 5)
+	b.DeleteGauge("dentry_count", nil)
+
+	req := b.buildRequest(time.Now().UnixNano())
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 2 {
+		t.Fatalf("expect 2 metrics, got %v", len(metrics))
+	}
+	gauge, sum := metrics[0], metrics[1]
+	if gauge.Name != "inode_count" || gauge.Gauge == nil || gauge.Gauge.DataPoints[0].AsDouble != 20 {
+		t.Fatalf("unexpected gauge: %+v", gauge)
+	}
+	if sum.Name != "op_count" || sum.Sum == nil || !sum.Sum.IsMonotonic || len(sum.Sum.DataPoints) != 2 {
+		t.Fatalf("unexpected sum: %+v", sum)
+	}
+	for _, dp := range sum.Sum.DataPoints {
+		if op := dp.Attributes[0].Value.StringValue; (op == "create" && dp.AsDouble != 3) || (op == "unlink" && dp.AsDouble != 1) {
+			t.Fatalf("unexpected data point of %v: %v", op, dp.AsDouble)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	defer func(e bool, b Backend) {
+		enabled = e
+		setBackend(b)
+	}(enabled, currentBackend())
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	enabled = true
+	setBackend(newPromBackend())
+	if err = Reload(config.LoadConfigString(`{"exporterBackend": "statsd", "statsdAddr": "` + conn.LocalAddr().String() + `"}`)); err == nil {
+		t.Fatalf("switching from the prometheus backend should fail")
+	}
+
+	old := &otlpBackend{service: "cfs_metanode", points: make(map[string]*otlpPoint), stopC: make(chan struct{})}
+	setBackend(old)
+	if err = Reload(config.LoadConfigString(`{"exporterBackend": "statsd"}`)); err == nil {
+		t.Fatalf("reload without statsdAddr should fail")
+	}
+	if currentBackend() != old {
+		t.Fatalf("the backend should be kept if the reload fails")
+	}
+	if err = Reload(config.LoadConfigString(`{"exporterBackend": "statsd", "statsdAddr": "` + conn.LocalAddr().String() + `"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := currentBackend().(*statsdBackend); !ok {
+		t.Fatalf("unexpected backend after reload: %T", currentBackend())
+	}
+	currentBackend().Stop()
+}
@@ -16,14 +16,10 @@ package exporter
 
 import (
 	"sync"
-
-	"github.com/chubaofs/chubaofs/util/log"
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
-	CounterGroup sync.Map
-	CounterPool  = &sync.Pool{New: func() interface{} {
+	CounterPool = &sync.Pool{New: func() interface{} {
 		return new(Counter)
 	}}
 	CounterCh chan *Counter
@@ -33,8 +29,7 @@ func collectCounter() {
 	CounterCh = make(chan *Counter, ChSize)
 	for {
 		m := <-CounterCh
-		metric := m.Metric()
-		metric.Add(float64(m.val))
+		currentBackend().AddCounter(m.name, m.labels, m.val)
 	}
 }
 
@@ -43,7 +38,7 @@ type Counter struct {
 }
 
 func NewCounter(name string) (c *Counter) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	c = new(Counter)
@@ -52,7 +47,7 @@ func NewCounter(name string) (c *Counter) {
 }
 
 func (c *Counter) Add(val int64) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	c.val = float64(val)
@@ -67,27 +62,9 @@ func (c *Counter) publish() {
 }
 
 func (c *Counter) AddWithLabels(val int64, labels map[string]string) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	c.labels = labels
 	c.Add(val)
 }
-
-func (c *Counter) Metric() prometheus.Counter {
-	metric := prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name:        c.name,
-			ConstLabels: c.labels,
-		})
-	key := c.Key()
-	actualMetric, load := CounterGroup.LoadOrStore(key, metric)
-	if !load {
-		err := prometheus.Register(actualMetric.(prometheus.Collector))
-		if err == nil {
-			log.LogInfo("register metric ", c.name)
-		}
-	}
-
-	return actualMetric.(prometheus.Counter)
-}
@@ -24,8 +24,6 @@ import (
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/log"
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -38,12 +36,12 @@ const (
 )
 
 var (
-	namespace         string
-	clustername       string
-	modulename        string
-	exporterPort      int64
-	enabledPrometheus = false
-	replacer          = strings.NewReplacer("-", "_", ".", "_", " ", "_", ",", "_", ":", "_")
+	namespace    string
+	clustername  string
+	modulename   string
+	exporterPort int64
+	enabled      = false
+	replacer     = strings.NewReplacer("-", "_", ".", "_", " ", "_", ",", "_", ":", "_")
 )
 
 func metricsName(name string) string {
@@ -57,31 +55,38 @@ func Init(role string, cfg *config.Config) {
 		log.LogInfof("%v exporter disabled", role)
 		return
 	}
-	port := cfg.GetInt64(ConfigKeyExporterPort)
-	if port == 0 {
-		log.LogInfof("%v exporter port not set", port)
+	b, err := newBackend(role, cfg)
+	if err != nil {
+		log.LogErrorf("%v exporter backend error: %v", role, err)
 		return
 	}
-	exporterPort = port
-	enabledPrometheus = true
-	http.Handle(PromHandlerPattern, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
-		Timeout: 5 * time.Second,
-	}))
 	namespace = AppName + "_" + role
-	addr := fmt.Sprintf(":%d", port)
-	go func() {
-		err := http.ListenAndServe(addr, nil)
-		if err != nil {
-			log.LogError("exporter http serve error: ", err)
+	if _, ok := b.(*promBackend); ok {
+		port := cfg.GetInt64(ConfigKeyExporterPort)
+		if port == 0 {
+			log.LogInfof("%v exporter port not set", port)
+			return
 		}
-	}()
+		exporterPort = port
+		http.Handle(PromHandlerPattern, promHandler())
+		addr := fmt.Sprintf(":%d", port)
+		go func() {
+			err := http.ListenAndServe(addr, nil)
+			if err != nil {
+				log.LogError("exporter http serve error: ", err)
+			}
+		}()
+	}
+	setBackend(b)
+	enabled = true
 
 	collect()
+	registerLogStormMetrics()
 
 	m := NewGauge("start_time")
 	m.Set(float64(time.Now().Unix() * 1000))
 
-	log.LogInfof("exporter Start: %v", addr)
+	log.LogInfof("exporter Start: %v %v", cfg.GetString(ConfigKeyExporterBackend), exporterPort)
 }
 
 // Init initializes the exporter.
@@ -91,22 +96,29 @@ func InitWithRouter(role string, cfg *config.Config, router *mux.Router, exPort
 		log.LogInfof("%v metrics exporter disabled", role)
 		return
 	}
-	exporterPort, _ = strconv.ParseInt(exPort, 10, 64)
-	enabledPrometheus = true
-	router.NewRoute().Name("metrics").
-		Methods(http.MethodGet).
-		Path(PromHandlerPattern).
-		Handler(promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
-			Timeout: 5 * time.Second,
-		}))
+	b, err := newBackend(role, cfg)
+	if err != nil {
+		log.LogErrorf("%v exporter backend error: %v", role, err)
+		return
+	}
+	if _, ok := b.(*promBackend); ok {
+		exporterPort, _ = strconv.ParseInt(exPort, 10, 64)
+		router.NewRoute().Name("metrics").
+			Methods(http.MethodGet).
+			Path(PromHandlerPattern).
+			Handler(promHandler())
+	}
+	setBackend(b)
+	enabled = true
 	namespace = AppName + "_" + role
 
 	collect()
+	registerLogStormMetrics()
 
 	m := NewGauge("start_time")
 	m.Set(float64(time.Now().Unix() * 1000))
 
-	log.LogInfof("exporter Start: %v %v", exporterPort, m)
+	log.LogInfof("exporter Start: %v %v %v", cfg.GetString(ConfigKeyExporterBackend), exporterPort, m)
 }
 
 func RegistConsul(cluster string, role string, cfg *config.Config) {
@@ -125,7 +137,7 @@ func RegistConsul(cluster string, role string, cfg *config.Config) {
 }
 
 func collect() {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	go collectCounter()
@@ -19,7 +19,6 @@ import (
 	"sync"
 
 	"github.com/chubaofs/chubaofs/util/log"
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
@@ -27,16 +26,14 @@ var (
 		return new(Gauge)
 	}}
 
-	GaugeGroup sync.Map
-	GaugeCh    chan *Gauge
+	GaugeCh chan *Gauge
 )
 
 func collectGauge() {
 	GaugeCh = make(chan *Gauge, ChSize)
 	for {
 		m := <-GaugeCh
-		metric := m.Metric()
-		metric.Set(m.val)
+		currentBackend().SetGauge(m.name, m.labels, m.val)
 		log.LogDebugf("collect metric %v", m)
 	}
 }
@@ -49,7 +46,7 @@ type Gauge struct {
 }
 
 func NewGauge(name string) (g *Gauge) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	g = new(Gauge)
@@ -69,28 +66,8 @@ func (g *Gauge) String() string {
 	return fmt.Sprintf("{name: %s, labels: %s, val: %v}", g.name, stringMapToString(g.labels), g.val)
 }
 
-func (c *Gauge) Metric() prometheus.Gauge {
-	metric := prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name:        c.name,
-			ConstLabels: c.labels,
-		})
-	key := c.Key()
-	actualMetric, load := GaugeGroup.LoadOrStore(key, metric)
-	if !load {
-		err := prometheus.Register(actualMetric.(prometheus.Collector))
-		if err == nil {
-			log.LogInfof("register metric %v", c.Name())
-		} else {
-			log.LogErrorf("register metric %v, %v", c.Name(), err)
-		}
-	}
-
-	return actualMetric.(prometheus.Gauge)
-}
-
 func (g *Gauge) Set(val float64) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	g.val = val
@@ -105,39 +82,57 @@ func (c *Gauge) publish() {
 }
 
 func (g *Gauge) SetWithLabels(val float64, labels map[string]string) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	g.labels = labels
 	g.Set(val)
 }
 
+// GaugeVec is a group of gauges with the same name and label names.
 type GaugeVec struct {
-	*prometheus.GaugeVec
+	name   string
+	labels []string
 }
 
+// NewGaugeVec returns a new gauge vector, the help is not exported by the backends.
 func NewGaugeVec(name, help string, labels []string) *GaugeVec {
-	if !enabledPrometheus {
+	if !enabled {
 		return nil
 	}
-	v := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: metricsName(name),
-			Help: help,
-		},
-		labels,
-	)
-
-	if err := prometheus.Register(v); err != nil {
-		log.LogErrorf("prometheus register gaugevec name:%v, labels:{%v} error: %v", name, labels, err)
-		return nil
+	return &GaugeVec{
+		name:   metricsName(name),
+		labels: labels,
 	}
+}
 
-	return &GaugeVec{GaugeVec: v}
+func (v *GaugeVec) labelsWithValues(lvs []string) (labels map[string]string, err error) {
+	if len(lvs) != len(v.labels) {
+		return nil, fmt.Errorf("gaugevec %v expects %v label values, got %v", v.name, len(v.labels), len(lvs))
+	}
+	labels = make(map[string]string, len(lvs))
+	for i, name := range v.labels {
+		labels[name] = lvs[i]
+	}
+	return
 }
 
 func (v *GaugeVec) SetWithLabelValues(val float64, lvs ...string) {
-	if m, err := v.GetMetricWithLabelValues(lvs...); err == nil {
-		m.Set(val)
+	if v == nil {
+		return
+	}
+	if labels, err := v.labelsWithValues(lvs); err == nil {
+		currentBackend().SetGauge(v.name, labels, val)
+	} else {
+		log.LogError(err.Error())
+	}
+}
+
+func (v *GaugeVec) DeleteLabelValues(lvs ...string) {
+	if v == nil {
+		return
+	}
+	if labels, err := v.labelsWithValues(lvs); err == nil {
+		currentBackend().DeleteGauge(v.name, labels)
 	}
 }
@@ -0,0 +1,40 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exporter
+
+import (
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	MetricLogSuppressed = "log_suppressed"
+	MetricLogStorm      = "log_storm"
+)
+
+// registerLogStormMetrics exports the lines suppressed by the log storm guard of util/log,
+// and whether a storm of each level is going on.
+func registerLogStormMetrics() {
+	log.SetStormHook(func(level string, suppressed int64, active bool) {
+		var labels = map[string]string{"level": level}
+		if suppressed > 0 {
+			NewCounter(MetricLogSuppressed).AddWithLabels(suppressed, labels)
+		}
+		var value float64
+		if active {
+			value = 1
+		}
+		NewGauge(MetricLogStorm).SetWithLabels(value, labels)
+	})
+}
@@ -0,0 +1,245 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	otlpScopeName             = "github.com/chubaofs/chubaofs/util/exporter"
+	otlpTemporalityCumulative = 2
+	otlpContentTypeJSON       = "application/json"
+	otlpAttributeServiceName  = "service.name"
+	otlpAttributeCluster      = "cfs.cluster"
+)
+
+// otlpBackend pushes the metrics to an OpenTelemetry collector periodically
+// with the JSON encoding of OTLP/HTTP. Counters are exported as cumulative
+// monotonic sums and gauges as gauges.
+type otlpBackend struct {
+	endpoint  string
+	service   string
+	interval  time.Duration
+	client    *http.Client
+	startTime int64
+	points    map[string]*otlpPoint
+	mu        sync.Mutex
+	stopC     chan struct{}
+}
+
+type otlpPoint struct {
+	name    string
+	labels  map[string]string
+	val     float64
+	counter bool
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+type otlpSum struct {
+	DataPoints             []*otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int              `json:"aggregationTemporality"`
+	IsMonotonic            bool             `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []*otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Metrics []*otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeMetrics []*otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpRequest struct {
+	ResourceMetrics []*otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+func newOtlpBackend(endpoint, service string, interval time.Duration) *otlpBackend {
+	b := &otlpBackend{
+		endpoint:  endpoint,
+		service:   service,
+		interval:  interval,
+		client:    &http.Client{Timeout: interval},
+		startTime: time.Now().UnixNano(),
+		points:    make(map[string]*otlpPoint),
+		stopC:     make(chan struct{}),
+	}
+	go b.pushWorker()
+	return b
+}
+
+func (b *otlpBackend) AddCounter(name string, labels map[string]string, val float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := metricKey(name, labels)
+	p, ok := b.points[key]
+	if !ok {
+		p = &otlpPoint{name: name, labels: labels, counter: true}
+		b.points[key] = p
+	}
+	p.val += val
+}
+
+func (b *otlpBackend) SetGauge(name string, labels map[string]string, val float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := metricKey(name, labels)
+	p, ok := b.points[key]
+	if !ok {
+		p = &otlpPoint{name: name, labels: labels}
+		b.points[key] = p
+	}
+	p.val = val
+}
+
+func (b *otlpBackend) DeleteGauge(name string, labels map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.points, metricKey(name, labels))
+}
+
+func (b *otlpBackend) Stop() {
+	close(b.stopC)
+}
+
+func otlpAttributes(labels map[string]string) (attrs []otlpKeyValue) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		kv := otlpKeyValue{Key: k}
+		kv.Value.StringValue = labels[k]
+		attrs = append(attrs, kv)
+	}
+	return
+}
+
+// buildRequest groups the data points by the metric name.
+func (b *otlpBackend) buildRequest(now int64) *otlpRequest {
+	start, ts := strconv.FormatInt(b.startTime, 10), strconv.FormatInt(now, 10)
+	metrics := make(map[string]*otlpMetric)
+	names := make([]string, 0)
+	b.mu.Lock()
+	for _, p := range b.points {
+		m, ok := metrics[p.name]
+		if !ok {
+			m = &otlpMetric{Name: p.name}
+			if p.counter {
+				m.Sum = &otlpSum{AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true}
+			} else {
+				m.Gauge = &otlpGauge{}
+			}
+			metrics[p.name] = m
+			names = append(names, p.name)
+		}
+		dp := &otlpDataPoint{
+			Attributes:        otlpAttributes(p.labels),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      ts,
+			AsDouble:          p.val,
+		}
+		if m.Sum != nil {
+			m.Sum.DataPoints = append(m.Sum.DataPoints, dp)
+		} else {
+			m.Gauge.DataPoints = append(m.Gauge.DataPoints, dp)
+		}
+	}
+	b.mu.Unlock()
+	sort.Strings(names)
+
+	resource := map[string]string{otlpAttributeServiceName: b.service}
+	if clustername != "" {
+		resource[otlpAttributeCluster] = clustername
+	}
+	sm := &otlpScopeMetrics{}
+	sm.Scope.Name = otlpScopeName
+	for _, name := range names {
+		sm.Metrics = append(sm.Metrics, metrics[name])
+	}
+	rm := &otlpResourceMetrics{ScopeMetrics: []*otlpScopeMetrics{sm}}
+	rm.Resource.Attributes = otlpAttributes(resource)
+	return &otlpRequest{ResourceMetrics: []*otlpResourceMetrics{rm}}
+}
+
+func (b *otlpBackend) push() (err error) {
+	var body []byte
+	if body, err = json.Marshal(b.buildRequest(time.Now().UnixNano())); err != nil {
+		return
+	}
+	resp, err := b.client.Post(b.endpoint, otlpContentTypeJSON, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+	return
+}
+
+func (b *otlpBackend) pushWorker() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopC:
+			return
+		case <-ticker.C:
+			if err := b.push(); err != nil {
+				log.LogWarnf("push metrics to %v error: %v", b.endpoint, err)
+			}
+		}
+	}
+}
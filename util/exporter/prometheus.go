@@ -0,0 +1,90 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// promBackend registers the metrics to the default prometheus registry,
+// which is scraped from the handler of PromHandlerPattern.
+type promBackend struct {
+	counters sync.Map
+	gauges   sync.Map
+}
+
+func newPromBackend() *promBackend {
+	return &promBackend{}
+}
+
+func promHandler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		Timeout: 5 * time.Second,
+	})
+}
+
+func metricKey(name string, labels map[string]string) string {
+	return stringMD5(fmt.Sprintf("{%s: %s}", name, stringMapToString(labels)))
+}
+
+func (b *promBackend) AddCounter(name string, labels map[string]string, val float64) {
+	metric := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name:        name,
+			ConstLabels: labels,
+		})
+	actualMetric, load := b.counters.LoadOrStore(metricKey(name, labels), metric)
+	if !load {
+		err := prometheus.Register(actualMetric.(prometheus.Collector))
+		if err == nil {
+			log.LogInfo("register metric ", name)
+		}
+	}
+	actualMetric.(prometheus.Counter).Add(val)
+}
+
+func (b *promBackend) SetGauge(name string, labels map[string]string, val float64) {
+	metric := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        name,
+			ConstLabels: labels,
+		})
+	actualMetric, load := b.gauges.LoadOrStore(metricKey(name, labels), metric)
+	if !load {
+		err := prometheus.Register(actualMetric.(prometheus.Collector))
+		if err == nil {
+			log.LogInfof("register metric %v %v", name, stringMapToString(labels))
+		} else {
+			log.LogErrorf("register metric %v %v, %v", name, stringMapToString(labels), err)
+		}
+	}
+	actualMetric.(prometheus.Gauge).Set(val)
+}
+
+func (b *promBackend) DeleteGauge(name string, labels map[string]string) {
+	if metric, ok := b.gauges.Load(metricKey(name, labels)); ok {
+		b.gauges.Delete(metricKey(name, labels))
+		prometheus.Unregister(metric.(prometheus.Collector))
+	}
+}
+
+func (b *promBackend) Stop() {}
@@ -0,0 +1,142 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	statsdMaxPacketSize  = 1432 // fits in the MTU of ethernet
+	statsdFlushInterval  = time.Second
+	statsdTypeCounter    = "c"
+	statsdTypeGauge      = "g"
+	statsdTagsSeparator  = "|#"
+	statsdLabelSeparator = ":"
+)
+
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_")
+
+// statsdBackend sends the metrics to a statsd server over UDP, the labels are
+// sent as tags in the format of DogStatsD, which is understood by most statsd servers.
+// The lines are batched into packets and flushed once a second.
+type statsdBackend struct {
+	conn  net.Conn
+	buf   bytes.Buffer
+	mu    sync.Mutex
+	stopC chan struct{}
+}
+
+func newStatsdBackend(addr string) (b *statsdBackend, err error) {
+	var conn net.Conn
+	if conn, err = net.Dial("udp", addr); err != nil {
+		return
+	}
+	b = &statsdBackend{
+		conn:  conn,
+		stopC: make(chan struct{}),
+	}
+	go b.flushWorker()
+	return
+}
+
+func (b *statsdBackend) AddCounter(name string, labels map[string]string, val float64) {
+	b.write(statsdLine(name, labels, val, statsdTypeCounter))
+}
+
+func (b *statsdBackend) SetGauge(name string, labels map[string]string, val float64) {
+	b.write(statsdLine(name, labels, val, statsdTypeGauge))
+}
+
+// DeleteGauge does nothing since statsd servers expire the metrics which are not reported.
+func (b *statsdBackend) DeleteGauge(name string, labels map[string]string) {}
+
+func (b *statsdBackend) Stop() {
+	close(b.stopC)
+}
+
+func statsdLine(name string, labels map[string]string, val float64, typ string) []byte {
+	var line bytes.Buffer
+	line.WriteString(name)
+	line.WriteString(":")
+	line.WriteString(strconv.FormatFloat(val, 'f', -1, 64))
+	line.WriteString("|")
+	line.WriteString(typ)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		line.WriteString(statsdTagsSeparator)
+		for i, k := range keys {
+			if i > 0 {
+				line.WriteString(",")
+			}
+			line.WriteString(replacer.Replace(k))
+			line.WriteString(statsdLabelSeparator)
+			line.WriteString(statsdTagReplacer.Replace(labels[k]))
+		}
+	}
+	line.WriteString("\n")
+	return line.Bytes()
+}
+
+func (b *statsdBackend) write(line []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf.Len()+len(line) > statsdMaxPacketSize {
+		b.flush()
+	}
+	b.buf.Write(line)
+}
+
+// flush sends the buffered lines, the caller should hold the lock.
+func (b *statsdBackend) flush() {
+	if b.buf.Len() == 0 {
+		return
+	}
+	if _, err := b.conn.Write(b.buf.Bytes()); err != nil {
+		log.LogDebugf("statsd send metrics error: %v", err)
+	}
+	b.buf.Reset()
+}
+
+func (b *statsdBackend) flushWorker() {
+	ticker := time.NewTicker(statsdFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopC:
+			b.mu.Lock()
+			b.flush()
+			b.mu.Unlock()
+			b.conn.Close()
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+			b.flush()
+			b.mu.Unlock()
+		}
+	}
+}
@@ -33,8 +33,7 @@ func collectTP() {
 	TPCh = make(chan *TimePoint, ChSize)
 	for {
 		m := <-TPCh
-		metric := m.Metric()
-		metric.Set(float64(m.val))
+		currentBackend().SetGauge(m.name, m.labels, m.val)
 		TPPool.Put(m)
 	}
 }
@@ -51,7 +50,7 @@ type TimePointCount struct {
 }
 
 func NewTP(name string) (tp *TimePoint) {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	tp = TPPool.Get().(*TimePoint)
@@ -61,7 +60,7 @@ func NewTP(name string) (tp *TimePoint) {
 }
 
 func (tp *TimePoint) Set() {
-	if !enabledPrometheus {
+	if !enabled {
 		return
 	}
 	val := time.Since(tp.startTime).Nanoseconds()
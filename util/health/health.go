@@ -0,0 +1,151 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package health serves the liveness and readiness of a role over http for the probes of Kubernetes
+// and the load balancers. The roles register their checks, a probe succeeds only if all the checks
+// of it pass. The readiness also requires the role to be running, which is set by the daemon once
+// the role has started and cleared when it begins to shut down.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	PathLiveness  = "/healthz"
+	PathReadiness = "/readyz"
+
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	checkRunning = "running"
+)
+
+// CheckFunc returns the reason if the role is not healthy or ready.
+type CheckFunc func() error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// CheckResult is the result of a check in the response of a probe.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the response of a probe, the status code is 200 if the status is ok, otherwise 503.
+type Report struct {
+	Status string         `json:"status"`
+	Checks []*CheckResult `json:"checks"`
+}
+
+var (
+	running   int32
+	checks    = make(map[string][]*check) // checks by the path of the probe
+	checkLock sync.RWMutex
+)
+
+// SetRunning marks whether the role is running.
+func SetRunning(isRunning bool) {
+	var value int32
+	if isRunning {
+		value = 1
+	}
+	atomic.StoreInt32(&running, value)
+}
+
+// RegisterLiveness adds a check to the liveness probe. The liveness fails only if the role can not
+// recover by itself, so that it is restarted.
+func RegisterLiveness(name string, fn CheckFunc) {
+	register(PathLiveness, name, fn)
+}
+
+// RegisterReadiness adds a check to the readiness probe. The readiness fails if the role can not serve
+// the requests for the moment.
+func RegisterReadiness(name string, fn CheckFunc) {
+	register(PathReadiness, name, fn)
+}
+
+func register(path, name string, fn CheckFunc) {
+	checkLock.Lock()
+	defer checkLock.Unlock()
+	checks[path] = append(checks[path], &check{name: name, fn: fn})
+}
+
+// Liveness runs the checks of the liveness probe.
+func Liveness() *Report {
+	return run(PathLiveness, nil)
+}
+
+// Readiness runs the checks of the readiness probe.
+func Readiness() *Report {
+	var result = &CheckResult{Name: checkRunning, Status: StatusOK}
+	if atomic.LoadInt32(&running) == 0 {
+		result.Status = StatusFail
+		result.Message = "not started or shutting down"
+	}
+	return run(PathReadiness, result)
+}
+
+func run(path string, first *CheckResult) (report *Report) {
+	report = &Report{Status: StatusOK, Checks: make([]*CheckResult, 0)}
+	if first != nil {
+		report.Checks = append(report.Checks, first)
+	}
+	checkLock.RLock()
+	var list = checks[path]
+	checkLock.RUnlock()
+	for _, c := range list {
+		var result = &CheckResult{Name: c.name, Status: StatusOK}
+		if err := c.fn(); err != nil {
+			result.Status = StatusFail
+			result.Message = err.Error()
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+			break
+		}
+	}
+	return
+}
+
+// LivenessHandler serves the liveness probe.
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, Liveness())
+}
+
+// ReadinessHandler serves the readiness probe.
+func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, Readiness())
+}
+
+func writeReport(w http.ResponseWriter, report *Report) {
+	var code = http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	data, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func probe(t *testing.T, handler http.HandlerFunc) (code int, report *Report) {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	report = &Report{}
+	if err := json.Unmarshal(w.Body.Bytes(), report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	return w.Code, report
+}
+
+func TestProbes(t *testing.T) {
+	var diskErr error
+	RegisterLiveness("disks", func() error { return diskErr })
+	RegisterReadiness("disks", func() error { return diskErr })
+
+	if code, report := probe(t, LivenessHandler); code != http.StatusOK || report.Status != StatusOK {
+		t.Fatalf("unexpected liveness: %v %v", code, report.Status)
+	}
+	if code, report := probe(t, ReadinessHandler); code != http.StatusServiceUnavailable || report.Checks[0].Status != StatusFail {
+		t.Fatalf("ready before running: %v %v", code, report.Status)
+	}
+
+	SetRunning(true)
+	if code, report := probe(t, ReadinessHandler); code != http.StatusOK || len(report.Checks) != 2 {
+		t.Fatalf("unexpected readiness: %v %v", code, report.Checks)
+	}
+
+	diskErr = errors.New("all 2 disks are unavailable")
+	code, report := probe(t, ReadinessHandler)
+	if code != http.StatusServiceUnavailable || report.Checks[1].Message != diskErr.Error() {
+		t.Fatalf("unexpected readiness with failed disks: %v %v", code, report.Checks[1])
+	}
+	if code, _ = probe(t, LivenessHandler); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected liveness with failed disks: %v", code)
+	}
+}
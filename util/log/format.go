@@ -0,0 +1,220 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Format is the format of the log lines.
+type Format uint32
+
+const (
+	// FormatText writes the lines as the time, the level, the caller and the message.
+	FormatText Format = iota
+	// FormatJSON writes every line as a JSON object with the fields of jsonLine, which is parsed by the log
+	// collectors such as ELK and Loki without regular expressions.
+	FormatJSON
+)
+
+const (
+	jsonTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+	modulePath     = "github.com/chubaofs/chubaofs/"
+)
+
+// ParseFormat returns the format of the name, which is text or json. The empty name is text.
+func ParseFormat(name string) (format Format, err error) {
+	switch strings.ToLower(name) {
+	case "", "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("log format only can be set: text, json")
+}
+
+func (f Format) String() string {
+	if f == FormatJSON {
+		return "json"
+	}
+	return "text"
+}
+
+// Fields are the structured fields of a log line, they are the fields of the JSON lines and are appended
+// to the message of the text lines. The zero fields are omitted.
+type Fields struct {
+	Partition uint64
+	Op        string
+	Latency   time.Duration
+}
+
+// jsonLine is a line of the JSON format. The role is the role of the server, and the module is the package
+// of the caller, such as metanode and sdk/data/stream. The latency is in microseconds.
+type jsonLine struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Role      string `json:"role,omitempty"`
+	Module    string `json:"module"`
+	Caller    string `json:"caller"`
+	Msg       string `json:"msg"`
+	Partition uint64 `json:"partition,omitempty"`
+	Op        string `json:"op,omitempty"`
+	Latency   int64  `json:"latency,omitempty"`
+}
+
+// SetFormat changes the format of the log at runtime, the role is written in the JSON lines.
+func SetFormat(format Format, role string) {
+	if gLog == nil {
+		return
+	}
+	gLog.setFormat(format, role)
+}
+
+func (l *Log) setFormat(format Format, role string) {
+	flags := log.LstdFlags | log.Lmicroseconds
+	if format == FormatJSON {
+		// the time is a field of the JSON lines
+		flags = 0
+	}
+	l.role.Store(role)
+	atomic.StoreUint32(&l.format, uint32(format))
+	for _, logger := range []*LogObject{l.debugLogger, l.infoLogger, l.warnLogger, l.errorLogger, l.readLogger,
+		l.updateLogger, l.criticalLogger} {
+		if logger != nil {
+			logger.SetFlags(flags)
+		}
+	}
+}
+
+// formatLine formats the message of the level with the caller at the depth of the call stack.
+func (l *Log) formatLine(depth int, s, level string, fields *Fields) string {
+	pc, file, line, ok := runtime.Caller(depth)
+	if !ok {
+		line = 0
+	}
+	if Format(atomic.LoadUint32(&l.format)) != FormatJSON {
+		short := file
+		for i := len(file) - 1; i > 0; i-- {
+			if file[i] == '/' {
+				short = file[i+1:]
+				break
+			}
+		}
+		if fields != nil {
+			s = strings.TrimSuffix(s, "\n") + fields.String()
+		}
+		return level + " " + short + ":" + strconv.Itoa(line) + ": " + s
+	}
+	entry := &jsonLine{
+		Time:   time.Now().Format(jsonTimeFormat),
+		Level:  strings.ToLower(strings.Trim(level, "[] ")),
+		Module: callerModule(pc),
+		Caller: path.Base(file) + ":" + strconv.Itoa(line),
+		Msg:    strings.TrimSuffix(s, "\n"),
+	}
+	entry.Role, _ = l.role.Load().(string)
+	if fields != nil {
+		entry.Partition = fields.Partition
+		entry.Op = fields.Op
+		entry.Latency = int64(fields.Latency / time.Microsecond)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return level + " " + s
+	}
+	return string(data)
+}
+
+// callerModule returns the package of the function relative to the repository, such as metanode
+// for github.com/chubaofs/chubaofs/metanode.(*metaPartition).Apply.
+func callerModule(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	// the package path ends at the first dot after the last slash
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		name = name[:slash+1+dot]
+	}
+	return strings.TrimPrefix(name, modulePath)
+}
+
+// formatNotice formats the notice of the log itself, such as the notices of the storm guard.
+func (l *Log) formatNotice(msg string) string {
+	if Format(atomic.LoadUint32(&l.format)) != FormatJSON {
+		return levelPrefixes[2] + " " + msg
+	}
+	return l.formatLine(2, msg, levelPrefixes[2], nil)
+}
+
+func (f *Fields) String() string {
+	var b strings.Builder
+	if f.Partition != 0 {
+		b.WriteString(" partition(" + strconv.FormatUint(f.Partition, 10) + ")")
+	}
+	if f.Op != "" {
+		b.WriteString(" op(" + f.Op + ")")
+	}
+	if f.Latency != 0 {
+		b.WriteString(" latency(" + f.Latency.String() + ")")
+	}
+	return b.String()
+}
+
+// LogDebugf logs the debug information with the fields.
+func (f Fields) LogDebugf(format string, v ...interface{}) {
+	if gLog == nil || !gLog.allow(DebugLevel) {
+		return
+	}
+	s := gLog.setPrefixWithFields(fmt.Sprintf(format, v...), levelPrefixes[0], &f)
+	gLog.debugLogger.Output(2, s)
+}
+
+// LogInfof logs the information with the fields.
+func (f Fields) LogInfof(format string, v ...interface{}) {
+	if gLog == nil || !gLog.allow(InfoLevel) {
+		return
+	}
+	s := gLog.setPrefixWithFields(fmt.Sprintf(format, v...), levelPrefixes[1], &f)
+	gLog.infoLogger.Output(2, s)
+}
+
+// LogWarnf logs the warnings with the fields.
+func (f Fields) LogWarnf(format string, v ...interface{}) {
+	if gLog == nil || !gLog.allow(WarnLevel) {
+		return
+	}
+	s := gLog.setPrefixWithFields(fmt.Sprintf(format, v...), levelPrefixes[2], &f)
+	gLog.warnLogger.Output(2, s)
+}
+
+// LogErrorf logs the errors with the fields.
+func (f Fields) LogErrorf(format string, v ...interface{}) {
+	if gLog == nil || !gLog.allow(ErrorLevel) {
+		return
+	}
+	s := gLog.setPrefixWithFields(fmt.Sprintf(format, v...), levelPrefixes[3], &f)
+	gLog.errorLogger.Output(2, s)
+}
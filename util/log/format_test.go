@@ -0,0 +1,59 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatLine(t *testing.T) {
+	l := new(Log)
+	fields := &Fields{Partition: 12, Op: "OpCreateInode", Latency: 1500 * time.Microsecond}
+	text := l.formatLine(1, "create inode\n", levelPrefixes[1], fields)
+	if !strings.HasPrefix(text, "[INFO ] format_test.go:") ||
+		!strings.HasSuffix(text, ": create inode partition(12) op(OpCreateInode) latency(1.5ms)") {
+		t.Fatalf("unexpected text line: %v", text)
+	}
+
+	l.setFormat(FormatJSON, "metanode")
+	var line jsonLine
+	if err := json.Unmarshal([]byte(l.formatLine(1, "create inode\n", levelPrefixes[1], fields)), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Level != "info" || line.Role != "metanode" || line.Module != "util/log" || line.Msg != "create inode" ||
+		line.Partition != 12 || line.Op != "OpCreateInode" || line.Latency != 1500 || !strings.HasPrefix(line.Caller, "format_test.go:") {
+		t.Fatalf("unexpected json line: %+v", line)
+	}
+	if _, err := time.Parse(jsonTimeFormat, line.Time); err != nil {
+		t.Fatalf("unexpected time of json line: %v", err)
+	}
+	if data := l.formatLine(1, "start", levelPrefixes[3], nil); strings.Contains(data, "partition") || strings.Contains(data, "latency") {
+		t.Fatalf("zero fields should be omitted: %v", data)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expect := range map[string]Format{"": FormatText, "text": FormatText, "JSON": FormatJSON} {
+		if format, err := ParseFormat(name); err != nil || format != expect {
+			t.Errorf("parse format[%v] expect [%v] but is [%v] err[%v]", name, expect, format, err)
+		}
+	}
+	if _, err := ParseFormat("logfmt"); err == nil {
+		t.Errorf("parse unknown format without err")
+	}
+}
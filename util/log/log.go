@@ -25,11 +25,10 @@ import (
 	"net/http"
 	"os"
 	"path"
-	"runtime"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -243,6 +242,10 @@ type Log struct {
 	msgC           chan string
 	rotate         *LogRotate
 	lastRolledTime time.Time
+	stormGuard     atomic.Value // *stormGuard
+	guardMu        sync.Mutex
+	format         uint32       // Format of the lines, accessed atomically
+	role           atomic.Value // role of the server written in the JSON lines
 }
 
 var (
@@ -301,6 +304,7 @@ func InitLog(dir, module string, level Level, rotate *LogRotate) (*Log, error) {
 		return nil, err
 	}
 	l.lastRolledTime = time.Now()
+	l.setStormGuard(DefaultStormLinesPerSecond)
 	go l.checkLogRotation(dir, module)
 
 	gLog = l
@@ -331,21 +335,13 @@ func (l *Log) initLog(logDir, module string, level Level) error {
 	return nil
 }
 
-// SetPrefix sets the log prefix.
+// SetPrefix sets the log prefix, or formats the line as JSON in the JSON format.
 func (l *Log) SetPrefix(s, level string) string {
-	_, file, line, ok := runtime.Caller(2)
-	if !ok {
-		line = 0
-	}
-	short := file
-	for i := len(file) - 1; i > 0; i-- {
-		if file[i] == '/' {
-			short = file[i+1:]
-			break
-		}
-	}
-	file = short
-	return level + " " + file + ":" + strconv.Itoa(line) + ": " + s
+	return l.formatLine(3, s, level, nil)
+}
+
+func (l *Log) setPrefixWithFields(s, level string, fields *Fields) string {
+	return l.formatLine(3, s, level, fields)
 }
 
 // Flush flushes the log.
@@ -368,19 +364,42 @@ func (l *Log) Flush() {
 
 const (
 	SetLogLevelPath = "/loglevel/set"
+	GetLogLevelPath = "/loglevel/get"
 )
 
+// SetLogLevel sets the level of the log, or the level of a module if the module is given.
+// The level default drops the level of the module.
 func SetLogLevel(w http.ResponseWriter, r *http.Request) {
 	var (
-		err error
+		err   error
+		level Level
 	)
 	if err = r.ParseForm(); err != nil {
 		buildFailureResp(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	levelStr := r.FormValue("level")
-	var level Level
-	switch strings.ToLower(levelStr) {
+	module := r.FormValue("module")
+	if module != "" && strings.ToLower(r.FormValue("level")) == LevelDefault {
+		ResetModuleLevel(module)
+		buildSuccessResp(w, fmt.Sprintf("reset log level of module %v success", module))
+		return
+	}
+	if level, err = ParseLevel(r.FormValue("level")); err != nil {
+		buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if module != "" {
+		SetModuleLevel(module, level)
+		buildSuccessResp(w, fmt.Sprintf("set log level of module %v success", module))
+		return
+	}
+	SetLevel(level)
+	buildSuccessResp(w, "set log level success")
+}
+
+// ParseLevel returns the level of the name, which is one of debug, info, warn, error, critical, read, write and fatal.
+func ParseLevel(name string) (level Level, err error) {
+	switch strings.ToLower(name) {
 	case "debug":
 		level = DebugLevel
 	case "info", "read", "write":
@@ -395,11 +414,16 @@ func SetLogLevel(w http.ResponseWriter, r *http.Request) {
 		level = FatalLevel
 	default:
 		err = fmt.Errorf("level only can be set :debug,info,warn,error,critical,read,write,fatal")
-		buildFailureResp(w, http.StatusBadRequest, err.Error())
+	}
+	return
+}
+
+// SetLevel changes the level of the log at runtime.
+func SetLevel(level Level) {
+	if gLog == nil {
 		return
 	}
-	gLog.level = Level(level)
-	buildSuccessResp(w, "set log level success")
+	gLog.level = level
 }
 
 func buildSuccessResp(w http.ResponseWriter, data interface{}) {
@@ -438,7 +462,7 @@ func LogWarn(v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if WarnLevel&gLog.level != gLog.level {
+	if !gLog.allow(WarnLevel) {
 		return
 	}
 	s := fmt.Sprintln(v...)
@@ -451,7 +475,7 @@ func LogWarnf(format string, v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if WarnLevel&gLog.level != gLog.level {
+	if !gLog.allow(WarnLevel) {
 		return
 	}
 	s := fmt.Sprintf(format, v...)
@@ -464,7 +488,7 @@ func LogInfo(v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if InfoLevel&gLog.level != gLog.level {
+	if !gLog.allow(InfoLevel) {
 		return
 	}
 	s := fmt.Sprintln(v...)
@@ -477,7 +501,7 @@ func LogInfof(format string, v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if InfoLevel&gLog.level != gLog.level {
+	if !gLog.allow(InfoLevel) {
 		return
 	}
 	s := fmt.Sprintf(format, v...)
@@ -490,7 +514,7 @@ func LogError(v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if ErrorLevel&gLog.level != gLog.level {
+	if !gLog.allow(ErrorLevel) {
 		return
 	}
 	s := fmt.Sprintln(v...)
@@ -503,7 +527,7 @@ func LogErrorf(format string, v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if ErrorLevel&gLog.level != gLog.level {
+	if !gLog.allow(ErrorLevel) {
 		return
 	}
 	s := fmt.Sprintf(format, v...)
@@ -516,7 +540,7 @@ func LogDebug(v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if DebugLevel&gLog.level != gLog.level {
+	if !gLog.allow(DebugLevel) {
 		return
 	}
 	s := fmt.Sprintln(v...)
@@ -529,7 +553,7 @@ func LogDebugf(format string, v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if DebugLevel&gLog.level != gLog.level {
+	if !gLog.allow(DebugLevel) {
 		return
 	}
 	s := fmt.Sprintf(format, v...)
@@ -584,7 +608,7 @@ func LogRead(v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if ReadLevel&gLog.level != gLog.level {
+	if !gLog.allow(ReadLevel) {
 		return
 	}
 	s := fmt.Sprintln(v...)
@@ -597,7 +621,7 @@ func LogReadf(format string, v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if ReadLevel&gLog.level != gLog.level {
+	if !gLog.allow(ReadLevel) {
 		return
 	}
 	s := fmt.Sprintf(format, v...)
@@ -610,7 +634,7 @@ func LogWrite(v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if UpdateLevel&gLog.level != gLog.level {
+	if !gLog.allow(UpdateLevel) {
 		return
 	}
 	s := fmt.Sprintln(v...)
@@ -623,7 +647,7 @@ func LogWritef(format string, v ...interface{}) {
 	if gLog == nil {
 		return
 	}
-	if UpdateLevel&gLog.level != gLog.level {
+	if !gLog.allow(UpdateLevel) {
 		return
 	}
 	s := fmt.Sprintf(format, v...)
@@ -78,6 +78,17 @@ func TestLog(t *testing.T) {
 	}
 }
 
+func TestParseLevel(t *testing.T) {
+	for name, expect := range map[string]Level{"debug": DebugLevel, "WARN": WarnLevel, "write": InfoLevel} {
+		if level, err := ParseLevel(name); err != nil || level != expect {
+			t.Errorf("parse level[%v] expect [%v] but is [%v] err[%v]", name, expect, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("parse unknown level without err")
+	}
+}
+
 // create file and modify modTime to 7 days ago
 func createFile(logFilePath string, modTime bool) (err error) {
 	_, err = os.Create(logFilePath)
@@ -0,0 +1,135 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelDefault drops the level of a module, so that the module follows the level of the log again.
+const LevelDefault = "default"
+
+var (
+	// the levels of the modules set at runtime, the map is replaced on every change
+	moduleLevels    atomic.Value // map[string]Level
+	moduleLevelLock sync.Mutex
+	// the modules of the callers by the program counters
+	callerModules sync.Map
+)
+
+// LogLevels is the level of the log and the levels of the modules set at runtime.
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// LevelName returns the name of the level.
+func LevelName(level Level) string {
+	switch level {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	case CriticalLevel:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// SetModuleLevel sets the level of the lines logged by the module at runtime, which takes precedence
+// over the level of the log. The module is a package relative to the repository such as metanode and
+// sdk/data/stream, and covers its sub-packages unless they have a level of their own.
+func SetModuleLevel(module string, level Level) {
+	updateModuleLevels(func(levels map[string]Level) {
+		levels[strings.Trim(module, "/")] = level
+	})
+}
+
+// ResetModuleLevel drops the level of the module.
+func ResetModuleLevel(module string) {
+	updateModuleLevels(func(levels map[string]Level) {
+		delete(levels, strings.Trim(module, "/"))
+	})
+}
+
+func updateModuleLevels(update func(levels map[string]Level)) {
+	moduleLevelLock.Lock()
+	defer moduleLevelLock.Unlock()
+	old, _ := moduleLevels.Load().(map[string]Level)
+	levels := make(map[string]Level, len(old)+1)
+	for module, level := range old {
+		levels[module] = level
+	}
+	update(levels)
+	moduleLevels.Store(levels)
+}
+
+// GetLevels returns the level of the log and the levels of the modules.
+func GetLevels() *LogLevels {
+	levels := &LogLevels{Modules: make(map[string]string)}
+	if gLog != nil {
+		levels.Level = LevelName(gLog.level)
+	}
+	modules, _ := moduleLevels.Load().(map[string]Level)
+	for module, level := range modules {
+		levels.Modules[module] = LevelName(level)
+	}
+	return levels
+}
+
+// effectiveLevel returns the level of the module of the caller at the depth of the call stack, or the level
+// of the log if no level is set for the module. The caller is only looked up if a module has a level.
+func (l *Log) effectiveLevel(depth int) Level {
+	modules, _ := moduleLevels.Load().(map[string]Level)
+	if len(modules) == 0 {
+		return l.level
+	}
+	pc, _, _, ok := runtime.Caller(depth)
+	if !ok {
+		return l.level
+	}
+	var module string
+	if cached, ok := callerModules.Load(pc); ok {
+		module = cached.(string)
+	} else {
+		module = callerModule(pc)
+		callerModules.Store(pc, module)
+	}
+	for {
+		if level, ok := modules[module]; ok {
+			return level
+		}
+		idx := strings.LastIndex(module, "/")
+		if idx < 0 {
+			return l.level
+		}
+		module = module[:idx]
+	}
+}
+
+// GetLogLevel responds the level of the log and the levels of the modules.
+func GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	buildSuccessResp(w, GetLevels())
+}
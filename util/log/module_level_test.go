@@ -0,0 +1,82 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestModuleLevel(t *testing.T) {
+	l := &Log{level: ErrorLevel}
+	// the same depth of the call stack as the exported log functions
+	logLine := func(level Level) bool {
+		return l.allow(level)
+	}
+	defer func() {
+		ResetModuleLevel("util")
+		ResetModuleLevel("util/log")
+		ResetModuleLevel("metanode")
+	}()
+
+	if logLine(DebugLevel) || !logLine(ErrorLevel) {
+		t.Fatalf("unexpected lines allowed without module levels")
+	}
+	SetModuleLevel("metanode", DebugLevel)
+	if logLine(DebugLevel) {
+		t.Fatalf("debug line allowed by the level of another module")
+	}
+	SetModuleLevel("util", DebugLevel)
+	if !logLine(DebugLevel) {
+		t.Fatalf("debug line not allowed by the level of the parent module")
+	}
+	SetModuleLevel("/util/log/", WarnLevel)
+	if logLine(InfoLevel) || !logLine(WarnLevel) {
+		t.Fatalf("the level of the module should take precedence over the parent module")
+	}
+	if levels := GetLevels(); len(levels.Modules) != 3 || levels.Modules["util/log"] != "warn" {
+		t.Fatalf("unexpected module levels: %v", levels.Modules)
+	}
+	ResetModuleLevel("util/log")
+	ResetModuleLevel("util")
+	if logLine(DebugLevel) {
+		t.Fatalf("debug line allowed after the module levels are reset")
+	}
+}
+
+func TestSetLogLevelOfModule(t *testing.T) {
+	set := func(values url.Values) int {
+		w := httptest.NewRecorder()
+		SetLogLevel(w, httptest.NewRequest(http.MethodPost, SetLogLevelPath+"?"+values.Encode(), nil))
+		return w.Code
+	}
+	if code := set(url.Values{"module": {"datanode"}, "level": {"debug"}}); code != http.StatusOK {
+		t.Fatalf("set level of module failed: code(%v)", code)
+	}
+	if GetLevels().Modules["datanode"] != "debug" {
+		t.Fatalf("level of module not set: %v", GetLevels().Modules)
+	}
+	if code := set(url.Values{"module": {"datanode"}, "level": {"verbose"}}); code != http.StatusBadRequest {
+		t.Fatalf("set unknown level should fail: code(%v)", code)
+	}
+	if code := set(url.Values{"module": {"datanode"}, "level": {LevelDefault}}); code != http.StatusOK {
+		t.Fatalf("reset level of module failed: code(%v)", code)
+	}
+	if _, ok := GetLevels().Modules["datanode"]; ok {
+		t.Fatalf("level of module not reset: %v", GetLevels().Modules)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultStormLinesPerSecond is the rate of a level considered as a log storm, 0 disables the guard.
+	DefaultStormLinesPerSecond = 10000
+	// StormSustainSeconds is how long the rate must be exceeded before the guard raises the level.
+	StormSustainSeconds = 5
+	// StormRecoverSeconds is how long the rate must stay below the limit before the level is restored.
+	StormRecoverSeconds = 30
+	// StormNoticeInterval is the interval of the suppression notices while a storm lasts.
+	StormNoticeInterval = time.Minute
+)
+
+// Levels watched by the storm guard, read and write logs are counted as info.
+var stormLevels = [...]Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel}
+var stormLevelNames = [...]string{"debug", "info", "warn", "error"}
+
+func stormLevelIndex(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 0
+	case InfoLevel:
+		return 1
+	case WarnLevel:
+		return 2
+	}
+	return 3
+}
+
+type stormStat struct {
+	count      int64 // lines of the current second, accessed atomically
+	suppressed int64 // lines suppressed since the last tick, accessed atomically
+	active     int32 // 1 if a storm of the level is going on, accessed atomically
+	sampled    int64 // lines counted for the sampling during the storms, accessed atomically
+
+	// following fields are only accessed by the ticker
+	over       int
+	under      int
+	since      time.Time
+	lastNotice time.Time
+	total      int64 // lines suppressed in the current storm
+}
+
+// stormGuard detects the sustained extreme log rates, such as an error logged in a tight loop,
+// and temporarily raises the effective log level above the stormy level to keep the log
+// partition from filling up. The error level is never raised above, the error lines exceeding
+// the limit of each second are dropped instead. A notice with the count of suppressed lines is
+// written to the warn log when a storm starts, every StormNoticeInterval and when it ends.
+type stormGuard struct {
+	limit  int64
+	raised uint32 // the raised effective level, 0 if not raised, accessed atomically
+	stats  [len(stormLevels)]stormStat
+	notice func(msg string)
+	stopC  chan struct{}
+	once   sync.Once
+}
+
+// StormHook is called every second for the levels suppressing lines or changing the storm state,
+// it is used to export the metrics of the guard.
+type StormHook func(level string, suppressed int64, active bool)
+
+var stormHook atomic.Value
+
+// stormSampleEvery keeps 1 of every n lines which are suppressed by the guard, 0 suppresses all of them.
+var stormSampleEvery int64
+
+// SetStormSampling keeps 1 of every n lines suppressed during the log storms, so that the stormy lines are
+// still seen in the log at a bearable rate. 0 or less suppresses all of them.
+func SetStormSampling(n int64) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&stormSampleEvery, n)
+}
+
+// SetStormHook sets the hook receiving the suppression counts of the log storm guard.
+func SetStormHook(hook StormHook) {
+	stormHook.Store(hook)
+}
+
+func newStormGuard(limit int64, notice func(msg string)) *stormGuard {
+	return &stormGuard{
+		limit:  limit,
+		notice: notice,
+		stopC:  make(chan struct{}),
+	}
+}
+
+// allow counts a line of the level and reports whether it should be written.
+func (g *stormGuard) allow(level Level) bool {
+	var stat = &g.stats[stormLevelIndex(level)]
+	var n = atomic.AddInt64(&stat.count, 1)
+	if raised := Level(atomic.LoadUint32(&g.raised)); raised != 0 && level&raised != raised {
+		return stat.suppress()
+	}
+	if level == ErrorLevel && n > g.limit && atomic.LoadInt32(&stat.active) == 1 {
+		return stat.suppress()
+	}
+	return true
+}
+
+// suppress counts a line to suppress, and returns true if the line is kept by the sampling.
+func (stat *stormStat) suppress() bool {
+	if every := atomic.LoadInt64(&stormSampleEvery); every > 0 && atomic.AddInt64(&stat.sampled, 1)%every == 0 {
+		return true
+	}
+	atomic.AddInt64(&stat.suppressed, 1)
+	return false
+}
+
+func (g *stormGuard) run() {
+	var ticker = time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stopC:
+			return
+		case now := <-ticker.C:
+			g.tick(now)
+		}
+	}
+}
+
+func (g *stormGuard) stop() {
+	g.once.Do(func() {
+		close(g.stopC)
+	})
+}
+
+// tick checks the rates of the last second and updates the storm states.
+func (g *stormGuard) tick(now time.Time) {
+	var raised Level
+	for i := range g.stats {
+		var stat = &g.stats[i]
+		var count = atomic.SwapInt64(&stat.count, 0)
+		var suppressed = atomic.SwapInt64(&stat.suppressed, 0)
+		var wasActive = atomic.LoadInt32(&stat.active) == 1
+		var active = wasActive
+		stat.total += suppressed
+		if count > g.limit {
+			stat.over, stat.under = stat.over+1, 0
+		} else {
+			stat.over, stat.under = 0, stat.under+1
+		}
+		switch {
+		case !wasActive && stat.over >= StormSustainSeconds:
+			active = true
+			stat.since, stat.lastNotice, stat.total = now, now, 0
+			g.notice(fmt.Sprintf("log storm detected: level(%v) rate(%v lines/s) limit(%v lines/s), suppressing lines%v",
+				stormLevelNames[i], count, g.limit, stormSamplingNotice()))
+		case wasActive && stat.under >= StormRecoverSeconds:
+			active = false
+			g.notice(fmt.Sprintf("log storm ended: level(%v) duration(%v) suppressed(%v lines)",
+				stormLevelNames[i], now.Sub(stat.since).Truncate(time.Second), stat.total))
+		case wasActive && now.Sub(stat.lastNotice) >= StormNoticeInterval:
+			stat.lastNotice = now
+			g.notice(fmt.Sprintf("log storm ongoing: level(%v) duration(%v) rate(%v lines/s) suppressed(%v lines)",
+				stormLevelNames[i], now.Sub(stat.since).Truncate(time.Second), count, stat.total))
+		}
+		if active != wasActive {
+			var v int32
+			if active {
+				v = 1
+			}
+			atomic.StoreInt32(&stat.active, v)
+		}
+		if active && stormLevels[i] != ErrorLevel {
+			// raise the level above the stormy one
+			raised = stormLevels[i+1]
+		}
+		if hook, ok := stormHook.Load().(StormHook); ok && hook != nil && (suppressed > 0 || active != wasActive) {
+			hook(stormLevelNames[i], suppressed, active)
+		}
+	}
+	atomic.StoreUint32(&g.raised, uint32(raised))
+}
+
+// SetStormLimit sets the rate of lines per second of a level considered as a log storm,
+// 0 disables the guard.
+func SetStormLimit(linesPerSecond int64) {
+	if gLog == nil {
+		return
+	}
+	gLog.setStormGuard(linesPerSecond)
+}
+
+func (l *Log) setStormGuard(linesPerSecond int64) {
+	l.guardMu.Lock()
+	defer l.guardMu.Unlock()
+	if old := l.guard(); old != nil {
+		old.stop()
+	}
+	if linesPerSecond <= 0 {
+		l.stormGuard.Store((*stormGuard)(nil))
+		return
+	}
+	var g = newStormGuard(linesPerSecond, func(msg string) {
+		l.warnLogger.Print(l.formatNotice(msg))
+	})
+	l.stormGuard.Store(g)
+	go g.run()
+}
+
+func (l *Log) guard() *stormGuard {
+	g, _ := l.stormGuard.Load().(*stormGuard)
+	return g
+}
+
+// allow reports whether a line of the level should be written. It is called by the exported log functions,
+// so the line is logged by the caller at the depth 3 of the call stack.
+func (l *Log) allow(level Level) bool {
+	if threshold := l.effectiveLevel(3); level&threshold != threshold {
+		return false
+	}
+	if g := l.guard(); g != nil {
+		return g.allow(level)
+	}
+	return true
+}
+
+func stormSamplingNotice() string {
+	if every := atomic.LoadInt64(&stormSampleEvery); every > 0 {
+		return fmt.Sprintf(" except 1 of every %v", every)
+	}
+	return ""
+}
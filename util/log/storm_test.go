@@ -0,0 +1,108 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStormGuard(t *testing.T) {
+	var notices []string
+	var g = newStormGuard(10, func(msg string) {
+		notices = append(notices, msg)
+	})
+	var now = time.Now()
+	var logLines = func(level Level, n int) (allowed int) {
+		for i := 0; i < n; i++ {
+			if g.allow(level) {
+				allowed++
+			}
+		}
+		return
+	}
+
+	// a sustained info storm raises the effective level to warn
+	for i := 0; i < StormSustainSeconds; i++ {
+		if allowed := logLines(InfoLevel, 100); allowed != 100 {
+			t.Fatalf("lines suppressed before storm detected: second(%v) allowed(%v)", i, allowed)
+		}
+		now = now.Add(time.Second)
+		g.tick(now)
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "detected: level(info)") {
+		t.Fatalf("expect storm detected notice: notices(%v)", notices)
+	}
+	if allowed := logLines(InfoLevel, 100); allowed != 0 {
+		t.Fatalf("info lines not suppressed during storm: allowed(%v)", allowed)
+	}
+	if logLines(DebugLevel, 1) != 0 || logLines(WarnLevel, 1) != 1 || logLines(ErrorLevel, 1) != 1 {
+		t.Fatalf("unexpected effective level during info storm")
+	}
+	now = now.Add(time.Second)
+	g.tick(now)
+
+	// the level is restored after the rate stays below the limit
+	for i := 0; i < StormRecoverSeconds; i++ {
+		now = now.Add(time.Second)
+		g.tick(now)
+	}
+	if len(notices) != 2 || !strings.Contains(notices[1], "ended: level(info)") || !strings.Contains(notices[1], "suppressed(100 lines)") {
+		t.Fatalf("expect storm ended notice: notices(%v)", notices)
+	}
+	if logLines(InfoLevel, 1) != 1 {
+		t.Fatalf("info lines suppressed after storm ended")
+	}
+
+	// error lines are limited per second instead of being raised above
+	for i := 0; i < StormSustainSeconds; i++ {
+		logLines(ErrorLevel, 100)
+		now = now.Add(time.Second)
+		g.tick(now)
+	}
+	if allowed := logLines(ErrorLevel, 100); allowed != 10 {
+		t.Fatalf("error lines not limited during storm: allowed(%v)", allowed)
+	}
+	if logLines(WarnLevel, 1) != 1 {
+		t.Fatalf("warn lines suppressed during error storm")
+	}
+}
+
+func TestStormSampling(t *testing.T) {
+	defer SetStormSampling(0)
+	SetStormSampling(10)
+	var g = newStormGuard(10, func(msg string) {})
+	var now = time.Now()
+	for i := 0; i < StormSustainSeconds; i++ {
+		for j := 0; j < 100; j++ {
+			g.allow(InfoLevel)
+		}
+		now = now.Add(time.Second)
+		g.tick(now)
+	}
+	var allowed int
+	for i := 0; i < 100; i++ {
+		if g.allow(InfoLevel) {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Fatalf("expect 1 of every 10 suppressed lines kept: allowed(%v)", allowed)
+	}
+	if suppressed := g.stats[stormLevelIndex(InfoLevel)].suppressed; suppressed != 90 {
+		t.Fatalf("unexpected suppressed lines: %v", suppressed)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package qos
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/util"
+	"golang.org/x/time/rate"
+)
+
+// the bandwidth bucket must be able to hold at least one full packet
+const minBandwidthBurst = 4 * util.MB
+
+// Limiter throttles the IO of a volume by two token buckets, one for the IOPS and one for the bandwidth.
+// A zero limit means unlimited.
+type Limiter struct {
+	sync.RWMutex
+	maxIOPS      uint64
+	maxBandwidth uint64 // bytes per second
+	iops         *rate.Limiter
+	bandwidth    *rate.Limiter
+}
+
+// NewLimiter returns a limiter with the given IOPS and bandwidth limits.
+func NewLimiter(maxIOPS, maxBandwidth uint64) (l *Limiter) {
+	l = &Limiter{
+		iops:      newBucket(0, 1),
+		bandwidth: newBucket(0, minBandwidthBurst),
+	}
+	l.Update(maxIOPS, maxBandwidth)
+	return
+}
+
+// Update changes the limits, the buckets are rebuilt only if the limit has changed.
+func (l *Limiter) Update(maxIOPS, maxBandwidth uint64) {
+	l.Lock()
+	defer l.Unlock()
+	if l.maxIOPS != maxIOPS {
+		l.maxIOPS = maxIOPS
+		l.iops = newBucket(maxIOPS, 1)
+	}
+	if l.maxBandwidth != maxBandwidth {
+		l.maxBandwidth = maxBandwidth
+		l.bandwidth = newBucket(maxBandwidth, minBandwidthBurst)
+	}
+}
+
+func newBucket(limit uint64, minBurst int) *rate.Limiter {
+	if limit == 0 {
+		return rate.NewLimiter(rate.Inf, minBurst)
+	}
+	burst := int(limit)
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// Limits returns the current IOPS and bandwidth limits.
+func (l *Limiter) Limits() (maxIOPS, maxBandwidth uint64) {
+	l.RLock()
+	defer l.RUnlock()
+	return l.maxIOPS, l.maxBandwidth
+}
+
+// Wait blocks until one IO of the given size is allowed.
+func (l *Limiter) Wait(ctx context.Context, size int) (err error) {
+	l.RLock()
+	iops, bandwidth := l.iops, l.bandwidth
+	l.RUnlock()
+	if err = iops.Wait(ctx); err != nil {
+		return
+	}
+	if size <= 0 {
+		return
+	}
+	if size > bandwidth.Burst() {
+		size = bandwidth.Burst()
+	}
+	return bandwidth.WaitN(ctx, size)
+}
@@ -0,0 +1,40 @@
+package qos
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(0, 0)
+	start := time.Now()
+	for i := 0; i < 10000; i++ {
+		if err := l.Wait(context.Background(), 128*1024); err != nil {
+			t.Fatalf("wait unlimited limiter failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("unlimited limiter should not block, elapsed[%v]", elapsed)
+	}
+
+	l.Update(10, 0)
+	if maxIOPS, maxBandwidth := l.Limits(); maxIOPS != 10 || maxBandwidth != 0 {
+		t.Fatalf("unexpected limits, maxIOPS[%v] maxBandwidth[%v]", maxIOPS, maxBandwidth)
+	}
+	start = time.Now()
+	// the burst is used up by the first 10 IOs, the next 5 IOs take about half a second
+	for i := 0; i < 15; i++ {
+		l.Wait(context.Background(), 0)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("limiter with maxIOPS[10] should block, elapsed[%v]", elapsed)
+	}
+
+	l.Update(0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, 0); err != nil {
+		t.Errorf("limiter should not block after the limit is removed: %v", err)
+	}
+}
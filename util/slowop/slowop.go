@@ -0,0 +1,182 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package slowop records the operations of the data nodes and the meta nodes which take longer than
+// the thresholds. The slow operations are logged as JSON lines, and the latest ones are kept in memory
+// to be queried through the prof port.
+package slowop
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// ConfigKeyThresholdMs is the threshold of all the operations, in the config file and in the runtime
+	// parameters of the nodes. The threshold of an operation is set by the key suffixed with the name of
+	// the operation, such as "slowOpThresholdMs.OpWrite". The slow log is disabled by a threshold of 0.
+	ConfigKeyThresholdMs = "slowOpThresholdMs"
+
+	DefaultThresholdMs = 500
+
+	opKeySeparator = "."
+	recentSize     = 1024
+)
+
+type thresholds struct {
+	all time.Duration
+	ops map[string]time.Duration
+}
+
+var (
+	configured    = time.Duration(DefaultThresholdMs) * time.Millisecond // threshold of the config file
+	current       atomic.Value                                           // *thresholds, replaced on update
+	thresholdLock sync.Mutex
+
+	recent     [recentSize]*proto.SlowOp // ring of the latest slow operations
+	recentNext int
+	recentLock sync.Mutex
+)
+
+func init() {
+	current.Store(&thresholds{all: configured})
+}
+
+// Init loads the threshold of all the operations from the config file.
+func Init(cfg *config.Config) (err error) {
+	value := cfg.GetFloat(ConfigKeyThresholdMs)
+	if value == -1 {
+		return
+	}
+	if value < 0 {
+		return fmt.Errorf("invalid %v: %v", ConfigKeyThresholdMs, value)
+	}
+	thresholdLock.Lock()
+	defer thresholdLock.Unlock()
+	configured = time.Duration(value) * time.Millisecond
+	t := load()
+	current.Store(&thresholds{all: configured, ops: t.ops})
+	log.LogInfof("slowop: threshold(%v)", configured)
+	return
+}
+
+func load() *thresholds {
+	return current.Load().(*thresholds)
+}
+
+// IsThresholdKey returns true if the key is the threshold of all the operations or of an operation.
+func IsThresholdKey(key string) bool {
+	return key == ConfigKeyThresholdMs || opOfKey(key) != ""
+}
+
+func opOfKey(key string) string {
+	if !strings.HasPrefix(key, ConfigKeyThresholdMs+opKeySeparator) {
+		return ""
+	}
+	return key[len(ConfigKeyThresholdMs+opKeySeparator):]
+}
+
+// SetThreshold changes the threshold of the key at runtime.
+func SetThreshold(key string, ms uint64) error {
+	return updateThreshold(key, time.Duration(ms)*time.Millisecond, false)
+}
+
+// ResetThreshold drops the threshold set at runtime, so that the operation follows the threshold of all
+// the operations, or the threshold of all the operations follows the config file again.
+func ResetThreshold(key string) error {
+	return updateThreshold(key, 0, true)
+}
+
+func updateThreshold(key string, threshold time.Duration, reset bool) error {
+	if !IsThresholdKey(key) {
+		return fmt.Errorf("unknown slow op threshold: %v", key)
+	}
+	thresholdLock.Lock()
+	defer thresholdLock.Unlock()
+	t := load()
+	updated := &thresholds{all: t.all, ops: make(map[string]time.Duration, len(t.ops)+1)}
+	for op, value := range t.ops {
+		updated.ops[op] = value
+	}
+	switch op := opOfKey(key); {
+	case op == "" && reset:
+		updated.all = configured
+	case op == "":
+		updated.all = threshold
+	case reset:
+		delete(updated.ops, op)
+	default:
+		updated.ops[op] = threshold
+	}
+	current.Store(updated)
+	return nil
+}
+
+// Thresholds returns the thresholds in milliseconds by the keys, the operations without a threshold of
+// their own are not listed.
+func Thresholds() map[string]string {
+	t := load()
+	values := map[string]string{ConfigKeyThresholdMs: strconv.FormatInt(int64(t.all/time.Millisecond), 10)}
+	for op, value := range t.ops {
+		values[ConfigKeyThresholdMs+opKeySeparator+op] = strconv.FormatInt(int64(value/time.Millisecond), 10)
+	}
+	return values
+}
+
+// IsSlow returns true if the latency of the operation exceeds its threshold.
+func IsSlow(op string, latency time.Duration) bool {
+	t := load()
+	threshold, ok := t.ops[op]
+	if !ok {
+		threshold = t.all
+	}
+	return threshold > 0 && latency >= threshold
+}
+
+// Record logs the slow operation and keeps it in the latest slow operations.
+func Record(op *proto.SlowOp) {
+	if op.Time == 0 {
+		op.Time = time.Now().Unix()
+	}
+	if data, err := json.Marshal(op); err == nil {
+		fields := log.Fields{Partition: op.PartitionID, Op: op.Op, Latency: time.Duration(op.LatencyUs) * time.Microsecond}
+		fields.LogWarnf("slowop: %s", data)
+	}
+	recentLock.Lock()
+	recent[recentNext] = op
+	recentNext = (recentNext + 1) % recentSize
+	recentLock.Unlock()
+}
+
+// Recent returns the latest slow operations finished since the unix time, from the oldest to the newest.
+func Recent(since int64) (ops []*proto.SlowOp) {
+	recentLock.Lock()
+	defer recentLock.Unlock()
+	for i := 0; i < recentSize; i++ {
+		op := recent[(recentNext+i)%recentSize]
+		if op != nil && op.Time >= since {
+			ops = append(ops, op)
+		}
+	}
+	return
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package slowop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestThresholds(t *testing.T) {
+	const opWrite = "OpWrite"
+	if !IsSlow(opWrite, DefaultThresholdMs*time.Millisecond) || IsSlow(opWrite, time.Millisecond) {
+		t.Fatalf("default threshold is not applied")
+	}
+	if err := SetThreshold(ConfigKeyThresholdMs+"."+opWrite, 10); err != nil {
+		t.Fatal(err)
+	}
+	if !IsSlow(opWrite, 10*time.Millisecond) || IsSlow("OpRead", 10*time.Millisecond) {
+		t.Fatalf("threshold of the operation is not applied")
+	}
+	if values := Thresholds(); len(values) != 2 || values[ConfigKeyThresholdMs+"."+opWrite] != "10" {
+		t.Fatalf("unexpected thresholds: %v", values)
+	}
+	if err := SetThreshold(ConfigKeyThresholdMs, 0); err != nil {
+		t.Fatal(err)
+	}
+	if IsSlow("OpRead", time.Hour) || !IsSlow(opWrite, time.Second) {
+		t.Fatalf("slow log of all the operations is not disabled")
+	}
+	if err := ResetThreshold(ConfigKeyThresholdMs + "." + opWrite); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetThreshold(ConfigKeyThresholdMs); err != nil {
+		t.Fatal(err)
+	}
+	if values := Thresholds(); len(values) != 1 || values[ConfigKeyThresholdMs] != "500" {
+		t.Fatalf("unexpected thresholds after reset: %v", values)
+	}
+	if err := SetThreshold(ConfigKeyThresholdMs+".", 10); err == nil {
+		t.Fatalf("threshold without operation is accepted")
+	}
+}
+
+func TestRecent(t *testing.T) {
+	for i := 0; i < recentSize+10; i++ {
+		Record(&proto.SlowOp{Time: int64(i), Op: "OpWrite", PartitionID: uint64(i)})
+	}
+	ops := Recent(0)
+	if len(ops) != recentSize || ops[0].PartitionID != 10 || ops[len(ops)-1].PartitionID != recentSize+9 {
+		t.Fatalf("unexpected recent ops: %v first(%v)", len(ops), ops[0].PartitionID)
+	}
+	if ops = Recent(recentSize + 5); len(ops) != 5 {
+		t.Fatalf("unexpected recent ops since: %v", len(ops))
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const (
+	otlpScopeName                = "github.com/chubaofs/chubaofs/util/tracing"
+	otlpContentTypeJSON          = "application/json"
+	otlpAttributeServiceName     = "service.name"
+	otlpAttributeServiceInstance = "service.instance.id"
+	otlpStatusCodeOk             = 1
+	otlpStatusCodeError          = 2
+)
+
+// otlpExporter posts the spans to the collector with the JSON encoding of OTLP/HTTP, in which
+// the IDs are encoded in hex.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+func newOtlpExporter(endpoint string) *otlpExporter {
+	return &otlpExporter{endpoint: endpoint, client: &http.Client{Timeout: exportTimeout}}
+}
+
+func otlpAttributes(attrs map[string]string) (kvs []otlpKeyValue) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		kv := otlpKeyValue{Key: k}
+		kv.Value.StringValue = attrs[k]
+		kvs = append(kvs, kv)
+	}
+	return
+}
+
+func buildRequest(service, instance string, spans []*Span) *otlpRequest {
+	ss := &otlpScopeSpans{}
+	ss.Scope.Name = otlpScopeName
+	for _, s := range spans {
+		span := &otlpSpan{
+			TraceID:           hex.EncodeToString(s.ctx.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.ctx.SpanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+			Status:            otlpStatus{Code: otlpStatusCodeOk},
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.err.Error()}
+		}
+		ss.Spans = append(ss.Spans, span)
+	}
+	rs := &otlpResourceSpans{ScopeSpans: []*otlpScopeSpans{ss}}
+	rs.Resource.Attributes = otlpAttributes(map[string]string{
+		otlpAttributeServiceName:     service,
+		otlpAttributeServiceInstance: instance,
+	})
+	return &otlpRequest{ResourceSpans: []*otlpResourceSpans{rs}}
+}
+
+func (e *otlpExporter) export(service, instance string, spans []*Span) (err error) {
+	var body []byte
+	if body, err = json.Marshal(buildRequest(service, instance, spans)); err != nil {
+		return
+	}
+	resp, err := e.client.Post(e.endpoint, otlpContentTypeJSON, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		err = fmt.Errorf("unexpected status: %v body(%v)", resp.Status, string(data))
+	}
+	return
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderTraceParent = "traceparent"
+
+	// ContextLen is the size of the binary form of the context in the packet protocol.
+	ContextLen = 24
+
+	traceParentVersion = "00"
+	traceFlagSampled   = 0x01
+)
+
+// SpanContext identifies a span in a trace. Only the contexts of the sampled spans are propagated,
+// so a valid context implies that the trace is sampled.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != [16]byte{} && c.SpanID != [8]byte{}
+}
+
+func (c SpanContext) String() string {
+	return fmt.Sprintf("%x-%x", c.TraceID, c.SpanID)
+}
+
+// Marshal returns the binary form of the context, the trace ID followed by the span ID.
+func (c SpanContext) Marshal() []byte {
+	out := make([]byte, ContextLen)
+	copy(out, c.TraceID[:])
+	copy(out[len(c.TraceID):], c.SpanID[:])
+	return out
+}
+
+// UnmarshalContext parses the binary form of the context, an invalid context is returned if the
+// input is too short.
+func UnmarshalContext(in []byte) (c SpanContext) {
+	if len(in) < ContextLen {
+		return
+	}
+	copy(c.TraceID[:], in)
+	copy(c.SpanID[:], in[len(c.TraceID):ContextLen])
+	return
+}
+
+// Inject sets the traceparent header of the http request if the context is valid.
+func Inject(header http.Header, c SpanContext) {
+	if !c.IsValid() {
+		return
+	}
+	header.Set(HeaderTraceParent, fmt.Sprintf("%v-%x-%x-%02x", traceParentVersion, c.TraceID, c.SpanID, traceFlagSampled))
+}
+
+// Extract parses the traceparent header of the http request. An invalid context is returned if the
+// header is absent, malformed or not sampled.
+func Extract(header http.Header) (c SpanContext) {
+	parts := strings.Split(strings.TrimSpace(header.Get(HeaderTraceParent)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return
+	}
+	var traceID, spanID, flags []byte
+	var err error
+	if traceID, err = hex.DecodeString(parts[1]); err != nil || len(traceID) != len(c.TraceID) {
+		return
+	}
+	if spanID, err = hex.DecodeString(parts[2]); err != nil || len(spanID) != len(c.SpanID) {
+		return
+	}
+	if flags, err = hex.DecodeString(parts[3]); err != nil || len(flags) != 1 || flags[0]&traceFlagSampled == 0 {
+		return
+	}
+	copy(c.TraceID[:], traceID)
+	copy(c.SpanID[:], spanID)
+	return
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing records the spans of the requests across the roles and exports them to an
+// OpenTelemetry collector with the JSON encoding of OTLP/HTTP. The trace context is propagated by
+// the W3C traceparent header in the http requests, and by the packet header in the packet protocol.
+package tracing
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ConfigKeyTracingEndpoint    = "tracingEndpoint"    // otlp/http traces endpoint, tracing is disabled if empty
+	ConfigKeyTracingSampleRatio = "tracingSampleRatio" // ratio of the requests traced without a sampled parent
+
+	SpanKindServer = 2
+	SpanKindClient = 3
+
+	AttributePeer        = "net.peer.name"
+	AttributePartitionID = "cfs.partition.id"
+	AttributeResult      = "cfs.result"
+
+	defaultSampleRatio = 0.01
+	queueSize          = 10000
+	batchSize          = 512
+	flushInterval      = 5 * time.Second
+	exportTimeout      = 10 * time.Second
+)
+
+var tracer *Tracer
+
+// Tracer samples the requests and exports the finished spans in batches. The spans are dropped if
+// the exporter falls behind, so that the requests are never blocked by the tracing.
+type Tracer struct {
+	service  string
+	instance string
+	ratio    float64
+	exporter *otlpExporter
+	spanC    chan *Span
+	stopC    chan struct{}
+	doneC    chan struct{}
+	dropped  uint64
+}
+
+// Init enables the tracing of the role if the endpoint is configured.
+func Init(role string, cfg *config.Config) (err error) {
+	endpoint := cfg.GetString(ConfigKeyTracingEndpoint)
+	if endpoint == "" {
+		return
+	}
+	ratio := defaultSampleRatio
+	if value := cfg.GetFloat(ConfigKeyTracingSampleRatio); value != -1 {
+		if value < 0 || value > 1 {
+			return fmt.Errorf("invalid %v: %v", ConfigKeyTracingSampleRatio, value)
+		}
+		ratio = value
+	}
+	tracer = newTracer("cfs_"+role, ratio, newOtlpExporter(endpoint))
+	log.LogInfof("tracing start: service(%v) endpoint(%v) ratio(%v)", tracer.service, endpoint, ratio)
+	return
+}
+
+// Stop exports the queued spans and disables the tracing.
+func Stop() {
+	if t := tracer; t != nil {
+		tracer = nil
+		t.stop()
+	}
+}
+
+func newTracer(service string, ratio float64, exporter *otlpExporter) *Tracer {
+	instance, _ := os.Hostname()
+	t := &Tracer{
+		service:  service,
+		instance: instance,
+		ratio:    ratio,
+		exporter: exporter,
+		spanC:    make(chan *Span, queueSize),
+		stopC:    make(chan struct{}),
+		doneC:    make(chan struct{}),
+	}
+	go t.exportWorker()
+	return t
+}
+
+func (t *Tracer) stop() {
+	close(t.stopC)
+	<-t.doneC
+}
+
+func (t *Tracer) queue(s *Span) {
+	select {
+	case t.spanC <- s:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+func (t *Tracer) exportWorker() {
+	defer close(t.doneC)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.export(t.service, t.instance, batch); err != nil {
+			log.LogWarnf("tracing: export %v spans failed: %v", len(batch), err)
+		}
+		batch = batch[:0]
+		if dropped := atomic.SwapUint64(&t.dropped, 0); dropped > 0 {
+			log.LogWarnf("tracing: %v spans dropped", dropped)
+		}
+	}
+	for {
+		select {
+		case s := <-t.spanC:
+			if batch = append(batch, s); len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stopC:
+			for {
+				select {
+				case s := <-t.spanC:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Span is an operation of a request in a role. A nil span is valid and records nothing, which is
+// returned if the tracing is disabled or the request is not sampled.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	kind     int
+	ctx      SpanContext
+	parentID [8]byte
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      error
+}
+
+// StartSpan starts a span of the request. The span joins the trace of the parent if it is valid,
+// otherwise a new trace is started if the request is picked by the sample ratio.
+func StartSpan(name string, kind int, parent SpanContext) *Span {
+	t := tracer
+	if t == nil {
+		return nil
+	}
+	if !parent.IsValid() && rand.Float64() >= t.ratio {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		s.ctx.TraceID = parent.TraceID
+		s.parentID = parent.SpanID
+	} else {
+		rand.Read(s.ctx.TraceID[:])
+	}
+	rand.Read(s.ctx.SpanID[:])
+	return s
+}
+
+// Context returns the context propagated to the children of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// SetAttribute records an attribute of the span, the value is formatted as a string.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = fmt.Sprint(value)
+}
+
+// Finish ends the span with the result of the operation and queues it to be exported.
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	s.tracer.queue(s)
+}
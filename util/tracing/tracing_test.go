@@ -0,0 +1,108 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPropagation(t *testing.T) {
+	var c SpanContext
+	if c.IsValid() {
+		t.Fatalf("zero context is valid")
+	}
+	header := make(http.Header)
+	Inject(header, c)
+	if header.Get(HeaderTraceParent) != "" {
+		t.Fatalf("invalid context is injected")
+	}
+	for i := range c.TraceID {
+		c.TraceID[i] = byte(i + 1)
+	}
+	for i := range c.SpanID {
+		c.SpanID[i] = byte(i + 0xa0)
+	}
+	if got := UnmarshalContext(c.Marshal()); got != c {
+		t.Fatalf("binary: expect %v, got %v", c, got)
+	}
+	Inject(header, c)
+	if value := header.Get(HeaderTraceParent); value != "00-0102030405060708090a0b0c0d0e0f10-a0a1a2a3a4a5a6a7-01" {
+		t.Fatalf("unexpected traceparent: %v", value)
+	}
+	if got := Extract(header); got != c {
+		t.Fatalf("traceparent: expect %v, got %v", c, got)
+	}
+	for _, value := range []string{
+		"00-0102030405060708090a0b0c0d0e0f10-a0a1a2a3a4a5a6a7-00",
+		"00-0102030405060708090a0b0c0d0e0f-a0a1a2a3a4a5a6a7-01",
+		"ff-0102030405060708090a0b0c0d0e0f10-a0a1a2a3a4a5a6a7-01",
+		"00-0102030405060708090a0b0c0d0e0f10-a0a1a2a3a4a5a6a7",
+	} {
+		header.Set(HeaderTraceParent, value)
+		if Extract(header).IsValid() {
+			t.Fatalf("unexpected valid context of traceparent: %v", value)
+		}
+	}
+}
+
+func TestExportSpans(t *testing.T) {
+	var received = make(chan *otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		req := &otlpRequest{}
+		if err := json.Unmarshal(data, req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- req
+	}))
+	defer server.Close()
+
+	if StartSpan("disabled", SpanKindClient, SpanContext{}) != nil {
+		t.Fatalf("span is started with tracing disabled")
+	}
+	tracer = newTracer("cfs_client", 1, newOtlpExporter(server.URL))
+	root := StartSpan("OpMetaCreateInode", SpanKindClient, SpanContext{})
+	root.SetAttribute("pid", 1)
+	child := StartSpan("OpMetaCreateInode", SpanKindServer, root.Context())
+	if child.Context().TraceID != root.Context().TraceID {
+		t.Fatalf("child is not in the trace of the parent")
+	}
+	child.Finish(errors.New("no space"))
+	root.Finish(nil)
+	Stop()
+
+	req := <-received
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans, got %v", len(spans))
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID || spans[0].Kind != SpanKindServer ||
+		spans[0].Status.Code != otlpStatusCodeError || spans[0].Status.Message != "no space" {
+		t.Fatalf("unexpected child span: %+v", spans[0])
+	}
+	if spans[1].ParentSpanID != "" || spans[1].Status.Code != otlpStatusCodeOk ||
+		len(spans[1].Attributes) != 1 || spans[1].Attributes[0].Value.StringValue != "1" {
+		t.Fatalf("unexpected root span: %+v", spans[1])
+	}
+	if StartSpan("stopped", SpanKindClient, SpanContext{}) != nil {
+		t.Fatalf("span is started after tracing stopped")
+	}
+}